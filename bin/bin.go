@@ -122,7 +122,16 @@ func ZGrab2Main() {
 		log.Fatalf("could not parse flags: %s", err)
 	}
 
+	if m, ok := flag.(*zgrab2.MergeCommand); ok {
+		// merging is a post-processing step on existing results, no modules are run
+		if err = m.Run(); err != nil {
+			log.Fatalf("could not merge results: %s", err)
+		}
+		return
+	}
+
 	var modTypes []string
+	var moduleFlags []any
 	if m, ok := flag.(*zgrab2.MultipleCommand); ok {
 		iniParser := zgrab2.NewIniParser()
 		var flagsReturned []any
@@ -157,6 +166,7 @@ func ZGrab2Main() {
 			if err = s.Init(f); err != nil {
 				log.Panicf("could not initialize multiple scanner: %v", err)
 			}
+			moduleFlags = append(moduleFlags, f)
 			zgrab2.RegisterScan(s.GetName(), s)
 		}
 	} else {
@@ -167,8 +177,12 @@ func ZGrab2Main() {
 		if err = s.Init(flag); err != nil {
 			log.Panicf("could not initialize scanner %s: %v", moduleType, err)
 		}
+		moduleFlags = append(moduleFlags, flag)
 		zgrab2.RegisterScan(moduleType, s)
 	}
+	if err = zgrab2.SetRunConfigHash(moduleFlags); err != nil {
+		log.Warnf("could not hash module configuration for run metadata: %v", err)
+	}
 	zgrab2.ValidateAndHandleFrameworkConfiguration() // will panic if there is an error
 	wg := sync.WaitGroup{}
 	monitor := zgrab2.MakeMonitor(1, &wg, modTypes)
@@ -190,6 +204,7 @@ func ZGrab2Main() {
 		Duration:          end.Sub(start).String(),
 		CLIInvocation:     strings.Join(os.Args, " "),
 		PerModuleMetadata: monitorStatuses,
+		Run:               zgrab2.GetRunMetadata(),
 	}
 	// Calculate total hosts scanned
 	for _, status := range monitorStatuses {
@@ -10,4 +10,5 @@ type Metadata struct {
 	Duration          string                            `json:"duration"`
 	CLIInvocation     string                            `json:"zgrab_cli_parameters,omitempty"`
 	NumTargetsScanned uint                              `json:"num_targets_scanned"`
+	Run               *zgrab2.RunMetadata               `json:"run,omitempty"`
 }
@@ -42,6 +42,10 @@ func init() {
 	if err != nil {
 		log.Fatalf("could not add multiple command: %v", err)
 	}
+	_, err = parser.AddCommand("merge", "Merge the results of several scans into one record per target", "", &config.Merge)
+	if err != nil {
+		log.Fatalf("could not add merge command: %v", err)
+	}
 	_, err = parser.AddGroup("General Options", "General options for controlling the behavior of ZGrab2", &config.GeneralOptions)
 	if err != nil {
 		log.Fatalf("could not add general options group: %v", err)
@@ -36,13 +36,18 @@ const (
 	defaultDNSServerRateLimit   = 10_000
 	defaultDNSResolutionTimeout = 10 * time.Second
 	defaultServerRateLimit      = 20
+	defaultLivenessTimeout      = time.Second
+	defaultLivenessRetries      = 1
+
+	defaultIPv6SamplesPerPrefix = 1
 )
 
 type GeneralOptions struct {
-	Senders          int    `short:"s" long:"senders" description:"Number of send goroutines to use"`
-	GOMAXPROCS       int    `long:"gomaxprocs" description:"Set GOMAXPROCS to set the number of CPU cores to use. 0 uses all available. (default: 0)"`
-	Prometheus       string `long:"prometheus" description:"Address to use for Prometheus server (e.g. localhost:8080). If empty, Prometheus is disabled."`
-	ReadLimitPerHost int    `long:"read-limit-per-host" description:"Maximum total kilobytes to read for a single host"`
+	Senders          int           `short:"s" long:"senders" description:"Number of send goroutines to use"`
+	GOMAXPROCS       int           `long:"gomaxprocs" description:"Set GOMAXPROCS to set the number of CPU cores to use. 0 uses all available. (default: 0)"`
+	Prometheus       string        `long:"prometheus" description:"Address to use for Prometheus server (e.g. localhost:8080). If empty, Prometheus is disabled."`
+	ReadLimitPerHost int           `long:"read-limit-per-host" description:"Maximum total kilobytes to read for a single host"`
+	TargetBudget     time.Duration `long:"target-budget" description:"Total time budget for all modules run against a single target, including DNS resolution (0 = no budget). Each module gets an equal share of what remains, on top of its own --target-timeout."`
 }
 
 type InputOutputOptions struct {
@@ -54,6 +59,16 @@ type InputOutputOptions struct {
 	StatusUpdatesFileName string `short:"u" long:"status-updates-file" description:"Status updates filename, use - for stderr."`
 	Debug                 bool   `long:"debug" description:"Include debug fields in the output."`
 	Flush                 bool   `long:"flush" description:"Flush after each line of output."`
+	JSONEncoder           string `long:"json-encoder" description:"JSON encoder used for results: stdlib, or jsoniter for higher throughput. Both produce identical output."`
+	Shuffle               bool   `long:"shuffle" description:"Read all input targets into memory and scan them in a pseudo-random order."`
+	Seed                  *int64 `long:"seed" description:"Seed for --shuffle and IPv6 prefix sampling. Using the same seed with the same input reproduces the same targets and order. A random seed is chosen if not set."`
+	IPv6Generator         string `long:"ipv6-generator" description:"Generate IPv6 targets from the input file with the named generator instead of reading CSV. Available: hitlist (one IPv6 address per line)."`
+	IPv6SamplePrefixLen   int    `long:"ipv6-sample-prefix-len" description:"Group hitlist addresses by prefixes of this length and sample from each. 0 scans every address."`
+	IPv6SamplesPerPrefix  int    `long:"ipv6-samples-per-prefix" description:"Number of hitlist addresses to scan from each sampled prefix."`
+	RunMetadata           bool   `long:"run-metadata" description:"Stamp a metadata block (scan ID, operator label, zgrab2 version, module config hash, start time) onto every result. Implied by --scan-id and --operator-label."`
+	ScanID                string `long:"scan-id" description:"Scan ID for the run metadata block. A random ID is generated if not set."`
+	OperatorLabel         string `long:"operator-label" description:"Free-form operator label for the run metadata block."`
+	TLSKeyLogFileName     string `long:"tls-key-log-file" description:"Append NSS key log lines for every TLS session established by any module to this file, so captured traffic can be decrypted. Defaults to $SSLKEYLOGFILE if set. WARNING: this exposes the session secrets."`
 }
 
 type NetworkingOptions struct {
@@ -66,6 +81,11 @@ type NetworkingOptions struct {
 	UserIPv4Choice       *bool         `long:"resolve-ipv4" description:"Use IPv4 for resolving domains (accept A records). True by default, use only --resolve-ipv6 for IPv6 only resolution. If used with --resolve-ipv6, will use both IPv4 and IPv6."`
 	UserIPv6Choice       *bool         `long:"resolve-ipv6" description:"Use IPv6 for resolving domains (accept AAAA records). IPv6 is disabled by default. If --resolve-ipv4 is not set and --resolve-ipv6 is, will only use IPv6. If used with --resolve-ipv4, will use both IPv4 and IPv6."`
 	ServerRateLimit      int           `long:"server-rate-limit" description:"Per-IP rate limit for connections to targets per second."`
+	ResolveAll           bool          `long:"resolve-all" description:"Scan every address a domain-only target resolves to (subject to --resolve-ipv4/--resolve-ipv6 and the blocklist), outputting one result per address, instead of one address chosen at random."`
+	LivenessCheck        string        `long:"liveness-check" description:"Pre-filter targets with a fast liveness check before running any module: syn (TCP SYN on a raw socket, Linux only, needs root or CAP_NET_RAW) or udp (an empty UDP datagram, where only an ICMP host or network unreachable counts as no response). Targets that don't respond are reported with an error and not scanned. Targets without a port of their own are checked on the port of each module that will scan them, and are scanned if any is live, or without a check if a module has no port."`
+	LivenessTimeout      time.Duration `long:"liveness-timeout" description:"How long to wait for a response to each liveness check attempt."`
+	LivenessRetries      int           `long:"liveness-retries" description:"Number of times to retry a liveness check that gets no response."`
+	Interface            string        `long:"interface" description:"Network interface used to reach link-local IPv6 targets that don't specify a zone (ex: fe80::1%eth0)."`
 }
 
 // Config is the high level framework options that will be parsed
@@ -75,11 +95,13 @@ type Config struct {
 	InputOutputOptions                   // CLI Options related to I/O. Just affects organization of --help
 	NetworkingOptions                    // CLI Options related to networking. Just affects organization of --help
 	Multiple             MultipleCommand `command:"multiple" description:"Multiple module actions"`
+	Merge                MergeCommand    `command:"merge" description:"Merge results into one record per target"`
 	inputFile            *os.File
 	outputFile           *os.File
 	metaFile             *os.File
 	statusUpdatesFile    *os.File
 	logFile              *os.File
+	tlsKeyLogFile        *os.File
 	inputTargets         InputTargetsFunc
 	outputResults        OutputResultsFunc
 	customDNSNameservers []string // will be non-empty if user specified custom DNS, we'll check these are reachable before populating
@@ -87,6 +109,7 @@ type Config struct {
 	localPorts           []uint16 // will be non-empty if user specified local ports
 	resolveIPv4          bool     // true if IPv4 is enabled, false if only IPv6 is enabled. Guaranteed to be set, whereas UserIPv4Choice may be nil if unset by the user
 	resolveIPv6          bool
+	seed                 int64 // seed for randomized input handling, taken from --seed or chosen at startup
 }
 
 // SetInputFunc sets the target input function to the provided function.
@@ -113,12 +136,16 @@ func init() {
 			MetaFileName:          defaultFileName,
 			OutputFileName:        defaultFileName,
 			StatusUpdatesFileName: defaultFileName,
+			JSONEncoder:           JSONEncoderStdlib,
+			IPv6SamplesPerPrefix:  defaultIPv6SamplesPerPrefix,
 		},
 		NetworkingOptions: NetworkingOptions{
 			ConnectionsPerHost:   defaultConnectionsPerHost,
 			DNSServerRateLimit:   defaultDNSServerRateLimit,
 			DNSResolutionTimeout: defaultDNSResolutionTimeout,
 			ServerRateLimit:      defaultServerRateLimit,
+			LivenessTimeout:      defaultLivenessTimeout,
+			LivenessRetries:      defaultLivenessRetries,
 		},
 	}
 	config.Multiple.ContinueOnError = true // set default for multiple value
@@ -144,7 +171,25 @@ func ValidateAndHandleFrameworkConfiguration() {
 		}
 		log.SetOutput(config.logFile)
 	}
+	if config.Seed != nil {
+		config.seed = *config.Seed
+	} else {
+		config.seed = time.Now().UnixNano()
+	}
 	SetInputFunc(InputTargetsCSV)
+	if config.IPv6Generator != "" {
+		generator := GetIPv6TargetGenerator(config.IPv6Generator)
+		if generator == nil {
+			log.Fatalf("unknown IPv6 target generator %s", config.IPv6Generator)
+		}
+		SetInputFunc(func(ch chan<- ScanTarget) error {
+			return generator(config.inputFile, ch)
+		})
+	}
+	if config.Shuffle {
+		log.Infof("shuffling input targets with seed %d", config.seed)
+		SetInputFunc(InputTargetsShuffled(config.inputTargets, config.seed))
+	}
 
 	if config.InputFileName == "-" {
 		config.inputFile = os.Stdin
@@ -163,6 +208,9 @@ func ValidateAndHandleFrameworkConfiguration() {
 			log.Fatal(err)
 		}
 	}
+	if err := setJSONEncoder(config.JSONEncoder); err != nil {
+		log.Fatal(err)
+	}
 	outputFunc := OutputResultsWriterFunc(config.outputFile)
 	SetOutputFunc(outputFunc)
 
@@ -184,6 +232,17 @@ func ValidateAndHandleFrameworkConfiguration() {
 		}
 	}
 
+	if config.RunMetadata || config.ScanID != "" || config.OperatorLabel != "" {
+		var err error
+		if runMetadata, err = newRunMetadata(config.ScanID, config.OperatorLabel, time.Now()); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := openTLSKeyLog(); err != nil {
+		log.Fatal(err)
+	}
+
 	// Validate Go Runtime config
 	if config.GOMAXPROCS < 0 {
 		log.Fatalf("invalid GOMAXPROCS (must be positive, given %d)", config.GOMAXPROCS)
@@ -207,6 +266,10 @@ func ValidateAndHandleFrameworkConfiguration() {
 		log.Fatalf("need at least one sender, given %d", config.Senders)
 	}
 
+	if config.TargetBudget < 0 {
+		log.Fatalf("invalid target budget (must be positive, given %s)", config.TargetBudget)
+	}
+
 	// validate connections per host
 	if config.ConnectionsPerHost <= 0 {
 		log.Fatalf("need at least one connection, given %d", config.ConnectionsPerHost)
@@ -261,6 +324,12 @@ func ValidateAndHandleFrameworkConfiguration() {
 		config.localAddrs = ips
 	}
 
+	if config.Interface != "" {
+		if _, err := net.InterfaceByName(config.Interface); err != nil {
+			log.Fatalf("could not find interface %s: %s", config.Interface, err)
+		}
+	}
+
 	if !config.resolveIPv4 && !config.resolveIPv6 {
 		log.Fatalf("must use either IPv4 or IPv6, or both. Use --use-ipv4 and/or --use-ipv6 to enable them.")
 	}
@@ -295,6 +364,15 @@ func ValidateAndHandleFrameworkConfiguration() {
 		// initialize to empty blocklist
 		blocklist = cidranger.NewPCTrieRanger()
 	}
+	if config.LivenessCheck != "" && liveness == nil {
+		if config.LivenessTimeout <= 0 || config.LivenessRetries < 0 {
+			log.Fatalf("invalid liveness check timeout %s or retries %d", config.LivenessTimeout, config.LivenessRetries)
+		}
+		var err error
+		if liveness, err = newLivenessChecker(config.LivenessCheck); err != nil {
+			log.Fatalf("could not set up liveness check: %s", err)
+		}
+	}
 	// Initialize the DNS rate limiter
 	// In an ideal world, this would be per-DNS server, but using the system DNS service (setting PreferGo on the resolver to false)
 	// offers the benefit of using the OS DNS cache. The tradeoff is we don't get visibility into which DNS server is chosen for each request.
@@ -303,6 +381,22 @@ func ValidateAndHandleFrameworkConfiguration() {
 	dnsRateLimiter = rate.NewLimiter(rate.Limit(config.DNSServerRateLimit), config.DNSServerRateLimit)
 }
 
+// openTLSKeyLog opens the --tls-key-log-file, or $SSLKEYLOGFILE if the flag isn't set, for appending.
+func openTLSKeyLog() error {
+	if config.TLSKeyLogFileName == "" {
+		config.TLSKeyLogFileName = os.Getenv("SSLKEYLOGFILE")
+	}
+	if config.TLSKeyLogFileName == "" || config.tlsKeyLogFile != nil {
+		return nil
+	}
+	var err error
+	if config.tlsKeyLogFile, err = os.OpenFile(config.TLSKeyLogFileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600); err != nil {
+		return fmt.Errorf("error opening TLS key log file: %w", err)
+	}
+	log.Warnf("writing TLS session secrets to %s", config.TLSKeyLogFileName)
+	return nil
+}
+
 // GetMetaFile returns the file to which metadata should be output
 func GetMetaFile() *os.File {
 	return config.metaFile
@@ -265,6 +265,8 @@ func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.
 	// Determine if address is a domain or an IP address
 	var conn net.Conn
 	host, port, err := net.SplitHostPort(address)
+	// zone identifiers (fe80::1%eth0) are passed through to the underlying dialer but aren't part of the IP itself
+	host, _ = splitIPZone(host)
 	if err == nil && net.ParseIP(host) == nil {
 		// address is a domain
 		conn, err = d.dialContextDomain(ctx, network, host, port)
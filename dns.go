@@ -0,0 +1,135 @@
+package zgrab2
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DNSNameserver returns the nameserver for the lookups of scans themselves, like those of ECH configs: the first one
+// of --dns-resolvers, or else of /etc/resolv.conf.
+func DNSNameserver() (string, error) {
+	if len(config.customDNSNameservers) > 0 {
+		return config.customDNSNameservers[0], nil
+	}
+	f, err := os.Open("/etc/resolv.conf")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) >= 2 && fields[0] == "nameserver" {
+			return net.JoinHostPort(fields[1], "53"), nil
+		}
+	}
+	return "", errors.New("no nameserver in /etc/resolv.conf")
+}
+
+// ExchangeDNS sends a recursive query for the records of type qtype of name to nameserver over UDP, or over TCP if the
+// response is truncated, and returns the response. The query sets the AD bit, so the AuthenticData of the response
+// tells whether a validating resolver authenticated the records with DNSSEC. Responses with an RCode other than
+// success are an error. If ctx has no deadline, --dns-resolution-timeout applies.
+func ExchangeDNS(ctx context.Context, nameserver, name string, qtype dnsmessage.Type) (*dnsmessage.Message, error) {
+	qname, err := dnsmessage.NewName(strings.TrimSuffix(name, ".") + ".")
+	if err != nil {
+		return nil, err
+	}
+	idBytes := make([]byte, 2)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, err
+	}
+	id := binary.BigEndian.Uint16(idBytes)
+	var opt dnsmessage.ResourceHeader
+	if err := opt.SetEDNS0(1232, dnsmessage.RCodeSuccess, false); err != nil {
+		return nil, err
+	}
+	query, err := (&dnsmessage.Message{
+		Header:      dnsmessage.Header{ID: id, RecursionDesired: true, AuthenticData: true},
+		Questions:   []dnsmessage.Question{{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}},
+		Additionals: []dnsmessage.Resource{{Header: opt, Body: &dnsmessage.OPTResource{}}},
+	}).Pack()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.DNSResolutionTimeout)
+		defer cancel()
+	}
+	response, err := exchangeDNS(ctx, "udp", nameserver, id, query)
+	if err == nil && response.Truncated {
+		response, err = exchangeDNS(ctx, "tcp", nameserver, id, query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if response.RCode != dnsmessage.RCodeSuccess {
+		return nil, errors.New(response.RCode.String())
+	}
+	return response, nil
+}
+
+// exchangeDNS sends the packed query with the given ID to nameserver over network ("udp" or "tcp") and returns the
+// response, dialing through the framework's Dialer so that the local address and blocklist settings apply.
+func exchangeDNS(ctx context.Context, network, nameserver string, id uint16, query []byte) (*dnsmessage.Message, error) {
+	dialer := NewDialer(nil)
+	if err := dialer.SetRandomLocalAddr(network, config.localAddrs, config.localPorts); err != nil {
+		return nil, fmt.Errorf("could not set random local address: %w", err)
+	}
+	conn, err := dialer.DialContext(ctx, network, nameserver)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if network == "tcp" {
+		// messages over TCP are prefixed with their length
+		if _, err := conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(query))), query...)); err != nil {
+			return nil, err
+		}
+		reader := bufio.NewReader(conn)
+		length := make([]byte, 2)
+		if _, err := io.ReadFull(reader, length); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, binary.BigEndian.Uint16(length))
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		var response dnsmessage.Message
+		if err := response.Unpack(buf); err != nil {
+			return nil, err
+		}
+		if response.ID != id || !response.Response {
+			return nil, errors.New("DNS response does not match the query")
+		}
+		return &response, nil
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		var response dnsmessage.Message
+		if err := response.Unpack(buf[:n]); err != nil || response.ID != id || !response.Response {
+			continue
+		}
+		return &response, nil
+	}
+}
@@ -0,0 +1,84 @@
+package zgrab2
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsAnswer returns the response to query, with a TXT record unless truncated is set.
+func dnsAnswer(t *testing.T, query []byte, truncated bool) []byte {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(query); err != nil {
+		t.Errorf("invalid query: %v", err)
+		return nil
+	}
+	msg.Response = true
+	msg.Truncated = truncated
+	msg.Additionals = nil
+	if !truncated {
+		msg.Answers = []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: msg.Questions[0].Name, Type: dnsmessage.TypeTXT, Class: dnsmessage.ClassINET},
+			Body:   &dnsmessage.TXTResource{TXT: []string{"v=STSv1; id=1"}},
+		}}
+	}
+	response, err := msg.Pack()
+	if err != nil {
+		t.Error(err)
+	}
+	return response
+}
+
+func TestExchangeDNSTruncated(t *testing.T) {
+	// a nameserver whose UDP responses are truncated, so that the answer is only available over TCP
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	pc, err := net.ListenPacket("udp", ln.Addr().String())
+	if err != nil {
+		t.Skipf("UDP port unavailable: %v", err)
+	}
+	defer pc.Close()
+	go func() {
+		buf := make([]byte, 4096)
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		pc.WriteTo(dnsAnswer(t, buf[:n], true), addr)
+	}()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		length := make([]byte, 2)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return
+		}
+		query := make([]byte, binary.BigEndian.Uint16(length))
+		if _, err := io.ReadFull(conn, query); err != nil {
+			return
+		}
+		response := dnsAnswer(t, query, false)
+		conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(response))), response...))
+	}()
+
+	response, err := ExchangeDNS(context.Background(), ln.Addr().String(), "_mta-sts.example", dnsmessage.TypeTXT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Truncated || len(response.Answers) != 1 {
+		t.Fatalf("response = %+v", response)
+	}
+	if txt, ok := response.Answers[0].Body.(*dnsmessage.TXTResource); !ok || len(txt.TXT) != 1 || txt.TXT[0] != "v=STSv1; id=1" {
+		t.Errorf("answer = %+v", response.Answers[0].Body)
+	}
+}
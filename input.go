@@ -4,6 +4,7 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
+	"math/rand"
 	"net"
 	"strconv"
 	"strings"
@@ -113,7 +114,8 @@ func InputTargetsCSV(ch chan<- ScanTarget) error {
 }
 
 // GetTargetsCSV reads targets from a CSV source, generates ScanTargets,
-// and delivers them to the provided channel.
+// and delivers them to the provided channel. An IPv6 address in the IP
+// field may carry a zone identifier, e.g. fe80::1%eth0.
 func GetTargetsCSV(source io.Reader, ch chan<- ScanTarget) error {
 	csvreader := csv.NewReader(source)
 	csvreader.Comment = '#'
@@ -128,6 +130,8 @@ func GetTargetsCSV(source io.Reader, ch chan<- ScanTarget) error {
 		if len(fields) == 0 {
 			continue
 		}
+		var zone string
+		fields[0], zone = splitIPZone(strings.TrimSpace(fields[0]))
 		ipnet, domain, tag, port, err := ParseCSVTarget(fields)
 		if err != nil {
 			log.Errorf("parse error, skipping: %v", err)
@@ -158,15 +162,48 @@ func GetTargetsCSV(source io.Reader, ch chan<- ScanTarget) error {
 				ip = ipnet.IP
 			}
 		}
+		if ip == nil {
+			zone = ""
+		}
 		if port == "" {
-			ch <- ScanTarget{IP: ip, Domain: domain, Tag: tag}
+			ch <- ScanTarget{IP: ip, Zone: zone, Domain: domain, Tag: tag}
 		} else {
-			ch <- ScanTarget{IP: ip, Domain: domain, Tag: tag, Port: port_uint}
+			ch <- ScanTarget{IP: ip, Zone: zone, Domain: domain, Tag: tag, Port: port_uint}
 		}
 	}
 	return nil
 }
 
+// InputTargetsShuffled wraps an InputTargetsFunc, buffering every target it
+// produces and then delivering them on the channel in a pseudo-random order.
+// The order is fully determined by the seed and the order of the underlying
+// input, so repeating a scan with the same seed and input reproduces it.
+func InputTargetsShuffled(source InputTargetsFunc, seed int64) InputTargetsFunc {
+	return func(ch chan<- ScanTarget) error {
+		var targets []ScanTarget
+		buffer := make(chan ScanTarget)
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- source(buffer)
+			close(buffer)
+		}()
+		for target := range buffer {
+			targets = append(targets, target)
+		}
+		if err := <-errCh; err != nil {
+			return err
+		}
+		rng := rand.New(rand.NewSource(seed))
+		rng.Shuffle(len(targets), func(i, j int) {
+			targets[i], targets[j] = targets[j], targets[i]
+		})
+		for _, target := range targets {
+			ch <- target
+		}
+		return nil
+	}
+}
+
 // InputTargetsFunc is a function type for target input functions.
 //
 // A function of this type generates ScanTargets on the provided
@@ -276,3 +276,113 @@ func TestCompareIPs(t *testing.T) {
 		}
 	}
 }
+
+func TestInputTargetsShuffled(t *testing.T) {
+	input := "10.0.0.0/28\n"
+	collect := func(seed int64) []string {
+		source := func(ch chan<- ScanTarget) error {
+			return GetTargetsCSV(strings.NewReader(input), ch)
+		}
+		ch := make(chan ScanTarget)
+		go func() {
+			if err := InputTargetsShuffled(source, seed)(ch); err != nil {
+				t.Errorf("InputTargetsShuffled error: %v", err)
+			}
+			close(ch)
+		}()
+		var res []string
+		for r := range ch {
+			res = append(res, r.IP.String())
+		}
+		return res
+	}
+
+	first := collect(1)
+	if len(first) != 16 {
+		t.Fatalf("wrong number of results (got %d; expected 16)", len(first))
+	}
+	if again := collect(1); strings.Join(first, ",") != strings.Join(again, ",") {
+		t.Errorf("same seed produced different orders (%v vs %v)", first, again)
+	}
+	if other := collect(2); strings.Join(first, ",") == strings.Join(other, ",") {
+		t.Errorf("different seeds produced the same order (%v)", first)
+	}
+	seen := make(map[string]bool)
+	for _, ip := range first {
+		seen[ip] = true
+	}
+	if len(seen) != 16 {
+		t.Errorf("shuffled output is missing targets (got %d unique; expected 16)", len(seen))
+	}
+}
+
+func TestGetTargetsCSVZone(t *testing.T) {
+	input := "fe80::1%eth0,,,22\nfe80::2\n10.0.0.1%eth0,example.com\n"
+	ch := make(chan ScanTarget)
+	go func() {
+		if err := GetTargetsCSV(strings.NewReader(input), ch); err != nil {
+			t.Errorf("GetTargets error: %v", err)
+		}
+		close(ch)
+	}()
+	var res []ScanTarget
+	for r := range ch {
+		res = append(res, r)
+	}
+	if len(res) != 2 {
+		t.Fatalf("wrong number of results (got %d; expected 2)", len(res))
+	}
+	if res[0].Host() != "fe80::1%eth0" || res[0].Port != 22 {
+		t.Errorf("wrong zoned target (got %s port %d)", res[0].Host(), res[0].Port)
+	}
+	if res[1].Host() != "fe80::2" || res[1].Zone != "" {
+		t.Errorf("wrong unzoned target (got %s)", res[1].Host())
+	}
+}
+
+func TestGetTargetsIPv6Hitlist(t *testing.T) {
+	input := `# hitlist
+2001:db8:0:1::1
+2001:db8:0:1::2
+2001:db8:0:1::3
+2001:db8:0:2::1
+10.0.0.1
+2001:db8:0:2::2
+`
+	collect := func(prefixLen, samples int, seed int64) []ScanTarget {
+		ch := make(chan ScanTarget)
+		go func() {
+			if err := GetTargetsIPv6Hitlist(strings.NewReader(input), prefixLen, samples, seed, ch); err != nil {
+				t.Errorf("GetTargetsIPv6Hitlist error: %v", err)
+			}
+			close(ch)
+		}()
+		var res []ScanTarget
+		for r := range ch {
+			res = append(res, r)
+		}
+		return res
+	}
+
+	if res := collect(0, 1, 0); len(res) != 5 {
+		t.Errorf("wrong number of unsampled results (got %d; expected 5)", len(res))
+	}
+	res := collect(64, 1, 7)
+	if len(res) != 2 {
+		t.Fatalf("wrong number of sampled results (got %d; expected 2)", len(res))
+	}
+	_, first, _ := net.ParseCIDR("2001:db8:0:1::/64")
+	_, second, _ := net.ParseCIDR("2001:db8:0:2::/64")
+	if !first.Contains(res[0].IP) || !second.Contains(res[1].IP) {
+		t.Errorf("samples not taken from each prefix in order (got %v)", res)
+	}
+	again := collect(64, 1, 7)
+	for i := range res {
+		if !res[i].IP.Equal(again[i].IP) {
+			t.Errorf("same seed produced different samples (%v vs %v)", res, again)
+		}
+	}
+	if res := collect(64, 5, 7); len(res) != 5 {
+		t.Errorf("wrong number of results with large sample (got %d; expected 5)", len(res))
+	}
+}
@@ -0,0 +1,140 @@
+package zgrab2
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// IPv6TargetGenerator produces IPv6 scan targets from the input source and
+// delivers them to the provided channel. Generators replace the default CSV
+// input handling when selected with --ipv6-generator.
+type IPv6TargetGenerator func(source io.Reader, ch chan<- ScanTarget) error
+
+var ipv6TargetGenerators map[string]IPv6TargetGenerator
+
+func init() {
+	ipv6TargetGenerators = make(map[string]IPv6TargetGenerator)
+	RegisterIPv6TargetGenerator("hitlist", func(source io.Reader, ch chan<- ScanTarget) error {
+		return GetTargetsIPv6Hitlist(source, config.IPv6SamplePrefixLen, config.IPv6SamplesPerPrefix, config.seed, ch)
+	})
+}
+
+// RegisterIPv6TargetGenerator makes an IPv6TargetGenerator available under
+// the given name for use with --ipv6-generator.
+func RegisterIPv6TargetGenerator(name string, generator IPv6TargetGenerator) {
+	if _, ok := ipv6TargetGenerators[name]; ok {
+		log.Fatalf("IPv6 target generator %s already registered", name)
+	}
+	ipv6TargetGenerators[name] = generator
+}
+
+// GetIPv6TargetGenerator returns the registered generator with the given name,
+// or nil if there is none.
+func GetIPv6TargetGenerator(name string) IPv6TargetGenerator {
+	return ipv6TargetGenerators[name]
+}
+
+// splitIPZone splits an IPv6 literal with a zone identifier (e.g.
+// fe80::1%eth0) into the address and the zone. If host is not a zoned IPv6
+// literal, it is returned unchanged with an empty zone.
+func splitIPZone(host string) (string, string) {
+	i := strings.LastIndexByte(host, '%')
+	if i < 0 {
+		return host, ""
+	}
+	if ip := net.ParseIP(host[:i]); ip == nil || ip.To4() != nil {
+		return host, ""
+	}
+	return host[:i], host[i+1:]
+}
+
+// joinIPZone formats ip, appending the zone identifier if one is set.
+func joinIPZone(ip net.IP, zone string) string {
+	if zone == "" {
+		return ip.String()
+	}
+	return ip.String() + "%" + zone
+}
+
+// applyDefaultZone scopes link-local IPv6 targets that do not carry their own
+// zone identifier to the interface selected with --interface.
+func applyDefaultZone(t *ScanTarget) {
+	if config.Interface == "" || t.IP == nil || t.Zone != "" {
+		return
+	}
+	if t.IP.To4() == nil && (t.IP.IsLinkLocalUnicast() || t.IP.IsLinkLocalMulticast()) {
+		t.Zone = config.Interface
+	}
+}
+
+// GetTargetsIPv6Hitlist reads a hitlist of IPv6 addresses, one per line, and
+// delivers them as ScanTargets. Comment lines begin with # and empty lines are
+// ignored.
+//
+// If prefixLen is greater than zero, addresses are grouped by their
+// prefixLen-bit prefix and at most samplesPerPrefix addresses are chosen from
+// each prefix, uniformly at random using seed. Prefixes are emitted in the
+// order they first appear in the hitlist.
+func GetTargetsIPv6Hitlist(source io.Reader, prefixLen int, samplesPerPrefix int, seed int64, ch chan<- ScanTarget) error {
+	if prefixLen < 0 || prefixLen > 128 {
+		return fmt.Errorf("invalid IPv6 prefix length %d", prefixLen)
+	}
+	if prefixLen > 0 && samplesPerPrefix <= 0 {
+		return fmt.Errorf("need at least one sample per prefix, given %d", samplesPerPrefix)
+	}
+	type reservoir struct {
+		seen    int
+		samples []ScanTarget
+	}
+	var prefixes []string
+	reservoirs := make(map[string]*reservoir)
+	rng := rand.New(rand.NewSource(seed))
+	mask := net.CIDRMask(prefixLen, 128)
+
+	scanner := bufio.NewScanner(source)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		addr, zone := splitIPZone(line)
+		ip := net.ParseIP(addr)
+		if ip == nil || ip.To4() != nil {
+			log.Errorf("parse error, skipping: %q is not an IPv6 address", line)
+			continue
+		}
+		target := ScanTarget{IP: ip, Zone: zone}
+		if prefixLen == 0 {
+			ch <- target
+			continue
+		}
+		prefix := ip.Mask(mask).String()
+		r, ok := reservoirs[prefix]
+		if !ok {
+			r = new(reservoir)
+			reservoirs[prefix] = r
+			prefixes = append(prefixes, prefix)
+		}
+		r.seen++
+		if len(r.samples) < samplesPerPrefix {
+			r.samples = append(r.samples, target)
+		} else if j := rng.Intn(r.seen); j < samplesPerPrefix {
+			r.samples[j] = target
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	for _, prefix := range prefixes {
+		for _, target := range reservoirs[prefix].samples {
+			ch <- target
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package zgrab2
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	"unsafe"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/modern-go/reflect2"
+)
+
+// JSON encoder backends selectable with --json-encoder
+const (
+	JSONEncoderStdlib   = "stdlib"
+	JSONEncoderJSONIter = "jsoniter"
+)
+
+// jsonEncoders maps each --json-encoder name to its marshal function. Every backend must produce output byte-for-byte
+// identical to encoding/json; json_encoder_test.go checks this.
+var jsonEncoders = map[string]func(v any) ([]byte, error){
+	JSONEncoderStdlib:   json.Marshal,
+	JSONEncoderJSONIter: newJSONIterAPI().Marshal,
+}
+
+// marshalJSON is the marshal function used to encode results, chosen with --json-encoder
+var marshalJSON = json.Marshal
+
+// setJSONEncoder selects the backend used to encode results.
+func setJSONEncoder(name string) error {
+	encoder, ok := jsonEncoders[name]
+	if !ok {
+		names := make([]string, 0, len(jsonEncoders))
+		for n := range jsonEncoders {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown JSON encoder %s, must be one of %s", name, strings.Join(names, ", "))
+	}
+	marshalJSON = encoder
+	return nil
+}
+
+// newJSONIterAPI returns a jsoniter configuration that matches encoding/json. jsoniter's own standard library
+// compatible configuration still differs in how it formats float exponents, invalid UTF-8 and the output of
+// MarshalJSON methods, so those are overridden by stdlibCompatExtension. HTML escaping is done by the extension's
+// string encoder, since jsoniter's EscapeHTML option would install its own string encoder ahead of the extension.
+func newJSONIterAPI() jsoniter.API {
+	api := jsoniter.Config{
+		SortMapKeys:            true,
+		ValidateJsonRawMessage: true,
+	}.Froze()
+	api.RegisterExtension(&stdlibCompatExtension{api: api})
+	return api
+}
+
+var (
+	jsonMarshalerType = reflect2.TypeOfPtr((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect2.TypeOfPtr((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// stdlibCompatExtension replaces jsoniter's float and string encoders with ones that produce the same bytes as
+// encoding/json. Types with their own MarshalJSON/MarshalText are left alone.
+type stdlibCompatExtension struct {
+	jsoniter.DummyExtension
+	api jsoniter.API
+}
+
+func (ext *stdlibCompatExtension) CreateEncoder(typ reflect2.Type) jsoniter.ValEncoder {
+	if typ.Implements(jsonMarshalerType) || typ.Implements(textMarshalerType) {
+		return nil
+	}
+	switch typ.Kind() {
+	case reflect.Float32:
+		return &stdlibFloatEncoder{bits: 32}
+	case reflect.Float64:
+		return &stdlibFloatEncoder{bits: 64}
+	case reflect.String:
+		return new(stdlibStringEncoder)
+	}
+	return nil
+}
+
+func (ext *stdlibCompatExtension) DecorateEncoder(typ reflect2.Type, encoder jsoniter.ValEncoder) jsoniter.ValEncoder {
+	if typ.Implements(jsonMarshalerType) || reflect2.PtrTo(typ).Implements(jsonMarshalerType) {
+		return &stdlibMarshalerEncoder{api: ext.api, encoder: encoder}
+	}
+	return encoder
+}
+
+// stdlibMarshalerEncoder compacts and HTML-escapes the output of MarshalJSON, as encoding/json does.
+type stdlibMarshalerEncoder struct {
+	api     jsoniter.API
+	encoder jsoniter.ValEncoder
+}
+
+func (enc *stdlibMarshalerEncoder) IsEmpty(ptr unsafe.Pointer) bool {
+	return enc.encoder.IsEmpty(ptr)
+}
+
+func (enc *stdlibMarshalerEncoder) Encode(ptr unsafe.Pointer, stream *jsoniter.Stream) {
+	inner := enc.api.BorrowStream(nil)
+	defer enc.api.ReturnStream(inner)
+	enc.encoder.Encode(ptr, inner)
+	if inner.Error != nil {
+		stream.Error = inner.Error
+		return
+	}
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, inner.Buffer()); err != nil {
+		stream.Error = err
+		return
+	}
+	var escaped bytes.Buffer
+	json.HTMLEscape(&escaped, compacted.Bytes())
+	stream.Write(escaped.Bytes())
+}
+
+type stdlibFloatEncoder struct {
+	bits int
+}
+
+func (enc *stdlibFloatEncoder) IsEmpty(ptr unsafe.Pointer) bool {
+	if enc.bits == 32 {
+		return *(*float32)(ptr) == 0
+	}
+	return *(*float64)(ptr) == 0
+}
+
+// Encode follows encoding/json's floatEncoder
+func (enc *stdlibFloatEncoder) Encode(ptr unsafe.Pointer, stream *jsoniter.Stream) {
+	var f float64
+	if enc.bits == 32 {
+		f = float64(*(*float32)(ptr))
+	} else {
+		f = *(*float64)(ptr)
+	}
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		stream.Error = fmt.Errorf("json: unsupported value: %s", strconv.FormatFloat(f, 'g', -1, enc.bits))
+		return
+	}
+	abs := math.Abs(f)
+	format := byte('f')
+	if abs != 0 {
+		if enc.bits == 64 && (abs < 1e-6 || abs >= 1e21) || enc.bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	b := strconv.AppendFloat(nil, f, format, -1, enc.bits)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(b)
+		if n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	stream.Write(b)
+}
+
+type stdlibStringEncoder struct{}
+
+func (enc *stdlibStringEncoder) IsEmpty(ptr unsafe.Pointer) bool {
+	return len(*(*string)(ptr)) == 0
+}
+
+func (enc *stdlibStringEncoder) Encode(ptr unsafe.Pointer, stream *jsoniter.Stream) {
+	s := *(*string)(ptr)
+	if utf8.ValidString(s) {
+		stream.WriteStringWithHTMLEscaped(s)
+		return
+	}
+	// rare enough that it isn't worth duplicating encoding/json's replacement logic
+	encoded, err := json.Marshal(s)
+	if err != nil {
+		stream.Error = err
+		return
+	}
+	stream.Write(encoded)
+}
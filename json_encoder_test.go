@@ -0,0 +1,131 @@
+package zgrab2
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	stdx509 "crypto/x509"
+	"crypto/x509/pkix"
+	"math"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/zmap/zcrypto/tls"
+	"github.com/zmap/zcrypto/x509"
+)
+
+type jsonEncoderTestResult struct {
+	Banner     string                 `json:"banner,omitempty"`
+	Raw        []byte                 `json:"raw,omitempty"`
+	Floats     []float64              `json:"floats"`
+	Float32    float32                `json:"float32"`
+	Ints       map[string]int64       `json:"ints"`
+	Nested     *jsonEncoderTestResult `json:"nested,omitempty"`
+	IP         net.IP                 `json:"ip"`
+	Time       time.Time              `json:"time"`
+	Duration   time.Duration          `json:"duration"`
+	Any        any                    `json:"any"`
+	Debug      string                 `json:"debug,omitempty" zgrab:"debug"`
+	unexported string
+	Embedded
+}
+
+type Embedded struct {
+	Name string `json:"name"`
+}
+
+type jsonEncoderTestMarshaler struct{}
+
+func (jsonEncoderTestMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`{ "html": "<b>",
+	  "list": [1, 2] }`), nil
+}
+
+// testCertificate returns a freshly generated self-signed certificate, parsed by zcrypto
+func testCertificate(t *testing.T) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	template := &stdx509.Certificate{
+		SerialNumber: big.NewInt(1234),
+		Subject:      pkix.Name{CommonName: "example.com <test>", Organization: []string{"Example & Co"}},
+		DNSNames:     []string{"example.com", "www.example.com"},
+		NotBefore:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	der, err := stdx509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("could not create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("could not parse certificate: %v", err)
+	}
+	return cert
+}
+
+func TestJSONEncodersIdentical(t *testing.T) {
+	errString := "connection refused"
+	results := []any{
+		&jsonEncoderTestResult{
+			Banner:   "SSH-2.0-OpenSSH <script>&   \xff\xfe invalid utf8 \"quoted\" \\ \t\n",
+			Raw:      []byte{0, 1, 2, 0xff},
+			Floats:   []float64{0, -0.0, 1, 1.5, 1e20, 1e21, 1e-6, 1e-7, 123456789.123456789, math.MaxFloat64, math.SmallestNonzeroFloat64},
+			Float32:  3.14159,
+			Ints:     map[string]int64{"z": 1, "a": -2, "m": math.MaxInt64, "<": math.MinInt64},
+			Nested:   &jsonEncoderTestResult{Banner: "inner", Any: []any{nil, true, 1.0, "x", map[string]any{"b": 1, "a": []int{}}}},
+			IP:       net.ParseIP("2001:db8::1"),
+			Time:     time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+			Duration: 1500 * time.Millisecond,
+			Any:      map[string]string{"k": "v"},
+			Debug:    "debug only",
+			Embedded: Embedded{Name: "embedded"},
+		},
+		&tls.ServerHello{Version: tls.VersionTLS12, CipherSuite: 0xc02f, Random: []byte{1, 2, 3}},
+		testCertificate(t),
+	}
+	for _, includeDebug := range []bool{false, true} {
+		for i, result := range results {
+			grab := &Grab{
+				IP:     "192.0.2.1",
+				Port:   443,
+				Domain: "example.com",
+				Data: map[string]ScanResponse{
+					"b": {Status: SCAN_SUCCESS, Protocol: "test", Port: 443, Result: result, Timestamp: "2024-01-02T03:04:05Z"},
+					"a": {Status: SCAN_CONNECTION_REFUSED, Protocol: "test", Error: &errString},
+				},
+				Run: &RunMetadata{ScanID: "id", StartTime: "2024-01-02T03:04:05Z"},
+			}
+			var outputs [][]byte
+			for _, name := range []string{JSONEncoderStdlib, JSONEncoderJSONIter} {
+				if err := setJSONEncoder(name); err != nil {
+					t.Fatalf("could not set encoder %s: %v", name, err)
+				}
+				out, err := EncodeGrab(grab, includeDebug)
+				if err != nil {
+					t.Fatalf("encoder %s failed on result %d: %v", name, i, err)
+				}
+				outputs = append(outputs, out)
+			}
+			if !bytes.Equal(outputs[0], outputs[1]) {
+				t.Errorf("encoders differ on result %d (debug=%t):\nstdlib:   %s\njsoniter: %s", i, includeDebug, outputs[0], outputs[1])
+			}
+		}
+	}
+	if err := setJSONEncoder(JSONEncoderStdlib); err != nil {
+		t.Fatalf("could not reset encoder: %v", err)
+	}
+}
+
+func TestSetJSONEncoderUnknown(t *testing.T) {
+	if err := setJSONEncoder("sonic"); err == nil {
+		t.Errorf("expected an error for an unknown encoder")
+	}
+	if marshalJSON == nil {
+		t.Errorf("encoder was cleared by an invalid name")
+	}
+}
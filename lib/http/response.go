@@ -88,6 +88,12 @@ type Response struct {
 	BodyHash string `json:"body_hash,omitempty"`
 	// Number of bytes read from the server and encoded into BodyText
 	BodyTextLength int64 `json:"body_length,omitempty"`
+	// Compression is set when the scanner decoded the body from its Content-Encoding
+	Compression *ResponseCompression `json:"compression,omitempty"`
+	// BodyTruncated is true if the body is longer than the part read into BodyText
+	BodyTruncated bool `json:"body_truncated,omitempty"`
+	// FullBody digests the whole body when the scanner reads beyond BodyText to hash it
+	FullBody *BodyDigest `json:"full_body,omitempty"`
 
 	// ContentLength records the length of the associated content. The
 	// value -1 indicates that the length is unknown. Unless Request.Method
@@ -137,6 +143,70 @@ type Response struct {
 	// The pointer is shared between responses and should not be
 	// modified.
 	TLS *tls.ConnectionState `json:"-"`
+
+	// HTTP2 records HTTP/2 framing details for responses received over
+	// HTTP/2, when the transport was asked to capture them.
+	HTTP2 *HTTP2Info `json:"http2,omitempty"`
+}
+
+// HTTP2Info holds details of the HTTP/2 connection and stream a Response
+// was received on.
+type HTTP2Info struct {
+	// Negotiation is how HTTP/2 was selected: "alpn", "h2c-upgrade" or
+	// "prior-knowledge".
+	Negotiation string `json:"negotiation,omitempty"`
+
+	// Settings lists the parameters of the server's SETTINGS frames, in the
+	// order they were received.
+	Settings []HTTP2Setting `json:"settings,omitempty"`
+
+	// PushEnabled is true if the client advertised SETTINGS_ENABLE_PUSH=1.
+	PushEnabled bool `json:"push_enabled"`
+
+	// PushPromises lists the PUSH_PROMISE frames the server sent for the
+	// stream. The promised streams are refused, so only the promised
+	// request is recorded.
+	PushPromises []HTTP2PushPromise `json:"push_promises,omitempty"`
+
+	// PseudoHeaders holds the response's pseudo-header fields, e.g. ":status".
+	PseudoHeaders []HTTP2Field `json:"pseudo_headers,omitempty"`
+}
+
+// HTTP2Setting is a single parameter of an HTTP/2 SETTINGS frame.
+type HTTP2Setting struct {
+	ID    uint16 `json:"id"`
+	Name  string `json:"name,omitempty"`
+	Value uint32 `json:"value"`
+}
+
+// HTTP2Field is a single decoded HTTP/2 header field.
+type HTTP2Field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HTTP2PushPromise is a request the server promised to push.
+type HTTP2PushPromise struct {
+	PromisedStreamID uint32       `json:"promised_stream_id"`
+	Headers          []HTTP2Field `json:"headers,omitempty"`
+}
+
+// ResponseCompression describes the decoding of a response body from its Content-Encoding. The sizes cover the
+// part of the body that was read.
+type ResponseCompression struct {
+	Encoding         string `json:"encoding"`
+	CompressedSize   int64  `json:"compressed_size"`
+	DecompressedSize int64  `json:"decompressed_size"`
+	Error            string `json:"error,omitempty"`
+}
+
+// BodyDigest holds the hashes of a response body that the scanner read up to a limit, past the part it stores.
+type BodyDigest struct {
+	Length  int64           `json:"length"`
+	SHA256  PageFingerprint `json:"sha256"`
+	SimHash string          `json:"simhash,omitempty"`
+	// Truncated is true if the body is longer than the limit, so that the digest covers only its start.
+	Truncated bool `json:"truncated,omitempty"`
 }
 
 // Hex returns the given fingerprint encoded as a hex string.
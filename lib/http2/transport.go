@@ -180,6 +180,15 @@ type Transport struct {
 	// The errType consists of only ASCII word characters.
 	CountError func(errType string)
 
+	// EnablePush advertises SETTINGS_ENABLE_PUSH=1 to the server. Received
+	// PUSH_PROMISE frames are recorded in Response.HTTP2 and the promised
+	// streams are refused.
+	EnablePush bool
+
+	// RecordHTTP2Info attaches the server's SETTINGS parameters and the
+	// response pseudo-header fields to each Response as Response.HTTP2.
+	RecordHTTP2Info bool
+
 	// t1, if non-nil, is the standard library Transport using
 	// this transport. Its settings are used (but not its
 	// RoundTrip method, etc).
@@ -403,6 +412,7 @@ type ClientConn struct {
 	readIdleTimeout             time.Duration
 	pingTimeout                 time.Duration
 	extendedConnectAllowed      bool
+	settingsLog                 []http.HTTP2Setting // peer SETTINGS, if t.RecordHTTP2Info
 
 	// rstStreamPingsBlocked works around an unfortunate gRPC behavior.
 	// gRPC strictly limits the number of PING frames that it will receive.
@@ -464,6 +474,8 @@ type clientStream struct {
 	respHeaderRecv chan struct{}  // closed when headers are received
 	res            *http.Response // set if respHeaderRecv is closed
 
+	pushPromises []http.HTTP2PushPromise // guarded by cc.mu; received before res
+
 	flow        outflow // guarded by cc.mu
 	inflow      inflow  // guarded by cc.mu
 	bytesRemain int64   // -1 means unknown; owned by transportResponseBody.Read
@@ -886,7 +898,7 @@ func (t *Transport) newClientConn(c net.Conn, singleUse bool) (*ClientConn, erro
 	}
 
 	initialSettings := []Setting{
-		{ID: SettingEnablePush, Val: 0},
+		{ID: SettingEnablePush, Val: enablePushValue(t.EnablePush)},
 		{ID: SettingInitialWindowSize, Val: uint32(cc.initialStreamRecvWindowSize)},
 	}
 	initialSettings = append(initialSettings, Setting{ID: SettingMaxFrameSize, Val: conf.MaxReadFrameSize})
@@ -2484,6 +2496,10 @@ func (rl *clientConnReadLoop) handleResponse(cs *clientStream, f *MetaHeadersFra
 		return nil, nil
 	}
 
+	if cs.cc.t.RecordHTTP2Info || cs.cc.t.EnablePush {
+		res.HTTP2 = cs.cc.http2Info(cs, f.PseudoFields())
+	}
+
 	res.ContentLength = -1
 	if clens := res.Header["Content-Length"]; len(clens) == 1 {
 		if cl, err := strconv.ParseUint(clens[0], 10, 63); err == nil {
@@ -2869,6 +2885,9 @@ func (rl *clientConnReadLoop) processSettingsNoWrite(f *SettingsFrame) error {
 
 	var seenMaxConcurrentStreams bool
 	err := f.ForeachSetting(func(s Setting) error {
+		if cc.t.RecordHTTP2Info {
+			cc.settingsLog = append(cc.settingsLog, http.HTTP2Setting{ID: uint16(s.ID), Name: s.ID.String(), Value: s.Val})
+		}
 		switch s.ID {
 		case SettingMaxFrameSize:
 			cc.maxFrameSize = s.Val
@@ -3062,6 +3081,9 @@ func (rl *clientConnReadLoop) processPing(f *PingFrame) error {
 }
 
 func (rl *clientConnReadLoop) processPushPromise(f *PushPromiseFrame) error {
+	if rl.cc.t.EnablePush {
+		return rl.recordPushPromise(f)
+	}
 	// We told the peer we don't want them.
 	// Spec says:
 	// "PUSH_PROMISE MUST NOT be sent if the SETTINGS_ENABLE_PUSH
@@ -3072,6 +3094,69 @@ func (rl *clientConnReadLoop) processPushPromise(f *PushPromiseFrame) error {
 	return ConnectionError(ErrCodeProtocol)
 }
 
+// recordPushPromise records a PUSH_PROMISE frame on the stream it was sent
+// for, then refuses the promised stream. Only the promised request headers
+// are of interest; the pushed response itself is never read.
+func (rl *clientConnReadLoop) recordPushPromise(f *PushPromiseFrame) error {
+	cc := rl.cc
+	if !f.HeadersEnded() {
+		// The Framer does not merge CONTINUATION frames into PUSH_PROMISE.
+		return ConnectionError(ErrCodeProtocol)
+	}
+	// The header block must be decoded even if the stream is gone, to keep
+	// the HPACK dynamic table in sync with the server.
+	fields, err := cc.fr.ReadMetaHeaders.DecodeFull(f.HeaderBlockFragment())
+	if err != nil {
+		return ConnectionError(ErrCodeCompression)
+	}
+	promise := http.HTTP2PushPromise{PromisedStreamID: f.PromiseID}
+	for _, hf := range fields {
+		promise.Headers = append(promise.Headers, http.HTTP2Field{Name: hf.Name, Value: hf.Value})
+	}
+
+	cc.mu.Lock()
+	if cs := cc.streams[f.StreamID]; cs != nil {
+		if cs.res != nil && cs.res.HTTP2 != nil {
+			cs.res.HTTP2.PushPromises = append(cs.res.HTTP2.PushPromises, promise)
+		} else {
+			cs.pushPromises = append(cs.pushPromises, promise)
+		}
+	}
+	cc.mu.Unlock()
+
+	cc.wmu.Lock()
+	defer cc.wmu.Unlock()
+	cc.fr.WriteRSTStream(f.PromiseID, ErrCodeRefusedStream)
+	return cc.bw.Flush()
+}
+
+// http2Info builds the Response.HTTP2 record for a response on cs.
+func (cc *ClientConn) http2Info(cs *clientStream, pseudo []hpack.HeaderField) *http.HTTP2Info {
+	info := &http.HTTP2Info{
+		Negotiation: "prior-knowledge",
+		PushEnabled: cc.t.EnablePush,
+	}
+	if cc.tlsState != nil {
+		info.Negotiation = "alpn"
+	}
+	for _, hf := range pseudo {
+		info.PseudoHeaders = append(info.PseudoHeaders, http.HTTP2Field{Name: hf.Name, Value: hf.Value})
+	}
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	info.Settings = append(info.Settings, cc.settingsLog...)
+	info.PushPromises, cs.pushPromises = cs.pushPromises, nil
+	return info
+}
+
+// enablePushValue returns the SETTINGS_ENABLE_PUSH value to advertise.
+func enablePushValue(enable bool) uint32 {
+	if enable {
+		return 1
+	}
+	return 0
+}
+
 // writeStreamReset sends a RST_STREAM frame.
 // When ping is true, it also sends a PING frame with a random payload.
 func (cc *ClientConn) writeStreamReset(streamID uint32, code ErrCode, ping bool, err error) {
@@ -5979,3 +5979,54 @@ func TestExtendedConnectReadFrameError(t *testing.T) {
 		t.Fatalf("after connection closed: RoundTrip succeeded; want error")
 	}
 }
+
+func TestTransportRecordsHTTP2Info(t *testing.T) {
+	tc := newTestClientConn(t, func(tr *Transport) {
+		tr.EnablePush = true
+		tr.RecordHTTP2Info = true
+	})
+	tc.wantSettings(map[SettingID]uint32{SettingEnablePush: 1})
+	tc.wantFrameType(FrameWindowUpdate)
+	tc.writeSettings(Setting{ID: SettingMaxConcurrentStreams, Val: 100})
+	tc.writeSettingsAck()
+	tc.wantFrameType(FrameSettings) // acknowledgement
+
+	req, _ := http.NewRequest("GET", "https://dummy.tld/", nil)
+	rt := tc.roundTrip(req)
+	tc.wantFrameType(FrameHeaders)
+
+	if err := tc.fr.WritePushPromise(PushPromiseParam{
+		StreamID:      rt.streamID(),
+		PromiseID:     2,
+		BlockFragment: tc.makeHeaderBlockFragment(":method", "GET", ":path", "/style.css"),
+		EndHeaders:    true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tc.wantRSTStream(2, ErrCodeRefusedStream)
+
+	tc.writeHeaders(HeadersFrameParam{
+		StreamID:      rt.streamID(),
+		EndHeaders:    true,
+		EndStream:     true,
+		BlockFragment: tc.makeHeaderBlockFragment(":status", "204"),
+	})
+	rt.wantStatus(204)
+
+	info := rt.response().HTTP2
+	if info == nil {
+		t.Fatal("response has no HTTP2 info")
+	}
+	if !info.PushEnabled {
+		t.Error("PushEnabled = false, want true")
+	}
+	if len(info.Settings) != 1 || info.Settings[0].Name != "MAX_CONCURRENT_STREAMS" || info.Settings[0].Value != 100 {
+		t.Errorf("Settings = %+v", info.Settings)
+	}
+	if len(info.PseudoHeaders) != 1 || info.PseudoHeaders[0] != (http.HTTP2Field{Name: ":status", Value: "204"}) {
+		t.Errorf("PseudoHeaders = %+v", info.PseudoHeaders)
+	}
+	if len(info.PushPromises) != 1 || info.PushPromises[0].PromisedStreamID != 2 || len(info.PushPromises[0].Headers) != 2 || info.PushPromises[0].Headers[1].Value != "/style.css" {
+		t.Errorf("PushPromises = %+v", info.PushPromises)
+	}
+}
@@ -0,0 +1,182 @@
+package mysql
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Packet headers and AuthMoreData payloads of the caching_sha2_password exchange. See
+// https://dev.mysql.com/doc/dev/mysql-server/latest/page_caching_sha2_authentication_exchanges.html
+const (
+	okPacketHeader          = 0x00
+	authMoreDataHeader      = 0x01
+	authSwitchRequestHeader = 0xfe
+	errPacketHeader         = 0xff
+
+	cachingSHA2FastAuthSuccess    = 0x03
+	cachingSHA2PerformFullAuth    = 0x04
+	cachingSHA2RequestPublicKey   = 0x02
+	cachingSHA2PluginName         = "caching_sha2_password"
+	cachingSHA2ScrambleLength     = 32
+	handshakeResponseCapabilities = CLIENT_LONG_PASSWORD | CLIENT_PROTOCOL_41 | CLIENT_SECURE_CONNECTION | CLIENT_PLUGIN_AUTH
+)
+
+// HandshakeResponsePacket is the HandshakeResponse41 packet the client answers the HandshakePacket with to log in.
+type HandshakeResponsePacket struct {
+	CapabilityFlags uint32 `json:"capability_flags"`
+	MaxPacketSize   uint32 `json:"max_packet_size"`
+	CharacterSet    byte   `json:"character_set"`
+	Username        string `json:"username"`
+	AuthResponse    []byte `zgrab:"debug" json:"auth_response,omitempty"`
+	AuthPluginName  string `json:"auth_plugin_name,omitempty"`
+}
+
+// EncodeBody encodes the HandshakeResponsePacket for transport to the server.
+func (p *HandshakeResponsePacket) EncodeBody() []byte {
+	ret := make([]byte, 32, 32+len(p.Username)+len(p.AuthResponse)+len(p.AuthPluginName)+3)
+	binary.LittleEndian.PutUint32(ret[0:], p.CapabilityFlags)
+	binary.LittleEndian.PutUint32(ret[4:], p.MaxPacketSize)
+	ret[8] = p.CharacterSet
+	ret = append(ret, p.Username...)
+	ret = append(ret, 0)
+	ret = append(ret, byte(len(p.AuthResponse)))
+	ret = append(ret, p.AuthResponse...)
+	ret = append(ret, p.AuthPluginName...)
+	return append(ret, 0)
+}
+
+// authDataPacket is a packet of raw authentication data sent by the client.
+type authDataPacket []byte
+
+// EncodeBody returns the data.
+func (p authDataPacket) EncodeBody() []byte {
+	return p
+}
+
+// CachingSHA2Log is the outcome of a caching_sha2_password login asking the server for the RSA public key it uses in
+// full authentication, on connections without TLS.
+type CachingSHA2Log struct {
+	// FastAuthentication tells whether the server accepted the scramble from its cache, which it never does for
+	// unknown users.
+	FastAuthentication bool `json:"fast_authentication,omitempty"`
+
+	// FullAuthentication tells whether the server asked for full authentication, in which clients without TLS
+	// encrypt the password with the public key.
+	FullAuthentication bool `json:"full_authentication"`
+
+	// AuthSwitchPlugin is the plugin the server switched the authentication to instead, if it did.
+	AuthSwitchPlugin string `json:"auth_switch_plugin,omitempty"`
+
+	// PublicKey is the PEM-encoded RSA public key the server sent.
+	PublicKey string `json:"public_key,omitempty"`
+
+	// PublicKeyBits is the size of the modulus of the public key.
+	PublicKeyBits int `json:"public_key_bits,omitempty"`
+
+	// ErrorCode and ErrorMessage are those of the ERRPacket the server ended the exchange with, if it did.
+	ErrorCode    *int   `json:"error_code,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// readAuthPacket reads a packet of the authentication phase, which readPacket cannot decode, and returns its body.
+func (c *Connection) readAuthPacket() ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(c.Connection, header[:]); err != nil {
+		return nil, fmt.Errorf("error reading packet header: %w", err)
+	}
+	c.SequenceNumber = header[3] + 1
+	header[3] = 0
+	packetSize := binary.LittleEndian.Uint32(header[:])
+	if packetSize == 0 || packetSize > 0x10000 {
+		return nil, fmt.Errorf("unexpected authentication packet size 0x%x", packetSize)
+	}
+	body := make([]byte, packetSize)
+	if _, err := io.ReadFull(c.Connection, body); err != nil {
+		return nil, fmt.Errorf("error reading %d bytes of authentication packet: %w", packetSize, err)
+	}
+	return body, nil
+}
+
+// RequestCachingSHA2PublicKey logs in as user with a random scramble using the caching_sha2_password plugin, and
+// requests the RSA public key when the server asks for full authentication, after which the login is abandoned. It
+// must be called after Connect, on a connection without TLS.
+func (c *Connection) RequestCachingSHA2PublicKey(user string) (*CachingSHA2Log, error) {
+	handshake := c.GetHandshake()
+	if handshake == nil {
+		return nil, errors.New("no handshake packet")
+	}
+	if handshake.CapabilityFlags&CLIENT_PLUGIN_AUTH == 0 {
+		return nil, errors.New("server does not support authentication plugins")
+	}
+	scramble := make([]byte, cachingSHA2ScrambleLength)
+	if _, err := rand.Read(scramble); err != nil {
+		return nil, err
+	}
+	maxPacketSize := c.Config.MaxPacketSize
+	if maxPacketSize == 0 {
+		maxPacketSize = 0xffffff
+	}
+	response := HandshakeResponsePacket{
+		CapabilityFlags: handshakeResponseCapabilities,
+		MaxPacketSize:   maxPacketSize,
+		CharacterSet:    handshake.CharacterSet,
+		Username:        user,
+		AuthResponse:    scramble,
+		AuthPluginName:  cachingSHA2PluginName,
+	}
+	if _, err := c.sendPacket(&response); err != nil {
+		return nil, fmt.Errorf("error sending HandshakeResponse packet: %w", err)
+	}
+	ret := &CachingSHA2Log{}
+	for {
+		body, err := c.readAuthPacket()
+		if err != nil {
+			return ret, err
+		}
+		switch {
+		case body[0] == errPacketHeader:
+			if len(body) < 9 {
+				return ret, fmt.Errorf("short ERR packet of %d bytes", len(body))
+			}
+			errPacket, err := c.readERRPacket(body)
+			if err != nil {
+				return ret, err
+			}
+			code := int(errPacket.ErrorCode)
+			ret.ErrorCode, ret.ErrorMessage = &code, errPacket.ErrorMessage
+			return ret, nil
+		case body[0] == okPacketHeader:
+			return ret, nil
+		case body[0] == authSwitchRequestHeader:
+			ret.AuthSwitchPlugin, _ = readNulString(body[1:])
+			return ret, nil
+		case body[0] == authMoreDataHeader && len(body) == 2 && body[1] == cachingSHA2FastAuthSuccess:
+			// an OK packet follows
+			ret.FastAuthentication = true
+		case body[0] == authMoreDataHeader && len(body) == 2 && body[1] == cachingSHA2PerformFullAuth:
+			ret.FullAuthentication = true
+			if _, err := c.sendPacket(authDataPacket{cachingSHA2RequestPublicKey}); err != nil {
+				return ret, fmt.Errorf("error requesting public key: %w", err)
+			}
+		case body[0] == authMoreDataHeader && ret.FullAuthentication:
+			ret.PublicKey = strings.TrimSpace(string(body[1:]))
+			if block, _ := pem.Decode(body[1:]); block != nil {
+				if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+					if rsaKey, ok := key.(*rsa.PublicKey); ok {
+						ret.PublicKeyBits = rsaKey.N.BitLen()
+					}
+				}
+			}
+			return ret, nil
+		default:
+			return ret, fmt.Errorf("unexpected authentication packet 0x%02x", body[0])
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package mysql
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"net"
+	"testing"
+)
+
+// fakeAuthServer answers each packet read from the client with the next packets of replies, and returns a
+// connection past the handshake.
+func fakeAuthServer(t *testing.T, replies ...[][]byte) *Connection {
+	client, server := net.Pipe()
+	t.Cleanup(func() { client.Close() })
+	go func() {
+		defer server.Close()
+		for _, reply := range replies {
+			var header [4]byte
+			if _, err := io.ReadFull(server, header[:]); err != nil {
+				return
+			}
+			sequence := header[3]
+			header[3] = 0
+			if _, err := io.CopyN(io.Discard, server, int64(binary.LittleEndian.Uint32(header[:]))); err != nil {
+				return
+			}
+			for _, body := range reply {
+				sequence++
+				packet := make([]byte, 4, 4+len(body))
+				binary.LittleEndian.PutUint32(packet, uint32(len(body)))
+				packet[3] = sequence
+				server.Write(append(packet, body...))
+			}
+		}
+	}()
+	c := NewConnection(&Config{})
+	c.Connection = client
+	c.ConnectionLog.Handshake = &ConnectionLogEntry{Parsed: &HandshakePacket{CapabilityFlags: CLIENT_PROTOCOL_41 | CLIENT_PLUGIN_AUTH}}
+	return c
+}
+
+func TestRequestCachingSHA2PublicKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	c := fakeAuthServer(t,
+		[][]byte{{authMoreDataHeader, cachingSHA2PerformFullAuth}},
+		[][]byte{append([]byte{authMoreDataHeader}, keyPEM...)})
+	ret, err := c.RequestCachingSHA2PublicKey("zgrab2")
+	if err != nil || !ret.FullAuthentication || ret.PublicKeyBits != 1024 || ret.PublicKey == "" || ret.ErrorCode != nil {
+		t.Errorf("full authentication = %+v, %v", ret, err)
+	}
+
+	errPacket := append([]byte{errPacketHeader, 0x15, 0x04, '#', '2', '8', '0', '0', '0'}, "Access denied"...)
+	c = fakeAuthServer(t, [][]byte{errPacket})
+	ret, err = c.RequestCachingSHA2PublicKey("zgrab2")
+	if err != nil || ret.FullAuthentication || ret.ErrorCode == nil || *ret.ErrorCode != 1045 {
+		t.Errorf("denied = %+v, %v", ret, err)
+	}
+
+	c = fakeAuthServer(t, [][]byte{append([]byte{authSwitchRequestHeader}, "mysql_native_password\x00"...)})
+	ret, err = c.RequestCachingSHA2PublicKey("zgrab2")
+	if err != nil || ret.AuthSwitchPlugin != "mysql_native_password" {
+		t.Errorf("auth switch = %+v, %v", ret, err)
+	}
+}
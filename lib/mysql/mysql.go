@@ -81,6 +81,13 @@ const (
 	CLIENT_CAN_HANDLE_EXPIRED_PASSWORDS
 	CLIENT_SESSION_TRACK
 	CLIENT_DEPRECATED_EOF
+	CLIENT_OPTIONAL_RESULTSET_METADATA
+	CLIENT_ZSTD_COMPRESSION_ALGORITHM
+	CLIENT_QUERY_ATTRIBUTES
+	MULTI_FACTOR_AUTHENTICATION
+	CLIENT_CAPABILITY_EXTENSION
+	CLIENT_SSL_VERIFY_SERVER_CERT
+	CLIENT_REMEMBER_OPTIONS
 )
 
 // Config defaults
@@ -155,6 +162,13 @@ func GetClientCapabilityFlags(flags uint32) map[string]bool {
 		"CLIENT_CAN_HANDLE_EXPIRED_PASSWORDS",
 		"CLIENT_SESSION_TRACK",
 		"CLIENT_DEPRECATED_EOF",
+		"CLIENT_OPTIONAL_RESULTSET_METADATA",
+		"CLIENT_ZSTD_COMPRESSION_ALGORITHM",
+		"CLIENT_QUERY_ATTRIBUTES",
+		"MULTI_FACTOR_AUTHENTICATION",
+		"CLIENT_CAPABILITY_EXTENSION",
+		"CLIENT_SSL_VERIFY_SERVER_CERT",
+		"CLIENT_REMEMBER_OPTIONS",
 	}
 	ret, _ := zgrab2.ListFlagsToSet(uint64(flags), consts)
 	return ret
@@ -0,0 +1,397 @@
+package smb
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/zmap/zgrab2/lib/smb/smb/encoder"
+)
+
+// See [MS-SMB2] Sect. 2.2.3.1
+// These are the types of the negotiate contexts sent with the SMB 3.1.1
+// dialect.
+const (
+	SMB2_PREAUTH_INTEGRITY_CAPABILITIES = 0x0001
+	SMB2_ENCRYPTION_CAPABILITIES        = 0x0002
+	SMB2_COMPRESSION_CAPABILITIES       = 0x0003
+	SMB2_RDMA_TRANSFORM_CAPABILITIES    = 0x0007
+	SMB2_SIGNING_CAPABILITIES           = 0x0008
+)
+
+// See [MS-SMB] Sect. 2.2.4.5.2
+// These are the SecurityMode flags of an SMBv1 negotiate response.
+const (
+	NEGOTIATE_SECURITY_SIGNATURES_ENABLED  = 0x04
+	NEGOTIATE_SECURITY_SIGNATURES_REQUIRED = 0x08
+)
+
+const smb2CompressionCapabilitiesFlagChained = 0x00000001
+
+var hashAlgorithmNames = map[uint16]string{
+	0x0001: "SHA-512",
+}
+
+var cipherNames = map[uint16]string{
+	0x0001: "AES-128-CCM",
+	0x0002: "AES-128-GCM",
+	0x0003: "AES-256-CCM",
+	0x0004: "AES-256-GCM",
+}
+
+var compressionAlgorithmNames = map[uint16]string{
+	0x0000: "NONE",
+	0x0001: "LZNT1",
+	0x0002: "LZ77",
+	0x0003: "LZ77+Huffman",
+	0x0004: "Pattern_V1",
+	0x0005: "LZ4",
+}
+
+var rdmaTransformNames = map[uint16]string{
+	0x0000: "NONE",
+	0x0001: "ENCRYPTION",
+	0x0002: "SIGNING",
+}
+
+var signingAlgorithmNames = map[uint16]string{
+	0x0000: "HMAC-SHA256",
+	0x0001: "AES-CMAC",
+	0x0002: "AES-GMAC",
+}
+
+// EnumeratedDialects are the SMB2 dialect revisions negotiated one at a time
+// by EnumerateDialects, after SMB 1.0.
+var EnumeratedDialects = []uint16{
+	DialectSmb_2_0_2,
+	DialectSmb_2_1,
+	DialectSmb_3_0,
+	DialectSmb_3_0_2,
+	DialectSmb_3_1_1,
+}
+
+// DialectLog is the server's response to a negotiate request offering a
+// single dialect.
+type DialectLog struct {
+	// Dialect is the dialect that was offered, e.g. "SMB 1.0" or "SMB 3.1.1".
+	Dialect string `json:"dialect"`
+
+	// Supported is true if the server accepted the dialect.
+	Supported bool `json:"supported"`
+
+	// Status is the NT status of the negotiate response, if it is not OK.
+	Status uint32 `json:"status,omitempty"`
+
+	// SigningEnabled and SigningRequired are taken from the server's
+	// SecurityMode for the dialect.
+	SigningEnabled  bool `json:"signing_enabled,omitempty"`
+	SigningRequired bool `json:"signing_required,omitempty"`
+
+	// Capabilities is the server's capabilities bitmask for the dialect; for
+	// SMB 1.0 these are the SMBv1 capabilities.
+	Capabilities uint32 `json:"capabilities,omitempty"`
+
+	// EncryptionSupported is true if the server set SMB2_CAP_ENCRYPTION (SMB
+	// 3.0 and 3.0.2) or selected a cipher (SMB 3.1.1). Whether encryption is
+	// required is only visible after authentication.
+	EncryptionSupported bool `json:"encryption_supported,omitempty"`
+
+	// The following are taken from the negotiate contexts of an SMB 3.1.1
+	// response.
+	PreauthIntegrityHash  string   `json:"preauth_integrity_hash,omitempty"`
+	EncryptionCipher      string   `json:"encryption_cipher,omitempty"`
+	SigningAlgorithm      string   `json:"signing_algorithm,omitempty"`
+	CompressionAlgorithms []string `json:"compression_algorithms,omitempty"`
+	RDMATransforms        []string `json:"rdma_transforms,omitempty"`
+
+	// Error is set if the server did not send a valid negotiate response.
+	Error string `json:"error,omitempty"`
+}
+
+func algorithmName(names map[uint16]string, id uint16) string {
+	if name, ok := names[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("UNKNOWN(0x%04x)", id)
+}
+
+// dialectName returns the version string of an SMB2 dialect revision, in the
+// same format as SMBVersions.VerString.
+func dialectName(dialect uint16) string {
+	major := 0x0f & (dialect >> 8)
+	minor := 0x0f & (dialect >> 4)
+	revision := 0x0f & dialect
+	if revision > 0 {
+		return fmt.Sprintf("SMB %d.%d.%d", major, minor, revision)
+	}
+	return fmt.Sprintf("SMB %d.%d", major, minor)
+}
+
+// EnumerateDialects negotiates SMB 1.0 and each of EnumeratedDialects on its
+// own connection, opened with dial, and logs the server's responses.
+func EnumerateDialects(dial func() (net.Conn, error), debug bool) []DialectLog {
+	ret := make([]DialectLog, 0, len(EnumeratedDialects)+1)
+	negotiate := func(name string, f func(s *Session, log *DialectLog) error) {
+		log := DialectLog{Dialect: name}
+		conn, err := dial()
+		if err != nil {
+			log.Error = err.Error()
+			ret = append(ret, log)
+			return
+		}
+		defer conn.Close()
+		s := &Session{
+			debug: debug,
+			conn:  conn,
+			trees: make(map[string]uint32),
+		}
+		if err := f(s, &log); err != nil {
+			log.Error = err.Error()
+		}
+		ret = append(ret, log)
+	}
+	negotiate("SMB 1.0", (*Session).negotiateDialectV1)
+	for _, dialect := range EnumeratedDialects {
+		negotiate(dialectName(dialect), func(s *Session, log *DialectLog) error {
+			return s.negotiateDialect(dialect, log)
+		})
+	}
+	return ret
+}
+
+// negotiateDialectV1 offers only the NT LM 0.12 dialect of SMBv1.
+func (s *Session) negotiateDialectV1(log *DialectLog) error {
+	buf, err := s.send(s.NewNegotiateReqV1())
+	if err != nil {
+		return err
+	}
+	if string(buf[0:4]) != ProtocolSmb {
+		// e.g. an SMB2 response from a server that only speaks SMB2
+		return nil
+	}
+	return parseNegotiateResponseV1(buf, log)
+}
+
+// parseNegotiateResponseV1 fills log from an SMBv1 negotiate response; see
+// [MS-SMB] Sect. 2.2.4.5.2.1.
+func parseNegotiateResponseV1(buf []byte, log *DialectLog) error {
+	if len(buf) < SmbHeaderV1Length+3 {
+		return errors.New("SMBv1 negotiate response too short")
+	}
+	if status := binary.LittleEndian.Uint32(buf[5:]); status != StatusOk {
+		log.Status = status
+		return nil
+	}
+	body := buf[SmbHeaderV1Length:]
+	wordCount := body[0]
+	if binary.LittleEndian.Uint16(body[1:]) == 0xffff {
+		// None of the offered dialects are supported
+		return nil
+	}
+	// The NT LM 0.12 response has 17 words of parameters
+	if wordCount < 17 || len(body) < 1+2*17 {
+		return errors.New("SMBv1 negotiate response has unexpected parameters")
+	}
+	log.Supported = true
+	securityMode := body[3]
+	log.SigningEnabled = securityMode&NEGOTIATE_SECURITY_SIGNATURES_ENABLED != 0
+	log.SigningRequired = securityMode&NEGOTIATE_SECURITY_SIGNATURES_REQUIRED != 0
+	log.Capabilities = binary.LittleEndian.Uint32(body[20:])
+	return nil
+}
+
+// negotiateContext encodes a single SMB2 NEGOTIATE_CONTEXT.
+func negotiateContext(contextType uint16, data []byte) []byte {
+	ret := make([]byte, 8, 8+len(data))
+	binary.LittleEndian.PutUint16(ret[0:], contextType)
+	binary.LittleEndian.PutUint16(ret[2:], uint16(len(data)))
+	return append(ret, data...)
+}
+
+// u16List encodes a list of algorithm identifiers after a fixed prefix.
+func u16List(prefix []byte, ids ...uint16) []byte {
+	ret := append([]byte{}, prefix...)
+	for _, id := range ids {
+		ret = binary.LittleEndian.AppendUint16(ret, id)
+	}
+	return ret
+}
+
+// newNegotiateContexts returns the contexts offered with SMB 3.1.1: every
+// known cipher, compression algorithm, RDMA transform and signing algorithm,
+// so that the server's response lists what it supports.
+func newNegotiateContexts() ([][]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	preauth := []byte{1, 0, byte(len(salt)), 0}
+	preauth = append(u16List(preauth, 0x0001), salt...)
+	compression := binary.LittleEndian.AppendUint32([]byte{5, 0, 0, 0}, smb2CompressionCapabilitiesFlagChained)
+	return [][]byte{
+		negotiateContext(SMB2_PREAUTH_INTEGRITY_CAPABILITIES, preauth),
+		negotiateContext(SMB2_ENCRYPTION_CAPABILITIES, u16List([]byte{4, 0}, 0x0002, 0x0001, 0x0004, 0x0003)),
+		negotiateContext(SMB2_COMPRESSION_CAPABILITIES, u16List(compression, 0x0001, 0x0002, 0x0003, 0x0004, 0x0005)),
+		negotiateContext(SMB2_RDMA_TRANSFORM_CAPABILITIES, u16List([]byte{2, 0, 0, 0, 0, 0, 0, 0}, 0x0001, 0x0002)),
+		negotiateContext(SMB2_SIGNING_CAPABILITIES, u16List([]byte{3, 0}, 0x0002, 0x0001, 0x0000)),
+	}, nil
+}
+
+// newNegotiateDialectReq encodes an SMB2 negotiate request offering only the
+// given dialect; see [MS-SMB2] Sect. 2.2.3.
+func (s *Session) newNegotiateDialectReq(dialect uint16) ([]byte, error) {
+	header := newHeader()
+	header.Command = CommandNegotiate
+	header.CreditCharge = 1
+	header.MessageID = s.messageID
+	buf, err := encoder.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	var capabilities uint32
+	if dialect >= DialectSmb_3_0 {
+		capabilities = SMB2_CAP_DFS | SMB2_CAP_LEASING | SMB2_CAP_LARGE_MTU | SMB2_CAP_MULTI_CHANNEL |
+			SMB2_CAP_PERSISTENT_HANDLES | SMB2_CAP_DIRECTORY_LEASING | SMB2_CAP_ENCRYPTION
+	}
+	body := make([]byte, 0, 38)
+	body = binary.LittleEndian.AppendUint16(body, 36) // StructureSize
+	body = binary.LittleEndian.AppendUint16(body, 1)  // DialectCount
+	body = binary.LittleEndian.AppendUint16(body, SecurityModeSigningEnabled)
+	body = binary.LittleEndian.AppendUint16(body, 0) // Reserved
+	body = binary.LittleEndian.AppendUint32(body, capabilities)
+	body = append(body, make([]byte, 16)...) // ClientGuid
+	// ClientStartTime, or NegotiateContextOffset / Count for SMB 3.1.1
+	body = append(body, make([]byte, 8)...)
+	body = binary.LittleEndian.AppendUint16(body, dialect)
+	buf = append(buf, body...)
+	if dialect != DialectSmb_3_1_1 {
+		return buf, nil
+	}
+	contexts, err := newNegotiateContexts()
+	if err != nil {
+		return nil, err
+	}
+	for i, context := range contexts {
+		// Each context starts 8-byte aligned from the start of the header
+		for len(buf)%8 != 0 {
+			buf = append(buf, 0)
+		}
+		if i == 0 {
+			binary.LittleEndian.PutUint32(buf[64+28:], uint32(len(buf)))
+			binary.LittleEndian.PutUint16(buf[64+32:], uint16(len(contexts)))
+		}
+		buf = append(buf, context...)
+	}
+	return buf, nil
+}
+
+// negotiateDialect offers only the given SMB2 dialect.
+func (s *Session) negotiateDialect(dialect uint16, log *DialectLog) error {
+	req, err := s.newNegotiateDialectReq(dialect)
+	if err != nil {
+		return err
+	}
+	buf, err := s.sendRaw(req)
+	if err != nil {
+		return err
+	}
+	if string(buf[0:4]) != ProtocolSmb2 {
+		return errors.New("SMBv1 response to SMB2 negotiate request")
+	}
+	return parseNegotiateResponse(buf, dialect, log)
+}
+
+// parseNegotiateResponse fills log from an SMB2 negotiate response to a
+// request offering dialect; see [MS-SMB2] Sect. 2.2.4.
+func parseNegotiateResponse(buf []byte, dialect uint16, log *DialectLog) error {
+	if len(buf) < 64+8 {
+		return errors.New("SMB2 negotiate response too short")
+	}
+	if status := binary.LittleEndian.Uint32(buf[8:]); status != StatusOk {
+		log.Status = status
+		return nil
+	}
+	body := buf[64:]
+	if len(body) < 64 {
+		return errors.New("SMB2 negotiate response too short")
+	}
+	securityMode := binary.LittleEndian.Uint16(body[2:])
+	if binary.LittleEndian.Uint16(body[4:]) != dialect {
+		return nil
+	}
+	log.Supported = true
+	log.SigningEnabled = securityMode&SecurityModeSigningEnabled != 0
+	log.SigningRequired = securityMode&SecurityModeSigningRequired != 0
+	log.Capabilities = binary.LittleEndian.Uint32(body[24:])
+	if dialect == DialectSmb_3_0 || dialect == DialectSmb_3_0_2 {
+		log.EncryptionSupported = log.Capabilities&SMB2_CAP_ENCRYPTION != 0
+	}
+	if dialect != DialectSmb_3_1_1 {
+		return nil
+	}
+	return parseNegotiateContexts(buf, int(binary.LittleEndian.Uint32(body[60:])), int(binary.LittleEndian.Uint16(body[6:])), log)
+}
+
+// readU16List reads count algorithm identifiers from data, starting at offset,
+// and returns their names.
+func readU16List(data []byte, offset int, count int, names map[uint16]string) ([]string, error) {
+	if offset+2*count > len(data) {
+		return nil, errors.New("negotiate context truncated")
+	}
+	ret := make([]string, count)
+	for i := range ret {
+		ret[i] = algorithmName(names, binary.LittleEndian.Uint16(data[offset+2*i:]))
+	}
+	return ret, nil
+}
+
+// parseNegotiateContexts reads the count negotiate contexts at offset from the
+// start of the SMB2 header in buf.
+func parseNegotiateContexts(buf []byte, offset int, count int, log *DialectLog) error {
+	for i := 0; i < count; i++ {
+		offset = (offset + 7) &^ 7
+		if offset+8 > len(buf) {
+			return errors.New("negotiate context truncated")
+		}
+		contextType := binary.LittleEndian.Uint16(buf[offset:])
+		length := int(binary.LittleEndian.Uint16(buf[offset+2:]))
+		offset += 8
+		if offset+length > len(buf) {
+			return errors.New("negotiate context truncated")
+		}
+		data := buf[offset : offset+length]
+		offset += length
+		if len(data) < 2 {
+			continue
+		}
+		n := int(binary.LittleEndian.Uint16(data))
+		var names []string
+		var err error
+		switch contextType {
+		case SMB2_PREAUTH_INTEGRITY_CAPABILITIES:
+			if names, err = readU16List(data, 4, n, hashAlgorithmNames); err == nil && len(names) > 0 {
+				log.PreauthIntegrityHash = names[0]
+			}
+		case SMB2_ENCRYPTION_CAPABILITIES:
+			// A cipher of 0 means the server does not support any offered cipher
+			if len(data) >= 4 && binary.LittleEndian.Uint16(data[2:]) != 0 {
+				log.EncryptionSupported = true
+				log.EncryptionCipher = algorithmName(cipherNames, binary.LittleEndian.Uint16(data[2:]))
+			}
+		case SMB2_COMPRESSION_CAPABILITIES:
+			log.CompressionAlgorithms, err = readU16List(data, 8, n, compressionAlgorithmNames)
+		case SMB2_RDMA_TRANSFORM_CAPABILITIES:
+			log.RDMATransforms, err = readU16List(data, 8, n, rdmaTransformNames)
+		case SMB2_SIGNING_CAPABILITIES:
+			if names, err = readU16List(data, 2, n, signingAlgorithmNames); err == nil && len(names) > 0 {
+				log.SigningAlgorithm = names[0]
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package smb
+
+import (
+	"encoding/binary"
+	"slices"
+	"testing"
+
+	"github.com/zmap/zgrab2/lib/smb/ntlmssp"
+	"github.com/zmap/zgrab2/lib/smb/smb/encoder"
+)
+
+// negotiateResponse builds an SMB2 negotiate response for dialect with the
+// given negotiate contexts.
+func negotiateResponse(dialect uint16, securityMode uint16, capabilities uint32, contexts ...[]byte) []byte {
+	buf, _ := encoder.Marshal(newHeader())
+	body := make([]byte, 64)
+	binary.LittleEndian.PutUint16(body[0:], 65)
+	binary.LittleEndian.PutUint16(body[2:], securityMode)
+	binary.LittleEndian.PutUint16(body[4:], dialect)
+	binary.LittleEndian.PutUint16(body[6:], uint16(len(contexts)))
+	binary.LittleEndian.PutUint32(body[24:], capabilities)
+	binary.LittleEndian.PutUint32(body[60:], 128)
+	buf = append(buf, body...)
+	for _, context := range contexts {
+		for len(buf)%8 != 0 {
+			buf = append(buf, 0)
+		}
+		buf = append(buf, context...)
+	}
+	return buf
+}
+
+func TestParseNegotiateResponse(t *testing.T) {
+	var log DialectLog
+	buf := negotiateResponse(DialectSmb_3_1_1, SecurityModeSigningEnabled|SecurityModeSigningRequired, SMB2_CAP_DFS,
+		negotiateContext(SMB2_PREAUTH_INTEGRITY_CAPABILITIES, u16List([]byte{1, 0, 0, 0}, 0x0001)),
+		negotiateContext(SMB2_ENCRYPTION_CAPABILITIES, u16List([]byte{1, 0}, 0x0004)),
+		negotiateContext(SMB2_COMPRESSION_CAPABILITIES, u16List([]byte{2, 0, 0, 0, 0, 0, 0, 0}, 0x0001, 0x0003)),
+		negotiateContext(SMB2_SIGNING_CAPABILITIES, u16List([]byte{1, 0}, 0x0002)),
+	)
+	if err := parseNegotiateResponse(buf, DialectSmb_3_1_1, &log); err != nil {
+		t.Fatalf("parseNegotiateResponse: %v", err)
+	}
+	if !log.Supported || !log.SigningRequired || log.Capabilities != SMB2_CAP_DFS {
+		t.Errorf("log = %+v", log)
+	}
+	if log.PreauthIntegrityHash != "SHA-512" || !log.EncryptionSupported || log.EncryptionCipher != "AES-256-GCM" || log.SigningAlgorithm != "AES-GMAC" {
+		t.Errorf("contexts = %+v", log)
+	}
+	if !slices.Equal(log.CompressionAlgorithms, []string{"LZNT1", "LZ77+Huffman"}) || log.RDMATransforms != nil {
+		t.Errorf("compression = %v, RDMA = %v", log.CompressionAlgorithms, log.RDMATransforms)
+	}
+
+	// A server that does not support the dialect picks another one or fails
+	log = DialectLog{}
+	if err := parseNegotiateResponse(negotiateResponse(DialectSmb_2_1, 0, 0), DialectSmb_3_0, &log); err != nil || log.Supported {
+		t.Errorf("mismatched dialect: %+v, %v", log, err)
+	}
+	buf = negotiateResponse(0, 0, 0)
+	binary.LittleEndian.PutUint32(buf[8:], StatusInvalidParameter)
+	log = DialectLog{}
+	if err := parseNegotiateResponse(buf, DialectSmb_3_1_1, &log); err != nil || log.Supported || log.Status != StatusInvalidParameter {
+		t.Errorf("error status: %+v, %v", log, err)
+	}
+}
+
+func TestNewNegotiateDialectReq(t *testing.T) {
+	var s Session
+	req, err := s.newNegotiateDialectReq(DialectSmb_3_1_1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset := binary.LittleEndian.Uint32(req[64+28:])
+	count := binary.LittleEndian.Uint16(req[64+32:])
+	if offset%8 != 0 || count != 5 || binary.LittleEndian.Uint16(req[64+36:]) != DialectSmb_3_1_1 {
+		t.Fatalf("offset = %d, count = %d", offset, count)
+	}
+	// The request's contexts parse with the response parser
+	var log DialectLog
+	if err := parseNegotiateContexts(req, int(offset), int(count), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.EncryptionCipher != "AES-128-GCM" || len(log.CompressionAlgorithms) != 5 || len(log.RDMATransforms) != 2 {
+		t.Errorf("contexts = %+v", log)
+	}
+
+	req, _ = s.newNegotiateDialectReq(DialectSmb_2_0_2)
+	if len(req) != 64+38 || binary.LittleEndian.Uint32(req[64+8:]) != 0 {
+		t.Errorf("SMB 2.0.2 request = %x", req)
+	}
+}
+
+func TestParseNegotiateResponseV1(t *testing.T) {
+	buf := make([]byte, SmbHeaderV1Length+1+2*17+2)
+	copy(buf, ProtocolSmb)
+	body := buf[SmbHeaderV1Length:]
+	body[0] = 17
+	body[3] = NEGOTIATE_SECURITY_SIGNATURES_ENABLED
+	binary.LittleEndian.PutUint32(body[20:], 0xf3f9)
+	var log DialectLog
+	if err := parseNegotiateResponseV1(buf, &log); err != nil {
+		t.Fatal(err)
+	}
+	if !log.Supported || !log.SigningEnabled || log.SigningRequired || log.Capabilities != 0xf3f9 {
+		t.Errorf("log = %+v", log)
+	}
+}
+
+func TestGetNTLMInfo(t *testing.T) {
+	utf16 := func(s string) []byte {
+		var ret []byte
+		for _, c := range s {
+			ret = binary.LittleEndian.AppendUint16(ret, uint16(c))
+		}
+		return ret
+	}
+	challenge := ntlmssp.Challenge{
+		NegotiateFlags: ntlmssp.FlgNegVersion,
+		// 10.0.20348, NTLMSSP revision 15
+		Version: 0x0f000000_4f7c_00_0a,
+		TargetInfo: &ntlmssp.AvPairSlice{
+			{AvID: ntlmssp.MsvAvNbComputerName, Value: utf16("DC01")},
+			{AvID: ntlmssp.MsvAvDnsDomainName, Value: utf16("corp.example")},
+			{AvID: ntlmssp.MsvAvTimestamp, Value: binary.LittleEndian.AppendUint64(nil, (11644473600+1700000000)*1e7)},
+		},
+	}
+	info := getNTLMInfo(&challenge)
+	expected := NTLMInfo{
+		NetBIOSComputerName: "DC01",
+		DNSDomainName:       "corp.example",
+		Timestamp:           1700000000,
+		OSVersion:           "10.0.20348",
+		OSBuild:             20348,
+		NTLMRevision:        15,
+	}
+	if info == nil || *info != expected {
+		t.Errorf("NTLM info = %+v", info)
+	}
+	if getNTLMInfo(&ntlmssp.Challenge{}) != nil {
+		t.Error("expected no NTLM info for an empty challenge")
+	}
+}
@@ -344,7 +344,12 @@ func (s *Session) send(req any) (res []byte, err error) {
 		s.Debug("", err)
 		return nil, err
 	}
+	return s.sendRaw(buf)
+}
 
+// sendRaw sends an already-encoded SMB message in a NetBIOS session message
+// and reads the response.
+func (s *Session) sendRaw(buf []byte) (res []byte, err error) {
 	b := new(bytes.Buffer)
 	if err = binary.Write(b, binary.BigEndian, uint32(len(buf))); err != nil {
 		s.Debug("", err)
@@ -2,6 +2,7 @@ package smb
 
 import (
 	"encoding/asn1"
+	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -80,6 +81,33 @@ type SessionSetupLog struct {
 
 	// NegotiateFlags are the flags from the challenge packet
 	NegotiateFlags uint32 `json:"negotiate_flags"`
+
+	// NTLMInfo, if present, holds the server's names and version from the
+	// challenge packet.
+	NTLMInfo *NTLMInfo `json:"ntlm_info,omitempty"`
+}
+
+// NTLMInfo contains the fields of the NTLMSSP challenge that identify the
+// server: the AV_PAIRs of its TargetInfo and its VERSION. See [MS-NLMP] Sect.
+// 2.2.1.2 and 2.2.2.
+type NTLMInfo struct {
+	NetBIOSComputerName string `json:"netbios_computer_name,omitempty"`
+	NetBIOSDomainName   string `json:"netbios_domain_name,omitempty"`
+	DNSComputerName     string `json:"dns_computer_name,omitempty"`
+	DNSDomainName       string `json:"dns_domain_name,omitempty"`
+	DNSTreeName         string `json:"dns_tree_name,omitempty"`
+
+	// Timestamp is the server's time (in seconds since the Unix epoch).
+	Timestamp uint32 `json:"timestamp,omitempty"`
+
+	// OSVersion is the product major.minor.build version, e.g. 10.0.20348.
+	OSVersion string `json:"os_version,omitempty"`
+
+	// OSBuild is the build number of OSVersion.
+	OSBuild uint16 `json:"os_build,omitempty"`
+
+	// NTLMRevision is the NTLMSSP revision from the VERSION structure.
+	NTLMRevision uint8 `json:"ntlm_revision,omitempty"`
 }
 
 // Parse the SMB version and dialect; version string
@@ -151,6 +179,14 @@ type SMBLog struct {
 	// HasNTLM is true if the server supports the NTLM authentication method.
 	HasNTLM bool `json:"has_ntlm"`
 
+	// SigningRequired is true if the server's negotiation response requires
+	// message signing.
+	SigningRequired bool `json:"signing_required,omitempty"`
+
+	// Dialects, if present, lists the server's responses to negotiating each
+	// dialect on its own (see EnumerateDialects).
+	Dialects []DialectLog `json:"dialects,omitempty"`
+
 	// NegotiationLog, if present, contains the server's response to the
 	// negotiation request.
 	NegotiationLog *NegotiationLog `json:"negotiation_log,omitempty"`
@@ -442,6 +478,7 @@ func (ls *LoggedSession) LoggedNegotiateProtocol(setup bool) error {
 	if negRes.Header.Status != StatusOk {
 		return errors.New(fmt.Sprintf("NT Status Error: %d\n", negRes.Header.Status))
 	}
+	logStruct.SigningRequired = negRes.SecurityMode&SecurityModeSigningRequired != 0
 
 	// Check SPNEGO security blob
 	spnegoOID, err := gss.ObjectIDStrToInt(gss.SpnegoOid)
@@ -535,6 +572,49 @@ func (ls *LoggedSession) LoggedNegotiateProtocol(setup bool) error {
 	}
 	logStruct.SessionSetupLog.TargetName = wstring(challenge.TargetName)
 	logStruct.SessionSetupLog.NegotiateFlags = challenge.NegotiateFlags
+	logStruct.SessionSetupLog.NTLMInfo = getNTLMInfo(&challenge)
 
 	return nil
 }
+
+// getNTLMInfo extracts the server's names, time and version from an NTLMSSP
+// challenge.
+func getNTLMInfo(challenge *ntlmssp.Challenge) *NTLMInfo {
+	info := new(NTLMInfo)
+	if challenge.TargetInfo != nil {
+		for _, pair := range *challenge.TargetInfo {
+			switch pair.AvID {
+			case ntlmssp.MsvAvNbComputerName:
+				info.NetBIOSComputerName = wstring(pair.Value)
+			case ntlmssp.MsvAvNbDomainName:
+				info.NetBIOSDomainName = wstring(pair.Value)
+			case ntlmssp.MsvAvDnsComputerName:
+				info.DNSComputerName = wstring(pair.Value)
+			case ntlmssp.MsvAvDnsDomainName:
+				info.DNSDomainName = wstring(pair.Value)
+			case ntlmssp.MsvAvDnsTreeName:
+				info.DNSTreeName = wstring(pair.Value)
+			case ntlmssp.MsvAvTimestamp:
+				// Skip timestamps before the Unix epoch, which getTime can't represent
+				if len(pair.Value) == 8 {
+					if filetime := binary.LittleEndian.Uint64(pair.Value); filetime/1e7 >= 11644473600 {
+						info.Timestamp = getTime(filetime)
+					}
+				}
+			}
+		}
+	}
+	if challenge.NegotiateFlags&ntlmssp.FlgNegVersion != 0 && challenge.Version != 0 {
+		// ProductMajorVersion, ProductMinorVersion, ProductBuild (little-endian),
+		// three reserved bytes, NTLMRevisionCurrent
+		major := uint8(challenge.Version)
+		minor := uint8(challenge.Version >> 8)
+		info.OSBuild = uint16(challenge.Version >> 16)
+		info.OSVersion = fmt.Sprintf("%d.%d.%d", major, minor, info.OSBuild)
+		info.NTLMRevision = uint8(challenge.Version >> 56)
+	}
+	if *info == (NTLMInfo{}) {
+		return nil
+	}
+	return info
+}
@@ -0,0 +1,99 @@
+package zgrab2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+)
+
+// Liveness check methods for --liveness-check
+const (
+	LivenessCheckSYN = "syn"
+	LivenessCheckUDP = "udp"
+)
+
+// errNotLive is returned by a livenessChecker when the target didn't respond in a way that shows it's worth scanning.
+var errNotLive = errors.New("target did not respond to liveness check")
+
+// livenessChecker performs a fast check, before any module runs, of whether a target is worth scanning.
+type livenessChecker interface {
+	// Check returns nil if the host at ip responded on port, or an error describing why it isn't considered live.
+	Check(ctx context.Context, ip net.IP, zone string, port uint) error
+}
+
+// liveness is the checker selected with --liveness-check, or nil if targets aren't pre-filtered
+var liveness livenessChecker
+
+// newLivenessChecker returns the checker for the given --liveness-check method.
+func newLivenessChecker(method string) (livenessChecker, error) {
+	switch method {
+	case LivenessCheckSYN:
+		return newSYNChecker()
+	case LivenessCheckUDP:
+		return new(udpChecker), nil
+	default:
+		return nil, fmt.Errorf("unknown liveness check %s, must be one of %s, %s", method, LivenessCheckSYN, LivenessCheckUDP)
+	}
+}
+
+// livenessPorts returns the ports the liveness check for a target should use: the target's own port if it has one,
+// otherwise the distinct ports of the modules that will scan it. It returns nil if any of them has no port, in which
+// case the target isn't checked.
+func livenessPorts(target *ScanTarget) []uint {
+	if target.Port != 0 {
+		return []uint{target.Port}
+	}
+	var ports []uint
+	for _, scannerName := range orderedScanners {
+		if (*scanners[scannerName]).GetTrigger() != target.Tag {
+			continue
+		}
+		port := defaultDialerGroupConfigToScanners[scannerName].BaseFlags.Port
+		if port == 0 {
+			return nil
+		}
+		if !slices.Contains(ports, port) {
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
+
+// checkLiveness runs the liveness check on each of the target's ports. The target is live if any of them is.
+func checkLiveness(ctx context.Context, target *ScanTarget) error {
+	var errs []error
+	for _, port := range livenessPorts(target) {
+		err := liveness.Check(ctx, target.IP, target.Zone, port)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("port %d: %w", port, err))
+	}
+	return errors.Join(errs...)
+}
+
+// udpChecker considers a host dead only if an empty UDP datagram is answered with an ICMP host or network
+// unreachable. Most UDP services ignore an empty datagram, and filtered ports drop it, so silence is inconclusive.
+type udpChecker struct{}
+
+func (c *udpChecker) Check(ctx context.Context, ip net.IP, zone string, port uint) error {
+	dialer := NewDialer(nil)
+	if err := dialer.SetRandomLocalAddr("udp", config.localAddrs, config.localPorts); err != nil {
+		return fmt.Errorf("could not set random local address: %w", err)
+	}
+	conn, err := dialer.DialContext(ctx, "udp", net.JoinHostPort(joinIPZone(ip, zone), strconv.Itoa(int(port))))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	probe := NewStaticUDPProbe("liveness", []byte{}, nil)
+	flags := &UDPFlags{Retries: config.LivenessRetries, TryTimeout: config.LivenessTimeout}
+	result, err := SendUDPProbe(ctx, conn, probe, &ScanTarget{IP: ip, Zone: zone, Port: port}, flags)
+	if err != nil && (result.ICMP == ICMPHostUnreachable || result.ICMP == ICMPNetUnreachable) {
+		return fmt.Errorf("%w: ICMP %s", errNotLive, result.ICMP)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package zgrab2
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TCP header flags
+const (
+	tcpFlagSYN = 0x02
+	tcpFlagRST = 0x04
+	tcpFlagACK = 0x10
+)
+
+type synReply struct {
+	rst bool
+}
+
+// synChecker sends a TCP SYN on a raw socket and waits for a SYN-ACK. Like ZMap, it keeps no per-probe state in the
+// packet path: the initial sequence number is a keyed hash of the destination, so replies are validated by their
+// acknowledgement number alone. The kernel, which doesn't know about the half-open connection, resets it.
+type synChecker struct {
+	conn4, conn6 *net.IPConn
+	secret       []byte
+	srcPort      uint16
+
+	mu      sync.Mutex
+	waiters map[string]chan synReply // keyed by synWaiterKey, only so replies can be delivered to the waiting goroutine
+}
+
+func newSYNChecker() (livenessChecker, error) {
+	c := &synChecker{
+		secret:  make([]byte, 32),
+		waiters: make(map[string]chan synReply),
+	}
+	if _, err := rand.Read(c.secret); err != nil {
+		return nil, fmt.Errorf("could not generate SYN cookie secret: %w", err)
+	}
+	port, err := rand.Int(rand.Reader, big.NewInt(65535-32768))
+	if err != nil {
+		return nil, fmt.Errorf("could not choose source port: %w", err)
+	}
+	c.srcPort = uint16(32768 + port.Int64())
+	if config.resolveIPv4 {
+		if c.conn4, err = net.ListenIP("ip4:tcp", nil); err != nil {
+			return nil, fmt.Errorf("could not open raw IPv4 socket for SYN liveness check (needs root or CAP_NET_RAW): %w", err)
+		}
+		go c.receive(c.conn4)
+	}
+	if config.resolveIPv6 {
+		if c.conn6, err = net.ListenIP("ip6:tcp", nil); err != nil {
+			return nil, fmt.Errorf("could not open raw IPv6 socket for SYN liveness check (needs root or CAP_NET_RAW): %w", err)
+		}
+		go c.receive(c.conn6)
+	}
+	return c, nil
+}
+
+func synWaiterKey(ip net.IP, port uint16) string {
+	return string(ip.To16()) + string([]byte{byte(port >> 8), byte(port)})
+}
+
+// cookie is the initial sequence number for a SYN to ip:port
+func (c *synChecker) cookie(ip net.IP, port uint16) uint32 {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(synWaiterKey(ip, port)))
+	return binary.BigEndian.Uint32(mac.Sum(nil))
+}
+
+// receive reads every TCP segment delivered to the raw socket and hands valid replies to their waiters.
+func (c *synChecker) receive(conn *net.IPConn) {
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := conn.ReadFromIP(buf)
+		if err != nil {
+			log.Errorf("SYN liveness check receiver stopped: %v", err)
+			return
+		}
+		if n < 20 {
+			continue
+		}
+		segment := buf[:n]
+		srcPort := binary.BigEndian.Uint16(segment[0:2])
+		dstPort := binary.BigEndian.Uint16(segment[2:4])
+		ack := binary.BigEndian.Uint32(segment[8:12])
+		flags := segment[13]
+		if dstPort != c.srcPort || flags&tcpFlagACK == 0 || ack != c.cookie(addr.IP, srcPort)+1 {
+			continue
+		}
+		var reply synReply
+		if flags&tcpFlagRST != 0 {
+			reply.rst = true
+		} else if flags&tcpFlagSYN == 0 {
+			continue
+		}
+		c.mu.Lock()
+		ch, ok := c.waiters[synWaiterKey(addr.IP, srcPort)]
+		c.mu.Unlock()
+		if ok {
+			select {
+			case ch <- reply:
+			default:
+			}
+		}
+	}
+}
+
+func (c *synChecker) Check(ctx context.Context, ip net.IP, zone string, port uint) error {
+	conn := c.conn4
+	if ip.To4() == nil {
+		conn = c.conn6
+	} else {
+		ip = ip.To4()
+	}
+	if conn == nil {
+		return fmt.Errorf("no raw socket for the address family of %s", ip)
+	}
+	// the raw socket bypasses Dialer, so apply its blocklist here
+	if blocklist != nil {
+		if contains, _ := blocklist.Contains(ip); contains {
+			return &ScanError{Status: SCAN_BLOCKLISTED_TARGET, Err: fmt.Errorf("liveness check of blocked IP: %s", ip)}
+		}
+	}
+	src, err := routeSource(ip, zone)
+	if err != nil {
+		return err
+	}
+	dstPort := uint16(port)
+	segment := buildSYN(src, ip, c.srcPort, dstPort, c.cookie(ip, dstPort))
+
+	key := synWaiterKey(ip, dstPort)
+	ch := make(chan synReply, 1)
+	c.mu.Lock()
+	c.waiters[key] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.waiters, key)
+		c.mu.Unlock()
+	}()
+
+	for attempt := 0; attempt <= config.LivenessRetries; attempt++ {
+		if _, err = conn.WriteToIP(segment, &net.IPAddr{IP: ip, Zone: zone}); err != nil {
+			return fmt.Errorf("could not send SYN: %w", err)
+		}
+		timer := time.NewTimer(config.LivenessTimeout)
+		select {
+		case reply := <-ch:
+			timer.Stop()
+			if reply.rst {
+				return fmt.Errorf("%w: port %d closed (RST)", errNotLive, port)
+			}
+			return nil
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return fmt.Errorf("%w: no SYN-ACK", errNotLive)
+}
+
+// routeSource returns the local address the kernel would use to reach dst, which the TCP checksum must cover.
+func routeSource(dst net.IP, zone string) (net.IP, error) {
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: dst, Zone: zone, Port: 9})
+	if err != nil {
+		return nil, fmt.Errorf("no route to %s: %w", dst, err)
+	}
+	defer conn.Close()
+	src := conn.LocalAddr().(*net.UDPAddr).IP
+	if v4 := src.To4(); v4 != nil && dst.To4() != nil {
+		return v4, nil
+	}
+	return src, nil
+}
+
+// buildSYN returns a 20-byte TCP SYN segment from src:srcPort to dst:dstPort with the given sequence number.
+func buildSYN(src, dst net.IP, srcPort, dstPort uint16, seq uint32) []byte {
+	segment := make([]byte, 20)
+	binary.BigEndian.PutUint16(segment[0:2], srcPort)
+	binary.BigEndian.PutUint16(segment[2:4], dstPort)
+	binary.BigEndian.PutUint32(segment[4:8], seq)
+	segment[12] = 5 << 4 // data offset, in 32-bit words
+	segment[13] = tcpFlagSYN
+	binary.BigEndian.PutUint16(segment[14:16], 65535) // window
+	binary.BigEndian.PutUint16(segment[16:18], tcpChecksum(src, dst, segment))
+	return segment
+}
+
+// tcpChecksum computes the TCP checksum of segment, including the IPv4 or IPv6 pseudo-header.
+func tcpChecksum(src, dst net.IP, segment []byte) uint16 {
+	var pseudo []byte
+	if src.To4() != nil && dst.To4() != nil {
+		pseudo = append(pseudo, src.To4()...)
+		pseudo = append(pseudo, dst.To4()...)
+		pseudo = append(pseudo, 0, 6, byte(len(segment)>>8), byte(len(segment)))
+	} else {
+		pseudo = append(pseudo, src.To16()...)
+		pseudo = append(pseudo, dst.To16()...)
+		pseudo = binary.BigEndian.AppendUint32(pseudo, uint32(len(segment)))
+		pseudo = append(pseudo, 0, 0, 0, 6)
+	}
+	var sum uint32
+	for _, b := range [][]byte{pseudo, segment} {
+		for i := 0; i+1 < len(b); i += 2 {
+			sum += uint32(binary.BigEndian.Uint16(b[i : i+2]))
+		}
+		if len(b)%2 == 1 {
+			sum += uint32(b[len(b)-1]) << 8
+		}
+	}
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
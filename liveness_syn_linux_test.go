@@ -0,0 +1,44 @@
+package zgrab2
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+func TestBuildSYN(t *testing.T) {
+	tests := []struct {
+		src, dst string
+	}{
+		{"192.0.2.1", "198.51.100.7"},
+		{"2001:db8::1", "2001:db8::2"},
+	}
+	for _, test := range tests {
+		src, dst := net.ParseIP(test.src), net.ParseIP(test.dst)
+		if src.To4() != nil {
+			src, dst = src.To4(), dst.To4()
+		}
+		segment := buildSYN(src, dst, 40000, 443, 0xdeadbeef)
+		if len(segment) != 20 || segment[13] != tcpFlagSYN {
+			t.Fatalf("malformed SYN %x", segment)
+		}
+		if binary.BigEndian.Uint16(segment[0:2]) != 40000 || binary.BigEndian.Uint16(segment[2:4]) != 443 || binary.BigEndian.Uint32(segment[4:8]) != 0xdeadbeef {
+			t.Errorf("wrong ports or sequence number in %x", segment)
+		}
+		// a segment with a correct checksum sums (with the pseudo-header) to 0xffff, so recomputing gives 0
+		if sum := tcpChecksum(src, dst, segment); sum != 0 {
+			t.Errorf("checksum of %s -> %s doesn't verify (got %#04x)", test.src, test.dst, sum)
+		}
+	}
+}
+
+func TestSYNCookie(t *testing.T) {
+	c := &synChecker{secret: []byte("secret")}
+	ip := net.ParseIP("192.0.2.1").To4()
+	if c.cookie(ip, 80) != c.cookie(net.ParseIP("192.0.2.1"), 80) {
+		t.Errorf("cookie depends on the IP representation")
+	}
+	if c.cookie(ip, 80) == c.cookie(ip, 443) {
+		t.Errorf("cookie doesn't depend on the port")
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package zgrab2
+
+import "errors"
+
+func newSYNChecker() (livenessChecker, error) {
+	return nil, errors.New("the SYN liveness check is only supported on Linux")
+}
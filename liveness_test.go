@@ -0,0 +1,32 @@
+package zgrab2
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestUDPChecker(t *testing.T) {
+	oldTimeout, oldRetries := config.LivenessTimeout, config.LivenessRetries
+	defer func() { config.LivenessTimeout, config.LivenessRetries = oldTimeout, oldRetries }()
+	config.LivenessTimeout, config.LivenessRetries = 100*time.Millisecond, 0
+
+	// a service that ignores the empty datagram
+	silent, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+	port := uint(silent.LocalAddr().(*net.UDPAddr).Port)
+	checker := new(udpChecker)
+	if err := checker.Check(context.Background(), net.IPv4(127, 0, 0, 1), "", port); err != nil {
+		t.Errorf("silent port reported dead: %v", err)
+	}
+
+	// a closed port answers with an ICMP port unreachable
+	silent.Close()
+	if err := checker.Check(context.Background(), net.IPv4(127, 0, 0, 1), "", port); err != nil {
+		t.Errorf("closed port reported dead: %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package zgrab2
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// Keys that results can be merged by with the merge command
+const (
+	MergeKeyIP       = "ip"
+	MergeKeyDomain   = "domain"
+	MergeKeyIPDomain = "ip-domain"
+)
+
+// MergeCommand contains the command line options for merging the results of several scans (e.g. separate runs of
+// different modules, or one run with several input lines per host) into a single record per target.
+type MergeCommand struct {
+	Key string `long:"key" default:"ip" choice:"ip" choice:"domain" choice:"ip-domain" description:"What identifies a target: its IP (falling back to domain for records without one), its domain (falling back to IP), or both"`
+}
+
+// Validate the options sent to MergeCommand
+func (x *MergeCommand) Validate(_ []string) error {
+	switch x.Key {
+	case MergeKeyIP, MergeKeyDomain, MergeKeyIPDomain:
+		return nil
+	default:
+		return fmt.Errorf("invalid merge key %s", x.Key)
+	}
+}
+
+// Help returns a usage string that will be output at the command line
+func (x *MergeCommand) Help() string {
+	return "Reads zgrab2 results from --input-file and writes one record per target to --output-file, " +
+		"combining the data of every module that scanned it. If two records have data for the same module, the " +
+		"later one is stored under <module>@<port>, or <module>#<n> if that is also taken."
+}
+
+// Run merges the results from the input file into the output file.
+func (x *MergeCommand) Run() error {
+	in := os.Stdin
+	if config.InputFileName != "-" {
+		f, err := os.Open(config.InputFileName)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	out := os.Stdout
+	if config.OutputFileName != "-" {
+		f, err := os.Create(config.OutputFileName)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	w := bufio.NewWriter(out)
+	if err := MergeResults(in, w, x.Key); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// mergedGrab is a Grab whose module results are kept as raw JSON, so merging doesn't depend on the modules' types
+type mergedGrab struct {
+	IP     string                     `json:"ip,omitempty"`
+	Port   uint                       `json:"port,omitempty"`
+	Domain string                     `json:"domain,omitempty"`
+	Data   map[string]json.RawMessage `json:"data,omitempty"`
+	Errors []string                   `json:"errors,omitempty"`
+	Runs   []json.RawMessage          `json:"runs,omitempty"`
+}
+
+type grabRecord struct {
+	IP     string                     `json:"ip"`
+	Port   uint                       `json:"port"`
+	Domain string                     `json:"domain"`
+	Data   map[string]json.RawMessage `json:"data"`
+	Error  string                     `json:"error"`
+	Run    json.RawMessage            `json:"run"`
+}
+
+// mergeKey identifies the target of a record
+func mergeKey(record *grabRecord, key string) string {
+	switch key {
+	case MergeKeyDomain:
+		if record.Domain != "" {
+			return "d:" + record.Domain
+		}
+		return "i:" + record.IP
+	case MergeKeyIPDomain:
+		return "b:" + record.IP + "|" + record.Domain
+	default:
+		if record.IP != "" {
+			return "i:" + record.IP
+		}
+		return "d:" + record.Domain
+	}
+}
+
+// MergeResults reads newline-delimited zgrab2 results from source and writes one combined record per target to
+// dest, in the order targets first appear. Errors affecting whole records and run metadata blocks are collected in
+// the errors and runs lists. The port is kept only if every merged record agrees on it.
+func MergeResults(source io.Reader, dest io.Writer, key string) error {
+	var order []string
+	merged := make(map[string]*mergedGrab)
+	ports := make(map[string]bool) // keys whose records disagree on the port
+	decoder := json.NewDecoder(source)
+	for line := 1; ; line++ {
+		var record grabRecord
+		if err := decoder.Decode(&record); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("could not decode record %d: %w", line, err)
+		}
+		k := mergeKey(&record, key)
+		m, ok := merged[k]
+		if !ok {
+			m = &mergedGrab{IP: record.IP, Domain: record.Domain, Port: record.Port, Data: make(map[string]json.RawMessage)}
+			merged[k] = m
+			order = append(order, k)
+		}
+		if m.IP == "" {
+			m.IP = record.IP
+		}
+		if m.Domain == "" {
+			m.Domain = record.Domain
+		}
+		if m.Port != record.Port {
+			ports[k] = true
+		}
+		if record.Error != "" {
+			m.Errors = append(m.Errors, record.Error)
+		}
+		if len(record.Run) > 0 && string(record.Run) != "null" {
+			m.Runs = append(m.Runs, record.Run)
+		}
+		for name, data := range record.Data {
+			m.Data[mergedModuleName(m.Data, name, data)] = data
+		}
+	}
+	encoder := json.NewEncoder(dest)
+	for _, k := range order {
+		m := merged[k]
+		if ports[k] {
+			m.Port = 0
+		}
+		if err := encoder.Encode(m); err != nil {
+			return fmt.Errorf("could not encode merged record: %w", err)
+		}
+	}
+	return nil
+}
+
+// mergedModuleName returns the name to store a module's data under, avoiding names already in data.
+func mergedModuleName(data map[string]json.RawMessage, name string, response json.RawMessage) string {
+	if _, taken := data[name]; !taken {
+		return name
+	}
+	var withPort struct {
+		Port uint `json:"port"`
+	}
+	if err := json.Unmarshal(response, &withPort); err == nil && withPort.Port != 0 {
+		candidate := name + "@" + strconv.Itoa(int(withPort.Port))
+		if _, taken := data[candidate]; !taken {
+			return candidate
+		}
+	}
+	for n := 2; ; n++ {
+		candidate := name + "#" + strconv.Itoa(n)
+		if _, taken := data[candidate]; !taken {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package zgrab2
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMergeResults(t *testing.T) {
+	input := `{"ip":"10.0.0.1","port":80,"data":{"http":{"status":"success","protocol":"http","port":80}}}
+{"ip":"10.0.0.2","domain":"example.com","data":{"ssh":{"status":"success","protocol":"ssh","port":22}},"run":{"scan_id":"a"}}
+{"ip":"10.0.0.1","port":443,"data":{"http":{"status":"success","protocol":"http","port":443},"tls":{"status":"success","protocol":"tls","port":443}}}
+{"ip":"10.0.0.1","port":443,"data":{"http":{"status":"io-timeout","protocol":"http","port":443}}}
+{"domain":"example.com","error":"could not resolve"}
+`
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{
+			key: MergeKeyIP,
+			expected: `{"ip":"10.0.0.1","data":{"http":{"status":"success","protocol":"http","port":80},"http#2":{"status":"io-timeout","protocol":"http","port":443},"http@443":{"status":"success","protocol":"http","port":443},"tls":{"status":"success","protocol":"tls","port":443}}}
+{"ip":"10.0.0.2","domain":"example.com","data":{"ssh":{"status":"success","protocol":"ssh","port":22}},"runs":[{"scan_id":"a"}]}
+{"domain":"example.com","errors":["could not resolve"]}
+`,
+		},
+		{
+			key: MergeKeyDomain,
+			expected: `{"ip":"10.0.0.1","data":{"http":{"status":"success","protocol":"http","port":80},"http#2":{"status":"io-timeout","protocol":"http","port":443},"http@443":{"status":"success","protocol":"http","port":443},"tls":{"status":"success","protocol":"tls","port":443}}}
+{"ip":"10.0.0.2","domain":"example.com","data":{"ssh":{"status":"success","protocol":"ssh","port":22}},"errors":["could not resolve"],"runs":[{"scan_id":"a"}]}
+`,
+		},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		if err := MergeResults(strings.NewReader(input), &out, test.key); err != nil {
+			t.Fatalf("merge by %s failed: %v", test.key, err)
+		}
+		if out.String() != test.expected {
+			t.Errorf("wrong merge by %s:\ngot:      %s\nexpected: %s", test.key, out.String(), test.expected)
+		}
+	}
+	if err := MergeResults(strings.NewReader("{not json"), &bytes.Buffer{}, MergeKeyIP); err == nil {
+		t.Errorf("expected an error for malformed input")
+	}
+}
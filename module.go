@@ -98,6 +98,17 @@ func (config *DialerGroupConfig) Validate() error {
 	if config.TLSEnabled && config.TLSFlags == nil {
 		return errors.New("TLS flags must be set if TLSEnabled is true")
 	}
+	if config.BaseFlags.Transports != "" {
+		if _, err := parseTransports(config.BaseFlags.Transports); err != nil {
+			return fmt.Errorf("invalid --transports: %w", err)
+		}
+		if config.NeedSeparateL4Dialer {
+			return errors.New("--transports is not supported by modules that establish their own TLS connections")
+		}
+		if config.TransportAgnosticDialerProtocol == TransportUDP {
+			return errors.New("--transports is not supported by UDP modules")
+		}
+	}
 	return nil
 }
 
@@ -125,6 +136,13 @@ func (config *DialerGroupConfig) GetDefaultDialerGroupFromConfig() (*DialerGroup
 			// module needs both L4 dialer and TLS wrapper
 			dialerGroup.TLSWrapper = GetDefaultTLSWrapper(config.TLSFlags)
 		}
+	} else if config.BaseFlags.Transports != "" {
+		// module only needs a TransportAgnosticDialer, and the user asked to try several transports in turn
+		transports, err := parseTransports(config.BaseFlags.Transports)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --transports: %w", err)
+		}
+		dialerGroup.TransportAgnosticDialer = getFallbackDialer(config.BaseFlags, config.TLSFlags, transports)
 	} else {
 		// module only needs a TransportAgnosticDialer
 		if config.TLSEnabled {
@@ -214,6 +232,9 @@ type ScanResponse struct {
 
 	Port uint `json:"port"`
 
+	// Transport is the transport the module connected with, set only when --transports lists more than one to try.
+	Transport string `json:"transport,omitempty"`
+
 	Result    any     `json:"result,omitempty"`
 	Timestamp string  `json:"timestamp,omitempty"`
 	Error     *string `json:"error,omitempty"`
@@ -251,6 +272,7 @@ type BaseFlags struct {
 	TargetTimeout  time.Duration `short:"t" long:"target-timeout" description:"Set max for how long a scan of a single target (IP, Domain, etc) can take (0 = no timeout)" default:"60s"`
 	Trigger        string        `short:"g" long:"trigger" description:"Invoke only on targets with specified tag"`
 	Verbose        bool          `short:"v" long:"verbose" description:"More verbose logging, include debug fields in the scan results if implemented"`
+	Transports     string        `long:"transports" description:"Comma-separated, ordered list of transports (tcp, tls) to connect with. If the handshake on one fails, the next is tried on the same port, so tcp can only come last. Not supported by modules that handle TLS themselves (e.g. STARTTLS)."`
 }
 
 // GetName returns the name of the respective scanner
@@ -0,0 +1,132 @@
+package adb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Commands, as little-endian ASCII.
+const (
+	commandCNXN = 0x4e584e43
+	commandAUTH = 0x48545541
+	commandSTLS = 0x534c5453
+)
+
+const (
+	// protocolVersion is the original protocol version. adbd only verifies data checksums of peers using it, which
+	// the scan computes anyway, so it is accepted by old and new devices alike.
+	protocolVersion = 0x01000000
+
+	maxPayload    = 256 * 1024
+	headerLength  = 24
+	maxBannerSize = 64 * 1024
+)
+
+var commandNames = map[uint32]string{
+	commandCNXN: "CNXN",
+	commandAUTH: "AUTH",
+	commandSTLS: "STLS",
+}
+
+var errInvalidMessage = errors.New("invalid ADB message")
+
+// message is a decoded ADB message.
+type message struct {
+	command uint32
+	arg0    uint32
+	arg1    uint32
+	data    []byte
+}
+
+// checksum returns the data checksum of the original protocol: the sum of the bytes.
+func checksum(data []byte) uint32 {
+	var sum uint32
+	for _, b := range data {
+		sum += uint32(b)
+	}
+	return sum
+}
+
+// encode returns the wire format of the message.
+func (msg *message) encode() []byte {
+	b := binary.LittleEndian.AppendUint32(nil, msg.command)
+	b = binary.LittleEndian.AppendUint32(b, msg.arg0)
+	b = binary.LittleEndian.AppendUint32(b, msg.arg1)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(msg.data)))
+	b = binary.LittleEndian.AppendUint32(b, checksum(msg.data))
+	b = binary.LittleEndian.AppendUint32(b, msg.command^0xFFFFFFFF)
+	return append(b, msg.data...)
+}
+
+// buildConnect returns the CNXN message a host sends to open the connection.
+func buildConnect() []byte {
+	msg := &message{command: commandCNXN, arg0: protocolVersion, arg1: maxPayload, data: []byte("host::\x00")}
+	return msg.encode()
+}
+
+// readMessage reads one message.
+func readMessage(r io.Reader) (*message, error) {
+	header := make([]byte, headerLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	msg := &message{
+		command: binary.LittleEndian.Uint32(header[0:4]),
+		arg0:    binary.LittleEndian.Uint32(header[4:8]),
+		arg1:    binary.LittleEndian.Uint32(header[8:12]),
+	}
+	if binary.LittleEndian.Uint32(header[20:24]) != msg.command^0xFFFFFFFF {
+		return nil, errInvalidMessage
+	}
+	length := binary.LittleEndian.Uint32(header[12:16])
+	if length > maxBannerSize {
+		return nil, fmt.Errorf("message length %d exceeds maximum", length)
+	}
+	msg.data = make([]byte, length)
+	if _, err := io.ReadFull(r, msg.data); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// Banner is the connection banner of a device: "<system type>:<serial>:<properties>".
+type Banner struct {
+	SystemType string   `json:"system_type"`
+	Serial     string   `json:"serial,omitempty"`
+	Product    string   `json:"product,omitempty"`
+	Model      string   `json:"model,omitempty"`
+	Device     string   `json:"device,omitempty"`
+	Features   []string `json:"features,omitempty"`
+}
+
+// parseBanner decodes a CNXN banner.
+func parseBanner(data []byte) *Banner {
+	parts := strings.SplitN(strings.TrimRight(string(data), "\x00"), ":", 3)
+	banner := &Banner{SystemType: parts[0]}
+	if len(parts) > 1 {
+		banner.Serial = parts[1]
+	}
+	if len(parts) < 3 {
+		return banner
+	}
+	for _, property := range strings.Split(parts[2], ";") {
+		key, value, ok := strings.Cut(property, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "ro.product.name":
+			banner.Product = value
+		case "ro.product.model":
+			banner.Model = value
+		case "ro.product.device":
+			banner.Device = value
+		case "features":
+			banner.Features = strings.Split(value, ",")
+		}
+	}
+	return banner
+}
@@ -0,0 +1,44 @@
+package adb
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestReadMessage(t *testing.T) {
+	request, err := readMessage(bytes.NewReader(buildConnect()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request.command != commandCNXN || request.arg0 != protocolVersion || string(request.data) != "host::\x00" {
+		t.Errorf("unexpected CNXN %+v", request)
+	}
+
+	reply := &message{
+		command: commandCNXN,
+		arg0:    0x01000001,
+		arg1:    maxPayload,
+		data:    []byte("device::ro.product.name=sdk_phone;ro.product.model=Pixel;ro.product.device=generic;features=shell_v2,cmd,stat_v2"),
+	}
+	msg, err := readMessage(bytes.NewReader(reply.encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Banner{
+		SystemType: "device",
+		Product:    "sdk_phone",
+		Model:      "Pixel",
+		Device:     "generic",
+		Features:   []string{"shell_v2", "cmd", "stat_v2"},
+	}
+	if banner := parseBanner(msg.data); !reflect.DeepEqual(banner, expected) {
+		t.Errorf("got banner %+v", banner)
+	}
+
+	corrupt := reply.encode()
+	corrupt[20] ^= 0xFF
+	if _, err = readMessage(bytes.NewReader(corrupt)); err == nil {
+		t.Error("expected error for a bad magic")
+	}
+}
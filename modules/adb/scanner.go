@@ -0,0 +1,161 @@
+// Package adb contains the zgrab2 Module implementation for the Android Debug Bridge.
+//
+// The scan sends the CNXN message a host opens a connection with. A device that doesn't require authentication
+// answers with its own CNXN carrying a banner with the product, model and supported features, and accepts shell
+// commands from anyone. Devices that require authentication answer with an AUTH token, and wireless debugging
+// devices with STLS.
+package adb
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// Command is the command of the device's answer: CNXN, AUTH or STLS.
+	Command string `json:"command"`
+
+	// Version is the protocol version the device announced in its CNXN or STLS.
+	Version    uint32 `json:"version,omitempty"`
+	MaxPayload uint32 `json:"max_payload,omitempty"`
+
+	// Unauthenticated is true if the device accepted the connection without authentication.
+	Unauthenticated bool `json:"unauthenticated"`
+
+	AuthRequired bool `json:"auth_required,omitempty"`
+	TLSRequired  bool `json:"tls_required,omitempty"`
+
+	Banner *Banner `json:"banner,omitempty"`
+}
+
+// Flags are the ADB-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the adb zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("adb", "Android Debug Bridge (ADB)", module.Description(), 5555, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Send an ADB CNXN and record the device banner of unauthenticated debug bridges"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "adb"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// Scan performs the configured scan on the ADB daemon.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	if _, err = conn.Write(buildConnect()); err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error sending CNXN to target %s: %w", target.String(), err)
+	}
+	msg, err := readMessage(conn)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error reading reply from target %s: %w", target.String(), err)
+	}
+	name, ok := commandNames[msg.command]
+	if !ok {
+		return zgrab2.SCAN_PROTOCOL_ERROR, nil, fmt.Errorf("unexpected command %#08x from target %s", msg.command, target.String())
+	}
+
+	results := &ScanResults{Command: name}
+	switch msg.command {
+	case commandCNXN:
+		results.Version = msg.arg0
+		results.MaxPayload = msg.arg1
+		results.Unauthenticated = true
+		results.Banner = parseBanner(msg.data)
+	case commandAUTH:
+		results.AuthRequired = true
+	case commandSTLS:
+		results.Version = msg.arg0
+		results.TLSRequired = true
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
@@ -0,0 +1,162 @@
+package ajp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Packet types.
+const (
+	typeForwardRequest = 2
+	typeSendBodyChunk  = 3
+	typeSendHeaders    = 4
+	typeEndResponse    = 5
+	typeGetBodyChunk   = 6
+	typeCPong          = 9
+	typeCPing          = 10
+)
+
+const (
+	methodGET = 2
+
+	// requestHeaderHost is the coded name of the Host request header.
+	requestHeaderHost      = 0xA00B
+	requestHeaderUserAgent = 0xA00E
+
+	maxPacketLength = 8192
+)
+
+// responseHeaderNames are the coded response header names.
+var responseHeaderNames = map[uint16]string{
+	0xA001: "Content-Type",
+	0xA002: "Content-Language",
+	0xA003: "Content-Length",
+	0xA004: "Date",
+	0xA005: "Last-Modified",
+	0xA006: "Location",
+	0xA007: "Set-Cookie",
+	0xA008: "Set-Cookie2",
+	0xA009: "Servlet-Engine",
+	0xA00A: "Status",
+	0xA00B: "WWW-Authenticate",
+}
+
+var errInvalidPacket = errors.New("invalid AJP packet")
+
+// packet frames a payload sent to the container.
+func packet(payload []byte) []byte {
+	b := []byte{0x12, 0x34}
+	b = binary.BigEndian.AppendUint16(b, uint16(len(payload)))
+	return append(b, payload...)
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	b = append(b, s...)
+	return append(b, 0)
+}
+
+// buildCPing returns a CPing packet.
+func buildCPing() []byte {
+	return packet([]byte{typeCPing})
+}
+
+// buildForwardRequest returns a forward request for a GET of path.
+func buildForwardRequest(host string, port uint16, path, userAgent string) []byte {
+	payload := []byte{typeForwardRequest, methodGET}
+	payload = appendString(payload, "HTTP/1.1")
+	payload = appendString(payload, path)
+	payload = appendString(payload, "127.0.0.1") // remote_addr
+	payload = appendString(payload, "localhost") // remote_host
+	payload = appendString(payload, host)
+	payload = binary.BigEndian.AppendUint16(payload, port)
+	payload = append(payload, 0) // is_ssl
+	payload = binary.BigEndian.AppendUint16(payload, 2)
+	payload = binary.BigEndian.AppendUint16(payload, requestHeaderHost)
+	payload = appendString(payload, host)
+	payload = binary.BigEndian.AppendUint16(payload, requestHeaderUserAgent)
+	payload = appendString(payload, userAgent)
+	payload = append(payload, 0xFF) // request_terminator
+	return packet(payload)
+}
+
+// readPacket reads a packet sent by the container and returns its payload.
+func readPacket(r io.Reader) ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if header[0] != 'A' || header[1] != 'B' {
+		return nil, errInvalidPacket
+	}
+	length := binary.BigEndian.Uint16(header[2:4])
+	if length == 0 || length > maxPacketLength {
+		return nil, fmt.Errorf("invalid packet length %d", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// Header is a response header.
+type Header struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// reader decodes the fields of a payload.
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) uint16() uint16 {
+	if r.err != nil || len(r.data) < 2 {
+		r.err = errInvalidPacket
+		return 0
+	}
+	v := binary.BigEndian.Uint16(r.data)
+	r.data = r.data[2:]
+	return v
+}
+
+func (r *reader) string() string {
+	length := r.uint16()
+	if r.err != nil || length == 0xFFFF {
+		return ""
+	}
+	if int(length)+1 > len(r.data) {
+		r.err = errInvalidPacket
+		return ""
+	}
+	s := string(r.data[:length])
+	r.data = r.data[length+1:]
+	return s
+}
+
+// parseSendHeaders decodes a SEND_HEADERS payload, without the type byte.
+func parseSendHeaders(data []byte) (int, string, []Header, error) {
+	r := &reader{data: data}
+	status := int(r.uint16())
+	message := r.string()
+	count := int(r.uint16())
+	var headers []Header
+	for i := 0; i < count && r.err == nil; i++ {
+		var name string
+		if len(r.data) >= 2 && r.data[0] == 0xA0 {
+			code := r.uint16()
+			name = responseHeaderNames[code]
+			if name == "" {
+				name = fmt.Sprintf("%#04x", code)
+			}
+		} else {
+			name = r.string()
+		}
+		headers = append(headers, Header{Name: name, Value: r.string()})
+	}
+	return status, message, headers, r.err
+}
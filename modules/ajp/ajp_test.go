@@ -0,0 +1,48 @@
+package ajp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestParseSendHeaders(t *testing.T) {
+	if cping := buildCPing(); !bytes.Equal(cping, []byte{0x12, 0x34, 0, 1, typeCPing}) {
+		t.Errorf("unexpected CPing %x", cping)
+	}
+	request := buildForwardRequest("example.com", 8009, "/", "test")
+	if request[4] != typeForwardRequest || request[len(request)-1] != 0xFF || int(binary.BigEndian.Uint16(request[2:4])) != len(request)-4 {
+		t.Errorf("unexpected forward request %x", request)
+	}
+
+	payload := []byte{typeSendHeaders}
+	payload = binary.BigEndian.AppendUint16(payload, 404)
+	payload = appendString(payload, "Not Found")
+	payload = binary.BigEndian.AppendUint16(payload, 2)
+	payload = binary.BigEndian.AppendUint16(payload, 0xA001)
+	payload = appendString(payload, "text/html;charset=utf-8")
+	payload = appendString(payload, "X-Custom")
+	payload = appendString(payload, "1")
+	framed := append([]byte{'A', 'B'}, binary.BigEndian.AppendUint16(nil, uint16(len(payload)))...)
+	framed = append(framed, payload...)
+
+	read, err := readPacket(bytes.NewReader(framed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, message, headers, err := parseSendHeaders(read[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Header{{"Content-Type", "text/html;charset=utf-8"}, {"X-Custom", "1"}}
+	if status != 404 || message != "Not Found" || !reflect.DeepEqual(headers, expected) {
+		t.Errorf("got %d %q %+v", status, message, headers)
+	}
+	if _, _, _, err = parseSendHeaders(read[1:10]); err == nil {
+		t.Error("expected error for truncated headers")
+	}
+	if match := tomcatVersionPattern.FindStringSubmatch("<h3>Apache Tomcat/9.0.31</h3>"); match == nil || match[1] != "9.0.31" {
+		t.Errorf("got version match %v", match)
+	}
+}
@@ -0,0 +1,221 @@
+// Package ajp contains the zgrab2 Module implementation for the Apache JServ Protocol (AJP13).
+//
+// The scan sends a CPing and records whether the container answers with a CPong. With --forward, it then forwards a
+// GET request for --path and records the response, from which the Tomcat version is taken if the container reveals
+// it. An AJP connector reachable from the internet is what the Ghostcat (CVE-2020-1938) file read needs.
+package ajp
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"regexp"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// tomcatVersionPattern matches the version in Tomcat error pages and Servlet-Engine headers.
+var tomcatVersionPattern = regexp.MustCompile(`Apache Tomcat/(\d+(?:\.\d+)*(?:-M\d+)?)`)
+
+// ForwardResponse is the container's response to the forwarded request.
+type ForwardResponse struct {
+	StatusCode    int      `json:"status_code"`
+	StatusMessage string   `json:"status_message,omitempty"`
+	Headers       []Header `json:"headers,omitempty"`
+	Body          string   `json:"body,omitempty"`
+	BodyTruncated bool     `json:"body_truncated,omitempty"`
+}
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// CPong is true if the container answered the CPing.
+	CPong bool `json:"cpong"`
+
+	Forward *ForwardResponse `json:"forward,omitempty"`
+
+	// TomcatVersion is the Tomcat version found in the forwarded response.
+	TomcatVersion string `json:"tomcat_version,omitempty"`
+}
+
+// Flags are the AJP-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+
+	Forward   bool   `long:"forward" description:"Forward a GET request after the CPing"`
+	Path      string `long:"path" default:"/" description:"Path of the forwarded request"`
+	UserAgent string `long:"user-agent" default:"Mozilla/5.0 zgrab/0.x" description:"User agent of the forwarded request"`
+	MaxSize   int    `long:"max-size" default:"16" description:"Max kilobytes to read of the forwarded response body"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the ajp zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("ajp", "Apache JServ Protocol (AJP13)", module.Description(), 8009, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Send an AJP CPing and optionally forward a request, recording the response and Tomcat version"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "ajp"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// forward sends the forward request and reads the response until END_RESPONSE or the body limit.
+func (scanner *Scanner) forward(conn io.ReadWriter, target *zgrab2.ScanTarget) (*ForwardResponse, error) {
+	host := target.Domain
+	if host == "" {
+		host = target.Host()
+	}
+	request := buildForwardRequest(host, uint16(target.Port), scanner.config.Path, scanner.config.UserAgent)
+	if _, err := conn.Write(request); err != nil {
+		return nil, err
+	}
+	response := new(ForwardResponse)
+	var body []byte
+	maxSize := scanner.config.MaxSize * 1024
+	for {
+		payload, err := readPacket(conn)
+		if err != nil {
+			return response, err
+		}
+		switch payload[0] {
+		case typeSendHeaders:
+			if response.StatusCode, response.StatusMessage, response.Headers, err = parseSendHeaders(payload[1:]); err != nil {
+				return response, err
+			}
+		case typeSendBodyChunk:
+			if len(payload) < 3 || int(binary.BigEndian.Uint16(payload[1:3])) > len(payload)-3 {
+				return response, errInvalidPacket
+			}
+			body = append(body, payload[3:3+binary.BigEndian.Uint16(payload[1:3])]...)
+			if len(body) >= maxSize {
+				response.Body = string(body[:maxSize])
+				response.BodyTruncated = true
+				return response, nil
+			}
+		case typeEndResponse:
+			response.Body = string(body)
+			return response, nil
+		default:
+			// GET_BODY_CHUNK and anything else: a GET has no body to send, so stop here
+			response.Body = string(body)
+			return response, nil
+		}
+	}
+}
+
+// Scan performs the configured scan on the AJP connector.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	if _, err = conn.Write(buildCPing()); err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error sending CPing to target %s: %w", target.String(), err)
+	}
+	payload, err := readPacket(conn)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error reading CPong from target %s: %w", target.String(), err)
+	}
+	if payload[0] != typeCPong {
+		return zgrab2.SCAN_PROTOCOL_ERROR, nil, fmt.Errorf("target %s answered CPing with packet type %d", target.String(), payload[0])
+	}
+	results := &ScanResults{CPong: true}
+	if !scanner.config.Forward {
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+
+	results.Forward, err = scanner.forward(conn, target)
+	if results.Forward != nil {
+		if match := tomcatVersionPattern.FindStringSubmatch(results.Forward.Body); match != nil {
+			results.TomcatVersion = match[1]
+		}
+		for _, header := range results.Forward.Headers {
+			if match := tomcatVersionPattern.FindStringSubmatch(header.Value); match != nil && results.TomcatVersion == "" {
+				results.TomcatVersion = match[1]
+			}
+		}
+	}
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error reading forward response from target %s: %w", target.String(), err)
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
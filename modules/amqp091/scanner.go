@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 
 	amqpLib "github.com/rabbitmq/amqp091-go"
 	log "github.com/sirupsen/logrus"
@@ -20,7 +21,7 @@ type Flags struct {
 	AuthUser         string `long:"auth-user" description:"Username to use for authentication. Must be used with --auth-pass. No auth is attempted if not provided."`
 	AuthPass         string `long:"auth-pass" description:"Password to use for authentication. Must be used with --auth-user. No auth is attempted if not provided."`
 
-	UseTLS          bool `long:"use-tls" description:"Use TLS to connect to the server. Note that AMQPS uses a different default port (5671) than AMQP (5672) and you will need to specify that port manually with -p."`
+	UseTLS          bool `long:"use-tls" description:"Use TLS to connect to the server. Note that AMQPS uses a different default port (5671) than AMQP (5672), where TLS is used even without this flag."`
 	zgrab2.TLSFlags `group:"TLS Options"`
 }
 
@@ -32,8 +33,12 @@ type Module struct {
 type Scanner struct {
 	config            *Flags
 	dialerGroupConfig *zgrab2.DialerGroupConfig
+	tlsWrapper        func(ctx context.Context, target *zgrab2.ScanTarget, conn net.Conn) (*zgrab2.TLSConnection, error)
 }
 
+// amqpsPort is the port of AMQP over implicit TLS.
+const amqpsPort = 5671
+
 type connectionTune struct {
 	ChannelMax int `json:"channel_max"`
 	FrameMax   int `json:"frame_max"`
@@ -42,12 +47,14 @@ type connectionTune struct {
 
 // https://www.rabbitmq.com/amqp-0-9-1-reference#connection.start.server-properties
 type knownServerProperties struct {
-	Product      string `json:"product"`
-	Version      string `json:"version"`
-	Platform     string `json:"platform"`
-	Copyright    string `json:"copyright"`
-	Information  string `json:"information"`
-	UnknownProps string `json:"unknown_props"`
+	Product      string          `json:"product"`
+	Version      string          `json:"version"`
+	Platform     string          `json:"platform"`
+	Copyright    string          `json:"copyright"`
+	Information  string          `json:"information"`
+	ClusterName  string          `json:"cluster_name,omitempty"`
+	Capabilities map[string]bool `json:"capabilities,omitempty"`
+	UnknownProps string          `json:"unknown_props"`
 }
 
 // copy known properties, and store unknown properties in serialized json string
@@ -73,6 +80,22 @@ func (p *knownServerProperties) populate(props amqpLib.Table) {
 		p.Information = information
 		delete(props, "information")
 	}
+	if clusterName, ok := props["cluster_name"].(string); ok {
+		p.ClusterName = clusterName
+		delete(props, "cluster_name")
+	}
+	if capabilities, ok := props["capabilities"].(amqpLib.Table); ok {
+		p.Capabilities = make(map[string]bool, len(capabilities))
+		for name, value := range capabilities {
+			if enabled, ok := value.(bool); ok {
+				p.Capabilities[name] = enabled
+				delete(capabilities, name)
+			}
+		}
+		if len(capabilities) == 0 {
+			delete(props, "capabilities")
+		}
+	}
 
 	if unknownProps, err := json.Marshal(props); err == nil {
 		p.UnknownProps = string(unknownProps)
@@ -87,6 +110,12 @@ type Result struct {
 	ServerProperties knownServerProperties `json:"server_properties"`
 	Locales          []string              `json:"locales"`
 
+	// Mechanisms are the SASL mechanisms offered in connection.start.
+	Mechanisms []string `json:"mechanisms,omitempty"`
+
+	// ImplicitTLS is true if the connection was wrapped in TLS because of the AMQPS port, without --use-tls.
+	ImplicitTLS bool `json:"implicit_tls,omitempty"`
+
 	AuthSuccess bool `json:"auth_success"`
 
 	Tune *connectionTune `json:"tune,omitempty"`
@@ -150,6 +179,7 @@ func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
 		TLSEnabled:                      f.UseTLS,
 		TLSFlags:                        &f.TLSFlags,
 	}
+	scanner.tlsWrapper = zgrab2.GetDefaultTLSWrapper(&f.TLSFlags)
 	return nil
 }
 
@@ -199,6 +229,19 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 		zgrab2.CloseConnAndHandleError(conn)
 	}()
 
+	if target.Port == amqpsPort && !scanner.config.UseTLS {
+		result.ImplicitTLS = true
+		tlsConn, err := scanner.tlsWrapper(ctx, target, conn)
+		if tlsConn != nil {
+			// keep the handshake log, with the certificates, even if the handshake failed
+			conn = tlsConn
+		}
+		if err != nil {
+			return zgrab2.TryGetScanStatus(err), result, fmt.Errorf("unable to perform TLS handshake with target (%v): %w", target.String(), err)
+		}
+	}
+	recorder := &startRecorder{Conn: conn}
+
 	// Prepare AMQP connection config
 	config := amqpLib.Config{
 		Vhost:      scanner.config.Vhost,
@@ -218,7 +261,7 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 	}
 
 	// Open the AMQP connection
-	amqpConn, err := amqpLib.Open(conn, config)
+	amqpConn, err := amqpLib.Open(recorder, config)
 	if err != nil {
 		result.Failure = err.Error()
 	}
@@ -245,6 +288,13 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 	result.VersionMinor = amqpConn.Minor
 	result.Locales = amqpConn.Locales
 	result.ServerProperties.populate(amqpConn.Properties)
+	if frame := recorder.frame(); frame != nil {
+		mechanisms, parseErr := parseStartMechanisms(frame)
+		if parseErr != nil {
+			log.Debugf("failed to parse SASL mechanisms of %v: %v", target.String(), parseErr)
+		}
+		result.Mechanisms = mechanisms
+	}
 
 	// Heuristic to see if we're authenticated.
 	// These values are expected to be non-zero if and only if a tune is received and we're authenticated.
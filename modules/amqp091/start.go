@@ -0,0 +1,90 @@
+package amqp091
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+)
+
+const (
+	// frameMethod is the type of method frames.
+	frameMethod = 1
+
+	// frameHeaderSize is the size of the type, channel and size fields, frameEnd the octet after the payload.
+	frameHeaderSize = 7
+	frameEnd        = 0xCE
+
+	// maxStartSize bounds the bytes recorded while waiting for connection.start.
+	maxStartSize = 128 * 1024
+)
+
+var errInvalidStart = errors.New("invalid connection.start frame")
+
+// startRecorder records the bytes read from the server until they hold the first frame, which is connection.start.
+// The amqp091 library does not expose the SASL mechanisms it carries.
+type startRecorder struct {
+	net.Conn
+	buf  []byte
+	done bool
+}
+
+func (r *startRecorder) Read(b []byte) (int, error) {
+	n, err := r.Conn.Read(b)
+	if !r.done {
+		r.buf = append(r.buf, b[:n]...)
+		if r.frame() != nil || len(r.buf) > maxStartSize {
+			r.done = true
+		}
+	}
+	return n, err
+}
+
+// frame returns the first frame, or nil if it has not been read completely.
+func (r *startRecorder) frame() []byte {
+	if len(r.buf) < frameHeaderSize {
+		return nil
+	}
+	size := int(binary.BigEndian.Uint32(r.buf[3:7]))
+	if size > maxStartSize || len(r.buf) < frameHeaderSize+size+1 {
+		return nil
+	}
+	return r.buf[:frameHeaderSize+size+1]
+}
+
+// parseStartMechanisms returns the SASL mechanisms offered in a connection.start frame.
+func parseStartMechanisms(frame []byte) ([]string, error) {
+	if len(frame) < frameHeaderSize+1 || frame[0] != frameMethod || frame[len(frame)-1] != frameEnd {
+		return nil, errInvalidStart
+	}
+	payload := frame[frameHeaderSize : len(frame)-1]
+	// class-id, method-id, version-major, version-minor
+	if len(payload) < 6 ||
+		binary.BigEndian.Uint16(payload[0:2]) != 10 || binary.BigEndian.Uint16(payload[2:4]) != 10 {
+		return nil, errInvalidStart
+	}
+	rest := payload[6:]
+	// server-properties is a table, prefixed with its size like a long string
+	if _, ok := readLongString(&rest); !ok {
+		return nil, errInvalidStart
+	}
+	mechanisms, ok := readLongString(&rest)
+	if !ok {
+		return nil, errInvalidStart
+	}
+	return strings.Fields(string(mechanisms)), nil
+}
+
+// readLongString reads a string with a 32-bit length from the start of b.
+func readLongString(b *[]byte) ([]byte, bool) {
+	if len(*b) < 4 {
+		return nil, false
+	}
+	length := binary.BigEndian.Uint32(*b)
+	if uint64(len(*b)-4) < uint64(length) {
+		return nil, false
+	}
+	ret := (*b)[4 : 4+length]
+	*b = (*b)[4+length:]
+	return ret, true
+}
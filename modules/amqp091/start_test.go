@@ -0,0 +1,103 @@
+package amqp091
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"slices"
+	"testing"
+
+	"github.com/zmap/zgrab2"
+)
+
+// longString encodes s with a 32-bit length.
+func longString(s []byte) []byte {
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(s))), s...)
+}
+
+// tableEntry encodes a field table entry with the given type and encoded value.
+func tableEntry(name string, fieldType byte, value []byte) []byte {
+	return append(append(append([]byte{byte(len(name))}, name...), fieldType), value...)
+}
+
+// connectionStart returns a connection.start frame offering mechanisms.
+func connectionStart(mechanisms string) []byte {
+	var capabilities []byte
+	capabilities = append(capabilities, tableEntry("publisher_confirms", 't', []byte{1})...)
+	capabilities = append(capabilities, tableEntry("per_consumer_qos", 't', []byte{0})...)
+	capabilities = append(capabilities, tableEntry("consumer_priorities", 'S', longString([]byte("yes")))...)
+	var properties []byte
+	properties = append(properties, tableEntry("product", 'S', longString([]byte("RabbitMQ")))...)
+	properties = append(properties, tableEntry("version", 'S', longString([]byte("3.13.1")))...)
+	properties = append(properties, tableEntry("cluster_name", 'S', longString([]byte("rabbit@node1")))...)
+	properties = append(properties, tableEntry("capabilities", 'F', longString(capabilities))...)
+
+	payload := []byte{0, 10, 0, 10, 0, 9}
+	payload = append(payload, longString(properties)...)
+	payload = append(payload, longString([]byte(mechanisms))...)
+	payload = append(payload, longString([]byte("en_US"))...)
+	frame := []byte{frameMethod, 0, 0}
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(payload)))
+	return append(append(frame, payload...), frameEnd)
+}
+
+func TestParseStartMechanisms(t *testing.T) {
+	frame := connectionStart("PLAIN AMQPLAIN  EXTERNAL")
+	mechanisms, err := parseStartMechanisms(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(mechanisms, []string{"PLAIN", "AMQPLAIN", "EXTERNAL"}) {
+		t.Errorf("mechanisms = %v", mechanisms)
+	}
+	if _, err := parseStartMechanisms(frame[:len(frame)-1]); err == nil {
+		t.Error("parsed frame without frame-end")
+	}
+	frame = frame[:len(frame)-20]
+	if _, err := parseStartMechanisms(append(frame, frameEnd)); err == nil {
+		t.Error("parsed truncated frame")
+	}
+}
+
+func TestScan(t *testing.T) {
+	scanner := &Scanner{config: &Flags{Vhost: "/"}}
+	dialGroup := &zgrab2.DialerGroup{TransportAgnosticDialer: func(ctx context.Context, target *zgrab2.ScanTarget) (net.Conn, error) {
+		client, server := net.Pipe()
+		go func() {
+			defer server.Close()
+			header := make([]byte, 8)
+			if _, err := io.ReadFull(server, header); err != nil || !bytes.Equal(header, []byte("AMQP\x00\x00\x09\x01")) {
+				return
+			}
+			// send the frame in two parts to exercise the recorder
+			start := connectionStart("AMQPLAIN EXTERNAL")
+			server.Write(start[:10])
+			server.Write(start[10:])
+		}()
+		return client, nil
+	}}
+
+	status, res, err := scanner.Scan(context.Background(), dialGroup, &zgrab2.ScanTarget{Port: 5672})
+	if err != nil || status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status = %s, err = %v", status, err)
+	}
+	result := res.(*Result)
+	if !slices.Equal(result.Mechanisms, []string{"AMQPLAIN", "EXTERNAL"}) {
+		t.Errorf("mechanisms = %v", result.Mechanisms)
+	}
+	properties := result.ServerProperties
+	if properties.Product != "RabbitMQ" || properties.Version != "3.13.1" || properties.ClusterName != "rabbit@node1" {
+		t.Errorf("server properties = %+v", properties)
+	}
+	if len(properties.Capabilities) != 2 || !properties.Capabilities["publisher_confirms"] || properties.Capabilities["per_consumer_qos"] {
+		t.Errorf("capabilities = %v", properties.Capabilities)
+	}
+	if properties.UnknownProps != `{"capabilities":{"consumer_priorities":"yes"}}` {
+		t.Errorf("unknown properties = %s", properties.UnknownProps)
+	}
+	if result.AuthSuccess || result.ImplicitTLS {
+		t.Errorf("result = %+v", result)
+	}
+}
@@ -13,6 +13,25 @@ type Log struct {
 	ModelName                   string `json:"model_name,omitempty"`
 	Description                 string `json:"description,omitempty"`
 	Location                    string `json:"location,omitempty"`
+
+	// Properties are the results of the ReadPropertyMultiple requests, each either the values of a property or the
+	// error returned for it. Only present if --read-property-multiple is set.
+	Properties []PropertyResult `json:"properties,omitempty"`
+
+	// ObjectList is the decoded object-list of the device.
+	ObjectList []ObjectIdentifier `json:"object_list,omitempty"`
+
+	// ObjectCount is the number of objects of the device.
+	ObjectCount *uint32 `json:"object_count,omitempty"`
+
+	// NetworkNumbers are the network numbers of the device's network port objects.
+	NetworkNumbers []uint16 `json:"network_numbers,omitempty"`
+
+	// SourceNetwork is the network number of the device, if its responses were routed from a remote network.
+	SourceNetwork *uint16 `json:"source_network,omitempty"`
+
+	// ReadPropertyMultipleError is set if a ReadPropertyMultiple request failed as a whole.
+	ReadPropertyMultipleError string `json:"read_property_multiple_error,omitempty"`
 }
 
 func (log *Log) sendReadProperty(c net.Conn, oid ObjectID, pid PropertyID) ([]byte, error, bool) {
@@ -0,0 +1,633 @@
+package bacnet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+)
+
+// APDU types and service choices used by ReadPropertyMultiple
+const (
+	APDU_TYPE_CONFIRMED_REQUEST          byte = 0x00
+	APDU_TYPE_COMPLEX_ACK                byte = 0x30
+	APDU_TYPE_ERROR                      byte = 0x50
+	APDU_TYPE_REJECT                     byte = 0x60
+	APDU_TYPE_ABORT                      byte = 0x70
+	APDU_FLAG_SEGMENTED                  byte = 0x08
+	SERVER_CHOICE_READ_PROPERTY_MULTIPLE byte = 0x0e
+)
+
+// NPDU control flags
+const (
+	NPDU_FLAG_NETWORK_MESSAGE byte = 0x80
+	NPDU_FLAG_DNET_PRESENT    byte = 0x20
+	NPDU_FLAG_SNET_PRESENT    byte = 0x08
+)
+
+// Object types and properties read with ReadPropertyMultiple
+const (
+	OBJECT_TYPE_DEVICE       uint16 = 8
+	OBJECT_TYPE_NETWORK_PORT uint16 = 56
+
+	PID_OBJECT_LIST            PropertyID = 0x4c
+	PID_NETWORK_NUMBER         uint32     = 425
+	PID_NETWORK_NUMBER_QUALITY uint32     = 426
+)
+
+// Application tag numbers
+const (
+	TAG_NULL              = 0
+	TAG_BOOLEAN           = 1
+	TAG_UNSIGNED          = 2
+	TAG_SIGNED            = 3
+	TAG_REAL              = 4
+	TAG_DOUBLE            = 5
+	TAG_OCTET_STRING      = 6
+	TAG_CHARACTER_STRING  = 7
+	TAG_BIT_STRING        = 8
+	TAG_ENUMERATED        = 9
+	TAG_OBJECT_IDENTIFIER = 12
+)
+
+// maxNetworkPorts bounds the number of network port objects whose network number is read.
+const maxNetworkPorts = 16
+
+var objectTypeNames = map[uint16]string{
+	0:  "analog-input",
+	1:  "analog-output",
+	2:  "analog-value",
+	3:  "binary-input",
+	4:  "binary-output",
+	5:  "binary-value",
+	6:  "calendar",
+	7:  "command",
+	8:  "device",
+	9:  "event-enrollment",
+	10: "file",
+	11: "group",
+	12: "loop",
+	13: "multi-state-input",
+	14: "multi-state-output",
+	15: "notification-class",
+	16: "program",
+	17: "schedule",
+	19: "multi-state-value",
+	20: "trend-log",
+	56: "network-port",
+}
+
+var propertyNames = map[uint32]string{
+	uint32(PID_FIRMWARE_REVISION): "firmware-revision",
+	uint32(PID_LOCATION):          "location",
+	uint32(PID_OBJECT_LIST):       "object-list",
+	PID_NETWORK_NUMBER:            "network-number",
+	PID_NETWORK_NUMBER_QUALITY:    "network-number-quality",
+}
+
+var errorClassNames = map[uint32]string{
+	0: "device",
+	1: "object",
+	2: "property",
+	3: "resources",
+	4: "security",
+	5: "services",
+	6: "vt",
+	7: "communication",
+}
+
+var errorCodeNames = map[uint32]string{
+	0:  "other",
+	2:  "configuration-in-progress",
+	3:  "device-busy",
+	9:  "invalid-data-type",
+	25: "operational-problem",
+	27: "read-access-denied",
+	29: "service-request-denied",
+	30: "timeout",
+	31: "unknown-object",
+	32: "unknown-property",
+	36: "unsupported-object-type",
+	42: "invalid-array-index",
+}
+
+var rejectReasonNames = map[byte]string{
+	0: "other",
+	1: "buffer-overflow",
+	2: "inconsistent-parameters",
+	3: "invalid-parameter-data-type",
+	4: "invalid-tag",
+	5: "missing-required-parameter",
+	6: "parameter-out-of-range",
+	7: "too-many-arguments",
+	8: "undefined-enumeration",
+	9: "unrecognized-service",
+}
+
+var abortReasonNames = map[byte]string{
+	0: "other",
+	1: "buffer-overflow",
+	2: "invalid-apdu-in-this-state",
+	3: "preempted-by-higher-priority-task",
+	4: "segmentation-not-supported",
+}
+
+// ObjectIdentifier is a decoded BACnetObjectIdentifier.
+type ObjectIdentifier struct {
+	Type     uint16 `json:"type"`
+	TypeName string `json:"type_name,omitempty"`
+	Instance uint32 `json:"instance"`
+}
+
+func newObjectIdentifier(oid uint32) ObjectIdentifier {
+	objectType := uint16(oid >> 22)
+	return ObjectIdentifier{Type: objectType, TypeName: objectTypeNames[objectType], Instance: oid & 0x3fffff}
+}
+
+func (oid ObjectIdentifier) encode() uint32 {
+	return uint32(oid.Type)<<22 | oid.Instance&0x3fffff
+}
+
+// PropertyError is the error returned for a single property.
+type PropertyError struct {
+	Class     uint32 `json:"class"`
+	ClassName string `json:"class_name,omitempty"`
+	Code      uint32 `json:"code"`
+	CodeName  string `json:"code_name,omitempty"`
+}
+
+// PropertyResult is the result of reading one property with ReadPropertyMultiple: either its values or an error.
+type PropertyResult struct {
+	Object       ObjectIdentifier `json:"object"`
+	Property     uint32           `json:"property"`
+	PropertyName string           `json:"property_name,omitempty"`
+	ArrayIndex   *uint32          `json:"array_index,omitempty"`
+
+	// Values are the decoded application values of the property.
+	Values []string `json:"values,omitempty"`
+
+	// Raw is the encoded value of the property.
+	Raw []byte `json:"raw,omitempty"`
+
+	Error *PropertyError `json:"error,omitempty"`
+}
+
+// propertyReference is a property to read, with an optional array index.
+type propertyReference struct {
+	property   uint32
+	arrayIndex *uint32
+}
+
+// objectReferences are the properties to read from one object.
+type objectReferences struct {
+	object     ObjectIdentifier
+	properties []propertyReference
+}
+
+// tag is a decoded BACnet tag.
+type tag struct {
+	number  uint8
+	context bool
+	opening bool
+	closing bool
+
+	// lvt is the length/value/type field, which is the value of application booleans.
+	lvt  uint32
+	data []byte
+}
+
+// readTag decodes the tag at the start of b, returning it and the rest of b.
+func readTag(b []byte) (*tag, []byte, error) {
+	if len(b) < 1 {
+		return nil, b, errBACNetPacketTooShort
+	}
+	t := &tag{number: b[0] >> 4, context: b[0]&0x08 != 0, lvt: uint32(b[0] & 0x07)}
+	b = b[1:]
+	if t.number == 0x0f {
+		if len(b) < 1 {
+			return nil, b, errBACNetPacketTooShort
+		}
+		t.number, b = b[0], b[1:]
+	}
+	if t.context && t.lvt == 6 {
+		t.opening = true
+		return t, b, nil
+	}
+	if t.context && t.lvt == 7 {
+		t.closing = true
+		return t, b, nil
+	}
+	if !t.context && t.number == TAG_BOOLEAN {
+		return t, b, nil
+	}
+	if t.lvt == 5 {
+		if len(b) < 1 {
+			return nil, b, errBACNetPacketTooShort
+		}
+		t.lvt, b = uint32(b[0]), b[1:]
+		switch t.lvt {
+		case 254:
+			if len(b) < 2 {
+				return nil, b, errBACNetPacketTooShort
+			}
+			t.lvt, b = uint32(binary.BigEndian.Uint16(b)), b[2:]
+		case 255:
+			if len(b) < 4 {
+				return nil, b, errBACNetPacketTooShort
+			}
+			t.lvt, b = binary.BigEndian.Uint32(b), b[4:]
+		}
+	}
+	if uint32(len(b)) < t.lvt {
+		return nil, b, errBACNetPacketTooShort
+	}
+	t.data, b = b[:t.lvt], b[t.lvt:]
+	return t, b, nil
+}
+
+// unsigned decodes the data of the tag as a big-endian unsigned integer.
+func (t *tag) unsigned() uint32 {
+	var ret uint32
+	for _, v := range t.data {
+		ret = ret<<8 | uint32(v)
+	}
+	return ret
+}
+
+// value decodes the data of an application tag as a string.
+func (t *tag) value() string {
+	switch t.number {
+	case TAG_NULL:
+		return "null"
+	case TAG_BOOLEAN:
+		return strconv.FormatBool(t.lvt != 0)
+	case TAG_UNSIGNED, TAG_ENUMERATED:
+		return strconv.FormatUint(uint64(t.unsigned()), 10)
+	case TAG_SIGNED:
+		value := int32(t.unsigned())
+		if n := len(t.data); n > 0 && n < 4 && t.data[0]&0x80 != 0 {
+			value -= 1 << (8 * n)
+		}
+		return strconv.FormatInt(int64(value), 10)
+	case TAG_REAL:
+		if len(t.data) == 4 {
+			return strconv.FormatFloat(float64(math.Float32frombits(binary.BigEndian.Uint32(t.data))), 'g', -1, 32)
+		}
+	case TAG_DOUBLE:
+		if len(t.data) == 8 {
+			return strconv.FormatFloat(math.Float64frombits(binary.BigEndian.Uint64(t.data)), 'g', -1, 64)
+		}
+	case TAG_CHARACTER_STRING:
+		// the first byte is the character set
+		if len(t.data) > 0 {
+			return string(t.data[1:])
+		}
+	case TAG_OBJECT_IDENTIFIER:
+		if len(t.data) == 4 {
+			oid := newObjectIdentifier(binary.BigEndian.Uint32(t.data))
+			return fmt.Sprintf("%d:%d", oid.Type, oid.Instance)
+		}
+	}
+	return fmt.Sprintf("%x", t.data)
+}
+
+// expectContextTag reads a context tag with the given number, and for primitive tags returns its data.
+func expectContextTag(b []byte, number uint8, opening, closing bool) (*tag, []byte, error) {
+	t, rest, err := readTag(b)
+	if err != nil {
+		return nil, b, err
+	}
+	if !t.context || t.number != number || t.opening != opening || t.closing != closing {
+		return nil, b, fmt.Errorf("unexpected tag %d, expected context tag %d", t.number, number)
+	}
+	return t, rest, nil
+}
+
+// encodeContextUnsigned encodes an unsigned value with a context tag.
+func encodeContextUnsigned(buf *bytes.Buffer, number uint8, value uint32) {
+	var data []byte
+	switch {
+	case value < 1<<8:
+		data = []byte{byte(value)}
+	case value < 1<<16:
+		data = binary.BigEndian.AppendUint16(nil, uint16(value))
+	case value < 1<<24:
+		data = []byte{byte(value >> 16), byte(value >> 8), byte(value)}
+	default:
+		data = binary.BigEndian.AppendUint32(nil, value)
+	}
+	buf.WriteByte(number<<4 | 0x08 | byte(len(data)))
+	buf.Write(data)
+}
+
+// newReadPropertyMultipleRequest encodes a ReadPropertyMultiple request, including its NPDU.
+func newReadPropertyMultipleRequest(invokeID byte, objects []objectReferences) []byte {
+	buf := new(bytes.Buffer)
+	npdu, _ := (&NPDU{Version: NPDU_VERSION_ASHRAE_135_1995, Control: NPDU_FLAG_EXPECTING_RESPONSE}).Marshal()
+	buf.Write(npdu)
+	// no segmentation accepted, up to 1476 bytes
+	buf.Write([]byte{APDU_TYPE_CONFIRMED_REQUEST, 0x05, invokeID, SERVER_CHOICE_READ_PROPERTY_MULTIPLE})
+	for _, object := range objects {
+		buf.WriteByte(0x0c)
+		_ = binary.Write(buf, binary.BigEndian, object.object.encode())
+		buf.WriteByte(0x1e)
+		for _, ref := range object.properties {
+			encodeContextUnsigned(buf, 0, ref.property)
+			if ref.arrayIndex != nil {
+				encodeContextUnsigned(buf, 1, *ref.arrayIndex)
+			}
+		}
+		buf.WriteByte(0x1f)
+	}
+	return buf.Bytes()
+}
+
+// parseNPDU decodes an NPDU header, returning the source network number if present and the APDU.
+func parseNPDU(b []byte) (*uint16, []byte, error) {
+	if len(b) < npduLength {
+		return nil, b, errBACNetPacketTooShort
+	}
+	control := b[1]
+	b = b[npduLength:]
+	if control&NPDU_FLAG_NETWORK_MESSAGE != 0 {
+		return nil, b, errors.New("network layer message")
+	}
+	if control&NPDU_FLAG_DNET_PRESENT != 0 {
+		if len(b) < 3 || len(b) < 3+int(b[2]) {
+			return nil, b, errBACNetPacketTooShort
+		}
+		b = b[3+int(b[2]):]
+	}
+	var snet *uint16
+	if control&NPDU_FLAG_SNET_PRESENT != 0 {
+		if len(b) < 3 || len(b) < 3+int(b[2]) {
+			return nil, b, errBACNetPacketTooShort
+		}
+		network := binary.BigEndian.Uint16(b)
+		snet = &network
+		b = b[3+int(b[2]):]
+	}
+	if control&NPDU_FLAG_DNET_PRESENT != 0 {
+		// hop count
+		if len(b) < 1 {
+			return nil, b, errBACNetPacketTooShort
+		}
+		b = b[1:]
+	}
+	return snet, b, nil
+}
+
+// parseRPMError decodes the error of an Error, Reject or Abort PDU answering the request.
+func parseRPMError(apdu []byte) error {
+	switch apdu[0] & 0xf0 {
+	case APDU_TYPE_ERROR:
+		// invoke ID, service choice, then error class and code; the choice may be wrapped in context tag 0
+		b := apdu[3:]
+		if t, rest, err := readTag(b); err == nil && t.opening {
+			b = rest
+		}
+		class, b, err := readTag(b)
+		if err != nil {
+			return errInvalidPacket
+		}
+		code, _, err := readTag(b)
+		if err != nil {
+			return errInvalidPacket
+		}
+		return fmt.Errorf("error: class %s (%d), code %s (%d)", errorClassNames[class.unsigned()], class.unsigned(), errorCodeNames[code.unsigned()], code.unsigned())
+	case APDU_TYPE_REJECT:
+		return fmt.Errorf("reject: %s (%d)", rejectReasonNames[apdu[2]], apdu[2])
+	case APDU_TYPE_ABORT:
+		return fmt.Errorf("abort: %s (%d)", abortReasonNames[apdu[2]], apdu[2])
+	}
+	return fmt.Errorf("unexpected APDU type 0x%02x", apdu[0])
+}
+
+// parseReadPropertyMultipleAck decodes the list of read access results of a ReadPropertyMultiple ack.
+func parseReadPropertyMultipleAck(b []byte) ([]PropertyResult, error) {
+	var ret []PropertyResult
+	for len(b) > 0 {
+		t, rest, err := expectContextTag(b, 0, false, false)
+		if err != nil {
+			return ret, err
+		}
+		object := newObjectIdentifier(t.unsigned())
+		if _, b, err = expectContextTag(rest, 1, true, false); err != nil {
+			return ret, err
+		}
+		for {
+			t, rest, err := readTag(b)
+			if err != nil {
+				return ret, err
+			}
+			if t.context && t.number == 1 && t.closing {
+				b = rest
+				break
+			}
+			if !t.context || t.number != 2 || t.opening || t.closing {
+				return ret, fmt.Errorf("unexpected tag %d, expected property identifier", t.number)
+			}
+			result := PropertyResult{Object: object, Property: t.unsigned(), PropertyName: propertyNames[t.unsigned()]}
+			if t, next, err := readTag(rest); err == nil && t.context && t.number == 3 && !t.opening && !t.closing {
+				index := t.unsigned()
+				result.ArrayIndex = &index
+				rest = next
+			}
+			if result.Values, result.Error, result.Raw, b, err = parsePropertyValue(rest); err != nil {
+				return ret, err
+			}
+			ret = append(ret, result)
+		}
+	}
+	return ret, nil
+}
+
+// parsePropertyValue decodes a property value (opening tag 4) or a property access error (opening tag 5).
+func parsePropertyValue(b []byte) (values []string, propErr *PropertyError, raw []byte, rest []byte, err error) {
+	t, rest, err := readTag(b)
+	if err != nil {
+		return
+	}
+	switch {
+	case t.context && t.opening && t.number == 4:
+		start := rest
+		depth := 0
+		for {
+			var v *tag
+			before := rest
+			if v, rest, err = readTag(rest); err != nil {
+				return
+			}
+			switch {
+			case v.opening:
+				depth++
+			case v.closing && depth == 0:
+				if v.number != 4 {
+					err = fmt.Errorf("unexpected closing tag %d", v.number)
+					return
+				}
+				raw = start[:len(start)-len(before)]
+				return
+			case v.closing:
+				depth--
+			case !v.context && depth == 0:
+				values = append(values, v.value())
+			}
+		}
+	case t.context && t.opening && t.number == 5:
+		var class, code *tag
+		if class, rest, err = readTag(rest); err != nil {
+			return
+		}
+		if code, rest, err = readTag(rest); err != nil {
+			return
+		}
+		propErr = &PropertyError{
+			Class:     class.unsigned(),
+			ClassName: errorClassNames[class.unsigned()],
+			Code:      code.unsigned(),
+			CodeName:  errorCodeNames[code.unsigned()],
+		}
+		_, rest, err = expectContextTag(rest, 5, false, true)
+		return
+	}
+	err = fmt.Errorf("unexpected tag %d, expected property value or error", t.number)
+	return
+}
+
+// readPropertyMultiple sends a ReadPropertyMultiple request and returns the results, skipping datagrams that answer
+// other requests.
+func (log *Log) readPropertyMultiple(c net.Conn, invokeID byte, objects []objectReferences) ([]PropertyResult, error) {
+	if err := SendVLC(c, newReadPropertyMultipleRequest(invokeID, objects)); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, MAX_BACNET_FRAME_LEN)
+	for attempts := 0; attempts < 4; attempts++ {
+		n, err := c.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		b, err := new(VLC).Unmarshal(buf[:n])
+		if err != nil {
+			return nil, err
+		}
+		snet, apdu, err := parseNPDU(b)
+		if err != nil || len(apdu) < 3 {
+			continue
+		}
+		if apdu[1] != invokeID {
+			continue
+		}
+		if snet != nil {
+			log.SourceNetwork = snet
+		}
+		if apdu[0]&0xf0 != APDU_TYPE_COMPLEX_ACK {
+			return nil, parseRPMError(apdu)
+		}
+		if apdu[0]&APDU_FLAG_SEGMENTED != 0 {
+			return nil, errors.New("segmented response not supported")
+		}
+		if apdu[2] != SERVER_CHOICE_READ_PROPERTY_MULTIPLE {
+			return nil, errInvalidPacket
+		}
+		return parseReadPropertyMultipleAck(apdu[3:])
+	}
+	return nil, errors.New("no response to ReadPropertyMultiple")
+}
+
+// QueryPropertyMultiple reads the object list, firmware revision and location of the device with ReadPropertyMultiple,
+// then the network numbers of its network port objects. If the device cannot return its whole object list in one
+// response, the object count (array index 0) is read instead.
+func (log *Log) QueryPropertyMultiple(c net.Conn) error {
+	device := ObjectIdentifier{Type: OBJECT_TYPE_DEVICE, TypeName: objectTypeNames[OBJECT_TYPE_DEVICE], Instance: log.InstanceNumber}
+	request := func(objectList propertyReference) []objectReferences {
+		return []objectReferences{{object: device, properties: []propertyReference{
+			objectList,
+			{property: uint32(PID_FIRMWARE_REVISION)},
+			{property: uint32(PID_LOCATION)},
+		}}}
+	}
+	results, err := log.readPropertyMultiple(c, 2, request(propertyReference{property: uint32(PID_OBJECT_LIST)}))
+	if err != nil {
+		// e.g. an abort for segmentation-not-supported because the object list is too long
+		zero := uint32(0)
+		var retryErr error
+		if results, retryErr = log.readPropertyMultiple(c, 3, request(propertyReference{property: uint32(PID_OBJECT_LIST), arrayIndex: &zero})); retryErr != nil {
+			log.ReadPropertyMultipleError = err.Error()
+			return err
+		}
+	}
+	log.Properties = append(log.Properties, results...)
+
+	var ports []objectReferences
+	for _, result := range results {
+		if result.Property != uint32(PID_OBJECT_LIST) || result.Error != nil {
+			continue
+		}
+		if result.ArrayIndex != nil {
+			if count, err := strconv.ParseUint(firstOrEmpty(result.Values), 10, 32); err == nil {
+				objectCount := uint32(count)
+				log.ObjectCount = &objectCount
+			}
+			continue
+		}
+		log.ObjectList, err = parseObjectList(result.Raw)
+		if err != nil {
+			return err
+		}
+		objectCount := uint32(len(log.ObjectList))
+		log.ObjectCount = &objectCount
+		for _, object := range log.ObjectList {
+			if object.Type == OBJECT_TYPE_NETWORK_PORT && len(ports) < maxNetworkPorts {
+				ports = append(ports, objectReferences{object: object, properties: []propertyReference{
+					{property: PID_NETWORK_NUMBER},
+					{property: PID_NETWORK_NUMBER_QUALITY},
+				}})
+			}
+		}
+	}
+	if len(ports) == 0 {
+		return nil
+	}
+	results, err = log.readPropertyMultiple(c, 4, ports)
+	if err != nil {
+		log.ReadPropertyMultipleError = err.Error()
+		return err
+	}
+	log.Properties = append(log.Properties, results...)
+	for _, result := range results {
+		if result.Property != PID_NETWORK_NUMBER || result.Error != nil || len(result.Values) == 0 {
+			continue
+		}
+		if number, err := strconv.ParseUint(result.Values[0], 10, 16); err == nil && number != 0 {
+			log.NetworkNumbers = append(log.NetworkNumbers, uint16(number))
+		}
+	}
+	return nil
+}
+
+// parseObjectList decodes the object identifiers of an encoded object-list.
+func parseObjectList(b []byte) ([]ObjectIdentifier, error) {
+	var ret []ObjectIdentifier
+	for len(b) > 0 {
+		t, rest, err := readTag(b)
+		if err != nil {
+			return ret, err
+		}
+		if !t.context && t.number == TAG_OBJECT_IDENTIFIER && len(t.data) == 4 {
+			ret = append(ret, newObjectIdentifier(binary.BigEndian.Uint32(t.data)))
+		}
+		b = rest
+	}
+	return ret, nil
+}
+
+func firstOrEmpty(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -0,0 +1,129 @@
+package bacnet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+
+	. "gopkg.in/check.v1"
+)
+
+type RPMSuite struct {
+}
+
+var _ = Suite(&RPMSuite{})
+
+// objectIDTag encodes an application-tagged object identifier.
+func objectIDTag(objectType uint16, instance uint32) []byte {
+	return binary.BigEndian.AppendUint32([]byte{0xc4}, uint32(objectType)<<22|instance)
+}
+
+// rpmAck returns a datagram with a ReadPropertyMultiple ack, routed from network 7.
+func rpmAck(invokeID byte, results []byte) []byte {
+	payload := []byte{NPDU_VERSION_ASHRAE_135_1995, NPDU_FLAG_SNET_PRESENT, 0x00, 0x07, 0x01, 0x05}
+	payload = append(payload, APDU_TYPE_COMPLEX_ACK, invokeID, SERVER_CHOICE_READ_PROPERTY_MULTIPLE)
+	payload = append(payload, results...)
+	vlc, _ := (&VLC{Type: VLC_TYPE_IP, Function: VLC_FUNCTION_UNICAST_NPDU, Length: uint16(4 + len(payload))}).Marshal()
+	return append(vlc, payload...)
+}
+
+func deviceResults() []byte {
+	b := new(bytes.Buffer)
+	b.WriteByte(0x0c)
+	binary.Write(b, binary.BigEndian, uint32(OBJECT_TYPE_DEVICE)<<22|1234)
+	b.WriteByte(0x1e)
+	// object-list
+	b.Write([]byte{0x29, byte(PID_OBJECT_LIST), 0x4e})
+	b.Write(objectIDTag(OBJECT_TYPE_DEVICE, 1234))
+	b.Write(objectIDTag(OBJECT_TYPE_NETWORK_PORT, 1))
+	b.Write(objectIDTag(0, 3))
+	b.WriteByte(0x4f)
+	// firmware-revision
+	b.Write([]byte{0x29, byte(PID_FIRMWARE_REVISION), 0x4e, 0x74, 0x00, '1', '.', '2', 0x4f})
+	// location: unknown-property
+	b.Write([]byte{0x29, byte(PID_LOCATION), 0x5e, 0x91, 0x02, 0x91, 0x20, 0x5f})
+	b.WriteByte(0x1f)
+	return b.Bytes()
+}
+
+func networkPortResults() []byte {
+	b := new(bytes.Buffer)
+	b.WriteByte(0x0c)
+	binary.Write(b, binary.BigEndian, uint32(OBJECT_TYPE_NETWORK_PORT)<<22|1)
+	b.WriteByte(0x1e)
+	b.Write([]byte{0x2a, 0x01, 0xa9, 0x4e, 0x21, 0x05, 0x4f})
+	b.Write([]byte{0x2a, 0x01, 0xaa, 0x5e, 0x91, 0x02, 0x91, 0x20, 0x5f})
+	b.WriteByte(0x1f)
+	return b.Bytes()
+}
+
+func (s *RPMSuite) TestReadTag(c *C) {
+	t, rest, err := readTag([]byte{0x75, 0x06, 0x00, 'h', 'e', 'l', 'l', 'o', 0xff})
+	c.Assert(err, IsNil)
+	c.Check(t.value(), Equals, "hello")
+	c.Check(rest, DeepEquals, []byte{0xff})
+
+	t, _, err = readTag([]byte{0x32, 0xff, 0x38})
+	c.Assert(err, IsNil)
+	c.Check(t.value(), Equals, "-200")
+
+	t, _, err = readTag([]byte{0x3e})
+	c.Assert(err, IsNil)
+	c.Check(t.opening, Equals, true)
+	c.Check(t.number, Equals, uint8(3))
+
+	_, _, err = readTag([]byte{0x74, 0x00})
+	c.Check(err, Equals, errBACNetPacketTooShort)
+}
+
+func (s *RPMSuite) TestReadPropertyMultipleRequest(c *C) {
+	zero := uint32(0)
+	req := newReadPropertyMultipleRequest(2, []objectReferences{{
+		object:     ObjectIdentifier{Type: OBJECT_TYPE_DEVICE, Instance: 1234},
+		properties: []propertyReference{{property: uint32(PID_OBJECT_LIST), arrayIndex: &zero}, {property: PID_NETWORK_NUMBER}},
+	}})
+	expected := []byte{0x01, 0x04, 0x00, 0x05, 0x02, 0x0e, 0x0c, 0x02, 0x00, 0x04, 0xd2, 0x1e, 0x09, 0x4c, 0x19, 0x00, 0x0a, 0x01, 0xa9, 0x1f}
+	c.Check(req, DeepEquals, expected)
+}
+
+func (s *RPMSuite) TestQueryPropertyMultiple(c *C) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		buf := make([]byte, MAX_BACNET_FRAME_LEN)
+		for _, results := range [][]byte{deviceResults(), networkPortResults()} {
+			n, err := server.Read(buf)
+			if err != nil || n < 10 {
+				return
+			}
+			server.Write(rpmAck(buf[8], results))
+		}
+	}()
+
+	log := &Log{InstanceNumber: 1234}
+	c.Assert(log.QueryPropertyMultiple(client), IsNil)
+	c.Check(log.ObjectList, DeepEquals, []ObjectIdentifier{
+		{Type: OBJECT_TYPE_DEVICE, TypeName: "device", Instance: 1234},
+		{Type: OBJECT_TYPE_NETWORK_PORT, TypeName: "network-port", Instance: 1},
+		{Type: 0, TypeName: "analog-input", Instance: 3},
+	})
+	c.Assert(log.ObjectCount, NotNil)
+	c.Check(*log.ObjectCount, Equals, uint32(3))
+	c.Check(log.NetworkNumbers, DeepEquals, []uint16{5})
+	c.Assert(log.SourceNetwork, NotNil)
+	c.Check(*log.SourceNetwork, Equals, uint16(7))
+
+	c.Assert(log.Properties, HasLen, 5)
+	c.Check(log.Properties[1].PropertyName, Equals, "firmware-revision")
+	c.Check(log.Properties[1].Values, DeepEquals, []string{"1.2"})
+	c.Check(log.Properties[2].Error, DeepEquals, &PropertyError{Class: 2, ClassName: "property", Code: 32, CodeName: "unknown-property"})
+	c.Check(log.Properties[4].PropertyName, Equals, "network-number-quality")
+	c.Check(log.Properties[4].Error, NotNil)
+}
+
+func (s *RPMSuite) TestParseRPMError(c *C) {
+	c.Check(parseRPMError([]byte{APDU_TYPE_ABORT, 2, 4}), ErrorMatches, "abort: segmentation-not-supported \\(4\\)")
+	c.Check(parseRPMError([]byte{APDU_TYPE_REJECT, 2, 9}), ErrorMatches, "reject: unrecognized-service \\(9\\)")
+	c.Check(parseRPMError([]byte{APDU_TYPE_ERROR, 2, SERVER_CHOICE_READ_PROPERTY_MULTIPLE, 0x91, 0x05, 0x91, 0x00}), ErrorMatches, "error: class services \\(5\\), code other \\(0\\)")
+}
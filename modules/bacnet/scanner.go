@@ -1,7 +1,9 @@
 // Package bacnet provides a zgrab2 module that scans for bacnet.
 // Default Port: 47808 / 0xBAC0 (UDP)
 //
-// Behavior and output copied identically from original zgrab.
+// Behavior and output copied identically from original zgrab, with the
+// addition of the --read-property-multiple flag, which reads the object list,
+// firmware revision, location and network numbers with ReadPropertyMultiple.
 package bacnet
 
 import (
@@ -19,7 +21,8 @@ import (
 // Flags holds the command-line configuration for the bacnet scan module.
 // Populated by the framework.
 type Flags struct {
-	zgrab2.BaseFlags `group:"Basic Options"`
+	zgrab2.BaseFlags     `group:"Basic Options"`
+	ReadPropertyMultiple bool `long:"read-property-multiple" description:"Read the object list, firmware revision, location and network numbers with ReadPropertyMultiple"`
 }
 
 // Module implements the zgrab2.Module interface.
@@ -114,14 +117,16 @@ func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
 // Attempts to query the following in sequence; if any fails, returning anything that has been detected so far.
 // (Unless QueryDeviceID fails, the service is considered to be detected)
 // 1. Device ID
-// 2. Vendor Number
-// 3. Vendor Name
-// 4. Firmware Revision
-// 5. App software revision
-// 6. Object name
-// 7. Model  name
-// 8. Description
-// 9. Location
+// 2. If --read-property-multiple is set, the object list, firmware revision, location and network numbers with
+// ReadPropertyMultiple (a failure is recorded in the result, but does not stop the scan)
+// 3. Vendor Number
+// 4. Vendor Name
+// 5. Firmware Revision
+// 6. App software revision
+// 7. Object name
+// 8. Model  name
+// 9. Description
+// 10. Location
 // The result is a bacnet.Log, and contains any of the above.
 func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
 	conn, err := dialGroup.Dial(ctx, target)
@@ -137,6 +142,11 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 	if err := ret.QueryDeviceID(conn); err != nil {
 		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error querying device id for target %v: %w", target.String(), err)
 	}
+	if scanner.config.ReadPropertyMultiple {
+		if err := ret.QueryPropertyMultiple(conn); err != nil {
+			log.Debugf("error reading properties of target %v with ReadPropertyMultiple: %v", target.String(), err)
+		}
+	}
 	if err := ret.QueryVendorNumber(conn); err != nil {
 		return zgrab2.TryGetScanStatus(err), ret, fmt.Errorf("error querying vendor number for target %v: %w", target.String(), err)
 	}
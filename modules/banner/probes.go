@@ -0,0 +1,92 @@
+package banner
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Probe is one entry of the --probes-file, sent in order until the response to one of them matches its pattern.
+type Probe struct {
+	Label   string
+	Payload []byte
+
+	// Pattern is nil if any non-empty response matches.
+	Pattern *regexp.Regexp
+}
+
+// ProbeResult records the outcome of sending one probe.
+type ProbeResult struct {
+	Label   string `json:"label"`
+	Length  int    `json:"length"`
+	Matched bool   `json:"matched"`
+	Error   string `json:"error,omitempty"`
+}
+
+// matches returns true if data is a response to the probe.
+func (probe *Probe) matches(data []byte) bool {
+	if probe.Pattern == nil {
+		return len(data) > 0
+	}
+	return probe.Pattern.Match(data)
+}
+
+// parsePayload decodes a hex payload, or reads it from a file if it starts with @.
+func parsePayload(payload string) ([]byte, error) {
+	if path, ok := strings.CutPrefix(payload, "@"); ok {
+		return os.ReadFile(path)
+	}
+	return hex.DecodeString(payload)
+}
+
+// parseProbes reads probes, one per line in the form "<label> <payload> [<pattern>]". The payload is either hex or
+// @<path> to send the contents of a file, the pattern is the rest of the line. Empty lines and lines starting with #
+// are skipped.
+func parseProbes(r io.Reader) ([]Probe, error) {
+	var probes []Probe
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		label, rest, _ := strings.Cut(line, " ")
+		payload, pattern, _ := strings.Cut(strings.TrimSpace(rest), " ")
+		if payload == "" {
+			return nil, fmt.Errorf("line %d: missing payload", lineNo)
+		}
+		probe := Probe{Label: label}
+		var err error
+		if probe.Payload, err = parsePayload(payload); err != nil {
+			return nil, fmt.Errorf("line %d: invalid payload: %w", lineNo, err)
+		}
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			if probe.Pattern, err = regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("line %d: invalid pattern: %w", lineNo, err)
+			}
+		}
+		probes = append(probes, probe)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(probes) == 0 {
+		return nil, errors.New("no probes found")
+	}
+	return probes, nil
+}
+
+// readProbesFile parses the probes in the file at path.
+func readProbesFile(path string) ([]Probe, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseProbes(f)
+}
@@ -0,0 +1,76 @@
+package banner
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/zmap/zgrab2"
+)
+
+func TestParseProbes(t *testing.T) {
+	probes, err := parseProbes(strings.NewReader("# comment\n\nredis 50494e470d0a ^\\+PONG\n  any 0a\nsmtp 48454c4f20780d0a ^250 .* ok\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(probes) != 3 {
+		t.Fatalf("probes = %+v", probes)
+	}
+	if probes[0].Label != "redis" || string(probes[0].Payload) != "PING\r\n" || !probes[0].matches([]byte("+PONG\r\n")) {
+		t.Errorf("probe 0 = %+v", probes[0])
+	}
+	if probes[1].Pattern != nil || probes[1].matches(nil) || !probes[1].matches([]byte("x")) {
+		t.Errorf("probe 1 = %+v", probes[1])
+	}
+	if probes[2].Pattern.String() != "^250 .* ok" {
+		t.Errorf("probe 2 pattern = %s", probes[2].Pattern)
+	}
+	for _, invalid := range []string{"", "label", "label zz", "label 0a (", "label @/nonexistent"} {
+		if _, err := parseProbes(strings.NewReader(invalid)); err == nil {
+			t.Errorf("%q parsed", invalid)
+		}
+	}
+}
+
+func TestScanProbes(t *testing.T) {
+	probes, err := parseProbes(strings.NewReader("http 474554202f0d0a0d0a ^HTTP/\nredis 50494e470d0a ^\\+PONG\nany 0a\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanner := &Scanner{
+		config: &Flags{ReadTimeout: 100, BufferSize: 1024, MaxReadSize: 1, MaxTries: 1},
+		probes: probes,
+	}
+	dialGroup := &zgrab2.DialerGroup{TransportAgnosticDialer: func(ctx context.Context, target *zgrab2.ScanTarget) (net.Conn, error) {
+		client, server := net.Pipe()
+		go func() {
+			defer server.Close()
+			buf := make([]byte, 64)
+			n, err := server.Read(buf)
+			if err != nil {
+				return
+			}
+			if bytes.Equal(buf[:n], []byte("PING\r\n")) {
+				server.Write([]byte("+PONG\r\n"))
+			} else {
+				server.Write([]byte("-ERR unknown command\r\n"))
+			}
+		}()
+		return client, nil
+	}}
+
+	status, res, err := scanner.Scan(context.Background(), dialGroup, &zgrab2.ScanTarget{})
+	if err != nil || status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status = %s, err = %v", status, err)
+	}
+	results := res.(*Results)
+	if results.Label != "redis" || results.Banner != "+PONG\r\n" {
+		t.Errorf("results = %+v", results)
+	}
+	expected := []ProbeResult{{Label: "http", Length: 22}, {Label: "redis", Length: 7, Matched: true}}
+	if len(results.Probes) != len(expected) || results.Probes[0] != expected[0] || results.Probes[1] != expected[1] {
+		t.Errorf("probes = %+v", results.Probes)
+	}
+}
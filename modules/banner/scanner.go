@@ -1,5 +1,6 @@
 // Package banner provides simple banner grab and matching implementation of the zgrab2.Module.
-// It sends a customizble probe (default to "\n") and filters the results based on custom regexp (--pattern)
+// It sends a customizble probe (default to "\n") and filters the results based on custom regexp (--pattern).
+// With --probes-file, it instead tries a list of labeled probes in order until a response matches the probe's pattern.
 
 package banner
 
@@ -42,6 +43,7 @@ type Flags struct {
 	MD5         bool   `long:"md5" description:"Calculate MD5 hash of banner value."`
 	SHA1        bool   `long:"sha1" description:"Calculate SHA1 hash of banner value."`
 	SHA256      bool   `long:"sha256" description:"Calculate SHA256 hash of banner value."`
+	ProbesFile  string `long:"probes-file" description:"Read probes to try in order from file, one per line as '<label> <payload> [<pattern>]', where payload is hex or @<path>. The first probe whose response matches its pattern (or is non-empty, without a pattern) labels the result. Mutually exclusive with --probe, --probe-file and --pattern."`
 }
 
 // Module is the implementation of the zgrab2.Module interface.
@@ -53,6 +55,7 @@ type Scanner struct {
 	config            *Flags
 	regex             *regexp.Regexp
 	probe             []byte
+	probes            []Probe
 	dialerGroupConfig *zgrab2.DialerGroupConfig
 }
 
@@ -64,6 +67,10 @@ type Results struct {
 	MD5    string         `json:"md5,omitempty"`
 	SHA1   string         `json:"sha1,omitempty"`
 	SHA256 string         `json:"sha256,omitempty"`
+
+	// Label is the label of the matching probe from --probes-file.
+	Label  string        `json:"label,omitempty"`
+	Probes []ProbeResult `json:"probes,omitempty"`
 }
 
 var ErrNoMatch = errors.New("pattern did not match")
@@ -117,6 +124,10 @@ func (f *Flags) Validate(_ []string) error {
 		log.Fatal("Cannot set both --probe and --probe-file")
 		return zgrab2.ErrInvalidArguments
 	}
+	if f.ProbesFile != "" && (f.Probe != "\\n" || f.ProbeFile != "" || f.Pattern != "") {
+		log.Fatal("Cannot set --probes-file together with --probe, --probe-file or --pattern")
+		return zgrab2.ErrInvalidArguments
+	}
 	return nil
 }
 
@@ -143,7 +154,13 @@ func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
 	if scanner.config.Pattern != "" {
 		scanner.regex = regexp.MustCompile(scanner.config.Pattern)
 	}
-	if len(f.ProbeFile) != 0 {
+	if len(f.ProbesFile) != 0 {
+		scanner.probes, err = readProbesFile(f.ProbesFile)
+		if err != nil {
+			log.Fatalf("Failed to read probes file: %v", err)
+			return zgrab2.ErrInvalidArguments
+		}
+	} else if len(f.ProbeFile) != 0 {
 		scanner.probe, err = os.ReadFile(f.ProbeFile)
 		if err != nil {
 			log.Fatal("Failed to open probe file")
@@ -167,14 +184,12 @@ func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
 	return nil
 }
 
-func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+// dial connects to target, retrying up to --max-tries times.
+func (scanner *Scanner) dial(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (net.Conn, error) {
 	var (
-		conn    net.Conn
-		err     error
-		readErr error
-		results Results
+		conn net.Conn
+		err  error
 	)
-
 	for try := 0; try < scanner.config.MaxTries; try++ {
 		conn, err = dialGroup.Dial(ctx, target)
 		if err != nil {
@@ -183,21 +198,30 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 		break
 	}
 	if err != nil {
-		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("failed to connect to %v: %w", target.String(), err)
+		return nil, fmt.Errorf("failed to connect to %v: %w", target.String(), err)
 	}
-	defer func() {
-		// attempt to collect TLS Log
-		if tlsConn, ok := conn.(*zgrab2.TLSConnection); ok {
-			results.TLSLog = tlsConn.GetLog()
-		}
-		// cleanup our connection
-		zgrab2.CloseConnAndHandleError(conn)
-	}()
+	return conn, nil
+}
 
-	var data []byte
+// closeConn collects the TLS log of conn, if any, into results and closes it.
+func closeConn(conn net.Conn, results *Results) {
+	// attempt to collect TLS Log
+	if tlsConn, ok := conn.(*zgrab2.TLSConnection); ok {
+		results.TLSLog = tlsConn.GetLog()
+	}
+	// cleanup our connection
+	zgrab2.CloseConnAndHandleError(conn)
+}
 
+// exchange sends probe on conn and returns the response, retrying up to --max-tries times.
+func (scanner *Scanner) exchange(conn net.Conn, probe []byte) ([]byte, error) {
+	var (
+		data    []byte
+		err     error
+		readErr error
+	)
 	for try := 0; try < scanner.config.MaxTries; try++ {
-		_, err = conn.Write(scanner.probe)
+		_, err = conn.Write(probe)
 		data, readErr = zgrab2.ReadAvailableWithOptions(conn,
 			scanner.config.BufferSize,
 			time.Duration(scanner.config.ReadTimeout)*time.Millisecond,
@@ -212,12 +236,16 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 		break
 	}
 	if err != nil {
-		return zgrab2.TryGetScanStatus(err), nil, err
+		return nil, err
 	}
 	if readErr != io.EOF && readErr != nil {
-		return zgrab2.TryGetScanStatus(readErr), nil, readErr
+		return nil, readErr
 	}
+	return data, nil
+}
 
+// setBanner stores data and its hashes in results.
+func (scanner *Scanner) setBanner(results *Results, data []byte) {
 	if scanner.config.Hex {
 		results.Banner = hex.EncodeToString(data)
 	} else if scanner.config.Base64 {
@@ -241,6 +269,68 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 			results.SHA256 = hex.EncodeToString(digest[:])
 		}
 	}
+}
+
+// scanProbes sends each of the probes on a new connection until a response matches. If none does, the banner is the
+// last response received.
+func (scanner *Scanner) scanProbes(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	var (
+		results   Results
+		err       error
+		responded bool
+	)
+	for i := range scanner.probes {
+		probe := &scanner.probes[i]
+		conn, dialErr := scanner.dial(ctx, dialGroup, target)
+		if dialErr != nil {
+			if !responded {
+				return zgrab2.TryGetScanStatus(dialErr), nil, dialErr
+			}
+			err = dialErr
+			break
+		}
+		var data []byte
+		data, err = scanner.exchange(conn, probe.Payload)
+		closeConn(conn, &results)
+		result := ProbeResult{Label: probe.Label, Length: len(data)}
+		if err != nil {
+			result.Error = err.Error()
+			results.Probes = append(results.Probes, result)
+			continue
+		}
+		responded = true
+		scanner.setBanner(&results, data)
+		result.Matched = probe.matches(data)
+		results.Probes = append(results.Probes, result)
+		if result.Matched {
+			results.Label = probe.Label
+			return zgrab2.SCAN_SUCCESS, &results, nil
+		}
+	}
+	if !responded {
+		return zgrab2.TryGetScanStatus(err), &results, err
+	}
+	return zgrab2.SCAN_PROTOCOL_ERROR, &results, ErrNoMatch
+}
+
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	if len(scanner.probes) > 0 {
+		return scanner.scanProbes(ctx, dialGroup, target)
+	}
+
+	var results Results
+	conn, err := scanner.dial(ctx, dialGroup, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, err
+	}
+	defer closeConn(conn, &results)
+
+	data, err := scanner.exchange(conn, scanner.probe)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, err
+	}
+	scanner.setBanner(&results, data)
+
 	if scanner.regex == nil {
 		return zgrab2.SCAN_SUCCESS, &results, nil
 	}
@@ -0,0 +1,71 @@
+package beanstalkd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxResponseSize bounds the body of an OK response.
+const maxResponseSize = 1 << 20
+
+var errInvalidResponse = errors.New("invalid beanstalkd response")
+
+// readOK reads the response to a command. An OK response returns its body; any other response (e.g.
+// UNKNOWN_COMMAND) is returned as an error.
+func readOK(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if !strings.HasPrefix(line, "OK ") {
+		return nil, &responseError{line}
+	}
+	length, err := strconv.Atoi(line[3:])
+	if err != nil || length < 0 || length > maxResponseSize {
+		return nil, errInvalidResponse
+	}
+	body := make([]byte, length+2)
+	if _, err = io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body[:length], nil
+}
+
+// responseError is a response other than OK.
+type responseError struct {
+	response string
+}
+
+func (e *responseError) Error() string {
+	return fmt.Sprintf("unexpected response %q", e.response)
+}
+
+// parseDict parses the YAML dictionary of a stats response into its keys and values, in order.
+func parseDict(body []byte) [][2]string {
+	var entries [][2]string
+	for _, line := range strings.Split(string(body), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || line == "---" {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		entries = append(entries, [2]string{strings.TrimSpace(key), value})
+	}
+	return entries
+}
+
+// parseList parses the YAML list of a list-tubes response.
+func parseList(body []byte) []string {
+	var items []string
+	for _, line := range strings.Split(string(body), "\n") {
+		if item, ok := strings.CutPrefix(line, "- "); ok {
+			items = append(items, strings.Trim(strings.TrimSpace(item), `"`))
+		}
+	}
+	return items
+}
@@ -0,0 +1,32 @@
+package beanstalkd
+
+import (
+	"bufio"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadOK(t *testing.T) {
+	stats := "---\ncurrent-jobs-ready: 3\nversion: \"1.13\"\nuptime: 86400\n"
+	r := bufio.NewReader(strings.NewReader(fmt.Sprintf("OK %d\r\n%s\r\n", len(stats), stats)))
+	body, err := readOK(r)
+	if err != nil || string(body) != stats {
+		t.Fatalf("got %q (%v)", body, err)
+	}
+	expected := [][2]string{{"current-jobs-ready", "3"}, {"version", "1.13"}, {"uptime", "86400"}}
+	if entries := parseDict(body); !reflect.DeepEqual(entries, expected) {
+		t.Errorf("got %q, expected %q", entries, expected)
+	}
+
+	if _, err = readOK(bufio.NewReader(strings.NewReader("UNKNOWN_COMMAND\r\n"))); err == nil {
+		t.Error("expected error for UNKNOWN_COMMAND")
+	}
+}
+
+func TestParseList(t *testing.T) {
+	if tubes := parseList([]byte("---\n- default\n- emails\n")); !reflect.DeepEqual(tubes, []string{"default", "emails"}) {
+		t.Errorf("got %q", tubes)
+	}
+}
@@ -0,0 +1,198 @@
+// Package beanstalkd contains the zgrab2 Module implementation for beanstalkd work queues.
+//
+// beanstalkd has no authentication. The scan issues stats and list-tubes and records the server's version, uptime
+// and job counters, and the names of its tubes.
+package beanstalkd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	Version  string `json:"version,omitempty"`
+	Uptime   int64  `json:"uptime,omitempty"`
+	PID      int64  `json:"pid,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+	OS       string `json:"os,omitempty"`
+	Platform string `json:"platform,omitempty"`
+
+	// ID is the random ID the server generated at startup.
+	ID string `json:"id,omitempty"`
+
+	Draining           bool  `json:"draining"`
+	CurrentConnections int64 `json:"current_connections"`
+	CurrentJobsReady   int64 `json:"current_jobs_ready"`
+	CurrentJobsBuried  int64 `json:"current_jobs_buried"`
+	TotalJobs          int64 `json:"total_jobs"`
+
+	Tubes []string `json:"tubes,omitempty"`
+}
+
+// Flags are the beanstalkd-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the beanstalkd zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("beanstalkd", "beanstalkd", module.Description(), 11300, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Issue stats and list-tubes to a beanstalkd server"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "beanstalkd"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// setStats copies the stats the scan records into results.
+func (results *ScanResults) setStats(entries [][2]string) {
+	for _, entry := range entries {
+		number, _ := strconv.ParseInt(entry[1], 10, 64)
+		switch entry[0] {
+		case "version":
+			results.Version = entry[1]
+		case "uptime":
+			results.Uptime = number
+		case "pid":
+			results.PID = number
+		case "hostname":
+			results.Hostname = entry[1]
+		case "os":
+			results.OS = entry[1]
+		case "platform":
+			results.Platform = entry[1]
+		case "id":
+			results.ID = entry[1]
+		case "draining":
+			results.Draining = entry[1] == "true"
+		case "current-connections":
+			results.CurrentConnections = number
+		case "current-jobs-ready":
+			results.CurrentJobsReady = number
+		case "current-jobs-buried":
+			results.CurrentJobsBuried = number
+		case "total-jobs":
+			results.TotalJobs = number
+		}
+	}
+}
+
+// Scan performs the configured scan on the beanstalkd server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+	reader := bufio.NewReader(conn)
+
+	if _, err = conn.Write([]byte("stats\r\n")); err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error sending stats to target %s: %w", target.String(), err)
+	}
+	body, err := readOK(reader)
+	if err != nil {
+		var responseErr *responseError
+		if errors.As(err, &responseErr) || errors.Is(err, errInvalidResponse) {
+			err = zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+		}
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error reading stats from target %s: %w", target.String(), err)
+	}
+	results := new(ScanResults)
+	results.setStats(parseDict(body))
+	if results.Version == "" {
+		return zgrab2.SCAN_PROTOCOL_ERROR, nil, fmt.Errorf("target %s is not a beanstalkd server", target.String())
+	}
+
+	if _, err = conn.Write([]byte("list-tubes\r\n")); err != nil {
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error sending list-tubes to target %s: %w", target.String(), err)
+	}
+	if body, err = readOK(reader); err != nil {
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error reading list-tubes from target %s: %w", target.String(), err)
+	}
+	results.Tubes = parseList(body)
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
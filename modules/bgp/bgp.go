@@ -0,0 +1,285 @@
+package bgp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// Message types.
+const (
+	messageOpen         = 1
+	messageNotification = 3
+)
+
+// headerLength is the length of the marker, length and type; maxMessageLength is the largest message RFC 4271
+// allows (RFC 8654 extended messages are not negotiated).
+const (
+	headerLength     = 19
+	maxMessageLength = 4096
+)
+
+// asTrans is the two-octet AS number announced by speakers with a four-octet AS number (RFC 6793).
+const asTrans = 23456
+
+// Optional parameter and capability codes.
+const (
+	paramCapabilities = 2
+
+	capabilityMultiprotocol = 1
+	capabilityFourOctetAS   = 65
+	capabilityFQDN          = 73
+)
+
+var errInvalidMessage = errors.New("invalid BGP message")
+
+var capabilityNames = map[byte]string{
+	1:   "multiprotocol",
+	2:   "route_refresh",
+	3:   "outbound_route_filtering",
+	5:   "extended_next_hop",
+	6:   "extended_message",
+	7:   "bgpsec",
+	8:   "multiple_labels",
+	9:   "role",
+	64:  "graceful_restart",
+	65:  "four_octet_as",
+	67:  "dynamic_capability",
+	68:  "multisession",
+	69:  "add_path",
+	70:  "enhanced_route_refresh",
+	71:  "long_lived_graceful_restart",
+	73:  "fqdn",
+	75:  "software_version",
+	128: "route_refresh_cisco",
+	131: "multisession_cisco",
+}
+
+var afiNames = map[uint16]string{1: "ipv4", 2: "ipv6", 25: "l2vpn", 16388: "bgp_ls"}
+
+var safiNames = map[byte]string{
+	1: "unicast", 2: "multicast", 4: "labeled_unicast", 5: "mvpn", 65: "vpls", 70: "evpn", 71: "bgp_ls",
+	73: "sr_te_policy", 128: "vpn", 129: "vpn_multicast", 132: "rtc", 133: "flowspec", 134: "flowspec_vpn",
+}
+
+var errorCodeNames = map[byte]string{
+	1: "message_header_error",
+	2: "open_message_error",
+	3: "update_message_error",
+	4: "hold_timer_expired",
+	5: "finite_state_machine_error",
+	6: "cease",
+	7: "route_refresh_message_error",
+}
+
+var openSubcodeNames = map[byte]string{
+	1: "unsupported_version_number",
+	2: "bad_peer_as",
+	3: "bad_bgp_identifier",
+	4: "unsupported_optional_parameter",
+	6: "unacceptable_hold_time",
+	7: "unsupported_capability",
+	8: "role_mismatch",
+}
+
+var ceaseSubcodeNames = map[byte]string{
+	1:  "maximum_number_of_prefixes_reached",
+	2:  "administrative_shutdown",
+	3:  "peer_deconfigured",
+	4:  "administrative_reset",
+	5:  "connection_rejected",
+	6:  "other_configuration_change",
+	7:  "connection_collision_resolution",
+	8:  "out_of_resources",
+	9:  "hard_reset",
+	10: "bfd_down",
+}
+
+// frame prepends the marker, length and type to a message body.
+func frame(messageType byte, body []byte) []byte {
+	message := bytes.Repeat([]byte{0xFF}, 16)
+	message = binary.BigEndian.AppendUint16(message, uint16(headerLength+len(body)))
+	message = append(message, messageType)
+	return append(message, body...)
+}
+
+// buildOpen returns an OPEN announcing asn, the hold time and router ID, with the four-octet AS, multiprotocol
+// IPv4 and IPv6 unicast and route refresh capabilities.
+func buildOpen(asn uint32, holdTime uint16, routerID net.IP) []byte {
+	myAS := uint16(asTrans)
+	if asn <= 0xFFFF {
+		myAS = uint16(asn)
+	}
+	var capabilities []byte
+	capabilities = append(capabilities, capabilityMultiprotocol, 4, 0, 1, 0, 1)
+	capabilities = append(capabilities, capabilityMultiprotocol, 4, 0, 2, 0, 1)
+	capabilities = append(capabilities, 2, 0)
+	capabilities = append(capabilities, capabilityFourOctetAS, 4)
+	capabilities = binary.BigEndian.AppendUint32(capabilities, asn)
+
+	body := []byte{4}
+	body = binary.BigEndian.AppendUint16(body, myAS)
+	body = binary.BigEndian.AppendUint16(body, holdTime)
+	body = append(body, routerID.To4()...)
+	body = append(body, byte(2+len(capabilities)), paramCapabilities, byte(len(capabilities)))
+	body = append(body, capabilities...)
+	return frame(messageOpen, body)
+}
+
+// buildCease returns a NOTIFICATION closing the session with an administrative shutdown.
+func buildCease() []byte {
+	return frame(messageNotification, []byte{6, 2})
+}
+
+// readMessage reads a message and returns its type and body.
+func readMessage(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, headerLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	if !bytes.Equal(header[:16], bytes.Repeat([]byte{0xFF}, 16)) {
+		return 0, nil, errInvalidMessage
+	}
+	length := int(binary.BigEndian.Uint16(header[16:18]))
+	if length < headerLength || length > maxMessageLength {
+		return 0, nil, errInvalidMessage
+	}
+	body := make([]byte, length-headerLength)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header[18], body, nil
+}
+
+// Capability is a capability the peer announced.
+type Capability struct {
+	Code  byte   `json:"code"`
+	Name  string `json:"name,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+// Open is the peer's OPEN message.
+type Open struct {
+	Version  byte   `json:"version"`
+	MyAS     uint16 `json:"my_as"`
+	HoldTime uint16 `json:"hold_time"`
+	RouterID string `json:"router_id"`
+
+	// ASN is the peer's AS number: the four-octet AS from its capability, if announced, otherwise MyAS.
+	ASN uint32 `json:"asn"`
+
+	Capabilities []Capability `json:"capabilities,omitempty"`
+
+	// AddressFamilies are the AFI/SAFI pairs of the multiprotocol capabilities, e.g. ipv4_unicast.
+	AddressFamilies []string `json:"address_families,omitempty"`
+
+	// Hostname and DomainName are from the FQDN capability.
+	Hostname   string `json:"hostname,omitempty"`
+	DomainName string `json:"domain_name,omitempty"`
+}
+
+// parseOpen decodes the body of an OPEN message.
+func parseOpen(body []byte) (*Open, error) {
+	if len(body) < 10 || len(body) < 10+int(body[9]) {
+		return nil, errInvalidMessage
+	}
+	open := &Open{
+		Version:  body[0],
+		MyAS:     binary.BigEndian.Uint16(body[1:3]),
+		HoldTime: binary.BigEndian.Uint16(body[3:5]),
+		RouterID: net.IP(body[5:9]).String(),
+	}
+	open.ASN = uint32(open.MyAS)
+	params := body[10 : 10+int(body[9])]
+	for len(params) >= 2 {
+		paramType, paramLength := params[0], int(params[1])
+		if len(params) < 2+paramLength {
+			return open, errInvalidMessage
+		}
+		if paramType == paramCapabilities {
+			if err := open.parseCapabilities(params[2 : 2+paramLength]); err != nil {
+				return open, err
+			}
+		}
+		params = params[2+paramLength:]
+	}
+	return open, nil
+}
+
+// parseCapabilities decodes the value of a capabilities parameter.
+func (open *Open) parseCapabilities(data []byte) error {
+	for len(data) >= 2 {
+		code, length := data[0], int(data[1])
+		if len(data) < 2+length {
+			return errInvalidMessage
+		}
+		value := data[2 : 2+length]
+		open.Capabilities = append(open.Capabilities, Capability{Code: code, Name: capabilityNames[code], Value: hex.EncodeToString(value)})
+		switch {
+		case code == capabilityMultiprotocol && length == 4:
+			open.AddressFamilies = append(open.AddressFamilies, addressFamily(binary.BigEndian.Uint16(value[0:2]), value[3]))
+		case code == capabilityFourOctetAS && length == 4:
+			open.ASN = binary.BigEndian.Uint32(value)
+		case code == capabilityFQDN && length >= 1 && length >= 1+int(value[0]):
+			open.Hostname = string(value[1 : 1+value[0]])
+			if rest := value[1+value[0]:]; len(rest) >= 1 && len(rest) >= 1+int(rest[0]) {
+				open.DomainName = string(rest[1 : 1+rest[0]])
+			}
+		}
+		data = data[2+length:]
+	}
+	return nil
+}
+
+// addressFamily names an AFI/SAFI pair.
+func addressFamily(afi uint16, safi byte) string {
+	afiName, ok := afiNames[afi]
+	if !ok {
+		afiName = fmt.Sprintf("afi_%d", afi)
+	}
+	safiName, ok := safiNames[safi]
+	if !ok {
+		safiName = fmt.Sprintf("safi_%d", safi)
+	}
+	return afiName + "_" + safiName
+}
+
+// Notification is a NOTIFICATION message from the peer.
+type Notification struct {
+	Code        byte   `json:"code"`
+	CodeName    string `json:"code_name,omitempty"`
+	Subcode     byte   `json:"subcode"`
+	SubcodeName string `json:"subcode_name,omitempty"`
+	Data        string `json:"data,omitempty"`
+
+	// Message is the shutdown communication of an administrative shutdown or reset (RFC 9003).
+	Message string `json:"message,omitempty"`
+}
+
+// parseNotification decodes the body of a NOTIFICATION message.
+func parseNotification(body []byte) (*Notification, error) {
+	if len(body) < 2 {
+		return nil, errInvalidMessage
+	}
+	notification := &Notification{
+		Code:     body[0],
+		CodeName: errorCodeNames[body[0]],
+		Subcode:  body[1],
+		Data:     hex.EncodeToString(body[2:]),
+	}
+	switch notification.Code {
+	case 2:
+		notification.SubcodeName = openSubcodeNames[notification.Subcode]
+	case 6:
+		notification.SubcodeName = ceaseSubcodeNames[notification.Subcode]
+		data := body[2:]
+		if (notification.Subcode == 2 || notification.Subcode == 4) && len(data) >= 1 && len(data) >= 1+int(data[0]) {
+			notification.Message = string(data[1 : 1+data[0]])
+		}
+	}
+	return notification, nil
+}
@@ -0,0 +1,50 @@
+package bgp
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParseOpen(t *testing.T) {
+	messageType, body, err := readMessage(bytes.NewReader(buildOpen(4200000000, 90, net.ParseIP("192.0.2.1"))))
+	if err != nil || messageType != messageOpen {
+		t.Fatalf("unexpected message %d (%v)", messageType, err)
+	}
+	open, err := parseOpen(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if open.Version != 4 || open.MyAS != asTrans || open.ASN != 4200000000 || open.HoldTime != 90 || open.RouterID != "192.0.2.1" {
+		t.Errorf("unexpected OPEN %+v", open)
+	}
+	if !reflect.DeepEqual(open.AddressFamilies, []string{"ipv4_unicast", "ipv6_unicast"}) {
+		t.Errorf("got address families %q", open.AddressFamilies)
+	}
+	if len(open.Capabilities) != 4 || open.Capabilities[2].Name != "route_refresh" {
+		t.Errorf("unexpected capabilities %+v", open.Capabilities)
+	}
+
+	if _, _, err = readMessage(bytes.NewReader(append([]byte{0}, buildCease()[1:]...))); err != errInvalidMessage {
+		t.Errorf("expected invalid marker to fail, got %v", err)
+	}
+}
+
+func TestParseNotification(t *testing.T) {
+	notification, err := parseNotification([]byte{6, 2, 5, 'b', 'y', 'e', '!', '!'})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Notification{
+		Code:        6,
+		CodeName:    "cease",
+		Subcode:     2,
+		SubcodeName: "administrative_shutdown",
+		Data:        "056279652121",
+		Message:     "bye!!",
+	}
+	if !reflect.DeepEqual(notification, expected) {
+		t.Errorf("got %+v, expected %+v", notification, expected)
+	}
+}
@@ -0,0 +1,163 @@
+// Package bgp contains the zgrab2 Module implementation for BGP.
+//
+// The scan sends an OPEN with a configurable AS number and records the peer's answer: its own OPEN, with its AS
+// number, hold time, router ID and capabilities, or the NOTIFICATION it closes the session with, typically because
+// the scanner is not a configured neighbor. After receiving an OPEN, the scan closes the session with a Cease
+// NOTIFICATION.
+package bgp
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	Open         *Open         `json:"open,omitempty"`
+	Notification *Notification `json:"notification,omitempty"`
+}
+
+// Flags are the BGP-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+
+	ASN      uint32 `long:"asn" default:"64512" description:"AS number to announce in the OPEN"`
+	HoldTime uint16 `long:"hold-time" default:"90" description:"Hold time to announce in the OPEN, in seconds (0 or at least 3)"`
+	RouterID string `long:"router-id" default:"192.0.2.1" description:"BGP identifier (IPv4 address) to announce in the OPEN"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the bgp zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("bgp", "BGP", module.Description(), 179, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Send a BGP OPEN and record the peer's OPEN or NOTIFICATION"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	if ip := net.ParseIP(f.RouterID); ip == nil || ip.To4() == nil {
+		return fmt.Errorf("invalid router ID %q", f.RouterID)
+	}
+	if f.HoldTime == 1 || f.HoldTime == 2 {
+		return fmt.Errorf("hold time must be 0 or at least 3")
+	}
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "bgp"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// Scan performs the configured scan on the BGP speaker.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	open := buildOpen(scanner.config.ASN, scanner.config.HoldTime, net.ParseIP(scanner.config.RouterID))
+	if _, err = conn.Write(open); err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error sending OPEN to target %s: %w", target.String(), err)
+	}
+	messageType, body, err := readMessage(conn)
+	if err != nil {
+		if err == errInvalidMessage {
+			err = zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+		}
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error reading response from target %s: %w", target.String(), err)
+	}
+
+	results := new(ScanResults)
+	switch messageType {
+	case messageOpen:
+		if results.Open, err = parseOpen(body); err != nil {
+			return zgrab2.SCAN_PROTOCOL_ERROR, results, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+		}
+		if _, err = conn.Write(buildCease()); err != nil {
+			log.Debugf("error sending Cease to target %s: %v", target.String(), err)
+		}
+	case messageNotification:
+		if results.Notification, err = parseNotification(body); err != nil {
+			return zgrab2.SCAN_PROTOCOL_ERROR, nil, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+		}
+	default:
+		return zgrab2.SCAN_PROTOCOL_ERROR, nil, fmt.Errorf("unexpected message type %d from target %s", messageType, target.String())
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
@@ -0,0 +1,194 @@
+// Package bitcoin contains the zgrab2 Module implementation for the Bitcoin P2P protocol.
+//
+// The scan sends a version message, as a connecting peer would, and decodes the version message the node answers
+// with: protocol version, services, user agent and the height of its best block. It doesn't complete the handshake
+// with a verack.
+package bitcoin
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// maxMessages bounds the number of messages read while waiting for the node's version.
+const maxMessages = 4
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	ProtocolVersion int32    `json:"protocol_version"`
+	Services        uint64   `json:"services"`
+	ServiceNames    []string `json:"service_names,omitempty"`
+	UserAgent       string   `json:"user_agent"`
+	StartHeight     int32    `json:"start_height"`
+	Relay           *bool    `json:"relay,omitempty"`
+
+	// Timestamp is the node's current time.
+	Timestamp time.Time `json:"timestamp"`
+
+	// AddrRecv is the scanner's address as seen by the node.
+	AddrRecv string `json:"addr_recv,omitempty"`
+}
+
+// Flags are the Bitcoin-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+
+	Network   string `long:"network" default:"mainnet" description:"Network to connect to: mainnet, testnet3, testnet4, signet or regtest"`
+	UserAgent string `long:"user-agent" default:"/zgrab2/" description:"User agent to announce in the version message"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the bitcoin zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("bitcoin", "Bitcoin", module.Description(), 8333, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Send a Bitcoin version message and record the node's version"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	if _, ok := networkMagics[f.Network]; !ok {
+		return fmt.Errorf("unknown network %q", f.Network)
+	}
+	if len(f.UserAgent) >= 0xFD {
+		return fmt.Errorf("user agent must be shorter than %d bytes", 0xFD)
+	}
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "bitcoin"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// Scan performs the configured scan on the Bitcoin node.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	magic := networkMagics[scanner.config.Network]
+	ip := net.IPv6zero
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		ip = addr.IP
+	}
+	nonce := make([]byte, 8)
+	_, _ = rand.Read(nonce)
+	request := buildVersion(magic, ip, uint16(target.Port), scanner.config.UserAgent, binary.LittleEndian.Uint64(nonce), time.Now())
+	if _, err = conn.Write(request); err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error sending version to target %s: %w", target.String(), err)
+	}
+
+	var payload []byte
+	for i := 0; i < maxMessages && payload == nil; i++ {
+		command, body, err := readMessage(conn, magic)
+		if err != nil {
+			if err == errInvalidMessage {
+				err = zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+			}
+			return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error reading version from target %s: %w", target.String(), err)
+		}
+		if command == "version" {
+			payload = body
+		}
+	}
+	if payload == nil {
+		return zgrab2.SCAN_PROTOCOL_ERROR, nil, fmt.Errorf("target %s did not send a version message", target.String())
+	}
+	version, err := parseVersion(payload)
+	if version == nil {
+		return zgrab2.SCAN_PROTOCOL_ERROR, nil, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+	}
+	results := &ScanResults{
+		ProtocolVersion: version.ProtocolVersion,
+		Services:        version.Services,
+		ServiceNames:    services(version.Services),
+		UserAgent:       version.UserAgent,
+		StartHeight:     version.StartHeight,
+		Relay:           version.Relay,
+		Timestamp:       version.Timestamp,
+		AddrRecv:        version.AddrRecv,
+	}
+	if err != nil {
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
@@ -0,0 +1,178 @@
+package bitcoin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// networkMagics are the start strings of the supported networks.
+var networkMagics = map[string][4]byte{
+	"mainnet":  {0xF9, 0xBE, 0xB4, 0xD9},
+	"testnet3": {0x0B, 0x11, 0x09, 0x07},
+	"testnet4": {0x1C, 0x16, 0x3F, 0x28},
+	"signet":   {0x0A, 0x03, 0xCF, 0x40},
+	"regtest":  {0xFA, 0xBF, 0xB5, 0xDA},
+}
+
+// protocolVersion is the protocol version the scan announces (Bitcoin Core 0.21+).
+const protocolVersion = 70016
+
+// maxPayloadLength bounds the payload of a message from the node; a version message is much smaller.
+const maxPayloadLength = 1 << 16
+
+// maxUserAgentLength is the longest user agent Bitcoin Core accepts.
+const maxUserAgentLength = 256
+
+var errInvalidMessage = errors.New("invalid message")
+
+// serviceNames are the names of the service bits.
+var serviceNames = []struct {
+	bit  uint64
+	name string
+}{
+	{1 << 0, "NODE_NETWORK"},
+	{1 << 1, "NODE_GETUTXO"},
+	{1 << 2, "NODE_BLOOM"},
+	{1 << 3, "NODE_WITNESS"},
+	{1 << 4, "NODE_XTHIN"},
+	{1 << 6, "NODE_COMPACT_FILTERS"},
+	{1 << 10, "NODE_NETWORK_LIMITED"},
+	{1 << 11, "NODE_P2P_V2"},
+}
+
+// checksum returns the first four bytes of the double SHA-256 of payload.
+func checksum(payload []byte) []byte {
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	return second[:4]
+}
+
+// frame prepends the message header to a payload.
+func frame(magic [4]byte, command string, payload []byte) []byte {
+	message := append([]byte{}, magic[:]...)
+	name := make([]byte, 12)
+	copy(name, command)
+	message = append(message, name...)
+	message = binary.LittleEndian.AppendUint32(message, uint32(len(payload)))
+	message = append(message, checksum(payload)...)
+	return append(message, payload...)
+}
+
+// appendAddress appends a network address without timestamp: services, IPv6 (or IPv4-mapped) address and port.
+func appendAddress(b []byte, ip net.IP, port uint16) []byte {
+	b = binary.LittleEndian.AppendUint64(b, 0)
+	b = append(b, ip.To16()...)
+	return binary.BigEndian.AppendUint16(b, port)
+}
+
+// appendVarString appends a string prefixed with its CompactSize length.
+func appendVarString(b []byte, s string) []byte {
+	// the flag validation keeps user agents short enough for the single-byte form
+	return append(append(b, byte(len(s))), s...)
+}
+
+// buildVersion returns a version message to a node at ip and port, announcing no services and a start height of 0.
+func buildVersion(magic [4]byte, ip net.IP, port uint16, userAgent string, nonce uint64, now time.Time) []byte {
+	payload := binary.LittleEndian.AppendUint32(nil, protocolVersion)
+	payload = binary.LittleEndian.AppendUint64(payload, 0)
+	payload = binary.LittleEndian.AppendUint64(payload, uint64(now.Unix()))
+	payload = appendAddress(payload, ip, port)
+	payload = appendAddress(payload, net.IPv6zero, 0)
+	payload = binary.LittleEndian.AppendUint64(payload, nonce)
+	payload = appendVarString(payload, userAgent)
+	payload = binary.LittleEndian.AppendUint32(payload, 0)
+	payload = append(payload, 0) // don't relay transactions
+	return frame(magic, "version", payload)
+}
+
+// readMessage reads a message and returns its command and payload. A message for another network is an error.
+func readMessage(r io.Reader, magic [4]byte) (string, []byte, error) {
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", nil, err
+	}
+	if !bytes.Equal(header[:4], magic[:]) {
+		return "", nil, errInvalidMessage
+	}
+	command := string(bytes.TrimRight(header[4:16], "\x00"))
+	length := binary.LittleEndian.Uint32(header[16:20])
+	if length > maxPayloadLength {
+		return "", nil, errInvalidMessage
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return "", nil, err
+	}
+	if !bytes.Equal(checksum(payload), header[20:24]) {
+		return "", nil, errInvalidMessage
+	}
+	return command, payload, nil
+}
+
+// Version is the node's version message.
+type Version struct {
+	ProtocolVersion int32
+	Services        uint64
+	Timestamp       time.Time
+
+	// AddrRecv is the scanner's address as seen by the node.
+	AddrRecv string
+
+	UserAgent   string
+	StartHeight int32
+
+	// Relay is absent before protocol version 70001.
+	Relay *bool
+}
+
+// parseVersion decodes the payload of a version message.
+func parseVersion(payload []byte) (*Version, error) {
+	// version, services, timestamp, two addresses, nonce and at least the length of the user agent
+	if len(payload) < 4+8+8+26+26+8+1 {
+		return nil, errInvalidMessage
+	}
+	version := &Version{
+		ProtocolVersion: int32(binary.LittleEndian.Uint32(payload[0:4])),
+		Services:        binary.LittleEndian.Uint64(payload[4:12]),
+		Timestamp:       time.Unix(int64(binary.LittleEndian.Uint64(payload[12:20])), 0).UTC(),
+	}
+	addrRecv := payload[20:46]
+	version.AddrRecv = net.JoinHostPort(net.IP(addrRecv[8:24]).String(), strconv.Itoa(int(binary.BigEndian.Uint16(addrRecv[24:26]))))
+
+	// the user agent's CompactSize length takes one byte, or three for lengths from 0xFD
+	rest := payload[80:]
+	length := int(rest[0])
+	rest = rest[1:]
+	if length == 0xFD && len(rest) >= 2 {
+		length = int(binary.LittleEndian.Uint16(rest[0:2]))
+		rest = rest[2:]
+	}
+	if length > maxUserAgentLength || len(rest) < length+4 {
+		return version, errInvalidMessage
+	}
+	version.UserAgent = string(rest[:length])
+	rest = rest[length:]
+	version.StartHeight = int32(binary.LittleEndian.Uint32(rest[0:4]))
+	if len(rest) > 4 {
+		relay := rest[4] != 0
+		version.Relay = &relay
+	}
+	return version, nil
+}
+
+// services returns the names of the service bits set in services.
+func services(bits uint64) []string {
+	var names []string
+	for _, service := range serviceNames {
+		if bits&service.bit != 0 {
+			names = append(names, service.name)
+		}
+	}
+	return names
+}
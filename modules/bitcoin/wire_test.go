@@ -0,0 +1,57 @@
+package bitcoin
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestChecksum(t *testing.T) {
+	// the checksum of an empty payload, as in every verack
+	if got := hex.EncodeToString(checksum(nil)); got != "5df6e0e2" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	magic := networkMagics["mainnet"]
+	now := time.Unix(1700000000, 0).UTC()
+	message := buildVersion(magic, net.ParseIP("192.0.2.10"), 8333, "/Satoshi:27.0.0/", 42, now)
+	command, payload, err := readMessage(bytes.NewReader(message), magic)
+	if err != nil || command != "version" {
+		t.Fatalf("unexpected message %q (%v)", command, err)
+	}
+	version, err := parseVersion(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	relay := false
+	expected := &Version{
+		ProtocolVersion: protocolVersion,
+		Timestamp:       now,
+		AddrRecv:        "192.0.2.10:8333",
+		UserAgent:       "/Satoshi:27.0.0/",
+		Relay:           &relay,
+	}
+	if !reflect.DeepEqual(version, expected) {
+		t.Errorf("got %+v, expected %+v", version, expected)
+	}
+
+	if _, _, err = readMessage(bytes.NewReader(message), networkMagics["testnet3"]); err != errInvalidMessage {
+		t.Errorf("expected error for other network, got %v", err)
+	}
+	message[len(message)-1] ^= 1
+	if _, _, err = readMessage(bytes.NewReader(message), magic); err != errInvalidMessage {
+		t.Errorf("expected checksum error, got %v", err)
+	}
+}
+
+func TestServices(t *testing.T) {
+	names := services(1 | 8 | 1024)
+	if !reflect.DeepEqual(names, []string{"NODE_NETWORK", "NODE_WITNESS", "NODE_NETWORK_LIMITED"}) {
+		t.Errorf("got %q", names)
+	}
+}
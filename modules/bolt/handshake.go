@@ -0,0 +1,113 @@
+package bolt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// handshakeMagic starts the handshake, followed by four version proposals.
+var handshakeMagic = []byte{0x60, 0x60, 0xB0, 0x17}
+
+// manifestV1 is the proposal, and answer, of version negotiation by manifest, where the server lists the
+// versions it supports (Bolt 5.7).
+const manifestV1 = 0x000001FF
+
+// maxManifestVersions bounds the versions read from a manifest.
+const maxManifestVersions = 64
+
+// version is a version proposal or answer: a major and minor version, and the number of preceding minor versions
+// the proposal also covers.
+type version struct {
+	major, minor, span byte
+}
+
+func (v version) marshal() []byte {
+	return []byte{0, v.span, v.minor, v.major}
+}
+
+func parseVersion(b []byte) version {
+	return version{major: b[3], minor: b[2], span: b[1]}
+}
+
+func (v version) String() string {
+	s := strconv.Itoa(int(v.major)) + "." + strconv.Itoa(int(v.minor))
+	if v.span > 0 {
+		return strconv.Itoa(int(v.major)) + "." + strconv.Itoa(int(v.minor)-int(min(v.span, v.minor))) + "-" + s
+	}
+	return s
+}
+
+// atLeast reports whether v is at least major.minor.
+func (v version) atLeast(major, minor byte) bool {
+	return v.major > major || v.major == major && v.minor >= minor
+}
+
+// proposals are the versions the scan proposes: the manifest, 5.0 to 5.8, 4.2 to 4.4 and 3.
+var proposals = []uint32{manifestV1, 0x00080805, 0x00020404, 0x00000003}
+
+// handshake builds the handshake.
+func handshake() []byte {
+	b := append([]byte{}, handshakeMagic...)
+	for _, p := range proposals {
+		b = binary.BigEndian.AppendUint32(b, p)
+	}
+	return b
+}
+
+// readVarint reads a base 128 varint, least significant group first, as used by the manifest.
+func readVarint(r io.Reader) (uint64, error) {
+	var v uint64
+	b := make([]byte, 1)
+	for shift := 0; shift < 64; shift += 7 {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return 0, err
+		}
+		v |= uint64(b[0]&0x7F) << shift
+		if b[0]&0x80 == 0 {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: varint longer than 64 bits", errInvalidMessage)
+}
+
+// readManifest reads the versions and capabilities of a version 1 manifest.
+func readManifest(r io.Reader) ([]version, uint64, error) {
+	n, err := readVarint(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	if n > maxManifestVersions {
+		return nil, 0, fmt.Errorf("%w: manifest of %d versions", errInvalidMessage, n)
+	}
+	b := make([]byte, 4*n)
+	if _, err = io.ReadFull(r, b); err != nil {
+		return nil, 0, err
+	}
+	versions := make([]version, n)
+	for i := range versions {
+		versions[i] = parseVersion(b[4*i:])
+	}
+	capabilities, err := readVarint(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	return versions, capabilities, nil
+}
+
+// choose returns the highest version of the manifest that the scan can speak, i.e. from 3.0 to 5.x.
+func choose(versions []version) (version, bool) {
+	var best version
+	found := false
+	for _, v := range versions {
+		if v.major < 3 || v.major > 5 {
+			continue
+		}
+		v.span = 0
+		if !found || v.atLeast(best.major, best.minor) {
+			best, found = v, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,291 @@
+package bolt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// maxMessageLength bounds the messages the scan reads, and maxDepth the nesting of the values it decodes.
+const (
+	maxMessageLength = 1 << 20
+	maxDepth         = 16
+)
+
+var errInvalidMessage = errors.New("invalid Bolt message")
+
+// Message signatures.
+const (
+	msgHello    = 0x01
+	msgGoodbye  = 0x02
+	msgLogon    = 0x6A
+	msgSuccess  = 0x70
+	msgIgnored  = 0x7E
+	msgFailure  = 0x7F
+	msgRecord   = 0x71
+	chunkHeader = 2
+)
+
+// appendString appends a PackStream string.
+func appendString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 0x10:
+		b = append(b, 0x80|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xD0, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xD1)
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b = append(b, 0xD2)
+		b = binary.BigEndian.AppendUint32(b, uint32(n))
+	}
+	return append(b, s...)
+}
+
+// appendMap appends a PackStream map of strings and nested maps, with its keys sorted.
+func appendMap(b []byte, m map[string]any) []byte {
+	b = append(b, 0xA0|byte(len(m)))
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		b = appendString(b, key)
+		switch v := m[key].(type) {
+		case string:
+			b = appendString(b, v)
+		case map[string]any:
+			b = appendMap(b, v)
+		}
+	}
+	return b
+}
+
+// message builds a chunked message with the signature and the map as its only field, or no fields for a nil map.
+func message(signature byte, fields map[string]any) []byte {
+	body := []byte{0xB0, signature}
+	if fields != nil {
+		body[0] = 0xB1
+		body = appendMap(body, fields)
+	}
+	// the messages the scan sends fit in a single chunk
+	b := binary.BigEndian.AppendUint16(nil, uint16(len(body)))
+	b = append(b, body...)
+	return append(b, 0, 0)
+}
+
+// readMessage reads a chunked message, skipping NOOP chunks between messages.
+func readMessage(r io.Reader) ([]byte, error) {
+	var body []byte
+	header := make([]byte, chunkHeader)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, err
+		}
+		n := int(binary.BigEndian.Uint16(header))
+		if n == 0 {
+			if len(body) == 0 {
+				continue
+			}
+			return body, nil
+		}
+		if len(body)+n > maxMessageLength {
+			return nil, fmt.Errorf("%w: message of more than %d bytes", errInvalidMessage, maxMessageLength)
+		}
+		chunk := make([]byte, n)
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return nil, err
+		}
+		body = append(body, chunk...)
+	}
+}
+
+// structure is a decoded PackStream structure.
+type structure struct {
+	signature byte
+	fields    []any
+}
+
+// decoder decodes PackStream values.
+type decoder struct {
+	b []byte
+}
+
+func (d *decoder) take(n int) ([]byte, error) {
+	if n < 0 || len(d.b) < n {
+		return nil, fmt.Errorf("%w: %d bytes left, %d needed", errInvalidMessage, len(d.b), n)
+	}
+	v := d.b[:n]
+	d.b = d.b[n:]
+	return v, nil
+}
+
+// size reads a size of the given number of bytes.
+func (d *decoder) size(bytes int) (int, error) {
+	b, err := d.take(bytes)
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	if n > uint64(len(d.b)) {
+		return 0, fmt.Errorf("%w: size %d with %d bytes left", errInvalidMessage, n, len(d.b))
+	}
+	return int(n), nil
+}
+
+// value decodes the next value: nil, bool, int64, float64, string, []byte, []any, map[string]any or *structure.
+func (d *decoder) value(depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("%w: values nested deeper than %d", errInvalidMessage, maxDepth)
+	}
+	b, err := d.take(1)
+	if err != nil {
+		return nil, err
+	}
+	marker := b[0]
+	switch {
+	case marker < 0x80:
+		return int64(marker), nil
+	case marker >= 0xF0:
+		return int64(int8(marker)), nil
+	case marker&0xF0 == 0x80:
+		return d.string(int(marker & 0x0F))
+	case marker&0xF0 == 0x90:
+		return d.list(int(marker&0x0F), depth)
+	case marker&0xF0 == 0xA0:
+		return d.dictionary(int(marker&0x0F), depth)
+	case marker&0xF0 == 0xB0:
+		return d.structure(int(marker&0x0F), depth)
+	}
+	switch marker {
+	case 0xC0:
+		return nil, nil
+	case 0xC1:
+		b, err := d.take(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case 0xC2, 0xC3:
+		return marker == 0xC3, nil
+	case 0xC8, 0xC9, 0xCA, 0xCB:
+		b, err := d.take(1 << (marker - 0xC8))
+		if err != nil {
+			return nil, err
+		}
+		var n int64
+		for i, c := range b {
+			if i == 0 {
+				n = int64(int8(c))
+			} else {
+				n = n<<8 | int64(c)
+			}
+		}
+		return n, nil
+	case 0xCC, 0xCD, 0xCE:
+		n, err := d.size(1 << (marker - 0xCC))
+		if err != nil {
+			return nil, err
+		}
+		return d.take(n)
+	case 0xD0, 0xD1, 0xD2:
+		n, err := d.size(1 << (marker - 0xD0))
+		if err != nil {
+			return nil, err
+		}
+		return d.string(n)
+	case 0xD4, 0xD5, 0xD6:
+		n, err := d.size(1 << (marker - 0xD4))
+		if err != nil {
+			return nil, err
+		}
+		return d.list(n, depth)
+	case 0xD8, 0xD9, 0xDA:
+		n, err := d.size(1 << (marker - 0xD8))
+		if err != nil {
+			return nil, err
+		}
+		return d.dictionary(n, depth)
+	}
+	return nil, fmt.Errorf("%w: marker %#02x", errInvalidMessage, marker)
+}
+
+func (d *decoder) string(n int) (string, error) {
+	b, err := d.take(n)
+	return string(b), err
+}
+
+func (d *decoder) list(n int, depth int) ([]any, error) {
+	// every value takes at least a byte
+	if n > len(d.b) {
+		return nil, fmt.Errorf("%w: list of %d values in %d bytes", errInvalidMessage, n, len(d.b))
+	}
+	values := make([]any, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func (d *decoder) dictionary(n int, depth int) (map[string]any, error) {
+	if n > len(d.b)/2 {
+		return nil, fmt.Errorf("%w: map of %d entries in %d bytes", errInvalidMessage, n, len(d.b))
+	}
+	m := make(map[string]any, n)
+	for i := 0; i < n; i++ {
+		key, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		k, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: map key of type %T", errInvalidMessage, key)
+		}
+		if m[k], err = d.value(depth + 1); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (d *decoder) structure(n int, depth int) (*structure, error) {
+	b, err := d.take(1)
+	if err != nil {
+		return nil, err
+	}
+	s := &structure{signature: b[0]}
+	if s.fields, err = d.list(n, depth); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// parseMessage decodes a response message: its signature and its metadata map, if it has one.
+func parseMessage(b []byte) (byte, map[string]any, error) {
+	d := &decoder{b: b}
+	v, err := d.value(0)
+	if err != nil {
+		return 0, nil, err
+	}
+	s, ok := v.(*structure)
+	if !ok {
+		return 0, nil, fmt.Errorf("%w: message of type %T", errInvalidMessage, v)
+	}
+	var metadata map[string]any
+	if len(s.fields) > 0 {
+		metadata, _ = s.fields[0].(map[string]any)
+	}
+	return s.signature, metadata, nil
+}
@@ -0,0 +1,127 @@
+package bolt
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestMessage(t *testing.T) {
+	b := message(msgHello, map[string]any{"user_agent": "zgrab2", "scheme": "none"})
+	expected := []byte{0x00, 0x21, 0xB1, 0x01, 0xA2,
+		0x86, 's', 'c', 'h', 'e', 'm', 'e', 0x84, 'n', 'o', 'n', 'e',
+		0x8A, 'u', 's', 'e', 'r', '_', 'a', 'g', 'e', 'n', 't', 0x86, 'z', 'g', 'r', 'a', 'b', '2',
+		0x00, 0x00}
+	if !bytes.Equal(b, expected) {
+		t.Errorf("got %x", b)
+	}
+	body, err := readMessage(bytes.NewReader(append([]byte{0, 0}, b...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, metadata, err := parseMessage(body)
+	if err != nil || signature != msgHello || !reflect.DeepEqual(metadata, map[string]any{"user_agent": "zgrab2", "scheme": "none"}) {
+		t.Errorf("got %#02x, %v, %v", signature, metadata, err)
+	}
+}
+
+func TestDecodeValues(t *testing.T) {
+	b := []byte{0x96,
+		0xC0, 0xC3, 0xF0, 0xC9, 0x01, 0x00, 0xCA, 0xFF, 0xFF, 0xFF, 0xFE,
+		0xD0, 0x11, 'n', 'e', 'o', '4', 'j', '/', '5', '.', '2', '6', '.', '0', '-', 'b', 'e', 't', 'a'}
+	v, err := (&decoder{b: b}).value(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []any{nil, true, int64(-16), int64(256), int64(-2), "neo4j/5.26.0-beta"}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("got %#v", v)
+	}
+	if _, err = (&decoder{b: []byte{0xD1, 0xFF, 0xFF, 'a'}}).value(0); !errors.Is(err, errInvalidMessage) {
+		t.Errorf("got %v for a truncated string", err)
+	}
+	if _, err = (&decoder{b: bytes.Repeat([]byte{0x91}, 32)}).value(0); !errors.Is(err, errInvalidMessage) {
+		t.Errorf("got %v for deeply nested lists", err)
+	}
+}
+
+func TestVersions(t *testing.T) {
+	for v, expected := range map[version]string{
+		{major: 5, minor: 8, span: 8}: "5.0-5.8",
+		{major: 4, minor: 4, span: 2}: "4.2-4.4",
+		{major: 4, minor: 4}:          "4.4",
+	} {
+		if s := v.String(); s != expected {
+			t.Errorf("got %s for %s", s, expected)
+		}
+	}
+	best, ok := choose([]version{{major: 4, minor: 4, span: 2}, {major: 5, minor: 8, span: 8}, {major: 6, minor: 0}})
+	if !ok || best != (version{major: 5, minor: 8}) {
+		t.Errorf("chose %v", best)
+	}
+}
+
+// server answers the handshake with a manifest and accepts HELLO and LOGON, or, with an old version, refuses the
+// "none" scheme in HELLO.
+func server(t *testing.T, conn net.Conn, manifest bool) {
+	defer conn.Close()
+	handshake := make([]byte, 20)
+	if _, err := conn.Read(handshake); err != nil {
+		return
+	}
+	if !manifest {
+		conn.Write([]byte{0, 0, 4, 4})
+		readMessage(conn)
+		failure := []byte{0xB1, msgFailure, 0xA1, 0x84, 'c', 'o', 'd', 'e'}
+		failure = appendString(failure, "Neo.ClientError.Security.Unauthorized")
+		conn.Write(append(append([]byte{0, byte(len(failure))}, failure...), 0, 0))
+		return
+	}
+	conn.Write([]byte{0, 0, 1, 0xFF, 2, 0, 8, 8, 5, 0, 2, 4, 4, 1})
+	choice := make([]byte, 5)
+	if _, err := conn.Read(choice); err != nil || !bytes.Equal(choice, []byte{0, 0, 8, 5, 0}) {
+		t.Errorf("got choice %x, %v", choice, err)
+		return
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := readMessage(conn); err != nil {
+			return
+		}
+		success := []byte{0xB1, msgSuccess, 0xA0}
+		if i == 0 {
+			success = []byte{0xB1, msgSuccess, 0xA2}
+			success = appendString(appendString(success, "server"), "Neo4j/5.26.0")
+			success = appendString(appendString(success, "connection_id"), "bolt-42")
+		}
+		conn.Write(append(append([]byte{0, byte(len(success))}, success...), 0, 0))
+	}
+}
+
+func TestHello(t *testing.T) {
+	scanner := &Scanner{config: &Flags{UserAgent: "zgrab2"}}
+	for _, manifest := range []bool{true, false} {
+		client, serverConn := net.Pipe()
+		go server(t, serverConn, manifest)
+		results := new(ScanResults)
+		v, ok, err := negotiate(client, results)
+		if err != nil || !ok {
+			t.Fatalf("got %v, %v", ok, err)
+		}
+		if err = scanner.hello(client, v, results); err != nil {
+			t.Fatal(err)
+		}
+		client.Close()
+		if manifest {
+			if v.String() != "5.8" || !reflect.DeepEqual(results.ManifestVersions, []string{"5.0-5.8", "4.2-4.4"}) || results.ManifestCapabilities != 1 {
+				t.Errorf("got version %s, %+v", v, results)
+			}
+			if results.Server != "Neo4j/5.26.0" || results.ConnectionID != "bolt-42" || results.AuthRequired {
+				t.Errorf("got %+v", results)
+			}
+		} else if v.String() != "4.4" || !results.AuthRequired || results.Failure.Code != "Neo.ClientError.Security.Unauthorized" {
+			t.Errorf("got version %s, %+v", v, results)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+// Package bolt contains the zgrab2 Module implementation for the Neo4j Bolt protocol.
+//
+// The scan proposes Bolt versions from 3.0 to 5.8 and, for servers from Neo4j 5.23 on, version negotiation by
+// manifest, in which the server lists every version it supports. It then sends HELLO without credentials and
+// records the server agent and connection ID. Up to Bolt 5.0, HELLO carries the "none" authentication scheme;
+// from Bolt 5.1 on, authentication is a separate LOGON, which the scan sends with the "none" scheme. Either way,
+// a security failure means the server requires authentication. The scan ends with GOODBYE.
+package bolt
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// Version is the version the server chose; it is empty if the server supports none of the proposals.
+	Version string `json:"version,omitempty"`
+
+	// ManifestVersions are the versions, or ranges of versions, the server listed in its manifest, and
+	// ManifestCapabilities the capabilities it announced.
+	ManifestVersions     []string `json:"manifest_versions,omitempty"`
+	ManifestCapabilities uint64   `json:"manifest_capabilities,omitempty"`
+
+	// Server is the server agent, e.g. Neo4j/5.26.0, and ConnectionID the ID of the connection on the server.
+	Server       string `json:"server,omitempty"`
+	ConnectionID string `json:"connection_id,omitempty"`
+
+	// AuthRequired is true if the server refused the "none" authentication scheme.
+	AuthRequired bool     `json:"auth_required"`
+	Failure      *Failure `json:"failure,omitempty"`
+
+	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
+}
+
+// Failure is a FAILURE message.
+type Failure struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// Flags are the Bolt-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.TLSFlags
+
+	UseTLS    bool   `long:"tls" description:"Connect over TLS, as servers with encryption enabled require"`
+	UserAgent string `long:"user-agent" default:"zgrab2" description:"User agent sent in HELLO"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the bolt zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("bolt", "Neo4j Bolt", module.Description(), 7687, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Negotiate a Bolt version with a Neo4j server and record its agent and whether it requires authentication"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "bolt"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	if f.UseTLS {
+		scanner.dialerGroupConfig.TLSEnabled = true
+		scanner.dialerGroupConfig.TLSFlags = &f.TLSFlags
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// negotiate performs the handshake and returns the version in use, and false if the server supports none of the
+// proposals.
+func negotiate(conn net.Conn, results *ScanResults) (version, bool, error) {
+	if _, err := conn.Write(handshake()); err != nil {
+		return version{}, false, err
+	}
+	answer := make([]byte, 4)
+	if _, err := io.ReadFull(conn, answer); err != nil {
+		return version{}, false, err
+	}
+	switch binary.BigEndian.Uint32(answer) {
+	case 0:
+		return version{}, false, nil
+	case manifestV1:
+		// the server lists its versions below
+	default:
+		v := parseVersion(answer)
+		if answer[0] != 0 || v.span != 0 || v.major < 3 || v.major > 5 {
+			return version{}, false, fmt.Errorf("%w: version %x is not one of the proposals", errInvalidMessage, answer)
+		}
+		return v, true, nil
+	}
+	versions, capabilities, err := readManifest(conn)
+	if err != nil {
+		return version{}, false, err
+	}
+	for _, v := range versions {
+		results.ManifestVersions = append(results.ManifestVersions, v.String())
+	}
+	results.ManifestCapabilities = capabilities
+	v, ok := choose(versions)
+	if !ok {
+		// the server waits for a choice, and a version of zero gives up
+		_, err = conn.Write([]byte{0, 0, 0, 0, 0})
+		return version{}, false, err
+	}
+	// the chosen version, and no capabilities
+	_, err = conn.Write(append(v.marshal(), 0))
+	return v, true, err
+}
+
+// exchange sends a message and returns the signature and metadata of the response.
+func exchange(conn net.Conn, msg []byte) (byte, map[string]any, error) {
+	if _, err := conn.Write(msg); err != nil {
+		return 0, nil, err
+	}
+	b, err := readMessage(conn)
+	if err != nil {
+		return 0, nil, err
+	}
+	return parseMessage(b)
+}
+
+// failure records a FAILURE response. Servers from Bolt 5.7 on may send the Neo4j code as neo4j_code.
+func (results *ScanResults) failure(metadata map[string]any) {
+	f := new(Failure)
+	f.Code, _ = metadata["code"].(string)
+	if f.Code == "" {
+		f.Code, _ = metadata["neo4j_code"].(string)
+	}
+	f.Message, _ = metadata["message"].(string)
+	results.Failure = f
+	results.AuthRequired = strings.HasPrefix(f.Code, "Neo.ClientError.Security.")
+}
+
+// hello sends HELLO, and LOGON from Bolt 5.1 on, both with the "none" authentication scheme.
+func (scanner *Scanner) hello(conn net.Conn, v version, results *ScanResults) error {
+	fields := map[string]any{"user_agent": scanner.config.UserAgent}
+	if !v.atLeast(5, 1) {
+		fields["scheme"] = "none"
+	}
+	if v.atLeast(5, 3) {
+		fields["bolt_agent"] = map[string]any{"product": scanner.config.UserAgent}
+	}
+	signature, metadata, err := exchange(conn, message(msgHello, fields))
+	if err != nil {
+		return err
+	}
+	switch signature {
+	case msgSuccess:
+		results.Server, _ = metadata["server"].(string)
+		results.ConnectionID, _ = metadata["connection_id"].(string)
+	case msgFailure:
+		results.failure(metadata)
+		return nil
+	default:
+		return fmt.Errorf("%w: message %#02x in answer to HELLO", errInvalidMessage, signature)
+	}
+	if !v.atLeast(5, 1) {
+		return nil
+	}
+
+	signature, metadata, err = exchange(conn, message(msgLogon, map[string]any{"scheme": "none"}))
+	if err != nil {
+		return err
+	}
+	switch signature {
+	case msgSuccess:
+	case msgFailure:
+		results.failure(metadata)
+	default:
+		return fmt.Errorf("%w: message %#02x in answer to LOGON", errInvalidMessage, signature)
+	}
+	return nil
+}
+
+// Scan negotiates a version and sends HELLO.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	results := new(ScanResults)
+	if tlsConn, ok := conn.(*zgrab2.TLSConnection); ok {
+		results.TLSLog = tlsConn.GetLog()
+	}
+	v, ok, err := negotiate(conn, results)
+	if err != nil {
+		return status(err), nil, fmt.Errorf("error negotiating version with target %s: %w", target.String(), err)
+	}
+	if !ok {
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+	results.Version = v.String()
+
+	if err = scanner.hello(conn, v, results); err != nil {
+		return status(err), results, fmt.Errorf("error sending HELLO to target %s: %w", target.String(), err)
+	}
+	if results.Failure == nil {
+		if _, err = conn.Write(message(msgGoodbye, nil)); err != nil {
+			log.Debugf("error sending GOODBYE to target %s: %v", target.String(), err)
+		}
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
+
+// status maps an error to a scan status, treating malformed and truncated messages as protocol errors.
+func status(err error) zgrab2.ScanStatus {
+	if errors.Is(err, errInvalidMessage) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return zgrab2.SCAN_PROTOCOL_ERROR
+	}
+	return zgrab2.TryGetScanStatus(err)
+}
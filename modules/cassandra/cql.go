@@ -0,0 +1,186 @@
+package cassandra
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// CQL native protocol opcodes
+const (
+	opError        = 0x00
+	opStartup      = 0x01
+	opReady        = 0x02
+	opAuthenticate = 0x03
+	opOptions      = 0x05
+	opSupported    = 0x06
+)
+
+// responseFlag is set in the version byte of responses.
+const responseFlag = 0x80
+
+// maxFrameBody bounds the body length of a response frame.
+const maxFrameBody = 256 * 1024
+
+// errorCodeNames are the CQL error codes (native protocol spec section 9).
+var errorCodeNames = map[int32]string{
+	0x0000: "server_error",
+	0x000A: "protocol_error",
+	0x0100: "bad_credentials",
+	0x1000: "unavailable",
+	0x1001: "overloaded",
+	0x1002: "is_bootstrapping",
+	0x1003: "truncate_error",
+	0x2000: "syntax_error",
+	0x2100: "unauthorized",
+	0x2200: "invalid",
+	0x2300: "config_error",
+}
+
+var errInvalidFrame = errors.New("invalid CQL frame")
+
+// frame is a CQL frame (protocol versions 3 to 5, before any v5 framing is negotiated).
+type frame struct {
+	version byte
+	flags   byte
+	stream  int16
+	opcode  byte
+	body    []byte
+}
+
+// encode returns the wire format of the frame.
+func (f *frame) encode() []byte {
+	out := []byte{f.version, f.flags}
+	out = binary.BigEndian.AppendUint16(out, uint16(f.stream))
+	out = append(out, f.opcode)
+	out = binary.BigEndian.AppendUint32(out, uint32(len(f.body)))
+	return append(out, f.body...)
+}
+
+// readFrame reads a response frame.
+func readFrame(r io.Reader) (*frame, error) {
+	header := make([]byte, 9)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	f := &frame{
+		version: header[0],
+		flags:   header[1],
+		stream:  int16(binary.BigEndian.Uint16(header[2:4])),
+		opcode:  header[4],
+	}
+	if f.version&responseFlag == 0 {
+		return nil, fmt.Errorf("%w: version byte %#x is not a response", errInvalidFrame, f.version)
+	}
+	length := binary.BigEndian.Uint32(header[5:9])
+	if length > maxFrameBody {
+		return nil, fmt.Errorf("%w: body length %d exceeds maximum", errInvalidFrame, length)
+	}
+	f.body = make([]byte, length)
+	if _, err := io.ReadFull(r, f.body); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// appendString appends a [string]: a short length followed by the bytes.
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// encodeStringMap returns a [string map] body.
+func encodeStringMap(entries [][2]string) []byte {
+	body := binary.BigEndian.AppendUint16(nil, uint16(len(entries)))
+	for _, entry := range entries {
+		body = appendString(body, entry[0])
+		body = appendString(body, entry[1])
+	}
+	return body
+}
+
+// reader decodes the notation types of frame bodies.
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) short() uint16 {
+	if r.err != nil || len(r.data) < 2 {
+		r.err = errInvalidFrame
+		return 0
+	}
+	v := binary.BigEndian.Uint16(r.data)
+	r.data = r.data[2:]
+	return v
+}
+
+func (r *reader) int() int32 {
+	if r.err != nil || len(r.data) < 4 {
+		r.err = errInvalidFrame
+		return 0
+	}
+	v := int32(binary.BigEndian.Uint32(r.data))
+	r.data = r.data[4:]
+	return v
+}
+
+func (r *reader) string() string {
+	n := int(r.short())
+	if r.err != nil || len(r.data) < n {
+		r.err = errInvalidFrame
+		return ""
+	}
+	s := string(r.data[:n])
+	r.data = r.data[n:]
+	return s
+}
+
+func (r *reader) stringList() []string {
+	n := int(r.short())
+	var list []string
+	for i := 0; i < n && r.err == nil; i++ {
+		list = append(list, r.string())
+	}
+	return list
+}
+
+// Option is an entry of the SUPPORTED string multimap.
+type Option struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// parseSupported decodes the [string multimap] body of a SUPPORTED response.
+func parseSupported(body []byte) ([]Option, error) {
+	r := &reader{data: body}
+	n := int(r.short())
+	var options []Option
+	for i := 0; i < n && r.err == nil; i++ {
+		options = append(options, Option{Name: r.string(), Values: r.stringList()})
+	}
+	return options, r.err
+}
+
+// Error is an ERROR response.
+type Error struct {
+	Code    int32  `json:"code"`
+	Name    string `json:"name,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// parseError decodes the body of an ERROR response.
+func parseError(body []byte) (*Error, error) {
+	r := &reader{data: body}
+	e := &Error{Code: r.int(), Message: r.string()}
+	e.Name = errorCodeNames[e.Code]
+	return e, r.err
+}
+
+// parseAuthenticate decodes the authenticator class of an AUTHENTICATE response.
+func parseAuthenticate(body []byte) (string, error) {
+	r := &reader{data: body}
+	authenticator := r.string()
+	return authenticator, r.err
+}
@@ -0,0 +1,52 @@
+package cassandra
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	request := &frame{version: 4, stream: 1, opcode: opStartup, body: encodeStringMap([][2]string{{"CQL_VERSION", "3.0.0"}})}
+	encoded := request.encode()
+	if want := []byte{4, 0, 0, 1, opStartup, 0, 0, 0, 22}; !bytes.Equal(encoded[:9], want) {
+		t.Errorf("got header %x, want %x", encoded[:9], want)
+	}
+	if _, err := readFrame(bytes.NewReader(encoded)); err == nil {
+		t.Error("expected error for a request frame")
+	}
+	encoded[0] |= responseFlag
+	response, err := readFrame(bytes.NewReader(encoded))
+	if err != nil || response.opcode != opStartup || !bytes.Equal(response.body, request.body) {
+		t.Errorf("got %+v (%v)", response, err)
+	}
+}
+
+func TestParseSupported(t *testing.T) {
+	body := []byte{0, 2}
+	body = appendString(body, "COMPRESSION")
+	body = append(body, 0, 2)
+	body = appendString(appendString(body, "snappy"), "lz4")
+	body = appendString(body, "CQL_VERSION")
+	body = append(body, 0, 1)
+	body = appendString(body, "3.4.5")
+	options, err := parseSupported(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Option{{Name: "COMPRESSION", Values: []string{"snappy", "lz4"}}, {Name: "CQL_VERSION", Values: []string{"3.4.5"}}}
+	if !reflect.DeepEqual(options, want) {
+		t.Errorf("got %+v", options)
+	}
+	if _, err = parseSupported(body[:len(body)-1]); err == nil {
+		t.Error("expected error for truncated body")
+	}
+}
+
+func TestParseError(t *testing.T) {
+	body := appendString([]byte{0, 0, 0, 0x0A}, "Invalid or unsupported protocol version (4); supported versions are (3/v3)")
+	e, err := parseError(body)
+	if err != nil || e.Code != 0x0A || e.Name != "protocol_error" {
+		t.Errorf("got %+v (%v)", e, err)
+	}
+}
@@ -0,0 +1,234 @@
+// Package cassandra contains the zgrab2 Module implementation for the Cassandra CQL native protocol.
+//
+// The scan sends OPTIONS to learn the supported CQL and protocol versions and compression algorithms, then STARTUP
+// without credentials: the server answers READY if authentication is disabled, or AUTHENTICATE with the class of
+// the configured authenticator. ScyllaDB and other servers speaking the protocol answer the same way.
+package cassandra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// ProtocolVersion is the native protocol version the scan spoke.
+	ProtocolVersion int `json:"protocol_version"`
+
+	// ProtocolVersions are the versions the server lists as supported (Cassandra 4.0+).
+	ProtocolVersions []string `json:"protocol_versions,omitempty"`
+
+	CQLVersions []string `json:"cql_versions,omitempty"`
+	Compression []string `json:"compression,omitempty"`
+
+	// Supported is the whole SUPPORTED response.
+	Supported []Option `json:"supported,omitempty"`
+
+	// AuthRequired is true if the server answered STARTUP with AUTHENTICATE.
+	AuthRequired bool `json:"auth_required"`
+
+	// Authenticator is the class of the server's authenticator, e.g. org.apache.cassandra.auth.PasswordAuthenticator.
+	Authenticator string `json:"authenticator,omitempty"`
+
+	Error *Error `json:"error,omitempty"`
+
+	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
+}
+
+// Flags are the Cassandra-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.TLSFlags
+
+	ProtocolVersion int  `long:"protocol-version" default:"4" description:"Native protocol version to speak (3 or 4); 4 falls back to 3 if unsupported"`
+	UseTLS          bool `long:"tls" description:"Connect over TLS (client encryption)"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the cassandra zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("cassandra", "Cassandra CQL native protocol", module.Description(), 9042, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Query the supported versions and options of a CQL server and whether it requires authentication"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	if f.ProtocolVersion != 3 && f.ProtocolVersion != 4 {
+		return fmt.Errorf("unsupported protocol version %d", f.ProtocolVersion)
+	}
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "cassandra"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	if f.UseTLS {
+		scanner.dialerGroupConfig.TLSEnabled = true
+		scanner.dialerGroupConfig.TLSFlags = &f.TLSFlags
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// exchange sends a request frame and reads the response.
+func exchange(conn net.Conn, version int, stream int16, opcode byte, body []byte) (*frame, error) {
+	request := &frame{version: byte(version), stream: stream, opcode: opcode, body: body}
+	if _, err := conn.Write(request.encode()); err != nil {
+		return nil, err
+	}
+	response, err := readFrame(conn)
+	if errors.Is(err, errInvalidFrame) {
+		return nil, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+	}
+	return response, err
+}
+
+// Scan performs the configured scan on the CQL server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	results := new(ScanResults)
+	var conn net.Conn
+	var response *frame
+	for version := scanner.config.ProtocolVersion; version >= 3; version-- {
+		var err error
+		if conn, err = dialGroup.Dial(ctx, target); err != nil {
+			return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+		}
+		if tlsConn, ok := conn.(*zgrab2.TLSConnection); ok && results.TLSLog == nil {
+			results.TLSLog = tlsConn.GetLog()
+		}
+		results.ProtocolVersion = version
+		if response, err = exchange(conn, version, 0, opOptions, nil); err != nil {
+			zgrab2.CloseConnAndHandleError(conn)
+			return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error sending OPTIONS to target %s: %w", target.String(), err)
+		}
+		if response.opcode != opError || version == 3 {
+			break
+		}
+		// the server doesn't speak this version and closes the connection; retry with the previous one
+		results.Error, _ = parseError(response.body)
+		zgrab2.CloseConnAndHandleError(conn)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	switch response.opcode {
+	case opSupported:
+		results.Error = nil
+	case opError:
+		var err error
+		if results.Error, err = parseError(response.body); err != nil {
+			return zgrab2.SCAN_PROTOCOL_ERROR, nil, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+		}
+		return zgrab2.SCAN_APPLICATION_ERROR, results, fmt.Errorf("OPTIONS failed: %s", results.Error.Message)
+	default:
+		return zgrab2.SCAN_PROTOCOL_ERROR, nil, fmt.Errorf("unexpected response opcode %#x to OPTIONS", response.opcode)
+	}
+	var err error
+	if results.Supported, err = parseSupported(response.body); err != nil {
+		return zgrab2.SCAN_PROTOCOL_ERROR, nil, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+	}
+	for _, option := range results.Supported {
+		switch option.Name {
+		case "PROTOCOL_VERSIONS":
+			results.ProtocolVersions = option.Values
+		case "CQL_VERSION":
+			results.CQLVersions = option.Values
+		case "COMPRESSION":
+			results.Compression = option.Values
+		}
+	}
+
+	cqlVersion := "3.0.0"
+	if len(results.CQLVersions) > 0 {
+		cqlVersion = results.CQLVersions[0]
+	}
+	response, err = exchange(conn, results.ProtocolVersion, 1, opStartup, encodeStringMap([][2]string{{"CQL_VERSION", cqlVersion}}))
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error sending STARTUP to target %s: %w", target.String(), err)
+	}
+	switch response.opcode {
+	case opReady:
+	case opAuthenticate:
+		results.AuthRequired = true
+		if results.Authenticator, err = parseAuthenticate(response.body); err != nil {
+			return zgrab2.SCAN_PROTOCOL_ERROR, results, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+		}
+	case opError:
+		results.Error, _ = parseError(response.body)
+	default:
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("unexpected response opcode %#x to STARTUP", response.opcode)
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
@@ -0,0 +1,236 @@
+package coap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CoAP option numbers (RFC 7252 section 5.10, RFC 7959)
+const (
+	optionURIPath       = 11
+	optionContentFormat = 12
+	optionBlock2        = 23
+)
+
+// contentFormats are the registered CoAP Content-Formats
+var contentFormats = map[int]string{
+	0:     "text/plain;charset=utf-8",
+	16:    "application/cose;cose-type=\"cose-encrypt0\"",
+	40:    "application/link-format",
+	41:    "application/xml",
+	42:    "application/octet-stream",
+	47:    "application/exi",
+	50:    "application/json",
+	60:    "application/cbor",
+	110:   "application/senml+json",
+	112:   "application/senml+cbor",
+	11542: "application/vnd.oma.lwm2m+tlv",
+	11543: "application/vnd.oma.lwm2m+json",
+}
+
+// ContentFormatName returns the media type of a Content-Format, or its number if it isn't registered.
+func ContentFormatName(format int) string {
+	if name, ok := contentFormats[format]; ok {
+		return name
+	}
+	return strconv.Itoa(format)
+}
+
+var errInvalidMessage = errors.New("invalid CoAP message")
+
+// message is a decoded CoAP message.
+type message struct {
+	messageType byte
+	code        byte
+	messageID   uint16
+	token       []byte
+	options     map[int][][]byte
+	payload     []byte
+}
+
+// codeString formats a response code as class.detail, e.g. 2.05.
+func codeString(code byte) string {
+	return fmt.Sprintf("%d.%02d", code>>5, code&0x1F)
+}
+
+// buildGet returns a confirmable GET request for the given path.
+func buildGet(messageID uint16, token []byte, path string) []byte {
+	packet := []byte{0x40 | byte(len(token)), 0x01} // version 1, CON, GET
+	packet = binary.BigEndian.AppendUint16(packet, messageID)
+	packet = append(packet, token...)
+	previous := 0
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		packet = appendOption(packet, optionURIPath-previous, []byte(segment))
+		previous = optionURIPath
+	}
+	return packet
+}
+
+// appendOption appends an option with the given delta to the previous option number.
+func appendOption(packet []byte, delta int, value []byte) []byte {
+	deltaNibble, deltaExt := optionNibble(delta)
+	lengthNibble, lengthExt := optionNibble(len(value))
+	packet = append(packet, deltaNibble<<4|lengthNibble)
+	packet = append(packet, deltaExt...)
+	packet = append(packet, lengthExt...)
+	return append(packet, value...)
+}
+
+// optionNibble returns the 4-bit encoding of an option delta or length and its extended bytes.
+func optionNibble(n int) (byte, []byte) {
+	switch {
+	case n < 13:
+		return byte(n), nil
+	case n < 269:
+		return 13, []byte{byte(n - 13)}
+	default:
+		return 14, binary.BigEndian.AppendUint16(nil, uint16(n-269))
+	}
+}
+
+// readOptionValue decodes an extended option delta or length.
+func readOptionValue(nibble byte, data []byte) (int, []byte, error) {
+	switch nibble {
+	case 13:
+		if len(data) < 1 {
+			return 0, nil, errInvalidMessage
+		}
+		return int(data[0]) + 13, data[1:], nil
+	case 14:
+		if len(data) < 2 {
+			return 0, nil, errInvalidMessage
+		}
+		return int(binary.BigEndian.Uint16(data)) + 269, data[2:], nil
+	case 15:
+		return 0, nil, errInvalidMessage
+	default:
+		return int(nibble), data, nil
+	}
+}
+
+// parseMessage decodes a CoAP message.
+func parseMessage(data []byte) (*message, error) {
+	if len(data) < 4 || data[0]>>6 != 1 {
+		return nil, errInvalidMessage
+	}
+	tokenLength := int(data[0] & 0x0F)
+	if tokenLength > 8 || len(data) < 4+tokenLength {
+		return nil, errInvalidMessage
+	}
+	msg := &message{
+		messageType: (data[0] >> 4) & 0x03,
+		code:        data[1],
+		messageID:   binary.BigEndian.Uint16(data[2:4]),
+		token:       data[4 : 4+tokenLength],
+		options:     make(map[int][][]byte),
+	}
+	rest := data[4+tokenLength:]
+	number := 0
+	for len(rest) > 0 {
+		if rest[0] == 0xFF {
+			msg.payload = rest[1:]
+			break
+		}
+		header := rest[0]
+		delta, next, err := readOptionValue(header>>4, rest[1:])
+		if err != nil {
+			return nil, err
+		}
+		length, next, err := readOptionValue(header&0x0F, next)
+		if err != nil {
+			return nil, err
+		}
+		if len(next) < length {
+			return nil, errInvalidMessage
+		}
+		number += delta
+		msg.options[number] = append(msg.options[number], next[:length])
+		rest = next[length:]
+	}
+	return msg, nil
+}
+
+// uintOption returns the value of an unsigned integer option.
+func (msg *message) uintOption(number int) (int, bool) {
+	values := msg.options[number]
+	if len(values) == 0 {
+		return 0, false
+	}
+	value := 0
+	for _, b := range values[0] {
+		value = value<<8 | int(b)
+	}
+	return value, true
+}
+
+// Resource is an entry of a CoRE Link Format document (RFC 6690).
+type Resource struct {
+	Path           string   `json:"path"`
+	ResourceType   string   `json:"rt,omitempty"`
+	Interface      string   `json:"if,omitempty"`
+	ContentFormats []int    `json:"ct,omitempty"`
+	Title          string   `json:"title,omitempty"`
+	Observable     bool     `json:"obs,omitempty"`
+	Attributes     []string `json:"attributes,omitempty"`
+}
+
+// splitOutsideQuotes splits s on sep, ignoring separators inside double quotes and angle brackets.
+func splitOutsideQuotes(s string, sep byte) []string {
+	var parts []string
+	inQuotes, inBrackets := false, false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == '<' && !inQuotes:
+			inBrackets = true
+		case c == '>' && !inQuotes:
+			inBrackets = false
+		case c == sep && !inQuotes && !inBrackets:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseLinkFormat parses a CoRE Link Format document. Malformed links are skipped.
+func parseLinkFormat(doc string) []Resource {
+	var resources []Resource
+	for _, link := range splitOutsideQuotes(doc, ',') {
+		params := splitOutsideQuotes(strings.TrimSpace(link), ';')
+		target := strings.TrimSpace(params[0])
+		if len(target) < 2 || target[0] != '<' || target[len(target)-1] != '>' {
+			continue
+		}
+		resource := Resource{Path: target[1 : len(target)-1]}
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			value = strings.Trim(value, `"`)
+			switch name {
+			case "rt":
+				resource.ResourceType = value
+			case "if":
+				resource.Interface = value
+			case "ct":
+				for _, ct := range strings.Fields(value) {
+					if format, err := strconv.Atoi(ct); err == nil {
+						resource.ContentFormats = append(resource.ContentFormats, format)
+					}
+				}
+			case "title":
+				resource.Title = value
+			case "obs":
+				resource.Observable = true
+			default:
+				resource.Attributes = append(resource.Attributes, strings.TrimSpace(param))
+			}
+		}
+		resources = append(resources, resource)
+	}
+	return resources
+}
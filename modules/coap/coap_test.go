@@ -0,0 +1,73 @@
+package coap
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestBuildGet(t *testing.T) {
+	got := buildGet(0x1234, []byte{0xAA}, "/.well-known/core")
+	want := append([]byte{0x41, 0x01, 0x12, 0x34, 0xAA, 0xBB}, []byte(".well-known")...)
+	want = append(append(want, 0x04), []byte("core")...)
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %x, want %x", got, want)
+	}
+	msg, err := parseMessage(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paths := msg.options[optionURIPath]; len(paths) != 2 || string(paths[1]) != "core" {
+		t.Errorf("unexpected Uri-Path options %q", paths)
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	// ACK 2.05 Content, Content-Format 40, Block2 num 0 M=1 SZX=2, extended option delta
+	data := []byte{0x62, 0x45, 0x12, 0x34, 0x01, 0x02, 0xC1, 0x28, 0xB1, 0x0A, 0xFF}
+	data = append(data, []byte(`</sensors>;ct=40`)...)
+	msg, err := parseMessage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.messageType != typeAcknowledgement || codeString(msg.code) != "2.05" || msg.messageID != 0x1234 {
+		t.Errorf("unexpected header %+v", msg)
+	}
+	if format, ok := msg.uintOption(optionContentFormat); !ok || format != 40 {
+		t.Errorf("got content format %d", format)
+	}
+	if block, ok := msg.uintOption(optionBlock2); !ok || block != 0x0A {
+		t.Errorf("got block2 %x", block)
+	}
+	if string(msg.payload) != "</sensors>;ct=40" {
+		t.Errorf("got payload %q", msg.payload)
+	}
+	if _, err := parseMessage([]byte{0x40, 0x45, 0x00, 0x01, 0xF0}); err == nil {
+		t.Error("expected error for reserved option nibble")
+	}
+}
+
+func TestParseLinkFormat(t *testing.T) {
+	doc := `</sensors/temp>;rt="temperature-c";if="sensor";ct="0 50";obs,</fw>;title="a,b";sz=1024,bogus`
+	want := []Resource{
+		{Path: "/sensors/temp", ResourceType: "temperature-c", Interface: "sensor", ContentFormats: []int{0, 50}, Observable: true},
+		{Path: "/fw", Title: "a,b", Attributes: []string{"sz=1024"}},
+	}
+	if got := parseLinkFormat(doc); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestParseDTLSResponse(t *testing.T) {
+	// a ClientHello isn't a server response
+	if result := parseDTLSResponse(buildClientHello()); result != nil {
+		t.Errorf("got %+v", result)
+	}
+	verify := []byte{22, 0xFE, 0xFF, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 3}
+	if result := parseDTLSResponse(verify); result == nil || result.Response != "hello_verify_request" || result.Version != "1.0" {
+		t.Errorf("got %+v", result)
+	}
+	if result := parseDTLSResponse([]byte("not dtls at all")); result != nil {
+		t.Errorf("got %+v", result)
+	}
+}
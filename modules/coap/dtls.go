@@ -0,0 +1,106 @@
+package coap
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+)
+
+// DTLS record and handshake types
+const (
+	dtlsContentAlert     = 21
+	dtlsContentHandshake = 22
+
+	dtlsServerHello        = 2
+	dtlsHelloVerifyRequest = 3
+)
+
+// dtlsCipherSuites are offered in the ClientHello: the CCM_8 suites mandated for CoAP over DTLS (RFC 7252 section
+// 9.1.3) followed by common GCM and CBC suites.
+var dtlsCipherSuites = []uint16{
+	0xC0A8, // TLS_PSK_WITH_AES_128_CCM_8
+	0xC0AE, // TLS_ECDHE_ECDSA_WITH_AES_128_CCM_8
+	0xC02B, // TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256
+	0xC02F, // TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256
+	0x00A8, // TLS_PSK_WITH_AES_128_GCM_SHA256
+	0x002F, // TLS_RSA_WITH_AES_128_CBC_SHA
+}
+
+// DTLSResult describes the target's response to a DTLS ClientHello.
+type DTLSResult struct {
+	// Version is the record layer version of the response, e.g. 1.2
+	Version string `json:"version,omitempty"`
+
+	// Response is the first handshake message received: hello_verify_request, server_hello or alert
+	Response string `json:"response,omitempty"`
+}
+
+// buildClientHello returns a DTLS 1.2 ClientHello record with an empty cookie.
+func buildClientHello() []byte {
+	body := []byte{0xFE, 0xFD} // client_version DTLS 1.2
+	random := make([]byte, 32)
+	_, _ = rand.Read(random)
+	body = append(body, random...)
+	body = append(body, 0, 0) // empty session_id and cookie
+	body = binary.BigEndian.AppendUint16(body, uint16(2*len(dtlsCipherSuites)))
+	for _, suite := range dtlsCipherSuites {
+		body = binary.BigEndian.AppendUint16(body, suite)
+	}
+	body = append(body, 1, 0) // null compression
+	extensions := []byte{
+		0x00, 0x0A, 0x00, 0x04, 0x00, 0x02, 0x00, 0x17, // supported_groups: secp256r1
+		0x00, 0x0B, 0x00, 0x02, 0x01, 0x00, // ec_point_formats: uncompressed
+		0x00, 0x0D, 0x00, 0x06, 0x00, 0x04, 0x04, 0x03, 0x04, 0x01, // signature_algorithms: ecdsa_secp256r1_sha256, rsa_pkcs1_sha256
+	}
+	body = binary.BigEndian.AppendUint16(body, uint16(len(extensions)))
+	body = append(body, extensions...)
+
+	handshake := []byte{1} // client_hello
+	handshake = appendUint24(handshake, len(body))
+	handshake = append(handshake, 0, 0) // message_seq
+	handshake = appendUint24(handshake, 0)
+	handshake = appendUint24(handshake, len(body))
+	handshake = append(handshake, body...)
+
+	record := []byte{dtlsContentHandshake, 0xFE, 0xFF} // DTLS 1.0 record version for compatibility
+	record = append(record, make([]byte, 8)...)        // epoch and sequence number
+	record = binary.BigEndian.AppendUint16(record, uint16(len(handshake)))
+	return append(record, handshake...)
+}
+
+func appendUint24(b []byte, n int) []byte {
+	return append(b, byte(n>>16), byte(n>>8), byte(n))
+}
+
+// parseDTLSResponse returns the DTLS result for a response record, or nil if it isn't a DTLS handshake or alert.
+func parseDTLSResponse(data []byte) *DTLSResult {
+	if len(data) < 14 || data[1] != 0xFE {
+		return nil
+	}
+	result := new(DTLSResult)
+	switch data[2] {
+	case 0xFF:
+		result.Version = "1.0"
+	case 0xFD:
+		result.Version = "1.2"
+	case 0xFC:
+		result.Version = "1.3"
+	default:
+		return nil
+	}
+	switch data[0] {
+	case dtlsContentAlert:
+		result.Response = "alert"
+	case dtlsContentHandshake:
+		switch data[13] {
+		case dtlsServerHello:
+			result.Response = "server_hello"
+		case dtlsHelloVerifyRequest:
+			result.Response = "hello_verify_request"
+		default:
+			return nil
+		}
+	default:
+		return nil
+	}
+	return result
+}
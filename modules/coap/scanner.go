@@ -0,0 +1,268 @@
+// Package coap contains the zgrab2 Module implementation for the Constrained Application Protocol (CoAP).
+//
+// The scan sends a confirmable GET for /.well-known/core and parses the CoRE Link Format resource directory in the
+// response. If the target doesn't answer plaintext CoAP (e.g. on coaps port 5684), it sends a DTLS ClientHello to
+// find out whether the endpoint requires DTLS.
+package coap
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// Message types
+const (
+	typeConfirmable     = 0
+	typeNonConfirmable  = 1
+	typeAcknowledgement = 2
+	typeReset           = 3
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// ResponseCode is the CoAP response code, e.g. 2.05
+	ResponseCode string `json:"response_code,omitempty"`
+
+	// Reset is true if the target rejected the request with a Reset message.
+	Reset bool `json:"reset,omitempty"`
+
+	// ContentFormat is the Content-Format of the response payload.
+	ContentFormat string `json:"content_format,omitempty"`
+
+	// MoreBlocks is true if the response is the first block of a larger (Block2) payload.
+	MoreBlocks bool `json:"more_blocks,omitempty"`
+
+	// Payload is the raw response payload.
+	Payload string `json:"payload,omitempty"`
+
+	// Resources is the parsed resource directory, if the payload is in CoRE Link Format.
+	Resources []Resource `json:"resources,omitempty"`
+
+	// ContentFormats lists the distinct content formats advertised by the resources.
+	ContentFormats []string `json:"content_formats,omitempty"`
+
+	// DTLS is the target's response to a DTLS ClientHello.
+	DTLS *DTLSResult `json:"dtls,omitempty"`
+
+	// DTLSRequired is true if the target ignored plaintext CoAP but answered DTLS.
+	DTLSRequired bool `json:"dtls_required,omitempty"`
+
+	// Probes records the outcome of every probe sent.
+	Probes []*zgrab2.UDPProbeResult `json:"probes,omitempty"`
+}
+
+// Flags are the CoAP-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.UDPFlags
+
+	Path        string `long:"path" default:"/.well-known/core" description:"Resource to GET"`
+	DTLS        bool   `long:"dtls" description:"Only probe for DTLS, skipping the plaintext request (e.g. for coaps on port 5684)"`
+	NoDTLSCheck bool   `long:"no-dtls-check" description:"Don't send a DTLS ClientHello when the plaintext request goes unanswered"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the coap zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("coap", "Constrained Application Protocol (CoAP)", module.Description(), 5683, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Fetch the CoAP resource directory (/.well-known/core) and detect whether DTLS is required"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	if err := f.UDPFlags.Validate(); err != nil {
+		return err
+	}
+	if f.DTLS && f.NoDTLSCheck {
+		return errors.New("--dtls and --no-dtls-check are mutually exclusive")
+	}
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "coap"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportUDP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// getProbe returns a probe for a confirmable GET of path. Piggybacked responses and resets are matched by message ID,
+// separate responses by token; empty acknowledgements are ignored so the probe keeps waiting for the response.
+func getProbe(path string) *zgrab2.UDPProbe {
+	token := make([]byte, 4)
+	_, _ = rand.Read(token)
+	messageID := binary.BigEndian.Uint16(token[2:])
+	return zgrab2.NewStaticUDPProbe("get", buildGet(messageID, token, path), func(_, response []byte) bool {
+		msg, err := parseMessage(response)
+		if err != nil {
+			return false
+		}
+		switch msg.messageType {
+		case typeReset:
+			return msg.messageID == messageID
+		case typeAcknowledgement:
+			return msg.messageID == messageID && msg.code != 0
+		default:
+			return string(msg.token) == string(token)
+		}
+	})
+}
+
+// dtlsProbe returns a probe for a DTLS ClientHello.
+func dtlsProbe() *zgrab2.UDPProbe {
+	return zgrab2.NewStaticUDPProbe("dtls", buildClientHello(), func(_, response []byte) bool {
+		return parseDTLSResponse(response) != nil
+	})
+}
+
+// Scan performs the configured scan on the CoAP endpoint.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	results := new(ScanResults)
+	if !scanner.config.DTLS {
+		probeResult, err := zgrab2.SendUDPProbe(ctx, conn, getProbe(scanner.config.Path), target, &scanner.config.UDPFlags)
+		results.Probes = append(results.Probes, probeResult)
+		if err == nil {
+			msg, err := parseMessage(probeResult.Response)
+			if err != nil {
+				return zgrab2.SCAN_PROTOCOL_ERROR, results, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+			}
+			if msg.messageType == typeConfirmable {
+				// acknowledge a separate response so the server stops retransmitting it
+				ack := []byte{0x40 | typeAcknowledgement<<4, 0}
+				ack = binary.BigEndian.AppendUint16(ack, msg.messageID)
+				_, _ = conn.Write(ack)
+			}
+			results.fill(msg)
+			return zgrab2.SCAN_SUCCESS, results, nil
+		}
+		if probeResult.ICMP != "" || scanner.config.NoDTLSCheck {
+			return zgrab2.TryGetScanStatus(err), results, err
+		}
+	}
+
+	probeResult, err := zgrab2.SendUDPProbe(ctx, conn, dtlsProbe(), target, &scanner.config.UDPFlags)
+	results.Probes = append(results.Probes, probeResult)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), results, err
+	}
+	results.DTLS = parseDTLSResponse(probeResult.Response)
+	results.DTLSRequired = true
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
+
+// fill records a CoAP response in the results.
+func (results *ScanResults) fill(msg *message) {
+	if msg.messageType == typeReset {
+		results.Reset = true
+		return
+	}
+	results.ResponseCode = codeString(msg.code)
+	results.Payload = string(msg.payload)
+	if block, ok := msg.uintOption(optionBlock2); ok {
+		results.MoreBlocks = block&0x08 != 0
+	}
+	format, ok := msg.uintOption(optionContentFormat)
+	if !ok {
+		return
+	}
+	results.ContentFormat = ContentFormatName(format)
+	if format != 40 {
+		return
+	}
+	results.Resources = parseLinkFormat(results.Payload)
+	seen := make(map[int]bool)
+	for _, resource := range results.Resources {
+		for _, ct := range resource.ContentFormats {
+			seen[ct] = true
+		}
+	}
+	formats := make([]int, 0, len(seen))
+	for ct := range seen {
+		formats = append(formats, ct)
+	}
+	sort.Ints(formats)
+	for _, ct := range formats {
+		results.ContentFormats = append(results.ContentFormats, ContentFormatName(ct))
+	}
+}
@@ -0,0 +1,7 @@
+package modules
+
+import "github.com/zmap/zgrab2/modules/codesys3"
+
+func init() {
+	codesys3.RegisterModule()
+}
@@ -0,0 +1,175 @@
+package codesys3
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf16"
+)
+
+// blockMagic starts every frame of the TCP block driver, followed by the length of the whole frame.
+const (
+	blockMagic        = 0x000117E8
+	blockHeaderLength = 8
+
+	// maxBlockLength bounds the frames the scan reads.
+	maxBlockLength = 1 << 16
+)
+
+// Router (layer 3) header fields.
+const (
+	routerMagic = 0xC5
+
+	// routerHeaderWords is the length of the fixed router header in 16-bit words, stored in the high three bits
+	// of the hop info beside the remaining hop count.
+	routerHeaderWords = 3
+	routerHopCount    = 13
+
+	// routerPacketInfo requests normal priority with full addresses.
+	routerPacketInfo = 0x40
+
+	serviceNameServer = 3
+	serviceNameClient = 4
+)
+
+// Name service commands.
+const (
+	nsResolveAll = 0xC202
+	nsAnswer     = 0xC280
+	nsVersion    = 0x0400
+
+	nsHeaderLength = 8
+	// nsAnswerLength is the length of the fixed part of the answer, before the names.
+	nsAnswerLength = 24
+)
+
+var errInvalidFrame = errors.New("invalid CODESYS V3 frame")
+
+// blockFrame wraps a router packet in a block driver frame.
+func blockFrame(packet []byte) []byte {
+	b := make([]byte, blockHeaderLength, blockHeaderLength+len(packet))
+	binary.LittleEndian.PutUint32(b[0:], blockMagic)
+	binary.LittleEndian.PutUint32(b[4:], uint32(blockHeaderLength+len(packet)))
+	return append(b, packet...)
+}
+
+// readBlockFrame reads a block driver frame and returns the router packet it carries.
+func readBlockFrame(r io.Reader) ([]byte, error) {
+	header := make([]byte, blockHeaderLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if magic := binary.LittleEndian.Uint32(header[0:]); magic != blockMagic {
+		return nil, fmt.Errorf("%w: block driver magic %#08x", errInvalidFrame, magic)
+	}
+	length := binary.LittleEndian.Uint32(header[4:])
+	if length < blockHeaderLength || length > maxBlockLength {
+		return nil, fmt.Errorf("%w: block driver length %d", errInvalidFrame, length)
+	}
+	packet := make([]byte, length-blockHeaderLength)
+	if _, err := io.ReadFull(r, packet); err != nil {
+		return nil, err
+	}
+	return packet, nil
+}
+
+// routerPacket builds a router packet to the service, without addresses, which the peer of a point-to-point
+// block driver does not need.
+func routerPacket(service byte, payload []byte) []byte {
+	b := []byte{routerMagic, routerHeaderWords<<5 | routerHopCount, routerPacketInfo, service, 0, 0}
+	return append(b, payload...)
+}
+
+// parseRouterPacket returns the service and payload of a router packet, skipping the addresses.
+func parseRouterPacket(b []byte) (byte, []byte, error) {
+	if len(b) < routerHeaderWords*2 || b[0] != routerMagic {
+		return 0, nil, fmt.Errorf("%w: router packet %x", errInvalidFrame, b[:min(len(b), 6)])
+	}
+	headerLength := int(b[1]>>5) * 2
+	// the receiver and sender address lengths are in words, and the addresses are padded to a multiple of four
+	// bytes
+	addresses := (int(b[5]>>4) + int(b[5]&0x0F)) * 2
+	offset := headerLength + (addresses+3)&^3
+	if headerLength < routerHeaderWords*2 || offset > len(b) {
+		return 0, nil, fmt.Errorf("%w: router header of %d bytes with %d bytes of addresses", errInvalidFrame, headerLength, addresses)
+	}
+	return b[3], b[offset:], nil
+}
+
+// resolveAll builds a name service request for the identification of the runtime.
+func resolveAll(msgID uint32) []byte {
+	b := make([]byte, nsHeaderLength)
+	binary.LittleEndian.PutUint16(b[0:], nsResolveAll)
+	binary.LittleEndian.PutUint16(b[2:], nsVersion)
+	binary.LittleEndian.PutUint32(b[4:], msgID)
+	return b
+}
+
+// Identification is a runtime's answer to the name service.
+type Identification struct {
+	// Version is the version of the name service answer.
+	Version     uint16 `json:"version"`
+	MaxChannels uint16 `json:"max_channels"`
+
+	// ByteOrder is the byte order of the runtime's CPU.
+	ByteOrder string `json:"byte_order"`
+
+	NodeName   string `json:"node_name"`
+	DeviceName string `json:"device_name"`
+	VendorName string `json:"vendor_name"`
+
+	TargetType uint32 `json:"target_type"`
+	TargetID   uint32 `json:"target_id"`
+	// TargetVersion is the runtime version, e.g. 3.5.17.0.
+	TargetVersion string `json:"target_version"`
+}
+
+// parseAnswer decodes a name service answer: the name service header, the fixed part of the answer and the
+// NUL-terminated UTF-16 node, device and vendor names.
+func parseAnswer(msgID uint32, b []byte) (*Identification, error) {
+	if len(b) < nsHeaderLength+nsAnswerLength {
+		return nil, fmt.Errorf("%w: name service answer of %d bytes", errInvalidFrame, len(b))
+	}
+	if cmd := binary.LittleEndian.Uint16(b[0:]); cmd != nsAnswer {
+		return nil, fmt.Errorf("%w: name service command %#04x", errInvalidFrame, cmd)
+	}
+	if id := binary.LittleEndian.Uint32(b[4:]); id != msgID {
+		return nil, fmt.Errorf("%w: answer to message %d in place of %d", errInvalidFrame, id, msgID)
+	}
+	b = b[nsHeaderLength:]
+	id := &Identification{
+		Version:     binary.LittleEndian.Uint16(b[0:]),
+		MaxChannels: binary.LittleEndian.Uint16(b[2:]),
+		ByteOrder:   "big",
+		TargetType:  binary.LittleEndian.Uint32(b[12:]),
+		TargetID:    binary.LittleEndian.Uint32(b[16:]),
+	}
+	if b[4] != 0 {
+		id.ByteOrder = "little"
+	}
+	version := binary.LittleEndian.Uint32(b[20:])
+	id.TargetVersion = strconv.Itoa(int(version>>24)) + "." + strconv.Itoa(int(version>>16&0xFF)) + "." +
+		strconv.Itoa(int(version>>8&0xFF)) + "." + strconv.Itoa(int(version&0xFF))
+
+	names := b[nsAnswerLength:]
+	for i, name := range []*string{&id.NodeName, &id.DeviceName, &id.VendorName} {
+		length := int(binary.LittleEndian.Uint16(b[6+2*i:]))
+		if len(names) < 2*length {
+			return nil, fmt.Errorf("%w: name of %d characters in %d bytes", errInvalidFrame, length, len(names))
+		}
+		*name = decodeUTF16(names[:2*length])
+		// skip the name and its terminator, which the last name may omit
+		names = names[min(len(names), 2*length+2):]
+	}
+	return id, nil
+}
+
+func decodeUTF16(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,88 @@
+package codesys3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"unicode/utf16"
+)
+
+// answer builds a name service answer with the given names.
+func answer(msgID uint32, names ...string) []byte {
+	b := make([]byte, nsHeaderLength+nsAnswerLength)
+	binary.LittleEndian.PutUint16(b[0:], nsAnswer)
+	binary.LittleEndian.PutUint16(b[2:], nsVersion)
+	binary.LittleEndian.PutUint32(b[4:], msgID)
+	fixed := b[nsHeaderLength:]
+	binary.LittleEndian.PutUint16(fixed[0:], 0x0103)
+	binary.LittleEndian.PutUint16(fixed[2:], 4)
+	fixed[4] = 1
+	binary.LittleEndian.PutUint32(fixed[12:], 0x1006)
+	binary.LittleEndian.PutUint32(fixed[16:], 0x00000010)
+	binary.LittleEndian.PutUint32(fixed[20:], 0x03051100)
+	for i, name := range names {
+		units := utf16.Encode([]rune(name))
+		binary.LittleEndian.PutUint16(b[nsHeaderLength+6+2*i:], uint16(len(units)))
+		for _, u := range append(units, 0) {
+			b = binary.LittleEndian.AppendUint16(b, u)
+		}
+	}
+	return b
+}
+
+func TestParseAnswer(t *testing.T) {
+	id, err := parseAnswer(42, answer(42, "plc01", "CODESYS Control for Linux SL", "3S - Smart Software Solutions GmbH"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Identification{
+		Version:       0x0103,
+		MaxChannels:   4,
+		ByteOrder:     "little",
+		NodeName:      "plc01",
+		DeviceName:    "CODESYS Control for Linux SL",
+		VendorName:    "3S - Smart Software Solutions GmbH",
+		TargetType:    0x1006,
+		TargetID:      0x10,
+		TargetVersion: "3.5.17.0",
+	}
+	if *id != expected {
+		t.Errorf("got %+v", id)
+	}
+	if _, err = parseAnswer(43, answer(42, "a", "b", "c")); !errors.Is(err, errInvalidFrame) {
+		t.Errorf("got %v for another message", err)
+	}
+	truncated := answer(42, "plc01", "device", "vendor")
+	if _, err = parseAnswer(42, truncated[:len(truncated)-8]); !errors.Is(err, errInvalidFrame) {
+		t.Errorf("got %v for a truncated answer", err)
+	}
+}
+
+func TestFrames(t *testing.T) {
+	frame := blockFrame(routerPacket(serviceNameServer, resolveAll(7)))
+	expected := []byte{
+		0xE8, 0x17, 0x01, 0x00, 22, 0, 0, 0,
+		0xC5, 0x6D, 0x40, 3, 0, 0,
+		0x02, 0xC2, 0x00, 0x04, 7, 0, 0, 0,
+	}
+	if !bytes.Equal(frame, expected) {
+		t.Errorf("got %x", frame)
+	}
+	packet, err := readBlockFrame(bytes.NewReader(frame))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an answer with a two-word receiver and a one-word sender address, padded to eight bytes
+	reply := append([]byte{0xC5, 0x6D, 0x40, serviceNameClient, 0, 0x21, 1, 2, 3, 4, 5, 6, 0, 0}, packet[6:]...)
+	service, payload, err := parseRouterPacket(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if service != serviceNameClient || !bytes.Equal(payload, resolveAll(7)) {
+		t.Errorf("got service %d, payload %x", service, payload)
+	}
+	if _, err = readBlockFrame(bytes.NewReader([]byte("HTTP/1.1 400 Bad"))); !errors.Is(err, errInvalidFrame) {
+		t.Errorf("got %v", err)
+	}
+}
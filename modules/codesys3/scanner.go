@@ -0,0 +1,163 @@
+// Package codesys3 contains the zgrab2 Module implementation for the CODESYS V3 runtime.
+//
+// The scan connects to the runtime's TCP block driver and sends a name service request, which the runtime answers
+// with its identification: the node, device and vendor names, the target type and ID, and the runtime version.
+// The request needs no login. Runtimes of CODESYS V2 are scanned by the codesys2 module.
+package codesys3
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// maxFrames bounds the frames read while waiting for the name service answer.
+const maxFrames = 8
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	Identification *Identification `json:"identification,omitempty"`
+}
+
+// Flags are the CODESYS V3-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the codesys3 zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("codesys3", "CODESYS V3", module.Description(), 11740, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Identify CODESYS V3 runtimes through the name service of their TCP block driver"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "codesys3"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// Scan sends a name service request and records the runtime's identification.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+	b := make([]byte, 4)
+	if _, err = rand.Read(b); err != nil {
+		return zgrab2.SCAN_UNKNOWN_ERROR, nil, err
+	}
+	msgID := binary.LittleEndian.Uint32(b)
+	if _, err = conn.Write(blockFrame(routerPacket(serviceNameServer, resolveAll(msgID)))); err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error sending name service request to target %s: %w", target.String(), err)
+	}
+	for i := 0; i < maxFrames; i++ {
+		packet, err := readBlockFrame(conn)
+		if err != nil {
+			return status(err), nil, fmt.Errorf("error reading from target %s: %w", target.String(), err)
+		}
+		service, payload, err := parseRouterPacket(packet)
+		if err != nil {
+			return zgrab2.SCAN_PROTOCOL_ERROR, nil, fmt.Errorf("invalid packet from target %s: %w", target.String(), err)
+		}
+		if service != serviceNameClient && service != serviceNameServer {
+			log.Debugf("ignoring packet for service %d from target %s", service, target.String())
+			continue
+		}
+		id, err := parseAnswer(msgID, payload)
+		if err != nil {
+			return zgrab2.SCAN_PROTOCOL_ERROR, nil, fmt.Errorf("invalid name service answer from target %s: %w", target.String(), err)
+		}
+		return zgrab2.SCAN_SUCCESS, &ScanResults{Identification: id}, nil
+	}
+	return zgrab2.SCAN_PROTOCOL_ERROR, nil, fmt.Errorf("no name service answer from target %s in %d frames", target.String(), maxFrames)
+}
+
+// status maps an error to a scan status, treating malformed and truncated frames as protocol errors.
+func status(err error) zgrab2.ScanStatus {
+	if errors.Is(err, errInvalidFrame) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return zgrab2.SCAN_PROTOCOL_ERROR
+	}
+	return zgrab2.TryGetScanStatus(err)
+}
@@ -0,0 +1,207 @@
+// Package couchdb contains the zgrab2 Module implementation for Apache CouchDB.
+//
+// The scan requests the welcome document at / for the version and vendor, /_up for the node's health, and
+// /_session to see which roles an anonymous request gets: with the "admin party" (no admin configured, the default
+// before CouchDB 3.0), every request has the _admin role.
+package couchdb
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+	"github.com/zmap/zgrab2/modules/httpapi"
+)
+
+// welcome is the response to GET /.
+type welcome struct {
+	CouchDB  string   `json:"couchdb"`
+	Version  string   `json:"version"`
+	GitSHA   string   `json:"git_sha"`
+	UUID     string   `json:"uuid"`
+	Features []string `json:"features"`
+	Vendor   struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"vendor"`
+}
+
+// upStatus is the response to GET /_up.
+type upStatus struct {
+	Status string `json:"status"`
+}
+
+// session is the response to GET /_session.
+type session struct {
+	OK      bool `json:"ok"`
+	UserCtx struct {
+		Name  *string  `json:"name"`
+		Roles []string `json:"roles"`
+	} `json:"userCtx"`
+}
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	Version       string   `json:"version,omitempty"`
+	GitSHA        string   `json:"git_sha,omitempty"`
+	UUID          string   `json:"uuid,omitempty"`
+	Features      []string `json:"features,omitempty"`
+	Vendor        string   `json:"vendor,omitempty"`
+	VendorVersion string   `json:"vendor_version,omitempty"`
+
+	// Status is the node status from /_up, e.g. ok or maintenance_mode.
+	Status string `json:"status,omitempty"`
+
+	// AnonymousRoles are the roles /_session reports for a request without credentials.
+	AnonymousRoles []string `json:"anonymous_roles,omitempty"`
+
+	// AdminParty is true if anonymous requests have the _admin role.
+	AdminParty bool `json:"admin_party"`
+
+	RootResponse    *httpapi.Response `json:"root_response,omitempty"`
+	UpResponse      *httpapi.Response `json:"up_response,omitempty"`
+	SessionResponse *httpapi.Response `json:"session_response,omitempty"`
+
+	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
+}
+
+// Flags are the CouchDB-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.TLSFlags
+	httpapi.Flags
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the couchdb zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("couchdb", "Apache CouchDB", module.Description(), 5984, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Query the version of a CouchDB server and whether anonymous requests have admin rights"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "couchdb"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = httpapi.NewDialerGroupConfig(&f.BaseFlags, &f.TLSFlags)
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// Scan performs the configured scan on the CouchDB server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	client := httpapi.NewClient(ctx, dialGroup, target, &scanner.config.Flags)
+	results := new(ScanResults)
+
+	var root welcome
+	var err error
+	results.RootResponse, err = client.GetJSON("/", &root)
+	results.TLSLog = client.TLSLog
+	if err != nil {
+		if results.RootResponse == nil && results.TLSLog == nil {
+			return zgrab2.TryGetScanStatus(err), nil, err
+		}
+		return zgrab2.TryGetScanStatus(err), results, err
+	}
+	// require_valid_user protects even the welcome document; /_up answers regardless since CouchDB 2.0
+	if results.RootResponse.Success() {
+		if root.CouchDB == "" {
+			return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("target %s is not a CouchDB server", target.String())
+		}
+		results.Version = root.Version
+		results.GitSHA = root.GitSHA
+		results.UUID = root.UUID
+		results.Features = root.Features
+		results.Vendor = root.Vendor.Name
+		results.VendorVersion = root.Vendor.Version
+	}
+
+	var up upStatus
+	if results.UpResponse, err = client.GetJSON("/_up", &up); err != nil {
+		return zgrab2.TryGetScanStatus(err), results, err
+	}
+	results.Status = up.Status
+	if !results.RootResponse.Success() && results.Status == "" {
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("target %s is not a CouchDB server (status %d)", target.String(), results.RootResponse.StatusCode)
+	}
+
+	var anonymous session
+	if results.SessionResponse, err = client.GetJSON("/_session", &anonymous); err != nil {
+		return zgrab2.TryGetScanStatus(err), results, err
+	}
+	if results.SessionResponse.Success() && anonymous.OK {
+		results.AnonymousRoles = anonymous.UserCtx.Roles
+		results.AdminParty = slices.Contains(anonymous.UserCtx.Roles, "_admin")
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
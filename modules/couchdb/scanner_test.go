@@ -0,0 +1,55 @@
+package couchdb
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/zmap/zgrab2"
+	"github.com/zmap/zgrab2/modules/httpapi/httpapitest"
+)
+
+// scan runs the scanner against a plaintext HTTP server with the given handler.
+func scan(t *testing.T, handler http.Handler) (zgrab2.ScanStatus, *ScanResults, error) {
+	t.Helper()
+	return httpapitest.Scan[ScanResults](t, &Scanner{config: new(Flags)}, handler)
+}
+
+func TestScanAdminParty(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{$}", httpapitest.JSONHandler(http.StatusOK, `{"couchdb":"Welcome","version":"3.3.3","git_sha":"40afbcfc7","uuid":"8b5f0c7e","features":["access-ready","partitioned"],"vendor":{"name":"The Apache Software Foundation"}}`))
+	mux.HandleFunc("/_up", httpapitest.JSONHandler(http.StatusOK, `{"status":"ok","seeds":{}}`))
+	mux.HandleFunc("/_session", httpapitest.JSONHandler(http.StatusOK, `{"ok":true,"userCtx":{"name":null,"roles":["_admin"]},"info":{"authentication_handlers":["cookie","default"]}}`))
+	status, results, err := scan(t, mux)
+	if err != nil || status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status = %s, err = %v", status, err)
+	}
+	if results.Version != "3.3.3" || results.UUID != "8b5f0c7e" || results.Vendor != "The Apache Software Foundation" || !slices.Equal(results.Features, []string{"access-ready", "partitioned"}) {
+		t.Errorf("results = %+v", results)
+	}
+	if results.Status != "ok" || !results.AdminParty || !slices.Equal(results.AnonymousRoles, []string{"_admin"}) {
+		t.Errorf("status = %q, admin party = %t, roles = %v", results.Status, results.AdminParty, results.AnonymousRoles)
+	}
+}
+
+func TestScanRequireValidUser(t *testing.T) {
+	unauthorized := httpapitest.JSONHandler(http.StatusUnauthorized, `{"error":"unauthorized","reason":"Authentication required."}`)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{$}", unauthorized)
+	mux.HandleFunc("/_up", httpapitest.JSONHandler(http.StatusOK, `{"status":"ok"}`))
+	mux.HandleFunc("/_session", unauthorized)
+	status, results, err := scan(t, mux)
+	if err != nil || status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status = %s, err = %v", status, err)
+	}
+	if results.Version != "" || results.Status != "ok" || results.AdminParty || results.AnonymousRoles != nil {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestScanNotCouchDB(t *testing.T) {
+	status, _, err := scan(t, httpapitest.JSONHandler(http.StatusOK, `{"name":"some other API"}`))
+	if err == nil || status != zgrab2.SCAN_PROTOCOL_ERROR {
+		t.Errorf("status = %s, err = %v", status, err)
+	}
+}
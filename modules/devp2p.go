@@ -0,0 +1,7 @@
+package modules
+
+import "github.com/zmap/zgrab2/modules/devp2p"
+
+func init() {
+	devp2p.RegisterModule()
+}
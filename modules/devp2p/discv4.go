@@ -0,0 +1,253 @@
+package devp2p
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Packet types.
+const (
+	packetPing        = 1
+	packetPong        = 2
+	packetENRRequest  = 5
+	packetENRResponse = 6
+)
+
+// discoveryVersion is the version announced in PING.
+const discoveryVersion = 4
+
+// packetExpiration is how far in the future the expiration of outgoing packets is.
+const packetExpiration = 20 * time.Second
+
+// headerLength is the length of the hash, signature and type that precede the RLP data of a packet.
+const headerLength = 32 + 65 + 1
+
+var errInvalidPacket = errors.New("invalid discv4 packet")
+
+func keccak256(data ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, b := range data {
+		h.Write(b)
+	}
+	return h.Sum(nil)
+}
+
+// encodePacket signs the RLP data of a packet and returns the packet; its first 32 bytes are its hash.
+func encodePacket(key *privateKey, packetType byte, data []byte) ([]byte, error) {
+	body := append([]byte{packetType}, data...)
+	signature, err := key.sign(keccak256(body))
+	if err != nil {
+		return nil, err
+	}
+	return append(keccak256(signature, body), append(signature, body...)...), nil
+}
+
+// packet is a received packet. The signature isn't verified; the scan only needs the node's answers.
+type packet struct {
+	hash       []byte
+	packetType byte
+	data       rlpItem
+}
+
+// decodePacket checks the hash of a packet and decodes its RLP data, which must be a list.
+func decodePacket(b []byte) (*packet, error) {
+	if len(b) <= headerLength || !bytes.Equal(b[:32], keccak256(b[32:])) {
+		return nil, errInvalidPacket
+	}
+	data, _, err := decodeRLP(b[headerLength:])
+	if err != nil || !data.isList {
+		return nil, errInvalidPacket
+	}
+	return &packet{hash: b[:32], packetType: b[headerLength-1], data: data}, nil
+}
+
+// expiration returns the expiration timestamp of a packet sent now.
+func expiration() []byte {
+	return rlpUint(uint64(time.Now().Add(packetExpiration).Unix()))
+}
+
+// encodeEndpoint encodes an endpoint as [ip, udp-port, tcp-port].
+func encodeEndpoint(ip net.IP, udpPort, tcpPort uint16) []byte {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	return rlpList(rlpBytes(ip), rlpUint(uint64(udpPort)), rlpUint(uint64(tcpPort)))
+}
+
+// decodeEndpoint formats an encoded endpoint as the IP and UDP port.
+func decodeEndpoint(item rlpItem) string {
+	if !item.isList || len(item.list) < 2 {
+		return ""
+	}
+	port, _ := item.list[1].uint()
+	return net.JoinHostPort(net.IP(item.list[0].data).String(), strconv.FormatUint(port, 10))
+}
+
+// buildPing returns a PING from the endpoint from to the endpoint to.
+func buildPing(key *privateKey, from, to *net.UDPAddr) ([]byte, error) {
+	data := rlpList(
+		rlpUint(discoveryVersion),
+		encodeEndpoint(from.IP, uint16(from.Port), 0),
+		encodeEndpoint(to.IP, uint16(to.Port), 0),
+		expiration(),
+	)
+	return encodePacket(key, packetPing, data)
+}
+
+// buildPong returns a PONG to the endpoint to, answering the PING with the given hash.
+func buildPong(key *privateKey, to *net.UDPAddr, pingHash []byte) ([]byte, error) {
+	data := rlpList(encodeEndpoint(to.IP, uint16(to.Port), 0), rlpBytes(pingHash), expiration())
+	return encodePacket(key, packetPong, data)
+}
+
+// buildENRRequest returns an ENRRequest.
+func buildENRRequest(key *privateKey) ([]byte, error) {
+	return encodePacket(key, packetENRRequest, rlpList(expiration()))
+}
+
+// Pong is the node's answer to the PING.
+type Pong struct {
+	// RecipientEndpoint is the scanner's IP and port as seen by the node.
+	RecipientEndpoint string `json:"recipient_endpoint,omitempty"`
+
+	// ENRSeq is the sequence number of the node's current record, if the node announces it.
+	ENRSeq uint64 `json:"enr_seq,omitempty"`
+}
+
+// parsePong decodes a PONG, checking that it answers the PING with the given hash.
+func parsePong(p *packet, pingHash []byte) (*Pong, bool) {
+	if p.packetType != packetPong || len(p.data.list) < 3 || !bytes.Equal(p.data.list[1].data, pingHash) {
+		return nil, false
+	}
+	pong := &Pong{RecipientEndpoint: decodeEndpoint(p.data.list[0])}
+	if len(p.data.list) > 3 {
+		pong.ENRSeq, _ = p.data.list[3].uint()
+	}
+	return pong, true
+}
+
+// parseENRResponse returns the record from an ENRResponse, checking that it answers the request with the given hash.
+func parseENRResponse(p *packet, requestHash []byte) (rlpItem, bool) {
+	if p.packetType != packetENRResponse || len(p.data.list) < 2 || !bytes.Equal(p.data.list[0].data, requestHash) {
+		return rlpItem{}, false
+	}
+	return p.data.list[1], true
+}
+
+// ENR is a decoded node record (EIP-778).
+type ENR struct {
+	// Record is the text form of the record, enr:<base64>.
+	Record string `json:"record"`
+
+	Seq uint64 `json:"seq"`
+
+	// ID is the identity scheme, e.g. v4.
+	ID string `json:"id,omitempty"`
+
+	// PublicKey is the hex-encoded compressed secp256k1 key of the node.
+	PublicKey string `json:"public_key,omitempty"`
+
+	// NodeID is the hex-encoded Keccak-256 hash of the uncompressed public key.
+	NodeID string `json:"node_id,omitempty"`
+
+	// SignatureValid is true if the record is signed by the key it contains.
+	SignatureValid bool `json:"signature_valid"`
+
+	IP   string `json:"ip,omitempty"`
+	TCP  uint64 `json:"tcp,omitempty"`
+	UDP  uint64 `json:"udp,omitempty"`
+	IP6  string `json:"ip6,omitempty"`
+	TCP6 uint64 `json:"tcp6,omitempty"`
+	UDP6 uint64 `json:"udp6,omitempty"`
+
+	// ForkHash and ForkNext are the EIP-2124 fork ID of the eth entry.
+	ForkHash string `json:"fork_hash,omitempty"`
+	ForkNext uint64 `json:"fork_next,omitempty"`
+
+	// Eth2ForkDigest is the fork digest of a consensus-layer node's eth2 entry.
+	Eth2ForkDigest string `json:"eth2_fork_digest,omitempty"`
+
+	// ClientName, ClientVersion and ClientBuild are from the EIP-7636 client entry.
+	ClientName    string `json:"client_name,omitempty"`
+	ClientVersion string `json:"client_version,omitempty"`
+	ClientBuild   string `json:"client_build,omitempty"`
+
+	// Keys are the keys of all entries in the record.
+	Keys []string `json:"keys,omitempty"`
+}
+
+// parseENR decodes a node record.
+func parseENR(record rlpItem) (*ENR, error) {
+	if !record.isList || len(record.list) < 2 || len(record.list)%2 != 0 || record.list[0].isList {
+		return nil, errInvalidPacket
+	}
+	enr := &ENR{Record: "enr:" + base64.RawURLEncoding.EncodeToString(record.raw)}
+	enr.Seq, _ = record.list[1].uint()
+	for i := 2; i < len(record.list); i += 2 {
+		key, value := string(record.list[i].data), record.list[i+1]
+		enr.Keys = append(enr.Keys, key)
+		switch key {
+		case "id":
+			enr.ID = string(value.data)
+		case "secp256k1":
+			enr.PublicKey = hex.EncodeToString(value.data)
+		case "ip":
+			if len(value.data) == net.IPv4len {
+				enr.IP = net.IP(value.data).String()
+			}
+		case "ip6":
+			if len(value.data) == net.IPv6len {
+				enr.IP6 = net.IP(value.data).String()
+			}
+		case "tcp":
+			enr.TCP, _ = value.uint()
+		case "udp":
+			enr.UDP, _ = value.uint()
+		case "tcp6":
+			enr.TCP6, _ = value.uint()
+		case "udp6":
+			enr.UDP6, _ = value.uint()
+		case "eth":
+			// [[fork-hash, fork-next], ...]
+			if value.isList && len(value.list) > 0 && value.list[0].isList && len(value.list[0].list) >= 2 {
+				forkID := value.list[0].list
+				enr.ForkHash = hex.EncodeToString(forkID[0].data)
+				enr.ForkNext, _ = forkID[1].uint()
+			}
+		case "eth2":
+			if len(value.data) >= 4 {
+				enr.Eth2ForkDigest = hex.EncodeToString(value.data[:4])
+			}
+		case "client":
+			// [name, version, build?]
+			if value.isList && len(value.list) >= 2 {
+				enr.ClientName = string(value.list[0].data)
+				enr.ClientVersion = string(value.list[1].data)
+				if len(value.list) > 2 {
+					enr.ClientBuild = string(value.list[2].data)
+				}
+			}
+		}
+	}
+
+	if enr.ID == "v4" {
+		compressed, _ := hex.DecodeString(enr.PublicKey)
+		if public, ok := decompress(compressed); ok {
+			enr.NodeID = hex.EncodeToString(keccak256(public.marshal()))
+			// the signature covers the record without it: [seq, k, v, ...]
+			var content [][]byte
+			for _, item := range record.list[1:] {
+				content = append(content, item.raw)
+			}
+			enr.SignatureValid = verify(public, keccak256(rlpList(content...)), record.list[0].data)
+		}
+	}
+	return enr, nil
+}
@@ -0,0 +1,132 @@
+package devp2p
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// exampleRecord is the example record of EIP-778.
+const exampleRecord = "enr:-IS4QHCYrYZbAKWCBRlAy5zzaDZXJBGkcnh4MHcBFZntXNFrdvJjX04jRzjzCBOonrkTfj499SZuOh8R33Ls8RRcy5wBgmlkgnY0gmlwhH8AAAGJc2VjcDI1NmsxoQPKY0yuDUmstAHYpMa2_oxVtw0RW_QAdpzBQA8yWM0xOIN1ZHCCdl8"
+
+func TestParseENR(t *testing.T) {
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(exampleRecord, "enr:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, rest, err := decodeRLP(raw)
+	if err != nil || len(rest) != 0 {
+		t.Fatalf("could not decode record: %v", err)
+	}
+	enr, err := parseENR(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &ENR{
+		Record:         exampleRecord,
+		Seq:            1,
+		ID:             "v4",
+		PublicKey:      "03ca634cae0d49acb401d8a4c6b6fe8c55b70d115bf400769cc1400f3258cd3138",
+		NodeID:         "a448f24c6d18e575453db13171562b71999873db5b286df957af199ec94617f7",
+		SignatureValid: true,
+		IP:             "127.0.0.1",
+		UDP:            30303,
+		Keys:           []string{"id", "ip", "secp256k1", "udp"},
+	}
+	if !reflect.DeepEqual(enr, expected) {
+		t.Errorf("got %+v, expected %+v", enr, expected)
+	}
+
+	raw[len(raw)-1]++
+	record, _, _ = decodeRLP(raw)
+	if enr, err = parseENR(record); err != nil || enr.SignatureValid {
+		t.Errorf("expected invalid signature for modified record, got %+v (%v)", enr, err)
+	}
+}
+
+func TestSign(t *testing.T) {
+	key, err := generateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.public.onCurve() {
+		t.Fatal("public key not on curve")
+	}
+	hash := keccak256([]byte("zgrab2"))
+	signature, err := key.sign(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verify(key.public, hash, signature) {
+		t.Error("signature did not verify")
+	}
+	hash[0] ^= 1
+	if verify(key.public, hash, signature) {
+		t.Error("signature verified for another hash")
+	}
+}
+
+func TestRLP(t *testing.T) {
+	long := bytes.Repeat([]byte{'a'}, 60)
+	encoded := rlpList(rlpUint(0), rlpUint(1024), rlpBytes([]byte("dog")), rlpBytes(long), rlpList())
+	if !bytes.HasPrefix(encoded, []byte{0xF8, 0x47, 0x80, 0x82, 0x04, 0x00, 0x83, 'd', 'o', 'g', 0xB8, 60}) {
+		t.Errorf("unexpected encoding % x", encoded)
+	}
+	item, rest, err := decodeRLP(encoded)
+	if err != nil || len(rest) != 0 || len(item.list) != 5 {
+		t.Fatalf("unexpected item %+v (%v)", item, err)
+	}
+	if v, ok := item.list[1].uint(); !ok || v != 1024 {
+		t.Errorf("got %d", v)
+	}
+	if !bytes.Equal(item.list[3].data, long) || !item.list[4].isList {
+		t.Errorf("unexpected items %+v", item.list[3:])
+	}
+	if _, _, err = decodeRLP(encoded[:len(encoded)-1]); err == nil {
+		t.Error("expected error for truncated list")
+	}
+}
+
+func TestPingPong(t *testing.T) {
+	key, err := generateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanner := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 40000}
+	node := &net.UDPAddr{IP: net.ParseIP("192.0.2.2"), Port: 30303}
+	ping, err := buildPing(key, scanner, node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decodePacket(ping)
+	if err != nil || decoded.packetType != packetPing {
+		t.Fatalf("unexpected packet %+v (%v)", decoded, err)
+	}
+	if to := decodeEndpoint(decoded.data.list[2]); to != "192.0.2.2:30303" {
+		t.Errorf("got to endpoint %s", to)
+	}
+
+	pongPacket, err := buildPong(key, scanner, ping[:32])
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err = decodePacket(pongPacket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pong, ok := parsePong(decoded, ping[:32])
+	if !ok || pong.RecipientEndpoint != "192.0.2.1:40000" {
+		t.Errorf("unexpected PONG %+v", pong)
+	}
+	if _, ok = parsePong(decoded, make([]byte, 32)); ok {
+		t.Error("expected PONG for another PING to be rejected")
+	}
+
+	pongPacket[len(pongPacket)-1] ^= 1
+	if _, err = decodePacket(pongPacket); err != errInvalidPacket {
+		t.Errorf("expected hash mismatch, got %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package devp2p
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var errInvalidRLP = errors.New("invalid RLP")
+
+// maxRLPDepth bounds the nesting of decoded lists.
+const maxRLPDepth = 16
+
+// rlpHeader returns the header of a string (offset 0x80) or list (offset 0xC0) of the given length.
+func rlpHeader(offset byte, length int) []byte {
+	if length < 56 {
+		return []byte{offset + byte(length)}
+	}
+	size := binary.BigEndian.AppendUint64(nil, uint64(length))
+	for size[0] == 0 {
+		size = size[1:]
+	}
+	return append([]byte{offset + 55 + byte(len(size))}, size...)
+}
+
+// rlpBytes encodes a byte string.
+func rlpBytes(b []byte) []byte {
+	if len(b) == 1 && b[0] < 0x80 {
+		return []byte{b[0]}
+	}
+	return append(rlpHeader(0x80, len(b)), b...)
+}
+
+// rlpUint encodes an unsigned integer as its minimal big-endian bytes.
+func rlpUint(v uint64) []byte {
+	b := binary.BigEndian.AppendUint64(nil, v)
+	for len(b) > 0 && b[0] == 0 {
+		b = b[1:]
+	}
+	return rlpBytes(b)
+}
+
+// rlpList encodes a list of already encoded items.
+func rlpList(items ...[]byte) []byte {
+	var payload []byte
+	for _, item := range items {
+		payload = append(payload, item...)
+	}
+	return append(rlpHeader(0xC0, len(payload)), payload...)
+}
+
+// rlpItem is a decoded RLP string or list.
+type rlpItem struct {
+	isList bool
+	data   []byte
+	list   []rlpItem
+
+	// raw is the whole encoding of the item.
+	raw []byte
+}
+
+// uint decodes a string item as a big-endian unsigned integer of at most 8 bytes.
+func (item rlpItem) uint() (uint64, bool) {
+	if item.isList || len(item.data) > 8 {
+		return 0, false
+	}
+	var v uint64
+	for _, b := range item.data {
+		v = v<<8 | uint64(b)
+	}
+	return v, true
+}
+
+// decodeRLP decodes the item at the start of data and returns it with the remaining bytes.
+func decodeRLP(data []byte) (rlpItem, []byte, error) {
+	return decodeRLPDepth(data, 0)
+}
+
+func decodeRLPDepth(data []byte, depth int) (rlpItem, []byte, error) {
+	if len(data) == 0 || depth > maxRLPDepth {
+		return rlpItem{}, nil, errInvalidRLP
+	}
+	prefix := data[0]
+	var headerLength, length int
+	var isList bool
+	switch {
+	case prefix < 0x80:
+		return rlpItem{data: data[:1], raw: data[:1]}, data[1:], nil
+	case prefix < 0xB8:
+		headerLength, length = 1, int(prefix-0x80)
+	case prefix < 0xC0:
+		headerLength, length = decodeLongLength(data, prefix-0xB7)
+	case prefix < 0xF8:
+		headerLength, length, isList = 1, int(prefix-0xC0), true
+	default:
+		headerLength, length = decodeLongLength(data, prefix-0xF7)
+		isList = true
+	}
+	if headerLength == 0 || length < 0 || len(data)-headerLength < length {
+		return rlpItem{}, nil, errInvalidRLP
+	}
+	item := rlpItem{isList: isList, data: data[headerLength : headerLength+length], raw: data[:headerLength+length]}
+	if isList {
+		rest := item.data
+		for len(rest) > 0 {
+			var child rlpItem
+			var err error
+			if child, rest, err = decodeRLPDepth(rest, depth+1); err != nil {
+				return rlpItem{}, nil, err
+			}
+			item.list = append(item.list, child)
+		}
+	}
+	return item, data[headerLength+length:], nil
+}
+
+// decodeLongLength decodes the length of a long string or list, whose header is the prefix followed by size bytes
+// of length. It returns a header length of 0 if the data is too short.
+func decodeLongLength(data []byte, size byte) (int, int) {
+	if size > 4 || len(data) < 1+int(size) {
+		return 0, 0
+	}
+	length := 0
+	for _, b := range data[1 : 1+size] {
+		length = length<<8 | int(b)
+	}
+	return 1 + int(size), length
+}
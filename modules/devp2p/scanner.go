@@ -0,0 +1,226 @@
+// Package devp2p contains the zgrab2 Module implementation for Ethereum node discovery (discv4).
+//
+// The scan sends a signed PING and records the node's PONG. The node then pings back to verify the scanner's
+// endpoint; once the scan has answered with a PONG, the node accepts an ENRRequest, and the scan records the node
+// record: public key, node ID, endpoints, the fork ID of the eth entry and the client entry, where present. The
+// packets are signed with a key generated when the scanner starts.
+//
+// Discovery v5 is not supported: its packets are masked with the recipient's node ID, which is only known from an
+// earlier exchange such as this one.
+package devp2p
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	Pong *Pong `json:"pong,omitempty"`
+	ENR  *ENR  `json:"enr,omitempty"`
+
+	// Probes are the PING and ENRRequest probes.
+	Probes []*zgrab2.UDPProbeResult `json:"probes,omitempty"`
+}
+
+// Flags are the devp2p-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.UDPFlags
+
+	NoENR bool `long:"no-enr" description:"Do not request the node record after the PONG"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+	key               *privateKey
+}
+
+// RegisterModule registers the devp2p zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("devp2p", "Ethereum devp2p discovery", module.Description(), 30303, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Ping an Ethereum node over discv4 and request its node record"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return f.UDPFlags.Validate()
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "devp2p"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	key, err := generateKey()
+	if err != nil {
+		return fmt.Errorf("could not generate discovery key: %w", err)
+	}
+	scanner.key = key
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportUDP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// answerPing waits for the node's PING and answers it with a PONG, so the node considers the scanner's endpoint
+// verified. It returns false if no PING arrived.
+func (scanner *Scanner) answerPing(ctx context.Context, conn net.Conn, remote *net.UDPAddr) bool {
+	deadline := time.Now().Add(scanner.config.TryTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	buf := make([]byte, 1280)
+	for time.Now().Before(deadline) {
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			return false
+		}
+		n, err := conn.Read(buf)
+		if err != nil {
+			return false
+		}
+		ping, err := decodePacket(buf[:n])
+		if err != nil || ping.packetType != packetPing {
+			continue
+		}
+		pong, err := buildPong(scanner.key, remote, ping.hash)
+		if err != nil {
+			return false
+		}
+		_, err = conn.Write(pong)
+		return err == nil
+	}
+	return false
+}
+
+// Scan performs the configured scan on the Ethereum node.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	local, _ := conn.LocalAddr().(*net.UDPAddr)
+	remote, _ := conn.RemoteAddr().(*net.UDPAddr)
+	if local == nil || remote == nil {
+		return zgrab2.SCAN_UNKNOWN_ERROR, nil, fmt.Errorf("connection to target %s is not a UDP connection", target.String())
+	}
+	ping, err := buildPing(scanner.key, local, remote)
+	if err != nil {
+		return zgrab2.SCAN_UNKNOWN_ERROR, nil, err
+	}
+	results := new(ScanResults)
+	probe := zgrab2.NewStaticUDPProbe("ping", ping, func(payload, response []byte) bool {
+		p, err := decodePacket(response)
+		if err != nil {
+			return false
+		}
+		_, ok := parsePong(p, payload[:32])
+		return ok
+	})
+	result, err := zgrab2.SendUDPProbe(ctx, conn, probe, target, &scanner.config.UDPFlags)
+	results.Probes = append(results.Probes, result)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), results, err
+	}
+	p, _ := decodePacket(result.Response)
+	results.Pong, _ = parsePong(p, ping[:32])
+	if scanner.config.NoENR {
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+
+	if !scanner.answerPing(ctx, conn, remote) {
+		log.Debugf("target %s did not ping back; requesting its record anyway", target.String())
+	}
+	request, err := buildENRRequest(scanner.key)
+	if err != nil {
+		return zgrab2.SCAN_UNKNOWN_ERROR, results, err
+	}
+	probe = zgrab2.NewStaticUDPProbe("enr-request", request, func(payload, response []byte) bool {
+		p, err := decodePacket(response)
+		if err != nil {
+			return false
+		}
+		_, ok := parseENRResponse(p, payload[:32])
+		return ok
+	})
+	result, err = zgrab2.SendUDPProbe(ctx, conn, probe, target, &scanner.config.UDPFlags)
+	results.Probes = append(results.Probes, result)
+	if err != nil {
+		log.Debugf("no ENRResponse from target %s: %v", target.String(), err)
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+	p, _ = decodePacket(result.Response)
+	record, _ := parseENRResponse(p, request[:32])
+	if results.ENR, err = parseENR(record); err != nil {
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
@@ -0,0 +1,204 @@
+package devp2p
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+// The secp256k1 curve y² = x³ + 7 over the prime field of order curveP, with base point (curveGx, curveGy) of order
+// curveN. The arithmetic here is only used to sign a handful of packets per scan, so it favors brevity over speed
+// and is not constant-time.
+var (
+	curveP, _  = new(big.Int).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC2F", 16)
+	curveN, _  = new(big.Int).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141", 16)
+	curveGx, _ = new(big.Int).SetString("79BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798", 16)
+	curveGy, _ = new(big.Int).SetString("483ADA7726A3C4655DA4FBFC0E1108A8FD17B448A68554199C47D08FFB10D4B8", 16)
+	curveB     = big.NewInt(7)
+	halfN      = new(big.Int).Rsh(curveN, 1)
+)
+
+// point is an affine point on the curve; a nil x is the point at infinity.
+type point struct {
+	x, y *big.Int
+}
+
+var basePoint = point{curveGx, curveGy}
+
+func (p point) infinity() bool {
+	return p.x == nil
+}
+
+// add returns p + q.
+func (p point) add(q point) point {
+	switch {
+	case p.infinity():
+		return q
+	case q.infinity():
+		return p
+	case p.x.Cmp(q.x) == 0:
+		if p.y.Cmp(q.y) != 0 || p.y.Sign() == 0 {
+			return point{}
+		}
+		return p.double()
+	}
+	// λ = (qy - py) / (qx - px)
+	num := new(big.Int).Sub(q.y, p.y)
+	den := new(big.Int).Sub(q.x, p.x)
+	den.ModInverse(den.Mod(den, curveP), curveP)
+	return p.withSlope(q, num.Mul(num, den).Mod(num, curveP))
+}
+
+// double returns 2p.
+func (p point) double() point {
+	if p.infinity() || p.y.Sign() == 0 {
+		return point{}
+	}
+	// λ = 3px² / 2py
+	num := new(big.Int).Mul(p.x, p.x)
+	num.Mul(num, big.NewInt(3))
+	den := new(big.Int).Lsh(p.y, 1)
+	den.ModInverse(den.Mod(den, curveP), curveP)
+	return p.withSlope(p, num.Mul(num, den).Mod(num, curveP))
+}
+
+// withSlope returns the third point on the line through p and q with slope lambda, reflected.
+func (p point) withSlope(q point, lambda *big.Int) point {
+	x := new(big.Int).Mul(lambda, lambda)
+	x.Sub(x, p.x).Sub(x, q.x).Mod(x, curveP)
+	y := new(big.Int).Sub(p.x, x)
+	y.Mul(y, lambda).Sub(y, p.y).Mod(y, curveP)
+	return point{x, y}
+}
+
+// mul returns k·p.
+func (p point) mul(k *big.Int) point {
+	var result point
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		result = result.double()
+		if k.Bit(i) == 1 {
+			result = result.add(p)
+		}
+	}
+	return result
+}
+
+// onCurve reports whether p satisfies the curve equation.
+func (p point) onCurve() bool {
+	if p.infinity() {
+		return false
+	}
+	left := new(big.Int).Mul(p.y, p.y)
+	right := new(big.Int).Exp(p.x, big.NewInt(3), curveP)
+	right.Add(right, curveB)
+	return left.Sub(left, right).Mod(left, curveP).Sign() == 0
+}
+
+// marshal returns the 64-byte encoding of p without the uncompressed-point prefix, as used for node IDs in discv4.
+func (p point) marshal() []byte {
+	b := make([]byte, 64)
+	p.x.FillBytes(b[:32])
+	p.y.FillBytes(b[32:])
+	return b
+}
+
+// decompress decodes a 33-byte compressed point.
+func decompress(b []byte) (point, bool) {
+	if len(b) != 33 || (b[0] != 2 && b[0] != 3) {
+		return point{}, false
+	}
+	x := new(big.Int).SetBytes(b[1:])
+	if x.Cmp(curveP) >= 0 {
+		return point{}, false
+	}
+	// y = (x³ + 7)^((p+1)/4), since p ≡ 3 mod 4
+	y := new(big.Int).Exp(x, big.NewInt(3), curveP)
+	y.Add(y, curveB)
+	exponent := new(big.Int).Add(curveP, big.NewInt(1))
+	y.Exp(y, exponent.Rsh(exponent, 2), curveP)
+	if y.Bit(0) != uint(b[0]&1) {
+		y.Sub(curveP, y)
+	}
+	p := point{x, y}
+	return p, p.onCurve()
+}
+
+// randomScalar returns a uniformly random integer in [1, n).
+func randomScalar() (*big.Int, error) {
+	limit := new(big.Int).Sub(curveN, big.NewInt(1))
+	k, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return nil, err
+	}
+	return k.Add(k, big.NewInt(1)), nil
+}
+
+// privateKey is a secp256k1 key pair.
+type privateKey struct {
+	d      *big.Int
+	public point
+}
+
+// generateKey returns a new random key pair.
+func generateKey() (*privateKey, error) {
+	d, err := randomScalar()
+	if err != nil {
+		return nil, err
+	}
+	return &privateKey{d: d, public: basePoint.mul(d)}, nil
+}
+
+// sign returns the 65-byte recoverable signature r || s || v of a 32-byte hash, with a low s as Ethereum requires.
+func (key *privateKey) sign(hash []byte) ([]byte, error) {
+	z := new(big.Int).SetBytes(hash)
+	for {
+		k, err := randomScalar()
+		if err != nil {
+			return nil, err
+		}
+		R := basePoint.mul(k)
+		r := new(big.Int).Mod(R.x, curveN)
+		if r.Sign() == 0 {
+			continue
+		}
+		s := new(big.Int).Mul(r, key.d)
+		s.Add(s, z).Mul(s, new(big.Int).ModInverse(k, curveN)).Mod(s, curveN)
+		if s.Sign() == 0 {
+			continue
+		}
+		v := byte(R.y.Bit(0))
+		if R.x.Cmp(curveN) >= 0 {
+			v |= 2
+		}
+		if s.Cmp(halfN) > 0 {
+			s.Sub(curveN, s)
+			v ^= 1
+		}
+		signature := make([]byte, 65)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:64])
+		signature[64] = v
+		return signature, nil
+	}
+}
+
+// verify checks the 64-byte signature r || s of a 32-byte hash against the public key.
+func verify(public point, hash []byte, signature []byte) bool {
+	if len(signature) < 64 {
+		return false
+	}
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:64])
+	if r.Sign() == 0 || s.Sign() == 0 || r.Cmp(curveN) >= 0 || s.Cmp(curveN) >= 0 {
+		return false
+	}
+	w := new(big.Int).ModInverse(s, curveN)
+	u1 := new(big.Int).SetBytes(hash)
+	u1.Mul(u1, w).Mod(u1, curveN)
+	u2 := new(big.Int).Mul(r, w)
+	u2.Mod(u2, curveN)
+	R := basePoint.mul(u1).add(public.mul(u2))
+	if R.infinity() {
+		return false
+	}
+	return new(big.Int).Mod(R.x, curveN).Cmp(r) == 0
+}
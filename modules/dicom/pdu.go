@@ -0,0 +1,379 @@
+package dicom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// PDU types (DICOM PS3.8 section 9.3).
+const (
+	pduAssociateRQ = 0x01
+	pduAssociateAC = 0x02
+	pduAssociateRJ = 0x03
+	pduDataTF      = 0x04
+	pduReleaseRQ   = 0x05
+	pduReleaseRP   = 0x06
+	pduAbort       = 0x07
+)
+
+// Item types of the association PDUs.
+const (
+	itemApplicationContext  = 0x10
+	itemPresentationContext = 0x20
+	itemPresentationResult  = 0x21
+	itemAbstractSyntax      = 0x30
+	itemTransferSyntax      = 0x40
+	itemUserInformation     = 0x50
+	itemMaxLength           = 0x51
+	itemImplementationUID   = 0x52
+	itemImplementationName  = 0x55
+)
+
+const (
+	pduHeaderLength = 6
+
+	// maxPDULength bounds the PDUs the scan reads, and is the maximum length the scan announces.
+	maxPDULength = 1 << 16
+
+	aeTitleLength = 16
+)
+
+// UIDs used by the scan.
+const (
+	applicationContextUID = "1.2.840.10008.3.1.1.1"
+	verificationUID       = "1.2.840.10008.1.1"
+	implicitVRLittleUID   = "1.2.840.10008.1.2"
+	explicitVRLittleUID   = "1.2.840.10008.1.2.1"
+
+	// implementationUID identifies zgrab2 in the association request. It is below the root for UUID-derived UIDs.
+	implementationUID  = "2.25.305828110656479458133049466549716440577"
+	implementationName = "ZGRAB2"
+)
+
+// abstractSyntaxes are the SOP classes proposed in the association request, one presentation context each, so the
+// accepted contexts show the services the target offers.
+var abstractSyntaxes = []struct {
+	uid  string
+	name string
+}{
+	{verificationUID, "Verification"},
+	{"1.2.840.10008.5.1.4.1.2.1.1", "Patient Root Query/Retrieve FIND"},
+	{"1.2.840.10008.5.1.4.1.2.2.1", "Study Root Query/Retrieve FIND"},
+	{"1.2.840.10008.5.1.4.1.2.2.2", "Study Root Query/Retrieve MOVE"},
+	{"1.2.840.10008.5.1.4.31", "Modality Worklist FIND"},
+	{"1.2.840.10008.5.1.4.1.1.2", "CT Image Storage"},
+	{"1.2.840.10008.5.1.4.1.1.7", "Secondary Capture Image Storage"},
+}
+
+var errInvalidPDU = errors.New("invalid DICOM PDU")
+
+// pdu is a received PDU.
+type pdu struct {
+	typ  byte
+	data []byte
+}
+
+// readPDU reads a PDU.
+func readPDU(r io.Reader) (*pdu, error) {
+	header := make([]byte, pduHeaderLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if header[0] < pduAssociateRQ || header[0] > pduAbort {
+		return nil, fmt.Errorf("%w: type %#02x", errInvalidPDU, header[0])
+	}
+	length := binary.BigEndian.Uint32(header[2:])
+	if length > maxPDULength {
+		return nil, fmt.Errorf("%w: length %d", errInvalidPDU, length)
+	}
+	p := &pdu{typ: header[0], data: make([]byte, length)}
+	if _, err := io.ReadFull(r, p.data); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// marshalPDU builds a PDU of the given type.
+func marshalPDU(typ byte, data []byte) []byte {
+	b := make([]byte, pduHeaderLength, pduHeaderLength+len(data))
+	b[0] = typ
+	binary.BigEndian.PutUint32(b[2:], uint32(len(data)))
+	return append(b, data...)
+}
+
+// item builds an item or sub-item.
+func item(typ byte, data []byte) []byte {
+	b := []byte{typ, 0, byte(len(data) >> 8), byte(len(data))}
+	return append(b, data...)
+}
+
+// aeTitle pads an AE title with spaces.
+func aeTitle(title string) []byte {
+	b := bytes.Repeat([]byte{' '}, aeTitleLength)
+	copy(b, title)
+	return b
+}
+
+// associateRequest builds an A-ASSOCIATE-RQ proposing a presentation context for each of abstractSyntaxes.
+func associateRequest(calledAE, callingAE string) []byte {
+	b := []byte{0, 1, 0, 0}
+	b = append(b, aeTitle(calledAE)...)
+	b = append(b, aeTitle(callingAE)...)
+	b = append(b, make([]byte, 32)...)
+	b = append(b, item(itemApplicationContext, []byte(applicationContextUID))...)
+	for i, syntax := range abstractSyntaxes {
+		context := []byte{byte(2*i + 1), 0, 0, 0}
+		context = append(context, item(itemAbstractSyntax, []byte(syntax.uid))...)
+		context = append(context, item(itemTransferSyntax, []byte(explicitVRLittleUID))...)
+		context = append(context, item(itemTransferSyntax, []byte(implicitVRLittleUID))...)
+		b = append(b, item(itemPresentationContext, context)...)
+	}
+	maxLength := binary.BigEndian.AppendUint32(nil, maxPDULength)
+	user := item(itemMaxLength, maxLength)
+	user = append(user, item(itemImplementationUID, []byte(implementationUID))...)
+	user = append(user, item(itemImplementationName, []byte(implementationName))...)
+	b = append(b, item(itemUserInformation, user)...)
+	return marshalPDU(pduAssociateRQ, b)
+}
+
+// forEachItem calls f with the type and data of each item in b.
+func forEachItem(b []byte, f func(typ byte, data []byte) error) error {
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return fmt.Errorf("%w: truncated item header", errInvalidPDU)
+		}
+		length := int(binary.BigEndian.Uint16(b[2:]))
+		if len(b) < 4+length {
+			return fmt.Errorf("%w: item %#02x of %d bytes in %d", errInvalidPDU, b[0], length, len(b)-4)
+		}
+		if err := f(b[0], b[4:4+length]); err != nil {
+			return err
+		}
+		b = b[4+length:]
+	}
+	return nil
+}
+
+// uid returns a UID without its NUL padding.
+func uid(b []byte) string {
+	return string(bytes.TrimRight(b, "\x00 "))
+}
+
+// PresentationContext is the target's answer to a proposed presentation context.
+type PresentationContext struct {
+	ID             uint8  `json:"id"`
+	AbstractSyntax string `json:"abstract_syntax"`
+	Name           string `json:"name"`
+	Result         string `json:"result"`
+	Accepted       bool   `json:"accepted"`
+	TransferSyntax string `json:"transfer_syntax,omitempty"`
+}
+
+// presentationResults names the results of presentation contexts (PS3.8 section 9.3.3.2).
+var presentationResults = map[byte]string{
+	0: "acceptance",
+	1: "user rejection",
+	2: "no reason",
+	3: "abstract syntax not supported",
+	4: "transfer syntaxes not supported",
+}
+
+func lookup(names map[byte]string, v byte) string {
+	if name, ok := names[v]; ok {
+		return name
+	}
+	return "unknown_" + strconv.Itoa(int(v))
+}
+
+// Association is the content of an A-ASSOCIATE-AC.
+type Association struct {
+	MaxPDULength              uint32                 `json:"max_pdu_length,omitempty"`
+	ImplementationClassUID    string                 `json:"implementation_class_uid,omitempty"`
+	ImplementationVersionName string                 `json:"implementation_version_name,omitempty"`
+	PresentationContexts      []*PresentationContext `json:"presentation_contexts,omitempty"`
+}
+
+// parseAssociateAccept decodes an A-ASSOCIATE-AC.
+func parseAssociateAccept(data []byte) (*Association, error) {
+	if len(data) < 68 {
+		return nil, fmt.Errorf("%w: A-ASSOCIATE-AC of %d bytes", errInvalidPDU, len(data))
+	}
+	a := new(Association)
+	err := forEachItem(data[68:], func(typ byte, item []byte) error {
+		switch typ {
+		case itemPresentationResult:
+			if len(item) < 4 {
+				return fmt.Errorf("%w: presentation context of %d bytes", errInvalidPDU, len(item))
+			}
+			context := &PresentationContext{ID: item[0], Result: lookup(presentationResults, item[2]), Accepted: item[2] == 0}
+			if i := int(item[0]) / 2; item[0]%2 == 1 && i < len(abstractSyntaxes) {
+				context.AbstractSyntax, context.Name = abstractSyntaxes[i].uid, abstractSyntaxes[i].name
+			}
+			a.PresentationContexts = append(a.PresentationContexts, context)
+			return forEachItem(item[4:], func(typ byte, sub []byte) error {
+				if typ == itemTransferSyntax && context.Accepted {
+					context.TransferSyntax = uid(sub)
+				}
+				return nil
+			})
+		case itemUserInformation:
+			return forEachItem(item, func(typ byte, sub []byte) error {
+				switch {
+				case typ == itemMaxLength && len(sub) == 4:
+					a.MaxPDULength = binary.BigEndian.Uint32(sub)
+				case typ == itemImplementationUID:
+					a.ImplementationClassUID = uid(sub)
+				case typ == itemImplementationName:
+					a.ImplementationVersionName = string(bytes.TrimRight(sub, " "))
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Rejection is the content of an A-ASSOCIATE-RJ or A-ABORT.
+type Rejection struct {
+	Result string `json:"result,omitempty"`
+	Source string `json:"source"`
+	Reason string `json:"reason"`
+}
+
+var rejectResults = map[byte]string{
+	1: "rejected permanent",
+	2: "rejected transient",
+}
+
+var rejectSources = map[byte]string{
+	1: "service user",
+	2: "service provider (ACSE)",
+	3: "service provider (presentation)",
+}
+
+// rejectReasons names the reasons of a rejection by source (PS3.8 section 9.3.4).
+var rejectReasons = map[byte]map[byte]string{
+	1: {1: "no reason given", 2: "application context name not supported", 3: "calling AE title not recognized", 7: "called AE title not recognized"},
+	2: {1: "no reason given", 2: "protocol version not supported"},
+	3: {1: "temporary congestion", 2: "local limit exceeded"},
+}
+
+// abortSources and abortReasons name the source and reason of an A-ABORT (PS3.8 section 9.3.8).
+var abortSources = map[byte]string{
+	0: "service user",
+	2: "service provider",
+}
+
+var abortReasons = map[byte]string{
+	0: "reason not specified",
+	1: "unrecognized PDU",
+	2: "unexpected PDU",
+	4: "unrecognized PDU parameter",
+	5: "unexpected PDU parameter",
+	6: "invalid PDU parameter value",
+}
+
+// parseReject decodes an A-ASSOCIATE-RJ.
+func parseReject(data []byte) (*Rejection, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("%w: A-ASSOCIATE-RJ of %d bytes", errInvalidPDU, len(data))
+	}
+	return &Rejection{
+		Result: lookup(rejectResults, data[1]),
+		Source: lookup(rejectSources, data[2]),
+		Reason: lookup(rejectReasons[data[2]], data[3]),
+	}, nil
+}
+
+// parseAbort decodes an A-ABORT.
+func parseAbort(data []byte) (*Rejection, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("%w: A-ABORT of %d bytes", errInvalidPDU, len(data))
+	}
+	return &Rejection{Source: lookup(abortSources, data[2]), Reason: lookup(abortReasons, data[3])}, nil
+}
+
+// Command set elements of C-ECHO (PS3.7 section 9.3.5).
+const (
+	tagCommandGroupLength   = 0x00000000
+	tagAffectedSOPClassUID  = 0x00000002
+	tagCommandField         = 0x00000100
+	tagMessageID            = 0x00000110
+	tagMessageIDBeingRespTo = 0x00000120
+	tagCommandDataSetType   = 0x00000800
+	tagStatus               = 0x00000900
+
+	commandEchoRQ  = 0x0030
+	commandEchoRSP = 0x8030
+	noDataSet      = 0x0101
+
+	// pdvCommandLast marks a PDV as the last fragment of a command.
+	pdvCommandLast = 0x03
+)
+
+// element encodes a command element in implicit VR little endian, padding the value to an even length.
+func element(tag uint32, value []byte) []byte {
+	if len(value)%2 == 1 {
+		value = append(value, 0)
+	}
+	b := binary.LittleEndian.AppendUint16(nil, uint16(tag>>16))
+	b = binary.LittleEndian.AppendUint16(b, uint16(tag))
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(value)))
+	return append(b, value...)
+}
+
+func us(v uint16) []byte {
+	return binary.LittleEndian.AppendUint16(nil, v)
+}
+
+// echoRequest builds a P-DATA-TF carrying a C-ECHO-RQ on the presentation context.
+func echoRequest(contextID byte, messageID uint16) []byte {
+	var command []byte
+	command = append(command, element(tagAffectedSOPClassUID, []byte(verificationUID))...)
+	command = append(command, element(tagCommandField, us(commandEchoRQ))...)
+	command = append(command, element(tagMessageID, us(messageID))...)
+	command = append(command, element(tagCommandDataSetType, us(noDataSet))...)
+	command = append(element(tagCommandGroupLength, binary.LittleEndian.AppendUint32(nil, uint32(len(command)))), command...)
+	pdv := binary.BigEndian.AppendUint32(nil, uint32(2+len(command)))
+	pdv = append(pdv, contextID, pdvCommandLast)
+	return marshalPDU(pduDataTF, append(pdv, command...))
+}
+
+// parseCommand decodes the elements of the command carried in a P-DATA-TF, which must fit in one PDV.
+func parseCommand(data []byte) (map[uint32][]byte, error) {
+	if len(data) < 6 {
+		return nil, fmt.Errorf("%w: P-DATA-TF of %d bytes", errInvalidPDU, len(data))
+	}
+	length := binary.BigEndian.Uint32(data)
+	if length < 2 || int(length) > len(data)-4 || data[5]&pdvCommandLast != pdvCommandLast {
+		return nil, fmt.Errorf("%w: PDV of %d bytes with control header %#02x", errInvalidPDU, length, data[5])
+	}
+	command := data[6 : 4+length]
+	elements := make(map[uint32][]byte)
+	for len(command) > 0 {
+		if len(command) < 8 {
+			return nil, fmt.Errorf("%w: truncated command element", errInvalidPDU)
+		}
+		tag := uint32(binary.LittleEndian.Uint16(command))<<16 | uint32(binary.LittleEndian.Uint16(command[2:]))
+		length := binary.LittleEndian.Uint32(command[4:])
+		if uint32(len(command)-8) < length {
+			return nil, fmt.Errorf("%w: command element %08x of %d bytes", errInvalidPDU, tag, length)
+		}
+		elements[tag] = command[8 : 8+length]
+		command = command[8+length:]
+	}
+	return elements, nil
+}
+
+// releaseRequest builds an A-RELEASE-RQ.
+func releaseRequest() []byte {
+	return marshalPDU(pduReleaseRQ, make([]byte, 4))
+}
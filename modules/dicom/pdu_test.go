@@ -0,0 +1,118 @@
+package dicom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+)
+
+// associateAccept builds an A-ASSOCIATE-AC accepting verification with implicit VR little endian and rejecting
+// the other contexts.
+func associateAccept() []byte {
+	b := make([]byte, 68)
+	b[1] = 1
+	b = append(b, item(itemApplicationContext, []byte(applicationContextUID))...)
+	for i := range abstractSyntaxes {
+		result := byte(3)
+		if i == 0 {
+			result = 0
+		}
+		b = append(b, item(itemPresentationResult, append([]byte{byte(2*i + 1), 0, result, 0}, item(itemTransferSyntax, []byte(implicitVRLittleUID+"\x00"))...))...)
+	}
+	user := item(itemMaxLength, []byte{0, 0, 0x40, 0})
+	user = append(user, item(itemImplementationUID, []byte("1.2.276.0.7230010.3.0.3.6.4\x00"))...)
+	user = append(user, item(itemImplementationName, []byte("OFFIS_DCMTK_364"))...)
+	return marshalPDU(pduAssociateAC, append(b, item(itemUserInformation, user)...))
+}
+
+func TestAssociateRequest(t *testing.T) {
+	b := associateRequest("ANY-SCP", "ZGRAB2")
+	p, err := readPDU(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.typ != pduAssociateRQ || !bytes.Equal(p.data[4:20], []byte("ANY-SCP         ")) || !bytes.Equal(p.data[20:36], []byte("ZGRAB2          ")) {
+		t.Errorf("got %x", b[:42])
+	}
+	var contexts int
+	err = forEachItem(p.data[68:], func(typ byte, _ []byte) error {
+		if typ == itemPresentationContext {
+			contexts++
+		}
+		return nil
+	})
+	if err != nil || contexts != len(abstractSyntaxes) {
+		t.Errorf("got %d presentation contexts, %v", contexts, err)
+	}
+}
+
+func TestParseAssociateAccept(t *testing.T) {
+	p, err := readPDU(bytes.NewReader(associateAccept()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := parseAssociateAccept(p.data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.MaxPDULength != 16384 || a.ImplementationClassUID != "1.2.276.0.7230010.3.0.3.6.4" || a.ImplementationVersionName != "OFFIS_DCMTK_364" {
+		t.Errorf("got %+v", a)
+	}
+	if len(a.PresentationContexts) != len(abstractSyntaxes) {
+		t.Fatalf("got %d presentation contexts", len(a.PresentationContexts))
+	}
+	expected := PresentationContext{ID: 1, AbstractSyntax: verificationUID, Name: "Verification", Result: "acceptance", Accepted: true, TransferSyntax: implicitVRLittleUID}
+	if *a.PresentationContexts[0] != expected {
+		t.Errorf("got %+v", a.PresentationContexts[0])
+	}
+	if pc := a.PresentationContexts[1]; pc.Accepted || pc.Result != "abstract syntax not supported" || pc.TransferSyntax != "" {
+		t.Errorf("got %+v", pc)
+	}
+}
+
+func TestParseReject(t *testing.T) {
+	r, err := parseReject([]byte{0, 1, 1, 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Rejection{Result: "rejected permanent", Source: "service user", Reason: "called AE title not recognized"}
+	if *r != expected {
+		t.Errorf("got %+v", r)
+	}
+}
+
+// TestEcho sends a C-ECHO to a fake target answering with a success status.
+func TestEcho(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		p, err := readPDU(server)
+		if err != nil {
+			return
+		}
+		elements, err := parseCommand(p.data)
+		if err != nil || binary.LittleEndian.Uint16(elements[tagCommandField]) != commandEchoRQ || string(elements[tagAffectedSOPClassUID]) != verificationUID+"\x00" {
+			server.Write(marshalPDU(pduAbort, make([]byte, 4)))
+			return
+		}
+		var command []byte
+		command = append(command, element(tagCommandField, us(commandEchoRSP))...)
+		command = append(command, element(tagMessageIDBeingRespTo, elements[tagMessageID])...)
+		command = append(command, element(tagStatus, us(0))...)
+		pdv := append(binary.BigEndian.AppendUint32(nil, uint32(2+len(command))), p.data[4], pdvCommandLast)
+		server.Write(marshalPDU(pduDataTF, append(pdv, command...)))
+	}()
+	status, err := echo(client, 1)
+	if err != nil || status != 0 {
+		t.Errorf("got %d, %v", status, err)
+	}
+}
+
+func TestReadPDUInvalid(t *testing.T) {
+	if _, err := readPDU(bytes.NewReader([]byte("HTTP/1.1 400"))); !errors.Is(err, errInvalidPDU) {
+		t.Errorf("got %v", err)
+	}
+}
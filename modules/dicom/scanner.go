@@ -0,0 +1,224 @@
+// Package dicom contains the zgrab2 Module implementation for the DICOM upper layer protocol.
+//
+// The scan requests an association with the configured AE titles, proposing a presentation context for
+// verification and for a few common query/retrieve, worklist and storage SOP classes. It records whether the
+// association was accepted, rejected or aborted, the accepted presentation contexts and the implementation class
+// UID and version name of the target. If verification was accepted, it sends a C-ECHO and records its status. The
+// association is released before closing the connection.
+package dicom
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// Response is the answer to the association request: accepted, rejected or aborted.
+	Response string `json:"response"`
+
+	Association *Association `json:"association,omitempty"`
+	Rejection   *Rejection   `json:"rejection,omitempty"`
+
+	// EchoStatus is the status of the C-ECHO, 0 meaning success.
+	EchoStatus *uint16 `json:"echo_status,omitempty"`
+
+	// Released is true if the target confirmed the release of the association.
+	Released bool `json:"released"`
+}
+
+// Flags are the DICOM-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+
+	CalledAETitle  string `long:"called-ae-title" default:"ANY-SCP" description:"AE title of the target; many targets reject associations to other titles"`
+	CallingAETitle string `long:"calling-ae-title" default:"ZGRAB2" description:"AE title the scan associates from"`
+	NoEcho         bool   `long:"no-echo" description:"Do not send a C-ECHO over an accepted association"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the dicom zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("dicom", "DICOM", module.Description(), 104, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Request a DICOM association, record the accepted presentation contexts and implementation, and send a C-ECHO"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	for _, title := range []string{f.CalledAETitle, f.CallingAETitle} {
+		if len(title) == 0 || len(title) > aeTitleLength {
+			return fmt.Errorf("AE title %q must have 1 to %d characters", title, aeTitleLength)
+		}
+	}
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "dicom"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// echo sends a C-ECHO on the presentation context and returns its status.
+func echo(conn net.Conn, contextID byte) (uint16, error) {
+	if _, err := conn.Write(echoRequest(contextID, 1)); err != nil {
+		return 0, err
+	}
+	p, err := readPDU(conn)
+	if err != nil {
+		return 0, err
+	}
+	if p.typ != pduDataTF {
+		return 0, fmt.Errorf("%w: PDU type %#02x in answer to C-ECHO", errInvalidPDU, p.typ)
+	}
+	elements, err := parseCommand(p.data)
+	if err != nil {
+		return 0, err
+	}
+	field, status := elements[tagCommandField], elements[tagStatus]
+	if len(field) != 2 || binary.LittleEndian.Uint16(field) != commandEchoRSP || len(status) != 2 {
+		return 0, fmt.Errorf("%w: answer to C-ECHO is not a C-ECHO-RSP", errInvalidPDU)
+	}
+	return binary.LittleEndian.Uint16(status), nil
+}
+
+// Scan requests an association with the target.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	if _, err = conn.Write(associateRequest(scanner.config.CalledAETitle, scanner.config.CallingAETitle)); err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error requesting association with target %s: %w", target.String(), err)
+	}
+	p, err := readPDU(conn)
+	if err != nil {
+		return status(err), nil, fmt.Errorf("error reading association response from target %s: %w", target.String(), err)
+	}
+	results := new(ScanResults)
+	switch p.typ {
+	case pduAssociateAC:
+		results.Response = "accepted"
+		results.Association, err = parseAssociateAccept(p.data)
+	case pduAssociateRJ:
+		results.Response = "rejected"
+		results.Rejection, err = parseReject(p.data)
+	case pduAbort:
+		results.Response = "aborted"
+		results.Rejection, err = parseAbort(p.data)
+	default:
+		err = fmt.Errorf("%w: PDU type %#02x in answer to A-ASSOCIATE-RQ", errInvalidPDU, p.typ)
+	}
+	if err != nil {
+		return zgrab2.SCAN_PROTOCOL_ERROR, nil, fmt.Errorf("invalid association response from target %s: %w", target.String(), err)
+	}
+	if results.Association == nil {
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+
+	for _, pc := range results.Association.PresentationContexts {
+		if scanner.config.NoEcho || !pc.Accepted || pc.AbstractSyntax != verificationUID {
+			continue
+		}
+		echoStatus, err := echo(conn, pc.ID)
+		if err != nil {
+			log.Debugf("error sending C-ECHO to target %s: %v", target.String(), err)
+			return zgrab2.SCAN_SUCCESS, results, nil
+		}
+		results.EchoStatus = &echoStatus
+	}
+	if _, err = conn.Write(releaseRequest()); err != nil {
+		log.Debugf("error releasing association with target %s: %v", target.String(), err)
+	} else if p, err = readPDU(conn); err != nil {
+		log.Debugf("error reading release response from target %s: %v", target.String(), err)
+	} else {
+		results.Released = p.typ == pduReleaseRP
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
+
+// status maps an error to a scan status, treating malformed and truncated PDUs as protocol errors.
+func status(err error) zgrab2.ScanStatus {
+	if errors.Is(err, errInvalidPDU) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return zgrab2.SCAN_PROTOCOL_ERROR
+	}
+	return zgrab2.TryGetScanStatus(err)
+}
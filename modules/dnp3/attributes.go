@@ -0,0 +1,188 @@
+package dnp3
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Data type codes of device attributes.
+const (
+	ATTRIBUTE_TYPE_VSTR = 1 // visible string
+	ATTRIBUTE_TYPE_UINT = 2 // unsigned integer
+	ATTRIBUTE_TYPE_INT  = 3 // signed integer
+	ATTRIBUTE_TYPE_FLT  = 4 // floating point
+)
+
+var attributeNames = map[uint8]string{
+	0xF0:                          "max_transmit_fragment_size",
+	0xF1:                          "max_receive_fragment_size",
+	APP_GROUP_0_SOFTWARE_VERSION:  "software_version",
+	APP_GROUP_0_HARDWARE_VERSION:  "hardware_version",
+	0xF4:                          "owner_name",
+	APP_GROUP_0_LOCATION:          "location",
+	APP_GROUP_0_DEVICE_ID:         "device_id",
+	APP_GROUP_0_DEVICE_NAME:       "device_name",
+	APP_GROUP_0_SERIAL_NUMBER:     "serial_number",
+	APP_GROUP_0_DNP3_SUBSET:       "dnp3_subset",
+	APP_GROUP_0_PRODUCT_NAME:      "product_name",
+	APP_GROUP_0_MANUFACTURER_NAME: "manufacturer_name",
+	APP_GROUP_0_LIST_ATTRIBUTES:   "attribute_list",
+}
+
+// makeReadAttributesRequest returns a frame reading all device attributes of the outstation at dstAddress.
+func makeReadAttributesRequest(srcAddress uint16, dstAddress uint16) []byte {
+	payload := []byte{
+		TRANSPORT_FIN_BIT | TRANSPORT_FIR_BIT | TRANSPORT_START_SEQUENCE,
+		0xC0 | APP_START_SEQUENCE, // FIR and FIN
+		APP_FUNC_CODE_READ,
+		APP_GROUP_0,
+		APP_GROUP_0_ALL_ATTRIBUTES,
+		APP_QUALIFIER_ALL_OBJECTS,
+	}
+	return makeUserDataFrame(srcAddress, dstAddress, payload)
+}
+
+// decodeAttributeValue returns the decoded value of strings and numbers, or "" for other data types.
+func decodeAttributeValue(dataType uint8, raw []byte) string {
+	switch dataType {
+	case ATTRIBUTE_TYPE_VSTR:
+		return string(raw)
+	case ATTRIBUTE_TYPE_UINT, ATTRIBUTE_TYPE_INT:
+		if len(raw) == 0 || len(raw) > 8 {
+			return ""
+		}
+		buf := make([]byte, 8)
+		copy(buf, raw)
+		if dataType == ATTRIBUTE_TYPE_INT && raw[len(raw)-1]&0x80 != 0 {
+			for i := len(raw); i < 8; i++ {
+				buf[i] = 0xFF
+			}
+		}
+		value := binary.LittleEndian.Uint64(buf)
+		if dataType == ATTRIBUTE_TYPE_INT {
+			return strconv.FormatInt(int64(value), 10)
+		}
+		return strconv.FormatUint(value, 10)
+	case ATTRIBUTE_TYPE_FLT:
+		switch len(raw) {
+		case 4:
+			return strconv.FormatFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(raw))), 'g', -1, 32)
+		case 8:
+			return strconv.FormatFloat(math.Float64frombits(binary.LittleEndian.Uint64(raw)), 'g', -1, 64)
+		}
+	}
+	return ""
+}
+
+// parseDeviceAttributes parses the group 0 objects of a response, stopping at the first object header it cannot
+// parse.
+func parseDeviceAttributes(objects []byte) ([]DeviceAttribute, error) {
+	var ret []DeviceAttribute
+	for len(objects) > 0 {
+		if len(objects) < 3 {
+			return ret, errors.New("truncated object header")
+		}
+		group, variation, qualifier := objects[0], objects[1], objects[2]
+		if group != APP_GROUP_0 {
+			return ret, fmt.Errorf("unexpected object group %d", group)
+		}
+		var count int
+		switch qualifier {
+		case APP_QUALIFIER_START_STOP_8:
+			if len(objects) < 5 || objects[4] < objects[3] {
+				return ret, errors.New("invalid object range")
+			}
+			count = int(objects[4]-objects[3]) + 1
+			objects = objects[5:]
+		case APP_QUALIFIER_START_STOP_16:
+			if len(objects) < 7 {
+				return ret, errors.New("invalid object range")
+			}
+			start, stop := binary.LittleEndian.Uint16(objects[3:5]), binary.LittleEndian.Uint16(objects[5:7])
+			if stop < start {
+				return ret, errors.New("invalid object range")
+			}
+			count = int(stop-start) + 1
+			objects = objects[7:]
+		default:
+			return ret, fmt.Errorf("unsupported qualifier 0x%02x", qualifier)
+		}
+		for ; count > 0; count-- {
+			if len(objects) < 2 || len(objects) < 2+int(objects[1]) {
+				return ret, errors.New("truncated attribute")
+			}
+			dataType, raw := objects[0], objects[2:2+int(objects[1])]
+			ret = append(ret, DeviceAttribute{
+				Variation: variation,
+				Name:      attributeNames[variation],
+				DataType:  dataType,
+				Value:     decodeAttributeValue(dataType, raw),
+				Raw:       raw,
+			})
+			objects = objects[2+len(raw):]
+		}
+	}
+	return ret, nil
+}
+
+// readFragments reads frames from conn until handle returns true or the deadline passes, passing it each
+// reassembled application fragment.
+func readFragments(conn net.Conn, deadline time.Time, handle func(*ApplicationFragment) bool) error {
+	var reassembler transportReassembler
+	var pending []byte
+	buf := make([]byte, 4096)
+	for time.Now().Before(deadline) {
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			return err
+		}
+		n, err := conn.Read(buf)
+		var frames []linkFrame
+		frames, pending = parseLinkFrames(append(pending, buf[:n]...))
+		for i := range frames {
+			if fragment := reassembler.add(&frames[i]); fragment != nil && handle(fragment) {
+				return nil
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return errors.New("timed out waiting for response")
+}
+
+// readDeviceAttributes reads all device attributes from the outstation at dstAddress, recording any unsolicited
+// responses received meanwhile in logStruct.
+func readDeviceAttributes(logStruct *DNP3Log, conn net.Conn, srcAddress uint16, dstAddress uint16, timeout time.Duration) *DeviceAttributesLog {
+	ret := &DeviceAttributesLog{Source: srcAddress, Destination: dstAddress}
+	if _, err := conn.Write(makeReadAttributesRequest(srcAddress, dstAddress)); err != nil {
+		ret.Error = err.Error()
+		return ret
+	}
+	err := readFragments(conn, time.Now().Add(timeout), func(fragment *ApplicationFragment) bool {
+		switch fragment.FunctionCode {
+		case APP_FUNC_CODE_UNSOLICITED:
+			logStruct.UnsolicitedResponses = append(logStruct.UnsolicitedResponses, *fragment)
+			return false
+		case APP_FUNC_CODE_RESPONSE:
+			if fragment.Source != dstAddress {
+				return false
+			}
+			ret.IIN = fragment.IIN
+			var err error
+			if ret.Attributes, err = parseDeviceAttributes(fragment.Objects); err != nil {
+				ret.Error = err.Error()
+			}
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		ret.Error = err.Error()
+	}
+	return ret
+}
@@ -63,8 +63,15 @@ func init() {
 	linkBatchRequest = makeLinkRequestBatch(0x0000, 1, 0x0000, 100)
 }
 
+// GetDNP3Banner sends link status requests from address 0 to addresses 0-99 and records the response.
 func GetDNP3Banner(logStruct *DNP3Log, connection net.Conn) error {
-	if n, err := connection.Write(linkBatchRequest); err != nil {
+	return GetDNP3BannerWithRequest(logStruct, connection, linkBatchRequest)
+}
+
+// GetDNP3BannerWithRequest sends the given link status requests and records the response, along with the addresses
+// that responded and any unsolicited responses it contains.
+func GetDNP3BannerWithRequest(logStruct *DNP3Log, connection net.Conn, request []byte) error {
+	if n, err := connection.Write(request); err != nil {
 		return fmt.Errorf("error when writing link batch request after %d bytes: %w", n, err)
 	}
 
@@ -77,6 +84,11 @@ func GetDNP3Banner(logStruct *DNP3Log, connection net.Conn) error {
 	if len(data) >= LINK_MIN_HEADER_LENGTH && binary.BigEndian.Uint16(data[0:2]) == LINK_START_FIELD {
 		logStruct.IsDNP3 = true
 		logStruct.RawResponse = data
+		frames, _ := parseLinkFrames(data)
+		var reassembler transportReassembler
+		for i := range frames {
+			logStruct.addFrame(&frames[i], &reassembler)
+		}
 		return nil
 	}
 
@@ -149,3 +161,25 @@ func makeLinkRequestBatch(startingSrcAddress uint16, numberSrc int, startingDest
 
 	return batchRequest
 }
+
+// addFrame records the addresses of a response frame, and the unsolicited response it may complete.
+func (logStruct *DNP3Log) addFrame(frame *linkFrame, reassembler *transportReassembler) {
+	if !frame.isPrimary() {
+		responder := LinkAddress{
+			Source:       frame.source,
+			Destination:  frame.destination,
+			FunctionCode: frame.functionCode(),
+			Function:     frame.functionName(),
+		}
+		for _, known := range logStruct.Responders {
+			if known == responder {
+				return
+			}
+		}
+		logStruct.Responders = append(logStruct.Responders, responder)
+		return
+	}
+	if fragment := reassembler.add(frame); fragment != nil && fragment.FunctionCode == APP_FUNC_CODE_UNSOLICITED {
+		logStruct.UnsolicitedResponses = append(logStruct.UnsolicitedResponses, *fragment)
+	}
+}
@@ -0,0 +1,228 @@
+package dnp3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Link and application constants beyond the ones used by the original banner grab.
+const (
+	LINK_DATA_BLOCK_SIZE          = 16   // user data is split into blocks of 16 bytes, each followed by a CRC
+	LINK_CONFIRMED_USER_DATA_FC   = 0x3  // primary function code for user data requiring a link confirmation
+	TRANSPORT_FIN_BIT             = 0x80 // final segment of a fragment
+	TRANSPORT_FIR_BIT             = 0x40 // first segment of a fragment
+	APP_FUNC_CODE_RESPONSE        = 0x81 // response to a request
+	APP_FUNC_CODE_UNSOLICITED     = 0x82 // unsolicited response
+	APP_QUALIFIER_START_STOP_8    = 0x00 // 1-byte start and stop indexes
+	APP_QUALIFIER_START_STOP_16   = 0x01 // 2-byte start and stop indexes
+	APP_QUALIFIER_ALL_OBJECTS     = 0x06 // no range, all objects
+	APP_MAX_FRAGMENT_SIZE         = 2048 // the largest application fragment reassembled
+	LINK_MAX_ADDRESS_PAIR_PROBES  = 4096 // the maximum number of link status requests sent
+	APP_GROUP_0_MANUFACTURER_NAME = 0xFC // group 0 attribute - device manufacturer's name
+)
+
+var primaryFunctionNames = map[uint8]string{
+	0x0: "RESET_LINK_STATES",
+	0x2: "TEST_LINK_STATES",
+	0x3: "CONFIRMED_USER_DATA",
+	0x4: "UNCONFIRMED_USER_DATA",
+	0x9: "REQUEST_LINK_STATUS",
+}
+
+var secondaryFunctionNames = map[uint8]string{
+	0x0: "ACK",
+	0x1: "NACK",
+	0xB: "LINK_STATUS",
+	0xF: "NOT_SUPPORTED",
+}
+
+var appFunctionNames = map[uint8]string{
+	APP_FUNC_CODE_RESPONSE:    "RESPONSE",
+	APP_FUNC_CODE_UNSOLICITED: "UNSOLICITED_RESPONSE",
+	0x83:                      "AUTHENTICATE_RESPONSE",
+}
+
+// linkFrame is a link layer frame with valid CRCs.
+type linkFrame struct {
+	control     byte
+	destination uint16
+	source      uint16
+	userData    []byte
+}
+
+func (f *linkFrame) isPrimary() bool {
+	return f.control&0x40 != 0
+}
+
+func (f *linkFrame) functionCode() uint8 {
+	return f.control & 0x0F
+}
+
+func (f *linkFrame) functionName() string {
+	if f.isPrimary() {
+		return primaryFunctionNames[f.functionCode()]
+	}
+	return secondaryFunctionNames[f.functionCode()]
+}
+
+// isUserData tells whether the frame carries a transport segment.
+func (f *linkFrame) isUserData() bool {
+	return f.isPrimary() && (f.functionCode() == LINK_CONFIRMED_USER_DATA_FC || f.functionCode() == LINK_UNCONFIRMED_USER_DATA_FC)
+}
+
+// linkFrameSize returns the size on the wire of a frame whose length field is length.
+func linkFrameSize(length byte) int {
+	n := int(length) - 5
+	return LINK_MIN_HEADER_LENGTH + n + 2*((n+LINK_DATA_BLOCK_SIZE-1)/LINK_DATA_BLOCK_SIZE)
+}
+
+// parseLinkFrames parses the complete frames in data, skipping any bytes that do not start a frame and frames with
+// invalid CRCs. It returns the frames and the trailing bytes of an incomplete frame.
+func parseLinkFrames(data []byte) ([]linkFrame, []byte) {
+	var ret []linkFrame
+	start := []byte{LINK_START_FIELD >> 8, LINK_START_FIELD & 0xFF}
+	for {
+		i := bytes.Index(data, start)
+		if i < 0 {
+			if len(data) > 0 && data[len(data)-1] == start[0] {
+				return ret, data[len(data)-1:]
+			}
+			return ret, nil
+		}
+		data = data[i:]
+		if len(data) < LINK_MIN_HEADER_LENGTH {
+			return ret, data
+		}
+		if data[2] < 5 || Crc16(data[:8]) != binary.LittleEndian.Uint16(data[8:10]) {
+			data = data[2:]
+			continue
+		}
+		size := linkFrameSize(data[2])
+		if len(data) < size {
+			return ret, data
+		}
+		frame := linkFrame{
+			control:     data[3],
+			destination: binary.LittleEndian.Uint16(data[4:6]),
+			source:      binary.LittleEndian.Uint16(data[6:8]),
+		}
+		valid := true
+		for block := data[LINK_MIN_HEADER_LENGTH:size]; len(block) > 0; {
+			n := min(LINK_DATA_BLOCK_SIZE, len(block)-2)
+			if Crc16(block[:n]) != binary.LittleEndian.Uint16(block[n:n+2]) {
+				valid = false
+				break
+			}
+			frame.userData = append(frame.userData, block[:n]...)
+			block = block[n+2:]
+		}
+		if valid {
+			ret = append(ret, frame)
+		}
+		data = data[size:]
+	}
+}
+
+// makeUserDataFrame returns an unconfirmed user data frame carrying payload, which must fit in a single frame.
+func makeUserDataFrame(srcAddress uint16, dstAddress uint16, payload []byte) []byte {
+	frame := makeLinkHeader(srcAddress, dstAddress, LINK_UNCONFIRMED_USER_DATA_FC, len(payload))
+	for len(payload) > 0 {
+		n := min(LINK_DATA_BLOCK_SIZE, len(payload))
+		frame = append(frame, payload[:n]...)
+		frame = binary.LittleEndian.AppendUint16(frame, Crc16(payload[:n]))
+		payload = payload[n:]
+	}
+	return frame
+}
+
+// transportReassembler reassembles the transport segments of user data frames into application fragments.
+type transportReassembler struct {
+	pending map[uint16][]byte
+}
+
+// add adds the segment of a user data frame, and returns the application fragment if the segment completes one.
+func (r *transportReassembler) add(frame *linkFrame) *ApplicationFragment {
+	if !frame.isUserData() || len(frame.userData) < 1 {
+		return nil
+	}
+	if r.pending == nil {
+		r.pending = make(map[uint16][]byte)
+	}
+	header := frame.userData[0]
+	segment := frame.userData[1:]
+	if header&TRANSPORT_FIR_BIT != 0 {
+		r.pending[frame.source] = append([]byte{}, segment...)
+	} else if data, ok := r.pending[frame.source]; ok && len(data)+len(segment) <= APP_MAX_FRAGMENT_SIZE {
+		r.pending[frame.source] = append(data, segment...)
+	} else {
+		delete(r.pending, frame.source)
+		return nil
+	}
+	if header&TRANSPORT_FIN_BIT == 0 {
+		return nil
+	}
+	data := r.pending[frame.source]
+	delete(r.pending, frame.source)
+	if len(data) < 4 {
+		return nil
+	}
+	// application control, function code, and for responses the 2-byte IIN
+	ret := &ApplicationFragment{
+		Source:       frame.source,
+		Destination:  frame.destination,
+		FunctionCode: data[1],
+		Function:     appFunctionNames[data[1]],
+		IIN:          binary.LittleEndian.Uint16(data[2:4]),
+	}
+	if len(data) > 4 {
+		ret.Objects = data[4:]
+	}
+	return ret
+}
+
+// parseAddresses parses a comma-separated list of link addresses and ranges, like "0-99,65519".
+func parseAddresses(s string) ([]uint16, error) {
+	var ret []uint16
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.ParseUint(strings.TrimSpace(first), 0, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid link address %q", first)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.ParseUint(strings.TrimSpace(last), 0, 16); err != nil {
+				return nil, fmt.Errorf("invalid link address %q", last)
+			}
+			if end < start {
+				return nil, fmt.Errorf("invalid link address range %q", part)
+			}
+		}
+		if len(ret)+int(end-start) >= LINK_MAX_ADDRESS_PAIR_PROBES {
+			return nil, fmt.Errorf("more than %d link addresses in %q", LINK_MAX_ADDRESS_PAIR_PROBES, s)
+		}
+		for addr := start; addr <= end; addr++ {
+			ret = append(ret, uint16(addr))
+		}
+	}
+	return ret, nil
+}
+
+// makeLinkRequests returns a link status request from each of the source addresses to each of the destination
+// addresses.
+func makeLinkRequests(sources []uint16, destinations []uint16) []byte {
+	var ret []byte
+	for _, src := range sources {
+		for _, dst := range destinations {
+			ret = append(ret, makeLinkHeader(src, dst, LINK_REQUEST_STATUS_FC, 0)...)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,163 @@
+package dnp3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"slices"
+	"testing"
+	"time"
+)
+
+// outstationFrame returns a frame from the outstation at src with the given control byte and user data.
+func outstationFrame(src uint16, dst uint16, control byte, payload []byte) []byte {
+	frame := []byte{0x05, 0x64, byte(5 + len(payload)), control}
+	frame = binary.LittleEndian.AppendUint16(frame, dst)
+	frame = binary.LittleEndian.AppendUint16(frame, src)
+	frame = binary.LittleEndian.AppendUint16(frame, Crc16(frame))
+	for len(payload) > 0 {
+		n := min(LINK_DATA_BLOCK_SIZE, len(payload))
+		frame = append(frame, payload[:n]...)
+		frame = binary.LittleEndian.AppendUint16(frame, Crc16(payload[:n]))
+		payload = payload[n:]
+	}
+	return frame
+}
+
+// attribute encodes a group 0 object with a 1-byte start/stop range.
+func attribute(variation byte, dataType byte, value []byte) []byte {
+	return append([]byte{APP_GROUP_0, variation, APP_QUALIFIER_START_STOP_8, 0, 0, dataType, byte(len(value))}, value...)
+}
+
+func TestParseLinkFrames(t *testing.T) {
+	payload := bytes.Repeat([]byte{0xAB}, 40)
+	data := append([]byte{0x00, 0x05}, makeUserDataFrame(3, 10, payload)...)
+	corrupt := makeLinkHeader(1, 2, LINK_REQUEST_STATUS_FC, 0)
+	corrupt[5] ^= 0xFF
+	data = append(data, corrupt...)
+	data = append(data, makeLinkHeader(4, 5, LINK_REQUEST_STATUS_FC, 0)...)
+	next := makeLinkHeader(6, 7, LINK_REQUEST_STATUS_FC, 0)
+	data = append(data, next[:6]...)
+
+	frames, rest := parseLinkFrames(data)
+	if len(frames) != 2 {
+		t.Fatalf("frames = %+v", frames)
+	}
+	if frames[0].source != 3 || frames[0].destination != 10 || !bytes.Equal(frames[0].userData, payload) || !frames[0].isUserData() {
+		t.Errorf("frame 0 = %+v", frames[0])
+	}
+	if frames[1].source != 4 || frames[1].functionName() != "REQUEST_LINK_STATUS" {
+		t.Errorf("frame 1 = %+v", frames[1])
+	}
+	if !bytes.Equal(rest, next[:6]) {
+		t.Errorf("rest = %x", rest)
+	}
+}
+
+func TestParseAddresses(t *testing.T) {
+	addresses, err := parseAddresses("0-2, 65519,0x10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(addresses, []uint16{0, 1, 2, 65519, 16}) {
+		t.Errorf("addresses = %v", addresses)
+	}
+	for _, invalid := range []string{"2-1", "65536", "0-65535"} {
+		if _, err := parseAddresses(invalid); err == nil {
+			t.Errorf("%q parsed", invalid)
+		}
+	}
+}
+
+func TestGetDNP3BannerWithRequest(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	request := makeLinkRequests([]uint16{0}, []uint16{9, 10})
+	unsolicited := append([]byte{0xF0, 0x82, 0x90, 0x00}, bytes.Repeat([]byte{0x01}, 20)...)
+	go func() {
+		defer server.Close()
+		if _, err := io.ReadFull(server, make([]byte, len(request))); err != nil {
+			return
+		}
+		var response []byte
+		response = append(response, outstationFrame(10, 0, 0x0B, nil)...)
+		// unsolicited response split into two transport segments
+		response = append(response, outstationFrame(10, 0, 0xC4, append([]byte{TRANSPORT_FIR_BIT}, unsolicited[:10]...))...)
+		response = append(response, outstationFrame(10, 0, 0xC4, append([]byte{TRANSPORT_FIN_BIT | 1}, unsolicited[10:]...))...)
+		server.Write(response)
+	}()
+
+	logStruct := new(DNP3Log)
+	if err := GetDNP3BannerWithRequest(logStruct, client, request); err != nil {
+		t.Fatal(err)
+	}
+	if !logStruct.IsDNP3 {
+		t.Error("not detected as DNP3")
+	}
+	expected := []LinkAddress{{Source: 10, Destination: 0, FunctionCode: LINK_STATUS_FC, Function: "LINK_STATUS"}}
+	if !slices.Equal(logStruct.Responders, expected) {
+		t.Errorf("responders = %+v", logStruct.Responders)
+	}
+	if len(logStruct.UnsolicitedResponses) != 1 {
+		t.Fatalf("unsolicited = %+v", logStruct.UnsolicitedResponses)
+	}
+	fragment := logStruct.UnsolicitedResponses[0]
+	if fragment.Function != "UNSOLICITED_RESPONSE" || fragment.IIN != 0x0090 || !bytes.Equal(fragment.Objects, unsolicited[4:]) {
+		t.Errorf("unsolicited = %+v", fragment)
+	}
+}
+
+func TestReadDeviceAttributes(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	objects := attribute(APP_GROUP_0_PRODUCT_NAME, ATTRIBUTE_TYPE_VSTR, []byte("RTU-5000"))
+	objects = append(objects, attribute(0xF0, ATTRIBUTE_TYPE_UINT, []byte{0x00, 0x08})...)
+	objects = append(objects, attribute(APP_GROUP_0_SOFTWARE_VERSION, ATTRIBUTE_TYPE_VSTR, []byte("2.1.0"))...)
+	go func() {
+		defer server.Close()
+		expected := makeReadAttributesRequest(0, 10)
+		received := make([]byte, len(expected))
+		if _, err := io.ReadFull(server, received); err != nil || !bytes.Equal(received, expected) {
+			return
+		}
+		application := append([]byte{0xC0, APP_FUNC_CODE_RESPONSE, 0x00, 0x00}, objects...)
+		for first := true; len(application) > 0; first = false {
+			n := min(200, len(application))
+			header := byte(0)
+			if first {
+				header |= TRANSPORT_FIR_BIT
+			}
+			if n == len(application) {
+				header |= TRANSPORT_FIN_BIT
+			}
+			server.Write(outstationFrame(10, 0, 0x44, append([]byte{header}, application[:n]...)))
+			application = application[n:]
+		}
+	}()
+
+	logStruct := new(DNP3Log)
+	attributes := readDeviceAttributes(logStruct, client, 0, 10, time.Second)
+	if attributes.Error != "" {
+		t.Fatal(attributes.Error)
+	}
+	var values []string
+	for _, attr := range attributes.Attributes {
+		values = append(values, attr.Name+"="+attr.Value)
+	}
+	if !slices.Equal(values, []string{"product_name=RTU-5000", "max_transmit_fragment_size=2048", "software_version=2.1.0"}) {
+		t.Errorf("attributes = %v", values)
+	}
+}
+
+func TestDecodeAttributeValue(t *testing.T) {
+	if v := decodeAttributeValue(ATTRIBUTE_TYPE_INT, []byte{0xFE, 0xFF}); v != "-2" {
+		t.Errorf("int = %s", v)
+	}
+	if v := decodeAttributeValue(ATTRIBUTE_TYPE_FLT, []byte{0x00, 0x00, 0xC0, 0x3F}); v != "1.5" {
+		t.Errorf("float = %s", v)
+	}
+	if v := decodeAttributeValue(5, []byte{0x01}); v != "" {
+		t.Errorf("octet string = %s", v)
+	}
+}
@@ -3,4 +3,71 @@ package dnp3
 type DNP3Log struct {
 	IsDNP3      bool   `json:"is_dnp3"`
 	RawResponse []byte `json:"raw_response,omitempty"`
+
+	// Responders are the link addresses that answered the link status requests.
+	Responders []LinkAddress `json:"responders,omitempty"`
+
+	// UnsolicitedResponses are the unsolicited application responses the outstation sent on its own.
+	UnsolicitedResponses []ApplicationFragment `json:"unsolicited_responses,omitempty"`
+
+	// DeviceAttributes is the response to reading the device attributes (group 0). Only present if --read-attributes
+	// is set and an outstation responded.
+	DeviceAttributes *DeviceAttributesLog `json:"device_attributes,omitempty"`
+}
+
+// LinkAddress is a pair of link addresses that exchanged a frame.
+type LinkAddress struct {
+	// Source is the address of the outstation.
+	Source uint16 `json:"source"`
+
+	// Destination is the master address the outstation answered to.
+	Destination uint16 `json:"destination"`
+
+	// FunctionCode is the link function code of the response, usually LINK_STATUS (11).
+	FunctionCode uint8 `json:"function_code"`
+
+	Function string `json:"function,omitempty"`
+}
+
+// ApplicationFragment is a reassembled application layer response.
+type ApplicationFragment struct {
+	Source       uint16 `json:"source"`
+	Destination  uint16 `json:"destination"`
+	FunctionCode uint8  `json:"function_code"`
+	Function     string `json:"function,omitempty"`
+
+	// IIN holds the internal indication bits, with IIN1 in the low byte.
+	IIN uint16 `json:"iin"`
+
+	// Objects is the raw object data following the application header.
+	Objects []byte `json:"objects,omitempty"`
+}
+
+// DeviceAttributesLog holds the device attributes read from an outstation.
+type DeviceAttributesLog struct {
+	// Source is the master address the request was sent from.
+	Source uint16 `json:"source"`
+
+	// Destination is the address of the outstation.
+	Destination uint16 `json:"destination"`
+
+	// IIN holds the internal indication bits of the response, with IIN1 in the low byte.
+	IIN uint16 `json:"iin"`
+
+	Attributes []DeviceAttribute `json:"attributes,omitempty"`
+
+	// Error is set if there was no valid response.
+	Error string `json:"error,omitempty"`
+}
+
+// DeviceAttribute is a single group 0 object.
+type DeviceAttribute struct {
+	Variation uint8  `json:"variation"`
+	Name      string `json:"name,omitempty"`
+	DataType  uint8  `json:"data_type"`
+
+	// Value is the decoded value of strings and numbers.
+	Value string `json:"value,omitempty"`
+
+	Raw []byte `json:"raw,omitempty"`
 }
@@ -1,14 +1,20 @@
 // Package dnp3 provides a zgrab2 module that scans for dnp3.
 // Default port: 20000 (TCP)
 //
-// Copied unmodified from the original zgrab.
-// Connects, and reads the banner. Returns the raw response.
+// Connects, sends a link status request from each of the --source-addresses
+// to each of the --destination-addresses, and reads the banner. Returns the
+// raw response, the addresses that responded and any unsolicited responses.
+//
+// The --unsolicited-wait flag keeps reading for unsolicited responses after
+// the banner, and --read-attributes reads the device attributes (group 0)
+// of the first outstation that responded.
 package dnp3
 
 import (
 	"context"
 	"fmt"
 	"net"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -18,7 +24,12 @@ import (
 // Flags holds the command-line configuration for the dnp3 scan module.
 // Populated by the framework.
 type Flags struct {
-	zgrab2.BaseFlags `group:"Basic Options"` // TODO: Support UDP?
+	zgrab2.BaseFlags     `group:"Basic Options"` // TODO: Support UDP?
+	SourceAddresses      string                  `long:"source-addresses" default:"0" description:"Comma-separated list of master link addresses and ranges to send the link status requests from"`
+	DestinationAddresses string                  `long:"destination-addresses" default:"0-99" description:"Comma-separated list of outstation link addresses and ranges to send the link status requests to"`
+	UnsolicitedWait      time.Duration           `long:"unsolicited-wait" default:"0s" description:"How long to keep reading for unsolicited responses after the link status responses"`
+	ReadAttributes       bool                    `long:"read-attributes" description:"Read the device attributes (group 0) of the first outstation that responded"`
+	ResponseTimeout      time.Duration           `long:"response-timeout" default:"2s" description:"How long to wait for the response to the device attributes request"`
 }
 
 // Module implements the zgrab2.Module interface.
@@ -29,6 +40,7 @@ type Module struct {
 type Scanner struct {
 	config            *Flags
 	dialerGroupConfig *zgrab2.DialerGroupConfig
+	linkRequests      []byte
 }
 
 // RegisterModule registers the zgrab2 module.
@@ -59,7 +71,27 @@ func (module *Module) Description() string {
 // On success, returns nil.
 // On failure, returns an error instance describing the error.
 func (flags *Flags) Validate(_ []string) error {
-	return nil
+	_, err := flags.linkRequests()
+	return err
+}
+
+// linkRequests returns the link status requests for the configured addresses.
+func (flags *Flags) linkRequests() ([]byte, error) {
+	sources, err := parseAddresses(flags.SourceAddresses)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --source-addresses: %w", err)
+	}
+	destinations, err := parseAddresses(flags.DestinationAddresses)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --destination-addresses: %w", err)
+	}
+	if len(sources) == 0 || len(destinations) == 0 {
+		return nil, fmt.Errorf("no link addresses to probe")
+	}
+	if len(sources)*len(destinations) > LINK_MAX_ADDRESS_PAIR_PROBES {
+		return nil, fmt.Errorf("more than %d link address pairs to probe", LINK_MAX_ADDRESS_PAIR_PROBES)
+	}
+	return makeLinkRequests(sources, destinations), nil
 }
 
 // Help returns the module's help string.
@@ -71,6 +103,11 @@ func (flags *Flags) Help() string {
 func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
 	f, _ := flags.(*Flags)
 	scanner.config = f
+	requests, err := f.linkRequests()
+	if err != nil {
+		return err
+	}
+	scanner.linkRequests = requests
 	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
 		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
 		BaseFlags:                       &f.BaseFlags,
@@ -108,7 +145,8 @@ func (scanner *Scanner) GetScanMetadata() any {
 }
 
 // Scan probes for a DNP3 service.
-// Connects to the configured TCP port (default 20000) and reads the banner.
+// Connects to the configured TCP port (default 20000) and reads the banner, then
+// optionally waits for unsolicited responses and reads the device attributes.
 func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
 	conn, err := dialGroup.Dial(ctx, target)
 	if err != nil {
@@ -119,8 +157,21 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 		zgrab2.CloseConnAndHandleError(conn)
 	}(conn)
 	ret := new(DNP3Log)
-	if err = GetDNP3Banner(ret, conn); err != nil {
+	if err = GetDNP3BannerWithRequest(ret, conn, scanner.linkRequests); err != nil {
 		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("could not get DNP3 banner for target %s: %w", target.String(), err)
 	}
+	if scanner.config.UnsolicitedWait > 0 {
+		// The wait ends in a timeout error; a closed connection leaves nothing to read for the attributes either.
+		_ = readFragments(conn, time.Now().Add(scanner.config.UnsolicitedWait), func(fragment *ApplicationFragment) bool {
+			if fragment.FunctionCode == APP_FUNC_CODE_UNSOLICITED {
+				ret.UnsolicitedResponses = append(ret.UnsolicitedResponses, *fragment)
+			}
+			return false
+		})
+	}
+	if scanner.config.ReadAttributes && len(ret.Responders) > 0 {
+		responder := ret.Responders[0]
+		ret.DeviceAttributes = readDeviceAttributes(ret, conn, responder.Destination, responder.Source, scanner.config.ResponseTimeout)
+	}
 	return zgrab2.SCAN_SUCCESS, ret, nil
 }
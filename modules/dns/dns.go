@@ -0,0 +1,286 @@
+package dns
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// ednsPayloadSize is the UDP payload size advertised in EDNS queries, as recommended by DNS Flag Day 2020.
+const ednsPayloadSize = 1232
+
+// optionNSID is the EDNS option code of the name server identifier (RFC 5001).
+const optionNSID = 3
+
+var typeNames = map[string]dnsmessage.Type{
+	"A":      dnsmessage.TypeA,
+	"NS":     dnsmessage.TypeNS,
+	"CNAME":  dnsmessage.TypeCNAME,
+	"SOA":    dnsmessage.TypeSOA,
+	"PTR":    dnsmessage.TypePTR,
+	"MX":     dnsmessage.TypeMX,
+	"TXT":    dnsmessage.TypeTXT,
+	"AAAA":   dnsmessage.TypeAAAA,
+	"SRV":    dnsmessage.TypeSRV,
+	"DS":     dnsmessage.Type(43),
+	"RRSIG":  dnsmessage.Type(46),
+	"DNSKEY": dnsmessage.Type(48),
+	"HTTPS":  dnsmessage.Type(65),
+	"ANY":    dnsmessage.TypeALL,
+	"CAA":    dnsmessage.Type(257),
+}
+
+var rcodeNames = map[int]string{
+	0:  "NOERROR",
+	1:  "FORMERR",
+	2:  "SERVFAIL",
+	3:  "NXDOMAIN",
+	4:  "NOTIMP",
+	5:  "REFUSED",
+	6:  "YXDOMAIN",
+	7:  "YXRRSET",
+	8:  "NXRRSET",
+	9:  "NOTAUTH",
+	10: "NOTZONE",
+	16: "BADVERS",
+	23: "BADCOOKIE",
+}
+
+// typeName returns the mnemonic of a type, or TYPE<n> for unknown types (RFC 3597).
+func typeName(t dnsmessage.Type) string {
+	for name, value := range typeNames {
+		if value == t && name != "ANY" {
+			return name
+		}
+	}
+	if t == dnsmessage.TypeALL {
+		return "ANY"
+	}
+	return "TYPE" + strconv.Itoa(int(t))
+}
+
+// rcodeName returns the mnemonic of a (possibly extended) response code.
+func rcodeName(rcode int) string {
+	if name, ok := rcodeNames[rcode]; ok {
+		return name
+	}
+	return "RCODE" + strconv.Itoa(rcode)
+}
+
+// Query is a question the scan asks.
+type Query struct {
+	Name  string
+	Type  dnsmessage.Type
+	Class dnsmessage.Class
+}
+
+// parseQueries parses a comma-separated list of name:type queries, e.g. example.com:A,example.com:AAAA. A query
+// without a type asks for A records.
+func parseQueries(s string) ([]Query, error) {
+	var queries []Query
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, typ, _ := strings.Cut(entry, ":")
+		if typ == "" {
+			typ = "A"
+		}
+		t, ok := typeNames[strings.ToUpper(typ)]
+		if !ok {
+			return nil, fmt.Errorf("unknown query type %q", typ)
+		}
+		if !strings.HasSuffix(name, ".") {
+			name += "."
+		}
+		if _, err := dnsmessage.NewName(name); err != nil {
+			return nil, fmt.Errorf("invalid query name %q: %w", name, err)
+		}
+		queries = append(queries, Query{Name: name, Type: t, Class: dnsmessage.ClassINET})
+	}
+	return queries, nil
+}
+
+// buildQuery returns a query with the recursion desired bit set. With edns, it carries an OPT record with the DO
+// bit and an NSID request.
+func buildQuery(id uint16, query Query, edns bool) ([]byte, error) {
+	name, err := dnsmessage.NewName(query.Name)
+	if err != nil {
+		return nil, err
+	}
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: query.Type, Class: query.Class}},
+	}
+	if edns {
+		var header dnsmessage.ResourceHeader
+		if err = header.SetEDNS0(ednsPayloadSize, dnsmessage.RCodeSuccess, true); err != nil {
+			return nil, err
+		}
+		msg.Additionals = []dnsmessage.Resource{{
+			Header: header,
+			Body:   &dnsmessage.OPTResource{Options: []dnsmessage.Option{{Code: optionNSID}}},
+		}}
+	}
+	return msg.Pack()
+}
+
+// matchesID returns a probe match function accepting responses with the given ID.
+func matchesID(id uint16) func(_, response []byte) bool {
+	return func(_, response []byte) bool {
+		var parser dnsmessage.Parser
+		header, err := parser.Start(response)
+		return err == nil && header.Response && header.ID == id
+	}
+}
+
+// frameTCP prefixes a message with its length, for DNS over TCP.
+func frameTCP(msg []byte) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(msg))), msg...)
+}
+
+// readTCP reads a length-prefixed message.
+func readTCP(r io.Reader) ([]byte, error) {
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// Answer is a resource record of the answer section.
+type Answer struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	TTL  uint32 `json:"ttl"`
+	Data string `json:"data"`
+}
+
+// EDNS describes the OPT record of a response.
+type EDNS struct {
+	Version uint8  `json:"version"`
+	UDPSize uint16 `json:"udp_size"`
+
+	// DO is true if the server echoed the DNSSEC OK bit.
+	DO bool `json:"do"`
+
+	// Options are the codes of the options the server returned.
+	Options []uint16 `json:"options,omitempty"`
+
+	// NSID is the name server identifier, if the server returned one.
+	NSID string `json:"nsid,omitempty"`
+}
+
+// Response is the answer to a query.
+type Response struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Class string `json:"class"`
+
+	RCode string `json:"rcode"`
+
+	Authoritative      bool `json:"authoritative"`
+	Truncated          bool `json:"truncated"`
+	RecursionDesired   bool `json:"recursion_desired"`
+	RecursionAvailable bool `json:"recursion_available"`
+	AuthenticData      bool `json:"authentic_data"`
+	CheckingDisabled   bool `json:"checking_disabled"`
+
+	Answers         []Answer `json:"answers,omitempty"`
+	AuthorityCount  int      `json:"authority_count"`
+	AdditionalCount int      `json:"additional_count"`
+
+	EDNS *EDNS `json:"edns,omitempty"`
+}
+
+// parseResponse decodes the response to query.
+func parseResponse(query Query, data []byte) (*Response, error) {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(data); err != nil {
+		return nil, err
+	}
+	class := "IN"
+	if query.Class == dnsmessage.ClassCHAOS {
+		class = "CH"
+	}
+	response := &Response{
+		Name:               query.Name,
+		Type:               typeName(query.Type),
+		Class:              class,
+		Authoritative:      msg.Authoritative,
+		Truncated:          msg.Truncated,
+		RecursionDesired:   msg.RecursionDesired,
+		RecursionAvailable: msg.RecursionAvailable,
+		AuthenticData:      msg.AuthenticData,
+		CheckingDisabled:   msg.CheckingDisabled,
+		AuthorityCount:     len(msg.Authorities),
+	}
+	rcode := msg.RCode
+	for _, additional := range msg.Additionals {
+		opt, ok := additional.Body.(*dnsmessage.OPTResource)
+		if !ok {
+			response.AdditionalCount++
+			continue
+		}
+		rcode = additional.Header.ExtendedRCode(msg.RCode)
+		response.EDNS = &EDNS{
+			Version: uint8(additional.Header.TTL >> 16),
+			UDPSize: uint16(additional.Header.Class),
+			DO:      additional.Header.DNSSECAllowed(),
+		}
+		for _, option := range opt.Options {
+			response.EDNS.Options = append(response.EDNS.Options, option.Code)
+			if option.Code == optionNSID {
+				response.EDNS.NSID = string(option.Data)
+			}
+		}
+	}
+	response.RCode = rcodeName(int(rcode))
+	for _, answer := range msg.Answers {
+		response.Answers = append(response.Answers, Answer{
+			Name: answer.Header.Name.String(),
+			Type: typeName(answer.Header.Type),
+			TTL:  answer.Header.TTL,
+			Data: formatBody(answer.Body),
+		})
+	}
+	return response, nil
+}
+
+// formatBody returns the presentation form of a record's data.
+func formatBody(body dnsmessage.ResourceBody) string {
+	switch body := body.(type) {
+	case *dnsmessage.AResource:
+		return net.IP(body.A[:]).String()
+	case *dnsmessage.AAAAResource:
+		return net.IP(body.AAAA[:]).String()
+	case *dnsmessage.CNAMEResource:
+		return body.CNAME.String()
+	case *dnsmessage.NSResource:
+		return body.NS.String()
+	case *dnsmessage.PTRResource:
+		return body.PTR.String()
+	case *dnsmessage.MXResource:
+		return fmt.Sprintf("%d %s", body.Pref, body.MX.String())
+	case *dnsmessage.TXTResource:
+		return strings.Join(body.TXT, "")
+	case *dnsmessage.SRVResource:
+		return fmt.Sprintf("%d %d %d %s", body.Priority, body.Weight, body.Port, body.Target.String())
+	case *dnsmessage.SOAResource:
+		return fmt.Sprintf("%s %s %d %d %d %d %d", body.NS.String(), body.MBox.String(), body.Serial, body.Refresh, body.Retry, body.Expire, body.MinTTL)
+	case *dnsmessage.UnknownResource:
+		return hex.EncodeToString(body.Data)
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+package dns
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestParseQueries(t *testing.T) {
+	queries, err := parseQueries("example.com, example.org:dnskey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Query{
+		{Name: "example.com.", Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET},
+		{Name: "example.org.", Type: dnsmessage.Type(48), Class: dnsmessage.ClassINET},
+	}
+	if !reflect.DeepEqual(queries, expected) {
+		t.Errorf("got %+v, expected %+v", queries, expected)
+	}
+	if _, err = parseQueries("example.com:BOGUS"); err == nil {
+		t.Error("expected error for unknown type")
+	}
+}
+
+func TestParseResponse(t *testing.T) {
+	query := Query{Name: "example.com.", Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}
+	packed, err := buildQuery(0x1234, query, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg dnsmessage.Message
+	if err = msg.Unpack(packed); err != nil {
+		t.Fatal(err)
+	}
+	if !msg.RecursionDesired || len(msg.Additionals) != 1 || !msg.Additionals[0].Header.DNSSECAllowed() {
+		t.Fatalf("unexpected query %+v", msg)
+	}
+
+	// answer the query as a validating resolver would
+	msg.Response, msg.RecursionAvailable, msg.AuthenticData = true, true, true
+	msg.Answers = []dnsmessage.Resource{{
+		Header: dnsmessage.ResourceHeader{Name: msg.Questions[0].Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 300},
+		Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}},
+	}}
+	msg.Additionals[0].Body = &dnsmessage.OPTResource{Options: []dnsmessage.Option{{Code: optionNSID, Data: []byte("ns1")}}}
+	if packed, err = msg.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if !matchesID(0x1234)(nil, packed) || matchesID(0x4321)(nil, packed) {
+		t.Error("unexpected ID match")
+	}
+	tcp, err := readTCP(bytes.NewReader(frameTCP(packed)))
+	if err != nil || !bytes.Equal(tcp, packed) {
+		t.Fatalf("TCP framing failed: %v", err)
+	}
+
+	response, err := parseResponse(query, packed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Response{
+		Name:               "example.com.",
+		Type:               "A",
+		Class:              "IN",
+		RCode:              "NOERROR",
+		RecursionDesired:   true,
+		RecursionAvailable: true,
+		AuthenticData:      true,
+		Answers:            []Answer{{Name: "example.com.", Type: "A", TTL: 300, Data: "192.0.2.1"}},
+		EDNS:               &EDNS{UDPSize: ednsPayloadSize, DO: true, Options: []uint16{optionNSID}, NSID: "ns1"},
+	}
+	if !reflect.DeepEqual(response, expected) {
+		t.Errorf("got %+v, expected %+v", response, expected)
+	}
+}
@@ -0,0 +1,239 @@
+// Package dns contains the zgrab2 Module implementation for DNS servers.
+//
+// The scan sends the configured queries with the recursion desired bit set, followed by the version.bind and
+// hostname.bind CHAOS TXT queries. Unless disabled, queries carry an EDNS OPT record with the DNSSEC OK bit and an
+// NSID request. For each query, it records the response code, header flags, answers and the EDNS record of the
+// response; a server that answers a recursive query with recursion available is an open resolver.
+package dns
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/zmap/zgrab2"
+)
+
+// chaosQueries identify the server software and instance.
+var chaosQueries = []Query{
+	{Name: "version.bind.", Type: dnsmessage.TypeTXT, Class: dnsmessage.ClassCHAOS},
+	{Name: "hostname.bind.", Type: dnsmessage.TypeTXT, Class: dnsmessage.ClassCHAOS},
+}
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	Responses []*Response `json:"responses,omitempty"`
+
+	// RecursionAvailable is true if any response had the RA bit set.
+	RecursionAvailable bool `json:"recursion_available"`
+
+	// OpenResolver is true if the server answered one of the configured queries with recursion available, NOERROR
+	// and a non-empty answer section.
+	OpenResolver bool `json:"open_resolver"`
+
+	// EDNS is true if the server returned an OPT record, and DNSSECOK if it echoed the DO bit.
+	EDNS     bool `json:"edns"`
+	DNSSECOK bool `json:"dnssec_ok"`
+
+	// Version and Hostname are the answers to version.bind and hostname.bind.
+	Version  string `json:"version,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+
+	// Probes records the probes, if the scan was made over UDP.
+	Probes []*zgrab2.UDPProbeResult `json:"probes,omitempty"`
+}
+
+// Flags are the DNS-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.UDPFlags
+
+	Queries string `long:"queries" default:"example.com:A" description:"Comma-separated name:type queries to send, e.g. example.com:A,example.com:DNSKEY"`
+	NoChaos bool   `long:"no-chaos" description:"Do not send the version.bind and hostname.bind CHAOS queries"`
+	NoEDNS  bool   `long:"no-edns" description:"Send queries without an EDNS OPT record"`
+	TCP     bool   `long:"tcp" description:"Send the queries over TCP instead of UDP"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+	queries           []Query
+}
+
+// RegisterModule registers the dns zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("dns", "DNS", module.Description(), 53, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Send DNS queries and record recursion, EDNS and DNSSEC handling"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	if _, err := parseQueries(f.Queries); err != nil {
+		return err
+	}
+	if !f.TCP {
+		return f.UDPFlags.Validate()
+	}
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "dns"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.queries, _ = parseQueries(f.Queries)
+	if !f.NoChaos {
+		scanner.queries = append(scanner.queries, chaosQueries...)
+	}
+	transport := zgrab2.TransportUDP
+	if f.TCP {
+		transport = zgrab2.TransportTCP
+	}
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: transport,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// exchange sends a query and returns the raw response.
+func (scanner *Scanner) exchange(ctx context.Context, conn net.Conn, target *zgrab2.ScanTarget, query Query, results *ScanResults) ([]byte, error) {
+	id := make([]byte, 2)
+	_, _ = rand.Read(id)
+	msg, err := buildQuery(binary.BigEndian.Uint16(id), query, !scanner.config.NoEDNS)
+	if err != nil {
+		return nil, err
+	}
+	if !scanner.config.TCP {
+		probe := zgrab2.NewStaticUDPProbe(typeName(query.Type)+" "+query.Name, msg, matchesID(binary.BigEndian.Uint16(id)))
+		result, err := zgrab2.SendUDPProbe(ctx, conn, probe, target, &scanner.config.UDPFlags)
+		results.Probes = append(results.Probes, result)
+		return result.Response, err
+	}
+	if _, err = conn.Write(frameTCP(msg)); err != nil {
+		return nil, err
+	}
+	return readTCP(conn)
+}
+
+// Scan performs the configured scan on the DNS server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	results := new(ScanResults)
+	var lastErr error
+	for _, query := range scanner.queries {
+		data, err := scanner.exchange(ctx, conn, target, query, results)
+		if err != nil {
+			lastErr = fmt.Errorf("query %s %s to target %s failed: %w", typeName(query.Type), query.Name, target.String(), err)
+			if scanner.config.TCP {
+				// the server closed the connection or stopped answering
+				break
+			}
+			if result := results.Probes[len(results.Probes)-1]; result.ICMP != "" {
+				break
+			}
+			continue
+		}
+		response, err := parseResponse(query, data)
+		if err != nil {
+			log.Debugf("invalid response from target %s: %v", target.String(), err)
+			lastErr = zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+			continue
+		}
+		results.Responses = append(results.Responses, response)
+		results.RecursionAvailable = results.RecursionAvailable || response.RecursionAvailable
+		if response.EDNS != nil {
+			results.EDNS = true
+			results.DNSSECOK = results.DNSSECOK || response.EDNS.DO
+		}
+		if query.Class == dnsmessage.ClassCHAOS {
+			if response.RCode == "NOERROR" && len(response.Answers) > 0 {
+				switch query.Name {
+				case "version.bind.":
+					results.Version = response.Answers[0].Data
+				case "hostname.bind.":
+					results.Hostname = response.Answers[0].Data
+				}
+			}
+		} else if response.RecursionAvailable && response.RCode == "NOERROR" && len(response.Answers) > 0 {
+			results.OpenResolver = true
+		}
+	}
+	if len(results.Responses) == 0 {
+		var partial any
+		if len(results.Probes) > 0 {
+			partial = results
+		}
+		return zgrab2.TryGetScanStatus(lastErr), partial, lastErr
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
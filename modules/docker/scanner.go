@@ -0,0 +1,237 @@
+// Package docker contains the zgrab2 Module implementation for exposed Docker Engine APIs.
+//
+// The scan requests /version and /info. A daemon that answers them without credentials gives full control over
+// the host; a daemon started with --tlsverify rejects the TLS handshake unless a client certificate is presented,
+// which the scan reports instead.
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+	"github.com/zmap/zgrab2/modules/httpapi"
+)
+
+// versionInfo is the response to GET /version.
+type versionInfo struct {
+	Version       string `json:"Version"`
+	APIVersion    string `json:"ApiVersion"`
+	MinAPIVersion string `json:"MinAPIVersion"`
+	GitCommit     string `json:"GitCommit"`
+	GoVersion     string `json:"GoVersion"`
+	Os            string `json:"Os"`
+	Arch          string `json:"Arch"`
+	KernelVersion string `json:"KernelVersion"`
+	Platform      struct {
+		Name string `json:"Name"`
+	} `json:"Platform"`
+}
+
+// systemInfo is the response to GET /info.
+type systemInfo struct {
+	ID                string `json:"ID"`
+	Name              string `json:"Name"`
+	OperatingSystem   string `json:"OperatingSystem"`
+	OSType            string `json:"OSType"`
+	Containers        int    `json:"Containers"`
+	ContainersRunning int    `json:"ContainersRunning"`
+	Images            int    `json:"Images"`
+	Swarm             struct {
+		LocalNodeState string `json:"LocalNodeState"`
+	} `json:"Swarm"`
+}
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	Version       string `json:"version,omitempty"`
+	APIVersion    string `json:"api_version,omitempty"`
+	MinAPIVersion string `json:"min_api_version,omitempty"`
+	GitCommit     string `json:"git_commit,omitempty"`
+	GoVersion     string `json:"go_version,omitempty"`
+	Os            string `json:"os,omitempty"`
+	Arch          string `json:"arch,omitempty"`
+	KernelVersion string `json:"kernel_version,omitempty"`
+	Platform      string `json:"platform,omitempty"`
+
+	ID                string `json:"id,omitempty"`
+	Name              string `json:"name,omitempty"`
+	OperatingSystem   string `json:"operating_system,omitempty"`
+	Containers        int    `json:"containers,omitempty"`
+	ContainersRunning int    `json:"containers_running,omitempty"`
+	Images            int    `json:"images,omitempty"`
+	SwarmNodeState    string `json:"swarm_node_state,omitempty"`
+
+	// Unauthenticated is true if the API answered without credentials.
+	Unauthenticated bool `json:"unauthenticated"`
+
+	// TLSRequired is true if the daemon rejected a plaintext request because it expects TLS.
+	TLSRequired bool `json:"tls_required,omitempty"`
+
+	// ClientCertRequired is true if the daemon rejected the TLS connection for lack of a client certificate.
+	ClientCertRequired bool `json:"client_cert_required,omitempty"`
+
+	VersionResponse *httpapi.Response `json:"version_response,omitempty"`
+	InfoResponse    *httpapi.Response `json:"info_response,omitempty"`
+
+	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
+}
+
+// Flags are the Docker-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.TLSFlags
+	httpapi.Flags
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the docker zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("docker", "Docker Engine API", module.Description(), 2375, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Query the version and info endpoints of a Docker Engine API (use --use-https for port 2376)"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "docker"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = httpapi.NewDialerGroupConfig(&f.BaseFlags, &f.TLSFlags)
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// isClientCertError returns true if err is the TLS alert a server sends when it requires a client certificate. With
+// TLS 1.3 the alert only arrives after the handshake, on the first read.
+func isClientCertError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "bad certificate") || strings.Contains(msg, "certificate required")
+}
+
+// Scan performs the configured scan on the Docker daemon.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	client := httpapi.NewClient(ctx, dialGroup, target, &scanner.config.Flags)
+	results := new(ScanResults)
+
+	var version versionInfo
+	var err error
+	results.VersionResponse, err = client.GetJSON("/version", &version)
+	results.TLSLog = client.TLSLog
+	if err != nil {
+		if scanner.config.UseHTTPS && isClientCertError(err) {
+			results.ClientCertRequired = true
+			return zgrab2.SCAN_SUCCESS, results, nil
+		}
+		if results.VersionResponse == nil && results.TLSLog == nil {
+			return zgrab2.TryGetScanStatus(err), nil, err
+		}
+		return zgrab2.TryGetScanStatus(err), results, err
+	}
+	resp := results.VersionResponse
+	if resp.StatusCode == 400 && strings.Contains(resp.Body, "HTTP request to an HTTPS server") {
+		results.TLSRequired = true
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+	if !resp.Success() || version.APIVersion == "" {
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("target %s is not a Docker daemon (status %d)", target.String(), resp.StatusCode)
+	}
+
+	results.Unauthenticated = true
+	results.Version = version.Version
+	results.APIVersion = version.APIVersion
+	results.MinAPIVersion = version.MinAPIVersion
+	results.GitCommit = version.GitCommit
+	results.GoVersion = version.GoVersion
+	results.Os = version.Os
+	results.Arch = version.Arch
+	results.KernelVersion = version.KernelVersion
+	results.Platform = version.Platform.Name
+
+	var info systemInfo
+	results.InfoResponse, err = client.GetJSON("/info", &info)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), results, err
+	}
+	if results.InfoResponse.Success() {
+		results.ID = info.ID
+		results.Name = info.Name
+		results.OperatingSystem = info.OperatingSystem
+		results.Containers = info.Containers
+		results.ContainersRunning = info.ContainersRunning
+		results.Images = info.Images
+		results.SwarmNodeState = info.Swarm.LocalNodeState
+		if results.Os == "" {
+			results.Os = info.OSType
+		}
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
@@ -0,0 +1,57 @@
+package docker
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/zmap/zgrab2"
+	"github.com/zmap/zgrab2/modules/httpapi/httpapitest"
+)
+
+// scan runs the scanner against a plaintext HTTP server with the given handler.
+func scan(t *testing.T, handler http.Handler) (zgrab2.ScanStatus, *ScanResults, error) {
+	t.Helper()
+	return httpapitest.Scan[ScanResults](t, &Scanner{config: new(Flags)}, handler)
+}
+
+func TestScanUnauthenticated(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"Platform":{"Name":"Docker Engine - Community"},"Version":"24.0.7","ApiVersion":"1.43","MinAPIVersion":"1.12","Os":"linux","Arch":"amd64","KernelVersion":"6.1.0"}`))
+	})
+	mux.HandleFunc("/info", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ID":"abcd","Name":"docker-host","OperatingSystem":"Debian GNU/Linux 12","Containers":5,"ContainersRunning":2,"Images":9,"Swarm":{"LocalNodeState":"inactive"}}`))
+	})
+	status, results, err := scan(t, mux)
+	if err != nil || status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status = %s, err = %v", status, err)
+	}
+	if !results.Unauthenticated || results.Version != "24.0.7" || results.APIVersion != "1.43" || results.Platform != "Docker Engine - Community" || results.Os != "linux" {
+		t.Errorf("version = %+v", results)
+	}
+	if results.Name != "docker-host" || results.Containers != 5 || results.ContainersRunning != 2 || results.Images != 9 || results.SwarmNodeState != "inactive" {
+		t.Errorf("info = %+v", results)
+	}
+}
+
+func TestScanTLSRequired(t *testing.T) {
+	// what the daemon answers on its TLS port to a plaintext request
+	status, results, err := scan(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Client sent an HTTP request to an HTTPS server.", http.StatusBadRequest)
+	}))
+	if err != nil || status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status = %s, err = %v", status, err)
+	}
+	if !results.TLSRequired || results.Unauthenticated {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestScanNotDocker(t *testing.T) {
+	status, _, err := scan(t, http.NotFoundHandler())
+	if err == nil || status != zgrab2.SCAN_PROTOCOL_ERROR {
+		t.Errorf("status = %s, err = %v", status, err)
+	}
+}
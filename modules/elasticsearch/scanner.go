@@ -0,0 +1,216 @@
+// Package elasticsearch contains the zgrab2 Module implementation for the Elasticsearch and OpenSearch REST API.
+//
+// The scan requests / and /_cluster/health and records the server version, cluster name and size, and whether the
+// API is reachable without authentication.
+package elasticsearch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+	"github.com/zmap/zgrab2/modules/httpapi"
+)
+
+// rootInfo is the response to GET /.
+type rootInfo struct {
+	Name        string `json:"name"`
+	ClusterName string `json:"cluster_name"`
+	ClusterUUID string `json:"cluster_uuid"`
+	Version     struct {
+		Number        string `json:"number"`
+		Distribution  string `json:"distribution"`
+		BuildFlavor   string `json:"build_flavor"`
+		BuildType     string `json:"build_type"`
+		BuildHash     string `json:"build_hash"`
+		BuildDate     string `json:"build_date"`
+		LuceneVersion string `json:"lucene_version"`
+	} `json:"version"`
+	Tagline string `json:"tagline"`
+}
+
+// clusterHealth is the response to GET /_cluster/health.
+type clusterHealth struct {
+	ClusterName       string `json:"cluster_name"`
+	Status            string `json:"status"`
+	NumberOfNodes     int    `json:"number_of_nodes"`
+	NumberOfDataNodes int    `json:"number_of_data_nodes"`
+	ActiveShards      int    `json:"active_shards"`
+}
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// Distribution is "elasticsearch" or "opensearch".
+	Distribution  string `json:"distribution,omitempty"`
+	Version       string `json:"version,omitempty"`
+	BuildFlavor   string `json:"build_flavor,omitempty"`
+	BuildHash     string `json:"build_hash,omitempty"`
+	LuceneVersion string `json:"lucene_version,omitempty"`
+	Tagline       string `json:"tagline,omitempty"`
+
+	NodeName          string `json:"node_name,omitempty"`
+	ClusterName       string `json:"cluster_name,omitempty"`
+	ClusterUUID       string `json:"cluster_uuid,omitempty"`
+	ClusterStatus     string `json:"cluster_status,omitempty"`
+	NumberOfNodes     int    `json:"number_of_nodes,omitempty"`
+	NumberOfDataNodes int    `json:"number_of_data_nodes,omitempty"`
+
+	// Unauthenticated is true if the API answered GET / without credentials.
+	Unauthenticated bool `json:"unauthenticated"`
+
+	Root   *httpapi.Response `json:"root,omitempty"`
+	Health *httpapi.Response `json:"health,omitempty"`
+
+	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
+}
+
+// Flags are the Elasticsearch-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.TLSFlags
+	httpapi.Flags
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the elasticsearch zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("elasticsearch", "Elasticsearch/OpenSearch REST API", module.Description(), 9200, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Query the root and cluster health endpoints of an Elasticsearch or OpenSearch node"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "elasticsearch"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = httpapi.NewDialerGroupConfig(&f.BaseFlags, &f.TLSFlags)
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// isSecurityChallenge returns true for the 401 that Elasticsearch/OpenSearch security sends.
+func isSecurityChallenge(resp *httpapi.Response) bool {
+	return (resp.StatusCode == 401 && strings.Contains(resp.WWWAuthenticate, `realm="security"`)) ||
+		(resp.Unauthorized() && strings.Contains(resp.Body, "security_exception"))
+}
+
+// Scan performs the configured scan on the Elasticsearch node.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	client := httpapi.NewClient(ctx, dialGroup, target, &scanner.config.Flags)
+	results := new(ScanResults)
+
+	var root rootInfo
+	var err error
+	results.Root, err = client.GetJSON("/", &root)
+	results.TLSLog = client.TLSLog
+	if results.Root == nil {
+		if results.TLSLog != nil {
+			return zgrab2.TryGetScanStatus(err), results, err
+		}
+		return zgrab2.TryGetScanStatus(err), nil, err
+	}
+	switch {
+	case err != nil:
+		return zgrab2.TryGetScanStatus(err), results, err
+	case isSecurityChallenge(results.Root):
+		// an Elasticsearch node with security enabled; there is nothing more to learn without credentials
+		return zgrab2.SCAN_SUCCESS, results, nil
+	case !results.Root.Success() || (root.Version.Number == "" && root.Tagline == ""):
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("target %s is not an Elasticsearch node (status %d)", target.String(), results.Root.StatusCode)
+	}
+
+	results.Unauthenticated = true
+	results.Distribution = root.Version.Distribution
+	if results.Distribution == "" {
+		results.Distribution = "elasticsearch"
+	}
+	results.Version = root.Version.Number
+	results.BuildFlavor = root.Version.BuildFlavor
+	results.BuildHash = root.Version.BuildHash
+	results.LuceneVersion = root.Version.LuceneVersion
+	results.Tagline = root.Tagline
+	results.NodeName = root.Name
+	results.ClusterName = root.ClusterName
+	results.ClusterUUID = root.ClusterUUID
+
+	var health clusterHealth
+	results.Health, err = client.GetJSON("/_cluster/health", &health)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), results, err
+	}
+	if results.Health.Success() {
+		results.ClusterStatus = health.Status
+		results.NumberOfNodes = health.NumberOfNodes
+		results.NumberOfDataNodes = health.NumberOfDataNodes
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
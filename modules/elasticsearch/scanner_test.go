@@ -0,0 +1,64 @@
+package elasticsearch
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/zmap/zgrab2"
+	"github.com/zmap/zgrab2/modules/httpapi/httpapitest"
+)
+
+// scan runs the scanner against a plaintext HTTP server with the given handler.
+func scan(t *testing.T, handler http.Handler) (zgrab2.ScanStatus, *ScanResults, error) {
+	t.Helper()
+	return httpapitest.Scan[ScanResults](t, &Scanner{config: new(Flags)}, handler)
+}
+
+func TestScanUnauthenticated(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{$}", httpapitest.JSONHandler(http.StatusOK, `{"name":"node-1","cluster_name":"logs","cluster_uuid":"Xq3kLZ2bQ","version":{"number":"8.12.2","build_flavor":"default","build_type":"docker","build_hash":"48a287ab","lucene_version":"9.9.2"},"tagline":"You Know, for Search"}`))
+	mux.HandleFunc("/_cluster/health", httpapitest.JSONHandler(http.StatusOK, `{"cluster_name":"logs","status":"yellow","number_of_nodes":3,"number_of_data_nodes":2,"active_shards":10}`))
+	status, results, err := scan(t, mux)
+	if err != nil || status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status = %s, err = %v", status, err)
+	}
+	if !results.Unauthenticated || results.Distribution != "elasticsearch" || results.Version != "8.12.2" || results.BuildFlavor != "default" || results.LuceneVersion != "9.9.2" {
+		t.Errorf("version = %+v", results)
+	}
+	if results.NodeName != "node-1" || results.ClusterName != "logs" || results.ClusterStatus != "yellow" || results.NumberOfNodes != 3 || results.NumberOfDataNodes != 2 {
+		t.Errorf("cluster = %+v", results)
+	}
+}
+
+func TestScanOpenSearch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{$}", httpapitest.JSONHandler(http.StatusOK, `{"name":"opensearch-node1","cluster_name":"opensearch-cluster","version":{"distribution":"opensearch","number":"2.11.1","lucene_version":"9.7.0"},"tagline":"The OpenSearch Project: https://opensearch.org/"}`))
+	mux.HandleFunc("/_cluster/health", httpapitest.JSONHandler(http.StatusForbidden, `{"error":{"type":"security_exception"},"status":403}`))
+	status, results, err := scan(t, mux)
+	if err != nil || status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status = %s, err = %v", status, err)
+	}
+	if !results.Unauthenticated || results.Distribution != "opensearch" || results.Version != "2.11.1" || results.ClusterStatus != "" || results.Health.StatusCode != http.StatusForbidden {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestScanSecurityEnabled(t *testing.T) {
+	status, results, err := scan(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="security" charset="UTF-8"`)
+		httpapitest.JSONHandler(http.StatusUnauthorized, `{"error":{"type":"security_exception","reason":"missing authentication credentials for REST request [/]"},"status":401}`)(w, r)
+	}))
+	if err != nil || status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status = %s, err = %v", status, err)
+	}
+	if results.Unauthenticated || results.Version != "" || results.Health != nil || results.Root.StatusCode != http.StatusUnauthorized {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestScanNotElasticsearch(t *testing.T) {
+	status, _, err := scan(t, httpapitest.JSONHandler(http.StatusOK, `{"name":"some other API"}`))
+	if err == nil || status != zgrab2.SCAN_PROTOCOL_ERROR {
+		t.Errorf("status = %s, err = %v", status, err)
+	}
+}
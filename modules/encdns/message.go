@@ -0,0 +1,157 @@
+package encdns
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// EDNS option codes the scan uses.
+const (
+	optionNSID    = 3
+	optionPadding = 12
+)
+
+// queryBlockSize is the block size queries are padded to, and responseBlockSize the one RFC 8467 recommends for
+// responses.
+const (
+	queryBlockSize    = 128
+	responseBlockSize = 468
+)
+
+// ednsPayloadSize is the payload size advertised in the OPT record.
+const ednsPayloadSize = 1232
+
+// buildQuery returns a query with the recursion desired bit set and an OPT record with an NSID request and padding
+// to a multiple of queryBlockSize.
+func buildQuery(id uint16, name string, qtype dnsmessage.Type, class dnsmessage.Class) ([]byte, error) {
+	n, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, err
+	}
+	var header dnsmessage.ResourceHeader
+	if err = header.SetEDNS0(ednsPayloadSize, dnsmessage.RCodeSuccess, false); err != nil {
+		return nil, err
+	}
+	opt := &dnsmessage.OPTResource{Options: []dnsmessage.Option{{Code: optionNSID}}}
+	msg := dnsmessage.Message{
+		Header:      dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions:   []dnsmessage.Question{{Name: n, Type: qtype, Class: class}},
+		Additionals: []dnsmessage.Resource{{Header: header, Body: opt}},
+	}
+	unpadded, err := msg.Pack()
+	if err != nil {
+		return nil, err
+	}
+	// the padding option itself takes four bytes
+	padding := (queryBlockSize - (len(unpadded)+4)%queryBlockSize) % queryBlockSize
+	opt.Options = append(opt.Options, dnsmessage.Option{Code: optionPadding, Data: make([]byte, padding)})
+	return msg.Pack()
+}
+
+// frame prefixes a message with its length, for DNS over TLS.
+func frame(msg []byte) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(msg))), msg...)
+}
+
+// readFramed reads a length-prefixed message.
+func readFramed(r io.Reader) ([]byte, error) {
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// Response summarizes a DNS response.
+type Response struct {
+	ID    uint16 `json:"-"`
+	RCode string `json:"rcode"`
+
+	RecursionAvailable bool `json:"recursion_available"`
+	AuthenticData      bool `json:"authentic_data"`
+
+	// Answers are the data of the answer records: addresses, names, or the text of TXT records.
+	Answers []string `json:"answers,omitempty"`
+
+	// NSID is the name server identifier, if the server returned one.
+	NSID string `json:"nsid,omitempty"`
+
+	// Length is the size of the response message, and Padded is true if it carried a padding option.
+	Length int  `json:"length"`
+	Padded bool `json:"padded"`
+}
+
+// parseResponse decodes a response.
+func parseResponse(data []byte) (*Response, error) {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(data); err != nil {
+		return nil, err
+	}
+	response := &Response{
+		ID:                 msg.ID,
+		RecursionAvailable: msg.RecursionAvailable,
+		AuthenticData:      msg.AuthenticData,
+		Length:             len(data),
+	}
+	rcode := msg.RCode
+	for _, additional := range msg.Additionals {
+		opt, ok := additional.Body.(*dnsmessage.OPTResource)
+		if !ok {
+			continue
+		}
+		rcode = additional.Header.ExtendedRCode(msg.RCode)
+		for _, option := range opt.Options {
+			switch option.Code {
+			case optionNSID:
+				response.NSID = string(option.Data)
+			case optionPadding:
+				response.Padded = true
+			}
+		}
+	}
+	response.RCode = rcodeName(rcode)
+	for _, answer := range msg.Answers {
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			response.Answers = append(response.Answers, net.IP(body.A[:]).String())
+		case *dnsmessage.AAAAResource:
+			response.Answers = append(response.Answers, net.IP(body.AAAA[:]).String())
+		case *dnsmessage.CNAMEResource:
+			response.Answers = append(response.Answers, body.CNAME.String())
+		case *dnsmessage.TXTResource:
+			var text string
+			for _, s := range body.TXT {
+				text += s
+			}
+			response.Answers = append(response.Answers, text)
+		}
+	}
+	return response, nil
+}
+
+// rcodeName returns the mnemonic of the common response codes, or RCODE<n>.
+func rcodeName(rcode dnsmessage.RCode) string {
+	switch rcode {
+	case dnsmessage.RCodeSuccess:
+		return "NOERROR"
+	case dnsmessage.RCodeFormatError:
+		return "FORMERR"
+	case dnsmessage.RCodeServerFailure:
+		return "SERVFAIL"
+	case dnsmessage.RCodeNameError:
+		return "NXDOMAIN"
+	case dnsmessage.RCodeNotImplemented:
+		return "NOTIMP"
+	case dnsmessage.RCodeRefused:
+		return "REFUSED"
+	}
+	return "RCODE" + strconv.Itoa(int(rcode))
+}
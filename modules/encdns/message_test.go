@@ -0,0 +1,69 @@
+package encdns
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestBuildQueryPadding(t *testing.T) {
+	for _, name := range []string{"example.com.", "a.very.long.name.under.a.subdomain.example.org."} {
+		query, err := buildQuery(1, name, dnsmessage.TypeA, dnsmessage.ClassINET)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(query)%queryBlockSize != 0 {
+			t.Errorf("query for %s is %d bytes, not a multiple of %d", name, len(query), queryBlockSize)
+		}
+		var msg dnsmessage.Message
+		if err = msg.Unpack(query); err != nil {
+			t.Fatal(err)
+		}
+		opt := msg.Additionals[0].Body.(*dnsmessage.OPTResource)
+		if len(opt.Options) != 2 || opt.Options[0].Code != optionNSID || opt.Options[1].Code != optionPadding {
+			t.Errorf("got options %+v", opt.Options)
+		}
+	}
+}
+
+func TestParseResponse(t *testing.T) {
+	var header dnsmessage.ResourceHeader
+	if err := header.SetEDNS0(1232, dnsmessage.RCodeSuccess, false); err != nil {
+		t.Fatal(err)
+	}
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: 7, Response: true, RecursionAvailable: true},
+		Answers: []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("version.bind."), Type: dnsmessage.TypeTXT, Class: dnsmessage.ClassCHAOS},
+			Body:   &dnsmessage.TXTResource{TXT: []string{"unbound 1.19.0"}},
+		}},
+		Additionals: []dnsmessage.Resource{{
+			Header: header,
+			Body: &dnsmessage.OPTResource{Options: []dnsmessage.Option{
+				{Code: optionNSID, Data: []byte("res1")},
+				{Code: optionPadding, Data: make([]byte, 20)},
+			}},
+		}},
+	}
+	data, err := msg.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := parseResponse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.ID != 7 || response.RCode != "NOERROR" || !response.RecursionAvailable || !response.Padded || response.NSID != "res1" {
+		t.Errorf("got %+v", response)
+	}
+	if len(response.Answers) != 1 || response.Answers[0] != "unbound 1.19.0" {
+		t.Errorf("got answers %v", response.Answers)
+	}
+
+	framed := frame(data)
+	read, err := readFramed(bytes.NewReader(framed))
+	if err != nil || !bytes.Equal(read, data) {
+		t.Errorf("framing round trip failed: %v", err)
+	}
+}
@@ -0,0 +1,297 @@
+// Package encdns contains the zgrab2 Module implementation for encrypted DNS: DNS over TLS (DoT, RFC 7858) and DNS
+// over HTTPS (DoH, RFC 8484).
+//
+// The scan sends a recursive A query for a benign name, followed by the version.bind CHAOS TXT query, either framed
+// over a TLS connection or POSTed as application/dns-message to the DoH path. Queries carry an NSID request and are
+// padded (RFC 7830, RFC 8467), so the results show whether the resolver pads its responses. The software is hinted
+// at by version.bind, the NSID and, for DoH, the Server header. The certificate is in the TLS log.
+package encdns
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/zmap/zgrab2"
+	"github.com/zmap/zgrab2/modules/httpapi"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// Protocol is dot or doh.
+	Protocol string `json:"protocol"`
+
+	// Response is the answer to the query for the configured name.
+	Response *Response `json:"response,omitempty"`
+
+	// Version is the answer to version.bind.
+	Version string `json:"version,omitempty"`
+
+	// PaddedToBlock is true if the response is padded to a multiple of the 468-byte block RFC 8467 recommends.
+	PaddedToBlock bool `json:"padded_to_block"`
+
+	// HTTPResponse summarizes the DoH response to the query, without the body, which is the DNS message.
+	HTTPResponse *httpapi.Response `json:"http_response,omitempty"`
+
+	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
+}
+
+// Flags are the encrypted DNS-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.TLSFlags
+
+	DoH       bool   `long:"doh" description:"Send the queries over HTTPS (DoH) instead of DNS over TLS"`
+	Path      string `long:"path" default:"/dns-query" description:"Path of the DoH endpoint"`
+	QueryName string `long:"query-name" default:"example.com" description:"Name whose A record is queried"`
+	NoChaos   bool   `long:"no-chaos" description:"Do not send the version.bind CHAOS query"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+	httpFlags         *httpapi.Flags
+}
+
+// RegisterModule registers the encdns zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("encdns", "Encrypted DNS (DoT/DoH)", module.Description(), 853, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Query a DNS over TLS or DNS over HTTPS resolver and record its padding, software hints and certificate"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	if !strings.HasPrefix(f.Path, "/") {
+		return fmt.Errorf("path must start with /, given %q", f.Path)
+	}
+	if !strings.HasSuffix(f.QueryName, ".") {
+		f.QueryName += "."
+	}
+	if _, err := dnsmessage.NewName(f.QueryName); err != nil {
+		return fmt.Errorf("invalid query-name %q: %w", f.QueryName, err)
+	}
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "encdns"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = httpapi.NewDialerGroupConfig(&f.BaseFlags, &f.TLSFlags)
+	scanner.httpFlags = &httpapi.Flags{UseHTTPS: true, UserAgent: "Mozilla/5.0 zgrab/0.x", MaxSize: 64}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// versionQuery is the CHAOS query for the server software version.
+const versionQuery = "version.bind."
+
+// queries returns the queries of the scan, with the given ID for the first one and the following IDs after it.
+func (scanner *Scanner) queries(id uint16) ([][]byte, error) {
+	query, err := buildQuery(id, scanner.config.QueryName, dnsmessage.TypeA, dnsmessage.ClassINET)
+	if err != nil {
+		return nil, err
+	}
+	queries := [][]byte{query}
+	if !scanner.config.NoChaos {
+		if query, err = buildQuery(id+1, versionQuery, dnsmessage.TypeTXT, dnsmessage.ClassCHAOS); err != nil {
+			return nil, err
+		}
+		queries = append(queries, query)
+	}
+	return queries, nil
+}
+
+// record stores the responses to the query and to the version query in the results.
+func (results *ScanResults) record(response, version *Response) {
+	results.Response = response
+	results.PaddedToBlock = response.Padded && response.Length%responseBlockSize == 0
+	if version != nil && version.RCode == "NOERROR" && len(version.Answers) > 0 {
+		results.Version = version.Answers[0]
+	}
+}
+
+// scanDoT sends the queries over a single TLS connection. DoT servers may answer out of order, so the responses
+// are matched by ID.
+func (scanner *Scanner) scanDoT(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget, results *ScanResults) error {
+	conn, err := dialGroup.L4Dialer(target)(ctx, "tcp", net.JoinHostPort(target.Host(), strconv.Itoa(int(target.Port))))
+	if err != nil {
+		return fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	tlsConn, err := dialGroup.TLSWrapper(ctx, target, conn)
+	if tlsConn != nil {
+		results.TLSLog = tlsConn.GetLog()
+	}
+	if err != nil {
+		zgrab2.CloseConnAndHandleError(conn)
+		return fmt.Errorf("error performing TLS handshake with target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(tlsConn)
+
+	b := make([]byte, 2)
+	_, _ = rand.Read(b)
+	id := binary.BigEndian.Uint16(b)
+	queries, err := scanner.queries(id)
+	if err != nil {
+		return err
+	}
+	var request []byte
+	for _, query := range queries {
+		request = append(request, frame(query)...)
+	}
+	if _, err = tlsConn.Write(request); err != nil {
+		return fmt.Errorf("error sending queries to target %s: %w", target.String(), err)
+	}
+
+	var response, version *Response
+	for range queries {
+		data, err := readFramed(tlsConn)
+		if err != nil {
+			if response != nil {
+				log.Debugf("no answer to version.bind from target %s: %v", target.String(), err)
+				break
+			}
+			return fmt.Errorf("error reading response from target %s: %w", target.String(), err)
+		}
+		r, err := parseResponse(data)
+		if err != nil {
+			return zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, fmt.Errorf("invalid response from target %s: %w", target.String(), err))
+		}
+		switch r.ID {
+		case id:
+			response = r
+		case id + 1:
+			version = r
+		}
+	}
+	if response == nil {
+		return zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, fmt.Errorf("target %s did not answer the query", target.String()))
+	}
+	results.record(response, version)
+	return nil
+}
+
+// scanDoH POSTs each query to the DoH path. The queries have ID 0, as RFC 8484 recommends for caching.
+func (scanner *Scanner) scanDoH(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget, results *ScanResults) error {
+	client := httpapi.NewClient(ctx, dialGroup, target, scanner.httpFlags)
+	queries, err := scanner.queries(0)
+	if err != nil {
+		return err
+	}
+	var response, version *Response
+	for i, query := range queries {
+		resp, err := client.Post(scanner.config.Path, "application/dns-message", query)
+		results.TLSLog = client.TLSLog
+		if err != nil {
+			if i > 0 {
+				log.Debugf("no answer to version.bind from target %s: %v", target.String(), err)
+				break
+			}
+			return err
+		}
+		body := []byte(resp.Body)
+		resp.Body = ""
+		if i == 0 {
+			results.HTTPResponse = resp
+		}
+		if !resp.Success() || !strings.HasPrefix(resp.ContentType, "application/dns-message") {
+			if i > 0 {
+				break
+			}
+			return zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, fmt.Errorf("target %s does not serve DoH at %s (status %d, content type %q)", target.String(), scanner.config.Path, resp.StatusCode, resp.ContentType))
+		}
+		r, err := parseResponse(body)
+		if err != nil {
+			return zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, fmt.Errorf("invalid response from target %s: %w", target.String(), err))
+		}
+		if i == 0 {
+			response = r
+		} else {
+			version = r
+		}
+	}
+	results.record(response, version)
+	return nil
+}
+
+// Scan performs the configured scan on the resolver.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	results := &ScanResults{Protocol: "dot"}
+	scan := scanner.scanDoT
+	if scanner.config.DoH {
+		results.Protocol = "doh"
+		scan = scanner.scanDoH
+	}
+	if err := scan(ctx, dialGroup, target, results); err != nil {
+		if results.TLSLog == nil {
+			return zgrab2.TryGetScanStatus(err), nil, err
+		}
+		return zgrab2.TryGetScanStatus(err), results, err
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 )
 
@@ -1396,6 +1397,9 @@ type CIPIdentity struct {
 	Serial            uint32
 	ProductNameLength uint8
 	ProductName       string
+	// State is only present when the device appends it after the product name.
+	State    uint8
+	HasState bool
 }
 
 const CIPIdentityMinSize = 33
@@ -1417,6 +1421,19 @@ func (identity *CIPIdentity) GetDeviceTypeString() (string, error) {
 	return val, nil
 }
 
+// SocketIP returns the address the device advertises for itself. Unlike the
+// rest of the encapsulation, the socket address is sent in network byte order.
+func (identity *CIPIdentity) SocketIP() net.IP {
+	ip := make(net.IP, 4)
+	binary.LittleEndian.PutUint32(ip, identity.SocketAddress.SinAddr)
+	return ip
+}
+
+// SocketPort returns the port the device advertises for itself.
+func (identity *CIPIdentity) SocketPort() uint16 {
+	return identity.SocketAddress.SinPort>>8 | identity.SocketAddress.SinPort<<8
+}
+
 func (identity *CIPIdentity) UnMarshal(identityBytes []byte) error {
 	if len(identityBytes) >= CIPIdentityMinSize {
 		identity.ProtocolVersion = binary.LittleEndian.Uint16(identityBytes[0:2])
@@ -1432,8 +1449,13 @@ func (identity *CIPIdentity) UnMarshal(identityBytes []byte) error {
 		identity.Serial = binary.LittleEndian.Uint32(identityBytes[28:32])
 		identity.ProductNameLength = identityBytes[32]
 
-		if len(identityBytes) >= CIPIdentityMinSize+int(identity.ProductNameLength) {
-			identity.ProductName = string(identityBytes[33 : 33+identity.ProductNameLength])
+		end := CIPIdentityMinSize + int(identity.ProductNameLength)
+		if len(identityBytes) >= end {
+			identity.ProductName = string(identityBytes[CIPIdentityMinSize:end])
+		}
+		if len(identityBytes) > end {
+			identity.State = identityBytes[end]
+			identity.HasState = true
 		}
 
 		return nil
@@ -1459,29 +1481,21 @@ func (c *EnipCon) Send(command int, payload []byte) (int, error) {
 }
 
 func (c *EnipCon) Recv() (EnipHeader, []byte, error) {
-	buffer := make([]byte, EnipHeaderSize, 4096)
+	buffer := make([]byte, EnipHeaderSize)
 	header := EnipHeader{}
-	read, err := c.Conn.Read(buffer)
-	if err != nil {
+	if _, err := io.ReadFull(c.Conn, buffer); err != nil {
 		return header, nil, err
-	} else if read != EnipHeaderSize {
-		return header, nil, errors.New("failed to read enip header")
 	}
-	err = header.UnMarshal(buffer)
-	if err != nil {
+	if err := header.UnMarshal(buffer); err != nil {
 		return EnipHeader{}, nil, err
-	} else if int(header.Length) > cap(buffer) {
-		return header, nil, errors.New("enip payload too big to handle")
 	}
-	buffer = buffer[:header.Length]
-	read, err = c.Conn.Read(buffer)
-	if err != nil {
+	// Length is a uint16, so the payload is bounded at 64KiB.
+	payload := make([]byte, header.Length)
+	if _, err := io.ReadFull(c.Conn, payload); err != nil {
 		return header, nil, err
-	} else if read != int(header.Length) {
-		return header, nil, errors.New("failed to read enip payload")
 	}
 
-	return header, buffer, nil
+	return header, payload, nil
 }
 
 func (c *EnipCon) GetCIPIdentity() (CIPIdentity, error) {
@@ -1514,6 +1528,10 @@ func (c *EnipCon) GetCIPIdentity() (CIPIdentity, error) {
 			}
 			Type := binary.LittleEndian.Uint16(payload[offset : offset+2])
 			Length := binary.LittleEndian.Uint16(payload[offset+2 : offset+4])
+			if offset+4+int(Length) > len(payload) {
+				return identity, fmt.Errorf("enip item %d overruns the response: %d bytes left, %d claimed",
+					i, len(payload)-offset-4, Length)
+			}
 			if Type == CIPIdentityType && Length >= CIPIdentityMinSize {
 				err := identity.UnMarshal(payload[offset+4 : offset+4+int(Length)])
 				if err != nil {
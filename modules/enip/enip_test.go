@@ -193,3 +193,50 @@ func TestEnip(t *testing.T) {
 		cfg.runTest(t, testName)
 	}
 }
+
+func TestCIPIdentityExtraFields(t *testing.T) {
+	response := EnipConfigs["Rockwell"].response
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		io.ReadFull(server, make([]byte, EnipHeaderSize))
+		// Split the response to make sure partial reads are reassembled.
+		server.Write(response[:10])
+		server.Write(response[10:])
+	}()
+	conn := EnipCon{Conn: client}
+	identity, err := conn.GetCIPIdentity()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if identity.ProtocolVersion != 1 || identity.Status != 0x34 {
+		t.Errorf("Wrong protocol version / status: %d / 0x%04x", identity.ProtocolVersion, identity.Status)
+	}
+	if !identity.HasState || identity.State != 3 {
+		t.Errorf("Expected state 3, got %d (present: %v)", identity.State, identity.HasState)
+	}
+	if ip := identity.SocketIP().String(); ip != "223.200.210.7" {
+		t.Errorf("Wrong socket address %s", ip)
+	}
+	if port := identity.SocketPort(); port != 44818 {
+		t.Errorf("Wrong socket port %d", port)
+	}
+}
+
+func TestCIPIdentityTruncatedItem(t *testing.T) {
+	response := append([]byte{}, EnipConfigs["Rockwell"].response...)
+	// Claim a longer identity item than the payload carries.
+	response[EnipHeaderSize+4] = 0x40
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		io.ReadFull(server, make([]byte, EnipHeaderSize))
+		server.Write(response)
+	}()
+	conn := EnipCon{Conn: client}
+	if _, err := conn.GetCIPIdentity(); err == nil || !strings.Contains(err.Error(), "overruns") {
+		t.Errorf("Expected overrun error, got %v", err)
+	}
+}
@@ -8,7 +8,10 @@ package enip
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 
 	log "github.com/sirupsen/logrus"
 
@@ -129,6 +132,19 @@ type EnipDeviceInfo struct {
 
 	// The name of the product: same as the model of the device
 	ProductName string `json:"product_name"`
+
+	// The encapsulation protocol version the device speaks
+	ProtocolVersion int `json:"protocol_version"`
+
+	// The raw identity status word (owned, configured, fault bits)
+	Status int `json:"status"`
+
+	// The identity state (0 nonexistent, 1 self testing, 2 standby, 3 operational, ...), if sent
+	State *int `json:"state,omitempty"`
+
+	// The address and port the device advertises in its socket address item
+	SocketAddress string `json:"socket_address,omitempty"`
+	SocketPort    int    `json:"socket_port,omitempty"`
 }
 
 func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
@@ -137,10 +153,14 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 	if err != nil {
 		return zgrab2.TryGetScanStatus(err), nil, err
 	}
-	defer conn.Close()
+	defer zgrab2.CloseConnAndHandleError(conn)
 	enipConn := EnipCon{Conn: conn, Session: 0}
 	identity, err := enipConn.GetCIPIdentity()
 	if err != nil {
+		var opErr *net.OpError
+		if err == io.EOF || errors.As(err, &opErr) {
+			return zgrab2.TryGetScanStatus(err), nil, err
+		}
 		return zgrab2.SCAN_PROTOCOL_ERROR, nil, err
 	}
 	vendor, err := identity.GetVendorString()
@@ -161,7 +181,18 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 		Revision:     revision,
 		Serial:       fmt.Sprintf("0x%08x", identity.Serial),
 		ProductName:  identity.ProductName,
+
+		ProtocolVersion: int(identity.ProtocolVersion),
+		Status:          int(identity.Status),
+	}
+	if identity.HasState {
+		state := int(identity.State)
+		scanResult.State = &state
+	}
+	if ip := identity.SocketIP(); !ip.IsUnspecified() {
+		scanResult.SocketAddress = ip.String()
+		scanResult.SocketPort = int(identity.SocketPort())
 	}
 
-	return zgrab2.SCAN_SUCCESS, scanResult, err
+	return zgrab2.SCAN_SUCCESS, scanResult, nil
 }
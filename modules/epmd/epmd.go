@@ -0,0 +1,106 @@
+package epmd
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Request and response codes.
+const (
+	namesRequest       = 110 // 'n'
+	portPlease2Request = 122 // 'z'
+	port2Response      = 119 // 'w'
+)
+
+// maxNamesLength bounds the NAMES response read.
+const maxNamesLength = 64 * 1024
+
+var errInvalidResponse = errors.New("invalid EPMD response")
+
+// buildRequest frames a request with its length.
+func buildRequest(code byte, data string) []byte {
+	b := binary.BigEndian.AppendUint16(nil, uint16(1+len(data)))
+	b = append(b, code)
+	return append(b, data...)
+}
+
+// Node is a node registered with EPMD.
+type Node struct {
+	Name string `json:"name"`
+	Port uint16 `json:"port"`
+
+	// The remaining fields come from the PORT_PLEASE2 response.
+	NodeType       string `json:"node_type,omitempty"`
+	Protocol       uint8  `json:"protocol,omitempty"`
+	HighestVersion uint16 `json:"highest_version,omitempty"`
+	LowestVersion  uint16 `json:"lowest_version,omitempty"`
+	Extra          string `json:"extra,omitempty"`
+}
+
+// readNames reads a NAMES response: EPMD's own port, then one "name <node> at port <port>" line per node.
+func readNames(r io.Reader) (uint32, []Node, error) {
+	var epmdPort uint32
+	if err := binary.Read(r, binary.BigEndian, &epmdPort); err != nil {
+		return 0, nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(r, maxNamesLength))
+	if err != nil && len(data) == 0 {
+		return epmdPort, nil, err
+	}
+	var nodes []Node
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		var name string
+		var port uint16
+		if _, err := fmt.Sscanf(scanner.Text(), "name %s at port %d", &name, &port); err == nil {
+			nodes = append(nodes, Node{Name: name, Port: port})
+		}
+	}
+	return epmdPort, nodes, nil
+}
+
+// readPort2 reads a PORT2 response into node.
+func readPort2(r io.Reader, node *Node) error {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return err
+	}
+	if header[0] != port2Response {
+		return errInvalidResponse
+	}
+	if header[1] != 0 {
+		return fmt.Errorf("PORT_PLEASE2 failed with result %d", header[1])
+	}
+	fixed := make([]byte, 10)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return err
+	}
+	node.Port = binary.BigEndian.Uint16(fixed[0:2])
+	switch fixed[2] {
+	case 77:
+		node.NodeType = "normal"
+	case 72:
+		node.NodeType = "hidden"
+	default:
+		node.NodeType = strconv.Itoa(int(fixed[2]))
+	}
+	node.Protocol = fixed[3]
+	node.HighestVersion = binary.BigEndian.Uint16(fixed[4:6])
+	node.LowestVersion = binary.BigEndian.Uint16(fixed[6:8])
+	nameLength := int(binary.BigEndian.Uint16(fixed[8:10]))
+	rest := make([]byte, nameLength+2)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return err
+	}
+	extra := make([]byte, binary.BigEndian.Uint16(rest[nameLength:]))
+	if _, err := io.ReadFull(r, extra); err != nil {
+		return err
+	}
+	node.Extra = string(extra)
+	return nil
+}
@@ -0,0 +1,43 @@
+package epmd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestReadNames(t *testing.T) {
+	if request := buildRequest(portPlease2Request, "rabbit"); !bytes.Equal(request, []byte("\x00\x07zrabbit")) {
+		t.Errorf("unexpected request %q", request)
+	}
+
+	response := binary.BigEndian.AppendUint32(nil, 4369)
+	response = append(response, "name rabbit at port 25672\nname couchdb at port 9100\n"...)
+	port, nodes, err := readNames(bytes.NewReader(response))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port != 4369 || !reflect.DeepEqual(nodes, []Node{{Name: "rabbit", Port: 25672}, {Name: "couchdb", Port: 9100}}) {
+		t.Errorf("got port %d, nodes %+v", port, nodes)
+	}
+
+	port2 := []byte{port2Response, 0}
+	port2 = binary.BigEndian.AppendUint16(port2, 25672)
+	port2 = append(port2, 77, 0)
+	port2 = binary.BigEndian.AppendUint16(port2, 6)
+	port2 = binary.BigEndian.AppendUint16(port2, 5)
+	port2 = binary.BigEndian.AppendUint16(port2, 6)
+	port2 = append(port2, "rabbit"...)
+	port2 = binary.BigEndian.AppendUint16(port2, 0)
+	node := Node{Name: "rabbit"}
+	if err = readPort2(bytes.NewReader(port2), &node); err != nil {
+		t.Fatal(err)
+	}
+	if node.NodeType != "normal" || node.HighestVersion != 6 || node.LowestVersion != 5 {
+		t.Errorf("unexpected node %+v", node)
+	}
+	if err = readPort2(bytes.NewReader([]byte{port2Response, 1}), &node); err == nil {
+		t.Error("expected error for a failed lookup")
+	}
+}
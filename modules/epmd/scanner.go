@@ -0,0 +1,159 @@
+// Package epmd contains the zgrab2 Module implementation for the Erlang Port Mapper Daemon.
+//
+// The scan sends a NAMES request, which lists the nodes registered on the host (e.g. rabbit for RabbitMQ or couchdb)
+// with their distribution ports, then a PORT_PLEASE2 request for each node to record its type and distribution
+// protocol versions. EPMD closes the connection after each response, so every request uses a new connection.
+package epmd
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// EPMDPort is the port EPMD reports listening on.
+	EPMDPort uint32 `json:"epmd_port"`
+
+	Nodes []Node `json:"nodes,omitempty"`
+}
+
+// Flags are the EPMD-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+
+	NoPortPlease bool `long:"no-port-please" description:"Do not send PORT_PLEASE2 requests for the listed nodes"`
+	MaxNodes     int  `long:"max-nodes" default:"16" description:"Maximum number of nodes to send PORT_PLEASE2 requests for"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the epmd zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("epmd", "Erlang Port Mapper Daemon (EPMD)", module.Description(), 4369, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Send EPMD NAMES and PORT_PLEASE2 requests and list the registered Erlang nodes"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "epmd"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// portPlease sends a PORT_PLEASE2 request for node on a new connection.
+func (scanner *Scanner) portPlease(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget, node *Node) error {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return err
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+	if _, err = conn.Write(buildRequest(portPlease2Request, node.Name)); err != nil {
+		return err
+	}
+	return readPort2(conn, node)
+}
+
+// Scan performs the configured scan on EPMD.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	if _, err = conn.Write(buildRequest(namesRequest, "")); err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error sending NAMES to target %s: %w", target.String(), err)
+	}
+	results := new(ScanResults)
+	if results.EPMDPort, results.Nodes, err = readNames(conn); err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error reading NAMES response from target %s: %w", target.String(), err)
+	}
+	if scanner.config.NoPortPlease {
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+	for i := range results.Nodes {
+		if i == scanner.config.MaxNodes {
+			break
+		}
+		if err := scanner.portPlease(ctx, dialGroup, target, &results.Nodes[i]); err != nil {
+			log.Debugf("PORT_PLEASE2 for %s to target %s failed: %v", results.Nodes[i].Name, target.String(), err)
+		}
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
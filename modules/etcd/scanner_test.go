@@ -0,0 +1,75 @@
+package etcd
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/zmap/zgrab2"
+	"github.com/zmap/zgrab2/modules/httpapi/httpapitest"
+)
+
+// fakeMember returns a handler for an etcd 3.3 member, which only serves the /v3beta gateway. If auth is set, range
+// requests are rejected as they are when authentication is enabled.
+func fakeMember(t *testing.T, auth bool) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"etcdserver":"3.3.27","etcdcluster":"3.3.0"}`))
+	})
+	mux.HandleFunc("POST /v3beta/maintenance/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"header":{"cluster_id":"14841639068965178418","member_id":"10276657743932975437","revision":"7","raft_term":"3"},"version":"3.3.27","dbSize":"24576","leader":"10276657743932975437","raftIndex":"12"}`))
+	})
+	mux.HandleFunc("POST /v3beta/kv/range", func(w http.ResponseWriter, r *http.Request) {
+		var request rangeRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Key != "AA==" || request.RangeEnd != "AA==" || !request.CountOnly {
+			t.Errorf("range request = %+v, %v", request, err)
+		}
+		if auth {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"etcdserver: user name is empty","code":3}`))
+			return
+		}
+		w.Write([]byte(`{"header":{"revision":"7"},"count":"42"}`))
+	})
+	return mux
+}
+
+// scan runs the scanner against a plaintext HTTP server with the given handler.
+func scan(t *testing.T, flags *Flags, handler http.Handler) (zgrab2.ScanStatus, *ScanResults, error) {
+	t.Helper()
+	return httpapitest.Scan[ScanResults](t, &Scanner{config: flags}, handler)
+}
+
+func TestScanUnauthenticatedRead(t *testing.T) {
+	status, results, err := scan(t, new(Flags), fakeMember(t, false))
+	if err != nil || status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status = %s, err = %v", status, err)
+	}
+	if results.ServerVersion != "3.3.27" || results.ClusterVersion != "3.3.0" || results.GatewayPrefix != "/v3beta" {
+		t.Errorf("results = %+v", results)
+	}
+	expectedStatus := Status{Version: "3.3.27", ClusterID: "14841639068965178418", MemberID: "10276657743932975437", Leader: "10276657743932975437",
+		IsLeader: true, DBSize: 24576, RaftTerm: 3, RaftIndex: 12, Revision: 7}
+	if results.Status == nil || !reflect.DeepEqual(*results.Status, expectedStatus) {
+		t.Errorf("status = %+v", results.Status)
+	}
+	if !results.UnauthenticatedRead || results.KeyCount != 42 || results.RangeError != "" {
+		t.Errorf("range: read = %t, count = %d, error = %q", results.UnauthenticatedRead, results.KeyCount, results.RangeError)
+	}
+}
+
+func TestScanAuthEnabled(t *testing.T) {
+	status, results, err := scan(t, new(Flags), fakeMember(t, true))
+	if err != nil || status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status = %s, err = %v", status, err)
+	}
+	if results.UnauthenticatedRead || results.KeyCount != 0 || results.RangeError != "etcdserver: user name is empty" {
+		t.Errorf("range: read = %t, count = %d, error = %q", results.UnauthenticatedRead, results.KeyCount, results.RangeError)
+	}
+
+	_, results, err = scan(t, &Flags{SkipRange: true}, fakeMember(t, true))
+	if err != nil || results.RangeError != "" || results.Status == nil {
+		t.Errorf("with --skip-range: results = %+v, err = %v", results, err)
+	}
+}
@@ -0,0 +1,284 @@
+package fins
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Command codes (Omron W342 section 5-1).
+const (
+	cmdControllerDataRead   = 0x0501
+	cmdControllerStatusRead = 0x0601
+)
+
+// FINS header fields.
+const (
+	icfCommand  = 0x80
+	icfResponse = 0x40
+	gatewayMax  = 0x02
+
+	// udpSourceNode is the node number the scan claims over UDP. The PLC answers the datagram's source address,
+	// so it only needs to be a valid node number.
+	udpSourceNode = 0x63
+)
+
+// headerLength is the length of the FINS header, and responseOffset the offset of a response's data, after the
+// command code and the end code.
+const (
+	headerLength   = 10
+	responseOffset = headerLength + 4
+)
+
+// FINS/TCP commands (Omron W421 section 7-4).
+const (
+	tcpNodeAddressRequest  = 0
+	tcpNodeAddressResponse = 1
+	tcpFrameSend           = 2
+	tcpHeaderLength        = 16
+
+	// maxTCPFrameLength bounds the FINS/TCP frames the scan reads.
+	maxTCPFrameLength = 1 << 16
+)
+
+var tcpMagic = []byte("FINS")
+
+var errInvalidFrame = errors.New("invalid FINS frame")
+
+// buildCommand builds a command frame from node sa1 to node da1.
+func buildCommand(da1, sa1, sid byte, command uint16, params []byte) []byte {
+	b := []byte{icfCommand, 0, gatewayMax, 0, da1, 0, 0, sa1, 0, sid, byte(command >> 8), byte(command)}
+	return append(b, params...)
+}
+
+// response is a decoded response frame.
+type response struct {
+	sid     byte
+	command uint16
+	endCode uint16
+	data    []byte
+}
+
+// parseResponse decodes a response frame.
+func parseResponse(b []byte) (*response, error) {
+	if len(b) < responseOffset {
+		return nil, fmt.Errorf("%w: response of %d bytes", errInvalidFrame, len(b))
+	}
+	if b[0]&icfResponse == 0 {
+		return nil, fmt.Errorf("%w: ICF %#02x is not a response", errInvalidFrame, b[0])
+	}
+	return &response{
+		sid:     b[9],
+		command: binary.BigEndian.Uint16(b[10:12]),
+		endCode: binary.BigEndian.Uint16(b[12:14]),
+		data:    b[responseOffset:],
+	}, nil
+}
+
+// matchesResponse returns a UDP probe matcher for responses to the command with the given service ID.
+func matchesResponse(sid byte, command uint16) func(_, response []byte) bool {
+	return func(_, b []byte) bool {
+		r, err := parseResponse(b)
+		return err == nil && r.sid == sid && r.command == command
+	}
+}
+
+// endCodeNames names the main response codes, without the relay error and PLC error bits
+// (Omron W342 section 5-1-3).
+var endCodeNames = map[uint16]string{
+	0x0000: "normal completion",
+	0x0001: "service canceled",
+	0x0101: "local node not in network",
+	0x0102: "token timeout",
+	0x0103: "retries failed",
+	0x0104: "too many send frames",
+	0x0105: "node address range error",
+	0x0106: "node address duplication",
+	0x0201: "destination node not in network",
+	0x0202: "unit missing",
+	0x0203: "third node missing",
+	0x0204: "destination node busy",
+	0x0205: "response timeout",
+	0x0401: "undefined command",
+	0x0402: "not supported by model/version",
+	0x0501: "destination address setting error",
+	0x0502: "no routing tables",
+	0x0503: "routing table error",
+	0x0504: "too many relays",
+	0x1001: "command too long",
+	0x1002: "command too short",
+	0x1003: "elements/data don't match",
+	0x1004: "command format error",
+	0x1005: "header error",
+	0x2001: "no protection table",
+	0x2002: "protected",
+	0x2101: "read-only",
+	0x2202: "mode error",
+	0x2205: "service in progress",
+	0x2301: "file device missing",
+	0x2302: "memory missing",
+	0x2303: "clock missing",
+	0x2601: "no access right",
+	0x2602: "service conflict",
+}
+
+// End code bits reporting relay errors and errors in the PLC, which don't affect the command's result.
+const (
+	endCodeRelayError    = 0x8000
+	endCodeNonFatalError = 0x0040
+	endCodeFatalError    = 0x0080
+)
+
+func endCodeName(code uint16) string {
+	code &^= endCodeRelayError | endCodeNonFatalError | endCodeFatalError
+	if name, ok := endCodeNames[code]; ok {
+		return name
+	}
+	return "end_code_" + strconv.FormatUint(uint64(code), 16)
+}
+
+// ascii returns a fixed-width ASCII field without its NUL and space padding.
+func ascii(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(bytes.TrimRight(b, " "))
+}
+
+// ControllerData is the response to CONTROLLER DATA READ.
+type ControllerData struct {
+	Model   string `json:"model"`
+	Version string `json:"version"`
+
+	// The area data, present if the controller sent it: the program area in Kwords, the IOM in Kbytes, the
+	// number of DM words, the timer/counter area in Kbytes, the expansion DM banks and the memory card.
+	ProgramAreaSize  *uint16 `json:"program_area_size,omitempty"`
+	IOMSize          *uint8  `json:"iom_size,omitempty"`
+	DMWords          *uint16 `json:"dm_words,omitempty"`
+	TimerCounterSize *uint8  `json:"timer_counter_size,omitempty"`
+	ExpansionDMSize  *uint8  `json:"expansion_dm_size,omitempty"`
+	MemoryCardType   *uint8  `json:"memory_card_type,omitempty"`
+	MemoryCardSize   *uint16 `json:"memory_card_size,omitempty"`
+}
+
+// parseControllerData decodes the data of a CONTROLLER DATA READ response: a 20-byte model, a 20-byte
+// version, 40 bytes for system use and the area data.
+func parseControllerData(b []byte) (*ControllerData, error) {
+	if len(b) < 40 {
+		return nil, fmt.Errorf("%w: controller data of %d bytes", errInvalidFrame, len(b))
+	}
+	data := &ControllerData{Model: ascii(b[0:20]), Version: ascii(b[20:40])}
+	if area := b[40:]; len(area) >= 40+12 {
+		area = area[40:]
+		u16 := func(offset int) *uint16 {
+			v := binary.BigEndian.Uint16(area[offset:])
+			return &v
+		}
+		u8 := func(offset int) *uint8 {
+			return &area[offset]
+		}
+		data.ProgramAreaSize = u16(0)
+		data.IOMSize = u8(2)
+		data.DMWords = u16(3)
+		data.TimerCounterSize = u8(5)
+		data.ExpansionDMSize = u8(6)
+		data.MemoryCardType = u8(9)
+		data.MemoryCardSize = u16(10)
+	}
+	return data, nil
+}
+
+// ControllerStatus is the response to CONTROLLER STATUS READ.
+type ControllerStatus struct {
+	Status string `json:"status"`
+	Mode   string `json:"mode"`
+
+	FatalError    uint16 `json:"fatal_error"`
+	NonFatalError uint16 `json:"non_fatal_error"`
+	FALNumber     uint16 `json:"fal_number,omitempty"`
+	ErrorMessage  string `json:"error_message,omitempty"`
+}
+
+var statusNames = map[byte]string{
+	0x00: "stop",
+	0x01: "run",
+	0x80: "standby",
+}
+
+var modeNames = map[byte]string{
+	0x00: "program",
+	0x02: "monitor",
+	0x04: "run",
+}
+
+func lookup(names map[byte]string, v byte) string {
+	if name, ok := names[v]; ok {
+		return name
+	}
+	return "unknown_" + strconv.Itoa(int(v))
+}
+
+// parseControllerStatus decodes the data of a CONTROLLER STATUS READ response: the status, the mode, the fatal
+// and non-fatal error flags, the message flags, the FAL/FALS number and a 16-byte error message.
+func parseControllerStatus(b []byte) (*ControllerStatus, error) {
+	if len(b) < 6 {
+		return nil, fmt.Errorf("%w: controller status of %d bytes", errInvalidFrame, len(b))
+	}
+	status := &ControllerStatus{
+		Status:        lookup(statusNames, b[0]),
+		Mode:          lookup(modeNames, b[1]),
+		FatalError:    binary.BigEndian.Uint16(b[2:4]),
+		NonFatalError: binary.BigEndian.Uint16(b[4:6]),
+	}
+	if len(b) >= 10 {
+		status.FALNumber = binary.BigEndian.Uint16(b[8:10])
+	}
+	if len(b) > 10 {
+		status.ErrorMessage = ascii(b[10:min(len(b), 26)])
+	}
+	return status, nil
+}
+
+// tcpFrame builds a FINS/TCP frame.
+func tcpFrame(command uint32, payload []byte) []byte {
+	b := make([]byte, tcpHeaderLength-4, tcpHeaderLength-4+len(payload))
+	copy(b, tcpMagic)
+	binary.BigEndian.PutUint32(b[4:], uint32(8+len(payload)))
+	binary.BigEndian.PutUint32(b[8:], command)
+	// the error code is always zero from the client
+	b = append(b, 0, 0, 0, 0)
+	return append(b, payload...)
+}
+
+// TCPError is a non-zero error code in a FINS/TCP header.
+type TCPError uint32
+
+func (e TCPError) Error() string {
+	return fmt.Sprintf("FINS/TCP error code %#x", uint32(e))
+}
+
+// readTCPFrame reads a FINS/TCP frame, returning its command and payload.
+func readTCPFrame(r io.Reader) (uint32, []byte, error) {
+	header := make([]byte, tcpHeaderLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	if !bytes.Equal(header[:4], tcpMagic) {
+		return 0, nil, fmt.Errorf("%w: magic %q", errInvalidFrame, header[:4])
+	}
+	length := binary.BigEndian.Uint32(header[4:8])
+	if length < 8 || length > maxTCPFrameLength {
+		return 0, nil, fmt.Errorf("%w: FINS/TCP length %d", errInvalidFrame, length)
+	}
+	payload := make([]byte, length-8)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	if code := binary.BigEndian.Uint32(header[12:16]); code != 0 {
+		return 0, payload, TCPError(code)
+	}
+	return binary.BigEndian.Uint32(header[8:12]), payload, nil
+}
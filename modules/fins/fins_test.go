@@ -0,0 +1,125 @@
+package fins
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+// buildResponse builds the response to a command frame.
+func buildResponse(request []byte, endCode uint16, data []byte) []byte {
+	b := []byte{0xC0, 0, gatewayMax, 0, request[7], 0, 0, request[4], 0, request[9], request[10], request[11], byte(endCode >> 8), byte(endCode)}
+	return append(b, data...)
+}
+
+// controllerData builds CONTROLLER DATA READ data with area data.
+func controllerData() []byte {
+	b := make([]byte, 92)
+	copy(b, "CJ2M-CPU31")
+	copy(b[20:], "02.01")
+	copy(b[80:], []byte{0x00, 0x3C, 0x17, 0x80, 0x00, 0x08, 0x00, 0x00, 0x00, 0x04, 0x00, 0x80})
+	return b
+}
+
+func TestParseControllerData(t *testing.T) {
+	data, err := parseControllerData(controllerData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Model != "CJ2M-CPU31" || data.Version != "02.01" {
+		t.Errorf("got %+v", data)
+	}
+	if *data.ProgramAreaSize != 60 || *data.IOMSize != 23 || *data.DMWords != 0x8000 || *data.TimerCounterSize != 8 || *data.MemoryCardType != 4 || *data.MemoryCardSize != 128 {
+		t.Errorf("got area data %d %d %d %d %d %d", *data.ProgramAreaSize, *data.IOMSize, *data.DMWords, *data.TimerCounterSize, *data.MemoryCardType, *data.MemoryCardSize)
+	}
+	// without area data
+	if data, err = parseControllerData(controllerData()[:40]); err != nil || data.ProgramAreaSize != nil {
+		t.Errorf("got %+v, %v", data, err)
+	}
+	if _, err = parseControllerData([]byte("short")); !errors.Is(err, errInvalidFrame) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestParseControllerStatus(t *testing.T) {
+	b := append([]byte{0x01, 0x04, 0x00, 0x00, 0x00, 0x40, 0x00, 0x00, 0x00, 0x00}, []byte("Battery error   ")...)
+	status, err := parseControllerStatus(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := ControllerStatus{Status: "run", Mode: "run", NonFatalError: 0x40, ErrorMessage: "Battery error"}
+	if *status != expected {
+		t.Errorf("got %+v", status)
+	}
+}
+
+func TestEndCodeName(t *testing.T) {
+	if name := endCodeName(0x0040); name != "normal completion" {
+		t.Errorf("got %s", name)
+	}
+	if name := endCodeName(0x8401); name != "undefined command" {
+		t.Errorf("got %s", name)
+	}
+}
+
+func TestMatchesResponse(t *testing.T) {
+	request := buildCommand(0, udpSourceNode, 7, cmdControllerDataRead, nil)
+	if !bytes.Equal(request, []byte{0x80, 0, 2, 0, 0, 0, 0, 0x63, 0, 7, 5, 1}) {
+		t.Errorf("got %x", request)
+	}
+	match := matchesResponse(7, cmdControllerDataRead)
+	if !match(request, buildResponse(request, 0, nil)) {
+		t.Error("response not matched")
+	}
+	if match(request, request) {
+		t.Error("command matched as a response")
+	}
+	if match(request, buildResponse(buildCommand(0, udpSourceNode, 8, cmdControllerDataRead, nil), 0, nil)) {
+		t.Error("response to another SID matched")
+	}
+}
+
+// TestTCPSession requests a node address and reads the controller data over FINS/TCP.
+func TestTCPSession(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		command, payload, err := readTCPFrame(server)
+		if err != nil || command != tcpNodeAddressRequest || len(payload) != 4 {
+			return
+		}
+		server.Write(tcpFrame(tcpNodeAddressResponse, []byte{0, 0, 0, 0xEF, 0, 0, 0, 0x01}))
+		command, payload, err = readTCPFrame(server)
+		if err != nil || command != tcpFrameSend {
+			return
+		}
+		server.Write(tcpFrame(tcpFrameSend, buildResponse(payload, 0, controllerData())))
+	}()
+	scanner := &Scanner{config: &Flags{TCP: true}}
+	s := &session{conn: client, tcp: true}
+	results := new(ScanResults)
+	if err := s.requestNode(results); err != nil {
+		t.Fatal(err)
+	}
+	if results.ClientNode != 0xEF || results.ServerNode != 1 {
+		t.Errorf("got nodes %d, %d", results.ClientNode, results.ServerNode)
+	}
+	r, err := scanner.command(context.Background(), s, nil, "controller-data-read", cmdControllerDataRead, results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.sid != 1 || r.endCode != 0 || len(r.data) != 92 {
+		t.Errorf("got %+v", r)
+	}
+}
+
+func TestTCPError(t *testing.T) {
+	frame := tcpFrame(tcpNodeAddressResponse, make([]byte, 8))
+	frame[15] = 0x21
+	if _, _, err := readTCPFrame(bytes.NewReader(frame)); !errors.Is(err, TCPError(0x21)) {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,255 @@
+// Package fins contains the zgrab2 Module implementation for Omron FINS.
+//
+// The scan sends CONTROLLER DATA READ to record the PLC's model, version and memory areas, and CONTROLLER STATUS
+// READ to record whether it is running, its operating mode and its error flags. By default the commands are sent
+// over FINS/UDP; with --tcp, the scan first requests a node address and wraps the commands in FINS/TCP frames.
+package fins
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	Transport string `json:"transport"`
+
+	// ClientNode and ServerNode are the node numbers exchanged over FINS/TCP.
+	ClientNode uint32 `json:"client_node,omitempty"`
+	ServerNode uint32 `json:"server_node,omitempty"`
+
+	// EndCode is the end code of CONTROLLER DATA READ, and EndCodeName its name.
+	EndCode     uint16 `json:"end_code"`
+	EndCodeName string `json:"end_code_name"`
+
+	Controller *ControllerData   `json:"controller,omitempty"`
+	Status     *ControllerStatus `json:"status,omitempty"`
+
+	// Probes records the commands, if the scan was made over UDP.
+	Probes []*zgrab2.UDPProbeResult `json:"probes,omitempty"`
+}
+
+// Flags are the FINS-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.UDPFlags
+
+	TCP bool `long:"tcp" description:"Send the commands over FINS/TCP instead of FINS/UDP"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the fins zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("fins", "Omron FINS", module.Description(), 9600, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Read the controller data and status of Omron PLCs over FINS"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "fins"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	transport := zgrab2.TransportUDP
+	if f.TCP {
+		transport = zgrab2.TransportTCP
+	}
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: transport,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// session sends commands to the PLC over one connection.
+type session struct {
+	conn       net.Conn
+	tcp        bool
+	sid        byte
+	clientNode byte
+	serverNode byte
+}
+
+// requestNode requests a node address over FINS/TCP, letting the PLC assign it.
+func (s *session) requestNode(results *ScanResults) error {
+	if _, err := s.conn.Write(tcpFrame(tcpNodeAddressRequest, make([]byte, 4))); err != nil {
+		return err
+	}
+	command, payload, err := readTCPFrame(s.conn)
+	if err != nil {
+		return err
+	}
+	if command != tcpNodeAddressResponse || len(payload) < 8 {
+		return fmt.Errorf("%w: FINS/TCP command %d of %d bytes in place of the node address", errInvalidFrame, command, len(payload))
+	}
+	results.ClientNode = binary.BigEndian.Uint32(payload[0:4])
+	results.ServerNode = binary.BigEndian.Uint32(payload[4:8])
+	s.clientNode, s.serverNode = byte(results.ClientNode), byte(results.ServerNode)
+	return nil
+}
+
+// command sends a command and returns the PLC's response.
+func (scanner *Scanner) command(ctx context.Context, s *session, target *zgrab2.ScanTarget, name string, code uint16, results *ScanResults) (*response, error) {
+	s.sid++
+	request := buildCommand(s.serverNode, s.clientNode, s.sid, code, nil)
+	var b []byte
+	if !s.tcp {
+		result, err := zgrab2.SendUDPProbe(ctx, s.conn, zgrab2.NewStaticUDPProbe(name, request, matchesResponse(s.sid, code)), target, &scanner.config.UDPFlags)
+		results.Probes = append(results.Probes, result)
+		if err != nil {
+			return nil, err
+		}
+		b = result.Response
+	} else {
+		if _, err := s.conn.Write(tcpFrame(tcpFrameSend, request)); err != nil {
+			return nil, err
+		}
+		command, payload, err := readTCPFrame(s.conn)
+		if err != nil {
+			return nil, err
+		}
+		if command != tcpFrameSend {
+			return nil, fmt.Errorf("%w: FINS/TCP command %d in place of a FINS frame", errInvalidFrame, command)
+		}
+		b = payload
+	}
+	r, err := parseResponse(b)
+	if err != nil {
+		return nil, err
+	}
+	if r.command != code {
+		return nil, fmt.Errorf("%w: response to command %#04x in place of %#04x", errInvalidFrame, r.command, code)
+	}
+	return r, nil
+}
+
+// Scan reads the controller data and status of the PLC.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+	s := &session{conn: conn, tcp: scanner.config.TCP, clientNode: udpSourceNode}
+	sid := make([]byte, 1)
+	if _, err = rand.Read(sid); err != nil {
+		return zgrab2.SCAN_UNKNOWN_ERROR, nil, err
+	}
+	s.sid = sid[0]
+
+	results := &ScanResults{Transport: "udp"}
+	if s.tcp {
+		results.Transport = "tcp"
+		if err = s.requestNode(results); err != nil {
+			return status(err), nil, fmt.Errorf("error requesting a node address from target %s: %w", target.String(), err)
+		}
+	}
+
+	r, err := scanner.command(ctx, s, target, "controller-data-read", cmdControllerDataRead, results)
+	if err != nil {
+		if len(results.Probes) > 0 {
+			return status(err), results, fmt.Errorf("error reading controller data from target %s: %w", target.String(), err)
+		}
+		return status(err), nil, fmt.Errorf("error reading controller data from target %s: %w", target.String(), err)
+	}
+	results.EndCode = r.endCode
+	results.EndCodeName = endCodeName(r.endCode)
+	if len(r.data) > 0 {
+		if results.Controller, err = parseControllerData(r.data); err != nil {
+			return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("invalid controller data from target %s: %w", target.String(), err)
+		}
+	}
+
+	if r, err = scanner.command(ctx, s, target, "controller-status-read", cmdControllerStatusRead, results); err != nil {
+		log.Debugf("error reading controller status from target %s: %v", target.String(), err)
+	} else if len(r.data) > 0 {
+		if results.Status, err = parseControllerStatus(r.data); err != nil {
+			log.Debugf("invalid controller status from target %s: %v", target.String(), err)
+		}
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
+
+// status maps an error to a scan status, treating malformed and truncated frames as protocol errors.
+func status(err error) zgrab2.ScanStatus {
+	var tcpErr TCPError
+	if errors.Is(err, errInvalidFrame) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &tcpErr) {
+		return zgrab2.SCAN_PROTOCOL_ERROR
+	}
+	return zgrab2.TryGetScanStatus(err)
+}
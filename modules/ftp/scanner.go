@@ -2,18 +2,23 @@
 //
 // Setting the --authtls flag will cause the scanner to attempt a upgrade the
 // connection to TLS. Settings for the TLS handshake / probe can be set with
-// the standard TLSFlags.
+// the standard TLSFlags. Connections to port 990 (implicit FTPS) are wrapped
+// in TLS from the start, as with --implicit-tls, unless --authtls is set.
 //
-// The scan performs a banner grab and (optionally) a TLS handshake.
+// The scan performs a banner grab, (optionally) a TLS handshake, and sends
+// FEAT and SYST. With --anonymous-login, it then tries to log in as anonymous
+// and, on success, records the working directory and the first page of its
+// listing.
 //
 // The output is the banner, any responses to the AUTH TLS/AUTH SSL commands,
-// and any TLS logs.
+// the FEAT and SYST responses, any anonymous login details, and any TLS logs.
 package ftp
 
 import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"regexp"
 	"strconv"
@@ -42,21 +47,67 @@ type ScanResults struct {
 	// to via AUTH TLS or AUTH SSL.
 	ImplicitTLS bool `json:"implicit_tls,omitempty"`
 
+	// FeatResp is the response to the FEAT command.
+	FeatResp string `json:"feat,omitempty"`
+
+	// Features are the features listed in a successful FEAT response.
+	Features []string `json:"features,omitempty"`
+
+	// SystResp is the response to the SYST command.
+	SystResp string `json:"syst,omitempty"`
+
+	// Anonymous is the result of the anonymous login.
+	// Only present if the AnonymousLogin flag is set.
+	Anonymous *AnonymousLog `json:"anonymous,omitempty"`
+
 	// TLSLog is the standard shared TLS handshake log.
 	// Only present if the FTPAuthTLS flag is set.
 	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
 }
 
+// AnonymousLog is the result of logging in as anonymous.
+type AnonymousLog struct {
+	// UserResp and PassResp are the responses to USER anonymous and PASS.
+	UserResp string `json:"user,omitempty"`
+	PassResp string `json:"pass,omitempty"`
+
+	// LoggedIn is true if the server accepted the anonymous login.
+	LoggedIn bool `json:"logged_in"`
+
+	// PWDResp is the response to PWD, and PWD the directory it names.
+	PWDResp string `json:"pwd_resp,omitempty"`
+	PWD     string `json:"pwd,omitempty"`
+
+	// ListResp is the final response to LIST.
+	ListResp string `json:"list_resp,omitempty"`
+
+	// List is the start of the listing of the working directory, at most
+	// --list-max-size bytes.
+	List string `json:"list,omitempty"`
+
+	// ListTruncated is true if the listing was longer than --list-max-size.
+	ListTruncated bool `json:"list_truncated,omitempty"`
+
+	// Error is set if the listing could not be retrieved.
+	Error string `json:"error,omitempty"`
+}
+
 // Flags are the FTP-specific command-line flags. Taken from the original zgrab.
 // (TODO: should FTPAuthTLS be on by default?).
 type Flags struct {
 	zgrab2.BaseFlags `group:"Basic Options"`
 	zgrab2.TLSFlags  `group:"TLS Options"`
 
-	FTPAuthTLS  bool `long:"authtls" description:"Collect FTPS certificates in addition to FTP banners"`
-	ImplicitTLS bool `long:"implicit-tls" description:"Attempt to connect via a TLS wrapped connection"`
+	FTPAuthTLS        bool   `long:"authtls" description:"Collect FTPS certificates in addition to FTP banners"`
+	ImplicitTLS       bool   `long:"implicit-tls" description:"Attempt to connect via a TLS wrapped connection (the default on port 990)"`
+	AnonymousLogin    bool   `long:"anonymous-login" description:"Try to log in as anonymous and, on success, record the working directory and the start of its listing"`
+	AnonymousPassword string `long:"anonymous-password" description:"The password sent for the anonymous login" default:"zgrab2@"`
+	ListMaxSize       int    `long:"list-max-size" description:"The maximum number of bytes of the directory listing to record" default:"4096"`
 }
 
+// implicitTLSPort is the port of FTP over implicit TLS.
+const implicitTLSPort = 990
+
 // Module implements the zgrab2.Module interface.
 type Module struct {
 }
@@ -76,6 +127,10 @@ type Connection struct {
 	config  *Flags
 	results ScanResults
 	conn    net.Conn
+
+	// dialData opens a data connection to the given port of the server,
+	// wrapping it in TLS if the control connection is.
+	dialData func(port string) (net.Conn, error)
 }
 
 // RegisterModule registers the ftp zgrab2 module.
@@ -108,6 +163,9 @@ func (f *Flags) Validate(_ []string) (err error) {
 	if f.FTPAuthTLS && f.ImplicitTLS {
 		err = errors.New("cannot specify both '--authtls' and '--implicit-tls' together")
 	}
+	if f.AnonymousLogin && f.ListMaxSize <= 0 {
+		err = errors.New("--list-max-size must be positive")
+	}
 	return
 }
 
@@ -129,7 +187,7 @@ func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
 		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
 		NeedSeparateL4Dialer:            true,
 		BaseFlags:                       &f.BaseFlags,
-		TLSEnabled:                      f.FTPAuthTLS || f.ImplicitTLS,
+		TLSEnabled:                      true,
 		TLSFlags:                        &f.TLSFlags,
 	}
 	return nil
@@ -252,27 +310,188 @@ func (ftp *Connection) GetFTPSCertificates(ctx context.Context, target *zgrab2.S
 	return nil
 }
 
+// GetFeatures sends FEAT and SYST and records their responses.
+func (ftp *Connection) GetFeatures() error {
+	ret, retCode, err := ftp.sendCommand("FEAT")
+	if err != nil {
+		return err
+	}
+	ftp.results.FeatResp = ret
+	if ftp.isOKResponse(retCode) {
+		ftp.results.Features = parseFeatures(ret)
+	}
+	ret, _, err = ftp.sendCommand("SYST")
+	if err != nil {
+		return err
+	}
+	ftp.results.SystResp = ret
+	return nil
+}
+
+// parseFeatures returns the features of a multi-line FEAT response: each line
+// between the first and the last, which start with a space (RFC 2389).
+func parseFeatures(resp string) []string {
+	var ret []string
+	for _, line := range strings.Split(resp, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, " ") {
+			ret = append(ret, strings.TrimSpace(line))
+		}
+	}
+	return ret
+}
+
+// pwdRegex matches the quoted directory in a PWD response, e.g.
+// `257 "/pub" is the current directory`; quotes inside it are doubled.
+var pwdRegex = regexp.MustCompile(`^257 "((?:[^"]|"")*)"`)
+
+// epsvRegex matches the port in an EPSV response, e.g.
+// "229 Entering Extended Passive Mode (|||6446|)".
+var epsvRegex = regexp.MustCompile(`\(([!-~])([!-~])([!-~])([0-9]+)([!-~])\)`)
+
+// pasvRegex matches the address in a PASV response, e.g.
+// "227 Entering Passive Mode (192,168,1,2,25,46)".
+var pasvRegex = regexp.MustCompile(`([0-9]+),([0-9]+),([0-9]+),([0-9]+),([0-9]+),([0-9]+)`)
+
+// getPassivePort returns the data port from an EPSV or PASV response. The
+// address in a PASV response is ignored, since data is only ever fetched from
+// the scanned host.
+func getPassivePort(resp string) (string, error) {
+	if m := epsvRegex.FindStringSubmatch(resp); m != nil {
+		return m[4], nil
+	}
+	if m := pasvRegex.FindStringSubmatch(resp); m != nil {
+		p1, err1 := strconv.ParseUint(m[5], 10, 8)
+		p2, err2 := strconv.ParseUint(m[6], 10, 8)
+		if err1 == nil && err2 == nil {
+			return strconv.FormatUint(p1<<8|p2, 10), nil
+		}
+	}
+	return "", fmt.Errorf("could not find the data port in %q", resp)
+}
+
+// AnonymousLogin logs in as anonymous and, if that succeeds, records the
+// working directory and the first --list-max-size bytes of its listing.
+func (ftp *Connection) AnonymousLogin() error {
+	anon := new(AnonymousLog)
+	ftp.results.Anonymous = anon
+	ret, retCode, err := ftp.sendCommand("USER anonymous")
+	if err != nil {
+		return err
+	}
+	anon.UserResp = ret
+	if retCode == "331" {
+		ret, retCode, err = ftp.sendCommand("PASS " + ftp.config.AnonymousPassword)
+		if err != nil {
+			return err
+		}
+		anon.PassResp = ret
+	}
+	if retCode != "230" {
+		return nil
+	}
+	anon.LoggedIn = true
+
+	ret, _, err = ftp.sendCommand("PWD")
+	if err != nil {
+		return err
+	}
+	anon.PWDResp = ret
+	if m := pwdRegex.FindStringSubmatch(ret); m != nil {
+		anon.PWD = strings.ReplaceAll(m[1], `""`, `"`)
+	}
+	if err := ftp.list(anon); err != nil {
+		anon.Error = err.Error()
+	}
+	return nil
+}
+
+// list fetches the listing of the working directory over a passive data
+// connection.
+func (ftp *Connection) list(anon *AnonymousLog) error {
+	if _, ok := ftp.conn.(*zgrab2.TLSConnection); ok {
+		// Protect the data connection as well
+		for _, cmd := range []string{"PBSZ 0", "PROT P"} {
+			ret, retCode, err := ftp.sendCommand(cmd)
+			if err != nil {
+				return err
+			}
+			if !ftp.isOKResponse(retCode) {
+				return fmt.Errorf("%s failed: %s", cmd, strings.TrimSpace(ret))
+			}
+		}
+	}
+	ret, retCode, err := ftp.sendCommand("EPSV")
+	if err != nil {
+		return err
+	}
+	if retCode != "229" {
+		if ret, retCode, err = ftp.sendCommand("PASV"); err != nil {
+			return err
+		}
+		if retCode != "227" {
+			return fmt.Errorf("could not enter passive mode: %s", strings.TrimSpace(ret))
+		}
+	}
+	port, err := getPassivePort(ret)
+	if err != nil {
+		return err
+	}
+	dataConn, err := ftp.dialData(port)
+	if err != nil {
+		return fmt.Errorf("could not open data connection: %w", err)
+	}
+	defer zgrab2.CloseConnAndHandleError(dataConn)
+
+	ret, retCode, err = ftp.sendCommand("LIST")
+	if err != nil {
+		return err
+	}
+	if retCode != "125" && retCode != "150" {
+		anon.ListResp = ret
+		return nil
+	}
+	listing, err := io.ReadAll(io.LimitReader(dataConn, int64(ftp.config.ListMaxSize)+1))
+	if len(listing) > ftp.config.ListMaxSize {
+		listing = listing[:ftp.config.ListMaxSize]
+		anon.ListTruncated = true
+	}
+	anon.List = string(listing)
+	if err != nil && !zgrab2.IsTimeoutError(err) {
+		return err
+	}
+	// Closing the data connection early makes the server abort the transfer
+	zgrab2.CloseConnAndHandleError(dataConn)
+	anon.ListResp, _, err = ftp.readResponse()
+	return err
+}
+
 // Scan performs the configured scan on the FTP server, as follows:
+//   - If the ImplicitTLS flag is set or the port is 990 (and the FTPAuthTLS
+//     flag is not set), wrap the connection in TLS.
 //   - Read the banner into results.Banner (if it is not a 2XX response, bail)
-//   - If the FTPAuthTLS flag is not set, finish.
-//   - Send the AUTH TLS command to the server. If the response is not 2XX, then
-//     send the AUTH SSL command. If the response is not 2XX, then finish.
-//   - Perform ths TLS handshake / any configured TLS scans, populating
-//     results.TLSLog.
+//   - If the FTPAuthTLS flag is set, send the AUTH TLS command to the server.
+//     If the response is not 2XX, then send the AUTH SSL command. If either
+//     succeeds, perform ths TLS handshake / any configured TLS scans,
+//     populating results.TLSLog.
+//   - Send FEAT and SYST, recording the responses.
+//   - If the AnonymousLogin flag is set, log in as anonymous; on success,
+//     record the PWD and the first page of the LIST output.
 //   - Return SCAN_SUCCESS, &results, nil
 func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
 	var err error
 	if dialGroup.L4Dialer == nil {
 		return zgrab2.SCAN_INVALID_INPUTS, nil, errors.New("l4 dialer is required for FTP")
 	}
-	if (scanner.config.FTPAuthTLS || scanner.config.ImplicitTLS) && dialGroup.TLSWrapper == nil {
+	implicitTLS := scanner.config.ImplicitTLS || (target.Port == implicitTLSPort && !scanner.config.FTPAuthTLS)
+	if (scanner.config.FTPAuthTLS || implicitTLS) && dialGroup.TLSWrapper == nil {
 		return zgrab2.SCAN_INVALID_INPUTS, nil, errors.New("must specify a TLS wrapper for FTPS")
 	}
 	conn, err := dialGroup.L4Dialer(target)(ctx, "tcp", net.JoinHostPort(target.Host(), strconv.Itoa(int(target.Port))))
 	if err != nil {
 		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %v: %w", target.String(), err)
 	}
-	if scanner.config.ImplicitTLS {
+	if implicitTLS {
 		tlsWrapper := dialGroup.TLSWrapper
 		if tlsWrapper == nil {
 			return zgrab2.SCAN_INVALID_INPUTS, nil, errors.New("TLS wrapper is required for implicit TLS")
@@ -283,17 +502,32 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 		}
 	}
 	results := ScanResults{
-		ImplicitTLS: scanner.config.ImplicitTLS,
+		ImplicitTLS: implicitTLS,
 	}
+	ftp := Connection{conn: conn, config: scanner.config, results: results}
 	defer func() {
 		// Check if we have a TLS conn and grab the log
-		if tlsConn, ok := conn.(*zgrab2.TLSConnection); ok {
-			results.TLSLog = tlsConn.GetLog()
+		if tlsConn, ok := ftp.conn.(*zgrab2.TLSConnection); ok {
+			ftp.results.TLSLog = tlsConn.GetLog()
 		}
 		// cleanup conn
-		zgrab2.CloseConnAndHandleError(conn)
+		zgrab2.CloseConnAndHandleError(ftp.conn)
 	}()
-	ftp := Connection{conn: conn, config: scanner.config, results: results}
+	ftp.dialData = func(port string) (net.Conn, error) {
+		dataConn, err := dialGroup.L4Dialer(target)(ctx, "tcp", net.JoinHostPort(target.Host(), port))
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := ftp.conn.(*zgrab2.TLSConnection); !ok {
+			return dataConn, nil
+		}
+		tlsConn, err := dialGroup.TLSWrapper(ctx, target, dataConn)
+		if err != nil {
+			zgrab2.CloseConnAndHandleError(dataConn)
+			return nil, err
+		}
+		return tlsConn, nil
+	}
 	is200Banner, err := ftp.GetFTPBanner()
 	if err != nil {
 		return zgrab2.TryGetScanStatus(err), &ftp.results, fmt.Errorf("error reading FTP banner for target %s: %w", target.String(), err)
@@ -307,5 +541,16 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 			return zgrab2.TryGetScanStatus(err), &ftp.results, fmt.Errorf("error getting FTPS certificates for target %s: %w", target.String(), err)
 		}
 	}
+	if !is200Banner {
+		return zgrab2.SCAN_SUCCESS, &ftp.results, nil
+	}
+	if err := ftp.GetFeatures(); err != nil {
+		return zgrab2.TryGetScanStatus(err), &ftp.results, fmt.Errorf("error sending FEAT / SYST to target %s: %w", target.String(), err)
+	}
+	if scanner.config.AnonymousLogin {
+		if err := ftp.AnonymousLogin(); err != nil {
+			return zgrab2.TryGetScanStatus(err), &ftp.results, fmt.Errorf("error logging in as anonymous to target %s: %w", target.String(), err)
+		}
+	}
 	return zgrab2.SCAN_SUCCESS, &ftp.results, nil
 }
@@ -0,0 +1,108 @@
+package ftp
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeServer answers each command read from the returned connection with the
+// reply for it; LIST is answered with 150, the listing on the data connection
+// and replies["LIST"].
+func fakeServer(t *testing.T, replies map[string]string, listing string) *Connection {
+	client, server := net.Pipe()
+	dataClient, dataServer := net.Pipe()
+	t.Cleanup(func() { client.Close(); dataClient.Close() })
+	go func() {
+		defer server.Close()
+		server.Write([]byte("220 ProFTPD Server ready.\r\n"))
+		reader := bufio.NewReader(server)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd, _, _ := strings.Cut(strings.TrimSpace(line), " ")
+			if cmd == "LIST" {
+				server.Write([]byte("150 Opening ASCII mode data connection\r\n"))
+				dataServer.Write([]byte(listing))
+				dataServer.Close()
+			}
+			reply, ok := replies[cmd]
+			if !ok {
+				reply = "500 Unknown command\r\n"
+			}
+			server.Write([]byte(reply))
+		}
+	}()
+	ftp := &Connection{
+		conn:   client,
+		config: &Flags{AnonymousPassword: "zgrab2@", ListMaxSize: 16},
+	}
+	ftp.dialData = func(port string) (net.Conn, error) {
+		if port != "6446" {
+			t.Errorf("data port = %s", port)
+		}
+		return dataClient, nil
+	}
+	return ftp
+}
+
+func TestAnonymousLogin(t *testing.T) {
+	ftp := fakeServer(t, map[string]string{
+		"FEAT": "211-Features:\r\n MDTM\r\n UTF8\r\n211 End\r\n",
+		"SYST": "215 UNIX Type: L8\r\n",
+		"USER": "331 Anonymous login ok, send your complete email address as your password\r\n",
+		"PASS": "230 Anonymous access granted, restrictions apply\r\n",
+		"PWD":  "257 \"/pub \"\"x\"\"\" is the current directory\r\n",
+		"EPSV": "229 Entering Extended Passive Mode (|||6446|)\r\n",
+		"LIST": "226 Transfer complete\r\n",
+	}, "drwxr-xr-x 2 ftp ftp 4096 Jan 01 00:00 pub\r\n")
+	if ok, err := ftp.GetFTPBanner(); !ok || err != nil {
+		t.Fatalf("GetFTPBanner = %v, %v", ok, err)
+	}
+	if err := ftp.GetFeatures(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ftp.results.Features) != 2 || ftp.results.Features[1] != "UTF8" || ftp.results.SystResp != "215 UNIX Type: L8\r\n" {
+		t.Errorf("features = %q, syst = %q", ftp.results.Features, ftp.results.SystResp)
+	}
+	if err := ftp.AnonymousLogin(); err != nil {
+		t.Fatal(err)
+	}
+	anon := ftp.results.Anonymous
+	if !anon.LoggedIn || anon.PWD != `/pub "x"` || anon.Error != "" {
+		t.Errorf("anonymous = %+v", anon)
+	}
+	if anon.List != "drwxr-xr-x 2 ftp" || !anon.ListTruncated || anon.ListResp != "226 Transfer complete\r\n" {
+		t.Errorf("list = %q, %v, %q", anon.List, anon.ListTruncated, anon.ListResp)
+	}
+}
+
+func TestAnonymousLoginRefused(t *testing.T) {
+	ftp := fakeServer(t, map[string]string{
+		"USER": "530 Anonymous access denied\r\n",
+	}, "")
+	ftp.GetFTPBanner()
+	if err := ftp.AnonymousLogin(); err != nil {
+		t.Fatal(err)
+	}
+	if ftp.results.Anonymous.LoggedIn || ftp.results.Anonymous.PWDResp != "" {
+		t.Errorf("anonymous = %+v", ftp.results.Anonymous)
+	}
+}
+
+func TestGetPassivePort(t *testing.T) {
+	for resp, expected := range map[string]string{
+		"229 Entering Extended Passive Mode (|||6446|)\r\n":  "6446",
+		"227 Entering Passive Mode (192,168,1,2,25,46).\r\n": "6446",
+	} {
+		if port, err := getPassivePort(resp); err != nil || port != expected {
+			t.Errorf("getPassivePort(%q) = %s, %v", resp, port, err)
+		}
+	}
+	if _, err := getPassivePort("227 Entering Passive Mode\r\n"); err == nil {
+		t.Error("expected an error for a response without a port")
+	}
+}
@@ -0,0 +1,7 @@
+package modules
+
+import "github.com/zmap/zgrab2/modules/gitdaemon"
+
+func init() {
+	gitdaemon.RegisterModule()
+}
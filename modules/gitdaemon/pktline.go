@@ -0,0 +1,92 @@
+package gitdaemon
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxPktLine is the largest pkt-line allowed by the protocol, including its length prefix.
+const maxPktLine = 65520
+
+var errInvalidPktLine = errors.New("invalid pkt-line")
+
+// encodePktLine returns data as a pkt-line.
+func encodePktLine(data string) []byte {
+	return []byte(fmt.Sprintf("%04x%s", len(data)+4, data))
+}
+
+// readPktLine reads a pkt-line. It returns flush as true for a flush-pkt (0000).
+func readPktLine(r io.Reader) (data string, flush bool, err error) {
+	prefix := make([]byte, 4)
+	if _, err = io.ReadFull(r, prefix); err != nil {
+		return "", false, err
+	}
+	length, err := strconv.ParseUint(string(prefix), 16, 16)
+	if err != nil {
+		return "", false, fmt.Errorf("%w: length %q", errInvalidPktLine, prefix)
+	}
+	switch {
+	case length == 0:
+		return "", true, nil
+	case length < 4 || length > maxPktLine:
+		return "", false, fmt.Errorf("%w: length %d", errInvalidPktLine, length)
+	}
+	buf := make([]byte, length-4)
+	if _, err = io.ReadFull(r, buf); err != nil {
+		return "", false, err
+	}
+	return string(buf), false, nil
+}
+
+// Ref is an advertised reference.
+type Ref struct {
+	Name string `json:"name"`
+	Hash string `json:"hash"`
+}
+
+// advertisement accumulates the reference advertisement of upload-pack.
+type advertisement struct {
+	Capabilities []string
+	Refs         []Ref
+	RefCount     int
+	Error        string
+}
+
+// add processes a pkt-line of the advertisement, keeping at most maxRefs refs. The first line carries the
+// capabilities after a NUL; an empty repository advertises them on the pseudo-ref "capabilities^{}".
+func (a *advertisement) add(line string, maxRefs int) error {
+	line = strings.TrimSuffix(line, "\n")
+	if msg, ok := strings.CutPrefix(line, "ERR "); ok {
+		a.Error = msg
+		return nil
+	}
+	ref, capabilities, hasCapabilities := strings.Cut(line, "\x00")
+	if hasCapabilities && a.Capabilities == nil {
+		a.Capabilities = strings.Fields(capabilities)
+	}
+	hash, name, ok := strings.Cut(ref, " ")
+	if !ok || len(hash) < 40 {
+		return fmt.Errorf("%w: ref %q", errInvalidPktLine, ref)
+	}
+	if name == "capabilities^{}" {
+		return nil
+	}
+	a.RefCount++
+	if len(a.Refs) < maxRefs {
+		a.Refs = append(a.Refs, Ref{Name: name, Hash: hash})
+	}
+	return nil
+}
+
+// capability returns the value of a name=value capability.
+func (a *advertisement) capability(name string) string {
+	for _, capability := range a.Capabilities {
+		if value, ok := strings.CutPrefix(capability, name+"="); ok {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,52 @@
+package gitdaemon
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPktLine(t *testing.T) {
+	if got := string(encodePktLine("git-upload-pack /\x00host=example.com\x00")); got != "0027git-upload-pack /\x00host=example.com\x00" {
+		t.Errorf("got %q", got)
+	}
+	r := bytes.NewReader([]byte("0009ERR x0000zzzz"))
+	if line, flush, err := readPktLine(r); err != nil || flush || line != "ERR x" {
+		t.Errorf("got %q, %v, %v", line, flush, err)
+	}
+	if _, flush, err := readPktLine(r); err != nil || !flush {
+		t.Errorf("expected flush, got %v, %v", flush, err)
+	}
+	if _, _, err := readPktLine(r); err == nil {
+		t.Error("expected error for invalid length")
+	}
+}
+
+func TestAdvertisement(t *testing.T) {
+	hash := strings.Repeat("a", 40)
+	adv := new(advertisement)
+	lines := []string{
+		hash + " HEAD\x00multi_ack side-band-64k symref=HEAD:refs/heads/main agent=git/2.43.0\n",
+		hash + " refs/heads/main\n",
+		hash + " refs/tags/v1\n",
+	}
+	for _, line := range lines {
+		if err := adv.add(line, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if adv.RefCount != 3 || len(adv.Refs) != 2 || adv.Refs[1].Name != "refs/heads/main" {
+		t.Errorf("unexpected refs %+v", adv)
+	}
+	if adv.capability("agent") != "git/2.43.0" || adv.capability("symref") != "HEAD:refs/heads/main" {
+		t.Errorf("unexpected capabilities %q", adv.Capabilities)
+	}
+
+	empty := new(advertisement)
+	if err := empty.add(strings.Repeat("0", 40)+" capabilities^{}\x00agent=git/2.39.2\n", 10); err != nil || empty.RefCount != 0 || len(empty.Capabilities) != 1 {
+		t.Errorf("unexpected advertisement %+v (%v)", empty, err)
+	}
+	if err := empty.add("garbage", 10); err == nil {
+		t.Error("expected error for malformed ref")
+	}
+}
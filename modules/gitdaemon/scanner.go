@@ -0,0 +1,194 @@
+// Package gitdaemon contains the zgrab2 Module implementation for the git:// protocol served by git daemon.
+//
+// The scan requests git-upload-pack for the configured repository path and records the reference advertisement:
+// the capabilities (including the server's agent string), the advertised refs, or the ERR line a daemon sends for a
+// repository it doesn't export. After the advertisement the scan sends a flush-pkt, ending the exchange without
+// fetching any objects.
+package gitdaemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// maxAdvertisementLines bounds the number of pkt-lines read from the advertisement.
+const maxAdvertisementLines = 100000
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	Repository string `json:"repository"`
+
+	// Capabilities are the capabilities advertised with the first ref.
+	Capabilities []string `json:"capabilities,omitempty"`
+
+	// Agent is the value of the agent capability, e.g. git/2.43.0.
+	Agent string `json:"agent,omitempty"`
+
+	// Head is the target of HEAD, from the symref capability.
+	Head string `json:"head,omitempty"`
+
+	// Refs are the first --max-refs advertised refs.
+	Refs []Ref `json:"refs,omitempty"`
+
+	// RefCount is the number of refs advertised.
+	RefCount int `json:"ref_count"`
+
+	// Error is the message of an ERR line, e.g. "access denied or repository not exported: /".
+	Error string `json:"error,omitempty"`
+}
+
+// Flags are the git-daemon-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+
+	Repository string `long:"repository" default:"/" description:"Repository path to request"`
+	MaxRefs    int    `long:"max-refs" default:"10" description:"Maximum number of advertised refs to record"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the gitdaemon zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("gitdaemon", "git daemon (git://)", module.Description(), 9418, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Request the reference advertisement of a repository from a git daemon"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "gitdaemon"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// Scan performs the configured scan on the git daemon.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	host := target.Domain
+	if host == "" {
+		host = target.Host()
+	}
+	request := encodePktLine("git-upload-pack " + scanner.config.Repository + "\x00host=" + host + "\x00")
+	if _, err = conn.Write(request); err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error sending request to target %s: %w", target.String(), err)
+	}
+
+	results := &ScanResults{Repository: scanner.config.Repository}
+	adv := new(advertisement)
+	for i := 0; i < maxAdvertisementLines; i++ {
+		line, flush, err := readPktLine(conn)
+		if err != nil {
+			if errors.Is(err, errInvalidPktLine) {
+				return zgrab2.SCAN_PROTOCOL_ERROR, nil, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+			}
+			if i == 0 {
+				// git daemon closes the connection without a word when the upload-pack service is disabled
+				return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error reading advertisement from target %s: %w", target.String(), err)
+			}
+			break
+		}
+		if flush {
+			// end the exchange without requesting anything
+			_, _ = conn.Write([]byte("0000"))
+			break
+		}
+		if err = adv.add(line, scanner.config.MaxRefs); err != nil {
+			return zgrab2.SCAN_PROTOCOL_ERROR, nil, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+		}
+		if adv.Error != "" {
+			break
+		}
+	}
+	results.Capabilities = adv.Capabilities
+	results.Agent = adv.capability("agent")
+	if symref := adv.capability("symref"); symref != "" {
+		if head, ok := strings.CutPrefix(symref, "HEAD:"); ok {
+			results.Head = head
+		}
+	}
+	results.Refs = adv.Refs
+	results.RefCount = adv.RefCount
+	results.Error = adv.Error
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
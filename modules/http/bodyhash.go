@@ -0,0 +1,103 @@
+package http
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash/fnv"
+	"io"
+
+	"github.com/zmap/zgrab2/lib/http"
+)
+
+// simHasher computes the 64-bit simhash of the words written to it, where a word is a run of ASCII letters and
+// digits, compared case-insensitively. Words may be split across writes.
+type simHasher struct {
+	weights [64]int
+	word    []byte
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func (s *simHasher) addWord() {
+	if len(s.word) == 0 {
+		return
+	}
+	h := fnv.New64a()
+	h.Write(s.word)
+	sum := h.Sum64()
+	for i := range s.weights {
+		if sum&(1<<i) != 0 {
+			s.weights[i]++
+		} else {
+			s.weights[i]--
+		}
+	}
+	s.word = s.word[:0]
+}
+
+func (s *simHasher) Write(p []byte) (int, error) {
+	for _, c := range p {
+		if !isWordByte(c) {
+			s.addWord()
+			continue
+		}
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		s.word = append(s.word, c)
+	}
+	return len(p), nil
+}
+
+// Sum64 returns the simhash of the words written so far, including a trailing one.
+func (s *simHasher) Sum64() uint64 {
+	s.addWord()
+	var sum uint64
+	for i, weight := range s.weights {
+		if weight > 0 {
+			sum |= 1 << i
+		}
+	}
+	return sum
+}
+
+// moreBody tells whether another byte can be read from a body.
+func moreBody(body io.Reader) bool {
+	var b [1]byte
+	n, _ := io.ReadFull(body, b[:])
+	return n == 1
+}
+
+// digestBody marks a response whose body is longer than the stored part read from it as truncated. With
+// --hash-full-body or --simhash, it first reads the rest of the body up to MaxHashSize kilobytes, without storing
+// it, and records the digest of the whole.
+func (scan *scan) digestBody(res *http.Response, stored []byte) {
+	config := scan.scanner.config
+	storedLen := int64(len(stored))
+	if !config.HashFullBody && !config.SimHash {
+		res.BodyTruncated = storedLen >= int64(config.MaxSize)*1024 && moreBody(res.Body)
+		return
+	}
+
+	digest := new(http.BodyDigest)
+	h := sha256.New()
+	var w io.Writer = h
+	var sim *simHasher
+	if config.SimHash {
+		sim = new(simHasher)
+		w = io.MultiWriter(h, sim)
+	}
+	w.Write(stored)
+	rest := max(int64(config.MaxHashSize)*1024-storedLen, 0)
+	n, _ := io.Copy(w, io.LimitReader(res.Body, rest))
+	digest.Length = storedLen + n
+	digest.Truncated = n == rest && moreBody(res.Body)
+	digest.SHA256 = h.Sum(nil)
+	if sim != nil {
+		digest.SimHash = fmt.Sprintf("%016x", sim.Sum64())
+	}
+	res.BodyTruncated = n > 0 || digest.Truncated
+	res.FullBody = digest
+}
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"math/bits"
+	"net"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zmap/zgrab2"
+)
+
+func simHash(chunks ...string) uint64 {
+	s := new(simHasher)
+	for _, chunk := range chunks {
+		s.Write([]byte(chunk))
+	}
+	return s.Sum64()
+}
+
+func TestSimHash(t *testing.T) {
+	page := strings.Repeat("<p>Welcome to the default page of this web server, it works.</p>\n", 20)
+	if a, b := simHash(page), simHash(page[:100], page[100:]); a != b {
+		t.Errorf("simhash of split writes %016x, want %016x", b, a)
+	}
+	if a, b := simHash("Hello World"), simHash("hello, world!"); a != b {
+		t.Errorf("simhash depends on case and punctuation: %016x, %016x", a, b)
+	}
+	similar := bits.OnesCount64(simHash(page) ^ simHash(page+"<footer>host 10.0.0.1</footer>"))
+	different := bits.OnesCount64(simHash(page) ^ simHash("404 page not found: the requested resource is unavailable"))
+	if similar >= different {
+		t.Errorf("distance of similar pages %d, of different pages %d", similar, different)
+	}
+}
+
+func TestDigestBody(t *testing.T) {
+	body := bytes.Repeat([]byte("zgrab2 streams bodies "), 1000)
+	server := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if r.URL.Path == "/small" {
+			w.Write([]byte("small"))
+			return
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+	addr := server.Listener.Addr().(*net.TCPAddr)
+	sum := sha256.Sum256(body)
+
+	for _, tc := range []struct {
+		endpoint      string
+		hash, simhash bool
+		maxHashSize   int
+		truncated     bool
+		fullLength    int64
+		fullTruncated bool
+	}{
+		{endpoint: "/small"},
+		{endpoint: "/", truncated: true},
+		{endpoint: "/small", hash: true, maxHashSize: 64, fullLength: 5},
+		{endpoint: "/", hash: true, maxHashSize: 64, truncated: true, fullLength: int64(len(body))},
+		{endpoint: "/", simhash: true, maxHashSize: 64, truncated: true, fullLength: int64(len(body))},
+		{endpoint: "/", hash: true, maxHashSize: 4, truncated: true, fullLength: 4096, fullTruncated: true},
+	} {
+		var module Module
+		flags := module.NewFlags().(*Flags)
+		flags.Endpoint = tc.endpoint
+		flags.Method = "GET"
+		flags.MaxSize = 1
+		flags.Port = uint(addr.Port)
+		flags.ConnectTimeout = time.Second
+		flags.HashFullBody = tc.hash
+		flags.SimHash = tc.simhash
+		flags.MaxHashSize = tc.maxHashSize
+		scanner := module.NewScanner().(*Scanner)
+		if err := scanner.Init(flags); err != nil {
+			t.Fatal(err)
+		}
+		dialerGroup, err := scanner.GetDialerGroupConfig().GetDefaultDialerGroupFromConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		status, ret, err := scanner.Scan(context.Background(), dialerGroup, &zgrab2.ScanTarget{IP: addr.IP, Port: uint(addr.Port)})
+		if status != zgrab2.SCAN_SUCCESS {
+			t.Fatalf("status %s: %v", status, err)
+		}
+		res := ret.(*Results).Response
+		if res.BodyTruncated != tc.truncated {
+			t.Errorf("%+v: body_truncated = %v", tc, res.BodyTruncated)
+		}
+		if len(res.BodyText) > 1024 {
+			t.Errorf("%+v: stored %d bytes of the body", tc, len(res.BodyText))
+		}
+		if !tc.hash && !tc.simhash {
+			if res.FullBody != nil {
+				t.Errorf("%+v: full_body = %+v", tc, res.FullBody)
+			}
+			continue
+		}
+		digest := res.FullBody
+		if digest == nil || digest.Length != tc.fullLength || digest.Truncated != tc.fullTruncated {
+			t.Fatalf("%+v: full_body = %+v", tc, digest)
+		}
+		if tc.fullLength == int64(len(body)) && !bytes.Equal(digest.SHA256, sum[:]) {
+			t.Errorf("%+v: sha256 = %s", tc, digest.SHA256.Hex())
+		}
+		if (digest.SimHash != "") != tc.simhash {
+			t.Errorf("%+v: simhash = %q", tc, digest.SimHash)
+		}
+	}
+}
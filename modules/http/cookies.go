@@ -0,0 +1,83 @@
+package http
+
+import (
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+
+	"github.com/zmap/zgrab2/lib/http"
+	"github.com/zmap/zgrab2/lib/http/cookiejar"
+)
+
+// Cookie is a cookie set by a response while the --cookie-jar is in use.
+type Cookie struct {
+	// URL is the URL of the request whose response set the cookie.
+	URL string `json:"url"`
+
+	Name        string     `json:"name"`
+	Value       string     `json:"value"`
+	Domain      string     `json:"domain,omitempty"`
+	Path        string     `json:"path,omitempty"`
+	Expires     *time.Time `json:"expires,omitempty"`
+	RawExpires  string     `json:"raw_expires,omitempty"`
+	MaxAge      int        `json:"max_age,omitempty"`
+	Secure      bool       `json:"secure"`
+	HttpOnly    bool       `json:"http_only"`
+	SameSite    string     `json:"same_site,omitempty"`
+	Partitioned bool       `json:"partitioned,omitempty"`
+
+	// Unparsed are the attributes of the Set-Cookie header that are not understood.
+	Unparsed []string `json:"unparsed,omitempty"`
+}
+
+// sameSiteNames maps the SameSite attribute to its name in the output.
+var sameSiteNames = map[http.SameSite]string{
+	http.SameSiteDefaultMode: "default",
+	http.SameSiteLaxMode:     "lax",
+	http.SameSiteStrictMode:  "strict",
+	http.SameSiteNoneMode:    "none",
+}
+
+// recordingJar is a cookie jar that records every cookie it is given.
+type recordingJar struct {
+	*cookiejar.Jar
+	mu      sync.Mutex
+	cookies []Cookie
+}
+
+// newRecordingJar returns an empty jar that applies the public suffix list to domain cookies.
+func newRecordingJar() *recordingJar {
+	// New only fails for invalid options
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	return &recordingJar{Jar: jar}
+}
+
+// SetCookies records the cookies set by a response to u before storing them in the jar.
+func (j *recordingJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	for _, c := range cookies {
+		cookie := Cookie{
+			URL:         u.String(),
+			Name:        c.Name,
+			Value:       c.Value,
+			Domain:      c.Domain,
+			Path:        c.Path,
+			RawExpires:  c.RawExpires,
+			MaxAge:      c.MaxAge,
+			Secure:      c.Secure,
+			HttpOnly:    c.HttpOnly,
+			SameSite:    sameSiteNames[c.SameSite],
+			Partitioned: c.Partitioned,
+			Unparsed:    c.Unparsed,
+		}
+		if !c.Expires.IsZero() {
+			expires := c.Expires
+			cookie.Expires = &expires
+		}
+		j.cookies = append(j.cookies, cookie)
+	}
+	j.mu.Unlock()
+	j.Jar.SetCookies(u, cookies)
+}
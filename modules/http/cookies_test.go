@@ -0,0 +1,75 @@
+package http
+
+import (
+	"context"
+	"net"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zmap/zgrab2"
+)
+
+func TestCookieJar(t *testing.T) {
+	// a load balancer that redirects until the client presents its cookie
+	server := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if cookie, err := r.Cookie("lb"); err == nil && cookie.Value == "node1" {
+			w.Write([]byte("content"))
+			return
+		}
+		stdhttp.SetCookie(w, &stdhttp.Cookie{Name: "lb", Value: "node1", Path: "/", HttpOnly: true, SameSite: stdhttp.SameSiteLaxMode})
+		w.Header().Add("Set-Cookie", "tracking=1; Max-Age=60; Secure; Priority=High")
+		stdhttp.Redirect(w, r, "/", stdhttp.StatusFound)
+	}))
+	defer server.Close()
+	addr := server.Listener.Addr().(*net.TCPAddr)
+
+	for _, jar := range []bool{false, true} {
+		var module Module
+		flags := module.NewFlags().(*Flags)
+		flags.Endpoint = "/"
+		flags.Method = "GET"
+		flags.MaxSize = 256
+		flags.MaxRedirects = 2
+		flags.Port = uint(addr.Port)
+		flags.ConnectTimeout = time.Second
+		flags.CookieJar = jar
+		scanner := module.NewScanner().(*Scanner)
+		if err := scanner.Init(flags); err != nil {
+			t.Fatal(err)
+		}
+		dialerGroup, err := scanner.GetDialerGroupConfig().GetDefaultDialerGroupFromConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		status, ret, err := scanner.Scan(context.Background(), dialerGroup, &zgrab2.ScanTarget{IP: addr.IP, Port: uint(addr.Port)})
+		results := ret.(*Results)
+		if !jar {
+			// without the jar, the redirects loop
+			if status == zgrab2.SCAN_SUCCESS || len(results.Cookies) != 0 {
+				t.Errorf("without jar: status %s, cookies %+v", status, results.Cookies)
+			}
+			continue
+		}
+		if status != zgrab2.SCAN_SUCCESS {
+			t.Fatalf("status %s: %v", status, err)
+		}
+		if results.Response.StatusCode != stdhttp.StatusOK || results.Response.BodyText != "content" {
+			t.Errorf("final response %d %q", results.Response.StatusCode, results.Response.BodyText)
+		}
+		if len(results.Cookies) != 2 {
+			t.Fatalf("got %d cookies, want 2: %+v", len(results.Cookies), results.Cookies)
+		}
+		lb, tracking := results.Cookies[0], results.Cookies[1]
+		if lb.Name != "lb" || lb.Value != "node1" || !lb.HttpOnly || lb.Secure || lb.SameSite != "lax" || lb.Path != "/" {
+			t.Errorf("lb cookie = %+v", lb)
+		}
+		if tracking.Name != "tracking" || tracking.MaxAge != 60 || !tracking.Secure || len(tracking.Unparsed) != 1 {
+			t.Errorf("tracking cookie = %+v", tracking)
+		}
+		if lb.URL != "http://"+addr.String()+"/" {
+			t.Errorf("cookie URL %q", lb.URL)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/zmap/zgrab2/lib/http"
+)
+
+// acceptEncoding is the Accept-Encoding sent with --decompress.
+const acceptEncoding = "gzip, br, zstd"
+
+// newDecoders maps the content encodings decoded with --decompress to their readers.
+var newDecoders = map[string]func(io.Reader) (io.ReadCloser, error){
+	"gzip": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	"x-gzip": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	"br": func(r io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(brotli.NewReader(r)), nil
+	},
+	"zstd": func(r io.Reader) (io.ReadCloser, error) {
+		// the body is read sequentially, so decoding concurrently would only add goroutines
+		d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	},
+}
+
+// countingReader counts the bytes read from a reader.
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	*c.n += int64(n)
+	return n, err
+}
+
+// decodedBody decodes a response body, creating its decoder on the first read. A decoding error is recorded in the
+// response's Compression and ends the body, so that what was decoded before it is kept.
+type decodedBody struct {
+	body        io.ReadCloser
+	compressed  *countingReader
+	newDecoder  func(io.Reader) (io.ReadCloser, error)
+	decoder     io.ReadCloser
+	compression *http.ResponseCompression
+}
+
+func (d *decodedBody) Read(p []byte) (int, error) {
+	if d.decoder == nil {
+		decoder, err := d.newDecoder(d.compressed)
+		if err != nil {
+			return 0, d.fail(err)
+		}
+		d.decoder = decoder
+	}
+	n, err := d.decoder.Read(p)
+	d.compression.DecompressedSize += int64(n)
+	if err != nil && err != io.EOF {
+		err = d.fail(err)
+	}
+	return n, err
+}
+
+// fail records a decoding error, unless the body is empty, and ends the body.
+func (d *decodedBody) fail(err error) error {
+	if d.compression.CompressedSize > 0 || !errors.Is(err, io.EOF) {
+		d.compression.Error = err.Error()
+	}
+	return io.EOF
+}
+
+func (d *decodedBody) Close() error {
+	if d.decoder != nil {
+		d.decoder.Close()
+	}
+	return d.body.Close()
+}
+
+// decodeBody replaces the body of a response in an encoding decoded with --decompress by its decoded content. It
+// does nothing if the body is already decoded.
+func (scan *scan) decodeBody(res *http.Response) {
+	if !scan.scanner.config.Decompress || res.Body == nil || res.Compression != nil {
+		return
+	}
+	encoding := strings.ToLower(strings.TrimSpace(res.Header.Get("Content-Encoding")))
+	newDecoder, ok := newDecoders[encoding]
+	if !ok {
+		return
+	}
+	res.Compression = &http.ResponseCompression{Encoding: encoding}
+	res.Body = &decodedBody{
+		body:        res.Body,
+		compressed:  &countingReader{r: res.Body, n: &res.Compression.CompressedSize},
+		newDecoder:  newDecoder,
+		compression: res.Compression,
+	}
+}
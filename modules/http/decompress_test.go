@@ -0,0 +1,98 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/zmap/zgrab2"
+)
+
+func TestDecompress(t *testing.T) {
+	content := strings.Repeat("<p>compressible content</p>\n", 200)
+	encoded := make(map[string][]byte)
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	gz.Write([]byte(content))
+	gz.Close()
+	encoded["gzip"] = bytes.Clone(b.Bytes())
+	b.Reset()
+	br := brotli.NewWriter(&b)
+	br.Write([]byte(content))
+	br.Close()
+	encoded["br"] = bytes.Clone(b.Bytes())
+	zw, _ := zstd.NewWriter(nil)
+	encoded["zstd"] = zw.EncodeAll([]byte(content), nil)
+	// a truncated stream
+	encoded["corrupt"] = encoded["br"][:len(encoded["br"])/2]
+
+	var acceptEncodings []string
+	server := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		acceptEncodings = append(acceptEncodings, r.Header.Get("Accept-Encoding"))
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		encoding := name
+		if name == "corrupt" {
+			encoding = "br"
+		}
+		w.Header().Set("Content-Encoding", encoding)
+		w.Write(encoded[name])
+	}))
+	defer server.Close()
+	addr := server.Listener.Addr().(*net.TCPAddr)
+
+	for _, name := range []string{"gzip", "br", "zstd", "corrupt"} {
+		var module Module
+		flags := module.NewFlags().(*Flags)
+		flags.Endpoint = "/" + name
+		flags.Method = "GET"
+		flags.MaxSize = 256
+		flags.Port = uint(addr.Port)
+		flags.ConnectTimeout = time.Second
+		flags.Decompress = true
+		scanner := module.NewScanner().(*Scanner)
+		if err := scanner.Init(flags); err != nil {
+			t.Fatal(err)
+		}
+		dialerGroup, err := scanner.GetDialerGroupConfig().GetDefaultDialerGroupFromConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		status, ret, err := scanner.Scan(context.Background(), dialerGroup, &zgrab2.ScanTarget{IP: addr.IP, Port: uint(addr.Port)})
+		if status != zgrab2.SCAN_SUCCESS {
+			t.Fatalf("%s: status %s: %v", name, status, err)
+		}
+		res := ret.(*Results).Response
+		compression := res.Compression
+		if compression == nil {
+			t.Fatalf("%s: no compression recorded", name)
+		}
+		if name == "corrupt" {
+			if compression.Error == "" || !strings.HasPrefix(content, res.BodyText) {
+				t.Errorf("corrupt: compression %+v, body %d bytes", compression, len(res.BodyText))
+			}
+			continue
+		}
+		if res.BodyText != content {
+			t.Errorf("%s: body not decoded: %q", name, res.BodyText[:min(len(res.BodyText), 32)])
+		}
+		want := *compression
+		want.Encoding, want.CompressedSize, want.DecompressedSize, want.Error = name, int64(len(encoded[name])), int64(len(content)), ""
+		if *compression != want {
+			t.Errorf("%s: compression = %+v, want %+v", name, *compression, want)
+		}
+	}
+	for _, acceptEncoding := range acceptEncodings {
+		if acceptEncoding != "gzip, br, zstd" {
+			t.Errorf("Accept-Encoding %q", acceptEncoding)
+		}
+	}
+}
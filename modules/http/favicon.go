@@ -0,0 +1,238 @@
+package http
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math/bits"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/zmap/zgrab2/lib/http"
+)
+
+// maxHTMLFavicons bounds the number of icons fetched from the links of the HTML response.
+const maxHTMLFavicons = 4
+
+// Favicon is an icon fetched with --favicon.
+type Favicon struct {
+	URL string `json:"url"`
+
+	// Source is "default" for /favicon.ico and "html" for icons linked from the response.
+	Source string `json:"source"`
+
+	StatusCode  int    `json:"status_code,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int    `json:"size,omitempty"`
+
+	// MMH3 is the Shodan-compatible hash: the signed 32-bit MurmurHash3 of the base64 encoding of the icon, with a
+	// newline after every 76 characters and at the end.
+	MMH3   *int32 `json:"mmh3,omitempty"`
+	MD5    string `json:"md5,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// murmur3 returns the MurmurHash3 x86 32-bit hash of data with seed 0.
+func murmur3(data []byte) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+	var h uint32
+	n := len(data) / 4
+	for i := 0; i < n; i++ {
+		k := binary.LittleEndian.Uint32(data[4*i:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+	tail := data[4*n:]
+	var k uint32
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
+
+// faviconHash returns the Shodan-compatible hash of an icon, which hashes the output of Python's
+// base64.encodebytes.
+func faviconHash(icon []byte) int32 {
+	encoded := base64.StdEncoding.EncodeToString(icon)
+	var b strings.Builder
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76])
+		b.WriteByte('\n')
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded)
+	b.WriteByte('\n')
+	return int32(murmur3([]byte(b.String())))
+}
+
+// setHashes records the size and hashes of an icon.
+func (f *Favicon) setHashes(icon []byte) {
+	f.Size = len(icon)
+	hash := faviconHash(icon)
+	f.MMH3 = &hash
+	md5Sum := md5.Sum(icon)
+	f.MD5 = hex.EncodeToString(md5Sum[:])
+	sha256Sum := sha256.Sum256(icon)
+	f.SHA256 = hex.EncodeToString(sha256Sum[:])
+}
+
+// iconLinks returns the href attributes of the <link> elements of an HTML document whose rel includes "icon",
+// such as "icon", "shortcut icon" and "apple-touch-icon".
+func iconLinks(body []byte) []string {
+	var links []string
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return links
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			if string(name) != "link" || !hasAttr {
+				continue
+			}
+			var rel, href string
+			for {
+				key, value, more := tokenizer.TagAttr()
+				switch string(key) {
+				case "rel":
+					rel = strings.ToLower(string(value))
+				case "href":
+					href = strings.TrimSpace(string(value))
+				}
+				if !more {
+					break
+				}
+			}
+			if href != "" && strings.Contains(rel, "icon") {
+				links = append(links, href)
+			}
+		}
+	}
+}
+
+// decodeDataURL returns the content of a base64 data: URL.
+func decodeDataURL(link string) ([]byte, string, error) {
+	header, data, ok := strings.Cut(strings.TrimPrefix(link, "data:"), ",")
+	mediaType, isBase64 := strings.CutSuffix(header, ";base64")
+	if !ok || !isBase64 {
+		return nil, "", errors.New("only base64 data URLs are supported")
+	}
+	icon, err := base64.StdEncoding.DecodeString(data)
+	return icon, mediaType, err
+}
+
+// fetchFavicons fetches /favicon.ico and the icons linked from the HTML of the final response on the same host,
+// and records their hashes. Inline icons in data: URLs are hashed without a request. Like the requests of the
+// sequence, the icon requests do not follow redirects.
+func (scan *scan) fetchFavicons() {
+	res := scan.results.Response
+	if res == nil || res.Request == nil || res.Request.URL == nil {
+		return
+	}
+	base := res.Request.URL
+	seen := make(map[string]bool)
+	candidates := []Favicon{{URL: base.ResolveReference(&url.URL{Path: "/favicon.ico"}).String(), Source: "default"}}
+	seen[candidates[0].URL] = true
+	if strings.Contains(strings.ToLower(res.Header.Get("Content-Type")), "html") {
+		for _, link := range iconLinks([]byte(res.BodyText)) {
+			if len(candidates) > maxHTMLFavicons {
+				break
+			}
+			if strings.HasPrefix(link, "data:") {
+				candidates = append(candidates, Favicon{URL: link, Source: "html"})
+				continue
+			}
+			ref, err := url.Parse(link)
+			if err != nil {
+				continue
+			}
+			u := base.ResolveReference(ref)
+			if u.Host != base.Host || (u.Scheme != "http" && u.Scheme != "https") || seen[u.String()] {
+				continue
+			}
+			seen[u.String()] = true
+			candidates = append(candidates, Favicon{URL: u.String(), Source: "html"})
+		}
+	}
+
+	scan.noRedirects = true
+	defer func() { scan.noRedirects = false }()
+	for _, favicon := range candidates {
+		if strings.HasPrefix(favicon.URL, "data:") {
+			icon, mediaType, err := decodeDataURL(favicon.URL)
+			if err != nil {
+				favicon.Error = err.Error()
+			} else {
+				favicon.ContentType = mediaType
+				favicon.setHashes(icon)
+			}
+			// the URL could be large, and the hashes identify it
+			favicon.URL = "data:" + mediaType
+		} else {
+			scan.fetchFavicon(&favicon)
+		}
+		scan.results.Favicons = append(scan.results.Favicons, favicon)
+	}
+}
+
+// fetchFavicon requests an icon and hashes it if the server returns it.
+func (scan *scan) fetchFavicon(favicon *Favicon) {
+	request, err := http.NewRequest("GET", favicon.URL, nil)
+	if err != nil {
+		favicon.Error = err.Error()
+		return
+	}
+	scan.prepareRequest(request)
+	res, err := scan.do(request)
+	if err != nil {
+		favicon.Error = err.Error()
+		return
+	}
+	defer res.Body.Close()
+	favicon.StatusCode = res.StatusCode
+	favicon.ContentType = res.Header.Get("Content-Type")
+	if res.StatusCode != http.StatusOK {
+		return
+	}
+	icon, err := io.ReadAll(io.LimitReader(res.Body, int64(scan.scanner.config.MaxSize)*1024))
+	if err != nil {
+		favicon.Error = err.Error()
+		return
+	}
+	if len(icon) > 0 {
+		favicon.setHashes(icon)
+	}
+}
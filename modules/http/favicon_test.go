@@ -0,0 +1,104 @@
+package http
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/zmap/zgrab2"
+)
+
+func TestMurmur3(t *testing.T) {
+	for input, want := range map[string]uint32{
+		"":      0,
+		"hello": 0x248bfa47,
+		"The quick brown fox jumps over the lazy dog": 0x2e4ff723,
+	} {
+		if got := murmur3([]byte(input)); got != want {
+			t.Errorf("murmur3(%q) = %#x, want %#x", input, got, want)
+		}
+	}
+}
+
+func TestFaviconHashEncoding(t *testing.T) {
+	// 60 bytes encode to 80 base64 characters, which Python's base64.encodebytes splits after 76
+	icon := make([]byte, 60)
+	encoded := base64.StdEncoding.EncodeToString(icon)
+	want := int32(murmur3([]byte(encoded[:76] + "\n" + encoded[76:] + "\n")))
+	if got := faviconHash(icon); got != want {
+		t.Errorf("faviconHash = %d, want %d", got, want)
+	}
+}
+
+func TestIconLinks(t *testing.T) {
+	body := []byte(`<html><head>
+<link rel="stylesheet" href="/style.css">
+<LINK REL="Shortcut Icon" HREF="/static/favicon.ico">
+<link rel="apple-touch-icon" sizes="180x180" href="touch.png" />
+<link rel="icon">
+</head></html>`)
+	want := []string{"/static/favicon.ico", "touch.png"}
+	if got := iconLinks(body); !slices.Equal(got, want) {
+		t.Errorf("iconLinks = %v, want %v", got, want)
+	}
+}
+
+func TestFetchFavicons(t *testing.T) {
+	icon := []byte("\x00\x00\x01\x00icon data")
+	server := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<link rel="icon" href="/icon.png"><link rel="icon" href="http://elsewhere.example/x.ico">` +
+				`<link rel="icon" href="data:image/png;base64,` + base64.StdEncoding.EncodeToString(icon) + `">`))
+		case "/icon.png":
+			w.Write(icon)
+		default:
+			stdhttp.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	addr := server.Listener.Addr().(*net.TCPAddr)
+
+	var module Module
+	flags := module.NewFlags().(*Flags)
+	flags.Endpoint = "/"
+	flags.Method = "GET"
+	flags.MaxSize = 256
+	flags.Port = uint(addr.Port)
+	flags.ConnectTimeout = time.Second
+	flags.Favicon = true
+	scanner := module.NewScanner().(*Scanner)
+	if err := scanner.Init(flags); err != nil {
+		t.Fatal(err)
+	}
+	dialerGroup, err := scanner.GetDialerGroupConfig().GetDefaultDialerGroupFromConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, ret, err := scanner.Scan(context.Background(), dialerGroup, &zgrab2.ScanTarget{IP: addr.IP, Port: uint(addr.Port)})
+	if status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status %s: %v", status, err)
+	}
+	favicons := ret.(*Results).Favicons
+	if len(favicons) != 3 {
+		t.Fatalf("got %d favicons, want 3: %+v", len(favicons), favicons)
+	}
+	if favicons[0].Source != "default" || favicons[0].StatusCode != stdhttp.StatusNotFound || favicons[0].MMH3 != nil {
+		t.Errorf("default favicon = %+v", favicons[0])
+	}
+	want := faviconHash(icon)
+	for _, favicon := range favicons[1:] {
+		if favicon.Source != "html" || favicon.MMH3 == nil || *favicon.MMH3 != want || favicon.Size != len(icon) {
+			t.Errorf("linked favicon = %+v, want mmh3 %d", favicon, want)
+		}
+	}
+	if favicons[2].URL != "data:image/png" {
+		t.Errorf("data URL recorded as %q", favicons[2].URL)
+	}
+}
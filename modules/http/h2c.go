@@ -0,0 +1,174 @@
+package http
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/zmap/zgrab2/lib/http"
+	"github.com/zmap/zgrab2/lib/http2"
+	"github.com/zmap/zgrab2/lib/http2/hpack"
+)
+
+// h2cSettings are the SETTINGS sent in the HTTP2-Settings header of an h2c
+// upgrade request, and again as the client's first frame after the upgrade.
+var h2cSettings = []http2.Setting{
+	{ID: http2.SettingEnablePush, Val: 1},
+}
+
+// h2cHeaderTableSize is the HPACK dynamic table size used to decode the
+// server's header blocks, the protocol default.
+const h2cHeaderTableSize = 4096
+
+// errH2CStreamReset is returned when the server resets the upgraded stream
+// before sending a response.
+var errH2CStreamReset = errors.New("server reset the h2c upgrade stream")
+
+// setH2CUpgradeHeaders asks the server to upgrade the connection of req to
+// HTTP/2 over cleartext (RFC 7540, section 3.2).
+func setH2CUpgradeHeaders(req *http.Request) {
+	payload := make([]byte, 0, 6*len(h2cSettings))
+	for _, s := range h2cSettings {
+		payload = binary.BigEndian.AppendUint16(payload, uint16(s.ID))
+		payload = binary.BigEndian.AppendUint32(payload, s.Val)
+	}
+	req.Header.Set("Connection", "Upgrade, HTTP2-Settings")
+	req.Header.Set("Upgrade", "h2c")
+	req.Header.Set("HTTP2-Settings", base64.RawURLEncoding.EncodeToString(payload))
+}
+
+// readH2CResponse speaks HTTP/2 over the connection of a "101 Switching
+// Protocols" response to an h2c upgrade request, and returns the response the
+// server sends on stream 1, which the upgrade implicitly opened. At most
+// maxBody bytes of the response body are read.
+func readH2CResponse(upgrade *http.Response, maxBody int64) (*http.Response, error) {
+	rw, ok := upgrade.Body.(io.ReadWriter)
+	if !ok {
+		return nil, errors.New("h2c upgrade response body is not writable")
+	}
+	if _, err := io.WriteString(rw, http2.ClientPreface); err != nil {
+		return nil, fmt.Errorf("could not write HTTP/2 preface: %w", err)
+	}
+	fr := http2.NewFramer(rw, rw)
+	fr.ReadMetaHeaders = hpack.NewDecoder(h2cHeaderTableSize, nil)
+	if err := fr.WriteSettings(h2cSettings...); err != nil {
+		return nil, fmt.Errorf("could not write HTTP/2 settings: %w", err)
+	}
+
+	info := &http.HTTP2Info{Negotiation: "h2c-upgrade", PushEnabled: true}
+	res := &http.Response{
+		Proto:         "HTTP/2.0",
+		ProtoMajor:    2,
+		Header:        make(http.Header),
+		Request:       upgrade.Request,
+		ContentLength: -1,
+		HTTP2:         info,
+	}
+	var body bytes.Buffer
+	gotHeaders := false
+	for {
+		f, err := fr.ReadFrame()
+		if err != nil {
+			if gotHeaders && errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("could not read HTTP/2 frame: %w", err)
+		}
+		done := false
+		switch f := f.(type) {
+		case *http2.SettingsFrame:
+			if f.IsAck() {
+				continue
+			}
+			f.ForeachSetting(func(s http2.Setting) error {
+				info.Settings = append(info.Settings, http.HTTP2Setting{ID: uint16(s.ID), Name: s.ID.String(), Value: s.Val})
+				return nil
+			})
+			err = fr.WriteSettingsAck()
+		case *http2.PingFrame:
+			if !f.IsAck() {
+				err = fr.WritePing(true, f.Data)
+			}
+		case *http2.PushPromiseFrame:
+			if !f.HeadersEnded() {
+				return nil, errors.New("PUSH_PROMISE continued in CONTINUATION frames")
+			}
+			fields, decErr := fr.ReadMetaHeaders.DecodeFull(f.HeaderBlockFragment())
+			if decErr != nil {
+				return nil, fmt.Errorf("could not decode PUSH_PROMISE headers: %w", decErr)
+			}
+			promise := http.HTTP2PushPromise{PromisedStreamID: f.PromiseID}
+			for _, hf := range fields {
+				promise.Headers = append(promise.Headers, http.HTTP2Field{Name: hf.Name, Value: hf.Value})
+			}
+			info.PushPromises = append(info.PushPromises, promise)
+			err = fr.WriteRSTStream(f.PromiseID, http2.ErrCodeRefusedStream)
+		case *http2.MetaHeadersFrame:
+			if f.StreamID != 1 {
+				continue
+			}
+			if !gotHeaders {
+				status := f.PseudoValue("status")
+				code, convErr := strconv.Atoi(status)
+				if convErr != nil {
+					return nil, fmt.Errorf("malformed :status pseudo header %q", status)
+				}
+				if code >= 100 && code <= 199 {
+					continue
+				}
+				gotHeaders = true
+				res.StatusCode = code
+				res.Status = status + " " + http.StatusText(code)
+				for _, hf := range f.PseudoFields() {
+					info.PseudoHeaders = append(info.PseudoHeaders, http.HTTP2Field{Name: hf.Name, Value: hf.Value})
+				}
+				for _, hf := range f.RegularFields() {
+					res.Header.Add(hf.Name, hf.Value)
+				}
+				if cl, clErr := strconv.ParseInt(res.Header.Get("Content-Length"), 10, 63); clErr == nil {
+					res.ContentLength = cl
+				}
+			}
+			done = f.StreamEnded()
+		case *http2.DataFrame:
+			if f.StreamID != 1 {
+				continue
+			}
+			data := f.Data()
+			if remaining := maxBody - int64(body.Len()); int64(len(data)) > remaining {
+				data = data[:remaining]
+			}
+			body.Write(data)
+			if n := uint32(len(f.Data())); n > 0 && !f.StreamEnded() {
+				if err = fr.WriteWindowUpdate(0, n); err == nil {
+					err = fr.WriteWindowUpdate(1, n)
+				}
+			}
+			done = f.StreamEnded() || int64(body.Len()) >= maxBody
+		case *http2.RSTStreamFrame:
+			if f.StreamID == 1 {
+				if !gotHeaders {
+					return nil, fmt.Errorf("%w: %v", errH2CStreamReset, f.ErrCode)
+				}
+				done = true
+			}
+		case *http2.GoAwayFrame:
+			if !gotHeaders {
+				return nil, fmt.Errorf("server sent GOAWAY before responding: %v", f.ErrCode)
+			}
+			done = true
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not write HTTP/2 frame: %w", err)
+		}
+		if done {
+			break
+		}
+	}
+	res.Body = io.NopCloser(&body)
+	return res, nil
+}
@@ -0,0 +1,121 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/zmap/zgrab2/lib/http"
+	"github.com/zmap/zgrab2/lib/http2"
+	"github.com/zmap/zgrab2/lib/http2/hpack"
+)
+
+func TestSetH2CUpgradeHeaders(t *testing.T) {
+	req, err := http.NewRequest("GET", "http://example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setH2CUpgradeHeaders(req)
+	if got := req.Header.Get("Upgrade"); got != "h2c" {
+		t.Errorf("Upgrade = %q, want h2c", got)
+	}
+	if got := req.Header.Get("Connection"); got != "Upgrade, HTTP2-Settings" {
+		t.Errorf("Connection = %q", got)
+	}
+	// SETTINGS_ENABLE_PUSH (0x2) = 1
+	if got := req.Header.Get("HTTP2-Settings"); got != "AAIAAAAB" {
+		t.Errorf("HTTP2-Settings = %q, want AAIAAAAB", got)
+	}
+}
+
+func headerBlock(t *testing.T, fields ...string) []byte {
+	var buf bytes.Buffer
+	enc := hpack.NewEncoder(&buf)
+	for i := 0; i < len(fields); i += 2 {
+		if err := enc.WriteField(hpack.HeaderField{Name: fields[i], Value: fields[i+1]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestReadH2CResponse(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		preface := make([]byte, len(http2.ClientPreface))
+		if _, err := io.ReadFull(server, preface); err != nil {
+			return
+		}
+		// Drain the client's SETTINGS, acknowledgements and resets.
+		go io.Copy(io.Discard, server)
+		fr := http2.NewFramer(server, nil)
+		fr.WriteSettings(http2.Setting{ID: http2.SettingMaxConcurrentStreams, Val: 128})
+		fr.WritePushPromise(http2.PushPromiseParam{
+			StreamID:      1,
+			PromiseID:     2,
+			BlockFragment: headerBlock(t, ":method", "GET", ":path", "/app.js"),
+			EndHeaders:    true,
+		})
+		fr.WriteHeaders(http2.HeadersFrameParam{
+			StreamID:      1,
+			BlockFragment: headerBlock(t, ":status", "200", "content-type", "text/plain", "content-length", "5"),
+			EndHeaders:    true,
+		})
+		fr.WriteData(1, true, []byte("hello"))
+	}()
+
+	upgrade := &http.Response{StatusCode: http.StatusSwitchingProtocols, Body: client}
+	res, err := readH2CResponse(upgrade, 1024)
+	if err != nil {
+		t.Fatalf("readH2CResponse: %v", err)
+	}
+	if res.StatusCode != 200 || res.Proto != "HTTP/2.0" || res.ContentLength != 5 {
+		t.Errorf("got status %d, proto %s, content length %d", res.StatusCode, res.Proto, res.ContentLength)
+	}
+	if got := res.Header.Get("Content-Type"); got != "text/plain" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	body, _ := io.ReadAll(res.Body)
+	if string(body) != "hello" {
+		t.Errorf("body = %q, want hello", body)
+	}
+	info := res.HTTP2
+	if info.Negotiation != "h2c-upgrade" || !info.PushEnabled {
+		t.Errorf("negotiation %q, push enabled %v", info.Negotiation, info.PushEnabled)
+	}
+	if len(info.Settings) != 1 || info.Settings[0].ID != uint16(http2.SettingMaxConcurrentStreams) || info.Settings[0].Value != 128 {
+		t.Errorf("Settings = %+v", info.Settings)
+	}
+	if len(info.PseudoHeaders) != 1 || info.PseudoHeaders[0].Value != "200" {
+		t.Errorf("PseudoHeaders = %+v", info.PseudoHeaders)
+	}
+	if len(info.PushPromises) != 1 || info.PushPromises[0].PromisedStreamID != 2 {
+		t.Errorf("PushPromises = %+v", info.PushPromises)
+	}
+}
+
+func TestReadH2CResponseReset(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		preface := make([]byte, len(http2.ClientPreface))
+		if _, err := io.ReadFull(server, preface); err != nil {
+			return
+		}
+		go io.Copy(io.Discard, server)
+		fr := http2.NewFramer(server, nil)
+		fr.WriteSettings()
+		fr.WriteRSTStream(1, http2.ErrCodeRefusedStream)
+	}()
+
+	upgrade := &http.Response{StatusCode: http.StatusSwitchingProtocols, Body: client}
+	if _, err := readH2CResponse(upgrade, 1024); err == nil {
+		t.Fatal("expected an error for a reset stream")
+	}
+}
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/zmap/zgrab2/lib/http"
+)
+
+// maxHTMLFieldLength bounds the length of each extracted field.
+const maxHTMLFieldLength = 1024
+
+// HTMLMetadata holds the fields extracted from an HTML response with --extract-html.
+type HTMLMetadata struct {
+	Title     string `json:"title,omitempty"`
+	Generator string `json:"generator,omitempty"`
+	Canonical string `json:"canonical,omitempty"`
+}
+
+// cleanHTMLField collapses the whitespace of an extracted field and truncates it.
+func cleanHTMLField(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > maxHTMLFieldLength {
+		s = s[:maxHTMLFieldLength]
+	}
+	return s
+}
+
+// isHTML reports whether a response is HTML, by its Content-Type or, if it has none, by its body.
+func isHTML(res *http.Response) bool {
+	contentType := strings.ToLower(res.Header.Get("Content-Type"))
+	if contentType != "" {
+		return strings.Contains(contentType, "html")
+	}
+	start := strings.ToLower(strings.TrimSpace(res.BodyText))
+	return strings.HasPrefix(start, "<!doctype html") || strings.HasPrefix(start, "<html")
+}
+
+// extractHTMLMetadata returns the first title, meta generator and canonical link of an HTML document, or nil if it
+// has none of them.
+func extractHTMLMetadata(body string) *HTMLMetadata {
+	meta := new(HTMLMetadata)
+	tokenizer := html.NewTokenizer(strings.NewReader(body))
+	inTitle := false
+	for {
+		tokenType := tokenizer.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			if *meta == (HTMLMetadata{}) {
+				return nil
+			}
+			return meta
+		case html.TextToken:
+			if inTitle && meta.Title == "" {
+				meta.Title = cleanHTMLField(html.UnescapeString(string(tokenizer.Text())))
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			if atom.Lookup(name) == atom.Title {
+				inTitle = false
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			tag := atom.Lookup(name)
+			if tag == atom.Title && tokenType == html.StartTagToken {
+				inTitle = true
+				continue
+			}
+			if !hasAttr || (tag != atom.Meta && tag != atom.Link) {
+				continue
+			}
+			attrs := make(map[string]string)
+			for {
+				key, value, more := tokenizer.TagAttr()
+				attrs[string(key)] = string(value)
+				if !more {
+					break
+				}
+			}
+			switch {
+			case tag == atom.Meta && strings.EqualFold(attrs["name"], "generator") && meta.Generator == "":
+				meta.Generator = cleanHTMLField(attrs["content"])
+			case tag == atom.Link && strings.EqualFold(strings.TrimSpace(attrs["rel"]), "canonical") && meta.Canonical == "":
+				meta.Canonical = cleanHTMLField(attrs["href"])
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package http
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zmap/zgrab2/lib/http"
+)
+
+func TestExtractHTMLMetadata(t *testing.T) {
+	body := `<!DOCTYPE html><html><head>
+<TITLE>
+  Welcome &amp; hello
+  world</TITLE>
+<meta name="Generator" content="WordPress 6.4.2">
+<meta name="generator" content="ignored">
+<link rel="canonical" href="https://example.com/">
+</head><body><title>not the page title</title></body></html>`
+	meta := extractHTMLMetadata(body)
+	want := HTMLMetadata{Title: "Welcome & hello world", Generator: "WordPress 6.4.2", Canonical: "https://example.com/"}
+	if meta == nil || *meta != want {
+		t.Errorf("extractHTMLMetadata = %+v, want %+v", meta, want)
+	}
+
+	if meta := extractHTMLMetadata("<html><body>nothing</body></html>"); meta != nil {
+		t.Errorf("got %+v for a page without metadata", meta)
+	}
+	if meta := extractHTMLMetadata("<title>" + strings.Repeat("x", 2000) + "</title>"); meta == nil || len(meta.Title) != maxHTMLFieldLength {
+		t.Error("long title not truncated")
+	}
+}
+
+func TestIsHTML(t *testing.T) {
+	for _, tc := range []struct {
+		contentType, body string
+		want              bool
+	}{
+		{"text/html; charset=utf-8", "", true},
+		{"application/xhtml+xml", "", true},
+		{"application/json", "<html>", false},
+		{"", "  <!DOCTYPE html><html>", true},
+		{"", "plain text", false},
+	} {
+		res := &http.Response{Header: make(http.Header), BodyText: tc.body}
+		if tc.contentType != "" {
+			res.Header.Set("Content-Type", tc.contentType)
+		}
+		if got := isHTML(res); got != tc.want {
+			t.Errorf("isHTML(%q, %q) = %v, want %v", tc.contentType, tc.body, got, tc.want)
+		}
+	}
+}
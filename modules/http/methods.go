@@ -0,0 +1,116 @@
+package http
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/zmap/zgrab2/lib/http"
+)
+
+// traceHeader is sent with TRACE and TRACK requests to detect whether the server reflects them.
+const traceHeader = "X-Zgrab2-Trace"
+
+// MethodsResult is the method enumeration done with --methods.
+type MethodsResult struct {
+	URL string `json:"url"`
+
+	// Allow lists the methods of the Allow header of the response to OPTIONS, and Public those of its Public
+	// header, which IIS sends as well.
+	Allow  []string `json:"allow,omitempty"`
+	Public []string `json:"public,omitempty"`
+
+	Probes []*MethodProbe `json:"probes,omitempty"`
+}
+
+// MethodProbe is how the server answered a request with one method.
+type MethodProbe struct {
+	Method string `json:"method"`
+	// URL is set if the request was not sent to the URL of the enumeration, as for PUT.
+	URL        string `json:"url,omitempty"`
+	StatusCode int    `json:"status_code,omitempty"`
+
+	// Advertised is true if the method is listed in Allow or Public, and Accepted if the server answered with a
+	// 2xx status, so that differences between them show methods that are not enforced as advertised.
+	Advertised bool `json:"advertised"`
+	Accepted   bool `json:"accepted"`
+
+	// Reflected is true if the response to TRACE or TRACK echoes the request back.
+	Reflected bool `json:"reflected,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// sendMethod sends a request with an empty body to target and records the answer in probe, returning the start of
+// the response body.
+func (scan *scan) sendMethod(probe *MethodProbe, target string, header http.Header) (*http.Response, []byte) {
+	request, err := http.NewRequest(probe.Method, target, nil)
+	if err != nil {
+		probe.Error = err.Error()
+		return nil, nil
+	}
+	scan.prepareRequest(request)
+	for k, v := range header {
+		request.Header[k] = v
+	}
+	res, err := scan.do(request)
+	if err != nil {
+		probe.Error = err.Error()
+		return nil, nil
+	}
+	defer res.Body.Close()
+	probe.StatusCode = res.StatusCode
+	probe.Accepted = res.StatusCode >= 200 && res.StatusCode < 300
+	body, _ := io.ReadAll(io.LimitReader(res.Body, int64(scan.scanner.config.MaxSize)*1024))
+	return res, body
+}
+
+// enumerateMethods sends OPTIONS, TRACE, TRACK and, with --methods-put, PUT to the URL of the final response,
+// without following redirects.
+func (scan *scan) enumerateMethods() {
+	res := scan.results.Response
+	if res == nil || res.Request == nil || res.Request.URL == nil {
+		return
+	}
+	base := res.Request.URL
+	result := &MethodsResult{URL: base.String()}
+	scan.results.Methods = result
+	scan.noRedirects = true
+	defer func() { scan.noRedirects = false }()
+
+	options := &MethodProbe{Method: "OPTIONS"}
+	if optionsRes, _ := scan.sendMethod(options, result.URL, nil); optionsRes != nil {
+		result.Allow = splitHeaderList(optionsRes.Header.Values("Allow"))
+		result.Public = splitHeaderList(optionsRes.Header.Values("Public"))
+	}
+	result.Probes = append(result.Probes, options)
+
+	nonce := make([]byte, 8)
+	rand.Read(nonce)
+	marker := hex.EncodeToString(nonce)
+	for _, method := range []string{"TRACE", "TRACK"} {
+		probe := &MethodProbe{Method: method}
+		if _, body := scan.sendMethod(probe, result.URL, http.Header{traceHeader: {marker}}); probe.Accepted {
+			probe.Reflected = bytes.Contains(body, []byte(marker))
+		}
+		result.Probes = append(result.Probes, probe)
+	}
+
+	if scan.scanner.config.MethodsPUT {
+		put := &MethodProbe{Method: "PUT"}
+		put.URL = base.ResolveReference(&url.URL{Path: "zgrab2-" + marker + ".txt"}).String()
+		scan.sendMethod(put, put.URL, nil)
+		result.Probes = append(result.Probes, put)
+	}
+
+	advertised := slices.Concat(result.Allow, result.Public)
+	for _, probe := range result.Probes {
+		probe.Advertised = slices.ContainsFunc(advertised, func(method string) bool {
+			return strings.EqualFold(method, probe.Method)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package http
+
+import (
+	"context"
+	"net"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zmap/zgrab2"
+)
+
+func TestEnumerateMethods(t *testing.T) {
+	var putPath string
+	server := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		switch r.Method {
+		case "GET":
+			w.Write([]byte("index"))
+		case "OPTIONS":
+			w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+			w.Header().Set("Public", "OPTIONS, TRACE, GET, HEAD")
+		case "TRACE":
+			dump, _ := httputil.DumpRequest(r, false)
+			w.Header().Set("Content-Type", "message/http")
+			w.Write(dump)
+		case "PUT":
+			putPath = r.URL.Path
+			w.WriteHeader(stdhttp.StatusCreated)
+		default:
+			w.WriteHeader(stdhttp.StatusMethodNotAllowed)
+		}
+	}))
+	defer server.Close()
+	addr := server.Listener.Addr().(*net.TCPAddr)
+
+	var module Module
+	flags := module.NewFlags().(*Flags)
+	flags.Endpoint = "/app/index.html"
+	flags.Method = "GET"
+	flags.MaxSize = 256
+	flags.Port = uint(addr.Port)
+	flags.ConnectTimeout = time.Second
+	flags.Methods = true
+	flags.MethodsPUT = true
+	scanner := module.NewScanner().(*Scanner)
+	if err := scanner.Init(flags); err != nil {
+		t.Fatal(err)
+	}
+	dialerGroup, err := scanner.GetDialerGroupConfig().GetDefaultDialerGroupFromConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, ret, err := scanner.Scan(context.Background(), dialerGroup, &zgrab2.ScanTarget{IP: addr.IP, Port: uint(addr.Port)})
+	if status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status %s: %v", status, err)
+	}
+	methods := ret.(*Results).Methods
+	if methods == nil || !slices.Equal(methods.Allow, []string{"GET", "HEAD", "OPTIONS"}) || len(methods.Public) != 4 {
+		t.Fatalf("methods = %+v", methods)
+	}
+	want := map[string]MethodProbe{
+		"OPTIONS": {StatusCode: stdhttp.StatusOK, Advertised: true, Accepted: true},
+		"TRACE":   {StatusCode: stdhttp.StatusOK, Advertised: true, Accepted: true, Reflected: true},
+		"TRACK":   {StatusCode: stdhttp.StatusMethodNotAllowed},
+		"PUT":     {StatusCode: stdhttp.StatusCreated, Accepted: true},
+	}
+	if len(methods.Probes) != len(want) {
+		t.Fatalf("got %d probes, want %d", len(methods.Probes), len(want))
+	}
+	for _, probe := range methods.Probes {
+		got := *probe
+		got.Method, got.URL = "", ""
+		if got != want[probe.Method] {
+			t.Errorf("%s: probe = %+v, want %+v", probe.Method, got, want[probe.Method])
+		}
+	}
+	if !strings.HasPrefix(putPath, "/app/zgrab2-") || !strings.HasSuffix(methods.Probes[3].URL, putPath) {
+		t.Errorf("PUT sent to %s, recorded %s", putPath, methods.Probes[3].URL)
+	}
+}
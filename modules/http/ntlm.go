@@ -0,0 +1,162 @@
+package http
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zmap/zgrab2/lib/http"
+	"github.com/zmap/zgrab2/lib/smb/ntlmssp"
+	"github.com/zmap/zgrab2/lib/smb/smb/encoder"
+)
+
+var errNoNTLMChallenge = errors.New("no NTLM challenge in the response")
+
+// NTLMInfo is the NTLM challenge (type-2 message) of a server, obtained with --ntlm.
+type NTLMInfo struct {
+	// Scheme is the authentication scheme the negotiate message was sent with: NTLM or Negotiate.
+	Scheme     string `json:"scheme"`
+	StatusCode int    `json:"status_code,omitempty"`
+
+	TargetName          string     `json:"target_name,omitempty"`
+	NetBIOSDomainName   string     `json:"netbios_domain_name,omitempty"`
+	NetBIOSComputerName string     `json:"netbios_computer_name,omitempty"`
+	DNSDomainName       string     `json:"dns_domain_name,omitempty"`
+	DNSComputerName     string     `json:"dns_computer_name,omitempty"`
+	DNSTreeName         string     `json:"dns_tree_name,omitempty"`
+	Timestamp           *time.Time `json:"timestamp,omitempty"`
+	NegotiateFlags      uint32     `json:"negotiate_flags"`
+
+	// OSVersion is the Windows version in the form major.minor.build, if the server sent it.
+	OSVersion    string `json:"os_version,omitempty"`
+	NTLMRevision uint8  `json:"ntlm_revision,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// authSchemes returns the authentication schemes offered in WWW-Authenticate headers, lower-cased.
+func authSchemes(header http.Header) map[string]bool {
+	schemes := make(map[string]bool)
+	for _, value := range header.Values("WWW-Authenticate") {
+		for _, challenge := range strings.Split(value, ",") {
+			if fields := strings.Fields(challenge); len(fields) > 0 {
+				schemes[strings.ToLower(fields[0])] = true
+			}
+		}
+	}
+	return schemes
+}
+
+// filetimeEpochDelta is the number of 100ns intervals between 1601-01-01 and the Unix epoch.
+const filetimeEpochDelta = 116444736000000000
+
+// parseNTLMChallenge decodes the NTLM challenge in a token, which may be wrapped in SPNEGO.
+func parseNTLMChallenge(token []byte) (*NTLMInfo, error) {
+	i := bytes.Index(token, []byte(ntlmssp.Signature))
+	if i < 0 {
+		return nil, errNoNTLMChallenge
+	}
+	challenge := ntlmssp.NewChallenge()
+	if err := encoder.Unmarshal(token[i:], &challenge); err != nil {
+		return nil, fmt.Errorf("invalid NTLM challenge: %w", err)
+	}
+	if challenge.MessageType != ntlmssp.TypeNtLmChallenge {
+		return nil, fmt.Errorf("unexpected NTLM message type %d", challenge.MessageType)
+	}
+	info := &NTLMInfo{NegotiateFlags: challenge.NegotiateFlags}
+	info.TargetName, _ = encoder.FromUnicode(challenge.TargetName)
+	if challenge.NegotiateFlags&ntlmssp.FlgNegVersion != 0 && challenge.Version != 0 {
+		v := challenge.Version
+		info.OSVersion = fmt.Sprintf("%d.%d.%d", v&0xff, (v>>8)&0xff, (v>>16)&0xffff)
+		info.NTLMRevision = uint8(v >> 56)
+	}
+	if challenge.TargetInfo != nil {
+		for _, pair := range *challenge.TargetInfo {
+			value, _ := encoder.FromUnicode(pair.Value)
+			switch pair.AvID {
+			case ntlmssp.MsvAvNbDomainName:
+				info.NetBIOSDomainName = value
+			case ntlmssp.MsvAvNbComputerName:
+				info.NetBIOSComputerName = value
+			case ntlmssp.MsvAvDnsDomainName:
+				info.DNSDomainName = value
+			case ntlmssp.MsvAvDnsComputerName:
+				info.DNSComputerName = value
+			case ntlmssp.MsvAvDnsTreeName:
+				info.DNSTreeName = value
+			case ntlmssp.MsvAvTimestamp:
+				if len(pair.Value) == 8 {
+					filetime := int64(binary.LittleEndian.Uint64(pair.Value))
+					timestamp := time.Unix(0, (filetime-filetimeEpochDelta)*100).UTC()
+					info.Timestamp = &timestamp
+				}
+			}
+		}
+	}
+	return info, nil
+}
+
+// fetchNTLMInfo sends an NTLM negotiate message (type-1) if the final response requires NTLM or Negotiate
+// authentication, and decodes the challenge of the server.
+func (scan *scan) fetchNTLMInfo() {
+	res := scan.results.Response
+	if res == nil || res.StatusCode != http.StatusUnauthorized || res.Request == nil || res.Request.URL == nil {
+		return
+	}
+	schemes := authSchemes(res.Header)
+	scheme := "NTLM"
+	if !schemes["ntlm"] {
+		if !schemes["negotiate"] {
+			return
+		}
+		scheme = "Negotiate"
+	}
+	info := &NTLMInfo{Scheme: scheme}
+	scan.results.NTLM = info
+
+	negotiate, err := encoder.Marshal(ntlmssp.NewNegotiate("", ""))
+	if err != nil {
+		info.Error = err.Error()
+		return
+	}
+	request, err := http.NewRequest("GET", res.Request.URL.String(), nil)
+	if err != nil {
+		info.Error = err.Error()
+		return
+	}
+	scan.prepareRequest(request)
+	request.Header.Set("Authorization", scheme+" "+base64.StdEncoding.EncodeToString(negotiate))
+	scan.noRedirects = true
+	defer func() { scan.noRedirects = false }()
+	challengeRes, err := scan.do(request)
+	if err != nil {
+		info.Error = err.Error()
+		return
+	}
+	defer challengeRes.Body.Close()
+	info.StatusCode = challengeRes.StatusCode
+
+	err = errNoNTLMChallenge
+	for _, value := range challengeRes.Header.Values("WWW-Authenticate") {
+		name, token, ok := strings.Cut(strings.TrimSpace(value), " ")
+		if !ok || !strings.EqualFold(name, scheme) {
+			continue
+		}
+		decoded, decodeErr := base64.StdEncoding.DecodeString(strings.TrimSpace(token))
+		if decodeErr != nil {
+			err = fmt.Errorf("invalid %s token: %w", scheme, decodeErr)
+			continue
+		}
+		var challenge *NTLMInfo
+		if challenge, err = parseNTLMChallenge(decoded); err == nil {
+			challenge.Scheme, challenge.StatusCode = info.Scheme, info.StatusCode
+			*info = *challenge
+			return
+		}
+	}
+	info.Error = err.Error()
+}
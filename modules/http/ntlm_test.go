@@ -0,0 +1,144 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"net"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/zmap/zgrab2"
+	"github.com/zmap/zgrab2/lib/smb/ntlmssp"
+)
+
+func utf16LE(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, u)
+	}
+	return b
+}
+
+// buildNTLMChallenge returns a challenge message (MS-NLMP section 2.2.1.2) of a Windows Server 2019 host.
+func buildNTLMChallenge() []byte {
+	targetName := utf16LE("CORP")
+	var targetInfo []byte
+	for _, pair := range []struct {
+		id    uint16
+		value []byte
+	}{
+		{ntlmssp.MsvAvNbDomainName, utf16LE("CORP")},
+		{ntlmssp.MsvAvNbComputerName, utf16LE("WEB01")},
+		{ntlmssp.MsvAvDnsDomainName, utf16LE("corp.example.com")},
+		{ntlmssp.MsvAvDnsComputerName, utf16LE("web01.corp.example.com")},
+		{ntlmssp.MsvAvDnsTreeName, utf16LE("example.com")},
+		// 2024-01-02 03:04:05 UTC
+		{ntlmssp.MsvAvTimestamp, binary.LittleEndian.AppendUint64(nil, uint64(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).UnixNano()/100+filetimeEpochDelta))},
+		{ntlmssp.MsvAvEOL, nil},
+	} {
+		targetInfo = binary.LittleEndian.AppendUint16(targetInfo, pair.id)
+		targetInfo = binary.LittleEndian.AppendUint16(targetInfo, uint16(len(pair.value)))
+		targetInfo = append(targetInfo, pair.value...)
+	}
+	flags := ntlmssp.FlgNegUnicode | ntlmssp.FlgNegNtLm | ntlmssp.FlgNegTargetInfo | ntlmssp.FlgNegVersion
+	b := []byte(ntlmssp.Signature)
+	b = binary.LittleEndian.AppendUint32(b, ntlmssp.TypeNtLmChallenge)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(targetName)))
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(targetName)))
+	b = binary.LittleEndian.AppendUint32(b, 56)
+	b = binary.LittleEndian.AppendUint32(b, flags)
+	b = append(b, "challnge"...)
+	b = append(b, make([]byte, 8)...)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(targetInfo)))
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(targetInfo)))
+	b = binary.LittleEndian.AppendUint32(b, uint32(56+len(targetName)))
+	// 10.0 build 17763, NTLM revision 15
+	b = append(b, 10, 0, 0x63, 0x45, 0, 0, 0, 15)
+	b = append(b, targetName...)
+	return append(b, targetInfo...)
+}
+
+func TestParseNTLMChallenge(t *testing.T) {
+	// the challenge wrapped in the start of an SPNEGO token
+	info, err := parseNTLMChallenge(append([]byte{0xa1, 0x81, 0xc0, 0x30}, buildNTLMChallenge()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := NTLMInfo{
+		TargetName:          "CORP",
+		NetBIOSDomainName:   "CORP",
+		NetBIOSComputerName: "WEB01",
+		DNSDomainName:       "corp.example.com",
+		DNSComputerName:     "web01.corp.example.com",
+		DNSTreeName:         "example.com",
+		OSVersion:           "10.0.17763",
+		NTLMRevision:        15,
+		NegotiateFlags:      ntlmssp.FlgNegUnicode | ntlmssp.FlgNegNtLm | ntlmssp.FlgNegTargetInfo | ntlmssp.FlgNegVersion,
+	}
+	if info.Timestamp == nil || !info.Timestamp.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("Timestamp = %v", info.Timestamp)
+	}
+	info.Timestamp = nil
+	if *info != want {
+		t.Errorf("info = %+v, want %+v", *info, want)
+	}
+
+	if _, err := parseNTLMChallenge([]byte("not a challenge")); err != errNoNTLMChallenge {
+		t.Errorf("err = %v", err)
+	}
+	if _, err := parseNTLMChallenge(buildNTLMChallenge()[:40]); err == nil {
+		t.Error("truncated challenge accepted")
+	}
+}
+
+func TestFetchNTLMInfo(t *testing.T) {
+	server := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "NTLM "); ok {
+			negotiate, err := base64.StdEncoding.DecodeString(token)
+			if err == nil && bytes.HasPrefix(negotiate, []byte(ntlmssp.Signature)) &&
+				binary.LittleEndian.Uint32(negotiate[8:]) == ntlmssp.TypeNtLmNegotiate {
+				w.Header().Set("WWW-Authenticate", "NTLM "+base64.StdEncoding.EncodeToString(buildNTLMChallenge()))
+			}
+		} else {
+			w.Header().Add("WWW-Authenticate", "Negotiate")
+			w.Header().Add("WWW-Authenticate", "NTLM")
+		}
+		w.WriteHeader(stdhttp.StatusUnauthorized)
+	}))
+	defer server.Close()
+	addr := server.Listener.Addr().(*net.TCPAddr)
+
+	var module Module
+	flags := module.NewFlags().(*Flags)
+	flags.Endpoint = "/"
+	flags.Method = "GET"
+	flags.MaxSize = 256
+	flags.Port = uint(addr.Port)
+	flags.ConnectTimeout = time.Second
+	flags.NTLM = true
+	scanner := module.NewScanner().(*Scanner)
+	if err := scanner.Init(flags); err != nil {
+		t.Fatal(err)
+	}
+	dialerGroup, err := scanner.GetDialerGroupConfig().GetDefaultDialerGroupFromConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, ret, err := scanner.Scan(context.Background(), dialerGroup, &zgrab2.ScanTarget{IP: addr.IP, Port: uint(addr.Port)})
+	if status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status %s: %v", status, err)
+	}
+	info := ret.(*Results).NTLM
+	if info == nil || info.Error != "" || info.Scheme != "NTLM" || info.StatusCode != stdhttp.StatusUnauthorized {
+		t.Fatalf("ntlm = %+v", info)
+	}
+	if info.DNSComputerName != "web01.corp.example.com" || info.OSVersion != "10.0.17763" {
+		t.Errorf("ntlm = %+v", info)
+	}
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/zmap/zgrab2/lib/http"
+)
+
+var (
+	// ErrCrossHostRedirect is returned when a redirect to another host exceeds MaxCrossHostRedirects.
+	ErrCrossHostRedirect = errors.New("cross-host redirect not followed")
+	// ErrCrossSchemeRedirect is returned when a redirect changes the scheme and NoCrossSchemeRedirects is set.
+	ErrCrossSchemeRedirect = errors.New("cross-scheme redirect not followed")
+)
+
+// RedirectHop is a response of the redirect chain, recorded when following redirects.
+type RedirectHop struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location,omitempty"`
+
+	// Headers are the headers of the response selected with --redirect-chain-headers.
+	Headers map[string][]string `json:"headers,omitempty"`
+
+	// IP is the address of the connection the response was read from.
+	IP string `json:"ip,omitempty"`
+
+	// CrossHost and CrossScheme tell whether Location points to another host or scheme than URL.
+	CrossHost   bool `json:"cross_host,omitempty"`
+	CrossScheme bool `json:"cross_scheme,omitempty"`
+
+	// NotFollowed is the reason the redirect of this response was not followed: max-redirects, cross-host or
+	// cross-scheme.
+	NotFollowed string `json:"not_followed,omitempty"`
+}
+
+// parseRedirectChainHeaders returns the canonical names of a comma-separated list of headers.
+func parseRedirectChainHeaders(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, http.CanonicalHeaderKey(name))
+		}
+	}
+	return names
+}
+
+// newRedirectHop records a response of the redirect chain.
+func (scan *scan) newRedirectHop(res *http.Response) RedirectHop {
+	hop := RedirectHop{
+		StatusCode: res.StatusCode,
+		Location:   res.Header.Get("Location"),
+		IP:         scan.lastConnAddr,
+	}
+	if res.Request != nil && res.Request.URL != nil {
+		hop.URL = res.Request.URL.String()
+		if next, err := res.Location(); err == nil {
+			hop.CrossHost = !strings.EqualFold(next.Hostname(), res.Request.URL.Hostname())
+			hop.CrossScheme = next.Scheme != res.Request.URL.Scheme
+		}
+	}
+	for _, name := range scan.scanner.redirectChainHeaders {
+		if values := res.Header.Values(name); len(values) > 0 {
+			if hop.Headers == nil {
+				hop.Headers = make(map[string][]string)
+			}
+			hop.Headers[name] = values
+		}
+	}
+	return hop
+}
+
+// checkRedirectPolicy records a redirect response in the chain and returns an error if the cross-host and
+// cross-scheme flags forbid following it.
+func (scan *scan) checkRedirectPolicy(res *http.Response) error {
+	hop := scan.newRedirectHop(res)
+	var err error
+	switch {
+	case hop.CrossScheme && scan.scanner.config.NoCrossSchemeRedirects:
+		hop.NotFollowed = "cross-scheme"
+		err = ErrCrossSchemeRedirect
+	case hop.CrossHost:
+		scan.crossHostRedirects++
+		if limit := scan.scanner.config.MaxCrossHostRedirects; limit >= 0 && scan.crossHostRedirects > limit {
+			hop.NotFollowed = "cross-host"
+			err = ErrCrossHostRedirect
+		}
+	}
+	scan.results.RedirectChain = append(scan.results.RedirectChain, hop)
+	return err
+}
+
+// finishRedirectChain records the final response in the chain, unless it is a redirect that was not followed and
+// is recorded already.
+func (scan *scan) finishRedirectChain(res *http.Response) {
+	if scan.scanner.config.MaxRedirects == 0 {
+		return
+	}
+	if chain := scan.results.RedirectChain; len(chain) > 0 && chain[len(chain)-1].NotFollowed != "" {
+		return
+	}
+	scan.results.RedirectChain = append(scan.results.RedirectChain, scan.newRedirectHop(res))
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"context"
+	"net"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zmap/zgrab2"
+)
+
+func TestRedirectChain(t *testing.T) {
+	var crossLocation string
+	server := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.Header().Set("Server", "test")
+		switch r.URL.Path {
+		case "/":
+			stdhttp.Redirect(w, r, "/a", stdhttp.StatusFound)
+		case "/a":
+			stdhttp.Redirect(w, r, crossLocation, stdhttp.StatusMovedPermanently)
+		case "/b":
+			stdhttp.Redirect(w, r, "/c", stdhttp.StatusMovedPermanently)
+		default:
+			stdhttp.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	addr := server.Listener.Addr().(*net.TCPAddr)
+
+	for _, test := range []struct {
+		name        string
+		location    string
+		configure   func(*Flags)
+		notFollowed string
+		hops        int
+	}{
+		{
+			name:        "cross-host",
+			location:    "http://other.example/",
+			configure:   func(flags *Flags) { flags.MaxCrossHostRedirects = 0 },
+			notFollowed: "cross-host",
+			hops:        2,
+		},
+		{
+			name:        "cross-scheme",
+			location:    "https://" + addr.String() + "/",
+			configure:   func(flags *Flags) { flags.NoCrossSchemeRedirects = true },
+			notFollowed: "cross-scheme",
+			hops:        2,
+		},
+		{
+			name:        "max-redirects",
+			location:    "/b",
+			configure:   func(flags *Flags) { flags.MaxRedirects = 1; flags.RedirectsSucceed = true },
+			notFollowed: "max-redirects",
+			// the check counts the initial request
+			hops: 3,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			crossLocation = test.location
+			var module Module
+			flags := module.NewFlags().(*Flags)
+			flags.Endpoint = "/"
+			flags.Method = "GET"
+			flags.MaxSize = 256
+			flags.MaxRedirects = 5
+			flags.MaxCrossHostRedirects = -1
+			flags.RedirectChainHeaders = "server"
+			flags.Port = uint(addr.Port)
+			flags.ConnectTimeout = time.Second
+			test.configure(flags)
+			scanner := module.NewScanner().(*Scanner)
+			if err := scanner.Init(flags); err != nil {
+				t.Fatal(err)
+			}
+			dialerGroup, err := scanner.GetDialerGroupConfig().GetDefaultDialerGroupFromConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			status, ret, err := scanner.Scan(context.Background(), dialerGroup, &zgrab2.ScanTarget{IP: addr.IP, Port: uint(addr.Port)})
+			if status != zgrab2.SCAN_SUCCESS {
+				t.Fatalf("status %s: %v", status, err)
+			}
+			results := ret.(*Results)
+			chain := results.RedirectChain
+			if len(chain) != test.hops {
+				t.Fatalf("got %d hops, want %d: %+v", len(chain), test.hops, chain)
+			}
+			if chain[0].StatusCode != stdhttp.StatusFound || chain[0].Location != "/a" || chain[0].NotFollowed != "" {
+				t.Errorf("first hop = %+v", chain[0])
+			}
+			last := chain[len(chain)-1]
+			if last.StatusCode != stdhttp.StatusMovedPermanently || last.NotFollowed != test.notFollowed {
+				t.Errorf("last hop = %+v, want not followed for %s", last, test.notFollowed)
+			}
+			if last.CrossHost != (test.notFollowed == "cross-host") || last.CrossScheme != (test.notFollowed == "cross-scheme") {
+				t.Errorf("last hop = %+v", last)
+			}
+			for _, hop := range chain {
+				if hop.IP != addr.String() || len(hop.Headers["Server"]) != 1 {
+					t.Errorf("hop = %+v, want ip %s and the Server header", hop, addr)
+				}
+			}
+			if results.Response.StatusCode != stdhttp.StatusMovedPermanently {
+				t.Errorf("final response status %d", results.Response.StatusCode)
+			}
+		})
+	}
+}
@@ -31,6 +31,7 @@ import (
 
 	"github.com/zmap/zgrab2"
 	"github.com/zmap/zgrab2/lib/http"
+	"github.com/zmap/zgrab2/lib/http/httptrace"
 	"github.com/zmap/zgrab2/lib/http2"
 )
 
@@ -63,6 +64,34 @@ type Flags struct {
 	// RedirectsSucceed causes the ErrTooManRedirects error to be suppressed
 	RedirectsSucceed bool `long:"redirects-succeed" description:"Redirects are always a success, even if max-redirects is exceeded"`
 
+	// MaxCrossHostRedirects and NoCrossSchemeRedirects restrict which redirects are followed. A redirect that is
+	// not followed is recorded as the final response.
+	MaxCrossHostRedirects  int    `long:"max-cross-host-redirects" default:"-1" description:"Max number of redirects to a different host to follow, or -1 for no limit other than max-redirects"`
+	NoCrossSchemeRedirects bool   `long:"no-cross-scheme-redirects" description:"Do not follow redirects that change the scheme, such as from http:// to https://"`
+	RedirectChainHeaders   string `long:"redirect-chain-headers" default:"Server,Set-Cookie,Strict-Transport-Security" description:"CSV of response headers to record for each hop of redirect_chain"`
+
+	// Decompress requests and decodes the br and zstd content encodings besides gzip.
+	Decompress bool `long:"decompress" description:"Advertise gzip, br and zstd in Accept-Encoding and decode response bodies in those encodings, recording the encoding and the compressed and decompressed sizes in each response's compression. Without it, only gzip is requested and it is decoded silently"`
+
+	// WebSocketPath enables the WebSocket upgrade probe.
+	WebSocketPath       string `long:"websocket-path" description:"Attempt a WebSocket upgrade on this path of the target after the initial request succeeds, and record the handshake in websocket"`
+	WebSocketProtocols  string `long:"websocket-protocols" description:"CSV of subprotocols to offer in Sec-WebSocket-Protocol with --websocket-path"`
+	WebSocketExtensions string `long:"websocket-extensions" default:"permessage-deflate; client_max_window_bits" description:"Extensions to offer in Sec-WebSocket-Extensions with --websocket-path"`
+
+	// NTLM decodes the NTLM challenge of servers that require NTLM or Negotiate authentication.
+	NTLM bool `long:"ntlm" description:"If the final response is a 401 offering NTLM or Negotiate authentication, send an NTLM negotiate message and record the server's challenge (NetBIOS and DNS names, OS version) in ntlm"`
+
+	// Methods enumerates the methods the server supports, for surveys of misconfigurations.
+	Methods    bool `long:"methods" description:"Send OPTIONS, TRACE and TRACK to the URL of the final response and record the advertised Allow header and how the server actually answers each method in methods"`
+	MethodsPUT bool `long:"methods-put" description:"With --methods, also send an empty PUT to a random path next to the final URL to detect whether uploads are accepted; this may create a file on the server"`
+
+	// VHosts and VHostsFile list hostnames to scan each target IP with, besides the target itself.
+	VHosts     string `long:"vhosts" description:"CSV of hostnames to also scan each target IP with, sending each as the Host header and TLS SNI, with one result per hostname in vhosts"`
+	VHostsFile string `long:"vhosts-file" description:"File with hostnames to also scan each target IP with, one per line; combined with --vhosts"`
+
+	// CookieJar replays cookies across the redirect chain and the further requests of the scan.
+	CookieJar bool `long:"cookie-jar" description:"Keep the cookies set by responses in an in-memory jar and send them with the following requests, including redirects, and record every cookie set in cookies"`
+
 	// Set arbitrary HTTP headers
 	CustomHeadersNames     string `long:"custom-headers-names" description:"CSV of custom HTTP headers to send to server"`
 	CustomHeadersValues    string `long:"custom-headers-values" description:"CSV of custom HTTP header values to send to server. Should match order of custom-headers-names."`
@@ -79,11 +108,38 @@ type Flags struct {
 	// WithBodyLength enables adding the body_size field to the Response
 	WithBodyLength bool `long:"with-body-size" description:"inserts the body_size field into the http result, listing how many bytes were read of the body"`
 
+	// HashFullBody and SimHash read bodies past MaxSize, without storing them, to digest them whole.
+	HashFullBody bool `long:"hash-full-body" description:"Read response bodies up to --max-hash-size while streaming and record their length and SHA-256 in full_body; only the first --max-size kilobytes are stored in body"`
+	SimHash      bool `long:"simhash" description:"Record a 64-bit simhash of the words of response bodies in full_body for clustering similar pages; implies --hash-full-body"`
+	MaxHashSize  int  `long:"max-hash-size" default:"10240" description:"Max kilobytes of a response body to read for --hash-full-body and --simhash"`
+
 	// Extract the raw header as it is on the wire
 	RawHeaders bool `long:"raw-headers" description:"Extract raw response up through headers"`
 
 	NoHTTP11 bool `long:"no-http1.1" description:"Use HTTP/2 with the initial request. If this connection is over TLS, we'll advertise HTTP/2 in ALPN. If not over TLS, we'll send an HTTP/2 over clear-text (h2c) request, sometimes known as http2 prior knowledge. Setting TLS.NextProtos will take precedence over this flag. Mutually exclusive with --no-http2"`
 	NoHTTP2  bool `long:"no-http2" description:"Use HTTP/1.1 with the initial request. If this connection is over TLS, we'll advertise HTTP/1.1 in ALPN. If not over TLS, we'll use a plain-text HTTP/1.1 request. Setting TLS.NextProtos will take precedence over this flag. Mutually exclusive with --no-http1.1"`
+
+	// UseHTTP2 negotiates HTTP/2 and records its framing details in the response's http2 field.
+	UseHTTP2 bool `long:"use-http2" description:"Negotiate HTTP/2 and record the server's SETTINGS frames, server push promises and response pseudo-headers. Over TLS, h2 is advertised via ALPN; over plain-text, an h2c upgrade is requested (or prior knowledge is used with --no-http1.1). Mutually exclusive with --no-http2"`
+
+	// Favicon fetches and hashes the site's icons after the initial request succeeds.
+	Favicon bool `long:"favicon" description:"Fetch /favicon.ico and up to 4 icons linked from the HTML response on the same host, and record their Shodan-compatible mmh3 hashes in favicons"`
+
+	// ExtractHTML records the title, generator and canonical link of an HTML response, which together with
+	// OmitBody avoids recording whole bodies for content classification.
+	ExtractHTML bool `long:"extract-html" description:"Extract the title, meta generator and canonical link of an HTML response into html"`
+	OmitBody    bool `long:"omit-body" description:"Do not record response bodies; their hashes, lengths and the fields extracted with --extract-html are kept"`
+
+	// Technologies matches the final response against a Wappalyzer-style ruleset.
+	Technologies     bool   `long:"technologies" description:"Detect the technologies of the final response (servers, frameworks, CMSs, JavaScript libraries) from its headers, cookies, HTML and script sources, and record them with their versions in technologies"`
+	TechnologiesFile string `long:"technologies-file" description:"JSON ruleset in the Wappalyzer format to use instead of the built-in one; implies --technologies"`
+
+	// SecurityTXT and RobotsTXT fetch well-known metadata files after the initial request succeeds.
+	SecurityTXT bool `long:"security-txt" description:"Fetch /.well-known/security.txt on the same connection and record its RFC 9116 fields in security_txt"`
+	RobotsTXT   bool `long:"robots-txt" description:"Fetch /robots.txt on the same connection and record it and its sitemaps in robots_txt"`
+
+	// RequestSequenceFile lists further requests to send to the target after the initial one.
+	RequestSequenceFile string `long:"request-sequence-file" description:"JSON file with a list of requests ({\"method\", \"endpoint\", \"headers\", \"body\"}) to send in order after the initial request succeeds, reusing its connection where possible. Their responses are recorded in sequence; they do not follow redirects"`
 }
 
 // A Results object is returned by the HTTP module's Scanner.Scan()
@@ -96,6 +152,42 @@ type Results struct {
 	// It contains all redirect response prior to the final response.
 	RedirectResponseChain []*http.Response `json:"redirect_response_chain,omitempty"`
 	NamesToIPs            []RedirectToIP   `json:"redirects_to_resolved_ips,omitempty"`
+
+	// Cookies are the cookies set by all responses when using --cookie-jar, in order.
+	Cookies []Cookie `json:"cookies,omitempty"`
+
+	// WebSocket is the outcome of the upgrade attempted with --websocket-path.
+	WebSocket *WebSocketResult `json:"websocket,omitempty"`
+
+	// NTLM is the NTLM challenge obtained with --ntlm.
+	NTLM *NTLMInfo `json:"ntlm,omitempty"`
+
+	// Methods is the method enumeration done with --methods.
+	Methods *MethodsResult `json:"methods,omitempty"`
+
+	// VHosts are the results of scanning the target IP with each of the --vhosts.
+	VHosts []VHostResult `json:"vhosts,omitempty"`
+
+	// RedirectChain summarizes every response when following redirects, ending with the final one.
+	RedirectChain []RedirectHop `json:"redirect_chain,omitempty"`
+
+	// Sequence holds the outcomes of the requests of the --request-sequence-file, in order.
+	Sequence []SequenceResult `json:"sequence,omitempty"`
+
+	// Favicons are the icons fetched with --favicon.
+	Favicons []Favicon `json:"favicons,omitempty"`
+
+	// HTML holds the fields extracted from the final response with --extract-html.
+	HTML *HTMLMetadata `json:"html,omitempty"`
+
+	// Technologies are the technologies detected in the final response with --technologies.
+	Technologies []Technology `json:"technologies,omitempty"`
+
+	// SecurityTXT is the security.txt file fetched with --security-txt.
+	SecurityTXT *SecurityTXT `json:"security_txt,omitempty"`
+
+	// RobotsTXT is the robots.txt file fetched with --robots-txt.
+	RobotsTXT *RobotsTXT `json:"robots_txt,omitempty"`
 }
 
 type RedirectToIP struct {
@@ -109,10 +201,15 @@ type Module struct {
 
 // Scanner is the implementation of the zgrab2.Scanner interface.
 type Scanner struct {
-	config            *Flags
-	customHeaders     map[string]string
-	decodedHashFn     func([]byte) string
-	dialerGroupConfig *zgrab2.DialerGroupConfig
+	config               *Flags
+	customHeaders        map[string]string
+	decodedHashFn        func([]byte) string
+	sequence             []SequenceRequest
+	redirectChainHeaders []string
+	vhosts               []string
+	webSocketTLSWrapper  func(context.Context, *zgrab2.ScanTarget, net.Conn) (*zgrab2.TLSConnection, error)
+	technologies         *technologyMatcher
+	dialerGroupConfig    *zgrab2.DialerGroupConfig
 }
 
 // scan holds the state for a single scan. This may entail multiple connections.
@@ -127,6 +224,12 @@ type scan struct {
 	url                    string
 	globalDeadline         time.Time
 	redirectsToResolvedIPs map[string]string // appended the result of DNS resolution for each
+	noRedirects            bool              // set while sending the requests of the sequence and the favicon requests
+	lastConnAddr           string            // remote address of the connection of the latest request of the redirect chain
+	crossHostRedirects     int
+	jar                    *recordingJar // the cookie jar with --cookie-jar
+	ctx                    context.Context
+	dialerGroup            *zgrab2.DialerGroup
 }
 
 // NewFlags returns an empty Flags object.
@@ -153,6 +256,9 @@ func (flags *Flags) Validate(_ []string) error {
 	if flags.NoHTTP2 && flags.NoHTTP11 {
 		return errors.New("cannot use both --no-http2 and --no-http1.1. Pick one or neither depending on which version you want to use")
 	}
+	if flags.UseHTTP2 && flags.NoHTTP2 {
+		return errors.New("cannot use both --use-http2 and --no-http2")
+	}
 	return nil
 }
 
@@ -270,6 +376,38 @@ func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
 		log.Panicf("Invalid ComputeDecodedBodyHashAlgorithm choice made it through zflags: %s", scanner.config.ComputeDecodedBodyHashAlgorithm)
 	}
 
+	scanner.redirectChainHeaders = parseRedirectChainHeaders(fl.RedirectChainHeaders)
+
+	if fl.WebSocketPath != "" {
+		// the upgrade is only possible over HTTP/1.1
+		tlsFlags := fl.TLSFlags
+		tlsFlags.Config = nil
+		tlsFlags.NextProtos = "http/1.1"
+		scanner.webSocketTLSWrapper = zgrab2.GetDefaultTLSWrapper(&tlsFlags)
+	}
+
+	vhosts, err := loadVHosts(fl.VHosts, fl.VHostsFile)
+	if err != nil {
+		return err
+	}
+	scanner.vhosts = vhosts
+
+	if fl.RequestSequenceFile != "" {
+		sequence, err := loadRequestSequence(fl.RequestSequenceFile)
+		if err != nil {
+			return err
+		}
+		scanner.sequence = sequence
+	}
+
+	if fl.Technologies || fl.TechnologiesFile != "" {
+		matcher, err := loadTechnologyMatcher(fl.TechnologiesFile)
+		if err != nil {
+			return err
+		}
+		scanner.technologies = matcher
+	}
+
 	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
 		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
 		NeedSeparateL4Dialer:            true,
@@ -339,41 +477,55 @@ func (scan *scan) withDeadlineContext(ctx context.Context) (context.Context, con
 // the redirectToLocalhost and MaxRedirects config
 func (scan *scan) getCheckRedirect() func(*http.Request, *http.Response, []*http.Request) error {
 	return func(req *http.Request, res *http.Response, via []*http.Request) error {
-		if scan.scanner.config.MaxRedirects == 0 {
+		scan.decodeBody(res)
+		if scan.scanner.config.MaxRedirects == 0 || scan.noRedirects {
 			return ErrDoNotRedirect
 		}
 		//len-1 because otherwise we'll return a failure on 1 redirect when we specify only 1 redirect. I.e. we are 0
 		if len(via)-1 > scan.scanner.config.MaxRedirects {
+			hop := scan.newRedirectHop(res)
+			hop.NotFollowed = "max-redirects"
+			scan.results.RedirectChain = append(scan.results.RedirectChain, hop)
 			return ErrTooManyRedirects
 		}
+		if err := scan.checkRedirectPolicy(res); err != nil {
+			return err
+		}
 		// We're following a re-direct. The IP that the framework resolved initially is no longer valid. Clearing
 		scan.target.IP = nil
 		scan.results.RedirectResponseChain = append(scan.results.RedirectResponseChain, res)
-		b := new(bytes.Buffer)
-		maxReadLen := int64(scan.scanner.config.MaxSize) * 1024
-		readLen := maxReadLen
-		if res.ContentLength >= 0 && res.ContentLength < maxReadLen {
-			readLen = res.ContentLength
-		}
-		bytesRead, _ := io.CopyN(b, res.Body, readLen)
-		if scan.scanner.config.WithBodyLength {
-			res.BodyTextLength = bytesRead
-		}
-		res.BodyText = b.String()
-		if len(res.BodyText) > 0 {
-			if scan.scanner.decodedHashFn != nil {
-				res.BodyHash = scan.scanner.decodedHashFn([]byte(res.BodyText))
-			} else {
-				m := sha256.New()
-				m.Write(b.Bytes())
-				res.BodySHA256 = m.Sum(nil)
-			}
-		}
-
+		scan.readBody(res)
 		return nil
 	}
 }
 
+// readBody reads up to MaxSize kilobytes of the body of a response other than the final one into its BodyText, and
+// hashes it.
+func (scan *scan) readBody(res *http.Response) {
+	b := new(bytes.Buffer)
+	maxReadLen := int64(scan.scanner.config.MaxSize) * 1024
+	readLen := maxReadLen
+	// the Content-Length of a decoded body is that of its encoding
+	if res.ContentLength >= 0 && res.ContentLength < maxReadLen && res.Compression == nil {
+		readLen = res.ContentLength
+	}
+	bytesRead, _ := io.CopyN(b, res.Body, readLen)
+	scan.digestBody(res, b.Bytes())
+	if scan.scanner.config.WithBodyLength {
+		res.BodyTextLength = bytesRead
+	}
+	res.BodyText = b.String()
+	if len(res.BodyText) > 0 {
+		if scan.scanner.decodedHashFn != nil {
+			res.BodyHash = scan.scanner.decodedHashFn([]byte(res.BodyText))
+		} else {
+			m := sha256.New()
+			m.Write(b.Bytes())
+			res.BodySHA256 = m.Sum(nil)
+		}
+	}
+}
+
 // Maps URL protocol to the default port for that protocol
 var protoToPort = map[string]uint16{
 	"http":  80,
@@ -407,6 +559,8 @@ func (scanner *Scanner) newHTTPScan(ctx context.Context, target *zgrab2.ScanTarg
 		target:                 target,
 		client:                 http.MakeNewClient(),
 		redirectsToResolvedIPs: make(map[string]string),
+		ctx:                    ctx,
+		dialerGroup:            dialerGroup,
 	}
 	ret.client.UserAgent = scanner.config.UserAgent
 	if scanner.config.TargetTimeout != 0 {
@@ -438,6 +592,8 @@ func (scanner *Scanner) newHTTPScan(ctx context.Context, target *zgrab2.ScanTarg
 				// Instead of TLS, just do raw TCP
 				return dialerGroup.L4Dialer(target)(ctx, network, addr)
 			},
+			EnablePush:      scanner.config.UseHTTP2,
+			RecordHTTP2Info: scanner.config.UseHTTP2,
 		}
 		ret.client.Transport = transport
 	} else {
@@ -479,15 +635,23 @@ func (scanner *Scanner) newHTTPScan(ctx context.Context, target *zgrab2.ScanTarg
 			ret.cancelFuncs = append(ret.cancelFuncs, cancelFunc)
 			return conn, nil
 		}
-		_, err := http2.ConfigureTransports(transport)
+		t2, err := http2.ConfigureTransports(transport)
 		if err != nil {
 			log.Errorf("unable to configure http2 transport: %v", err)
+		} else if scanner.config.UseHTTP2 {
+			t2.EnablePush = true
+			t2.RecordHTTP2Info = true
 		}
 		ret.client.Transport = transport
 	}
 
 	ret.client.CheckRedirect = ret.getCheckRedirect()
-	ret.client.Jar = nil // Don't send or receive cookies (otherwise use CookieJar)
+	if scanner.config.CookieJar {
+		ret.jar = newRecordingJar()
+		ret.client.Jar = ret.jar
+	} else {
+		ret.client.Jar = nil // Don't send or receive cookies
+	}
 	if deadline, ok := ctx.Deadline(); ok {
 		ret.client.Timeout = min(ret.client.Timeout, time.Until(deadline))
 	}
@@ -501,29 +665,16 @@ func (scanner *Scanner) newHTTPScan(ctx context.Context, target *zgrab2.ScanTarg
 	return &ret
 }
 
-// Grab performs the HTTP scan -- implementation taken from zgrab/zlib/grabber.go
-func (scan *scan) Grab() *zgrab2.ScanError {
-	// TODO: Allow body?
-	var (
-		request *http.Request
-		err     error
-	)
-	if len(scan.scanner.config.RequestBody) > 0 {
-		request, err = http.NewRequest(scan.scanner.config.Method, scan.url, strings.NewReader(scan.scanner.config.RequestBody))
-	} else if len(scan.scanner.config.RequestBodyHex) > 0 {
-		reqbody, _ := hex.DecodeString(scan.scanner.config.RequestBodyHex)
-		request, err = http.NewRequest(scan.scanner.config.Method, scan.url, bytes.NewReader(reqbody))
-	} else {
-		request, err = http.NewRequest(scan.scanner.config.Method, scan.url, nil)
-	}
-	if err != nil {
-		return zgrab2.NewScanError(zgrab2.SCAN_UNKNOWN_ERROR, err)
-	}
-
+// prepareRequest sets the headers and protocol version shared by the initial request and those of the sequence.
+func (scan *scan) prepareRequest(request *http.Request) {
 	request.SkipHost = scan.scanner.config.SkipHost
 
 	// By default, the following headers are *always* set:
 	// Host, User-Agent, Accept, Accept-Encoding
+	if scan.scanner.config.Decompress {
+		// the transport then leaves the decoding, including of gzip, to decodeBody
+		request.Header.Set("Accept-Encoding", acceptEncoding)
+	}
 	if scan.scanner.customHeaders != nil {
 		request.Header.Set("Accept", "*/*")
 		for k, v := range scan.scanner.customHeaders {
@@ -542,12 +693,62 @@ func (scan *scan) Grab() *zgrab2.ScanError {
 		request.ProtoMajor = 2
 		request.ProtoMinor = 0
 	}
+}
+
+// Grab performs the HTTP scan -- implementation taken from zgrab/zlib/grabber.go
+func (scan *scan) Grab() *zgrab2.ScanError {
+	// TODO: Allow body?
+	var (
+		request *http.Request
+		err     error
+	)
+	if len(scan.scanner.config.RequestBody) > 0 {
+		request, err = http.NewRequest(scan.scanner.config.Method, scan.url, strings.NewReader(scan.scanner.config.RequestBody))
+	} else if len(scan.scanner.config.RequestBodyHex) > 0 {
+		reqbody, _ := hex.DecodeString(scan.scanner.config.RequestBodyHex)
+		request, err = http.NewRequest(scan.scanner.config.Method, scan.url, bytes.NewReader(reqbody))
+	} else {
+		request, err = http.NewRequest(scan.scanner.config.Method, scan.url, nil)
+	}
+	if err != nil {
+		return zgrab2.NewScanError(zgrab2.SCAN_UNKNOWN_ERROR, err)
+	}
+	scan.prepareRequest(request)
+
+	// Over plain-text HTTP/1.1, --use-http2 asks the server to upgrade to h2c
+	upgradeH2C := scan.scanner.config.UseHTTP2 && !scan.scanner.config.NoHTTP11 && request.URL.Scheme == "http"
+	if upgradeH2C {
+		setH2CUpgradeHeaders(request)
+	}
+
+	if scan.scanner.config.MaxRedirects > 0 {
+		request = request.WithContext(httptrace.WithClientTrace(request.Context(), &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				if info.Conn != nil && info.Conn.RemoteAddr() != nil {
+					scan.lastConnAddr = info.Conn.RemoteAddr().String()
+				}
+			},
+		}))
+	}
 
 	resp, err := scan.client.Do(request)
 	if resp != nil && resp.Body != nil {
 		defer resp.Body.Close()
 	}
+	if err == nil && upgradeH2C && resp.StatusCode == http.StatusSwitchingProtocols {
+		maxBody := int64(scan.scanner.config.MaxSize) * 1024
+		h2Resp, h2Err := readH2CResponse(resp, maxBody)
+		if h2Err != nil {
+			scan.results.Response = resp
+			return zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, fmt.Errorf("error reading h2c response from %s: %w", scan.url, h2Err))
+		}
+		resp = h2Resp
+	}
 	scan.results.Response = resp
+	if resp != nil {
+		scan.decodeBody(resp)
+		scan.finishRedirectChain(resp)
+	}
 	if err != nil {
 		var urlError *url.Error
 		if errors.As(err, &urlError) {
@@ -556,7 +757,7 @@ func (scan *scan) Grab() *zgrab2.ScanError {
 	}
 	if err != nil {
 		switch err {
-		case ErrDoNotRedirect:
+		case ErrDoNotRedirect, ErrCrossHostRedirect, ErrCrossSchemeRedirect:
 			break
 		case ErrTooManyRedirects:
 			if scan.scanner.config.RedirectsSucceed {
@@ -571,12 +772,13 @@ func (scan *scan) Grab() *zgrab2.ScanError {
 	buf := new(bytes.Buffer)
 	maxReadLen := int64(scan.scanner.config.MaxSize) * 1024
 	readLen := maxReadLen
-	if resp.ContentLength >= 0 && resp.ContentLength < maxReadLen {
+	if resp.ContentLength >= 0 && resp.ContentLength < maxReadLen && resp.Compression == nil {
 		readLen = resp.ContentLength
 	}
 	if n, err := io.CopyN(buf, resp.Body, readLen); err != nil && !strings.Contains(err.Error(), "EOF") {
 		return zgrab2.NewScanError(zgrab2.SCAN_UNKNOWN_ERROR, fmt.Errorf("error populating response body after %d bytes: %w", n, err))
 	}
+	scan.digestBody(resp, buf.Bytes())
 
 	encoder, encoding, certain := charset.DetermineEncoding(buf.Bytes(), resp.Header.Get("content-type"))
 
@@ -652,6 +854,52 @@ func (scan *scan) Grab() *zgrab2.ScanError {
 	return nil
 }
 
+// finish performs the optional steps that follow a successful initial request.
+func (scan *scan) finish() {
+	scan.runSequence()
+	if scan.scanner.config.Favicon {
+		scan.fetchFavicons()
+	}
+	if scan.scanner.config.SecurityTXT || scan.scanner.config.RobotsTXT {
+		scan.fetchMetadataFiles()
+	}
+	if scan.scanner.config.WebSocketPath != "" {
+		scan.probeWebSocket()
+	}
+	if scan.scanner.config.NTLM {
+		scan.fetchNTLMInfo()
+	}
+	if scan.scanner.config.Methods {
+		scan.enumerateMethods()
+	}
+	if res := scan.results.Response; scan.scanner.config.ExtractHTML && res != nil && isHTML(res) {
+		scan.results.HTML = extractHTMLMetadata(res.BodyText)
+	}
+	if res := scan.results.Response; scan.scanner.technologies != nil && res != nil {
+		scan.results.Technologies = scan.scanner.technologies.match(res)
+	}
+	if scan.scanner.config.OmitBody {
+		responses := append([]*http.Response{scan.results.Response}, scan.results.RedirectResponseChain...)
+		for _, result := range scan.results.Sequence {
+			responses = append(responses, result.Response)
+		}
+		for _, res := range responses {
+			if res != nil {
+				res.BodyText = ""
+			}
+		}
+		if scan.results.SecurityTXT != nil {
+			scan.results.SecurityTXT.Body = ""
+		}
+		if scan.results.RobotsTXT != nil {
+			scan.results.RobotsTXT.Body = ""
+		}
+	}
+	if scan.jar != nil {
+		scan.results.Cookies = scan.jar.cookies
+	}
+}
+
 // Scan implements the zgrab2.Scanner interface and performs the full scan of
 // the target. If the scanner is configured to follow redirects, this may entail
 // multiple TCP connections to hosts other than target.
@@ -659,6 +907,17 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 	if dialGroup == nil || dialGroup.L4Dialer == nil || dialGroup.TLSWrapper == nil {
 		return zgrab2.SCAN_INVALID_INPUTS, nil, errors.New("must specify a dialer group with L4 dialer and TLS wrapper")
 	}
+	// following a redirect clears the IP of the target, which the virtual hosts are scanned on
+	vhostTarget := *target
+	status, results, err := scanner.scanTarget(ctx, dialGroup, target)
+	if res, ok := results.(*Results); ok && len(scanner.vhosts) > 0 && vhostTarget.IP != nil {
+		res.VHosts = scanner.scanVHosts(ctx, dialGroup, vhostTarget)
+	}
+	return status, results, err
+}
+
+// scanTarget scans a single target, retrying over HTTPS if configured.
+func (scanner *Scanner) scanTarget(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
 	scan := scanner.newHTTPScan(ctx, target, scanner.config.UseHTTPS, dialGroup)
 	defer scan.Cleanup()
 	err := scan.Grab()
@@ -671,10 +930,12 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 			if retryError != nil {
 				return err.Unpack(&scan.results)
 			}
+			retry.finish()
 			return zgrab2.SCAN_SUCCESS, &retry.results, nil
 		}
 		return err.Unpack(&scan.results)
 	}
+	scan.finish()
 	// Copy over the resolved names to IPs
 	if len(scan.redirectsToResolvedIPs) > 0 {
 		scan.results.NamesToIPs = make([]RedirectToIP, 0, len(scan.redirectsToResolvedIPs))
@@ -0,0 +1,129 @@
+package http
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zmap/zgrab2/lib/http"
+)
+
+// SequenceRequest is an entry of the JSON list in the --request-sequence-file.
+type SequenceRequest struct {
+	// Method defaults to GET.
+	Method string `json:"method"`
+
+	// Endpoint is the path and query of the request, relative to the target.
+	Endpoint string `json:"endpoint"`
+
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+}
+
+// SequenceResult is the outcome of one request of the sequence.
+type SequenceResult struct {
+	Response *http.Response `json:"response,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// loadRequestSequence reads and checks a --request-sequence-file.
+func loadRequestSequence(file string) ([]SequenceRequest, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var sequence []SequenceRequest
+	if err := json.Unmarshal(content, &sequence); err != nil {
+		return nil, fmt.Errorf("invalid request sequence in %s: %w", file, err)
+	}
+	for i := range sequence {
+		entry := &sequence[i]
+		if entry.Method == "" {
+			entry.Method = "GET"
+		}
+		if !strings.HasPrefix(entry.Endpoint, "/") {
+			return nil, fmt.Errorf("request %d of the sequence in %s: endpoint %q must start with /", i, file, entry.Endpoint)
+		}
+		for name := range entry.Headers {
+			switch strings.ToLower(name) {
+			case "host", "content-length":
+				return nil, fmt.Errorf("request %d of the sequence in %s: header %s cannot be set", i, file, name)
+			}
+		}
+	}
+	return sequence, nil
+}
+
+// runSequence sends the requests of the --request-sequence-file in order, with the client of the initial request so
+// that open connections are reused. Requests of the sequence do not follow redirects, and a failed request does not
+// stop the sequence.
+func (scan *scan) runSequence() {
+	base, err := url.Parse(scan.url)
+	if err != nil {
+		return
+	}
+	scan.noRedirects = true
+	defer func() { scan.noRedirects = false }()
+	for _, entry := range scan.scanner.sequence {
+		res, err := scan.sendSequenceRequest(base, entry)
+		result := SequenceResult{Response: res}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		scan.results.Sequence = append(scan.results.Sequence, result)
+	}
+}
+
+// sendSequenceRequest sends one request of the sequence and reads its response.
+func (scan *scan) sendSequenceRequest(base *url.URL, entry SequenceRequest) (*http.Response, error) {
+	ref, err := url.Parse(entry.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	var body io.Reader
+	if entry.Body != "" {
+		body = strings.NewReader(entry.Body)
+	}
+	request, err := http.NewRequest(entry.Method, base.ResolveReference(ref).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	scan.prepareRequest(request)
+	for name, value := range entry.Headers {
+		request.Header.Set(name, value)
+	}
+
+	res, err := scan.do(request)
+	if err != nil {
+		return res, err
+	}
+	defer res.Body.Close()
+	scan.readBody(res)
+	if !utf8.ValidString(res.BodyText) {
+		res.BodyText = base64.StdEncoding.EncodeToString([]byte(res.BodyText))
+	}
+	return res, nil
+}
+
+// do sends a request that does not follow redirects, unwraps the client's errors and decodes the body with
+// --decompress.
+func (scan *scan) do(request *http.Request) (*http.Response, error) {
+	res, err := scan.client.Do(request)
+	var urlError *url.Error
+	if errors.As(err, &urlError) {
+		err = urlError.Err
+	}
+	if err != nil && !errors.Is(err, ErrDoNotRedirect) {
+		return res, err
+	}
+	if res != nil {
+		scan.decodeBody(res)
+	}
+	return res, nil
+}
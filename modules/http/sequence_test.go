@@ -0,0 +1,120 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zmap/zgrab2"
+)
+
+func TestLoadRequestSequence(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		file := filepath.Join(dir, strconv.Itoa(len(content))+".json")
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+
+	sequence, err := loadRequestSequence(write(`[{"endpoint": "/"}, {"method": "POST", "endpoint": "/login", "headers": {"X-Test": "1"}, "body": "a=b"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sequence) != 2 || sequence[0].Method != "GET" || sequence[1].Headers["X-Test"] != "1" {
+		t.Errorf("unexpected sequence %+v", sequence)
+	}
+	for _, content := range []string{
+		`{"endpoint": "/"}`,
+		`[{"endpoint": "server-status"}]`,
+		`[{"endpoint": "/", "headers": {"Host": "example.com"}}]`,
+	} {
+		if _, err := loadRequestSequence(write(content)); err == nil {
+			t.Errorf("sequence %s accepted", content)
+		}
+	}
+}
+
+func TestRequestSequence(t *testing.T) {
+	var connections atomic.Int32
+	server := httptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte("index"))
+		case "/server-status":
+			w.Write([]byte(r.Method + " " + r.Header.Get("X-Test") + " " + string(body)))
+		case "/moved":
+			stdhttp.Redirect(w, r, "/", stdhttp.StatusFound)
+		default:
+			stdhttp.NotFound(w, r)
+		}
+	}))
+	server.Config.ConnState = func(_ net.Conn, state stdhttp.ConnState) {
+		if state == stdhttp.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+	addr := server.Listener.Addr().(*net.TCPAddr)
+
+	file := filepath.Join(t.TempDir(), "sequence.json")
+	content := `[{"method": "POST", "endpoint": "/server-status", "headers": {"X-Test": "yes"}, "body": "data"}, {"endpoint": "/moved"}]`
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var module Module
+	flags := module.NewFlags().(*Flags)
+	flags.Endpoint = "/"
+	flags.Method = "GET"
+	flags.UserAgent = "Mozilla/5.0 zgrab/0.x"
+	flags.MaxSize = 256
+	flags.MaxRedirects = 1
+	flags.Port = uint(addr.Port)
+	flags.ConnectTimeout = time.Second
+	flags.RequestSequenceFile = file
+	scanner := module.NewScanner().(*Scanner)
+	if err := scanner.Init(flags); err != nil {
+		t.Fatal(err)
+	}
+	dialerGroup, err := scanner.GetDialerGroupConfig().GetDefaultDialerGroupFromConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, ret, err := scanner.Scan(context.Background(), dialerGroup, &zgrab2.ScanTarget{IP: addr.IP, Port: uint(addr.Port)})
+	if status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status %s: %v", status, err)
+	}
+	results := ret.(*Results)
+	if results.Response.BodyText != "index" {
+		t.Errorf("initial body = %q", results.Response.BodyText)
+	}
+	if len(results.Sequence) != 2 {
+		t.Fatalf("got %d sequence results, want 2", len(results.Sequence))
+	}
+	if res := results.Sequence[0].Response; res == nil || res.BodyText != "POST yes data" {
+		t.Errorf("first sequence result = %+v", results.Sequence[0])
+	}
+	// requests of the sequence do not follow redirects
+	if res := results.Sequence[1].Response; res == nil || res.StatusCode != stdhttp.StatusFound {
+		t.Errorf("second sequence result = %+v", results.Sequence[1])
+	}
+	if len(results.RedirectResponseChain) != 0 {
+		t.Errorf("sequence added %d responses to the redirect chain", len(results.RedirectResponseChain))
+	}
+	if n := connections.Load(); n != 1 {
+		t.Errorf("scan used %d connections, want 1", n)
+	}
+}
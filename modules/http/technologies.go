@@ -0,0 +1,326 @@
+package http
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/zmap/zgrab2/lib/http"
+)
+
+// defaultTechnologies is the ruleset used by --technologies unless --technologies-file replaces it.
+//
+//go:embed technologies.json
+var defaultTechnologies []byte
+
+// Technology is a technology detected with --technologies.
+type Technology struct {
+	Name       string   `json:"name"`
+	Version    string   `json:"version,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+}
+
+// patternList is a list of patterns in a ruleset, which may also be given as a single string.
+type patternList []string
+
+func (p *patternList) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*p = patternList{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	*p = list
+	return nil
+}
+
+// technologyRule is an entry of a ruleset in the Wappalyzer format, keyed by the technology name. Only the
+// categories, headers, cookies, meta, html, scriptSrc and implies fields are used.
+type technologyRule struct {
+	Categories []string               `json:"categories"`
+	Headers    map[string]patternList `json:"headers"`
+	Cookies    map[string]patternList `json:"cookies"`
+	Meta       map[string]patternList `json:"meta"`
+	HTML       patternList            `json:"html"`
+	ScriptSrc  patternList            `json:"scriptSrc"`
+	Implies    patternList            `json:"implies"`
+}
+
+// pattern is a compiled pattern: a case-insensitive regular expression and the template of the version, in which
+// \1 to \9 refer to the groups of the expression.
+type pattern struct {
+	re      *regexp.Regexp
+	version string
+}
+
+// technology is a compiled technologyRule.
+type technology struct {
+	name       string
+	categories []string
+	headers    map[string][]pattern
+	cookies    map[string][]pattern
+	meta       map[string][]pattern
+	html       []pattern
+	scriptSrc  []pattern
+	implies    []string
+}
+
+// technologyMatcher detects the technologies of a ruleset in responses.
+type technologyMatcher struct {
+	technologies []*technology
+	byName       map[string]*technology
+}
+
+// parsePattern compiles a pattern with optional fields such as "\;version:\1" and "\;confidence:50". Only the
+// version field is used.
+func parsePattern(s string) (pattern, error) {
+	parts := strings.Split(s, `\;`)
+	re, err := regexp.Compile("(?i)" + parts[0])
+	if err != nil {
+		return pattern{}, err
+	}
+	p := pattern{re: re}
+	for _, field := range parts[1:] {
+		if version, ok := strings.CutPrefix(field, "version:"); ok {
+			p.version = version
+		}
+	}
+	return p, nil
+}
+
+// compilePatterns compiles a list of patterns, skipping those that Go's regular expressions do not support, such
+// as lookaheads.
+func compilePatterns(name string, list patternList) []pattern {
+	var patterns []pattern
+	for _, s := range list {
+		p, err := parsePattern(s)
+		if err != nil {
+			log.Debugf("skipping pattern %q of technology %s: %v", s, name, err)
+			continue
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns
+}
+
+// compilePatternMap compiles patterns keyed by a header, cookie or meta name, with lower-case keys where the names
+// are case-insensitive.
+func compilePatternMap(name string, m map[string]patternList, lower bool) map[string][]pattern {
+	if len(m) == 0 {
+		return nil
+	}
+	compiled := make(map[string][]pattern, len(m))
+	for key, list := range m {
+		if lower {
+			key = strings.ToLower(key)
+		}
+		compiled[key] = compilePatterns(name, list)
+	}
+	return compiled
+}
+
+// newTechnologyMatcher compiles a ruleset: a JSON object mapping technology names to rules, either at the top level
+// or under a "technologies" key.
+func newTechnologyMatcher(ruleset []byte) (*technologyMatcher, error) {
+	var rules map[string]technologyRule
+	var wrapped struct {
+		Technologies map[string]technologyRule `json:"technologies"`
+	}
+	if err := json.Unmarshal(ruleset, &wrapped); err == nil && len(wrapped.Technologies) > 0 {
+		rules = wrapped.Technologies
+	} else if err := json.Unmarshal(ruleset, &rules); err != nil {
+		return nil, fmt.Errorf("invalid technology ruleset: %w", err)
+	}
+
+	m := &technologyMatcher{byName: make(map[string]*technology, len(rules))}
+	for name, rule := range rules {
+		t := &technology{
+			name:       name,
+			categories: rule.Categories,
+			headers:    compilePatternMap(name, rule.Headers, true),
+			cookies:    compilePatternMap(name, rule.Cookies, false),
+			meta:       compilePatternMap(name, rule.Meta, true),
+			html:       compilePatterns(name, rule.HTML),
+			scriptSrc:  compilePatterns(name, rule.ScriptSrc),
+		}
+		for _, implied := range rule.Implies {
+			implied, _, _ = strings.Cut(implied, `\;`)
+			t.implies = append(t.implies, implied)
+		}
+		m.technologies = append(m.technologies, t)
+		m.byName[name] = t
+	}
+	slices.SortFunc(m.technologies, func(a, b *technology) int { return strings.Compare(a.name, b.name) })
+	return m, nil
+}
+
+// loadTechnologyMatcher compiles the ruleset of a file, or the embedded one if file is empty.
+func loadTechnologyMatcher(file string) (*technologyMatcher, error) {
+	if file == "" {
+		return newTechnologyMatcher(defaultTechnologies)
+	}
+	ruleset, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return newTechnologyMatcher(ruleset)
+}
+
+// versionGroup matches the references to the groups of a pattern in its version template.
+var versionGroup = regexp.MustCompile(`\\(\d)`)
+
+// match returns whether a pattern matches s, and the version it extracts.
+func (p *pattern) match(s string) (bool, string) {
+	groups := p.re.FindStringSubmatch(s)
+	if groups == nil {
+		return false, ""
+	}
+	version := versionGroup.ReplaceAllStringFunc(p.version, func(ref string) string {
+		i, _ := strconv.Atoi(ref[1:])
+		if i < len(groups) {
+			return groups[i]
+		}
+		return ""
+	})
+	return true, strings.TrimSpace(version)
+}
+
+// document holds the parts of an HTML body that the patterns refer to.
+type document struct {
+	scriptSrcs []string
+	meta       map[string][]string
+}
+
+// parseDocument collects the script sources and meta fields of an HTML body.
+func parseDocument(body string) *document {
+	doc := &document{meta: make(map[string][]string)}
+	tokenizer := html.NewTokenizer(strings.NewReader(body))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return doc
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			tag := atom.Lookup(name)
+			if !hasAttr || (tag != atom.Script && tag != atom.Meta) {
+				continue
+			}
+			attrs := make(map[string]string)
+			for {
+				key, value, more := tokenizer.TagAttr()
+				attrs[string(key)] = string(value)
+				if !more {
+					break
+				}
+			}
+			if tag == atom.Script && attrs["src"] != "" {
+				doc.scriptSrcs = append(doc.scriptSrcs, attrs["src"])
+			}
+			if tag == atom.Meta {
+				key := attrs["name"]
+				if key == "" {
+					key = attrs["property"]
+				}
+				if key != "" {
+					doc.meta[strings.ToLower(key)] = append(doc.meta[strings.ToLower(key)], attrs["content"])
+				}
+			}
+		}
+	}
+}
+
+// detection accumulates whether a technology matched and the first version a pattern extracted for it.
+type detection struct {
+	matched bool
+	version string
+}
+
+func (d *detection) check(patterns []pattern, values ...string) {
+	for i := range patterns {
+		for _, value := range values {
+			if ok, version := patterns[i].match(value); ok {
+				d.matched = true
+				if d.version == "" {
+					d.version = version
+				}
+			}
+		}
+	}
+}
+
+// match returns the technologies detected in a response, with those they imply, sorted by name.
+func (m *technologyMatcher) match(res *http.Response) []Technology {
+	cookies := make(map[string][]string)
+	for _, cookie := range res.Cookies() {
+		cookies[cookie.Name] = append(cookies[cookie.Name], cookie.Value)
+	}
+	body := res.BodyText
+	doc := parseDocument(body)
+
+	detected := make(map[string]*detection)
+	for _, t := range m.technologies {
+		d := new(detection)
+		for name, patterns := range t.headers {
+			if values := res.Header.Values(name); len(values) > 0 {
+				d.check(patterns, values...)
+			}
+		}
+		for name, patterns := range t.cookies {
+			if values, ok := cookies[name]; ok {
+				d.check(patterns, values...)
+			}
+		}
+		for name, patterns := range t.meta {
+			if values, ok := doc.meta[name]; ok {
+				d.check(patterns, values...)
+			}
+		}
+		d.check(t.html, body)
+		d.check(t.scriptSrc, doc.scriptSrcs...)
+		if d.matched {
+			detected[t.name] = d
+		}
+	}
+
+	// add the implied technologies, which may imply further ones
+	queue := make([]string, 0, len(detected))
+	for name := range detected {
+		queue = append(queue, name)
+	}
+	for len(queue) > 0 {
+		t := m.byName[queue[0]]
+		queue = queue[1:]
+		if t == nil {
+			continue
+		}
+		for _, implied := range t.implies {
+			if _, ok := detected[implied]; !ok {
+				detected[implied] = &detection{matched: true}
+				queue = append(queue, implied)
+			}
+		}
+	}
+
+	technologies := make([]Technology, 0, len(detected))
+	for name, d := range detected {
+		technology := Technology{Name: name, Version: d.version}
+		if t := m.byName[name]; t != nil {
+			technology.Categories = t.categories
+		}
+		technologies = append(technologies, technology)
+	}
+	slices.SortFunc(technologies, func(a, b Technology) int { return strings.Compare(a.Name, b.Name) })
+	return technologies
+}
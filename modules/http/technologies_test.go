@@ -0,0 +1,96 @@
+package http
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/zmap/zgrab2/lib/http"
+)
+
+func TestDefaultTechnologies(t *testing.T) {
+	m, err := newTechnologyMatcher(defaultTechnologies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every pattern of the built-in ruleset must compile
+	var rules map[string]technologyRule
+	if err := json.Unmarshal(defaultTechnologies, &rules); err != nil {
+		t.Fatal(err)
+	}
+	for name, rule := range rules {
+		for _, list := range [][]string{rule.HTML, rule.ScriptSrc} {
+			for _, s := range list {
+				if _, err := parsePattern(s); err != nil {
+					t.Errorf("%s: %v", name, err)
+				}
+			}
+		}
+		for _, patterns := range []map[string]patternList{rule.Headers, rule.Cookies, rule.Meta} {
+			for _, list := range patterns {
+				for _, s := range list {
+					if _, err := parsePattern(s); err != nil {
+						t.Errorf("%s: %v", name, err)
+					}
+				}
+			}
+		}
+		for _, implied := range rule.Implies {
+			if m.byName[implied] == nil {
+				t.Errorf("%s implies unknown technology %s", name, implied)
+			}
+		}
+	}
+
+	res := &http.Response{Header: make(http.Header)}
+	res.Header.Set("Server", "nginx/1.25.3")
+	res.Header.Set("X-Powered-By", "PHP/8.2.1")
+	res.Header.Add("Set-Cookie", "PHPSESSID=abc; path=/")
+	res.BodyText = `<html><head><meta name="generator" content="WordPress 6.4.2">
+<script src="/wp-includes/js/jquery/jquery.min.js?ver=3.7.1"></script>
+<script src="https://code.jquery.com/jquery-3.7.1.min.js"></script></head></html>`
+	got := m.match(res)
+	want := []Technology{
+		{Name: "Nginx", Version: "1.25.3", Categories: []string{"Web servers", "Reverse proxies"}},
+		{Name: "PHP", Version: "8.2.1", Categories: []string{"Programming languages"}},
+		{Name: "WordPress", Version: "6.4.2", Categories: []string{"CMS", "Blogs"}},
+		{Name: "jQuery", Version: "3.7.1", Categories: []string{"JavaScript libraries"}},
+	}
+	if !slices.EqualFunc(got, want, func(a, b Technology) bool {
+		return a.Name == b.Name && a.Version == b.Version && slices.Equal(a.Categories, b.Categories)
+	}) {
+		t.Errorf("match = %+v, want %+v", got, want)
+	}
+}
+
+func TestTechnologyRuleset(t *testing.T) {
+	// a ruleset in the layout of Wappalyzer's files, with an unsupported lookahead that is skipped
+	ruleset := []byte(`{"technologies": {
+		"Foo": {"headers": {"x-foo": "^foo ([\\d.]+)\\;version:\\1\\;confidence:50"}, "implies": "Bar\\;confidence:50"},
+		"Bar": {"html": ["(?!x)bar", "<bar-app"]},
+		"Baz": {"cookies": {"baz_session": ""}}
+	}}`)
+	m, err := newTechnologyMatcher(ruleset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := &http.Response{Header: make(http.Header)}
+	res.Header.Set("X-Foo", "FOO 2.1")
+	got := m.match(res)
+	want := []Technology{{Name: "Bar"}, {Name: "Foo", Version: "2.1"}}
+	if !slices.EqualFunc(got, want, func(a, b Technology) bool { return a.Name == b.Name && a.Version == b.Version }) {
+		t.Errorf("match = %+v, want %+v", got, want)
+	}
+
+	res = &http.Response{Header: make(http.Header), BodyText: "<bar-app></bar-app>"}
+	res.Header.Add("Set-Cookie", "baz_session=1")
+	got = m.match(res)
+	want = []Technology{{Name: "Bar"}, {Name: "Baz"}}
+	if !slices.EqualFunc(got, want, func(a, b Technology) bool { return a.Name == b.Name && a.Version == b.Version }) {
+		t.Errorf("match = %+v, want %+v", got, want)
+	}
+
+	if _, err := newTechnologyMatcher([]byte(`[]`)); err == nil {
+		t.Error("invalid ruleset accepted")
+	}
+}
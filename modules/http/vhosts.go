@@ -0,0 +1,65 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/zmap/zgrab2"
+)
+
+// VHostResult is the result of scanning a target IP with one of the --vhosts as its domain.
+type VHostResult struct {
+	Host   string            `json:"host"`
+	Status zgrab2.ScanStatus `json:"status"`
+	Error  string            `json:"error,omitempty"`
+	Result *Results          `json:"result,omitempty"`
+}
+
+// loadVHosts returns the hostnames of the --vhosts list and file, lower-cased and without duplicates.
+func loadVHosts(list, file string) ([]string, error) {
+	names := strings.Split(list, ",")
+	if file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			if line = strings.TrimSpace(line); !strings.HasPrefix(line, "#") {
+				names = append(names, line)
+			}
+		}
+	}
+	var vhosts []string
+	for _, name := range names {
+		name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+		if name == "" || slices.Contains(vhosts, name) {
+			continue
+		}
+		if strings.ContainsAny(name, "/: \t") {
+			return nil, fmt.Errorf("invalid virtual host %q", name)
+		}
+		vhosts = append(vhosts, name)
+	}
+	return vhosts, nil
+}
+
+// scanVHosts scans the IP of a target with each of the --vhosts as its domain, which is sent as the Host header and
+// the TLS SNI.
+func (scanner *Scanner) scanVHosts(ctx context.Context, dialGroup *zgrab2.DialerGroup, target zgrab2.ScanTarget) []VHostResult {
+	results := make([]VHostResult, 0, len(scanner.vhosts))
+	for _, vhost := range scanner.vhosts {
+		vhostTarget := target
+		vhostTarget.Domain = vhost
+		status, ret, err := scanner.scanTarget(ctx, dialGroup, &vhostTarget)
+		result := VHostResult{Host: vhost, Status: status}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		result.Result, _ = ret.(*Results)
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"context"
+	"net"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/zmap/zgrab2"
+)
+
+func TestLoadVHosts(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "vhosts")
+	if err := os.WriteFile(file, []byte("# names from certificates\nWWW.example.com.\n\nmail.example.com\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	vhosts, err := loadVHosts("www.example.com, api.example.com", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"www.example.com", "api.example.com", "mail.example.com"}; !slices.Equal(vhosts, want) {
+		t.Errorf("vhosts = %q, want %q", vhosts, want)
+	}
+	if _, err := loadVHosts("example.com/path", ""); err == nil {
+		t.Error("invalid virtual host accepted")
+	}
+}
+
+func TestVHosts(t *testing.T) {
+	handler := stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		host, _, _ := net.SplitHostPort(r.Host)
+		if host != "a.example" && host != "b.example" {
+			stdhttp.NotFound(w, r)
+			return
+		}
+		sni := ""
+		if r.TLS != nil {
+			sni = r.TLS.ServerName
+		}
+		w.Write([]byte(host + " " + sni))
+	})
+
+	for _, useHTTPS := range []bool{false, true} {
+		var server *httptest.Server
+		if useHTTPS {
+			server = httptest.NewTLSServer(handler)
+		} else {
+			server = httptest.NewServer(handler)
+		}
+		addr := server.Listener.Addr().(*net.TCPAddr)
+
+		var module Module
+		flags := module.NewFlags().(*Flags)
+		flags.Endpoint = "/"
+		flags.Method = "GET"
+		flags.MaxSize = 256
+		flags.Port = uint(addr.Port)
+		flags.ConnectTimeout = time.Second
+		flags.UseHTTPS = useHTTPS
+		flags.VHosts = "a.example,b.example"
+		scanner := module.NewScanner().(*Scanner)
+		if err := scanner.Init(flags); err != nil {
+			t.Fatal(err)
+		}
+		dialerGroup, err := scanner.GetDialerGroupConfig().GetDefaultDialerGroupFromConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// resolve the virtual hosts to the IP of the target, as the dialer's fake resolver does outside of tests
+		l4Dialer := dialerGroup.L4Dialer
+		dialerGroup.L4Dialer = func(target *zgrab2.ScanTarget) func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return l4Dialer(target)(ctx, network, addr.String())
+			}
+		}
+		status, ret, err := scanner.Scan(context.Background(), dialerGroup, &zgrab2.ScanTarget{IP: addr.IP, Port: uint(addr.Port)})
+		server.Close()
+		if status != zgrab2.SCAN_SUCCESS {
+			t.Fatalf("status %s: %v", status, err)
+		}
+		results := ret.(*Results)
+		if results.Response.StatusCode != stdhttp.StatusNotFound {
+			t.Errorf("IP scan status %d", results.Response.StatusCode)
+		}
+		if len(results.VHosts) != 2 {
+			t.Fatalf("got %d vhost results, want 2", len(results.VHosts))
+		}
+		for _, vhost := range results.VHosts {
+			want := vhost.Host + " "
+			if useHTTPS {
+				want += vhost.Host
+			}
+			if vhost.Status != zgrab2.SCAN_SUCCESS || vhost.Result == nil || vhost.Result.Response.BodyText != want {
+				t.Errorf("https %v: vhost result = %+v, want body %q", useHTTPS, vhost, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package http
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/zmap/zgrab2"
+	"github.com/zmap/zgrab2/lib/http"
+)
+
+// webSocketGUID is appended to the key of a WebSocket handshake to compute the accept value (RFC 6455 section 1.3).
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocketResult is the outcome of the WebSocket upgrade attempted with --websocket-path.
+type WebSocketResult struct {
+	URL string `json:"url"`
+
+	// Upgraded is true if the server answered with 101 Switching Protocols and the accept value of the key.
+	Upgraded   bool        `json:"upgraded"`
+	StatusCode int         `json:"status_code,omitempty"`
+	Headers    http.Header `json:"headers,omitempty"`
+
+	// AcceptValid tells whether Sec-WebSocket-Accept matches the key that was sent.
+	AcceptValid bool `json:"accept_valid"`
+
+	// Protocols and Extensions are those the server selected in Sec-WebSocket-Protocol and Sec-WebSocket-Extensions.
+	Protocols  []string `json:"protocols,omitempty"`
+	Extensions []string `json:"extensions,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// webSocketAccept returns the Sec-WebSocket-Accept value for a key.
+func webSocketAccept(key string) string {
+	h := sha1.Sum([]byte(key + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// splitHeaderList splits the comma-separated values of a header.
+func splitHeaderList(values []string) []string {
+	var list []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// newWebSocketRequest returns the handshake request for the --websocket-path on the host of the initial request,
+// and the key it sends.
+func (scan *scan) newWebSocketRequest() (*http.Request, string, error) {
+	base, err := url.Parse(scan.url)
+	if err != nil {
+		return nil, "", err
+	}
+	ref, err := url.Parse(scan.scanner.config.WebSocketPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid WebSocket path: %w", err)
+	}
+	request, err := http.NewRequest("GET", base.ResolveReference(ref).String(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, "", err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+	request.Header.Set("User-Agent", scan.scanner.config.UserAgent)
+	for k, v := range scan.scanner.customHeaders {
+		request.Header.Set(k, v)
+	}
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Upgrade", "websocket")
+	request.Header.Set("Origin", base.Scheme+"://"+base.Host)
+	request.Header.Set("Sec-WebSocket-Version", "13")
+	request.Header.Set("Sec-WebSocket-Key", key)
+	if protocols := scan.scanner.config.WebSocketProtocols; protocols != "" {
+		request.Header.Set("Sec-WebSocket-Protocol", protocols)
+	}
+	if extensions := scan.scanner.config.WebSocketExtensions; extensions != "" {
+		request.Header.Set("Sec-WebSocket-Extensions", extensions)
+	}
+	return request, key, nil
+}
+
+// probeWebSocket attempts a WebSocket upgrade on a new connection to the target. Over TLS, it offers only
+// http/1.1 with ALPN, since the upgrade is not possible over HTTP/2. The connection is closed after the handshake.
+func (scan *scan) probeWebSocket() {
+	result := new(WebSocketResult)
+	scan.results.WebSocket = result
+	request, key, err := scan.newWebSocketRequest()
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+	result.URL = request.URL.String()
+
+	port := request.URL.Port()
+	if port == "" {
+		port = fmt.Sprint(protoToPort[request.URL.Scheme])
+	}
+	ctx, cancel := scan.withDeadlineContext(scan.ctx)
+	defer cancel()
+	conn, err := scan.dialerGroup.L4Dialer(scan.target)(ctx, "tcp", net.JoinHostPort(request.URL.Hostname(), port))
+	if err != nil {
+		result.Error = fmt.Sprintf("error opening connection: %v", err)
+		return
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+	if request.URL.Scheme == "https" {
+		tlsConn, err := scan.scanner.webSocketTLSWrapper(ctx, scan.target, conn)
+		if err != nil {
+			result.Error = fmt.Sprintf("error performing TLS handshake: %v", err)
+			return
+		}
+		conn = tlsConn
+	}
+	if err := request.Write(conn); err != nil {
+		result.Error = fmt.Sprintf("error sending handshake: %v", err)
+		return
+	}
+	res, err := http.ReadResponse(bufio.NewReader(conn), request)
+	if err != nil {
+		result.Error = fmt.Sprintf("error reading handshake response: %v", err)
+		return
+	}
+	res.Body.Close()
+
+	result.StatusCode = res.StatusCode
+	result.Headers = res.Header
+	result.AcceptValid = res.Header.Get("Sec-WebSocket-Accept") == webSocketAccept(key)
+	result.Upgraded = res.StatusCode == http.StatusSwitchingProtocols && result.AcceptValid &&
+		strings.EqualFold(res.Header.Get("Upgrade"), "websocket")
+	result.Protocols = splitHeaderList(res.Header.Values("Sec-WebSocket-Protocol"))
+	result.Extensions = splitHeaderList(res.Header.Values("Sec-WebSocket-Extensions"))
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"context"
+	"net"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zmap/zgrab2"
+)
+
+func TestWebSocketAccept(t *testing.T) {
+	// RFC 6455 section 1.3
+	if got := webSocketAccept("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("webSocketAccept = %s", got)
+	}
+}
+
+func TestProbeWebSocket(t *testing.T) {
+	handler := stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if r.URL.Path != "/ws" || !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			w.Write([]byte("index"))
+			return
+		}
+		if r.ProtoMajor != 1 || r.Header.Get("Sec-WebSocket-Protocol") != "chat, superchat" {
+			stdhttp.Error(w, "bad handshake", stdhttp.StatusBadRequest)
+			return
+		}
+		conn, rw, err := stdhttp.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + webSocketAccept(r.Header.Get("Sec-WebSocket-Key")) + "\r\n" +
+			"Sec-WebSocket-Protocol: chat\r\nSec-WebSocket-Extensions: permessage-deflate; server_no_context_takeover\r\n\r\n")
+		rw.Flush()
+	})
+
+	for _, useHTTPS := range []bool{false, true} {
+		server := httptest.NewUnstartedServer(handler)
+		if useHTTPS {
+			server.EnableHTTP2 = true
+			server.StartTLS()
+		} else {
+			server.Start()
+		}
+		addr := server.Listener.Addr().(*net.TCPAddr)
+
+		for _, path := range []string{"/ws", "/"} {
+			var module Module
+			flags := module.NewFlags().(*Flags)
+			flags.Endpoint = "/"
+			flags.Method = "GET"
+			flags.MaxSize = 256
+			flags.Port = uint(addr.Port)
+			flags.ConnectTimeout = time.Second
+			flags.UseHTTPS = useHTTPS
+			flags.WebSocketPath = path
+			flags.WebSocketProtocols = "chat, superchat"
+			scanner := module.NewScanner().(*Scanner)
+			if err := scanner.Init(flags); err != nil {
+				t.Fatal(err)
+			}
+			dialerGroup, err := scanner.GetDialerGroupConfig().GetDefaultDialerGroupFromConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			status, ret, err := scanner.Scan(context.Background(), dialerGroup, &zgrab2.ScanTarget{IP: addr.IP, Port: uint(addr.Port)})
+			if status != zgrab2.SCAN_SUCCESS {
+				t.Fatalf("status %s: %v", status, err)
+			}
+			ws := ret.(*Results).WebSocket
+			if ws == nil || ws.Error != "" {
+				t.Fatalf("https %v, path %s: websocket = %+v", useHTTPS, path, ws)
+			}
+			if path == "/" {
+				if ws.Upgraded || ws.StatusCode != stdhttp.StatusOK {
+					t.Errorf("https %v: upgrade of a plain page = %+v", useHTTPS, ws)
+				}
+				continue
+			}
+			if !ws.Upgraded || !ws.AcceptValid || !slices.Equal(ws.Protocols, []string{"chat"}) ||
+				!slices.Equal(ws.Extensions, []string{"permessage-deflate; server_no_context_takeover"}) {
+				t.Errorf("https %v: websocket = %+v", useHTTPS, ws)
+			}
+		}
+		server.Close()
+	}
+}
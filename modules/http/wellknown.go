@@ -0,0 +1,178 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zmap/zgrab2/lib/http"
+)
+
+// MetadataFile is a well-known file fetched with --security-txt or --robots-txt.
+type MetadataFile struct {
+	URL         string `json:"url"`
+	StatusCode  int    `json:"status_code,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+
+	// Body is the content of the file, if the server returned it and it is text.
+	Body string `json:"body,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// SecurityTXT is a security.txt file (RFC 9116).
+type SecurityTXT struct {
+	MetadataFile
+
+	// Signed is true if the file is signed with OpenPGP cleartext signature framework.
+	Signed bool `json:"signed"`
+
+	Contact            []string `json:"contact,omitempty"`
+	Expires            string   `json:"expires,omitempty"`
+	Encryption         []string `json:"encryption,omitempty"`
+	Acknowledgments    []string `json:"acknowledgments,omitempty"`
+	PreferredLanguages []string `json:"preferred_languages,omitempty"`
+	Canonical          []string `json:"canonical,omitempty"`
+	Policy             []string `json:"policy,omitempty"`
+	Hiring             []string `json:"hiring,omitempty"`
+	CSAF               []string `json:"csaf,omitempty"`
+
+	// Unknown are the names of fields that RFC 9116 and its registry do not define.
+	Unknown []string `json:"unknown,omitempty"`
+}
+
+// RobotsTXT is a robots.txt file.
+type RobotsTXT struct {
+	MetadataFile
+
+	Sitemaps []string `json:"sitemaps,omitempty"`
+}
+
+// fetchMetadataFile requests a file from the root of base and returns its content if the server returned it
+// with status 200.
+func (scan *scan) fetchMetadataFile(base *url.URL, path string, file *MetadataFile) []byte {
+	file.URL = base.ResolveReference(&url.URL{Path: path}).String()
+	request, err := http.NewRequest("GET", file.URL, nil)
+	if err != nil {
+		file.Error = err.Error()
+		return nil
+	}
+	scan.prepareRequest(request)
+	res, err := scan.do(request)
+	if err != nil {
+		file.Error = err.Error()
+		return nil
+	}
+	defer res.Body.Close()
+	file.StatusCode = res.StatusCode
+	file.ContentType = res.Header.Get("Content-Type")
+	if res.StatusCode != http.StatusOK {
+		return nil
+	}
+	content, err := io.ReadAll(io.LimitReader(res.Body, int64(scan.scanner.config.MaxSize)*1024))
+	if err != nil {
+		file.Error = err.Error()
+		return nil
+	}
+	if utf8.Valid(content) {
+		file.Body = string(content)
+	}
+	return content
+}
+
+// fetchMetadataFiles fetches the well-known files selected by the flags from the host of the final response. Like
+// the requests of the sequence, they do not follow redirects.
+func (scan *scan) fetchMetadataFiles() {
+	res := scan.results.Response
+	if res == nil || res.Request == nil || res.Request.URL == nil {
+		return
+	}
+	base := res.Request.URL
+	scan.noRedirects = true
+	defer func() { scan.noRedirects = false }()
+	if scan.scanner.config.SecurityTXT {
+		securityTXT := new(SecurityTXT)
+		if content := scan.fetchMetadataFile(base, "/.well-known/security.txt", &securityTXT.MetadataFile); content != nil {
+			securityTXT.parse(content)
+		}
+		scan.results.SecurityTXT = securityTXT
+	}
+	if scan.scanner.config.RobotsTXT {
+		robotsTXT := new(RobotsTXT)
+		if content := scan.fetchMetadataFile(base, "/robots.txt", &robotsTXT.MetadataFile); content != nil {
+			robotsTXT.parse(content)
+		}
+		scan.results.RobotsTXT = robotsTXT
+	}
+}
+
+// parse records the fields of a security.txt file. Fields are case-insensitive, and lines inside the OpenPGP
+// armor of a signed file are skipped.
+func (s *SecurityTXT) parse(content []byte) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	inSignature := false
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "-----BEGIN PGP SIGNED MESSAGE-----":
+			s.Signed = true
+			continue
+		case line == "-----BEGIN PGP SIGNATURE-----":
+			inSignature = true
+			continue
+		case line == "-----END PGP SIGNATURE-----":
+			inSignature = false
+			continue
+		case inSignature || line == "" || strings.HasPrefix(line, "#"):
+			continue
+		}
+		// dash-escaped lines of the signed text (RFC 4880 section 7.1)
+		line = strings.TrimPrefix(line, "- ")
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "contact":
+			s.Contact = append(s.Contact, value)
+		case "expires":
+			s.Expires = value
+		case "encryption":
+			s.Encryption = append(s.Encryption, value)
+		case "acknowledgments", "acknowledgements":
+			s.Acknowledgments = append(s.Acknowledgments, value)
+		case "preferred-languages":
+			for _, language := range strings.Split(value, ",") {
+				s.PreferredLanguages = append(s.PreferredLanguages, strings.TrimSpace(language))
+			}
+		case "canonical":
+			s.Canonical = append(s.Canonical, value)
+		case "policy":
+			s.Policy = append(s.Policy, value)
+		case "hiring":
+			s.Hiring = append(s.Hiring, value)
+		case "csaf":
+			s.CSAF = append(s.CSAF, value)
+		case "hash":
+			// the Hash header of the OpenPGP cleartext signature
+		default:
+			s.Unknown = append(s.Unknown, strings.TrimSpace(name))
+		}
+	}
+}
+
+// parse records the sitemaps listed in a robots.txt file.
+func (r *RobotsTXT) parse(content []byte) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "sitemap") {
+			r.Sitemaps = append(r.Sitemaps, strings.TrimSpace(value))
+		}
+	}
+}
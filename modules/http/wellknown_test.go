@@ -0,0 +1,127 @@
+package http
+
+import (
+	"context"
+	"net"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/zmap/zgrab2"
+)
+
+func TestParseSecurityTXT(t *testing.T) {
+	content := []byte(`-----BEGIN PGP SIGNED MESSAGE-----
+Hash: SHA256
+
+# Our security policy
+Contact: mailto:security@example.com
+contact: https://example.com/security
+Expires: 2027-01-01T00:00:00.000Z
+Encryption: https://example.com/pgp-key.txt
+Preferred-Languages: en, de
+Canonical: https://example.com/.well-known/security.txt
+Policy: https://example.com/disclosure
+Acknowledgments: https://example.com/hall-of-fame
+Hiring: https://example.com/jobs
+CSAF: https://example.com/.well-known/csaf/provider-metadata.json
+X-Bounty: yes
+-----BEGIN PGP SIGNATURE-----
+
+Contact: not-a-field
+-----END PGP SIGNATURE-----
+`)
+	var s SecurityTXT
+	s.parse(content)
+	if !s.Signed {
+		t.Error("signature not detected")
+	}
+	if want := []string{"mailto:security@example.com", "https://example.com/security"}; !slices.Equal(s.Contact, want) {
+		t.Errorf("Contact = %q, want %q", s.Contact, want)
+	}
+	if s.Expires != "2027-01-01T00:00:00.000Z" {
+		t.Errorf("Expires = %q", s.Expires)
+	}
+	if want := []string{"en", "de"}; !slices.Equal(s.PreferredLanguages, want) {
+		t.Errorf("PreferredLanguages = %q, want %q", s.PreferredLanguages, want)
+	}
+	for name, got := range map[string][]string{
+		"Encryption":      s.Encryption,
+		"Canonical":       s.Canonical,
+		"Policy":          s.Policy,
+		"Acknowledgments": s.Acknowledgments,
+		"Hiring":          s.Hiring,
+		"CSAF":            s.CSAF,
+	} {
+		if len(got) != 1 {
+			t.Errorf("%s = %q, want one value", name, got)
+		}
+	}
+	if want := []string{"X-Bounty"}; !slices.Equal(s.Unknown, want) {
+		t.Errorf("Unknown = %q, want %q", s.Unknown, want)
+	}
+}
+
+func TestFetchMetadataFiles(t *testing.T) {
+	server := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte("index"))
+		case "/.well-known/security.txt":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Write([]byte("Contact: mailto:security@example.com\nExpires: 2027-01-01T00:00:00Z\n"))
+		case "/robots.txt":
+			stdhttp.Redirect(w, r, "/robots-elsewhere.txt", stdhttp.StatusFound)
+		default:
+			stdhttp.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	addr := server.Listener.Addr().(*net.TCPAddr)
+
+	var module Module
+	flags := module.NewFlags().(*Flags)
+	flags.Endpoint = "/"
+	flags.Method = "GET"
+	flags.MaxSize = 256
+	flags.Port = uint(addr.Port)
+	flags.ConnectTimeout = time.Second
+	flags.SecurityTXT = true
+	flags.RobotsTXT = true
+	flags.OmitBody = true
+	scanner := module.NewScanner().(*Scanner)
+	if err := scanner.Init(flags); err != nil {
+		t.Fatal(err)
+	}
+	dialerGroup, err := scanner.GetDialerGroupConfig().GetDefaultDialerGroupFromConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, ret, err := scanner.Scan(context.Background(), dialerGroup, &zgrab2.ScanTarget{IP: addr.IP, Port: uint(addr.Port)})
+	if status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status %s: %v", status, err)
+	}
+	results := ret.(*Results)
+	securityTXT := results.SecurityTXT
+	if securityTXT == nil || securityTXT.StatusCode != stdhttp.StatusOK || securityTXT.Body != "" {
+		t.Fatalf("security.txt = %+v", securityTXT)
+	}
+	if !slices.Equal(securityTXT.Contact, []string{"mailto:security@example.com"}) || securityTXT.Signed {
+		t.Errorf("security.txt fields = %+v", securityTXT)
+	}
+	// the redirect is recorded, not followed
+	robotsTXT := results.RobotsTXT
+	if robotsTXT == nil || robotsTXT.StatusCode != stdhttp.StatusFound || robotsTXT.Error != "" {
+		t.Errorf("robots.txt = %+v", robotsTXT)
+	}
+}
+
+func TestParseRobotsTXT(t *testing.T) {
+	var r RobotsTXT
+	r.parse([]byte("User-agent: *\nDisallow: /admin # private\nsitemap: https://example.com/sitemap.xml\n"))
+	if want := []string{"https://example.com/sitemap.xml"}; !slices.Equal(r.Sitemaps, want) {
+		t.Errorf("Sitemaps = %q, want %q", r.Sitemaps, want)
+	}
+}
@@ -0,0 +1,92 @@
+package http3
+
+import (
+	"strconv"
+	"strings"
+)
+
+// AltSvc is an alternative service advertised in an Alt-Svc header (RFC 7838).
+type AltSvc struct {
+	Protocol string `json:"protocol"`
+	Host     string `json:"host,omitempty"`
+	Port     uint16 `json:"port"`
+
+	// MaxAge is the ma parameter in seconds, if present.
+	MaxAge *uint64 `json:"max_age,omitempty"`
+}
+
+// parseAltSvc parses the values of Alt-Svc headers. Malformed entries and the "clear" value are skipped.
+func parseAltSvc(values []string) []AltSvc {
+	var entries []AltSvc
+	for _, value := range values {
+		for _, entry := range splitQuoted(value, ',') {
+			params := splitQuoted(entry, ';')
+			protocol, authority, ok := strings.Cut(strings.TrimSpace(params[0]), "=")
+			if !ok {
+				continue
+			}
+			authority = strings.Trim(strings.TrimSpace(authority), `"`)
+			// the host is empty for the same host, and may be an IPv6 literal in brackets
+			i := strings.LastIndex(authority, ":")
+			if i < 0 {
+				continue
+			}
+			port, err := strconv.ParseUint(authority[i+1:], 10, 16)
+			if err != nil {
+				continue
+			}
+			alt := AltSvc{Protocol: unescapeProtocol(strings.TrimSpace(protocol)), Host: authority[:i], Port: uint16(port)}
+			for _, param := range params[1:] {
+				name, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(name, "ma") {
+					continue
+				}
+				if maxAge, err := strconv.ParseUint(strings.Trim(v, `"`), 10, 64); err == nil {
+					alt.MaxAge = &maxAge
+				}
+			}
+			entries = append(entries, alt)
+		}
+	}
+	return entries
+}
+
+// splitQuoted splits s at sep, ignoring separators inside quoted strings.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unescapeProtocol decodes the percent-encoding of an ALPN protocol ID in an Alt-Svc entry.
+func unescapeProtocol(protocol string) string {
+	var b strings.Builder
+	for i := 0; i < len(protocol); i++ {
+		if protocol[i] == '%' && i+2 < len(protocol) {
+			if c, err := strconv.ParseUint(protocol[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(protocol[i])
+	}
+	return b.String()
+}
+
+// isH3 reports whether an Alt-Svc entry advertises HTTP/3, including its drafts.
+func (alt *AltSvc) isH3() bool {
+	return alt.Protocol == "h3" || strings.HasPrefix(alt.Protocol, "h3-")
+}
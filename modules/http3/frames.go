@@ -0,0 +1,282 @@
+package http3
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// HTTP/3 frame types (RFC 9114 section 7.2).
+const (
+	frameData        = 0x00
+	frameHeaders     = 0x01
+	frameCancelPush  = 0x03
+	frameSettings    = 0x04
+	framePushPromise = 0x05
+	frameGoAway      = 0x07
+	frameMaxPushID   = 0x0D
+)
+
+// Unidirectional stream types (RFC 9114 section 6.2 and RFC 9204 section 4.2).
+const (
+	streamControl      = 0x00
+	streamPush         = 0x01
+	streamQPACKEncoder = 0x02
+	streamQPACKDecoder = 0x03
+)
+
+var streamTypeNames = map[uint64]string{
+	streamControl:      "control",
+	streamPush:         "push",
+	streamQPACKEncoder: "qpack_encoder",
+	streamQPACKDecoder: "qpack_decoder",
+}
+
+// SETTINGS parameters (RFC 9114 section 7.2.4.1, RFC 9204, RFC 9220 and RFC 9297).
+const (
+	settingQPACKMaxTableCapacity = 0x01
+	settingMaxFieldSectionSize   = 0x06
+	settingQPACKBlockedStreams   = 0x07
+	settingEnableConnectProtocol = 0x08
+	settingH3Datagram            = 0x33
+	settingEnableWebTransport    = 0x2B603742
+)
+
+var settingNames = map[uint64]string{
+	settingQPACKMaxTableCapacity: "SETTINGS_QPACK_MAX_TABLE_CAPACITY",
+	settingMaxFieldSectionSize:   "SETTINGS_MAX_FIELD_SECTION_SIZE",
+	settingQPACKBlockedStreams:   "SETTINGS_QPACK_BLOCKED_STREAMS",
+	settingEnableConnectProtocol: "SETTINGS_ENABLE_CONNECT_PROTOCOL",
+	settingH3Datagram:            "SETTINGS_H3_DATAGRAM",
+	settingEnableWebTransport:    "SETTINGS_ENABLE_WEBTRANSPORT",
+}
+
+// HTTP/3 error codes (RFC 9114 section 8.1 and RFC 9204 section 6).
+var errorNames = map[uint64]string{
+	0x0100: "H3_NO_ERROR",
+	0x0101: "H3_GENERAL_PROTOCOL_ERROR",
+	0x0102: "H3_INTERNAL_ERROR",
+	0x0103: "H3_STREAM_CREATION_ERROR",
+	0x0104: "H3_CLOSED_CRITICAL_STREAM",
+	0x0105: "H3_FRAME_UNEXPECTED",
+	0x0106: "H3_FRAME_ERROR",
+	0x0107: "H3_EXCESSIVE_LOAD",
+	0x0108: "H3_ID_ERROR",
+	0x0109: "H3_SETTINGS_ERROR",
+	0x010A: "H3_MISSING_SETTINGS",
+	0x010B: "H3_REQUEST_REJECTED",
+	0x010C: "H3_REQUEST_CANCELLED",
+	0x010D: "H3_REQUEST_INCOMPLETE",
+	0x010E: "H3_MESSAGE_ERROR",
+	0x010F: "H3_CONNECT_ERROR",
+	0x0110: "H3_VERSION_FALLBACK",
+	0x0200: "QPACK_DECOMPRESSION_FAILED",
+	0x0201: "QPACK_ENCODER_STREAM_ERROR",
+	0x0202: "QPACK_DECODER_STREAM_ERROR",
+}
+
+var errInvalidFrame = errors.New("invalid HTTP/3 frame")
+
+// Setting is an HTTP/3 SETTINGS parameter.
+type Setting struct {
+	ID    uint64 `json:"id"`
+	Name  string `json:"name,omitempty"`
+	Value uint64 `json:"value"`
+}
+
+// isGrease reports whether an identifier is one of the values of the form 0x1f * N + 0x21 that RFC 9114 reserves
+// to exercise the handling of unknown frame types, stream types and settings.
+func isGrease(id uint64) bool {
+	return id >= 0x21 && (id-0x21)%0x1F == 0
+}
+
+// settingName names a setting, or returns "GREASE" for the reserved identifiers.
+func settingName(id uint64) string {
+	if name, ok := settingNames[id]; ok {
+		return name
+	}
+	if isGrease(id) {
+		return "GREASE"
+	}
+	return ""
+}
+
+// streamTypeName names a unidirectional stream type, or returns "grease" for the reserved types and the type in
+// hex for unknown ones.
+func streamTypeName(streamType uint64) string {
+	if name, ok := streamTypeNames[streamType]; ok {
+		return name
+	}
+	if isGrease(streamType) {
+		return "grease"
+	}
+	return fmt.Sprintf("%#x", streamType)
+}
+
+// appendVarInt appends a QUIC variable-length integer.
+func appendVarInt(b []byte, v uint64) []byte {
+	switch {
+	case v < 1<<6:
+		return append(b, byte(v))
+	case v < 1<<14:
+		return binary.BigEndian.AppendUint16(b, uint16(v)|0x4000)
+	case v < 1<<30:
+		return binary.BigEndian.AppendUint32(b, uint32(v)|0x80000000)
+	}
+	return binary.BigEndian.AppendUint64(b, v|0xC000000000000000)
+}
+
+// readVarInt decodes a QUIC variable-length integer and returns it with the remaining bytes. ok is false if b is
+// too short.
+func readVarInt(b []byte) (v uint64, rest []byte, ok bool) {
+	if len(b) == 0 {
+		return 0, nil, false
+	}
+	length := 1 << (b[0] >> 6)
+	if len(b) < length {
+		return 0, nil, false
+	}
+	v = uint64(b[0] & 0x3F)
+	for _, c := range b[1:length] {
+		v = v<<8 | uint64(c)
+	}
+	return v, b[length:], true
+}
+
+// appendFrame appends an HTTP/3 frame.
+func appendFrame(b []byte, frameType uint64, payload []byte) []byte {
+	b = appendVarInt(b, frameType)
+	b = appendVarInt(b, uint64(len(payload)))
+	return append(b, payload...)
+}
+
+// frame is a decoded HTTP/3 frame.
+type frame struct {
+	frameType uint64
+	payload   []byte
+}
+
+// readFrames decodes the complete frames at the start of b, and returns the bytes of a trailing partial frame.
+func readFrames(b []byte) ([]frame, []byte) {
+	var frames []frame
+	for len(b) > 0 {
+		frameType, rest, ok := readVarInt(b)
+		if !ok {
+			break
+		}
+		length, rest, ok := readVarInt(rest)
+		if !ok || uint64(len(rest)) < length {
+			break
+		}
+		frames = append(frames, frame{frameType: frameType, payload: rest[:length]})
+		b = rest[length:]
+	}
+	return frames, b
+}
+
+// buildControlStream returns the start of the client's control stream: the stream type and an empty SETTINGS
+// frame, leaving all settings at their defaults. In particular, the QPACK dynamic table stays disabled.
+func buildControlStream() []byte {
+	return appendFrame([]byte{streamControl}, frameSettings, nil)
+}
+
+// parseSettings decodes the payload of a SETTINGS frame.
+func parseSettings(payload []byte) ([]Setting, error) {
+	var settings []Setting
+	for len(payload) > 0 {
+		id, rest, ok := readVarInt(payload)
+		if !ok {
+			return settings, errInvalidFrame
+		}
+		value, rest, ok := readVarInt(rest)
+		if !ok {
+			return settings, errInvalidFrame
+		}
+		settings = append(settings, Setting{ID: id, Name: settingName(id), Value: value})
+		payload = rest
+	}
+	return settings, nil
+}
+
+// controlStream is what the scan decodes from the server's control stream.
+type controlStream struct {
+	settings     []Setting
+	seenSettings bool
+	goAway       *uint64
+}
+
+// parseControlStream decodes the frames of the server's control stream, after the stream type. The first frame
+// must be SETTINGS.
+func parseControlStream(b []byte) (*controlStream, error) {
+	frames, _ := readFrames(b)
+	c := new(controlStream)
+	for i, f := range frames {
+		switch {
+		case i == 0 && f.frameType != frameSettings:
+			return c, fmt.Errorf("control stream starts with frame type %#x instead of SETTINGS", f.frameType)
+		case f.frameType == frameSettings:
+			if i > 0 {
+				return c, errors.New("second SETTINGS frame on the control stream")
+			}
+			var err error
+			if c.settings, err = parseSettings(f.payload); err != nil {
+				return c, err
+			}
+			c.seenSettings = true
+		case f.frameType == frameGoAway:
+			id, _, ok := readVarInt(f.payload)
+			if !ok {
+				return c, errInvalidFrame
+			}
+			c.goAway = &id
+		}
+	}
+	return c, nil
+}
+
+// response is what the scan decodes from the request stream.
+type response struct {
+	// headers are the fields of the final HEADERS frame; interim (1xx) responses are skipped.
+	headers  []field
+	body     []byte
+	trailers []field
+
+	// complete is set when the stream ended or the body reached the size limit.
+	complete bool
+}
+
+// parseResponse decodes the frames on the request stream. done reports whether the server finished the stream.
+// The body is truncated to maxBody bytes.
+func parseResponse(b []byte, done bool, maxBody int) (*response, error) {
+	frames, _ := readFrames(b)
+	r := &response{complete: done}
+	for _, f := range frames {
+		switch f.frameType {
+		case frameHeaders:
+			fields, err := decodeFieldSection(f.payload)
+			if err != nil {
+				return r, err
+			}
+			if r.headers == nil {
+				if status := pseudoValue(fields, ":status"); len(status) == 3 && status[0] == '1' {
+					continue
+				}
+				r.headers = fields
+			} else {
+				r.trailers = fields
+			}
+		case frameData:
+			if r.headers == nil {
+				return r, errors.New("DATA frame before HEADERS")
+			}
+			remaining := maxBody - len(r.body)
+			if len(f.payload) > remaining {
+				r.body = append(r.body, f.payload[:remaining]...)
+				r.complete = true
+				return r, nil
+			}
+			r.body = append(r.body, f.payload...)
+		}
+	}
+	return r, nil
+}
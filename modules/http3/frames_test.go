@@ -0,0 +1,93 @@
+package http3
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseControlStream(t *testing.T) {
+	var settings []byte
+	settings = appendVarInt(settings, settingQPACKMaxTableCapacity)
+	settings = appendVarInt(settings, 4096)
+	settings = appendVarInt(settings, settingQPACKBlockedStreams)
+	settings = appendVarInt(settings, 16)
+	settings = appendVarInt(settings, 0x21+0x1F*3)
+	settings = appendVarInt(settings, 1)
+	b := appendFrame(nil, frameSettings, settings)
+	b = appendFrame(b, frameGoAway, appendVarInt(nil, 8))
+	// a partial frame at the end is left for later
+	b = append(b, frameGoAway, 4)
+
+	c, err := parseControlStream(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Setting{
+		{ID: settingQPACKMaxTableCapacity, Name: "SETTINGS_QPACK_MAX_TABLE_CAPACITY", Value: 4096},
+		{ID: settingQPACKBlockedStreams, Name: "SETTINGS_QPACK_BLOCKED_STREAMS", Value: 16},
+		{ID: 0x21 + 0x1F*3, Name: "GREASE", Value: 1},
+	}
+	if !c.seenSettings || !slices.Equal(c.settings, want) {
+		t.Errorf("settings = %v, want %v", c.settings, want)
+	}
+	if c.goAway == nil || *c.goAway != 8 {
+		t.Errorf("GOAWAY = %v, want 8", c.goAway)
+	}
+
+	if _, err := parseControlStream(appendFrame(nil, frameGoAway, []byte{0})); err == nil {
+		t.Error("control stream without SETTINGS accepted")
+	}
+}
+
+func TestParseResponse(t *testing.T) {
+	interim := appendFrame(nil, frameHeaders, encodeFieldSection([]field{{":status", "103"}, {"link", "</style.css>"}}))
+	headers := appendFrame(nil, frameHeaders, encodeFieldSection([]field{{":status", "200"}, {"content-type", "text/plain"}}))
+	trailers := appendFrame(nil, frameHeaders, encodeFieldSection([]field{{"x-checksum", "abc"}}))
+	b := append(interim, headers...)
+	b = appendFrame(b, frameData, []byte("hello, "))
+	b = appendFrame(b, 0x21, []byte("reserved frame type"))
+	b = appendFrame(b, frameData, []byte("world"))
+	b = append(b, trailers...)
+
+	r, err := parseResponse(b, true, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := pseudoValue(r.headers, ":status"); status != "200" {
+		t.Errorf(":status = %q, want 200", status)
+	}
+	if string(r.body) != "hello, world" {
+		t.Errorf("body = %q", r.body)
+	}
+	if len(r.trailers) != 1 || r.trailers[0].value != "abc" {
+		t.Errorf("trailers = %v", r.trailers)
+	}
+
+	r, err = parseResponse(b, false, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.complete || string(r.body) != "hello" {
+		t.Errorf("truncated body = %q, complete = %v", r.body, r.complete)
+	}
+
+	if r, _ := parseResponse(headers[:len(headers)-1], false, 1024); r.headers != nil {
+		t.Error("partial HEADERS frame decoded")
+	}
+	if _, err := parseResponse(appendFrame(nil, frameData, []byte("x")), true, 1024); err == nil {
+		t.Error("DATA before HEADERS accepted")
+	}
+}
+
+func TestStreamTypeName(t *testing.T) {
+	for streamType, want := range map[uint64]string{
+		streamControl:      "control",
+		streamQPACKDecoder: "qpack_decoder",
+		0x21 + 0x1F:        "grease",
+		0x54:               "0x54",
+	} {
+		if got := streamTypeName(streamType); got != want {
+			t.Errorf("streamTypeName(%#x) = %q, want %q", streamType, got, want)
+		}
+	}
+}
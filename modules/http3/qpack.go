@@ -0,0 +1,316 @@
+package http3
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zmap/zgrab2/lib/http2/hpack"
+)
+
+// field is a decoded header field.
+type field struct {
+	name, value string
+}
+
+// staticTable is the QPACK static table (RFC 9204 appendix A).
+var staticTable = [...]field{
+	{":authority", ""},
+	{":path", "/"},
+	{"age", "0"},
+	{"content-disposition", ""},
+	{"content-length", "0"},
+	{"cookie", ""},
+	{"date", ""},
+	{"etag", ""},
+	{"if-modified-since", ""},
+	{"if-none-match", ""},
+	{"last-modified", ""},
+	{"link", ""},
+	{"location", ""},
+	{"referer", ""},
+	{"set-cookie", ""},
+	{":method", "CONNECT"},
+	{":method", "DELETE"},
+	{":method", "GET"},
+	{":method", "HEAD"},
+	{":method", "OPTIONS"},
+	{":method", "POST"},
+	{":method", "PUT"},
+	{":scheme", "http"},
+	{":scheme", "https"},
+	{":status", "103"},
+	{":status", "200"},
+	{":status", "304"},
+	{":status", "404"},
+	{":status", "503"},
+	{"accept", "*/*"},
+	{"accept", "application/dns-message"},
+	{"accept-encoding", "gzip, deflate, br"},
+	{"accept-ranges", "bytes"},
+	{"access-control-allow-headers", "cache-control"},
+	{"access-control-allow-headers", "content-type"},
+	{"access-control-allow-origin", "*"},
+	{"cache-control", "max-age=0"},
+	{"cache-control", "max-age=2592000"},
+	{"cache-control", "max-age=604800"},
+	{"cache-control", "no-cache"},
+	{"cache-control", "no-store"},
+	{"cache-control", "public, max-age=31536000"},
+	{"content-encoding", "br"},
+	{"content-encoding", "gzip"},
+	{"content-type", "application/dns-message"},
+	{"content-type", "application/javascript"},
+	{"content-type", "application/json"},
+	{"content-type", "application/x-www-form-urlencoded"},
+	{"content-type", "image/gif"},
+	{"content-type", "image/jpeg"},
+	{"content-type", "image/png"},
+	{"content-type", "text/css"},
+	{"content-type", "text/html; charset=utf-8"},
+	{"content-type", "text/plain"},
+	{"content-type", "text/plain;charset=utf-8"},
+	{"range", "bytes=0-"},
+	{"strict-transport-security", "max-age=31536000"},
+	{"strict-transport-security", "max-age=31536000; includesubdomains"},
+	{"strict-transport-security", "max-age=31536000; includesubdomains; preload"},
+	{"vary", "accept-encoding"},
+	{"vary", "origin"},
+	{"x-content-type-options", "nosniff"},
+	{"x-xss-protection", "1; mode=block"},
+	{":status", "100"},
+	{":status", "204"},
+	{":status", "206"},
+	{":status", "302"},
+	{":status", "400"},
+	{":status", "403"},
+	{":status", "421"},
+	{":status", "425"},
+	{":status", "500"},
+	{"accept-language", ""},
+	{"access-control-allow-credentials", "FALSE"},
+	{"access-control-allow-credentials", "TRUE"},
+	{"access-control-allow-headers", "*"},
+	{"access-control-allow-methods", "get"},
+	{"access-control-allow-methods", "get, post, options"},
+	{"access-control-allow-methods", "options"},
+	{"access-control-expose-headers", "content-length"},
+	{"access-control-request-headers", "content-type"},
+	{"access-control-request-method", "get"},
+	{"access-control-request-method", "post"},
+	{"alt-svc", "clear"},
+	{"authorization", ""},
+	{"content-security-policy", "script-src 'none'; object-src 'none'; base-uri 'none'"},
+	{"early-data", "1"},
+	{"expect-ct", ""},
+	{"forwarded", ""},
+	{"if-range", ""},
+	{"origin", ""},
+	{"purpose", "prefetch"},
+	{"server", ""},
+	{"timing-allow-origin", "*"},
+	{"upgrade-insecure-requests", "1"},
+	{"user-agent", ""},
+	{"x-forwarded-for", ""},
+	{"x-frame-options", "deny"},
+	{"x-frame-options", "sameorigin"},
+}
+
+var (
+	errInvalidFieldSection = errors.New("invalid QPACK field section")
+
+	// errDynamicTable is returned for field sections that refer to the dynamic table, which the scan leaves
+	// disabled by not raising SETTINGS_QPACK_MAX_TABLE_CAPACITY.
+	errDynamicTable = errors.New("QPACK field section refers to the dynamic table")
+)
+
+// appendPrefixInt appends an integer with an n-bit prefix (RFC 7541 section 5.1) to b, whose last byte holds the
+// bits above the prefix.
+func appendPrefixInt(b []byte, n uint, v uint64) []byte {
+	max := uint64(1)<<n - 1
+	if v < max {
+		b[len(b)-1] |= byte(v)
+		return b
+	}
+	b[len(b)-1] |= byte(max)
+	v -= max
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// readPrefixInt decodes an integer with an n-bit prefix starting at b[0].
+func readPrefixInt(b []byte, n uint) (uint64, []byte, error) {
+	if len(b) == 0 {
+		return 0, nil, errInvalidFieldSection
+	}
+	max := uint64(1)<<n - 1
+	v := uint64(b[0]) & max
+	b = b[1:]
+	if v < max {
+		return v, b, nil
+	}
+	for shift := uint(0); len(b) > 0; shift += 7 {
+		if shift > 56 {
+			return 0, nil, errInvalidFieldSection
+		}
+		c := b[0]
+		b = b[1:]
+		v += uint64(c&0x7F) << shift
+		if c&0x80 == 0 {
+			return v, b, nil
+		}
+	}
+	return 0, nil, errInvalidFieldSection
+}
+
+// readString decodes a string literal whose Huffman flag is the bit above its n-bit length prefix.
+func readString(b []byte, n uint) (string, []byte, error) {
+	if len(b) == 0 {
+		return "", nil, errInvalidFieldSection
+	}
+	huffman := b[0]&(1<<n) != 0
+	length, rest, err := readPrefixInt(b, n)
+	if err != nil {
+		return "", nil, err
+	}
+	if uint64(len(rest)) < length {
+		return "", nil, errInvalidFieldSection
+	}
+	s, rest := rest[:length], rest[length:]
+	if !huffman {
+		return string(s), rest, nil
+	}
+	decoded, err := hpack.HuffmanDecodeToString(s)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid Huffman string: %w", err)
+	}
+	return decoded, rest, nil
+}
+
+// staticEntry returns an entry of the static table.
+func staticEntry(index uint64) (field, error) {
+	if index >= uint64(len(staticTable)) {
+		return field{}, fmt.Errorf("static table index %d out of range", index)
+	}
+	return staticTable[index], nil
+}
+
+// encodeFieldSection encodes header fields without the dynamic table, using static table entries where they
+// match. Literals are not Huffman-coded.
+func encodeFieldSection(fields []field) []byte {
+	// Required Insert Count and Delta Base are both 0
+	b := []byte{0, 0}
+	for _, f := range fields {
+		nameIndex := -1
+		valueIndex := -1
+		for i, entry := range staticTable {
+			if entry.name != f.name {
+				continue
+			}
+			if nameIndex < 0 {
+				nameIndex = i
+			}
+			if entry.value == f.value {
+				valueIndex = i
+				break
+			}
+		}
+		switch {
+		case valueIndex >= 0:
+			// indexed field line, static table
+			b = appendPrefixInt(append(b, 0xC0), 6, uint64(valueIndex))
+		case nameIndex >= 0:
+			// literal field line with a static name reference
+			b = appendPrefixInt(append(b, 0x50), 4, uint64(nameIndex))
+			b = appendPrefixInt(append(b, 0), 7, uint64(len(f.value)))
+			b = append(b, f.value...)
+		default:
+			// literal field line with a literal name
+			b = appendPrefixInt(append(b, 0x20), 3, uint64(len(f.name)))
+			b = append(b, f.name...)
+			b = appendPrefixInt(append(b, 0), 7, uint64(len(f.value)))
+			b = append(b, f.value...)
+		}
+	}
+	return b
+}
+
+// decodeFieldSection decodes a field section that only refers to the static table.
+func decodeFieldSection(b []byte) ([]field, error) {
+	requiredInsertCount, rest, err := readPrefixInt(b, 8)
+	if err != nil {
+		return nil, err
+	}
+	if requiredInsertCount != 0 {
+		return nil, errDynamicTable
+	}
+	// the sign bit and Delta Base are meaningless without dynamic table references
+	if _, rest, err = readPrefixInt(rest, 7); err != nil {
+		return nil, err
+	}
+
+	var fields []field
+	for len(rest) > 0 {
+		c := rest[0]
+		switch {
+		case c&0x80 != 0:
+			// indexed field line
+			if c&0x40 == 0 {
+				return fields, errDynamicTable
+			}
+			var index uint64
+			if index, rest, err = readPrefixInt(rest, 6); err != nil {
+				return fields, err
+			}
+			entry, err := staticEntry(index)
+			if err != nil {
+				return fields, err
+			}
+			fields = append(fields, entry)
+		case c&0x40 != 0:
+			// literal field line with a name reference
+			if c&0x10 == 0 {
+				return fields, errDynamicTable
+			}
+			var index uint64
+			if index, rest, err = readPrefixInt(rest, 4); err != nil {
+				return fields, err
+			}
+			entry, err := staticEntry(index)
+			if err != nil {
+				return fields, err
+			}
+			var value string
+			if value, rest, err = readString(rest, 7); err != nil {
+				return fields, err
+			}
+			fields = append(fields, field{name: entry.name, value: value})
+		case c&0x20 != 0:
+			// literal field line with a literal name
+			var name, value string
+			if name, rest, err = readString(rest, 3); err != nil {
+				return fields, err
+			}
+			if value, rest, err = readString(rest, 7); err != nil {
+				return fields, err
+			}
+			fields = append(fields, field{name: name, value: value})
+		default:
+			// indexed field line or literal with a post-base index
+			return fields, errDynamicTable
+		}
+	}
+	return fields, nil
+}
+
+// pseudoValue returns the value of a pseudo-header field, or "" if it is missing.
+func pseudoValue(fields []field, name string) string {
+	for _, f := range fields {
+		if f.name == name {
+			return f.value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package http3
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/zmap/zgrab2/lib/http2/hpack"
+)
+
+func TestStaticTable(t *testing.T) {
+	if len(staticTable) != 99 {
+		t.Fatalf("static table has %d entries, want 99", len(staticTable))
+	}
+	for index, want := range map[int]field{
+		0:  {":authority", ""},
+		17: {":method", "GET"},
+		25: {":status", "200"},
+		63: {":status", "100"},
+		98: {"x-frame-options", "sameorigin"},
+	} {
+		if staticTable[index] != want {
+			t.Errorf("entry %d = %v, want %v", index, staticTable[index], want)
+		}
+	}
+}
+
+func TestFieldSectionRoundTrip(t *testing.T) {
+	fields := []field{
+		{":method", "GET"},
+		{":scheme", "https"},
+		{":authority", "example.com"},
+		{":path", "/index.html"},
+		{"user-agent", "Mozilla/5.0 zgrab/0.x"},
+		{"accept", "*/*"},
+		{"x-custom", "value"},
+		{"x-long", string(make([]byte, 300))},
+	}
+	b := encodeFieldSection(fields)
+	// :method GET is static entry 17, a single indexed field line
+	if b[2] != 0xC0|17 {
+		t.Errorf("first field line = %#x, want %#x", b[2], 0xC0|17)
+	}
+	decoded, err := decodeFieldSection(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(decoded, fields) {
+		t.Errorf("decoded %v, want %v", decoded, fields)
+	}
+}
+
+func TestDecodeHuffman(t *testing.T) {
+	// literal field line with a static name reference to server (92), Huffman-coded value
+	value := hpack.AppendHuffmanString(nil, "nginx")
+	b := []byte{0, 0, 0x50 | 0x0F, 92 - 0x0F, 0x80 | byte(len(value))}
+	b = append(b, value...)
+	// literal field line with a literal, Huffman-coded name
+	name := hpack.AppendHuffmanString(nil, "x-powered-by")
+	b = appendPrefixInt(append(b, 0x20|0x08), 3, uint64(len(name)))
+	b = append(b, name...)
+	b = append(b, 3, 'P', 'H', 'P')
+
+	fields, err := decodeFieldSection(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []field{{"server", "nginx"}, {"x-powered-by", "PHP"}}
+	if !slices.Equal(fields, want) {
+		t.Errorf("decoded %v, want %v", fields, want)
+	}
+}
+
+func TestDecodeDynamicTable(t *testing.T) {
+	for _, b := range [][]byte{
+		// nonzero Required Insert Count
+		{1, 0},
+		// indexed field line referring to the dynamic table
+		{0, 0, 0x80},
+		// literal field line with a dynamic name reference
+		{0, 0, 0x40, 0},
+		// indexed field line with a post-base index
+		{0, 0, 0x10},
+	} {
+		if _, err := decodeFieldSection(b); !errors.Is(err, errDynamicTable) {
+			t.Errorf("decodeFieldSection(%x) = %v, want errDynamicTable", b, err)
+		}
+	}
+	if _, err := decodeFieldSection([]byte{0, 0, 0xFF, 0x7F}); err == nil {
+		t.Error("static index out of range accepted")
+	}
+}
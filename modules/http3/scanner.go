@@ -0,0 +1,523 @@
+// Package http3 contains the zgrab2 Module implementation for HTTP/3.
+//
+// Unless --no-http1 is set, the scan first sends the request over HTTP/1.1 with TLS on the target's TCP port and
+// records the Alt-Svc header, which is how servers advertise HTTP/3. It then sends the same request over QUIC
+// version 1, on the target's UDP port or, with --follow-alt-svc, on the port of an advertised h3 alternative for
+// the same host. The scan records the server's SETTINGS, including its QPACK dynamic table limits, the types of
+// the unidirectional streams it opens, and the response, and compares the HTTP/3 response against the HTTP/1.1
+// one.
+//
+// The scanner never enables the QPACK dynamic table, so servers must encode their field sections with the static
+// table and literals only. Certificates are not verified on the QUIC connection.
+package http3
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+	"github.com/zmap/zgrab2/lib/http"
+	"github.com/zmap/zgrab2/modules/quic"
+)
+
+// QPACKSettings are the server's limits for the QPACK dynamic table. Both are 0, their default, if the server
+// didn't send them.
+type QPACKSettings struct {
+	MaxTableCapacity uint64 `json:"max_table_capacity"`
+	BlockedStreams   uint64 `json:"blocked_streams"`
+}
+
+// Identity compares the HTTP/3 response against the HTTP/1.1 response to the same request.
+type Identity struct {
+	StatusCodeMatch bool `json:"status_code_match"`
+	BodyMatch       bool `json:"body_match"`
+	ServerMatch     bool `json:"server_match"`
+
+	// Differences are the lower-case names of the headers that are missing from one of the responses or have
+	// different values, except for those expected to differ between connections, such as Date.
+	Differences []string `json:"differences,omitempty"`
+}
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// HTTP1Response is the response to the request over HTTP/1.1 with TLS, and HTTP1TLSLog its TLS handshake.
+	// HTTP1Error is set if that request failed, which does not end the scan.
+	HTTP1Response *http.Response `json:"http1_response,omitempty"`
+	HTTP1TLSLog   *zgrab2.TLSLog `json:"http1_tls,omitempty"`
+	HTTP1Error    string         `json:"http1_error,omitempty"`
+
+	// AltSvc are the alternative services of the HTTP/1.1 response, and AltSvcH3 is true if one of them is
+	// HTTP/3.
+	AltSvc   []AltSvc `json:"alt_svc,omitempty"`
+	AltSvcH3 bool     `json:"alt_svc_h3"`
+
+	// Port is the UDP port of the QUIC connection.
+	Port uint16 `json:"port"`
+
+	ALPN                string                    `json:"alpn,omitempty"`
+	CipherSuite         string                    `json:"cipher_suite,omitempty"`
+	TransportParameters *quic.TransportParameters `json:"transport_parameters,omitempty"`
+
+	// Settings are the parameters of the server's SETTINGS frame, in the order it sent them.
+	Settings []Setting      `json:"settings,omitempty"`
+	QPACK    *QPACKSettings `json:"qpack,omitempty"`
+
+	// ServerStreams are the types of the unidirectional streams the server opened.
+	ServerStreams []string `json:"server_streams,omitempty"`
+
+	// GoAwayID is the stream ID of the server's GOAWAY frame, if it sent one.
+	GoAwayID *uint64 `json:"goaway_id,omitempty"`
+
+	Response *http.Response `json:"response,omitempty"`
+	Identity *Identity      `json:"identity,omitempty"`
+
+	// ConnectionClose is the CONNECTION_CLOSE frame the server sent, if any. Application errors are named after
+	// the HTTP/3 and QPACK error codes.
+	ConnectionClose *quic.ConnectionClose `json:"connection_close,omitempty"`
+}
+
+// Flags are the HTTP/3-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.TLSFlags
+	zgrab2.UDPFlags
+
+	Method       string `long:"method" default:"GET" description:"Set HTTP request method type"`
+	Endpoint     string `long:"endpoint" default:"/" description:"Send an HTTP request to an endpoint"`
+	UserAgent    string `long:"user-agent" default:"Mozilla/5.0 zgrab/0.x" description:"Set a custom user agent"`
+	MaxSize      int    `long:"max-size" default:"256" description:"Max kilobytes to read in response to an HTTP request"`
+	NoHTTP1      bool   `long:"no-http1" description:"Do not send the request over HTTP/1.1 first, and do not compare the responses"`
+	FollowAltSvc bool   `long:"follow-alt-svc" description:"Connect to the UDP port of the h3 alternative service the HTTP/1.1 response advertises for the same host, instead of the target's port"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the http3 zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("http3", "HTTP/3", module.Description(), 443, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Send an HTTP/3 request over QUIC, record the server's SETTINGS and compare the response against HTTP/1.1"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	if f.MaxSize <= 0 {
+		return fmt.Errorf("--max-size must be positive")
+	}
+	if f.NoHTTP1 && f.FollowAltSvc {
+		return fmt.Errorf("--follow-alt-svc needs the HTTP/1.1 response")
+	}
+	return f.UDPFlags.Validate()
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "http3"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		NeedSeparateL4Dialer:            true,
+		BaseFlags:                       &f.BaseFlags,
+		TLSEnabled:                      true,
+		TLSFlags:                        &f.TLSFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// authority returns the host and, unless it is the default, the port of the request URL.
+func authority(target *zgrab2.ScanTarget) string {
+	host := target.Domain
+	if host == "" {
+		host = target.Host()
+	}
+	if target.Port == 443 {
+		if strings.Contains(host, ":") {
+			return "[" + host + "]"
+		}
+		return host
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(target.Port)))
+}
+
+// newRequest returns the request the scan sends over both protocols.
+func (scanner *Scanner) newRequest(target *zgrab2.ScanTarget) (*http.Request, error) {
+	req, err := http.NewRequest(scanner.config.Method, "https://"+authority(target)+scanner.config.Endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", scanner.config.UserAgent)
+	req.Header.Set("Accept", "*/*")
+	req.Close = true
+	return req, nil
+}
+
+// setBody records the body of a response the way the http module does: as text if it is valid UTF-8 and base64
+// otherwise, along with its SHA-256 digest.
+func setBody(res *http.Response, body []byte) {
+	res.BodyText = string(body)
+	if len(body) > 0 {
+		digest := sha256.Sum256(body)
+		res.BodySHA256 = digest[:]
+	}
+	if !utf8.Valid(body) {
+		res.BodyText = base64.StdEncoding.EncodeToString(body)
+	}
+}
+
+// fetchHTTP1 sends the request over HTTP/1.1 with TLS, and returns the response with its raw body.
+func (scanner *Scanner) fetchHTTP1(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget, req *http.Request, results *ScanResults) (*http.Response, []byte, error) {
+	conn, err := dialGroup.L4Dialer(target)(ctx, "tcp", net.JoinHostPort(target.Host(), strconv.Itoa(int(target.Port))))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening connection: %w", err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+	tlsConn, err := dialGroup.TLSWrapper(ctx, target, conn)
+	if tlsConn != nil {
+		results.HTTP1TLSLog = tlsConn.GetLog()
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error performing TLS handshake: %w", err)
+	}
+	if err := req.Write(tlsConn); err != nil {
+		return nil, nil, fmt.Errorf("error sending request: %w", err)
+	}
+	res, err := http.ReadResponse(bufio.NewReader(tlsConn), req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading response: %w", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, int64(scanner.config.MaxSize)*1024))
+	if err != nil && len(body) == 0 {
+		return nil, nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	setBody(res, body)
+	return res, body, nil
+}
+
+// quicPort returns the UDP port to connect to: that of the first h3 alternative service on the same host with
+// --follow-alt-svc, and the target's port otherwise.
+func (scanner *Scanner) quicPort(target *zgrab2.ScanTarget, results *ScanResults) uint16 {
+	if scanner.config.FollowAltSvc {
+		for _, alt := range results.AltSvc {
+			if alt.isH3() && (alt.Host == "" || alt.Host == target.Domain) {
+				return alt.Port
+			}
+		}
+	}
+	return uint16(target.Port)
+}
+
+// tlsConfig returns the TLS configuration of the QUIC connection.
+func (scanner *Scanner) tlsConfig(target *zgrab2.ScanTarget) *tls.Config {
+	serverName := scanner.config.ServerName
+	if serverName == "" {
+		serverName = target.Domain
+	}
+	return &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS13,
+		NextProtos:         []string{"h3"},
+		// a single key share keeps the ClientHello within one Initial packet
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+		KeyLogWriter:     zgrab2.TLSKeyLogWriter(),
+	}
+}
+
+// requestFields returns the header fields of the HTTP/3 request.
+func requestFields(req *http.Request) []field {
+	fields := []field{
+		{":method", req.Method},
+		{":scheme", "https"},
+		{":authority", req.Host},
+		{":path", req.URL.RequestURI()},
+	}
+	for _, name := range []string{"User-Agent", "Accept"} {
+		fields = append(fields, field{strings.ToLower(name), req.Header.Get(name)})
+	}
+	return fields
+}
+
+// serverStreams decodes the unidirectional streams opened by the server, and returns their types and the control
+// stream, if it arrived.
+func serverStreams(c *quic.Conn) ([]string, *controlStream, error) {
+	var types []string
+	var control *controlStream
+	for _, id := range c.PeerStreams() {
+		// HTTP/3 servers only open unidirectional streams, the second bit of the stream ID
+		if id&0x02 == 0 {
+			continue
+		}
+		data, _ := c.Stream(id)
+		streamType, rest, ok := readVarInt(data)
+		if !ok {
+			continue
+		}
+		types = append(types, streamTypeName(streamType))
+		if streamType == streamControl && control == nil {
+			var err error
+			if control, err = parseControlStream(rest); err != nil {
+				return types, control, err
+			}
+		}
+	}
+	return types, control, nil
+}
+
+// newResponse converts a decoded HTTP/3 response.
+func newResponse(r *response, req *http.Request) (*http.Response, error) {
+	status := pseudoValue(r.headers, ":status")
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return nil, fmt.Errorf("malformed :status pseudo header %q", status)
+	}
+	res := &http.Response{
+		Status:        status + " " + http.StatusText(code),
+		StatusCode:    code,
+		Proto:         "HTTP/3.0",
+		ProtoMajor:    3,
+		Header:        make(http.Header),
+		Request:       req,
+		ContentLength: -1,
+	}
+	for _, f := range r.headers {
+		if !strings.HasPrefix(f.name, ":") {
+			res.Header.Add(f.name, f.value)
+		}
+	}
+	if r.trailers != nil {
+		res.Trailer = make(http.Header)
+		for _, f := range r.trailers {
+			res.Trailer.Add(f.name, f.value)
+		}
+	}
+	if cl, err := strconv.ParseInt(res.Header.Get("Content-Length"), 10, 63); err == nil {
+		res.ContentLength = cl
+	}
+	setBody(res, r.body)
+	return res, nil
+}
+
+// volatileHeaders are the headers that are expected to differ between two responses to the same request, or
+// between HTTP/1.1 and HTTP/3, which has no connection-specific headers.
+var volatileHeaders = []string{"age", "alt-svc", "connection", "date", "expires", "keep-alive", "set-cookie", "transfer-encoding"}
+
+// compare compares the HTTP/3 response against the HTTP/1.1 response.
+func compare(http1 *http.Response, body1 []byte, http3 *http.Response, body3 []byte) *Identity {
+	identity := &Identity{
+		StatusCodeMatch: http1.StatusCode == http3.StatusCode,
+		BodyMatch:       string(body1) == string(body3),
+		ServerMatch:     http1.Header.Get("Server") == http3.Header.Get("Server"),
+	}
+	names := make(map[string]bool)
+	for name := range http1.Header {
+		names[strings.ToLower(name)] = true
+	}
+	for name := range http3.Header {
+		names[strings.ToLower(name)] = true
+	}
+	for name := range names {
+		if slices.Contains(volatileHeaders, name) {
+			continue
+		}
+		if !slices.Equal(http1.Header.Values(name), http3.Header.Values(name)) {
+			identity.Differences = append(identity.Differences, name)
+		}
+	}
+	slices.Sort(identity.Differences)
+	return identity
+}
+
+// closeStatus records a CONNECTION_CLOSE from the server and returns the status of the scan it ended.
+func closeStatus(closeErr *quic.CloseError, results *ScanResults) (zgrab2.ScanStatus, error) {
+	results.ConnectionClose = closeErr.Close
+	if closeErr.Close.Application {
+		closeErr.Close.ErrorName = errorNames[closeErr.Close.ErrorCode]
+		return zgrab2.SCAN_APPLICATION_ERROR, zgrab2.NewScanError(zgrab2.SCAN_APPLICATION_ERROR, closeErr)
+	}
+	return zgrab2.SCAN_PROTOCOL_ERROR, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, closeErr)
+}
+
+// Scan performs the configured scan on the HTTP/3 server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	if dialGroup.L4Dialer == nil || dialGroup.TLSWrapper == nil {
+		return zgrab2.SCAN_UNKNOWN_ERROR, nil, errors.New("dial group must have an L4 dialer and a TLS wrapper")
+	}
+	req, err := scanner.newRequest(target)
+	if err != nil {
+		return zgrab2.SCAN_UNKNOWN_ERROR, nil, fmt.Errorf("could not build request for target %s: %w", target.String(), err)
+	}
+
+	results := new(ScanResults)
+	var body1 []byte
+	if !scanner.config.NoHTTP1 {
+		results.HTTP1Response, body1, err = scanner.fetchHTTP1(ctx, dialGroup, target, req, results)
+		if err != nil {
+			log.Debugf("HTTP/1.1 request to target %s failed: %v", target.String(), err)
+			results.HTTP1Error = err.Error()
+		} else {
+			results.AltSvc = parseAltSvc(results.HTTP1Response.Header.Values("Alt-Svc"))
+			results.AltSvcH3 = slices.ContainsFunc(results.AltSvc, func(alt AltSvc) bool { return alt.isH3() })
+		}
+	}
+
+	results.Port = scanner.quicPort(target, results)
+	conn, err := dialGroup.L4Dialer(target)(ctx, "udp", net.JoinHostPort(target.Host(), strconv.Itoa(int(results.Port))))
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	c, err := quic.Dial(ctx, conn, scanner.tlsConfig(target), scanner.config.TryTimeout, scanner.config.Retries)
+	var closeErr *quic.CloseError
+	if errors.As(err, &closeErr) {
+		status, err := closeStatus(closeErr, results)
+		return status, results, err
+	}
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error performing QUIC handshake with target %s: %w", target.String(), err)
+	}
+	defer c.Close()
+	state := c.ConnectionState()
+	results.ALPN = state.NegotiatedProtocol
+	results.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	if results.TransportParameters, err = c.TransportParameters(); err != nil {
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+	}
+
+	control := c.OpenStream(false)
+	c.Write(control, buildControlStream(), false)
+	requestStream := c.OpenStream(true)
+	c.Write(requestStream, appendFrame(nil, frameHeaders, encodeFieldSection(requestFields(req))), true)
+
+	// the exchange ends once the response is complete and the server's SETTINGS have arrived
+	maxBody := scanner.config.MaxSize * 1024
+	var resp *response
+	var serverControl *controlStream
+	var parseErr error
+	update := func() bool {
+		data, done := c.Stream(requestStream)
+		if resp, parseErr = parseResponse(data, done, maxBody); parseErr != nil {
+			return true
+		}
+		if results.ServerStreams, serverControl, parseErr = serverStreams(c); parseErr != nil {
+			return true
+		}
+		return resp.complete && serverControl != nil && serverControl.seenSettings
+	}
+	runErr := c.Run(ctx, update)
+	update()
+
+	if serverControl != nil {
+		results.Settings = serverControl.settings
+		results.GoAwayID = serverControl.goAway
+		if serverControl.seenSettings {
+			results.QPACK = new(QPACKSettings)
+			for _, s := range serverControl.settings {
+				switch s.ID {
+				case settingQPACKMaxTableCapacity:
+					results.QPACK.MaxTableCapacity = s.Value
+				case settingQPACKBlockedStreams:
+					results.QPACK.BlockedStreams = s.Value
+				}
+			}
+		}
+	}
+	if parseErr != nil {
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, parseErr)
+	}
+	if resp.headers != nil {
+		if results.Response, err = newResponse(resp, req); err != nil {
+			return zgrab2.SCAN_PROTOCOL_ERROR, results, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+		}
+		if results.HTTP1Response != nil {
+			results.Identity = compare(results.HTTP1Response, body1, results.Response, resp.body)
+		}
+	}
+	if errors.As(runErr, &closeErr) {
+		status, err := closeStatus(closeErr, results)
+		return status, results, err
+	}
+	switch {
+	case resp.complete && resp.headers == nil:
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, errors.New("server ended the request stream without a response"))
+	case !resp.complete && runErr != nil:
+		return zgrab2.TryGetScanStatus(runErr), results, fmt.Errorf("error reading HTTP/3 response from target %s: %w", target.String(), runErr)
+	}
+	// a complete response is a success even if the server never sent its SETTINGS
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
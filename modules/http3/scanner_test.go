@@ -0,0 +1,77 @@
+package http3
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/zmap/zgrab2/lib/http"
+)
+
+func TestParseAltSvc(t *testing.T) {
+	entries := parseAltSvc([]string{
+		`h3=":443"; ma=86400, h3-29=":8443"; ma="3600"; persist=1`,
+		`h2="alt.example.com:443", h3="[2001:db8::1]:443", clear, w%3Dx=":80"`,
+	})
+	day, hour := uint64(86400), uint64(3600)
+	want := []AltSvc{
+		{Protocol: "h3", Port: 443, MaxAge: &day},
+		{Protocol: "h3-29", Port: 8443, MaxAge: &hour},
+		{Protocol: "h2", Host: "alt.example.com", Port: 443},
+		{Protocol: "h3", Host: "[2001:db8::1]", Port: 443},
+		{Protocol: "w=x", Port: 80},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i := range want {
+		got := entries[i]
+		if got.Protocol != want[i].Protocol || got.Host != want[i].Host || got.Port != want[i].Port ||
+			(got.MaxAge == nil) != (want[i].MaxAge == nil) || (got.MaxAge != nil && *got.MaxAge != *want[i].MaxAge) {
+			t.Errorf("entry %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+	if !entries[1].isH3() || entries[2].isH3() {
+		t.Error("isH3 misclassifies h3-29 or h2")
+	}
+}
+
+func TestNewResponseAndCompare(t *testing.T) {
+	r := &response{
+		headers: []field{
+			{":status", "200"},
+			{"server", "nginx"},
+			{"content-length", "5"},
+			{"date", "Thu, 15 Oct 2026 00:00:00 GMT"},
+			{"x-h3-only", "1"},
+		},
+		body:     []byte("hello"),
+		trailers: []field{{"x-checksum", "abc"}},
+	}
+	res, err := newResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 200 || res.Status != "200 OK" || res.Proto != "HTTP/3.0" || res.ContentLength != 5 {
+		t.Errorf("unexpected response %+v", res)
+	}
+	if res.BodyText != "hello" || len(res.BodySHA256) == 0 || res.Trailer.Get("X-Checksum") != "abc" {
+		t.Errorf("unexpected body or trailers %+v", res)
+	}
+
+	http1 := &http.Response{StatusCode: 200, Header: make(http.Header)}
+	http1.Header.Set("Server", "nginx")
+	http1.Header.Set("Content-Length", "5")
+	http1.Header.Set("Date", "Thu, 15 Oct 2026 00:00:01 GMT")
+	http1.Header.Set("Connection", "close")
+	identity := compare(http1, []byte("hello"), res, r.body)
+	if !identity.StatusCodeMatch || !identity.BodyMatch || !identity.ServerMatch {
+		t.Errorf("identity = %+v, want all matches", identity)
+	}
+	if want := []string{"x-h3-only"}; !slices.Equal(identity.Differences, want) {
+		t.Errorf("differences = %v, want %v", identity.Differences, want)
+	}
+
+	if _, err := newResponse(&response{headers: []field{{"server", "nginx"}}}, nil); err == nil {
+		t.Error("response without :status accepted")
+	}
+}
@@ -0,0 +1,227 @@
+// Package httpapi contains the code shared by modules that query JSON HTTP APIs, such as the Elasticsearch, Docker
+// and Kubernetes modules. It issues plain HTTP/1.1 GET requests over the module's DialerGroup, one connection per
+// request, and records a summary of each response.
+package httpapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/zmap/zgrab2"
+	"github.com/zmap/zgrab2/lib/http"
+)
+
+// Flags are the HTTP options shared by the HTTP API modules. Modules embed them alongside BaseFlags and TLSFlags.
+type Flags struct {
+	UseHTTPS  bool   `long:"use-https" description:"Connect over HTTPS"`
+	UserAgent string `long:"user-agent" default:"Mozilla/5.0 zgrab/0.x" description:"Set a custom user agent"`
+	MaxSize   int    `long:"max-size" default:"256" description:"Max kilobytes to read of each response body"`
+}
+
+// Response summarizes the response to a single API request.
+type Response struct {
+	Path            string `json:"path"`
+	StatusCode      int    `json:"status_code"`
+	Status          string `json:"status,omitempty"`
+	ContentType     string `json:"content_type,omitempty"`
+	Server          string `json:"server,omitempty"`
+	WWWAuthenticate string `json:"www_authenticate,omitempty"`
+	Body            string `json:"body,omitempty"`
+
+	header http.Header
+}
+
+// Header returns the first value of the named response header.
+func (r *Response) Header(name string) string {
+	return r.header.Get(name)
+}
+
+// Values returns all values of the named response header.
+func (r *Response) Values(name string) []string {
+	return r.header.Values(name)
+}
+
+// Success returns true for 2xx responses.
+func (r *Response) Success() bool {
+	return r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// Unauthorized returns true if the server required authentication (401) or refused access (403).
+func (r *Response) Unauthorized() bool {
+	return r.StatusCode == http.StatusUnauthorized || r.StatusCode == http.StatusForbidden
+}
+
+// Client sends requests to a single target.
+type Client struct {
+	ctx       context.Context
+	dialGroup *zgrab2.DialerGroup
+	target    *zgrab2.ScanTarget
+	flags     *Flags
+
+	// TLSLog is the handshake log of the first connection, if the DialerGroup uses TLS.
+	TLSLog *zgrab2.TLSLog
+
+	// Header holds additional headers sent with each request.
+	Header http.Header
+}
+
+// NewClient returns a Client for the target.
+func NewClient(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget, flags *Flags) *Client {
+	return &Client{ctx: ctx, dialGroup: dialGroup, target: target, flags: flags}
+}
+
+// NewDialerGroupConfig returns the DialerGroupConfig HTTP API modules use. TLS is set up by the Client itself so
+// that the handshake log is kept even if the handshake fails, e.g. because the server requires a client certificate.
+func NewDialerGroupConfig(baseFlags *zgrab2.BaseFlags, tlsFlags *zgrab2.TLSFlags) *zgrab2.DialerGroupConfig {
+	return &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		NeedSeparateL4Dialer:            true,
+		BaseFlags:                       baseFlags,
+		TLSEnabled:                      true,
+		TLSFlags:                        tlsFlags,
+	}
+}
+
+// dial opens a connection to the target, upgrading it to TLS if --use-https is set.
+func (c *Client) dial() (net.Conn, error) {
+	if c.dialGroup.L4Dialer == nil {
+		return nil, errors.New("l4 dialer is required for HTTP API modules")
+	}
+	conn, err := c.dialGroup.L4Dialer(c.target)(c.ctx, "tcp", net.JoinHostPort(c.target.Host(), strconv.Itoa(int(c.target.Port))))
+	if err != nil {
+		return nil, fmt.Errorf("error opening connection to target %s: %w", c.target.String(), err)
+	}
+	if !c.flags.UseHTTPS {
+		return conn, nil
+	}
+	tlsConn, err := c.dialGroup.TLSWrapper(c.ctx, c.target, conn)
+	if tlsConn != nil && c.TLSLog == nil {
+		c.TLSLog = tlsConn.GetLog()
+	}
+	if err != nil {
+		zgrab2.CloseConnAndHandleError(conn)
+		return nil, fmt.Errorf("error performing TLS handshake with target %s: %w", c.target.String(), err)
+	}
+	return tlsConn, nil
+}
+
+// hostHeader returns the value of the Host header for the target.
+func (c *Client) hostHeader() string {
+	host := c.target.Domain
+	if host == "" {
+		host = c.target.Host()
+	}
+	defaultPort := uint(80)
+	if c.flags.UseHTTPS {
+		defaultPort = 443
+	}
+	if c.target.Port == 0 || c.target.Port == defaultPort {
+		if strings.Contains(host, ":") {
+			// an IPv6 literal
+			return "[" + host + "]"
+		}
+		return host
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(c.target.Port)))
+}
+
+// Get requests path and returns the response. The body is truncated to the configured maximum size.
+func (c *Client) Get(path string) (*Response, error) {
+	return c.do("GET", path, "", nil)
+}
+
+// Post sends body to path and returns the response. The body is truncated to the configured maximum size.
+func (c *Client) Post(path string, contentType string, body []byte) (*Response, error) {
+	return c.do("POST", path, contentType, body)
+}
+
+// do sends a single request on a new connection.
+func (c *Client) do(method string, path string, contentType string, requestBody []byte) (*Response, error) {
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	request := method + " " + path + " HTTP/1.1\r\n" +
+		"Host: " + c.hostHeader() + "\r\n" +
+		"User-Agent: " + c.flags.UserAgent + "\r\n" +
+		"Accept: application/json, */*\r\n" +
+		"Connection: close\r\n"
+	if requestBody != nil {
+		request += "Content-Type: " + contentType + "\r\n" +
+			"Content-Length: " + strconv.Itoa(len(requestBody)) + "\r\n"
+	}
+	if c.Header != nil {
+		var extra strings.Builder
+		_ = c.Header.Write(&extra)
+		request += extra.String()
+	}
+	if _, err = conn.Write(append([]byte(request+"\r\n"), requestBody...)); err != nil {
+		return nil, fmt.Errorf("error sending request for %s to target %s: %w", path, c.target.String(), err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: method})
+	if err != nil {
+		return nil, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, fmt.Errorf("error reading response for %s from target %s: %w", path, c.target.String(), err))
+	}
+	defer resp.Body.Close()
+
+	maxSize := int64(c.flags.MaxSize) * 1024
+	if maxSize <= 0 {
+		maxSize = 256 * 1024
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize))
+	if err != nil && len(body) == 0 {
+		return nil, fmt.Errorf("error reading body for %s from target %s: %w", path, c.target.String(), err)
+	}
+	return &Response{
+		Path:            path,
+		StatusCode:      resp.StatusCode,
+		Status:          resp.Status,
+		ContentType:     resp.Header.Get("Content-Type"),
+		Server:          resp.Header.Get("Server"),
+		WWWAuthenticate: resp.Header.Get("Www-Authenticate"),
+		Body:            string(body),
+		header:          resp.Header,
+	}, nil
+}
+
+// GetJSON requests path and, if the response is successful, decodes its JSON body into v. A non-JSON body of a
+// successful response is reported as a SCAN_PROTOCOL_ERROR.
+func (c *Client) GetJSON(path string, v any) (*Response, error) {
+	resp, err := c.Get(path)
+	return resp, c.decode(resp, err, v)
+}
+
+// PostJSON sends request as JSON to path and decodes a successful response into v, like GetJSON.
+func (c *Client) PostJSON(path string, request any, v any) (*Response, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Post(path, "application/json", body)
+	return resp, c.decode(resp, err, v)
+}
+
+// decode unmarshals the body of a successful response into v.
+func (c *Client) decode(resp *Response, err error, v any) error {
+	if err != nil || !resp.Success() {
+		return err
+	}
+	if err = json.Unmarshal([]byte(resp.Body), v); err != nil {
+		return zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, fmt.Errorf("invalid JSON in response for %s from target %s: %w", resp.Path, c.target.String(), err))
+	}
+	return nil
+}
+
+// LooksLikeJSON returns true if the content type of the response is JSON.
+func (r *Response) LooksLikeJSON() bool {
+	return strings.Contains(r.ContentType, "json")
+}
@@ -0,0 +1,123 @@
+package httpapi
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zmap/zgrab2"
+	"github.com/zmap/zgrab2/lib/http"
+)
+
+func TestGetJSON(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	requests := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 1024)
+		n, _ := conn.Read(buf)
+		requests <- string(buf[:n])
+		_, _ = conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nServer: test\r\nContent-Length: 15\r\n\r\n{\"version\":\"1\"}"))
+	}()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	target := &zgrab2.ScanTarget{IP: net.ParseIP("127.0.0.1"), Domain: "api.example", Port: uint(port)}
+	dialGroup := &zgrab2.DialerGroup{
+		L4Dialer: func(*zgrab2.ScanTarget) func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return (&net.Dialer{Timeout: time.Second}).DialContext
+		},
+	}
+	client := NewClient(context.Background(), dialGroup, target, &Flags{UserAgent: "test-agent"})
+	client.Header = http.Header{"X-Test": {"1"}}
+
+	var body struct {
+		Version string `json:"version"`
+	}
+	resp, err := client.GetJSON("/version", &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Success() || resp.Server != "test" || resp.Header("content-type") != "application/json" || !resp.LooksLikeJSON() || body.Version != "1" {
+		t.Errorf("unexpected response %+v, body %+v", resp, body)
+	}
+	request := <-requests
+	want := "GET /version HTTP/1.1\r\nHost: api.example:" + strconv.Itoa(port) + "\r\nUser-Agent: test-agent\r\n"
+	if len(request) < len(want) || request[:len(want)] != want || !strings.Contains(request, "\r\nX-Test: 1\r\n") {
+		t.Errorf("unexpected request %q", request)
+	}
+}
+
+func TestPostJSON(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	requests := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 1024)
+		n, _ := conn.Read(buf)
+		requests <- string(buf[:n])
+		_, _ = conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: 13\r\n\r\n{\"count\":\"3\"}"))
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	dialGroup := &zgrab2.DialerGroup{
+		L4Dialer: func(*zgrab2.ScanTarget) func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return (&net.Dialer{Timeout: time.Second}).DialContext
+		},
+	}
+	client := NewClient(context.Background(), dialGroup, &zgrab2.ScanTarget{IP: addr.IP, Port: uint(addr.Port)}, &Flags{UserAgent: "test-agent"})
+
+	var body struct {
+		Count string `json:"count"`
+	}
+	resp, err := client.PostJSON("/v3/kv/range", map[string]bool{"count_only": true}, &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Success() || body.Count != "3" {
+		t.Errorf("unexpected response %+v, body %+v", resp, body)
+	}
+	request := <-requests
+	if !strings.HasPrefix(request, "POST /v3/kv/range HTTP/1.1\r\n") || !strings.Contains(request, "\r\nContent-Type: application/json\r\nContent-Length: 19\r\n") ||
+		!strings.HasSuffix(request, "\r\n\r\n{\"count_only\":true}") {
+		t.Errorf("unexpected request %q", request)
+	}
+}
+
+func TestHostHeader(t *testing.T) {
+	for _, test := range []struct {
+		target   zgrab2.ScanTarget
+		useHTTPS bool
+		expected string
+	}{
+		{zgrab2.ScanTarget{IP: net.ParseIP("192.0.2.1"), Port: 80}, false, "192.0.2.1"},
+		{zgrab2.ScanTarget{IP: net.ParseIP("192.0.2.1"), Port: 9200}, false, "192.0.2.1:9200"},
+		{zgrab2.ScanTarget{IP: net.ParseIP("192.0.2.1"), Domain: "api.example", Port: 443}, true, "api.example"},
+		{zgrab2.ScanTarget{IP: net.ParseIP("2001:db8::1"), Port: 80}, false, "[2001:db8::1]"},
+		{zgrab2.ScanTarget{IP: net.ParseIP("2001:db8::1"), Port: 443}, true, "[2001:db8::1]"},
+		{zgrab2.ScanTarget{IP: net.ParseIP("2001:db8::1"), Port: 9200}, false, "[2001:db8::1]:9200"},
+	} {
+		client := NewClient(context.Background(), nil, &test.target, &Flags{UseHTTPS: test.useHTTPS})
+		if host := client.hostHeader(); host != test.expected {
+			t.Errorf("%s: Host = %q, expected %q", test.target.String(), host, test.expected)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// Package httpapitest runs HTTP API module scanners against fake servers in tests.
+package httpapitest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zmap/zgrab2"
+)
+
+// Scan runs scanner against a plaintext HTTP server with the given handler and returns its results as a *T.
+func Scan[T any](t *testing.T, scanner zgrab2.Scanner, handler http.Handler) (zgrab2.ScanStatus, *T, error) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	addr := server.Listener.Addr().(*net.TCPAddr)
+	dialGroup := &zgrab2.DialerGroup{
+		L4Dialer: func(*zgrab2.ScanTarget) func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return (&net.Dialer{Timeout: time.Second}).DialContext
+		},
+	}
+	status, res, err := scanner.Scan(context.Background(), dialGroup, &zgrab2.ScanTarget{IP: addr.IP, Port: uint(addr.Port)})
+	results, _ := res.(*T)
+	return status, results, err
+}
+
+// JSONHandler answers every request with the given status code and JSON body.
+func JSONHandler(code int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		w.Write([]byte(body))
+	}
+}
@@ -0,0 +1,7 @@
+package modules
+
+import "github.com/zmap/zgrab2/modules/iec104"
+
+func init() {
+	iec104.RegisterModule()
+}
@@ -0,0 +1,230 @@
+package iec104
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// startByte opens every APDU.
+const startByte = 0x68
+
+// U-format functions of the first control octet (IEC 60870-5-104 section 5.3).
+const (
+	uStartDTAct = 0x07
+	uStartDTCon = 0x0B
+	uStopDTAct  = 0x13
+	uStopDTCon  = 0x23
+	uTestFRAct  = 0x43
+	uTestFRCon  = 0x83
+)
+
+// Frame formats, from the low bits of the first control octet.
+const (
+	formatI = iota
+	formatS
+	formatU
+)
+
+// ASDU type identifications and causes of transmission used by the scan.
+const (
+	typeInterrogation = 100
+
+	causeActivation       = 6
+	causeActivationCon    = 7
+	causeActivationTerm   = 10
+	causeStationInterrog  = 20
+	causeUnknownCommonAdr = 46
+
+	// qualifierStation is the qualifier of interrogation for a station interrogation.
+	qualifierStation = 20
+
+	// causeNegative is the P/N bit of the cause of transmission octet, and causeTest its T bit.
+	causeNegative = 0x40
+	causeTest     = 0x80
+)
+
+// asduHeaderLength is the length of the data unit identifier with the default field sizes: type, variable
+// structure qualifier, two octets of cause and two of common address.
+const asduHeaderLength = 6
+
+var errInvalidAPDU = errors.New("invalid IEC 104 APDU")
+
+// apdu is a received frame.
+type apdu struct {
+	format int
+	// function is the U-format function.
+	function byte
+	sendSeq  uint16
+	recvSeq  uint16
+	asdu     []byte
+}
+
+// uFrame builds a U-format frame with the given function.
+func uFrame(function byte) []byte {
+	return []byte{startByte, 4, function, 0, 0, 0}
+}
+
+// sFrame builds an S-format frame acknowledging the I-format frames up to recvSeq.
+func sFrame(recvSeq uint16) []byte {
+	return []byte{startByte, 4, 0x01, 0, byte(recvSeq << 1), byte(recvSeq >> 7)}
+}
+
+// iFrame builds an I-format frame carrying the ASDU.
+func iFrame(sendSeq, recvSeq uint16, asdu []byte) []byte {
+	b := []byte{startByte, byte(4 + len(asdu)), byte(sendSeq << 1), byte(sendSeq >> 7), byte(recvSeq << 1), byte(recvSeq >> 7)}
+	return append(b, asdu...)
+}
+
+// interrogationCommand builds a station interrogation (C_IC_NA_1) for the common address.
+func interrogationCommand(commonAddress uint16) []byte {
+	return []byte{
+		typeInterrogation, 1, causeActivation, 0,
+		byte(commonAddress), byte(commonAddress >> 8),
+		0, 0, 0,
+		qualifierStation,
+	}
+}
+
+// readAPDU reads a frame.
+func readAPDU(r io.Reader) (*apdu, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	if header[0] != startByte {
+		return nil, fmt.Errorf("%w: start byte %#02x", errInvalidAPDU, header[0])
+	}
+	if header[1] < 4 {
+		return nil, fmt.Errorf("%w: length %d", errInvalidAPDU, header[1])
+	}
+	body := make([]byte, header[1])
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	a := new(apdu)
+	switch {
+	case body[0]&1 == 0:
+		a.format = formatI
+		a.sendSeq = uint16(body[0])>>1 | uint16(body[1])<<7
+		a.recvSeq = uint16(body[2])>>1 | uint16(body[3])<<7
+		a.asdu = body[4:]
+	case body[0]&3 == 1:
+		a.format = formatS
+		a.recvSeq = uint16(body[2])>>1 | uint16(body[3])<<7
+	default:
+		a.format = formatU
+		a.function = body[0]
+	}
+	if a.format != formatI && len(body) != 4 {
+		return nil, fmt.Errorf("%w: control frame of length %d", errInvalidAPDU, len(body))
+	}
+	return a, nil
+}
+
+// asdu is the data unit identifier of a received ASDU.
+type asdu struct {
+	typeID        byte
+	objects       int
+	sequence      bool
+	cause         byte
+	negative      bool
+	test          bool
+	originator    byte
+	commonAddress uint16
+}
+
+// parseASDU decodes the data unit identifier of an ASDU.
+func parseASDU(b []byte) (*asdu, error) {
+	if len(b) < asduHeaderLength {
+		return nil, fmt.Errorf("%w: ASDU of %d bytes", errInvalidAPDU, len(b))
+	}
+	return &asdu{
+		typeID:        b[0],
+		objects:       int(b[1] & 0x7F),
+		sequence:      b[1]&0x80 != 0,
+		cause:         b[2] & 0x3F,
+		negative:      b[2]&causeNegative != 0,
+		test:          b[2]&causeTest != 0,
+		originator:    b[3],
+		commonAddress: uint16(b[4]) | uint16(b[5])<<8,
+	}, nil
+}
+
+// typeNames names the common type identifications (IEC 60870-5-101 section 7.2.1.1).
+var typeNames = map[byte]string{
+	1:   "M_SP_NA_1",
+	3:   "M_DP_NA_1",
+	5:   "M_ST_NA_1",
+	7:   "M_BO_NA_1",
+	9:   "M_ME_NA_1",
+	11:  "M_ME_NB_1",
+	13:  "M_ME_NC_1",
+	15:  "M_IT_NA_1",
+	20:  "M_PS_NA_1",
+	21:  "M_ME_ND_1",
+	30:  "M_SP_TB_1",
+	31:  "M_DP_TB_1",
+	32:  "M_ST_TB_1",
+	33:  "M_BO_TB_1",
+	34:  "M_ME_TD_1",
+	35:  "M_ME_TE_1",
+	36:  "M_ME_TF_1",
+	37:  "M_IT_TB_1",
+	45:  "C_SC_NA_1",
+	46:  "C_DC_NA_1",
+	47:  "C_RC_NA_1",
+	48:  "C_SE_NA_1",
+	49:  "C_SE_NB_1",
+	50:  "C_SE_NC_1",
+	51:  "C_BO_NA_1",
+	58:  "C_SC_TA_1",
+	59:  "C_DC_TA_1",
+	70:  "M_EI_NA_1",
+	100: "C_IC_NA_1",
+	101: "C_CI_NA_1",
+	102: "C_RD_NA_1",
+	103: "C_CS_NA_1",
+	105: "C_RP_NA_1",
+	107: "C_TS_TA_1",
+}
+
+func typeName(typeID byte) string {
+	if name, ok := typeNames[typeID]; ok {
+		return name
+	}
+	return "type_" + strconv.Itoa(int(typeID))
+}
+
+// causeNames names the causes of transmission (IEC 60870-5-101 section 7.2.3).
+var causeNames = map[byte]string{
+	1:  "periodic",
+	2:  "background scan",
+	3:  "spontaneous",
+	4:  "initialized",
+	5:  "request",
+	6:  "activation",
+	7:  "activation confirmation",
+	8:  "deactivation",
+	9:  "deactivation confirmation",
+	10: "activation termination",
+	11: "remote command",
+	12: "local command",
+	13: "file transfer",
+	20: "interrogated by station",
+	44: "unknown type identification",
+	45: "unknown cause of transmission",
+	46: "unknown common address",
+	47: "unknown information object address",
+}
+
+func causeName(cause byte) string {
+	if name, ok := causeNames[cause]; ok {
+		return name
+	}
+	if cause > causeStationInterrog && cause <= causeStationInterrog+16 {
+		return "interrogated by group " + strconv.Itoa(int(cause-causeStationInterrog))
+	}
+	return "cause_" + strconv.Itoa(int(cause))
+}
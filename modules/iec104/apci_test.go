@@ -0,0 +1,121 @@
+package iec104
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestFrames(t *testing.T) {
+	if b := uFrame(uStartDTAct); !bytes.Equal(b, []byte{0x68, 4, 0x07, 0, 0, 0}) {
+		t.Errorf("got STARTDT %x", b)
+	}
+	b := iFrame(200, 3, interrogationCommand(0xFFFF))
+	expected := []byte{0x68, 14, 0x90, 0x01, 0x06, 0x00, 100, 1, 6, 0, 0xFF, 0xFF, 0, 0, 0, 20}
+	if !bytes.Equal(b, expected) {
+		t.Errorf("got interrogation %x", b)
+	}
+	a, err := readAPDU(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.format != formatI || a.sendSeq != 200 || a.recvSeq != 3 || len(a.asdu) != 10 {
+		t.Errorf("got %+v", a)
+	}
+	a, err = readAPDU(bytes.NewReader(sFrame(300)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.format != formatS || a.recvSeq != 300 {
+		t.Errorf("got %+v", a)
+	}
+	if _, err := readAPDU(bytes.NewReader([]byte("HTTP/1.1 400"))); !errors.Is(err, errInvalidAPDU) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestParseASDU(t *testing.T) {
+	unit, err := parseASDU([]byte{100, 1, 0x47, 3, 0x34, 0x12, 0, 0, 0, 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := asdu{typeID: 100, objects: 1, cause: 7, negative: true, originator: 3, commonAddress: 0x1234}
+	if *unit != expected {
+		t.Errorf("got %+v", unit)
+	}
+	if name := causeName(22); name != "interrogated by group 2" {
+		t.Errorf("got %s", name)
+	}
+	if name := typeName(200); name != "type_200" {
+		t.Errorf("got %s", name)
+	}
+}
+
+// station answers TESTFR and STARTDT, sends a test frame of its own, and answers the interrogation from
+// common address 7 with two single points. Its frames are written from another goroutine, as net.Pipe does
+// not buffer and the client writes while the station does.
+func station(conn net.Conn) {
+	defer conn.Close()
+	frames := make(chan []byte, 16)
+	defer close(frames)
+	go func() {
+		for frame := range frames {
+			conn.Write(frame)
+		}
+	}()
+	var sendSeq uint16
+	send := func(asdu []byte) {
+		frames <- iFrame(sendSeq, 1, asdu)
+		sendSeq++
+	}
+	for {
+		a, err := readAPDU(conn)
+		if err != nil {
+			return
+		}
+		switch {
+		case a.format == formatU && a.function == uTestFRAct:
+			frames <- uFrame(uTestFRCon)
+		case a.format == formatU && a.function == uStartDTAct:
+			frames <- uFrame(uTestFRAct)
+			frames <- uFrame(uStartDTCon)
+		case a.format == formatI:
+			send([]byte{100, 1, causeActivationCon, 0, 7, 0, 0, 0, 0, 20})
+			send([]byte{1, 0x82, causeStationInterrog, 0, 7, 0, 1, 0, 0, 1, 0})
+			send([]byte{100, 1, causeActivationTerm, 0, 7, 0, 0, 0, 0, 20})
+		}
+	}
+}
+
+func TestInterrogation(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go station(server)
+	c := &connection{conn: client}
+	for _, frame := range [][2]byte{{uTestFRAct, uTestFRCon}, {uStartDTAct, uStartDTCon}} {
+		confirmed, err := c.control(frame[0], frame[1])
+		if err != nil || !confirmed {
+			t.Fatalf("got %v, %v", confirmed, err)
+		}
+	}
+	result, err := c.interrogate(0xFFFF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Interrogation{
+		CommonAddress:      0xFFFF,
+		Confirmation:       "activation confirmation",
+		Terminated:         true,
+		CommonAddresses:    []uint16{7},
+		ASDUTypes:          []string{"C_IC_NA_1=2", "M_SP_NA_1=1"},
+		InformationObjects: 2,
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("got %+v", result)
+	}
+	if c.recvSeq != 3 || c.sendSeq != 1 {
+		t.Errorf("got sequence numbers %d/%d", c.sendSeq, c.recvSeq)
+	}
+}
@@ -0,0 +1,310 @@
+// Package iec104 contains the zgrab2 Module implementation for IEC 60870-5-104.
+//
+// The scan sends a TESTFR test frame, which a station answers even while another master holds the data transfer,
+// and then STARTDT to start data transfer. Unless disabled, it then sends a station interrogation to the
+// configured common address (the broadcast address by default) and records how the station answers: the
+// confirmation, the common addresses of the ASDUs it returns, and their types. The scan stops data transfer
+// before closing the connection.
+package iec104
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// maxFrames bounds the frames read while waiting for a confirmation or the end of the interrogation, and
+// ackWindow is the number of I-format frames acknowledged at once (the default w of the standard).
+const (
+	maxFrames = 256
+	ackWindow = 8
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// TestFrameConfirmed and StartDTConfirmed are true if the station confirmed TESTFR and STARTDT.
+	TestFrameConfirmed bool `json:"testfr_confirmed"`
+	StartDTConfirmed   bool `json:"startdt_confirmed"`
+
+	Interrogation *Interrogation `json:"interrogation,omitempty"`
+}
+
+// Interrogation is the station's answer to a station interrogation.
+type Interrogation struct {
+	// CommonAddress is the address the interrogation was sent to.
+	CommonAddress uint16 `json:"common_address"`
+
+	// Confirmation names the cause of transmission of the station's confirmation, Negative is its P/N bit, and
+	// Terminated is true if the station signalled the end of the interrogation.
+	Confirmation string `json:"confirmation,omitempty"`
+	Negative     bool   `json:"negative"`
+	Terminated   bool   `json:"terminated"`
+
+	// CommonAddresses are the distinct common addresses of the ASDUs the station sent.
+	CommonAddresses []uint16 `json:"common_addresses,omitempty"`
+
+	// ASDUTypes are the type identifications of the ASDUs the station sent, as name=count.
+	ASDUTypes []string `json:"asdu_types,omitempty"`
+
+	// InformationObjects counts the information objects returned in answer to the interrogation.
+	InformationObjects int `json:"information_objects"`
+}
+
+// Flags are the IEC 104-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+
+	CommonAddress   uint `long:"common-address" default:"65535" description:"Common address of the ASDU to interrogate, 65535 is the broadcast address"`
+	NoInterrogation bool `long:"no-interrogation" description:"Only send TESTFR and STARTDT, without a station interrogation"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the iec104 zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("iec104", "IEC 60870-5-104", module.Description(), 2404, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Probe for IEC 60870-5-104 stations with TESTFR and STARTDT and record their answer to a station interrogation"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	if f.CommonAddress > 0xFFFF {
+		return fmt.Errorf("common address %d is out of range", f.CommonAddress)
+	}
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "iec104"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// connection is an IEC 104 connection, tracking the sequence numbers of I-format frames.
+type connection struct {
+	conn    net.Conn
+	sendSeq uint16
+	recvSeq uint16
+	unacked int
+}
+
+// next reads the next frame, confirming test frames from the station and acknowledging its I-format frames.
+func (c *connection) next() (*apdu, error) {
+	a, err := readAPDU(c.conn)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case a.format == formatU && a.function == uTestFRAct:
+		if _, err := c.conn.Write(uFrame(uTestFRCon)); err != nil {
+			return nil, err
+		}
+	case a.format == formatI:
+		c.recvSeq = (a.sendSeq + 1) & 0x7FFF
+		if c.unacked++; c.unacked >= ackWindow {
+			if _, err := c.conn.Write(sFrame(c.recvSeq)); err != nil {
+				return nil, err
+			}
+			c.unacked = 0
+		}
+	}
+	return a, nil
+}
+
+// control sends a U-format frame and waits for its confirmation, returning false if the station sent another
+// U-format frame in its place.
+func (c *connection) control(act, con byte) (bool, error) {
+	if _, err := c.conn.Write(uFrame(act)); err != nil {
+		return false, err
+	}
+	for i := 0; i < maxFrames; i++ {
+		a, err := c.next()
+		if err != nil {
+			return false, err
+		}
+		if a.format != formatU || a.function == uTestFRAct {
+			continue
+		}
+		return a.function == con, nil
+	}
+	return false, fmt.Errorf("%w: no confirmation in %d frames", errInvalidAPDU, maxFrames)
+}
+
+// interrogate sends a station interrogation and reads the station's answer until it terminates the
+// interrogation or stops sending.
+func (c *connection) interrogate(commonAddress uint16) (*Interrogation, error) {
+	if _, err := c.conn.Write(iFrame(c.sendSeq, c.recvSeq, interrogationCommand(commonAddress))); err != nil {
+		return nil, err
+	}
+	c.sendSeq++
+	result := &Interrogation{CommonAddress: commonAddress}
+	addresses := make(map[uint16]bool)
+	types := make(map[string]int)
+	defer func() {
+		for address := range addresses {
+			result.CommonAddresses = append(result.CommonAddresses, address)
+		}
+		sort.Slice(result.CommonAddresses, func(i, j int) bool {
+			return result.CommonAddresses[i] < result.CommonAddresses[j]
+		})
+		for name, count := range types {
+			result.ASDUTypes = append(result.ASDUTypes, name+"="+strconv.Itoa(count))
+		}
+		sort.Strings(result.ASDUTypes)
+	}()
+	for i := 0; i < maxFrames; i++ {
+		a, err := c.next()
+		if err != nil {
+			return result, err
+		}
+		if a.format != formatI {
+			continue
+		}
+		unit, err := parseASDU(a.asdu)
+		if err != nil {
+			return result, err
+		}
+		addresses[unit.commonAddress] = true
+		types[typeName(unit.typeID)]++
+		if unit.typeID != typeInterrogation {
+			if unit.cause == causeStationInterrog {
+				result.InformationObjects += unit.objects
+			}
+			continue
+		}
+		switch unit.cause {
+		case causeActivationTerm:
+			result.Terminated = true
+			return result, nil
+		case causeActivation:
+			// an echo of the command, not an answer
+		default:
+			result.Confirmation = causeName(unit.cause)
+			result.Negative = unit.negative
+			if unit.negative || unit.cause != causeActivationCon {
+				return result, nil
+			}
+		}
+	}
+	return result, nil
+}
+
+// Scan probes the target for an IEC 104 station.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+	c := &connection{conn: conn}
+
+	results := new(ScanResults)
+	results.TestFrameConfirmed, err = c.control(uTestFRAct, uTestFRCon)
+	if err != nil {
+		return status(err), nil, fmt.Errorf("error sending TESTFR to target %s: %w", target.String(), err)
+	}
+	results.StartDTConfirmed, err = c.control(uStartDTAct, uStartDTCon)
+	if err != nil {
+		log.Debugf("error sending STARTDT to target %s: %v", target.String(), err)
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+	if !results.StartDTConfirmed || scanner.config.NoInterrogation {
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+
+	results.Interrogation, err = c.interrogate(uint16(scanner.config.CommonAddress))
+	if err != nil {
+		// stations often stay silent after a rejected or unanswered interrogation, so what was read is kept
+		log.Debugf("error reading interrogation response from target %s: %v", target.String(), err)
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+	if c.unacked > 0 {
+		_, _ = conn.Write(sFrame(c.recvSeq))
+	}
+	// the connection is closed right after, so the STOPDT confirmation is not awaited
+	_, _ = conn.Write(uFrame(uStopDTAct))
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
+
+// status maps an error to a scan status, treating malformed and truncated frames as protocol errors.
+func status(err error) zgrab2.ScanStatus {
+	if errors.Is(err, errInvalidAPDU) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return zgrab2.SCAN_PROTOCOL_ERROR
+	}
+	return zgrab2.TryGetScanStatus(err)
+}
@@ -0,0 +1,242 @@
+package ike
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strconv"
+)
+
+// headerLength is the length of the ISAKMP/IKE header, which is the same in IKEv1 and IKEv2.
+const headerLength = 28
+
+// Exchange types.
+const (
+	exchangeIdentityProtection = 2 // IKEv1 Main Mode
+	exchangeAggressive         = 4
+	exchangeInformational      = 5
+	exchangeIKESAInit          = 34
+)
+
+var errInvalidMessage = errors.New("invalid IKE message")
+
+// header is the decoded message header.
+type header struct {
+	initiatorSPI []byte
+	responderSPI []byte
+	nextPayload  uint8
+	version      uint8
+	exchangeType uint8
+	flags        uint8
+	messageID    uint32
+}
+
+// payload is a generic payload, with the header stripped.
+type payload struct {
+	payloadType uint8
+	body        []byte
+}
+
+// newSPI returns a random initiator SPI.
+func newSPI() []byte {
+	spi := make([]byte, 8)
+	_, _ = rand.Read(spi)
+	return spi
+}
+
+// buildMessage returns a message with the given header fields and payloads, chaining the next payload fields.
+func buildMessage(initiatorSPI []byte, version, exchangeType, flags uint8, payloads ...payload) []byte {
+	first := uint8(0)
+	if len(payloads) > 0 {
+		first = payloads[0].payloadType
+	}
+	msg := append([]byte(nil), initiatorSPI...)
+	msg = append(msg, make([]byte, 8)...)
+	msg = append(msg, first, version, exchangeType, flags)
+	msg = binary.BigEndian.AppendUint32(msg, 0)
+	msg = append(msg, 0, 0, 0, 0) // length, filled in below
+	for i, p := range payloads {
+		next := uint8(0)
+		if i+1 < len(payloads) {
+			next = payloads[i+1].payloadType
+		}
+		msg = append(msg, next, 0)
+		msg = binary.BigEndian.AppendUint16(msg, uint16(4+len(p.body)))
+		msg = append(msg, p.body...)
+	}
+	binary.BigEndian.PutUint32(msg[24:28], uint32(len(msg)))
+	return msg
+}
+
+// parseMessage decodes the header and payload chain of a message.
+func parseMessage(data []byte) (*header, []payload, error) {
+	if len(data) < headerLength {
+		return nil, nil, errInvalidMessage
+	}
+	hdr := &header{
+		initiatorSPI: data[0:8],
+		responderSPI: data[8:16],
+		nextPayload:  data[16],
+		version:      data[17],
+		exchangeType: data[18],
+		flags:        data[19],
+		messageID:    binary.BigEndian.Uint32(data[20:24]),
+	}
+	length := int(binary.BigEndian.Uint32(data[24:28]))
+	if length < headerLength || length > len(data) {
+		return nil, nil, errInvalidMessage
+	}
+	var payloads []payload
+	rest := data[headerLength:length]
+	for next := hdr.nextPayload; next != 0; {
+		if len(rest) < 4 {
+			return nil, nil, errInvalidMessage
+		}
+		payloadLength := int(binary.BigEndian.Uint16(rest[2:4]))
+		if payloadLength < 4 || payloadLength > len(rest) {
+			return nil, nil, errInvalidMessage
+		}
+		payloads = append(payloads, payload{payloadType: next, body: rest[4:payloadLength]})
+		next = rest[0]
+		rest = rest[payloadLength:]
+	}
+	return hdr, payloads, nil
+}
+
+// matchesSPI returns a probe match function accepting messages for the given initiator SPI.
+func matchesSPI(spi []byte) func(_, response []byte) bool {
+	return func(_, response []byte) bool {
+		return len(response) >= headerLength && bytes.Equal(response[:8], spi)
+	}
+}
+
+// Transform is a negotiated transform or SA attribute.
+type Transform struct {
+	// Type is the transform type, e.g. encryption, prf, integrity, dh_group, hash or auth_method.
+	Type      string `json:"type"`
+	ID        uint16 `json:"id"`
+	Name      string `json:"name,omitempty"`
+	KeyLength uint16 `json:"key_length,omitempty"`
+}
+
+// newTransform returns a transform, looking its ID up in names.
+func newTransform(transformType string, id uint16, names map[uint16]string) Transform {
+	return Transform{Type: transformType, ID: id, Name: names[id]}
+}
+
+// VendorID is a Vendor ID payload.
+type VendorID struct {
+	// Value is the hex-encoded payload.
+	Value string `json:"value"`
+
+	// Name is the name of a known vendor ID, or the payload itself if it is printable.
+	Name string `json:"name,omitempty"`
+}
+
+// knownVendorIDs maps hex-encoded vendor ID prefixes to names.
+var knownVendorIDs = []struct {
+	prefix string
+	name   string
+}{
+	{"4a131c81070358455c5728f20e95452f", "RFC 3947 NAT-T"},
+	{"90cb80913ebb696e086381b5ec427b1f", "draft-ietf-ipsec-nat-t-ike-02\\n"},
+	{"cd60464335df21f87cfdb2fc68b6a448", "draft-ietf-ipsec-nat-t-ike-02"},
+	{"4485152d18b6bbcd0be8a8469579ddcc", "draft-ietf-ipsec-nat-t-ike-00"},
+	{"afcad71368a1f1c96b8696fc77570100", "Dead Peer Detection v1.0"},
+	{"09002689dfd6b712", "XAUTH"},
+	{"12f5f28c457168a9702d9fe274cc", "Cisco Unity"},
+	{"4048b7d56ebce88525e7de7f00d6c2d3", "IKE Fragmentation"},
+	{"1e2b516905991c7d7c96fcbfb587e461", "Microsoft Windows"},
+	{"882fe56d6fd20dbc2251613b2ebe5beb", "strongSwan"},
+	{"4865617274426561745f4e6f74696679", "HeartBeat Notify"},
+}
+
+// natTraversalVendorIDs are the vendor IDs announcing IKEv1 NAT traversal support.
+var natTraversalVendorIDs = map[string]bool{
+	"RFC 3947 NAT-T":                   true,
+	"draft-ietf-ipsec-nat-t-ike-02\\n": true,
+	"draft-ietf-ipsec-nat-t-ike-02":    true,
+	"draft-ietf-ipsec-nat-t-ike-00":    true,
+}
+
+// newVendorID decodes a Vendor ID payload.
+func newVendorID(body []byte) VendorID {
+	vid := VendorID{Value: hex.EncodeToString(body)}
+	for _, known := range knownVendorIDs {
+		if len(vid.Value) >= len(known.prefix) && vid.Value[:len(known.prefix)] == known.prefix {
+			vid.Name = known.name
+			return vid
+		}
+	}
+	printable := len(body) > 0
+	for _, b := range body {
+		if b < 0x20 || b > 0x7E {
+			printable = false
+			break
+		}
+	}
+	if printable {
+		vid.Name = string(body)
+	}
+	return vid
+}
+
+// Notification is a Notify (IKEv2) or Notification (IKEv1) payload.
+type Notification struct {
+	Type uint16 `json:"type"`
+	Name string `json:"name,omitempty"`
+
+	// Data is the hex-encoded notification data.
+	Data string `json:"data,omitempty"`
+}
+
+// notifyNames maps notify message types to names. Error types up to 16383 are shared by IKEv1 and IKEv2; the
+// status types are IKEv2's.
+var notifyNames = map[uint16]string{
+	1:     "UNSUPPORTED_CRITICAL_PAYLOAD",
+	4:     "INVALID_IKE_SPI",
+	5:     "INVALID_MAJOR_VERSION",
+	7:     "INVALID_SYNTAX",
+	9:     "INVALID_MESSAGE_ID",
+	11:    "INVALID_SPI",
+	14:    "NO_PROPOSAL_CHOSEN",
+	16:    "PAYLOAD_MALFORMED",
+	17:    "INVALID_KE_PAYLOAD",
+	24:    "AUTHENTICATION_FAILED",
+	34:    "SINGLE_PAIR_REQUIRED",
+	35:    "NO_ADDITIONAL_SAS",
+	36:    "INTERNAL_ADDRESS_FAILURE",
+	37:    "FAILED_CP_REQUIRED",
+	38:    "TS_UNACCEPTABLE",
+	43:    "TEMPORARY_FAILURE",
+	16384: "INITIAL_CONTACT",
+	16385: "SET_WINDOW_SIZE",
+	16388: "NAT_DETECTION_SOURCE_IP",
+	16389: "NAT_DETECTION_DESTINATION_IP",
+	16390: "COOKIE",
+	16392: "HTTP_CERT_LOOKUP_SUPPORTED",
+	16404: "MULTIPLE_AUTH_SUPPORTED",
+	16396: "MOBIKE_SUPPORTED",
+	16406: "REDIRECT_SUPPORTED",
+	16407: "REDIRECT",
+	16417: "EAP_ONLY_AUTHENTICATION",
+	16418: "CHILDLESS_IKEV2_SUPPORTED",
+	16430: "IKEV2_FRAGMENTATION_SUPPORTED",
+	16431: "SIGNATURE_HASH_ALGORITHMS",
+}
+
+// newNotification returns a notification of the given type.
+func newNotification(notifyType uint16, data []byte) Notification {
+	name, ok := notifyNames[notifyType]
+	if !ok {
+		name = strconv.Itoa(int(notifyType))
+	}
+	return Notification{Type: notifyType, Name: name, Data: hex.EncodeToString(data)}
+}
+
+// isError reports whether the notification is an error.
+func (n Notification) isError() bool {
+	return n.Type < 16384
+}
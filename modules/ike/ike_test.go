@@ -0,0 +1,78 @@
+package ike
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestBuildAndParseV2(t *testing.T) {
+	spi := newSPI()
+	addr := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 500}
+	request := buildV2(spi, 19, []byte{0xC0, 0x0C}, addr, addr)
+	hdr, payloads, err := parseMessage(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(hdr.initiatorSPI, spi) || hdr.version != 0x20 || hdr.exchangeType != exchangeIKESAInit || hdr.flags != 0x08 {
+		t.Errorf("unexpected header %+v", hdr)
+	}
+	var types []uint8
+	for _, p := range payloads {
+		types = append(types, p.payloadType)
+	}
+	expected := []uint8{v2PayloadNotify, v2PayloadSA, v2PayloadKE, v2PayloadNonce, v2PayloadNotify, v2PayloadNotify, v2PayloadNotify}
+	if !reflect.DeepEqual(types, expected) {
+		t.Errorf("got payload types %v", types)
+	}
+	if cookie, ok := parseV2Notify(payloads[0].body); !ok || cookie.Name != "COOKIE" || cookie.Data != "c00c" {
+		t.Errorf("got cookie notify %+v", cookie)
+	}
+	if len(payloads[2].body) != 4+64 {
+		t.Errorf("got KE payload of length %d", len(payloads[2].body))
+	}
+	if !matchesSPI(spi)(nil, request) || matchesSPI(newSPI())(nil, request) {
+		t.Error("SPI matching failed")
+	}
+
+	// a responder echoes a single proposal with one transform of each type
+	sa := buildV2SA([]v2Transform{{v2TransformEncryption, 12, 256}, {v2TransformPRF, 5, 0}, {v2TransformIntegrity, 12, 0}, {v2TransformDH, 19, 0}})
+	transforms := parseV2SA(sa)
+	if len(transforms) != 4 || transforms[0] != (Transform{Type: "encryption", ID: 12, Name: "AES_CBC", KeyLength: 256}) || transforms[3].Name != "ECP_256" {
+		t.Errorf("got transforms %+v", transforms)
+	}
+}
+
+func TestParseV1SA(t *testing.T) {
+	sa := buildV1SA([]v1Offer{{encryption: 7, keyLength: 128, hash: 2, authMethod: 65001, group: 2}})
+	expected := []Transform{
+		{Type: "encryption", ID: 7, Name: "AES-CBC", KeyLength: 128},
+		{Type: "hash", ID: 2, Name: "SHA1"},
+		{Type: "auth_method", ID: 65001, Name: "XAUTH-InitPSK"},
+		{Type: "dh_group", ID: 2, Name: "MODP_1024"},
+	}
+	if transforms := parseV1SA(sa); !reflect.DeepEqual(transforms, expected) {
+		t.Errorf("got transforms %+v", transforms)
+	}
+
+	request := buildV1(newSPI(), true, 2, 3, "vpn@example.com")
+	_, payloads, err := parseMessage(request)
+	if err != nil || len(payloads) != 4 || payloads[3].payloadType != v1PayloadID || string(payloads[3].body[4:]) != "vpn@example.com" {
+		t.Fatalf("unexpected aggressive mode request %x (%v)", request, err)
+	}
+}
+
+func TestNewVendorID(t *testing.T) {
+	nat, _ := hex.DecodeString("4a131c81070358455c5728f20e95452f")
+	if vid := newVendorID(nat); vid.Name != "RFC 3947 NAT-T" || !natTraversalVendorIDs[vid.Name] {
+		t.Errorf("got %+v", vid)
+	}
+	if vid := newVendorID([]byte("Cisco VPN Concentrator")); vid.Name != "Cisco VPN Concentrator" {
+		t.Errorf("got %+v", vid)
+	}
+	if vid := newVendorID([]byte{0, 1, 2}); vid.Name != "" || vid.Value != "000102" {
+		t.Errorf("got %+v", vid)
+	}
+}
@@ -0,0 +1,207 @@
+package ike
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+)
+
+// IKEv1 payload types.
+const (
+	v1PayloadSA           = 1
+	v1PayloadProposal     = 2
+	v1PayloadTransform    = 3
+	v1PayloadKE           = 4
+	v1PayloadID           = 5
+	v1PayloadHash         = 8
+	v1PayloadNonce        = 10
+	v1PayloadNotification = 11
+	v1PayloadVendorID     = 13
+)
+
+// IKEv1 SA attribute types.
+const (
+	v1AttrEncryption   = 1
+	v1AttrHash         = 2
+	v1AttrAuthMethod   = 3
+	v1AttrGroup        = 4
+	v1AttrLifeType     = 11
+	v1AttrLifeDuration = 12
+	v1AttrKeyLength    = 14
+)
+
+var v1EncryptionNames = map[uint16]string{
+	1: "DES-CBC",
+	2: "IDEA-CBC",
+	3: "Blowfish-CBC",
+	4: "RC5-R16-B64-CBC",
+	5: "3DES-CBC",
+	6: "CAST-CBC",
+	7: "AES-CBC",
+}
+
+var v1HashNames = map[uint16]string{
+	1: "MD5",
+	2: "SHA1",
+	3: "Tiger",
+	4: "SHA2-256",
+	5: "SHA2-384",
+	6: "SHA2-512",
+}
+
+var v1AuthMethodNames = map[uint16]string{
+	1:     "PSK",
+	2:     "DSS",
+	3:     "RSA-Sig",
+	4:     "RSA-Enc",
+	5:     "RSA-RevEnc",
+	64221: "Hybrid-InitRSA",
+	65001: "XAUTH-InitPSK",
+	65005: "XAUTH-InitRSA",
+}
+
+// v1Offer is one combination of SA attributes offered in the proposal.
+type v1Offer struct {
+	encryption, keyLength, hash, authMethod, group uint16
+}
+
+// v1Offers returns the transforms offered for the given Diffie-Hellman groups.
+func v1Offers(groups []uint16) []v1Offer {
+	var offers []v1Offer
+	for _, group := range groups {
+		for _, enc := range []v1Offer{{encryption: 7, keyLength: 256}, {encryption: 7, keyLength: 128}, {encryption: 5}} {
+			for _, hash := range []uint16{4, 2} {
+				for _, auth := range []uint16{1, 3} {
+					offers = append(offers, v1Offer{enc.encryption, enc.keyLength, hash, auth, group})
+				}
+			}
+		}
+	}
+	return offers
+}
+
+// appendBasicAttribute appends an attribute in the TV format.
+func appendBasicAttribute(b []byte, attrType, value uint16) []byte {
+	b = binary.BigEndian.AppendUint16(b, 0x8000|attrType)
+	return binary.BigEndian.AppendUint16(b, value)
+}
+
+// buildV1SA returns an ISAKMP SA payload body with a single proposal containing the offers.
+func buildV1SA(offers []v1Offer) []byte {
+	var transforms []byte
+	for i, offer := range offers {
+		next := byte(v1PayloadTransform)
+		if i == len(offers)-1 {
+			next = 0
+		}
+		attrs := appendBasicAttribute(nil, v1AttrEncryption, offer.encryption)
+		if offer.keyLength != 0 {
+			attrs = appendBasicAttribute(attrs, v1AttrKeyLength, offer.keyLength)
+		}
+		attrs = appendBasicAttribute(attrs, v1AttrHash, offer.hash)
+		attrs = appendBasicAttribute(attrs, v1AttrAuthMethod, offer.authMethod)
+		attrs = appendBasicAttribute(attrs, v1AttrGroup, offer.group)
+		attrs = appendBasicAttribute(attrs, v1AttrLifeType, 1)
+		attrs = appendBasicAttribute(attrs, v1AttrLifeDuration, 28800)
+
+		transforms = append(transforms, next, 0)
+		transforms = binary.BigEndian.AppendUint16(transforms, uint16(8+len(attrs)))
+		transforms = append(transforms, byte(i+1), 1, 0, 0) // transform number, KEY_IKE
+		transforms = append(transforms, attrs...)
+	}
+	sa := []byte{0, 0, 0, 1, 0, 0, 0, 1} // DOI IPsec, SIT_IDENTITY_ONLY
+	sa = append(sa, 0, 0)
+	sa = binary.BigEndian.AppendUint16(sa, uint16(8+len(transforms)))
+	sa = append(sa, 1, 1, 0, byte(len(offers))) // proposal 1, PROTO_ISAKMP, no SPI
+	return append(sa, transforms...)
+}
+
+// buildV1 returns the first message of a Main Mode or, if aggressive is set, an Aggressive Mode exchange. Aggressive
+// Mode carries the key exchange and identity in the first message, so only group can be offered.
+func buildV1(spi []byte, aggressive bool, group uint16, idType uint8, identity string) []byte {
+	if !aggressive {
+		return buildMessage(spi, 0x10, exchangeIdentityProtection, 0,
+			payload{v1PayloadSA, buildV1SA(v1Offers([]uint16{14, 2}))})
+	}
+	ke := make([]byte, keyExchangeLengths[group])
+	_, _ = rand.Read(ke)
+	nonce := make([]byte, 20)
+	_, _ = rand.Read(nonce)
+	id := append([]byte{idType, 17}, 0x01, 0xF4) // UDP port 500
+	id = append(id, identity...)
+	return buildMessage(spi, 0x10, exchangeAggressive, 0,
+		payload{v1PayloadSA, buildV1SA(v1Offers([]uint16{group}))},
+		payload{v1PayloadKE, ke},
+		payload{v1PayloadNonce, nonce},
+		payload{v1PayloadID, id},
+	)
+}
+
+// parseV1Attributes decodes the SA attributes of the transform the responder chose.
+func parseV1Attributes(attrs []byte) []Transform {
+	var transforms []Transform
+	var encryption *Transform
+	for len(attrs) >= 4 {
+		attrType := binary.BigEndian.Uint16(attrs[0:2])
+		var value uint16
+		if attrType&0x8000 != 0 {
+			attrType &^= 0x8000
+			value = binary.BigEndian.Uint16(attrs[2:4])
+			attrs = attrs[4:]
+		} else {
+			// variable-length values are only used for attributes not recorded here
+			length := int(binary.BigEndian.Uint16(attrs[2:4]))
+			if len(attrs) < 4+length {
+				break
+			}
+			attrs = attrs[4+length:]
+			continue
+		}
+		switch attrType {
+		case v1AttrEncryption:
+			transforms = append(transforms, newTransform("encryption", value, v1EncryptionNames))
+			encryption = &transforms[len(transforms)-1]
+		case v1AttrHash:
+			transforms = append(transforms, newTransform("hash", value, v1HashNames))
+		case v1AttrAuthMethod:
+			transforms = append(transforms, newTransform("auth_method", value, v1AuthMethodNames))
+		case v1AttrGroup:
+			transforms = append(transforms, newTransform("dh_group", value, groupNames))
+		case v1AttrKeyLength:
+			if encryption != nil {
+				encryption.KeyLength = value
+			}
+		}
+	}
+	return transforms
+}
+
+// parseV1SA returns the transform chosen in an SA payload from the responder.
+func parseV1SA(body []byte) []Transform {
+	if len(body) < 8+4+4 {
+		return nil
+	}
+	proposal := body[8:]
+	proposalLength := int(binary.BigEndian.Uint16(proposal[2:4]))
+	if proposalLength < 8 || proposalLength > len(proposal) {
+		return nil
+	}
+	spiSize := int(proposal[6])
+	rest := proposal[8:proposalLength]
+	if len(rest) < spiSize+8 {
+		return nil
+	}
+	transform := rest[spiSize:]
+	transformLength := int(binary.BigEndian.Uint16(transform[2:4]))
+	if transformLength < 8 || transformLength > len(transform) {
+		return nil
+	}
+	return parseV1Attributes(transform[8:transformLength])
+}
+
+// parseV1Notification decodes a Notification payload body.
+func parseV1Notification(body []byte) (Notification, bool) {
+	if len(body) < 8 || len(body) < 8+int(body[5]) {
+		return Notification{}, false
+	}
+	return newNotification(binary.BigEndian.Uint16(body[6:8]), body[8+int(body[5]):]), true
+}
@@ -0,0 +1,241 @@
+package ike
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"net"
+)
+
+// IKEv2 payload types.
+const (
+	v2PayloadSA       = 33
+	v2PayloadKE       = 34
+	v2PayloadCertReq  = 38
+	v2PayloadNonce    = 40
+	v2PayloadNotify   = 41
+	v2PayloadVendorID = 43
+)
+
+// IKEv2 transform types.
+const (
+	v2TransformEncryption = 1
+	v2TransformPRF        = 2
+	v2TransformIntegrity  = 3
+	v2TransformDH         = 4
+)
+
+// IKEv2 notify message types handled by the scan.
+const (
+	notifyInvalidKEPayload       = 17
+	notifyNATDetectionSourceIP   = 16388
+	notifyNATDetectionDestIP     = 16389
+	notifyCookie                 = 16390
+	notifyFragmentationSupported = 16430
+)
+
+var v2EncryptionNames = map[uint16]string{
+	2:  "DES",
+	3:  "3DES",
+	12: "AES_CBC",
+	13: "AES_CTR",
+	18: "AES_GCM_8",
+	19: "AES_GCM_12",
+	20: "AES_GCM_16",
+	28: "CHACHA20_POLY1305",
+}
+
+var v2PRFNames = map[uint16]string{
+	1: "HMAC_MD5",
+	2: "HMAC_SHA1",
+	4: "AES128_XCBC",
+	5: "HMAC_SHA2_256",
+	6: "HMAC_SHA2_384",
+	7: "HMAC_SHA2_512",
+}
+
+var v2IntegrityNames = map[uint16]string{
+	1:  "HMAC_MD5_96",
+	2:  "HMAC_SHA1_96",
+	5:  "AES_XCBC_96",
+	12: "HMAC_SHA2_256_128",
+	13: "HMAC_SHA2_384_192",
+	14: "HMAC_SHA2_512_256",
+}
+
+// groupNames maps Diffie-Hellman group numbers, which IKEv1 and IKEv2 share, to names.
+var groupNames = map[uint16]string{
+	1:  "MODP_768",
+	2:  "MODP_1024",
+	5:  "MODP_1536",
+	14: "MODP_2048",
+	15: "MODP_3072",
+	16: "MODP_4096",
+	19: "ECP_256",
+	20: "ECP_384",
+	21: "ECP_521",
+	31: "Curve25519",
+}
+
+// keyExchangeLengths are the lengths of the public values of the supported groups.
+var keyExchangeLengths = map[uint16]int{
+	1:  96,
+	2:  128,
+	5:  192,
+	14: 256,
+	15: 384,
+	16: 512,
+	19: 64,
+	20: 96,
+	21: 132,
+	31: 32,
+}
+
+var v2TransformTypes = map[uint8]struct {
+	name  string
+	names map[uint16]string
+}{
+	v2TransformEncryption: {"encryption", v2EncryptionNames},
+	v2TransformPRF:        {"prf", v2PRFNames},
+	v2TransformIntegrity:  {"integrity", v2IntegrityNames},
+	v2TransformDH:         {"dh_group", groupNames},
+}
+
+// v2Transform is a transform offered in the proposal.
+type v2Transform struct {
+	transformType uint8
+	id            uint16
+	keyLength     uint16
+}
+
+// v2Offers returns the transforms offered, with group first among the Diffie-Hellman groups.
+func v2Offers(group uint16) []v2Transform {
+	offers := []v2Transform{
+		{v2TransformEncryption, 12, 256},
+		{v2TransformEncryption, 12, 128},
+		{v2TransformEncryption, 3, 0},
+		{v2TransformPRF, 5, 0},
+		{v2TransformPRF, 7, 0},
+		{v2TransformPRF, 2, 0},
+		{v2TransformIntegrity, 12, 0},
+		{v2TransformIntegrity, 14, 0},
+		{v2TransformIntegrity, 2, 0},
+		{v2TransformDH, group, 0},
+	}
+	for _, other := range []uint16{14, 19, 20, 31, 2} {
+		if other != group {
+			offers = append(offers, v2Transform{v2TransformDH, other, 0})
+		}
+	}
+	return offers
+}
+
+// buildV2SA returns an SA payload body with a single IKE proposal containing the offers.
+func buildV2SA(offers []v2Transform) []byte {
+	var transforms []byte
+	for i, offer := range offers {
+		last := byte(3)
+		if i == len(offers)-1 {
+			last = 0
+		}
+		var attrs []byte
+		if offer.keyLength != 0 {
+			attrs = appendBasicAttribute(nil, v1AttrKeyLength, offer.keyLength) // Key Length is attribute 14 in IKEv2 too
+		}
+		transforms = append(transforms, last, 0)
+		transforms = binary.BigEndian.AppendUint16(transforms, uint16(8+len(attrs)))
+		transforms = append(transforms, offer.transformType, 0)
+		transforms = binary.BigEndian.AppendUint16(transforms, offer.id)
+		transforms = append(transforms, attrs...)
+	}
+	sa := []byte{0, 0}
+	sa = binary.BigEndian.AppendUint16(sa, uint16(8+len(transforms)))
+	sa = append(sa, 1, 1, 0, byte(len(offers))) // proposal 1, IKE, no SPI
+	return append(sa, transforms...)
+}
+
+// buildV2Notify returns a Notify payload body without an SPI.
+func buildV2Notify(notifyType uint16, data []byte) []byte {
+	body := binary.BigEndian.AppendUint16([]byte{0, 0}, notifyType)
+	return append(body, data...)
+}
+
+// natDetectionHash returns the NAT detection data for an address: SHA1(SPIi | SPIr | IP | port).
+func natDetectionHash(initiatorSPI []byte, addr *net.UDPAddr) []byte {
+	ip := addr.IP
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	h := sha1.New()
+	h.Write(initiatorSPI)
+	h.Write(make([]byte, 8))
+	h.Write(ip)
+	_ = binary.Write(h, binary.BigEndian, uint16(addr.Port))
+	return h.Sum(nil)
+}
+
+// buildV2 returns an IKE_SA_INIT request. If cookie is set, it is returned to the responder in a COOKIE notify.
+func buildV2(spi []byte, group uint16, cookie []byte, local, remote *net.UDPAddr) []byte {
+	var payloads []payload
+	if cookie != nil {
+		payloads = append(payloads, payload{v2PayloadNotify, buildV2Notify(notifyCookie, cookie)})
+	}
+	ke := binary.BigEndian.AppendUint16(nil, group)
+	ke = append(ke, 0, 0)
+	ke = append(ke, make([]byte, keyExchangeLengths[group])...)
+	_, _ = rand.Read(ke[4:])
+	nonce := make([]byte, 32)
+	_, _ = rand.Read(nonce)
+	payloads = append(payloads,
+		payload{v2PayloadSA, buildV2SA(v2Offers(group))},
+		payload{v2PayloadKE, ke},
+		payload{v2PayloadNonce, nonce},
+	)
+	if local != nil && remote != nil {
+		payloads = append(payloads,
+			payload{v2PayloadNotify, buildV2Notify(notifyNATDetectionSourceIP, natDetectionHash(spi, local))},
+			payload{v2PayloadNotify, buildV2Notify(notifyNATDetectionDestIP, natDetectionHash(spi, remote))},
+		)
+	}
+	payloads = append(payloads, payload{v2PayloadNotify, buildV2Notify(notifyFragmentationSupported, nil)})
+	return buildMessage(spi, 0x20, exchangeIKESAInit, 0x08, payloads...) // Initiator flag
+}
+
+// parseV2SA returns the transforms the responder chose.
+func parseV2SA(body []byte) []Transform {
+	if len(body) < 8 {
+		return nil
+	}
+	proposalLength := int(binary.BigEndian.Uint16(body[2:4]))
+	if proposalLength < 8+int(body[6]) || proposalLength > len(body) {
+		return nil
+	}
+	rest := body[8+int(body[6]) : proposalLength]
+	var transforms []Transform
+	for len(rest) >= 8 {
+		length := int(binary.BigEndian.Uint16(rest[2:4]))
+		if length < 8 || length > len(rest) {
+			break
+		}
+		transformType := rest[4]
+		id := binary.BigEndian.Uint16(rest[6:8])
+		info, ok := v2TransformTypes[transformType]
+		if ok {
+			transform := newTransform(info.name, id, info.names)
+			if attrs := rest[8:length]; len(attrs) >= 4 && binary.BigEndian.Uint16(attrs[0:2]) == 0x8000|v1AttrKeyLength {
+				transform.KeyLength = binary.BigEndian.Uint16(attrs[2:4])
+			}
+			transforms = append(transforms, transform)
+		}
+		rest = rest[length:]
+	}
+	return transforms
+}
+
+// parseV2Notify decodes a Notify payload body.
+func parseV2Notify(body []byte) (Notification, bool) {
+	if len(body) < 4 || len(body) < 4+int(body[1]) {
+		return Notification{}, false
+	}
+	return newNotification(binary.BigEndian.Uint16(body[2:4]), body[4+int(body[1]):]), true
+}
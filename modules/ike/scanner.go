@@ -0,0 +1,311 @@
+// Package ike contains the zgrab2 Module implementation for IKE (IPsec) responders.
+//
+// For IKEv2, the scan sends an IKE_SA_INIT offering common transforms and records the ones the responder chose,
+// following COOKIE and INVALID_KE_PAYLOAD replies once each. For IKEv1, it sends the first Main Mode message, or
+// with --aggressive the first Aggressive Mode message; a responder that answers the latter with its key exchange
+// and hash has Aggressive Mode enabled, which exposes a crackable hash of the pre-shared key.
+package ike
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// Version is the IKE version of the response, e.g. 2.0.
+	Version string `json:"version,omitempty"`
+
+	// Exchange is the exchange type of the response: main_mode, aggressive_mode, ike_sa_init or informational.
+	Exchange string `json:"exchange,omitempty"`
+
+	// ResponderSPI is the hex-encoded SPI the responder chose.
+	ResponderSPI string `json:"responder_spi,omitempty"`
+
+	// Transforms are the transforms (IKEv2) or SA attributes (IKEv1) the responder accepted.
+	Transforms []Transform `json:"transforms,omitempty"`
+
+	// KeyExchangeGroup is the Diffie-Hellman group of the responder's KE payload.
+	KeyExchangeGroup uint16 `json:"key_exchange_group,omitempty"`
+
+	VendorIDs     []VendorID     `json:"vendor_ids,omitempty"`
+	Notifications []Notification `json:"notifications,omitempty"`
+
+	// Error is the name of the first error notification, e.g. NO_PROPOSAL_CHOSEN.
+	Error string `json:"error,omitempty"`
+
+	// NATTraversal is true if the responder announced NAT traversal support, through a vendor ID in IKEv1 or NAT
+	// detection notifications in IKEv2.
+	NATTraversal bool `json:"nat_traversal"`
+
+	// AggressiveMode is true if the responder answered an Aggressive Mode request with its key exchange and hash.
+	AggressiveMode bool `json:"aggressive_mode"`
+
+	// CertificateRequest is true if the responder requested a certificate.
+	CertificateRequest bool `json:"certificate_request,omitempty"`
+
+	Probes []*zgrab2.UDPProbeResult `json:"probes,omitempty"`
+}
+
+// Flags are the IKE-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.UDPFlags
+
+	IKEVersion int    `long:"ike-version" default:"2" description:"IKE version to negotiate (1 or 2)"`
+	Aggressive bool   `long:"aggressive" description:"Use IKEv1 Aggressive Mode instead of Main Mode"`
+	DHGroup    uint16 `long:"dh-group" default:"14" description:"Diffie-Hellman group of the key exchange, for IKEv2 and IKEv1 Aggressive Mode"`
+	ID         string `long:"id" default:"zgrab2@example.com" description:"Identity sent in Aggressive Mode"`
+	IDType     uint8  `long:"id-type" default:"3" description:"Identity type sent in Aggressive Mode, e.g. 3 for ID_USER_FQDN or 11 for ID_KEY_ID"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the ike zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("ike", "Internet Key Exchange (IKE)", module.Description(), 500, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Send an IKEv1 or IKEv2 SA proposal and record the accepted transforms, vendor IDs and NAT traversal support"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	switch f.IKEVersion {
+	case 1:
+	case 2:
+		if f.Aggressive {
+			return fmt.Errorf("--aggressive requires --ike-version=1")
+		}
+	default:
+		return fmt.Errorf("unsupported IKE version %d", f.IKEVersion)
+	}
+	if _, ok := keyExchangeLengths[f.DHGroup]; !ok {
+		return fmt.Errorf("unsupported Diffie-Hellman group %d", f.DHGroup)
+	}
+	return f.UDPFlags.Validate()
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "ike"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportUDP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+var exchangeNames = map[uint8]string{
+	exchangeIdentityProtection: "main_mode",
+	exchangeAggressive:         "aggressive_mode",
+	exchangeInformational:      "informational",
+	exchangeIKESAInit:          "ike_sa_init",
+	37:                         "informational", // IKEv2 INFORMATIONAL
+}
+
+// maxExchanges bounds the requests sent, allowing one retry each for a cookie and a different key exchange group.
+const maxExchanges = 3
+
+// Scan performs the configured scan on the IKE responder.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+	local, _ := conn.LocalAddr().(*net.UDPAddr)
+	remote, _ := conn.RemoteAddr().(*net.UDPAddr)
+
+	results := new(ScanResults)
+	group := scanner.config.DHGroup
+	var cookie []byte
+	var hdr *header
+	var payloads []payload
+	for i := 0; i < maxExchanges; i++ {
+		spi := newSPI()
+		var request []byte
+		if scanner.config.IKEVersion == 1 {
+			request = buildV1(spi, scanner.config.Aggressive, group, scanner.config.IDType, scanner.config.ID)
+		} else {
+			request = buildV2(spi, group, cookie, local, remote)
+		}
+		probe := zgrab2.NewStaticUDPProbe(fmt.Sprintf("ikev%d", scanner.config.IKEVersion), request, matchesSPI(spi))
+		probeResult, err := zgrab2.SendUDPProbe(ctx, conn, probe, target, &scanner.config.UDPFlags)
+		results.Probes = append(results.Probes, probeResult)
+		if err != nil {
+			if i == 0 {
+				return zgrab2.TryGetScanStatus(err), nil, err
+			}
+			return zgrab2.TryGetScanStatus(err), results, err
+		}
+		if hdr, payloads, err = parseMessage(probeResult.Response); err != nil {
+			return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("invalid response from target %s: %w", target.String(), err)
+		}
+		if scanner.config.IKEVersion == 1 {
+			break
+		}
+		retry := false
+		for _, p := range payloads {
+			if p.payloadType != v2PayloadNotify {
+				continue
+			}
+			notification, ok := parseV2Notify(p.body)
+			if !ok {
+				continue
+			}
+			data, _ := hex.DecodeString(notification.Data)
+			switch notification.Type {
+			case notifyCookie:
+				if cookie == nil {
+					cookie, retry = data, true
+				}
+			case notifyInvalidKEPayload:
+				if len(data) == 2 {
+					suggested := uint16(data[0])<<8 | uint16(data[1])
+					if _, ok := keyExchangeLengths[suggested]; ok && suggested != group {
+						group, retry = suggested, true
+					}
+				}
+			}
+		}
+		if !retry {
+			break
+		}
+		log.Debugf("retrying IKE_SA_INIT to target %s with group %d", target.String(), group)
+	}
+
+	results.Version = fmt.Sprintf("%d.%d", hdr.version>>4, hdr.version&0x0F)
+	results.Exchange = exchangeNames[hdr.exchangeType]
+	results.ResponderSPI = hex.EncodeToString(hdr.responderSPI)
+	if hdr.version>>4 == 1 {
+		scanner.readV1(payloads, results)
+	} else {
+		scanner.readV2(payloads, results)
+	}
+	for _, notification := range results.Notifications {
+		if notification.isError() {
+			results.Error = notification.Name
+			break
+		}
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
+
+// readV1 records the payloads of an IKEv1 response.
+func (scanner *Scanner) readV1(payloads []payload, results *ScanResults) {
+	var keyExchange, hash bool
+	for _, p := range payloads {
+		switch p.payloadType {
+		case v1PayloadSA:
+			results.Transforms = parseV1SA(p.body)
+		case v1PayloadKE:
+			keyExchange = true
+		case v1PayloadHash:
+			hash = true
+		case v1PayloadVendorID:
+			vid := newVendorID(p.body)
+			results.VendorIDs = append(results.VendorIDs, vid)
+			if natTraversalVendorIDs[vid.Name] {
+				results.NATTraversal = true
+			}
+		case v1PayloadNotification:
+			if notification, ok := parseV1Notification(p.body); ok {
+				results.Notifications = append(results.Notifications, notification)
+			}
+		}
+	}
+	results.AggressiveMode = scanner.config.Aggressive && results.Transforms != nil && keyExchange && hash
+}
+
+// readV2 records the payloads of an IKEv2 response.
+func (scanner *Scanner) readV2(payloads []payload, results *ScanResults) {
+	for _, p := range payloads {
+		switch p.payloadType {
+		case v2PayloadSA:
+			results.Transforms = parseV2SA(p.body)
+		case v2PayloadKE:
+			if len(p.body) >= 2 {
+				results.KeyExchangeGroup = uint16(p.body[0])<<8 | uint16(p.body[1])
+			}
+		case v2PayloadCertReq:
+			results.CertificateRequest = true
+		case v2PayloadVendorID:
+			results.VendorIDs = append(results.VendorIDs, newVendorID(p.body))
+		case v2PayloadNotify:
+			if notification, ok := parseV2Notify(p.body); ok {
+				results.Notifications = append(results.Notifications, notification)
+				if notification.Type == notifyNATDetectionSourceIP || notification.Type == notifyNATDetectionDestIP {
+					results.NATTraversal = true
+				}
+			}
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"io"
 	"net"
 	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/zmap/zgrab2"
 )
@@ -36,3 +38,43 @@ func (conn *Connection) SendCommand(cmd string) (string, error) {
 	}
 	return conn.ReadResponse()
 }
+
+// SendTaggedCommand sends a command prefixed with tag, followed by a CRLF, then reads the server's response up to the
+// tagged status line, including the untagged responses preceding it.
+func (conn *Connection) SendTaggedCommand(tag string, cmd string) (string, error) {
+	if _, err := conn.Conn.Write([]byte(tag + " " + cmd + "\r\n")); err != nil {
+		return "", err
+	}
+	ret := make([]byte, readBufferSize)
+	n, err := zgrab2.ReadUntilRegex(conn.Conn, ret, regexp.MustCompile(`(?m)^`+regexp.QuoteMeta(tag)+` [^\r\n]*\r\n\z`))
+	if err != nil && err != io.EOF && !zgrab2.IsTimeoutError(err) {
+		return "", err
+	}
+	return string(ret[:n]), nil
+}
+
+// parseCapabilities returns the capabilities of the untagged CAPABILITY responses of response.
+func parseCapabilities(response string) []string {
+	var capabilities []string
+	for _, line := range strings.Split(response, "\n") {
+		if fields := strings.Fields(line); len(fields) > 1 && fields[0] == "*" && strings.EqualFold(fields[1], "CAPABILITY") {
+			capabilities = append(capabilities, fields[2:]...)
+		}
+	}
+	return capabilities
+}
+
+// diffCapabilities returns the capabilities of after missing from before, and those of before missing from after.
+func diffCapabilities(before []string, after []string) (added []string, removed []string) {
+	for _, capability := range after {
+		if !slices.Contains(before, capability) {
+			added = append(added, capability)
+		}
+	}
+	for _, capability := range before {
+		if !slices.Contains(after, capability) {
+			removed = append(removed, capability)
+		}
+	}
+	return added, removed
+}
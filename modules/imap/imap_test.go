@@ -0,0 +1,32 @@
+package imap
+
+import (
+	"net"
+	"slices"
+	"testing"
+)
+
+func TestSendTaggedCommand(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		server.Read(make([]byte, 64))
+		// the untagged response arrives apart from the tagged one
+		server.Write([]byte("* CAPABILITY IMAP4rev1 STARTTLS LOGINDISABLED\r\n"))
+		server.Write([]byte("a001 OK CAPABILITY completed\r\n"))
+	}()
+	conn := Connection{Conn: client}
+	ret, err := conn.SendTaggedCommand("a001", "CAPABILITY")
+	if err != nil || ret != "* CAPABILITY IMAP4rev1 STARTTLS LOGINDISABLED\r\na001 OK CAPABILITY completed\r\n" {
+		t.Fatalf("SendTaggedCommand = %q, %v", ret, err)
+	}
+	before := parseCapabilities(ret)
+	if !slices.Equal(before, []string{"IMAP4rev1", "STARTTLS", "LOGINDISABLED"}) {
+		t.Errorf("unexpected capabilities: %q", before)
+	}
+	added, removed := diffCapabilities(before, parseCapabilities("* CAPABILITY IMAP4rev1 AUTH=PLAIN\r\na001 OK\r\n"))
+	if !slices.Equal(added, []string{"AUTH=PLAIN"}) || !slices.Equal(removed, []string{"STARTTLS", "LOGINDISABLED"}) {
+		t.Errorf("unexpected diff: added %q, removed %q", added, removed)
+	}
+}
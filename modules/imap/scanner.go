@@ -12,6 +12,10 @@
 // --imaps does not change the default port number from 143, so
 // it should usually be coupled with e.g. --port 993.
 //
+// The --send-capability flag tells the scanner to send a CAPABILITY
+// command, and with --starttls to send it again after the upgrade
+// and record how the capabilities changed.
+//
 // The --send-close flag tells the scanner to send a CLOSE command
 // before disconnecting.
 //
@@ -43,6 +47,25 @@ type ScanResults struct {
 	// StartTLS is the server's response to the STARTTLS command, if it is sent.
 	StartTLS string `json:"starttls,omitempty"`
 
+	// CAPABILITY is the server's response to the CAPABILITY command, if it is sent.
+	CAPABILITY string `json:"capability,omitempty"`
+
+	// Capabilities are the capabilities of the CAPABILITY response.
+	Capabilities []string `json:"capabilities,omitempty"`
+
+	// CAPABILITYAfterSTARTTLS is the server's response to the CAPABILITY command sent again after STARTTLS.
+	CAPABILITYAfterSTARTTLS string `json:"capability_after_starttls,omitempty"`
+
+	// CapabilitiesAfterSTARTTLS are the capabilities of the CAPABILITY response after STARTTLS.
+	CapabilitiesAfterSTARTTLS []string `json:"capabilities_after_starttls,omitempty"`
+
+	// CapabilitiesAdded are the capabilities advertised after STARTTLS only, like the AUTH= mechanisms of servers
+	// refusing to authenticate in the clear.
+	CapabilitiesAdded []string `json:"capabilities_added,omitempty"`
+
+	// CapabilitiesRemoved are the capabilities advertised before STARTTLS only, like STARTTLS and LOGINDISABLED.
+	CapabilitiesRemoved []string `json:"capabilities_removed,omitempty"`
+
 	// CLOSE is the server's response to the CLOSE command, if it is sent.
 	CLOSE string `json:"close,omitempty"`
 
@@ -56,6 +79,9 @@ type Flags struct {
 	zgrab2.BaseFlags `group:"Basic Options"`
 	zgrab2.TLSFlags  `group:"TLS Options"`
 
+	// SendCAPABILITY indicates that the CAPABILITY command should be sent, and again after STARTTLS.
+	SendCAPABILITY bool `long:"send-capability" description:"Send the CAPABILITY command, and again after --starttls, recording how the capabilities changed."`
+
 	// SendCLOSE indicates that the CLOSE command should be sent.
 	SendCLOSE bool `long:"send-close" description:"Send the CLOSE command before closing."`
 
@@ -194,8 +220,10 @@ func VerifyIMAPContents(banner string) zgrab2.ScanStatus {
 //  2. If --imaps is set, perform a TLS handshake using the command-line
 //     flags.
 //  3. Read the banner.
+//  4. If --send-capability is sent, send a001 CAPABILITY, read the result.
 //  6. If --starttls is sent, send a001 STARTTLS, read the result, negotiate a
-//     TLS connection using the command-line flags.
+//     TLS connection using the command-line flags, and send a001 CAPABILITY
+//     again if --send-capability is sent.
 //  7. If --send-close is sent, send a001 CLOSE and read the result.
 //  8. Close the connection.
 func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
@@ -235,6 +263,14 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 	}
 	result.Banner = banner
 	var ret string
+	if scanner.config.SendCAPABILITY {
+		ret, err = conn.SendTaggedCommand("a001", "CAPABILITY")
+		if err != nil {
+			return zgrab2.TryGetScanStatus(err), result, fmt.Errorf("error sending CAPABILITY command for IMAP %s: %w", target.String(), err)
+		}
+		result.CAPABILITY = ret
+		result.Capabilities = parseCapabilities(ret)
+	}
 	if scanner.config.StartTLS {
 		ret, err = conn.SendCommand("a001 STARTTLS")
 		if err != nil {
@@ -246,10 +282,24 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 		}
 		tlsConn, err := dialGroup.TLSWrapper(ctx, target, c)
 		if err != nil {
-			return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error wrapping TLS connection for target %s: %w", target.String(), err)
+			if tlsConn != nil {
+				// keep the certificates and parameters of a failed handshake
+				result.TLSLog = tlsConn.GetLog()
+			}
+			return zgrab2.TryGetScanStatus(err), result, fmt.Errorf("error wrapping TLS connection for target %s: %w", target.String(), err)
 		}
 		result.TLSLog = tlsConn.GetLog()
 		conn.Conn = tlsConn
+		if scanner.config.SendCAPABILITY {
+			// servers advertise other capabilities once the connection is secure
+			ret, err = conn.SendTaggedCommand("a001", "CAPABILITY")
+			if err != nil {
+				return zgrab2.TryGetScanStatus(err), result, fmt.Errorf("error sending CAPABILITY command after STARTTLS for IMAP %s: %w", target.String(), err)
+			}
+			result.CAPABILITYAfterSTARTTLS = ret
+			result.CapabilitiesAfterSTARTTLS = parseCapabilities(ret)
+			result.CapabilitiesAdded, result.CapabilitiesRemoved = diffCapabilities(result.Capabilities, result.CapabilitiesAfterSTARTTLS)
+		}
 	}
 	if scanner.config.SendCLOSE {
 		ret, err := conn.SendCommand("a001 CLOSE")
@@ -0,0 +1,243 @@
+// Package influxdb contains the zgrab2 Module implementation for the InfluxDB HTTP API.
+//
+// The scan requests /ping, whose headers carry the version and build of InfluxDB 1.x and 2.x, and /health (1.8+).
+// It then runs SHOW DATABASES through the 1.x query endpoint without credentials; 2.x and 1.x with authentication
+// enabled reject the query.
+package influxdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+	"github.com/zmap/zgrab2/modules/httpapi"
+)
+
+// health is the response to GET /health.
+type health struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+}
+
+// queryResponse is the response to a 1.x query.
+type queryResponse struct {
+	Results []struct {
+		Series []struct {
+			Name   string  `json:"name"`
+			Values [][]any `json:"values"`
+		} `json:"series"`
+		Error string `json:"error"`
+	} `json:"results"`
+	Error string `json:"error"`
+}
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	Version string `json:"version,omitempty"`
+
+	// Build is the build type from X-Influxdb-Build: OSS, ENT (Enterprise) or Cloud.
+	Build  string `json:"build,omitempty"`
+	Commit string `json:"commit,omitempty"`
+
+	// HealthStatus is the status from /health, e.g. pass.
+	HealthStatus  string `json:"health_status,omitempty"`
+	HealthMessage string `json:"health_message,omitempty"`
+
+	// UnauthenticatedQuery is true if SHOW DATABASES succeeded without credentials.
+	UnauthenticatedQuery bool `json:"unauthenticated_query"`
+
+	// Databases are the databases SHOW DATABASES listed.
+	Databases []string `json:"databases,omitempty"`
+
+	// QueryError is the error the server returned for the query, e.g. because authentication is required.
+	QueryError string `json:"query_error,omitempty"`
+
+	PingResponse   *httpapi.Response `json:"ping_response,omitempty"`
+	HealthResponse *httpapi.Response `json:"health_response,omitempty"`
+	QueryResponse  *httpapi.Response `json:"query_response,omitempty"`
+
+	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
+}
+
+// Flags are the InfluxDB-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.TLSFlags
+	httpapi.Flags
+
+	SkipQuery bool `long:"skip-query" description:"Do not check whether queries are possible without credentials"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the influxdb zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("influxdb", "InfluxDB", module.Description(), 8086, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Query the version of an InfluxDB server and whether it answers queries without credentials"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "influxdb"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = httpapi.NewDialerGroupConfig(&f.BaseFlags, &f.TLSFlags)
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// Scan performs the configured scan on the InfluxDB server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	client := httpapi.NewClient(ctx, dialGroup, target, &scanner.config.Flags)
+	results := new(ScanResults)
+
+	var err error
+	results.PingResponse, err = client.Get("/ping")
+	results.TLSLog = client.TLSLog
+	if err != nil {
+		if results.TLSLog == nil {
+			return zgrab2.TryGetScanStatus(err), nil, err
+		}
+		return zgrab2.TryGetScanStatus(err), results, err
+	}
+	results.Version = results.PingResponse.Header("X-Influxdb-Version")
+	results.Build = results.PingResponse.Header("X-Influxdb-Build")
+
+	var status health
+	if results.HealthResponse, err = client.GetJSON("/health", &status); err != nil {
+		return zgrab2.TryGetScanStatus(err), results, err
+	}
+	if status.Name == "influxdb" {
+		results.HealthStatus = status.Status
+		results.HealthMessage = status.Message
+		results.Commit = status.Commit
+		if results.Version == "" {
+			results.Version = status.Version
+		}
+	}
+	if results.Version == "" && results.HealthStatus == "" {
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("target %s is not an InfluxDB server (status %d)", target.String(), results.PingResponse.StatusCode)
+	}
+	if scanner.config.SkipQuery {
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+
+	var query queryResponse
+	results.QueryResponse, err = client.GetJSON("/query?q="+url.QueryEscape("SHOW DATABASES"), &query)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), results, err
+	}
+	switch {
+	case !results.QueryResponse.Success():
+		// error bodies aren't decoded by GetJSON
+		results.QueryError = results.QueryResponse.Status
+		if msg := errorMessage(results.QueryResponse.Body); msg != "" {
+			results.QueryError = msg
+		}
+	case query.Error != "":
+		results.QueryError = query.Error
+	case len(query.Results) > 0 && query.Results[0].Error != "":
+		results.QueryError = query.Results[0].Error
+	default:
+		results.UnauthenticatedQuery = true
+		for _, result := range query.Results {
+			for _, series := range result.Series {
+				for _, row := range series.Values {
+					if len(row) > 0 {
+						if name, ok := row[0].(string); ok {
+							results.Databases = append(results.Databases, name)
+						}
+					}
+				}
+			}
+		}
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
+
+// errorMessage returns the message of a 1.x ({"error": ...}) or 2.x ({"code": ..., "message": ...}) error body.
+func errorMessage(body string) string {
+	var e struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal([]byte(body), &e) != nil {
+		return ""
+	}
+	if e.Error != "" {
+		return e.Error
+	}
+	return e.Message
+}
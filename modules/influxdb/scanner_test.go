@@ -0,0 +1,103 @@
+package influxdb
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/zmap/zgrab2"
+	"github.com/zmap/zgrab2/modules/httpapi/httpapitest"
+)
+
+// fakeServer returns a handler for an InfluxDB 1.8 server. If auth is set, queries are rejected as they are when
+// authentication is enabled.
+func fakeServer(t *testing.T, auth bool) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Influxdb-Build", "OSS")
+		w.Header().Set("X-Influxdb-Version", "1.8.10")
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"checks":[],"message":"ready for queries and writes","name":"influxdb","status":"pass","version":"1.8.10"}`))
+	})
+	mux.HandleFunc("/query", func(w http.ResponseWriter, r *http.Request) {
+		if q := r.URL.Query().Get("q"); q != "SHOW DATABASES" {
+			t.Errorf("query = %q", q)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if auth {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"unable to parse authentication credentials"}`))
+			return
+		}
+		w.Write([]byte(`{"results":[{"statement_id":0,"series":[{"name":"databases","columns":["name"],"values":[["_internal"],["telegraf"]]}]}]}`))
+	})
+	return mux
+}
+
+// scan runs the scanner against a plaintext HTTP server with the given handler.
+func scan(t *testing.T, flags *Flags, handler http.Handler) (zgrab2.ScanStatus, *ScanResults, error) {
+	t.Helper()
+	return httpapitest.Scan[ScanResults](t, &Scanner{config: flags}, handler)
+}
+
+func TestScanUnauthenticatedQuery(t *testing.T) {
+	status, results, err := scan(t, new(Flags), fakeServer(t, false))
+	if err != nil || status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status = %s, err = %v", status, err)
+	}
+	if results.Version != "1.8.10" || results.Build != "OSS" || results.HealthStatus != "pass" || results.HealthMessage != "ready for queries and writes" {
+		t.Errorf("results = %+v", results)
+	}
+	if !results.UnauthenticatedQuery || !slices.Equal(results.Databases, []string{"_internal", "telegraf"}) || results.QueryError != "" {
+		t.Errorf("query: unauthenticated = %t, databases = %v, error = %q", results.UnauthenticatedQuery, results.Databases, results.QueryError)
+	}
+}
+
+func TestScanAuthEnabled(t *testing.T) {
+	status, results, err := scan(t, new(Flags), fakeServer(t, true))
+	if err != nil || status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status = %s, err = %v", status, err)
+	}
+	if results.UnauthenticatedQuery || results.Databases != nil || results.QueryError != "unable to parse authentication credentials" {
+		t.Errorf("query: unauthenticated = %t, databases = %v, error = %q", results.UnauthenticatedQuery, results.Databases, results.QueryError)
+	}
+
+	_, results, err = scan(t, &Flags{SkipQuery: true}, fakeServer(t, true))
+	if err != nil || results.QueryResponse != nil || results.Version != "1.8.10" {
+		t.Errorf("with --skip-query: results = %+v, err = %v", results, err)
+	}
+}
+
+func TestScanInfluxDB2(t *testing.T) {
+	// 2.x answers /ping without a version header and only reports it in /health
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name":"influxdb","message":"ready for queries and writes","status":"pass","checks":[],"version":"v2.7.5","commit":"09a9607fd9"}`))
+	})
+	mux.HandleFunc("/query", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"code":"unauthorized","message":"unauthorized access"}`))
+	})
+	status, results, err := scan(t, new(Flags), mux)
+	if err != nil || status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status = %s, err = %v", status, err)
+	}
+	if results.Version != "v2.7.5" || results.Commit != "09a9607fd9" || results.UnauthenticatedQuery || results.QueryError != "unauthorized access" {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestScanNotInfluxDB(t *testing.T) {
+	status, _, err := scan(t, new(Flags), http.NotFoundHandler())
+	if err == nil || status != zgrab2.SCAN_PROTOCOL_ERROR {
+		t.Errorf("status = %s, err = %v", status, err)
+	}
+}
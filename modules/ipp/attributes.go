@@ -0,0 +1,206 @@
+package ipp
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+)
+
+// Printer attributes parsed into typed fields of ScanResults
+const (
+	DocumentFormatSupported      string = "document-format-supported"
+	PrinterFirmwareName          string = "printer-firmware-name"
+	PrinterFirmwareStringVersion string = "printer-firmware-string-version"
+	PrinterFirmwareVersion       string = "printer-firmware-version"
+	MarkerNames                  string = "marker-names"
+	MarkerTypes                  string = "marker-types"
+	MarkerColors                 string = "marker-colors"
+	MarkerLevels                 string = "marker-levels"
+	MarkerLowLevels              string = "marker-low-levels"
+	MarkerHighLevels             string = "marker-high-levels"
+	IdentifyActionsSupported     string = "identify-actions-supported"
+	IdentifyActionsDefault       string = "identify-actions-default"
+	PrinterMakeAndModel          string = "printer-make-and-model"
+	PrinterDeviceID              string = "printer-device-id"
+	PrinterUUID                  string = "printer-uuid"
+	PrinterState                 string = "printer-state"
+	PrinterLocation              string = "printer-location"
+	PrinterInfo                  string = "printer-info"
+	valueTagInteger              byte   = 0x21
+	valueTagEnum                 byte   = 0x23
+	printerStateIdle             int32  = 3
+	printerStateProcessing       int32  = 4
+	printerStateStopped          int32  = 5
+)
+
+// extendedAttributes are requested in addition to "all" with --extended-attributes. Printers that leave some of them
+// out of "all" return them when they are named explicitly, and ignore the names they don't know.
+var extendedAttributes = []string{
+	"printer-description",
+	"job-template",
+	DocumentFormatSupported,
+	PrinterFirmwareName,
+	PrinterFirmwareStringVersion,
+	PrinterFirmwareVersion,
+	MarkerNames,
+	MarkerTypes,
+	MarkerColors,
+	MarkerLevels,
+	MarkerLowLevels,
+	MarkerHighLevels,
+	IdentifyActionsSupported,
+	IdentifyActionsDefault,
+	PrinterDeviceID,
+	PrinterUUID,
+}
+
+var printerStateNames = map[int32]string{
+	printerStateIdle:       "idle",
+	printerStateProcessing: "processing",
+	printerStateStopped:    "stopped",
+}
+
+// Firmware is one entry of the printer-firmware-* attributes.
+type Firmware struct {
+	Name          string `json:"name,omitempty"`
+	StringVersion string `json:"string_version,omitempty"`
+
+	// Version is the hex encoding of the printer-firmware-version octetString.
+	Version string `json:"version,omitempty"`
+}
+
+// Marker is one supply (toner, ink, ...) of the marker-* attributes.
+type Marker struct {
+	Name  string `json:"name,omitempty"`
+	Type  string `json:"type,omitempty"`
+	Color string `json:"color,omitempty"`
+
+	// Level is the level of the supply in percent, or a negative value with a special meaning: -1 (unavailable),
+	// -2 (unknown) or -3 (unknown, but not empty).
+	Level     *int32 `json:"level,omitempty"`
+	LowLevel  *int32 `json:"low_level,omitempty"`
+	HighLevel *int32 `json:"high_level,omitempty"`
+}
+
+// strings returns the values of attr as strings.
+func (attr *Attribute) strings() []string {
+	ret := make([]string, 0, len(attr.Values))
+	for _, v := range attr.Values {
+		ret = append(ret, string(v.Bytes))
+	}
+	return ret
+}
+
+// integers returns the values of an integer or enum attribute.
+func (attr *Attribute) integers() []int32 {
+	if attr.ValueTag != valueTagInteger && attr.ValueTag != valueTagEnum {
+		return nil
+	}
+	var ret []int32
+	for _, v := range attr.Values {
+		if len(v.Bytes) == 4 {
+			ret = append(ret, int32(binary.BigEndian.Uint32(v.Bytes)))
+		}
+	}
+	return ret
+}
+
+// firstString returns the first value of attr as a string, or "".
+func (attr *Attribute) firstString() string {
+	if len(attr.Values) == 0 {
+		return ""
+	}
+	return string(attr.Values[0].Bytes)
+}
+
+// parsePrinterAttributes fills in the typed fields of results from the first occurrence of each attribute in attrs.
+func (results *ScanResults) parsePrinterAttributes(attrs []*Attribute) {
+	byName := make(map[string]*Attribute)
+	for _, attr := range attrs {
+		if _, ok := byName[attr.Name]; !ok {
+			byName[attr.Name] = attr
+		}
+	}
+	get := func(name string) *Attribute {
+		if attr, ok := byName[name]; ok {
+			return attr
+		}
+		return &Attribute{}
+	}
+
+	if len(results.AttributeDocumentFormats) == 0 {
+		results.AttributeDocumentFormats = get(DocumentFormatSupported).strings()
+	}
+	if len(results.AttributeIdentifyActions) == 0 {
+		results.AttributeIdentifyActions = get(IdentifyActionsSupported).strings()
+	}
+	if results.AttributeIdentifyActionsDefault == nil {
+		if actions := get(IdentifyActionsDefault).strings(); len(actions) > 0 {
+			results.AttributeIdentifyActionsDefault = actions
+		}
+	}
+	setString := func(field *string, name string) {
+		if *field == "" {
+			*field = get(name).firstString()
+		}
+	}
+	setString(&results.AttributeMakeAndModel, PrinterMakeAndModel)
+	setString(&results.AttributeDeviceID, PrinterDeviceID)
+	setString(&results.AttributeUUID, PrinterUUID)
+	setString(&results.AttributeLocation, PrinterLocation)
+	setString(&results.AttributeInfo, PrinterInfo)
+	if states := get(PrinterState).integers(); results.AttributeState == "" && len(states) > 0 {
+		if name, ok := printerStateNames[states[0]]; ok {
+			results.AttributeState = name
+		}
+	}
+
+	if len(results.AttributeFirmware) == 0 {
+		names := get(PrinterFirmwareName).strings()
+		stringVersions := get(PrinterFirmwareStringVersion).strings()
+		versions := get(PrinterFirmwareVersion).Values
+		for i := 0; i < max(len(names), len(stringVersions), len(versions)); i++ {
+			var fw Firmware
+			if i < len(names) {
+				fw.Name = names[i]
+			}
+			if i < len(stringVersions) {
+				fw.StringVersion = stringVersions[i]
+			}
+			if i < len(versions) {
+				fw.Version = hex.EncodeToString(versions[i].Bytes)
+			}
+			results.AttributeFirmware = append(results.AttributeFirmware, fw)
+		}
+	}
+
+	if len(results.AttributeMarkers) == 0 {
+		names := get(MarkerNames).strings()
+		types := get(MarkerTypes).strings()
+		colors := get(MarkerColors).strings()
+		levels := get(MarkerLevels).integers()
+		lowLevels := get(MarkerLowLevels).integers()
+		highLevels := get(MarkerHighLevels).integers()
+		level := func(levels []int32, i int) *int32 {
+			if i < len(levels) {
+				return &levels[i]
+			}
+			return nil
+		}
+		for i := 0; i < max(len(names), len(levels)); i++ {
+			var marker Marker
+			if i < len(names) {
+				marker.Name = names[i]
+			}
+			if i < len(types) {
+				marker.Type = types[i]
+			}
+			if i < len(colors) {
+				marker.Color = colors[i]
+			}
+			marker.Level = level(levels, i)
+			marker.LowLevel = level(lowLevels, i)
+			marker.HighLevel = level(highLevels, i)
+			results.AttributeMarkers = append(results.AttributeMarkers, marker)
+		}
+	}
+}
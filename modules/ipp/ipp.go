@@ -118,7 +118,8 @@ func getPrintersRequest(major, minor int8) (*bytes.Buffer, *zgrab2.ScanError) {
 // TODO: Store everything except uri statically?
 // Construct a minimal request that an IPP server will respond to
 // IPP request encoding described at https://tools.ietf.org/html/rfc8010#section-3.1.1
-func getPrinterAttributesRequest(major, minor int8, uri string, tls bool) (*bytes.Buffer, error) {
+// Any extra keywords are requested along with "all", as additional values of requested-attributes.
+func getPrinterAttributesRequest(major, minor int8, uri string, tls bool, extra ...string) (*bytes.Buffer, error) {
 	var b bytes.Buffer
 	// Using newest version number, because we must provide a supported major version number
 	// Object must reply to unsupported major version with
@@ -153,6 +154,12 @@ func getPrinterAttributesRequest(major, minor int8, uri string, tls bool) (*byte
 	if err := AttributeByteString(0x44, "requested-attributes", "all", &b); err != nil {
 		return nil, fmt.Errorf("failed to write AttributeByteString for requested-attributes: %w", err)
 	}
+	// additional values are encoded with an empty name (RFC 8010 Section 3.1.5)
+	for _, keyword := range extra {
+		if err := AttributeByteString(0x44, "", keyword, &b); err != nil {
+			return nil, fmt.Errorf("failed to write AttributeByteString for requested-attributes: %w", err)
+		}
+	}
 
 	//end-of-attributes-tag = 3
 	b.Write([]byte{3})
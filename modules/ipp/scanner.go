@@ -87,6 +87,18 @@ type ScanResults struct {
 	AttributeIPPVersions []string     `json:"attr_ipp_versions,omitempty"`
 	AttributePrinterURIs []string     `json:"attr_printer_uris,omitempty"`
 
+	AttributeDocumentFormats        []string   `json:"attr_document_formats,omitempty"`
+	AttributeFirmware               []Firmware `json:"attr_firmware,omitempty"`
+	AttributeMarkers                []Marker   `json:"attr_markers,omitempty"`
+	AttributeIdentifyActions        []string   `json:"attr_identify_actions,omitempty"`
+	AttributeIdentifyActionsDefault []string   `json:"attr_identify_actions_default,omitempty"`
+	AttributeMakeAndModel           string     `json:"attr_make_and_model,omitempty"`
+	AttributeDeviceID               string     `json:"attr_device_id,omitempty"`
+	AttributeUUID                   string     `json:"attr_uuid,omitempty"`
+	AttributeLocation               string     `json:"attr_location,omitempty"`
+	AttributeInfo                   string     `json:"attr_info,omitempty"`
+	AttributeState                  string     `json:"attr_state,omitempty"`
+
 	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
 }
 
@@ -108,6 +120,8 @@ type Flags struct {
 
 	// TODO: Maybe separately implement both an ipps connection and upgrade to https
 	IPPSecure bool `long:"ipps" description:"Perform a TLS handshake immediately upon connecting."`
+
+	ExtendedAttributes bool `long:"extended-attributes" description:"Explicitly request supported document formats, firmware versions, marker levels and identify-actions in addition to \"all\""`
 }
 
 // Module implements the zgrab2.Module interface.
@@ -442,6 +456,7 @@ func (scanner *Scanner) tryReadAttributes(resp *http.Response, scan *scan) *zgra
 			scan.results.AttributePrinterURIs = append(scan.results.AttributePrinterURIs, string(attr.Values[0].Bytes))
 		}
 	}
+	scan.results.parsePrinterAttributes(scan.results.Attributes)
 
 	return nil
 }
@@ -563,7 +578,11 @@ func isIPP(resp *http.Response) bool {
 
 func (scanner *Scanner) Grab(scan *scan, target *zgrab2.ScanTarget, version *version) *zgrab2.ScanError {
 	// Send get-printer-attributes request to the host, preferably a print server
-	body, err := getPrinterAttributesRequest(version.Major, version.Minor, scan.url, scan.tls)
+	var extra []string
+	if scanner.config.ExtendedAttributes {
+		extra = extendedAttributes
+	}
+	body, err := getPrinterAttributesRequest(version.Major, version.Minor, scan.url, scan.tls, extra...)
 	if err != nil {
 		return zgrab2.NewScanError(zgrab2.SCAN_UNKNOWN_ERROR, fmt.Errorf("could not get printer attributes req: %w", err))
 	}
@@ -1 +1,100 @@
 package ipp
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+// printerAttributesResponse returns a successful get-printer-attributes response with the given printer attributes.
+func printerAttributesResponse(attributes func(b *bytes.Buffer)) []byte {
+	var b bytes.Buffer
+	b.Write([]byte{2, 0, 0, 0, 0, 0, 0, 1, 1})
+	AttributeByteString(0x47, "attributes-charset", "utf-8", &b)
+	b.WriteByte(4)
+	attributes(&b)
+	b.WriteByte(3)
+	return b.Bytes()
+}
+
+func integer(name string, values ...int32) func(b *bytes.Buffer) {
+	return func(b *bytes.Buffer) {
+		for i, v := range values {
+			if i > 0 {
+				name = ""
+			}
+			AttributeByteString(valueTagInteger, name, string([]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}), b)
+		}
+	}
+}
+
+func keywords(valueTag byte, name string, values ...string) func(b *bytes.Buffer) {
+	return func(b *bytes.Buffer) {
+		for i, v := range values {
+			if i > 0 {
+				name = ""
+			}
+			AttributeByteString(valueTag, name, v, b)
+		}
+	}
+}
+
+func TestGetPrinterAttributesRequestExtra(t *testing.T) {
+	b, err := getPrinterAttributesRequest(2, 0, "http://127.0.0.1:631/ipp", false, MarkerLevels, PrinterUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected bytes.Buffer
+	AttributeByteString(0x44, "requested-attributes", "all", &expected)
+	AttributeByteString(0x44, "", MarkerLevels, &expected)
+	AttributeByteString(0x44, "", PrinterUUID, &expected)
+	expected.WriteByte(3)
+	if !bytes.HasSuffix(b.Bytes(), expected.Bytes()) {
+		t.Errorf("request = %x", b.Bytes())
+	}
+}
+
+func TestParsePrinterAttributes(t *testing.T) {
+	body := printerAttributesResponse(func(b *bytes.Buffer) {
+		keywords(0x49, DocumentFormatSupported, "application/pdf", "image/urf")(b)
+		keywords(0x41, PrinterMakeAndModel, "ACME LaserJet 9000")(b)
+		keywords(0x42, PrinterFirmwareName, "BOOT", "MAIN")(b)
+		keywords(0x41, PrinterFirmwareStringVersion, "1.0", "2.3.4")(b)
+		keywords(0x30, PrinterFirmwareVersion, "\x01\x00", "\x02\x03\x04")(b)
+		keywords(0x42, MarkerNames, "Black Toner", "Cyan Toner")(b)
+		keywords(0x44, MarkerTypes, "toner", "toner")(b)
+		keywords(0x42, MarkerColors, "#000000", "#00FFFF")(b)
+		integer(MarkerLevels, 80, -3)(b)
+		integer(MarkerLowLevels, 10, 10)(b)
+		keywords(0x44, IdentifyActionsSupported, "display", "sound")(b)
+		keywords(0x44, IdentifyActionsDefault, "sound")(b)
+		AttributeByteString(valueTagEnum, PrinterState, "\x00\x00\x00\x04", b)
+	})
+	attrs, err := readAllAttributes(body, &Scanner{config: &Flags{MaxSize: 256}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results ScanResults
+	results.parsePrinterAttributes(attrs)
+
+	if !slices.Equal(results.AttributeDocumentFormats, []string{"application/pdf", "image/urf"}) {
+		t.Errorf("document formats = %v", results.AttributeDocumentFormats)
+	}
+	if results.AttributeMakeAndModel != "ACME LaserJet 9000" || results.AttributeState != "processing" {
+		t.Errorf("make and model = %q, state = %q", results.AttributeMakeAndModel, results.AttributeState)
+	}
+	expectedFirmware := []Firmware{{Name: "BOOT", StringVersion: "1.0", Version: "0100"}, {Name: "MAIN", StringVersion: "2.3.4", Version: "020304"}}
+	if !slices.Equal(results.AttributeFirmware, expectedFirmware) {
+		t.Errorf("firmware = %+v", results.AttributeFirmware)
+	}
+	if len(results.AttributeMarkers) != 2 {
+		t.Fatalf("markers = %+v", results.AttributeMarkers)
+	}
+	cyan := results.AttributeMarkers[1]
+	if cyan.Name != "Cyan Toner" || cyan.Type != "toner" || cyan.Color != "#00FFFF" || *cyan.Level != -3 || *cyan.LowLevel != 10 || cyan.HighLevel != nil {
+		t.Errorf("marker = %+v", cyan)
+	}
+	if !slices.Equal(results.AttributeIdentifyActions, []string{"display", "sound"}) || !slices.Equal(results.AttributeIdentifyActionsDefault, []string{"sound"}) {
+		t.Errorf("identify actions = %v, default %v", results.AttributeIdentifyActions, results.AttributeIdentifyActionsDefault)
+	}
+}
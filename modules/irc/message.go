@@ -0,0 +1,64 @@
+package irc
+
+import "strings"
+
+// message is a parsed IRC message.
+type message struct {
+	prefix  string
+	command string
+	params  []string
+}
+
+// parseMessage parses a line of the form "[@tags] [:prefix] command [params...] [:trailing]".
+func parseMessage(line string) *message {
+	line = strings.TrimRight(line, "\r\n")
+	if strings.HasPrefix(line, "@") {
+		// message tags (IRCv3) aren't recorded
+		_, line, _ = strings.Cut(line, " ")
+	}
+	msg := new(message)
+	if strings.HasPrefix(line, ":") {
+		msg.prefix, line, _ = strings.Cut(line[1:], " ")
+	}
+	line = strings.TrimLeft(line, " ")
+	msg.command, line, _ = strings.Cut(line, " ")
+	msg.command = strings.ToUpper(msg.command)
+	for line != "" {
+		line = strings.TrimLeft(line, " ")
+		if trailing, ok := strings.CutPrefix(line, ":"); ok {
+			msg.params = append(msg.params, trailing)
+			break
+		}
+		var param string
+		param, line, _ = strings.Cut(line, " ")
+		if param != "" {
+			msg.params = append(msg.params, param)
+		}
+	}
+	return msg
+}
+
+// param returns the i-th parameter, or "" if there are fewer.
+func (msg *message) param(i int) string {
+	if i < len(msg.params) {
+		return msg.params[i]
+	}
+	return ""
+}
+
+// trailing returns the last parameter.
+func (msg *message) trailing() string {
+	if len(msg.params) == 0 {
+		return ""
+	}
+	return msg.params[len(msg.params)-1]
+}
+
+// isupportTokens returns the tokens of an RPL_ISUPPORT (005) message: the parameters between the nick and the
+// trailing "are supported by this server".
+func (msg *message) isupportTokens() []string {
+	if len(msg.params) < 3 {
+		return nil
+	}
+	return msg.params[1 : len(msg.params)-1]
+}
@@ -0,0 +1,47 @@
+package irc
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestParseMessage(t *testing.T) {
+	msg := parseMessage("@time=2024-01-01T00:00:00Z :irc.example.net 005 zg0000001 NETWORK=Example CHANTYPES=# :are supported by this server\r\n")
+	if msg.prefix != "irc.example.net" || msg.command != "005" {
+		t.Errorf("unexpected message %+v", msg)
+	}
+	if got := msg.isupportTokens(); !reflect.DeepEqual(got, []string{"NETWORK=Example", "CHANTYPES=#"}) {
+		t.Errorf("got tokens %q", got)
+	}
+	msg = parseMessage("PING :12345\r\n")
+	if msg.command != "PING" || msg.trailing() != "12345" || msg.prefix != "" {
+		t.Errorf("unexpected message %+v", msg)
+	}
+}
+
+func TestSession(t *testing.T) {
+	var sent bytes.Buffer
+	s := &session{conn: &sent, results: new(ScanResults), register: true}
+	for _, line := range []string{
+		":irc.example.net NOTICE * :*** Looking up your hostname...",
+		":irc.example.net CAP * LS * :multi-prefix sasl",
+		":irc.example.net CAP * LS :server-time",
+		"PING :abc",
+		":irc.example.net 001 zg0000001 :Welcome to the Example IRC Network",
+		":irc.example.net 004 zg0000001 irc.example.net ircd-2.11.2 aoOirw abeiIklmnoOpqrRstv",
+		":irc.example.net 005 zg0000001 NETWORK=Example :are supported by this server",
+		":irc.example.net 375 zg0000001 :- irc.example.net Message of the Day -",
+	} {
+		if err := s.handle(parseMessage(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	results := s.results
+	if !s.done || results.Version != "ircd-2.11.2" || results.Network != "Example" || len(results.Capabilities) != 3 || len(results.Notices) != 1 {
+		t.Errorf("unexpected results %+v", results)
+	}
+	if got := sent.String(); got != "CAP END\r\nPONG :abc\r\n" {
+		t.Errorf("sent %q", got)
+	}
+}
@@ -0,0 +1,293 @@
+// Package irc contains the zgrab2 Module implementation for IRC.
+//
+// The scan records the notices a server sends on connect and the IRCv3 capabilities it lists in answer to CAP LS.
+// Unless --no-register is given, it then registers a random, throwaway nick and collects the registration numerics
+// 001 to 005: the welcome message, server name and version, creation date, supported modes and the ISUPPORT tokens
+// (including the network name). It quits once the server starts sending the MOTD.
+package irc
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// maxLines bounds the number of lines read from the server.
+const maxLines = 1000
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// Notices are the NOTICEs sent before registration, e.g. "*** Looking up your hostname...".
+	Notices []string `json:"notices,omitempty"`
+
+	// Capabilities are the IRCv3 capabilities listed by CAP LS.
+	Capabilities []string `json:"capabilities,omitempty"`
+
+	Nick string `json:"nick,omitempty"`
+
+	// Welcome is the text of RPL_WELCOME (001).
+	Welcome string `json:"welcome,omitempty"`
+
+	// YourHost is the text of RPL_YOURHOST (002), which names the server and its version.
+	YourHost string `json:"your_host,omitempty"`
+
+	// Created is the text of RPL_CREATED (003).
+	Created string `json:"created,omitempty"`
+
+	// ServerName, Version, UserModes and ChannelModes come from RPL_MYINFO (004).
+	ServerName   string `json:"server_name,omitempty"`
+	Version      string `json:"version,omitempty"`
+	UserModes    string `json:"user_modes,omitempty"`
+	ChannelModes string `json:"channel_modes,omitempty"`
+
+	// ISupport are the RPL_ISUPPORT (005) tokens, e.g. NETWORK=Libera.Chat or CHANTYPES=#.
+	ISupport []string `json:"isupport,omitempty"`
+
+	// Network is the value of the NETWORK ISUPPORT token.
+	Network string `json:"network,omitempty"`
+
+	// Error is the ERROR or error numeric the server closed the registration with, e.g. a K-line.
+	Error string `json:"error,omitempty"`
+
+	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
+}
+
+// Flags are the IRC-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.TLSFlags
+
+	UseTLS     bool `long:"tls" description:"Connect over TLS (e.g. on port 6697)"`
+	NoRegister bool `long:"no-register" description:"Only record the connect notices and capabilities; do not register a nick"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the irc zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("irc", "Internet Relay Chat (IRC)", module.Description(), 6667, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Register a throwaway nick on an IRC server and record its version and ISUPPORT tokens"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "irc"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	if f.UseTLS {
+		scanner.dialerGroupConfig.TLSEnabled = true
+		scanner.dialerGroupConfig.TLSFlags = &f.TLSFlags
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// randomNick returns a nick of the form zg<7 digits>, which fits the 9 character limit of RFC 1459.
+func randomNick() string {
+	n, _ := rand.Int(rand.Reader, big.NewInt(10000000))
+	return fmt.Sprintf("zg%07d", n.Int64())
+}
+
+// session is the state of the exchange with the server.
+type session struct {
+	conn     io.Writer
+	results  *ScanResults
+	register bool
+	capsDone bool
+	done     bool
+	retried  bool
+}
+
+func (s *session) send(line string) error {
+	_, err := s.conn.Write([]byte(line + "\r\n"))
+	return err
+}
+
+// handle processes a message from the server.
+func (s *session) handle(msg *message) error {
+	results := s.results
+	switch msg.command {
+	case "PING":
+		return s.send("PONG :" + msg.trailing())
+	case "NOTICE":
+		if results.Welcome == "" {
+			results.Notices = append(results.Notices, msg.trailing())
+		}
+	case "CAP":
+		if strings.ToUpper(msg.param(1)) != "LS" {
+			return nil
+		}
+		results.Capabilities = append(results.Capabilities, strings.Fields(msg.trailing())...)
+		// "CAP * LS * :..." announces that more lines follow
+		if msg.param(2) == "*" && len(msg.params) > 3 {
+			return nil
+		}
+		s.capsDone = true
+		if !s.register {
+			s.done = true
+			return nil
+		}
+		return s.send("CAP END")
+	case "ERROR":
+		results.Error = msg.trailing()
+		s.done = true
+	case "001":
+		results.Welcome = msg.trailing()
+	case "002":
+		results.YourHost = msg.trailing()
+	case "003":
+		results.Created = msg.trailing()
+	case "004":
+		results.ServerName = msg.param(1)
+		results.Version = msg.param(2)
+		results.UserModes = msg.param(3)
+		results.ChannelModes = msg.param(4)
+	case "005":
+		for _, token := range msg.isupportTokens() {
+			results.ISupport = append(results.ISupport, token)
+			if network, ok := strings.CutPrefix(token, "NETWORK="); ok {
+				results.Network = network
+			}
+		}
+	case "433": // ERR_NICKNAMEINUSE
+		if s.retried {
+			results.Error = msg.trailing()
+			s.done = true
+			return nil
+		}
+		s.retried = true
+		results.Nick = randomNick()
+		return s.send("NICK " + results.Nick)
+	case "375", "376", "422", "251": // MOTD start/end, no MOTD, LUSERS: registration is over
+		if results.Welcome != "" {
+			s.done = true
+		}
+	case "432", "451", "463", "464", "465": // bad nick, not registered, no permission, password required, banned
+		results.Error = msg.command + " " + msg.trailing()
+		s.done = true
+	}
+	return nil
+}
+
+// Scan performs the configured scan on the IRC server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	results := new(ScanResults)
+	if tlsConn, ok := conn.(*zgrab2.TLSConnection); ok {
+		results.TLSLog = tlsConn.GetLog()
+	}
+	s := &session{conn: conn, results: results, register: !scanner.config.NoRegister}
+	// CAP LS suspends registration until CAP END, so it is sent first; servers without IRCv3 ignore it
+	request := "CAP LS 302"
+	if s.register {
+		results.Nick = randomNick()
+		request += "\r\nNICK " + results.Nick + "\r\nUSER zgrab2 0 * :zgrab2"
+	}
+	if err = s.send(request); err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error sending registration to target %s: %w", target.String(), err)
+	}
+
+	reader := bufio.NewReader(conn)
+	received := 0
+	for ; received < maxLines && !s.done; received++ {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if received == 0 {
+				return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error reading from target %s: %w", target.String(), err)
+			}
+			break
+		}
+		if err = s.handle(parseMessage(line)); err != nil {
+			break
+		}
+	}
+	if s.register && results.Welcome != "" {
+		_ = s.send("QUIT :zgrab2")
+	}
+	if results.Welcome == "" && !s.capsDone && len(results.Notices) == 0 && results.Error == "" {
+		return zgrab2.SCAN_PROTOCOL_ERROR, nil, fmt.Errorf("target %s does not look like an IRC server", target.String())
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
@@ -0,0 +1,247 @@
+package iscsi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PDU opcodes (RFC 7143 section 11.1.1).
+const (
+	opLogoutRequest = 0x06
+	opTextRequest   = 0x04
+	opLoginRequest  = 0x03
+	opTextResponse  = 0x24
+	opLoginResponse = 0x23
+	opReject        = 0x3F
+
+	// opImmediate marks a request for immediate delivery.
+	opImmediate = 0x40
+)
+
+// Flags of the second byte of login and text PDUs.
+const (
+	flagTransit  = 0x80
+	flagContinue = 0x40
+	flagFinal    = 0x80
+)
+
+// Login stages.
+const (
+	stageSecurity    = 0
+	stageOperational = 1
+	stageFullFeature = 3
+)
+
+// bhsLength is the length of the Basic Header Segment.
+const bhsLength = 48
+
+// maxDataSegmentLength bounds the data segment the scan reads.
+const maxDataSegmentLength = 1 << 20
+
+// noTargetTransferTag is the reserved target transfer tag of a new text request.
+const noTargetTransferTag = 0xFFFFFFFF
+
+var errInvalidPDU = errors.New("invalid iSCSI PDU")
+
+// pdu is a request or response, without header or data digests, which the scan never negotiates.
+type pdu struct {
+	bhs  [bhsLength]byte
+	data []byte
+}
+
+func (p *pdu) opcode() byte {
+	return p.bhs[0] & 0x3F
+}
+
+func (p *pdu) flags() byte {
+	return p.bhs[1]
+}
+
+func (p *pdu) uint32At(offset int) uint32 {
+	return binary.BigEndian.Uint32(p.bhs[offset:])
+}
+
+// marshal returns the PDU with its data segment length set and its data padded to a multiple of four bytes.
+func (p *pdu) marshal() []byte {
+	length := len(p.data)
+	p.bhs[5], p.bhs[6], p.bhs[7] = byte(length>>16), byte(length>>8), byte(length)
+	b := append(p.bhs[:], p.data...)
+	return append(b, make([]byte, (4-length%4)%4)...)
+}
+
+// readPDU reads a PDU, skipping any additional header segments.
+func readPDU(r io.Reader) (*pdu, error) {
+	p := new(pdu)
+	if _, err := io.ReadFull(r, p.bhs[:]); err != nil {
+		return nil, err
+	}
+	ahsLength := int(p.bhs[4]) * 4
+	dataLength := int(p.bhs[5])<<16 | int(p.bhs[6])<<8 | int(p.bhs[7])
+	if dataLength > maxDataSegmentLength {
+		return nil, fmt.Errorf("%w: data segment of %d bytes", errInvalidPDU, dataLength)
+	}
+	rest := make([]byte, ahsLength+dataLength+(4-dataLength%4)%4)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, err
+	}
+	p.data = rest[ahsLength : ahsLength+dataLength]
+	return p, nil
+}
+
+// encodeKeys encodes text keys as NUL-terminated key=value pairs.
+func encodeKeys(keys [][2]string) []byte {
+	var b []byte
+	for _, kv := range keys {
+		b = append(b, kv[0]+"="+kv[1]...)
+		b = append(b, 0)
+	}
+	return b
+}
+
+// keyValue is a text key and its value.
+type keyValue struct {
+	Key   string
+	Value string
+}
+
+// decodeKeys decodes NUL-terminated key=value pairs, keeping their order, since SendTargets repeats keys.
+func decodeKeys(data []byte) []keyValue {
+	var pairs []keyValue
+	for _, entry := range strings.Split(string(data), "\x00") {
+		if entry == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(entry, "=")
+		pairs = append(pairs, keyValue{Key: key, Value: value})
+	}
+	return pairs
+}
+
+// loginRequest builds a login request moving from stage csg to nsg.
+func loginRequest(isid [6]byte, itt, cmdSN, expStatSN uint32, csg, nsg byte, keys [][2]string) []byte {
+	p := &pdu{data: encodeKeys(keys)}
+	p.bhs[0] = opImmediate | opLoginRequest
+	p.bhs[1] = flagTransit | csg<<2 | nsg
+	copy(p.bhs[8:14], isid[:])
+	binary.BigEndian.PutUint32(p.bhs[16:], itt)
+	binary.BigEndian.PutUint32(p.bhs[24:], cmdSN)
+	binary.BigEndian.PutUint32(p.bhs[28:], expStatSN)
+	return p.marshal()
+}
+
+// loginResponse is the decoded content of a login response.
+type loginResponse struct {
+	transit       bool
+	nsg           byte
+	versionMax    byte
+	versionActive byte
+	tsih          uint16
+	statSN        uint32
+	expCmdSN      uint32
+	statusClass   byte
+	statusDetail  byte
+	keys          []keyValue
+}
+
+// parseLoginResponse decodes a login response.
+func parseLoginResponse(p *pdu) (*loginResponse, error) {
+	if p.opcode() == opReject {
+		return nil, fmt.Errorf("%w: target rejected the login request (reason %#02x)", errInvalidPDU, p.bhs[2])
+	}
+	if p.opcode() != opLoginResponse {
+		return nil, fmt.Errorf("%w: expected a login response, got opcode %#02x", errInvalidPDU, p.opcode())
+	}
+	return &loginResponse{
+		transit:       p.flags()&flagTransit != 0,
+		nsg:           p.flags() & 3,
+		versionMax:    p.bhs[2],
+		versionActive: p.bhs[3],
+		tsih:          binary.BigEndian.Uint16(p.bhs[14:]),
+		statSN:        p.uint32At(24),
+		expCmdSN:      p.uint32At(28),
+		statusClass:   p.bhs[36],
+		statusDetail:  p.bhs[37],
+		keys:          decodeKeys(p.data),
+	}, nil
+}
+
+// textRequest builds a text request. ttt is noTargetTransferTag for a new request, or the tag of the response
+// being continued.
+func textRequest(itt, ttt, cmdSN, expStatSN uint32, keys [][2]string) []byte {
+	p := &pdu{data: encodeKeys(keys)}
+	p.bhs[0] = opImmediate | opTextRequest
+	p.bhs[1] = flagFinal
+	binary.BigEndian.PutUint32(p.bhs[16:], itt)
+	binary.BigEndian.PutUint32(p.bhs[20:], ttt)
+	binary.BigEndian.PutUint32(p.bhs[24:], cmdSN)
+	binary.BigEndian.PutUint32(p.bhs[28:], expStatSN)
+	return p.marshal()
+}
+
+// logoutRequest builds a request to close the session.
+func logoutRequest(itt, cmdSN, expStatSN uint32) []byte {
+	p := new(pdu)
+	p.bhs[0] = opImmediate | opLogoutRequest
+	p.bhs[1] = flagFinal
+	binary.BigEndian.PutUint32(p.bhs[16:], itt)
+	binary.BigEndian.PutUint32(p.bhs[24:], cmdSN)
+	binary.BigEndian.PutUint32(p.bhs[28:], expStatSN)
+	return p.marshal()
+}
+
+// loginStatusNames names the status class and detail of login responses (RFC 7143 section 11.13.5).
+var loginStatusNames = map[uint16]string{
+	0x0000: "success",
+	0x0101: "target moved temporarily",
+	0x0102: "target moved permanently",
+	0x0201: "authentication failure",
+	0x0202: "authorization failure",
+	0x0203: "not found",
+	0x0204: "target removed",
+	0x0205: "unsupported version",
+	0x0206: "too many connections",
+	0x0207: "missing parameter",
+	0x0208: "can't include in session",
+	0x0209: "session type not supported",
+	0x020A: "session does not exist",
+	0x020B: "invalid during login",
+	0x0300: "target error",
+	0x0301: "service unavailable",
+	0x0302: "out of resources",
+}
+
+// loginStatusName names a login status.
+func loginStatusName(class, detail byte) string {
+	if name, ok := loginStatusNames[uint16(class)<<8|uint16(detail)]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown (%#02x/%#02x)", class, detail)
+}
+
+// Target is a target reported by SendTargets.
+type Target struct {
+	Name string `json:"name"`
+
+	// Addresses are the portals of the target, as address:port,portal group tag.
+	Addresses []string `json:"addresses,omitempty"`
+}
+
+// parseSendTargets groups the TargetAddress keys of a SendTargets response under the preceding TargetName.
+func parseSendTargets(pairs []keyValue) []Target {
+	var targets []Target
+	for _, kv := range pairs {
+		switch kv.Key {
+		case "TargetName":
+			targets = append(targets, Target{Name: kv.Value})
+		case "TargetAddress":
+			if len(targets) > 0 {
+				last := &targets[len(targets)-1]
+				last.Addresses = append(last.Addresses, kv.Value)
+			}
+		}
+	}
+	return targets
+}
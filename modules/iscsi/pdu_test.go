@@ -0,0 +1,97 @@
+package iscsi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// response builds a target PDU with the given opcode, flags and text keys.
+func response(opcode, flags byte, statSN uint32, keys [][2]string) []byte {
+	p := &pdu{data: encodeKeys(keys)}
+	p.bhs[0] = opcode
+	p.bhs[1] = flags
+	binary.BigEndian.PutUint32(p.bhs[24:], statSN)
+	binary.BigEndian.PutUint32(p.bhs[28:], 1)
+	return p.marshal()
+}
+
+func TestLoginRequest(t *testing.T) {
+	isid := [6]byte{0x80, 1, 2, 3, 4, 5}
+	b := loginRequest(isid, 7, 1, 0, stageSecurity, stageFullFeature, [][2]string{{"SessionType", "Discovery"}})
+	if len(b) != bhsLength+24 {
+		t.Fatalf("got %d bytes", len(b))
+	}
+	if b[0] != 0x43 || b[1] != 0x83 || !bytes.Equal(b[8:14], isid[:]) || b[7] != 22 {
+		t.Errorf("got header %x", b[:bhsLength])
+	}
+	p, err := readPDU(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := decodeKeys(p.data); len(keys) != 1 || keys[0] != (keyValue{"SessionType", "Discovery"}) {
+		t.Errorf("got keys %v", keys)
+	}
+}
+
+func TestParseSendTargets(t *testing.T) {
+	pairs := decodeKeys(encodeKeys([][2]string{
+		{"TargetName", "iqn.2003-01.org.linux-iscsi:disk1"},
+		{"TargetAddress", "192.0.2.1:3260,1"},
+		{"TargetAddress", "[2001:db8::1]:3260,1"},
+		{"TargetName", "iqn.2003-01.org.linux-iscsi:disk2"},
+	}))
+	expected := []Target{
+		{Name: "iqn.2003-01.org.linux-iscsi:disk1", Addresses: []string{"192.0.2.1:3260,1", "[2001:db8::1]:3260,1"}},
+		{Name: "iqn.2003-01.org.linux-iscsi:disk2"},
+	}
+	if targets := parseSendTargets(pairs); !reflect.DeepEqual(targets, expected) {
+		t.Errorf("got %+v", targets)
+	}
+}
+
+// TestSession logs in through the operational stage and reads a SendTargets response split over two PDUs.
+func TestSession(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		replies := [][]byte{
+			response(opLoginResponse, flagTransit|stageSecurity<<2|stageOperational, 1, [][2]string{{"AuthMethod", "None"}, {"TargetPortalGroupTag", "1"}}),
+			response(opLoginResponse, flagTransit|stageOperational<<2|stageFullFeature, 2, nil),
+			response(opTextResponse, flagContinue, 3, [][2]string{{"TargetName", "iqn.example:a"}}),
+			response(opTextResponse, flagFinal, 4, [][2]string{{"TargetAddress", "192.0.2.1:3260,1"}}),
+		}
+		for _, reply := range replies {
+			if _, err := readPDU(server); err != nil {
+				return
+			}
+			if _, err := server.Write(reply); err != nil {
+				return
+			}
+		}
+	}()
+
+	scanner := &Scanner{config: &Flags{InitiatorName: "iqn.example:initiator"}}
+	s, err := newSession(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	login, err := scanner.login(s, "None")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !login.transit || login.nsg != stageFullFeature || len(s.loginKeys) != 2 || s.loginKeys[1].Value != "1" {
+		t.Errorf("got login response %+v, keys %v", login, s.loginKeys)
+	}
+	pairs, err := s.sendTargets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Target{{Name: "iqn.example:a", Addresses: []string{"192.0.2.1:3260,1"}}}
+	if targets := parseSendTargets(pairs); !reflect.DeepEqual(targets, expected) {
+		t.Errorf("got targets %+v", targets)
+	}
+}
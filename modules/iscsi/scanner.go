@@ -0,0 +1,306 @@
+// Package iscsi contains the zgrab2 Module implementation for iSCSI.
+//
+// The scan logs in to a discovery session without authentication and issues SendTargets=All, recording the names
+// and portals of the targets the portal advertises. If the login is refused for lack of authentication, a second
+// login offering CHAP shows whether the portal requires it. The session is closed with a logout.
+package iscsi
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// maxLoginSteps bounds the login requests of a login, and maxTextResponses the text responses of SendTargets.
+const (
+	maxLoginSteps    = 4
+	maxTextResponses = 16
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// LoginStatus names the status of the final login response; StatusClass and StatusDetail are its raw values.
+	LoginStatus  string `json:"login_status"`
+	StatusClass  uint8  `json:"status_class"`
+	StatusDetail uint8  `json:"status_detail"`
+
+	VersionMax    uint8 `json:"version_max"`
+	VersionActive uint8 `json:"version_active"`
+
+	// LoginKeys are the key=value pairs the portal returned during the login.
+	LoginKeys []string `json:"login_keys,omitempty"`
+
+	// AuthRequired is true if the login without authentication failed. CHAPRequired is true if the portal then
+	// chose CHAP when offered, and AuthMethod is the method it chose.
+	AuthRequired bool   `json:"auth_required"`
+	CHAPRequired bool   `json:"chap_required"`
+	AuthMethod   string `json:"auth_method,omitempty"`
+
+	Targets []Target `json:"targets,omitempty"`
+}
+
+// Flags are the iSCSI-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+
+	InitiatorName string `long:"initiator-name" default:"iqn.2024-10.org.zmap:zgrab2" description:"iSCSI name of the initiator"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the iscsi zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("iscsi", "iSCSI", module.Description(), 3260, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Log in to an iSCSI discovery session and record the targets from SendTargets and whether CHAP is required"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "iscsi"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// session is a discovery session being set up on a connection.
+type session struct {
+	conn      net.Conn
+	isid      [6]byte
+	itt       uint32
+	cmdSN     uint32
+	expStatSN uint32
+
+	// loginKeys are the text keys of the login responses.
+	loginKeys []keyValue
+}
+
+// newSession returns a session with a random ISID of the random type.
+func newSession(conn net.Conn) (*session, error) {
+	s := &session{conn: conn, cmdSN: 1}
+	if _, err := rand.Read(s.isid[1:]); err != nil {
+		return nil, err
+	}
+	s.isid[0] = 0x80
+	return s, nil
+}
+
+// login logs in offering the given authentication methods, and returns the last login response. The portal may
+// go straight to the full feature phase or through the operational stage; a login response without the transit
+// bit in the security stage means the portal wants to authenticate.
+func (scanner *Scanner) login(s *session, authMethods string) (*loginResponse, error) {
+	csg := byte(stageSecurity)
+	requestKeys := [][2]string{
+		{"InitiatorName", scanner.config.InitiatorName},
+		{"SessionType", "Discovery"},
+		{"AuthMethod", authMethods},
+	}
+	for step := 0; step < maxLoginSteps; step++ {
+		if _, err := s.conn.Write(loginRequest(s.isid, s.itt, s.cmdSN, s.expStatSN, csg, stageFullFeature, requestKeys)); err != nil {
+			return nil, err
+		}
+		p, err := readPDU(s.conn)
+		if err != nil {
+			return nil, err
+		}
+		response, err := parseLoginResponse(p)
+		if err != nil {
+			return nil, err
+		}
+		s.expStatSN = response.statSN + 1
+		s.loginKeys = append(s.loginKeys, response.keys...)
+		if response.statusClass != 0 || (response.transit && response.nsg == stageFullFeature) {
+			return response, nil
+		}
+		if response.transit {
+			csg = response.nsg
+		} else if csg == stageSecurity {
+			return response, nil
+		}
+		requestKeys = nil
+	}
+	return nil, fmt.Errorf("%w: login did not complete in %d steps", errInvalidPDU, maxLoginSteps)
+}
+
+// sendTargets issues SendTargets=All, following continued responses.
+func (s *session) sendTargets() ([]keyValue, error) {
+	s.itt++
+	ttt := uint32(noTargetTransferTag)
+	keys := [][2]string{{"SendTargets", "All"}}
+	var data []byte
+	for i := 0; i < maxTextResponses; i++ {
+		if _, err := s.conn.Write(textRequest(s.itt, ttt, s.cmdSN, s.expStatSN, keys)); err != nil {
+			return nil, err
+		}
+		p, err := readPDU(s.conn)
+		if err != nil {
+			return nil, err
+		}
+		if p.opcode() != opTextResponse {
+			return nil, fmt.Errorf("%w: expected a text response, got opcode %#02x", errInvalidPDU, p.opcode())
+		}
+		s.expStatSN = p.uint32At(24) + 1
+		data = append(data, p.data...)
+		if p.flags()&flagContinue == 0 {
+			return decodeKeys(data), nil
+		}
+		ttt = p.uint32At(20)
+		keys = nil
+	}
+	return nil, fmt.Errorf("%w: SendTargets response continued beyond %d PDUs", errInvalidPDU, maxTextResponses)
+}
+
+// checkCHAP logs in on a new connection offering CHAP, and returns the method the portal chose.
+func (scanner *Scanner) checkCHAP(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (string, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return "", err
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+	s, err := newSession(conn)
+	if err != nil {
+		return "", err
+	}
+	if _, err = scanner.login(s, "CHAP,None"); err != nil {
+		return "", err
+	}
+	for _, kv := range s.loginKeys {
+		if kv.Key == "AuthMethod" {
+			return kv.Value, nil
+		}
+	}
+	return "", nil
+}
+
+// Scan performs the configured scan on the iSCSI portal.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+	s, err := newSession(conn)
+	if err != nil {
+		return zgrab2.SCAN_UNKNOWN_ERROR, nil, err
+	}
+
+	response, err := scanner.login(s, "None")
+	if err != nil {
+		if errors.Is(err, errInvalidPDU) || errors.Is(err, io.ErrUnexpectedEOF) {
+			err = zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+		}
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error logging in to target %s: %w", target.String(), err)
+	}
+	results := new(ScanResults)
+	for _, kv := range s.loginKeys {
+		results.LoginKeys = append(results.LoginKeys, kv.Key+"="+kv.Value)
+	}
+	results.LoginStatus = loginStatusName(response.statusClass, response.statusDetail)
+	results.StatusClass = response.statusClass
+	results.StatusDetail = response.statusDetail
+	results.VersionMax = response.versionMax
+	results.VersionActive = response.versionActive
+
+	loggedIn := response.statusClass == 0 && response.transit
+	// the portal stayed in the security stage, or failed the login with an authentication or authorization failure
+	authFailure := response.statusClass == 2 && (response.statusDetail == 1 || response.statusDetail == 2)
+	if !loggedIn && (response.statusClass == 0 || authFailure) {
+		results.AuthRequired = true
+		if results.AuthMethod, err = scanner.checkCHAP(ctx, dialGroup, target); err != nil {
+			log.Debugf("could not check CHAP on target %s: %v", target.String(), err)
+		}
+		results.CHAPRequired = results.AuthMethod == "CHAP"
+	}
+	if !loggedIn {
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+
+	s.cmdSN = response.expCmdSN
+	pairs, err := s.sendTargets()
+	if err != nil {
+		if errors.Is(err, errInvalidPDU) {
+			err = zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+		}
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error sending SendTargets to target %s: %w", target.String(), err)
+	}
+	results.Targets = parseSendTargets(pairs)
+	// the connection is closed right after, so the logout response is not awaited
+	_, _ = conn.Write(logoutRequest(s.itt+1, s.cmdSN, s.expStatSN))
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
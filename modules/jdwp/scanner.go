@@ -0,0 +1,240 @@
+// Package jdwp contains the zgrab2 Module implementation for the Java Debug Wire Protocol.
+//
+// The scan performs the JDWP handshake and sends a VirtualMachine.Version command, recording the JVM's description
+// and versions. An exposed JDWP agent allows anyone who can connect to execute arbitrary code in the JVM.
+package jdwp
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+const handshake = "JDWP-Handshake"
+
+const (
+	commandSetVirtualMachine = 1
+	commandVersion           = 1
+
+	// replyFlag marks a reply packet.
+	replyFlag = 0x80
+
+	headerLength = 11
+
+	maxPacketLength = 64 * 1024
+)
+
+var errInvalidReply = errors.New("invalid JDWP reply")
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// Handshake is true if the agent completed the JDWP handshake.
+	Handshake bool `json:"handshake"`
+
+	Description string `json:"description,omitempty"`
+	JDWPMajor   int32  `json:"jdwp_major,omitempty"`
+	JDWPMinor   int32  `json:"jdwp_minor,omitempty"`
+	VMVersion   string `json:"vm_version,omitempty"`
+	VMName      string `json:"vm_name,omitempty"`
+
+	// ErrorCode is the JDWP error the Version command failed with.
+	ErrorCode uint16 `json:"error_code,omitempty"`
+}
+
+// Flags are the JDWP-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the jdwp zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("jdwp", "Java Debug Wire Protocol (JDWP)", module.Description(), 8000, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Perform the JDWP handshake and record the JVM version"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "jdwp"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// buildCommand returns a command packet without data.
+func buildCommand(id uint32, commandSet, command uint8) []byte {
+	packet := binary.BigEndian.AppendUint32(nil, headerLength)
+	packet = binary.BigEndian.AppendUint32(packet, id)
+	return append(packet, 0, commandSet, command)
+}
+
+// readReply reads a reply packet and returns its error code and data.
+func readReply(r io.Reader, id uint32) (uint16, []byte, error) {
+	header := make([]byte, headerLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length < headerLength || length > maxPacketLength || binary.BigEndian.Uint32(header[4:8]) != id || header[8] != replyFlag {
+		return 0, nil, errInvalidReply
+	}
+	data := make([]byte, length-headerLength)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+	return binary.BigEndian.Uint16(header[9:11]), data, nil
+}
+
+// reader decodes the fields of a reply.
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) int() int32 {
+	if r.err != nil || len(r.data) < 4 {
+		r.err = errInvalidReply
+		return 0
+	}
+	v := int32(binary.BigEndian.Uint32(r.data))
+	r.data = r.data[4:]
+	return v
+}
+
+func (r *reader) string() string {
+	length := r.int()
+	if r.err != nil || length < 0 || int(length) > len(r.data) {
+		r.err = errInvalidReply
+		return ""
+	}
+	s := string(r.data[:length])
+	r.data = r.data[length:]
+	return s
+}
+
+// readVersion decodes the reply to VirtualMachine.Version.
+func readVersion(data []byte, results *ScanResults) error {
+	r := &reader{data: data}
+	results.Description = r.string()
+	results.JDWPMajor = r.int()
+	results.JDWPMinor = r.int()
+	results.VMVersion = r.string()
+	results.VMName = r.string()
+	return r.err
+}
+
+// Scan performs the configured scan on the JDWP agent.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	if _, err = conn.Write([]byte(handshake)); err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error sending handshake to target %s: %w", target.String(), err)
+	}
+	answer := make([]byte, len(handshake))
+	if _, err = io.ReadFull(conn, answer); err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error reading handshake from target %s: %w", target.String(), err)
+	}
+	if !bytes.Equal(answer, []byte(handshake)) {
+		return zgrab2.SCAN_PROTOCOL_ERROR, nil, fmt.Errorf("target %s answered the handshake with %q", target.String(), answer)
+	}
+
+	results := &ScanResults{Handshake: true}
+	const id = 1
+	if _, err = conn.Write(buildCommand(id, commandSetVirtualMachine, commandVersion)); err != nil {
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error sending Version command to target %s: %w", target.String(), err)
+	}
+	errorCode, data, err := readReply(conn, id)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error reading Version reply from target %s: %w", target.String(), err)
+	}
+	if errorCode != 0 {
+		results.ErrorCode = errorCode
+		return zgrab2.SCAN_APPLICATION_ERROR, results, fmt.Errorf("version command to target %s failed with error %d", target.String(), errorCode)
+	}
+	if err = readVersion(data, results); err != nil {
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("invalid Version reply from target %s: %w", target.String(), err)
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
@@ -0,0 +1,44 @@
+package jdwp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestReadVersion(t *testing.T) {
+	if command := buildCommand(7, commandSetVirtualMachine, commandVersion); len(command) != headerLength || command[9] != 1 || command[10] != 1 {
+		t.Fatalf("unexpected command %x", command)
+	}
+
+	appendString := func(b []byte, s string) []byte {
+		return append(binary.BigEndian.AppendUint32(b, uint32(len(s))), s...)
+	}
+	data := appendString(nil, "Java Debug Wire Protocol (Reference Implementation) version 17.0")
+	data = binary.BigEndian.AppendUint32(data, 17)
+	data = binary.BigEndian.AppendUint32(data, 0)
+	data = appendString(data, "17.0.8")
+	data = appendString(data, "OpenJDK 64-Bit Server VM")
+	reply := binary.BigEndian.AppendUint32(nil, uint32(headerLength+len(data)))
+	reply = binary.BigEndian.AppendUint32(reply, 7)
+	reply = append(reply, replyFlag, 0, 0)
+	reply = append(reply, data...)
+
+	errorCode, body, err := readReply(bytes.NewReader(reply), 7)
+	if err != nil || errorCode != 0 {
+		t.Fatalf("got error code %d (%v)", errorCode, err)
+	}
+	results := new(ScanResults)
+	if err = readVersion(body, results); err != nil {
+		t.Fatal(err)
+	}
+	if results.JDWPMajor != 17 || results.VMVersion != "17.0.8" || results.VMName != "OpenJDK 64-Bit Server VM" {
+		t.Errorf("unexpected results %+v", results)
+	}
+	if err = readVersion(body[:20], new(ScanResults)); err == nil {
+		t.Error("expected error for truncated reply")
+	}
+	if _, _, err = readReply(bytes.NewReader(reply), 8); err == nil {
+		t.Error("expected error for mismatched ID")
+	}
+}
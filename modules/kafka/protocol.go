@@ -0,0 +1,311 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// API keys used by the scan.
+const (
+	apiMetadata      = 3
+	apiSaslHandshake = 17
+	apiApiVersions   = 18
+)
+
+// maxMetadataVersion is the last Metadata version without tagged fields, which the scan does not encode.
+const maxMetadataVersion = 8
+
+// maxResponseLength bounds the responses the scan reads.
+const maxResponseLength = 1 << 20
+
+// Error codes the scan interprets.
+const (
+	errCodeNone                     = 0
+	errCodeUnsupportedSaslMechanism = 33
+)
+
+var errInvalidResponse = errors.New("invalid Kafka response")
+
+// apiNames names the API keys (https://kafka.apache.org/protocol#protocol_api_keys).
+var apiNames = map[int16]string{
+	0:  "Produce",
+	1:  "Fetch",
+	2:  "ListOffsets",
+	3:  "Metadata",
+	4:  "LeaderAndIsr",
+	5:  "StopReplica",
+	6:  "UpdateMetadata",
+	7:  "ControlledShutdown",
+	8:  "OffsetCommit",
+	9:  "OffsetFetch",
+	10: "FindCoordinator",
+	11: "JoinGroup",
+	12: "Heartbeat",
+	13: "LeaveGroup",
+	14: "SyncGroup",
+	15: "DescribeGroups",
+	16: "ListGroups",
+	17: "SaslHandshake",
+	18: "ApiVersions",
+	19: "CreateTopics",
+	20: "DeleteTopics",
+	21: "DeleteRecords",
+	22: "InitProducerId",
+	23: "OffsetForLeaderEpoch",
+	24: "AddPartitionsToTxn",
+	25: "AddOffsetsToTxn",
+	26: "EndTxn",
+	27: "WriteTxnMarkers",
+	28: "TxnOffsetCommit",
+	29: "DescribeAcls",
+	30: "CreateAcls",
+	31: "DeleteAcls",
+	32: "DescribeConfigs",
+	33: "AlterConfigs",
+	34: "AlterReplicaLogDirs",
+	35: "DescribeLogDirs",
+	36: "SaslAuthenticate",
+	37: "CreatePartitions",
+	38: "CreateDelegationToken",
+	39: "RenewDelegationToken",
+	40: "ExpireDelegationToken",
+	41: "DescribeDelegationToken",
+	42: "DeleteGroups",
+	43: "ElectLeaders",
+	44: "IncrementalAlterConfigs",
+	45: "AlterPartitionReassignments",
+	46: "ListPartitionReassignments",
+	47: "OffsetDelete",
+	48: "DescribeClientQuotas",
+	49: "AlterClientQuotas",
+	50: "DescribeUserScramCredentials",
+	51: "AlterUserScramCredentials",
+	55: "DescribeQuorum",
+	57: "UpdateFeatures",
+	60: "DescribeCluster",
+	61: "DescribeProducers",
+	64: "UnregisterBroker",
+	65: "DescribeTransactions",
+	66: "ListTransactions",
+	68: "ConsumerGroupHeartbeat",
+	69: "ConsumerGroupDescribe",
+	71: "GetTelemetrySubscriptions",
+	72: "PushTelemetry",
+	74: "ListClientMetricsResources",
+	75: "DescribeTopicPartitions",
+}
+
+func apiName(key int16) string {
+	if name, ok := apiNames[key]; ok {
+		return name
+	}
+	return "api_" + strconv.Itoa(int(key))
+}
+
+// request builds a request with a version 1 header.
+func request(apiKey, apiVersion int16, correlationID int32, clientID string, body []byte) []byte {
+	b := make([]byte, 4, 14+len(clientID)+len(body))
+	b = binary.BigEndian.AppendUint16(b, uint16(apiKey))
+	b = binary.BigEndian.AppendUint16(b, uint16(apiVersion))
+	b = binary.BigEndian.AppendUint32(b, uint32(correlationID))
+	b = appendString(b, clientID)
+	b = append(b, body...)
+	binary.BigEndian.PutUint32(b, uint32(len(b)-4))
+	return b
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// readResponse reads a response with a version 0 header and returns its body.
+func readResponse(r io.Reader, correlationID int32) (*decoder, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[0:])
+	if length < 4 || length > maxResponseLength {
+		return nil, fmt.Errorf("%w: length %d", errInvalidResponse, length)
+	}
+	if id := int32(binary.BigEndian.Uint32(header[4:])); id != correlationID {
+		return nil, fmt.Errorf("%w: correlation ID %d in place of %d", errInvalidResponse, id, correlationID)
+	}
+	body := make([]byte, length-4)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return &decoder{b: body}, nil
+}
+
+// decoder reads the fields of a response, recording the first error.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.b) < n {
+		d.err = fmt.Errorf("%w: %d bytes left, %d needed", errInvalidResponse, len(d.b), n)
+		return nil
+	}
+	v := d.b[:n]
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) int16() int16 {
+	if b := d.take(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *decoder) int32() int32 {
+	if b := d.take(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+// nullableString returns a string, and nil for a null string.
+func (d *decoder) nullableString() *string {
+	n := d.int16()
+	if n < 0 {
+		return nil
+	}
+	s := string(d.take(int(n)))
+	return &s
+}
+
+func (d *decoder) string() string {
+	if s := d.nullableString(); s != nil {
+		return *s
+	}
+	return ""
+}
+
+// arrayLength returns the length of an array, bounded by the bytes left to keep a forged length from allocating.
+func (d *decoder) arrayLength(minElementLength int) int {
+	n := int(d.int32())
+	if n > len(d.b)/minElementLength {
+		d.err = fmt.Errorf("%w: array of %d elements in %d bytes", errInvalidResponse, n, len(d.b))
+		return 0
+	}
+	return max(n, 0)
+}
+
+// APIVersion is the range of versions a broker supports for an API.
+type APIVersion struct {
+	Key        int16  `json:"key"`
+	Name       string `json:"name"`
+	MinVersion int16  `json:"min_version"`
+	MaxVersion int16  `json:"max_version"`
+}
+
+// parseApiVersions decodes an ApiVersions version 0 response.
+func parseApiVersions(d *decoder) (int16, []APIVersion, error) {
+	errorCode := d.int16()
+	n := d.arrayLength(6)
+	versions := make([]APIVersion, 0, n)
+	for i := 0; i < n; i++ {
+		key := d.int16()
+		versions = append(versions, APIVersion{Key: key, Name: apiName(key), MinVersion: d.int16(), MaxVersion: d.int16()})
+	}
+	return errorCode, versions, d.err
+}
+
+// supported returns the highest version of the API that both the broker and the scan support, or -1.
+func supported(versions []APIVersion, key, maxVersion int16) int16 {
+	for _, v := range versions {
+		if v.Key == key && v.MinVersion <= maxVersion {
+			return min(v.MaxVersion, maxVersion)
+		}
+	}
+	return -1
+}
+
+// metadataRequest builds the body of a Metadata request for no topics.
+func metadataRequest(version int16) []byte {
+	var b []byte
+	if version == 0 {
+		// version 0 has no null topic array, and an empty one means all topics
+		return binary.BigEndian.AppendUint32(b, 0)
+	}
+	b = binary.BigEndian.AppendUint32(b, 0)
+	if version >= 4 {
+		// allow_auto_topic_creation
+		b = append(b, 0)
+	}
+	if version >= 8 {
+		// include_cluster_authorized_operations, include_topic_authorized_operations
+		b = append(b, 0, 0)
+	}
+	return b
+}
+
+// Broker is a broker of the cluster.
+type Broker struct {
+	NodeID int32   `json:"node_id"`
+	Host   string  `json:"host"`
+	Port   int32   `json:"port"`
+	Rack   *string `json:"rack,omitempty"`
+}
+
+// Metadata is the cluster metadata from a Metadata response.
+type Metadata struct {
+	Brokers      []Broker `json:"brokers,omitempty"`
+	ClusterID    *string  `json:"cluster_id,omitempty"`
+	ControllerID *int32   `json:"controller_id,omitempty"`
+}
+
+// parseMetadata decodes the brokers, cluster ID and controller of a Metadata response, ignoring the topics.
+func parseMetadata(d *decoder, version int16) (*Metadata, error) {
+	if version >= 3 {
+		// throttle_time_ms
+		d.int32()
+	}
+	m := new(Metadata)
+	n := d.arrayLength(10)
+	for i := 0; i < n; i++ {
+		broker := Broker{NodeID: d.int32(), Host: d.string(), Port: d.int32()}
+		if version >= 1 {
+			broker.Rack = d.nullableString()
+		}
+		m.Brokers = append(m.Brokers, broker)
+	}
+	if version >= 2 {
+		m.ClusterID = d.nullableString()
+	}
+	if version >= 1 {
+		controller := d.int32()
+		m.ControllerID = &controller
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return m, nil
+}
+
+// saslHandshakeRequest builds the body of a SaslHandshake request for the mechanism.
+func saslHandshakeRequest(mechanism string) []byte {
+	return appendString(nil, mechanism)
+}
+
+// parseSaslHandshake decodes a SaslHandshake response, returning the broker's enabled mechanisms.
+func parseSaslHandshake(d *decoder) (int16, []string, error) {
+	errorCode := d.int16()
+	n := d.arrayLength(2)
+	mechanisms := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		mechanisms = append(mechanisms, d.string())
+	}
+	return errorCode, mechanisms, d.err
+}
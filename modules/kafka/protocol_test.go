@@ -0,0 +1,157 @@
+package kafka
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/zmap/zgrab2"
+)
+
+// response builds a response with a version 0 header.
+func response(correlationID int32, body []byte) []byte {
+	b := binary.BigEndian.AppendUint32(nil, uint32(4+len(body)))
+	b = binary.BigEndian.AppendUint32(b, uint32(correlationID))
+	return append(b, body...)
+}
+
+func apiVersionsBody() []byte {
+	b := []byte{0, 0, 0, 0, 0, 3}
+	for _, v := range [][3]int16{{apiMetadata, 0, 12}, {apiSaslHandshake, 0, 1}, {apiApiVersions, 0, 3}} {
+		for _, n := range v {
+			b = binary.BigEndian.AppendUint16(b, uint16(n))
+		}
+	}
+	return b
+}
+
+func TestRequest(t *testing.T) {
+	b := request(apiApiVersions, 0, 1, "zgrab2", nil)
+	expected := []byte{0, 0, 0, 16, 0, 18, 0, 0, 0, 0, 0, 1, 0, 6, 'z', 'g', 'r', 'a', 'b', '2'}
+	if !bytes.Equal(b, expected) {
+		t.Errorf("got %x", b)
+	}
+}
+
+func TestParseApiVersions(t *testing.T) {
+	d, err := readResponse(bytes.NewReader(response(1, apiVersionsBody())), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errorCode, versions, err := parseApiVersions(d)
+	if err != nil || errorCode != 0 {
+		t.Fatalf("got %d, %v", errorCode, err)
+	}
+	if len(versions) != 3 || versions[0] != (APIVersion{Key: 3, Name: "Metadata", MinVersion: 0, MaxVersion: 12}) {
+		t.Errorf("got %+v", versions)
+	}
+	if v := supported(versions, apiMetadata, maxMetadataVersion); v != 8 {
+		t.Errorf("got Metadata version %d", v)
+	}
+	if v := supported(versions, 0, 3); v != -1 {
+		t.Errorf("got Produce version %d", v)
+	}
+	if _, err = readResponse(bytes.NewReader(response(2, nil)), 1); !errors.Is(err, errInvalidResponse) {
+		t.Errorf("got %v for another correlation ID", err)
+	}
+	// a TLS alert read as a response
+	if _, err = readResponse(bytes.NewReader([]byte{0x15, 0x03, 0x03, 0x00, 0x02, 0x02, 0x46, 0}), 1); !errors.Is(err, errInvalidResponse) {
+		t.Errorf("got %v for a TLS alert", err)
+	}
+}
+
+func TestParseMetadata(t *testing.T) {
+	var b []byte
+	b = binary.BigEndian.AppendUint32(b, 0)
+	b = binary.BigEndian.AppendUint32(b, 2)
+	for i, host := range []string{"kafka-0.internal", "kafka-1.internal"} {
+		b = binary.BigEndian.AppendUint32(b, uint32(i))
+		b = appendString(b, host)
+		b = binary.BigEndian.AppendUint32(b, 9092)
+		b = binary.BigEndian.AppendUint16(b, 0xFFFF)
+	}
+	b = appendString(b, "MkU3OEVBNTcwNTJENDM2Qg")
+	b = binary.BigEndian.AppendUint32(b, 1)
+	b = binary.BigEndian.AppendUint32(b, 0)
+	b = binary.BigEndian.AppendUint32(b, 0)
+	m, err := parseMetadata(&decoder{b: b}, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Brokers) != 2 || m.Brokers[1] != (Broker{NodeID: 1, Host: "kafka-1.internal", Port: 9092}) {
+		t.Errorf("got brokers %+v", m.Brokers)
+	}
+	if *m.ClusterID != "MkU3OEVBNTcwNTJENDM2Qg" || *m.ControllerID != 1 {
+		t.Errorf("got cluster %s, controller %d", *m.ClusterID, *m.ControllerID)
+	}
+	if _, err = parseMetadata(&decoder{b: b[:20]}, 8); !errors.Is(err, errInvalidResponse) {
+		t.Errorf("got %v for a truncated response", err)
+	}
+}
+
+// TestSASLRequired scans a fake SASL listener, which answers ApiVersions and SaslHandshake and closes the
+// connection on Metadata.
+func TestSASLRequired(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				header := make([]byte, 12)
+				for {
+					if _, err := conn.Read(header); err != nil {
+						return
+					}
+					length := binary.BigEndian.Uint32(header)
+					rest := make([]byte, length-8)
+					conn.Read(rest)
+					correlationID := int32(binary.BigEndian.Uint32(header[8:]))
+					switch binary.BigEndian.Uint16(header[4:]) {
+					case apiApiVersions:
+						conn.Write(response(correlationID, apiVersionsBody()))
+					case apiSaslHandshake:
+						body := []byte{0, errCodeUnsupportedSaslMechanism, 0, 0, 0, 2}
+						body = appendString(appendString(body, "PLAIN"), "SCRAM-SHA-512")
+						conn.Write(response(correlationID, body))
+					default:
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	var module Module
+	flags := module.NewFlags().(*Flags)
+	flags.ClientID = "zgrab2"
+	flags.NoTLSFallback = true
+	scanner := module.NewScanner()
+	if err = scanner.Init(flags); err != nil {
+		t.Fatal(err)
+	}
+	dialGroup, err := scanner.GetDialerGroupConfig().GetDefaultDialerGroupFromConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().(*net.TCPAddr)
+	target := &zgrab2.ScanTarget{IP: addr.IP, Port: uint(addr.Port)}
+	status, result, err := scanner.Scan(t.Context(), dialGroup, target)
+	if status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("got %s, %v", status, err)
+	}
+	results := result.(*ScanResults)
+	if !results.SASLRequired || !reflect.DeepEqual(results.SASLMechanisms, []string{"PLAIN", "SCRAM-SHA-512"}) || len(results.APIVersions) != 3 {
+		t.Errorf("got %+v", results)
+	}
+}
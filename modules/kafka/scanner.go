@@ -0,0 +1,273 @@
+// Package kafka contains the zgrab2 Module implementation for the Kafka wire protocol.
+//
+// The scan sends ApiVersions to record the range of versions the broker supports for each API, and then Metadata,
+// at the highest version both sides support, for no topics, to record the cluster ID, the controller and the
+// brokers of the cluster. A broker on a SASL listener answers ApiVersions but closes the connection on Metadata;
+// the scan then sends SaslHandshake on a new connection to record the enabled mechanisms. If the broker does not
+// answer ApiVersions in plaintext, the scan retries over TLS, unless --use-tls already selected it.
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// TLSRequired is true if the broker only answered over TLS.
+	TLSRequired bool `json:"tls_required"`
+
+	APIVersionsError int16        `json:"api_versions_error"`
+	APIVersions      []APIVersion `json:"api_versions,omitempty"`
+
+	Metadata    *Metadata `json:"metadata,omitempty"`
+	BrokerCount int       `json:"broker_count"`
+
+	// SASLRequired is true if the broker closed the connection on Metadata, and SASLMechanisms are the mechanisms
+	// it then listed in answer to SaslHandshake.
+	SASLRequired   bool     `json:"sasl_required"`
+	SASLMechanisms []string `json:"sasl_mechanisms,omitempty"`
+
+	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
+}
+
+// Flags are the Kafka-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.TLSFlags
+
+	UseTLS        bool   `long:"use-tls" description:"Connect over TLS"`
+	NoTLSFallback bool   `long:"no-tls-fallback" description:"Do not retry over TLS if the broker does not answer in plaintext"`
+	ClientID      string `long:"client-id" default:"zgrab2" description:"Client ID sent in the request headers"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the kafka zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("kafka", "Kafka", module.Description(), 9092, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Send ApiVersions and Metadata to a Kafka broker and record the supported versions, cluster and whether SASL or TLS is required"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "kafka"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		NeedSeparateL4Dialer:            true,
+		BaseFlags:                       &f.BaseFlags,
+		TLSEnabled:                      true,
+		TLSFlags:                        &f.TLSFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// Correlation IDs of the requests.
+const (
+	correlationApiVersions = iota + 1
+	correlationMetadata
+	correlationSaslHandshake
+)
+
+// connect opens a connection to the broker, over TLS if useTLS is set.
+func (scanner *Scanner) connect(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget, useTLS bool, results *ScanResults) (net.Conn, error) {
+	conn, err := dialGroup.L4Dialer(target)(ctx, "tcp", net.JoinHostPort(target.Host(), strconv.Itoa(int(target.Port))))
+	if err != nil || !useTLS {
+		return conn, err
+	}
+	tlsConn, err := dialGroup.TLSWrapper(ctx, target, conn)
+	if tlsConn != nil {
+		results.TLSLog = tlsConn.GetLog()
+	}
+	if err != nil {
+		zgrab2.CloseConnAndHandleError(conn)
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// call sends a request and returns the decoder of its response.
+func (scanner *Scanner) call(conn net.Conn, apiKey, apiVersion int16, correlationID int32, body []byte) (*decoder, error) {
+	if _, err := conn.Write(request(apiKey, apiVersion, correlationID, scanner.config.ClientID, body)); err != nil {
+		return nil, err
+	}
+	return readResponse(conn, correlationID)
+}
+
+// apiVersions sends ApiVersions and records the broker's answer.
+func (scanner *Scanner) apiVersions(conn net.Conn, results *ScanResults) error {
+	d, err := scanner.call(conn, apiApiVersions, 0, correlationApiVersions, nil)
+	if err != nil {
+		return err
+	}
+	results.APIVersionsError, results.APIVersions, err = parseApiVersions(d)
+	return err
+}
+
+// closed reports whether err means the broker closed the connection.
+func closed(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// saslMechanisms sends SaslHandshake for a mechanism no broker enables, which the broker answers with the
+// mechanisms it does enable.
+func (scanner *Scanner) saslMechanisms(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget, useTLS bool, results *ScanResults) ([]string, error) {
+	version := supported(results.APIVersions, apiSaslHandshake, 1)
+	if version < 0 {
+		return nil, nil
+	}
+	conn, err := scanner.connect(ctx, dialGroup, target, useTLS, results)
+	if err != nil {
+		return nil, err
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+	d, err := scanner.call(conn, apiSaslHandshake, version, correlationSaslHandshake, saslHandshakeRequest("ZGRAB2-PROBE"))
+	if err != nil {
+		return nil, err
+	}
+	errorCode, mechanisms, err := parseSaslHandshake(d)
+	if err != nil {
+		return nil, err
+	}
+	if errorCode != errCodeNone && errorCode != errCodeUnsupportedSaslMechanism {
+		return nil, fmt.Errorf("SaslHandshake failed with error code %d", errorCode)
+	}
+	return mechanisms, nil
+}
+
+// Scan sends ApiVersions and Metadata to the broker.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	results := new(ScanResults)
+	useTLS := scanner.config.UseTLS
+	conn, err := scanner.connect(ctx, dialGroup, target, useTLS, results)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	err = scanner.apiVersions(conn, results)
+	if err != nil && !useTLS && !scanner.config.NoTLSFallback && (closed(err) || errors.Is(err, errInvalidResponse)) {
+		tlsConn, tlsErr := scanner.connect(ctx, dialGroup, target, true, results)
+		if tlsErr == nil {
+			if tlsErr = scanner.apiVersions(tlsConn, results); tlsErr == nil {
+				zgrab2.CloseConnAndHandleError(conn)
+				conn, err, useTLS = tlsConn, nil, true
+				results.TLSRequired = true
+			} else {
+				zgrab2.CloseConnAndHandleError(tlsConn)
+			}
+		}
+		if tlsErr != nil {
+			log.Debugf("no answer to ApiVersions over TLS from target %s: %v", target.String(), tlsErr)
+			results.TLSLog = nil
+		}
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+	if err != nil {
+		return status(err), nil, fmt.Errorf("error sending ApiVersions to target %s: %w", target.String(), err)
+	}
+
+	version := supported(results.APIVersions, apiMetadata, maxMetadataVersion)
+	if version < 0 {
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+	d, err := scanner.call(conn, apiMetadata, version, correlationMetadata, metadataRequest(version))
+	if err != nil {
+		if !closed(err) {
+			return status(err), results, fmt.Errorf("error sending Metadata to target %s: %w", target.String(), err)
+		}
+		results.SASLRequired = true
+		if results.SASLMechanisms, err = scanner.saslMechanisms(ctx, dialGroup, target, useTLS, results); err != nil {
+			log.Debugf("error listing SASL mechanisms of target %s: %v", target.String(), err)
+		}
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+	if results.Metadata, err = parseMetadata(d, version); err != nil {
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("invalid Metadata response from target %s: %w", target.String(), err)
+	}
+	results.BrokerCount = len(results.Metadata.Brokers)
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
+
+// status maps an error to a scan status, treating malformed and truncated responses as protocol errors.
+func status(err error) zgrab2.ScanStatus {
+	if errors.Is(err, errInvalidResponse) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return zgrab2.SCAN_PROTOCOL_ERROR
+	}
+	return zgrab2.TryGetScanStatus(err)
+}
@@ -0,0 +1,250 @@
+package kerberos
+
+import (
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Kerberos message types (RFC 4120 section 7.5.7)
+const (
+	msgTypeASReq    = 10
+	msgTypeKRBError = 30
+)
+
+// Pre-authentication data types carrying supported encryption types (RFC 4120 section 7.5.2)
+const (
+	paETypeInfo  = 11
+	paETypeInfo2 = 19
+)
+
+// Error codes (RFC 4120 section 7.5.9)
+var errorCodeNames = map[int32]string{
+	6:  "KDC_ERR_C_PRINCIPAL_UNKNOWN",
+	7:  "KDC_ERR_S_PRINCIPAL_UNKNOWN",
+	12: "KDC_ERR_POLICY",
+	14: "KDC_ERR_ETYPE_NOSUPP",
+	18: "KDC_ERR_CLIENT_REVOKED",
+	24: "KDC_ERR_PREAUTH_FAILED",
+	25: "KDC_ERR_PREAUTH_REQUIRED",
+	37: "KRB_AP_ERR_SKEW",
+	52: "KRB_ERR_RESPONSE_TOO_BIG",
+	60: "KRB_ERR_GENERIC",
+	68: "KDC_ERR_WRONG_REALM",
+}
+
+// Encryption types (RFC 3961 section 8 and the IANA registry)
+var etypeNames = map[int32]string{
+	1:  "des-cbc-crc",
+	3:  "des-cbc-md5",
+	16: "des3-cbc-sha1-kd",
+	17: "aes128-cts-hmac-sha1-96",
+	18: "aes256-cts-hmac-sha1-96",
+	19: "aes128-cts-hmac-sha256-128",
+	20: "aes256-cts-hmac-sha384-192",
+	23: "rc4-hmac",
+	24: "rc4-hmac-exp",
+	25: "camellia128-cts-cmac",
+	26: "camellia256-cts-cmac",
+}
+
+// requestedETypes are offered in the AS-REQ, strongest first.
+var requestedETypes = []int32{20, 19, 18, 17, 26, 25, 16, 23, 24, 3, 1}
+
+// EncryptionType is an encryption type the KDC announced for the principal.
+type EncryptionType struct {
+	Value int32  `json:"value"`
+	Name  string `json:"name,omitempty"`
+	Salt  string `json:"salt,omitempty"`
+}
+
+// KRBError holds the fields of interest of a KRB-ERROR.
+type KRBError struct {
+	ErrorCode int32    `json:"error_code"`
+	ErrorName string   `json:"error_name,omitempty"`
+	Realm     string   `json:"realm,omitempty"`
+	CRealm    string   `json:"crealm,omitempty"`
+	SName     []string `json:"sname,omitempty"`
+	EText     string   `json:"e_text,omitempty"`
+	EData     []byte   `json:"e_data,omitempty"`
+}
+
+type principalName struct {
+	NameType   int32    `asn1:"explicit,tag:0"`
+	NameString []string `asn1:"explicit,tag:1"`
+}
+
+type krbError struct {
+	PVNO      int           `asn1:"explicit,tag:0"`
+	MsgType   int           `asn1:"explicit,tag:1"`
+	CTime     time.Time     `asn1:"generalized,optional,explicit,tag:2"`
+	CUSec     int           `asn1:"optional,explicit,tag:3"`
+	STime     time.Time     `asn1:"generalized,explicit,tag:4"`
+	SUSec     int           `asn1:"explicit,tag:5"`
+	ErrorCode int32         `asn1:"explicit,tag:6"`
+	CRealm    string        `asn1:"optional,explicit,tag:7"`
+	CName     principalName `asn1:"optional,explicit,tag:8"`
+	Realm     string        `asn1:"explicit,tag:9"`
+	SName     principalName `asn1:"explicit,tag:10"`
+	EText     string        `asn1:"optional,explicit,tag:11"`
+	EData     []byte        `asn1:"optional,explicit,tag:12"`
+}
+
+type paData struct {
+	Type  int32  `asn1:"explicit,tag:1"`
+	Value []byte `asn1:"explicit,tag:2"`
+}
+
+type etypeInfo2Entry struct {
+	EType     int32  `asn1:"explicit,tag:0"`
+	Salt      string `asn1:"optional,explicit,tag:1"`
+	S2KParams []byte `asn1:"optional,explicit,tag:2"`
+}
+
+type etypeInfoEntry struct {
+	EType int32  `asn1:"explicit,tag:0"`
+	Salt  []byte `asn1:"optional,explicit,tag:1"`
+}
+
+// kerbErrorData is the Windows KERB-ERROR-DATA carried in e-data ([MS-KILE] 2.2.1).
+type kerbErrorData struct {
+	DataType  int32  `asn1:"explicit,tag:1"`
+	DataValue []byte `asn1:"optional,explicit,tag:2"`
+}
+
+var errNotKRBError = errors.New("response is not a KRB-ERROR")
+
+// der returns the DER TLV with the given identifier octet and content.
+func der(tag byte, parts ...[]byte) []byte {
+	var content []byte
+	for _, part := range parts {
+		content = append(content, part...)
+	}
+	out := []byte{tag}
+	switch n := len(content); {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n <= 0xFF:
+		out = append(out, 0x81, byte(n))
+	default:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	}
+	return append(out, content...)
+}
+
+// explicit wraps content in a context-specific constructed tag.
+func explicit(tag byte, content []byte) []byte {
+	return der(0xA0|tag, content)
+}
+
+func derInteger(v int64) []byte {
+	b, _ := asn1.Marshal(v)
+	return b
+}
+
+func derGeneralString(s string) []byte {
+	return der(0x1B, []byte(s))
+}
+
+func derPrincipal(nameType int64, names ...string) []byte {
+	var strings [][]byte
+	for _, name := range names {
+		strings = append(strings, derGeneralString(name))
+	}
+	return der(0x30, explicit(0, derInteger(nameType)), explicit(1, der(0x30, strings...)))
+}
+
+// buildASReq returns an AS-REQ without pre-authentication for client@realm, requesting a TGT.
+func buildASReq(client, realm string, nonce uint32) []byte {
+	var etypes [][]byte
+	for _, etype := range requestedETypes {
+		etypes = append(etypes, derInteger(int64(etype)))
+	}
+	body := der(0x30,
+		explicit(0, der(0x03, []byte{0x00, 0x50, 0x80, 0x00, 0x10})), // forwardable, proxiable, renewable, renewable-ok
+		explicit(1, derPrincipal(1, client)),                         // NT-PRINCIPAL
+		explicit(2, derGeneralString(realm)),
+		explicit(3, derPrincipal(2, "krbtgt", realm)), // NT-SRV-INST
+		explicit(5, der(0x18, []byte("20370913024805Z"))),
+		explicit(7, derInteger(int64(nonce))),
+		explicit(8, der(0x30, etypes...)),
+	)
+	req := der(0x30,
+		explicit(1, derInteger(5)),
+		explicit(2, derInteger(msgTypeASReq)),
+		explicit(4, body),
+	)
+	return der(0x6A, req) // [APPLICATION 10]
+}
+
+// parseKRBError parses a KRB-ERROR.
+func parseKRBError(data []byte) (*krbError, error) {
+	if len(data) == 0 || data[0] != 0x7E { // [APPLICATION 30]
+		return nil, errNotKRBError
+	}
+	var e krbError
+	if _, err := asn1.UnmarshalWithParams(data, &e, "application,explicit,tag:30"); err != nil {
+		return nil, fmt.Errorf("invalid KRB-ERROR: %w", err)
+	}
+	return &e, nil
+}
+
+// supportedETypes extracts the encryption types from the PA-ETYPE-INFO2 (or PA-ETYPE-INFO) in the METHOD-DATA of a
+// KDC_ERR_PREAUTH_REQUIRED error.
+func supportedETypes(eData []byte) []EncryptionType {
+	var methods []paData
+	if _, err := asn1.Unmarshal(eData, &methods); err != nil {
+		return nil
+	}
+	var etypes []EncryptionType
+	for _, method := range methods {
+		switch method.Type {
+		case paETypeInfo2:
+			var entries []etypeInfo2Entry
+			if _, err := asn1.Unmarshal(method.Value, &entries); err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				etypes = append(etypes, EncryptionType{Value: entry.EType, Name: etypeNames[entry.EType], Salt: entry.Salt})
+			}
+			return etypes
+		case paETypeInfo:
+			var entries []etypeInfoEntry
+			if _, err := asn1.Unmarshal(method.Value, &entries); err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				etypes = append(etypes, EncryptionType{Value: entry.EType, Name: etypeNames[entry.EType], Salt: string(entry.Salt)})
+			}
+		}
+	}
+	return etypes
+}
+
+var (
+	mitETextRegex     = regexp.MustCompile(`^[A-Z_]+$`)
+	heimdalETextRegex = regexp.MustCompile(`^Client \(.*\) unknown|^Need to use PA-`)
+)
+
+// identifyImplementation guesses the KDC implementation from the way it phrases the error. This is a heuristic:
+// MIT krb5 sends its internal status (e.g. "CLIENT_NOT_FOUND") as e-text, Heimdal sends a sentence, and Active
+// Directory sends no e-text but a KERB-ERROR-DATA (or, for pre-authentication, METHOD-DATA) in e-data.
+func identifyImplementation(e *krbError) string {
+	switch {
+	case mitETextRegex.MatchString(e.EText):
+		return "mit"
+	case heimdalETextRegex.MatchString(e.EText):
+		return "heimdal"
+	case e.EText == "" && len(e.EData) > 0:
+		var data kerbErrorData
+		if _, err := asn1.Unmarshal(e.EData, &data); err == nil && data.DataType != 0 {
+			return "windows"
+		}
+		if e.ErrorCode == 25 && len(supportedETypes(e.EData)) > 0 {
+			return "windows"
+		}
+	}
+	return ""
+}
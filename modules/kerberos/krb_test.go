@@ -0,0 +1,87 @@
+package kerberos
+
+import (
+	"encoding/asn1"
+	"testing"
+	"time"
+)
+
+// buildKRBError encodes a KRB-ERROR the way a KDC would.
+func buildKRBError(code int64, etext string, edata []byte) []byte {
+	fields := [][]byte{
+		explicit(0, derInteger(5)),
+		explicit(1, derInteger(msgTypeKRBError)),
+		explicit(4, der(0x18, []byte("20240102030405Z"))),
+		explicit(5, derInteger(123456)),
+		explicit(6, derInteger(code)),
+		explicit(9, derGeneralString("CORP.EXAMPLE")),
+		explicit(10, derPrincipal(2, "krbtgt", "CORP.EXAMPLE")),
+	}
+	if etext != "" {
+		fields = append(fields, explicit(11, derGeneralString(etext)))
+	}
+	if edata != nil {
+		fields = append(fields, explicit(12, der(0x04, edata)))
+	}
+	return der(0x7E, der(0x30, fields...))
+}
+
+func TestBuildASReq(t *testing.T) {
+	var raw asn1.RawValue
+	rest, err := asn1.Unmarshal(buildASReq("alice", "CORP.EXAMPLE", 42), &raw)
+	if err != nil || len(rest) != 0 {
+		t.Fatalf("AS-REQ is not valid DER: %v", err)
+	}
+	if raw.Class != asn1.ClassApplication || raw.Tag != msgTypeASReq {
+		t.Errorf("unexpected outer tag %d/%d", raw.Class, raw.Tag)
+	}
+}
+
+func TestParseKRBError(t *testing.T) {
+	krbErr, err := parseKRBError(buildKRBError(6, "CLIENT_NOT_FOUND", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if krbErr.ErrorCode != 6 || krbErr.Realm != "CORP.EXAMPLE" || krbErr.SUSec != 123456 {
+		t.Errorf("unexpected error %+v", krbErr)
+	}
+	if want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC); !krbErr.STime.Equal(want) {
+		t.Errorf("got server time %s, want %s", krbErr.STime, want)
+	}
+	if len(krbErr.SName.NameString) != 2 || krbErr.SName.NameString[0] != "krbtgt" {
+		t.Errorf("unexpected sname %v", krbErr.SName.NameString)
+	}
+	if impl := identifyImplementation(krbErr); impl != "mit" {
+		t.Errorf("got implementation %q, want mit", impl)
+	}
+
+	if _, err = parseKRBError([]byte{0x6B, 0x00}); err != errNotKRBError {
+		t.Errorf("expected errNotKRBError, got %v", err)
+	}
+}
+
+func TestSupportedETypes(t *testing.T) {
+	etypeInfo2 := der(0x30,
+		der(0x30, explicit(0, derInteger(18)), explicit(1, derGeneralString("CORP.EXAMPLEalice"))),
+		der(0x30, explicit(0, derInteger(23))),
+	)
+	methodData := der(0x30,
+		der(0x30, explicit(1, derInteger(2)), explicit(2, der(0x04))),
+		der(0x30, explicit(1, derInteger(paETypeInfo2)), explicit(2, der(0x04, etypeInfo2))),
+	)
+	krbErr, err := parseKRBError(buildKRBError(25, "", methodData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	etypes := supportedETypes(krbErr.EData)
+	want := []EncryptionType{
+		{Value: 18, Name: "aes256-cts-hmac-sha1-96", Salt: "CORP.EXAMPLEalice"},
+		{Value: 23, Name: "rc4-hmac"},
+	}
+	if len(etypes) != len(want) || etypes[0] != want[0] || etypes[1] != want[1] {
+		t.Errorf("got %+v, want %+v", etypes, want)
+	}
+	if impl := identifyImplementation(krbErr); impl != "windows" {
+		t.Errorf("got implementation %q, want windows", impl)
+	}
+}
@@ -0,0 +1,252 @@
+// Package kerberos contains the zgrab2 Module implementation for Kerberos KDCs.
+//
+// The scan sends an AS-REQ without pre-authentication for a (by default random, non-existent) client principal and
+// parses the KRB-ERROR the KDC answers with. For an existing principal that requires pre-authentication, the error
+// lists the encryption types the KDC supports for it.
+package kerberos
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// Principal is the client principal the AS-REQ was sent for.
+	Principal string `json:"principal"`
+
+	Error *KRBError `json:"error,omitempty"`
+
+	// Realm is the realm the KDC answered for.
+	Realm string `json:"realm,omitempty"`
+
+	// SupportedETypes are the encryption types announced in a KDC_ERR_PREAUTH_REQUIRED error.
+	SupportedETypes []EncryptionType `json:"supported_etypes,omitempty"`
+
+	// ServerTime is the KDC's current time.
+	ServerTime *time.Time `json:"server_time,omitempty"`
+
+	// ClockSkew is the difference between the KDC's and the local time, in seconds.
+	ClockSkew int64 `json:"clock_skew"`
+
+	// Implementation is a best-effort guess of the KDC implementation (mit, heimdal or windows).
+	Implementation string `json:"implementation,omitempty"`
+
+	// UDPProbe records the probe, if the scan was made over UDP.
+	UDPProbe *zgrab2.UDPProbeResult `json:"udp_probe,omitempty"`
+}
+
+// Flags are the Kerberos-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.UDPFlags
+
+	Principal string `long:"principal" description:"Client principal to request a ticket for. Defaults to a random, non-existent name"`
+	Realm     string `long:"realm" description:"Realm to request a ticket in. Defaults to the upper-cased target domain, or EXAMPLE.COM"`
+	UDP       bool   `long:"udp" description:"Send the AS-REQ over UDP instead of TCP"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the kerberos zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("kerberos", "Kerberos", module.Description(), 88, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Send an AS-REQ to a Kerberos KDC and parse the resulting KRB-ERROR"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	if f.UDP {
+		return f.UDPFlags.Validate()
+	}
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "kerberos"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	transport := zgrab2.TransportTCP
+	if f.UDP {
+		transport = zgrab2.TransportUDP
+	}
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: transport,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// maxTCPMessageSize bounds the length prefix of a KDC reply over TCP.
+const maxTCPMessageSize = 1 << 16
+
+// exchange sends the AS-REQ and returns the raw reply.
+func (scanner *Scanner) exchange(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget, request []byte, results *ScanResults) ([]byte, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	if scanner.config.UDP {
+		probe := zgrab2.NewStaticUDPProbe("as-req", request, nil)
+		results.UDPProbe, err = zgrab2.SendUDPProbe(ctx, conn, probe, target, &scanner.config.UDPFlags)
+		if err != nil {
+			return nil, err
+		}
+		return results.UDPProbe.Response, nil
+	}
+
+	// over TCP, each message is prefixed with its length (RFC 4120 section 7.2.2)
+	framed := binary.BigEndian.AppendUint32(nil, uint32(len(request)))
+	if _, err = conn.Write(append(framed, request...)); err != nil {
+		return nil, fmt.Errorf("error sending AS-REQ to target %s: %w", target.String(), err)
+	}
+	var length uint32
+	if err = binary.Read(conn, binary.BigEndian, &length); err != nil {
+		return nil, fmt.Errorf("error reading reply from target %s: %w", target.String(), err)
+	}
+	if length > maxTCPMessageSize {
+		return nil, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, fmt.Errorf("reply length %d from target %s exceeds maximum", length, target.String()))
+	}
+	reply := make([]byte, length)
+	if _, err = io.ReadFull(conn, reply); err != nil {
+		return nil, fmt.Errorf("error reading reply from target %s: %w", target.String(), err)
+	}
+	return reply, nil
+}
+
+// principalAndRealm returns the client principal and realm to use for the target.
+func (scanner *Scanner) principalAndRealm(target *zgrab2.ScanTarget) (string, string) {
+	principal := scanner.config.Principal
+	if principal == "" {
+		random := make([]byte, 6)
+		_, _ = rand.Read(random)
+		principal = "zgrab2-" + hex.EncodeToString(random)
+	}
+	realm := scanner.config.Realm
+	if realm == "" {
+		realm = "EXAMPLE.COM"
+		if target.Domain != "" {
+			realm = strings.ToUpper(target.Domain)
+		}
+	}
+	return principal, realm
+}
+
+// Scan performs the configured scan on the KDC.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	principal, realm := scanner.principalAndRealm(target)
+	nonce := make([]byte, 4)
+	_, _ = rand.Read(nonce)
+	request := buildASReq(principal, realm, binary.BigEndian.Uint32(nonce)&0x7FFFFFFF)
+
+	results := &ScanResults{Principal: principal + "@" + realm}
+	reply, err := scanner.exchange(ctx, dialGroup, target, request, results)
+	if err != nil {
+		var partial any
+		if results.UDPProbe != nil {
+			partial = results
+		}
+		return zgrab2.TryGetScanStatus(err), partial, err
+	}
+	if len(reply) > 0 && reply[0] == 0x6B { // [APPLICATION 11] AS-REP
+		// the principal exists and does not require pre-authentication
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+	krbErr, err := parseKRBError(reply)
+	if err != nil {
+		return zgrab2.SCAN_PROTOCOL_ERROR, nil, fmt.Errorf("invalid reply from target %s: %w", target.String(), err)
+	}
+
+	serverTime := krbErr.STime.Add(time.Duration(krbErr.SUSec) * time.Microsecond)
+	results.Error = &KRBError{
+		ErrorCode: krbErr.ErrorCode,
+		ErrorName: errorCodeNames[krbErr.ErrorCode],
+		Realm:     krbErr.Realm,
+		CRealm:    krbErr.CRealm,
+		SName:     krbErr.SName.NameString,
+		EText:     krbErr.EText,
+		EData:     krbErr.EData,
+	}
+	results.Realm = krbErr.Realm
+	results.ServerTime = &serverTime
+	results.ClockSkew = int64(time.Until(serverTime).Seconds())
+	if krbErr.ErrorCode == 25 { // KDC_ERR_PREAUTH_REQUIRED
+		results.SupportedETypes = supportedETypes(krbErr.EData)
+	}
+	results.Implementation = identifyImplementation(krbErr)
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
@@ -0,0 +1,216 @@
+// Package kubernetes contains the zgrab2 Module implementation for Kubernetes API servers.
+//
+// The scan requests /version and /healthz, which the default RBAC policy allows for unauthenticated users, and
+// infers from the responses whether anonymous authentication is enabled. The serving certificate is captured in the
+// TLS log.
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+	"github.com/zmap/zgrab2/modules/httpapi"
+)
+
+// versionInfo is the response to GET /version.
+type versionInfo struct {
+	Major        string `json:"major"`
+	Minor        string `json:"minor"`
+	GitVersion   string `json:"gitVersion"`
+	GitCommit    string `json:"gitCommit"`
+	GitTreeState string `json:"gitTreeState"`
+	BuildDate    string `json:"buildDate"`
+	GoVersion    string `json:"goVersion"`
+	Compiler     string `json:"compiler"`
+	Platform     string `json:"platform"`
+}
+
+// BuildInfo is the build information the API server reports.
+type BuildInfo struct {
+	Major        string `json:"major,omitempty"`
+	Minor        string `json:"minor,omitempty"`
+	GitVersion   string `json:"git_version,omitempty"`
+	GitCommit    string `json:"git_commit,omitempty"`
+	GitTreeState string `json:"git_tree_state,omitempty"`
+	BuildDate    string `json:"build_date,omitempty"`
+	GoVersion    string `json:"go_version,omitempty"`
+	Compiler     string `json:"compiler,omitempty"`
+	Platform     string `json:"platform,omitempty"`
+}
+
+// Values of ScanResults.AnonymousAuth
+const (
+	AnonymousEnabled  = "enabled"
+	AnonymousDenied   = "enabled-but-forbidden"
+	AnonymousDisabled = "disabled"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	Build *BuildInfo `json:"build,omitempty"`
+
+	// Healthz is the body of the /healthz response, normally "ok".
+	Healthz string `json:"healthz,omitempty"`
+
+	// AnonymousAuth is "enabled" if unauthenticated requests were served, "enabled-but-forbidden" if they were
+	// authenticated as system:anonymous but denied by authorization, and "disabled" if they were rejected with 401.
+	AnonymousAuth string `json:"anonymous_auth,omitempty"`
+
+	VersionResponse *httpapi.Response `json:"version_response,omitempty"`
+	HealthzResponse *httpapi.Response `json:"healthz_response,omitempty"`
+
+	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
+}
+
+// Flags are the Kubernetes-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.TLSFlags
+
+	NoTLS     bool   `long:"no-tls" description:"Connect over plain HTTP, e.g. to the insecure port 8080"`
+	UserAgent string `long:"user-agent" default:"Mozilla/5.0 zgrab/0.x" description:"Set a custom user agent"`
+	MaxSize   int    `long:"max-size" default:"64" description:"Max kilobytes to read of each response body"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	httpFlags         *httpapi.Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the kubernetes zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("kubernetes", "Kubernetes API server", module.Description(), 6443, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Query the version and health endpoints of a Kubernetes API server"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "kubernetes"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.httpFlags = &httpapi.Flags{UseHTTPS: !f.NoTLS, UserAgent: f.UserAgent, MaxSize: f.MaxSize}
+	scanner.dialerGroupConfig = httpapi.NewDialerGroupConfig(&f.BaseFlags, &f.TLSFlags)
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// isKubernetesStatus returns true if the body is a Kubernetes Status object, as sent with 401 and 403 responses.
+func isKubernetesStatus(resp *httpapi.Response) bool {
+	return strings.Contains(resp.Body, `"kind"`) && strings.Contains(resp.Body, `"Status"`)
+}
+
+// Scan performs the configured scan on the API server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	client := httpapi.NewClient(ctx, dialGroup, target, scanner.httpFlags)
+	results := new(ScanResults)
+
+	var version versionInfo
+	var err error
+	results.VersionResponse, err = client.GetJSON("/version", &version)
+	results.TLSLog = client.TLSLog
+	if err != nil {
+		if results.VersionResponse == nil && results.TLSLog == nil {
+			return zgrab2.TryGetScanStatus(err), nil, err
+		}
+		return zgrab2.TryGetScanStatus(err), results, err
+	}
+
+	resp := results.VersionResponse
+	switch {
+	case resp.Success() && version.GitVersion != "":
+		results.AnonymousAuth = AnonymousEnabled
+		results.Build = &BuildInfo{
+			Major:        version.Major,
+			Minor:        version.Minor,
+			GitVersion:   version.GitVersion,
+			GitCommit:    version.GitCommit,
+			GitTreeState: version.GitTreeState,
+			BuildDate:    version.BuildDate,
+			GoVersion:    version.GoVersion,
+			Compiler:     version.Compiler,
+			Platform:     version.Platform,
+		}
+	case resp.StatusCode == 401 && isKubernetesStatus(resp):
+		results.AnonymousAuth = AnonymousDisabled
+	case resp.StatusCode == 403 && isKubernetesStatus(resp):
+		results.AnonymousAuth = AnonymousDenied
+	default:
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("target %s is not a Kubernetes API server (status %d)", target.String(), resp.StatusCode)
+	}
+
+	results.HealthzResponse, err = client.Get("/healthz")
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), results, err
+	}
+	if results.HealthzResponse.Success() {
+		results.Healthz = strings.TrimSpace(results.HealthzResponse.Body)
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
@@ -0,0 +1,72 @@
+package kubernetes
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/zmap/zgrab2"
+	"github.com/zmap/zgrab2/modules/httpapi/httpapitest"
+)
+
+// scan runs the scanner with --no-tls against an HTTP server with the given handler.
+func scan(t *testing.T, handler http.Handler) (zgrab2.ScanStatus, *ScanResults, error) {
+	t.Helper()
+	scanner := new(Scanner)
+	if err := scanner.Init(&Flags{NoTLS: true, UserAgent: "test-agent", MaxSize: 64}); err != nil {
+		t.Fatal(err)
+	}
+	return httpapitest.Scan[ScanResults](t, scanner, handler)
+}
+
+// statusHandler answers every request with a Kubernetes Status object, as the API server does when it rejects one.
+func statusHandler(code int, reason string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		w.Write([]byte(`{"kind":"Status","apiVersion":"v1","metadata":{},"status":"Failure","reason":"` + reason + `","code":` + strconv.Itoa(code) + `}`))
+	})
+}
+
+func TestScanAnonymousEnabled(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"major":"1","minor":"29","gitVersion":"v1.29.2","gitCommit":"4b8e819","gitTreeState":"clean","goVersion":"go1.21.7","compiler":"gc","platform":"linux/amd64"}`))
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+	status, results, err := scan(t, mux)
+	if err != nil || status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status = %s, err = %v", status, err)
+	}
+	if results.AnonymousAuth != AnonymousEnabled || results.Healthz != "ok" {
+		t.Errorf("results = %+v", results)
+	}
+	if build := results.Build; build == nil || build.GitVersion != "v1.29.2" || build.Minor != "29" || build.Platform != "linux/amd64" {
+		t.Errorf("build = %+v", results.Build)
+	}
+}
+
+func TestScanAnonymousRejected(t *testing.T) {
+	for code, expected := range map[int]string{http.StatusUnauthorized: AnonymousDisabled, http.StatusForbidden: AnonymousDenied} {
+		status, results, err := scan(t, statusHandler(code, http.StatusText(code)))
+		if err != nil || status != zgrab2.SCAN_SUCCESS {
+			t.Fatalf("%d: status = %s, err = %v", code, status, err)
+		}
+		if results.AnonymousAuth != expected || results.Build != nil || results.Healthz != "" {
+			t.Errorf("%d: results = %+v", code, results)
+		}
+	}
+}
+
+func TestScanNotKubernetes(t *testing.T) {
+	// a 401 that isn't a Kubernetes Status object, e.g. from a proxy
+	status, _, err := scan(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	}))
+	if err == nil || status != zgrab2.SCAN_PROTOCOL_ERROR {
+		t.Errorf("status = %s, err = %v", status, err)
+	}
+}
@@ -0,0 +1,7 @@
+package modules
+
+import "github.com/zmap/zgrab2/modules/l2tp"
+
+func init() {
+	l2tp.RegisterModule()
+}
@@ -0,0 +1,160 @@
+package l2tp
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Control message types.
+const (
+	messageSCCRQ   = 1
+	messageSCCRP   = 2
+	messageSCCCN   = 3
+	messageStopCCN = 4
+)
+
+var messageNames = map[uint16]string{
+	messageSCCRQ:   "SCCRQ",
+	messageSCCRP:   "SCCRP",
+	messageSCCCN:   "SCCCN",
+	messageStopCCN: "StopCCN",
+	6:              "HELLO",
+}
+
+// AVP attribute types (RFC 2661 section 4.4).
+const (
+	avpMessageType         = 0
+	avpResultCode          = 1
+	avpProtocolVersion     = 2
+	avpFramingCapabilities = 3
+	avpBearerCapabilities  = 4
+	avpFirmwareRevision    = 6
+	avpHostName            = 7
+	avpVendorName          = 8
+	avpAssignedTunnelID    = 9
+	avpReceiveWindowSize   = 10
+	avpChallenge           = 11
+)
+
+const (
+	// controlFlags marks a version 2 control message with the length and sequence fields present.
+	controlFlags = 0xC802
+
+	// controlHeaderLength is the length of a control message header with length and sequence fields.
+	controlHeaderLength = 12
+
+	avpMandatory = 0x8000
+	avpHidden    = 0x4000
+)
+
+var errInvalidMessage = errors.New("invalid L2TP control message")
+
+// avp is a decoded attribute-value pair. Only IETF AVPs (vendor ID 0) are kept.
+type avp struct {
+	attrType uint16
+	hidden   bool
+	value    []byte
+}
+
+// appendAVP appends a mandatory IETF AVP.
+func appendAVP(b []byte, attrType uint16, value []byte) []byte {
+	b = binary.BigEndian.AppendUint16(b, avpMandatory|uint16(6+len(value)))
+	b = binary.BigEndian.AppendUint16(b, 0)
+	b = binary.BigEndian.AppendUint16(b, attrType)
+	return append(b, value...)
+}
+
+// buildControlMessage returns a control message of the given type with the given AVPs following the Message Type.
+func buildControlMessage(tunnelID, ns, nr, messageType uint16, avps []byte) []byte {
+	body := appendAVP(nil, avpMessageType, binary.BigEndian.AppendUint16(nil, messageType))
+	body = append(body, avps...)
+	msg := binary.BigEndian.AppendUint16(nil, controlFlags)
+	msg = binary.BigEndian.AppendUint16(msg, uint16(controlHeaderLength+len(body)))
+	msg = binary.BigEndian.AppendUint16(msg, tunnelID)
+	msg = binary.BigEndian.AppendUint16(msg, 0) // session ID
+	msg = binary.BigEndian.AppendUint16(msg, ns)
+	msg = binary.BigEndian.AppendUint16(msg, nr)
+	return append(msg, body...)
+}
+
+// buildSCCRQ returns a Start-Control-Connection-Request.
+func buildSCCRQ(hostName string, tunnelID uint16) []byte {
+	avps := appendAVP(nil, avpProtocolVersion, []byte{1, 0})
+	avps = appendAVP(avps, avpHostName, []byte(hostName))
+	avps = appendAVP(avps, avpFramingCapabilities, []byte{0, 0, 0, 3})
+	avps = appendAVP(avps, avpBearerCapabilities, []byte{0, 0, 0, 3})
+	avps = appendAVP(avps, avpAssignedTunnelID, binary.BigEndian.AppendUint16(nil, tunnelID))
+	avps = appendAVP(avps, avpReceiveWindowSize, []byte{0, 4})
+	return buildControlMessage(0, 0, 0, messageSCCRQ, avps)
+}
+
+// buildStopCCN returns a Stop-Control-Connection-Notification closing the tunnel the peer assigned.
+func buildStopCCN(peerTunnelID, tunnelID uint16) []byte {
+	avps := appendAVP(nil, avpAssignedTunnelID, binary.BigEndian.AppendUint16(nil, tunnelID))
+	avps = appendAVP(avps, avpResultCode, []byte{0, 1}) // general request to clear control connection
+	return buildControlMessage(peerTunnelID, 1, 1, messageStopCCN, avps)
+}
+
+// controlMessage is a decoded control message.
+type controlMessage struct {
+	tunnelID    uint16
+	messageType uint16
+	avps        []avp
+}
+
+// parseControlMessage decodes a control message. Zero-length bodies (acknowledgements) are rejected.
+func parseControlMessage(data []byte) (*controlMessage, error) {
+	if len(data) < controlHeaderLength || binary.BigEndian.Uint16(data[0:2])&0xC80F != controlFlags {
+		return nil, errInvalidMessage
+	}
+	length := int(binary.BigEndian.Uint16(data[2:4]))
+	if length < controlHeaderLength || length > len(data) {
+		return nil, errInvalidMessage
+	}
+	msg := &controlMessage{tunnelID: binary.BigEndian.Uint16(data[4:6])}
+	rest := data[controlHeaderLength:length]
+	for len(rest) > 0 {
+		if len(rest) < 6 {
+			return nil, errInvalidMessage
+		}
+		flags := binary.BigEndian.Uint16(rest[0:2])
+		avpLength := int(flags & 0x03FF)
+		if avpLength < 6 || avpLength > len(rest) {
+			return nil, errInvalidMessage
+		}
+		if binary.BigEndian.Uint16(rest[2:4]) == 0 {
+			msg.avps = append(msg.avps, avp{
+				attrType: binary.BigEndian.Uint16(rest[4:6]),
+				hidden:   flags&avpHidden != 0,
+				value:    rest[6:avpLength],
+			})
+		}
+		rest = rest[avpLength:]
+	}
+	// the Message Type AVP must come first
+	if len(msg.avps) == 0 || msg.avps[0].attrType != avpMessageType || len(msg.avps[0].value) != 2 {
+		return nil, errInvalidMessage
+	}
+	msg.messageType = binary.BigEndian.Uint16(msg.avps[0].value)
+	return msg, nil
+}
+
+// ResultCode is a Result Code AVP.
+type ResultCode struct {
+	Result  uint16 `json:"result"`
+	Error   uint16 `json:"error,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// parseResultCode decodes a Result Code AVP value.
+func parseResultCode(value []byte) *ResultCode {
+	if len(value) < 2 {
+		return nil
+	}
+	result := &ResultCode{Result: binary.BigEndian.Uint16(value)}
+	if len(value) >= 4 {
+		result.Error = binary.BigEndian.Uint16(value[2:4])
+		result.Message = string(value[4:])
+	}
+	return result
+}
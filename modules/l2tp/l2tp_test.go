@@ -0,0 +1,41 @@
+package l2tp
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestParseControlMessage(t *testing.T) {
+	request := buildSCCRQ("scanner", 0x1234)
+	msg, err := parseControlMessage(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.messageType != messageSCCRQ || len(msg.avps) != 7 || string(msg.avps[2].value) != "scanner" {
+		t.Errorf("unexpected SCCRQ %+v", msg)
+	}
+
+	avps := appendAVP(nil, avpHostName, []byte("lns.example.com"))
+	avps = appendAVP(avps, avpVendorName, []byte("Example Networks"))
+	avps = appendAVP(avps, avpFirmwareRevision, []byte{0x06, 0x80})
+	reply := buildControlMessage(0x1234, 0, 1, messageSCCRP, avps)
+	if !isControlMessage(nil, reply) {
+		t.Fatal("SCCRP not recognized")
+	}
+	msg, err = parseControlMessage(reply)
+	if err != nil || msg.tunnelID != 0x1234 || messageNames[msg.messageType] != "SCCRP" || string(msg.avps[2].value) != "Example Networks" {
+		t.Errorf("unexpected SCCRP %+v (%v)", msg, err)
+	}
+
+	// a zero-length body acknowledgement
+	ack := make([]byte, controlHeaderLength)
+	binary.BigEndian.PutUint16(ack[0:2], controlFlags)
+	binary.BigEndian.PutUint16(ack[2:4], controlHeaderLength)
+	if isControlMessage(nil, ack) {
+		t.Error("acknowledgement accepted as a reply")
+	}
+
+	if result := parseResultCode([]byte{0, 2, 0, 6, 'n', 'o'}); result == nil || result.Result != 2 || result.Error != 6 || result.Message != "no" {
+		t.Errorf("got result code %+v", result)
+	}
+}
@@ -0,0 +1,234 @@
+// Package l2tp contains the zgrab2 Module implementation for L2TP.
+//
+// The scan sends a Start-Control-Connection-Request and records the AVPs of the Start-Control-Connection-Reply, which
+// name the host, vendor and firmware revision of the LAC or LNS. The control connection is then torn down with a
+// StopCCN.
+package l2tp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// Capabilities are the framing or bearer capabilities of the peer.
+type Capabilities struct {
+	Synchronous  bool `json:"synchronous"`
+	Asynchronous bool `json:"asynchronous"`
+}
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// MessageType is the type of the reply, usually SCCRP or StopCCN.
+	MessageType string `json:"message_type"`
+
+	ProtocolVersion  string `json:"protocol_version,omitempty"`
+	HostName         string `json:"host_name,omitempty"`
+	VendorName       string `json:"vendor_name,omitempty"`
+	FirmwareRevision uint16 `json:"firmware_revision,omitempty"`
+
+	// AssignedTunnelID is the tunnel ID the peer assigned to the control connection.
+	AssignedTunnelID  uint16 `json:"assigned_tunnel_id,omitempty"`
+	ReceiveWindowSize uint16 `json:"receive_window_size,omitempty"`
+
+	FramingCapabilities *Capabilities `json:"framing_capabilities,omitempty"`
+
+	// BearerCapabilities uses synchronous for digital and asynchronous for analog access.
+	BearerCapabilities *Capabilities `json:"bearer_capabilities,omitempty"`
+
+	// ChallengeRequired is true if the peer sent a Challenge AVP, i.e. it authenticates tunnels with a shared secret.
+	ChallengeRequired bool `json:"challenge_required"`
+
+	// HiddenAVPs is true if some AVPs were hidden with the tunnel secret and could not be decoded.
+	HiddenAVPs bool `json:"hidden_avps,omitempty"`
+
+	ResultCode *ResultCode `json:"result_code,omitempty"`
+
+	UDPProbe *zgrab2.UDPProbeResult `json:"udp_probe,omitempty"`
+}
+
+// Flags are the L2TP-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.UDPFlags
+
+	HostName string `long:"host-name" default:"zgrab2" description:"Host name sent in the SCCRQ"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the l2tp zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("l2tp", "Layer 2 Tunneling Protocol (L2TP)", module.Description(), 1701, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Send an L2TP SCCRQ and record the host name, vendor and firmware revision from the reply"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return f.UDPFlags.Validate()
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "l2tp"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportUDP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// isControlMessage reports whether response is a control message other than an acknowledgement.
+func isControlMessage(_, response []byte) bool {
+	_, err := parseControlMessage(response)
+	return err == nil
+}
+
+// capabilities decodes a framing or bearer capabilities value.
+func capabilities(value []byte) *Capabilities {
+	if len(value) != 4 {
+		return nil
+	}
+	return &Capabilities{Synchronous: value[3]&0x01 != 0, Asynchronous: value[3]&0x02 != 0}
+}
+
+// Scan performs the configured scan on the L2TP peer.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	id := make([]byte, 2)
+	_, _ = rand.Read(id)
+	tunnelID := binary.BigEndian.Uint16(id) | 1 // tunnel ID 0 is reserved
+	results := new(ScanResults)
+	probe := zgrab2.NewStaticUDPProbe("sccrq", buildSCCRQ(scanner.config.HostName, tunnelID), isControlMessage)
+	results.UDPProbe, err = zgrab2.SendUDPProbe(ctx, conn, probe, target, &scanner.config.UDPFlags)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, err
+	}
+	msg, err := parseControlMessage(results.UDPProbe.Response)
+	if err != nil {
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("invalid reply from target %s: %w", target.String(), err)
+	}
+
+	results.MessageType = messageNames[msg.messageType]
+	if results.MessageType == "" {
+		results.MessageType = fmt.Sprintf("%d", msg.messageType)
+	}
+	for _, attr := range msg.avps {
+		if attr.hidden {
+			results.HiddenAVPs = true
+			continue
+		}
+		value := attr.value
+		switch attr.attrType {
+		case avpResultCode:
+			results.ResultCode = parseResultCode(value)
+		case avpProtocolVersion:
+			if len(value) == 2 {
+				results.ProtocolVersion = fmt.Sprintf("%d.%d", value[0], value[1])
+			}
+		case avpFramingCapabilities:
+			results.FramingCapabilities = capabilities(value)
+		case avpBearerCapabilities:
+			results.BearerCapabilities = capabilities(value)
+		case avpFirmwareRevision:
+			if len(value) == 2 {
+				results.FirmwareRevision = binary.BigEndian.Uint16(value)
+			}
+		case avpHostName:
+			results.HostName = string(value)
+		case avpVendorName:
+			results.VendorName = string(value)
+		case avpAssignedTunnelID:
+			if len(value) == 2 {
+				results.AssignedTunnelID = binary.BigEndian.Uint16(value)
+			}
+		case avpReceiveWindowSize:
+			if len(value) == 2 {
+				results.ReceiveWindowSize = binary.BigEndian.Uint16(value)
+			}
+		case avpChallenge:
+			results.ChallengeRequired = true
+		}
+	}
+
+	if msg.messageType == messageSCCRP && results.AssignedTunnelID != 0 {
+		// tear the control connection down so the peer doesn't keep retransmitting the SCCRP
+		if _, err := conn.Write(buildStopCCN(results.AssignedTunnelID, tunnelID)); err != nil {
+			log.Debugf("failed to send StopCCN to target %s: %v", target.String(), err)
+		}
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
@@ -0,0 +1,321 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BER tags used by LDAP (RFC 4511)
+const (
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0A
+	tagSequence    = 0x30
+	tagSet         = 0x31
+
+	tagBindRequest       = 0x60
+	tagBindResponse      = 0x61
+	tagSearchRequest     = 0x63
+	tagSearchResultEntry = 0x64
+	tagSearchResultDone  = 0x65
+	tagExtendedRequest   = 0x77
+	tagExtendedResponse  = 0x78
+
+	tagSimpleAuthentication = 0x80
+	tagFilterPresent        = 0x87
+	tagExtendedRequestName  = 0x80
+)
+
+// oidStartTLS is the name of the StartTLS extended operation (RFC 4511 section 4.14).
+const oidStartTLS = "1.3.6.1.4.1.1466.20037"
+
+// maxMessageSize bounds the size of a single LDAP message read from the server.
+const maxMessageSize = 1 << 20
+
+var resultCodeNames = map[int64]string{
+	0:  "success",
+	1:  "operationsError",
+	2:  "protocolError",
+	3:  "timeLimitExceeded",
+	4:  "sizeLimitExceeded",
+	7:  "authMethodNotSupported",
+	8:  "strongerAuthRequired",
+	12: "unavailableCriticalExtension",
+	13: "confidentialityRequired",
+	32: "noSuchObject",
+	48: "inappropriateAuthentication",
+	49: "invalidCredentials",
+	50: "insufficientAccessRights",
+	51: "busy",
+	52: "unavailable",
+	53: "unwillingToPerform",
+	80: "other",
+}
+
+// Result is an LDAPResult, the common part of most responses.
+type Result struct {
+	ResultCode        int64  `json:"result_code"`
+	ResultName        string `json:"result_name,omitempty"`
+	MatchedDN         string `json:"matched_dn,omitempty"`
+	DiagnosticMessage string `json:"diagnostic_message,omitempty"`
+}
+
+var errTruncated = errors.New("truncated BER encoding")
+
+// berValue is a single decoded TLV.
+type berValue struct {
+	tag     byte
+	content []byte
+}
+
+// berEncode returns the TLV with the given tag whose content is the concatenation of the given parts.
+func berEncode(tag byte, parts ...[]byte) []byte {
+	length := 0
+	for _, part := range parts {
+		length += len(part)
+	}
+	out := []byte{tag}
+	switch {
+	case length < 0x80:
+		out = append(out, byte(length))
+	case length <= 0xFF:
+		out = append(out, 0x81, byte(length))
+	default:
+		out = append(out, 0x82, byte(length>>8), byte(length))
+	}
+	for _, part := range parts {
+		out = append(out, part...)
+	}
+	return out
+}
+
+// berInteger encodes a non-negative INTEGER or ENUMERATED value.
+func berInteger(tag byte, v int) []byte {
+	content := []byte{byte(v)}
+	for v >>= 8; v > 0; v >>= 8 {
+		content = append([]byte{byte(v)}, content...)
+	}
+	if content[0]&0x80 != 0 {
+		content = append([]byte{0}, content...)
+	}
+	return berEncode(tag, content)
+}
+
+func berString(tag byte, s string) []byte {
+	return berEncode(tag, []byte(s))
+}
+
+// berDecode decodes the TLV at the start of data and returns it and the remaining bytes.
+func berDecode(data []byte) (berValue, []byte, error) {
+	if len(data) < 2 {
+		return berValue{}, nil, errTruncated
+	}
+	length, offset, err := berLength(data[1:])
+	if err != nil {
+		return berValue{}, nil, err
+	}
+	offset++
+	if len(data) < offset+length {
+		return berValue{}, nil, errTruncated
+	}
+	return berValue{tag: data[0], content: data[offset : offset+length]}, data[offset+length:], nil
+}
+
+// berLength decodes a definite length and returns it and the number of bytes it occupied.
+func berLength(data []byte) (int, int, error) {
+	if len(data) < 1 {
+		return 0, 0, errTruncated
+	}
+	if data[0]&0x80 == 0 {
+		return int(data[0]), 1, nil
+	}
+	n := int(data[0] & 0x7F)
+	if n == 0 || n > 4 {
+		return 0, 0, fmt.Errorf("unsupported BER length encoding 0x%02x", data[0])
+	}
+	if len(data) < 1+n {
+		return 0, 0, errTruncated
+	}
+	length := 0
+	for _, b := range data[1 : 1+n] {
+		length = length<<8 | int(b)
+	}
+	return length, 1 + n, nil
+}
+
+// children decodes the content of a constructed value.
+func (v berValue) children() ([]berValue, error) {
+	var values []berValue
+	rest := v.content
+	for len(rest) > 0 {
+		child, next, err := berDecode(rest)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, child)
+		rest = next
+	}
+	return values, nil
+}
+
+func (v berValue) integer() int64 {
+	var result int64
+	if len(v.content) > 0 && v.content[0]&0x80 != 0 {
+		result = -1
+	}
+	for _, b := range v.content {
+		result = result<<8 | int64(b)
+	}
+	return result
+}
+
+// message is a decoded LDAPMessage envelope.
+type message struct {
+	id       int64
+	protocol berValue
+}
+
+// buildMessage wraps a protocolOp in an LDAPMessage.
+func buildMessage(id int, protocolOp []byte) []byte {
+	return berEncode(tagSequence, berInteger(tagInteger, id), protocolOp)
+}
+
+// buildAnonymousBind returns an LDAPv3 anonymous simple bind request.
+func buildAnonymousBind(id int) []byte {
+	return buildMessage(id, berEncode(tagBindRequest,
+		berInteger(tagInteger, 3),
+		berString(tagOctetString, ""),
+		berString(tagSimpleAuthentication, ""),
+	))
+}
+
+// buildRootDSESearch returns a base-scope search of the empty DN for the given attributes.
+func buildRootDSESearch(id int, attributes []string) []byte {
+	var list [][]byte
+	for _, attribute := range attributes {
+		list = append(list, berString(tagOctetString, attribute))
+	}
+	return buildMessage(id, berEncode(tagSearchRequest,
+		berString(tagOctetString, ""),
+		berInteger(tagEnumerated, 0), // baseObject
+		berInteger(tagEnumerated, 0), // neverDerefAliases
+		berInteger(tagInteger, 0),
+		berInteger(tagInteger, 0),
+		berEncode(tagBoolean, []byte{0}),
+		berString(tagFilterPresent, "objectClass"),
+		berEncode(tagSequence, list...),
+	))
+}
+
+// buildStartTLS returns a StartTLS extended request.
+func buildStartTLS(id int) []byte {
+	return buildMessage(id, berEncode(tagExtendedRequest, berString(tagExtendedRequestName, oidStartTLS)))
+}
+
+// readMessage reads a single LDAPMessage.
+func readMessage(r *bufio.Reader) (*message, error) {
+	header, err := r.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if header[0] != tagSequence {
+		return nil, fmt.Errorf("invalid LDAP message tag 0x%02x", header[0])
+	}
+	lengthBytes := 1
+	if header[1]&0x80 != 0 {
+		lengthBytes += int(header[1] & 0x7F)
+	}
+	header, err = r.Peek(1 + lengthBytes)
+	if err != nil {
+		return nil, err
+	}
+	length, _, err := berLength(header[1:])
+	if err != nil {
+		return nil, err
+	}
+	if length > maxMessageSize {
+		return nil, fmt.Errorf("LDAP message length %d exceeds maximum", length)
+	}
+	raw := make([]byte, 1+lengthBytes+length)
+	if _, err = io.ReadFull(r, raw); err != nil {
+		return nil, err
+	}
+	return parseMessage(raw)
+}
+
+func parseMessage(raw []byte) (*message, error) {
+	envelope, _, err := berDecode(raw)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := envelope.children()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) < 2 || fields[0].tag != tagInteger {
+		return nil, errors.New("invalid LDAP message")
+	}
+	return &message{id: fields[0].integer(), protocol: fields[1]}, nil
+}
+
+// parseResult parses the LDAPResult at the start of a response.
+func parseResult(op berValue) (*Result, error) {
+	fields, err := op.children()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) < 3 || fields[0].tag != tagEnumerated {
+		return nil, errors.New("invalid LDAPResult")
+	}
+	code := fields[0].integer()
+	return &Result{
+		ResultCode:        code,
+		ResultName:        resultCodeNames[code],
+		MatchedDN:         string(fields[1].content),
+		DiagnosticMessage: string(fields[2].content),
+	}, nil
+}
+
+// Attribute is a single attribute of a search result entry.
+type Attribute struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// parseSearchResultEntry returns the attributes of a SearchResultEntry.
+func parseSearchResultEntry(op berValue) ([]Attribute, error) {
+	fields, err := op.children()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) < 2 {
+		return nil, errors.New("invalid SearchResultEntry")
+	}
+	attributeList, err := fields[1].children()
+	if err != nil {
+		return nil, err
+	}
+	var attributes []Attribute
+	for _, attribute := range attributeList {
+		parts, err := attribute.children()
+		if err != nil {
+			return nil, err
+		}
+		if len(parts) < 2 || parts[1].tag != tagSet {
+			return nil, errors.New("invalid PartialAttribute")
+		}
+		values, err := parts[1].children()
+		if err != nil {
+			return nil, err
+		}
+		parsed := Attribute{Name: string(parts[0].content), Values: make([]string, 0, len(values))}
+		for _, value := range values {
+			parsed.Values = append(parsed.Values, string(value.content))
+		}
+		attributes = append(attributes, parsed)
+	}
+	return attributes, nil
+}
@@ -0,0 +1,311 @@
+// Package ldap contains the zgrab2 Module implementation for LDAP.
+//
+// The scan performs an anonymous simple bind, reads the RootDSE and, unless disabled, attempts StartTLS to capture
+// the server certificate. No credentials are ever sent.
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// rootDSEAttributes are requested explicitly since most servers treat them as operational attributes.
+var rootDSEAttributes = []string{
+	"namingContexts",
+	"defaultNamingContext",
+	"supportedLDAPVersion",
+	"supportedSASLMechanisms",
+	"supportedExtension",
+	"supportedControl",
+	"vendorName",
+	"vendorVersion",
+	"dnsHostName",
+	"serverName",
+	"subschemaSubentry",
+	"*",
+	"+",
+}
+
+// RootDSE holds the well-known attributes of the server's root DSE.
+type RootDSE struct {
+	NamingContexts          []string `json:"naming_contexts,omitempty"`
+	DefaultNamingContext    string   `json:"default_naming_context,omitempty"`
+	SupportedLDAPVersion    []string `json:"supported_ldap_version,omitempty"`
+	SupportedSASLMechanisms []string `json:"supported_sasl_mechanisms,omitempty"`
+	SupportedExtension      []string `json:"supported_extension,omitempty"`
+	SupportedControl        []string `json:"supported_control,omitempty"`
+	VendorName              string   `json:"vendor_name,omitempty"`
+	VendorVersion           string   `json:"vendor_version,omitempty"`
+	DNSHostName             string   `json:"dns_host_name,omitempty"`
+	ServerName              string   `json:"server_name,omitempty"`
+
+	// Attributes holds all returned attributes, including the ones above.
+	Attributes []Attribute `json:"attributes,omitempty"`
+}
+
+func newRootDSE(list []Attribute) *RootDSE {
+	attributes := make(map[string][]string, len(list))
+	for _, attribute := range list {
+		attributes[attribute.Name] = attribute.Values
+	}
+	first := func(name string) string {
+		if values := attributes[name]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	return &RootDSE{
+		NamingContexts:          attributes["namingContexts"],
+		DefaultNamingContext:    first("defaultNamingContext"),
+		SupportedLDAPVersion:    attributes["supportedLDAPVersion"],
+		SupportedSASLMechanisms: attributes["supportedSASLMechanisms"],
+		SupportedExtension:      attributes["supportedExtension"],
+		SupportedControl:        attributes["supportedControl"],
+		VendorName:              first("vendorName"),
+		VendorVersion:           first("vendorVersion"),
+		DNSHostName:             first("dnsHostName"),
+		ServerName:              first("serverName"),
+		Attributes:              list,
+	}
+}
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// Bind is the result of the anonymous bind.
+	Bind *Result `json:"bind,omitempty"`
+
+	// Search is the result of the RootDSE search.
+	Search *Result `json:"search,omitempty"`
+
+	RootDSE *RootDSE `json:"root_dse,omitempty"`
+
+	// StartTLS is the result of the StartTLS extended operation, if attempted.
+	StartTLS *Result `json:"starttls,omitempty"`
+
+	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
+}
+
+// Flags are the LDAP-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.TLSFlags
+
+	LDAPS      bool `long:"ldaps" description:"Use LDAP over TLS (LDAPS) from the start"`
+	NoStartTLS bool `long:"no-starttls" description:"Do not attempt StartTLS"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the ldap zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("ldap", "Lightweight Directory Access Protocol (LDAP)", module.Description(), 389, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Perform an anonymous bind, read the RootDSE and check for StartTLS support"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "ldap"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		NeedSeparateL4Dialer:            true,
+		BaseFlags:                       &f.BaseFlags,
+		TLSEnabled:                      true,
+		TLSFlags:                        &f.TLSFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// Connection holds the state for a single connection to the LDAP server.
+type Connection struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	messageID int
+}
+
+// request sends a request built for the next message ID and returns the responses up to and including the one
+// with the given tag.
+func (c *Connection) request(build func(id int) []byte, final byte) ([]berValue, error) {
+	c.messageID++
+	if _, err := c.conn.Write(build(c.messageID)); err != nil {
+		return nil, err
+	}
+	var responses []berValue
+	for {
+		msg, err := readMessage(c.reader)
+		if err != nil {
+			return responses, err
+		}
+		if msg.id == 0 {
+			// unsolicited notification, e.g. Notice of Disconnection
+			result, err := parseResult(msg.protocol)
+			if err != nil {
+				return responses, err
+			}
+			return responses, fmt.Errorf("server sent notice of disconnection: %s %s", result.ResultName, result.DiagnosticMessage)
+		}
+		if msg.id != int64(c.messageID) {
+			continue
+		}
+		responses = append(responses, msg.protocol)
+		if msg.protocol.tag == final {
+			return responses, nil
+		}
+	}
+}
+
+// singleResult sends a request expecting a single LDAPResult-based response.
+func (c *Connection) singleResult(build func(id int) []byte, final byte) (*Result, error) {
+	responses, err := c.request(build, final)
+	if err != nil {
+		return nil, err
+	}
+	return parseResult(responses[len(responses)-1])
+}
+
+// Scan performs the configured scan on the LDAP server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	if dialGroup.L4Dialer == nil {
+		return zgrab2.SCAN_UNKNOWN_ERROR, nil, errors.New("l4 dialer is required for ldap")
+	}
+	conn, err := dialGroup.L4Dialer(target)(ctx, "tcp", net.JoinHostPort(target.Host(), strconv.Itoa(int(target.Port))))
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer func() {
+		zgrab2.CloseConnAndHandleError(conn)
+	}()
+
+	results := new(ScanResults)
+	if scanner.config.LDAPS {
+		tlsConn, err := dialGroup.TLSWrapper(ctx, target, conn)
+		if tlsConn != nil {
+			results.TLSLog = tlsConn.GetLog()
+			conn = tlsConn
+		}
+		if err != nil {
+			return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error performing TLS handshake with target %s: %w", target.String(), err)
+		}
+	}
+	ldap := &Connection{conn: conn, reader: bufio.NewReader(conn)}
+
+	results.Bind, err = ldap.singleResult(buildAnonymousBind, tagBindResponse)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error binding to target %s: %w", target.String(), err)
+	}
+
+	// servers that reject anonymous binds usually still serve the RootDSE
+	responses, err := ldap.request(func(id int) []byte { return buildRootDSESearch(id, rootDSEAttributes) }, tagSearchResultDone)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error searching RootDSE of target %s: %w", target.String(), err)
+	}
+	for _, response := range responses {
+		switch response.tag {
+		case tagSearchResultEntry:
+			attributes, err := parseSearchResultEntry(response)
+			if err != nil {
+				return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("invalid RootDSE from target %s: %w", target.String(), err)
+			}
+			results.RootDSE = newRootDSE(attributes)
+		case tagSearchResultDone:
+			if results.Search, err = parseResult(response); err != nil {
+				return zgrab2.SCAN_PROTOCOL_ERROR, results, err
+			}
+		}
+	}
+
+	if scanner.config.LDAPS || scanner.config.NoStartTLS {
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+	results.StartTLS, err = ldap.singleResult(buildStartTLS, tagExtendedResponse)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error sending StartTLS to target %s: %w", target.String(), err)
+	}
+	if results.StartTLS.ResultCode != 0 {
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+	tlsConn, err := dialGroup.TLSWrapper(ctx, target, conn)
+	if tlsConn != nil {
+		results.TLSLog = tlsConn.GetLog()
+		conn = tlsConn
+	}
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error performing StartTLS handshake with target %s: %w", target.String(), err)
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
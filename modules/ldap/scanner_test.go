@@ -0,0 +1,126 @@
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"slices"
+	"testing"
+
+	"github.com/zmap/zgrab2"
+)
+
+// buildResult returns a response with the given tag carrying an LDAPResult.
+func buildResult(id int, tag byte, code int, diagnosticMessage string) []byte {
+	return buildMessage(id, berEncode(tag,
+		berInteger(tagEnumerated, code),
+		berString(tagOctetString, ""),
+		berString(tagOctetString, diagnosticMessage),
+	))
+}
+
+// buildAttribute returns a PartialAttribute with the given values.
+func buildAttribute(name string, values ...string) []byte {
+	var set [][]byte
+	for _, value := range values {
+		set = append(set, berString(tagOctetString, value))
+	}
+	return berEncode(tagSequence, berString(tagOctetString, name), berEncode(tagSet, set...))
+}
+
+// fakeServer answers an anonymous bind, a RootDSE search and StartTLS, which it doesn't support.
+func fakeServer(server net.Conn) {
+	defer server.Close()
+	reader := bufio.NewReader(server)
+	for {
+		msg, err := readMessage(reader)
+		if err != nil {
+			return
+		}
+		id := int(msg.id)
+		switch msg.protocol.tag {
+		case tagBindRequest:
+			server.Write(buildResult(id, tagBindResponse, 0, ""))
+		case tagSearchRequest:
+			// an unrelated message ID is skipped
+			server.Write(buildResult(id+1, tagSearchResultDone, 0, ""))
+			server.Write(buildMessage(id, berEncode(tagSearchResultEntry,
+				berString(tagOctetString, ""),
+				berEncode(tagSequence,
+					buildAttribute("namingContexts", "dc=example,dc=com", "cn=config"),
+					buildAttribute("vendorName", "Example Directory"),
+					buildAttribute("supportedLDAPVersion", "2", "3"),
+				),
+			)))
+			server.Write(buildResult(id, tagSearchResultDone, 0, ""))
+		case tagExtendedRequest:
+			server.Write(buildResult(id, tagExtendedResponse, 2, "unsupported extended operation"))
+		}
+	}
+}
+
+func scan(t *testing.T, flags *Flags, serve func(server net.Conn)) (zgrab2.ScanStatus, *ScanResults, error) {
+	t.Helper()
+	dialGroup := &zgrab2.DialerGroup{
+		L4Dialer: func(*zgrab2.ScanTarget) func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return func(ctx context.Context, network, addr string) (net.Conn, error) {
+				client, server := net.Pipe()
+				go serve(server)
+				return client, nil
+			}
+		},
+	}
+	scanner := &Scanner{config: flags}
+	status, res, err := scanner.Scan(context.Background(), dialGroup, &zgrab2.ScanTarget{IP: net.ParseIP("192.0.2.1"), Port: 389})
+	results, _ := res.(*ScanResults)
+	return status, results, err
+}
+
+func TestScan(t *testing.T) {
+	status, results, err := scan(t, new(Flags), fakeServer)
+	if err != nil || status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status = %s, err = %v", status, err)
+	}
+	if results.Bind == nil || results.Bind.ResultName != "success" || results.Search == nil || results.Search.ResultCode != 0 {
+		t.Errorf("bind = %+v, search = %+v", results.Bind, results.Search)
+	}
+	rootDSE := results.RootDSE
+	if rootDSE == nil || rootDSE.VendorName != "Example Directory" || !slices.Equal(rootDSE.NamingContexts, []string{"dc=example,dc=com", "cn=config"}) ||
+		!slices.Equal(rootDSE.SupportedLDAPVersion, []string{"2", "3"}) || len(rootDSE.Attributes) != 3 {
+		t.Errorf("root DSE = %+v", rootDSE)
+	}
+	if results.StartTLS == nil || results.StartTLS.ResultName != "protocolError" || results.StartTLS.DiagnosticMessage != "unsupported extended operation" || results.TLSLog != nil {
+		t.Errorf("StartTLS = %+v", results.StartTLS)
+	}
+
+	_, results, err = scan(t, &Flags{NoStartTLS: true}, fakeServer)
+	if err != nil || results.StartTLS != nil {
+		t.Errorf("StartTLS attempted with --no-starttls: %+v, %v", results.StartTLS, err)
+	}
+}
+
+func TestScanNoticeOfDisconnection(t *testing.T) {
+	status, results, err := scan(t, new(Flags), func(server net.Conn) {
+		defer server.Close()
+		if _, err := readMessage(bufio.NewReader(server)); err != nil {
+			return
+		}
+		server.Write(buildResult(0, tagExtendedResponse, 52, "server is shutting down"))
+	})
+	if err == nil || results != nil || status == zgrab2.SCAN_SUCCESS {
+		t.Errorf("status = %s, results = %+v, err = %v", status, results, err)
+	}
+}
+
+func TestBERLength(t *testing.T) {
+	for _, length := range []int{0, 0x7F, 0x80, 0xFF, 0x100, 0xFFFF} {
+		encoded := berEncode(tagOctetString, make([]byte, length))
+		value, rest, err := berDecode(append(encoded, 0xAA))
+		if err != nil || len(value.content) != length || len(rest) != 1 {
+			t.Errorf("length %d: decoded %d bytes, %d left, %v", length, len(value.content), len(rest), err)
+		}
+	}
+	if _, _, err := berDecode([]byte{tagOctetString, 0x85, 1, 2, 3, 4, 5}); err == nil {
+		t.Error("decoded 5-byte length")
+	}
+}
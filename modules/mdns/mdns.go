@@ -0,0 +1,119 @@
+package mdns
+
+import (
+	"net"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// servicesName is the DNS-SD meta-query name enumerating the service types of a host (RFC 6763 section 9).
+const servicesName = "_services._dns-sd._udp.local."
+
+// buildQuery returns a PTR query for name. Queries sent from a port other than 5353 are "legacy unicast" queries
+// (RFC 6762 section 6.7), which responders answer directly to the sender with the query ID echoed.
+func buildQuery(id uint16, name string) ([]byte, error) {
+	parsed, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, err
+	}
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: id},
+		Questions: []dnsmessage.Question{{
+			Name:  parsed,
+			Type:  dnsmessage.TypePTR,
+			Class: dnsmessage.ClassINET,
+		}},
+	}
+	return msg.Pack()
+}
+
+// matchesID returns a probe match function accepting responses with the given ID.
+func matchesID(id uint16) func(_, response []byte) bool {
+	return func(_, response []byte) bool {
+		var parser dnsmessage.Parser
+		header, err := parser.Start(response)
+		return err == nil && header.Response && header.ID == id
+	}
+}
+
+// Instance is an advertised service instance.
+type Instance struct {
+	Name        string   `json:"name"`
+	ServiceType string   `json:"service_type"`
+	Host        string   `json:"host,omitempty"`
+	Port        uint16   `json:"port,omitempty"`
+	TXT         []string `json:"txt,omitempty"`
+	Addresses   []string `json:"addresses,omitempty"`
+}
+
+// records accumulates the records of all responses, keyed by lower-cased owner name.
+type records struct {
+	ptr  map[string][]string
+	srv  map[string]*dnsmessage.SRVResource
+	txt  map[string][]string
+	addr map[string][]string
+}
+
+func newRecords() *records {
+	return &records{
+		ptr:  make(map[string][]string),
+		srv:  make(map[string]*dnsmessage.SRVResource),
+		txt:  make(map[string][]string),
+		addr: make(map[string][]string),
+	}
+}
+
+// add records the answers and additional records of a response.
+func (r *records) add(response []byte) error {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(response); err != nil {
+		return err
+	}
+	for _, resource := range append(msg.Answers, msg.Additionals...) {
+		owner := strings.ToLower(resource.Header.Name.String())
+		switch body := resource.Body.(type) {
+		case *dnsmessage.PTRResource:
+			r.ptr[owner] = appendUnique(r.ptr[owner], body.PTR.String())
+		case *dnsmessage.SRVResource:
+			r.srv[owner] = body
+		case *dnsmessage.TXTResource:
+			r.txt[owner] = body.TXT
+		case *dnsmessage.AResource:
+			r.addr[owner] = appendUnique(r.addr[owner], net.IP(body.A[:]).String())
+		case *dnsmessage.AAAAResource:
+			r.addr[owner] = appendUnique(r.addr[owner], net.IP(body.AAAA[:]).String())
+		}
+	}
+	return nil
+}
+
+// instances returns the instances of the service type found in the records.
+func (r *records) instances(serviceType string) []Instance {
+	var instances []Instance
+	for _, name := range r.ptr[strings.ToLower(serviceType)] {
+		instance := Instance{Name: name, ServiceType: serviceType}
+		owner := strings.ToLower(name)
+		if srv, ok := r.srv[owner]; ok {
+			instance.Host = srv.Target.String()
+			instance.Port = srv.Port
+			instance.Addresses = r.addr[strings.ToLower(instance.Host)]
+		}
+		if txt, ok := r.txt[owner]; ok && !(len(txt) == 1 && txt[0] == "") {
+			instance.TXT = txt
+		}
+		instances = append(instances, instance)
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].Name < instances[j].Name })
+	return instances
+}
+
+func appendUnique(list []string, value string) []string {
+	for _, existing := range list {
+		if existing == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
@@ -0,0 +1,56 @@
+package mdns
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestRecords(t *testing.T) {
+	query, err := buildQuery(0x1234, servicesName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matchesID(0x1234)(nil, query) {
+		t.Error("query accepted as a response")
+	}
+
+	name := func(s string) dnsmessage.Name { return dnsmessage.MustNewName(s) }
+	header := func(owner string, rrType dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name(owner), Type: rrType, Class: dnsmessage.ClassINET, TTL: 10}
+	}
+	response := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: 0x1234, Response: true, Authoritative: true},
+		Answers: []dnsmessage.Resource{
+			{Header: header("_http._tcp.local.", dnsmessage.TypePTR), Body: &dnsmessage.PTRResource{PTR: name("Printer._http._tcp.local.")}},
+		},
+		Additionals: []dnsmessage.Resource{
+			{Header: header("Printer._http._tcp.local.", dnsmessage.TypeSRV), Body: &dnsmessage.SRVResource{Target: name("printer.local."), Port: 80}},
+			{Header: header("Printer._http._tcp.local.", dnsmessage.TypeTXT), Body: &dnsmessage.TXTResource{TXT: []string{"path=/"}}},
+			{Header: header("printer.local.", dnsmessage.TypeA), Body: &dnsmessage.AResource{A: [4]byte{192, 0, 2, 7}}},
+		},
+	}
+	packed, err := response.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !matchesID(0x1234)(nil, packed) || matchesID(0x4321)(nil, packed) {
+		t.Error("ID matching failed")
+	}
+	recs := newRecords()
+	if err = recs.add(packed); err != nil {
+		t.Fatal(err)
+	}
+	expected := []Instance{{
+		Name:        "Printer._http._tcp.local.",
+		ServiceType: "_http._tcp.local.",
+		Host:        "printer.local.",
+		Port:        80,
+		TXT:         []string{"path=/"},
+		Addresses:   []string{"192.0.2.7"},
+	}}
+	if instances := recs.instances("_http._tcp.local."); !reflect.DeepEqual(instances, expected) {
+		t.Errorf("got instances %+v", instances)
+	}
+}
@@ -0,0 +1,181 @@
+// Package mdns contains the zgrab2 Module implementation for mDNS/DNS-SD.
+//
+// The scan sends a unicast query for _services._dns-sd._udp.local to list the service types a host advertises,
+// then a PTR query for each type to collect its instances with their SRV, TXT and address records. Most responders
+// only answer unicast queries from the local link, so results from remote hosts usually indicate a responder exposed
+// beyond it.
+package mdns
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// ServiceTypes are the advertised service types, e.g. _http._tcp.local.
+	ServiceTypes []string `json:"service_types,omitempty"`
+
+	Instances []Instance `json:"instances,omitempty"`
+
+	Probes []*zgrab2.UDPProbeResult `json:"probes,omitempty"`
+}
+
+// Flags are the mDNS-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.UDPFlags
+
+	MaxServices int  `long:"max-services" default:"16" description:"Maximum number of service types to query for instances"`
+	NoInstances bool `long:"no-instances" description:"Only list the service types"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the mdns zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("mdns", "Multicast DNS (mDNS/DNS-SD)", module.Description(), 5353, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Send a unicast DNS-SD query and record the advertised service types and instances"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return f.UDPFlags.Validate()
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "mdns"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportUDP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// query sends a PTR query for name and adds the response to recs.
+func (scanner *Scanner) query(ctx context.Context, conn net.Conn, target *zgrab2.ScanTarget, name string, recs *records, results *ScanResults) error {
+	id := make([]byte, 2)
+	_, _ = rand.Read(id)
+	queryID := binary.BigEndian.Uint16(id)
+	request, err := buildQuery(queryID, name)
+	if err != nil {
+		return zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, fmt.Errorf("invalid name %q: %w", name, err))
+	}
+	probe := zgrab2.NewStaticUDPProbe(name, request, matchesID(queryID))
+	probeResult, err := zgrab2.SendUDPProbe(ctx, conn, probe, target, &scanner.config.UDPFlags)
+	results.Probes = append(results.Probes, probeResult)
+	if err != nil {
+		return err
+	}
+	if err = recs.add(probeResult.Response); err != nil {
+		return zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, fmt.Errorf("invalid response to query for %s: %w", name, err))
+	}
+	return nil
+}
+
+// Scan performs the configured scan on the mDNS responder.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	results := new(ScanResults)
+	recs := newRecords()
+	if err = scanner.query(ctx, conn, target, servicesName, recs, results); err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, err
+	}
+	results.ServiceTypes = recs.ptr[servicesName]
+	sort.Strings(results.ServiceTypes)
+	if scanner.config.NoInstances {
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+
+	for i, serviceType := range results.ServiceTypes {
+		if i == scanner.config.MaxServices {
+			break
+		}
+		// responders often include the instances in the first response already
+		if len(recs.instances(serviceType)) == 0 {
+			if err = scanner.query(ctx, conn, target, serviceType, recs, results); err != nil {
+				log.Debugf("query for %s to target %s failed: %v", serviceType, target.String(), err)
+				continue
+			}
+		}
+		results.Instances = append(results.Instances, recs.instances(serviceType)...)
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
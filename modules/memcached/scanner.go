@@ -4,7 +4,7 @@ package memcached
 // https://github.com/memcached/memcached/blob/master/doc/protocol.txt
 
 // Package memcached provides a zgrab2 module that scans for memcache servers.
-// Default port: 11211 (TCP)
+// Default port: 11211 (TCP, or UDP with --udp)
 import (
 	"context"
 	"encoding/binary"
@@ -25,6 +25,9 @@ import (
 // Populated by the framework.
 type Flags struct {
 	zgrab2.BaseFlags `group:"Basic Options"`
+	zgrab2.UDPFlags
+
+	UDP bool `long:"udp" description:"Scan over UDP and report the response amplification"`
 }
 
 // Module implements the zgrab2.Module interface.
@@ -65,6 +68,9 @@ func (module *Module) Description() string {
 // On success, returns nil.
 // On failure, returns an error instance describing the error.
 func (flags *Flags) Validate([]string) error {
+	if flags.UDP {
+		return flags.UDPFlags.Validate()
+	}
 	return nil
 }
 
@@ -77,8 +83,12 @@ func (flags *Flags) Help() string {
 func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
 	f, _ := flags.(*Flags)
 	scanner.config = f
+	transport := zgrab2.TransportTCP
+	if f.UDP {
+		transport = zgrab2.TransportUDP
+	}
 	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
-		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		TransportAgnosticDialerProtocol: transport,
 		BaseFlags:                       &f.BaseFlags,
 	}
 	return nil
@@ -119,6 +129,7 @@ type MemcachedResult struct {
 	SupportsAscii   bool                 `json:"supports_ascii"`  // true if the server supports plain-text ASCII protocol
 	SupportsBinary  bool                 `json:"supports_binary"` // true if the server supports binary protocol
 	Stats           MemcachedResultStats `json:"stats"`
+	UDP             *UDPAmplification    `json:"udp,omitempty"` // set when scanning over UDP
 }
 
 type MemcachedResultStats struct {
@@ -414,6 +425,14 @@ func ScanAscii(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab
 	}
 }
 
+// binaryStatRequest returns the binary "stat" command - From https://docs.memcached.org/protocols/binary/#stat
+func binaryStatRequest() []byte {
+	message := []byte{0x80, 0x10, 0x00, 0x00}
+	// Add padding to make message necessary length of 24 bytes. Anything past the header would be read as the start of
+	// another request.
+	return append(message, make([]byte, 20)...)
+}
+
 // Find server that doesn't support ASCII but supports binary
 func ScanBinary(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, *MemcachedResult, error) {
 	result := MemcachedResult{}
@@ -426,17 +445,7 @@ func ScanBinary(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgra
 		zgrab2.CloseConnAndHandleError(conn)
 	}(conn)
 
-	// Send the binary "stat" command - From https://docs.memcached.org/protocols/binary/#stat
-	var message []byte
-	message = append(message, 0x80, 0x10, 0x00, 0x00)
-
-	// Add padding to make message necessary length of 24 bytes
-	message = append(message, make([]byte, 20)...)
-	for i := 0; i < 20; i++ {
-		message = append(message, byte(0x00))
-	}
-
-	_, err = conn.Write(message)
+	_, err = conn.Write(binaryStatRequest())
 	if err != nil {
 		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("unable to write target (%s): %w", target.String(), err)
 	}
@@ -464,6 +473,13 @@ func ScanBinary(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgra
 
 // Scan probes for a memcached service.
 func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	if scanner.config.UDP {
+		status, result, err := scanner.ScanUDP(ctx, dialGroup, target)
+		if result == nil {
+			return status, nil, err
+		}
+		return status, *result, err
+	}
 	_, asciiResult, asciiErr := ScanAscii(ctx, dialGroup, target)
 	_, binaryResult, binaryErr := ScanBinary(ctx, dialGroup, target)
 
@@ -1,6 +1,13 @@
 package memcached
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+
+	"github.com/zmap/zgrab2"
+)
 
 func TestSnakeToCamel(t *testing.T) {
 	tests := []struct {
@@ -21,3 +28,50 @@ func TestSnakeToCamel(t *testing.T) {
 		}
 	}
 }
+
+func TestReassembleUDPResponse(t *testing.T) {
+	request := buildUDPRequest(0xBEEF, []byte("stats\r\n"))
+	frame, err := parseUDPFrame(request)
+	if err != nil || frame.requestID != 0xBEEF || frame.total != 1 || string(frame.payload) != "stats\r\n" {
+		t.Fatalf("unexpected request frame %+v (%v)", frame, err)
+	}
+
+	var frames []*udpFrame
+	for _, datagram := range [][]byte{
+		{0xBE, 0xEF, 0, 1, 0, 2, 0, 0, 'E', 'N', 'D', '\r', '\n'},
+		{0xBE, 0xEF, 0, 0, 0, 2, 0, 0, 'S', 'T', 'A', 'T', ' ', 'p', 'i', 'd', ' ', '1', '\r', '\n'},
+	} {
+		frame, err := parseUDPFrame(datagram)
+		if err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, frame)
+	}
+	if got := string(reassembleUDPResponse(frames)); got != "STAT pid 1\r\nEND\r\n" {
+		t.Errorf("got %q", got)
+	}
+
+	if _, err = parseUDPFrame([]byte{1, 2, 3}); err == nil {
+		t.Error("expected error for short datagram")
+	}
+}
+
+func TestScanBinaryRequest(t *testing.T) {
+	requests := make(chan []byte, 1)
+	dialGroup := &zgrab2.DialerGroup{TransportAgnosticDialer: func(ctx context.Context, target *zgrab2.ScanTarget) (net.Conn, error) {
+		client, server := net.Pipe()
+		go func() {
+			defer server.Close()
+			buf := make([]byte, 64)
+			n, _ := server.Read(buf)
+			requests <- buf[:n]
+		}()
+		return client, nil
+	}}
+	ScanBinary(context.Background(), dialGroup, &zgrab2.ScanTarget{})
+	// the stat command is just the 24-byte request header, with no key, extras or value
+	expected := append([]byte{0x80, 0x10, 0x00, 0x00}, make([]byte, 20)...)
+	if request := <-requests; !bytes.Equal(request, expected) {
+		t.Errorf("request = %x, expected %x", request, expected)
+	}
+}
@@ -0,0 +1,155 @@
+package memcached
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zmap/zgrab2"
+)
+
+// udpHeaderLength is the length of the frame header memcached prepends to every UDP datagram, see
+// https://github.com/memcached/memcached/blob/master/doc/protocol.txt (UDP protocol)
+const udpHeaderLength = 8
+
+// maxUDPDatagrams bounds the number of datagrams read for a single response.
+const maxUDPDatagrams = 128
+
+// UDPAmplification describes how much larger the response to a UDP request was than the request itself.
+type UDPAmplification struct {
+	RequestBytes  int     `json:"request_bytes"`
+	ResponseBytes int     `json:"response_bytes"`
+	Datagrams     int     `json:"datagrams"`
+	Factor        float64 `json:"factor"`
+	// Vulnerable is true if the response was larger than the request, i.e. the service can be abused for
+	// reflection/amplification attacks.
+	Vulnerable bool `json:"vulnerable"`
+}
+
+// buildUDPRequest prepends a frame header with the given request ID to payload.
+func buildUDPRequest(requestID uint16, payload []byte) []byte {
+	header := make([]byte, udpHeaderLength)
+	binary.BigEndian.PutUint16(header[0:2], requestID)
+	binary.BigEndian.PutUint16(header[4:6], 1)
+	return append(header, payload...)
+}
+
+// udpFrame is a datagram of a UDP response.
+type udpFrame struct {
+	requestID uint16
+	sequence  uint16
+	total     uint16
+	payload   []byte
+}
+
+func parseUDPFrame(datagram []byte) (*udpFrame, error) {
+	if len(datagram) < udpHeaderLength {
+		return nil, errors.New("datagram shorter than frame header")
+	}
+	return &udpFrame{
+		requestID: binary.BigEndian.Uint16(datagram[0:2]),
+		sequence:  binary.BigEndian.Uint16(datagram[2:4]),
+		total:     binary.BigEndian.Uint16(datagram[4:6]),
+		payload:   datagram[udpHeaderLength:],
+	}, nil
+}
+
+// reassembleUDPResponse orders the frames of a response by sequence number and concatenates their payloads.
+func reassembleUDPResponse(frames []*udpFrame) []byte {
+	sort.Slice(frames, func(i, j int) bool { return frames[i].sequence < frames[j].sequence })
+	var payload []byte
+	for _, frame := range frames {
+		payload = append(payload, frame.payload...)
+	}
+	return payload
+}
+
+// exchangeUDP sends payload in a single datagram and collects all datagrams of the response.
+func (scanner *Scanner) exchangeUDP(ctx context.Context, conn net.Conn, target *zgrab2.ScanTarget, name string, payload []byte) ([]byte, *UDPAmplification, error) {
+	requestID := uint16(rand.Intn(1 << 16))
+	request := buildUDPRequest(requestID, payload)
+	probe := zgrab2.NewStaticUDPProbe(name, request, func(_, response []byte) bool {
+		frame, err := parseUDPFrame(response)
+		return err == nil && frame.requestID == requestID
+	})
+	probeResult, err := zgrab2.SendUDPProbe(ctx, conn, probe, target, &scanner.config.UDPFlags)
+	if err != nil {
+		return nil, nil, err
+	}
+	first, _ := parseUDPFrame(probeResult.Response)
+	frames := []*udpFrame{first}
+	amplification := &UDPAmplification{RequestBytes: len(request), ResponseBytes: len(probeResult.Response)}
+
+	// the remaining datagrams follow immediately; stop at the first gap
+	buf := make([]byte, 65535)
+	for len(frames) < int(first.total) && len(frames) < maxUDPDatagrams {
+		if err = conn.SetReadDeadline(time.Now().Add(scanner.config.TryTimeout)); err != nil {
+			break
+		}
+		n, err := conn.Read(buf)
+		if err != nil {
+			break
+		}
+		frame, err := parseUDPFrame(buf[:n])
+		if err != nil || frame.requestID != requestID {
+			continue
+		}
+		frame.payload = append([]byte(nil), frame.payload...)
+		frames = append(frames, frame)
+		amplification.ResponseBytes += n
+	}
+	amplification.Datagrams = len(frames)
+	amplification.Factor = float64(amplification.ResponseBytes) / float64(amplification.RequestBytes)
+	amplification.Vulnerable = amplification.ResponseBytes > amplification.RequestBytes
+	return reassembleUDPResponse(frames), amplification, nil
+}
+
+// ScanUDP scans a memcached server over UDP with the ASCII "stats" command, falling back to the binary protocol if
+// the server does not answer it.
+func (scanner *Scanner) ScanUDP(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, *MemcachedResult, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("unable to dial target (%s): %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	response, amplification, asciiErr := scanner.exchangeUDP(ctx, conn, target, "stats", []byte("stats\r\n"))
+	if asciiErr == nil {
+		var stats []string
+		for _, line := range strings.Split(string(response), "\n") {
+			if strings.HasPrefix(line, "STAT ") {
+				stats = append(stats, strings.TrimSpace(strings.TrimPrefix(line, "STAT ")))
+			}
+		}
+		if len(stats) > 0 {
+			result := PopulateResults(stats)
+			result.SupportsAscii = true
+			result.UDP = amplification
+			return zgrab2.SCAN_SUCCESS, &result, nil
+		}
+		asciiErr = zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, fmt.Errorf("no valid stats in UDP response from %s", target.String()))
+	}
+	if zgrab2.TryGetScanStatus(asciiErr) == zgrab2.SCAN_CONNECTION_REFUSED {
+		// ICMP port unreachable; there's no point in trying the binary protocol
+		return zgrab2.SCAN_CONNECTION_REFUSED, nil, asciiErr
+	}
+
+	response, amplification, err = scanner.exchangeUDP(ctx, conn, target, "binary-stat", binaryStatRequest())
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("target supports neither ascii nor binary over UDP (%s): %w", target.String(), errors.Join(asciiErr, err))
+	}
+	stats, err := CleanBinary(response)
+	if err != nil {
+		return zgrab2.SCAN_PROTOCOL_ERROR, nil, fmt.Errorf("protocol-error: %v (%s)", err, target.String())
+	}
+	result := PopulateResults(stats)
+	result.SupportsBinary = true
+	result.UDP = amplification
+	return zgrab2.SCAN_SUCCESS, &result, nil
+}
@@ -0,0 +1,200 @@
+package minecraft
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// maxPacketLength bounds the length of a packet from the server; the status carries the favicon as base64 PNG.
+const maxPacketLength = 1 << 21
+
+// Packet IDs in the handshaking and status states.
+const (
+	packetHandshake = 0x00
+	packetStatus    = 0x00
+)
+
+// nextStateStatus asks the server to switch to the status state after the handshake.
+const nextStateStatus = 1
+
+var errInvalidPacket = errors.New("invalid packet")
+
+// appendVarInt appends v in the protocol's LEB128 encoding; negative values take five bytes.
+func appendVarInt(b []byte, v int32) []byte {
+	u := uint32(v)
+	for u >= 0x80 {
+		b = append(b, byte(u)|0x80)
+		u >>= 7
+	}
+	return append(b, byte(u))
+}
+
+// readVarInt reads a VarInt of at most five bytes.
+func readVarInt(r io.ByteReader) (int32, error) {
+	var v uint32
+	for i := 0; i < 5; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		v |= uint32(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			return int32(v), nil
+		}
+	}
+	return 0, errInvalidPacket
+}
+
+// appendString appends a VarInt-prefixed string.
+func appendString(b []byte, s string) []byte {
+	return append(appendVarInt(b, int32(len(s))), s...)
+}
+
+// framePacket prefixes a packet ID and body with their length.
+func framePacket(id int32, body []byte) []byte {
+	packet := append(appendVarInt(nil, id), body...)
+	return append(appendVarInt(nil, int32(len(packet))), packet...)
+}
+
+// buildHandshake returns the handshake switching to the status state, followed by the status request.
+func buildHandshake(protocolVersion int32, address string, port uint16) []byte {
+	body := appendVarInt(nil, protocolVersion)
+	body = appendString(body, address)
+	body = binary.BigEndian.AppendUint16(body, port)
+	body = appendVarInt(body, nextStateStatus)
+	return append(framePacket(packetHandshake, body), framePacket(packetStatus, nil)...)
+}
+
+// readPacket reads a packet and returns its ID and body.
+func readPacket(r *bufio.Reader) (int32, []byte, error) {
+	length, err := readVarInt(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if length <= 0 || length > maxPacketLength {
+		return 0, nil, errInvalidPacket
+	}
+	packet := make([]byte, length)
+	if _, err = io.ReadFull(r, packet); err != nil {
+		return 0, nil, err
+	}
+	body := bytes.NewReader(packet)
+	id, err := readVarInt(body)
+	if err != nil {
+		return 0, nil, errInvalidPacket
+	}
+	return id, packet[len(packet)-body.Len():], nil
+}
+
+// parseStatusString decodes the string that makes up the body of a status response.
+func parseStatusString(body []byte) (string, error) {
+	r := bytes.NewReader(body)
+	length, err := readVarInt(r)
+	if err != nil || length < 0 || int(length) > r.Len() {
+		return "", errInvalidPacket
+	}
+	start := len(body) - r.Len()
+	return string(body[start : start+int(length)]), nil
+}
+
+// chatComponent is a chat component as used for the description: a string, or an object with text and extra
+// components.
+type chatComponent struct {
+	Text  string
+	Extra []chatComponent
+}
+
+func (c *chatComponent) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		c.Text = s
+		return nil
+	}
+	var object struct {
+		Text  string          `json:"text"`
+		Extra []chatComponent `json:"extra"`
+	}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+	c.Text, c.Extra = object.Text, object.Extra
+	return nil
+}
+
+// plainText returns the text of the component and its children, without formatting codes.
+func (c *chatComponent) plainText() string {
+	var sb strings.Builder
+	c.appendText(&sb)
+	return stripFormatting(sb.String())
+}
+
+func (c *chatComponent) appendText(sb *strings.Builder) {
+	sb.WriteString(c.Text)
+	for i := range c.Extra {
+		c.Extra[i].appendText(sb)
+	}
+}
+
+// stripFormatting removes the legacy section-sign formatting codes, e.g. §a.
+func stripFormatting(s string) string {
+	var sb strings.Builder
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '§' {
+			i++
+			continue
+		}
+		sb.WriteRune(runes[i])
+	}
+	return sb.String()
+}
+
+// Player is an entry of the player sample.
+type Player struct {
+	Name string `json:"name"`
+	ID   string `json:"id"`
+}
+
+// Mod is a mod the server announces.
+type Mod struct {
+	ID      string `json:"id"`
+	Version string `json:"version,omitempty"`
+}
+
+// status is the JSON status response.
+type status struct {
+	Version struct {
+		Name     string `json:"name"`
+		Protocol int32  `json:"protocol"`
+	} `json:"version"`
+	Players struct {
+		Max    int      `json:"max"`
+		Online int      `json:"online"`
+		Sample []Player `json:"sample"`
+	} `json:"players"`
+	Description        chatComponent `json:"description"`
+	Favicon            string        `json:"favicon"`
+	EnforcesSecureChat bool          `json:"enforcesSecureChat"`
+
+	// ModInfo is sent by Forge up to 1.12
+	ModInfo *struct {
+		Type    string `json:"type"`
+		ModList []struct {
+			ModID   string `json:"modid"`
+			Version string `json:"version"`
+		} `json:"modList"`
+	} `json:"modinfo"`
+
+	// ForgeData is sent by Forge from 1.13
+	ForgeData *struct {
+		Mods []struct {
+			ModID     string `json:"modId"`
+			ModMarker string `json:"modmarker"`
+		} `json:"mods"`
+	} `json:"forgeData"`
+}
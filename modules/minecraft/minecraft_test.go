@@ -0,0 +1,61 @@
+package minecraft
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestVarInt(t *testing.T) {
+	for _, v := range []int32{0, 1, 127, 128, 25565, 2147483647, -1} {
+		encoded := appendVarInt(nil, v)
+		decoded, err := readVarInt(bytes.NewReader(encoded))
+		if err != nil || decoded != v {
+			t.Errorf("%d: got %d (%v) from % x", v, decoded, err, encoded)
+		}
+	}
+	if encoded := appendVarInt(nil, -1); len(encoded) != 5 {
+		t.Errorf("expected five bytes for -1, got % x", encoded)
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	r := bufio.NewReader(bytes.NewReader(buildHandshake(-1, "mc.example.com", 25565)))
+	id, body, err := readPacket(r)
+	if err != nil || id != packetHandshake {
+		t.Fatalf("unexpected handshake packet %d (%v)", id, err)
+	}
+	expected := append(appendVarInt(nil, -1), 14)
+	expected = append(expected, "mc.example.com"...)
+	expected = append(expected, 0x63, 0xDD, nextStateStatus)
+	if !bytes.Equal(body, expected) {
+		t.Errorf("got % x, expected % x", body, expected)
+	}
+	if id, body, err = readPacket(r); err != nil || id != packetStatus || len(body) != 0 {
+		t.Errorf("unexpected status request %d % x (%v)", id, body, err)
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	raw := `{"version":{"name":"1.20.4","protocol":765},"players":{"max":20,"online":1,"sample":[{"name":"Steve","id":"8667ba71-b85a-4004-af54-457a9734eed7"}]},` +
+		`"description":{"text":"§aA ","extra":[{"text":"Minecraft"},"§r Server"]}}`
+	body := appendString(nil, raw)
+	decoded, err := parseStatusString(body)
+	if err != nil || decoded != raw {
+		t.Fatalf("got %q (%v)", decoded, err)
+	}
+	var s status
+	if err = json.Unmarshal([]byte(decoded), &s); err != nil {
+		t.Fatal(err)
+	}
+	if motd := s.Description.plainText(); motd != "A Minecraft Server" {
+		t.Errorf("got MOTD %q", motd)
+	}
+	if s.Version.Protocol != 765 || s.Players.Online != 1 || len(s.Players.Sample) != 1 || s.Players.Sample[0].Name != "Steve" {
+		t.Errorf("unexpected status %+v", s)
+	}
+	if _, err = parseStatusString(body[:len(body)-1]); err == nil {
+		t.Error("expected error for truncated status")
+	}
+}
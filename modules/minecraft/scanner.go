@@ -0,0 +1,193 @@
+// Package minecraft contains the zgrab2 Module implementation for the Minecraft server list ping.
+//
+// The scan sends the handshake packet switching to the status state and a status request, as the client's server
+// list does, and decodes the JSON status: version, MOTD, player counts and sample, and the mods announced by Forge
+// servers.
+package minecraft
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	VersionName     string `json:"version_name,omitempty"`
+	ProtocolVersion int32  `json:"protocol_version"`
+
+	// MOTD is the description shown in the server list, as plain text.
+	MOTD string `json:"motd,omitempty"`
+
+	PlayersOnline int      `json:"players_online"`
+	PlayersMax    int      `json:"players_max"`
+	PlayerSample  []Player `json:"player_sample,omitempty"`
+
+	HasFavicon         bool `json:"has_favicon"`
+	EnforcesSecureChat bool `json:"enforces_secure_chat"`
+
+	// ModLoader is the mod loader the server announces mods for, e.g. FML or forge.
+	ModLoader string `json:"mod_loader,omitempty"`
+	Mods      []Mod  `json:"mods,omitempty"`
+
+	// Status is the raw JSON status.
+	Status string `json:"status,omitempty"`
+}
+
+// Flags are the Minecraft-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+
+	ProtocolVersion int32  `long:"protocol-version" default:"-1" description:"Protocol version to announce in the handshake; -1 asks the server for its own"`
+	ServerAddress   string `long:"server-address" description:"Server address to send in the handshake. Defaults to the target domain or IP"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the minecraft zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("minecraft", "Minecraft", module.Description(), 25565, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Perform a Minecraft server list ping and record the status"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "minecraft"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// Scan performs the configured scan on the Minecraft server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	address := scanner.config.ServerAddress
+	if address == "" {
+		address = target.Host()
+	}
+	if _, err = conn.Write(buildHandshake(scanner.config.ProtocolVersion, address, uint16(target.Port))); err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error sending handshake to target %s: %w", target.String(), err)
+	}
+	id, body, err := readPacket(bufio.NewReader(conn))
+	if err == nil && id != packetStatus {
+		err = errInvalidPacket
+	}
+	if err != nil {
+		if err == errInvalidPacket {
+			err = zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+		}
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error reading status from target %s: %w", target.String(), err)
+	}
+	raw, err := parseStatusString(body)
+	if err != nil {
+		return zgrab2.SCAN_PROTOCOL_ERROR, nil, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+	}
+
+	results := &ScanResults{Status: raw}
+	var s status
+	if err = json.Unmarshal([]byte(raw), &s); err != nil {
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("invalid status from target %s: %w", target.String(), err)
+	}
+	results.VersionName = stripFormatting(s.Version.Name)
+	results.ProtocolVersion = s.Version.Protocol
+	results.MOTD = strings.TrimSpace(s.Description.plainText())
+	results.PlayersOnline = s.Players.Online
+	results.PlayersMax = s.Players.Max
+	results.PlayerSample = s.Players.Sample
+	results.HasFavicon = s.Favicon != ""
+	results.EnforcesSecureChat = s.EnforcesSecureChat
+	switch {
+	case s.ModInfo != nil:
+		results.ModLoader = s.ModInfo.Type
+		for _, mod := range s.ModInfo.ModList {
+			results.Mods = append(results.Mods, Mod{ID: mod.ModID, Version: mod.Version})
+		}
+	case s.ForgeData != nil:
+		results.ModLoader = "forge"
+		for _, mod := range s.ForgeData.Mods {
+			results.Mods = append(results.Mods, Mod{ID: mod.ModID, Version: mod.ModMarker})
+		}
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
@@ -0,0 +1,226 @@
+package modbus
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReadDevId codes of the Read Device Identification request, selecting the category of objects to read.
+const (
+	ReadDeviceIDBasic    = 0x01
+	ReadDeviceIDRegular  = 0x02
+	ReadDeviceIDExtended = 0x03
+	ReadDeviceIDSpecific = 0x04
+)
+
+// meiReadDeviceID is the MEI type of the Read Device Identification request.
+const meiReadDeviceID = 0x0E
+
+// maxStaleResponses is the number of late responses to earlier requests skipped while waiting for a response.
+const maxStaleResponses = 4
+
+// maxDeviceIDRequests bounds the number of requests sent to read the objects of one category.
+const maxDeviceIDRequests = 16
+
+// errStaleResponse is returned by GetModbusResponse for a response to an earlier request.
+var errStaleResponse = errors.New("modbus: response to an earlier request")
+
+// deviceIDCategories are the categories of objects, with the ReadDevId code reading them and their first object ID.
+var deviceIDCategories = []struct {
+	name        string
+	code        byte
+	firstObject byte
+}{
+	{"basic", ReadDeviceIDBasic, 0x00},
+	{"regular", ReadDeviceIDRegular, 0x03},
+	{"extended", ReadDeviceIDExtended, 0x80},
+}
+
+// DeviceIDCategory is the result of reading the objects of one category.
+type DeviceIDCategory struct {
+	// Name is basic, regular or extended.
+	Name string `json:"name"`
+
+	// Requests is the number of requests sent, one more for each response with MoreFollows set.
+	Requests int `json:"requests"`
+
+	// ObjectCount is the number of objects returned for the category.
+	ObjectCount int `json:"object_count"`
+
+	// ExceptionResponse is set if the server returned an exception.
+	ExceptionResponse *ExceptionResponse `json:"exception_response,omitempty"`
+
+	// Error is set if the server did not return a valid response.
+	Error string `json:"error,omitempty"`
+}
+
+// DeviceIdentification holds all objects read from the device, in every category its conformity level supports.
+type DeviceIdentification struct {
+	// ConformityLevel is the conformity level from the first response.
+	ConformityLevel int `json:"conformity_level"`
+
+	// Categories are the results of reading each category.
+	Categories []DeviceIDCategory `json:"categories,omitempty"`
+
+	// Objects are the objects of all categories.
+	Objects MEIObjectSet `json:"objects,omitempty"`
+}
+
+// UnitLog is the response of one unit ID to the basic Read Device Identification request.
+type UnitLog struct {
+	UnitID int `json:"unit_id"`
+
+	// Responded is set if the unit ID returned any valid response, including an exception.
+	Responded bool `json:"responded"`
+
+	MEIResponse *MEIResponse `json:"mei_response,omitempty"`
+
+	ExceptionResponse *ExceptionResponse `json:"exception_response,omitempty"`
+
+	// Error is set if there was no valid response, e.g. on a timeout.
+	Error string `json:"error,omitempty"`
+}
+
+// parseUnitIDs parses a comma-separated list of unit IDs and ranges, like "1-10,247".
+func parseUnitIDs(s string) ([]int, error) {
+	var ret []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.ParseUint(strings.TrimSpace(first), 0, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unit ID %q", first)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.ParseUint(strings.TrimSpace(last), 0, 8); err != nil {
+				return nil, fmt.Errorf("invalid unit ID %q", last)
+			}
+			if end < start {
+				return nil, fmt.Errorf("invalid unit ID range %q", part)
+			}
+		}
+		for id := start; id <= end; id++ {
+			ret = append(ret, int(id))
+		}
+	}
+	return ret, nil
+}
+
+// send writes the request to the server.
+func (c *Conn) send(req *ModbusRequest) error {
+	data, err := c.MarshalRequest(req)
+	if err != nil {
+		return err
+	}
+	for w := 0; w < len(data); {
+		written, err := c.getUnderlyingConn().Write(data[w:])
+		w += written
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// request sends a Read Device Identification request with a new transaction ID and returns its response, skipping late
+// responses to earlier requests. If timeout is nonzero, it bounds the wait for the response.
+func (c *Conn) request(unitID int, code byte, objectID byte, timeout time.Duration) (*ModbusResponse, error) {
+	c.transactionID++
+	c.skipStale = true
+	err := c.send(&ModbusRequest{
+		UnitID:   unitID,
+		Function: ModbusFunctionEncapsulatedInterface,
+		Data:     []byte{meiReadDeviceID, code, objectID},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if timeout > 0 {
+		if err := c.getUnderlyingConn().SetReadDeadline(time.Now().Add(timeout)); err != nil {
+			return nil, err
+		}
+	}
+	for i := 0; ; i++ {
+		res, err := c.GetModbusResponse()
+		if err == errStaleResponse && i < maxStaleResponses {
+			continue
+		}
+		if res == nil {
+			return nil, err
+		}
+		if res.Function&0x7F != ModbusFunctionEncapsulatedInterface {
+			return nil, fmt.Errorf("invalid response function code 0x%02x", res.Function)
+		}
+		return res, nil
+	}
+}
+
+// readDeviceIdentification reads the objects of each category supported by the conformity level, following
+// MoreFollows / NextObjectID. Unknown conformity levels try every category.
+func (c *Conn) readDeviceIdentification(unitID int, conformityLevel int, strict bool) *DeviceIdentification {
+	ret := &DeviceIdentification{ConformityLevel: conformityLevel}
+	level := conformityLevel & 0x7F
+	if level < ReadDeviceIDBasic || level > ReadDeviceIDExtended {
+		level = ReadDeviceIDExtended
+	}
+	for _, category := range deviceIDCategories[:level] {
+		result := DeviceIDCategory{Name: category.name}
+		objectID := category.firstObject
+		for result.Requests < maxDeviceIDRequests {
+			result.Requests++
+			res, err := c.request(unitID, category.code, objectID, 0)
+			if err != nil {
+				result.Error = err.Error()
+				break
+			}
+			event, err := res.getEvent(strict)
+			if err != nil {
+				result.Error = err.Error()
+				break
+			}
+			if event.ExceptionResponse != nil {
+				result.ExceptionResponse = event.ExceptionResponse
+				break
+			}
+			result.ObjectCount += len(event.MEIResponse.Objects)
+			ret.Objects = append(ret.Objects, event.MEIResponse.Objects...)
+			next := byte(event.MEIResponse.NextObjectID)
+			if !event.MEIResponse.MoreFollows || next <= objectID {
+				break
+			}
+			objectID = next
+		}
+		ret.Categories = append(ret.Categories, result)
+	}
+	return ret
+}
+
+// sweepUnits sends the basic Read Device Identification request to each unit ID, waiting at most timeout for each
+// response.
+func (c *Conn) sweepUnits(unitIDs []int, timeout time.Duration, strict bool) []UnitLog {
+	ret := make([]UnitLog, 0, len(unitIDs))
+	for _, unitID := range unitIDs {
+		unit := UnitLog{UnitID: unitID}
+		res, err := c.request(unitID, ReadDeviceIDBasic, 0x00, timeout)
+		if err == nil {
+			var event *ModbusEvent
+			if event, err = res.getEvent(strict); err == nil {
+				unit.Responded = true
+				unit.MEIResponse = event.MEIResponse
+				unit.ExceptionResponse = event.ExceptionResponse
+			}
+		}
+		if err != nil {
+			unit.Error = err.Error()
+		}
+		ret = append(ret, unit)
+	}
+	return ret
+}
@@ -129,6 +129,51 @@ type ExceptionResponse struct {
 	// ExceptionType is the type code representing the exception.
 	// For details see e.g. section 7 of http://www.modbus.org/docs/Modbus_Application_Protocol_V1_1b.pdf
 	ExceptionType byte `json:"exception_type"`
+
+	// ExceptionName is the name of ExceptionType, e.g. "illegal_function", or "unknown".
+	ExceptionName string `json:"exception_name,omitempty"`
+
+	// IsGatewayError is set for the gateway exceptions (0x0A and 0x0B), which come from a gateway in front of the
+	// requested unit rather than from the unit itself.
+	IsGatewayError bool `json:"is_gateway_error,omitempty"`
+}
+
+// Exception codes from section 7 of the Modbus Application Protocol Specification.
+const (
+	ExceptionIllegalFunction                    ExceptionCode = 0x01
+	ExceptionIllegalDataAddress                 ExceptionCode = 0x02
+	ExceptionIllegalDataValue                   ExceptionCode = 0x03
+	ExceptionServerDeviceFailure                ExceptionCode = 0x04
+	ExceptionAcknowledge                        ExceptionCode = 0x05
+	ExceptionServerDeviceBusy                   ExceptionCode = 0x06
+	ExceptionMemoryParityError                  ExceptionCode = 0x08
+	ExceptionGatewayPathUnavailable             ExceptionCode = 0x0A
+	ExceptionGatewayTargetDeviceFailedToRespond ExceptionCode = 0x0B
+)
+
+var exceptionNames = map[ExceptionCode]string{
+	ExceptionIllegalFunction:                    "illegal_function",
+	ExceptionIllegalDataAddress:                 "illegal_data_address",
+	ExceptionIllegalDataValue:                   "illegal_data_value",
+	ExceptionServerDeviceFailure:                "server_device_failure",
+	ExceptionAcknowledge:                        "acknowledge",
+	ExceptionServerDeviceBusy:                   "server_device_busy",
+	ExceptionMemoryParityError:                  "memory_parity_error",
+	ExceptionGatewayPathUnavailable:             "gateway_path_unavailable",
+	ExceptionGatewayTargetDeviceFailedToRespond: "gateway_target_device_failed_to_respond",
+}
+
+// Name returns the name of the exception code, or "unknown".
+func (e ExceptionCode) Name() string {
+	if name, ok := exceptionNames[e]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// IsGatewayError returns true for the exceptions raised by gateways.
+func (e ExceptionCode) IsGatewayError() bool {
+	return e == ExceptionGatewayPathUnavailable || e == ExceptionGatewayTargetDeviceFailedToRespond
 }
 
 // ModbusEvent is the response object. Either MEIResponse or ExceptionResponse will be set.
@@ -156,6 +201,12 @@ type ModbusEvent struct {
 
 	// Raw is the full raw response from the server, including the header.
 	Raw []byte `json:"raw,omitempty"`
+
+	// DeviceIdentification holds the objects of all categories; only present if --full-device-id is set.
+	DeviceIdentification *DeviceIdentification `json:"device_identification,omitempty"`
+
+	// Units are the responses to the requests sent to each of the --unit-ids.
+	Units []UnitLog `json:"units,omitempty"`
 }
 
 // IsException returns true if this response indicates an exception has occurred.
@@ -201,6 +252,8 @@ func (m *ModbusResponse) getExceptionResponse(strict bool) (*ExceptionResponse,
 	return &ExceptionResponse{
 		ExceptionFunction: exceptionFunction,
 		ExceptionType:     exceptionType,
+		ExceptionName:     ExceptionCode(exceptionType).Name(),
+		IsGatewayError:    ExceptionCode(exceptionType).IsGatewayError(),
 	}, nil
 }
 
@@ -215,10 +268,9 @@ func (m *ModbusResponse) getMEIResponse(strict bool) (*MEIResponse, error) {
 	if meiType != 0x0E {
 		return nil, fmt.Errorf("invalid response data (expected 0xee, got 0x%02x)", meiType)
 	}
-	// TODO: Allow different values here
 	readType := m.Data[1]
-	if readType != 1 {
-		return nil, fmt.Errorf("invalid response data (expected 0x01, got 0x%02x)", readType)
+	if readType < ReadDeviceIDBasic || readType > ReadDeviceIDSpecific {
+		return nil, fmt.Errorf("invalid response data (expected ReadDevId code 0x01-0x04, got 0x%02x)", readType)
 	}
 	conformityLevel := m.Data[2]
 	moreFollows := (m.Data[3] != 0)
@@ -292,7 +344,7 @@ type ModbusRequest struct {
 func (c *Conn) MarshalRequest(r *ModbusRequest) (data []byte, err error) {
 	data = make([]byte, 7+1+len(r.Data))
 	// Request ID: default ZG
-	binary.BigEndian.PutUint16(data[0:2], c.scanner.config.RequestID)
+	binary.BigEndian.PutUint16(data[0:2], c.transactionID)
 	// Protocol: must be 0
 	binary.BigEndian.PutUint16(data[2:4], 0)
 	msglen := len(r.Data) + 2 // unit ID and function
@@ -334,8 +386,8 @@ func (c *Conn) GetModbusResponse() (*ModbusResponse, error) {
 
 	// first 4 bytes should be known, verify them
 	requestID := binary.BigEndian.Uint16(header[0:2])
-	if requestID != c.scanner.config.RequestID {
-		return nil, fmt.Errorf("modbus: requestID did not match (got 0x%02x, expected 0x%02x)", requestID, c.scanner.config.RequestID)
+	if requestID != c.transactionID && !c.skipStale {
+		return nil, fmt.Errorf("modbus: requestID did not match (got 0x%02x, expected 0x%02x)", requestID, c.transactionID)
 	}
 	protocolID := binary.BigEndian.Uint16(header[2:4])
 	if protocolID != 0 {
@@ -375,6 +427,10 @@ func (c *Conn) GetModbusResponse() (*ModbusResponse, error) {
 	if len(body) < 1 {
 		return nil, readError
 	}
+	if requestID != c.transactionID {
+		// A late response to an earlier request, which was read completely so that the next response can be read.
+		return nil, errStaleResponse
+	}
 
 	//TODO this really should be done by a more elegant unmarshaling function
 	resp := &ModbusResponse{
@@ -14,9 +14,16 @@
 // The --strict flag allows turning on new validity checks beyond those
 // done in the original zgrab, to help rule out false matches.
 //
+// The --full-device-id flag reads the objects of every category (basic,
+// regular, extended) the device's conformity level supports.
+//
+// The --unit-ids flag sends the basic request to each unit ID in a list of
+// IDs and ranges (e.g. "1-10,247") and records which ones respond.
+//
 // The output is the same as the original ZGrab: a "modbus event" object,
 // with either the parsed MEI response or the parsed exception info.
-// The only addition is a "raw" field containing the raw response data.
+// Additions are a "raw" field containing the raw response data, the names of
+// exception codes, and the results of --full-device-id and --unit-ids.
 package modbus
 
 import (
@@ -24,6 +31,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -38,6 +46,9 @@ type Flags struct {
 	ObjectID         uint8                   `long:"object-id" description:"The ObjectID of the object to be read." default:"0x00"`
 	Strict           bool                    `long:"strict" description:"If set, perform stricter checks on the response data to get fewer false positives"`
 	RequestID        uint16                  `long:"request-id" description:"Override the default request ID." default:"0x5A47"`
+	FullDeviceID     bool                    `long:"full-device-id" description:"Read the device identification objects of all categories supported by the device"`
+	UnitIDs          string                  `long:"unit-ids" description:"Comma-separated list of unit IDs and ranges (e.g. 1-10,247) to probe for a response"`
+	UnitTimeout      time.Duration           `long:"unit-timeout" description:"How long to wait for the response of each unit ID probed with --unit-ids" default:"2s"`
 }
 
 // Module implements the zgrab2.Module interface.
@@ -48,6 +59,7 @@ type Module struct {
 type Scanner struct {
 	config            *Flags
 	dialerGroupConfig *zgrab2.DialerGroupConfig
+	unitIDs           []int
 }
 
 // RegisterModule registers the zgrab2 module.
@@ -84,6 +96,9 @@ func (flags *Flags) Validate(_ []string) error {
 			log.Warnf("ObjectIDs 0x07...0x7F are reserved (requested 0x%02x)", flags.ObjectID)
 		}
 	}
+	if _, err := parseUnitIDs(flags.UnitIDs); err != nil {
+		return fmt.Errorf("invalid --unit-ids: %w", err)
+	}
 	return nil
 }
 
@@ -96,6 +111,11 @@ func (flags *Flags) Help() string {
 func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
 	f, _ := flags.(*Flags)
 	scanner.config = f
+	unitIDs, err := parseUnitIDs(f.UnitIDs)
+	if err != nil {
+		return err
+	}
+	scanner.unitIDs = unitIDs
 	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
 		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
 		BaseFlags:                       &f.BaseFlags,
@@ -137,6 +157,13 @@ func (scanner *Scanner) GetScanMetadata() any {
 type Conn struct {
 	Conn    net.Conn
 	scanner *Scanner
+
+	// transactionID is the ID of the last request sent.
+	transactionID uint16
+
+	// skipStale is set once responses to earlier requests may still arrive; these are then read completely and
+	// skipped rather than failing the read.
+	skipStale bool
 }
 
 func (c *Conn) getUnderlyingConn() net.Conn {
@@ -153,7 +180,9 @@ func (c *Conn) getUnderlyingConn() net.Conn {
 //		 ObjectID = <flags.ObjectID, default 0: VendorName>
 //
 // If the response is not a valid modbus response to this packet, then fail with a SCAN_PROTOCOL_ERROR.
-// Otherwise, return the parsed response and status (SCAN_SUCCESS or SCAN_APPLICATION_ERROR)
+// Otherwise, if --full-device-id is set and there was no exception, read the objects of every category the device
+// supports, then if --unit-ids is set, probe each of the unit IDs. Return the parsed response and status
+// (SCAN_SUCCESS or SCAN_APPLICATION_ERROR)
 func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
 	conn, err := dialGroup.Dial(ctx, target)
 	if err != nil {
@@ -161,7 +190,7 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 	}
 	defer zgrab2.CloseConnAndHandleError(conn)
 
-	c := Conn{Conn: conn, scanner: scanner}
+	c := Conn{Conn: conn, scanner: scanner, transactionID: scanner.config.RequestID}
 	req := ModbusRequest{
 		UnitID:   int(scanner.config.UnitID),
 		Function: ModbusFunctionEncapsulatedInterface,
@@ -172,18 +201,8 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 		},
 	}
 
-	data, err := c.MarshalRequest(&req)
-	if err != nil {
-		log.Fatalf("Unexpected error marshaling modbus packet: %v", err)
-	}
-	w := 0
-	var written int
-	for w < len(data) {
-		written, err = c.getUnderlyingConn().Write(data[w:])
-		w += written
-		if err != nil {
-			return zgrab2.TryGetScanStatus(err), nil, err
-		}
+	if err := c.send(&req); err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, err
 	}
 
 	res, err := c.GetModbusResponse()
@@ -219,6 +238,13 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 		return zgrab2.SCAN_PROTOCOL_ERROR, nil, err
 	}
 
+	if scanner.config.FullDeviceID && ret.MEIResponse != nil {
+		ret.DeviceIdentification = c.readDeviceIdentification(int(scanner.config.UnitID), ret.MEIResponse.ConformityLevel, scanner.config.Strict)
+	}
+	if len(scanner.unitIDs) > 0 {
+		ret.Units = c.sweepUnits(scanner.unitIDs, scanner.config.UnitTimeout, scanner.config.Strict)
+	}
+
 	status := zgrab2.SCAN_SUCCESS
 	if res.IsException() {
 		// Note the exception, but note that the modbus protocol was detected
@@ -0,0 +1,148 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"slices"
+	"testing"
+	"time"
+)
+
+// object encodes a device identification object.
+func object(id byte, value string) []byte {
+	return append([]byte{id, byte(len(value))}, value...)
+}
+
+// fakeServer answers Read Device Identification requests on conn until it is closed, using handler to build the PDU
+// (function code and data) of each response. A nil PDU sends no response.
+func fakeServer(conn net.Conn, handler func(unitID, code, objectID byte) []byte) {
+	defer conn.Close()
+	for {
+		header := make([]byte, 8)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		data := make([]byte, int(binary.BigEndian.Uint16(header[4:6]))-2)
+		if _, err := io.ReadFull(conn, data); err != nil || len(data) != 3 {
+			return
+		}
+		pdu := handler(header[6], data[1], data[2])
+		if pdu == nil {
+			continue
+		}
+		response := binary.BigEndian.AppendUint16(header[:4:4], uint16(len(pdu)+1))
+		response = append(response, header[6])
+		if _, err := conn.Write(append(response, pdu...)); err != nil {
+			return
+		}
+	}
+}
+
+// deviceIDResponse returns the PDU of a Read Device Identification response.
+func deviceIDResponse(code, conformity, next byte, objects ...[]byte) []byte {
+	more := byte(0)
+	if next != 0 {
+		more = 0xFF
+	}
+	ret := []byte{0x2B, meiReadDeviceID, code, conformity, more, next, byte(len(objects))}
+	for _, obj := range objects {
+		ret = append(ret, obj...)
+	}
+	return ret
+}
+
+func TestParseUnitIDs(t *testing.T) {
+	ids, err := parseUnitIDs("1-3, 247,0x10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ids, []int{1, 2, 3, 247, 16}) {
+		t.Errorf("ids = %v", ids)
+	}
+	for _, invalid := range []string{"3-1", "256", "a"} {
+		if _, err := parseUnitIDs(invalid); err == nil {
+			t.Errorf("%q parsed", invalid)
+		}
+	}
+}
+
+func TestExceptionResponse(t *testing.T) {
+	res := &ModbusResponse{Function: 0xAB, Data: []byte{0x0B}}
+	event, err := res.getEvent(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := event.ExceptionResponse
+	if ex.ExceptionFunction != 0x2B || ex.ExceptionName != "gateway_target_device_failed_to_respond" || !ex.IsGatewayError {
+		t.Errorf("exception = %+v", ex)
+	}
+	if ExceptionCode(0x42).Name() != "unknown" {
+		t.Error("unexpected name for unknown exception code")
+	}
+}
+
+func TestReadDeviceIdentification(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go fakeServer(server, func(unitID, code, objectID byte) []byte {
+		switch {
+		case code == ReadDeviceIDBasic && objectID == 0:
+			return deviceIDResponse(code, 0x83, 0x02, object(0, "Vendor"), object(1, "P-1"))
+		case code == ReadDeviceIDBasic && objectID == 2:
+			return deviceIDResponse(code, 0x83, 0, object(2, "V1.2"))
+		case code == ReadDeviceIDRegular:
+			return deviceIDResponse(code, 0x83, 0, object(4, "Product"))
+		}
+		return []byte{0xAB, byte(ExceptionIllegalDataAddress)}
+	})
+
+	c := &Conn{Conn: client, scanner: &Scanner{config: &Flags{}}}
+	ident := c.readDeviceIdentification(1, 0x83, true)
+	var names []string
+	for _, category := range ident.Categories {
+		names = append(names, category.Name)
+	}
+	if !slices.Equal(names, []string{"basic", "regular", "extended"}) {
+		t.Fatalf("categories = %v", names)
+	}
+	if basic := ident.Categories[0]; basic.Requests != 2 || basic.ObjectCount != 3 {
+		t.Errorf("basic = %+v", basic)
+	}
+	if ex := ident.Categories[2].ExceptionResponse; ex == nil || ex.ExceptionName != "illegal_data_address" {
+		t.Errorf("extended = %+v", ident.Categories[2])
+	}
+	expected := MEIObjectSet{{OIDVendor, "Vendor"}, {OIDProductCode, "P-1"}, {OIDRevision, "V1.2"}, {OIDProductName, "Product"}}
+	if !slices.Equal(ident.Objects, expected) {
+		t.Errorf("objects = %+v", ident.Objects)
+	}
+}
+
+func TestSweepUnits(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go fakeServer(server, func(unitID, code, objectID byte) []byte {
+		switch unitID {
+		case 1:
+			return deviceIDResponse(code, 0x01, 0, object(0, "Vendor"))
+		case 2:
+			return []byte{0xAB, byte(ExceptionGatewayTargetDeviceFailedToRespond)}
+		}
+		return nil
+	})
+
+	c := &Conn{Conn: client, scanner: &Scanner{config: &Flags{}}}
+	units := c.sweepUnits([]int{3, 1, 2}, 50*time.Millisecond, true)
+	if len(units) != 3 {
+		t.Fatalf("units = %+v", units)
+	}
+	if units[0].Responded || units[0].Error == "" {
+		t.Errorf("unit 3 = %+v", units[0])
+	}
+	if !units[1].Responded || units[1].MEIResponse == nil || units[1].MEIResponse.Objects[0].Value != "Vendor" {
+		t.Errorf("unit 1 = %+v", units[1])
+	}
+	if !units[2].Responded || units[2].ExceptionResponse == nil || !units[2].ExceptionResponse.IsGatewayError {
+		t.Errorf("unit 2 = %+v", units[2])
+	}
+}
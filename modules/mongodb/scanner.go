@@ -26,9 +26,12 @@ type Flags struct {
 type Scanner struct {
 	config              *Flags
 	isMasterMsg         []byte
+	helloMsg            []byte
 	buildInfoCommandMsg []byte
 	buildInfoOpMsg      []byte
 	listDatabasesMsg    []byte
+	listDatabasesOpMsg  []byte
+	serverStatusOpMsg   []byte
 	dialerGroupConfig   *zgrab2.DialerGroupConfig
 }
 
@@ -119,10 +122,11 @@ func getOpMsg(section []byte) []byte {
 	return out
 }
 
-// getBuildInfoOpMsg returns a mongodb "OP" message containing query to retrieve MongoDB build info.
-func getBuildInfoOpMsg() []byte {
+// getCommandOpMsg returns a mongodb "OP" message running cmd against the admin database.
+// The command name must be the first element of cmd, so it is a bson.D rather than a bson.M.
+func getCommandOpMsg(cmd bson.D) []byte {
 	// gleaned from tshark
-	section_payload, err := bson.Marshal(bson.M{"buildinfo": 1, "$db": "admin"})
+	section_payload, err := bson.Marshal(append(cmd, bson.DocElem{Name: "$db", Value: "admin"}))
 	if err != nil {
 		// programmer error
 		log.Fatalf("Invalid BSON: %v", err)
@@ -133,6 +137,11 @@ func getBuildInfoOpMsg() []byte {
 	return op_msg
 }
 
+// getBuildInfoOpMsg returns a mongodb "OP" message containing query to retrieve MongoDB build info.
+func getBuildInfoOpMsg() []byte {
+	return getCommandOpMsg(bson.D{{Name: "buildinfo", Value: 1}})
+}
+
 // BuildEnvironment_t holds build environment information returned by scan.
 type BuildEnvironment_t struct {
 	Distmod    string `bson:"distmod,omitempty" json:"dist_mod,omitempty"`
@@ -174,6 +183,30 @@ type IsMaster_t struct {
 	ReadOnly                     bool  `bson:"readOnly" json:"read_only"`
 }
 
+// Hello_t holds the data returned by a hello query, the successor of isMaster
+type Hello_t struct {
+	Ok                float64  `bson:"ok" json:"-"`
+	IsWritablePrimary bool     `bson:"isWritablePrimary" json:"is_writable_primary"`
+	Secondary         bool     `bson:"secondary,omitempty" json:"secondary,omitempty"`
+	SetName           string   `bson:"setName,omitempty" json:"set_name,omitempty"`
+	Hosts             []string `bson:"hosts,omitempty" json:"hosts,omitempty"`
+	Primary           string   `bson:"primary,omitempty" json:"primary,omitempty"`
+	Me                string   `bson:"me,omitempty" json:"me,omitempty"`
+	Msg               string   `bson:"msg,omitempty" json:"msg,omitempty"`
+	ConnectionID      int32    `bson:"connectionId,omitempty" json:"connection_id,omitempty"`
+	MaxWireVersion    int32    `bson:"maxWireVersion,omitempty" json:"max_wire_version,omitempty"`
+}
+
+// serverStatus_t holds the fields of a serverStatus reply the scanner records.
+type serverStatus_t struct {
+	Ok            float64 `bson:"ok"`
+	ErrMsg        string  `bson:"errmsg,omitempty"`
+	CodeName      string  `bson:"codeName,omitempty"`
+	StorageEngine struct {
+		Name string `bson:"name"`
+	} `bson:"storageEngine"`
+}
+
 type DatabaseInfo_t struct {
 	Name       string `bson:"name" json:"name"`
 	SizeOnDisk int64  `bson:"sizeOnDisk" json:"size_on_disk"`
@@ -183,13 +216,25 @@ type DatabaseInfo_t struct {
 type ListDatabases_t struct {
 	Databases []DatabaseInfo_t `bson:"databases,omitempty" json:"databases,omitempty"`
 	TotalSize int64            `bson:"totalSize,omitempty" json:"total_size,omitempty"`
+	// Error is the error message of a failed listDatabases command, typically because authentication is required.
+	Error    string  `bson:"errmsg,omitempty" json:"error,omitempty"`
+	CodeName string  `bson:"codeName,omitempty" json:"code_name,omitempty"`
+	Ok       float64 `bson:"ok" json:"-"`
 }
 
 // Result holds the data returned by a scan
 type Result struct {
 	IsMaster     *IsMaster_t      `json:"is_master,omitempty"`
+	Hello        *Hello_t         `json:"hello,omitempty"`
 	BuildInfo    *BuildInfo_t     `json:"build_info,omitempty"`
 	DatabaseInfo *ListDatabases_t `json:"database_info,omitempty"`
+
+	// UnauthenticatedAccess is true if the server ran listDatabases without credentials.
+	UnauthenticatedAccess bool `json:"unauthenticated_access"`
+
+	// StorageEngine is the name of the storage engine in use, from serverStatus, which only
+	// runs with unauthenticated access.
+	StorageEngine string `json:"storage_engine,omitempty"`
 }
 
 // Init initializes the scanner
@@ -200,6 +245,9 @@ func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
 	scanner.buildInfoCommandMsg = getBuildInfoQuery()
 	scanner.buildInfoOpMsg = getBuildInfoOpMsg()
 	scanner.listDatabasesMsg = getListDatabasesMsg()
+	scanner.helloMsg = getCommandOpMsg(bson.D{{Name: "hello", Value: 1}})
+	scanner.listDatabasesOpMsg = getCommandOpMsg(bson.D{{Name: "listDatabases", Value: 1}})
+	scanner.serverStatusOpMsg = getCommandOpMsg(bson.D{{Name: "serverStatus", Value: 1}})
 	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
 		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
 		BaseFlags:                       &f.BaseFlags,
@@ -317,78 +365,131 @@ func getIsMaster(conn *Connection) (*IsMaster_t, error) {
 	return document, nil
 }
 
-func listDatabases(conn *Connection) (*ListDatabases_t, error) {
-	document := ListDatabases_t{}
-	if err := conn.Write(conn.scanner.listDatabasesMsg); err != nil {
-		return nil, fmt.Errorf("failed to write listDatabases message: %w", err)
+// runCommand writes a command message and unmarshals the reply document into document. Replies to OP_MSG
+// commands carry it after the flags and section kind, replies to OP_QUERY commands after the OP_REPLY fields.
+func runCommand(conn *Connection, msg []byte, opMsg bool, document any) error {
+	if err := conn.Write(msg); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
 	}
-
-	msg, err := conn.ReadMsg()
+	reply, err := conn.readMsg(maxCommandReplyLen)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read listDatabases response: %w", err)
+		return fmt.Errorf("failed to read reply: %w", err)
+	}
+	doc_offset := MSGHEADER_LEN + 20
+	if opMsg {
+		doc_offset = MSGHEADER_LEN + 5
+	}
+	if len(reply) < doc_offset+4 {
+		return fmt.Errorf("server truncated message - no reply doc (%d bytes: %s)", len(reply), hex.EncodeToString(reply))
+	}
+	if err = bson.Unmarshal(reply[doc_offset:], document); err != nil {
+		return fmt.Errorf("server sent invalid BSON reply doc: %w", err)
 	}
+	return nil
+}
 
-	if err = bson.Unmarshal(msg[MSGHEADER_LEN+20:], &document); err != nil {
-		return nil, fmt.Errorf("failed to unmarshall listDatabases message: %w", err)
+// listDatabases issues the listDatabases command, which fails unless the server permits unauthenticated access.
+func listDatabases(conn *Connection, opMsg bool) (*ListDatabases_t, error) {
+	document := ListDatabases_t{}
+	msg := conn.scanner.listDatabasesMsg
+	if opMsg {
+		msg = conn.scanner.listDatabasesOpMsg
+	}
+	if err := runCommand(conn, msg, opMsg, &document); err != nil {
+		return nil, fmt.Errorf("listDatabases: %w", err)
 	}
 	return &document, nil
 }
 
-// Scan connects to a host and performs a scan.
-// https://github.com/mongodb/specifications/blob/master/source/message/OP_MSG.rst
-func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
-	scan, err := scanner.StartScan(ctx, target, dialGroup)
-	if err != nil {
-		return zgrab2.TryGetScanStatus(err), nil, err
+// getHello issues the hello command, which servers before 4.4.2 may not know.
+// https://www.mongodb.com/docs/manual/reference/command/hello/
+func getHello(conn *Connection) (*Hello_t, error) {
+	document := Hello_t{}
+	if err := runCommand(conn, conn.scanner.helloMsg, true, &document); err != nil {
+		return nil, fmt.Errorf("hello: %w", err)
 	}
-	defer scan.Close()
+	return &document, nil
+}
 
-	result := scan.result
-	result.IsMaster, err = getIsMaster(scan.conn)
-	if err != nil {
-		return zgrab2.SCAN_PROTOCOL_ERROR, nil, fmt.Errorf("isMaster query failed to target %s: %w", target.String(), err)
+// getStorageEngine returns the name of the storage engine from serverStatus, which requires unauthenticated access.
+func getStorageEngine(conn *Connection) (string, error) {
+	var document serverStatus_t
+	if err := runCommand(conn, conn.scanner.serverStatusOpMsg, true, &document); err != nil {
+		return "", fmt.Errorf("serverStatus: %w", err)
 	}
-
-	result.DatabaseInfo, err = listDatabases(scan.conn)
+	if document.Ok != 1 {
+		return "", fmt.Errorf("serverStatus failed: %s (%s)", document.ErrMsg, document.CodeName)
+	}
+	return document.StorageEngine.Name, nil
+}
+
+// scanConnection runs the commands of a scan on conn, filling out result.
+//  1. isMaster, which every server answers over OP_QUERY.
+//  2. hello, on servers with OP_MSG support (maxWireVersion >= 6).
+//  3. listDatabases, whose success means the server permits unauthenticated access.
+//  4. buildInfo, which servers answer without credentials.
+//  5. serverStatus for the storage engine, with unauthenticated access.
+func scanConnection(conn *Connection, result *Result) (zgrab2.ScanStatus, error) {
+	var err error
+	result.IsMaster, err = getIsMaster(conn)
 	if err != nil {
-		return zgrab2.SCAN_PROTOCOL_ERROR, nil, fmt.Errorf("listDatabases query failed to target %s: %w", target.String(), err)
+		return zgrab2.SCAN_PROTOCOL_ERROR, fmt.Errorf("isMaster query failed: %w", err)
 	}
 
-	var query []byte
-	var resplen_offset int
-	var resp_offset int
-
 	// See: https://github.com/mongodb/specifications/blob/master/source/message/OP_MSG.rst
 	// "OP_MSG is only available in MongoDB 3.6 (maxWireVersion >= 6) and later."
-	if result.IsMaster.MaxWireVersion < 6 {
-		query = scanner.buildInfoCommandMsg
-		resplen_offset = 4
-		resp_offset = 20
-	} else {
-		query = scanner.buildInfoOpMsg
-		resplen_offset = 5
-		resp_offset = 5
+	opMsg := result.IsMaster.MaxWireVersion >= 6
+
+	if opMsg {
+		hello, err := getHello(conn)
+		if err != nil {
+			return zgrab2.TryGetScanStatus(err), err
+		}
+		if hello.Ok == 1 {
+			result.Hello = hello
+		}
 	}
 
-	err = scan.conn.Write(query)
+	result.DatabaseInfo, err = listDatabases(conn, opMsg)
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to write buildInfo message to target %s: %w", target.String(), err)
+		return zgrab2.SCAN_PROTOCOL_ERROR, fmt.Errorf("listDatabases query failed: %w", err)
 	}
-	msg, err := scan.conn.ReadMsg()
-	if err != nil {
-		return zgrab2.TryGetScanStatus(err), &result, fmt.Errorf("failed to read buildInfo message from target %s: %w", target.String(), err)
+	result.UnauthenticatedAccess = result.DatabaseInfo.Ok == 1
+
+	query := conn.scanner.buildInfoCommandMsg
+	if opMsg {
+		query = conn.scanner.buildInfoOpMsg
+	}
+	if err = runCommand(conn, query, opMsg, &result.BuildInfo); err != nil {
+		return zgrab2.TryGetScanStatus(err), fmt.Errorf("buildInfo query failed: %w", err)
+	}
+
+	if result.UnauthenticatedAccess && opMsg {
+		if result.StorageEngine, err = getStorageEngine(conn); err != nil {
+			// the scan still succeeded
+			log.Debugf("Could not get the storage engine: %v", err)
+		}
 	}
+	return zgrab2.SCAN_SUCCESS, nil
+}
 
-	if len(msg) < MSGHEADER_LEN+resplen_offset {
-		err = fmt.Errorf("server truncated message - no metadata doc (%d bytes: %s)", len(msg), hex.EncodeToString(msg))
-		return zgrab2.SCAN_PROTOCOL_ERROR, &result, err
+// Scan connects to a host and performs a scan.
+// https://github.com/mongodb/specifications/blob/master/source/message/OP_MSG.rst
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	scan, err := scanner.StartScan(ctx, target, dialGroup)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, err
 	}
+	defer scan.Close()
 
-	err = bson.Unmarshal(msg[MSGHEADER_LEN+resp_offset:], &result.BuildInfo)
+	status, err := scanConnection(scan.conn, scan.result)
 	if err != nil {
-		return zgrab2.SCAN_PROTOCOL_ERROR, nil, fmt.Errorf("failed to unmarshall buildInfo message from target %s: %w", target.String(), err)
+		if scan.result.IsMaster == nil {
+			return status, nil, fmt.Errorf("target %s: %w", target.String(), err)
+		}
+		return status, scan.result, fmt.Errorf("target %s: %w", target.String(), err)
 	}
-	return zgrab2.SCAN_SUCCESS, &result, nil
+	return zgrab2.SCAN_SUCCESS, scan.result, nil
 }
 
 // RegisterModule registers the zgrab2 module.
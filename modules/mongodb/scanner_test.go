@@ -0,0 +1,116 @@
+package mongodb
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/zmap/zgrab2"
+)
+
+// fakeServer answers each command read from the returned connection with the reply for its name.
+func fakeServer(t *testing.T, replies map[string]bson.M) *Connection {
+	client, server := net.Pipe()
+	t.Cleanup(func() { client.Close() })
+	go func() {
+		defer server.Close()
+		for {
+			var header [MSGHEADER_LEN]byte
+			if _, err := io.ReadFull(server, header[:]); err != nil {
+				return
+			}
+			body := make([]byte, binary.LittleEndian.Uint32(header[0:])-MSGHEADER_LEN)
+			if _, err := io.ReadFull(server, body); err != nil {
+				return
+			}
+			var doc []byte
+			opCode := binary.LittleEndian.Uint32(header[12:])
+			switch opCode {
+			case OP_QUERY:
+				// flags, collection name, skip and return counts
+				idx := 4
+				for body[idx] != 0 {
+					idx++
+				}
+				doc = body[idx+9:]
+			case OP_MSG:
+				doc = body[5:]
+			}
+			var command bson.D
+			if err := bson.Unmarshal(doc, &command); err != nil || len(command) == 0 {
+				return
+			}
+			reply, ok := replies[command[0].Name]
+			if !ok {
+				reply = bson.M{"ok": 0.0, "errmsg": "no such command", "codeName": "CommandNotFound"}
+			}
+			payload, _ := bson.Marshal(reply)
+			var msg []byte
+			if opCode == OP_QUERY {
+				msg = make([]byte, MSGHEADER_LEN+20, MSGHEADER_LEN+20+len(payload))
+				binary.LittleEndian.PutUint32(msg[12:], OP_REPLY)
+				binary.LittleEndian.PutUint32(msg[MSGHEADER_LEN+16:], 1)
+			} else {
+				msg = make([]byte, MSGHEADER_LEN+5, MSGHEADER_LEN+5+len(payload))
+				binary.LittleEndian.PutUint32(msg[12:], OP_MSG)
+			}
+			msg = append(msg, payload...)
+			binary.LittleEndian.PutUint32(msg[0:], uint32(len(msg)))
+			server.Write(msg)
+		}
+	}()
+	scanner := new(Scanner)
+	if err := scanner.Init(&Flags{}); err != nil {
+		t.Fatal(err)
+	}
+	return &Connection{scanner: scanner, conn: client}
+}
+
+func TestScanConnection(t *testing.T) {
+	buildInfo := bson.M{"ok": 1.0, "version": "7.0.5", "gitVersion": "7809d71e84e314b497f282ea8aa06d7ded3eb205", "storageEngines": []string{"devnull", "wiredTiger"}}
+	tests := map[string]struct {
+		listDatabases bson.M
+		unauth        bool
+		storageEngine string
+	}{
+		"open": {
+			listDatabases: bson.M{"ok": 1.0, "databases": []bson.M{{"name": "admin", "sizeOnDisk": int64(40960), "empty": false}}, "totalSize": int64(40960)},
+			unauth:        true,
+			storageEngine: "wiredTiger",
+		},
+		"authenticated": {
+			listDatabases: bson.M{"ok": 0.0, "errmsg": "command listDatabases requires authentication", "code": 13, "codeName": "Unauthorized"},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			conn := fakeServer(t, map[string]bson.M{
+				"isMaster":      {"ok": 1.0, "ismaster": true, "maxWireVersion": 21},
+				"hello":         {"ok": 1.0, "isWritablePrimary": true, "setName": "rs0", "hosts": []string{"db0:27017"}},
+				"listDatabases": test.listDatabases,
+				"buildinfo":     buildInfo,
+				"serverStatus":  {"ok": 1.0, "storageEngine": bson.M{"name": "wiredTiger"}},
+			})
+			var result Result
+			status, err := scanConnection(conn, &result)
+			if status != zgrab2.SCAN_SUCCESS || err != nil {
+				t.Fatalf("scanConnection = %v, %v", status, err)
+			}
+			if result.Hello == nil || result.Hello.SetName != "rs0" || !result.Hello.IsWritablePrimary {
+				t.Errorf("hello = %+v", result.Hello)
+			}
+			if result.BuildInfo == nil || result.BuildInfo.Version != "7.0.5" || result.BuildInfo.GitVersion == "" {
+				t.Errorf("build info = %+v", result.BuildInfo)
+			}
+			if result.UnauthenticatedAccess != test.unauth || result.StorageEngine != test.storageEngine {
+				t.Errorf("unauthenticated access = %v, storage engine = %q", result.UnauthenticatedAccess, result.StorageEngine)
+			}
+			if !test.unauth && result.DatabaseInfo.CodeName != "Unauthorized" {
+				t.Errorf("database info = %+v", result.DatabaseInfo)
+			}
+		})
+	}
+}
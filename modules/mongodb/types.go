@@ -36,6 +36,9 @@ const (
 	QUERY_RESP_AWAIT_CAP    = 8
 
 	MSGHEADER_LEN = 16
+
+	// Replies to commands are only read once isMaster identified a MongoDB server, so they may be larger
+	maxCommandReplyLen = 1024 * 1024
 )
 
 // Connection holds the state for a single connection within a scan.
@@ -49,13 +52,18 @@ type Connection struct {
 
 // ReadMsg reads a full MongoDB message from the connection.
 func (conn *Connection) ReadMsg() ([]byte, error) {
+	return conn.readMsg(5125)
+}
+
+// readMsg reads a full MongoDB message of at most maxlen bytes from the connection.
+func (conn *Connection) readMsg(maxlen uint32) ([]byte, error) {
 	var msglen_buf [4]byte
 	_, err := io.ReadFull(conn.conn, msglen_buf[:])
 	if err != nil {
 		return nil, err
 	}
 	msglen := binary.LittleEndian.Uint32(msglen_buf[:])
-	if msglen < 4 || msglen > 5125 {
+	if msglen < 4 || msglen > maxlen {
 		// msglen is length of message which includes msglen itself; Less than
 		// four is invalid. More than a few K probably mean this isn't actually
 		// a mongodb server.
@@ -0,0 +1,169 @@
+package mqtt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// v3ReturnCodes are the CONNACK return codes of MQTT 3.1.1 (section 3.2.2.3).
+var v3ReturnCodes = map[byte]string{
+	0x00: "accepted",
+	0x01: "unacceptable_protocol_version",
+	0x02: "identifier_rejected",
+	0x03: "server_unavailable",
+	0x04: "bad_username_or_password",
+	0x05: "not_authorized",
+}
+
+// v5ReasonCodes are the CONNACK reason codes of MQTT 5.0 (section 3.2.2.2).
+var v5ReasonCodes = map[byte]string{
+	0x00: "success",
+	0x80: "unspecified_error",
+	0x81: "malformed_packet",
+	0x82: "protocol_error",
+	0x83: "implementation_specific_error",
+	0x84: "unsupported_protocol_version",
+	0x85: "client_identifier_not_valid",
+	0x86: "bad_username_or_password",
+	0x87: "not_authorized",
+	0x88: "server_unavailable",
+	0x89: "server_busy",
+	0x8A: "banned",
+	0x8C: "bad_authentication_method",
+	0x90: "topic_name_invalid",
+	0x95: "packet_too_large",
+	0x97: "quota_exceeded",
+	0x99: "payload_format_invalid",
+	0x9A: "retain_not_supported",
+	0x9B: "qos_not_supported",
+	0x9C: "use_another_server",
+	0x9D: "server_moved",
+	0x9F: "connection_rate_exceeded",
+}
+
+// returnCodeName returns the name of a CONNACK return/reason code.
+func returnCodeName(code byte, v5 bool) string {
+	names := v3ReturnCodes
+	if v5 {
+		names = v5ReasonCodes
+	}
+	if name, ok := names[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown (0x%02x)", code)
+}
+
+// UserProperty is a name/value pair sent in a User Property.
+type UserProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ConnAckProperties are the properties an MQTT 5 broker advertises in its CONNACK (section 3.2.2.3).
+type ConnAckProperties struct {
+	SessionExpiryInterval            *uint32        `json:"session_expiry_interval,omitempty"`
+	ReceiveMaximum                   *uint16        `json:"receive_maximum,omitempty"`
+	MaximumQoS                       *uint8         `json:"maximum_qos,omitempty"`
+	RetainAvailable                  *bool          `json:"retain_available,omitempty"`
+	MaximumPacketSize                *uint32        `json:"maximum_packet_size,omitempty"`
+	AssignedClientIdentifier         string         `json:"assigned_client_identifier,omitempty"`
+	TopicAliasMaximum                *uint16        `json:"topic_alias_maximum,omitempty"`
+	ReasonString                     string         `json:"reason_string,omitempty"`
+	UserProperties                   []UserProperty `json:"user_properties,omitempty"`
+	WildcardSubscriptionAvailable    *bool          `json:"wildcard_subscription_available,omitempty"`
+	SubscriptionIdentifiersAvailable *bool          `json:"subscription_identifiers_available,omitempty"`
+	SharedSubscriptionAvailable      *bool          `json:"shared_subscription_available,omitempty"`
+	ServerKeepAlive                  *uint16        `json:"server_keep_alive,omitempty"`
+	ResponseInformation              string         `json:"response_information,omitempty"`
+	ServerReference                  string         `json:"server_reference,omitempty"`
+	AuthenticationMethod             string         `json:"authentication_method,omitempty"`
+}
+
+var errMalformedProperties = errors.New("malformed CONNACK properties")
+
+// propertyReader reads property values from a byte slice.
+type propertyReader struct {
+	data []byte
+	err  error
+}
+
+func (r *propertyReader) take(n int) []byte {
+	if r.err != nil || len(r.data) < n {
+		r.err = errMalformedProperties
+		return make([]byte, n)
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *propertyReader) byte() byte     { return r.take(1)[0] }
+func (r *propertyReader) uint16() uint16 { return binary.BigEndian.Uint16(r.take(2)) }
+func (r *propertyReader) uint32() uint32 { return binary.BigEndian.Uint32(r.take(4)) }
+func (r *propertyReader) string() string { return string(r.take(int(r.uint16()))) }
+func (r *propertyReader) bool() *bool    { b := r.byte() == 1; return &b }
+
+// parseConnAckProperties parses the property list of an MQTT 5 CONNACK, without its length prefix.
+func parseConnAckProperties(data []byte) (*ConnAckProperties, error) {
+	props := new(ConnAckProperties)
+	r := &propertyReader{data: data}
+	for len(r.data) > 0 && r.err == nil {
+		id, n := binary.Uvarint(r.data)
+		if n <= 0 {
+			return props, errMalformedProperties
+		}
+		r.data = r.data[n:]
+		switch id {
+		case 0x11:
+			v := r.uint32()
+			props.SessionExpiryInterval = &v
+		case 0x12:
+			props.AssignedClientIdentifier = r.string()
+		case 0x13:
+			v := r.uint16()
+			props.ServerKeepAlive = &v
+		case 0x15:
+			props.AuthenticationMethod = r.string()
+		case 0x16:
+			r.take(int(r.uint16())) // authentication data
+		case 0x1A:
+			props.ResponseInformation = r.string()
+		case 0x1C:
+			props.ServerReference = r.string()
+		case 0x1F:
+			props.ReasonString = r.string()
+		case 0x21:
+			v := r.uint16()
+			props.ReceiveMaximum = &v
+		case 0x22:
+			v := r.uint16()
+			props.TopicAliasMaximum = &v
+		case 0x24:
+			v := r.byte()
+			props.MaximumQoS = &v
+		case 0x25:
+			props.RetainAvailable = r.bool()
+		case 0x26:
+			name := r.string()
+			props.UserProperties = append(props.UserProperties, UserProperty{Name: name, Value: r.string()})
+		case 0x27:
+			v := r.uint32()
+			props.MaximumPacketSize = &v
+		case 0x28:
+			props.WildcardSubscriptionAvailable = r.bool()
+		case 0x29:
+			props.SubscriptionIdentifiersAvailable = r.bool()
+		case 0x2A:
+			props.SharedSubscriptionAvailable = r.bool()
+		default:
+			return props, fmt.Errorf("%w: unknown property 0x%02x", errMalformedProperties, id)
+		}
+	}
+	return props, r.err
+}
+
+// encodeVariableByteInteger encodes n as an MQTT Variable Byte Integer.
+func encodeVariableByteInteger(n int) []byte {
+	return binary.AppendUvarint(nil, uint64(n))
+}
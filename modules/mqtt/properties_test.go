@@ -0,0 +1,77 @@
+package mqtt
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestParseConnAckProperties(t *testing.T) {
+	data := []byte{
+		0x21, 0x00, 0x0A, // Receive Maximum 10
+		0x24, 0x01, // Maximum QoS 1
+		0x25, 0x00, // Retain Available false
+		0x12, 0x00, 0x03, 'a', 'b', 'c', // Assigned Client Identifier
+		0x26, 0x00, 0x01, 'k', 0x00, 0x01, 'v', // User Property
+		0x2A, 0x01, // Shared Subscription Available true
+	}
+	props, err := parseConnAckProperties(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if props.ReceiveMaximum == nil || *props.ReceiveMaximum != 10 {
+		t.Errorf("bad receive maximum %v", props.ReceiveMaximum)
+	}
+	if props.MaximumQoS == nil || *props.MaximumQoS != 1 {
+		t.Errorf("bad maximum QoS %v", props.MaximumQoS)
+	}
+	if props.RetainAvailable == nil || *props.RetainAvailable {
+		t.Errorf("bad retain available %v", props.RetainAvailable)
+	}
+	if props.SharedSubscriptionAvailable == nil || !*props.SharedSubscriptionAvailable {
+		t.Errorf("bad shared subscription available %v", props.SharedSubscriptionAvailable)
+	}
+	if props.AssignedClientIdentifier != "abc" || len(props.UserProperties) != 1 || props.UserProperties[0] != (UserProperty{"k", "v"}) {
+		t.Errorf("unexpected properties %+v", props)
+	}
+
+	if _, err = parseConnAckProperties([]byte{0x21, 0x00}); err == nil {
+		t.Error("expected error for truncated property")
+	}
+}
+
+func TestSendMQTTConnectPacket(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	mqtt := Connection{conn: client, config: &Flags{ClientID: "MQTTClient"}}
+	go func() {
+		_ = mqtt.SendMQTTConnectPacket(true)
+		client.Close()
+	}()
+	got := make([]byte, 64)
+	n, _ := server.Read(got)
+	want := []byte{
+		0x10, 0x17,
+		0x00, 0x04, 'M', 'Q', 'T', 'T', 0x05, 0x02, 0x00, 0x3C, 0x00,
+		0x00, 0x0A, 'M', 'Q', 'T', 'T', 'C', 'l', 'i', 'e', 'n', 't',
+	}
+	if !bytes.Equal(got[:n], want) {
+		t.Errorf("got %x, want %x", got[:n], want)
+	}
+}
+
+func TestProcessConnAckInvalidPropertiesLength(t *testing.T) {
+	for _, body := range [][]byte{
+		// 10 byte variable byte integer overflowing int
+		{0x00, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f},
+		// 5 byte variable byte integer
+		{0x00, 0x00, 0x80, 0x80, 0x80, 0x80, 0x01},
+		// properties length past the end of the packet
+		{0x00, 0x00, 0x05, 0x21, 0x00},
+	} {
+		mqtt := Connection{config: &Flags{}}
+		if err := mqtt.processConnAck(body); err == nil {
+			t.Errorf("%x: processed", body)
+		}
+	}
+}
@@ -15,10 +15,14 @@ import (
 
 // ScanResults is the output of the scan.
 type ScanResults struct {
-	SessionPresent    bool           `json:"session_present,omitempty"`
-	ConnectReturnCode byte           `json:"connect_return_code,omitempty"`
-	Response          string         `json:"response,omitempty"`
-	TLSLog            *zgrab2.TLSLog `json:"tls,omitempty"`
+	SessionPresent    bool   `json:"session_present,omitempty"`
+	ConnectReturnCode byte   `json:"connect_return_code,omitempty"`
+	ReturnCodeName    string `json:"return_code_name,omitempty"`
+	// AnonymousAccepted is true if the broker accepted the CONNECT, which carries no credentials.
+	AnonymousAccepted bool               `json:"anonymous_accepted"`
+	Properties        *ConnAckProperties `json:"properties,omitempty"`
+	Response          string             `json:"response,omitempty"`
+	TLSLog            *zgrab2.TLSLog     `json:"tls,omitempty"`
 }
 
 // Flags are the MQTT-specific command-line flags.
@@ -26,8 +30,9 @@ type Flags struct {
 	zgrab2.BaseFlags
 	zgrab2.TLSFlags
 
-	V5     bool `long:"v5" description:"Scanning MQTT v5.0. Otherwise scanning MQTT v3.1.1"`
-	UseTLS bool `long:"tls" description:"Use TLS for the MQTT connection"`
+	V5       bool   `long:"v5" description:"Scanning MQTT v5.0. Otherwise scanning MQTT v3.1.1"`
+	UseTLS   bool   `long:"tls" description:"Use TLS for the MQTT connection (usually on port 8883)"`
+	ClientID string `long:"client-id" default:"MQTTClient" description:"Client identifier to send in the CONNECT packet"`
 }
 
 // Module implements the zgrab2.Module interface.
@@ -75,6 +80,9 @@ func (m *Module) Description() string {
 
 // Validate flags
 func (f *Flags) Validate(_ []string) error {
+	if len(f.ClientID) > 0xFFFF {
+		return fmt.Errorf("client-id must be at most %d bytes", 0xFFFF)
+	}
 	return nil
 }
 
@@ -127,41 +135,26 @@ func (scanner *Scanner) GetTrigger() string {
 
 // SendMQTTConnectPacket constructs and sends an MQTT CONNECT packet to the server.
 func (mqtt *Connection) SendMQTTConnectPacket(v5 bool) error {
-	var packet []byte
+	variableHeader := []byte{
+		0x00, 0x04, 'M', 'Q', 'T', 'T', // Protocol Name
+		0x04,       // Protocol Level (MQTT v3.1.1)
+		0x02,       // Connect Flags (Clean Start)
+		0x00, 0x3C, // Keep Alive (60 seconds)
+	}
 	if v5 {
-		packet = []byte{
-			// Fixed Header
-			0x10, // Control Packet Type (CONNECT) and flags
-			0x17, // Remaining Length (23 bytes)
-
-			// Variable Header
-			0x00, 0x04, 'M', 'Q', 'T', 'T', // Protocol Name
-			0x05,       // Protocol Level (MQTT v5.0)
-			0x02,       // Connect Flags (Clean Start)
-			0x00, 0x3C, // Keep Alive (60 seconds)
-
-			// Properties
-			0x00, // Properties Length (0)
-
-			// Payload
-			0x00, 0x0A, 'M', 'Q', 'T', 'T', 'C', 'l', 'i', 'e', 'n', 't', // Client Identifier
-		}
-	} else {
-		packet = []byte{
-			// Fixed Header
-			0x10, // Control Packet Type (CONNECT) and flags
-			0x16, // Remaining Length (22 bytes)
-
-			// Variable Header
-			0x00, 0x04, 'M', 'Q', 'T', 'T', // Protocol Name
-			0x04,       // Protocol Level (MQTT v3.1.1)
-			0x02,       // Connect Flags (Clean Start)
-			0x00, 0x3C, // Keep Alive (60 seconds)
-
-			// Payload
-			0x00, 0x0A, 'M', 'Q', 'T', 'T', 'C', 'l', 'i', 'e', 'n', 't', // Client Identifier
-		}
+		variableHeader[6] = 0x05                      // Protocol Level (MQTT v5.0)
+		variableHeader = append(variableHeader, 0x00) // Properties Length (0)
 	}
+
+	// Payload: Client Identifier
+	clientID := mqtt.config.ClientID
+	payload := binary.BigEndian.AppendUint16(nil, uint16(len(clientID)))
+	payload = append(payload, clientID...)
+
+	// Fixed Header: Control Packet Type (CONNECT) and flags, Remaining Length
+	packet := append([]byte{0x10}, encodeVariableByteInteger(len(variableHeader)+len(payload))...)
+	packet = append(packet, variableHeader...)
+	packet = append(packet, payload...)
 	_, err := mqtt.conn.Write(packet)
 	return err
 }
@@ -188,6 +181,8 @@ func (mqtt *Connection) ReadMQTTv3Packet() error {
 
 	mqtt.results.SessionPresent = (response[2] & 0x01) == 0x01
 	mqtt.results.ConnectReturnCode = response[3]
+	mqtt.results.ReturnCodeName = returnCodeName(response[3], false)
+	mqtt.results.AnonymousAccepted = response[3] == 0x00
 
 	return nil
 }
@@ -229,7 +224,7 @@ func (mqtt *Connection) ReadMQTTv5Packet() error {
 	// Process the packet based on its type
 	switch packetType {
 	case 2: // CONNACK
-		return mqtt.processConnAck(packet)
+		return mqtt.processConnAck(packet[1+len(remainingLengthBytes):])
 	case 14: // DISCONNECT
 		return mqtt.processDisconnect(packet)
 	default:
@@ -237,23 +232,33 @@ func (mqtt *Connection) ReadMQTTv5Packet() error {
 	}
 }
 
-func (mqtt *Connection) processConnAck(packet []byte) error {
-	if len(packet) < 4 {
+// processConnAck parses the variable header of an MQTT 5 CONNACK.
+func (mqtt *Connection) processConnAck(body []byte) error {
+	if len(body) < 2 {
 		return errors.New("invalid CONNACK packet length")
 	}
 
-	mqtt.results.SessionPresent = (packet[2] & 0x01) == 0x01
-	mqtt.results.ConnectReturnCode = packet[3]
+	mqtt.results.SessionPresent = (body[0] & 0x01) == 0x01
+	mqtt.results.ConnectReturnCode = body[1]
+	mqtt.results.ReturnCodeName = returnCodeName(body[1], true)
+	mqtt.results.AnonymousAccepted = body[1] == 0x00
 
 	// Process properties if present
-	if len(packet) > 4 {
-		propertiesLength, n := binary.Uvarint(packet[4:])
-		propertiesStart := 4 + n
-		propertiesEnd := propertiesStart + int(propertiesLength)
-
-		if propertiesEnd > len(packet) {
+	if len(body) > 2 {
+		// variable byte integers are at most 4 bytes long
+		propertiesLength, n := binary.Uvarint(body[2:])
+		if n <= 0 || n > 4 {
+			return errors.New("invalid properties length in CONNACK")
+		}
+		propertiesStart := 2 + n
+		if propertiesLength > uint64(len(body)-propertiesStart) {
 			return errors.New("invalid properties length in CONNACK")
 		}
+		properties, err := parseConnAckProperties(body[propertiesStart : propertiesStart+int(propertiesLength)])
+		mqtt.results.Properties = properties
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -267,10 +272,7 @@ func (mqtt *Connection) processDisconnect(packet []byte) error {
 	// Process properties if present
 	if len(packet) > 3 {
 		propertiesLength, n := binary.Uvarint(packet[3:])
-		propertiesStart := 3 + n
-		propertiesEnd := propertiesStart + int(propertiesLength)
-
-		if propertiesEnd > len(packet) {
+		if n <= 0 || n > 4 || propertiesLength > uint64(len(packet)-3-n) {
 			return errors.New("invalid properties length in DISCONNECT")
 		}
 	}
@@ -230,23 +230,26 @@ var encryptModeToString = map[EncryptMode]string{
 
 // ServerVersion is a direct representation of the VERSION PRELOGIN token value.
 type ServerVersion struct {
-	Major       uint8  `json:"major"`
-	Minor       uint8  `json:"minor"`
-	BuildNumber uint16 `json:"build_number"`
+	Major          uint8  `json:"major"`
+	Minor          uint8  `json:"minor"`
+	BuildNumber    uint16 `json:"build_number"`
+	SubBuildNumber uint16 `json:"sub_build_number,omitempty"`
 }
 
 // Decode a VERSION response and return the parsed ServerVersion struct
 // As defined in the MSDN docs, these come from token 0:
 //
 //	VERSION -- UL_VERSION = ((US_BUILD<<16)|(VER_SQL_MINOR<<8)|( VER_SQL_MAJOR))
+//	           US_SUBBUILD
 func decodeServerVersion(buf []byte) *ServerVersion {
 	if len(buf) != 6 {
 		return nil
 	}
 	return &ServerVersion{
-		Major:       buf[0],
-		Minor:       buf[1],
-		BuildNumber: binary.BigEndian.Uint16(buf[2:4]),
+		Major:          buf[0],
+		Minor:          buf[1],
+		BuildNumber:    binary.BigEndian.Uint16(buf[2:4]),
+		SubBuildNumber: binary.BigEndian.Uint16(buf[4:6]),
 	}
 }
 
@@ -0,0 +1,34 @@
+package mssql
+
+import (
+	"testing"
+)
+
+func TestDecodePreloginOptions(t *testing.T) {
+	sent := PreloginOptions{
+		PreloginVersion:         {15, 0, 0x07, 0xd0, 0, 5},
+		PreloginEncryption:      {EncryptModeOff},
+		PreloginInstance:        []byte("SQLEXPRESS\x00"),
+		PreloginThreadID:        {0, 0, 0x12, 0x34},
+		PreloginMARS:            {1},
+		PreloginFedAuthRequired: {0},
+	}
+	body, err := sent.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	options, rest, err := decodePreloginOptions(body)
+	if err != nil || len(rest) != 0 || len(*options) != len(sent) {
+		t.Fatalf("decodePreloginOptions = %v, %x, %v", options, rest, err)
+	}
+	version := options.GetVersion()
+	if version.String() != "15.0.2000" || version.SubBuildNumber != 5 {
+		t.Errorf("version = %+v", version)
+	}
+	if mars, err := options.GetByteOption(PreloginMARS); err != nil || mars != 1 {
+		t.Errorf("MARS = %d, %v", mars, err)
+	}
+	if string((*options)[PreloginInstance]) != "SQLEXPRESS\x00" {
+		t.Errorf("instance = %q", (*options)[PreloginInstance])
+	}
+}
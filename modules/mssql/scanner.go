@@ -7,8 +7,11 @@
 //
 // The scan performs a PRELOGIN and if possible does a TLS handshake.
 //
-// The output is the the server version and instance name, and if applicable the
-// TLS output.
+// The output is the the server version and instance name, the other PRELOGIN
+// options, and if applicable the TLS output.
+//
+// Named instances listen on other ports, which the mssql-browser module
+// enumerates through the SQL Server Browser service on UDP port 1434.
 package mssql
 
 import (
@@ -36,9 +39,20 @@ type ScanResults struct {
 	// server returning an empty name and no name being returned.
 	InstanceName *string `json:"instance_name,omitempty"`
 
-	// PreloginOptions are the raw key-value pairs returned by the server in
-	// response to the PRELOGIN call. Debug only.
-	PreloginOptions *PreloginOptions `json:"prelogin_options,omitempty" zgrab:"debug"`
+	// PreloginOptions are the options returned by the server in response to
+	// the PRELOGIN call, decoded into VERSION, ENCRYPTION, INSTOPT, THREADID,
+	// MARS, TRACEID, FEDAUTHREQUIRED and NONCE, with any other option kept
+	// raw.
+	PreloginOptions *PreloginOptions `json:"prelogin_options,omitempty"`
+
+	// MARS is true if the server supports Multiple Active Result Sets, as
+	// per the MARS option of the PRELOGIN response.
+	MARS *bool `json:"mars,omitempty"`
+
+	// FedAuthRequired is true if the server requires federated
+	// authentication, as per the FEDAUTHREQUIRED option of the PRELOGIN
+	// response.
+	FedAuthRequired *bool `json:"fed_auth_required,omitempty"`
 
 	// EncryptMode is the mode negotiated with the server.
 	EncryptMode *EncryptMode `json:"encrypt_mode,omitempty"`
@@ -142,7 +156,8 @@ func (scanner *Scanner) GetScanMetadata() any {
 // 3. Read the PRELOGIN response from the server.
 // 4. If the server encrypt mode is EncryptModeNotSupported, break.
 // 5. Perform a TLS handshake, with the packets wrapped in TDS headers.
-// 6. Decode the Version and InstanceName from the PRELOGIN response
+// 6. Decode the Version, InstanceName, MARS and FedAuthRequired from the
+// PRELOGIN response
 func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
 	l4Dialer := dialGroup.L4Dialer
 	if l4Dialer == nil {
@@ -175,6 +190,14 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 		if version != nil {
 			result.Version = version.String()
 		}
+		if mars, err := sql.PreloginOptions.GetByteOption(PreloginMARS); err == nil {
+			temp := mars == 1
+			result.MARS = &temp
+		}
+		if fedAuth, err := sql.PreloginOptions.GetByteOption(PreloginFedAuthRequired); err == nil {
+			temp := fedAuth == 1
+			result.FedAuthRequired = &temp
+		}
 		name, ok := (*sql.PreloginOptions)[PreloginInstance]
 		if ok {
 			temp := strings.Trim(string(name), "\x00\r\n")
@@ -0,0 +1,209 @@
+// Package mssqlbrowser contains the zgrab2 Module implementation for the SQL Server Browser service, the companion
+// of the mssql module.
+//
+// The scan sends a CLNT_UCAST_EX request of the SQL Server Resolution Protocol (SSRP) to UDP port 1434, and records
+// the instances the browser lists with their versions and TCP ports, which can be scanned with the mssql module.
+// See https://learn.microsoft.com/en-us/openspecs/windows_protocols/mc-sqlr/.
+package mssqlbrowser
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+const (
+	// clntUcastEx asks the browser for all instances on the host.
+	clntUcastEx = 0x03
+
+	// svrResp is the first byte of a response, followed by the uint16 size of the data.
+	svrResp = 0x05
+)
+
+// Instance is an instance of SQL Server listed by the browser.
+type Instance struct {
+	ServerName   string `json:"server_name,omitempty"`
+	InstanceName string `json:"instance_name,omitempty"`
+	IsClustered  bool   `json:"is_clustered"`
+	Version      string `json:"version,omitempty"`
+
+	// TCPPort is the port the instance listens on, if it accepts TCP connections.
+	TCPPort uint16 `json:"tcp_port,omitempty"`
+
+	// NamedPipe is the named pipe the instance listens on, if any.
+	NamedPipe string `json:"named_pipe,omitempty"`
+}
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	Instances []Instance `json:"instances,omitempty"`
+
+	UDPProbe *zgrab2.UDPProbeResult `json:"udp_probe,omitempty"`
+}
+
+// Flags are the SQL Server Browser-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.UDPFlags
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the mssql-browser zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("mssql-browser", "SQL Server Browser (SSRP)", module.Description(), 1434, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() any {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "List the SQL Server instances of a host and their TCP ports through the SQL Server Browser"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return f.UDPFlags.Validate()
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "mssql-browser"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportUDP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// isResponse tells whether a datagram is an SVR_RESP.
+func isResponse(_, response []byte) bool {
+	return len(response) >= 3 && response[0] == svrResp
+}
+
+// parseResponse decodes the instances of an SVR_RESP, whose data is a list of instances separated by ";;", each a
+// list of key;value pairs separated by ";".
+func parseResponse(response []byte) ([]Instance, error) {
+	if !isResponse(nil, response) {
+		return nil, errors.New("not an SVR_RESP")
+	}
+	size := int(binary.LittleEndian.Uint16(response[1:3]))
+	data := response[3:]
+	if len(data) < size {
+		return nil, fmt.Errorf("response data truncated to %d of %d bytes", len(data), size)
+	}
+	var ret []Instance
+	for _, record := range strings.Split(string(data[:size]), ";;") {
+		fields := strings.Split(record, ";")
+		if len(fields) < 2 {
+			continue
+		}
+		var instance Instance
+		for i := 0; i+1 < len(fields); i += 2 {
+			value := fields[i+1]
+			switch strings.ToLower(fields[i]) {
+			case "servername":
+				instance.ServerName = value
+			case "instancename":
+				instance.InstanceName = value
+			case "isclustered":
+				instance.IsClustered = strings.EqualFold(value, "yes")
+			case "version":
+				instance.Version = value
+			case "tcp":
+				if port, err := strconv.ParseUint(value, 10, 16); err == nil {
+					instance.TCPPort = uint16(port)
+				}
+			case "np":
+				instance.NamedPipe = value
+			}
+		}
+		ret = append(ret, instance)
+	}
+	return ret, nil
+}
+
+// Scan sends a CLNT_UCAST_EX request to the SQL Server Browser and lists the instances in its response.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	results := new(ScanResults)
+	probe := zgrab2.NewStaticUDPProbe("clnt-ucast-ex", []byte{clntUcastEx}, isResponse)
+	results.UDPProbe, err = zgrab2.SendUDPProbe(ctx, conn, probe, target, &scanner.config.UDPFlags)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, err
+	}
+	results.Instances, err = parseResponse(results.UDPProbe.Response)
+	if err != nil {
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("invalid response from target %s: %w", target.String(), err)
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
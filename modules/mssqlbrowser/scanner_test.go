@@ -0,0 +1,29 @@
+package mssqlbrowser
+
+import (
+	"encoding/binary"
+	"slices"
+	"testing"
+)
+
+func TestParseResponse(t *testing.T) {
+	data := "ServerName;DB01;InstanceName;MSSQLSERVER;IsClustered;No;Version;15.0.2000.5;tcp;1433;np;\\\\DB01\\pipe\\sql\\query;;" +
+		"ServerName;DB01;InstanceName;SQLEXPRESS;IsClustered;No;Version;16.0.1000.6;tcp;49723;;"
+	response := binary.LittleEndian.AppendUint16([]byte{svrResp}, uint16(len(data)))
+	response = append(response, data...)
+
+	instances, err := parseResponse(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Instance{
+		{ServerName: "DB01", InstanceName: "MSSQLSERVER", Version: "15.0.2000.5", TCPPort: 1433, NamedPipe: `\\DB01\pipe\sql\query`},
+		{ServerName: "DB01", InstanceName: "SQLEXPRESS", Version: "16.0.1000.6", TCPPort: 49723},
+	}
+	if !slices.Equal(instances, expected) {
+		t.Errorf("instances = %+v", instances)
+	}
+	if _, err := parseResponse(response[:len(response)-10]); err == nil {
+		t.Error("truncated response parsed")
+	}
+}
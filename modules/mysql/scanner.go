@@ -58,7 +58,15 @@ type ScanResults struct {
 
 	// AuthPluginName is the name of the authentication plugin, returned
 	// in the initial HandshakePacket.
-	AuthPluginName string `json:"auth_plugin_name,omitempty" zgrab:"debug"`
+	AuthPluginName string `json:"auth_plugin_name,omitempty"`
+
+	// AuthPluginSalt describes the format of AuthPluginData, the salt of
+	// the authentication plugin.
+	AuthPluginSalt *SaltFormat `json:"auth_plugin_salt,omitempty"`
+
+	// CachingSHA2 is the outcome of requesting the RSA public key of the
+	// caching_sha2_password plugin, if --request-public-key is used.
+	CachingSHA2 *mysql.CachingSHA2Log `json:"caching_sha2,omitempty"`
 
 	// ErrorCode is only set if there is an error returned by the server,
 	// for example if the scanner is not on the allowed hosts list.
@@ -79,6 +87,38 @@ type ScanResults struct {
 	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
 }
 
+// SaltFormat describes the salt the server sends in the HandshakePacket.
+type SaltFormat struct {
+	// Length is the length of the salt without a trailing NUL byte: 20
+	// for the scrambles of mysql_native_password and
+	// caching_sha2_password.
+	Length int `json:"length"`
+
+	// NULTerminated tells whether the salt ends with a NUL byte, as those
+	// of MySQL and MariaDB servers do.
+	NULTerminated bool `json:"nul_terminated"`
+
+	// ASCII tells whether all bytes of the salt are 7-bit, as MySQL and
+	// MariaDB generate them.
+	ASCII bool `json:"ascii"`
+}
+
+// getSaltFormat returns the format of the auth plugin data salt.
+func getSaltFormat(salt []byte) *SaltFormat {
+	ret := &SaltFormat{ASCII: true}
+	if len(salt) > 0 && salt[len(salt)-1] == 0 {
+		ret.NULTerminated = true
+		salt = salt[:len(salt)-1]
+	}
+	ret.Length = len(salt)
+	for _, b := range salt {
+		if b >= 0x80 {
+			ret.ASCII = false
+		}
+	}
+	return ret
+}
+
 // Put the error into the results.
 func (results *ScanResults) setError(err *mysql.ERRPacket) {
 	if err != nil {
@@ -111,6 +151,7 @@ func readResultsFromConnectionLog(connectionLog *mysql.ConnectionLog) *ScanResul
 			ret.AuthPluginData = make([]byte, len1+len(handshake.AuthPluginData2))
 			copy(ret.AuthPluginData[0:len1], handshake.AuthPluginData1)
 			copy(ret.AuthPluginData[len1:], handshake.AuthPluginData2)
+			ret.AuthPluginSalt = getSaltFormat(ret.AuthPluginData)
 			ret.CharacterSet = handshake.CharacterSet
 			ret.StatusFlags = mysql.GetServerStatusFlags(handshake.StatusFlags)
 			ret.CapabilityFlags = mysql.GetClientCapabilityFlags(handshake.CapabilityFlags)
@@ -140,6 +181,9 @@ func readResultsFromConnectionLog(connectionLog *mysql.ConnectionLog) *ScanResul
 type Flags struct {
 	zgrab2.BaseFlags `group:"Basic Options"`
 	zgrab2.TLSFlags  `group:"TLS Options"`
+
+	RequestPublicKey bool   `long:"request-public-key" description:"On a second connection without TLS, log in as --public-key-user with a random password using caching_sha2_password, and record whether the server asks for full authentication and the RSA public key it sends for it. Servers log the failed login"`
+	PublicKeyUser    string `long:"public-key-user" default:"zgrab2" description:"User name of --request-public-key"`
 }
 
 // Module is the implementation of the zgrab2.Module interface.
@@ -232,11 +276,35 @@ func (scanner *Scanner) GetScanMetadata() any {
 	return nil
 }
 
+// requestPublicKey requests the caching_sha2_password public key of the
+// server on a new connection. Errors are logged, as the key is optional.
+func (scanner *Scanner) requestPublicKey(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) *mysql.CachingSHA2Log {
+	conn, err := dialGroup.L4Dialer(target)(ctx, "tcp", net.JoinHostPort(target.Host(), strconv.Itoa(int(target.Port))))
+	if err != nil {
+		log.Debugf("error dialing target %s for the public key: %v", target.String(), err)
+		return nil
+	}
+	sql := mysql.NewConnection(&mysql.Config{})
+	defer sql.Disconnect()
+	if err = sql.Connect(conn); err != nil {
+		log.Debugf("error connecting to target %s for the public key: %v", target.String(), err)
+		return nil
+	}
+	ret, err := sql.RequestCachingSHA2PublicKey(scanner.config.PublicKeyUser)
+	if err != nil {
+		log.Debugf("error requesting the public key of target %s: %v", target.String(), err)
+	}
+	return ret
+}
+
 // Scan probles the target for a MySQL server.
 //  1. Connects and waits to receive the handshake packet.
 //  2. If the server supports SSL, send an SSLRequest packet, then
 //     perform the standard TLS actions.
-//  3. Process and return the results.
+//  3. Process the results.
+//  4. If --request-public-key is set, request the caching_sha2_password
+//     public key on a second connection.
+//  5. Return the results.
 func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
 	// check for necessary dialers
 	l4Dialer := dialGroup.L4Dialer
@@ -263,6 +331,12 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 			return zgrab2.SCAN_PROTOCOL_ERROR, nil, errors.New("TLS wrapper required for mysql")
 		}
 		if tlsConn, err = tlsWrapper(ctx, target, conn); err != nil {
+			if tlsConn != nil {
+				// keep the certificates of a failed handshake
+				result := readResultsFromConnectionLog(&sql.ConnectionLog)
+				result.TLSLog = tlsConn.GetLog()
+				return zgrab2.TryGetScanStatus(err), result, fmt.Errorf("error wrapping connection in TLS for target %s: %w", target.String(), err)
+			}
 			return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error wrapping connection in TLS for target %s: %w", target.String(), err)
 		}
 		// Replace sql.Connection to allow hypothetical future calls to go over the secure connection
@@ -278,6 +352,9 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 	if err != nil {
 		log.Errorf("error disconnecting from target %s: %v", target.String(), err)
 	}
+	if scanner.config.RequestPublicKey {
+		result.CachingSHA2 = scanner.requestPublicKey(ctx, dialGroup, target)
+	}
 	// If we made it this far, the scan was a success. The result will be grabbed in the defer block above.
 	return zgrab2.SCAN_SUCCESS, result, nil
 }
@@ -0,0 +1,222 @@
+// Package nats contains the zgrab2 Module implementation for the NATS messaging system.
+//
+// A NATS server sends an INFO line with a JSON description of itself as soon as a client connects. The scan parses
+// it and, with --tls, upgrades the connection to TLS if the server supports it.
+package nats
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// maxInfoLength bounds the INFO line; servers with many connect_urls send a few kilobytes.
+const maxInfoLength = 64 * 1024
+
+// Info is the server description from the INFO line.
+type Info struct {
+	ServerID      string   `json:"server_id,omitempty"`
+	ServerName    string   `json:"server_name,omitempty"`
+	Version       string   `json:"version,omitempty"`
+	Proto         int      `json:"proto"`
+	GitCommit     string   `json:"git_commit,omitempty"`
+	Go            string   `json:"go,omitempty"`
+	Host          string   `json:"host,omitempty"`
+	Port          int      `json:"port,omitempty"`
+	Headers       bool     `json:"headers,omitempty"`
+	AuthRequired  bool     `json:"auth_required,omitempty"`
+	TLSRequired   bool     `json:"tls_required,omitempty"`
+	TLSVerify     bool     `json:"tls_verify,omitempty"`
+	TLSAvailable  bool     `json:"tls_available,omitempty"`
+	MaxPayload    int64    `json:"max_payload,omitempty"`
+	JetStream     bool     `json:"jetstream,omitempty"`
+	ClientID      uint64   `json:"client_id,omitempty"`
+	ClientIP      string   `json:"client_ip,omitempty"`
+	Cluster       string   `json:"cluster,omitempty"`
+	ConnectURLs   []string `json:"connect_urls,omitempty"`
+	WSConnectURLs []string `json:"ws_connect_urls,omitempty"`
+	LameDuckMode  bool     `json:"ldm,omitempty"`
+	Domain        string   `json:"domain,omitempty"`
+	// Nonce is set when the server expects the client to sign it (NKey or JWT authentication).
+	Nonce string `json:"nonce,omitempty"`
+}
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	Info *Info `json:"info,omitempty"`
+
+	// Raw is the JSON of the INFO line, as sent.
+	Raw string `json:"raw,omitempty"`
+
+	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
+}
+
+// Flags are the NATS-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.TLSFlags
+
+	UseTLS bool `long:"tls" description:"Upgrade the connection to TLS after INFO if the server supports it"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the nats zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("nats", "NATS", module.Description(), 4222, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Read the INFO banner a NATS server sends on connect"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "nats"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		NeedSeparateL4Dialer:            true,
+		BaseFlags:                       &f.BaseFlags,
+		TLSEnabled:                      true,
+		TLSFlags:                        &f.TLSFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+var errNotNATS = errors.New("server did not send INFO")
+
+// readInfo reads and parses the INFO line.
+func readInfo(r *bufio.Reader) (string, *Info, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := r.ReadLine()
+		if err != nil {
+			return "", nil, err
+		}
+		line = append(line, chunk...)
+		if len(line) > maxInfoLength {
+			return "", nil, fmt.Errorf("INFO line exceeds %d bytes", maxInfoLength)
+		}
+		if !isPrefix {
+			break
+		}
+	}
+	op, raw, _ := strings.Cut(string(line), " ")
+	if !strings.EqualFold(op, "INFO") {
+		return "", nil, errNotNATS
+	}
+	raw = strings.TrimSpace(raw)
+	info := new(Info)
+	if err := json.Unmarshal([]byte(raw), info); err != nil {
+		return raw, nil, fmt.Errorf("invalid INFO: %w", err)
+	}
+	return raw, info, nil
+}
+
+// Scan performs the configured scan on the NATS server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.L4Dialer(target)(ctx, "tcp", net.JoinHostPort(target.Host(), strconv.Itoa(int(target.Port))))
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer func() {
+		zgrab2.CloseConnAndHandleError(conn)
+	}()
+
+	results := new(ScanResults)
+	raw, info, err := readInfo(bufio.NewReader(conn))
+	if err != nil {
+		if raw != "" || errors.Is(err, errNotNATS) {
+			results.Raw = raw
+			return zgrab2.SCAN_PROTOCOL_ERROR, results, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+		}
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error reading INFO from target %s: %w", target.String(), err)
+	}
+	results.Raw, results.Info = raw, info
+
+	if scanner.config.UseTLS && (info.TLSRequired || info.TLSAvailable) {
+		tlsConn, err := dialGroup.TLSWrapper(ctx, target, conn)
+		if tlsConn != nil {
+			results.TLSLog = tlsConn.GetLog()
+			conn = tlsConn
+		}
+		if err != nil {
+			return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("TLS handshake with target %s failed: %w", target.String(), err)
+		}
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
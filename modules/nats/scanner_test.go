@@ -0,0 +1,25 @@
+package nats
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadInfo(t *testing.T) {
+	line := `INFO {"server_id":"NABC","version":"2.10.7","proto":1,"auth_required":true,"tls_available":true,"max_payload":1048576,"connect_urls":["10.0.0.1:4222"]}` + "\r\n"
+	raw, info, err := readInfo(bufio.NewReader(strings.NewReader(line)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(raw, `{"server_id"`) || info.Version != "2.10.7" || !info.AuthRequired || !info.TLSAvailable || info.MaxPayload != 1048576 || len(info.ConnectURLs) != 1 {
+		t.Errorf("unexpected info %+v", info)
+	}
+	if _, _, err = readInfo(bufio.NewReader(strings.NewReader("+OK\r\n"))); !errors.Is(err, errNotNATS) {
+		t.Errorf("got %v", err)
+	}
+	if raw, _, err = readInfo(bufio.NewReader(strings.NewReader("INFO {broken\r\n"))); err == nil || raw != "{broken" {
+		t.Errorf("got %q, %v", raw, err)
+	}
+}
@@ -0,0 +1,176 @@
+package nbns
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+const (
+	typeNBSTAT = 0x0021
+	classIN    = 0x0001
+
+	headerLength = 12
+
+	// nameEntryLength is the length of a NODE_NAME entry: a 15-byte name, the suffix and the flags.
+	nameEntryLength = 18
+
+	macLength = 6
+)
+
+// NAME_FLAGS bits.
+const (
+	nameFlagGroup      = 0x8000
+	nameFlagDeregister = 0x1000
+	nameFlagConflict   = 0x0800
+	nameFlagActive     = 0x0400
+	nameFlagPermanent  = 0x0200
+)
+
+var errInvalidResponse = errors.New("invalid NBSTAT response")
+
+// encodeName returns the first-level encoding (RFC 1001 section 14.1) of a NetBIOS name, as a DNS label.
+func encodeName(name string, suffix byte) []byte {
+	raw := make([]byte, 16)
+	copy(raw, name)
+	if name != "*" {
+		for i := len(name); i < 15; i++ {
+			raw[i] = ' '
+		}
+	}
+	raw[15] = suffix
+	encoded := []byte{32}
+	for _, b := range raw {
+		encoded = append(encoded, 'A'+b>>4, 'A'+b&0x0F)
+	}
+	return append(encoded, 0)
+}
+
+// buildNodeStatusRequest returns a NODE STATUS REQUEST for the wildcard name.
+func buildNodeStatusRequest(id uint16) []byte {
+	msg := binary.BigEndian.AppendUint16(nil, id)
+	msg = append(msg, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0) // flags, one question
+	msg = append(msg, encodeName("*", 0)...)
+	msg = binary.BigEndian.AppendUint16(msg, typeNBSTAT)
+	return binary.BigEndian.AppendUint16(msg, classIN)
+}
+
+// matchesID returns a probe match function accepting responses with the given transaction ID.
+func matchesID(id uint16) func(_, response []byte) bool {
+	return func(_, response []byte) bool {
+		return len(response) >= headerLength && binary.BigEndian.Uint16(response) == id && response[2]&0x80 != 0
+	}
+}
+
+// Name is a name registered on the node.
+type Name struct {
+	Name   string `json:"name"`
+	Suffix uint8  `json:"suffix"`
+	Group  bool   `json:"group"`
+
+	// Service describes the name type given by the suffix, e.g. "Workstation Service" or "Domain Controllers".
+	Service string `json:"service,omitempty"`
+
+	Active        bool   `json:"active"`
+	Permanent     bool   `json:"permanent,omitempty"`
+	Conflict      bool   `json:"conflict,omitempty"`
+	Deregister    bool   `json:"deregister,omitempty"`
+	OwnerNodeType string `json:"owner_node_type,omitempty"`
+}
+
+var uniqueServices = map[uint8]string{
+	0x00: "Workstation Service",
+	0x03: "Messenger Service",
+	0x06: "RAS Server Service",
+	0x1B: "Domain Master Browser",
+	0x1D: "Master Browser",
+	0x1F: "NetDDE Service",
+	0x20: "File Server Service",
+	0x21: "RAS Client Service",
+	0xBE: "Network Monitor Agent",
+	0xBF: "Network Monitor Application",
+}
+
+var groupServices = map[uint8]string{
+	0x00: "Domain Name",
+	0x01: "Master Browser",
+	0x1C: "Domain Controllers",
+	0x1E: "Browser Service Elections",
+}
+
+var ownerNodeTypes = []string{"B", "P", "M", "H"}
+
+// nodeStatus is a decoded NODE STATUS RESPONSE.
+type nodeStatus struct {
+	names []Name
+	mac   []byte
+}
+
+// skipName skips a (possibly compressed) name starting at offset and returns the offset following it.
+func skipName(data []byte, offset int) (int, error) {
+	for offset < len(data) {
+		length := int(data[offset])
+		switch {
+		case length == 0:
+			return offset + 1, nil
+		case length&0xC0 == 0xC0:
+			return offset + 2, nil
+		default:
+			offset += 1 + length
+		}
+	}
+	return 0, errInvalidResponse
+}
+
+// parseNodeStatus decodes a NODE STATUS RESPONSE.
+func parseNodeStatus(data []byte) (*nodeStatus, error) {
+	if len(data) < headerLength || binary.BigEndian.Uint16(data[6:8]) == 0 {
+		return nil, errInvalidResponse
+	}
+	offset, err := skipName(data, headerLength)
+	if err != nil || offset+10 > len(data) {
+		return nil, errInvalidResponse
+	}
+	if binary.BigEndian.Uint16(data[offset:]) != typeNBSTAT {
+		return nil, errInvalidResponse
+	}
+	rdLength := int(binary.BigEndian.Uint16(data[offset+8:]))
+	rdata := data[offset+10:]
+	if rdLength > len(rdata) {
+		return nil, errInvalidResponse
+	}
+	rdata = rdata[:rdLength]
+	if len(rdata) < 1 {
+		return nil, errInvalidResponse
+	}
+	count := int(rdata[0])
+	entries := rdata[1:]
+	if len(entries) < count*nameEntryLength {
+		return nil, errInvalidResponse
+	}
+	status := new(nodeStatus)
+	for i := 0; i < count; i++ {
+		entry := entries[i*nameEntryLength : (i+1)*nameEntryLength]
+		flags := binary.BigEndian.Uint16(entry[16:18])
+		name := Name{
+			Name:          strings.TrimRight(string(entry[:15]), " \x00"),
+			Suffix:        entry[15],
+			Group:         flags&nameFlagGroup != 0,
+			Active:        flags&nameFlagActive != 0,
+			Permanent:     flags&nameFlagPermanent != 0,
+			Conflict:      flags&nameFlagConflict != 0,
+			Deregister:    flags&nameFlagDeregister != 0,
+			OwnerNodeType: ownerNodeTypes[flags>>13&0x03],
+		}
+		if name.Group {
+			name.Service = groupServices[name.Suffix]
+		} else {
+			name.Service = uniqueServices[name.Suffix]
+		}
+		status.names = append(status.names, name)
+	}
+	if statistics := entries[count*nameEntryLength:]; len(statistics) >= macLength {
+		status.mac = statistics[:macLength]
+	}
+	return status, nil
+}
@@ -0,0 +1,49 @@
+package nbns
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestParseNodeStatus(t *testing.T) {
+	request := buildNodeStatusRequest(0xABCD)
+	if len(request) != 50 || string(request[13:45]) != "CKAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA" {
+		t.Fatalf("unexpected request %x", request)
+	}
+
+	entry := func(name string, suffix byte, flags uint16) []byte {
+		b := []byte(name + "               ")[:15]
+		b = append(b, suffix)
+		return binary.BigEndian.AppendUint16(b, flags)
+	}
+	rdata := []byte{3}
+	rdata = append(rdata, entry("WS01", 0x00, 0x0400)...)
+	rdata = append(rdata, entry("CORP", 0x00, 0x8400)...)
+	rdata = append(rdata, entry("WS01", 0x20, 0x0400)...)
+	rdata = append(rdata, 0x00, 0x11, 0x22, 0x33, 0x44, 0x55)
+	rdata = append(rdata, make([]byte, 40)...)
+
+	response := []byte{0xAB, 0xCD, 0x84, 0x00, 0, 0, 0, 1, 0, 0, 0, 0}
+	response = append(response, encodeName("*", 0)...)
+	response = append(response, 0, 0x21, 0, 1, 0, 0, 0, 0)
+	response = binary.BigEndian.AppendUint16(response, uint16(len(rdata)))
+	response = append(response, rdata...)
+
+	if !matchesID(0xABCD)(nil, response) || matchesID(0xABCD)(nil, request) {
+		t.Fatal("response matching failed")
+	}
+	status, err := parseNodeStatus(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.names) != 3 || status.names[0].Name != "WS01" || status.names[0].Service != "Workstation Service" ||
+		!status.names[1].Group || status.names[1].Service != "Domain Name" || status.names[2].Service != "File Server Service" {
+		t.Errorf("unexpected names %+v", status.names)
+	}
+	if len(status.mac) != 6 || status.mac[5] != 0x55 {
+		t.Errorf("got MAC %x", status.mac)
+	}
+	if _, err = parseNodeStatus(response[:60]); err == nil {
+		t.Error("expected error for truncated response")
+	}
+}
@@ -0,0 +1,173 @@
+// Package nbns contains the zgrab2 Module implementation for the NetBIOS Name Service.
+//
+// The scan sends a NODE STATUS REQUEST for the wildcard name and records the names registered on the node, from
+// which the workstation and domain (or workgroup) names are derived, and the unit ID, which Windows fills with the
+// MAC address of the interface.
+package nbns
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	Names []Name `json:"names,omitempty"`
+
+	// Workstation is the unique name with the Workstation Service suffix.
+	Workstation string `json:"workstation,omitempty"`
+
+	// Domain is the group name with the Domain Name suffix, i.e. the domain or workgroup.
+	Domain string `json:"domain,omitempty"`
+
+	// MACAddress is the unit ID of the node status. Samba reports all zeroes.
+	MACAddress string `json:"mac_address,omitempty"`
+
+	FileServer       bool `json:"file_server"`
+	DomainController bool `json:"domain_controller"`
+	MasterBrowser    bool `json:"master_browser"`
+
+	UDPProbe *zgrab2.UDPProbeResult `json:"udp_probe,omitempty"`
+}
+
+// Flags are the NBNS-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.UDPFlags
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the nbns zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("nbns", "NetBIOS Name Service (NBNS)", module.Description(), 137, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Send an NBNS node status request and record the registered names and MAC address"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return f.UDPFlags.Validate()
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "nbns"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportUDP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// Scan performs the configured scan on the NetBIOS name service.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	id := make([]byte, 2)
+	_, _ = rand.Read(id)
+	transactionID := binary.BigEndian.Uint16(id)
+	results := new(ScanResults)
+	probe := zgrab2.NewStaticUDPProbe("node-status", buildNodeStatusRequest(transactionID), matchesID(transactionID))
+	results.UDPProbe, err = zgrab2.SendUDPProbe(ctx, conn, probe, target, &scanner.config.UDPFlags)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, err
+	}
+	status, err := parseNodeStatus(results.UDPProbe.Response)
+	if err != nil {
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("invalid response from target %s: %w", target.String(), err)
+	}
+
+	results.Names = status.names
+	for _, name := range status.names {
+		switch {
+		case name.Suffix == 0x00 && !name.Group && results.Workstation == "":
+			results.Workstation = name.Name
+		case name.Suffix == 0x00 && name.Group && results.Domain == "":
+			results.Domain = name.Name
+		case name.Suffix == 0x20 && !name.Group:
+			results.FileServer = true
+		case name.Suffix == 0x1C && name.Group:
+			results.DomainController = true
+		case name.Suffix == 0x1D && !name.Group, name.Suffix == 0x1B && !name.Group:
+			results.MasterBrowser = true
+		}
+	}
+	if status.mac != nil {
+		results.MACAddress = net.HardwareAddr(status.mac).String()
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
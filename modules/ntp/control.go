@@ -0,0 +1,302 @@
+package ntp
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ControlOpcode is the 5-bit operation code of a mode-6 control message, defined in appendix B of
+// https://tools.ietf.org/html/rfc1305 and in https://tools.ietf.org/html/rfc9327
+type ControlOpcode uint8
+
+const (
+	// ControlReadStatus requests the status of the system or of an association
+	ControlReadStatus ControlOpcode = 1
+
+	// ControlReadVariables requests the variables of the system (association ID 0) or of an association
+	ControlReadVariables ControlOpcode = 2
+)
+
+// controlHeaderSize is the size of the fixed part of a mode-6 message
+const controlHeaderSize = 12
+
+// ErrControlResponse is returned if a mode-6 response has the error bit set
+var ErrControlResponse = errors.New("control response has error bit set")
+
+// ControlPacketHeader represents the header of a mode-6 control message
+type ControlPacketHeader struct {
+	LeapIndicator LeapIndicator   `json:"leap_indicator"`
+	Version       uint8           `json:"version"`
+	Mode          AssociationMode `json:"mode"`
+	IsResponse    bool            `json:"is_response"`
+	IsError       bool            `json:"is_error"`
+	HasMore       bool            `json:"has_more"`
+	Opcode        ControlOpcode   `json:"opcode"`
+	Sequence      uint16          `json:"sequence"`
+	Status        uint16          `json:"status"`
+	AssociationID uint16          `json:"association_id"`
+	Offset        uint16          `json:"offset"`
+	Count         uint16          `json:"count"`
+}
+
+// Encode encodes the header as the first 12 bytes of a mode-6 message
+func (header *ControlPacketHeader) Encode() ([]byte, error) {
+	if header.Version>>3 != 0 || header.Opcode>>5 != 0 || header.LeapIndicator>>2 != 0 {
+		return nil, ErrInvalidHeader
+	}
+	ret := make([]byte, controlHeaderSize)
+	ret[0] = uint8(header.LeapIndicator)<<6 | header.Version<<3 | uint8(Control)
+	ret[1] = uint8(header.Opcode)
+	if header.IsResponse {
+		ret[1] |= 0x80
+	}
+	if header.IsError {
+		ret[1] |= 0x40
+	}
+	if header.HasMore {
+		ret[1] |= 0x20
+	}
+	binary.BigEndian.PutUint16(ret[2:4], header.Sequence)
+	binary.BigEndian.PutUint16(ret[4:6], header.Status)
+	binary.BigEndian.PutUint16(ret[6:8], header.AssociationID)
+	binary.BigEndian.PutUint16(ret[8:10], header.Offset)
+	binary.BigEndian.PutUint16(ret[10:12], header.Count)
+	return ret, nil
+}
+
+// decodeControlPacketHeader decodes a mode-6 header from the first 12 bytes of buf
+func decodeControlPacketHeader(buf []byte) (*ControlPacketHeader, error) {
+	if len(buf) < controlHeaderSize {
+		return nil, ErrInvalidHeader
+	}
+	return &ControlPacketHeader{
+		LeapIndicator: LeapIndicator(buf[0] >> 6),
+		Version:       buf[0] >> 3 & 0x07,
+		Mode:          AssociationMode(buf[0] & 0x07),
+		IsResponse:    buf[1]&0x80 != 0,
+		IsError:       buf[1]&0x40 != 0,
+		HasMore:       buf[1]&0x20 != 0,
+		Opcode:        ControlOpcode(buf[1] & 0x1f),
+		Sequence:      binary.BigEndian.Uint16(buf[2:4]),
+		Status:        binary.BigEndian.Uint16(buf[4:6]),
+		AssociationID: binary.BigEndian.Uint16(buf[6:8]),
+		Offset:        binary.BigEndian.Uint16(buf[8:10]),
+		Count:         binary.BigEndian.Uint16(buf[10:12]),
+	}, nil
+}
+
+// Variable is a single name=value pair from a READVAR response
+type Variable struct {
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
+}
+
+// ReadVarLog holds the system variables returned by a mode-6 READVAR request
+type ReadVarLog struct {
+	// Header is the header of the first response packet. Debug only.
+	Header *ControlPacketHeader `json:"header,omitempty" zgrab:"debug"`
+
+	// Variables are all variables returned by the server, in order.
+	Variables []Variable `json:"variables,omitempty"`
+
+	// Version is the value of the version variable, e.g. "ntpd 4.2.8p15@1.3728-o".
+	Version string `json:"version,omitempty"`
+
+	// Processor is the value of the processor variable.
+	Processor string `json:"processor,omitempty"`
+
+	// System is the value of the system variable, e.g. "Linux/5.10.0".
+	System string `json:"system,omitempty"`
+
+	// RefID is the value of the refid variable.
+	RefID string `json:"refid,omitempty"`
+
+	// Stratum is the value of the stratum variable.
+	Stratum *uint8 `json:"stratum,omitempty"`
+
+	// Amplification records the size of the request and of all responses.
+	Amplification *AmplificationLog `json:"amplification,omitempty"`
+}
+
+// AmplificationLog compares the size of a request with the size of the responses it caused
+type AmplificationLog struct {
+	// RequestBytes is the size of the request datagram.
+	RequestBytes int `json:"request_bytes"`
+
+	// ResponseBytes is the total size of all response datagrams read.
+	ResponseBytes int `json:"response_bytes"`
+
+	// ResponsePackets is the number of response datagrams read.
+	ResponsePackets int `json:"response_packets"`
+
+	// Ratio is ResponseBytes / RequestBytes.
+	Ratio float64 `json:"ratio"`
+
+	// Truncated is set if the server had more responses to send when --max-response-packets was reached.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// newAmplificationLog returns the amplification of the exchange recorded by conn.
+func newAmplificationLog(conn *countingConn, truncated bool) *AmplificationLog {
+	ret := &AmplificationLog{
+		RequestBytes:    conn.written,
+		ResponseBytes:   conn.read,
+		ResponsePackets: conn.packets,
+		Truncated:       truncated,
+	}
+	if ret.RequestBytes > 0 {
+		ret.Ratio = float64(ret.ResponseBytes) / float64(ret.RequestBytes)
+	}
+	return ret
+}
+
+// countingConn counts the bytes written to and the datagrams and bytes read from a connection
+type countingConn struct {
+	net.Conn
+	written int
+	read    int
+	packets int
+}
+
+func (conn *countingConn) Write(b []byte) (int, error) {
+	n, err := conn.Conn.Write(b)
+	conn.written += n
+	return n, err
+}
+
+func (conn *countingConn) Read(b []byte) (int, error) {
+	n, err := conn.Conn.Read(b)
+	if n > 0 {
+		conn.read += n
+		conn.packets++
+	}
+	return n, err
+}
+
+// parseVariables splits the data of a READVAR response into its comma-separated name=value pairs, stripping the
+// quotes around string values.
+func parseVariables(data string) []Variable {
+	var ret []Variable
+	var fields []string
+	start, quoted := 0, false
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				fields = append(fields, data[start:i])
+				start = i + 1
+			}
+		}
+	}
+	fields = append(fields, data[start:])
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(field, "=")
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = value[1 : len(value)-1]
+		}
+		ret = append(ret, Variable{Name: strings.TrimSpace(name), Value: value})
+	}
+	return ret
+}
+
+// setVariables fills in the variables of the log and its convenience fields.
+func (readVar *ReadVarLog) setVariables(variables []Variable) {
+	readVar.Variables = variables
+	for _, v := range variables {
+		switch v.Name {
+		case "version":
+			readVar.Version = v.Value
+		case "processor":
+			readVar.Processor = v.Value
+		case "system":
+			readVar.System = v.Value
+		case "refid":
+			readVar.RefID = v.Value
+		case "stratum":
+			if stratum, err := strconv.ParseUint(v.Value, 10, 8); err == nil {
+				s := uint8(stratum)
+				readVar.Stratum = &s
+			}
+		}
+	}
+}
+
+// ReadVar sends a READVAR request for the system variables (association ID 0) and reassembles the fragments of the
+// response, reading at most maxPackets datagrams.
+func (scanner *Scanner) ReadVar(sock net.Conn, maxPackets int) (*ReadVarLog, error) {
+	conn := &countingConn{Conn: sock}
+	ret := &ReadVarLog{}
+	const sequence = 1
+	request, err := (&ControlPacketHeader{
+		Version:  scanner.config.Version,
+		Opcode:   ControlReadVariables,
+		Sequence: sequence,
+	}).Encode()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(request); err != nil {
+		return nil, err
+	}
+
+	// Fragments may arrive in any order: the response is complete once the last fragment (the one without the more
+	// bit) has been seen and all data before it has been received.
+	var data []byte
+	received, total := 0, -1
+	hasMore := func() bool { return total < 0 || received < total }
+	buf := make([]byte, 2048)
+	for i := 0; i < maxPackets && hasMore(); i++ {
+		n, err := conn.Read(buf)
+		if err != nil {
+			if ret.Header == nil {
+				return nil, err
+			}
+			ret.Amplification = newAmplificationLog(conn, false)
+			ret.setVariables(parseVariables(string(data)))
+			return ret, err
+		}
+		header, err := decodeControlPacketHeader(buf[:n])
+		if err != nil || header.Mode != Control || !header.IsResponse || header.Sequence != sequence || header.Opcode != ControlReadVariables {
+			log.Debugf("Ignoring unexpected packet in READVAR response (%d bytes)", n)
+			continue
+		}
+		if ret.Header == nil {
+			ret.Header = header
+		}
+		if header.IsError {
+			ret.Amplification = newAmplificationLog(conn, false)
+			return ret, ErrControlResponse
+		}
+		end := int(header.Offset) + int(header.Count)
+		if controlHeaderSize+int(header.Count) > n {
+			ret.Amplification = newAmplificationLog(conn, false)
+			return ret, ErrInvalidResponse
+		}
+		if end > len(data) {
+			data = append(data, make([]byte, end-len(data))...)
+		}
+		copy(data[header.Offset:end], buf[controlHeaderSize:controlHeaderSize+int(header.Count)])
+		received += int(header.Count)
+		if !header.HasMore {
+			total = end
+		}
+	}
+	if ret.Header == nil {
+		return nil, ErrInvalidResponse
+	}
+	ret.Amplification = newAmplificationLog(conn, hasMore())
+	ret.setVariables(parseVariables(string(data)))
+	return ret, nil
+}
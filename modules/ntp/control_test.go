@@ -0,0 +1,106 @@
+package ntp
+
+import (
+	"net"
+	"slices"
+	"testing"
+)
+
+// controlResponse returns a READVAR response fragment carrying data at offset.
+func controlResponse(t *testing.T, sequence uint16, offset int, data string, more bool) []byte {
+	header, err := (&ControlPacketHeader{
+		Version:    3,
+		IsResponse: true,
+		HasMore:    more,
+		Opcode:     ControlReadVariables,
+		Sequence:   sequence,
+		Offset:     uint16(offset),
+		Count:      uint16(len(data)),
+	}).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(header, data...)
+}
+
+func TestParseVariables(t *testing.T) {
+	variables := parseVariables("version=\"ntpd 4.2.8p15@1.3728-o, built\", processor=\"x86_64\",\r\nstratum=2, refid=192.0.2.1, flag")
+	expected := []Variable{
+		{Name: "version", Value: "ntpd 4.2.8p15@1.3728-o, built"},
+		{Name: "processor", Value: "x86_64"},
+		{Name: "stratum", Value: "2"},
+		{Name: "refid", Value: "192.0.2.1"},
+		{Name: "flag"},
+	}
+	if !slices.Equal(variables, expected) {
+		t.Errorf("variables = %+v", variables)
+	}
+}
+
+func TestReadVar(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	first := "version=\"ntpd 4.2.8p15\", system=\"Linux/5.10\", "
+	second := "stratum=3, refid=PPS"
+	var requestLen int
+	go func() {
+		defer server.Close()
+		buf := make([]byte, 64)
+		n, err := server.Read(buf)
+		if err != nil {
+			return
+		}
+		requestLen = n
+		request, err := decodeControlPacketHeader(buf[:n])
+		if err != nil || request.Opcode != ControlReadVariables || request.AssociationID != 0 {
+			return
+		}
+		// Fragments may arrive out of order.
+		server.Write(controlResponse(t, request.Sequence, len(first), second, false))
+		server.Write(controlResponse(t, request.Sequence, 0, first, true))
+	}()
+
+	scanner := &Scanner{config: &Flags{Version: 3}}
+	readVar, err := scanner.ReadVar(client, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requestLen != controlHeaderSize {
+		t.Errorf("request length = %d", requestLen)
+	}
+	if readVar.Version != "ntpd 4.2.8p15" || readVar.System != "Linux/5.10" || readVar.RefID != "PPS" {
+		t.Errorf("readvar = %+v", readVar)
+	}
+	if readVar.Stratum == nil || *readVar.Stratum != 3 {
+		t.Errorf("stratum = %v", readVar.Stratum)
+	}
+	amplification := readVar.Amplification
+	if amplification.ResponsePackets != 2 || amplification.ResponseBytes != 2*controlHeaderSize+len(first)+len(second) || amplification.Truncated {
+		t.Errorf("amplification = %+v", amplification)
+	}
+	if amplification.Ratio != float64(amplification.ResponseBytes)/controlHeaderSize {
+		t.Errorf("ratio = %f", amplification.Ratio)
+	}
+}
+
+func TestReadVarTruncated(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		buf := make([]byte, 64)
+		if _, err := server.Read(buf); err != nil {
+			return
+		}
+		server.Write(controlResponse(t, 1, 0, "version=\"x\", ", true))
+	}()
+
+	scanner := &Scanner{config: &Flags{Version: 3}}
+	readVar, err := scanner.ReadVar(client, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !readVar.Amplification.Truncated || readVar.Version != "x" {
+		t.Errorf("readvar = %+v, amplification = %+v", readVar, readVar.Amplification)
+	}
+}
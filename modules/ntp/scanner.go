@@ -5,6 +5,13 @@
 //
 // Passing the monlist flag will check for the DDoS-amplifying MONLIST command.
 //
+// Passing the readvar flag will send a mode 6 READVAR request for the system
+// variables (version, system, refid, stratum, ...).
+//
+// For both, the sizes of the request and of the responses are recorded to
+// measure the amplification the server offers. At most --max-response-packets
+// responses are read to each request.
+//
 // The results of the scan are the version number and the time returned by the
 // server, and if verbose results are enabled, the entire parsed response
 // packet(s).
@@ -771,6 +778,15 @@ type Results struct {
 	// MonListHeader is the header returned by the call to monlist.
 	// Only present if --monlist is set. Debug only.
 	MonListHeader *PrivatePacketHeader `json:"monlist_header,omitempty" zgrab:"debug"`
+
+	// MonListAmplification compares the size of the monlist request with
+	// the size of its responses.
+	// Only present if --monlist is set.
+	MonListAmplification *AmplificationLog `json:"monlist_amplification,omitempty"`
+
+	// ReadVar holds the system variables returned by a mode 6 READVAR request.
+	// Only present if --readvar is set.
+	ReadVar *ReadVarLog `json:"readvar,omitempty"`
 }
 
 // Flags holds the command-line flags for the scanner.
@@ -781,6 +797,8 @@ type Flags struct {
 	SkipGetTime      bool   `long:"skip-get-time" description:"If set, don't request the Server time"`
 	MonList          bool   `long:"monlist" description:"Perform a ReqMonGetList request"`
 	RequestCode      string `long:"request-code" description:"Specify a request code for MonList other than ReqMonGetList" default:"REQ_MON_GETLIST"`
+	ReadVar          bool   `long:"readvar" description:"Perform a mode 6 READVAR request for the system variables"`
+	MaxResponses     int    `long:"max-response-packets" description:"The maximum number of response packets to read for the monlist and readvar requests" default:"100"`
 }
 
 // Module is the zgrab2 module implementation
@@ -819,6 +837,9 @@ func (module *Module) Description() string {
 
 // Validate checks that the flags are valid
 func (cfg *Flags) Validate(_ []string) error {
+	if cfg.MaxResponses < 1 {
+		return fmt.Errorf("max-response-packets must be positive, given %d", cfg.MaxResponses)
+	}
 	return nil
 }
 
@@ -935,14 +956,27 @@ func (scanner *Scanner) SendAndReceive(impl ImplNumber, req RequestCode, body []
 	return inPacket, ret, nil
 }
 
-// MonList does a ReqMonGetList call to the Server and populates result with the output
+// MonList does a ReqMonGetList call to the Server and populates result with the output.
+// Further response packets are counted, but not decoded, for the amplification.
 func (scanner *Scanner) MonList(sock net.Conn, result *Results) (zgrab2.ScanStatus, error) {
 	ReqCode, err := getRequestCode(scanner.config.RequestCode)
 	if err != nil {
 		panic(err)
 	}
 	body := make([]byte, 40)
-	header, ret, err := scanner.SendAndReceive(ImplXNTPD, ReqCode, body, sock)
+	conn := &countingConn{Conn: sock}
+	header, ret, err := scanner.SendAndReceive(ImplXNTPD, ReqCode, body, conn)
+	hasMore := header != nil && header.HasMore && err == nil
+	buf := make([]byte, 512)
+	for hasMore && conn.packets < scanner.config.MaxResponses {
+		n, readErr := conn.Read(buf)
+		if readErr != nil {
+			break
+		}
+		more, decodeErr := decodePrivatePacketHeader(buf[:n])
+		hasMore = decodeErr == nil && more.HasMore
+	}
+	result.MonListAmplification = newAmplificationLog(conn, hasMore)
 	if ret != nil {
 		result.MonListResponse = ret
 	}
@@ -994,7 +1028,9 @@ func (scanner *Scanner) GetTime(sock net.Conn) (*NTPHeader, error) {
 // line arguments as follows:
 //  1. If SkipGetTime is not set, send a GetTime packet to the server and read
 //     the response packet into the result.
-//  2. If MonList is set, send a MONLIST packet to the server and read the
+//  2. If ReadVar is set, send a mode 6 READVAR packet to the server and read
+//     the system variables into the result.
+//  3. If MonList is set, send a MONLIST packet to the server and read the
 //     response packet into the result.
 //
 // The presence of an NTP service at the target can be inferred by a non-nil
@@ -1020,10 +1056,26 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 		result.Time = &temp
 		result.Version = &inPacket.Version
 	}
+	if scanner.config.ReadVar {
+		readVar, err := scanner.ReadVar(sock, scanner.config.MaxResponses)
+		result.ReadVar = readVar
+		if err != nil {
+			status := zgrab2.TryGetScanStatus(err)
+			if err == ErrInvalidResponse {
+				status = zgrab2.SCAN_PROTOCOL_ERROR
+			} else if err == ErrControlResponse {
+				status = zgrab2.SCAN_APPLICATION_ERROR
+			}
+			if scanner.config.SkipGetTime && readVar == nil {
+				return status, nil, err
+			}
+			return status, result, err
+		}
+	}
 	if scanner.config.MonList {
 		status, err := scanner.MonList(sock, result)
 		if err != nil {
-			if scanner.config.SkipGetTime {
+			if scanner.config.SkipGetTime && result.ReadVar == nil {
 				// TODO: Currently, returning a non-nil result means that the service was positively detected.
 				// It may be safer to add an explicit flag for this (status == success is not sufficient, since e.g. you can get a timeout after positively identifying the service)
 				// This also means that partial TLS handshakes cannot be returned
@@ -0,0 +1,273 @@
+package opcua
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+)
+
+var errInvalidMessage = errors.New("invalid OPC UA message")
+
+// maxArrayLength bounds the arrays the scan decodes.
+const maxArrayLength = 1024
+
+// unixEpoch is the Unix epoch as an OPC UA DateTime, which counts 100ns intervals since 1601.
+const unixEpoch = 116444736000000000
+
+// encoder appends values in the OPC UA binary encoding (OPC 10000-6 section 5.2).
+type encoder struct {
+	b []byte
+}
+
+func (e *encoder) uint8(v uint8) {
+	e.b = append(e.b, v)
+}
+
+func (e *encoder) uint16(v uint16) {
+	e.b = binary.LittleEndian.AppendUint16(e.b, v)
+}
+
+func (e *encoder) uint32(v uint32) {
+	e.b = binary.LittleEndian.AppendUint32(e.b, v)
+}
+
+func (e *encoder) int32(v int32) {
+	e.uint32(uint32(v))
+}
+
+func (e *encoder) float64(v float64) {
+	e.b = binary.LittleEndian.AppendUint64(e.b, math.Float64bits(v))
+}
+
+func (e *encoder) dateTime(t time.Time) {
+	e.b = binary.LittleEndian.AppendUint64(e.b, uint64(t.UnixNano()/100+unixEpoch))
+}
+
+// byteString encodes a ByteString; nil is encoded as the null ByteString.
+func (e *encoder) byteString(v []byte) {
+	if v == nil {
+		e.int32(-1)
+		return
+	}
+	e.int32(int32(len(v)))
+	e.b = append(e.b, v...)
+}
+
+// string encodes a String; the empty string is encoded as the null String.
+func (e *encoder) string(s string) {
+	if s == "" {
+		e.int32(-1)
+		return
+	}
+	e.byteString([]byte(s))
+}
+
+// nodeID encodes a numeric NodeId of namespace 0.
+func (e *encoder) nodeID(id uint32) {
+	switch {
+	case id < 256:
+		e.b = append(e.b, 0x00, byte(id))
+	case id < 1<<16:
+		e.b = append(e.b, 0x01, 0)
+		e.uint16(uint16(id))
+	default:
+		e.b = append(e.b, 0x02)
+		e.uint16(0)
+		e.uint32(id)
+	}
+}
+
+// requestHeader encodes a RequestHeader with the given authentication token, an encoded NodeId.
+func (e *encoder) requestHeader(token []byte, handle uint32, timeout time.Duration) {
+	if token == nil {
+		e.nodeID(0)
+	} else {
+		e.b = append(e.b, token...)
+	}
+	e.dateTime(time.Now())
+	e.uint32(handle)
+	e.uint32(0) // return diagnostics
+	e.string("")
+	e.uint32(uint32(timeout / time.Millisecond))
+	// an empty additional header
+	e.nodeID(0)
+	e.uint8(0)
+}
+
+// decoder reads values in the OPC UA binary encoding. The first error is kept and later reads return zero values.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.b) < n {
+		d.err = errInvalidMessage
+		return nil
+	}
+	v := d.b[:n]
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) uint8() uint8 {
+	if b := d.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) uint16() uint16 {
+	if b := d.next(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (d *decoder) uint32() uint32 {
+	if b := d.next(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *decoder) int32() int32 {
+	return int32(d.uint32())
+}
+
+func (d *decoder) uint64() uint64 {
+	if b := d.next(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *decoder) dateTime() time.Time {
+	v := int64(d.uint64())
+	if v <= 0 {
+		return time.Time{}
+	}
+	v -= unixEpoch
+	return time.Unix(v/1e7, v%1e7*100).UTC()
+}
+
+// byteString decodes a ByteString; the null ByteString is nil.
+func (d *decoder) byteString() []byte {
+	length := d.int32()
+	if length < 0 {
+		return nil
+	}
+	return d.next(int(length))
+}
+
+func (d *decoder) string() string {
+	return string(d.byteString())
+}
+
+// arrayLength decodes the length of an array, treating the null array as empty.
+func (d *decoder) arrayLength() int {
+	length := d.int32()
+	if length > maxArrayLength {
+		d.err = errInvalidMessage
+		return 0
+	}
+	if length < 0 {
+		return 0
+	}
+	return int(length)
+}
+
+func (d *decoder) stringArray() []string {
+	var values []string
+	for n := d.arrayLength(); n > 0 && d.err == nil; n-- {
+		values = append(values, d.string())
+	}
+	return values
+}
+
+// nodeID decodes a NodeId and returns its encoding, to be sent back as is, and its numeric identifier, which is 0
+// for non-numeric NodeIds.
+func (d *decoder) nodeID() ([]byte, uint32) {
+	start := d.b
+	var id uint32
+	switch d.uint8() & 0x3F {
+	case 0x00:
+		id = uint32(d.uint8())
+	case 0x01:
+		d.uint8()
+		id = uint32(d.uint16())
+	case 0x02:
+		d.uint16()
+		id = d.uint32()
+	case 0x03, 0x05:
+		d.uint16()
+		d.byteString()
+	case 0x04:
+		d.uint16()
+		d.next(16)
+	default:
+		d.err = errInvalidMessage
+	}
+	if d.err != nil {
+		return nil, 0
+	}
+	return start[:len(start)-len(d.b)], id
+}
+
+// localizedText decodes a LocalizedText and returns its text.
+func (d *decoder) localizedText() string {
+	mask := d.uint8()
+	if mask&0x01 != 0 {
+		d.string()
+	}
+	if mask&0x02 != 0 {
+		return d.string()
+	}
+	return ""
+}
+
+// skipDiagnosticInfo skips a DiagnosticInfo.
+func (d *decoder) skipDiagnosticInfo(depth int) {
+	mask := d.uint8()
+	for _, bit := range []uint8{0x01, 0x02, 0x04, 0x08} {
+		if mask&bit != 0 {
+			d.int32()
+		}
+	}
+	if mask&0x10 != 0 {
+		d.string()
+	}
+	if mask&0x20 != 0 {
+		d.uint32()
+	}
+	if mask&0x40 != 0 {
+		if depth > 4 {
+			d.err = errInvalidMessage
+			return
+		}
+		d.skipDiagnosticInfo(depth + 1)
+	}
+}
+
+// skipExtensionObject skips an ExtensionObject.
+func (d *decoder) skipExtensionObject() {
+	d.nodeID()
+	if d.uint8()&0x03 != 0 {
+		d.byteString()
+	}
+}
+
+// responseHeader decodes a ResponseHeader and returns its service result.
+func (d *decoder) responseHeader() uint32 {
+	d.dateTime()
+	d.uint32() // request handle
+	result := d.uint32()
+	d.skipDiagnosticInfo(0)
+	d.stringArray()
+	d.skipExtensionObject()
+	return result
+}
@@ -0,0 +1,325 @@
+package opcua
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Binary encoding IDs of the structures the scan sends and receives.
+const (
+	idAnonymousIdentityToken    = 321
+	idServiceFault              = 397
+	idGetEndpointsRequest       = 428
+	idGetEndpointsResponse      = 431
+	idOpenSecureChannelRequest  = 446
+	idOpenSecureChannelResponse = 449
+	idCloseSecureChannelRequest = 452
+	idCreateSessionRequest      = 461
+	idCreateSessionResponse     = 464
+	idActivateSessionRequest    = 467
+	idActivateSessionResponse   = 470
+	idCloseSessionRequest       = 473
+	idReadRequest               = 631
+	idReadResponse              = 634
+)
+
+// securityPolicyNone is the URI of the security policy without signing or encryption.
+const securityPolicyNone = "http://opcfoundation.org/UA/SecurityPolicy#None"
+
+// securityModeNone is the MessageSecurityMode without signing or encryption.
+const securityModeNone = 1
+
+// bufferSize is the buffer size and maximum chunk size the scan announces.
+const bufferSize = 1 << 16
+
+// maxMessageSize bounds the size of a message, over all its chunks.
+const maxMessageSize = 1 << 24
+
+// securityModeNames names the MessageSecurityMode values.
+var securityModeNames = map[uint32]string{
+	0: "invalid",
+	1: "none",
+	2: "sign",
+	3: "sign_and_encrypt",
+}
+
+// userTokenTypeNames names the UserTokenType values.
+var userTokenTypeNames = map[uint32]string{
+	0: "anonymous",
+	1: "username",
+	2: "certificate",
+	3: "issued_token",
+}
+
+// applicationTypeNames names the ApplicationType values.
+var applicationTypeNames = map[uint32]string{
+	0: "server",
+	1: "client",
+	2: "client_and_server",
+	3: "discovery_server",
+}
+
+// statusNames names common StatusCodes.
+var statusNames = map[uint32]string{
+	0x00000000: "Good",
+	0x80010000: "BadUnexpectedError",
+	0x80020000: "BadInternalError",
+	0x80060000: "BadEncodingError",
+	0x80070000: "BadDecodingError",
+	0x800B0000: "BadServiceUnsupported",
+	0x80130000: "BadSecurityChecksFailed",
+	0x801F0000: "BadUserAccessDenied",
+	0x80200000: "BadIdentityTokenInvalid",
+	0x80210000: "BadIdentityTokenRejected",
+	0x80240000: "BadNonceInvalid",
+	0x80250000: "BadSessionIdInvalid",
+	0x803D0000: "BadNotSupported",
+	0x80540000: "BadSecurityModeRejected",
+	0x80550000: "BadSecurityPolicyRejected",
+	0x80560000: "BadTooManySessions",
+	0x807D0000: "BadTcpServerTooBusy",
+	0x807E0000: "BadTcpMessageTypeInvalid",
+	0x807F0000: "BadTcpSecureChannelUnknown",
+	0x80800000: "BadTcpMessageTooLarge",
+	0x80810000: "BadTcpNotEnoughResources",
+	0x80820000: "BadTcpInternalError",
+	0x80830000: "BadTcpEndpointUrlInvalid",
+}
+
+// statusName names a StatusCode, or formats it in hex.
+func statusName(code uint32) string {
+	if name, ok := statusNames[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%08X", code)
+}
+
+// StatusError is an ERR message, an aborted message, or a ServiceFault.
+type StatusError struct {
+	Code   uint32 `json:"code"`
+	Name   string `json:"name"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func newStatusError(code uint32, reason string) *StatusError {
+	return &StatusError{Code: code, Name: statusName(code), Reason: reason}
+}
+
+func (err *StatusError) Error() string {
+	if err.Reason != "" {
+		return fmt.Sprintf("server returned %s: %s", err.Name, err.Reason)
+	}
+	return "server returned " + err.Name
+}
+
+// frame returns a message of the given type, a single final chunk.
+func frame(messageType string, body []byte) []byte {
+	b := append([]byte(messageType), 'F')
+	b = binary.LittleEndian.AppendUint32(b, uint32(8+len(body)))
+	return append(b, body...)
+}
+
+// readChunk reads a chunk and returns its message type, chunk type and content after the header.
+func readChunk(r io.Reader) (string, byte, []byte, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", 0, nil, err
+	}
+	size := binary.LittleEndian.Uint32(header[4:])
+	if size < 8 || size > bufferSize*4 {
+		return "", 0, nil, fmt.Errorf("%w: chunk of %d bytes", errInvalidMessage, size)
+	}
+	body := make([]byte, size-8)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return "", 0, nil, err
+	}
+	return string(header[:3]), header[3], body, nil
+}
+
+// Acknowledge holds the limits of an ACK message.
+type Acknowledge struct {
+	ProtocolVersion   uint32 `json:"protocol_version"`
+	ReceiveBufferSize uint32 `json:"receive_buffer_size"`
+	SendBufferSize    uint32 `json:"send_buffer_size"`
+	MaxMessageSize    uint32 `json:"max_message_size"`
+	MaxChunkCount     uint32 `json:"max_chunk_count"`
+}
+
+// channel is a secure channel with security policy None.
+type channel struct {
+	conn      net.Conn
+	timeout   time.Duration
+	channelID uint32
+	tokenID   uint32
+	sequence  uint32
+	requestID uint32
+}
+
+// hello exchanges HEL and ACK for the endpoint URL.
+func (c *channel) hello(endpointURL string) (*Acknowledge, error) {
+	e := new(encoder)
+	e.uint32(0) // protocol version
+	e.uint32(bufferSize)
+	e.uint32(bufferSize)
+	e.uint32(0) // no limit on the message size
+	e.uint32(0) // or on the chunk count
+	e.string(endpointURL)
+	if _, err := c.conn.Write(frame("HEL", e.b)); err != nil {
+		return nil, err
+	}
+	messageType, _, body, err := readChunk(c.conn)
+	if err != nil {
+		return nil, err
+	}
+	d := &decoder{b: body}
+	switch messageType {
+	case "ACK":
+		ack := &Acknowledge{
+			ProtocolVersion:   d.uint32(),
+			ReceiveBufferSize: d.uint32(),
+			SendBufferSize:    d.uint32(),
+			MaxMessageSize:    d.uint32(),
+			MaxChunkCount:     d.uint32(),
+		}
+		return ack, d.err
+	case "ERR":
+		code := d.uint32()
+		return nil, newStatusError(code, d.string())
+	}
+	return nil, fmt.Errorf("%w: expected ACK, got %q", errInvalidMessage, messageType)
+}
+
+// sequenceHeader appends the sequence header of the next chunk.
+func (c *channel) sequenceHeader(e *encoder) {
+	c.sequence++
+	c.requestID++
+	e.uint32(c.sequence)
+	e.uint32(c.requestID)
+}
+
+// open opens the secure channel with an OPN request.
+func (c *channel) open() error {
+	e := new(encoder)
+	e.uint32(0) // secure channel ID
+	e.string(securityPolicyNone)
+	e.byteString(nil) // sender certificate
+	e.byteString(nil) // receiver certificate thumbprint
+	c.sequenceHeader(e)
+	e.nodeID(idOpenSecureChannelRequest)
+	e.requestHeader(nil, c.requestID, c.timeout)
+	e.uint32(0) // client protocol version
+	e.uint32(0) // issue
+	e.uint32(securityModeNone)
+	e.byteString([]byte{})
+	e.uint32(uint32(time.Hour / time.Millisecond))
+	if _, err := c.conn.Write(frame("OPN", e.b)); err != nil {
+		return err
+	}
+
+	typeID, d, err := c.readResponse("OPN")
+	if err != nil {
+		return err
+	}
+	if typeID != idOpenSecureChannelResponse {
+		return fmt.Errorf("%w: unexpected response type %d to OpenSecureChannel", errInvalidMessage, typeID)
+	}
+	if result := d.responseHeader(); result != 0 {
+		return newStatusError(result, "")
+	}
+	d.uint32() // server protocol version
+	c.channelID = d.uint32()
+	c.tokenID = d.uint32()
+	return d.err
+}
+
+// call sends a service request with the given encoding ID and body, and returns the encoding ID of the response
+// and a decoder of the response after its ResponseHeader. A ServiceFault, or a bad service result, is returned as
+// a *StatusError.
+func (c *channel) call(typeID uint32, body func(e *encoder)) (uint32, *decoder, error) {
+	e := new(encoder)
+	e.uint32(c.channelID)
+	e.uint32(c.tokenID)
+	c.sequenceHeader(e)
+	e.nodeID(typeID)
+	body(e)
+	if _, err := c.conn.Write(frame("MSG", e.b)); err != nil {
+		return 0, nil, err
+	}
+	responseID, d, err := c.readResponse("MSG")
+	if err != nil {
+		return 0, nil, err
+	}
+	result := d.responseHeader()
+	if d.err != nil {
+		return 0, nil, d.err
+	}
+	if result != 0 || responseID == idServiceFault {
+		return responseID, nil, newStatusError(result, "")
+	}
+	return responseID, d, nil
+}
+
+// readResponse reads the chunks of an OPN or MSG response, and returns the encoding ID of its body and a decoder
+// of the rest.
+func (c *channel) readResponse(expected string) (uint32, *decoder, error) {
+	var body []byte
+	for {
+		messageType, chunkType, chunk, err := readChunk(c.conn)
+		if err != nil {
+			return 0, nil, err
+		}
+		d := &decoder{b: chunk}
+		if messageType == "ERR" {
+			code := d.uint32()
+			return 0, nil, newStatusError(code, d.string())
+		}
+		if messageType != expected {
+			return 0, nil, fmt.Errorf("%w: expected %s, got %q", errInvalidMessage, expected, messageType)
+		}
+		d.uint32() // secure channel ID
+		if messageType == "OPN" {
+			d.string()
+			d.byteString()
+			d.byteString()
+		} else {
+			d.uint32() // token ID
+		}
+		d.uint32() // sequence number
+		d.uint32() // request ID
+		if d.err != nil {
+			return 0, nil, d.err
+		}
+		switch chunkType {
+		case 'A':
+			code := d.uint32()
+			return 0, nil, newStatusError(code, d.string())
+		case 'C', 'F':
+			if len(body)+len(d.b) > maxMessageSize {
+				return 0, nil, fmt.Errorf("%w: message longer than %d bytes", errInvalidMessage, maxMessageSize)
+			}
+			body = append(body, d.b...)
+		default:
+			return 0, nil, fmt.Errorf("%w: chunk type %q", errInvalidMessage, chunkType)
+		}
+		if chunkType == 'F' {
+			break
+		}
+	}
+	d := &decoder{b: body}
+	_, typeID := d.nodeID()
+	return typeID, d, d.err
+}
+
+// close sends CloseSecureChannel; the server closes the connection without answering.
+func (c *channel) close() {
+	e := new(encoder)
+	e.uint32(c.channelID)
+	e.uint32(c.tokenID)
+	c.sequenceHeader(e)
+	e.nodeID(idCloseSecureChannelRequest)
+	e.requestHeader(nil, c.requestID, c.timeout)
+	_, _ = c.conn.Write(frame("CLO", e.b))
+}
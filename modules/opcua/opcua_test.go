@@ -0,0 +1,190 @@
+package opcua
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// responseHeader appends a ResponseHeader with the given service result.
+func responseHeader(e *encoder, result uint32) {
+	e.dateTime(time.Now())
+	e.uint32(1)
+	e.uint32(result)
+	e.uint8(0)  // no diagnostics
+	e.int32(-1) // string table
+	e.nodeID(0)
+	e.uint8(0)
+}
+
+// symmetric wraps a service response in a MSG chunk.
+func symmetric(typeID uint32, body func(e *encoder)) []byte {
+	e := new(encoder)
+	e.uint32(5) // channel ID
+	e.uint32(6) // token ID
+	e.uint32(1)
+	e.uint32(1)
+	e.nodeID(typeID)
+	body(e)
+	return frame("MSG", e.b)
+}
+
+func testEndpoint(e *encoder, mode uint32, policy string) {
+	e.string("opc.tcp://plc:4840")
+	e.string("urn:plc:server")
+	e.string("urn:vendor:product")
+	e.uint8(0x02)
+	e.string("PLC Server")
+	e.uint32(0)
+	e.string("")
+	e.string("")
+	e.int32(1)
+	e.string("opc.tcp://plc:4840")
+	e.byteString(nil)
+	e.uint32(mode)
+	e.string(policy)
+	e.int32(1)
+	e.string("anon")
+	e.uint32(0)
+	e.string("")
+	e.string("")
+	e.string("")
+	e.string("http://opcfoundation.org/UA-Profile/Transport/uatcp-uasc-uabinary")
+	e.uint8(0)
+}
+
+// server answers the scan's requests in order.
+func server(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	opn := new(encoder)
+	opn.uint32(5)
+	opn.string(securityPolicyNone)
+	opn.byteString(nil)
+	opn.byteString(nil)
+	opn.uint32(1)
+	opn.uint32(1)
+	opn.nodeID(idOpenSecureChannelResponse)
+	responseHeader(opn, 0)
+	opn.uint32(0)
+	opn.uint32(5)
+	opn.uint32(6)
+	opn.dateTime(time.Now())
+	opn.uint32(3600000)
+	opn.byteString(nil)
+
+	ack := new(encoder)
+	for _, v := range []uint32{0, 65536, 65536, 0, 0} {
+		ack.uint32(v)
+	}
+	buildDate := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	replies := [][]byte{
+		frame("ACK", ack.b),
+		frame("OPN", opn.b),
+		symmetric(idGetEndpointsResponse, func(e *encoder) {
+			responseHeader(e, 0)
+			e.int32(2)
+			testEndpoint(e, 3, "http://opcfoundation.org/UA/SecurityPolicy#Basic256Sha256")
+			testEndpoint(e, securityModeNone, securityPolicyNone)
+		}),
+		symmetric(idCreateSessionResponse, func(e *encoder) {
+			responseHeader(e, 0)
+			e.nodeID(1000)
+			e.b = append(e.b, 0x05, 0x01, 0x00, 0x02, 0x00, 0x00, 0x00, 0xAB, 0xCD)
+		}),
+		symmetric(idActivateSessionResponse, func(e *encoder) {
+			responseHeader(e, 0)
+		}),
+		symmetric(idReadResponse, func(e *encoder) {
+			responseHeader(e, 0)
+			e.int32(6)
+			for _, s := range []string{"urn:vendor:product", "Vendor", "PLC", "1.2.3", "42"} {
+				e.uint8(0x01)
+				e.uint8(variantString)
+				e.string(s)
+			}
+			e.uint8(0x01)
+			e.uint8(variantDateTime)
+			e.dateTime(buildDate)
+		}),
+	}
+	for _, reply := range replies {
+		if _, _, _, err := readChunk(conn); err != nil {
+			t.Errorf("server: %v", err)
+			return
+		}
+		if _, err := conn.Write(reply); err != nil {
+			return
+		}
+	}
+	// the scan closes the session and the channel
+	_, _, _, _ = readChunk(conn)
+	_, _, _, _ = readChunk(conn)
+}
+
+func TestChannel(t *testing.T) {
+	client, conn := net.Pipe()
+	defer client.Close()
+	go server(t, conn)
+
+	c := &channel{conn: client, timeout: time.Second}
+	ack, err := c.hello("opc.tcp://plc:4840")
+	if err != nil || ack.ReceiveBufferSize != 65536 {
+		t.Fatalf("got %+v, error %v", ack, err)
+	}
+	if err = c.open(); err != nil {
+		t.Fatal(err)
+	}
+	if c.channelID != 5 || c.tokenID != 6 {
+		t.Errorf("got channel %d token %d", c.channelID, c.tokenID)
+	}
+	endpoints, err := c.getEndpoints("opc.tcp://plc:4840")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(endpoints) != 2 || endpoints[0].SecurityMode != "sign_and_encrypt" || endpoints[1].server.Name != "PLC Server" {
+		t.Fatalf("got endpoints %+v", endpoints)
+	}
+	policyID, ok := anonymousPolicy(endpoints)
+	if !ok || policyID != "anon" {
+		t.Fatalf("got anonymous policy %q %v", policyID, ok)
+	}
+
+	scanner := new(Scanner)
+	results := new(ScanResults)
+	if err = scanner.readBuildInfo(c, "opc.tcp://plc:4840", policyID, results); err != nil {
+		t.Fatal(err)
+	}
+	c.close()
+	buildDate := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	expected := &BuildInfo{
+		ProductURI:       "urn:vendor:product",
+		ManufacturerName: "Vendor",
+		ProductName:      "PLC",
+		SoftwareVersion:  "1.2.3",
+		BuildNumber:      "42",
+		BuildDate:        &buildDate,
+	}
+	if !results.AnonymousAccess || !reflect.DeepEqual(results.BuildInfo, expected) {
+		t.Errorf("got %+v", results.BuildInfo)
+	}
+}
+
+func TestHelloError(t *testing.T) {
+	client, conn := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer conn.Close()
+		_, _, _, _ = readChunk(conn)
+		e := new(encoder)
+		e.uint32(0x80830000)
+		e.string("unknown endpoint")
+		_, _ = conn.Write(frame("ERR", e.b))
+	}()
+	c := &channel{conn: client}
+	_, err := c.hello("opc.tcp://plc:4840/wrong")
+	statusErr, ok := err.(*StatusError)
+	if !ok || statusErr.Name != "BadTcpEndpointUrlInvalid" || statusErr.Reason != "unknown endpoint" {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+// Package opcua contains the zgrab2 Module implementation for OPC UA over TCP (opc.tcp).
+//
+// The scan sends a Hello, opens a secure channel with security policy None and calls GetEndpoints, recording the
+// endpoints with their security modes, policies and user token types, the server's application description and
+// its application instance certificate. If an endpoint accepts anonymous users without security, the scan then
+// creates and activates a session and reads the server's BuildInfo: product, manufacturer, version and build.
+package opcua
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	ztls "github.com/zmap/zcrypto/tls"
+	"github.com/zmap/zcrypto/x509"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	Acknowledge *Acknowledge `json:"acknowledge,omitempty"`
+
+	// Error is the error message or service fault the server ended the scan with, if any.
+	Error *StatusError `json:"error,omitempty"`
+
+	Server    *Application `json:"server,omitempty"`
+	Endpoints []Endpoint   `json:"endpoints,omitempty"`
+
+	// Certificate is the application instance certificate of the first endpoint that has one.
+	Certificate *ztls.SimpleCertificate `json:"certificate,omitempty"`
+
+	// AnonymousAccess is true if the server activated an anonymous session without security.
+	AnonymousAccess bool       `json:"anonymous_access"`
+	BuildInfo       *BuildInfo `json:"build_info,omitempty"`
+}
+
+// Flags are the OPC UA-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+
+	EndpointPath string `long:"endpoint-path" description:"Path of the endpoint URL, e.g. /UA/Server"`
+	NoBuildInfo  bool   `long:"no-build-info" description:"Do not open an anonymous session to read the build information"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the opcua zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("opcua", "OPC Unified Architecture (OPC UA)", module.Description(), 4840, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Open an OPC UA secure channel without security and record the server's endpoints, certificate and build information"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "opcua"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// endpointURL returns the opc.tcp URL of the target.
+func (scanner *Scanner) endpointURL(target *zgrab2.ScanTarget) string {
+	host := target.Domain
+	if host == "" {
+		host = target.Host()
+	}
+	return "opc.tcp://" + net.JoinHostPort(host, strconv.Itoa(int(target.Port))) + scanner.config.EndpointPath
+}
+
+// anonymousPolicy returns the ID of a user token policy for anonymous users on an endpoint without security, or
+// false if there is none.
+func anonymousPolicy(endpoints []Endpoint) (string, bool) {
+	for _, endpoint := range endpoints {
+		if endpoint.SecurityMode != "none" || endpoint.SecurityPolicy != securityPolicyNone {
+			continue
+		}
+		for _, token := range endpoint.UserTokens {
+			if token.Type == "anonymous" && (token.SecurityPolicy == "" || token.SecurityPolicy == securityPolicyNone) {
+				return token.PolicyID, true
+			}
+		}
+	}
+	return "", false
+}
+
+// readBuildInfo activates an anonymous session and reads the BuildInfo.
+func (scanner *Scanner) readBuildInfo(c *channel, url string, policyID string, results *ScanResults) error {
+	token, err := c.createSession(url)
+	if err != nil {
+		return fmt.Errorf("error creating session: %w", err)
+	}
+	defer c.closeSession(token)
+	if err = c.activateSession(token, policyID); err != nil {
+		return fmt.Errorf("error activating anonymous session: %w", err)
+	}
+	results.AnonymousAccess = true
+	if results.BuildInfo, err = c.readBuildInfo(token); err != nil {
+		return fmt.Errorf("error reading build information: %w", err)
+	}
+	return nil
+}
+
+// Scan performs the configured scan on the OPC UA server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	results := new(ScanResults)
+	c := &channel{conn: conn, timeout: scanner.config.TargetTimeout}
+	url := scanner.endpointURL(target)
+	// an error message proves an OPC UA server as well as a successful response does
+	var statusErr *StatusError
+	if results.Acknowledge, err = c.hello(url); err != nil {
+		if errors.As(err, &statusErr) {
+			results.Error = statusErr
+			return zgrab2.SCAN_SUCCESS, results, nil
+		}
+		if errors.Is(err, errInvalidMessage) || errors.Is(err, io.ErrUnexpectedEOF) {
+			err = zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+		}
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error sending hello to target %s: %w", target.String(), err)
+	}
+
+	err = c.open()
+	if err == nil {
+		defer c.close()
+		results.Endpoints, err = c.getEndpoints(url)
+	}
+	if err != nil {
+		if errors.As(err, &statusErr) {
+			results.Error = statusErr
+			return zgrab2.SCAN_SUCCESS, results, nil
+		}
+		if errors.Is(err, errInvalidMessage) || errors.Is(err, io.ErrUnexpectedEOF) {
+			err = zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+		}
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error getting endpoints of target %s: %w", target.String(), err)
+	}
+	for _, endpoint := range results.Endpoints {
+		if results.Server == nil {
+			server := endpoint.server
+			results.Server = &server
+		}
+		if results.Certificate == nil && len(endpoint.certificate) > 0 {
+			parsed, _ := x509.ParseCertificate(endpoint.certificate)
+			results.Certificate = &ztls.SimpleCertificate{Raw: endpoint.certificate, Parsed: parsed}
+		}
+	}
+
+	if policyID, ok := anonymousPolicy(results.Endpoints); ok && !scanner.config.NoBuildInfo {
+		if err = scanner.readBuildInfo(c, url, policyID, results); err != nil {
+			log.Debugf("could not read the build information of target %s: %v", target.String(), err)
+		}
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
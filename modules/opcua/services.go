@@ -0,0 +1,268 @@
+package opcua
+
+import (
+	"crypto/rand"
+	"time"
+)
+
+// Application describes an OPC UA application.
+type Application struct {
+	URI           string   `json:"uri,omitempty"`
+	ProductURI    string   `json:"product_uri,omitempty"`
+	Name          string   `json:"name,omitempty"`
+	Type          string   `json:"type,omitempty"`
+	GatewayURI    string   `json:"gateway_uri,omitempty"`
+	DiscoveryURLs []string `json:"discovery_urls,omitempty"`
+}
+
+// UserToken is a user identity token policy of an endpoint.
+type UserToken struct {
+	PolicyID       string `json:"policy_id,omitempty"`
+	Type           string `json:"type"`
+	SecurityPolicy string `json:"security_policy,omitempty"`
+}
+
+// Endpoint is an endpoint returned by GetEndpoints.
+type Endpoint struct {
+	URL              string      `json:"url"`
+	SecurityMode     string      `json:"security_mode"`
+	SecurityPolicy   string      `json:"security_policy"`
+	SecurityLevel    uint8       `json:"security_level"`
+	TransportProfile string      `json:"transport_profile,omitempty"`
+	UserTokens       []UserToken `json:"user_tokens,omitempty"`
+
+	server      Application
+	certificate []byte
+}
+
+// application decodes an ApplicationDescription.
+func (d *decoder) application() Application {
+	app := Application{
+		URI:        d.string(),
+		ProductURI: d.string(),
+		Name:       d.localizedText(),
+	}
+	appType := d.uint32()
+	app.Type = applicationTypeNames[appType]
+	app.GatewayURI = d.string()
+	d.string() // discovery profile URI
+	app.DiscoveryURLs = d.stringArray()
+	return app
+}
+
+// endpoint decodes an EndpointDescription.
+func (d *decoder) endpoint() Endpoint {
+	endpoint := Endpoint{URL: d.string(), server: d.application(), certificate: d.byteString()}
+	endpoint.SecurityMode = securityModeNames[d.uint32()]
+	endpoint.SecurityPolicy = d.string()
+	for n := d.arrayLength(); n > 0 && d.err == nil; n-- {
+		token := UserToken{PolicyID: d.string(), Type: userTokenTypeNames[d.uint32()]}
+		d.string() // issued token type
+		d.string() // issuer endpoint URL
+		token.SecurityPolicy = d.string()
+		endpoint.UserTokens = append(endpoint.UserTokens, token)
+	}
+	endpoint.TransportProfile = d.string()
+	endpoint.SecurityLevel = d.uint8()
+	return endpoint
+}
+
+// endpoints decodes an array of EndpointDescriptions.
+func (d *decoder) endpoints() []Endpoint {
+	var endpoints []Endpoint
+	for n := d.arrayLength(); n > 0 && d.err == nil; n-- {
+		endpoints = append(endpoints, d.endpoint())
+	}
+	return endpoints
+}
+
+// getEndpoints calls GetEndpoints for the endpoint URL.
+func (c *channel) getEndpoints(endpointURL string) ([]Endpoint, error) {
+	_, d, err := c.call(idGetEndpointsRequest, func(e *encoder) {
+		e.requestHeader(nil, c.requestID, c.timeout)
+		e.string(endpointURL)
+		e.int32(-1) // locale IDs
+		e.int32(-1) // profile URIs
+	})
+	if err != nil {
+		return nil, err
+	}
+	endpoints := d.endpoints()
+	return endpoints, d.err
+}
+
+// createSession calls CreateSession and returns the authentication token.
+func (c *channel) createSession(endpointURL string) ([]byte, error) {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	_, d, err := c.call(idCreateSessionRequest, func(e *encoder) {
+		e.requestHeader(nil, c.requestID, c.timeout)
+		e.string("urn:zgrab2:client")
+		e.string("urn:zgrab2")
+		e.uint8(0x02) // localized text with only a text
+		e.string("zgrab2")
+		e.uint32(1) // client
+		e.string("")
+		e.string("")
+		e.int32(-1)
+		e.string("") // server URI
+		e.string(endpointURL)
+		e.string("zgrab2")
+		e.byteString(nonce)
+		e.byteString(nil) // client certificate
+		e.float64(float64(time.Minute / time.Millisecond))
+		e.uint32(0) // max response message size
+	})
+	if err != nil {
+		return nil, err
+	}
+	d.nodeID() // session ID
+	token, _ := d.nodeID()
+	return append([]byte(nil), token...), d.err
+}
+
+// activateSession activates the session with an anonymous identity token of the given policy.
+func (c *channel) activateSession(token []byte, policyID string) error {
+	_, _, err := c.call(idActivateSessionRequest, func(e *encoder) {
+		e.requestHeader(token, c.requestID, c.timeout)
+		e.string("")      // client signature algorithm
+		e.byteString(nil) // and signature
+		e.int32(-1)       // client software certificates
+		e.int32(-1)       // locale IDs
+		identity := new(encoder)
+		identity.string(policyID)
+		e.nodeID(idAnonymousIdentityToken)
+		e.uint8(0x01)
+		e.byteString(identity.b)
+		e.string("")      // user token signature algorithm
+		e.byteString(nil) // and signature
+	})
+	return err
+}
+
+// closeSession sends CloseSession without waiting for the response.
+func (c *channel) closeSession(token []byte) {
+	e := new(encoder)
+	e.uint32(c.channelID)
+	e.uint32(c.tokenID)
+	c.sequenceHeader(e)
+	e.nodeID(idCloseSessionRequest)
+	e.requestHeader(token, c.requestID, c.timeout)
+	e.uint8(1) // delete subscriptions
+	_, _ = c.conn.Write(frame("MSG", e.b))
+}
+
+// Node IDs of the BuildInfo variables of the Server object.
+const (
+	nodeProductName      = 2261
+	nodeProductURI       = 2262
+	nodeManufacturerName = 2263
+	nodeSoftwareVersion  = 2264
+	nodeBuildNumber      = 2265
+	nodeBuildDate        = 2266
+)
+
+// attributeValue is the Value attribute.
+const attributeValue = 13
+
+// Variant types of the BuildInfo values.
+const (
+	variantString   = 12
+	variantDateTime = 13
+)
+
+// BuildInfo is the build information of the server.
+type BuildInfo struct {
+	ProductURI       string     `json:"product_uri,omitempty"`
+	ManufacturerName string     `json:"manufacturer_name,omitempty"`
+	ProductName      string     `json:"product_name,omitempty"`
+	SoftwareVersion  string     `json:"software_version,omitempty"`
+	BuildNumber      string     `json:"build_number,omitempty"`
+	BuildDate        *time.Time `json:"build_date,omitempty"`
+}
+
+// readBuildInfo reads the BuildInfo variables in a session.
+func (c *channel) readBuildInfo(token []byte) (*BuildInfo, error) {
+	nodes := []uint32{nodeProductURI, nodeManufacturerName, nodeProductName, nodeSoftwareVersion, nodeBuildNumber, nodeBuildDate}
+	_, d, err := c.call(idReadRequest, func(e *encoder) {
+		e.requestHeader(token, c.requestID, c.timeout)
+		e.float64(0) // max age
+		e.uint32(3)  // no timestamps
+		e.int32(int32(len(nodes)))
+		for _, node := range nodes {
+			e.nodeID(node)
+			e.uint32(attributeValue)
+			e.string("") // index range
+			e.uint16(0)  // data encoding namespace
+			e.string("") // and name
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	info := new(BuildInfo)
+	fields := []*string{&info.ProductURI, &info.ManufacturerName, &info.ProductName, &info.SoftwareVersion, &info.BuildNumber}
+	for i, n := 0, d.arrayLength(); i < n && d.err == nil; i++ {
+		value, ok := d.dataValue()
+		if !ok || i >= len(nodes) {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			if i < len(fields) {
+				*fields[i] = v
+			}
+		case time.Time:
+			if nodes[i] == nodeBuildDate && !v.IsZero() {
+				info.BuildDate = &v
+			}
+		}
+	}
+	return info, d.err
+}
+
+// dataValue decodes a DataValue, returning its value if it is a scalar String or DateTime.
+func (d *decoder) dataValue() (any, bool) {
+	mask := d.uint8()
+	var value any
+	ok := false
+	if mask&0x01 != 0 {
+		value, ok = d.variant()
+	}
+	if mask&0x02 != 0 {
+		d.uint32() // status code
+	}
+	if mask&0x04 != 0 {
+		d.uint64()
+	}
+	if mask&0x08 != 0 {
+		d.uint64()
+	}
+	if mask&0x10 != 0 {
+		d.uint16()
+	}
+	if mask&0x20 != 0 {
+		d.uint16()
+	}
+	return value, ok && d.err == nil
+}
+
+// variant decodes a scalar String or DateTime Variant. Other types end the decoding, since their length is not
+// known to the scan.
+func (d *decoder) variant() (any, bool) {
+	encoding := d.uint8()
+	if encoding&0xC0 != 0 {
+		d.err = errInvalidMessage
+		return nil, false
+	}
+	switch encoding {
+	case variantString:
+		return d.string(), true
+	case variantDateTime:
+		return d.dateTime(), true
+	}
+	d.err = errInvalidMessage
+	return nil, false
+}
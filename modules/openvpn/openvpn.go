@@ -0,0 +1,137 @@
+package openvpn
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+)
+
+// Control channel opcodes, stored in the high five bits of the first byte of each packet.
+const (
+	opcodeControlHardResetClientV1 = 1
+	opcodeControlHardResetServerV1 = 2
+	opcodeControlSoftResetV1       = 3
+	opcodeControlV1                = 4
+	opcodeAckV1                    = 5
+	opcodeDataV1                   = 6
+	opcodeControlHardResetClientV2 = 7
+	opcodeControlHardResetServerV2 = 8
+	opcodeDataV2                   = 9
+	opcodeControlHardResetClientV3 = 10
+	opcodeControlWKCV1             = 11
+)
+
+var opcodeNames = map[byte]string{
+	opcodeControlHardResetClientV1: "P_CONTROL_HARD_RESET_CLIENT_V1",
+	opcodeControlHardResetServerV1: "P_CONTROL_HARD_RESET_SERVER_V1",
+	opcodeControlSoftResetV1:       "P_CONTROL_SOFT_RESET_V1",
+	opcodeControlV1:                "P_CONTROL_V1",
+	opcodeAckV1:                    "P_ACK_V1",
+	opcodeDataV1:                   "P_DATA_V1",
+	opcodeControlHardResetClientV2: "P_CONTROL_HARD_RESET_CLIENT_V2",
+	opcodeControlHardResetServerV2: "P_CONTROL_HARD_RESET_SERVER_V2",
+	opcodeDataV2:                   "P_DATA_V2",
+	opcodeControlHardResetClientV3: "P_CONTROL_HARD_RESET_CLIENT_V3",
+	opcodeControlWKCV1:             "P_CONTROL_WKC_V1",
+}
+
+// maxTCPPacketSize bounds the length prefix of a packet over TCP.
+const maxTCPPacketSize = 1 << 14
+
+var errInvalidPacket = errors.New("invalid OpenVPN packet")
+
+// newSessionID returns a random 64-bit session ID.
+func newSessionID() []byte {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return id
+}
+
+// buildHardResetClient returns a P_CONTROL_HARD_RESET_CLIENT_V2 without tls-auth or tls-crypt protection: the
+// opcode, the session ID, an empty ACK array and message packet ID 0.
+func buildHardResetClient(sessionID []byte) []byte {
+	packet := []byte{opcodeControlHardResetClientV2 << 3}
+	packet = append(packet, sessionID...)
+	packet = append(packet, 0)
+	return binary.BigEndian.AppendUint32(packet, 0)
+}
+
+// frameTCP prefixes a packet with its length, as OpenVPN does over TCP.
+func frameTCP(packet []byte) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(packet))), packet...)
+}
+
+// readTCPPacket reads one length-prefixed packet.
+func readTCPPacket(r io.Reader) ([]byte, error) {
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	if length == 0 || length > maxTCPPacketSize {
+		return nil, errInvalidPacket
+	}
+	packet := make([]byte, length)
+	if _, err := io.ReadFull(r, packet); err != nil {
+		return nil, err
+	}
+	return packet, nil
+}
+
+// ControlPacket is a decoded control channel packet from the server.
+type ControlPacket struct {
+	Opcode        string   `json:"opcode"`
+	KeyID         uint8    `json:"key_id"`
+	SessionID     string   `json:"session_id"`
+	AckedIDs      []uint32 `json:"acked_ids,omitempty"`
+	PeerSessionID string   `json:"peer_session_id,omitempty"`
+	PacketID      uint32   `json:"packet_id"`
+	PayloadLen    int      `json:"payload_length,omitempty"`
+
+	opcode        byte
+	peerSessionID []byte
+}
+
+// parseControlPacket decodes an unprotected control channel packet.
+func parseControlPacket(data []byte) (*ControlPacket, error) {
+	if len(data) < 10 {
+		return nil, errInvalidPacket
+	}
+	packet := &ControlPacket{
+		opcode:    data[0] >> 3,
+		KeyID:     data[0] & 0x07,
+		SessionID: hex.EncodeToString(data[1:9]),
+	}
+	name, ok := opcodeNames[packet.opcode]
+	if !ok || packet.opcode == opcodeDataV1 || packet.opcode == opcodeDataV2 {
+		return nil, errInvalidPacket
+	}
+	packet.Opcode = name
+	acks := int(data[9])
+	rest := data[10:]
+	if len(rest) < acks*4 {
+		return nil, errInvalidPacket
+	}
+	for i := 0; i < acks; i++ {
+		packet.AckedIDs = append(packet.AckedIDs, binary.BigEndian.Uint32(rest[i*4:]))
+	}
+	rest = rest[acks*4:]
+	if acks > 0 {
+		if len(rest) < 8 {
+			return nil, errInvalidPacket
+		}
+		packet.peerSessionID = rest[:8]
+		packet.PeerSessionID = hex.EncodeToString(packet.peerSessionID)
+		rest = rest[8:]
+	}
+	if packet.opcode == opcodeAckV1 {
+		return packet, nil
+	}
+	if len(rest) < 4 {
+		return nil, errInvalidPacket
+	}
+	packet.PacketID = binary.BigEndian.Uint32(rest)
+	packet.PayloadLen = len(rest) - 4
+	return packet, nil
+}
@@ -0,0 +1,46 @@
+package openvpn
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestParseControlPacket(t *testing.T) {
+	clientSession := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	request := buildHardResetClient(clientSession)
+	if len(request) != 14 || request[0] != 0x38 {
+		t.Fatalf("unexpected reset %x", request)
+	}
+
+	// P_CONTROL_HARD_RESET_SERVER_V2 acknowledging packet 0 of the client session
+	reply := []byte{opcodeControlHardResetServerV2 << 3}
+	reply = append(reply, 0xA0, 0xA1, 0xA2, 0xA3, 0xA4, 0xA5, 0xA6, 0xA7)
+	reply = append(reply, 1, 0, 0, 0, 0)
+	reply = append(reply, clientSession...)
+	reply = binary.BigEndian.AppendUint32(reply, 0)
+
+	framed, err := readTCPPacket(bytes.NewReader(frameTCP(reply)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	packet, err := parseControlPacket(framed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packet.Opcode != "P_CONTROL_HARD_RESET_SERVER_V2" || packet.SessionID != "a0a1a2a3a4a5a6a7" || len(packet.AckedIDs) != 1 {
+		t.Errorf("unexpected packet %+v", packet)
+	}
+	if !bytes.Equal(packet.peerSessionID, clientSession) {
+		t.Errorf("got peer session %x", packet.peerSessionID)
+	}
+	if !isServerPacket(nil, reply) {
+		t.Error("reply not recognized as a server packet")
+	}
+
+	for _, invalid := range [][]byte{reply[:12], {opcodeDataV2 << 3, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, {0xF8, 0, 0, 0, 0, 0, 0, 0, 0, 0}} {
+		if _, err := parseControlPacket(invalid); err == nil {
+			t.Errorf("expected error for %x", invalid)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+// Package openvpn contains the zgrab2 Module implementation for OpenVPN.
+//
+// The scan sends a P_CONTROL_HARD_RESET_CLIENT_V2 without an HMAC. A server without tls-auth or tls-crypt answers
+// with a P_CONTROL_HARD_RESET_SERVER_V2; a server using either silently drops the packet. Over UDP, a dropped packet
+// can't be told apart from a filtered port, so the "dropped" response type is only conclusive over TCP.
+package openvpn
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// Response types.
+const (
+	// ResponseResetServer means the server answered the reset, so it does not use tls-auth or tls-crypt.
+	ResponseResetServer = "reset_server"
+
+	// ResponseDropped means the server accepted the packet but did not answer, as tls-auth and tls-crypt servers do.
+	ResponseDropped = "dropped"
+
+	// ResponseNone means nothing is listening, e.g. the port answered with ICMP port unreachable.
+	ResponseNone = "none"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	Transport string `json:"transport"`
+
+	// Response classifies the server's reaction to the reset: reset_server, dropped or none.
+	Response string `json:"response"`
+
+	// Unprotected is true if the server answered a reset without an HMAC, i.e. it does not use tls-auth or tls-crypt.
+	Unprotected bool `json:"unprotected"`
+
+	// Packet is the server's reply.
+	Packet *ControlPacket `json:"packet,omitempty"`
+
+	// AcksClient is true if the reply acknowledges the reset sent by the scanner.
+	AcksClient bool `json:"acks_client,omitempty"`
+
+	UDPProbe *zgrab2.UDPProbeResult `json:"udp_probe,omitempty"`
+}
+
+// Flags are the OpenVPN-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.UDPFlags
+
+	TCP bool `long:"tcp" description:"Send the reset over TCP instead of UDP"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the openvpn zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("openvpn", "OpenVPN", module.Description(), 1194, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Send an OpenVPN hard reset and check whether the server answers without tls-auth or tls-crypt"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	if !f.TCP {
+		return f.UDPFlags.Validate()
+	}
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "openvpn"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	transport := zgrab2.TransportUDP
+	if f.TCP {
+		transport = zgrab2.TransportTCP
+	}
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: transport,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// isServerPacket reports whether response is an unprotected control packet from the server.
+func isServerPacket(_, response []byte) bool {
+	_, err := parseControlPacket(response)
+	return err == nil
+}
+
+// exchange sends the reset and returns the server's reply.
+func (scanner *Scanner) exchange(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget, request []byte, results *ScanResults) ([]byte, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	if !scanner.config.TCP {
+		probe := zgrab2.NewStaticUDPProbe("hard-reset-client", request, isServerPacket)
+		results.UDPProbe, err = zgrab2.SendUDPProbe(ctx, conn, probe, target, &scanner.config.UDPFlags)
+		if err != nil {
+			return nil, err
+		}
+		return results.UDPProbe.Response, nil
+	}
+
+	if _, err = conn.Write(frameTCP(request)); err != nil {
+		return nil, fmt.Errorf("error sending reset to target %s: %w", target.String(), err)
+	}
+	// the connection was accepted, so any failure from here on means the server dropped the reset
+	results.Response = ResponseDropped
+	reply, err := readTCPPacket(conn)
+	if err != nil {
+		return nil, fmt.Errorf("error reading reply from target %s: %w", target.String(), err)
+	}
+	return reply, nil
+}
+
+// Scan performs the configured scan on the OpenVPN server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	results := &ScanResults{Transport: "udp"}
+	if scanner.config.TCP {
+		results.Transport = "tcp"
+	}
+	sessionID := newSessionID()
+	reply, err := scanner.exchange(ctx, dialGroup, target, buildHardResetClient(sessionID), results)
+	if err != nil {
+		status := zgrab2.TryGetScanStatus(err)
+		if results.UDPProbe != nil {
+			switch {
+			case results.UDPProbe.ICMP != "":
+				results.Response = ResponseNone
+			case status == zgrab2.SCAN_IO_TIMEOUT:
+				results.Response = ResponseDropped
+			}
+		}
+		if results.Response == "" {
+			return status, nil, err
+		}
+		return status, results, err
+	}
+
+	packet, err := parseControlPacket(reply)
+	if err != nil {
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("invalid reply from target %s: %w", target.String(), err)
+	}
+	results.Packet = packet
+	results.AcksClient = bytes.Equal(packet.peerSessionID, sessionID)
+	switch packet.opcode {
+	case opcodeControlHardResetServerV2, opcodeControlHardResetServerV1:
+		results.Response = ResponseResetServer
+		results.Unprotected = true
+	default:
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("unexpected %s reply from target %s", packet.Opcode, target.String())
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
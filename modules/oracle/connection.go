@@ -13,6 +13,21 @@ type HandshakeLog struct {
 	// AcceptVersion is the protocol version value from the Accept packet.
 	AcceptVersion uint16 `json:"accept_version"`
 
+	// AcceptSDU is the Session Data Unit size the server chose in the Accept
+	// packet.
+	AcceptSDU uint16 `json:"accept_sdu,omitempty"`
+
+	// AcceptTDU is the Transfer Data Unit size the server chose in the Accept
+	// packet.
+	AcceptTDU uint16 `json:"accept_tdu,omitempty"`
+
+	// AcceptExtraRaw is the data between the fixed fields of the Accept packet
+	// and its AcceptData; newer servers put e.g. 32-bit SDU / TDU values here.
+	AcceptExtraRaw []byte `json:"accept_extra_raw,omitempty"`
+
+	// AcceptDataRaw is the AcceptData payload of the Accept packet.
+	AcceptDataRaw []byte `json:"accept_data_raw,omitempty"`
+
 	// GlobalServiceOptions is the set of GlobalServiceOptions flags that the
 	// server returns in the Accept packet.
 	GlobalServiceOptions map[string]bool `json:"global_service_options,omitempty"`
@@ -58,9 +73,6 @@ type HandshakeLog struct {
 	// format.
 	RefuseVersion string `json:"refuse_version,omitempty"`
 
-	// DidResend is set to true if the server sent a Resend packet after the
-	// first Connect packet.
-
 	// NSNVersion is the ReleaseVersion string (in dotted decimal format) in the
 	// root of the Native Service Negotiation packet.
 	NSNVersion string `json:"nsn_version,omitempty"`
@@ -141,16 +153,45 @@ func u16Flag(v string) uint16 {
 	return uint16(ret)
 }
 
-// Connect to the server and do a handshake with the given config.
-func (conn *Connection) Connect(connectDescriptor string) (*HandshakeLog, error) {
-	result := HandshakeLog{}
+// DescriptorProbe is the server's response to a Connect packet naming a single
+// SID or service name.
+type DescriptorProbe struct {
+	// Kind is the CONNECT_DATA key that was set: SID or SERVICE_NAME.
+	Kind string `json:"kind"`
+
+	// Name is the SID or service name that was tried.
+	Name string `json:"name"`
+
+	// Response is the type of the packet the server responded with, e.g.
+	// ACCEPT, REFUSE or REDIRECT. Omitted if no valid packet was returned.
+	Response string `json:"response,omitempty"`
+
+	// Acknowledged is true if the listener accepted the connect descriptor or
+	// redirected the client to the instance serving it.
+	Acknowledged bool `json:"acknowledged"`
+
+	// RefuseErrorCode is the DESCRIPTION.ERR value of the Refuse packet, e.g.
+	// 12505 for an unknown SID or 12514 for an unknown service name.
+	RefuseErrorCode string `json:"refuse_error_code,omitempty"`
+
+	// RedirectTargetRaw is the connect descriptor returned in the Redirect
+	// packet, if one is sent.
+	RedirectTargetRaw string `json:"redirect_target_raw,omitempty"`
+
+	// Error is set if the probe could not be completed.
+	Error string `json:"error,omitempty"`
+}
+
+// getConnectPacket builds the Connect packet for the given connect descriptor
+// from the scanner's configuration.
+func (conn *Connection) getConnectPacket(connectDescriptor string) (*TNSConnect, error) {
 	extraData := []byte{}
 	if len(connectDescriptor)+len(extraData)+0x3A > 0x7fff {
 		return nil, ErrInvalidInput
 	}
 
 	// TODO: Variable fields in the connect descriptor (e.g. host?)
-	connectPacket := &TNSConnect{
+	return &TNSConnect{
 		Version:                 conn.scanner.config.Version,
 		MinVersion:              conn.scanner.config.MinVersion,
 		GlobalServiceOptions:    ServiceOptions(u16Flag(conn.scanner.config.GlobalServiceOptions)),
@@ -170,6 +211,45 @@ func (conn *Connection) Connect(connectDescriptor string) (*HandshakeLog, error)
 		ConnectionID1:           [8]byte{0, 0, 0, 0, 0, 0, 0, 0},
 		Unknown3A:               extraData,
 		ConnectDescriptor:       connectDescriptor,
+	}, nil
+}
+
+// Probe sends a Connect packet with the given connect descriptor and records
+// how the server responds to it, without continuing the handshake.
+func (conn *Connection) Probe(probe *DescriptorProbe, connectDescriptor string) error {
+	connectPacket, err := conn.getConnectPacket(connectDescriptor)
+	if err != nil {
+		return err
+	}
+	response, err := conn.SendPacket(connectPacket)
+	if err != nil {
+		return err
+	}
+	probe.Response = response.GetType().String()
+	switch resp := response.(type) {
+	case *TNSAccept:
+		probe.Acknowledged = true
+	case *TNSRedirect:
+		probe.Acknowledged = true
+		probe.RedirectTargetRaw = string(resp.Data)
+	case *TNSRefuse:
+		if desc, err := DecodeDescriptor(string(resp.Data)); err == nil {
+			if codes := desc.GetValues("DESCRIPTION.ERR"); len(codes) > 0 {
+				probe.RefuseErrorCode = codes[0]
+			}
+		}
+	default:
+		return ErrUnexpectedResponse
+	}
+	return nil
+}
+
+// Connect to the server and do a handshake with the given config.
+func (conn *Connection) Connect(connectDescriptor string) (*HandshakeLog, error) {
+	result := HandshakeLog{}
+	connectPacket, err := conn.getConnectPacket(connectDescriptor)
+	if err != nil {
+		return nil, err
 	}
 	response, err := conn.SendPacket(connectPacket)
 
@@ -186,7 +266,7 @@ func (conn *Connection) Connect(connectDescriptor string) (*HandshakeLog, error)
 		accept = resp
 	case *TNSRedirect:
 		result.RedirectTargetRaw = string(resp.Data)
-		if desc, err = DecodeDescriptor(result.RedirectTargetRaw); err != nil {
+		if desc, err = DecodeDescriptor(result.RedirectTargetRaw); err == nil {
 			result.RedirectTarget = desc
 		}
 		// TODO: Follow redirects?
@@ -214,6 +294,10 @@ func (conn *Connection) Connect(connectDescriptor string) (*HandshakeLog, error)
 	// TODO: Unclear what all of these values these do. Defaults taken from the
 	// values sent by the Oracle SQLPlus 11.2 client.
 	result.AcceptVersion = accept.Version
+	result.AcceptSDU = accept.SDU
+	result.AcceptTDU = accept.TDU
+	result.AcceptExtraRaw = accept.Unknown18
+	result.AcceptDataRaw = accept.AcceptData
 	result.GlobalServiceOptions = accept.GlobalServiceOptions.Set()
 	result.ConnectFlags0 = accept.ConnectFlags0.Set()
 	result.ConnectFlags1 = accept.ConnectFlags1.Set()
@@ -316,6 +400,9 @@ func (conn *Connection) Connect(connectDescriptor string) (*HandshakeLog, error)
 	if err != nil {
 		return &result, err
 	}
+	if nsnResponse.Version != 0 {
+		result.NSNVersion = nsnResponse.Version.String()
+	}
 	result.NSNServiceVersions = make(map[string]string)
 	for _, svc := range nsnResponse.Services {
 		if !svc.Type.IsUnknown() {
@@ -0,0 +1,95 @@
+package oracle
+
+import (
+	"net"
+	"testing"
+
+	"github.com/zmap/zgrab2"
+)
+
+// fakeListener answers the first Connect packet read from the returned
+// connection with response.
+func fakeListener(t *testing.T, response TNSPacketBody) *Connection {
+	driver := getTNSDriver()
+	client, server := net.Pipe()
+	t.Cleanup(func() { client.Close() })
+	go func() {
+		defer server.Close()
+		packet, err := driver.ReadTNSPacket(server)
+		if err != nil || packet.Body.GetType() != PacketTypeConnect {
+			return
+		}
+		encoded, err := driver.EncodePacket(&TNSPacket{Body: response})
+		if err != nil {
+			return
+		}
+		server.Write(encoded)
+	}()
+	flags := new(Flags)
+	flags.GlobalServiceOptions = "0x0C41"
+	flags.SDU = "0x2000"
+	flags.TDU = "0xFFFF"
+	flags.ProtocolCharacterisics = "0x7F08"
+	flags.ConnectFlags = "0x4141"
+	return &Connection{
+		conn:      client,
+		scanner:   &Scanner{config: flags},
+		target:    &zgrab2.ScanTarget{},
+		tnsDriver: driver,
+	}
+}
+
+func refusePacket(data string) *TNSRefuse {
+	return &TNSRefuse{AppReason: 0x22, DataLength: uint16(len(data)), Data: []byte(data)}
+}
+
+func TestProbe(t *testing.T) {
+	redirect := "(ADDRESS=(PROTOCOL=TCP)(HOST=10.0.0.2)(PORT=49152))"
+	tests := map[string]struct {
+		response TNSPacketBody
+		expected DescriptorProbe
+	}{
+		"accept": {
+			response: validTNSAccept["01. 013A-0139"].Value.Body,
+			expected: DescriptorProbe{Response: "ACCEPT", Acknowledged: true},
+		},
+		"redirect": {
+			response: &TNSRedirect{DataLength: uint16(len(redirect)), Data: []byte(redirect)},
+			expected: DescriptorProbe{Response: "REDIRECT", Acknowledged: true, RedirectTargetRaw: redirect},
+		},
+		"unknown SID": {
+			response: refusePacket("(DESCRIPTION=(TMP=)(VSNNUM=0)(ERR=12505)(ERROR_STACK=(ERROR=(CODE=12505)(EMFI=4))))"),
+			expected: DescriptorProbe{Response: "REFUSE", RefuseErrorCode: "12505"},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			conn := fakeListener(t, test.response)
+			var probe DescriptorProbe
+			if err := conn.Probe(&probe, getProbeDescriptor("SID", "ORCL")); err != nil {
+				t.Fatalf("Probe: %v", err)
+			}
+			if probe != test.expected {
+				t.Errorf("probe = %+v, expected %+v", probe, test.expected)
+			}
+		})
+	}
+}
+
+func TestConnectRefuse(t *testing.T) {
+	conn := fakeListener(t, refusePacket("(DESCRIPTION=(ERR=1153)(VSNNUM=186647040)(ERROR_STACK=(ERROR=(CODE=1153)(EMFI=4))))"))
+	log, err := conn.Connect(getProbeDescriptor("SERVICE_NAME", "XE"))
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if log.RefuseVersion != "11.2.0.2.0" || log.RefuseReasonApp != "0x22" {
+		t.Errorf("handshake = %+v", log)
+	}
+}
+
+func TestSplitNames(t *testing.T) {
+	names := splitNames(" ORCL,,XE ,")
+	if len(names) != 2 || names[0] != "ORCL" || names[1] != "XE" {
+		t.Errorf("splitNames = %q", names)
+	}
+}
@@ -18,12 +18,17 @@
 //
 // The output includes the server's protocol version and any component release
 // versions that are returned.
+//
+// If --probe-sids is set, the scan then sends one Connect packet per entry of
+// --sids and --service-names, each on a new connection, and records whether the
+// listener accepts, redirects or refuses the resulting connect descriptor.
 package oracle
 
 import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 
@@ -38,6 +43,10 @@ type ScanResults struct {
 	// TLSLog contains the log of the TLS handshake (and any additional
 	// configured TLS scan operations).
 	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
+
+	// DescriptorProbes are the listener's responses to each SID and service
+	// name tried with --probe-sids.
+	DescriptorProbes []DescriptorProbe `json:"descriptor_probes,omitempty"`
 }
 
 // Flags holds the command-line configuration for the HTTP scan module.
@@ -90,6 +99,16 @@ type Flags struct {
 	// NewTNS causes the client to use the newer TNS header format with 32-bit
 	// lengths.
 	NewTNS bool `long:"new-tns" description:"If set, use new-style TNS headers"`
+
+	// ProbeSIDs enables testing the SIDs and ServiceNames lists.
+	ProbeSIDs bool `long:"probe-sids" description:"After the handshake, test which of --sids and --service-names the listener acknowledges, one connection each."`
+
+	// SIDs is a comma-separated list of SIDs to try with --probe-sids.
+	SIDs string `long:"sids" description:"Comma-separated list of SIDs to try with --probe-sids." default:"ORCL,XE,ORCLCDB,PROD,TEST"`
+
+	// ServiceNames is a comma-separated list of service names to try with
+	// --probe-sids.
+	ServiceNames string `long:"service-names" description:"Comma-separated list of service names to try with --probe-sids." default:"ORCL,XE,ORCLPDB1,XEPDB1"`
 }
 
 // Module implements the zgrab2.Module interface.
@@ -149,9 +168,31 @@ func (flags *Flags) Validate(_ []string) error {
 	if _, err := EncodeReleaseVersion(flags.ReleaseVersion); err != nil {
 		return fmt.Errorf("release-version: %s is not a valid five-component dotted-decimal number", flags.ReleaseVersion)
 	}
+	for _, name := range append(splitNames(flags.SIDs), splitNames(flags.ServiceNames)...) {
+		if strings.ContainsAny(name, "()=") {
+			return fmt.Errorf("sids / service-names: %s may not contain parentheses or '='", name)
+		}
+	}
 	return nil
 }
 
+// splitNames splits a comma-separated list, dropping empty entries.
+func splitNames(list string) []string {
+	var ret []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			ret = append(ret, name)
+		}
+	}
+	return ret
+}
+
+// getProbeDescriptor returns the connect descriptor used to test a single SID
+// or service name; kind is the CONNECT_DATA key, SID or SERVICE_NAME.
+func getProbeDescriptor(kind, name string) string {
+	return fmt.Sprintf("(DESCRIPTION=(CONNECT_DATA=(%s=%s)(CID=(PROGRAM=zgrab2))))", kind, name)
+}
+
 // Help returns the module's help string.
 func (flags *Flags) Help() string {
 	return ""
@@ -230,7 +271,9 @@ func (scanner *Scanner) getTNSDriver() *TNSDriver {
 //  7. Pull the server protocol version and other flags from the Accept packet
 //     into the results, then send a Native Security Negotiation Data packet.
 //  8. If the response is not a Data packet, exit with SCAN_APPLICATION_ERROR.
-//  9. Pull the versions out of the response and exit with SCAN_SUCCESS.
+//  9. Pull the versions out of the response.
+//  10. If --probe-sids is set, send a Connect packet for each SID and service
+//     name on a new connection and record the response.
 func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
 	results := new(ScanResults)
 
@@ -261,6 +304,8 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 		results.Handshake = handshakeLog
 	}
 
+	zgrab2.CloseConnAndHandleError(sock)
+
 	if err != nil {
 		switch err {
 		case ErrUnexpectedResponse:
@@ -270,5 +315,36 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 		}
 	}
 
+	if scanner.config.ProbeSIDs {
+		for _, sid := range splitNames(scanner.config.SIDs) {
+			results.DescriptorProbes = append(results.DescriptorProbes, scanner.probeDescriptor(ctx, dialGroup, target, "SID", sid))
+		}
+		for _, serviceName := range splitNames(scanner.config.ServiceNames) {
+			results.DescriptorProbes = append(results.DescriptorProbes, scanner.probeDescriptor(ctx, dialGroup, target, "SERVICE_NAME", serviceName))
+		}
+	}
+
 	return zgrab2.SCAN_SUCCESS, results, nil
 }
+
+// probeDescriptor opens a new connection to the target and records the
+// response to a Connect packet for the given SID or service name.
+func (scanner *Scanner) probeDescriptor(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget, kind, name string) DescriptorProbe {
+	probe := DescriptorProbe{Kind: kind, Name: name}
+	sock, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		probe.Error = err.Error()
+		return probe
+	}
+	defer zgrab2.CloseConnAndHandleError(sock)
+	conn := Connection{
+		conn:      sock,
+		scanner:   scanner,
+		target:    target,
+		tnsDriver: scanner.getTNSDriver(),
+	}
+	if err = conn.Probe(&probe, getProbeDescriptor(kind, name)); err != nil {
+		probe.Error = err.Error()
+	}
+	return probe
+}
@@ -788,6 +788,12 @@ func ReadTNSAccept(reader io.Reader, header *TNSHeader) (*TNSAccept, error) {
 	next.read(&ret.DataOffset)
 	next.read(&ret.ConnectFlags0)
 	next.read(&ret.ConnectFlags1)
+	if err := next.Error(); err != nil {
+		return nil, err
+	}
+	if ret.DataOffset < 16+8 {
+		return nil, ErrInvalidData
+	}
 	unknownLen := ret.DataOffset - 16 - 8
 	next.readNew(&ret.Unknown18, int(unknownLen))
 	next.readNew(&ret.AcceptData, int(ret.DataLength))
@@ -838,7 +844,7 @@ func (packet *TNSRefuse) Encode() ([]byte, error) {
 
 // GetType identifies the packet as PacketTypeRefuse.
 func (packet *TNSRefuse) GetType() PacketType {
-	return PacketTypeRedirect
+	return PacketTypeRefuse
 }
 
 // ReadTNSRefuse reads a TNSRefuse packet from the stream, which should
@@ -1523,6 +1529,8 @@ func (driver *TNSDriver) ReadTNSPacket(reader io.Reader) (*TNSPacket, error) {
 		body, err = ReadTNSAccept(reader, header)
 	case PacketTypeRefuse:
 		body, err = ReadTNSRefuse(reader, header)
+	case PacketTypeRedirect:
+		body, err = ReadTNSRedirect(reader, header)
 	case PacketTypeResend:
 		body, err = ReadTNSResend(reader, header)
 	case PacketTypeData:
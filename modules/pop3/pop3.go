@@ -7,6 +7,8 @@ import (
 	"io"
 	"net"
 	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/zmap/zgrab2"
 )
@@ -14,6 +16,9 @@ import (
 // This is the regex used in zgrab.
 var pop3EndRegex = regexp.MustCompile(`(?:\r\n\.\r\n$)|(?:\r\n$)`)
 
+// pop3MultilineEndRegex matches a complete multi-line response, or a single-line error response.
+var pop3MultilineEndRegex = regexp.MustCompile(`(?:\r\n\.\r\n$)|(?:^-ERR[^\r\n]*\r\n$)`)
+
 const readBufferSize int = 0x10000
 
 // Connection wraps the state and access to the SMTP connection.
@@ -40,3 +45,47 @@ func (conn *Connection) SendCommand(cmd string) (string, error) {
 	}
 	return conn.ReadResponse()
 }
+
+// SendMultilineCommand sends a command with a multi-line response, followed by a CRLF, then reads the server's response
+// up to its terminating line.
+func (conn *Connection) SendMultilineCommand(cmd string) (string, error) {
+	if _, err := conn.Conn.Write([]byte(cmd + "\r\n")); err != nil {
+		return "", err
+	}
+	ret := make([]byte, readBufferSize)
+	n, err := zgrab2.ReadUntilRegex(conn.Conn, ret, pop3MultilineEndRegex)
+	if err != nil && err != io.EOF && !zgrab2.IsTimeoutError(err) {
+		return "", err
+	}
+	return string(ret[:n]), nil
+}
+
+// parseCapabilities returns the capability lines of a successful CAPA response.
+func parseCapabilities(response string) []string {
+	lines := strings.Split(strings.TrimRight(response, "\r\n"), "\n")
+	if !strings.HasPrefix(lines[0], "+OK") {
+		return nil
+	}
+	var capabilities []string
+	for _, line := range lines[1:] {
+		if line = strings.TrimSpace(line); line != "" && line != "." {
+			capabilities = append(capabilities, line)
+		}
+	}
+	return capabilities
+}
+
+// diffCapabilities returns the capabilities of after missing from before, and those of before missing from after.
+func diffCapabilities(before []string, after []string) (added []string, removed []string) {
+	for _, capability := range after {
+		if !slices.Contains(before, capability) {
+			added = append(added, capability)
+		}
+	}
+	for _, capability := range before {
+		if !slices.Contains(after, capability) {
+			removed = append(removed, capability)
+		}
+	}
+	return added, removed
+}
@@ -0,0 +1,38 @@
+package pop3
+
+import (
+	"net"
+	"slices"
+	"testing"
+)
+
+func TestSendMultilineCommand(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		server.Read(make([]byte, 64))
+		// the status line arrives apart from the capabilities
+		server.Write([]byte("+OK Capability list follows\r\n"))
+		server.Write([]byte("TOP\r\nUSER\r\nSTLS\r\n.\r\n"))
+		server.Read(make([]byte, 64))
+		server.Write([]byte("-ERR unknown command\r\n"))
+	}()
+	conn := Connection{Conn: client}
+	ret, err := conn.SendMultilineCommand("CAPA")
+	if err != nil || ret != "+OK Capability list follows\r\nTOP\r\nUSER\r\nSTLS\r\n.\r\n" {
+		t.Fatalf("SendMultilineCommand = %q, %v", ret, err)
+	}
+	before := parseCapabilities(ret)
+	if !slices.Equal(before, []string{"TOP", "USER", "STLS"}) {
+		t.Errorf("unexpected capabilities: %q", before)
+	}
+	ret, err = conn.SendMultilineCommand("CAPA")
+	if err != nil || parseCapabilities(ret) != nil {
+		t.Errorf("SendMultilineCommand of an error = %q, %v", ret, err)
+	}
+	added, removed := diffCapabilities(before, []string{"TOP", "USER", "SASL PLAIN"})
+	if !slices.Equal(added, []string{"SASL PLAIN"}) || !slices.Equal(removed, []string{"STLS"}) {
+		t.Errorf("unexpected diff: added %q, removed %q", added, removed)
+	}
+}
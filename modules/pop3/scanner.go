@@ -5,6 +5,10 @@
 // The --send-help and --send-noop flags tell the scanner to send a
 // HELP or NOOP command and read the response.
 //
+// The --send-capa flag tells the scanner to send a CAPA command, and
+// with --starttls to send it again after the upgrade and record how the
+// capabilities changed.
+//
 // The --pop3s flag tells the scanner to perform a TLS handshake
 // immediately after connecting, before even attempting to read
 // the banner.
@@ -49,9 +53,28 @@ type ScanResults struct {
 	// HELP is the server's response to the HELP command, if it is sent.
 	HELP string `json:"help,omitempty"`
 
+	// CAPA is the server's response to the CAPA command, if it is sent.
+	CAPA string `json:"capa,omitempty"`
+
+	// Capabilities are the capability lines of the CAPA response.
+	Capabilities []string `json:"capabilities,omitempty"`
+
 	// StartTLS is the server's response to the STARTTLS command, if it is sent.
 	StartTLS string `json:"starttls,omitempty"`
 
+	// CAPAAfterSTARTTLS is the server's response to the CAPA command sent again after STLS.
+	CAPAAfterSTARTTLS string `json:"capa_after_starttls,omitempty"`
+
+	// CapabilitiesAfterSTARTTLS are the capability lines of the CAPA response after STLS.
+	CapabilitiesAfterSTARTTLS []string `json:"capabilities_after_starttls,omitempty"`
+
+	// CapabilitiesAdded are the capabilities advertised after STLS only, like the SASL mechanisms of servers refusing
+	// to authenticate in the clear.
+	CapabilitiesAdded []string `json:"capabilities_added,omitempty"`
+
+	// CapabilitiesRemoved are the capabilities advertised before STLS only, like STLS itself.
+	CapabilitiesRemoved []string `json:"capabilities_removed,omitempty"`
+
 	// QUIT is the server's response to the QUIT command, if it is sent.
 	QUIT string `json:"quit,omitempty"`
 
@@ -71,6 +94,9 @@ type Flags struct {
 	// SendNOOP indicates that the NOOP command should be sent.
 	SendNOOP bool `long:"send-noop" description:"Send the NOOP command before closing."`
 
+	// SendCAPA indicates that the CAPA command should be sent, and again after STLS.
+	SendCAPA bool `long:"send-capa" description:"Send the CAPA command, and again after --starttls, recording how the capabilities changed."`
+
 	// SendQUIT indicates that the QUIT command should be sent.
 	SendQUIT bool `long:"send-quit" description:"Send the QUIT command before closing."`
 
@@ -210,10 +236,12 @@ func VerifyPOP3Contents(banner string) zgrab2.ScanStatus {
 //  3. Read the banner.
 //  4. If --send-help is sent, send HELP, read the result.
 //  5. If --send-noop is sent, send NOOP, read the result.
-//  6. If --starttls is sent, send STLS, read the result, negotiate a
-//     TLS connection using the command-line flags.
-//  7. If --send-quit is sent, send QUIT and read the result.
-//  8. Close the connection.
+//  6. If --send-capa is sent, send CAPA, read the result.
+//  7. If --starttls is sent, send STLS, read the result, negotiate a
+//     TLS connection using the command-line flags, and send CAPA again if
+//     --send-capa is sent.
+//  8. If --send-quit is sent, send QUIT and read the result.
+//  9. Close the connection.
 func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
 	// check for necessary dialers
 	l4Dialer := dialGroup.L4Dialer
@@ -267,6 +295,14 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 		}
 		result.NOOP = ret
 	}
+	if scanner.config.SendCAPA {
+		ret, err = conn.SendMultilineCommand("CAPA")
+		if err != nil {
+			return zgrab2.TryGetScanStatus(err), result, err
+		}
+		result.CAPA = ret
+		result.Capabilities = parseCapabilities(ret)
+	}
 	if scanner.config.StartTLS {
 		ret, err = conn.SendCommand("STLS")
 		if err != nil {
@@ -279,10 +315,24 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 		var tlsConn *zgrab2.TLSConnection
 		tlsConn, err = tlsWrapper(ctx, target, conn.Conn)
 		if err != nil {
-			return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error wrapping connection in TLS for target %s: %w", target.String(), err)
+			if tlsConn != nil {
+				// keep the certificates and parameters of a failed handshake
+				result.TLSLog = tlsConn.GetLog()
+			}
+			return zgrab2.TryGetScanStatus(err), result, fmt.Errorf("error wrapping connection in TLS for target %s: %w", target.String(), err)
 		}
 		result.TLSLog = tlsConn.GetLog()
 		conn.Conn = tlsConn
+		if scanner.config.SendCAPA {
+			// servers advertise other capabilities once the connection is secure
+			ret, err = conn.SendMultilineCommand("CAPA")
+			if err != nil {
+				return zgrab2.TryGetScanStatus(err), result, err
+			}
+			result.CAPAAfterSTARTTLS = ret
+			result.CapabilitiesAfterSTARTTLS = parseCapabilities(ret)
+			result.CapabilitiesAdded, result.CapabilitiesRemoved = diffCapabilities(result.Capabilities, result.CapabilitiesAfterSTARTTLS)
+		}
 	}
 	if scanner.config.SendQUIT {
 		ret, err = conn.SendCommand("QUIT")
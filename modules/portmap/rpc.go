@@ -0,0 +1,240 @@
+package portmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Programs, versions and procedures the scan calls.
+const (
+	programPortmapper = 100000
+	programMountd     = 100005
+
+	portmapperVersion = 2
+	procDump          = 4
+
+	// mountdMaxVersion is the highest mountd version the scan uses; EXPORT has the same form in versions 1 to 3.
+	mountdMaxVersion = 3
+	procExport       = 5
+)
+
+// IP protocol numbers of portmapper mappings.
+const (
+	protocolTCP = 6
+	protocolUDP = 17
+)
+
+// lastFragment marks the last fragment of a record over TCP (RFC 5531 section 11).
+const lastFragment = 0x80000000
+
+// maxRecordSize bounds the size of a reply over TCP.
+const maxRecordSize = 1 << 20
+
+// maxEntries bounds the entries of the lists the scan decodes.
+const maxEntries = 4096
+
+var errInvalidReply = errors.New("invalid RPC reply")
+
+// programNames names well-known ONC RPC programs.
+var programNames = map[uint32]string{
+	100000: "portmapper",
+	100001: "rstatd",
+	100002: "rusersd",
+	100003: "nfs",
+	100004: "ypserv",
+	100005: "mountd",
+	100007: "ypbind",
+	100008: "walld",
+	100009: "yppasswdd",
+	100011: "rquotad",
+	100012: "sprayd",
+	100021: "nlockmgr",
+	100024: "status",
+	100026: "bootparam",
+	100068: "cmsd",
+	100083: "ttdbserverd",
+	100227: "nfs_acl",
+	150001: "pcnfsd",
+	300019: "amd",
+}
+
+// buildCall returns a call with AUTH_NONE credentials and the given encoded arguments.
+func buildCall(xid, program, version, procedure uint32, args []byte) []byte {
+	b := make([]byte, 0, 40+len(args))
+	for _, v := range []uint32{xid, 0, 2, program, version, procedure, 0, 0, 0, 0} {
+		b = binary.BigEndian.AppendUint32(b, v)
+	}
+	return append(b, args...)
+}
+
+// frame wraps a message in a single-fragment record, for TCP.
+func frame(msg []byte) []byte {
+	return append(binary.BigEndian.AppendUint32(nil, lastFragment|uint32(len(msg))), msg...)
+}
+
+// readRecord reads a record over TCP, joining its fragments.
+func readRecord(r io.Reader) ([]byte, error) {
+	var record []byte
+	for {
+		var header uint32
+		if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+			return nil, err
+		}
+		length := header &^ lastFragment
+		if len(record)+int(length) > maxRecordSize {
+			return nil, fmt.Errorf("%w: record longer than %d bytes", errInvalidReply, maxRecordSize)
+		}
+		fragment := make([]byte, length)
+		if _, err := io.ReadFull(r, fragment); err != nil {
+			return nil, err
+		}
+		record = append(record, fragment...)
+		if header&lastFragment != 0 {
+			return record, nil
+		}
+	}
+}
+
+// acceptStatNames and rejectStatNames name the errors of accepted and denied replies.
+var (
+	acceptStatNames = []string{"SUCCESS", "PROG_UNAVAIL", "PROG_MISMATCH", "PROC_UNAVAIL", "GARBAGE_ARGS", "SYSTEM_ERR"}
+	rejectStatNames = []string{"RPC_MISMATCH", "AUTH_ERROR"}
+)
+
+// ReplyError is an RPC reply other than a successful one.
+type ReplyError struct {
+	Denied bool
+	Status uint32
+}
+
+func (err *ReplyError) Error() string {
+	names := acceptStatNames
+	if err.Denied {
+		names = rejectStatNames
+	}
+	if int(err.Status) < len(names) {
+		return "RPC call failed: " + names[err.Status]
+	}
+	return fmt.Sprintf("RPC call failed with status %d", err.Status)
+}
+
+// xdrReader decodes XDR data.
+type xdrReader struct {
+	b   []byte
+	err error
+}
+
+func (r *xdrReader) uint32() uint32 {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.b) < 4 {
+		r.err = errInvalidReply
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.b)
+	r.b = r.b[4:]
+	return v
+}
+
+// opaque reads variable-length opaque data or a string, with its padding.
+func (r *xdrReader) opaque() []byte {
+	length := r.uint32()
+	padded := (uint64(length) + 3) &^ 3
+	if r.err != nil {
+		return nil
+	}
+	if uint64(len(r.b)) < padded {
+		r.err = errInvalidReply
+		return nil
+	}
+	v := r.b[:length]
+	r.b = r.b[padded:]
+	return v
+}
+
+// parseReply checks the reply header and returns a reader of the results.
+func parseReply(xid uint32, data []byte) (*xdrReader, error) {
+	r := &xdrReader{b: data}
+	if r.uint32() != xid || r.uint32() != 1 {
+		return nil, fmt.Errorf("%w: not a reply to the call", errInvalidReply)
+	}
+	if r.uint32() != 0 {
+		return nil, &ReplyError{Denied: true, Status: r.uint32()}
+	}
+	r.uint32() // verifier flavor
+	r.opaque()
+	if status := r.uint32(); r.err == nil && status != 0 {
+		return nil, &ReplyError{Status: status}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r, nil
+}
+
+// matchesReply returns a UDP probe match function accepting replies to the call with the given xid.
+func matchesReply(xid uint32) func(_, response []byte) bool {
+	return func(_, response []byte) bool {
+		return len(response) >= 8 && binary.BigEndian.Uint32(response) == xid && binary.BigEndian.Uint32(response[4:]) == 1
+	}
+}
+
+// Program is a mapping registered with the portmapper.
+type Program struct {
+	Program  uint32 `json:"program"`
+	Name     string `json:"name,omitempty"`
+	Version  uint32 `json:"version"`
+	Protocol string `json:"protocol"`
+	Port     uint32 `json:"port"`
+}
+
+// parseDump decodes the mappings of a PMAPPROC_DUMP reply.
+func parseDump(r *xdrReader) ([]Program, error) {
+	var programs []Program
+	for r.uint32() == 1 && len(programs) < maxEntries {
+		p := Program{Program: r.uint32(), Version: r.uint32()}
+		switch protocol := r.uint32(); protocol {
+		case protocolTCP:
+			p.Protocol = "tcp"
+		case protocolUDP:
+			p.Protocol = "udp"
+		default:
+			p.Protocol = fmt.Sprintf("%d", protocol)
+		}
+		p.Port = r.uint32()
+		p.Name = programNames[p.Program]
+		programs = append(programs, p)
+	}
+	return programs, r.err
+}
+
+// Export is a file system exported by mountd.
+type Export struct {
+	Directory string   `json:"directory"`
+	Groups    []string `json:"groups,omitempty"`
+
+	// WorldReadable is true if the file system is exported to any host: without a host list, or to "*".
+	WorldReadable bool `json:"world_readable"`
+}
+
+// parseExports decodes the exports of a MOUNTPROC_EXPORT reply.
+func parseExports(r *xdrReader) ([]Export, error) {
+	var exports []Export
+	for r.uint32() == 1 && len(exports) < maxEntries {
+		e := Export{Directory: string(r.opaque())}
+		for r.uint32() == 1 && len(e.Groups) < maxEntries {
+			e.Groups = append(e.Groups, string(r.opaque()))
+		}
+		e.WorldReadable = len(e.Groups) == 0
+		for _, group := range e.Groups {
+			if group == "*" {
+				e.WorldReadable = true
+			}
+		}
+		exports = append(exports, e)
+	}
+	return exports, r.err
+}
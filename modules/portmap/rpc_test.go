@@ -0,0 +1,103 @@
+package portmap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// reply builds an accepted reply with the given results.
+func reply(xid, status uint32, results ...uint32) []byte {
+	var b []byte
+	for _, v := range append([]uint32{xid, 1, 0, 0, 0, status}, results...) {
+		b = binary.BigEndian.AppendUint32(b, v)
+	}
+	return b
+}
+
+// xdrString encodes a string as XDR words.
+func xdrString(s string) []uint32 {
+	words := []uint32{uint32(len(s))}
+	padded := append([]byte(s), make([]byte, (4-len(s)%4)%4)...)
+	for i := 0; i < len(padded); i += 4 {
+		words = append(words, binary.BigEndian.Uint32(padded[i:]))
+	}
+	return words
+}
+
+func TestParseDump(t *testing.T) {
+	data := reply(42, 0,
+		1, 100000, 2, 6, 111,
+		1, 100005, 3, 17, 20048,
+		0)
+	r, err := parseReply(42, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	programs, err := parseDump(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Program{
+		{Program: 100000, Name: "portmapper", Version: 2, Protocol: "tcp", Port: 111},
+		{Program: 100005, Name: "mountd", Version: 3, Protocol: "udp", Port: 20048},
+	}
+	if !reflect.DeepEqual(programs, expected) {
+		t.Errorf("got %+v", programs)
+	}
+	scanner := &Scanner{config: &Flags{UDP: true}}
+	if port, version := scanner.mountd(programs); port != 20048 || version != 3 {
+		t.Errorf("got mountd port %d version %d", port, version)
+	}
+}
+
+func TestParseExports(t *testing.T) {
+	var words []uint32
+	words = append(words, 1)
+	words = append(words, xdrString("/srv/public")...)
+	words = append(words, 0, 1)
+	words = append(words, xdrString("/srv/home")...)
+	words = append(words, 1)
+	words = append(words, xdrString("10.0.0.0/8")...)
+	words = append(words, 0, 0)
+	r, err := parseReply(7, reply(7, 0, words...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exports, err := parseExports(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Export{
+		{Directory: "/srv/public", WorldReadable: true},
+		{Directory: "/srv/home", Groups: []string{"10.0.0.0/8"}},
+	}
+	if !reflect.DeepEqual(exports, expected) {
+		t.Errorf("got %+v", exports)
+	}
+}
+
+func TestParseReplyErrors(t *testing.T) {
+	var replyErr *ReplyError
+	if _, err := parseReply(1, reply(1, 1)); !errors.As(err, &replyErr) || replyErr.Error() != "RPC call failed: PROG_UNAVAIL" {
+		t.Errorf("got %v", err)
+	}
+	if _, err := parseReply(2, reply(1, 0)); !errors.Is(err, errInvalidReply) {
+		t.Errorf("got %v for a mismatched xid", err)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	// a record split over two fragments
+	msg := buildCall(1, programPortmapper, portmapperVersion, procDump, nil)
+	var b []byte
+	b = binary.BigEndian.AppendUint32(b, 8)
+	b = append(b, msg[:8]...)
+	b = append(b, frame(msg[8:])...)
+	record, err := readRecord(bytes.NewReader(b))
+	if err != nil || !bytes.Equal(record, msg) {
+		t.Errorf("got %x, error %v", record, err)
+	}
+}
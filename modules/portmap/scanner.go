@@ -0,0 +1,230 @@
+// Package portmap contains the zgrab2 Module implementation for the ONC RPC portmapper (rpcbind).
+//
+// The scan calls PMAPPROC_DUMP to list the programs registered with the portmapper, with their versions,
+// protocols and ports. With --exports, it then calls MOUNTPROC_EXPORT on mountd, over the same transport, to list
+// the NFS exports and the hosts they are exported to; an export without a host list is open to any host.
+package portmap
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	Programs []Program `json:"programs,omitempty"`
+
+	// MountdPort is the port of the mountd queried for exports.
+	MountdPort uint32   `json:"mountd_port,omitempty"`
+	Exports    []Export `json:"exports,omitempty"`
+
+	// Probes records the calls, if the scan was made over UDP.
+	Probes []*zgrab2.UDPProbeResult `json:"probes,omitempty"`
+}
+
+// Flags are the portmapper-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.UDPFlags
+
+	UDP     bool `long:"udp" description:"Send the calls over UDP instead of TCP"`
+	Exports bool `long:"exports" description:"List the NFS exports from mountd"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the portmap zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("portmap", "SunRPC portmapper (rpcbind)", module.Description(), 111, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "List the programs registered with an ONC RPC portmapper and, optionally, the NFS exports"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return f.UDPFlags.Validate()
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "portmap"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		NeedSeparateL4Dialer:            true,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// network returns the transport of the calls.
+func (scanner *Scanner) network() string {
+	if scanner.config.UDP {
+		return "udp"
+	}
+	return "tcp"
+}
+
+// call makes an RPC call on conn and returns a reader of its results.
+func (scanner *Scanner) call(ctx context.Context, conn net.Conn, target *zgrab2.ScanTarget, name string, program, version, procedure uint32, results *ScanResults) (*xdrReader, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	xid := binary.BigEndian.Uint32(b)
+	msg := buildCall(xid, program, version, procedure, nil)
+	var reply []byte
+	if scanner.config.UDP {
+		result, err := zgrab2.SendUDPProbe(ctx, conn, zgrab2.NewStaticUDPProbe(name, msg, matchesReply(xid)), target, &scanner.config.UDPFlags)
+		results.Probes = append(results.Probes, result)
+		if err != nil {
+			return nil, err
+		}
+		reply = result.Response
+	} else {
+		if _, err := conn.Write(frame(msg)); err != nil {
+			return nil, err
+		}
+		var err error
+		if reply, err = readRecord(conn); err != nil {
+			return nil, err
+		}
+	}
+	return parseReply(xid, reply)
+}
+
+// mountd returns the port and version of the mountd registered for the scan's transport, preferring the highest
+// version up to 3, or 0 if there is none.
+func (scanner *Scanner) mountd(programs []Program) (port uint32, version uint32) {
+	for _, p := range programs {
+		if p.Program == programMountd && p.Protocol == scanner.network() && p.Version <= mountdMaxVersion && p.Version > version {
+			port, version = p.Port, p.Version
+		}
+	}
+	return port, version
+}
+
+// listExports calls MOUNTPROC_EXPORT on mountd.
+func (scanner *Scanner) listExports(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget, port, version uint32, results *ScanResults) error {
+	conn, err := dialGroup.L4Dialer(target)(ctx, scanner.network(), net.JoinHostPort(target.Host(), strconv.Itoa(int(port))))
+	if err != nil {
+		return err
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+	r, err := scanner.call(ctx, conn, target, "export", programMountd, version, procExport, results)
+	if err != nil {
+		return err
+	}
+	results.Exports, err = parseExports(r)
+	return err
+}
+
+// Scan performs the configured scan on the portmapper.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.L4Dialer(target)(ctx, scanner.network(), net.JoinHostPort(target.Host(), strconv.Itoa(int(target.Port))))
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	results := new(ScanResults)
+	r, err := scanner.call(ctx, conn, target, "dump", programPortmapper, portmapperVersion, procDump, results)
+	if err == nil {
+		results.Programs, err = parseDump(r)
+	}
+	if err != nil {
+		var replyErr *ReplyError
+		if errors.As(err, &replyErr) || errors.Is(err, errInvalidReply) || errors.Is(err, io.ErrUnexpectedEOF) {
+			err = zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+		}
+		var partial any
+		if len(results.Probes) > 0 {
+			partial = results
+		}
+		return zgrab2.TryGetScanStatus(err), partial, fmt.Errorf("error dumping the portmapper of target %s: %w", target.String(), err)
+	}
+
+	if scanner.config.Exports {
+		port, version := scanner.mountd(results.Programs)
+		if port == 0 {
+			log.Debugf("target %s has no mountd registered for %s", target.String(), scanner.network())
+			return zgrab2.SCAN_SUCCESS, results, nil
+		}
+		results.MountdPort = port
+		if err = scanner.listExports(ctx, dialGroup, target, port, version, results); err != nil {
+			log.Debugf("could not list the exports of target %s: %v", target.String(), err)
+		}
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
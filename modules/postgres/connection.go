@@ -37,6 +37,10 @@ type Connection struct {
 
 	// IsSSL is true if Connection is a TLS connection.
 	IsSSL bool
+
+	// RequestError is the 'E'-type packet the server rejected the last
+	// SSLRequest or GSSENCRequest with, if it did.
+	RequestError *ServerPacket
 }
 
 // ServerPacket is a direct representation of the response packet
@@ -170,7 +174,22 @@ func (c *Connection) tryReadPacket(header byte) (*ServerPacket, *zgrab2.ScanErro
 // it returns false and possibly an error.
 func (c *Connection) RequestSSL() (bool, *zgrab2.ScanError) {
 	// NOTE: The SSLRequest request type was introduced in version 7.2, released in 2002 (though the oldest supported version is 9.3, released 2013-09-09)
-	if err := c.SendU32(postgresSSLRequest); err != nil {
+	return c.requestEncryption(postgresSSLRequest, 'S')
+}
+
+// RequestGSSEncryption sends a GSSENCRequest packet to the server, and
+// returns true if and only if the server reports that it supports GSSAPI
+// encryption. Otherwise it returns false and possibly an error; servers
+// older than version 12 reject the request with an error.
+func (c *Connection) RequestGSSEncryption() (bool, *zgrab2.ScanError) {
+	return c.requestEncryption(postgresGSSENCRequest, 'G')
+}
+
+// requestEncryption sends the given request code to the server, and
+// returns true if and only if the server answers with the single byte
+// accepted, or false for a single 'N'.
+func (c *Connection) requestEncryption(code uint32, accepted byte) (bool, *zgrab2.ScanError) {
+	if err := c.SendU32(code); err != nil {
 		return false, zgrab2.DetectScanError(err)
 	}
 	var header [1]byte
@@ -186,19 +205,20 @@ func (c *Connection) RequestSSL() (bool, *zgrab2.ScanError) {
 	switch header[0] {
 	case 'N':
 		return false, nil
-	case 'S':
+	case accepted:
 		return true, nil
 	}
-	// It was neither a single 'N' / 'S', so it's a failure -- at this point it's just a question of determining if it's an application error (valid packet) or a protocol error
+	// It was neither a single 'N' / accepted, so it's a failure -- at this point it's just a question of determining if it's an application error (valid packet) or a protocol error
 	packet, scanError := c.tryReadPacket(header[0])
 	if scanError != nil {
 		return false, scanError
 	}
 	switch packet.Type {
 	case 'E':
-		return false, zgrab2.NewScanError(zgrab2.SCAN_APPLICATION_ERROR, fmt.Errorf("application rejected SSLRequest packet -- response = %s", packet.ToString()))
+		c.RequestError = packet
+		return false, zgrab2.NewScanError(zgrab2.SCAN_APPLICATION_ERROR, fmt.Errorf("application rejected request 0x%08x -- response = %s", code, packet.ToString()))
 	default:
-		// Returning PROTOCOL_ERROR here since any garbage data that starts with a small-ish u32 could be a valid packet, and no known server versions return anything beyond S/N/E.
+		// Returning PROTOCOL_ERROR here since any garbage data that starts with a small-ish u32 could be a valid packet, and no known server versions return anything beyond the single bytes and E.
 		return false, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, fmt.Errorf("unexpected response type '%c' from server (full response = %s)", packet.Type, packet.ToString()))
 
 	}
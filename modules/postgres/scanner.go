@@ -1,7 +1,7 @@
 // Package postgres contains the postgres zgrab2 Module implementation.
-// The Scan does three (or four -- see below) consecutive connections to
-// the server, using different StartupMessages each time, and adds the
-// server's response to each to the output.
+// The Scan does up to five (see below) consecutive connections to the
+// server, using different requests and StartupMessages each time, and
+// adds the server's response to each to the output.
 // If any of database/user/application-name are specified on the command
 // line, the fourth StartupMessage is sent with the provided data. This
 // may allow additional data, such as detailed server parameters, to be
@@ -15,6 +15,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -28,6 +29,12 @@ import (
 const (
 	// From https://www.postgresql.org/docs/10/static/protocol-message-formats.html: "The SSL request code. The value is chosen to contain 1234 in the most significant 16 bits, and 5679 in the least significant 16 bits. (To avoid confusion, this code must not be the same as any protocol version number.)"
 	postgresSSLRequest = 80877103
+
+	// From the same page: "The GSSAPI Encryption request code. The value is chosen to contain 1234 in the most significant 16 bits, and 5680 in the least significant 16 bits."
+	postgresGSSENCRequest = 80877104
+
+	// The SASL mechanism with channel binding, see https://www.postgresql.org/docs/current/sasl-authentication.html
+	scramSHA256Plus = "SCRAM-SHA-256-PLUS"
 )
 
 const (
@@ -81,6 +88,39 @@ type Results struct {
 	// TransactionStatus is the value of the 'Z'-type packet returned by
 	// the server after the final StartupMessage.
 	TransactionStatus string `json:"transaction_status,omitempty"`
+
+	// ParameterStatus lists the 'S'-type packets returned after the final
+	// StartupMessage, in the order the server sent them.
+	ParameterStatus []ParameterStatus `json:"parameter_status,omitempty"`
+
+	// SASLMechanisms lists the mechanisms of the AuthenticationSASL packet
+	// returned after the final StartupMessage, if the server asks for SASL
+	// authentication.
+	SASLMechanisms []string `json:"sasl_mechanisms,omitempty"`
+
+	// SupportsChannelBinding is true if SASLMechanisms includes
+	// SCRAM-SHA-256-PLUS, which servers only offer over TLS.
+	SupportsChannelBinding bool `json:"supports_channel_binding,omitempty"`
+
+	// GSSEncryption is the response to a GSSENCRequest, unless
+	// --skip-gss is set.
+	GSSEncryption *GSSEncryption `json:"gss_encryption,omitempty"`
+}
+
+// ParameterStatus is the name and value of an 'S'-type packet.
+type ParameterStatus struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// GSSEncryption is the response of the server to a GSSENCRequest.
+type GSSEncryption struct {
+	// Supported is true if the server is willing to use GSSAPI encryption.
+	Supported bool `json:"supported"`
+
+	// Error is the error the server rejected the request with, as servers
+	// before version 12 do.
+	Error *PostgresError `json:"error,omitempty"`
 }
 
 // PostgresError is parsed the payload of an 'E'-type packet, mapping
@@ -123,6 +163,7 @@ type Flags struct {
 	zgrab2.BaseFlags `group:"Basic Options"`
 	zgrab2.TLSFlags  `group:"TLS Options"`
 	SkipSSL          bool   `long:"skip-ssl" description:"If set, do not attempt to negotiate an SSL connection"`
+	SkipGSS          bool   `long:"skip-gss" description:"If set, do not send a GSSENCRequest to check for GSSAPI encryption support"`
 	ProtocolVersion  string `long:"protocol-version" description:"The protocol to use in the StartupPacket" default:"3.0"`
 	User             string `long:"user" description:"Username to pass to StartupMessage. If omitted, no user will be sent." default:""`
 	Database         string `long:"database" description:"Database to pass to StartupMessage. If omitted, none will be sent." default:""`
@@ -208,6 +249,18 @@ func decodeError(buf []byte) *PostgresError {
 	return &ret
 }
 
+// decodeSASLMechanisms() decodes the payload of an AuthenticationSASL packet, a list of NUL-terminated mechanism names
+func decodeSASLMechanisms(payload []byte) []string {
+	var ret []string
+	for _, mechanism := range strings.Split(string(payload), "\x00") {
+		if mechanism == "" {
+			break
+		}
+		ret = append(ret, mechanism)
+	}
+	return ret
+}
+
 // appendStringList() adds an entry to a semicolon-separated list; if the list is empty, no semicolon is added.
 func appendStringList(dest string, val string) string {
 	if dest == "" {
@@ -231,6 +284,7 @@ func (results *Results) decodeServerResponse(packets []*ServerPacket) {
 			parts := strings.Split(string(packet.Body), "\x00")
 			if len(parts) == 2 || (len(parts) == 3 && len(parts[2]) == 0) {
 				serverParams[parts[0]] = parts[1]
+				results.ParameterStatus = append(results.ParameterStatus, ParameterStatus{Name: parts[0], Value: parts[1]})
 			} else {
 				log.Debugf("Unexpected format for ParameterStatus packet (%d parts)", len(parts))
 				serverParams.appendBadParam(packet)
@@ -255,7 +309,16 @@ func (results *Results) decodeServerResponse(packets []*ServerPacket) {
 				results.TransactionStatus = string(packet.Body[0])
 			}
 		case 'R':
+			if len(packet.Body) < 4 {
+				log.Debugf("Bad size for Authentication (%d)", packet.Length)
+				serverParams.appendBadParam(packet)
+				continue
+			}
 			results.AuthenticationMode = decodeAuthMode(packet.Body)
+			if results.AuthenticationMode.Mode == "sasl" {
+				results.SASLMechanisms = decodeSASLMechanisms(results.AuthenticationMode.Payload)
+				results.SupportsChannelBinding = slices.Contains(results.SASLMechanisms, scramSHA256Plus)
+			}
 		case 'E':
 			results.UserStartupError = decodeError(packet.Body)
 		default:
@@ -354,6 +417,10 @@ func (scanner *Scanner) DoSSL(ctx context.Context, sql *Connection, dialGroup *z
 		return errors.New("dial group does not have a TLS wrapper")
 	}
 	if conn, err = tlsWrapper(ctx, sql.Target, sql.Connection); err != nil {
+		if conn != nil {
+			// Keep the failed handshake, so that its log can be returned
+			sql.Connection = conn
+		}
 		return fmt.Errorf("could not wrap connection in TLS to %s: %w", sql.Target.String(), err)
 	}
 	// Replace sql.Connection to allow future calls to go over the secure connection
@@ -362,6 +429,7 @@ func (scanner *Scanner) DoSSL(ctx context.Context, sql *Connection, dialGroup *z
 }
 
 // newConnection opens up a new connection to the ScanTarget, and if necessary, attempts to update the connection to SSL
+// If the TLS handshake fails, the connection is returned along with the error, so that the TLS log can be recorded
 func (scanner *Scanner) newConnection(ctx context.Context, target *zgrab2.ScanTarget, mgr *connectionManager, useSSL bool, dialGroup *zgrab2.DialerGroup) (*Connection, *zgrab2.ScanError) {
 	var conn net.Conn
 	var err error
@@ -383,7 +451,7 @@ func (scanner *Scanner) newConnection(ctx context.Context, target *zgrab2.ScanTa
 		}
 		if hasSSL {
 			if err = scanner.DoSSL(ctx, &sql, dialGroup); err != nil {
-				return nil, zgrab2.NewScanError(zgrab2.SCAN_APPLICATION_ERROR, err)
+				return &sql, zgrab2.NewScanError(zgrab2.SCAN_APPLICATION_ERROR, err)
 			}
 			sql.IsSSL = true
 		}
@@ -391,6 +459,26 @@ func (scanner *Scanner) newConnection(ctx context.Context, target *zgrab2.ScanTa
 	return &sql, nil
 }
 
+// requestGSSEncryption sends a GSSENCRequest on a new connection and returns the server's response, or nil on failure
+func (scanner *Scanner) requestGSSEncryption(ctx context.Context, target *zgrab2.ScanTarget, mgr *connectionManager, dialGroup *zgrab2.DialerGroup) *GSSEncryption {
+	sql, connectErr := scanner.newConnection(ctx, target, mgr, false, dialGroup)
+	if connectErr != nil {
+		log.Debugf("Error connecting to %s for GSSENCRequest: %v", target.String(), connectErr)
+		return nil
+	}
+	defer mgr.closeConnection(sql)
+	supported, requestErr := sql.RequestGSSEncryption()
+	if requestErr != nil {
+		if sql.RequestError == nil {
+			log.Debugf("Error sending GSSENCRequest to %s: %v", target.String(), requestErr)
+			return nil
+		}
+		return &GSSEncryption{Error: decodeError(sql.RequestError.Body)}
+	}
+	// Nothing more is done on this connection, since zgrab2 does not implement GSSAPI
+	return &GSSEncryption{Supported: supported}
+}
+
 // Return the default KVPs used for all Startup messages
 func (scanner *Scanner) getDefaultKVPs() map[string]string {
 	return map[string]string{
@@ -399,28 +487,36 @@ func (scanner *Scanner) getDefaultKVPs() map[string]string {
 	}
 }
 
-// Scan does the actual scanning. It opens up to four connections:
+// Scan does the actual scanning. It opens up to five connections:
 //
 //  1. Sends a bogus protocol version in hopes of getting a list of
 //     supported protcols back. Results here are supported_versions and
 //     and tls (* if applicable).
 //
-//  2. Send a too-high protocol version (255.255) to get full error
+//     If the TLS handshake fails, the connection is retried without TLS,
+//     keeping the tls log of the failed handshake.
+//
+//  2. Unless --skip-gss is set, send a GSSENCRequest to check whether the
+//     server supports GSSAPI encryption. This is where it gets the
+//     gss_encryption result.
+//
+//  3. Send a too-high protocol version (255.255) to get full error
 //     message, including line numbers, which could be useful for probing
 //     server version. This is where it gets the protcol_error result.
 //
-//  3. Send a StartupMessage with a valid protocol version (by default
+//  4. Send a StartupMessage with a valid protocol version (by default
 //     3.0, but this can be overridden on the command line), but omit the
 //     user field. This is where it gets the startup_error result.
 //
-//  4. Only sent if at least one of user/database/application-name
-//     command line flags are provided. Does the same as #3, but includes
+//  5. Only sent if at least one of user/database/application-name
+//     command line flags are provided. Does the same as #4, but includes
 //     any/all of user/database/application-name. This is where it gets
-//     backend_key_data, server_parameters, authentication_mode,
+//     backend_key_data, server_parameters, parameter_status,
+//     authentication_mode, sasl_mechanisms, supports_channel_binding,
 //     transaction_status and user_startup_error.
 //
-//     - NOTE: TLS is only used for the first connection, and then only if
-//     both client and server support it.
+//     - NOTE: TLS is only used for the first and last connections, and
+//     then only if both client and server support it.
 func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
 	var results Results
 
@@ -434,6 +530,12 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 		sql, connectErr := scanner.newConnection(ctx, target, mgr, useSSL, dialGroup)
 		if connectErr != nil {
 			if connectErr.Status == zgrab2.SCAN_APPLICATION_ERROR {
+				if sql != nil {
+					// Keep the certificates of a failed TLS handshake
+					if tlsConn, ok := sql.Connection.(*zgrab2.TLSConnection); ok {
+						results.TLSLog = tlsConn.GetLog()
+					}
+				}
 				continue
 			}
 			return connectErr.Unpack(nil)
@@ -445,7 +547,6 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 			results.TLSLog = sql.GetTLSLog()
 		} else {
 			results.IsSSL = false
-			results.TLSLog = nil
 		}
 		// Do SSL the first round, so that if we bail, we still have the TLS logs
 
@@ -473,6 +574,11 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 		break
 	}
 
+	// Check whether the server supports GSSAPI encryption; failures here do not fail the scan
+	if !scanner.Config.SkipGSS {
+		results.GSSEncryption = scanner.requestGSSEncryption(ctx, target, mgr, dialGroup)
+	}
+
 	// Send too-high protocol version (255.255) StartupMessage to get full error message (including line numbers, useful for probing server version)
 	{
 		sql, connectErr := scanner.newConnection(ctx, target, mgr, false, dialGroup)
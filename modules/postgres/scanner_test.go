@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"slices"
+	"testing"
+
+	"github.com/zmap/zgrab2"
+)
+
+func TestDecodeServerResponse(t *testing.T) {
+	sasl := []byte{0, 0, 0, 10}
+	sasl = append(sasl, "SCRAM-SHA-256-PLUS\x00SCRAM-SHA-256\x00\x00"...)
+	var results Results
+	results.decodeServerResponse([]*ServerPacket{
+		{Type: 'S', Length: 24, Body: []byte("server_version\x0016.2\x00")},
+		{Type: 'S', Length: 18, Body: []byte("TimeZone\x00UTC\x00")},
+		{Type: 'R', Length: uint32(len(sasl) + 4), Body: sasl},
+	})
+	if !slices.Equal(results.ParameterStatus, []ParameterStatus{{"server_version", "16.2"}, {"TimeZone", "UTC"}}) {
+		t.Errorf("parameter status = %v", results.ParameterStatus)
+	}
+	if (*results.ServerParameters)["server_version"] != "16.2" {
+		t.Errorf("server parameters = %v", *results.ServerParameters)
+	}
+	if results.AuthenticationMode.Mode != "sasl" || !slices.Equal(results.SASLMechanisms, []string{"SCRAM-SHA-256-PLUS", "SCRAM-SHA-256"}) || !results.SupportsChannelBinding {
+		t.Errorf("SASL = %v, %v, %v", results.AuthenticationMode, results.SASLMechanisms, results.SupportsChannelBinding)
+	}
+}
+
+func TestRequestGSSEncryption(t *testing.T) {
+	errorResponse := []byte("E\x00\x00\x00\x21SFATAL\x00C0A000\x00Munsupported\x00\x00")
+	binary.BigEndian.PutUint32(errorResponse[1:], uint32(len(errorResponse)-1))
+	for name, test := range map[string]struct {
+		response  []byte
+		supported bool
+		rejected  bool
+	}{
+		"supported":   {response: []byte("G"), supported: true},
+		"unsupported": {response: []byte("N")},
+		"old server":  {response: errorResponse, rejected: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			go func() {
+				defer server.Close()
+				var request [8]byte
+				if _, err := io.ReadFull(server, request[:]); err != nil || binary.BigEndian.Uint32(request[4:]) != postgresGSSENCRequest {
+					return
+				}
+				server.Write(test.response)
+			}()
+			sql := Connection{Target: &zgrab2.ScanTarget{}, Connection: client}
+			supported, err := sql.RequestGSSEncryption()
+			if supported != test.supported || (err != nil) != test.rejected {
+				t.Errorf("RequestGSSEncryption = %v, %v", supported, err)
+			}
+			if test.rejected && (sql.RequestError == nil || (*decodeError(sql.RequestError.Body))["code"] != "0A000") {
+				t.Errorf("request error = %v", sql.RequestError)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Conn is a QUIC version 1 client connection with a completed handshake, for modules that exchange application
+// data over QUIC, such as HTTP/3. It implements only what a scan needs: it opens streams, queues data on them, and
+// collects what the server sends. Nothing is retransmitted until the server goes silent, at which point all data
+// is sent again.
+type Conn struct {
+	h *handshake
+
+	tryTimeout time.Duration
+	retries    int
+
+	// nextBidi and nextUni are the IDs of the next streams the client opens.
+	nextBidi, nextUni uint64
+}
+
+// CloseError is returned when the server closes the connection.
+type CloseError struct {
+	Close *ConnectionClose
+}
+
+func (e *CloseError) Error() string {
+	name := e.Close.ErrorName
+	if name == "" {
+		name = fmt.Sprintf("%#x", e.Close.ErrorCode)
+	}
+	if e.Close.Reason != "" {
+		return fmt.Sprintf("server closed the connection with %s: %s", name, e.Close.Reason)
+	}
+	return "server closed the connection with " + name
+}
+
+// Dial performs the QUIC version 1 handshake over conn, a connected UDP socket. The server may stay silent for
+// tryTimeout on retries+1 consecutive attempts before Dial, and later Run, give up. The certificate is not
+// verified unless config asks for it.
+func Dial(ctx context.Context, conn net.Conn, config *tls.Config, tryTimeout time.Duration, retries int) (*Conn, error) {
+	h, err := newHandshake(ctx, conn, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.run(ctx, tryTimeout, retries); err != nil {
+		h.tls.Close()
+		return nil, err
+	}
+	if h.close != nil {
+		h.tls.Close()
+		return nil, &CloseError{Close: h.close}
+	}
+	return &Conn{h: h, tryTimeout: tryTimeout, retries: retries, nextUni: streamUnidirectional}, nil
+}
+
+// ConnectionState returns the state of the TLS handshake.
+func (c *Conn) ConnectionState() tls.ConnectionState {
+	return c.h.tls.ConnectionState()
+}
+
+// TransportParameters returns the server's transport parameters.
+func (c *Conn) TransportParameters() (*TransportParameters, error) {
+	return c.h.parameters()
+}
+
+// OpenStream returns the ID of a new client-initiated stream.
+func (c *Conn) OpenStream(bidirectional bool) uint64 {
+	next := &c.nextUni
+	if bidirectional {
+		next = &c.nextBidi
+	}
+	id := *next
+	*next += 4
+	c.h.getStream(id)
+	return id
+}
+
+// Write queues data to send on a stream, and closes the stream for sending if fin is set. The data is sent by the
+// next call to Run.
+func (c *Conn) Write(id uint64, data []byte, fin bool) {
+	st := c.h.getStream(id)
+	st.send = append(st.send, data...)
+	st.sendFin = st.sendFin || fin
+}
+
+// Run sends the queued data and collects the server's until done returns true. It returns a *CloseError if the
+// server closes the connection, and a timeout error if the server stays silent.
+func (c *Conn) Run(ctx context.Context, done func() bool) error {
+	if err := c.h.exchange(ctx, c.tryTimeout, c.retries, done); err != nil {
+		return err
+	}
+	if c.h.close != nil && !done() {
+		return &CloseError{Close: c.h.close}
+	}
+	return nil
+}
+
+// Stream returns the data received on a stream so far, and whether the server finished sending on it, either
+// regularly or by resetting the stream.
+func (c *Conn) Stream(id uint64) (data []byte, done bool) {
+	st, ok := c.h.streams[id]
+	if !ok {
+		return nil, false
+	}
+	return st.data, st.done()
+}
+
+// PeerStreams returns the IDs of the streams opened by the server, in the order their first data arrived.
+func (c *Conn) PeerStreams() []uint64 {
+	return c.h.peerStreams
+}
+
+// ConnectionClose returns the CONNECTION_CLOSE frame the server sent, if any.
+func (c *Conn) ConnectionClose() *ConnectionClose {
+	return c.h.close
+}
+
+// Close sends a CONNECTION_CLOSE frame with no error, unless the server closed the connection, and releases the
+// TLS state.
+func (c *Conn) Close() {
+	c.h.closeConnection()
+}
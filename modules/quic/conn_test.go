@@ -0,0 +1,241 @@
+package quic
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// testServer is a minimal QUIC server: it completes the handshake and echoes the data of each bidirectional stream
+// back once the client finishes it.
+type testServer struct {
+	t    *testing.T
+	pc   net.PacketConn
+	peer net.Addr
+	tls  *tls.QUICConn
+
+	cid, clientCID []byte
+	spaces         [numSpaces]space
+	received       map[uint64][]byte
+	echoed         map[uint64]bool
+	sentDone       bool
+}
+
+func testCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func (s *testServer) drainEvents() {
+	for {
+		event := s.tls.NextEvent()
+		switch event.Kind {
+		case tls.QUICNoEvent:
+			return
+		case tls.QUICSetReadSecret, tls.QUICSetWriteSecret:
+			i := spaceHandshake
+			if event.Level == tls.QUICEncryptionLevelApplication {
+				i = spaceApplication
+			}
+			k, err := newKeys(event.Suite, event.Data)
+			if err != nil {
+				s.t.Error(err)
+				return
+			}
+			if event.Kind == tls.QUICSetReadSecret {
+				s.spaces[i].read = k
+			} else {
+				s.spaces[i].write = k
+			}
+		case tls.QUICWriteData:
+			i := spaceInitial
+			if event.Level == tls.QUICEncryptionLevelHandshake {
+				i = spaceHandshake
+			}
+			s.spaces[i].sent = append(s.spaces[i].sent, event.Data...)
+		case tls.QUICTransportParametersRequired:
+			s.tls.SetTransportParameters(buildTransportParameters(s.cid))
+		}
+	}
+}
+
+func (s *testServer) handle(in []byte) {
+	packets, _ := splitDatagram(in)
+	for _, p := range packets {
+		i := spaceInitial
+		switch {
+		case p.short:
+			i = spaceApplication
+		case p.packetType == packetHandshake:
+			i = spaceHandshake
+		case s.tls == nil:
+			s.clientCID = append([]byte(nil), p.scid...)
+			// the Initial keys derive from the client's choice of destination connection ID
+			client, server := initialKeys(p.dcid)
+			s.spaces[spaceInitial].read, s.spaces[spaceInitial].write = client, server
+			s.tls = tls.QUICServer(&tls.QUICConfig{TLSConfig: &tls.Config{
+				Certificates: []tls.Certificate{testCertificate(s.t)},
+				NextProtos:   []string{"h3"},
+				MinVersion:   tls.VersionTLS13,
+			}})
+			if err := s.tls.Start(context.Background()); err != nil {
+				s.t.Error(err)
+				return
+			}
+		}
+		sp := &s.spaces[i]
+		if sp.read == nil {
+			continue
+		}
+		_, payload, err := p.open(sp.read, sp.largest)
+		if err != nil {
+			continue
+		}
+		f, err := parseFrames(payload)
+		if err != nil {
+			s.t.Errorf("server: %v", err)
+			return
+		}
+		if data := sp.crypto.add(f.crypto); len(data) > 0 {
+			level := tls.QUICEncryptionLevelInitial
+			if i == spaceHandshake {
+				level = tls.QUICEncryptionLevelHandshake
+			}
+			if err := s.tls.HandleData(level, data); err != nil {
+				s.t.Errorf("server: %v", err)
+				return
+			}
+			s.drainEvents()
+		}
+		for _, sf := range f.streams {
+			s.received[sf.id] = append(s.received[sf.id], sf.data...)
+			if sf.fin && !s.echoed[sf.id] {
+				s.echoed[sf.id] = true
+				s.reply(buildStreamFrame(sf.id, 0, s.received[sf.id], true))
+			}
+		}
+		if f.close != nil {
+			return
+		}
+	}
+	var datagram []byte
+	for i := spaceInitial; i < spaceApplication; i++ {
+		sp := &s.spaces[i]
+		if pending := sp.sent[sp.sentOffset:]; len(pending) > 0 && sp.write != nil {
+			header := &longHeader{packetType: spacePacketTypes[i], version: version1, dcid: s.clientCID, scid: s.cid}
+			datagram = append(datagram, sealLongPacket(sp.write, header, sp.nextPN, append(buildCryptoFrame(sp.sentOffset, pending), make([]byte, 16)...))...)
+			sp.nextPN++
+			sp.sentOffset = uint64(len(sp.sent))
+		}
+	}
+	if s.tls != nil && s.tls.ConnectionState().HandshakeComplete && !s.sentDone && s.spaces[spaceHandshake].crypto.offset > 0 {
+		s.sentDone = true
+		app := &s.spaces[spaceApplication]
+		datagram = append(datagram, sealShortPacket(app.write, s.clientCID, app.nextPN, append([]byte{frameHandshakeDone}, make([]byte, 16)...))...)
+		app.nextPN++
+	}
+	if len(datagram) > 0 {
+		s.pc.WriteTo(datagram, s.peer)
+	}
+}
+
+func (s *testServer) reply(payload []byte) {
+	app := &s.spaces[spaceApplication]
+	s.pc.WriteTo(sealShortPacket(app.write, s.clientCID, app.nextPN, append(payload, make([]byte, 16)...)), s.peer)
+	app.nextPN++
+}
+
+func (s *testServer) serve() {
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, addr, err := s.pc.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		s.peer = addr
+		s.handle(append([]byte(nil), buf[:n]...))
+	}
+}
+
+func TestConnStreams(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("no UDP on loopback: %v", err)
+	}
+	defer pc.Close()
+	server := &testServer{t: t, pc: pc, cid: []byte{1, 2, 3, 4, 5, 6, 7, 8}, received: make(map[uint64][]byte), echoed: make(map[uint64]bool)}
+	go server.serve()
+
+	conn, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c, err := Dial(ctx, conn, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"h3"}, MinVersion: tls.VersionTLS13}, time.Second, 2)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer c.Close()
+	if alpn := c.ConnectionState().NegotiatedProtocol; alpn != "h3" {
+		t.Errorf("ALPN = %q, want h3", alpn)
+	}
+
+	long := make([]byte, 2500)
+	for i := range long {
+		long[i] = byte(i)
+	}
+	id := c.OpenStream(true)
+	c.Write(id, long, true)
+	if next := c.OpenStream(true); next != id+4 {
+		t.Errorf("second stream ID = %d, want %d", next, id+4)
+	}
+	err = c.Run(ctx, func() bool {
+		_, done := c.Stream(id)
+		return done
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	data, _ := c.Stream(id)
+	if string(data) != string(long) {
+		t.Errorf("echoed %d bytes, want %d", len(data), len(long))
+	}
+	if !c.h.confirmed {
+		t.Error("handshake not confirmed after HANDSHAKE_DONE")
+	}
+}
+
+func TestDecodePacketNumber(t *testing.T) {
+	// RFC 9000 appendix A.3
+	if got := decodePacketNumber(0xa82f30ea, 0x9b32, 2); got != 0xa82f9b32 {
+		t.Errorf("got %#x, want 0xa82f9b32", got)
+	}
+	if got := decodePacketNumber(0, 3, 1); got != 3 {
+		t.Errorf("got %d, want 3", got)
+	}
+	if got := decodePacketNumber(300, 0x2d, 1); got != 301 {
+		t.Errorf("got %d, want 301", got)
+	}
+}
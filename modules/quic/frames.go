@@ -0,0 +1,335 @@
+package quic
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Frame types that may appear in Initial and Handshake packets.
+const (
+	framePadding            = 0x00
+	framePing               = 0x01
+	frameAck                = 0x02
+	frameAckECN             = 0x03
+	frameCrypto             = 0x06
+	frameConnectionClose    = 0x1C
+	frameConnectionCloseApp = 0x1D
+)
+
+// Frame types that only appear in 1-RTT packets. STREAM frames use the types from frameStream to frameStreamMax,
+// with the low bits flagging the presence of the offset (0x04) and length (0x02) fields and the end of the stream
+// (0x01).
+const (
+	frameResetStream        = 0x04
+	frameStopSending        = 0x05
+	frameNewToken           = 0x07
+	frameStream             = 0x08
+	frameStreamMax          = 0x0F
+	frameMaxData            = 0x10
+	frameMaxStreamData      = 0x11
+	frameMaxStreamsBidi     = 0x12
+	frameMaxStreamsUni      = 0x13
+	frameDataBlocked        = 0x14
+	frameStreamDataBlocked  = 0x15
+	frameStreamsBlockedBidi = 0x16
+	frameStreamsBlockedUni  = 0x17
+	frameNewConnectionID    = 0x18
+	frameRetireConnectionID = 0x19
+	framePathChallenge      = 0x1A
+	framePathResponse       = 0x1B
+	frameHandshakeDone      = 0x1E
+	frameDatagram           = 0x30
+	frameDatagramLength     = 0x31
+)
+
+// cryptoErrorBase is added to a TLS alert to form the transport error code of a CRYPTO_ERROR.
+const cryptoErrorBase = 0x0100
+
+// maxCryptoBufferSize bounds the offset of the CRYPTO data the scan buffers at each encryption level.
+const maxCryptoBufferSize = 1 << 16
+
+// maxStreamBufferSize bounds the offset of the data the scan buffers on each stream. It matches the
+// initial_max_stream_data limits the scan advertises.
+const maxStreamBufferSize = 1 << 18
+
+var transportErrorNames = map[uint64]string{
+	0x00: "NO_ERROR",
+	0x01: "INTERNAL_ERROR",
+	0x02: "CONNECTION_REFUSED",
+	0x03: "FLOW_CONTROL_ERROR",
+	0x04: "STREAM_LIMIT_ERROR",
+	0x05: "STREAM_STATE_ERROR",
+	0x06: "FINAL_SIZE_ERROR",
+	0x07: "FRAME_ENCODING_ERROR",
+	0x08: "TRANSPORT_PARAMETER_ERROR",
+	0x09: "CONNECTION_ID_LIMIT_ERROR",
+	0x0A: "PROTOCOL_VIOLATION",
+	0x0B: "INVALID_TOKEN",
+	0x0C: "APPLICATION_ERROR",
+	0x0D: "CRYPTO_BUFFER_EXCEEDED",
+	0x0E: "KEY_UPDATE_ERROR",
+	0x0F: "AEAD_LIMIT_REACHED",
+	0x10: "NO_VIABLE_PATH",
+	0x11: "VERSION_NEGOTIATION_ERROR",
+}
+
+// ConnectionClose is a CONNECTION_CLOSE frame from the server.
+type ConnectionClose struct {
+	ErrorCode uint64 `json:"error_code"`
+
+	// ErrorName is the transport error name, or CRYPTO_ERROR with the TLS alert for errors from the handshake.
+	ErrorName string `json:"error_name,omitempty"`
+
+	// Application is true if the frame closed the connection with an application error code.
+	Application bool   `json:"application"`
+	FrameType   uint64 `json:"frame_type,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// errorName names a transport error code.
+func errorName(code uint64) string {
+	if name, ok := transportErrorNames[code]; ok {
+		return name
+	}
+	if code >= cryptoErrorBase && code < cryptoErrorBase+0x100 {
+		return fmt.Sprintf("CRYPTO_ERROR (alert %d)", code-cryptoErrorBase)
+	}
+	return ""
+}
+
+// streamFrame is a STREAM frame, or the final size of a RESET_STREAM frame with reset set.
+type streamFrame struct {
+	id     uint64
+	offset uint64
+	data   []byte
+	fin    bool
+	reset  bool
+}
+
+// frames is the decoded content of a packet payload the scan acts on.
+type frames struct {
+	// crypto maps the offsets of CRYPTO frames to their data.
+	crypto map[uint64][]byte
+
+	streams       []streamFrame
+	handshakeDone bool
+
+	close *ConnectionClose
+}
+
+// skipVarInts skips n variable-length integers.
+func skipVarInts(b []byte, n int) ([]byte, error) {
+	var err error
+	for i := 0; i < n; i++ {
+		if _, b, err = readVarInt(b); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// parseFrames decodes the frames of a packet payload. Frames that need no action from the scan, such as flow
+// control updates, are skipped.
+func parseFrames(payload []byte) (*frames, error) {
+	f := &frames{crypto: make(map[uint64][]byte)}
+	for len(payload) > 0 {
+		frameType, rest, err := readVarInt(payload)
+		if err != nil {
+			return f, err
+		}
+		switch frameType {
+		case framePadding, framePing:
+			payload = rest
+		case frameAck, frameAckECN:
+			// largest acknowledged, ACK delay, range count, first range, then the ranges and ECN counts
+			var values []uint64
+			for i := 0; i < 4; i++ {
+				var v uint64
+				if v, rest, err = readVarInt(rest); err != nil {
+					return f, err
+				}
+				values = append(values, v)
+			}
+			skip := 2 * values[2]
+			if frameType == frameAckECN {
+				skip += 3
+			}
+			for i := uint64(0); i < skip; i++ {
+				if _, rest, err = readVarInt(rest); err != nil {
+					return f, err
+				}
+			}
+			payload = rest
+		case frameCrypto:
+			var offset, length uint64
+			if offset, rest, err = readVarInt(rest); err != nil {
+				return f, err
+			}
+			if length, rest, err = readVarInt(rest); err != nil {
+				return f, err
+			}
+			if uint64(len(rest)) < length || offset+length > maxCryptoBufferSize {
+				return f, errInvalidPacket
+			}
+			f.crypto[offset] = rest[:length]
+			payload = rest[length:]
+		case frameConnectionClose, frameConnectionCloseApp:
+			c := &ConnectionClose{Application: frameType == frameConnectionCloseApp}
+			if c.ErrorCode, rest, err = readVarInt(rest); err != nil {
+				return f, err
+			}
+			if !c.Application {
+				if c.FrameType, rest, err = readVarInt(rest); err != nil {
+					return f, err
+				}
+				c.ErrorName = errorName(c.ErrorCode)
+			}
+			var length uint64
+			if length, rest, err = readVarInt(rest); err != nil {
+				return f, err
+			}
+			if uint64(len(rest)) < length {
+				return f, errInvalidPacket
+			}
+			c.Reason = string(rest[:length])
+			f.close = c
+			return f, nil
+		case frameHandshakeDone:
+			f.handshakeDone = true
+			payload = rest
+		case frameMaxData, frameMaxStreamsBidi, frameMaxStreamsUni, frameDataBlocked, frameStreamsBlockedBidi,
+			frameStreamsBlockedUni, frameRetireConnectionID:
+			if payload, err = skipVarInts(rest, 1); err != nil {
+				return f, err
+			}
+		case frameMaxStreamData, frameStreamDataBlocked, frameStopSending:
+			if payload, err = skipVarInts(rest, 2); err != nil {
+				return f, err
+			}
+		case frameResetStream:
+			sf := streamFrame{reset: true}
+			if sf.id, rest, err = readVarInt(rest); err != nil {
+				return f, err
+			}
+			if rest, err = skipVarInts(rest, 1); err != nil {
+				return f, err
+			}
+			if sf.offset, rest, err = readVarInt(rest); err != nil {
+				return f, err
+			}
+			f.streams = append(f.streams, sf)
+			payload = rest
+		case frameNewToken:
+			var length uint64
+			if length, rest, err = readVarInt(rest); err != nil || uint64(len(rest)) < length {
+				return f, errInvalidPacket
+			}
+			payload = rest[length:]
+		case frameNewConnectionID:
+			// sequence number, retire prior to, then the length-prefixed connection ID and the reset token
+			if rest, err = skipVarInts(rest, 2); err != nil {
+				return f, err
+			}
+			if len(rest) < 1 || len(rest) < 1+int(rest[0])+16 {
+				return f, errInvalidPacket
+			}
+			payload = rest[1+int(rest[0])+16:]
+		case framePathChallenge, framePathResponse:
+			if len(rest) < 8 {
+				return f, errInvalidPacket
+			}
+			payload = rest[8:]
+		case frameDatagram:
+			payload = nil
+		case frameDatagramLength:
+			var length uint64
+			if length, rest, err = readVarInt(rest); err != nil || uint64(len(rest)) < length {
+				return f, errInvalidPacket
+			}
+			payload = rest[length:]
+		default:
+			if frameType < frameStream || frameType > frameStreamMax {
+				return f, fmt.Errorf("unexpected frame type %#x", frameType)
+			}
+			sf := streamFrame{fin: frameType&0x01 != 0}
+			if sf.id, rest, err = readVarInt(rest); err != nil {
+				return f, err
+			}
+			if frameType&0x04 != 0 {
+				if sf.offset, rest, err = readVarInt(rest); err != nil {
+					return f, err
+				}
+			}
+			length := uint64(len(rest))
+			if frameType&0x02 != 0 {
+				if length, rest, err = readVarInt(rest); err != nil {
+					return f, err
+				}
+			}
+			if uint64(len(rest)) < length || sf.offset+length > maxStreamBufferSize {
+				return f, errInvalidPacket
+			}
+			sf.data = rest[:length]
+			f.streams = append(f.streams, sf)
+			payload = rest[length:]
+		}
+	}
+	return f, nil
+}
+
+// cryptoStream reassembles the CRYPTO frames of one encryption level.
+type cryptoStream struct {
+	pending map[uint64][]byte
+	offset  uint64
+}
+
+// add stores the frames' data and returns the data now contiguous with what was delivered before.
+func (s *cryptoStream) add(crypto map[uint64][]byte) []byte {
+	if s.pending == nil {
+		s.pending = make(map[uint64][]byte)
+	}
+	for offset, data := range crypto {
+		s.pending[offset] = data
+	}
+	offsets := make([]uint64, 0, len(s.pending))
+	for offset := range s.pending {
+		offsets = append(offsets, offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	var out []byte
+	for _, offset := range offsets {
+		data := s.pending[offset]
+		end := offset + uint64(len(data))
+		if offset > s.offset {
+			break
+		}
+		delete(s.pending, offset)
+		if end > s.offset {
+			// skip any retransmitted prefix
+			out = append(out, data[s.offset-offset:]...)
+			s.offset = end
+		}
+	}
+	return out
+}
+
+// buildCryptoFrame returns a CRYPTO frame carrying data at offset.
+func buildCryptoFrame(offset uint64, data []byte) []byte {
+	b := appendVarInt([]byte{frameCrypto}, offset)
+	b = appendVarInt(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+// buildAckFrame returns an ACK frame acknowledging the packets from smallest to largest.
+func buildAckFrame(smallest, largest uint64) []byte {
+	b := appendVarInt([]byte{frameAck}, largest)
+	b = append(b, 0, 0) // ACK delay, no further ranges
+	return appendVarInt(b, largest-smallest)
+}
+
+// buildConnectionClose returns a CONNECTION_CLOSE frame with the given transport error code.
+func buildConnectionClose(code uint64) []byte {
+	b := appendVarInt([]byte{frameConnectionClose}, code)
+	return append(b, 0, 0) // frame type, reason length
+}
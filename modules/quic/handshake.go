@@ -0,0 +1,420 @@
+package quic
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Packet number spaces of the encryption levels the scan uses. Early data is never sent; 1-RTT packets are only
+// exchanged by a Conn.
+const (
+	spaceInitial = iota
+	spaceHandshake
+	spaceApplication
+	numSpaces
+)
+
+// spacePacketTypes are the long header packet types of the packet number spaces. 1-RTT packets have short headers.
+var spacePacketTypes = [numSpaces]byte{packetInitial, packetHandshake, 0}
+
+// maxStreamFrameData bounds the stream data flush puts into one 1-RTT packet, keeping datagrams under the minimum
+// QUIC datagram size.
+const maxStreamFrameData = 1000
+
+// maxDatagramSize is the largest datagram the scan reads.
+const maxDatagramSize = 65535
+
+// errRetry is returned by handleDatagram after a Retry packet, when the Initial must be sent again.
+var errRetry = errors.New("retry")
+
+// errVersionNegotiation is returned by handleDatagram when the server answered the Initial with a Version
+// Negotiation packet.
+var errVersionNegotiation = errors.New("server sent a version negotiation packet")
+
+// space is the state of a packet number space.
+type space struct {
+	read, write *keys
+
+	// crypto reassembles the server's CRYPTO frames, and sent holds all CRYPTO data queued for the server, of
+	// which the first sentOffset bytes have been sent.
+	crypto     cryptoStream
+	sent       []byte
+	sentOffset uint64
+
+	nextPN uint64
+
+	// smallest and largest are the packet numbers of the server's packets received, and ack is set if they
+	// need to be acknowledged.
+	smallest, largest uint64
+	received, ack     bool
+}
+
+// handshake is a client connection performing the QUIC version 1 handshake.
+type handshake struct {
+	conn net.Conn
+	tls  *tls.QUICConn
+
+	// dcid is the destination connection ID, initially random and then chosen by the server; scid is the scan's
+	// connection ID; token is the token of a Retry packet.
+	dcid, scid, token []byte
+
+	spaces [numSpaces]space
+
+	// buffered holds Handshake and 1-RTT packets received before the keys to open them.
+	buffered []*packet
+
+	// streams are the streams of a Conn, and peerStreams the IDs of those opened by the server, in order.
+	streams     map[uint64]*stream
+	peerStreams []uint64
+
+	retried        bool
+	serverInitial  bool
+	peerParameters []byte
+	close          *ConnectionClose
+	versions       []uint32
+	handshakeDone  bool
+
+	// confirmed is set once the server's HANDSHAKE_DONE frame arrives; the Initial and Handshake keys are then
+	// discarded.
+	confirmed bool
+}
+
+// newHandshake starts the TLS handshake and queues the ClientHello.
+func newHandshake(ctx context.Context, conn net.Conn, config *tls.Config) (*handshake, error) {
+	h := &handshake{
+		conn:    conn,
+		dcid:    make([]byte, connectionIDLength),
+		scid:    make([]byte, connectionIDLength),
+		streams: make(map[uint64]*stream),
+	}
+	if _, err := rand.Read(h.dcid); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(h.scid); err != nil {
+		return nil, err
+	}
+	h.spaces[spaceInitial].write, h.spaces[spaceInitial].read = initialKeys(h.dcid)
+	h.tls = tls.QUICClient(&tls.QUICConfig{TLSConfig: config})
+	h.tls.SetTransportParameters(buildTransportParameters(h.scid))
+	if err := h.tls.Start(ctx); err != nil {
+		return nil, err
+	}
+	if err := h.drainEvents(); err != nil {
+		h.tls.Close()
+		return nil, err
+	}
+	return h, nil
+}
+
+// drainEvents acts on the events of the TLS connection.
+func (h *handshake) drainEvents() error {
+	for {
+		event := h.tls.NextEvent()
+		switch event.Kind {
+		case tls.QUICNoEvent:
+			return nil
+		case tls.QUICSetReadSecret, tls.QUICSetWriteSecret:
+			i := spaceHandshake
+			switch event.Level {
+			case tls.QUICEncryptionLevelHandshake:
+			case tls.QUICEncryptionLevelApplication:
+				i = spaceApplication
+			default:
+				continue
+			}
+			k, err := newKeys(event.Suite, event.Data)
+			if err != nil {
+				return err
+			}
+			if event.Kind == tls.QUICSetReadSecret {
+				h.spaces[i].read = k
+			} else {
+				h.spaces[i].write = k
+			}
+		case tls.QUICWriteData:
+			if s := h.levelSpace(event.Level); s != nil {
+				s.sent = append(s.sent, event.Data...)
+			}
+		case tls.QUICTransportParameters:
+			h.peerParameters = append([]byte(nil), event.Data...)
+		case tls.QUICTransportParametersRequired:
+			h.tls.SetTransportParameters(buildTransportParameters(h.scid))
+		case tls.QUICHandshakeDone:
+			h.handshakeDone = true
+		}
+	}
+}
+
+// levelSpace returns the packet number space of a TLS encryption level, or nil for the levels the scan ignores.
+func (h *handshake) levelSpace(level tls.QUICEncryptionLevel) *space {
+	switch level {
+	case tls.QUICEncryptionLevelInitial:
+		return &h.spaces[spaceInitial]
+	case tls.QUICEncryptionLevelHandshake:
+		return &h.spaces[spaceHandshake]
+	}
+	return nil
+}
+
+// flush returns the datagram carrying the queued CRYPTO data, stream data and acknowledgements, or nil if there is
+// nothing to send. The given CONNECTION_CLOSE frame, if any, goes into the 1-RTT packet once the handshake is
+// confirmed, and into the Handshake packet before. A datagram with an Initial packet is padded to the minimum size.
+// A datagram carries at most maxStreamFrameData bytes of stream data, so flush must be called until it returns nil.
+func (h *handshake) flush(closeFrame []byte) []byte {
+	var datagram []byte
+	for i := range h.spaces {
+		s := &h.spaces[i]
+		if s.write == nil {
+			continue
+		}
+		var payload []byte
+		if s.ack {
+			payload = append(payload, buildAckFrame(s.smallest, s.largest)...)
+		}
+		if pending := s.sent[s.sentOffset:]; len(pending) > 0 {
+			payload = append(payload, buildCryptoFrame(s.sentOffset, pending)...)
+		}
+		if i == spaceApplication {
+			payload = append(payload, h.streamFrames()...)
+		}
+		if (i == spaceHandshake && !h.confirmed) || (i == spaceApplication && h.confirmed) {
+			payload = append(payload, closeFrame...)
+		}
+		if len(payload) == 0 {
+			continue
+		}
+		if i == spaceApplication {
+			if len(payload) < 16 {
+				payload = append(payload, make([]byte, 16-len(payload))...)
+			}
+			datagram = append(datagram, sealShortPacket(s.write, h.dcid, s.nextPN, payload)...)
+			s.nextPN++
+			s.ack = false
+			continue
+		}
+		header := &longHeader{packetType: spacePacketTypes[i], version: version1, dcid: h.dcid, scid: h.scid}
+		if i == spaceInitial {
+			header.token = h.token
+			// the header protection sample needs at least 16 bytes after the packet number
+			if len(payload) < 16 {
+				payload = append(payload, make([]byte, 16-len(payload))...)
+			}
+			if size := len(sealLongPacket(s.write, header, 0, payload)); size < minInitialDatagramSize {
+				payload = append(payload, make([]byte, minInitialDatagramSize-size)...)
+			}
+		} else if len(payload) < 16 {
+			payload = append(payload, make([]byte, 16-len(payload))...)
+		}
+		datagram = append(datagram, sealLongPacket(s.write, header, s.nextPN, payload)...)
+		s.nextPN++
+		s.sentOffset = uint64(len(s.sent))
+		s.ack = false
+	}
+	return datagram
+}
+
+// resend marks all CRYPTO and stream data as unsent and all received packets as unacknowledged, so the next
+// datagrams retransmit everything and elicit the server's retransmissions.
+func (h *handshake) resend() {
+	for i := range h.spaces {
+		h.spaces[i].sentOffset = 0
+		h.spaces[i].ack = h.spaces[i].received
+	}
+	for _, st := range h.streams {
+		st.sentOffset, st.finSent = 0, false
+	}
+}
+
+// handleDatagram processes the packets of a datagram from the server. It returns errRetry after a Retry and
+// errVersionNegotiation after a Version Negotiation packet.
+func (h *handshake) handleDatagram(datagram []byte) error {
+	packets, err := splitDatagram(datagram)
+	for _, p := range packets {
+		if !bytes.Equal(p.dcid, h.scid) {
+			continue
+		}
+		switch {
+		case p.short:
+			if err := h.handlePacket(p); err != nil {
+				return err
+			}
+		case p.version == 0:
+			h.versions = p.versions
+			return errVersionNegotiation
+		case p.packetType == packetRetry:
+			// a client accepts only one Retry, and none after the server's Initial
+			if h.retried || h.serverInitial || len(p.token) == 0 {
+				continue
+			}
+			h.retried = true
+			h.token = append([]byte(nil), p.token...)
+			h.dcid = append([]byte(nil), p.scid...)
+			initial := &h.spaces[spaceInitial]
+			initial.write, initial.read = initialKeys(h.dcid)
+			h.resend()
+			return errRetry
+		case p.packetType == packetInitial || p.packetType == packetHandshake:
+			if err := h.handlePacket(p); err != nil {
+				return err
+			}
+		}
+	}
+	if err != nil && len(packets) == 0 {
+		return err
+	}
+	return nil
+}
+
+// handlePacket opens a packet and processes its frames.
+func (h *handshake) handlePacket(p *packet) error {
+	i := spaceInitial
+	switch {
+	case p.short:
+		i = spaceApplication
+	case p.packetType == packetHandshake:
+		i = spaceHandshake
+	}
+	s := &h.spaces[i]
+	if s.read == nil {
+		if len(h.buffered) < 8 && !h.confirmed {
+			h.buffered = append(h.buffered, p)
+		}
+		return nil
+	}
+	pn, payload, err := p.open(s.read, s.largest)
+	if err != nil {
+		// packets that fail authentication are dropped, as they may have been injected
+		return nil
+	}
+	if i == spaceInitial && !h.serverInitial {
+		h.serverInitial = true
+		// the server's first Initial chooses the connection ID the scan sends to
+		h.dcid = append([]byte(nil), p.scid...)
+	}
+	f, err := parseFrames(payload)
+	if err != nil {
+		return err
+	}
+	if !s.received || pn < s.smallest {
+		s.smallest = pn
+	}
+	if !s.received || pn > s.largest {
+		s.largest = pn
+	}
+	s.received, s.ack = true, true
+	if f.close != nil {
+		h.close = f.close
+		return nil
+	}
+	for _, sf := range f.streams {
+		h.receiveStream(sf)
+	}
+	if f.handshakeDone && !h.confirmed {
+		// the server has the client's Finished: the Initial and Handshake packet number spaces are done with
+		h.confirmed = true
+		for j := spaceInitial; j < spaceApplication; j++ {
+			h.spaces[j].read, h.spaces[j].write = nil, nil
+		}
+	}
+	if data := s.crypto.add(f.crypto); len(data) > 0 {
+		level := tls.QUICEncryptionLevelInitial
+		switch i {
+		case spaceHandshake:
+			level = tls.QUICEncryptionLevelHandshake
+		case spaceApplication:
+			level = tls.QUICEncryptionLevelApplication
+		}
+		if err := h.tls.HandleData(level, data); err != nil {
+			return err
+		}
+		if err := h.drainEvents(); err != nil {
+			return err
+		}
+		// new keys may open the buffered packets; those still without keys are buffered again
+		if len(h.buffered) > 0 {
+			buffered := h.buffered
+			h.buffered = nil
+			for _, b := range buffered {
+				if err := h.handlePacket(b); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// run exchanges datagrams until the handshake completes, the server closes the connection, or the server stays
+// silent for tryTimeout on retries+1 consecutive attempts. The initial datagram has already been answered.
+func (h *handshake) run(ctx context.Context, tryTimeout time.Duration, retries int) error {
+	return h.exchange(ctx, tryTimeout, retries, func() bool { return h.handshakeDone })
+}
+
+// exchange sends the queued data and processes the server's datagrams until done returns true, the server closes
+// the connection, or the server stays silent for tryTimeout on retries+1 consecutive attempts.
+func (h *handshake) exchange(ctx context.Context, tryTimeout time.Duration, retries int, done func() bool) error {
+	buf := make([]byte, maxDatagramSize)
+	attempts := 0
+	for !done() && h.close == nil {
+		for datagram := h.flush(nil); datagram != nil; datagram = h.flush(nil) {
+			if _, err := h.conn.Write(datagram); err != nil {
+				return err
+			}
+		}
+		deadline := time.Now().Add(tryTimeout)
+		if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+			deadline = ctxDeadline
+		}
+		if err := h.conn.SetReadDeadline(deadline); err != nil {
+			return err
+		}
+		n, err := h.conn.Read(buf)
+		if err != nil {
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() || ctx.Err() != nil {
+				return err
+			}
+			if attempts++; attempts > retries {
+				return err
+			}
+			// nothing arrived: probe the server by retransmitting everything not acknowledged
+			h.resend()
+			continue
+		}
+		attempts = 0
+		if err := h.handleDatagram(buf[:n]); err != nil && !errors.Is(err, errRetry) {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeConnection sends the remaining CRYPTO data and acknowledgements, with a CONNECTION_CLOSE frame with no error
+// if the Handshake or, once the handshake is confirmed, the 1-RTT keys are available.
+func (h *handshake) closeConnection() {
+	defer h.tls.Close()
+	if h.close != nil {
+		return
+	}
+	if datagram := h.flush(buildConnectionClose(0)); datagram != nil {
+		_, _ = h.conn.Write(datagram)
+	}
+}
+
+// parameters returns the server's transport parameters, if it sent any.
+func (h *handshake) parameters() (*TransportParameters, error) {
+	if h.peerParameters == nil {
+		return nil, nil
+	}
+	params, err := parseTransportParameters(h.peerParameters)
+	if err != nil {
+		return params, fmt.Errorf("invalid transport parameters: %w", err)
+	}
+	return params, nil
+}
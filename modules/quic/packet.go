@@ -0,0 +1,370 @@
+package quic
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// version1 is QUIC version 1 (RFC 9000), the only version the scan completes handshakes for.
+const version1 = 0x00000001
+
+// initialSaltV1 is the salt of the Initial secrets of QUIC version 1 (RFC 9001 section 5.2).
+var initialSaltV1 = []byte{
+	0x38, 0x76, 0x2c, 0xf7, 0xf5, 0x59, 0x34, 0xb3, 0x4d, 0x17,
+	0x9a, 0xe6, 0xa4, 0xc8, 0x0c, 0xad, 0xcc, 0xbb, 0x7f, 0x0a,
+}
+
+// Long header packet types of version 1.
+const (
+	packetInitial   = 0
+	packetHandshake = 2
+	packetRetry     = 3
+)
+
+// minInitialDatagramSize is the size client datagrams carrying Initial packets are padded to.
+const minInitialDatagramSize = 1200
+
+// packetNumberLength is the length of the packet numbers the scan sends.
+const packetNumberLength = 4
+
+// connectionIDLength is the length of the connection IDs the scan chooses. Short header packets carry no length for
+// their destination connection ID, so it must be known to parse them.
+const connectionIDLength = 8
+
+var errInvalidPacket = errors.New("invalid QUIC packet")
+
+// appendVarInt appends a variable-length integer (RFC 9000 section 16).
+func appendVarInt(b []byte, v uint64) []byte {
+	switch {
+	case v < 1<<6:
+		return append(b, byte(v))
+	case v < 1<<14:
+		return binary.BigEndian.AppendUint16(b, uint16(v)|0x4000)
+	case v < 1<<30:
+		return binary.BigEndian.AppendUint32(b, uint32(v)|0x80000000)
+	}
+	return binary.BigEndian.AppendUint64(b, v|0xC000000000000000)
+}
+
+// readVarInt decodes a variable-length integer and returns it with the remaining bytes.
+func readVarInt(b []byte) (uint64, []byte, error) {
+	if len(b) == 0 {
+		return 0, nil, errInvalidPacket
+	}
+	length := 1 << (b[0] >> 6)
+	if len(b) < length {
+		return 0, nil, errInvalidPacket
+	}
+	v := uint64(b[0] & 0x3F)
+	for _, c := range b[1:length] {
+		v = v<<8 | uint64(c)
+	}
+	return v, b[length:], nil
+}
+
+// hkdfExpandLabel is HKDF-Expand-Label of TLS 1.3 with an empty context.
+func hkdfExpandLabel(h func() hash.Hash, secret []byte, label string, length int) []byte {
+	info := binary.BigEndian.AppendUint16(nil, uint16(length))
+	info = append(info, byte(len("tls13 ")+len(label)))
+	info = append(info, "tls13 "+label...)
+	info = append(info, 0)
+	out, err := hkdf.Expand(h, secret, string(info), length)
+	if err != nil {
+		// only possible for lengths longer than 255 hash sizes
+		panic(err)
+	}
+	return out
+}
+
+// keys protect the packets of one encryption level in one direction.
+type keys struct {
+	aead cipher.AEAD
+	iv   []byte
+
+	// mask returns the header protection mask for a sample of the ciphertext.
+	mask func(sample []byte) []byte
+}
+
+// newKeys derives the packet protection keys from a traffic secret for the given TLS 1.3 cipher suite.
+func newKeys(suite uint16, secret []byte) (*keys, error) {
+	switch suite {
+	case tls.TLS_AES_128_GCM_SHA256:
+		return newAESKeys(sha256.New, secret, 16)
+	case tls.TLS_AES_256_GCM_SHA384:
+		return newAESKeys(sha512.New384, secret, 32)
+	case tls.TLS_CHACHA20_POLY1305_SHA256:
+		key := hkdfExpandLabel(sha256.New, secret, "quic key", chacha20poly1305.KeySize)
+		aead, err := chacha20poly1305.New(key)
+		if err != nil {
+			return nil, err
+		}
+		hp := hkdfExpandLabel(sha256.New, secret, "quic hp", chacha20.KeySize)
+		return &keys{
+			aead: aead,
+			iv:   hkdfExpandLabel(sha256.New, secret, "quic iv", aead.NonceSize()),
+			mask: func(sample []byte) []byte {
+				mask := make([]byte, 5)
+				stream, err := chacha20.NewUnauthenticatedCipher(hp, sample[4:16])
+				if err != nil {
+					return mask
+				}
+				stream.SetCounter(binary.LittleEndian.Uint32(sample[0:4]))
+				stream.XORKeyStream(mask, mask)
+				return mask
+			},
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported cipher suite %#04x", suite)
+}
+
+func newAESKeys(h func() hash.Hash, secret []byte, keyLength int) (*keys, error) {
+	block, err := aes.NewCipher(hkdfExpandLabel(h, secret, "quic key", keyLength))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	hp, err := aes.NewCipher(hkdfExpandLabel(h, secret, "quic hp", keyLength))
+	if err != nil {
+		return nil, err
+	}
+	return &keys{
+		aead: aead,
+		iv:   hkdfExpandLabel(h, secret, "quic iv", aead.NonceSize()),
+		mask: func(sample []byte) []byte {
+			mask := make([]byte, aes.BlockSize)
+			hp.Encrypt(mask, sample[:aes.BlockSize])
+			return mask
+		},
+	}, nil
+}
+
+// initialKeys derives the client and server Initial keys from the client's first destination connection ID.
+func initialKeys(dcid []byte) (client *keys, server *keys) {
+	initialSecret, _ := hkdf.Extract(sha256.New, dcid, initialSaltV1)
+	client, _ = newKeys(tls.TLS_AES_128_GCM_SHA256, hkdfExpandLabel(sha256.New, initialSecret, "client in", sha256.Size))
+	server, _ = newKeys(tls.TLS_AES_128_GCM_SHA256, hkdfExpandLabel(sha256.New, initialSecret, "server in", sha256.Size))
+	return client, server
+}
+
+// nonce returns the AEAD nonce of a packet number.
+func (k *keys) nonce(pn uint64) []byte {
+	nonce := append([]byte(nil), k.iv...)
+	for i := 0; i < 8; i++ {
+		nonce[len(nonce)-1-i] ^= byte(pn >> (8 * i))
+	}
+	return nonce
+}
+
+// longHeader holds the fields of a long header packet the scan sends.
+type longHeader struct {
+	packetType byte
+	version    uint32
+	dcid       []byte
+	scid       []byte
+	token      []byte
+}
+
+// sealLongPacket builds and protects a long header packet with the given payload.
+func sealLongPacket(k *keys, header *longHeader, pn uint64, payload []byte) []byte {
+	b := []byte{0xC0 | header.packetType<<4 | (packetNumberLength - 1)}
+	b = binary.BigEndian.AppendUint32(b, header.version)
+	b = append(b, byte(len(header.dcid)))
+	b = append(b, header.dcid...)
+	b = append(b, byte(len(header.scid)))
+	b = append(b, header.scid...)
+	if header.packetType == packetInitial {
+		b = appendVarInt(b, uint64(len(header.token)))
+		b = append(b, header.token...)
+	}
+	// a two-byte length keeps the header size independent of the payload size
+	length := packetNumberLength + len(payload) + k.aead.Overhead()
+	b = binary.BigEndian.AppendUint16(b, uint16(length)|0x4000)
+	pnOffset := len(b)
+	b = binary.BigEndian.AppendUint32(b, uint32(pn))
+	b = k.aead.Seal(b, k.nonce(pn), payload, b)
+
+	mask := k.mask(b[pnOffset+4 : pnOffset+4+16])
+	b[0] ^= mask[0] & 0x0F
+	for i := 0; i < packetNumberLength; i++ {
+		b[pnOffset+i] ^= mask[1+i]
+	}
+	return b
+}
+
+// sealShortPacket builds and protects a 1-RTT (short header) packet with the given payload.
+func sealShortPacket(k *keys, dcid []byte, pn uint64, payload []byte) []byte {
+	b := []byte{0x40 | (packetNumberLength - 1)}
+	b = append(b, dcid...)
+	pnOffset := len(b)
+	b = binary.BigEndian.AppendUint32(b, uint32(pn))
+	b = k.aead.Seal(b, k.nonce(pn), payload, b)
+
+	mask := k.mask(b[pnOffset+4 : pnOffset+4+16])
+	b[0] ^= mask[0] & 0x1F
+	for i := 0; i < packetNumberLength; i++ {
+		b[pnOffset+i] ^= mask[1+i]
+	}
+	return b
+}
+
+// packet is a received long or short header packet.
+type packet struct {
+	// short is set for 1-RTT packets, which have no type, version or source connection ID.
+	short bool
+
+	packetType byte
+	version    uint32
+	dcid       []byte
+	scid       []byte
+
+	// token is the token of an Initial or Retry packet.
+	token []byte
+
+	// versions are the versions listed in a Version Negotiation packet (version 0).
+	versions []uint32
+
+	// protected is the whole packet, and pnOffset the offset of the packet number, for Initial and Handshake
+	// packets still to be decrypted.
+	protected []byte
+	pnOffset  int
+}
+
+// splitDatagram parses the packets coalesced into a datagram. A short header packet has no length and extends to
+// the end of the datagram.
+func splitDatagram(datagram []byte) ([]*packet, error) {
+	var packets []*packet
+	for len(datagram) > 0 {
+		if datagram[0]&0x80 == 0 {
+			if datagram[0]&0x40 == 0 || len(datagram) < 1+connectionIDLength+20 {
+				break
+			}
+			packets = append(packets, &packet{
+				short:     true,
+				dcid:      datagram[1 : 1+connectionIDLength],
+				protected: datagram,
+				pnOffset:  1 + connectionIDLength,
+			})
+			break
+		}
+		p, rest, err := parseLongHeader(datagram)
+		if err != nil {
+			return packets, err
+		}
+		packets = append(packets, p)
+		datagram = rest
+	}
+	return packets, nil
+}
+
+// parseLongHeader parses the header of the long header packet at the start of b and returns it with the bytes
+// following the packet.
+func parseLongHeader(b []byte) (*packet, []byte, error) {
+	if len(b) < 7 {
+		return nil, nil, errInvalidPacket
+	}
+	p := &packet{packetType: (b[0] >> 4) & 3, version: binary.BigEndian.Uint32(b[1:5])}
+	rest := b[5:]
+	var ok bool
+	if p.dcid, rest, ok = readConnectionID(rest); !ok {
+		return nil, nil, errInvalidPacket
+	}
+	if p.scid, rest, ok = readConnectionID(rest); !ok {
+		return nil, nil, errInvalidPacket
+	}
+
+	if p.version == 0 {
+		for len(rest) >= 4 {
+			p.versions = append(p.versions, binary.BigEndian.Uint32(rest))
+			rest = rest[4:]
+		}
+		return p, nil, nil
+	}
+	if p.version != version1 {
+		return nil, nil, fmt.Errorf("unsupported version %#08x", p.version)
+	}
+	if p.packetType == packetRetry {
+		// the token is followed by the 16-byte integrity tag
+		if len(rest) < 16 {
+			return nil, nil, errInvalidPacket
+		}
+		p.token = rest[:len(rest)-16]
+		return p, nil, nil
+	}
+	if p.packetType == packetInitial {
+		tokenLength, r, err := readVarInt(rest)
+		if err != nil || uint64(len(r)) < tokenLength {
+			return nil, nil, errInvalidPacket
+		}
+		p.token, rest = r[:tokenLength], r[tokenLength:]
+	}
+	length, r, err := readVarInt(rest)
+	if err != nil || uint64(len(r)) < length || length < 20 {
+		return nil, nil, errInvalidPacket
+	}
+	p.pnOffset = len(b) - len(r)
+	p.protected = b[:p.pnOffset+int(length)]
+	return p, r[length:], nil
+}
+
+// readConnectionID reads a length-prefixed connection ID.
+func readConnectionID(b []byte) ([]byte, []byte, bool) {
+	if len(b) < 1 || b[0] > 20 || len(b) < 1+int(b[0]) {
+		return nil, nil, false
+	}
+	return b[1 : 1+b[0]], b[1+b[0]:], true
+}
+
+// open removes the protection of a packet and returns its packet number and payload. largest is the largest packet
+// number received in the packet's number space, from which the full packet number is recovered.
+func (p *packet) open(k *keys, largest uint64) (uint64, []byte, error) {
+	b := append([]byte(nil), p.protected...)
+	mask := k.mask(b[p.pnOffset+4 : p.pnOffset+4+16])
+	if p.short {
+		b[0] ^= mask[0] & 0x1F
+	} else {
+		b[0] ^= mask[0] & 0x0F
+	}
+	pnLength := int(b[0]&3) + 1
+	var pn uint64
+	for i := 0; i < pnLength; i++ {
+		b[p.pnOffset+i] ^= mask[1+i]
+		pn = pn<<8 | uint64(b[p.pnOffset+i])
+	}
+	pn = decodePacketNumber(largest, pn, pnLength)
+	headerLength := p.pnOffset + pnLength
+	payload, err := k.aead.Open(nil, k.nonce(pn), b[headerLength:], b[:headerLength])
+	if err != nil {
+		return 0, nil, err
+	}
+	return pn, payload, nil
+}
+
+// decodePacketNumber recovers a full packet number from its truncated encoding of pnLength bytes, choosing the
+// candidate closest to the next expected packet number (RFC 9000 appendix A.3).
+func decodePacketNumber(largest, truncated uint64, pnLength int) uint64 {
+	expected := largest + 1
+	window := uint64(1) << (8 * pnLength)
+	halfWindow := window / 2
+	candidate := (expected &^ (window - 1)) | truncated
+	switch {
+	case candidate+halfWindow <= expected && candidate < (1<<62)-window:
+		return candidate + window
+	case candidate > expected+halfWindow && candidate >= window:
+		return candidate - window
+	}
+	return candidate
+}
@@ -0,0 +1,126 @@
+package quic
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func fromHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// TestInitialKeys checks the Initial keys of RFC 9001 appendix A.1 through the IVs and the header protection
+// masks of the samples in appendices A.2 and A.3.
+func TestInitialKeys(t *testing.T) {
+	client, server := initialKeys(fromHex(t, "8394c8f03e515708"))
+	for _, test := range []struct {
+		name         string
+		k            *keys
+		iv           string
+		sample, mask string
+	}{
+		{"client", client, "fa044b2f42a3fd3b46fb255c", "d1b1c98dd7689fb8ec11d242b123dc9b", "437b9aec36"},
+		{"server", server, "0ac1493ca1905853b0bba03e", "2cd0991cd25b0aac406a5816b6394100", "2ec0d8356a"},
+	} {
+		if iv := hex.EncodeToString(test.k.iv); iv != test.iv {
+			t.Errorf("%s IV: got %s, expected %s", test.name, iv, test.iv)
+		}
+		if mask := hex.EncodeToString(test.k.mask(fromHex(t, test.sample))[:5]); mask != test.mask {
+			t.Errorf("%s mask: got %s, expected %s", test.name, mask, test.mask)
+		}
+	}
+}
+
+// TestChaCha20Keys checks the ChaCha20-Poly1305 keys of RFC 9001 appendix A.5.
+func TestChaCha20Keys(t *testing.T) {
+	k, err := newKeys(tls.TLS_CHACHA20_POLY1305_SHA256, fromHex(t, "9ac312a7f877468ebe69422748ad00a15443f18203a07d6060f688f30f21632b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nonce := hex.EncodeToString(k.nonce(654360564)); nonce != "e0459b3474bdd0e46d417eb0" {
+		t.Errorf("got nonce %s", nonce)
+	}
+	if mask := hex.EncodeToString(k.mask(fromHex(t, "5e5cd55c41f69080575d7999c25a5bfb"))); mask != "aefefe7d03" {
+		t.Errorf("got mask %s", mask)
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	client, _ := initialKeys(fromHex(t, "8394c8f03e515708"))
+	header := &longHeader{packetType: packetInitial, version: version1, dcid: fromHex(t, "8394c8f03e515708"), scid: []byte{1, 2, 3, 4}, token: []byte("token")}
+	payload := append(buildCryptoFrame(0, []byte("client hello")), make([]byte, 20)...)
+	datagram := sealLongPacket(client, header, 7, payload)
+	datagram = append(datagram, sealLongPacket(client, &longHeader{packetType: packetHandshake, version: version1, dcid: header.dcid, scid: header.scid}, 8, payload)...)
+
+	packets, err := splitDatagram(datagram)
+	if err != nil || len(packets) != 2 {
+		t.Fatalf("got %d packets, error %v", len(packets), err)
+	}
+	if p := packets[0]; p.packetType != packetInitial || !bytes.Equal(p.token, header.token) || !bytes.Equal(p.scid, header.scid) {
+		t.Errorf("got Initial header %+v", p)
+	}
+	for i, p := range packets {
+		pn, opened, err := p.open(client, 0)
+		if err != nil {
+			t.Fatalf("packet %d: %v", i, err)
+		}
+		if pn != uint64(7+i) || !bytes.Equal(opened, payload) {
+			t.Errorf("packet %d: got packet number %d, payload %x", i, pn, opened)
+		}
+		f, err := parseFrames(opened)
+		if err != nil || string(f.crypto[0]) != "client hello" {
+			t.Errorf("packet %d: got frames %+v, error %v", i, f, err)
+		}
+	}
+}
+
+func TestTransportParameters(t *testing.T) {
+	b := buildTransportParameters([]byte{1, 2, 3, 4})
+	// version_information with v1 chosen, and an unknown parameter
+	b = append(b, 0x11, 8, 0, 0, 0, 1, 0x6b, 0x33, 0x43, 0xcf)
+	b = append(b, 0x40, 0x5b, 0)
+	params, err := parseTransportParameters(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.InitialSourceConnectionID != "01020304" || params.MaxIdleTimeout != 30000 || params.InitialMaxStreamsBidi != 100 {
+		t.Errorf("got %+v", params)
+	}
+	if params.MaxUDPPayloadSize != 65527 || params.ActiveConnectionIDLimit != 2 {
+		t.Errorf("defaults not applied: %+v", params)
+	}
+	if params.ChosenVersion != "v1" || len(params.AvailableVersions) != 1 || params.AvailableVersions[0] != "v2" {
+		t.Errorf("got version information %s %v", params.ChosenVersion, params.AvailableVersions)
+	}
+	if len(params.Unknown) != 1 || params.Unknown[0] != 0x5b {
+		t.Errorf("got unknown parameters %v", params.Unknown)
+	}
+}
+
+func TestVersionNegotiation(t *testing.T) {
+	probe := buildVersionProbe([]byte{1, 2, 3, 4, 5, 6, 7, 8}, []byte{9, 10, 11, 12})
+	if len(probe) != minInitialDatagramSize {
+		t.Errorf("got probe of %d bytes", len(probe))
+	}
+	response := []byte{0x80, 0, 0, 0, 0, 4, 9, 10, 11, 12, 8, 1, 2, 3, 4, 5, 6, 7, 8,
+		0, 0, 0, 1, 0xff, 0, 0, 29, 0x1a, 0x2a, 0x3a, 0x4a, 'Q', '0', '4', '6'}
+	p, _, err := parseLongHeader(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, version := range p.versions {
+		names = append(names, versionName(version))
+	}
+	if strings.Join(names, ",") != "v1,draft-29,grease,Q046" {
+		t.Errorf("got versions %v", names)
+	}
+}
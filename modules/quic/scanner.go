@@ -0,0 +1,355 @@
+// Package quic contains the zgrab2 Module implementation for QUIC.
+//
+// The scan first sends an Initial packet with a reserved version, which a QUIC server must answer with a Version
+// Negotiation packet listing its supported versions. It then performs the QUIC version 1 handshake with TLS 1.3
+// and records the server's transport parameters, the negotiated cipher suite and ALPN protocol, and the
+// certificate chain. The handshake ends with a CONNECTION_CLOSE once the server's Finished arrives; no 1-RTT
+// packets are exchanged. Certificates are not verified.
+package quic
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	ztls "github.com/zmap/zcrypto/tls"
+	"github.com/zmap/zcrypto/x509"
+
+	"github.com/zmap/zgrab2"
+)
+
+// Named QUIC versions besides version 1.
+const (
+	version2              = 0x6b3343cf
+	versionDraftPrefix    = 0xff000000
+	versionNegotiationVer = 0x1a2a3a4a
+)
+
+// versionName returns the name of a QUIC version: "v1", "v2", "draft-NN", "grease" for the reserved versions,
+// the four-character tag of Google QUIC versions such as "Q046", or the version in hex.
+func versionName(version uint32) string {
+	switch {
+	case version == version1:
+		return "v1"
+	case version == version2:
+		return "v2"
+	case version&0xffffff00 == versionDraftPrefix:
+		return fmt.Sprintf("draft-%d", version&0xff)
+	case version&0x0f0f0f0f == 0x0a0a0a0a:
+		return "grease"
+	case version>>24 == 'Q' || version>>24 == 'T':
+		tag := binary.BigEndian.AppendUint32(nil, version)
+		if isPrintable(tag) {
+			return string(tag)
+		}
+	}
+	return fmt.Sprintf("0x%08x", version)
+}
+
+func isPrintable(b []byte) bool {
+	for _, c := range b {
+		if c < 0x20 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// VersionNegotiation is true if the server answered the Initial with a reserved version with a Version
+	// Negotiation packet, and SupportedVersions are the versions it listed.
+	VersionNegotiation bool     `json:"version_negotiation"`
+	SupportedVersions  []string `json:"supported_versions,omitempty"`
+
+	// Version is the version of the handshake, if the server answered it.
+	Version string `json:"version,omitempty"`
+
+	// Retry is true if the server sent a Retry packet to validate the scanner's address.
+	Retry bool `json:"retry"`
+
+	TransportParameters *TransportParameters `json:"transport_parameters,omitempty"`
+
+	CipherSuite string `json:"cipher_suite,omitempty"`
+	ALPN        string `json:"alpn,omitempty"`
+
+	Certificates []ztls.SimpleCertificate `json:"certificates,omitempty"`
+
+	// ConnectionClose is the CONNECTION_CLOSE frame the server ended the handshake with, if any.
+	ConnectionClose *ConnectionClose `json:"connection_close,omitempty"`
+
+	HandshakeComplete bool `json:"handshake_complete"`
+
+	// Probes are the version negotiation probe and the client's first Initial.
+	Probes []*zgrab2.UDPProbeResult `json:"probes,omitempty"`
+}
+
+// Flags are the QUIC-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.UDPFlags
+
+	ServerName           string `long:"server-name" description:"Server name sent in the TLS SNI extension; defaults to the target's domain name"`
+	ALPN                 string `long:"alpn" default:"h3" description:"Comma-separated ALPN protocols to offer"`
+	NoVersionNegotiation bool   `long:"no-version-negotiation" description:"Do not probe for the server's supported versions"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the quic zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("quic", "QUIC", module.Description(), 443, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Perform the QUIC handshake and record the supported versions, transport parameters and certificates"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return f.UDPFlags.Validate()
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "quic"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportUDP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// buildVersionProbe returns a datagram with an Initial-like long header packet of a reserved version, with the
+// given connection IDs, padded to the minimum Initial size.
+func buildVersionProbe(dcid, scid []byte) []byte {
+	b := []byte{0xC0}
+	b = binary.BigEndian.AppendUint32(b, versionNegotiationVer)
+	b = append(b, byte(len(dcid)))
+	b = append(b, dcid...)
+	b = append(b, byte(len(scid)))
+	b = append(b, scid...)
+	return append(b, make([]byte, minInitialDatagramSize-len(b))...)
+}
+
+// negotiateVersions sends the version negotiation probe and records the versions the server lists.
+func (scanner *Scanner) negotiateVersions(ctx context.Context, conn net.Conn, target *zgrab2.ScanTarget, results *ScanResults) error {
+	ids := make([]byte, 16)
+	if _, err := rand.Read(ids); err != nil {
+		return err
+	}
+	scid := ids[8:]
+	probe := zgrab2.NewStaticUDPProbe("version-negotiation", buildVersionProbe(ids[:8], scid), func(_, response []byte) bool {
+		p, _, err := parseLongHeader(response)
+		return err == nil && p.version == 0 && bytes.Equal(p.dcid, scid)
+	})
+	result, err := zgrab2.SendUDPProbe(ctx, conn, probe, target, &scanner.config.UDPFlags)
+	results.Probes = append(results.Probes, result)
+	if err != nil {
+		return err
+	}
+	p, _, _ := parseLongHeader(result.Response)
+	results.setVersions(p.versions)
+	return nil
+}
+
+// setVersions records the versions of a Version Negotiation packet.
+func (results *ScanResults) setVersions(versions []uint32) {
+	results.VersionNegotiation = true
+	results.SupportedVersions = nil
+	for _, version := range versions {
+		results.SupportedVersions = append(results.SupportedVersions, versionName(version))
+	}
+}
+
+// tlsConfig returns the TLS configuration of the handshake with the target.
+func (scanner *Scanner) tlsConfig(target *zgrab2.ScanTarget) *tls.Config {
+	serverName := scanner.config.ServerName
+	if serverName == "" {
+		serverName = target.Domain
+	}
+	return &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS13,
+		NextProtos:         strings.Split(scanner.config.ALPN, ","),
+		// a single key share keeps the ClientHello within one Initial packet
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+		KeyLogWriter:     zgrab2.TLSKeyLogWriter(),
+	}
+}
+
+// Scan performs the configured scan on the QUIC server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	results := new(ScanResults)
+	if !scanner.config.NoVersionNegotiation {
+		if err := scanner.negotiateVersions(ctx, conn, target, results); err != nil {
+			log.Debugf("no version negotiation from target %s: %v", target.String(), err)
+		} else if !slices.Contains(results.SupportedVersions, versionName(version1)) {
+			return zgrab2.SCAN_SUCCESS, results, nil
+		}
+	}
+
+	h, err := newHandshake(ctx, conn, scanner.tlsConfig(target))
+	if err != nil {
+		return zgrab2.SCAN_UNKNOWN_ERROR, nil, fmt.Errorf("could not start TLS handshake with target %s: %w", target.String(), err)
+	}
+	defer h.closeConnection()
+
+	// the first Initial, and the one after a Retry, are retransmitted until the server answers
+	for {
+		initial := h.flush(nil)
+		probe := zgrab2.NewStaticUDPProbe("initial", initial, func(_, response []byte) bool {
+			packets, _ := splitDatagram(response)
+			for _, p := range packets {
+				if bytes.Equal(p.dcid, h.scid) {
+					return true
+				}
+			}
+			return false
+		})
+		result, err := zgrab2.SendUDPProbe(ctx, conn, probe, target, &scanner.config.UDPFlags)
+		results.Probes = append(results.Probes, result)
+		if err != nil {
+			return scanner.status(err, results)
+		}
+		err = h.handleDatagram(result.Response)
+		if errors.Is(err, errRetry) {
+			results.Retry = true
+			continue
+		}
+		if errors.Is(err, errVersionNegotiation) {
+			// the server doesn't support version 1 after all
+			results.setVersions(h.versions)
+			return zgrab2.SCAN_SUCCESS, results, nil
+		}
+		if err != nil {
+			return zgrab2.SCAN_PROTOCOL_ERROR, results, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+		}
+		break
+	}
+
+	err = h.run(ctx, scanner.config.TryTimeout, scanner.config.Retries)
+	paramsErr := scanner.record(h, results)
+	if err != nil {
+		if errors.Is(err, errVersionNegotiation) {
+			results.setVersions(h.versions)
+			return zgrab2.SCAN_SUCCESS, results, nil
+		}
+		return scanner.status(err, results)
+	}
+	if paramsErr != nil {
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, paramsErr)
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
+
+// status returns the status of a scan that failed with err: a timeout or an ICMP error before the server answered,
+// or a protocol error after it.
+func (scanner *Scanner) status(err error, results *ScanResults) (zgrab2.ScanStatus, any, error) {
+	status := zgrab2.TryGetScanStatus(err)
+	if status == zgrab2.SCAN_UNKNOWN_ERROR {
+		status = zgrab2.SCAN_PROTOCOL_ERROR
+		err = zgrab2.NewScanError(status, err)
+	}
+	if !results.VersionNegotiation && results.Version == "" {
+		return status, nil, err
+	}
+	return status, results, err
+}
+
+// record copies the outcome of the handshake into the results. It returns an error if the server's transport
+// parameters are invalid.
+func (scanner *Scanner) record(h *handshake, results *ScanResults) error {
+	if h.serverInitial {
+		results.Version = versionName(version1)
+	}
+	results.ConnectionClose = h.close
+	results.HandshakeComplete = h.handshakeDone
+	state := h.tls.ConnectionState()
+	if state.CipherSuite != 0 {
+		results.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	}
+	results.ALPN = state.NegotiatedProtocol
+	for _, cert := range state.PeerCertificates {
+		parsed, _ := x509.ParseCertificate(cert.Raw)
+		results.Certificates = append(results.Certificates, ztls.SimpleCertificate{Raw: cert.Raw, Parsed: parsed})
+	}
+	var err error
+	results.TransportParameters, err = h.parameters()
+	return err
+}
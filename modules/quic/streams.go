@@ -0,0 +1,105 @@
+package quic
+
+import (
+	"slices"
+)
+
+// Stream ID bits (RFC 9000 section 2.1): the low bit is set for streams initiated by the server, the next bit for
+// unidirectional streams.
+const (
+	streamServerInitiated = 0x01
+	streamUnidirectional  = 0x02
+)
+
+// stream is the state of one stream of a Conn: the data queued for the server and the data received from it.
+type stream struct {
+	send       []byte
+	sendFin    bool
+	sentOffset uint64
+	finSent    bool
+
+	recv cryptoStream
+	data []byte
+
+	// finalSize is the size of the server's data, known once fin is set. reset is set if the server abandoned the
+	// stream with RESET_STREAM.
+	finalSize uint64
+	fin       bool
+	reset     bool
+}
+
+// done reports whether all the server's data on the stream has arrived, or the server reset it.
+func (st *stream) done() bool {
+	return st.reset || (st.fin && uint64(len(st.data)) >= st.finalSize)
+}
+
+// getStream returns the state of a stream, creating it on first use and recording streams opened by the server.
+func (h *handshake) getStream(id uint64) *stream {
+	st, ok := h.streams[id]
+	if !ok {
+		st = new(stream)
+		h.streams[id] = st
+		if id&streamServerInitiated != 0 {
+			h.peerStreams = append(h.peerStreams, id)
+		}
+	}
+	return st
+}
+
+// receiveStream stores the data of a STREAM or RESET_STREAM frame.
+func (h *handshake) receiveStream(sf streamFrame) {
+	st := h.getStream(sf.id)
+	if sf.reset {
+		st.reset = true
+		return
+	}
+	st.data = append(st.data, st.recv.add(map[uint64][]byte{sf.offset: sf.data})...)
+	if sf.fin {
+		st.fin = true
+		st.finalSize = sf.offset + uint64(len(sf.data))
+	}
+}
+
+// streamFrames returns STREAM frames carrying up to maxStreamFrameData bytes of the queued stream data, and marks
+// that data as sent.
+func (h *handshake) streamFrames() []byte {
+	ids := make([]uint64, 0, len(h.streams))
+	for id := range h.streams {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	var b []byte
+	budget := maxStreamFrameData
+	for _, id := range ids {
+		st := h.streams[id]
+		pending := st.send[st.sentOffset:]
+		if len(pending) == 0 && (!st.sendFin || st.finSent) {
+			continue
+		}
+		if budget == 0 {
+			break
+		}
+		if len(pending) > budget {
+			pending = pending[:budget]
+		}
+		fin := st.sendFin && st.sentOffset+uint64(len(pending)) == uint64(len(st.send))
+		b = append(b, buildStreamFrame(id, st.sentOffset, pending, fin)...)
+		st.sentOffset += uint64(len(pending))
+		st.finSent = fin
+		budget -= len(pending)
+	}
+	return b
+}
+
+// buildStreamFrame returns a STREAM frame with explicit offset and length fields.
+func buildStreamFrame(id, offset uint64, data []byte, fin bool) []byte {
+	frameType := byte(frameStream | 0x04 | 0x02)
+	if fin {
+		frameType |= 0x01
+	}
+	b := appendVarInt([]byte{frameType}, id)
+	b = appendVarInt(b, offset)
+	b = appendVarInt(b, uint64(len(data)))
+	return append(b, data...)
+}
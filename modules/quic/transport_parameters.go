@@ -0,0 +1,173 @@
+package quic
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+)
+
+// Transport parameter IDs (RFC 9000 section 18.2, RFC 9221, RFC 9287 and RFC 9368).
+const (
+	paramOriginalDestinationConnectionID = 0x00
+	paramMaxIdleTimeout                  = 0x01
+	paramStatelessResetToken             = 0x02
+	paramMaxUDPPayloadSize               = 0x03
+	paramInitialMaxData                  = 0x04
+	paramInitialMaxStreamDataBidiLocal   = 0x05
+	paramInitialMaxStreamDataBidiRemote  = 0x06
+	paramInitialMaxStreamDataUni         = 0x07
+	paramInitialMaxStreamsBidi           = 0x08
+	paramInitialMaxStreamsUni            = 0x09
+	paramAckDelayExponent                = 0x0A
+	paramMaxAckDelay                     = 0x0B
+	paramDisableActiveMigration          = 0x0C
+	paramPreferredAddress                = 0x0D
+	paramActiveConnectionIDLimit         = 0x0E
+	paramInitialSourceConnectionID       = 0x0F
+	paramRetrySourceConnectionID         = 0x10
+	paramVersionInformation              = 0x11
+	paramMaxDatagramFrameSize            = 0x20
+	paramGreaseQUICBit                   = 0x2AB2
+)
+
+// buildTransportParameters returns the client's transport parameters: modest flow control limits, and the source
+// connection ID it uses.
+func buildTransportParameters(scid []byte) []byte {
+	var b []byte
+	appendInt := func(id, value uint64) {
+		encoded := appendVarInt(nil, value)
+		b = appendVarInt(appendVarInt(b, id), uint64(len(encoded)))
+		b = append(b, encoded...)
+	}
+	appendInt(paramMaxIdleTimeout, 30000)
+	appendInt(paramInitialMaxData, 1<<20)
+	appendInt(paramInitialMaxStreamDataBidiLocal, 1<<18)
+	appendInt(paramInitialMaxStreamDataBidiRemote, 1<<18)
+	appendInt(paramInitialMaxStreamDataUni, 1<<18)
+	appendInt(paramInitialMaxStreamsBidi, 100)
+	appendInt(paramInitialMaxStreamsUni, 100)
+	b = appendVarInt(appendVarInt(b, paramInitialSourceConnectionID), uint64(len(scid)))
+	return append(b, scid...)
+}
+
+// TransportParameters are the server's transport parameters. Integer parameters the server omitted have their
+// default values, which are 0 except for MaxUDPPayloadSize, AckDelayExponent, MaxAckDelay and
+// ActiveConnectionIDLimit.
+type TransportParameters struct {
+	OriginalDestinationConnectionID string `json:"original_destination_connection_id,omitempty"`
+	InitialSourceConnectionID       string `json:"initial_source_connection_id,omitempty"`
+	RetrySourceConnectionID         string `json:"retry_source_connection_id,omitempty"`
+	StatelessResetToken             string `json:"stateless_reset_token,omitempty"`
+
+	MaxIdleTimeout                 uint64 `json:"max_idle_timeout"`
+	MaxUDPPayloadSize              uint64 `json:"max_udp_payload_size"`
+	InitialMaxData                 uint64 `json:"initial_max_data"`
+	InitialMaxStreamDataBidiLocal  uint64 `json:"initial_max_stream_data_bidi_local"`
+	InitialMaxStreamDataBidiRemote uint64 `json:"initial_max_stream_data_bidi_remote"`
+	InitialMaxStreamDataUni        uint64 `json:"initial_max_stream_data_uni"`
+	InitialMaxStreamsBidi          uint64 `json:"initial_max_streams_bidi"`
+	InitialMaxStreamsUni           uint64 `json:"initial_max_streams_uni"`
+	AckDelayExponent               uint64 `json:"ack_delay_exponent"`
+	MaxAckDelay                    uint64 `json:"max_ack_delay"`
+	ActiveConnectionIDLimit        uint64 `json:"active_connection_id_limit"`
+	MaxDatagramFrameSize           uint64 `json:"max_datagram_frame_size,omitempty"`
+
+	DisableActiveMigration bool `json:"disable_active_migration"`
+	PreferredAddress       bool `json:"preferred_address"`
+	GreaseQUICBit          bool `json:"grease_quic_bit"`
+
+	// ChosenVersion and AvailableVersions are from the version_information parameter.
+	ChosenVersion     string   `json:"chosen_version,omitempty"`
+	AvailableVersions []string `json:"available_versions,omitempty"`
+
+	// Unknown are the IDs of the parameters not decoded above, including GREASE parameters.
+	Unknown []uint64 `json:"unknown,omitempty"`
+}
+
+// parseTransportParameters decodes the server's transport parameters.
+func parseTransportParameters(b []byte) (*TransportParameters, error) {
+	params := &TransportParameters{
+		MaxUDPPayloadSize:       65527,
+		AckDelayExponent:        3,
+		MaxAckDelay:             25,
+		ActiveConnectionIDLimit: 2,
+	}
+	for len(b) > 0 {
+		id, rest, err := readVarInt(b)
+		if err != nil {
+			return params, err
+		}
+		length, rest, err := readVarInt(rest)
+		if err != nil || uint64(len(rest)) < length {
+			return params, errInvalidPacket
+		}
+		value := rest[:length]
+		b = rest[length:]
+
+		integer, _, intErr := readVarInt(value)
+		switch id {
+		case paramOriginalDestinationConnectionID:
+			params.OriginalDestinationConnectionID = hex.EncodeToString(value)
+		case paramInitialSourceConnectionID:
+			params.InitialSourceConnectionID = hex.EncodeToString(value)
+		case paramRetrySourceConnectionID:
+			params.RetrySourceConnectionID = hex.EncodeToString(value)
+		case paramStatelessResetToken:
+			params.StatelessResetToken = hex.EncodeToString(value)
+		case paramDisableActiveMigration:
+			params.DisableActiveMigration = true
+		case paramPreferredAddress:
+			params.PreferredAddress = true
+		case paramGreaseQUICBit:
+			params.GreaseQUICBit = true
+		case paramVersionInformation:
+			if len(value) < 4 || len(value)%4 != 0 {
+				return params, fmt.Errorf("invalid version_information parameter")
+			}
+			params.ChosenVersion = versionName(binary.BigEndian.Uint32(value))
+			for i := 4; i < len(value); i += 4 {
+				params.AvailableVersions = append(params.AvailableVersions, versionName(binary.BigEndian.Uint32(value[i:])))
+			}
+		case paramMaxIdleTimeout, paramMaxUDPPayloadSize, paramInitialMaxData, paramInitialMaxStreamDataBidiLocal,
+			paramInitialMaxStreamDataBidiRemote, paramInitialMaxStreamDataUni, paramInitialMaxStreamsBidi,
+			paramInitialMaxStreamsUni, paramAckDelayExponent, paramMaxAckDelay, paramActiveConnectionIDLimit,
+			paramMaxDatagramFrameSize:
+			if intErr != nil {
+				return params, fmt.Errorf("invalid integer transport parameter %#x", id)
+			}
+			*params.integer(id) = integer
+		default:
+			params.Unknown = append(params.Unknown, id)
+		}
+	}
+	return params, nil
+}
+
+// integer returns the field of an integer parameter.
+func (params *TransportParameters) integer(id uint64) *uint64 {
+	switch id {
+	case paramMaxIdleTimeout:
+		return &params.MaxIdleTimeout
+	case paramMaxUDPPayloadSize:
+		return &params.MaxUDPPayloadSize
+	case paramInitialMaxData:
+		return &params.InitialMaxData
+	case paramInitialMaxStreamDataBidiLocal:
+		return &params.InitialMaxStreamDataBidiLocal
+	case paramInitialMaxStreamDataBidiRemote:
+		return &params.InitialMaxStreamDataBidiRemote
+	case paramInitialMaxStreamDataUni:
+		return &params.InitialMaxStreamDataUni
+	case paramInitialMaxStreamsBidi:
+		return &params.InitialMaxStreamsBidi
+	case paramInitialMaxStreamsUni:
+		return &params.InitialMaxStreamsUni
+	case paramAckDelayExponent:
+		return &params.AckDelayExponent
+	case paramMaxAckDelay:
+		return &params.MaxAckDelay
+	case paramActiveConnectionIDLimit:
+		return &params.ActiveConnectionIDLimit
+	}
+	return &params.MaxDatagramFrameSize
+}
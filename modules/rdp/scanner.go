@@ -0,0 +1,255 @@
+// Package rdp contains the zgrab2 Module implementation for the Remote Desktop Protocol.
+//
+// The scan sends an X.224 Connection Request carrying an RDP Negotiation Request and records the server's
+// negotiation response. If the server selects a TLS-based security protocol (TLS, CredSSP/NLA, ...), the connection is
+// upgraded to TLS so that the server certificate is captured.
+package rdp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// Negotiation is the server's response to the initial connection request.
+	Negotiation *NegotiationResult `json:"negotiation,omitempty"`
+
+	// SupportedProtocols lists the security protocols the server accepted when offered alone. Only set if
+	// --enumerate is given.
+	SupportedProtocols []string `json:"supported_protocols,omitempty"`
+
+	// TLSLog is the TLS handshake, if the server selected a TLS-based security protocol.
+	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
+}
+
+// Flags are the RDP-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.TLSFlags
+
+	Protocols string `long:"protocols" default:"ssl,hybrid,hybrid_ex" description:"Comma-separated security protocols to request (rdp, ssl, hybrid, rdstls, hybrid_ex)"`
+	Cookie    string `long:"cookie" description:"Username to send in the mstshash routing cookie"`
+	Enumerate bool   `long:"enumerate" description:"Open one connection per security protocol to enumerate all protocols the server supports"`
+	NoTLS     bool   `long:"no-tls" description:"Do not perform the TLS handshake when the server selects a TLS-based protocol"`
+
+	requested uint32
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the rdp zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("rdp", "Remote Desktop Protocol (RDP)", module.Description(), 3389, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Negotiate RDP security protocols and grab the server certificate"
+}
+
+// parseProtocols converts a comma-separated list of protocol names into a requestedProtocols bitmask.
+func parseProtocols(protocols string) (uint32, error) {
+	var requested uint32
+	for _, name := range strings.Split(protocols, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for value, protocolName := range protocolNames {
+			if protocolName == name {
+				requested |= value
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown RDP security protocol %q", name)
+		}
+	}
+	return requested, nil
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	requested, err := parseProtocols(f.Protocols)
+	if err != nil {
+		return err
+	}
+	f.requested = requested
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "rdp"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		NeedSeparateL4Dialer:            true,
+		BaseFlags:                       &f.BaseFlags,
+		TLSEnabled:                      true,
+		TLSFlags:                        &f.TLSFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// negotiate opens a new connection and sends a connection request for the given protocols. The caller must close the
+// returned connection.
+func (scanner *Scanner) negotiate(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget, requested uint32) (net.Conn, *NegotiationResult, error) {
+	if dialGroup.L4Dialer == nil {
+		return nil, nil, errors.New("l4 dialer is required for rdp")
+	}
+	conn, err := dialGroup.L4Dialer(target)(ctx, "tcp", net.JoinHostPort(target.Host(), strconv.Itoa(int(target.Port))))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	if _, err = conn.Write(buildConnectionRequest(requested, scanner.config.Cookie)); err != nil {
+		zgrab2.CloseConnAndHandleError(conn)
+		return nil, nil, fmt.Errorf("error sending connection request to target %s: %w", target.String(), err)
+	}
+	result, err := readConnectionConfirm(conn)
+	if err != nil {
+		zgrab2.CloseConnAndHandleError(conn)
+		return nil, nil, fmt.Errorf("error reading connection confirm from target %s: %w", target.String(), err)
+	}
+	return conn, result, nil
+}
+
+// usesTLS returns true if the selected protocol starts with a TLS handshake.
+func (result *NegotiationResult) usesTLS() bool {
+	return result.SelectedProtocol != "" && result.selected != ProtocolRDP
+}
+
+// enumerate offers each security protocol on its own and returns the ones the server accepted.
+func (scanner *Scanner) enumerate(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) []string {
+	// CredSSP-based protocols must be offered together with TLS, see [MS-RDPBCGR] 2.2.1.1.1
+	candidates := []struct {
+		name      string
+		requested uint32
+		selected  uint32
+	}{
+		{"rdp", ProtocolRDP, ProtocolRDP},
+		{"ssl", ProtocolSSL, ProtocolSSL},
+		{"hybrid", ProtocolSSL | ProtocolHybrid, ProtocolHybrid},
+		{"rdstls", ProtocolSSL | ProtocolRDSTLS, ProtocolRDSTLS},
+		{"hybrid_ex", ProtocolSSL | ProtocolHybrid | ProtocolHybridEx, ProtocolHybridEx},
+	}
+	var supported []string
+	for _, candidate := range candidates {
+		conn, result, err := scanner.negotiate(ctx, dialGroup, target, candidate.requested)
+		if err != nil {
+			log.Debugf("rdp: could not negotiate %s with %s: %v", candidate.name, target.String(), err)
+			continue
+		}
+		zgrab2.CloseConnAndHandleError(conn)
+		if result.Failure != "" {
+			continue
+		}
+		if result.selected == candidate.selected || (result.legacy && candidate.selected == ProtocolRDP) {
+			supported = append(supported, candidate.name)
+		}
+	}
+	return supported
+}
+
+// Scan performs the configured scan on the RDP server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, negotiation, err := scanner.negotiate(ctx, dialGroup, target, scanner.config.requested)
+	if err != nil {
+		if errors.Is(err, errInvalidResponse) {
+			return zgrab2.SCAN_PROTOCOL_ERROR, nil, err
+		}
+		return zgrab2.TryGetScanStatus(err), nil, err
+	}
+	defer func() {
+		zgrab2.CloseConnAndHandleError(conn)
+	}()
+
+	results := &ScanResults{Negotiation: negotiation}
+	if negotiation.usesTLS() && !scanner.config.NoTLS {
+		if dialGroup.TLSWrapper == nil {
+			return zgrab2.SCAN_UNKNOWN_ERROR, results, errors.New("dial group does not have a TLS wrapper")
+		}
+		tlsConn, err := dialGroup.TLSWrapper(ctx, target, conn)
+		if tlsConn != nil {
+			results.TLSLog = tlsConn.GetLog()
+			conn = tlsConn
+		}
+		if err != nil {
+			return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error performing TLS handshake with target %s: %w", target.String(), err)
+		}
+	}
+
+	if scanner.config.Enumerate {
+		results.SupportedProtocols = scanner.enumerate(ctx, dialGroup, target)
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
@@ -0,0 +1,168 @@
+package rdp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Security protocols from the RDP Negotiation Request/Response, see [MS-RDPBCGR] 2.2.1.1.1
+const (
+	ProtocolRDP      uint32 = 0x00000000
+	ProtocolSSL      uint32 = 0x00000001
+	ProtocolHybrid   uint32 = 0x00000002
+	ProtocolRDSTLS   uint32 = 0x00000004
+	ProtocolHybridEx uint32 = 0x00000008
+	ProtocolRDSAAD   uint32 = 0x00000010
+)
+
+var protocolNames = map[uint32]string{
+	ProtocolRDP:      "rdp",
+	ProtocolSSL:      "ssl",
+	ProtocolHybrid:   "hybrid",
+	ProtocolRDSTLS:   "rdstls",
+	ProtocolHybridEx: "hybrid_ex",
+	ProtocolRDSAAD:   "rdsaad",
+}
+
+// ProtocolName returns the name of a selected security protocol
+func ProtocolName(protocol uint32) string {
+	if name, ok := protocolNames[protocol]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown (0x%08x)", protocol)
+}
+
+// RDP_NEG_FAILURE failure codes, see [MS-RDPBCGR] 2.2.1.2.2
+var failureCodeNames = map[uint32]string{
+	1: "ssl_required_by_server",
+	2: "ssl_not_allowed_by_server",
+	3: "ssl_cert_not_on_server",
+	4: "inconsistent_flags",
+	5: "hybrid_required_by_server",
+	6: "ssl_with_user_auth_required_by_server",
+}
+
+// Negotiation message types
+const (
+	typeNegReq     = 0x01
+	typeNegRsp     = 0x02
+	typeNegFailure = 0x03
+)
+
+// X.224 TPDU codes
+const (
+	x224ConnectionRequest = 0xE0
+	x224ConnectionConfirm = 0xD0
+)
+
+// NegotiationFlags are the flags of an RDP_NEG_RSP, see [MS-RDPBCGR] 2.2.1.2.1
+type NegotiationFlags struct {
+	ExtendedClientDataSupported           bool  `json:"extended_client_data_supported"`
+	DynVCGFXProtocolSupported             bool  `json:"dynvc_gfx_protocol_supported"`
+	RestrictedAdminModeSupported          bool  `json:"restricted_admin_mode_supported"`
+	RedirectedAuthenticationModeSupported bool  `json:"redirected_authentication_mode_supported"`
+	Raw                                   uint8 `json:"raw"`
+}
+
+func parseNegotiationFlags(flags uint8) *NegotiationFlags {
+	return &NegotiationFlags{
+		ExtendedClientDataSupported:           flags&0x01 != 0,
+		DynVCGFXProtocolSupported:             flags&0x02 != 0,
+		RestrictedAdminModeSupported:          flags&0x08 != 0,
+		RedirectedAuthenticationModeSupported: flags&0x10 != 0,
+		Raw:                                   flags,
+	}
+}
+
+// NegotiationResult is the server's reply to a single X.224 Connection Request
+type NegotiationResult struct {
+	// SelectedProtocol is set if the server sent an RDP_NEG_RSP, or left empty if it sent none, which means it only
+	// supports standard RDP security.
+	SelectedProtocol string            `json:"selected_protocol,omitempty"`
+	Flags            *NegotiationFlags `json:"flags,omitempty"`
+	// Failure is set if the server sent an RDP_NEG_FAILURE
+	Failure     string `json:"failure,omitempty"`
+	FailureCode uint32 `json:"failure_code,omitempty"`
+
+	selected uint32
+	legacy   bool
+}
+
+var errInvalidResponse = errors.New("invalid X.224 connection confirm")
+
+// buildConnectionRequest returns a TPKT-framed X.224 Connection Request carrying an RDP Negotiation Request for the
+// given protocols, and an optional routing cookie.
+func buildConnectionRequest(requested uint32, cookie string) []byte {
+	var variable []byte
+	if cookie != "" {
+		variable = append(variable, "Cookie: mstshash="+cookie+"\r\n"...)
+	}
+	variable = append(variable, typeNegReq, 0x00, 0x08, 0x00)
+	variable = binary.LittleEndian.AppendUint32(variable, requested)
+
+	x224 := []byte{byte(6 + len(variable)), x224ConnectionRequest, 0, 0, 0, 0, 0}
+	x224 = append(x224, variable...)
+
+	tpkt := []byte{0x03, 0x00, 0, 0}
+	binary.BigEndian.PutUint16(tpkt[2:4], uint16(4+len(x224)))
+	return append(tpkt, x224...)
+}
+
+// readConnectionConfirm reads a TPKT-framed X.224 Connection Confirm and parses its negotiation response, if any.
+func readConnectionConfirm(r io.Reader) (*NegotiationResult, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if header[0] != 0x03 {
+		return nil, fmt.Errorf("%w: bad TPKT version 0x%02x", errInvalidResponse, header[0])
+	}
+	length := int(binary.BigEndian.Uint16(header[2:4]))
+	if length < 11 || length > 4096 {
+		return nil, fmt.Errorf("%w: bad TPKT length %d", errInvalidResponse, length)
+	}
+	body := make([]byte, length-4)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return parseConnectionConfirm(body)
+}
+
+// parseConnectionConfirm parses the X.224 part of a Connection Confirm.
+func parseConnectionConfirm(body []byte) (*NegotiationResult, error) {
+	if len(body) < 7 || body[1]&0xF0 != x224ConnectionConfirm {
+		return nil, errInvalidResponse
+	}
+	li := int(body[0])
+	if li < 6 {
+		// the fixed part of a Connection Confirm is 6 bytes
+		return nil, fmt.Errorf("%w: length indicator %d too short", errInvalidResponse, li)
+	}
+	if li+1 > len(body) {
+		return nil, fmt.Errorf("%w: length indicator %d exceeds TPDU", errInvalidResponse, li)
+	}
+	variable := body[7 : li+1]
+	if len(variable) < 8 {
+		// no negotiation response: the server only speaks standard RDP security
+		return &NegotiationResult{legacy: true}, nil
+	}
+	value := binary.LittleEndian.Uint32(variable[4:8])
+	switch variable[0] {
+	case typeNegRsp:
+		return &NegotiationResult{
+			SelectedProtocol: ProtocolName(value),
+			Flags:            parseNegotiationFlags(variable[1]),
+			selected:         value,
+		}, nil
+	case typeNegFailure:
+		name, ok := failureCodeNames[value]
+		if !ok {
+			name = fmt.Sprintf("unknown (%d)", value)
+		}
+		return &NegotiationResult{Failure: name, FailureCode: value}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown negotiation type 0x%02x", errInvalidResponse, variable[0])
+	}
+}
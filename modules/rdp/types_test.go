@@ -0,0 +1,65 @@
+package rdp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestBuildConnectionRequest(t *testing.T) {
+	got := buildConnectionRequest(ProtocolSSL|ProtocolHybrid, "")
+	want, _ := hex.DecodeString("030000130ee000000000000100080003000000")
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %x, want %x", got, want)
+	}
+}
+
+func TestReadConnectionConfirm(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		selected string
+		failure  string
+		legacy   bool
+		flags    uint8
+	}{
+		{"hybrid", "030000130ed000001234000209080002000000", "hybrid", "", false, 0x09},
+		{"failure", "030000130ed000001234000300080005000000", "", "hybrid_required_by_server", false, 0},
+		{"legacy", "0300000b06d00000123400", "", "", true, 0},
+	}
+	for _, test := range tests {
+		raw, _ := hex.DecodeString(test.input)
+		result, err := readConnectionConfirm(bytes.NewReader(raw))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if result.SelectedProtocol != test.selected || result.Failure != test.failure || result.legacy != test.legacy {
+			t.Errorf("%s: got %+v", test.name, result)
+		}
+		if test.flags != 0 && (result.Flags == nil || result.Flags.Raw != test.flags || !result.Flags.RestrictedAdminModeSupported) {
+			t.Errorf("%s: bad flags %+v", test.name, result.Flags)
+		}
+	}
+
+	if _, err := readConnectionConfirm(bytes.NewReader([]byte("SSH-2.0-OpenSSH\r\n"))); err == nil {
+		t.Error("expected error for non-RDP response")
+	}
+	// length indicators shorter than the fixed part of the TPDU
+	for _, body := range [][]byte{{2, 0xD0, 0, 0, 0, 0, 0}, {5, 0xD0, 0, 0, 0, 0, 0, 0}} {
+		if _, err := parseConnectionConfirm(body); !errors.Is(err, errInvalidResponse) {
+			t.Errorf("%x: expected errInvalidResponse, got %v", body, err)
+		}
+	}
+}
+
+func TestParseProtocols(t *testing.T) {
+	requested, err := parseProtocols("ssl, hybrid_ex")
+	if err != nil || requested != ProtocolSSL|ProtocolHybridEx {
+		t.Errorf("got 0x%x, %v", requested, err)
+	}
+	if _, err = parseProtocols("ssl,bogus"); err == nil {
+		t.Error("expected error for unknown protocol")
+	}
+}
@@ -84,6 +84,15 @@ type Result struct {
 	// AuthResponse is only included if --password is set.
 	AuthResponse string `json:"auth_response,omitempty"`
 
+	// AuthRequired is true if the server refused a command with a NOAUTH
+	// error, i.e. a password is required.
+	AuthRequired bool `json:"auth_required,omitempty"`
+
+	// ProtectedMode is true if the server refused the PING command with a
+	// DENIED error: it runs in protected mode, without a password, and only
+	// accepts connections from the loopback interface.
+	ProtectedMode bool `json:"protected_mode,omitempty"`
+
 	// InfoResponse is the response from the INFO command: "Lines can contain a
 	// section name (starting with a # character) or a property. All the
 	// properties are in the form of field:value terminated by \r\n."
@@ -146,6 +155,18 @@ type Result struct {
 	// if present. It specifies the total number of commands processed by the server.
 	CommandsProcessed uint32 `json:"total_commands_processed,omitempty"`
 
+	// InfoSections are the sections of the InfoResponse with their fields,
+	// in order.
+	InfoSections []InfoSection `json:"info_sections,omitempty"`
+
+	// Modules lists the modules loaded by the server, from MODULE LIST.
+	// Only sent if INFO succeeds.
+	Modules []LoadedModule `json:"modules,omitempty"`
+
+	// Config holds the values of selected CONFIG GET commands. Only sent if
+	// INFO succeeds.
+	Config *Config `json:"config,omitempty"`
+
 	// NonexistentResponse is the response to the non-existent command; even if
 	// auth is required, this may give a different error than existing commands.
 	NonexistentResponse string `json:"nonexistent_response,omitempty"`
@@ -279,6 +300,8 @@ func (scanner *Scanner) initCommands() error {
 		"PING":        "PING",
 		"AUTH":        "AUTH",
 		"INFO":        "INFO",
+		"MODULE":      "MODULE",
+		"CONFIG":      "CONFIG",
 		"NONEXISTENT": "NONEXISTENT",
 		"QUIT":        "QUIT",
 	}
@@ -363,6 +386,80 @@ func forceToString(val RedisValue) string {
 	}
 }
 
+// recordRefusal flags the result if val is the error of a server in
+// protected mode (DENIED) or one requiring a password (NOAUTH).
+func (result *Result) recordRefusal(val RedisValue) {
+	if err, ok := val.(ErrorMessage); ok {
+		switch err.ErrorPrefix() {
+		case "DENIED":
+			result.ProtectedMode = true
+		case "NOAUTH":
+			result.AuthRequired = true
+		}
+	}
+}
+
+// parseInfoSections splits the response of the INFO command into its
+// sections. Fields before the first section header are put in a section
+// without a name.
+func parseInfoSections(info string) []InfoSection {
+	var ret []InfoSection
+	for _, line := range strings.Split(info, "\r\n") {
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#"):
+			ret = append(ret, InfoSection{Name: strings.TrimSpace(line[1:])})
+		default:
+			if len(ret) == 0 {
+				ret = append(ret, InfoSection{})
+			}
+			name, value, _ := strings.Cut(line, ":")
+			section := &ret[len(ret)-1]
+			section.Fields = append(section.Fields, InfoField{Name: name, Value: value})
+		}
+	}
+	return ret
+}
+
+// parseModuleList reads the modules from the response of MODULE LIST, an
+// array with an array of name/value pairs for each module.
+func parseModuleList(val RedisValue) []LoadedModule {
+	modules, ok := val.(RedisArray)
+	if !ok {
+		return nil
+	}
+	var ret []LoadedModule
+	for _, module := range modules {
+		pairs, ok := module.(RedisArray)
+		if !ok {
+			continue
+		}
+		var loaded LoadedModule
+		for i := 0; i+1 < len(pairs); i += 2 {
+			switch forceToString(pairs[i]) {
+			case "name":
+				loaded.Name = forceToString(pairs[i+1])
+			case "ver":
+				if version, ok := pairs[i+1].(Integer); ok {
+					loaded.Version = int64(version)
+				}
+			}
+		}
+		ret = append(ret, loaded)
+	}
+	return ret
+}
+
+// parseConfigGet returns the value of a single parameter from the response
+// of CONFIG GET, an array of name/value pairs.
+func parseConfigGet(val RedisValue) string {
+	pair, ok := val.(RedisArray)
+	if !ok || len(pair) != 2 {
+		return ""
+	}
+	return forceToString(pair[1])
+}
+
 // Protocol returns the protocol identifer for the scanner.
 func (scanner *Scanner) Protocol() string {
 	return "redis"
@@ -390,11 +487,14 @@ func convToUint32(s string) uint32 {
 // 1. PING
 // 2. (only if --password is provided) AUTH <password>
 // 3. INFO
-// 4. NONEXISTENT
-// 5. (only if --custom-commands is provided) CustomCommands <args>
-// 6. QUIT
+// 4. (only if INFO succeeds) MODULE LIST, CONFIG GET bind, CONFIG GET protected-mode
+// 5. NONEXISTENT
+// 6. (only if --custom-commands is provided) CustomCommands <args>
+// 7. QUIT
 // The responses for each of these is logged, and if INFO succeeds, the version
 // is scraped from it.
+// A server in protected mode refuses PING and closes the connection, so the scan
+// stops there.
 func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
 	// ping, info, quit
 	scan, err := scanner.StartScan(ctx, target, dialGroup)
@@ -413,6 +513,11 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 	// From this point forward, we always return a non-nil result, implying that
 	// we have positively identified that a redis service is present.
 	result.PingResponse = forceToString(pingResponse)
+	result.recordRefusal(pingResponse)
+	if result.ProtectedMode {
+		result.TLSLog = scan.conn.GetTLSLog()
+		return zgrab2.SCAN_SUCCESS, result, nil
+	}
 	if scanner.config.Password != "" {
 		var authResponse RedisValue
 		authResponse, err = scan.SendCommand(scanner.commandMappings["AUTH"], scanner.config.Password)
@@ -426,7 +531,9 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 		return zgrab2.TryGetScanStatus(err), result, err
 	}
 	result.InfoResponse = forceToString(infoResponse)
+	result.recordRefusal(infoResponse)
 	if infoResponseBulk, ok := infoResponse.(BulkString); ok {
+		result.InfoSections = parseInfoSections(string(infoResponseBulk))
 		for _, line := range strings.Split(string(infoResponseBulk), "\r\n") {
 			linePrefixSuffix := strings.SplitN(line, ":", 2)
 			prefix := linePrefixSuffix[0]
@@ -475,6 +582,32 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 			}
 		}
 	}
+	if _, ok := infoResponse.(BulkString); ok {
+		// AUTH is not required
+		moduleResponse, err := scan.SendCommand(scanner.commandMappings["MODULE"], "LIST")
+		if err != nil {
+			return zgrab2.TryGetScanStatus(err), result, err
+		}
+		result.Modules = parseModuleList(moduleResponse)
+		result.Config = &Config{}
+		for _, param := range []struct {
+			name  string
+			value *string
+		}{
+			{"bind", &result.Config.Bind},
+			{"protected-mode", &result.Config.ProtectedMode},
+		} {
+			configResponse, err := scan.SendCommand(scanner.commandMappings["CONFIG"], "GET", param.name)
+			if err != nil {
+				return zgrab2.TryGetScanStatus(err), result, err
+			}
+			*param.value = parseConfigGet(configResponse)
+		}
+		if *result.Config == (Config{}) {
+			// CONFIG is renamed or disabled
+			result.Config = nil
+		}
+	}
 	bogusResponse, err := scan.SendCommand(scanner.commandMappings["NONEXISTENT"])
 	if err != nil {
 		return zgrab2.TryGetScanStatus(err), result, err
@@ -0,0 +1,48 @@
+package redis
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseInfoSections(t *testing.T) {
+	sections := parseInfoSections("# Server\r\nredis_version:7.2.4\r\nos:Linux 6.1.0 x86_64\r\n\r\n# Keyspace\r\ndb0:keys=1,expires=0,avg_ttl=0\r\n")
+	if len(sections) != 2 || sections[0].Name != "Server" || sections[1].Name != "Keyspace" {
+		t.Fatalf("sections = %+v", sections)
+	}
+	if !slices.Equal(sections[0].Fields, []InfoField{{"redis_version", "7.2.4"}, {"os", "Linux 6.1.0 x86_64"}}) {
+		t.Errorf("server fields = %+v", sections[0].Fields)
+	}
+	if !slices.Equal(sections[1].Fields, []InfoField{{"db0", "keys=1,expires=0,avg_ttl=0"}}) {
+		t.Errorf("keyspace fields = %+v", sections[1].Fields)
+	}
+}
+
+func TestParseModuleList(t *testing.T) {
+	modules := parseModuleList(RedisArray{
+		RedisArray{BulkString("name"), BulkString("search"), BulkString("ver"), Integer(20811)},
+		RedisArray{BulkString("name"), BulkString("ReJSON"), BulkString("ver"), Integer(20607), BulkString("path"), BulkString("/opt/rejson.so")},
+	})
+	if !slices.Equal(modules, []LoadedModule{{"search", 20811}, {"ReJSON", 20607}}) {
+		t.Errorf("modules = %+v", modules)
+	}
+	if modules := parseModuleList(ErrorMessage("ERR unknown command 'MODULE'")); modules != nil {
+		t.Errorf("modules of an error = %+v", modules)
+	}
+}
+
+func TestRecordRefusal(t *testing.T) {
+	var result Result
+	result.recordRefusal(ErrorMessage("DENIED Redis is running in protected mode because protected mode is enabled"))
+	if !result.ProtectedMode || result.AuthRequired {
+		t.Errorf("protected mode refusal = %+v", result)
+	}
+	result = Result{}
+	result.recordRefusal(ErrorMessage("NOAUTH Authentication required."))
+	if result.ProtectedMode || !result.AuthRequired {
+		t.Errorf("auth refusal = %+v", result)
+	}
+	if value := parseConfigGet(RedisArray{BulkString("protected-mode"), BulkString("yes")}); value != "yes" {
+		t.Errorf("CONFIG GET value = %q", value)
+	}
+}
@@ -440,3 +440,29 @@ type CustomResponse struct {
 	Arguments string `json:"arguments,omitempty"`
 	Response  string `json:"response,omitempty"`
 }
+
+// InfoSection is a section of the INFO response, like "Server" or
+// "Keyspace", with its fields in order.
+type InfoSection struct {
+	Name   string      `json:"name"`
+	Fields []InfoField `json:"fields,omitempty"`
+}
+
+// InfoField is a field:value line of the INFO response.
+type InfoField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// LoadedModule is a module loaded by the server, as listed by MODULE LIST.
+type LoadedModule struct {
+	Name    string `json:"name"`
+	Version int64  `json:"version"`
+}
+
+// Config holds the values of the CONFIG GET commands sent when AUTH is not
+// required.
+type Config struct {
+	Bind          string `json:"bind,omitempty"`
+	ProtectedMode string `json:"protected_mode,omitempty"`
+}
@@ -0,0 +1,213 @@
+package rmi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// JRMP protocol bytes.
+const (
+	streamProtocol = 0x4B
+	protocolAck    = 0x4E
+	messageCall    = 0x50
+	messageReturn  = 0x51
+
+	// exceptionalReturn is the return type of a call that threw an exception.
+	exceptionalReturn = 2
+)
+
+// Java serialization stream constants.
+const (
+	streamMagic     = 0xACED
+	streamVersion   = 5
+	tcNull          = 0x70
+	tcClassDesc     = 0x72
+	tcString        = 0x74
+	tcArray         = 0x75
+	tcBlockData     = 0x77
+	tcEndBlockData  = 0x78
+	maxResponseSize = 64 * 1024
+)
+
+// Registry operations and the interface hash of the registry stub.
+const (
+	operationList   = 1
+	operationLookup = 2
+	registryHash    = 0x44154dc9d4e63bdf
+)
+
+var errInvalidStream = errors.New("invalid serialization stream")
+
+// handshake is the client's JRMI header: magic, version 2 and the stream protocol.
+var handshake = []byte{'J', 'R', 'M', 'I', 0, 2, streamProtocol}
+
+func appendUTF(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// buildClientEndpoint returns the endpoint the client sends after the ProtocolAck. The registry ignores it.
+func buildClientEndpoint() []byte {
+	return binary.BigEndian.AppendUint32(appendUTF(nil, "0.0.0.0"), 0)
+}
+
+// buildCall returns a Call message invoking operation on the registry, with the serialized arguments appended.
+func buildCall(operation int32, arguments []byte) []byte {
+	call := []byte{messageCall}
+	call = binary.BigEndian.AppendUint16(call, streamMagic)
+	call = binary.BigEndian.AppendUint16(call, streamVersion)
+	// the registry's ObjID (object number 0 and a zero UID), the operation and the interface hash
+	header := make([]byte, 22)
+	header = binary.BigEndian.AppendUint32(header, uint32(operation))
+	header = binary.BigEndian.AppendUint64(header, registryHash)
+	call = append(call, tcBlockData, byte(len(header)))
+	call = append(call, header...)
+	return append(call, arguments...)
+}
+
+// buildLookup returns a Call message for lookup(name).
+func buildLookup(name string) []byte {
+	return buildCall(operationLookup, appendUTF([]byte{tcString}, name))
+}
+
+// Endpoint is a host and port.
+type Endpoint struct {
+	Host string `json:"host"`
+	Port uint32 `json:"port"`
+}
+
+// readProtocolAck reads the server's answer to the handshake, which carries the client's endpoint as the server sees it.
+func readProtocolAck(r io.Reader) (*Endpoint, error) {
+	ack := make([]byte, 3)
+	if _, err := io.ReadFull(r, ack); err != nil {
+		return nil, err
+	}
+	if ack[0] != protocolAck {
+		return nil, errInvalidStream
+	}
+	host := make([]byte, binary.BigEndian.Uint16(ack[1:3])+4)
+	if _, err := io.ReadFull(r, host); err != nil {
+		return nil, err
+	}
+	return &Endpoint{Host: string(host[:len(host)-4]), Port: binary.BigEndian.Uint32(host[len(host)-4:])}, nil
+}
+
+// returnValue is the decoded header of a Return message; body holds the serialized value or exception.
+type returnValue struct {
+	exceptional bool
+	body        []byte
+}
+
+// parseReturn decodes the header of a Return message.
+func parseReturn(data []byte) (*returnValue, error) {
+	// Return, magic, version, then a block with the return type and the UID
+	if len(data) < 7+2+15 || data[0] != messageReturn || binary.BigEndian.Uint16(data[1:3]) != streamMagic || data[5] != tcBlockData || data[6] < 15 {
+		return nil, errInvalidStream
+	}
+	return &returnValue{exceptional: data[7] == exceptionalReturn, body: data[7+int(data[6]):]}, nil
+}
+
+// reader decodes the parts of a serialization stream the scan needs.
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil || len(r.data) < n {
+		r.err = errInvalidStream
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *reader) byte() byte {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *reader) utf() string {
+	length := r.next(2)
+	if length == nil {
+		return ""
+	}
+	return string(r.next(int(binary.BigEndian.Uint16(length))))
+}
+
+// classDesc reads a class descriptor without fields, as used by String[], and returns the class name.
+func (r *reader) classDesc() string {
+	if r.byte() != tcClassDesc {
+		r.err = errInvalidStream
+		return ""
+	}
+	name := r.utf()
+	r.next(8 + 1) // serialVersionUID, flags
+	fields := r.next(2)
+	if fields == nil || binary.BigEndian.Uint16(fields) != 0 {
+		r.err = errInvalidStream
+		return ""
+	}
+	// no class annotations, and no superclass
+	if r.byte() != tcEndBlockData || r.byte() != tcNull {
+		r.err = errInvalidStream
+	}
+	return name
+}
+
+// parseStringArray decodes a serialized String[].
+func parseStringArray(data []byte) ([]string, error) {
+	r := &reader{data: data}
+	if r.byte() != tcArray || r.classDesc() != "[Ljava.lang.String;" {
+		return nil, errInvalidStream
+	}
+	count := r.next(4)
+	if r.err != nil {
+		return nil, r.err
+	}
+	var names []string
+	for i := uint32(0); i < binary.BigEndian.Uint32(count) && r.err == nil; i++ {
+		if r.byte() != tcString {
+			return names, errInvalidStream
+		}
+		names = append(names, r.utf())
+	}
+	return names, r.err
+}
+
+// exceptionClass returns the class name of the first class descriptor in a serialized exception.
+func exceptionClass(data []byte) string {
+	i := bytes.IndexByte(data, tcClassDesc)
+	if i < 0 {
+		return ""
+	}
+	r := &reader{data: data[i+1:]}
+	return r.utf()
+}
+
+// stubEndpoint finds the endpoint of the remote reference in a serialized stub: the external form of a UnicastRef
+// is the class name, then (for UnicastRef2 only) a format byte, then the host and port.
+func stubEndpoint(data []byte) *Endpoint {
+	for _, refClass := range []string{"UnicastRef2", "UnicastRef"} {
+		i := bytes.Index(data, appendUTF(nil, refClass))
+		if i < 0 {
+			continue
+		}
+		r := &reader{data: data[i+2+len(refClass):]}
+		if refClass == "UnicastRef2" {
+			r.byte()
+		}
+		host := r.utf()
+		port := r.next(4)
+		if r.err != nil {
+			return nil
+		}
+		return &Endpoint{Host: host, Port: binary.BigEndian.Uint32(port)}
+	}
+	return nil
+}
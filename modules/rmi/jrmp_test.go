@@ -0,0 +1,61 @@
+package rmi
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// returnHeader is a Return message header with the given return type and a zero UID.
+func returnHeader(returnType byte) []byte {
+	header := []byte{messageReturn, 0xAC, 0xED, 0, streamVersion, tcBlockData, 15, returnType}
+	return append(header, make([]byte, 14)...)
+}
+
+func TestParseStringArray(t *testing.T) {
+	data := returnHeader(1)
+	data = append(data, tcArray, tcClassDesc)
+	data = appendUTF(data, "[Ljava.lang.String;")
+	data = append(data, 0xAD, 0xD2, 0x56, 0xE7, 0xE9, 0x1D, 0x7B, 0x47, 0x02, 0, 0, tcEndBlockData, tcNull)
+	data = binary.BigEndian.AppendUint32(data, 2)
+	data = appendUTF(append(data, tcString), "jmxrmi")
+	data = appendUTF(append(data, tcString), "app")
+
+	if !listComplete(data) {
+		t.Fatal("expected complete list response")
+	}
+	if listComplete(data[:len(data)-1]) {
+		t.Error("expected truncated list response to be incomplete")
+	}
+	ret, err := parseReturn(data)
+	if err != nil || ret.exceptional {
+		t.Fatalf("unexpected return %+v (%v)", ret, err)
+	}
+	names, err := parseStringArray(ret.body)
+	if err != nil || !reflect.DeepEqual(names, []string{"jmxrmi", "app"}) {
+		t.Errorf("got %q (%v)", names, err)
+	}
+}
+
+func TestStubEndpoint(t *testing.T) {
+	body := []byte{0x73, 0x7D, 0, 0, 0, 2}
+	body = appendUTF(body, "java.rmi.Remote")
+	body = appendUTF(append(body, tcBlockData, 0x30), "UnicastRef2")
+	body = appendUTF(append(body, 0x01), "10.0.0.5")
+	body = binary.BigEndian.AppendUint32(body, 40123)
+
+	want := &Endpoint{Host: "10.0.0.5", Port: 40123}
+	if got := stubEndpoint(body); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := stubEndpoint(body[:len(body)-2]); got != nil {
+		t.Errorf("expected nil for truncated stub, got %+v", got)
+	}
+
+	exception := append(returnHeader(exceptionalReturn), 0x73, tcClassDesc)
+	exception = appendUTF(exception, "java.rmi.NotBoundException")
+	ret, err := parseReturn(exception)
+	if err != nil || !ret.exceptional || exceptionClass(ret.body) != "java.rmi.NotBoundException" {
+		t.Errorf("unexpected exception return %+v (%v)", ret, err)
+	}
+}
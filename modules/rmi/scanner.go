@@ -0,0 +1,263 @@
+// Package rmi contains the zgrab2 Module implementation for Java RMI registries.
+//
+// The scan performs the JRMI handshake and calls list() on the registry to record the bound names. If a JMX
+// connector is bound (as jmxrmi), it looks the stub up to find the connector's port and checks whether that port is
+// reachable on the target. The scan only calls the registry's list and lookup operations; it never invokes methods
+// of the bound objects.
+package rmi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// jmxName is the name the JMX agent binds its connector under.
+const jmxName = "jmxrmi"
+
+// JMXConnector describes the JMX connector bound in the registry.
+type JMXConnector struct {
+	// Endpoint is the endpoint of the connector's remote reference. Its host is often an internal address.
+	Endpoint *Endpoint `json:"endpoint,omitempty"`
+
+	// Reachable is true if the connector's port accepted a connection on the target.
+	Reachable bool `json:"reachable"`
+
+	// Exception is the class of the exception the lookup failed with.
+	Exception string `json:"exception,omitempty"`
+}
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// ClientEndpoint is the scanner's endpoint as seen by the server.
+	ClientEndpoint *Endpoint `json:"client_endpoint,omitempty"`
+
+	BoundNames []string `json:"bound_names,omitempty"`
+
+	// Exception is the class of the exception list() failed with.
+	Exception string `json:"exception,omitempty"`
+
+	JMX *JMXConnector `json:"jmx,omitempty"`
+}
+
+// Flags are the RMI-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+
+	NoJMXCheck bool `long:"no-jmx-check" description:"Do not look up and connect to a bound JMX connector"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the rmi zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("rmi", "Java RMI Registry", module.Description(), 1099, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Call list() on a Java RMI registry and check whether a bound JMX connector is reachable"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "rmi"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		NeedSeparateL4Dialer:            true,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// dial opens a TCP connection to port on the target.
+func dial(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget, port uint) (net.Conn, error) {
+	return dialGroup.L4Dialer(target)(ctx, "tcp", net.JoinHostPort(target.Host(), strconv.Itoa(int(port))))
+}
+
+// call performs the handshake on a new connection, sends a Call message and reads the response until complete
+// returns true for it.
+func call(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget, message []byte, complete func([]byte) bool) (*Endpoint, []byte, error) {
+	conn, err := dial(ctx, dialGroup, target, target.Port)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	if _, err = conn.Write(handshake); err != nil {
+		return nil, nil, err
+	}
+	endpoint, err := readProtocolAck(conn)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err = conn.Write(append(buildClientEndpoint(), message...)); err != nil {
+		return endpoint, nil, err
+	}
+	// the connection stays open after the Return, so read until the value is complete
+	var response []byte
+	buf := make([]byte, 4096)
+	for len(response) < maxResponseSize {
+		n, err := conn.Read(buf)
+		response = append(response, buf[:n]...)
+		if complete(response) {
+			return endpoint, response, nil
+		}
+		if err != nil {
+			return endpoint, response, err
+		}
+	}
+	return endpoint, response, fmt.Errorf("response exceeds %d bytes", maxResponseSize)
+}
+
+// listComplete reports whether data holds a complete response to list().
+func listComplete(data []byte) bool {
+	ret, err := parseReturn(data)
+	if err != nil {
+		return false
+	}
+	if ret.exceptional {
+		return exceptionClass(ret.body) != ""
+	}
+	_, err = parseStringArray(ret.body)
+	return err == nil
+}
+
+// lookupComplete reports whether data holds enough of a response to lookup() to find the stub's endpoint.
+func lookupComplete(data []byte) bool {
+	ret, err := parseReturn(data)
+	if err != nil {
+		return false
+	}
+	if ret.exceptional {
+		return exceptionClass(ret.body) != ""
+	}
+	return stubEndpoint(ret.body) != nil
+}
+
+// checkJMX looks up the JMX connector and checks whether its port is reachable on the target.
+func (scanner *Scanner) checkJMX(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (*JMXConnector, error) {
+	_, response, err := call(ctx, dialGroup, target, buildLookup(jmxName), lookupComplete)
+	if err != nil {
+		return nil, err
+	}
+	connector := new(JMXConnector)
+	ret, _ := parseReturn(response)
+	if ret.exceptional {
+		connector.Exception = exceptionClass(ret.body)
+		return connector, nil
+	}
+	connector.Endpoint = stubEndpoint(ret.body)
+	if connector.Endpoint.Port == 0 || connector.Endpoint.Port > 65535 {
+		return connector, nil
+	}
+	conn, err := dial(ctx, dialGroup, target, uint(connector.Endpoint.Port))
+	if err != nil {
+		log.Debugf("JMX connector port %d on target %s is not reachable: %v", connector.Endpoint.Port, target.String(), err)
+		return connector, nil
+	}
+	zgrab2.CloseConnAndHandleError(conn)
+	connector.Reachable = true
+	return connector, nil
+}
+
+// Scan performs the configured scan on the RMI registry.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	endpoint, response, err := call(ctx, dialGroup, target, buildCall(operationList, nil), listComplete)
+	if endpoint == nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("JRMI handshake with target %s failed: %w", target.String(), err)
+	}
+	results := &ScanResults{ClientEndpoint: endpoint}
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("list() call to target %s failed: %w", target.String(), err)
+	}
+	ret, _ := parseReturn(response)
+	if ret.exceptional {
+		results.Exception = exceptionClass(ret.body)
+		return zgrab2.SCAN_APPLICATION_ERROR, results, fmt.Errorf("list() call to target %s threw %s", target.String(), results.Exception)
+	}
+	results.BoundNames, _ = parseStringArray(ret.body)
+
+	if scanner.config.NoJMXCheck {
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+	for _, name := range results.BoundNames {
+		if name != jmxName {
+			continue
+		}
+		if results.JMX, err = scanner.checkJMX(ctx, dialGroup, target); err != nil {
+			log.Debugf("lookup of %s on target %s failed: %v", jmxName, target.String(), err)
+		}
+		break
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
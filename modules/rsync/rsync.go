@@ -0,0 +1,65 @@
+package rsync
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// greetingPrefix starts the greeting, the end of the module list, and errors.
+const greetingPrefix = "@RSYNCD: "
+
+var errNotRsync = errors.New("server did not send an rsync greeting")
+
+// Greeting is the server's protocol greeting.
+type Greeting struct {
+	// ProtocolVersion is the protocol version, e.g. 31.0.
+	ProtocolVersion string `json:"protocol_version"`
+
+	// Digests are the checksum algorithms the server supports, announced since protocol 31 (rsync 3.2).
+	Digests []string `json:"digests,omitempty"`
+}
+
+// ModuleInfo is an entry of the module list.
+type ModuleInfo struct {
+	Name    string `json:"name"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// parseGreeting parses a "@RSYNCD: <version>[ <digest>...]" line.
+func parseGreeting(line string) (*Greeting, error) {
+	rest, ok := strings.CutPrefix(line, greetingPrefix)
+	if !ok {
+		return nil, errNotRsync
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: empty version", errNotRsync)
+	}
+	return &Greeting{ProtocolVersion: fields[0], Digests: fields[1:]}, nil
+}
+
+// listing accumulates the lines the server sends in answer to a module list request.
+type listing struct {
+	Modules []ModuleInfo
+	MOTD    []string
+	Error   string
+	done    bool
+}
+
+// add processes a line of the response. Module lines are "name<TAB>comment", with the name padded to 15 characters;
+// any other line before them is part of the message of the day.
+func (l *listing) add(line string) {
+	switch {
+	case line == greetingPrefix+"EXIT":
+		l.done = true
+	case strings.HasPrefix(line, "@ERROR"):
+		l.Error = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(line, "@ERROR"), ":"))
+		l.done = true
+	case strings.Contains(line, "\t"):
+		name, comment, _ := strings.Cut(line, "\t")
+		l.Modules = append(l.Modules, ModuleInfo{Name: strings.TrimSpace(name), Comment: strings.TrimSpace(comment)})
+	case len(l.Modules) == 0:
+		l.MOTD = append(l.MOTD, line)
+	}
+}
@@ -0,0 +1,33 @@
+package rsync
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGreeting(t *testing.T) {
+	greeting, err := parseGreeting("@RSYNCD: 31.0 sha512 sha256 sha1 md5 md4")
+	if err != nil || greeting.ProtocolVersion != "31.0" || len(greeting.Digests) != 5 {
+		t.Errorf("got %+v (%v)", greeting, err)
+	}
+	if _, err = parseGreeting("SSH-2.0-OpenSSH_9.6"); err == nil {
+		t.Error("expected error for non-rsync greeting")
+	}
+}
+
+func TestListing(t *testing.T) {
+	list := new(listing)
+	for _, line := range []string{"Welcome to the mirror", "", "pub            \tPublic files", "backup         \t", "@RSYNCD: EXIT"} {
+		list.add(line)
+	}
+	want := []ModuleInfo{{Name: "pub", Comment: "Public files"}, {Name: "backup"}}
+	if !list.done || !reflect.DeepEqual(list.Modules, want) || len(list.MOTD) != 2 {
+		t.Errorf("unexpected listing %+v", list)
+	}
+
+	list = new(listing)
+	list.add("@ERROR: access denied to  from unknown (192.0.2.1)")
+	if !list.done || list.Error != "access denied to  from unknown (192.0.2.1)" {
+		t.Errorf("unexpected listing %+v", list)
+	}
+}
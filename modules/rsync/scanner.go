@@ -0,0 +1,173 @@
+// Package rsync contains the zgrab2 Module implementation for rsync daemons.
+//
+// The scan reads the server's greeting, answers with its own, and sends an empty module name, which asks the daemon
+// for the list of modules it is configured to advertise. No module is opened and no file is transferred.
+package rsync
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// maxLines bounds the number of lines read in answer to the list request.
+const maxLines = 4096
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	Greeting *Greeting `json:"greeting,omitempty"`
+
+	// MOTD is the message of the day sent before the module list.
+	MOTD string `json:"motd,omitempty"`
+
+	Modules []ModuleInfo `json:"modules,omitempty"`
+
+	// Error is the @ERROR message the daemon sent, e.g. when listing is disabled for the client's address.
+	Error string `json:"error,omitempty"`
+}
+
+// Flags are the rsync-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+
+	ClientVersion string `long:"client-version" default:"31.0" description:"Protocol version to announce in the client greeting"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the rsync zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("rsync", "rsync daemon", module.Description(), 873, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Read the greeting of an rsync daemon and list its modules"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "rsync"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// readLine reads a line, without its terminator.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// Scan performs the configured scan on the rsync daemon.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	reader := bufio.NewReader(conn)
+	line, err := readLine(reader)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error reading greeting from target %s: %w", target.String(), err)
+	}
+	results := new(ScanResults)
+	if results.Greeting, err = parseGreeting(line); err != nil {
+		return zgrab2.SCAN_PROTOCOL_ERROR, nil, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+	}
+
+	// the greeting and the empty module name can be sent together
+	if _, err = conn.Write([]byte(greetingPrefix + scanner.config.ClientVersion + "\n\n")); err != nil {
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error sending list request to target %s: %w", target.String(), err)
+	}
+	list := new(listing)
+	for i := 0; i < maxLines && !list.done; i++ {
+		if line, err = readLine(reader); err != nil {
+			break
+		}
+		list.add(line)
+	}
+	results.Modules = list.Modules
+	results.MOTD = strings.Join(list.MOTD, "\n")
+	results.Error = list.Error
+	if !list.done && err != nil {
+		// the greeting is still worth reporting
+		log.Debugf("error reading module list from target %s: %v", target.String(), err)
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
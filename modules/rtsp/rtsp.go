@@ -0,0 +1,137 @@
+package rtsp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// Header is a response header, in the order it was received.
+type Header struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Response is an RTSP response.
+type Response struct {
+	StatusCode   int      `json:"status_code"`
+	ReasonPhrase string   `json:"reason_phrase,omitempty"`
+	Headers      []Header `json:"headers,omitempty"`
+	Body         string   `json:"body,omitempty"`
+
+	// BodyTruncated is true if the body was longer than --max-size and only its start was recorded.
+	BodyTruncated bool `json:"body_truncated,omitempty"`
+}
+
+// Get returns the value of the first header with the given name.
+func (r *Response) Get(name string) string {
+	name = textproto.CanonicalMIMEHeaderKey(name)
+	for _, header := range r.Headers {
+		if header.Name == name {
+			return header.Value
+		}
+	}
+	return ""
+}
+
+// Values returns the values of all headers with the given name.
+func (r *Response) Values(name string) []string {
+	name = textproto.CanonicalMIMEHeaderKey(name)
+	var values []string
+	for _, header := range r.Headers {
+		if header.Name == name {
+			values = append(values, header.Value)
+		}
+	}
+	return values
+}
+
+// buildRequest returns an RTSP/1.0 request without a body.
+func buildRequest(method, url string, cseq int, userAgent string, extra ...string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s RTSP/1.0\r\n", method, url)
+	fmt.Fprintf(&b, "CSeq: %d\r\n", cseq)
+	if userAgent != "" {
+		fmt.Fprintf(&b, "User-Agent: %s\r\n", userAgent)
+	}
+	for _, header := range extra {
+		b.WriteString(header + "\r\n")
+	}
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
+
+var errInvalidResponse = errors.New("invalid RTSP response")
+
+// readResponse reads a response from r, keeping at most maxBody bytes of its body.
+func readResponse(r *bufio.Reader, maxBody int) (*Response, error) {
+	tp := textproto.NewReader(r)
+	statusLine, err := tp.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+	version, status, _ := strings.Cut(statusLine, " ")
+	if !strings.HasPrefix(version, "RTSP/") {
+		return nil, fmt.Errorf("%w: status line %q", errInvalidResponse, statusLine)
+	}
+	code, reason, _ := strings.Cut(status, " ")
+	resp := &Response{ReasonPhrase: reason}
+	if resp.StatusCode, err = strconv.Atoi(code); err != nil {
+		return nil, fmt.Errorf("%w: status line %q", errInvalidResponse, statusLine)
+	}
+	for {
+		line, err := tp.ReadContinuedLine()
+		if err != nil {
+			return nil, err
+		}
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: header %q", errInvalidResponse, line)
+		}
+		resp.Headers = append(resp.Headers, Header{
+			Name:  textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name)),
+			Value: strings.TrimSpace(value),
+		})
+	}
+	length, _ := strconv.Atoi(resp.Get("Content-Length"))
+	if length <= 0 {
+		return resp, nil
+	}
+	keep := min(length, maxBody)
+	body := make([]byte, keep)
+	if _, err = io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	resp.Body = string(body)
+	if keep < length {
+		resp.BodyTruncated = true
+		if _, err = r.Discard(length - keep); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// splitList splits a comma-separated header value.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// authScheme returns the scheme of a WWW-Authenticate challenge, e.g. Digest.
+func authScheme(challenge string) string {
+	scheme, _, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	return scheme
+}
@@ -0,0 +1,31 @@
+package rtsp
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestReadResponse(t *testing.T) {
+	raw := "RTSP/1.0 200 OK\r\nCSeq: 2\r\nContent-Type: application/sdp\r\nContent-Length: 10\r\n\r\nv=0\r\no=- 1" +
+		"RTSP/1.0 401 Unauthorized\r\nCSeq: 3\r\nWWW-Authenticate: Digest realm=\"cam\", nonce=\"x\"\r\nwww-authenticate: Basic realm=\"cam\"\r\n\r\n"
+	reader := bufio.NewReader(strings.NewReader(raw))
+	resp, err := readResponse(reader, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || resp.Body != "v=0\r" || !resp.BodyTruncated || resp.Get("cseq") != "2" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	resp, err = readResponse(reader, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenges := resp.Values("WWW-Authenticate")
+	if resp.StatusCode != 401 || len(challenges) != 2 || authScheme(challenges[0]) != "Digest" || authScheme(challenges[1]) != "Basic" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if _, err = readResponse(bufio.NewReader(strings.NewReader("HTTP/1.1 200 OK\r\n\r\n")), 4); err == nil {
+		t.Error("expected error for HTTP response")
+	}
+}
@@ -0,0 +1,226 @@
+// Package rtsp contains the zgrab2 Module implementation for RTSP.
+//
+// The scan sends OPTIONS and then DESCRIBE for the configured path on the same connection, recording the server
+// banner, the methods listed in the Public header, and whether DESCRIBE requires authentication (as most IP cameras
+// should) or returns the stream's SDP description to anyone.
+package rtsp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// Server is the Server header of the first response that had one.
+	Server string `json:"server,omitempty"`
+
+	// Methods are the methods listed in the Public header of the OPTIONS response.
+	Methods []string `json:"methods,omitempty"`
+
+	// AuthRequired is true if DESCRIBE was answered with 401 Unauthorized.
+	AuthRequired bool `json:"auth_required"`
+
+	// AuthSchemes are the schemes of the WWW-Authenticate challenges, e.g. Basic or Digest.
+	AuthSchemes []string `json:"auth_schemes,omitempty"`
+
+	Options  *Response `json:"options,omitempty"`
+	Describe *Response `json:"describe,omitempty"`
+}
+
+// Flags are the RTSP-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+
+	Path      string `long:"path" default:"/" description:"Path of the stream to DESCRIBE"`
+	UserAgent string `long:"user-agent" default:"zgrab2" description:"User-Agent header to send; empty to omit it"`
+	MaxSize   int    `long:"max-size" default:"16" description:"Max kilobytes to read of each response body"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the rtsp zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("rtsp", "Real Time Streaming Protocol (RTSP)", module.Description(), 554, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Send RTSP OPTIONS and DESCRIBE requests and record the server, methods and authentication requirement"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	if f.MaxSize <= 0 {
+		return fmt.Errorf("max-size must be positive, given %d", f.MaxSize)
+	}
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "rtsp"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// connection is an RTSP connection to the target, reopened if the server closes it between requests.
+type connection struct {
+	ctx       context.Context
+	dialGroup *zgrab2.DialerGroup
+	target    *zgrab2.ScanTarget
+	conn      net.Conn
+	reader    *bufio.Reader
+	cseq      int
+}
+
+// open (re)connects to the target.
+func (c *connection) open() error {
+	c.close()
+	conn, err := c.dialGroup.Dial(c.ctx, c.target)
+	if err != nil {
+		return err
+	}
+	c.conn, c.reader = conn, bufio.NewReader(conn)
+	return nil
+}
+
+func (c *connection) close() {
+	if c.conn != nil {
+		zgrab2.CloseConnAndHandleError(c.conn)
+		c.conn = nil
+	}
+}
+
+// do sends a request and reads the response, reconnecting once if the previous response closed the connection.
+func (scanner *Scanner) do(c *connection, method, url string, extra ...string) (*Response, error) {
+	c.cseq++
+	request := buildRequest(method, url, c.cseq, scanner.config.UserAgent, extra...)
+	if _, err := c.conn.Write(request); err != nil {
+		if err = c.open(); err != nil {
+			return nil, err
+		}
+		if _, err = c.conn.Write(request); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := readResponse(c.reader, scanner.config.MaxSize*1024)
+	if errors.Is(err, errInvalidResponse) {
+		return nil, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+	}
+	if err == nil && resp.Get("Connection") == "close" {
+		c.close()
+	}
+	return resp, err
+}
+
+// Scan performs the configured scan on the RTSP server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	c := &connection{ctx: ctx, dialGroup: dialGroup, target: target}
+	if err := c.open(); err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer c.close()
+
+	url := "rtsp://" + net.JoinHostPort(target.Host(), strconv.Itoa(int(target.Port))) + scanner.config.Path
+	results := new(ScanResults)
+	var err error
+	if results.Options, err = scanner.do(c, "OPTIONS", url); err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error sending OPTIONS to target %s: %w", target.String(), err)
+	}
+	results.Server = results.Options.Get("Server")
+	for _, public := range results.Options.Values("Public") {
+		results.Methods = append(results.Methods, splitList(public)...)
+	}
+
+	if c.conn == nil {
+		if err = c.open(); err != nil {
+			return zgrab2.SCAN_SUCCESS, results, nil
+		}
+	}
+	if results.Describe, err = scanner.do(c, "DESCRIBE", url, "Accept: application/sdp"); err != nil {
+		// OPTIONS already identified the server
+		log.Debugf("DESCRIBE failed on target %s: %v", target.String(), err)
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+	if results.Server == "" {
+		results.Server = results.Describe.Get("Server")
+	}
+	if results.Describe.StatusCode == 401 {
+		results.AuthRequired = true
+		for _, challenge := range results.Describe.Values("WWW-Authenticate") {
+			results.AuthSchemes = append(results.AuthSchemes, authScheme(challenge))
+		}
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
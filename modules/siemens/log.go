@@ -46,4 +46,34 @@ type S7Log struct {
 
 	// Fiirmware is the third field returned in the module identification response.
 	Firmware string `json:"firmware,omitempty"`
+
+	// S7Plus is the result of the S7comm-plus session setup, only present with --s7comm-plus.
+	S7Plus *S7PlusLog `json:"s7_plus,omitempty"`
+}
+
+// S7PlusLog is the output of the S7comm-plus session setup used by S7-1200 and S7-1500 PLCs.
+type S7PlusLog struct {
+	// IsS7Plus indicates that the PLC answered the CreateObject request.
+	IsS7Plus bool `json:"is_s7_plus"`
+
+	// ProtocolVersion is the version field of the response header.
+	ProtocolVersion byte `json:"protocol_version,omitempty"`
+
+	// ReturnValue is the return value of the CreateObject response.
+	ReturnValue uint64 `json:"return_value"`
+
+	// SessionID is the first object ID of the CreateObject response.
+	SessionID uint32 `json:"session_id,omitempty"`
+
+	// Version is the version string of the server session, for example "1;6ES7 511-1AK01-0AB0 ;V2.1".
+	Version string `json:"version,omitempty"`
+
+	// OrderNumber is the order number (MLFB) of the CPU from the version string.
+	OrderNumber string `json:"order_number,omitempty"`
+
+	// Firmware is the firmware version of the CPU from the version string.
+	Firmware string `json:"firmware,omitempty"`
+
+	// Error is set if the session setup failed.
+	Error string `json:"error,omitempty"`
 }
@@ -0,0 +1,240 @@
+package siemens
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"time"
+)
+
+// S7comm-plus is the protocol of S7-1200 and S7-1500 PLCs. Its session setup is a CreateObject request for a server
+// session, which the PLC answers with the session ID and a version string with the order number and firmware of the
+// CPU, for example "1;6ES7 511-1AK01-0AB0 ;V2.1".
+const (
+	S7PLUS_PROTOCOL_ID          = byte(0x72)
+	S7PLUS_PROTOCOL_VERSION_1   = byte(0x01)
+	S7PLUS_OPCODE_REQUEST       = byte(0x31)
+	S7PLUS_OPCODE_RESPONSE      = byte(0x32)
+	S7PLUS_FUNCTION_CREATE      = uint16(0x04ca)
+	S7PLUS_ELEMENT_START_OBJECT = byte(0xa1)
+	S7PLUS_ELEMENT_END_OBJECT   = byte(0xa2)
+	S7PLUS_ELEMENT_ATTRIBUTE    = byte(0xa3)
+	S7PLUS_DATATYPE_UDINT       = byte(0x04)
+	S7PLUS_DATATYPE_RID         = byte(0x13)
+
+	// Object, class and attribute IDs used to create the server session
+	s7PlusObjectNullServerSession      = uint32(288)
+	s7PlusObjectServerSessionContainer = uint32(285)
+	s7PlusObjectGetNewRIDOnServer      = uint32(211)
+	s7PlusClassServerSession           = uint32(287)
+	s7PlusClassSubscriptions           = uint32(255)
+	s7PlusAttributeServerSessionClient = uint32(300)
+
+	// s7PlusClientRID identifies the client session, as sent by the TIA portal
+	s7PlusClientRID = uint32(0x80c3c901)
+
+	// s7PlusSourceTSAP is the calling TSAP of S7comm-plus connections
+	s7PlusSourceTSAP = uint16(0x0600)
+
+	// s7PlusMaxResponseSize bounds the reassembled CreateObject response
+	s7PlusMaxResponseSize = 64 * 1024
+)
+
+var s7PlusVersionRegexp = regexp.MustCompile(`\d+;([0-9A-Z][0-9A-Z \-]{5,}?) *;V(\d+(?:\.\d+)+)`)
+
+var errNotS7Plus = errors.New("not a S7comm-plus packet")
+
+// appendVLQ appends v with the variable-length encoding of S7comm-plus: big-endian groups of 7 bits, all but the last
+// with the high bit set.
+func appendVLQ(b []byte, v uint32) []byte {
+	var groups [5]byte
+	i := len(groups) - 1
+	groups[i] = byte(v & 0x7f)
+	for v >>= 7; v > 0; v >>= 7 {
+		i--
+		groups[i] = 0x80 | byte(v&0x7f)
+	}
+	return append(b, groups[i:]...)
+}
+
+// readVLQ reads a variable-length unsigned integer from the start of b.
+func readVLQ(b []byte) (uint64, []byte, error) {
+	var v uint64
+	for i := 0; i < len(b) && i < 10; i++ {
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, b[i+1:], nil
+		}
+	}
+	return 0, b, errS7PacketTooShort
+}
+
+// appendS7PlusObject appends the start of an object of class classID to b, to be ended with S7PLUS_ELEMENT_END_OBJECT.
+func appendS7PlusObject(b []byte, relationID uint32, classID uint32) []byte {
+	b = append(b, S7PLUS_ELEMENT_START_OBJECT)
+	b = binary.BigEndian.AppendUint32(b, relationID)
+	b = appendVLQ(b, classID)
+	b = appendVLQ(b, 0) // class flags
+	return appendVLQ(b, 0)
+}
+
+// makeS7PlusCreateSessionBytes returns the TPKT packet with the CreateObject request for a new server session.
+func makeS7PlusCreateSessionBytes() ([]byte, error) {
+	data := []byte{S7PLUS_OPCODE_REQUEST, 0, 0}
+	data = binary.BigEndian.AppendUint16(data, S7PLUS_FUNCTION_CREATE)
+	data = append(data, 0, 0)
+	data = binary.BigEndian.AppendUint16(data, 1) // sequence number
+	data = binary.BigEndian.AppendUint32(data, s7PlusObjectNullServerSession)
+	data = append(data, 0x36) // transport flags
+
+	// request set: the object to create, in the server session container
+	data = binary.BigEndian.AppendUint32(data, s7PlusObjectServerSessionContainer)
+	data = append(data, 0x00, S7PLUS_DATATYPE_UDINT, 0x00)
+	data = append(data, 0, 0, 0, 0)
+	data = appendS7PlusObject(data, s7PlusObjectGetNewRIDOnServer, s7PlusClassServerSession)
+	data = append(data, S7PLUS_ELEMENT_ATTRIBUTE)
+	data = appendVLQ(data, s7PlusAttributeServerSessionClient)
+	data = append(data, 0x00, S7PLUS_DATATYPE_RID)
+	data = binary.BigEndian.AppendUint32(data, s7PlusClientRID)
+	data = appendS7PlusObject(data, s7PlusObjectGetNewRIDOnServer, s7PlusClassSubscriptions)
+	data = append(data, S7PLUS_ELEMENT_END_OBJECT)
+	data = append(data, S7PLUS_ELEMENT_END_OBJECT)
+	data = append(data, 0, 0, 0, 0)
+
+	packet := []byte{S7PLUS_PROTOCOL_ID, S7PLUS_PROTOCOL_VERSION_1}
+	packet = binary.BigEndian.AppendUint16(packet, uint16(len(data)))
+	packet = append(packet, data...)
+	packet = append(packet, S7PLUS_PROTOCOL_ID, S7PLUS_PROTOCOL_VERSION_1, 0, 0) // trailer
+
+	cotpDataPacket := COTPDataPacket{Data: packet}
+	cotpDataPacketBytes, err := cotpDataPacket.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	tpktPacket := TPKTPacket{Data: cotpDataPacketBytes}
+	return tpktPacket.Marshal()
+}
+
+// makeS7PlusCOTPConnectionPacketBytes returns a COTP connection request with the named TSAP dstTsap, such as
+// SIMATIC-ROOT-HMI, which S7comm-plus uses instead of the rack and slot of classic S7.
+func makeS7PlusCOTPConnectionPacketBytes(dstTsap string) ([]byte, error) {
+	if len(dstTsap) == 0 || len(dstTsap) > 32 {
+		return nil, fmt.Errorf("invalid TSAP %q", dstTsap)
+	}
+	cotp := []byte{0, 0xe0, 0, 0, 0, 0x04, 0}
+	cotp = append(cotp, 0xc1, 2)
+	cotp = binary.BigEndian.AppendUint16(cotp, s7PlusSourceTSAP)
+	cotp = append(cotp, 0xc2, byte(len(dstTsap)))
+	cotp = append(cotp, dstTsap...)
+	cotp = append(cotp, 0xc0, 1, 0x0a)
+	cotp[0] = byte(len(cotp) - 1)
+	tpktPacket := TPKTPacket{Data: cotp}
+	return tpktPacket.Marshal()
+}
+
+// readCOTPData reads TPKT packets until the COTP data packet with the EOT flag and returns the reassembled data.
+func readCOTPData(connection net.Conn, readTimeout time.Duration) ([]byte, error) {
+	var ret []byte
+	header := make([]byte, tpktLength)
+	for {
+		if err := connection.SetReadDeadline(time.Now().Add(readTimeout)); err != nil {
+			return ret, err
+		}
+		if _, err := io.ReadFull(connection, header); err != nil {
+			return ret, err
+		}
+		length := int(binary.BigEndian.Uint16(header[2:4]))
+		if header[0] != 3 || length < tpktLength+3 {
+			return ret, errInvalidPacket
+		}
+		packet := make([]byte, length-tpktLength)
+		if _, err := io.ReadFull(connection, packet); err != nil {
+			return ret, err
+		}
+		// header length, PDU type and TPDU number with the EOT flag
+		if packet[0] < 2 || int(packet[0]) >= len(packet) || packet[1] != 0xf0 {
+			return ret, errInvalidPacket
+		}
+		ret = append(ret, packet[packet[0]+1:]...)
+		if packet[2]&0x80 != 0 {
+			return ret, nil
+		}
+		if len(ret) > s7PlusMaxResponseSize {
+			return ret, errors.New("S7comm-plus response too large")
+		}
+	}
+}
+
+// parseS7PlusCreateSessionResponse parses the CreateObject response into logStruct.
+func parseS7PlusCreateSessionResponse(logStruct *S7PlusLog, data []byte) error {
+	// protocol id, version, data length, opcode, reserved, function, reserved, sequence number, transport flags
+	if len(data) < 14 {
+		return errS7PacketTooShort
+	}
+	if data[0] != S7PLUS_PROTOCOL_ID {
+		return errNotS7Plus
+	}
+	logStruct.ProtocolVersion = data[1]
+	if data[4] != S7PLUS_OPCODE_RESPONSE || binary.BigEndian.Uint16(data[7:9]) != S7PLUS_FUNCTION_CREATE {
+		return fmt.Errorf("unexpected S7comm-plus response: opcode 0x%02x, function 0x%04x", data[4], binary.BigEndian.Uint16(data[7:9]))
+	}
+	returnValue, rest, err := readVLQ(data[14:])
+	if err != nil {
+		return err
+	}
+	logStruct.ReturnValue = returnValue
+	if len(rest) > 0 {
+		count := int(rest[0])
+		rest = rest[1:]
+		for i := 0; i < count; i++ {
+			var id uint64
+			if id, rest, err = readVLQ(rest); err != nil {
+				return err
+			}
+			if i == 0 {
+				logStruct.SessionID = uint32(id)
+			}
+		}
+	}
+	if match := s7PlusVersionRegexp.FindSubmatch(rest); match != nil {
+		logStruct.Version = string(match[0])
+		logStruct.OrderNumber = string(match[1])
+		logStruct.Firmware = string(match[2])
+	}
+	return nil
+}
+
+// GetS7PlusBanner negotiates a S7comm-plus session with the TSAP dstTsap and stores the identification of the PLC in
+// logStruct.
+func GetS7PlusBanner(logStruct *S7PlusLog, connection net.Conn, dstTsap string, readTimeout time.Duration) error {
+	connPacketBytes, err := makeS7PlusCOTPConnectionPacketBytes(dstTsap)
+	if err != nil {
+		return fmt.Errorf("could not make COTP connection packet bytes: %w", err)
+	}
+	connResponseBytes, err := sendRequestReadResponse(connection, connPacketBytes, readTimeout)
+	if err != nil {
+		return fmt.Errorf("could not send request and read response: %w", err)
+	}
+	if _, err = unmarshalCOTPConnectionResponse(connResponseBytes); err != nil {
+		return fmt.Errorf("could not unmarshal COTP connection response: %w", err)
+	}
+
+	requestBytes, err := makeS7PlusCreateSessionBytes()
+	if err != nil {
+		return fmt.Errorf("could not make CreateObject request bytes: %w", err)
+	}
+	if n, err := connection.Write(requestBytes); err != nil {
+		return fmt.Errorf("error encountered after writing %d bytes: %w", n, err)
+	}
+	response, err := readCOTPData(connection, readTimeout)
+	if err != nil {
+		return fmt.Errorf("could not read CreateObject response: %w", err)
+	}
+	if err := parseS7PlusCreateSessionResponse(logStruct, response); err != nil {
+		return fmt.Errorf("could not parse CreateObject response: %w", err)
+	}
+	return nil
+}
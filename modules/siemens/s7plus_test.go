@@ -0,0 +1,100 @@
+package siemens
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// tpktData wraps payload in a TPKT packet with a COTP data header, setting EOT if last.
+func tpktData(payload []byte, last bool) []byte {
+	tpdu := byte(0)
+	if last {
+		tpdu = 0x80
+	}
+	packet := []byte{3, 0, 0, 0, 2, 0xf0, tpdu}
+	packet = append(packet, payload...)
+	binary.BigEndian.PutUint16(packet[2:4], uint16(len(packet)))
+	return packet
+}
+
+func TestVLQ(t *testing.T) {
+	for v, expected := range map[uint32]string{0: "00", 127: "7f", 255: "817f", 287: "821f", 0x80c3c901: "88868f9201"} {
+		encoded := appendVLQ(nil, v)
+		if hex.EncodeToString(encoded) != expected {
+			t.Errorf("%d encoded as %x", v, encoded)
+		}
+		decoded, rest, err := readVLQ(append(encoded, 0xff))
+		if err != nil || decoded != uint64(v) || !bytes.Equal(rest, []byte{0xff}) {
+			t.Errorf("%x decoded as %d, %x, %v", encoded, decoded, rest, err)
+		}
+	}
+	if _, _, err := readVLQ([]byte{0x81, 0x82}); err == nil {
+		t.Error("decoded truncated VLQ")
+	}
+}
+
+func TestMakeS7PlusCreateSessionBytes(t *testing.T) {
+	packet, err := makeS7PlusCreateSessionBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "7201003a" + // header
+		"31000004ca0000000100000120" + "36" +
+		"0000011d" + "000400" + "00000000" +
+		"a1000000d3821f0000" + "a3822c0013" + "80c3c901" +
+		"a1000000d3817f0000" + "a2" + "a2" + "00000000" +
+		"72010000" // trailer
+	if hex.EncodeToString(packet[7:]) != expected {
+		t.Errorf("request = %x", packet[7:])
+	}
+	if int(binary.BigEndian.Uint16(packet[2:4])) != len(packet) {
+		t.Errorf("TPKT length = %d, expected %d", binary.BigEndian.Uint16(packet[2:4]), len(packet))
+	}
+}
+
+func TestGetS7PlusBanner(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	response := []byte{S7PLUS_PROTOCOL_ID, 0x01, 0, 0, S7PLUS_OPCODE_RESPONSE, 0, 0, 0x04, 0xca, 0, 0, 0, 1, 0}
+	response = append(response, 0x00, 2)
+	response = appendVLQ(response, 0x70000359)
+	response = appendVLQ(response, 0x70000359)
+	response = append(response, S7PLUS_ELEMENT_START_OBJECT, 0, 0, 0x01, 0x32, 0x15, 28)
+	response = append(response, "1;6ES7 511-1AK01-0AB0 ;V2.1"...)
+	response = append(response, S7PLUS_ELEMENT_END_OBJECT, 0x72, 0x01, 0, 0)
+	go func() {
+		defer server.Close()
+		buf := make([]byte, 1024)
+		n, err := server.Read(buf)
+		if err != nil || !bytes.Contains(buf[:n], []byte("SIMATIC-ROOT-HMI")) {
+			return
+		}
+		server.Write([]byte{3, 0, 0, 11, 6, 0xd0, 0, 4, 0, 1, 0})
+		if _, err := io.ReadFull(server, buf[:4]); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(server, buf[4:binary.BigEndian.Uint16(buf[2:4])]); err != nil {
+			return
+		}
+		// split the response across two COTP data packets
+		server.Write(tpktData(response[:20], false))
+		server.Write(tpktData(response[20:], true))
+	}()
+
+	logStruct := new(S7PlusLog)
+	if err := GetS7PlusBanner(logStruct, client, "SIMATIC-ROOT-HMI", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if logStruct.SessionID != 0x70000359 || logStruct.ProtocolVersion != 1 || logStruct.ReturnValue != 0 {
+		t.Errorf("log = %+v", logStruct)
+	}
+	if logStruct.OrderNumber != "6ES7 511-1AK01-0AB0" || logStruct.Firmware != "2.1" {
+		t.Errorf("order number = %q, firmware = %q", logStruct.OrderNumber, logStruct.Firmware)
+	}
+}
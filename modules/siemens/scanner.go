@@ -5,6 +5,7 @@ package siemens
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"time"
@@ -19,6 +20,8 @@ import (
 type Flags struct {
 	zgrab2.BaseFlags `group:"Basic Options"` // TODO: configurable TSAP source / destination, etc
 	ReadTimeout      time.Duration           `long:"read-timeout" default:"500ms" description:"Timeout for reading S7 responses"`
+	S7Plus           bool                    `long:"s7comm-plus" description:"Also negotiate a S7comm-plus session, to identify S7-1200 and S7-1500 PLCs"`
+	S7PlusTSAP       string                  `long:"s7comm-plus-tsap" default:"SIMATIC-ROOT-HMI" description:"The destination TSAP of the S7comm-plus connection"`
 }
 
 // Module implements the zgrab2.Module interface.
@@ -59,6 +62,9 @@ func (module *Module) Description() string {
 // On success, returns nil.
 // On failure, returns an error instance describing the error.
 func (flags *Flags) Validate(_ []string) error {
+	if flags.S7Plus && (len(flags.S7PlusTSAP) == 0 || len(flags.S7PlusTSAP) > 32) {
+		return errors.New("--s7comm-plus-tsap must be between 1 and 32 characters")
+	}
 	return nil
 }
 
@@ -114,7 +120,8 @@ func (scanner *Scanner) GetScanMetadata() any {
 // 4. Negotiate S7
 // 5. Request to read the module identification (and store it in the output)
 // 6. Request to read the component identification (and store it in the output)
-// 7. Return the output
+// 7. With --s7comm-plus, reconnect and negotiate a S7comm-plus session with the TSAP --s7comm-plus-tsap
+// 8. Return the output
 func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
 	conn, err := dialGroup.Dial(ctx, target)
 	if err != nil {
@@ -123,8 +130,33 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 	defer zgrab2.CloseConnAndHandleError(conn)
 	result := new(S7Log)
 	err = GetS7Banner(result, conn, func() (net.Conn, error) { return dialGroup.Dial(ctx, target) }, scanner.config.ReadTimeout)
+	if scanner.config.S7Plus {
+		result.S7Plus = scanner.getS7PlusBanner(ctx, dialGroup, target)
+		if result.S7Plus.IsS7Plus && !result.IsS7 {
+			// S7-1200 and S7-1500 PLCs may not answer classic S7
+			result.IsS7 = true
+			err = nil
+		}
+	}
 	if !result.IsS7 {
 		result = nil
 	}
 	return zgrab2.TryGetScanStatus(err), result, err
 }
+
+// getS7PlusBanner negotiates a S7comm-plus session on a new connection.
+func (scanner *Scanner) getS7PlusBanner(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) *S7PlusLog {
+	ret := new(S7PlusLog)
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		ret.Error = fmt.Sprintf("could not establish connection to target %s: %v", target.String(), err)
+		return ret
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+	if err := GetS7PlusBanner(ret, conn, scanner.config.S7PlusTSAP, scanner.config.ReadTimeout); err != nil {
+		ret.Error = err.Error()
+		return ret
+	}
+	ret.IsS7Plus = true
+	return ret
+}
@@ -0,0 +1,202 @@
+// Package sip contains the zgrab2 Module implementation for SIP.
+//
+// The scan sends an OPTIONS request over UDP, TCP or TLS and records the identifying headers of the final
+// response: Server and User-Agent, the allowed methods and the supported extensions.
+package sip
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	Transport string `json:"transport"`
+
+	// Response is the final (non-1xx) response to the OPTIONS request.
+	Response *Response `json:"response,omitempty"`
+
+	// Provisional are the 1xx responses received before the final one.
+	Provisional []*Response `json:"provisional,omitempty"`
+
+	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
+
+	Probe *zgrab2.UDPProbeResult `json:"probe,omitempty"`
+}
+
+// Flags are the SIP-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.UDPFlags
+	zgrab2.TLSFlags
+
+	Transport string `long:"transport" default:"udp" description:"Transport to send the request over (udp, tcp or tls)"`
+	UserAgent string `long:"user-agent" default:"zgrab2" description:"User-Agent header to send; empty to omit it"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the sip zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("sip", "Session Initiation Protocol (SIP)", module.Description(), 5060, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Send a SIP OPTIONS request and record the server's identity, methods and extensions"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	switch f.Transport {
+	case "udp":
+		return f.UDPFlags.Validate()
+	case "tcp", "tls":
+		return nil
+	default:
+		return fmt.Errorf("unknown transport %q", f.Transport)
+	}
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "sip"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	switch f.Transport {
+	case "udp":
+		scanner.dialerGroupConfig.TransportAgnosticDialerProtocol = zgrab2.TransportUDP
+	case "tls":
+		scanner.dialerGroupConfig.TLSEnabled = true
+		scanner.dialerGroupConfig.TLSFlags = &f.TLSFlags
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// randomToken returns a random hex string of n bytes.
+func randomToken(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Scan performs the configured scan on the SIP server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	results := &ScanResults{Transport: scanner.config.Transport}
+	if tlsConn, ok := conn.(*zgrab2.TLSConnection); ok {
+		results.TLSLog = tlsConn.GetLog()
+	}
+	req := &request{
+		callID: randomToken(12),
+		branch: "z9hG4bK" + randomToken(8), // the magic cookie marks RFC 3261 branch IDs
+		tag:    randomToken(4),
+	}
+	payload := buildOptions(req, scanner.config.Transport, target.Host(), conn.LocalAddr(), scanner.config.UserAgent)
+
+	if scanner.config.Transport == "udp" {
+		probe := zgrab2.NewStaticUDPProbe("options", payload, func(_, response []byte) bool {
+			resp, err := parseDatagram(response)
+			return err == nil && resp.callID == req.callID && resp.StatusCode >= 200
+		})
+		results.Probe, err = zgrab2.SendUDPProbe(ctx, conn, probe, target, &scanner.config.UDPFlags)
+		if err != nil {
+			return zgrab2.TryGetScanStatus(err), results, err
+		}
+		results.Response, _ = parseDatagram(results.Probe.Response)
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+
+	if _, err = conn.Write(payload); err != nil {
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error sending OPTIONS to target %s: %w", target.String(), err)
+	}
+	reader := bufio.NewReader(conn)
+	for {
+		resp, err := readResponse(reader, true)
+		if errors.Is(err, errInvalidResponse) {
+			return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("invalid response from target %s: %w", target.String(), err)
+		}
+		if err != nil {
+			return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error reading response from target %s: %w", target.String(), err)
+		}
+		if resp.StatusCode >= 200 {
+			results.Response = resp
+			return zgrab2.SCAN_SUCCESS, results, nil
+		}
+		results.Provisional = append(results.Provisional, resp)
+	}
+}
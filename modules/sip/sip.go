@@ -0,0 +1,188 @@
+package sip
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// compactHeaders maps the compact header forms (RFC 3261 section 7.3.3 and extensions) to their full names.
+var compactHeaders = map[string]string{
+	"A": "Accept-Contact",
+	"B": "Referred-By",
+	"C": "Content-Type",
+	"E": "Content-Encoding",
+	"F": "From",
+	"I": "Call-Id",
+	"K": "Supported",
+	"L": "Content-Length",
+	"M": "Contact",
+	"O": "Event",
+	"R": "Refer-To",
+	"S": "Subject",
+	"T": "To",
+	"U": "Allow-Events",
+	"V": "Via",
+}
+
+// maxBodySize bounds the body read from a response over a stream transport.
+const maxBodySize = 64 * 1024
+
+// Header is a response header, in the order it was received.
+type Header struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Response is a SIP response.
+type Response struct {
+	StatusCode   int    `json:"status_code"`
+	ReasonPhrase string `json:"reason_phrase,omitempty"`
+
+	Server    string `json:"server,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+
+	// Allow lists the methods the server supports.
+	Allow []string `json:"allow,omitempty"`
+
+	// Supported lists the option tags (extensions) the server supports.
+	Supported []string `json:"supported,omitempty"`
+
+	Accept      []string `json:"accept,omitempty"`
+	AllowEvents []string `json:"allow_events,omitempty"`
+
+	Headers []Header `json:"headers,omitempty"`
+
+	callID string
+}
+
+// request holds what identifies an OPTIONS request and its response.
+type request struct {
+	callID string
+	branch string
+	tag    string
+}
+
+// buildOptions returns an OPTIONS request to the target.
+func buildOptions(req *request, transport, host string, local net.Addr, userAgent string) []byte {
+	localHost, localPort := "0.0.0.0", "0"
+	if addr, ok := local.(*net.UDPAddr); ok {
+		localHost, localPort = addr.IP.String(), strconv.Itoa(addr.Port)
+	} else if addr, ok := local.(*net.TCPAddr); ok {
+		localHost, localPort = addr.IP.String(), strconv.Itoa(addr.Port)
+	}
+	hostPort := net.JoinHostPort(localHost, localPort)
+	scheme := "sip"
+	if transport == "tls" {
+		scheme = "sips"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "OPTIONS %s:%s SIP/2.0\r\n", scheme, host)
+	fmt.Fprintf(&b, "Via: SIP/2.0/%s %s;branch=%s;rport\r\n", strings.ToUpper(transport), hostPort, req.branch)
+	b.WriteString("Max-Forwards: 70\r\n")
+	fmt.Fprintf(&b, "From: <%s:zgrab2@%s>;tag=%s\r\n", scheme, hostPort, req.tag)
+	fmt.Fprintf(&b, "To: <%s:%s>\r\n", scheme, host)
+	fmt.Fprintf(&b, "Call-ID: %s\r\n", req.callID)
+	b.WriteString("CSeq: 1 OPTIONS\r\n")
+	fmt.Fprintf(&b, "Contact: <%s:zgrab2@%s>\r\n", scheme, hostPort)
+	b.WriteString("Accept: application/sdp\r\n")
+	if userAgent != "" {
+		fmt.Fprintf(&b, "User-Agent: %s\r\n", userAgent)
+	}
+	b.WriteString("Content-Length: 0\r\n\r\n")
+	return []byte(b.String())
+}
+
+var errInvalidResponse = errors.New("invalid SIP response")
+
+// readResponse reads a response from r. Over a stream transport, bodies are discarded; over UDP the datagram is
+// the whole message, so nothing needs to follow the headers.
+func readResponse(r *bufio.Reader, stream bool) (*Response, error) {
+	tp := textproto.NewReader(r)
+	statusLine, err := tp.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+	version, status, _ := strings.Cut(statusLine, " ")
+	if version != "SIP/2.0" {
+		return nil, fmt.Errorf("%w: status line %q", errInvalidResponse, statusLine)
+	}
+	code, reason, _ := strings.Cut(status, " ")
+	resp := &Response{ReasonPhrase: reason}
+	if resp.StatusCode, err = strconv.Atoi(code); err != nil || resp.StatusCode < 100 || resp.StatusCode > 699 {
+		return nil, fmt.Errorf("%w: status line %q", errInvalidResponse, statusLine)
+	}
+
+	contentLength := 0
+	for {
+		line, err := tp.ReadContinuedLine()
+		if err != nil {
+			if !stream && errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: header %q", errInvalidResponse, line)
+		}
+		name = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name))
+		if full, ok := compactHeaders[name]; ok {
+			name = full
+		}
+		value = strings.TrimSpace(value)
+		resp.Headers = append(resp.Headers, Header{Name: name, Value: value})
+		switch name {
+		case "Server":
+			resp.Server = value
+		case "User-Agent":
+			resp.UserAgent = value
+		case "Allow":
+			resp.Allow = append(resp.Allow, splitList(value)...)
+		case "Supported":
+			resp.Supported = append(resp.Supported, splitList(value)...)
+		case "Accept":
+			resp.Accept = append(resp.Accept, splitList(value)...)
+		case "Allow-Events":
+			resp.AllowEvents = append(resp.AllowEvents, splitList(value)...)
+		case "Call-Id":
+			resp.callID = value
+		case "Content-Length":
+			contentLength, _ = strconv.Atoi(value)
+		}
+	}
+	if stream && contentLength > 0 {
+		if contentLength > maxBodySize {
+			return nil, fmt.Errorf("%w: content length %d exceeds maximum", errInvalidResponse, contentLength)
+		}
+		if _, err = r.Discard(contentLength); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// parseDatagram parses a response received over UDP.
+func parseDatagram(data []byte) (*Response, error) {
+	return readResponse(bufio.NewReader(bytes.NewReader(data)), false)
+}
+
+// splitList splits a comma-separated header value.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
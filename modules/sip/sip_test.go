@@ -0,0 +1,67 @@
+package sip
+
+import (
+	"bufio"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildOptions(t *testing.T) {
+	req := &request{callID: "abc", branch: "z9hG4bK1", tag: "t"}
+	got := string(buildOptions(req, "udp", "192.0.2.1", &net.UDPAddr{IP: net.IPv4(198, 51, 100, 7), Port: 5060}, "zgrab2"))
+	for _, line := range []string{
+		"OPTIONS sip:192.0.2.1 SIP/2.0\r\n",
+		"Via: SIP/2.0/UDP 198.51.100.7:5060;branch=z9hG4bK1;rport\r\n",
+		"Call-ID: abc\r\n",
+		"User-Agent: zgrab2\r\n",
+	} {
+		if !strings.Contains(got, line) {
+			t.Errorf("request is missing %q:\n%s", line, got)
+		}
+	}
+	if !strings.HasSuffix(got, "Content-Length: 0\r\n\r\n") {
+		t.Errorf("request not terminated:\n%s", got)
+	}
+}
+
+func TestReadResponse(t *testing.T) {
+	raw := "SIP/2.0 100 Trying\r\ni: abc\r\nl: 0\r\n\r\n" +
+		"SIP/2.0 200 OK\r\n" +
+		"Via: SIP/2.0/TCP 198.51.100.7:5060;branch=z9hG4bK1\r\n" +
+		"Call-ID: abc\r\n" +
+		"Server: Asterisk PBX 18.0.0\r\n" +
+		"Allow: INVITE, ACK, CANCEL,\r\n OPTIONS, BYE\r\n" +
+		"k: replaces, timer\r\n" +
+		"Content-Length: 4\r\n\r\nbody"
+	reader := bufio.NewReader(strings.NewReader(raw))
+	provisional, err := readResponse(reader, true)
+	if err != nil || provisional.StatusCode != 100 || provisional.callID != "abc" {
+		t.Fatalf("got %+v (%v)", provisional, err)
+	}
+	resp, err := readResponse(reader, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || resp.ReasonPhrase != "OK" || resp.Server != "Asterisk PBX 18.0.0" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if want := []string{"INVITE", "ACK", "CANCEL", "OPTIONS", "BYE"}; !reflect.DeepEqual(resp.Allow, want) {
+		t.Errorf("got Allow %q", resp.Allow)
+	}
+	if want := []string{"replaces", "timer"}; !reflect.DeepEqual(resp.Supported, want) {
+		t.Errorf("got Supported %q", resp.Supported)
+	}
+	if _, err = reader.Peek(1); err == nil {
+		t.Error("body was not consumed")
+	}
+
+	if _, err = parseDatagram([]byte("HTTP/1.1 200 OK\r\n\r\n")); err == nil {
+		t.Error("expected error for HTTP response")
+	}
+	// a datagram without the blank line after the headers
+	if resp, err = parseDatagram([]byte("SIP/2.0 404 Not Found\r\nCall-ID: x")); err != nil || resp.callID != "x" {
+		t.Errorf("got %+v (%v)", resp, err)
+	}
+}
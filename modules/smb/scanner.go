@@ -19,6 +19,9 @@ type Flags struct {
 	zgrab2.BaseFlags `group:"Basic Options"`
 	// SetupSession tells the client to continue the handshake up to the point where credentials would be needed.
 	SetupSession bool `long:"setup-session" description:"After getting the response from the negotiation request, send a setup session packet."`
+
+	// EnumerateDialects tells the client to negotiate each dialect from SMB 1.0 to SMB 3.1.1 on its own connection.
+	EnumerateDialects bool `long:"enumerate-dialects" description:"Negotiate each dialect (SMB 1.0 through 3.1.1) on a new connection and record which the server accepts."`
 }
 
 // Module implements the zgrab2.Module interface.
@@ -118,7 +121,10 @@ func (scanner *Scanner) GetScanMetadata() any {
 //  4. If --setup-session is not set, exit with success.
 //  5. Send a setup session packet to the server with appropriate values
 //  6. Read the response from the server; on failure, exit with the log so far.
-//  7. Return the log.
+//  7. If --enumerate-dialects is set, negotiate each dialect on a new
+//     connection, recording signing / encryption requirements and the SMB
+//     3.1.1 negotiate contexts.
+//  8. Return the log.
 func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
 	conn, err := dialGroup.Dial(ctx, target)
 	if err != nil {
@@ -146,5 +152,10 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 			return zgrab2.TryGetScanStatus(err), result, err
 		}
 	}
+	if scanner.config.EnumerateDialects {
+		result.Dialects = smb.EnumerateDialects(func() (net.Conn, error) {
+			return dialGroup.Dial(ctx, target)
+		}, verbose)
+	}
 	return zgrab2.SCAN_SUCCESS, result, nil
 }
@@ -0,0 +1,191 @@
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/zmap/zgrab2"
+)
+
+// Verdicts of a DANEResult.
+const (
+	// DANEVerdictNoRecords is the verdict for a name without TLSA records, to which DANE does not apply.
+	DANEVerdictNoRecords = "no_records"
+	// DANEVerdictValid is the verdict for a certificate matching a usable TLSA record.
+	DANEVerdictValid = "valid"
+	// DANEVerdictInvalid is the verdict for a certificate matching none of the usable TLSA records.
+	DANEVerdictInvalid = "invalid"
+	// DANEVerdictUnusable is the verdict for TLSA records none of which are usable, leaving clients with opportunistic
+	// TLS.
+	DANEVerdictUnusable = "unusable"
+)
+
+// Certificate usages, selectors and matching types of TLSA records (RFC 6698).
+const (
+	tlsaUsageDANETA = 2
+	tlsaUsageDANEEE = 3
+
+	tlsaSelectorCertificate = 0
+	tlsaSelectorSPKI        = 1
+
+	tlsaMatchingFull   = 0
+	tlsaMatchingSHA256 = 1
+	tlsaMatchingSHA512 = 2
+
+	typeTLSA = dnsmessage.Type(52)
+)
+
+// TLSARecord is a TLSA record of the server.
+type TLSARecord struct {
+	Usage        uint8 `json:"usage"`
+	Selector     uint8 `json:"selector"`
+	MatchingType uint8 `json:"matching_type"`
+	// Data is the certificate association data, in hex as in zone files.
+	Data string `json:"data"`
+	// Usable is false for the records SMTP clients ignore: those of the PKIX usages, and those with an unknown
+	// usage, selector or matching type.
+	Usable  bool `json:"usable"`
+	Matched bool `json:"matched"`
+}
+
+// DANEResult is the outcome of validating the certificate of the server against its TLSA records (DANE for SMTP,
+// RFC 7672).
+type DANEResult struct {
+	// Name is the name of the TLSA records, _port._tcp. followed by the domain of the target.
+	Name string `json:"name"`
+	// Authenticated tells whether the resolver validated the records with DNSSEC by setting the AD bit. DANE only
+	// applies to authenticated records.
+	Authenticated bool         `json:"authenticated"`
+	Records       []TLSARecord `json:"records,omitempty"`
+	Verdict       string       `json:"verdict,omitempty"`
+	Error         string       `json:"error,omitempty"`
+}
+
+// parseTLSA returns the TLSA record of rdata.
+func parseTLSA(rdata []byte) (TLSARecord, error) {
+	if len(rdata) < 4 {
+		return TLSARecord{}, errors.New("short TLSA record")
+	}
+	record := TLSARecord{Usage: rdata[0], Selector: rdata[1], MatchingType: rdata[2], Data: hex.EncodeToString(rdata[3:])}
+	record.Usable = (record.Usage == tlsaUsageDANETA || record.Usage == tlsaUsageDANEEE) &&
+		record.Selector <= tlsaSelectorSPKI && record.MatchingType <= tlsaMatchingSHA512
+	return record, nil
+}
+
+// matches tells whether the selected content of cert matches the record.
+func (record *TLSARecord) matches(cert *x509.Certificate) bool {
+	content := cert.Raw
+	if record.Selector == tlsaSelectorSPKI {
+		content = cert.RawSubjectPublicKeyInfo
+	}
+	switch record.MatchingType {
+	case tlsaMatchingSHA256:
+		digest := sha256.Sum256(content)
+		content = digest[:]
+	case tlsaMatchingSHA512:
+		digest := sha512.Sum512(content)
+		content = digest[:]
+	}
+	data, err := hex.DecodeString(record.Data)
+	return err == nil && bytes.Equal(content, data)
+}
+
+// validateDANE matches the certificate chain of the server against records, and returns the verdict. A DANE-EE record
+// matches the server certificate alone, and a DANE-TA record a certificate of the chain the server certificate is
+// valid for domain with as its trust anchor.
+func validateDANE(records []TLSARecord, chain []*x509.Certificate, domain string) string {
+	if len(records) == 0 {
+		return DANEVerdictNoRecords
+	}
+	verdict := DANEVerdictUnusable
+	for i := range records {
+		record := &records[i]
+		if !record.Usable || len(chain) == 0 {
+			continue
+		}
+		if verdict == DANEVerdictUnusable {
+			verdict = DANEVerdictInvalid
+		}
+		switch record.Usage {
+		case tlsaUsageDANEEE:
+			record.Matched = record.matches(chain[0])
+		case tlsaUsageDANETA:
+			for j := 1; j < len(chain) && !record.Matched; j++ {
+				if !record.matches(chain[j]) {
+					continue
+				}
+				roots, intermediates := x509.NewCertPool(), x509.NewCertPool()
+				roots.AddCert(chain[j])
+				for _, cert := range chain[1:j] {
+					intermediates.AddCert(cert)
+				}
+				_, err := chain[0].Verify(x509.VerifyOptions{DNSName: domain, Roots: roots, Intermediates: intermediates})
+				record.Matched = err == nil
+			}
+		}
+		if record.Matched {
+			verdict = DANEVerdictValid
+		}
+	}
+	return verdict
+}
+
+// lookupTLSA returns the TLSA records of name, and whether the resolver authenticated them.
+func lookupTLSA(ctx context.Context, nameserver, name string) ([]TLSARecord, bool, error) {
+	response, err := zgrab2.ExchangeDNS(ctx, nameserver, name, typeTLSA)
+	if err != nil {
+		return nil, false, fmt.Errorf("TLSA lookup of %s: %w", name, err)
+	}
+	if response.Truncated {
+		return nil, false, fmt.Errorf("TLSA lookup of %s: truncated response", name)
+	}
+	var records []TLSARecord
+	for _, answer := range response.Answers {
+		if resource, ok := answer.Body.(*dnsmessage.UnknownResource); ok && resource.Type == typeTLSA {
+			record, err := parseTLSA(resource.Data)
+			if err != nil {
+				return nil, false, fmt.Errorf("TLSA lookup of %s: %w", name, err)
+			}
+			records = append(records, record)
+		}
+	}
+	return records, response.AuthenticData, nil
+}
+
+// checkDANE looks up the TLSA records of the target, and validates the certificate chain of tlsConn against them.
+func (scanner *Scanner) checkDANE(ctx context.Context, target *zgrab2.ScanTarget, tlsConn *zgrab2.TLSConnection) *DANEResult {
+	result := &DANEResult{}
+	if target.Domain == "" {
+		result.Error = "DANE requires the domain of the target"
+		return result
+	}
+	result.Name = fmt.Sprintf("_%d._tcp.%s", target.Port, target.Domain)
+	nameserver, err := zgrab2.DNSNameserver()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if result.Records, result.Authenticated, err = lookupTLSA(ctx, nameserver, result.Name); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	var chain []*x509.Certificate
+	for _, peer := range tlsConn.ConnectionState().PeerCertificates {
+		cert, err := x509.ParseCertificate(peer.Raw)
+		if err != nil {
+			result.Error = fmt.Sprintf("could not parse the certificate chain: %v", err)
+			return result
+		}
+		chain = append(chain, cert)
+	}
+	result.Verdict = validateDANE(result.Records, chain, target.Domain)
+	return result
+}
@@ -0,0 +1,122 @@
+package smtp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestValidateDANE(t *testing.T) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, _ := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	ca, _ := x509.ParseCertificate(caDER)
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		DNSNames:     []string{"mx.example"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, &key.PublicKey, caKey)
+	leaf, _ := x509.ParseCertificate(der)
+	chain := []*x509.Certificate{leaf, ca}
+	leafSPKI := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+	caSPKI := sha256.Sum256(ca.RawSubjectPublicKeyInfo)
+	record := func(rdata string) TLSARecord {
+		b, _ := hex.DecodeString(rdata)
+		r, err := parseTLSA(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	tests := map[string]struct {
+		records []TLSARecord
+		domain  string
+		verdict string
+	}{
+		"no records":     {nil, "mx.example", DANEVerdictNoRecords},
+		"DANE-EE":        {[]TLSARecord{record("030101" + hex.EncodeToString(leafSPKI[:]))}, "other.example", DANEVerdictValid},
+		"DANE-EE full":   {[]TLSARecord{record("0300" + "00" + hex.EncodeToString(leaf.Raw))}, "mx.example", DANEVerdictValid},
+		"DANE-TA":        {[]TLSARecord{record("020101" + hex.EncodeToString(caSPKI[:]))}, "mx.example", DANEVerdictValid},
+		"DANE-TA name":   {[]TLSARecord{record("020101" + hex.EncodeToString(caSPKI[:]))}, "other.example", DANEVerdictInvalid},
+		"mismatch":       {[]TLSARecord{record("030101" + hex.EncodeToString(caSPKI[:]))}, "mx.example", DANEVerdictInvalid},
+		"PKIX unusable":  {[]TLSARecord{record("010101" + hex.EncodeToString(leafSPKI[:]))}, "mx.example", DANEVerdictUnusable},
+		"one of several": {[]TLSARecord{record("030101" + hex.EncodeToString(caSPKI[:])), record("030101" + hex.EncodeToString(leafSPKI[:]))}, "mx.example", DANEVerdictValid},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if verdict := validateDANE(test.records, chain, test.domain); verdict != test.verdict {
+				t.Errorf("verdict %s, wanted %s: %+v", verdict, test.verdict, test.records)
+			}
+		})
+	}
+}
+
+func TestLookupTLSA(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if query.Unpack(buf[:n]) != nil || len(query.Questions) != 1 || query.Questions[0].Type != typeTLSA {
+				continue
+			}
+			question := query.Questions[0]
+			response := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, AuthenticData: true},
+				Questions: query.Questions,
+			}
+			if question.Name.String() == "_25._tcp.mx.example." {
+				response.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: 60},
+					Body:   &dnsmessage.UnknownResource{Type: typeTLSA, Data: []byte{3, 1, 1, 0xaa, 0xbb}},
+				}}
+			}
+			packed, _ := response.Pack()
+			conn.WriteTo(packed, addr)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	records, authenticated, err := lookupTLSA(ctx, conn.LocalAddr().String(), "_25._tcp.mx.example")
+	if err != nil || !authenticated || len(records) != 1 {
+		t.Fatalf("lookupTLSA = %+v, %v, %v", records, authenticated, err)
+	}
+	if r := records[0]; r.Usage != 3 || r.Selector != 1 || r.MatchingType != 1 || r.Data != "aabb" || !r.Usable {
+		t.Errorf("unexpected record: %+v", r)
+	}
+	if records, _, err := lookupTLSA(ctx, conn.LocalAddr().String(), "_25._tcp.none.example"); err != nil || len(records) != 0 {
+		t.Errorf("lookup of a name without records = %+v, %v", records, err)
+	}
+}
@@ -0,0 +1,124 @@
+package smtp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExtensionCheck is the outcome of exercising an ESMTP extension, whether or not the server advertises it.
+type ExtensionCheck struct {
+	// Advertised tells whether the last EHLO response lists the extension.
+	Advertised bool `json:"advertised"`
+	// Honored tells whether the server behaved as the extension specifies.
+	Honored bool `json:"honored"`
+	// Discrepancy tells whether the server advertises the extension without honoring it, or the other way around.
+	Discrepancy bool `json:"discrepancy"`
+	// Responses are the replies to the commands of the check, in order.
+	Responses []string `json:"responses,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// ExtensionChecks holds the checks of the PIPELINING, CHUNKING and SMTPUTF8 extensions, none of which send any
+// message content.
+type ExtensionChecks struct {
+	SMTPUTF8   *ExtensionCheck `json:"smtputf8,omitempty"`
+	Chunking   *ExtensionCheck `json:"chunking,omitempty"`
+	Pipelining *ExtensionCheck `json:"pipelining,omitempty"`
+}
+
+// advertises tells whether extensions, as returned by parseEHLO, include the extension keyword.
+func advertises(extensions []string, keyword string) bool {
+	for _, extension := range extensions {
+		if fields := strings.Fields(extension); len(fields) > 0 && strings.EqualFold(fields[0], keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// replyCode returns the code of reply, or 0 if it has none.
+func replyCode(reply string) int {
+	code, _ := getSMTPCode(reply)
+	return code
+}
+
+// sendCommands sends the commands of a check one after the other, recording their replies in check.
+func sendCommands(conn *Connection, check *ExtensionCheck, commands ...string) error {
+	for _, command := range commands {
+		ret, err := conn.SendCommand(command)
+		if err != nil {
+			check.Error = err.Error()
+			return fmt.Errorf("could not send %s command: %w", strings.Fields(command)[0], err)
+		}
+		check.Responses = append(check.Responses, ret)
+	}
+	return nil
+}
+
+// checkSMTPUTF8 starts a transaction from an address with a non-ASCII local part, which servers honoring SMTPUTF8
+// accept along with the SMTPUTF8 parameter (RFC 6531).
+func checkSMTPUTF8(conn *Connection, check *ExtensionCheck, mailFrom string) error {
+	if err := sendCommands(conn, check, "MAIL FROM:<"+mailFrom+"> SMTPUTF8", "RSET"); err != nil {
+		return err
+	}
+	code := replyCode(check.Responses[0])
+	check.Honored = code >= 200 && code < 300
+	return nil
+}
+
+// checkChunking sends an empty chunk that is not the last one in a transaction, which servers honoring CHUNKING
+// accept, or reject for lack of a valid recipient, instead of rejecting BDAT as an unknown command (RFC 3030). The
+// transaction is then reset, so no message is ever submitted.
+func checkChunking(conn *Connection, check *ExtensionCheck, mailFrom string) error {
+	if err := sendCommands(conn, check, "MAIL FROM:<"+mailFrom+">", "RCPT TO:<postmaster>", "BDAT 0", "RSET"); err != nil {
+		return err
+	}
+	code := replyCode(check.Responses[2])
+	check.Honored = code != 0 && code != 500 && code != 502
+	return nil
+}
+
+// checkPipelining sends the commands of a transaction and RSET in a single write, which servers honoring PIPELINING
+// answer in order (RFC 2920). Servers enforcing synchronization reject the commands, or close the connection.
+func checkPipelining(conn *Connection, check *ExtensionCheck, mailFrom string) error {
+	commands := []string{"MAIL FROM:<" + mailFrom + ">", "RCPT TO:<postmaster>", "RSET"}
+	if _, err := conn.Conn.Write([]byte(strings.Join(commands, "\r\n") + "\r\n")); err != nil {
+		check.Error = err.Error()
+		return fmt.Errorf("could not send pipelined commands: %w", err)
+	}
+	replies, err := conn.ReadReplies(len(commands))
+	check.Responses = replies
+	if err != nil {
+		check.Error = err.Error()
+		return fmt.Errorf("could not read replies to pipelined commands: %w", err)
+	}
+	mailCode, rsetCode := replyCode(replies[0]), replyCode(replies[2])
+	check.Honored = mailCode >= 200 && mailCode < 300 && rsetCode >= 200 && rsetCode < 300
+	return nil
+}
+
+// checkExtensions exercises the SMTPUTF8, CHUNKING and PIPELINING extensions in turn, the last as it may cost the
+// connection, and compares their behavior with the extensions advertised.
+func checkExtensions(conn *Connection, extensions []string, mailFrom string) (*ExtensionChecks, error) {
+	checks := &ExtensionChecks{}
+	localPart, domain, _ := strings.Cut(mailFrom, "@")
+	for _, c := range []struct {
+		keyword string
+		check   **ExtensionCheck
+		run     func(*Connection, *ExtensionCheck, string) error
+		from    string
+	}{
+		{"SMTPUTF8", &checks.SMTPUTF8, checkSMTPUTF8, localPart + "-ü@" + domain},
+		{"CHUNKING", &checks.Chunking, checkChunking, mailFrom},
+		{"PIPELINING", &checks.Pipelining, checkPipelining, mailFrom},
+	} {
+		check := &ExtensionCheck{Advertised: advertises(extensions, c.keyword)}
+		*c.check = check
+		err := c.run(conn, check, c.from)
+		check.Discrepancy = err == nil && check.Advertised != check.Honored
+		if err != nil {
+			return checks, err
+		}
+	}
+	return checks, nil
+}
@@ -0,0 +1,67 @@
+package smtp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckExtensions(t *testing.T) {
+	// a server honoring all three extensions, of which it only advertises PIPELINING and CHUNKING
+	conn, commands := fakeServerFunc(t, func(command string) string {
+		switch {
+		case strings.HasPrefix(command, "BDAT"):
+			return "250 2.0.0 0 octets received\r\n"
+		case strings.HasPrefix(command, "RCPT"):
+			return "250 2.1.5 Ok\r\n"
+		default:
+			return "250-2.1.0 Ok\r\n250 2.1.0 Continue\r\n"
+		}
+	})
+	checks, err := checkExtensions(conn, []string{"PIPELINING", "CHUNKING", "SIZE 1000"}, "zgrab2@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := checks.Pipelining; !c.Advertised || !c.Honored || c.Discrepancy || len(c.Responses) != 3 {
+		t.Errorf("PIPELINING: %+v", c)
+	}
+	if c := checks.Chunking; !c.Advertised || !c.Honored || c.Discrepancy {
+		t.Errorf("CHUNKING: %+v", c)
+	}
+	if c := checks.SMTPUTF8; c.Advertised || !c.Honored || !c.Discrepancy {
+		t.Errorf("SMTPUTF8: %+v", c)
+	}
+	conn.Conn.Close()
+	for _, command := range *commands {
+		if strings.HasPrefix(command, "DATA") || strings.HasPrefix(command, "BDAT") && command != "BDAT 0" {
+			t.Errorf("unexpected command %q", command)
+		}
+	}
+	if (*commands)[0] != "MAIL FROM:<zgrab2-ü@example.com> SMTPUTF8" {
+		t.Errorf("unexpected SMTPUTF8 command %q", (*commands)[0])
+	}
+
+	// a server advertising CHUNKING and SMTPUTF8 without honoring them
+	conn, _ = fakeServerFunc(t, func(command string) string {
+		switch {
+		case strings.HasSuffix(command, "SMTPUTF8"):
+			return "555 5.5.4 Unsupported option: SMTPUTF8\r\n"
+		case strings.HasPrefix(command, "BDAT"):
+			return "500 5.5.2 Error: command not recognized\r\n"
+		default:
+			return "250 2.0.0 Ok\r\n"
+		}
+	})
+	checks, err = checkExtensions(conn, []string{"CHUNKING", "SMTPUTF8"}, "zgrab2@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := checks.Chunking; c.Honored || !c.Discrepancy {
+		t.Errorf("unhonored CHUNKING: %+v", c)
+	}
+	if c := checks.SMTPUTF8; c.Honored || !c.Discrepancy {
+		t.Errorf("unhonored SMTPUTF8: %+v", c)
+	}
+	if c := checks.Pipelining; c.Advertised || !c.Honored || !c.Discrepancy {
+		t.Errorf("unadvertised PIPELINING: %+v", c)
+	}
+}
@@ -0,0 +1,223 @@
+package smtp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/zmap/zgrab2"
+)
+
+// maxMTASTSPolicySize bounds the size of the MTA-STS policies read, which RFC 8461 recommends limiting to 64 KiB.
+const maxMTASTSPolicySize = 64 << 10
+
+// MTASTSPolicy is a parsed MTA-STS policy file (RFC 8461 section 3.2).
+type MTASTSPolicy struct {
+	Version string `json:"version,omitempty"`
+	// Mode is enforce, testing or none.
+	Mode string `json:"mode,omitempty"`
+	// MX are the patterns of the MX hosts of the domain, which may start with a *. wildcard label.
+	MX []string `json:"mx,omitempty"`
+	// MaxAge is the lifetime of the policy in seconds.
+	MaxAge uint64 `json:"max_age,omitempty"`
+	Raw    string `json:"raw"`
+}
+
+// MTASTSResult holds the MTA-STS TXT record and policy of a mail domain.
+type MTASTSResult struct {
+	Domain string `json:"domain"`
+	// Record is the TXT record of _mta-sts followed by the domain.
+	Record string `json:"record,omitempty"`
+	// ID is the id field of the record, which domains change along with the policy.
+	ID          string `json:"id,omitempty"`
+	RecordError string `json:"record_error,omitempty"`
+	PolicyURL   string `json:"policy_url"`
+	HTTPStatus  int    `json:"http_status,omitempty"`
+	// Policy is the policy fetched from PolicyURL, which is fetched even without a record.
+	Policy      *MTASTSPolicy `json:"policy,omitempty"`
+	PolicyError string        `json:"policy_error,omitempty"`
+	// MXMatched tells whether the domain of the target matches an MX pattern of the policy, if it is not the mail
+	// domain itself.
+	MXMatched *bool `json:"mx_matched,omitempty"`
+}
+
+// TLSRPTResult holds the SMTP TLS Reporting record of a mail domain (RFC 8460).
+type TLSRPTResult struct {
+	// Record is the TXT record of _smtp._tls followed by the domain.
+	Record string `json:"record,omitempty"`
+	// RUA are the mailto: and https: URIs reports are sent to.
+	RUA   []string `json:"rua,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+// lookupPolicyRecord returns the TXT record of name starting with the version prefix, ignoring the others. Domains
+// with several such records have none, as both RFC 8460 and RFC 8461 require.
+func lookupPolicyRecord(ctx context.Context, nameserver, name, prefix string) (string, error) {
+	response, err := zgrab2.ExchangeDNS(ctx, nameserver, name, dnsmessage.TypeTXT)
+	if err != nil {
+		return "", fmt.Errorf("TXT lookup of %s: %w", name, err)
+	}
+	var records []string
+	for _, answer := range response.Answers {
+		if txt, ok := answer.Body.(*dnsmessage.TXTResource); ok {
+			if record := strings.Join(txt.TXT, ""); strings.HasPrefix(record, prefix) {
+				records = append(records, record)
+			}
+		}
+	}
+	switch len(records) {
+	case 0:
+		return "", fmt.Errorf("no %s record at %s", strings.TrimSuffix(prefix, ";"), name)
+	case 1:
+		return records[0], nil
+	default:
+		return "", fmt.Errorf("%d %s records at %s", len(records), strings.TrimSuffix(prefix, ";"), name)
+	}
+}
+
+// recordFields returns the key=value fields of a policy record, separated by semicolons.
+func recordFields(record string) map[string]string {
+	fields := make(map[string]string)
+	for _, field := range strings.Split(record, ";") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(field), "="); ok {
+			fields[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return fields
+}
+
+// parseMTASTSPolicy parses the key: value lines of an MTA-STS policy.
+func parseMTASTSPolicy(raw string) (*MTASTSPolicy, error) {
+	policy := &MTASTSPolicy{Raw: raw}
+	for _, line := range strings.Split(raw, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "version":
+			policy.Version = value
+		case "mode":
+			policy.Mode = value
+		case "mx":
+			policy.MX = append(policy.MX, value)
+		case "max_age":
+			maxAge, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return policy, fmt.Errorf("invalid max_age %q", value)
+			}
+			policy.MaxAge = maxAge
+		}
+	}
+	if policy.Version != "STSv1" {
+		return policy, fmt.Errorf("invalid policy version %q", policy.Version)
+	}
+	return policy, nil
+}
+
+// fetchMTASTSPolicy fetches the policy at url with client, which must not follow redirects.
+func fetchMTASTSPolicy(ctx context.Context, client *http.Client, url string, result *MTASTSResult) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		result.PolicyError = err.Error()
+		return
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		result.PolicyError = err.Error()
+		return
+	}
+	defer resp.Body.Close()
+	result.HTTPStatus = resp.StatusCode
+	if resp.StatusCode != http.StatusOK {
+		result.PolicyError = fmt.Sprintf("policy host returned HTTP %s", resp.Status)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxMTASTSPolicySize))
+	if err != nil {
+		result.PolicyError = err.Error()
+		return
+	}
+	if result.Policy, err = parseMTASTSPolicy(string(body)); err != nil {
+		result.PolicyError = err.Error()
+	}
+}
+
+// mxMatches tells whether host matches the MX pattern of a policy, in which a leading *. matches a single label.
+func mxMatches(pattern, host string) bool {
+	pattern, host = strings.ToLower(strings.TrimSuffix(pattern, ".")), strings.ToLower(strings.TrimSuffix(host, "."))
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		label, rest, found := strings.Cut(host, ".")
+		return found && label != "" && rest == suffix
+	}
+	return pattern == host
+}
+
+// getMTASTS looks up the MTA-STS record and fetches the policy of domain, over connections opened with dial.
+func getMTASTS(ctx context.Context, nameserver string, dial func(ctx context.Context, network, addr string) (net.Conn, error), domain string) *MTASTSResult {
+	result := &MTASTSResult{Domain: domain, PolicyURL: "https://mta-sts." + domain + "/.well-known/mta-sts.txt"}
+	if record, err := lookupPolicyRecord(ctx, nameserver, "_mta-sts."+domain, "v=STSv1;"); err != nil {
+		result.RecordError = err.Error()
+	} else {
+		result.Record, result.ID = record, recordFields(record)["id"]
+	}
+	client := &http.Client{
+		Transport: &http.Transport{DialContext: dial, DisableKeepAlives: true},
+		// policy hosts must not redirect
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	fetchMTASTSPolicy(ctx, client, result.PolicyURL, result)
+	return result
+}
+
+// getTLSRPT looks up the TLS-RPT record of domain.
+func getTLSRPT(ctx context.Context, nameserver string, domain string) *TLSRPTResult {
+	result := &TLSRPTResult{}
+	record, err := lookupPolicyRecord(ctx, nameserver, "_smtp._tls."+domain, "v=TLSRPTv1;")
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Record = record
+	for _, uri := range strings.Split(recordFields(record)["rua"], ",") {
+		if uri = strings.TrimSpace(uri); uri != "" {
+			result.RUA = append(result.RUA, uri)
+		}
+	}
+	return result
+}
+
+// getMailPolicies returns the MTA-STS and TLS-RPT policies of the mail domain of the target.
+func (scanner *Scanner) getMailPolicies(ctx context.Context, target *zgrab2.ScanTarget) (*MTASTSResult, *TLSRPTResult) {
+	domain := scanner.config.MailDomain
+	if domain == "" {
+		domain = target.Domain
+	}
+	if domain == "" {
+		err := "MTA-STS requires the domain of the target or --mail-domain"
+		return &MTASTSResult{RecordError: err}, &TLSRPTResult{Error: err}
+	}
+	nameserver, err := zgrab2.DNSNameserver()
+	if err != nil {
+		return &MTASTSResult{Domain: domain, RecordError: err.Error()}, &TLSRPTResult{Error: err.Error()}
+	}
+	dial := func(ctx context.Context, _, addr string) (net.Conn, error) {
+		return zgrab2.GetDefaultTCPDialer(&scanner.config.BaseFlags)(ctx, target, addr)
+	}
+	mtaSTS := getMTASTS(ctx, nameserver, dial, domain)
+	if mtaSTS.Policy != nil && target.Domain != "" && !strings.EqualFold(target.Domain, domain) {
+		matched := false
+		for _, pattern := range mtaSTS.Policy.MX {
+			matched = matched || mxMatches(pattern, target.Domain)
+		}
+		mtaSTS.MXMatched = &matched
+	}
+	return mtaSTS, getTLSRPT(ctx, nameserver, domain)
+}
@@ -0,0 +1,115 @@
+package smtp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestParseMTASTSPolicy(t *testing.T) {
+	policy, err := parseMTASTSPolicy("version: STSv1\r\nmode: enforce\r\nmx: mail.example.com\r\nmx: *.example.net\r\nmax_age: 604800\r\n")
+	if err != nil || policy.Mode != "enforce" || policy.MaxAge != 604800 || !slices.Equal(policy.MX, []string{"mail.example.com", "*.example.net"}) {
+		t.Errorf("parseMTASTSPolicy = %+v, %v", policy, err)
+	}
+	if _, err := parseMTASTSPolicy("mode: enforce\n"); err == nil {
+		t.Error("policy without a version parsed")
+	}
+	for pattern, host := range map[string]string{"mail.example.com": "MAIL.example.com.", "*.example.net": "mx1.example.net"} {
+		if !mxMatches(pattern, host) {
+			t.Errorf("%s does not match %s", pattern, host)
+		}
+	}
+	for pattern, host := range map[string]string{"mail.example.com": "mx.example.com", "*.example.net": "a.mx1.example.net"} {
+		if mxMatches(pattern, host) {
+			t.Errorf("%s matches %s", pattern, host)
+		}
+	}
+}
+
+func TestFetchMTASTSPolicy(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/mta-sts.txt":
+			w.Write([]byte("version: STSv1\nmode: testing\nmx: mail.example.com\nmax_age: 86400\n"))
+		default:
+			http.Redirect(w, r, "/.well-known/mta-sts.txt", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+	client := server.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result := &MTASTSResult{}
+	fetchMTASTSPolicy(ctx, client, server.URL+"/.well-known/mta-sts.txt", result)
+	if result.PolicyError != "" || result.HTTPStatus != http.StatusOK || result.Policy == nil || result.Policy.Mode != "testing" {
+		t.Errorf("policy: %+v", result)
+	}
+	// redirects are not followed
+	result = &MTASTSResult{}
+	fetchMTASTSPolicy(ctx, client, server.URL+"/policy", result)
+	if result.PolicyError == "" || result.HTTPStatus != http.StatusFound || result.Policy != nil {
+		t.Errorf("redirected policy: %+v", result)
+	}
+}
+
+func TestLookupPolicyRecord(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	records := map[string][][]string{
+		"_mta-sts.example.com.":   {{"v=STSv1; id=20160831085700Z;"}, {"unrelated"}},
+		"_smtp._tls.example.com.": {{"v=TLSRPTv1; ", "rua=mailto:tls@example.com,https://report.example.com/v1"}},
+		"_mta-sts.example.net.":   {{"v=STSv1; id=1"}, {"v=STSv1; id=2"}},
+	}
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if query.Unpack(buf[:n]) != nil || len(query.Questions) != 1 || query.Questions[0].Type != dnsmessage.TypeTXT {
+				continue
+			}
+			question := query.Questions[0]
+			response := dnsmessage.Message{Header: dnsmessage.Header{ID: query.ID, Response: true}, Questions: query.Questions}
+			for _, txt := range records[question.Name.String()] {
+				response.Answers = append(response.Answers, dnsmessage.Resource{
+					Header: dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: 60},
+					Body:   &dnsmessage.TXTResource{TXT: txt},
+				})
+			}
+			packed, _ := response.Pack()
+			conn.WriteTo(packed, addr)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	nameserver := conn.LocalAddr().String()
+
+	record, err := lookupPolicyRecord(ctx, nameserver, "_mta-sts.example.com", "v=STSv1;")
+	if err != nil || recordFields(record)["id"] != "20160831085700Z" {
+		t.Errorf("MTA-STS record = %q, %v", record, err)
+	}
+	if _, err := lookupPolicyRecord(ctx, nameserver, "_mta-sts.example.net", "v=STSv1;"); err == nil {
+		t.Error("domain with two MTA-STS records has one")
+	}
+	tlsRPT := getTLSRPT(ctx, nameserver, "example.com")
+	if tlsRPT.Error != "" || !slices.Equal(tlsRPT.RUA, []string{"mailto:tls@example.com", "https://report.example.com/v1"}) {
+		t.Errorf("TLS-RPT: %+v", tlsRPT)
+	}
+	if tlsRPT := getTLSRPT(ctx, nameserver, "example.org"); tlsRPT.Error == "" {
+		t.Errorf("TLS-RPT of a domain without a record: %+v", tlsRPT)
+	}
+}
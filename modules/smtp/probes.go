@@ -0,0 +1,93 @@
+package smtp
+
+import (
+	"fmt"
+)
+
+// Verdicts of a RelayProbe.
+const (
+	// RelayAccepted is the verdict for a server accepting the recipient in the external domain, indicating an open
+	// relay; it may still refuse the message after DATA, which is never sent.
+	RelayAccepted = "accepted"
+	// RelayRejected is the verdict for a server refusing the recipient with a permanent error.
+	RelayRejected = "rejected"
+	// RelayTemporaryFailure is the verdict for a server refusing the recipient with a transient error, as greylisting
+	// servers do.
+	RelayTemporaryFailure = "temporary_failure"
+	// RelaySenderRejected is the verdict for a server refusing the sender, so that no recipient is sent.
+	RelaySenderRejected = "sender_rejected"
+)
+
+// AddressProbe is the response to a VRFY or EXPN command.
+type AddressProbe struct {
+	Address  string `json:"address"`
+	Response string `json:"response"`
+	Code     int    `json:"code,omitempty"`
+	// Exposed tells whether the response tells existing and unknown users apart: a server confirming the address
+	// (250 or 251) or rejecting it as unknown (550 or 551) exposes its users to enumeration, while one answering
+	// 252 or refusing the command does not.
+	Exposed bool `json:"exposed"`
+}
+
+// RelayProbe holds the responses to a mail transaction to a recipient in an external domain, which is reset before
+// any DATA is sent.
+type RelayProbe struct {
+	MailFrom     string `json:"mail_from"`
+	MailFromCode int    `json:"mail_from_code,omitempty"`
+	RcptTo       string `json:"rcpt_to,omitempty"`
+	RcptToCode   int    `json:"rcpt_to_code,omitempty"`
+	RSET         string `json:"rset,omitempty"`
+	Verdict      string `json:"verdict,omitempty"`
+}
+
+// probeAddress sends command, VRFY or EXPN, for address.
+func probeAddress(conn *Connection, command string, address string) (*AddressProbe, error) {
+	ret, err := conn.SendCommand(command + " " + address)
+	if err != nil {
+		return nil, fmt.Errorf("could not send %s command: %w", command, err)
+	}
+	probe := &AddressProbe{Address: address, Response: ret}
+	if code, err := getSMTPCode(ret); err == nil {
+		probe.Code = code
+		probe.Exposed = code == 250 || code == 251 || code == 550 || code == 551
+	}
+	return probe, nil
+}
+
+// probeRelay starts a mail transaction from mailFrom to rcptTo, classifies the response to the recipient, and resets
+// the transaction.
+func probeRelay(conn *Connection, mailFrom string, rcptTo string) (*RelayProbe, error) {
+	probe := &RelayProbe{}
+	ret, err := conn.SendCommand("MAIL FROM:<" + mailFrom + ">")
+	if err != nil {
+		return probe, fmt.Errorf("could not send MAIL FROM command: %w", err)
+	}
+	probe.MailFrom = ret
+	if probe.MailFromCode, err = getSMTPCode(ret); err != nil {
+		return probe, fmt.Errorf("could not get MAIL FROM command code: %w", err)
+	}
+	if probe.MailFromCode < 200 || probe.MailFromCode >= 300 {
+		probe.Verdict = RelaySenderRejected
+	} else {
+		if ret, err = conn.SendCommand("RCPT TO:<" + rcptTo + ">"); err != nil {
+			return probe, fmt.Errorf("could not send RCPT TO command: %w", err)
+		}
+		probe.RcptTo = ret
+		if probe.RcptToCode, err = getSMTPCode(ret); err != nil {
+			return probe, fmt.Errorf("could not get RCPT TO command code: %w", err)
+		}
+		switch {
+		case probe.RcptToCode >= 200 && probe.RcptToCode < 300:
+			probe.Verdict = RelayAccepted
+		case probe.RcptToCode >= 400 && probe.RcptToCode < 500:
+			probe.Verdict = RelayTemporaryFailure
+		default:
+			probe.Verdict = RelayRejected
+		}
+	}
+	if ret, err = conn.SendCommand("RSET"); err != nil {
+		return probe, fmt.Errorf("could not send RSET command: %w", err)
+	}
+	probe.RSET = ret
+	return probe, nil
+}
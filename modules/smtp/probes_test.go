@@ -0,0 +1,97 @@
+package smtp
+
+import (
+	"bufio"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeServer answers each command read from conn with the response for its first word, and records the commands.
+func fakeServer(t *testing.T, responses map[string]string) (*Connection, *[]string) {
+	return fakeServerFunc(t, func(command string) string {
+		if response, ok := responses[strings.Fields(command)[0]]; ok {
+			return response
+		}
+		return "502 5.5.1 Unrecognized command\r\n"
+	})
+}
+
+// fakeServerFunc answers each command read from conn with the response of respond, and records the commands.
+func fakeServerFunc(t *testing.T, respond func(command string) string) (*Connection, *[]string) {
+	client, server := net.Pipe()
+	t.Cleanup(func() { client.Close() })
+	var commands []string
+	go func() {
+		defer server.Close()
+		reader := bufio.NewReader(server)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.TrimSpace(line)
+			commands = append(commands, command)
+			server.Write([]byte(respond(command)))
+		}
+	}()
+	return &Connection{Conn: client}, &commands
+}
+
+func TestProbeAddress(t *testing.T) {
+	conn, _ := fakeServer(t, map[string]string{
+		"VRFY": "250 2.1.5 Postmaster <postmaster@mx.example>\r\n",
+		"EXPN": "252 2.5.2 Cannot VRFY user\r\n",
+	})
+	probe, err := probeAddress(conn, "VRFY", "postmaster")
+	if err != nil || probe.Code != 250 || !probe.Exposed || probe.Address != "postmaster" {
+		t.Errorf("VRFY = %+v, %v", probe, err)
+	}
+	probe, err = probeAddress(conn, "EXPN", "postmaster")
+	if err != nil || probe.Code != 252 || probe.Exposed {
+		t.Errorf("EXPN = %+v, %v", probe, err)
+	}
+}
+
+func TestProbeRelay(t *testing.T) {
+	tests := map[string]struct {
+		responses map[string]string
+		verdict   string
+		commands  []string
+	}{
+		"open relay": {
+			responses: map[string]string{"MAIL": "250 2.1.0 Ok\r\n", "RCPT": "250 2.1.5 Ok\r\n", "RSET": "250 2.0.0 Ok\r\n"},
+			verdict:   RelayAccepted,
+			commands:  []string{"MAIL FROM:<a@example.com>", "RCPT TO:<b@example.net>", "RSET"},
+		},
+		"relay denied": {
+			responses: map[string]string{"MAIL": "250 2.1.0 Ok\r\n", "RCPT": "554 5.7.1 Relay access denied\r\n", "RSET": "250 2.0.0 Ok\r\n"},
+			verdict:   RelayRejected,
+			commands:  []string{"MAIL FROM:<a@example.com>", "RCPT TO:<b@example.net>", "RSET"},
+		},
+		"greylisted": {
+			responses: map[string]string{"MAIL": "250 2.1.0 Ok\r\n", "RCPT": "450 4.2.0 Greylisted\r\n", "RSET": "250 2.0.0 Ok\r\n"},
+			verdict:   RelayTemporaryFailure,
+			commands:  []string{"MAIL FROM:<a@example.com>", "RCPT TO:<b@example.net>", "RSET"},
+		},
+		"sender rejected": {
+			responses: map[string]string{"MAIL": "553 5.7.1 Sender rejected\r\n", "RSET": "250 2.0.0 Ok\r\n"},
+			verdict:   RelaySenderRejected,
+			commands:  []string{"MAIL FROM:<a@example.com>", "RSET"},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			conn, commands := fakeServer(t, test.responses)
+			probe, err := probeRelay(conn, "a@example.com", "b@example.net")
+			if err != nil || probe.Verdict != test.verdict {
+				t.Errorf("probeRelay = %+v, %v", probe, err)
+			}
+			conn.Conn.Close()
+			if !slices.Equal(*commands, test.commands) {
+				t.Errorf("unexpected commands: %q", *commands)
+			}
+		})
+	}
+}
@@ -30,6 +30,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -52,12 +53,41 @@ type ScanResults struct {
 	// EHLO is the server's response to the EHLO command, if one is sent.
 	EHLO string `json:"ehlo,omitempty"`
 
+	// Extensions are the ESMTP extensions of the EHLO response, with their parameters.
+	Extensions []string `json:"extensions,omitempty"`
+
+	// AuthMechanisms are the SASL mechanisms the server advertises in the EHLO response.
+	AuthMechanisms []string `json:"auth_mechanisms,omitempty"`
+
 	// HELP is the server's response to the HELP command, if it is sent.
 	HELP string `json:"help,omitempty"`
 
 	// StartTLS is the server's response to the STARTTLS command, if it is sent.
 	StartTLS string `json:"starttls,omitempty"`
 
+	// EHLOAfterSTARTTLS is the server's response to the EHLO command sent again after STARTTLS, as RFC 3207
+	// requires, since servers may advertise other extensions over TLS.
+	EHLOAfterSTARTTLS string `json:"ehlo_after_starttls,omitempty"`
+
+	// ExtensionsAfterSTARTTLS are the ESMTP extensions of the EHLO response after STARTTLS.
+	ExtensionsAfterSTARTTLS []string `json:"extensions_after_starttls,omitempty"`
+
+	// AuthMechanismsAfterSTARTTLS are the SASL mechanisms of the EHLO response after STARTTLS.
+	AuthMechanismsAfterSTARTTLS []string `json:"auth_mechanisms_after_starttls,omitempty"`
+
+	// VRFY is the server's response to the VRFY command, if it is sent.
+	VRFY *AddressProbe `json:"vrfy,omitempty"`
+
+	// EXPN is the server's response to the EXPN command, if it is sent.
+	EXPN *AddressProbe `json:"expn,omitempty"`
+
+	// Relay holds the responses to the relay probe, if --relay-probe is used.
+	Relay *RelayProbe `json:"relay,omitempty"`
+
+	// ExtensionChecks holds the checks of whether the server honors the extensions it advertises, if --check-extensions
+	// is used.
+	ExtensionChecks *ExtensionChecks `json:"extension_checks,omitempty"`
+
 	// QUIT is the server's response to the QUIT command, if it is sent.
 	QUIT string `json:"quit,omitempty"`
 
@@ -67,6 +97,15 @@ type ScanResults struct {
 
 	// TLSLog is the standard TLS log, if STARTTLS is sent or if --SMTPS is used
 	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
+
+	// MTASTS is the MTA-STS record and policy of the mail domain, if --mta-sts is used
+	MTASTS *MTASTSResult `json:"mta_sts,omitempty"`
+
+	// TLSRPT is the TLS-RPT record of the mail domain, if --mta-sts is used
+	TLSRPT *TLSRPTResult `json:"tls_rpt,omitempty"`
+
+	// DANE is the validation of the TLS certificate against the TLSA records of the target, if --dane is used
+	DANE *DANEResult `json:"dane,omitempty"`
 }
 
 // Flags holds the command-line configuration for the HTTP scan module.
@@ -92,6 +131,36 @@ type Flags struct {
 
 	// SMTPSecure indicates that the entire transaction should be wrapped in a TLS session.
 	SMTPSecure bool `long:"smtps" description:"Perform a TLS handshake immediately upon connecting."`
+
+	// SendVRFY indicates that the client should send the VRFY command for ProbeAddress.
+	SendVRFY bool `long:"send-vrfy" description:"Send the VRFY command for --probe-address, recording whether the response exposes users to enumeration"`
+
+	// SendEXPN indicates that the client should send the EXPN command for ProbeAddress.
+	SendEXPN bool `long:"send-expn" description:"Send the EXPN command for --probe-address, recording whether the response exposes users to enumeration"`
+
+	// ProbeAddress is the address of the VRFY and EXPN commands.
+	ProbeAddress string `long:"probe-address" default:"postmaster" description:"Address or user name to send with VRFY and EXPN"`
+
+	// RelayProbe indicates that the client should start a mail transaction to an external recipient, never sending DATA.
+	RelayProbe bool `long:"relay-probe" description:"Send MAIL FROM and RCPT TO for a recipient in an external domain, followed by RSET and never by DATA, to classify whether the server relays mail"`
+
+	// RelayMailFrom is the sender of the relay probe and the extension checks.
+	RelayMailFrom string `long:"relay-mail-from" default:"zgrab2@example.com" description:"Sender address of --relay-probe and --check-extensions"`
+
+	// RelayRcptTo is the recipient of the relay probe, in a domain the server should not accept mail for.
+	RelayRcptTo string `long:"relay-rcpt-to" default:"zgrab2@example.net" description:"Recipient address of --relay-probe, in a domain the server is not responsible for"`
+
+	// CheckExtensions indicates that the client should check whether the server honors PIPELINING, CHUNKING and SMTPUTF8.
+	CheckExtensions bool `long:"check-extensions" description:"Check whether the server honors PIPELINING, CHUNKING and SMTPUTF8 with transactions that are reset before any message content, and record discrepancies with the extensions it advertises"`
+
+	// MTASTS indicates that the MTA-STS and TLS-RPT policies of the mail domain should be retrieved.
+	MTASTS bool `long:"mta-sts" description:"Look up the MTA-STS and TLS-RPT records of the mail domain with the --dns-resolvers, and fetch and parse the MTA-STS policy from https://mta-sts.<domain>/.well-known/mta-sts.txt"`
+
+	// MailDomain is the mail domain of the MTA-STS and TLS-RPT policies, if it is not the domain of the target.
+	MailDomain string `long:"mail-domain" description:"Mail domain of --mta-sts, when the domain of the target is its MX host, whose match against the MX patterns of the policy is then recorded"`
+
+	// DANE indicates that the certificate of the TLS connection should be validated against the TLSA records of the target.
+	DANE bool `long:"dane" description:"Look up the TLSA records of the target domain with the --dns-resolvers and validate the certificate of the TLS connection against them (DANE, RFC 7672)"`
 }
 
 // Module implements the zgrab2.Module interface.
@@ -211,6 +280,36 @@ func getCommand(cmd string, arg string) string {
 	return cmd + " " + arg
 }
 
+// parseEHLO returns the extensions of an EHLO response, which follow the greeting on its first line, and the SASL
+// mechanisms of its AUTH extension, including the AUTH= form of early implementations.
+func parseEHLO(response string) (extensions []string, authMechanisms []string) {
+	lines := strings.Split(strings.TrimRight(response, "\r\n"), "\n")
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if len(line) < 4 || line[3] != '-' && line[3] != ' ' {
+			continue
+		}
+		extension := strings.TrimSpace(line[4:])
+		if extension == "" {
+			continue
+		}
+		extensions = append(extensions, extension)
+		fields := strings.Fields(extension)
+		keyword := strings.ToUpper(fields[0])
+		if keyword == "AUTH" || strings.HasPrefix(keyword, "AUTH=") {
+			if mechanism := strings.TrimPrefix(keyword, "AUTH="); mechanism != keyword && mechanism != "" {
+				fields = append(fields, mechanism)
+			}
+			for _, mechanism := range fields[1:] {
+				if mechanism = strings.ToUpper(mechanism); !slices.Contains(authMechanisms, mechanism) {
+					authMechanisms = append(authMechanisms, mechanism)
+				}
+			}
+		}
+	}
+	return extensions, authMechanisms
+}
+
 // Verify that an SMTP code was returned, and that it is a successful one!
 // Return code on SCAN_APPLICATION_ERROR for better info
 func VerifySMTPContents(banner string) (zgrab2.ScanStatus, int) {
@@ -233,27 +332,38 @@ func VerifySMTPContents(banner string) (zgrab2.ScanStatus, int) {
 }
 
 // Scan performs the SMTP scan.
-//  1. Open a TCP connection to the target port (default 25).
-//  2. If --smtps is set, perform a TLS handshake.
-//  3. Read the banner.
-//  4. If --send-ehlo or --send-helo is sent, send the corresponding EHLO
+//  1. If --mta-sts is set, retrieve the MTA-STS and TLS-RPT policies.
+//  2. Open a TCP connection to the target port (default 25).
+//  3. If --smtps is set, perform a TLS handshake.
+//  4. Read the banner.
+//  5. If --send-ehlo or --send-helo is sent, send the corresponding EHLO
 //     or HELO command.
-//  5. If --send-help is sent, send HELP, read the result.
-//  6. If --starttls is sent, send STARTTLS, read the result, negotiate a
-//     TLS connection.
-//  7. If --send-quit is sent, send QUIT and read the result.
-//  8. Close the connection.
+//  6. If --send-help is sent, send HELP, read the result.
+//  7. If --starttls is sent, send STARTTLS, read the result, negotiate a
+//     TLS connection, and send EHLO again if it was sent before.
+//  8. If --send-vrfy, --send-expn or --relay-probe are sent, send VRFY,
+//     EXPN, or MAIL FROM, RCPT TO and RSET, and read the results.
+//  9. If --check-extensions is sent, check SMTPUTF8, CHUNKING and
+//     PIPELINING.
+//  10. If --send-quit is sent, send QUIT and read the result.
+//  11. Close the connection.
 func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
 	l4Dialer := dialGroup.L4Dialer
 	if l4Dialer == nil {
 		return zgrab2.SCAN_INVALID_INPUTS, nil, errors.New("no L4 dialer found. SMTP requires a L4 dialer")
 	}
+	// the policies are retrieved first, so that the SMTP session does not wait on them
+	var mtaSTS *MTASTSResult
+	var tlsRPT *TLSRPTResult
+	if scanner.config.MTASTS {
+		mtaSTS, tlsRPT = scanner.getMailPolicies(ctx, target)
+	}
 	conn, err := l4Dialer(target)(ctx, "tcp", net.JoinHostPort(target.Host(), strconv.Itoa(int(target.Port))))
 	if err != nil {
 		return zgrab2.TryGetScanStatus(err), nil, err
 	}
 	defer zgrab2.CloseConnAndHandleError(conn)
-	result := &ScanResults{}
+	result := &ScanResults{MTASTS: mtaSTS, TLSRPT: tlsRPT}
 	if scanner.config.SMTPSecure {
 		tlsWrapper := dialGroup.TLSWrapper
 		if tlsWrapper == nil {
@@ -266,6 +376,9 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 		}
 		result.TLSLog = tlsConn.GetLog()
 		result.ImplicitTLS = true
+		if scanner.config.DANE {
+			result.DANE = scanner.checkDANE(ctx, target, tlsConn)
+		}
 		conn = tlsConn
 	}
 	smtpConn := Connection{Conn: conn}
@@ -293,6 +406,7 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 			return zgrab2.TryGetScanStatus(err), result, fmt.Errorf("could not send EHLO command: %w", err)
 		}
 		result.EHLO = ret
+		result.Extensions, result.AuthMechanisms = parseEHLO(ret)
 		moduleMetadata.incrementHostsSupportingEHLO() // mark that we found a host that supports EHLO
 	} else {
 		// send a HELO msg since server doesn't support EHLO
@@ -332,11 +446,51 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 		}
 		tlsConn, err := tlsWrapper(ctx, target, smtpConn.Conn)
 		if err != nil {
+			if tlsConn != nil {
+				// keep the handshake log of a failed handshake
+				result.TLSLog = tlsConn.GetLog()
+			}
 			return zgrab2.TryGetScanStatus(err), result, fmt.Errorf("could not initiate a TLS connection: %w", err)
 		}
 		result.TLSLog = tlsConn.GetLog()
+		if scanner.config.DANE {
+			result.DANE = scanner.checkDANE(ctx, target, tlsConn)
+		}
 		smtpConn.Conn = tlsConn
 		moduleMetadata.incrementHostsSupportingSTARTTLS() // mark that we found a host that supports STARTTLS
+		if shouldSendEHLO {
+			// the client must discard what it knows of the server from before STARTTLS
+			ret, err := smtpConn.SendCommand(getCommand("EHLO", target.Domain))
+			if err != nil {
+				return zgrab2.TryGetScanStatus(err), result, fmt.Errorf("could not send EHLO command after STARTTLS: %w", err)
+			}
+			result.EHLOAfterSTARTTLS = ret
+			result.ExtensionsAfterSTARTTLS, result.AuthMechanismsAfterSTARTTLS = parseEHLO(ret)
+		}
+	}
+	if scanner.config.SendVRFY {
+		if result.VRFY, err = probeAddress(&smtpConn, "VRFY", scanner.config.ProbeAddress); err != nil {
+			return zgrab2.TryGetScanStatus(err), result, err
+		}
+	}
+	if scanner.config.SendEXPN {
+		if result.EXPN, err = probeAddress(&smtpConn, "EXPN", scanner.config.ProbeAddress); err != nil {
+			return zgrab2.TryGetScanStatus(err), result, err
+		}
+	}
+	if scanner.config.RelayProbe {
+		if result.Relay, err = probeRelay(&smtpConn, scanner.config.RelayMailFrom, scanner.config.RelayRcptTo); err != nil {
+			return zgrab2.TryGetScanStatus(err), result, err
+		}
+	}
+	if scanner.config.CheckExtensions {
+		extensions := result.Extensions
+		if result.EHLOAfterSTARTTLS != "" {
+			extensions = result.ExtensionsAfterSTARTTLS
+		}
+		if result.ExtensionChecks, err = checkExtensions(&smtpConn, extensions, scanner.config.RelayMailFrom); err != nil {
+			return zgrab2.TryGetScanStatus(err), result, err
+		}
 	}
 	if scanner.config.SendQUIT {
 		ret, err := smtpConn.SendCommand("QUIT")
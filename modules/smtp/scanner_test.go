@@ -1,6 +1,7 @@
 package smtp
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/zmap/zgrab2"
@@ -52,3 +53,23 @@ func TestVerifySMTPContents(t *testing.T) {
 	}
 
 }
+
+func TestParseEHLO(t *testing.T) {
+	extensions, mechanisms := parseEHLO("250-mail.example.com Hello [192.0.2.1]\r\n" +
+		"250-SIZE 35882577\r\n" +
+		"250-8BITMIME\r\n" +
+		"250-AUTH LOGIN plain XOAUTH2\r\n" +
+		"250-AUTH=LOGIN\r\n" +
+		"250 STARTTLS\r\n")
+	if !slices.Equal(extensions, []string{"SIZE 35882577", "8BITMIME", "AUTH LOGIN plain XOAUTH2", "AUTH=LOGIN", "STARTTLS"}) {
+		t.Errorf("unexpected extensions: %q", extensions)
+	}
+	if !slices.Equal(mechanisms, []string{"LOGIN", "PLAIN", "XOAUTH2"}) {
+		t.Errorf("unexpected AUTH mechanisms: %q", mechanisms)
+	}
+
+	extensions, mechanisms = parseEHLO("250 mail.example.com\r\n")
+	if extensions != nil || mechanisms != nil {
+		t.Errorf("unexpected extensions of a response without any: %q, %q", extensions, mechanisms)
+	}
+}
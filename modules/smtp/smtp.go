@@ -4,6 +4,7 @@ import (
 	"io"
 	"net"
 	"regexp"
+	"strings"
 
 	"github.com/zmap/zgrab2"
 )
@@ -37,3 +38,37 @@ func (conn *Connection) SendCommand(cmd string) (string, error) {
 	}
 	return conn.ReadResponse()
 }
+
+// ReadReplies reads from the connection until it has read count replies, as pipelined commands are answered, and
+// returns them in order. The replies read before an error are returned along with it.
+func (conn *Connection) ReadReplies(count int) ([]string, error) {
+	var replies []string
+	var reply strings.Builder
+	buf := make([]byte, readBufferSize)
+	pending := ""
+	for len(replies) < count {
+		n, err := conn.Conn.Read(buf)
+		pending += string(buf[:n])
+		for len(replies) < count {
+			i := strings.Index(pending, "\r\n")
+			if i < 0 {
+				break
+			}
+			line := pending[:i+2]
+			pending = pending[i+2:]
+			reply.WriteString(line)
+			// the last line of a reply has a space, or nothing, after its code
+			if len(line) < 4 || line[3] != '-' {
+				replies = append(replies, reply.String())
+				reply.Reset()
+			}
+		}
+		if len(pending)+reply.Len() > readBufferSize {
+			return replies, zgrab2.ErrInsufficientBuffer
+		}
+		if err != nil && len(replies) < count {
+			return replies, err
+		}
+	}
+	return replies, nil
+}
@@ -0,0 +1,198 @@
+package snmp
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BER tags used by SNMP. Only single-byte tags occur in SNMP messages.
+const (
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagNull        = 0x05
+	tagOID         = 0x06
+	tagSequence    = 0x30
+	tagIPAddress   = 0x40
+	tagCounter32   = 0x41
+	tagGauge32     = 0x42
+	tagTimeTicks   = 0x43
+	tagCounter64   = 0x46
+
+	tagNoSuchObject   = 0x80
+	tagNoSuchInstance = 0x81
+	tagEndOfMibView   = 0x82
+
+	tagGetRequest  = 0xA0
+	tagGetResponse = 0xA2
+	tagReport      = 0xA8
+)
+
+var errTruncated = errors.New("truncated BER encoding")
+
+// berValue is a single decoded TLV.
+type berValue struct {
+	tag     byte
+	content []byte
+}
+
+// berEncode returns the TLV with the given tag whose content is the concatenation of the given parts.
+func berEncode(tag byte, parts ...[]byte) []byte {
+	length := 0
+	for _, part := range parts {
+		length += len(part)
+	}
+	out := []byte{tag}
+	switch {
+	case length < 0x80:
+		out = append(out, byte(length))
+	case length <= 0xFF:
+		out = append(out, 0x81, byte(length))
+	default:
+		out = append(out, 0x82, byte(length>>8), byte(length))
+	}
+	for _, part := range parts {
+		out = append(out, part...)
+	}
+	return out
+}
+
+// berInteger encodes a two's complement INTEGER with the minimal number of bytes.
+func berInteger(v int64) []byte {
+	var content []byte
+	for {
+		content = append([]byte{byte(v)}, content...)
+		if (v < 0x80 && v >= -0x80) || len(content) == 8 {
+			break
+		}
+		v >>= 8
+	}
+	return berEncode(tagInteger, content)
+}
+
+func berOctetString(s string) []byte {
+	return berEncode(tagOctetString, []byte(s))
+}
+
+// berOID encodes a dotted object identifier. It panics on malformed input, since all OIDs are constants.
+func berOID(oid string) []byte {
+	parts := strings.Split(oid, ".")
+	arcs := make([]uint64, len(parts))
+	for i, part := range parts {
+		arc, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			panic(fmt.Sprintf("invalid OID %s", oid))
+		}
+		arcs[i] = arc
+	}
+	// the first two arcs share one subidentifier
+	subidentifiers := append([]uint64{arcs[0]*40 + arcs[1]}, arcs[2:]...)
+	var content []byte
+	for _, subidentifier := range subidentifiers {
+		enc := []byte{byte(subidentifier & 0x7F)}
+		for subidentifier >>= 7; subidentifier > 0; subidentifier >>= 7 {
+			enc = append([]byte{byte(subidentifier&0x7F) | 0x80}, enc...)
+		}
+		content = append(content, enc...)
+	}
+	return berEncode(tagOID, content)
+}
+
+// berDecode decodes the TLV at the start of data and returns it and the remaining bytes.
+func berDecode(data []byte) (berValue, []byte, error) {
+	if len(data) < 2 {
+		return berValue{}, nil, errTruncated
+	}
+	tag := data[0]
+	length := int(data[1])
+	offset := 2
+	if length&0x80 != 0 {
+		n := length & 0x7F
+		if n == 0 || n > 3 || len(data) < 2+n {
+			return berValue{}, nil, fmt.Errorf("unsupported BER length encoding 0x%02x", data[1])
+		}
+		length = 0
+		for _, b := range data[2 : 2+n] {
+			length = length<<8 | int(b)
+		}
+		offset += n
+	}
+	if len(data) < offset+length {
+		return berValue{}, nil, errTruncated
+	}
+	return berValue{tag: tag, content: data[offset : offset+length]}, data[offset+length:], nil
+}
+
+// children decodes the content of a constructed value.
+func (v berValue) children() ([]berValue, error) {
+	var values []berValue
+	rest := v.content
+	for len(rest) > 0 {
+		child, next, err := berDecode(rest)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, child)
+		rest = next
+	}
+	return values, nil
+}
+
+// expect decodes a constructed value and checks that it has the given tag and at least n children.
+func (v berValue) expect(tag byte, n int) ([]berValue, error) {
+	if v.tag != tag {
+		return nil, fmt.Errorf("unexpected BER tag 0x%02x, expected 0x%02x", v.tag, tag)
+	}
+	values, err := v.children()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) < n {
+		return nil, fmt.Errorf("BER value 0x%02x has %d elements, expected %d", tag, len(values), n)
+	}
+	return values, nil
+}
+
+// integer returns the value of an INTEGER, or of one of the unsigned application types.
+func (v berValue) integer() (int64, error) {
+	if len(v.content) == 0 || len(v.content) > 9 {
+		return 0, fmt.Errorf("invalid integer length %d", len(v.content))
+	}
+	var result int64
+	if v.tag == tagInteger && v.content[0]&0x80 != 0 {
+		result = -1
+	}
+	for _, b := range v.content {
+		result = result<<8 | int64(b)
+	}
+	return result, nil
+}
+
+// oid returns the dotted representation of an OBJECT IDENTIFIER.
+func (v berValue) oid() (string, error) {
+	if v.tag != tagOID || len(v.content) == 0 {
+		return "", errors.New("invalid OID")
+	}
+	var subidentifiers []uint64
+	var subidentifier uint64
+	for i, b := range v.content {
+		subidentifier = subidentifier<<7 | uint64(b&0x7F)
+		if b&0x80 == 0 {
+			subidentifiers = append(subidentifiers, subidentifier)
+			subidentifier = 0
+		} else if i == len(v.content)-1 {
+			return "", errors.New("truncated OID")
+		}
+	}
+	// the first subidentifier encodes the first two arcs
+	first := subidentifiers[0]
+	arcs := []string{strconv.FormatUint(first/40, 10), strconv.FormatUint(first%40, 10)}
+	if first >= 80 {
+		arcs = []string{"2", strconv.FormatUint(first-80, 10)}
+	}
+	for _, subidentifier := range subidentifiers[1:] {
+		arcs = append(arcs, strconv.FormatUint(subidentifier, 10))
+	}
+	return strings.Join(arcs, "."), nil
+}
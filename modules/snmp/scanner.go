@@ -0,0 +1,213 @@
+// Package snmp contains the zgrab2 Module implementation for SNMP.
+//
+// The scan sends a GetRequest for sysDescr, sysObjectID and sysUpTime with each configured community string and
+// SNMP version (v1, v2c), and an SNMPv3 discovery request to learn the agent's engine ID. All probes are sent over
+// the same UDP socket and matched to their responses by request/message ID.
+package snmp
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// Communities holds the response to each community string the agent accepted.
+	Communities []*CommunityResponse `json:"communities,omitempty"`
+
+	// Engine is the agent's SNMPv3 engine information.
+	Engine *EngineInfo `json:"engine,omitempty"`
+
+	// Probes records the outcome of every probe sent.
+	Probes []*zgrab2.UDPProbeResult `json:"probes,omitempty"`
+}
+
+// Flags are the SNMP-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.UDPFlags
+
+	Communities string `long:"communities" default:"public" description:"Comma-separated community strings to try"`
+	Versions    string `long:"versions" default:"v2c,v3" description:"Comma-separated SNMP versions to probe (v1, v2c, v3)"`
+
+	communities []string
+	versions    map[string]bool
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the snmp zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("snmp", "Simple Network Management Protocol (SNMP)", module.Description(), 161, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Probe SNMP community strings and discover the SNMPv3 engine ID"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	if err := f.UDPFlags.Validate(); err != nil {
+		return err
+	}
+	f.versions = make(map[string]bool)
+	for _, version := range strings.Split(f.Versions, ",") {
+		version = strings.TrimSpace(version)
+		switch version {
+		case "v1", "v2c", "v3":
+			f.versions[version] = true
+		case "":
+		default:
+			return fmt.Errorf("unknown SNMP version %q", version)
+		}
+	}
+	if len(f.versions) == 0 {
+		return fmt.Errorf("no SNMP versions given")
+	}
+	f.communities = strings.Split(f.Communities, ",")
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "snmp"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportUDP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// communityProbe returns a probe for a GetRequest with the given version and community.
+func communityProbe(version int64, community string) *zgrab2.UDPProbe {
+	requestID := rand.Int31()
+	return zgrab2.NewStaticUDPProbe(versionName(version)+":"+community, buildGetRequest(version, community, requestID), func(_, response []byte) bool {
+		id, err := communityRequestID(response)
+		return err == nil && id == int64(requestID)
+	})
+}
+
+// discoveryProbe returns a probe for an SNMPv3 engine discovery request.
+func discoveryProbe() *zgrab2.UDPProbe {
+	messageID := rand.Int31()
+	return zgrab2.NewStaticUDPProbe("v3:discovery", buildDiscoveryRequest(messageID), func(_, response []byte) bool {
+		id, err := v3MessageID(response)
+		return err == nil && id == int64(messageID)
+	})
+}
+
+// Scan performs the configured scan on the SNMP agent.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	results := new(ScanResults)
+	var lastErr error
+	for _, version := range []int64{versionV1, versionV2c} {
+		if !scanner.config.versions[versionName(version)] {
+			continue
+		}
+		for _, community := range scanner.config.communities {
+			probeResult, err := zgrab2.SendUDPProbe(ctx, conn, communityProbe(version, community), target, &scanner.config.UDPFlags)
+			results.Probes = append(results.Probes, probeResult)
+			if err != nil {
+				lastErr = err
+				if probeResult.ICMP != "" {
+					// the agent (or a firewall) rejects the port outright; further probes are pointless
+					return zgrab2.TryGetScanStatus(err), results, err
+				}
+				continue
+			}
+			response, err := parseGetResponse(probeResult.Response)
+			if err != nil {
+				lastErr = zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, fmt.Errorf("invalid response to probe %s: %w", probeResult.Probe, err))
+				continue
+			}
+			results.Communities = append(results.Communities, response)
+		}
+	}
+
+	if scanner.config.versions["v3"] {
+		probeResult, err := zgrab2.SendUDPProbe(ctx, conn, discoveryProbe(), target, &scanner.config.UDPFlags)
+		results.Probes = append(results.Probes, probeResult)
+		if err != nil {
+			lastErr = err
+		} else if results.Engine, err = parseDiscoveryReport(probeResult.Response); err != nil {
+			lastErr = zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, fmt.Errorf("invalid response to probe %s: %w", probeResult.Probe, err))
+		}
+	}
+
+	if len(results.Communities) == 0 && results.Engine == nil {
+		return zgrab2.TryGetScanStatus(lastErr), results, lastErr
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
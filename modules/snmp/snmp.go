@@ -0,0 +1,298 @@
+package snmp
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// SNMP message versions
+const (
+	versionV1  = 0
+	versionV2c = 1
+	versionV3  = 3
+)
+
+// System group OIDs collected from community responses
+const (
+	oidSysDescr    = "1.3.6.1.2.1.1.1.0"
+	oidSysObjectID = "1.3.6.1.2.1.1.2.0"
+	oidSysUpTime   = "1.3.6.1.2.1.1.3.0"
+)
+
+// SystemInfo holds the values of the system group returned by a GetRequest.
+type SystemInfo struct {
+	SysDescr    string `json:"sys_descr,omitempty"`
+	SysObjectID string `json:"sys_object_id,omitempty"`
+	// SysUpTime is in hundredths of a second
+	SysUpTime uint32 `json:"sys_uptime,omitempty"`
+}
+
+// CommunityResponse is the response to a v1/v2c GetRequest with one community string.
+type CommunityResponse struct {
+	Version     string      `json:"version"`
+	Community   string      `json:"community"`
+	ErrorStatus int64       `json:"error_status,omitempty"`
+	System      *SystemInfo `json:"system,omitempty"`
+}
+
+// EngineInfo is the authoritative engine information from an SNMPv3 discovery Report.
+type EngineInfo struct {
+	EngineID    string `json:"engine_id"`
+	EngineBoots int64  `json:"engine_boots"`
+	EngineTime  int64  `json:"engine_time"`
+	// Enterprise is the IANA private enterprise number the engine ID is based on, if it follows RFC 3411
+	Enterprise uint32 `json:"enterprise,omitempty"`
+	Vendor     string `json:"vendor,omitempty"`
+	// Format is the format of the remainder of the engine ID (ipv4, ipv6, mac, text, octets or enterprise)
+	Format string `json:"format,omitempty"`
+	// Data is the decoded remainder of the engine ID, for the ipv4, ipv6, mac and text formats
+	Data string `json:"data,omitempty"`
+}
+
+// enterpriseVendors maps common private enterprise numbers to vendors.
+var enterpriseVendors = map[uint32]string{
+	9:     "Cisco",
+	11:    "Hewlett-Packard",
+	311:   "Microsoft",
+	674:   "Dell",
+	1991:  "Brocade (Foundry)",
+	2011:  "Huawei",
+	2021:  "UC Davis (ucd-snmp)",
+	2636:  "Juniper",
+	4526:  "Netgear",
+	6876:  "VMware",
+	8072:  "Net-SNMP",
+	12356: "Fortinet",
+	14988: "MikroTik",
+	25461: "Palo Alto Networks",
+	30065: "Arista",
+	41112: "Ubiquiti",
+}
+
+var engineIDFormats = map[byte]string{
+	1: "ipv4",
+	2: "ipv6",
+	3: "mac",
+	4: "text",
+	5: "octets",
+}
+
+var errUnexpectedResponse = errors.New("unexpected SNMP response")
+
+// buildGetRequest returns a v1/v2c GetRequest for the system group OIDs.
+func buildGetRequest(version int64, community string, requestID int32) []byte {
+	var bindings [][]byte
+	for _, oid := range []string{oidSysDescr, oidSysObjectID, oidSysUpTime} {
+		bindings = append(bindings, berEncode(tagSequence, berOID(oid), berEncode(tagNull)))
+	}
+	pdu := berEncode(tagGetRequest,
+		berInteger(int64(requestID)),
+		berInteger(0),
+		berInteger(0),
+		berEncode(tagSequence, bindings...),
+	)
+	return berEncode(tagSequence, berInteger(version), berOctetString(community), pdu)
+}
+
+// buildDiscoveryRequest returns an unauthenticated SNMPv3 GetRequest with an empty engine ID, to which agents reply
+// with a Report carrying their authoritative engine ID, boots and time (RFC 3414 section 4).
+func buildDiscoveryRequest(messageID int32) []byte {
+	globalData := berEncode(tagSequence,
+		berInteger(int64(messageID)),
+		berInteger(65507),
+		berOctetString("\x04"), // reportable, noAuthNoPriv
+		berInteger(3),          // USM
+	)
+	securityParameters := berEncode(tagSequence,
+		berOctetString(""),
+		berInteger(0),
+		berInteger(0),
+		berOctetString(""),
+		berOctetString(""),
+		berOctetString(""),
+	)
+	scopedPDU := berEncode(tagSequence,
+		berOctetString(""),
+		berOctetString(""),
+		berEncode(tagGetRequest, berInteger(int64(messageID)), berInteger(0), berInteger(0), berEncode(tagSequence)),
+	)
+	return berEncode(tagSequence, berInteger(versionV3), globalData, berOctetString(string(securityParameters)), scopedPDU)
+}
+
+// communityRequestID returns the request ID of a v1/v2c message.
+func communityRequestID(data []byte) (int64, error) {
+	message, _, err := berDecode(data)
+	if err != nil {
+		return 0, err
+	}
+	fields, err := message.expect(tagSequence, 3)
+	if err != nil {
+		return 0, err
+	}
+	pdu, err := fields[2].children()
+	if err != nil || len(pdu) < 1 {
+		return 0, errUnexpectedResponse
+	}
+	return pdu[0].integer()
+}
+
+// v3MessageID returns the msgID of a v3 message.
+func v3MessageID(data []byte) (int64, error) {
+	message, _, err := berDecode(data)
+	if err != nil {
+		return 0, err
+	}
+	fields, err := message.expect(tagSequence, 3)
+	if err != nil {
+		return 0, err
+	}
+	globalData, err := fields[1].expect(tagSequence, 1)
+	if err != nil {
+		return 0, err
+	}
+	return globalData[0].integer()
+}
+
+// parseGetResponse parses a v1/v2c GetResponse to a system group GetRequest.
+func parseGetResponse(data []byte) (*CommunityResponse, error) {
+	message, _, err := berDecode(data)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := message.expect(tagSequence, 3)
+	if err != nil {
+		return nil, err
+	}
+	version, err := fields[0].integer()
+	if err != nil {
+		return nil, err
+	}
+	pdu, err := fields[2].expect(tagGetResponse, 4)
+	if err != nil {
+		return nil, err
+	}
+	response := &CommunityResponse{Version: versionName(version), Community: string(fields[1].content)}
+	if response.ErrorStatus, err = pdu[1].integer(); err != nil {
+		return nil, err
+	}
+	bindings, err := pdu[3].expect(tagSequence, 0)
+	if err != nil {
+		return nil, err
+	}
+	system := new(SystemInfo)
+	found := false
+	for _, binding := range bindings {
+		pair, err := binding.expect(tagSequence, 2)
+		if err != nil {
+			return nil, err
+		}
+		oid, err := pair[0].oid()
+		if err != nil {
+			return nil, err
+		}
+		value := pair[1]
+		switch {
+		case oid == oidSysDescr && value.tag == tagOctetString:
+			system.SysDescr = string(value.content)
+		case oid == oidSysObjectID && value.tag == tagOID:
+			system.SysObjectID, err = value.oid()
+		case oid == oidSysUpTime && value.tag == tagTimeTicks:
+			var ticks int64
+			ticks, err = value.integer()
+			system.SysUpTime = uint32(ticks)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+	}
+	if found {
+		response.System = system
+	}
+	return response, nil
+}
+
+// parseDiscoveryReport parses the USM security parameters of a v3 discovery response.
+func parseDiscoveryReport(data []byte) (*EngineInfo, error) {
+	message, _, err := berDecode(data)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := message.expect(tagSequence, 3)
+	if err != nil {
+		return nil, err
+	}
+	if version, err := fields[0].integer(); err != nil || version != versionV3 {
+		return nil, errUnexpectedResponse
+	}
+	if fields[2].tag != tagOctetString {
+		return nil, errUnexpectedResponse
+	}
+	usm, _, err := berDecode(fields[2].content)
+	if err != nil {
+		return nil, err
+	}
+	params, err := usm.expect(tagSequence, 3)
+	if err != nil {
+		return nil, err
+	}
+	engine := parseEngineID(params[0].content)
+	if engine.EngineBoots, err = params[1].integer(); err != nil {
+		return nil, err
+	}
+	if engine.EngineTime, err = params[2].integer(); err != nil {
+		return nil, err
+	}
+	return engine, nil
+}
+
+// parseEngineID decodes an snmpEngineID as described in RFC 3411 section 5.
+func parseEngineID(id []byte) *EngineInfo {
+	engine := &EngineInfo{EngineID: hex.EncodeToString(id)}
+	if len(id) < 5 || id[0]&0x80 == 0 {
+		// pre-RFC 3411 format: 12 octets, enterprise number followed by enterprise-specific data
+		if len(id) >= 4 {
+			engine.Enterprise = binary.BigEndian.Uint32(id[:4])
+			engine.Vendor = enterpriseVendors[engine.Enterprise]
+		}
+		return engine
+	}
+	engine.Enterprise = binary.BigEndian.Uint32(id[:4]) &^ 0x80000000
+	engine.Vendor = enterpriseVendors[engine.Enterprise]
+	format, rest := id[4], id[5:]
+	if format >= 128 {
+		engine.Format = "enterprise"
+		return engine
+	}
+	engine.Format = engineIDFormats[format]
+	switch format {
+	case 1, 2:
+		if len(rest) == 4 || len(rest) == 16 {
+			engine.Data = net.IP(rest).String()
+		}
+	case 3:
+		if len(rest) == 6 {
+			engine.Data = fmt.Sprintf("%02x:%02x:%02x:%02x:%02x:%02x", rest[0], rest[1], rest[2], rest[3], rest[4], rest[5])
+		}
+	case 4:
+		engine.Data = string(rest)
+	}
+	return engine
+}
+
+func versionName(version int64) string {
+	switch version {
+	case versionV1:
+		return "v1"
+	case versionV2c:
+		return "v2c"
+	case versionV3:
+		return "v3"
+	}
+	return fmt.Sprintf("unknown (%d)", version)
+}
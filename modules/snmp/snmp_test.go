@@ -0,0 +1,107 @@
+package snmp
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestBEROID(t *testing.T) {
+	for _, oid := range []string{oidSysDescr, "1.3.6.1.4.1.8072.3.2.10", "2.999.1"} {
+		value, rest, err := berDecode(berOID(oid))
+		if err != nil || len(rest) != 0 {
+			t.Fatalf("%s: %v", oid, err)
+		}
+		got, err := value.oid()
+		if err != nil || got != oid {
+			t.Errorf("got %s (%v), want %s", got, err, oid)
+		}
+	}
+}
+
+func TestBERInteger(t *testing.T) {
+	for _, n := range []int64{0, 127, 128, 255, 256, -1, -129, 1 << 31, -(1 << 31)} {
+		value, _, err := berDecode(berInteger(n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := value.integer()
+		if err != nil || got != n {
+			t.Errorf("got %d (%v), want %d", got, err, n)
+		}
+	}
+}
+
+func TestParseGetResponse(t *testing.T) {
+	response := berEncode(tagSequence,
+		berInteger(versionV2c),
+		berOctetString("public"),
+		berEncode(tagGetResponse,
+			berInteger(1234),
+			berInteger(0),
+			berInteger(0),
+			berEncode(tagSequence,
+				berEncode(tagSequence, berOID(oidSysDescr), berOctetString("Linux router 5.10")),
+				berEncode(tagSequence, berOID(oidSysObjectID), berOID("1.3.6.1.4.1.8072.3.2.10")),
+				berEncode(tagSequence, berOID(oidSysUpTime), berEncode(tagTimeTicks, []byte{0x00, 0xbc, 0x61, 0x4e})),
+			),
+		),
+	)
+	id, err := communityRequestID(response)
+	if err != nil || id != 1234 {
+		t.Errorf("request ID: got %d (%v)", id, err)
+	}
+	parsed, err := parseGetResponse(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Version != "v2c" || parsed.Community != "public" || parsed.System == nil {
+		t.Fatalf("unexpected response %+v", parsed)
+	}
+	want := SystemInfo{SysDescr: "Linux router 5.10", SysObjectID: "1.3.6.1.4.1.8072.3.2.10", SysUpTime: 12345678}
+	if *parsed.System != want {
+		t.Errorf("got %+v, want %+v", *parsed.System, want)
+	}
+}
+
+func TestParseDiscoveryReport(t *testing.T) {
+	engineID, _ := hex.DecodeString("80001f8803525400123456")
+	usm := berEncode(tagSequence,
+		berOctetString(string(engineID)),
+		berInteger(7),
+		berInteger(86400),
+		berOctetString(""),
+		berOctetString(""),
+		berOctetString(""),
+	)
+	report := berEncode(tagSequence,
+		berInteger(versionV3),
+		berEncode(tagSequence, berInteger(42), berInteger(65507), berOctetString("\x00"), berInteger(3)),
+		berOctetString(string(usm)),
+		berEncode(tagSequence, berOctetString(""), berOctetString(""), berEncode(tagReport, berInteger(42), berInteger(0), berInteger(0), berEncode(tagSequence))),
+	)
+	id, err := v3MessageID(report)
+	if err != nil || id != 42 {
+		t.Errorf("message ID: got %d (%v)", id, err)
+	}
+	engine, err := parseDiscoveryReport(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := EngineInfo{
+		EngineID:    "80001f8803525400123456",
+		EngineBoots: 7,
+		EngineTime:  86400,
+		Enterprise:  8072,
+		Vendor:      "Net-SNMP",
+		Format:      "mac",
+		Data:        "52:54:00:12:34:56",
+	}
+	if *engine != want {
+		t.Errorf("got %+v, want %+v", *engine, want)
+	}
+
+	// the discovery request itself must be a well-formed v3 message
+	if id, err = v3MessageID(buildDiscoveryRequest(99)); err != nil || id != 99 {
+		t.Errorf("discovery request: got %d (%v)", id, err)
+	}
+}
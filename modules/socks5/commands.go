@@ -0,0 +1,183 @@
+package socks5
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+// SOCKS5 commands (RFC 1928 Section 4)
+const (
+	cmdConnect      byte = 0x01
+	cmdUDPAssociate byte = 0x03
+)
+
+// Address types (RFC 1928 Section 5)
+const (
+	atypIPv4   byte = 0x01
+	atypDomain byte = 0x03
+	atypIPv6   byte = 0x04
+)
+
+// Values of CommandResult.Egress
+const (
+	EgressTarget      = "target"
+	EgressUnspecified = "unspecified"
+	EgressOther       = "other"
+)
+
+// authMethods are the methods offered one by one with --enumerate-methods, as assigned by IANA.
+var authMethods = map[byte]string{
+	0x00: "no authentication required",
+	0x01: "GSSAPI",
+	0x02: "username/password",
+	0x03: "challenge-handshake authentication",
+	0x05: "challenge-response authentication",
+	0x06: "secure sockets layer",
+	0x07: "NDS authentication",
+	0x08: "multi-authentication framework",
+	0x09: "JSON parameter block",
+}
+
+// AuthMethod is the response to a method selection message offering a single method.
+type AuthMethod struct {
+	Method   byte   `json:"method"`
+	Name     string `json:"name"`
+	Accepted bool   `json:"accepted"`
+	Error    string `json:"error,omitempty"`
+}
+
+// CommandResult is the reply to a CONNECT or UDP ASSOCIATE request.
+type CommandResult struct {
+	Reply        byte   `json:"reply"`
+	ReplyName    string `json:"reply_name"`
+	BoundAddress string `json:"bound_address,omitempty"`
+	BoundPort    uint16 `json:"bound_port"`
+
+	// Egress compares the bound address to the address of the proxy: "target" if the proxy used the scanned address,
+	// "unspecified" for 0.0.0.0 or ::, and "other" if it used a different address.
+	Egress string `json:"egress,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// encodeAddress encodes host:port as ATYP, DST.ADDR and DST.PORT of a request.
+func encodeAddress(hostPort string) ([]byte, error) {
+	host, portString, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %s: %w", portString, err)
+	}
+	var ret []byte
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) == 0 || len(host) > 255 {
+			return nil, fmt.Errorf("invalid host %q", host)
+		}
+		ret = append([]byte{atypDomain, byte(len(host))}, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		ret = append([]byte{atypIPv4}, ip4...)
+	} else {
+		ret = append([]byte{atypIPv6}, ip...)
+	}
+	return binary.BigEndian.AppendUint16(ret, uint16(port)), nil
+}
+
+// readReply reads a variable-length reply to a request and returns its raw bytes.
+func (conn *Connection) readReply() ([]byte, error) {
+	resp := make([]byte, 5)
+	if _, err := io.ReadFull(conn.conn, resp); err != nil {
+		return nil, err
+	}
+	var addressLength int
+	switch resp[3] {
+	case atypIPv4:
+		addressLength = net.IPv4len
+	case atypIPv6:
+		addressLength = net.IPv6len
+	case atypDomain:
+		addressLength = 1 + int(resp[4])
+	default:
+		return resp, fmt.Errorf("unknown address type 0x%02x", resp[3])
+	}
+	rest := make([]byte, addressLength+2-1)
+	if _, err := io.ReadFull(conn.conn, rest); err != nil {
+		return resp, err
+	}
+	return append(resp, rest...), nil
+}
+
+// parseReply parses a complete reply read with readReply.
+func parseReply(resp []byte) (*CommandResult, error) {
+	if len(resp) < 6 {
+		return nil, errors.New("response too short")
+	}
+	ret := &CommandResult{Reply: resp[1], ReplyName: getReplyDescription(resp[1])}
+	address := resp[4 : len(resp)-2]
+	switch resp[3] {
+	case atypIPv4, atypIPv6:
+		ret.BoundAddress = net.IP(address).String()
+	case atypDomain:
+		ret.BoundAddress = string(address[1:])
+	}
+	ret.BoundPort = binary.BigEndian.Uint16(resp[len(resp)-2:])
+	return ret, nil
+}
+
+// egress classifies the bound address of a successful reply relative to the address of the proxy.
+func (conn *Connection) egress(result *CommandResult) string {
+	bound := net.ParseIP(result.BoundAddress)
+	if bound == nil {
+		return EgressOther
+	}
+	if bound.IsUnspecified() {
+		return EgressUnspecified
+	}
+	if host, _, err := net.SplitHostPort(conn.conn.RemoteAddr().String()); err == nil && bound.Equal(net.ParseIP(host)) {
+		return EgressTarget
+	}
+	return EgressOther
+}
+
+// request sends the command cmd for the destination hostPort and parses the reply.
+func (conn *Connection) request(cmd byte, hostPort string) (*CommandResult, []byte, error) {
+	address, err := encodeAddress(hostPort)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := conn.sendCommand(append([]byte{0x05, cmd, 0x00}, address...)); err != nil {
+		return nil, nil, fmt.Errorf("error sending request: %w", err)
+	}
+	resp, err := conn.readReply()
+	if err != nil {
+		return nil, resp, fmt.Errorf("error reading reply: %w", err)
+	}
+	result, err := parseReply(resp)
+	if err != nil {
+		return nil, resp, err
+	}
+	if result.Reply == 0x00 {
+		result.Egress = conn.egress(result)
+	}
+	return result, resp, nil
+}
+
+// selectMethod offers only method and returns whether the server accepted it.
+func (conn *Connection) selectMethod(method byte) (bool, error) {
+	if err := conn.sendCommand([]byte{0x05, 0x01, method}); err != nil {
+		return false, fmt.Errorf("error sending version identifier/method selection: %w", err)
+	}
+	resp := make([]byte, 2)
+	if _, err := io.ReadFull(conn.conn, resp); err != nil {
+		return false, fmt.Errorf("error reading method selection response: %w", err)
+	}
+	if resp[0] != 0x05 {
+		return false, fmt.Errorf("unexpected version 0x%02x", resp[0])
+	}
+	return resp[1] == method, nil
+}
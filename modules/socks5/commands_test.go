@@ -0,0 +1,113 @@
+package socks5
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/zmap/zgrab2"
+)
+
+// fakeProxy serves a single SOCKS5 connection that accepts no authentication and username/password, and replies to
+// CONNECT from 10.0.0.5:4000 and to UDP ASSOCIATE with a relay on 0.0.0.0:5000.
+func fakeProxy(server net.Conn) {
+	defer server.Close()
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(server, header); err != nil {
+		return
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(server, methods); err != nil {
+		return
+	}
+	selected := byte(0xFF)
+	for _, method := range methods {
+		if method == 0x00 || method == 0x02 {
+			selected = method
+		}
+	}
+	server.Write([]byte{0x05, selected})
+	if selected != 0x00 {
+		return
+	}
+	request := make([]byte, 10)
+	if _, err := io.ReadFull(server, request); err != nil {
+		return
+	}
+	switch request[1] {
+	case cmdConnect:
+		server.Write([]byte{0x05, 0x00, 0x00, atypIPv4, 10, 0, 0, 5, 0x0F, 0xA0})
+	case cmdUDPAssociate:
+		server.Write([]byte{0x05, 0x00, 0x00, atypIPv4, 0, 0, 0, 0, 0x13, 0x88})
+	default:
+		server.Write([]byte{0x05, 0x07, 0x00, atypIPv4, 0, 0, 0, 0, 0, 0})
+	}
+}
+
+func TestEncodeAddress(t *testing.T) {
+	for hostPort, expected := range map[string][]byte{
+		"192.0.2.1:80":    {atypIPv4, 192, 0, 2, 1, 0, 80},
+		"example.com:443": append(append([]byte{atypDomain, 11}, "example.com"...), 0x01, 0xBB),
+		"[::1]:53":        append(append([]byte{atypIPv6}, net.IPv6loopback...), 0, 53),
+	} {
+		encoded, err := encodeAddress(hostPort)
+		if err != nil || !bytes.Equal(encoded, expected) {
+			t.Errorf("%s: %x, %v", hostPort, encoded, err)
+		}
+	}
+	for _, invalid := range []string{"192.0.2.1", "192.0.2.1:65536", ":80"} {
+		if _, err := encodeAddress(invalid); err == nil {
+			t.Errorf("%q encoded", invalid)
+		}
+	}
+}
+
+func TestParseReply(t *testing.T) {
+	reply, err := parseReply(append(append([]byte{0x05, 0x00, 0x00, atypDomain, 9}, "proxy.lan"...), 0x04, 0x38))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.BoundAddress != "proxy.lan" || reply.BoundPort != 1080 || reply.ReplyName != "succeeded" {
+		t.Errorf("reply = %+v", reply)
+	}
+}
+
+func TestScan(t *testing.T) {
+	scanner := &Scanner{config: &Flags{EnumerateMethods: true, UDPAssociate: true, ConnectDestination: "192.0.2.1:80"}}
+	dialGroup := &zgrab2.DialerGroup{TransportAgnosticDialer: func(ctx context.Context, target *zgrab2.ScanTarget) (net.Conn, error) {
+		client, server := net.Pipe()
+		go fakeProxy(server)
+		return client, nil
+	}}
+	status, res, err := scanner.Scan(context.Background(), dialGroup, &zgrab2.ScanTarget{})
+	if err != nil || status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status = %s, err = %v", status, err)
+	}
+	results := res.(*ScanResults)
+
+	var accepted []byte
+	for _, method := range results.Methods {
+		if method.Error != "" {
+			t.Errorf("method %d: %s", method.Method, method.Error)
+		}
+		if method.Accepted {
+			accepted = append(accepted, method.Method)
+		}
+	}
+	if len(results.Methods) != len(authMethods) || !bytes.Equal(accepted, []byte{0x00, 0x02}) {
+		t.Errorf("methods = %+v", results.Methods)
+	}
+	expectedConnect := CommandResult{ReplyName: "succeeded", BoundAddress: "10.0.0.5", BoundPort: 4000, Egress: EgressOther}
+	if results.Connect == nil || *results.Connect != expectedConnect {
+		t.Errorf("connect = %+v", results.Connect)
+	}
+	if results.ConnectionResponseExplanation["Bound Port"] != "4000" {
+		t.Errorf("explanation = %v", results.ConnectionResponseExplanation)
+	}
+	expectedUDP := CommandResult{ReplyName: "succeeded", BoundAddress: "0.0.0.0", BoundPort: 5000, Egress: EgressUnspecified}
+	if results.UDPAssociate == nil || *results.UDPAssociate != expectedUDP {
+		t.Errorf("udp associate = %+v", results.UDPAssociate)
+	}
+}
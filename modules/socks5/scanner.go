@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"slices"
 	"strconv"
 
 	log "github.com/sirupsen/logrus"
@@ -20,11 +21,24 @@ type ScanResults struct {
 	MethodSelection               string            `json:"method_selection,omitempty"`
 	ConnectionResponse            string            `json:"connection_response,omitempty"`
 	ConnectionResponseExplanation map[string]string `json:"connection_response_explanation,omitempty"`
+
+	// Methods is the response to each authentication method offered on its own, with --enumerate-methods.
+	Methods []AuthMethod `json:"methods,omitempty"`
+
+	// Connect is the parsed reply to the CONNECT request.
+	Connect *CommandResult `json:"connect,omitempty"`
+
+	// UDPAssociate is the reply to a UDP ASSOCIATE request, with --udp-associate. The bound address and port are
+	// those of the UDP relay.
+	UDPAssociate *CommandResult `json:"udp_associate,omitempty"`
 }
 
 // Flags are the SOCKS5-specific command-line flags.
 type Flags struct {
 	zgrab2.BaseFlags
+	EnumerateMethods   bool   `long:"enumerate-methods" description:"Offer each authentication method on its own connection and record which ones the server accepts"`
+	ConnectDestination string `long:"connect-destination" default:"166.111.4.100:80" description:"Destination (host:port) of the CONNECT request sent when no authentication is required"`
+	UDPAssociate       bool   `long:"udp-associate" description:"If no authentication is required, send a UDP ASSOCIATE request on a separate connection"`
 }
 
 // Module implements the zgrab2.Module interface.
@@ -72,6 +86,9 @@ func (m *Module) Description() string {
 
 // Validate flags
 func (f *Flags) Validate(_ []string) (err error) {
+	if _, err := encodeAddress(f.ConnectDestination); err != nil {
+		return fmt.Errorf("invalid --connect-destination: %w", err)
+	}
 	return
 }
 
@@ -138,17 +155,18 @@ func (conn *Connection) sendCommand(cmd []byte) error {
 
 // explainResponse converts the raw response into a human-readable explanation.
 func explainResponse(resp []byte) map[string]string {
-	if len(resp) < 10 {
-		return map[string]string{"error": "response too short"}
+	reply, err := parseReply(resp)
+	if err != nil {
+		return map[string]string{"error": err.Error()}
 	}
 
 	return map[string]string{
 		"Version":       fmt.Sprintf("0x%02x (SOCKS Version 5)", resp[0]),
-		"Reply":         fmt.Sprintf("0x%02x (%s)", resp[1], getReplyDescription(resp[1])),
+		"Reply":         fmt.Sprintf("0x%02x (%s)", resp[1], reply.ReplyName),
 		"Reserved":      fmt.Sprintf("0x%02x", resp[2]),
 		"Address Type":  fmt.Sprintf("0x%02x (%s)", resp[3], getAddressTypeDescription(resp[3])),
-		"Bound Address": fmt.Sprintf("%d.%d.%d.%d", resp[4], resp[5], resp[6], resp[7]),
-		"Bound Port":    strconv.Itoa(int(resp[8])<<8 | int(resp[9])),
+		"Bound Address": reply.BoundAddress,
+		"Bound Port":    strconv.Itoa(int(reply.BoundPort)),
 	}
 }
 
@@ -216,20 +234,14 @@ func (conn *Connection) PerformHandshake() (bool, error) {
 
 // PerformConnectionRequest sends a connection request to the SOCKS5 server.
 func (conn *Connection) PerformConnectionRequest() error {
-	// Send a connection request
-	req := []byte{0x05, 0x01, 0x00, 0x01, 0xA6, 0x6F, 0x04, 0x64, 0x00, 0x50} // VER = 0x05, CMD = CONNECT, RSV = 0x00, ATYP = IPv4, DST.ADDR = 166.111.4.100, DST.PORT = 80
-	err := conn.sendCommand(req)
+	// Send a connection request to --connect-destination and read the response
+	reply, resp, err := conn.request(cmdConnect, conn.config.ConnectDestination)
 	if err != nil {
-		return fmt.Errorf("error sending connection request: %w", err)
-	}
-
-	// Read connection response
-	resp, err := conn.readResponse(10)
-	if err != nil {
-		return fmt.Errorf("error reading connection response: %w", err)
+		return err
 	}
 	conn.results.ConnectionResponse = hex.EncodeToString(resp)
 	conn.results.ConnectionResponseExplanation = explainResponse(resp)
+	conn.results.Connect = reply
 
 	if resp[1] > 0x80 {
 		return fmt.Errorf("connection request failed with response: %x", resp)
@@ -238,16 +250,69 @@ func (conn *Connection) PerformConnectionRequest() error {
 	return nil
 }
 
+// enumerateMethods offers each authentication method on a new connection and records whether it was accepted.
+func (scanner *Scanner) enumerateMethods(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) []AuthMethod {
+	methods := make([]byte, 0, len(authMethods))
+	for method := range authMethods {
+		methods = append(methods, method)
+	}
+	slices.Sort(methods)
+
+	ret := make([]AuthMethod, 0, len(methods))
+	for _, method := range methods {
+		result := AuthMethod{Method: method, Name: authMethods[method]}
+		conn, err := dialGroup.Dial(ctx, target)
+		if err != nil {
+			result.Error = err.Error()
+			ret = append(ret, result)
+			continue
+		}
+		socks5Conn := Connection{conn: conn, config: scanner.config}
+		if result.Accepted, err = socks5Conn.selectMethod(method); err != nil {
+			result.Error = err.Error()
+		}
+		zgrab2.CloseConnAndHandleError(conn)
+		ret = append(ret, result)
+	}
+	return ret
+}
+
+// udpAssociate requests a UDP relay on a new connection, without authentication.
+func (scanner *Scanner) udpAssociate(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) *CommandResult {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return &CommandResult{Error: err.Error()}
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+	socks5Conn := Connection{conn: conn, config: scanner.config}
+	if accepted, err := socks5Conn.selectMethod(0x00); err != nil || !accepted {
+		if err == nil {
+			err = errors.New("no acceptable authentication methods")
+		}
+		return &CommandResult{Error: err.Error()}
+	}
+	// The client does not know the address it will send datagrams from, so it sends all zeros
+	result, _, err := socks5Conn.request(cmdUDPAssociate, "0.0.0.0:0")
+	if err != nil {
+		return &CommandResult{Error: err.Error()}
+	}
+	return result
+}
+
 // Scan performs the configured scan on the SOCKS5 server.
 func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
 	var have_auth bool
+	var methods []AuthMethod
+	if scanner.config.EnumerateMethods {
+		methods = scanner.enumerateMethods(ctx, dialGroup, target)
+	}
 	conn, err := dialGroup.Dial(ctx, target)
 	if err != nil {
 		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to %s: %w", target.String(), err)
 	}
 	defer zgrab2.CloseConnAndHandleError(conn)
 
-	results := ScanResults{}
+	results := ScanResults{Methods: methods}
 	socks5Conn := Connection{conn: conn, config: scanner.config, results: results}
 
 	have_auth, err = socks5Conn.PerformHandshake()
@@ -260,6 +325,9 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 	}
 
 	err = socks5Conn.PerformConnectionRequest()
+	if scanner.config.UDPAssociate {
+		socks5Conn.results.UDPAssociate = scanner.udpAssociate(ctx, dialGroup, target)
+	}
 	if err != nil {
 		return zgrab2.TryGetScanStatus(err), &socks5Conn.results, fmt.Errorf("error during connection request: %w", err)
 	}
@@ -0,0 +1,227 @@
+// Package ssdp contains the zgrab2 Module implementation for SSDP (UPnP discovery).
+//
+// The scan sends a unicast M-SEARCH and records the responses, one per advertised device or service. With
+// --fetch-description, it then requests the UPnP device description from the first LOCATION on the target itself;
+// locations on other hosts are not followed.
+package ssdp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+	"github.com/zmap/zgrab2/modules/httpapi"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	Responses []*Response `json:"responses,omitempty"`
+
+	// Device is the device description fetched from the LOCATION of the first response.
+	Device *Device `json:"device,omitempty"`
+
+	DescriptionResponse *httpapi.Response `json:"description_response,omitempty"`
+
+	// DescriptionError explains why the device description could not be fetched or parsed.
+	DescriptionError string `json:"description_error,omitempty"`
+
+	UDPProbe *zgrab2.UDPProbeResult `json:"udp_probe,omitempty"`
+}
+
+// Flags are the SSDP-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.UDPFlags
+
+	SearchTarget     string `long:"search-target" default:"ssdp:all" description:"Search target (ST) of the M-SEARCH"`
+	MaxResponses     int    `long:"max-responses" default:"16" description:"Maximum number of responses to collect"`
+	FetchDescription bool   `long:"fetch-description" description:"Fetch and parse the UPnP device description from the LOCATION of the first response"`
+	UserAgent        string `long:"user-agent" default:"Mozilla/5.0 zgrab/0.x" description:"User agent for the device description request"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the ssdp zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("ssdp", "Simple Service Discovery Protocol (SSDP)", module.Description(), 1900, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Send an SSDP M-SEARCH and record the responses, optionally fetching the UPnP device description"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return f.UDPFlags.Validate()
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "ssdp"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportUDP,
+		NeedSeparateL4Dialer:            true,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// readMore collects further responses to the M-SEARCH until the try timeout passes without one.
+func (scanner *Scanner) readMore(conn net.Conn, results *ScanResults) {
+	buf := make([]byte, 8192)
+	for len(results.Responses) < scanner.config.MaxResponses {
+		if err := conn.SetReadDeadline(time.Now().Add(scanner.config.TryTimeout)); err != nil {
+			return
+		}
+		n, err := conn.Read(buf)
+		if err != nil {
+			return
+		}
+		if response, err := parseResponse(buf[:n]); err == nil {
+			results.Responses = append(results.Responses, response)
+		}
+	}
+}
+
+// fetchDescription requests the device description at location, if it is on the target.
+func (scanner *Scanner) fetchDescription(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget, targetIP net.IP, location string, results *ScanResults) {
+	parsed, err := url.Parse(location)
+	if err != nil || parsed.Scheme != "http" {
+		results.DescriptionError = "unsupported location " + location
+		return
+	}
+	host := parsed.Hostname()
+	if ip := net.ParseIP(host); ip == nil || !ip.Equal(targetIP) {
+		results.DescriptionError = "location is not on the target: " + location
+		return
+	}
+	port := 80
+	if parsed.Port() != "" {
+		if port, err = strconv.Atoi(parsed.Port()); err != nil {
+			results.DescriptionError = "invalid location port: " + location
+			return
+		}
+	}
+	descriptionTarget := *target
+	descriptionTarget.IP = targetIP
+	descriptionTarget.Port = uint(port)
+	client := httpapi.NewClient(ctx, dialGroup, &descriptionTarget, &httpapi.Flags{UserAgent: scanner.config.UserAgent})
+	results.DescriptionResponse, err = client.Get(parsed.RequestURI())
+	if err != nil {
+		results.DescriptionError = err.Error()
+		return
+	}
+	if !results.DescriptionResponse.Success() {
+		results.DescriptionError = "description request failed: " + results.DescriptionResponse.Status
+		return
+	}
+	if results.Device, err = parseDescription(results.DescriptionResponse.Body); err != nil {
+		results.DescriptionError = "invalid description: " + err.Error()
+	}
+}
+
+// Scan performs the configured scan on the SSDP responder.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	address := net.JoinHostPort(target.Host(), strconv.Itoa(int(target.Port)))
+	conn, err := dialGroup.L4Dialer(target)(ctx, "udp", address)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	results := new(ScanResults)
+	probe := zgrab2.NewStaticUDPProbe("m-search", buildSearch(scanner.config.SearchTarget), isResponse)
+	results.UDPProbe, err = zgrab2.SendUDPProbe(ctx, conn, probe, target, &scanner.config.UDPFlags)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, err
+	}
+	response, err := parseResponse(results.UDPProbe.Response)
+	if err != nil {
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("invalid response from target %s: %w", target.String(), err)
+	}
+	results.Responses = append(results.Responses, response)
+	scanner.readMore(conn, results)
+
+	if scanner.config.FetchDescription {
+		var targetIP net.IP
+		if remote, ok := conn.RemoteAddr().(*net.UDPAddr); ok {
+			targetIP = remote.IP
+		}
+		for _, response := range results.Responses {
+			if response.Location != "" {
+				scanner.fetchDescription(ctx, dialGroup, target, targetIP, response.Location, results)
+				break
+			}
+		}
+		if results.DescriptionError != "" {
+			log.Debugf("could not fetch device description from target %s: %s", target.String(), results.DescriptionError)
+		}
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
@@ -0,0 +1,107 @@
+package ssdp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"net/textproto"
+	"strings"
+)
+
+var errInvalidResponse = errors.New("invalid SSDP response")
+
+// buildSearch returns a unicast M-SEARCH request for the search target.
+func buildSearch(searchTarget string) []byte {
+	return []byte("M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: 239.255.255.250:1900\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 1\r\n" +
+		"ST: " + searchTarget + "\r\n" +
+		"\r\n")
+}
+
+// Response is a response to the M-SEARCH.
+type Response struct {
+	StatusCode   string `json:"status_code"`
+	Server       string `json:"server,omitempty"`
+	Location     string `json:"location,omitempty"`
+	USN          string `json:"usn,omitempty"`
+	ST           string `json:"st,omitempty"`
+	CacheControl string `json:"cache_control,omitempty"`
+	BootID       string `json:"boot_id,omitempty"`
+}
+
+// isResponse reports whether data looks like an HTTP-over-UDP response.
+func isResponse(_, data []byte) bool {
+	return bytes.HasPrefix(data, []byte("HTTP/1."))
+}
+
+// parseResponse decodes the status line and headers of a response.
+func parseResponse(data []byte) (*Response, error) {
+	reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(data)))
+	statusLine, err := reader.ReadLine()
+	if err != nil {
+		return nil, errInvalidResponse
+	}
+	fields := strings.Fields(statusLine)
+	if len(fields) < 2 || !strings.HasPrefix(fields[0], "HTTP/1.") {
+		return nil, errInvalidResponse
+	}
+	header, err := reader.ReadMIMEHeader()
+	if err != nil && len(header) == 0 {
+		return nil, errInvalidResponse
+	}
+	return &Response{
+		StatusCode:   fields[1],
+		Server:       header.Get("Server"),
+		Location:     header.Get("Location"),
+		USN:          header.Get("Usn"),
+		ST:           header.Get("St"),
+		CacheControl: header.Get("Cache-Control"),
+		BootID:       header.Get("Bootid.upnp.org"),
+	}, nil
+}
+
+// Service is a service of a UPnP device.
+type Service struct {
+	ServiceType string `xml:"serviceType" json:"service_type,omitempty"`
+	ServiceID   string `xml:"serviceId" json:"service_id,omitempty"`
+	ControlURL  string `xml:"controlURL" json:"control_url,omitempty"`
+	SCPDURL     string `xml:"SCPDURL" json:"scpd_url,omitempty"`
+	EventSubURL string `xml:"eventSubURL" json:"event_sub_url,omitempty"`
+}
+
+// Device is a UPnP device description, including its embedded devices.
+type Device struct {
+	DeviceType       string    `xml:"deviceType" json:"device_type,omitempty"`
+	FriendlyName     string    `xml:"friendlyName" json:"friendly_name,omitempty"`
+	Manufacturer     string    `xml:"manufacturer" json:"manufacturer,omitempty"`
+	ManufacturerURL  string    `xml:"manufacturerURL" json:"manufacturer_url,omitempty"`
+	ModelDescription string    `xml:"modelDescription" json:"model_description,omitempty"`
+	ModelName        string    `xml:"modelName" json:"model_name,omitempty"`
+	ModelNumber      string    `xml:"modelNumber" json:"model_number,omitempty"`
+	ModelURL         string    `xml:"modelURL" json:"model_url,omitempty"`
+	SerialNumber     string    `xml:"serialNumber" json:"serial_number,omitempty"`
+	UDN              string    `xml:"UDN" json:"udn,omitempty"`
+	PresentationURL  string    `xml:"presentationURL" json:"presentation_url,omitempty"`
+	Services         []Service `xml:"serviceList>service" json:"services,omitempty"`
+	Devices          []Device  `xml:"deviceList>device" json:"devices,omitempty"`
+}
+
+// deviceDescription is the root element of a device description document.
+type deviceDescription struct {
+	Device Device `xml:"device"`
+}
+
+// parseDescription decodes a device description document.
+func parseDescription(body string) (*Device, error) {
+	var description deviceDescription
+	if err := xml.Unmarshal([]byte(body), &description); err != nil {
+		return nil, err
+	}
+	if description.Device.DeviceType == "" && description.Device.UDN == "" {
+		return nil, errors.New("no device in description")
+	}
+	return &description.Device, nil
+}
@@ -0,0 +1,52 @@
+package ssdp
+
+import "testing"
+
+func TestParseResponse(t *testing.T) {
+	data := []byte("HTTP/1.1 200 OK\r\n" +
+		"CACHE-CONTROL: max-age=1800\r\n" +
+		"EXT:\r\n" +
+		"LOCATION: http://192.0.2.1:49152/rootDesc.xml\r\n" +
+		"SERVER: Linux/3.14 UPnP/1.1 MiniUPnPd/2.1\r\n" +
+		"ST: upnp:rootdevice\r\n" +
+		"USN: uuid:12345678-1234-1234-1234-123456789abc::upnp:rootdevice\r\n" +
+		"\r\n")
+	if !isResponse(nil, data) || isResponse(nil, buildSearch("ssdp:all")) {
+		t.Fatal("response detection failed")
+	}
+	response, err := parseResponse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != "200" || response.Location != "http://192.0.2.1:49152/rootDesc.xml" ||
+		response.Server != "Linux/3.14 UPnP/1.1 MiniUPnPd/2.1" || response.ST != "upnp:rootdevice" || response.CacheControl != "max-age=1800" {
+		t.Errorf("unexpected response %+v", response)
+	}
+}
+
+func TestParseDescription(t *testing.T) {
+	body := `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+<specVersion><major>1</major><minor>0</minor></specVersion>
+<device>
+<deviceType>urn:schemas-upnp-org:device:InternetGatewayDevice:1</deviceType>
+<friendlyName>Example Router</friendlyName>
+<manufacturer>Example</manufacturer>
+<modelName>R1000</modelName>
+<UDN>uuid:12345678-1234-1234-1234-123456789abc</UDN>
+<serviceList><service><serviceType>urn:schemas-upnp-org:service:Layer3Forwarding:1</serviceType><controlURL>/ctl/L3F</controlURL></service></serviceList>
+<deviceList><device><deviceType>urn:schemas-upnp-org:device:WANDevice:1</deviceType></device></deviceList>
+</device>
+</root>`
+	device, err := parseDescription(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device.FriendlyName != "Example Router" || device.ModelName != "R1000" || len(device.Services) != 1 ||
+		device.Services[0].ControlURL != "/ctl/L3F" || len(device.Devices) != 1 {
+		t.Errorf("unexpected device %+v", device)
+	}
+	if _, err = parseDescription("<html></html>"); err == nil {
+		t.Error("expected error for a document without a device")
+	}
+}
@@ -0,0 +1,248 @@
+// Package stun contains the zgrab2 Module implementation for STUN and TURN servers.
+//
+// The scan sends a STUN Binding request and records the mapped (reflexive) address, the SOFTWARE attribute and, for
+// RFC 5780 servers, the other address. It then sends an unauthenticated TURN Allocate request: a TURN server answers
+// with an error, usually 401 Unauthorized with a realm, which identifies it as a relay even when credentials are
+// unknown.
+package stun
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// TURNResult describes the response to the Allocate request.
+type TURNResult struct {
+	// Supported is true if the server answered the Allocate request.
+	Supported bool `json:"supported"`
+
+	// Allocated is true if the server granted an allocation without credentials.
+	Allocated bool `json:"allocated,omitempty"`
+
+	Error    *ErrorCode `json:"error,omitempty"`
+	Realm    string     `json:"realm,omitempty"`
+	Software string     `json:"software,omitempty"`
+}
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	Transport string `json:"transport"`
+
+	// MappedAddress is the client's address as seen by the server.
+	MappedAddress string `json:"mapped_address,omitempty"`
+
+	Software       string `json:"software,omitempty"`
+	ResponseOrigin string `json:"response_origin,omitempty"`
+	OtherAddress   string `json:"other_address,omitempty"`
+
+	// BindingError is the error the server answered the Binding request with.
+	BindingError *ErrorCode `json:"binding_error,omitempty"`
+
+	TURN *TURNResult `json:"turn,omitempty"`
+
+	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
+
+	Probes []*zgrab2.UDPProbeResult `json:"probes,omitempty"`
+}
+
+// Flags are the STUN-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.UDPFlags
+	zgrab2.TLSFlags
+
+	Transport string `long:"transport" default:"udp" description:"Transport to send the requests over (udp, tcp or tls)"`
+	NoTURN    bool   `long:"no-turn" description:"Do not send a TURN Allocate request"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the stun zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("stun", "Session Traversal Utilities for NAT (STUN/TURN)", module.Description(), 3478, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Send STUN Binding and TURN Allocate requests and record the mapped address and server software"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	switch f.Transport {
+	case "udp":
+		return f.UDPFlags.Validate()
+	case "tcp", "tls":
+		return nil
+	default:
+		return fmt.Errorf("unknown transport %q", f.Transport)
+	}
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "stun"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	switch f.Transport {
+	case "udp":
+		scanner.dialerGroupConfig.TransportAgnosticDialerProtocol = zgrab2.TransportUDP
+	case "tls":
+		scanner.dialerGroupConfig.TLSEnabled = true
+		scanner.dialerGroupConfig.TLSFlags = &f.TLSFlags
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// exchange sends a request and returns the response with the same transaction ID.
+func (scanner *Scanner) exchange(ctx context.Context, conn net.Conn, target *zgrab2.ScanTarget, name string, request []byte, results *ScanResults) (*message, error) {
+	transactionID := request[8:20]
+	var response []byte
+	if scanner.config.Transport == "udp" {
+		probe := zgrab2.NewStaticUDPProbe(name, request, matches(transactionID))
+		probeResult, err := zgrab2.SendUDPProbe(ctx, conn, probe, target, &scanner.config.UDPFlags)
+		results.Probes = append(results.Probes, probeResult)
+		if err != nil {
+			return nil, err
+		}
+		response = probeResult.Response
+	} else {
+		if _, err := conn.Write(request); err != nil {
+			return nil, err
+		}
+		var err error
+		if response, err = readMessage(conn); err != nil {
+			return nil, err
+		}
+	}
+	msg, err := parseMessage(response)
+	if err != nil || string(msg.transactionID) != string(transactionID) {
+		return nil, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, fmt.Errorf("invalid response to %s request", name))
+	}
+	return msg, nil
+}
+
+// Scan performs the configured scan on the STUN server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	results := &ScanResults{Transport: scanner.config.Transport}
+	if tlsConn, ok := conn.(*zgrab2.TLSConnection); ok {
+		results.TLSLog = tlsConn.GetLog()
+	}
+	binding, err := scanner.exchange(ctx, conn, target, "binding", buildRequest(typeBindingRequest, newTransactionID()), results)
+	if err != nil {
+		var partial any
+		if len(results.Probes) > 0 || results.TLSLog != nil {
+			partial = results
+		}
+		return zgrab2.TryGetScanStatus(err), partial, fmt.Errorf("binding request to target %s failed: %w", target.String(), err)
+	}
+	switch binding.messageType {
+	case typeBindingSuccess:
+		results.MappedAddress = binding.address(attrXORMappedAddress, true)
+		if results.MappedAddress == "" {
+			results.MappedAddress = binding.address(attrMappedAddress, false)
+		}
+		results.ResponseOrigin = binding.address(attrResponseOrigin, false)
+		results.OtherAddress = binding.address(attrOtherAddress, false)
+	case typeBindingError:
+		results.BindingError = binding.errorCode()
+	default:
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("unexpected response type %#04x to binding request", binding.messageType)
+	}
+	results.Software = binding.text(attrSoftware)
+	if scanner.config.NoTURN {
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+
+	allocate, err := scanner.exchange(ctx, conn, target, "allocate", buildAllocateRequest(newTransactionID()), results)
+	results.TURN = new(TURNResult)
+	if err != nil {
+		// plain STUN servers ignore or drop requests they don't implement
+		log.Debugf("allocate request to target %s failed: %v", target.String(), err)
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+	switch allocate.messageType {
+	case typeAllocateSuccess:
+		results.TURN.Supported = true
+		results.TURN.Allocated = true
+	case typeAllocateError:
+		results.TURN.Error = allocate.errorCode()
+		// 400 Bad Request and 420 Unknown Attribute come from STUN servers that parsed but don't implement TURN
+		results.TURN.Supported = results.TURN.Error != nil && results.TURN.Error.Code != 400 && results.TURN.Error.Code != 420
+	}
+	results.TURN.Realm = allocate.text(attrRealm)
+	results.TURN.Software = allocate.text(attrSoftware)
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
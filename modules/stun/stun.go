@@ -0,0 +1,203 @@
+package stun
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+)
+
+// magicCookie identifies RFC 5389 messages.
+const magicCookie = 0x2112A442
+
+// headerLength is the length of the STUN message header.
+const headerLength = 20
+
+// maxMessageLength bounds the length of a message read from a stream.
+const maxMessageLength = 64 * 1024
+
+// Message types
+const (
+	typeBindingRequest  = 0x0001
+	typeBindingSuccess  = 0x0101
+	typeBindingError    = 0x0111
+	typeAllocateRequest = 0x0003
+	typeAllocateSuccess = 0x0103
+	typeAllocateError   = 0x0113
+)
+
+// Attribute types
+const (
+	attrMappedAddress      = 0x0001
+	attrErrorCode          = 0x0009
+	attrRealm              = 0x0014
+	attrNonce              = 0x0015
+	attrRequestedTransport = 0x0019
+	attrXORMappedAddress   = 0x0020
+	attrSoftware           = 0x8022
+	attrResponseOrigin     = 0x802B
+	attrOtherAddress       = 0x802C
+)
+
+var errInvalidMessage = errors.New("invalid STUN message")
+
+// message is a decoded STUN message.
+type message struct {
+	messageType   uint16
+	transactionID []byte
+	attributes    []attribute
+}
+
+type attribute struct {
+	attrType uint16
+	value    []byte
+}
+
+// newTransactionID returns a random 96-bit transaction ID.
+func newTransactionID() []byte {
+	id := make([]byte, 12)
+	_, _ = rand.Read(id)
+	return id
+}
+
+// buildRequest returns a request of the given type with the given attributes.
+func buildRequest(messageType uint16, transactionID []byte, attributes ...attribute) []byte {
+	var body []byte
+	for _, attr := range attributes {
+		body = binary.BigEndian.AppendUint16(body, attr.attrType)
+		body = binary.BigEndian.AppendUint16(body, uint16(len(attr.value)))
+		body = append(body, attr.value...)
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+	}
+	packet := binary.BigEndian.AppendUint16(nil, messageType)
+	packet = binary.BigEndian.AppendUint16(packet, uint16(len(body)))
+	packet = binary.BigEndian.AppendUint32(packet, magicCookie)
+	packet = append(packet, transactionID...)
+	return append(packet, body...)
+}
+
+// buildAllocateRequest returns an unauthenticated TURN Allocate request for a UDP relay.
+func buildAllocateRequest(transactionID []byte) []byte {
+	return buildRequest(typeAllocateRequest, transactionID, attribute{attrRequestedTransport, []byte{17, 0, 0, 0}})
+}
+
+// parseMessage decodes a STUN message.
+func parseMessage(data []byte) (*message, error) {
+	if len(data) < headerLength || data[0]&0xC0 != 0 || binary.BigEndian.Uint32(data[4:8]) != magicCookie {
+		return nil, errInvalidMessage
+	}
+	length := int(binary.BigEndian.Uint16(data[2:4]))
+	if len(data) < headerLength+length {
+		return nil, errInvalidMessage
+	}
+	msg := &message{
+		messageType:   binary.BigEndian.Uint16(data[0:2]),
+		transactionID: data[8:20],
+	}
+	body := data[headerLength : headerLength+length]
+	for len(body) >= 4 {
+		attrType := binary.BigEndian.Uint16(body[0:2])
+		attrLength := int(binary.BigEndian.Uint16(body[2:4]))
+		if len(body) < 4+attrLength {
+			return nil, errInvalidMessage
+		}
+		msg.attributes = append(msg.attributes, attribute{attrType, body[4 : 4+attrLength]})
+		padded := 4 + (attrLength+3)&^3
+		if padded > len(body) {
+			break
+		}
+		body = body[padded:]
+	}
+	return msg, nil
+}
+
+// readMessage reads a message from a stream transport.
+func readMessage(r io.Reader) ([]byte, error) {
+	header := make([]byte, headerLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	length := int(binary.BigEndian.Uint16(header[2:4]))
+	if binary.BigEndian.Uint32(header[4:8]) != magicCookie || length > maxMessageLength {
+		return nil, errInvalidMessage
+	}
+	data := make([]byte, headerLength+length)
+	copy(data, header)
+	if _, err := io.ReadFull(r, data[headerLength:]); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// attribute returns the value of the first attribute of the given type.
+func (msg *message) attribute(attrType uint16) ([]byte, bool) {
+	for _, attr := range msg.attributes {
+		if attr.attrType == attrType {
+			return attr.value, true
+		}
+	}
+	return nil, false
+}
+
+// address decodes a (XOR-)MAPPED-ADDRESS style attribute as host:port.
+func (msg *message) address(attrType uint16, xor bool) string {
+	value, ok := msg.attribute(attrType)
+	if !ok || len(value) < 8 {
+		return ""
+	}
+	port := binary.BigEndian.Uint16(value[2:4])
+	var ip net.IP
+	switch value[1] {
+	case 0x01:
+		ip = append(net.IP(nil), value[4:8]...)
+	case 0x02:
+		if len(value) < 20 {
+			return ""
+		}
+		ip = append(net.IP(nil), value[4:20]...)
+	default:
+		return ""
+	}
+	if xor {
+		port ^= magicCookie >> 16
+		key := binary.BigEndian.AppendUint32(nil, magicCookie)
+		key = append(key, msg.transactionID...)
+		for i := range ip {
+			ip[i] ^= key[i]
+		}
+	}
+	return net.JoinHostPort(ip.String(), strconv.Itoa(int(port)))
+}
+
+// ErrorCode is an ERROR-CODE attribute.
+type ErrorCode struct {
+	Code   int    `json:"code"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// errorCode decodes the ERROR-CODE attribute.
+func (msg *message) errorCode() *ErrorCode {
+	value, ok := msg.attribute(attrErrorCode)
+	if !ok || len(value) < 4 {
+		return nil
+	}
+	return &ErrorCode{Code: int(value[2]&0x07)*100 + int(value[3]), Reason: string(value[4:])}
+}
+
+// text returns a string attribute.
+func (msg *message) text(attrType uint16) string {
+	value, _ := msg.attribute(attrType)
+	return string(value)
+}
+
+// matches reports whether data is a response to the request with the given transaction ID.
+func matches(transactionID []byte) func(_, response []byte) bool {
+	return func(_, response []byte) bool {
+		msg, err := parseMessage(response)
+		return err == nil && string(msg.transactionID) == string(transactionID)
+	}
+}
@@ -0,0 +1,43 @@
+package stun
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseBindingResponse(t *testing.T) {
+	transactionID := bytes.Repeat([]byte{0xAB}, 12)
+	// XOR-MAPPED-ADDRESS 192.0.2.1:32853 (RFC 5769 section 2.2), SOFTWARE and an ERROR-CODE
+	response := buildRequest(typeBindingSuccess, transactionID,
+		attribute{attrXORMappedAddress, []byte{0x00, 0x01, 0xA1, 0x47, 0xE1, 0x12, 0xA6, 0x43}},
+		attribute{attrSoftware, []byte("test vector")},
+		attribute{attrErrorCode, []byte{0, 0, 4, 1, 'U', 'n', 'a', 'u', 't', 'h', 'o', 'r', 'i', 'z', 'e', 'd'}},
+	)
+	if !matches(transactionID)(nil, response) {
+		t.Fatal("response does not match its transaction ID")
+	}
+	if matches(make([]byte, 12))(nil, response) {
+		t.Error("response matches a different transaction ID")
+	}
+	msg, err := parseMessage(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := msg.address(attrXORMappedAddress, true); got != "192.0.2.1:32853" {
+		t.Errorf("got mapped address %q", got)
+	}
+	if got := msg.text(attrSoftware); got != "test vector" {
+		t.Errorf("got software %q", got)
+	}
+	if got := msg.errorCode(); got == nil || got.Code != 401 || got.Reason != "Unauthorized" {
+		t.Errorf("got error code %+v", got)
+	}
+
+	framed, err := readMessage(bytes.NewReader(append(response, 0xFF)))
+	if err != nil || !bytes.Equal(framed, response) {
+		t.Errorf("readMessage returned %x (%v)", framed, err)
+	}
+	if _, err = parseMessage(response[:10]); err == nil {
+		t.Error("expected error for truncated message")
+	}
+}
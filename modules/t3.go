@@ -0,0 +1,7 @@
+package modules
+
+import "github.com/zmap/zgrab2/modules/t3"
+
+func init() {
+	t3.RegisterModule()
+}
@@ -0,0 +1,168 @@
+// Package t3 contains the zgrab2 Module implementation for the WebLogic T3 protocol.
+//
+// The scan sends the T3 client hello (or the T3S hello over TLS with --tls) and parses the HELO response, which
+// carries the WebLogic version and the connection parameters the server agreed to. A server that refuses T3, e.g.
+// because a connection filter blocks it, answers with an error line instead, which the scan records.
+package t3
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// Response is the server's reply to the hello, one line per entry.
+	Response []string `json:"response,omitempty"`
+
+	Hello *Hello `json:"hello,omitempty"`
+
+	// Rejected is true if the server answered with a LGIN error line, e.g. because a connection filter blocks T3.
+	Rejected bool `json:"rejected"`
+
+	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
+}
+
+// Flags are the T3-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.TLSFlags
+
+	ClientVersion string `long:"client-version" default:"12.2.1" description:"WebLogic version to announce in the hello"`
+	UseTLS        bool   `long:"tls" description:"Connect over TLS and send the T3S hello"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the t3 zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("t3", "WebLogic T3", module.Description(), 7001, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Send the T3 hello to a WebLogic server and parse the HELO response"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "t3"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	if f.UseTLS {
+		scanner.dialerGroupConfig.TLSEnabled = true
+		scanner.dialerGroupConfig.TLSFlags = &f.TLSFlags
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// Scan performs the configured scan on the WebLogic server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	results := new(ScanResults)
+	scheme := "t3"
+	if tlsConn, ok := conn.(*zgrab2.TLSConnection); ok {
+		results.TLSLog = tlsConn.GetLog()
+		scheme = "t3s"
+	}
+	if _, err = conn.Write(buildHello(scheme, scanner.config.ClientVersion)); err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error sending hello to target %s: %w", target.String(), err)
+	}
+	results.Response, err = readResponse(bufio.NewReader(conn))
+	if len(results.Response) == 0 {
+		if err == nil {
+			err = errInvalidResponse
+		}
+		var partial any
+		if results.TLSLog != nil {
+			partial = results
+		}
+		return zgrab2.TryGetScanStatus(err), partial, fmt.Errorf("error reading response from target %s: %w", target.String(), err)
+	}
+
+	if results.Hello, err = parseHello(results.Response); err != nil {
+		if strings.HasPrefix(results.Response[0], "LGIN:") {
+			results.Rejected = true
+			return zgrab2.SCAN_APPLICATION_ERROR, results, fmt.Errorf("target %s rejected the connection: %s", target.String(), results.Response[0])
+		}
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
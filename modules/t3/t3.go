@@ -0,0 +1,92 @@
+package t3
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxResponseLines bounds the number of lines read from the server's reply to the hello.
+const maxResponseLines = 32
+
+var errInvalidResponse = errors.New("invalid T3 response")
+
+// buildHello returns the client hello for the given scheme (t3 or t3s) and client version, announcing the default
+// abbreviation table size, header length and maximum message size.
+func buildHello(scheme string, version string) []byte {
+	return []byte(fmt.Sprintf("%s %s\nAS:255\nHL:19\nMS:10000000\n\n", scheme, version))
+}
+
+// readResponse reads the lines of the server's reply up to the terminating empty line.
+func readResponse(r *bufio.Reader) ([]string, error) {
+	var lines []string
+	for len(lines) < maxResponseLines {
+		line, err := r.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if line != "" {
+			lines = append(lines, line)
+		}
+		if err != nil {
+			return lines, err
+		}
+		if line == "" && len(lines) > 0 {
+			return lines, nil
+		}
+	}
+	return lines, nil
+}
+
+// Param is a header line of the HELO response other than the ones the scan decodes.
+type Param struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Hello is the server's HELO response.
+type Hello struct {
+	// Version is the WebLogic version, e.g. 12.2.1.3.0.
+	Version string `json:"version"`
+
+	// Tunneled is the flag following the version; true if the server expects HTTP tunneling.
+	Tunneled bool `json:"tunneled"`
+
+	// AbbrevTableSize, HeaderLength and MaxMessageSize are the AS, HL and MS parameters the server agreed to.
+	AbbrevTableSize int `json:"abbrev_table_size,omitempty"`
+	HeaderLength    int `json:"header_length,omitempty"`
+	MaxMessageSize  int `json:"max_message_size,omitempty"`
+
+	// Params are the remaining parameters, e.g. PN (the server's public name) in newer versions.
+	Params []Param `json:"params,omitempty"`
+}
+
+// parseHello decodes the lines of a HELO response.
+func parseHello(lines []string) (*Hello, error) {
+	if len(lines) == 0 || !strings.HasPrefix(lines[0], "HELO:") {
+		return nil, errInvalidResponse
+	}
+	hello := &Hello{Version: strings.TrimPrefix(lines[0], "HELO:")}
+	if i := strings.LastIndexByte(hello.Version, '.'); i >= 0 {
+		if tunneled, err := strconv.ParseBool(hello.Version[i+1:]); err == nil {
+			hello.Version, hello.Tunneled = hello.Version[:i], tunneled
+		}
+	}
+	for _, line := range lines[1:] {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch name {
+		case "AS":
+			hello.AbbrevTableSize, _ = strconv.Atoi(value)
+		case "HL":
+			hello.HeaderLength, _ = strconv.Atoi(value)
+		case "MS":
+			hello.MaxMessageSize, _ = strconv.Atoi(value)
+		default:
+			hello.Params = append(hello.Params, Param{Name: name, Value: value})
+		}
+	}
+	return hello, nil
+}
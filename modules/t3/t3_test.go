@@ -0,0 +1,34 @@
+package t3
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseHello(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("HELO:12.2.1.3.0.false\nAS:2048\nHL:19\nMS:10000000\nPN:DOMAIN\n\n"))
+	lines, err := readResponse(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hello, err := parseHello(lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Hello{
+		Version:         "12.2.1.3.0",
+		AbbrevTableSize: 2048,
+		HeaderLength:    19,
+		MaxMessageSize:  10000000,
+		Params:          []Param{{Name: "PN", Value: "DOMAIN"}},
+	}
+	if !reflect.DeepEqual(hello, expected) {
+		t.Errorf("got %+v, expected %+v", hello, expected)
+	}
+
+	if _, err = parseHello([]string{"LGIN:Socket connection rejected by filter"}); err == nil {
+		t.Error("expected error for rejected connection")
+	}
+}
@@ -30,6 +30,36 @@ type TelnetLog struct {
 
 	// Dont is the list of options that the server requests the client *not* use.
 	Dont []TelnetOption `json:"dont,omitempty"`
+
+	// Negotiation is the complete sequence of option commands sent by the server and the client, in order.
+	Negotiation []NegotiationEvent `json:"negotiation,omitempty"`
+
+	// TerminalTypeRequested is true if the server sent DO TERMINAL-TYPE.
+	TerminalTypeRequested bool `json:"terminal_type_requested,omitempty"`
+
+	// TerminalTypeQueried is true if the server asked for the terminal type with SB TERMINAL-TYPE SEND, which it
+	// only does after the client agrees to --terminal-type.
+	TerminalTypeQueried bool `json:"terminal_type_queried,omitempty"`
+
+	// NAWSRequested is true if the server sent DO NAWS.
+	NAWSRequested bool `json:"naws_requested,omitempty"`
+
+	// Prompt is the classification of the banner.
+	Prompt *PromptLog `json:"prompt,omitempty"`
+}
+
+// NegotiationEvent is a single option command sent during the negotiation.
+type NegotiationEvent struct {
+	// Sender is either "server" or "client".
+	Sender string `json:"sender"`
+
+	// Command is one of WILL, WONT, DO, DONT or SB (a subnegotiation).
+	Command string `json:"command"`
+
+	Option TelnetOption `json:"option"`
+
+	// Data is the payload of a subnegotiation, after the option.
+	Data []byte `json:"data,omitempty"`
 }
 
 // isTelnet checks if this struct represents having actually detected a Telnet service.
@@ -0,0 +1,87 @@
+package telnet
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	// PromptClassRouterCLI is a router or switch command line (Cisco, Huawei, MikroTik, Juniper, ...).
+	PromptClassRouterCLI = "router_cli"
+
+	// PromptClassBusyBox is a BusyBox login, as found on embedded Linux devices.
+	PromptClassBusyBox = "busybox"
+
+	// PromptClassWindows is the Microsoft Telnet Service.
+	PromptClassWindows = "windows"
+
+	// PromptClassUnknown is any other banner.
+	PromptClassUnknown = "unknown"
+)
+
+// PromptLog classifies the banner by the kind of device that sent it and by what its last line asks for.
+type PromptLog struct {
+	// Class is one of router_cli, busybox, windows or unknown.
+	Class string `json:"class"`
+
+	// Type is what the last line of the banner asks for: login, password or shell (a command prompt). It is
+	// omitted if the last line is not a recognized prompt.
+	Type string `json:"type,omitempty"`
+
+	// Line is the last non-empty line of the banner.
+	Line string `json:"line,omitempty"`
+}
+
+// promptClassMarkers are substrings of the lowercased banner identifying each class, checked in order.
+var promptClassMarkers = []struct {
+	class   string
+	markers []string
+}{
+	{PromptClassWindows, []string{"microsoft telnet", "welcome to microsoft"}},
+	{PromptClassBusyBox, []string{"busybox", "(none) login:"}},
+	{PromptClassRouterCLI, []string{"user access verification", "cisco", "mikrotik", "routeros", "huawei", "junos", "juniper", "comware", "zyxel"}},
+}
+
+// routerPromptRegex matches command prompts like "Router>", "switch#" or "<Huawei>".
+var routerPromptRegex = regexp.MustCompile(`^(<[\w.\-]+>|[\w.\-]+(\(config[^)]*\))?[>#])$`)
+
+// classifyPrompt classifies a telnet banner; it returns nil for an empty banner.
+func classifyPrompt(banner string) *PromptLog {
+	var line string
+	lines := strings.FieldsFunc(banner, func(r rune) bool { return r == '\r' || r == '\n' })
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line = strings.TrimSpace(lines[i]); line != "" {
+			break
+		}
+	}
+	if line == "" {
+		return nil
+	}
+	ret := &PromptLog{Class: PromptClassUnknown, Line: line}
+	lower := strings.ToLower(banner)
+	for _, class := range promptClassMarkers {
+		for _, marker := range class.markers {
+			if strings.Contains(lower, marker) {
+				ret.Class = class.class
+				break
+			}
+		}
+		if ret.Class != PromptClassUnknown {
+			break
+		}
+	}
+	if ret.Class == PromptClassUnknown && routerPromptRegex.MatchString(line) {
+		ret.Class = PromptClassRouterCLI
+	}
+
+	lowerLine := strings.ToLower(line)
+	switch {
+	case strings.HasSuffix(lowerLine, "login:") || strings.HasSuffix(lowerLine, "username:") || strings.HasSuffix(lowerLine, "user name:") || strings.HasSuffix(lowerLine, "user:"):
+		ret.Type = "login"
+	case strings.HasSuffix(lowerLine, "password:"):
+		ret.Type = "password"
+	case strings.HasSuffix(line, "$") || strings.HasSuffix(line, "#") || strings.HasSuffix(line, ">") || strings.HasSuffix(line, "%"):
+		ret.Type = "shell"
+	}
+	return ret
+}
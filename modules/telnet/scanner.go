@@ -5,15 +5,19 @@
 // that will be read for the banner.
 //
 // The scan negotiates the options and attempts to grab the banner, using the
-// same behavior as the original zgrab.
+// same behavior as the original zgrab: all options are refused, except for
+// TERMINAL-TYPE and NAWS if --terminal-type and --window-size are set.
 //
 // The output contains the banner and the negotiated options, in the same
-// format as the original zgrab.
+// format as the original zgrab, along with the complete negotiation sequence
+// and a classification of the banner's prompt.
 package telnet
 
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 
@@ -24,8 +28,10 @@ import (
 // Populated by the framework.
 type Flags struct {
 	zgrab2.BaseFlags `group:"Basic Options"`
-	MaxReadSize      int  `long:"max-read-size" description:"Set the maximum number of bytes to read when grabbing the banner" default:"65536"`
-	Banner           bool `long:"force-banner" description:"Always return banner if it has non-zero bytes"`
+	MaxReadSize      int    `long:"max-read-size" description:"Set the maximum number of bytes to read when grabbing the banner" default:"65536"`
+	Banner           bool   `long:"force-banner" description:"Always return banner if it has non-zero bytes"`
+	TerminalType     string `long:"terminal-type" description:"If set, agree to the server's DO TERMINAL-TYPE and send this terminal type (e.g. xterm) when asked"`
+	WindowSize       string `long:"window-size" description:"If set, agree to the server's DO NAWS and send this window size, as WIDTHxHEIGHT (e.g. 80x24)"`
 }
 
 // Module implements the zgrab2.Module interface.
@@ -36,6 +42,7 @@ type Module struct {
 type Scanner struct {
 	config            *Flags
 	dialerGroupConfig *zgrab2.DialerGroupConfig
+	options           *NegotiationOptions
 }
 
 // RegisterModule registers the zgrab2 module.
@@ -66,9 +73,31 @@ func (module *Module) Description() string {
 // On success, returns nil.
 // On failure, returns an error instance describing the error.
 func (flags *Flags) Validate(_ []string) error {
+	if flags.WindowSize != "" {
+		if _, _, err := parseWindowSize(flags.WindowSize); err != nil {
+			return fmt.Errorf("window-size: %w", err)
+		}
+	}
 	return nil
 }
 
+// parseWindowSize parses a WIDTHxHEIGHT window size.
+func parseWindowSize(size string) (uint16, uint16, error) {
+	w, h, ok := strings.Cut(strings.ToLower(size), "x")
+	if !ok {
+		return 0, 0, fmt.Errorf("%s is not of the form WIDTHxHEIGHT", size)
+	}
+	width, err := strconv.ParseUint(w, 10, 16)
+	if err != nil || width == 0 {
+		return 0, 0, fmt.Errorf("invalid width %s", w)
+	}
+	height, err := strconv.ParseUint(h, 10, 16)
+	if err != nil || height == 0 {
+		return 0, 0, fmt.Errorf("invalid height %s", h)
+	}
+	return uint16(width), uint16(height), nil
+}
+
 // Help returns the module's help string.
 func (flags *Flags) Help() string {
 	return ""
@@ -78,6 +107,14 @@ func (flags *Flags) Help() string {
 func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
 	f, _ := flags.(*Flags)
 	scanner.config = f
+	scanner.options = &NegotiationOptions{TerminalType: f.TerminalType}
+	if f.WindowSize != "" {
+		width, height, err := parseWindowSize(f.WindowSize)
+		if err != nil {
+			return err
+		}
+		scanner.options.Width, scanner.options.Height = width, height
+	}
 	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
 		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
 		BaseFlags:                       &f.BaseFlags,
@@ -122,7 +159,7 @@ func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup,
 	}
 	defer zgrab2.CloseConnAndHandleError(conn)
 	result := new(TelnetLog)
-	if err := GetTelnetBanner(result, conn, scanner.config.MaxReadSize); err != nil {
+	if err := GetTelnetBanner(result, conn, scanner.config.MaxReadSize, scanner.options); err != nil {
 		if scanner.config.Banner && len(result.Banner) > 0 {
 			return zgrab2.TryGetScanStatus(err), result, err
 		} else {
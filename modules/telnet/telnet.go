@@ -20,7 +20,6 @@ import (
 	"errors"
 	"io"
 	"net"
-	"strconv"
 	"time"
 
 	"github.com/zmap/zgrab2"
@@ -46,6 +45,22 @@ const (
 	// GO_AHEAD is the special go ahead command.
 	GO_AHEAD = byte(0xf9)
 
+	// SB marks the start of a subnegotiation.
+	SB = byte(0xfa)
+
+	// SE marks the end of a subnegotiation.
+	SE = byte(0xf0)
+
+	// TERMINAL_TYPE is the Terminal Type option (RFC 1091).
+	TERMINAL_TYPE = byte(24)
+
+	// TERMINAL_TYPE_IS and TERMINAL_TYPE_SEND are the Terminal Type subnegotiation commands.
+	TERMINAL_TYPE_IS   = byte(0)
+	TERMINAL_TYPE_SEND = byte(1)
+
+	// NAWS is the Negotiate About Window Size option (RFC 1073).
+	NAWS = byte(31)
+
 	// IAC_CMD_LENGTH gives the length of the special IAC command (inclusive).
 	IAC_CMD_LENGTH = 3
 
@@ -92,10 +107,18 @@ func (opt *TelnetOption) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+var commandToName = map[byte]string{
+	WILL: "WILL",
+	WONT: "WONT",
+	DO:   "DO",
+	DONT: "DONT",
+	SB:   "SB",
+}
+
 // GetTelnetBanner attempts to negotiate the options and fetch the telnet banner over the given connection, reading at
 // most maxReadSize bytes.
-func GetTelnetBanner(logStruct *TelnetLog, conn net.Conn, maxReadSize int) (err error) {
-	if err = NegotiateOptions(logStruct, conn); err != nil {
+func GetTelnetBanner(logStruct *TelnetLog, conn net.Conn, maxReadSize int, options *NegotiationOptions) (err error) {
+	if err = NegotiateOptions(logStruct, conn, options); err != nil {
 		return err
 	}
 	// Keep reading until READ_BUFFER_LENGTH chunks until
@@ -115,6 +138,7 @@ func GetTelnetBanner(logStruct *TelnetLog, conn net.Conn, maxReadSize int) (err
 	if err != nil && err != io.EOF && !zgrab2.IsTimeoutError(err) {
 		return err
 	}
+	logStruct.Prompt = classifyPrompt(logStruct.Banner)
 	// Make sure it is a telnet banner
 	if !logStruct.isTelnet() {
 		return zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, errors.New("invalid response for Telnet"))
@@ -122,85 +146,207 @@ func GetTelnetBanner(logStruct *TelnetLog, conn net.Conn, maxReadSize int) (err
 	return nil
 }
 
-// NegotiateOptions attempts to negotiate the connection options over the given connection.
-func NegotiateOptions(logStruct *TelnetLog, conn net.Conn) error {
-	var readBuffer, retBuffer []byte
-	var option, optionType, returnOptionType byte
-	var iacIndex, firstUnreadIndex, numBytes, numDataBytes int
-	var err error
-
-	for finishedNegotiating := false; !finishedNegotiating; {
-		readBuffer = make([]byte, READ_BUFFER_LENGTH)
-		retBuffer = nil
-		numBytes, err = conn.Read(readBuffer)
-		numDataBytes = numBytes
+// NegotiationOptions controls which of the server's option requests the client agrees to; all others are refused.
+type NegotiationOptions struct {
+	// TerminalType, if set, makes the client agree to DO TERMINAL-TYPE and send it when asked (RFC 1091).
+	TerminalType string
+
+	// Width and Height, if non-zero, make the client agree to DO NAWS and send them as its window size (RFC 1073).
+	Width  uint16
+	Height uint16
+}
+
+// negotiator holds the state of an option negotiation.
+type negotiator struct {
+	log     *TelnetLog
+	options *NegotiationOptions
+
+	// enabled is the set of options the client has agreed to use.
+	enabled map[byte]bool
+}
 
+// NegotiateOptions attempts to negotiate the connection options over the given connection.
+func NegotiateOptions(logStruct *TelnetLog, conn net.Conn, options *NegotiationOptions) error {
+	if options == nil {
+		options = new(NegotiationOptions)
+	}
+	n := negotiator{log: logStruct, options: options, enabled: make(map[byte]bool)}
+	readBuffer := make([]byte, READ_BUFFER_LENGTH)
+	var pending []byte
+	for {
+		numBytes, err := conn.Read(readBuffer)
 		if err != nil {
 			return err
 		}
-
-		if numBytes == len(readBuffer) {
+		if numBytes == len(readBuffer) || len(pending) >= READ_BUFFER_LENGTH {
 			return errors.New("not enough buffer space for telnet options")
 		}
+		data, reply, rest := n.process(append(pending, readBuffer[:numBytes]...))
+		pending = rest
+		if len(reply) > 0 {
+			if _, err = conn.Write(reply); err != nil {
+				return err
+			}
+		}
+		// Negotiation is done once the server sends data
+		if len(data) > 0 {
+			logStruct.Banner = string(data)
+			return nil
+		}
+	}
+}
 
-		// Negotiate options
+// process handles the commands in buf, returning the data bytes, the client's replies, and any incomplete command at
+// the end of buf.
+func (n *negotiator) process(buf []byte) (data []byte, reply []byte, rest []byte) {
+	for i := 0; i < len(buf); {
+		if buf[i] != IAC {
+			data = append(data, buf[i])
+			i++
+			continue
+		}
+		if i+1 >= len(buf) {
+			return data, reply, buf[i:]
+		}
+		switch command := buf[i+1]; command {
+		case IAC:
+			// An escaped 0xFF data byte
+			data = append(data, IAC)
+			i += 2
+		case WILL, WONT, DO, DONT:
+			if i+2 >= len(buf) {
+				return data, reply, buf[i:]
+			}
+			reply = append(reply, n.handleOption(command, buf[i+2])...)
+			i += IAC_CMD_LENGTH
+		case SB:
+			end := getSubnegotiationEnd(buf[i+2:])
+			if end == -1 {
+				return data, reply, buf[i:]
+			}
+			reply = append(reply, n.handleSubnegotiation(unescapeIAC(buf[i+2:i+2+end]))...)
+			i += 2 + end + 2
+		default:
+			// GO_AHEAD, NOP and the other two-byte commands carry no options
+			i += 2
+		}
+	}
+	return data, reply, nil
+}
 
-		for iacIndex = getIACIndex(readBuffer); iacIndex != -1; iacIndex = getIACIndex(readBuffer) {
-			optionType = readBuffer[iacIndex+1]
-			option = readBuffer[iacIndex+2]
+// record appends a command to the log's negotiation sequence.
+func (n *negotiator) record(sender string, command byte, option byte, data []byte) {
+	n.log.Negotiation = append(n.log.Negotiation, NegotiationEvent{
+		Sender:  sender,
+		Command: commandToName[command],
+		Option:  TelnetOption(option),
+		Data:    data,
+	})
+}
 
-			// ignore go ahead
-			if optionType == GO_AHEAD {
-				readBuffer = readBuffer[0:iacIndex]
-				numBytes = iacIndex
-				break
-			}
+// handleOption records a WILL, WONT, DO or DONT from the server and returns the client's reply.
+func (n *negotiator) handleOption(optionType byte, option byte) []byte {
+	n.record("server", optionType, option, nil)
 
-			// record all offered options
-			opt := TelnetOption(option)
-			switch optionType {
-			case WILL:
-				logStruct.Will = append(logStruct.Will, opt)
-			case DO:
-				logStruct.Do = append(logStruct.Do, opt)
-			case WONT:
-				logStruct.Wont = append(logStruct.Wont, opt)
-			case DONT:
-				logStruct.Dont = append(logStruct.Dont, opt)
-			}
+	// record all offered options
+	opt := TelnetOption(option)
+	switch optionType {
+	case WILL:
+		n.log.Will = append(n.log.Will, opt)
+	case DO:
+		n.log.Do = append(n.log.Do, opt)
+	case WONT:
+		n.log.Wont = append(n.log.Wont, opt)
+	case DONT:
+		n.log.Dont = append(n.log.Dont, opt)
+	}
 
-			// reject all offered options
-			switch optionType {
-			case WILL, WONT:
-				returnOptionType = DONT
-			case DO, DONT:
-				returnOptionType = WONT
-			default:
-				return errors.New("Unsupported telnet IAC option type" + strconv.Itoa(int(optionType)))
+	if optionType == DO {
+		switch option {
+		case TERMINAL_TYPE:
+			n.log.TerminalTypeRequested = true
+		case NAWS:
+			n.log.NAWSRequested = true
+		}
+		if n.enabled[option] {
+			// Don't acknowledge a request for an option that is already enabled
+			return nil
+		}
+		if (option == TERMINAL_TYPE && n.options.TerminalType != "") || (option == NAWS && n.options.Width != 0 && n.options.Height != 0) {
+			n.enabled[option] = true
+			reply := n.reply(WILL, option, nil)
+			if option == NAWS {
+				size := []byte{byte(n.options.Width >> 8), byte(n.options.Width), byte(n.options.Height >> 8), byte(n.options.Height)}
+				reply = append(reply, n.reply(SB, option, size)...)
 			}
-			retBuffer = append(retBuffer, IAC)
-			retBuffer = append(retBuffer, returnOptionType)
-			retBuffer = append(retBuffer, option)
-
-			firstUnreadIndex = iacIndex + IAC_CMD_LENGTH
-			numDataBytes -= firstUnreadIndex
-			readBuffer = readBuffer[firstUnreadIndex:]
+			return reply
 		}
+	}
 
-		if _, err = conn.Write(retBuffer); err != nil {
-			return err
+	// reject all other offered options
+	var returnOptionType byte
+	switch optionType {
+	case WILL, WONT:
+		returnOptionType = DONT
+	case DO, DONT:
+		returnOptionType = WONT
+		delete(n.enabled, option)
+	}
+	return n.reply(returnOptionType, option, nil)
+}
+
+// handleSubnegotiation records a subnegotiation (without the IAC SB and IAC SE) from the server and returns the
+// client's reply.
+func (n *negotiator) handleSubnegotiation(subnegotiation []byte) []byte {
+	if len(subnegotiation) == 0 {
+		return nil
+	}
+	option := subnegotiation[0]
+	n.record("server", SB, option, subnegotiation[1:])
+	if option == TERMINAL_TYPE && len(subnegotiation) > 1 && subnegotiation[1] == TERMINAL_TYPE_SEND {
+		n.log.TerminalTypeQueried = true
+		if n.enabled[TERMINAL_TYPE] {
+			return n.reply(SB, TERMINAL_TYPE, append([]byte{TERMINAL_TYPE_IS}, n.options.TerminalType...))
 		}
+	}
+	return nil
+}
 
-		numIACBytes := numBytes - numDataBytes
-		finishedNegotiating = numBytes != numIACBytes
+// reply records and encodes a command from the client.
+func (n *negotiator) reply(command byte, option byte, data []byte) []byte {
+	n.record("client", command, option, data)
+	ret := []byte{IAC, command, option}
+	if command == SB {
+		ret = append(ret, escapeIAC(data)...)
+		ret = append(ret, IAC, SE)
 	}
+	return ret
+}
 
-	// no more IAC commands, just read the resulting data
-	if numDataBytes >= 0 {
-		logStruct.Banner = string(readBuffer[0:numDataBytes])
+// getSubnegotiationEnd returns the index of the IAC SE ending the subnegotiation at the start of buffer, or -1 if it
+// has not been read yet.
+func getSubnegotiationEnd(buffer []byte) int {
+	for i := 0; i+1 < len(buffer); i++ {
+		if buffer[i] != IAC {
+			continue
+		}
+		if buffer[i+1] == SE {
+			return i
+		}
+		// Skip the second byte of an escaped IAC
+		i++
 	}
+	return -1
+}
 
-	return nil
+// escapeIAC doubles the 0xFF bytes in data.
+func escapeIAC(data []byte) []byte {
+	return bytes.ReplaceAll(data, []byte{IAC}, []byte{IAC, IAC})
+}
+
+// unescapeIAC undoes escapeIAC.
+func unescapeIAC(data []byte) []byte {
+	return bytes.ReplaceAll(data, []byte{IAC, IAC}, []byte{IAC})
 }
 
 func getIACIndex(buffer []byte) int {
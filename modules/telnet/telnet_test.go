@@ -0,0 +1,90 @@
+package telnet
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestNegotiateOptions(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	replies := make(chan []byte, 2)
+	go func() {
+		defer server.Close()
+		buf := make([]byte, 256)
+		for _, msg := range [][]byte{
+			{IAC, DO, TERMINAL_TYPE, IAC, DO, NAWS, IAC, WILL, 1},
+			{IAC, SB, TERMINAL_TYPE, TERMINAL_TYPE_SEND, IAC, SE},
+		} {
+			if _, err := server.Write(msg); err != nil {
+				return
+			}
+			n, err := server.Read(buf)
+			if err != nil {
+				return
+			}
+			replies <- append([]byte{}, buf[:n]...)
+		}
+		server.Write([]byte("BusyBox v1.31.1 built-in shell\r\n(none) login: "))
+		io.Copy(io.Discard, server)
+	}()
+
+	var log TelnetLog
+	if err := NegotiateOptions(&log, client, &NegotiationOptions{TerminalType: "xterm", Width: 80, Height: 255}); err != nil {
+		t.Fatalf("NegotiateOptions: %v", err)
+	}
+	expected := [][]byte{
+		{IAC, WILL, TERMINAL_TYPE, IAC, WILL, NAWS, IAC, SB, NAWS, 0, 80, 0, IAC, IAC, IAC, SE, IAC, DONT, 1},
+		append(append([]byte{IAC, SB, TERMINAL_TYPE, TERMINAL_TYPE_IS}, "xterm"...), IAC, SE),
+	}
+	for i, want := range expected {
+		if got := <-replies; !bytes.Equal(got, want) {
+			t.Errorf("reply %d = %x, expected %x", i, got, want)
+		}
+	}
+	if !log.TerminalTypeRequested || !log.TerminalTypeQueried || !log.NAWSRequested {
+		t.Errorf("log = %+v", log)
+	}
+	if len(log.Negotiation) != 9 || log.Negotiation[7].Command != "SB" || log.Negotiation[8].Sender != "client" {
+		t.Errorf("negotiation = %+v", log.Negotiation)
+	}
+	if log.Banner != "BusyBox v1.31.1 built-in shell\r\n(none) login: " {
+		t.Errorf("banner = %q", log.Banner)
+	}
+}
+
+func TestProcessSplitCommands(t *testing.T) {
+	n := negotiator{log: new(TelnetLog), options: new(NegotiationOptions), enabled: make(map[byte]bool)}
+	data, reply, rest := n.process([]byte{IAC, DO, 1, IAC, SB, 24, 1})
+	if len(data) != 0 || !bytes.Equal(reply, []byte{IAC, WONT, 1}) || !bytes.Equal(rest, []byte{IAC, SB, 24, 1}) {
+		t.Fatalf("process = %x, %x, %x", data, reply, rest)
+	}
+	data, reply, rest = n.process(append(rest, IAC, SE, 'a', IAC, IAC, IAC, GO_AHEAD, 'b'))
+	if !bytes.Equal(data, []byte{'a', IAC, 'b'}) || reply != nil || rest != nil {
+		t.Errorf("process = %x, %x, %x", data, reply, rest)
+	}
+	if !n.log.TerminalTypeQueried {
+		t.Error("expected TerminalTypeQueried")
+	}
+}
+
+func TestClassifyPrompt(t *testing.T) {
+	tests := map[string]PromptLog{
+		"\r\n\r\nUser Access Verification\r\n\r\nUsername: ": {Class: PromptClassRouterCLI, Type: "login", Line: "Username:"},
+		"\r\nRouter>": {Class: PromptClassRouterCLI, Type: "shell", Line: "Router>"},
+		"BusyBox v1.19.4 (2019-01-01) built-in shell (ash)\n# ": {Class: PromptClassBusyBox, Type: "shell", Line: "#"},
+		"Welcome to Microsoft Telnet Service \r\n\r\nlogin: ":   {Class: PromptClassWindows, Type: "login", Line: "login:"},
+		"Ubuntu 22.04 LTS\r\nhost login: ":                      {Class: PromptClassUnknown, Type: "login", Line: "host login:"},
+		"Password: ":                                            {Class: PromptClassUnknown, Type: "password", Line: "Password:"},
+	}
+	for banner, expected := range tests {
+		if got := classifyPrompt(banner); got == nil || *got != expected {
+			t.Errorf("classifyPrompt(%q) = %+v, expected %+v", banner, got, expected)
+		}
+	}
+	if classifyPrompt("\r\n") != nil {
+		t.Error("expected nil for an empty banner")
+	}
+}
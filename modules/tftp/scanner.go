@@ -0,0 +1,229 @@
+// Package tftp contains the zgrab2 Module implementation for TFTP.
+//
+// The scan sends a read request for a (by default random, non-existent) file with RFC 2347 options and classifies
+// the response: the error code and message a server sends for a missing file, whether it negotiates options, and
+// whether it answers from a new port, fingerprint the implementation. If the server starts a transfer instead, the
+// scan aborts it with an ERROR before any data is acknowledged.
+package tftp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// Filename is the file the read request was sent for.
+	Filename string `json:"filename"`
+
+	// Response is the opcode of the server's response: error, oack or data.
+	Response string `json:"response,omitempty"`
+
+	ErrorCode    *uint16 `json:"error_code,omitempty"`
+	ErrorName    string  `json:"error_name,omitempty"`
+	ErrorMessage string  `json:"error_message,omitempty"`
+
+	// Options are the options the server acknowledged in an OACK.
+	Options []Option `json:"options,omitempty"`
+
+	// FileExists is true if the server started a transfer (OACK or DATA) for the file.
+	FileExists bool `json:"file_exists,omitempty"`
+
+	// ServerPort is the port (the server's transfer ID) the response came from.
+	ServerPort int `json:"server_port,omitempty"`
+
+	// SamePort is true if the server answered from the port the request was sent to, rather than a new one.
+	SamePort bool `json:"same_port,omitempty"`
+
+	// Implementation is a best-effort guess of the server implementation from its error message.
+	Implementation string `json:"implementation,omitempty"`
+
+	Probe *zgrab2.UDPProbeResult `json:"probe,omitempty"`
+}
+
+// Flags are the TFTP-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.UDPFlags
+
+	Filename  string `long:"filename" description:"File to request. Defaults to a random, non-existent name"`
+	Mode      string `long:"mode" default:"octet" description:"Transfer mode (octet or netascii)"`
+	NoOptions bool   `long:"no-options" description:"Don't send RFC 2347 options (blksize, tsize, timeout) with the request"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the tftp zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("tftp", "Trivial File Transfer Protocol (TFTP)", module.Description(), 69, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Send a TFTP read request for a missing file and fingerprint the server from its response"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	if err := f.UDPFlags.Validate(); err != nil {
+		return err
+	}
+	switch f.Mode {
+	case "octet", "netascii":
+	default:
+		return fmt.Errorf("unsupported transfer mode %q", f.Mode)
+	}
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "tftp"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportUDP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// filename returns the file to request.
+func (scanner *Scanner) filename() string {
+	if scanner.config.Filename != "" {
+		return scanner.config.Filename
+	}
+	suffix := make([]byte, 6)
+	_, _ = rand.Read(suffix)
+	return "zgrab2-" + hex.EncodeToString(suffix)
+}
+
+// openTransferConn dials the target through the dialer group, so the blocklist and source address options apply,
+// then reopens the same local address unconnected so responses from the server's new transfer port are received.
+func openTransferConn(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (*transferConn, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	local, localOK := conn.LocalAddr().(*net.UDPAddr)
+	server, serverOK := conn.RemoteAddr().(*net.UDPAddr)
+	zgrab2.CloseConnAndHandleError(conn)
+	if !localOK || !serverOK {
+		return nil, errors.New("dialer did not return a UDP connection")
+	}
+	udpConn, err := net.ListenUDP("udp", local)
+	if err != nil {
+		// the port was taken in the meantime; any port will do
+		if udpConn, err = net.ListenUDP("udp", &net.UDPAddr{IP: local.IP, Zone: local.Zone}); err != nil {
+			return nil, err
+		}
+	}
+	return &transferConn{UDPConn: udpConn, server: server}, nil
+}
+
+// Scan performs the configured scan on the TFTP server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := openTransferConn(ctx, dialGroup, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	results := &ScanResults{Filename: scanner.filename()}
+	var options []Option
+	if !scanner.config.NoOptions {
+		options = requestOptions
+	}
+	probe := zgrab2.NewStaticUDPProbe("rrq", buildReadRequest(results.Filename, scanner.config.Mode, options), func(_, response []byte) bool {
+		p, err := parsePacket(response)
+		return err == nil && p.opcode != opAck
+	})
+	results.Probe, err = zgrab2.SendUDPProbe(ctx, conn, probe, target, &scanner.config.UDPFlags)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), results, err
+	}
+	response, _ := parsePacket(results.Probe.Response)
+	results.Response = opcodeName(response.opcode)
+	results.ServerPort = conn.peer.Port
+	results.SamePort = conn.peer.Port == conn.server.Port
+	switch response.opcode {
+	case opError:
+		results.ErrorCode = &response.errorCode
+		results.ErrorName = errorCodeNames[response.errorCode]
+		results.ErrorMessage = response.message
+		results.Implementation = identifyImplementation(response.message)
+	case opOACK:
+		results.Options = response.options
+		results.FileExists = true
+		conn.sendToPeer(buildError(errTerminated, "transfer terminated"))
+	case opData:
+		results.FileExists = true
+		conn.sendToPeer(buildError(errTerminated, "transfer terminated"))
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
@@ -0,0 +1,196 @@
+package tftp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// TFTP opcodes (RFC 1350, RFC 2347)
+const (
+	opRRQ   = 1
+	opData  = 3
+	opAck   = 4
+	opError = 5
+	opOACK  = 6
+)
+
+var opcodeNames = map[uint16]string{
+	opRRQ:   "rrq",
+	2:       "wrq",
+	opData:  "data",
+	opAck:   "ack",
+	opError: "error",
+	opOACK:  "oack",
+}
+
+// errorCodeNames are the TFTP error codes (RFC 1350, RFC 2347).
+var errorCodeNames = map[uint16]string{
+	0: "not_defined",
+	1: "file_not_found",
+	2: "access_violation",
+	3: "disk_full",
+	4: "illegal_operation",
+	5: "unknown_transfer_id",
+	6: "file_already_exists",
+	7: "no_such_user",
+	8: "option_negotiation_failed",
+}
+
+// errTerminated is the error code sent to end a transfer the scan doesn't want.
+const errTerminated = 8
+
+var errInvalidPacket = errors.New("invalid TFTP packet")
+
+// Option is a TFTP option (RFC 2347) acknowledged by the server.
+type Option struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// requestOptions are sent with the read request to find out whether the server supports option negotiation.
+var requestOptions = []Option{
+	{Name: "blksize", Value: "1428"},
+	{Name: "tsize", Value: "0"},
+	{Name: "timeout", Value: "3"},
+}
+
+// packet is a decoded server packet.
+type packet struct {
+	opcode    uint16
+	errorCode uint16
+	message   string
+	options   []Option
+	block     uint16
+}
+
+// buildReadRequest returns an RRQ for filename in the given mode, with the given options.
+func buildReadRequest(filename, mode string, options []Option) []byte {
+	request := binary.BigEndian.AppendUint16(nil, opRRQ)
+	for _, field := range []string{filename, mode} {
+		request = append(append(request, field...), 0)
+	}
+	for _, option := range options {
+		request = append(append(request, option.Name...), 0)
+		request = append(append(request, option.Value...), 0)
+	}
+	return request
+}
+
+// buildError returns an ERROR packet.
+func buildError(code uint16, message string) []byte {
+	packet := binary.BigEndian.AppendUint16(nil, opError)
+	packet = binary.BigEndian.AppendUint16(packet, code)
+	return append(append(packet, message...), 0)
+}
+
+// parsePacket decodes a packet sent by the server in response to a read request.
+func parsePacket(data []byte) (*packet, error) {
+	if len(data) < 2 {
+		return nil, errInvalidPacket
+	}
+	p := &packet{opcode: binary.BigEndian.Uint16(data)}
+	body := data[2:]
+	switch p.opcode {
+	case opError:
+		if len(body) < 2 {
+			return nil, errInvalidPacket
+		}
+		p.errorCode = binary.BigEndian.Uint16(body)
+		// the message should be NUL-terminated, but not every server bothers
+		message, _, _ := bytes.Cut(body[2:], []byte{0})
+		p.message = string(message)
+	case opOACK:
+		fields := bytes.Split(bytes.TrimSuffix(body, []byte{0}), []byte{0})
+		if len(fields)%2 != 0 {
+			return nil, errInvalidPacket
+		}
+		for i := 0; i < len(fields); i += 2 {
+			p.options = append(p.options, Option{Name: string(fields[i]), Value: string(fields[i+1])})
+		}
+	case opData, opAck:
+		if len(body) < 2 {
+			return nil, errInvalidPacket
+		}
+		p.block = binary.BigEndian.Uint16(body)
+	default:
+		return nil, errInvalidPacket
+	}
+	return p, nil
+}
+
+// opcodeName returns the name of an opcode, or its number if it is unknown.
+func opcodeName(opcode uint16) string {
+	if name, ok := opcodeNames[opcode]; ok {
+		return name
+	}
+	return strconv.Itoa(int(opcode))
+}
+
+// implementationPatterns match the error message servers send for a missing file. "File not found" is the message
+// table of the original BSD tftpd, which tftpd-hpa, atftpd and many embedded servers inherited.
+var implementationPatterns = []struct {
+	pattern        *regexp.Regexp
+	implementation string
+}{
+	{regexp.MustCompile(`^file .+ not found$`), "dnsmasq"},
+	{regexp.MustCompile(`^The system cannot find the file specified\.?$`), "windows"},
+	{regexp.MustCompile(`^File not found$`), "bsd"},
+}
+
+// identifyImplementation returns a best-effort guess of the server implementation from its error message.
+func identifyImplementation(message string) string {
+	for _, candidate := range implementationPatterns {
+		if candidate.pattern.MatchString(message) {
+			return candidate.implementation
+		}
+	}
+	return ""
+}
+
+// transferConn adapts an unconnected UDP socket for zgrab2.SendUDPProbe. TFTP servers answer a request from a new
+// port (the server's transfer ID), which a connected socket would drop, so reads accept datagrams from any port on
+// the server's address and remember the port they came from.
+type transferConn struct {
+	*net.UDPConn
+	server *net.UDPAddr
+	// peer is the address of the last datagram received from the server
+	peer *net.UDPAddr
+}
+
+// Write sends b to the server's well-known port.
+func (c *transferConn) Write(b []byte) (int, error) {
+	return c.WriteToUDP(b, c.server)
+}
+
+// Read returns the next datagram from the server's address, discarding those from other hosts.
+func (c *transferConn) Read(b []byte) (int, error) {
+	for {
+		n, addr, err := c.ReadFromUDP(b)
+		if err != nil {
+			return n, err
+		}
+		if addr.IP.Equal(c.server.IP) {
+			c.peer = addr
+			return n, nil
+		}
+	}
+}
+
+// RemoteAddr returns the server's well-known address.
+func (c *transferConn) RemoteAddr() net.Addr {
+	return c.server
+}
+
+// sendToPeer sends b to the port the server last answered from, ignoring errors: it is only used to end transfers.
+func (c *transferConn) sendToPeer(b []byte) {
+	if c.peer == nil {
+		return
+	}
+	_ = c.SetWriteDeadline(time.Now().Add(time.Second))
+	_, _ = c.WriteToUDP(b, c.peer)
+}
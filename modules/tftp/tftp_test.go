@@ -0,0 +1,51 @@
+package tftp
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestBuildReadRequest(t *testing.T) {
+	got := buildReadRequest("x", "octet", []Option{{Name: "tsize", Value: "0"}})
+	want := []byte("\x00\x01x\x00octet\x00tsize\x000\x00")
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestParsePacket(t *testing.T) {
+	p, err := parsePacket([]byte("\x00\x05\x00\x01File not found\x00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.opcode != opError || p.errorCode != 1 || p.message != "File not found" {
+		t.Errorf("unexpected error packet %+v", p)
+	}
+	if got := identifyImplementation(p.message); got != "bsd" {
+		t.Errorf("got implementation %q", got)
+	}
+
+	// unterminated message
+	if p, err = parsePacket([]byte("\x00\x05\x00\x01file /srv/tftp/x not found")); err != nil || p.message != "file /srv/tftp/x not found" {
+		t.Errorf("got %+v (%v)", p, err)
+	}
+	if got := identifyImplementation(p.message); got != "dnsmasq" {
+		t.Errorf("got implementation %q", got)
+	}
+
+	p, err = parsePacket([]byte("\x00\x06blksize\x001428\x00tsize\x00512\x00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Option{{Name: "blksize", Value: "1428"}, {Name: "tsize", Value: "512"}}
+	if p.opcode != opOACK || !reflect.DeepEqual(p.options, want) {
+		t.Errorf("unexpected OACK %+v", p)
+	}
+
+	for _, invalid := range [][]byte{{0}, {0, 5, 0}, []byte("\x00\x06blksize\x00"), {0, 9, 0, 0}} {
+		if _, err := parsePacket(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
@@ -2,7 +2,12 @@ package modules
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 
@@ -12,6 +17,32 @@ import (
 type TLSFlags struct {
 	zgrab2.BaseFlags `group:"Basic Options"`
 	zgrab2.TLSFlags  `group:"TLS Options"`
+
+	// ECH attempts an Encrypted Client Hello handshake on a second connection.
+	ECH       bool   `long:"ech" description:"After the handshake, attempt a TLS 1.3 handshake with Encrypted Client Hello on a new connection and record whether the server accepts it and its retry configs in ech. Without a config, GREASE ECH is sent, which servers supporting ECH reject with their configs"`
+	ECHConfig string `long:"ech-config" description:"Base64 ECHConfigList to use with --ech"`
+	ECHNoDNS  bool   `long:"ech-no-dns" description:"With --ech and no --ech-config, do not look up the ECHConfigList in the HTTPS DNS record of the target and send GREASE ECH"`
+
+	// ValidateSCTs validates the SCTs of the certificate against a Certificate Transparency log list.
+	ValidateSCTs bool   `long:"validate-scts" description:"Validate the Signed Certificate Timestamps of the certificate, the TLS extension and the stapled OCSP response against the CT log list of --ct-log-list, and record the log and validity of each in ct"`
+	CTLogList    string `long:"ct-log-list" description:"CT log list to validate SCTs against, in the v3 JSON format of https://www.gstatic.com/ct/log_list/v3/log_list.json; required by --validate-scts, which it implies"`
+
+	// OCSPQuery queries the OCSP responder of the certificate.
+	OCSPQuery bool `long:"ocsp-query" description:"Query the OCSP responder of the certificate for its status, and record the response and the latency of the responder in ocsp.query"`
+
+	// PQ offers post-quantum hybrid key shares on a second connection, since the handshake library has no ML-KEM.
+	PQ       bool   `long:"pq" description:"Send a TLS 1.3 ClientHello with post-quantum hybrid key shares along classical ones on a new connection, and record the group the server selects in pq"`
+	PQOnly   bool   `long:"pq-only" description:"With --pq, offer only the post-quantum groups; implies --pq"`
+	PQGroups string `long:"pq-groups" default:"X25519MLKEM768,X25519Kyber768Draft00" description:"Comma-separated post-quantum groups to offer with --pq: X25519MLKEM768, SecP256r1MLKEM768 or X25519Kyber768Draft00"`
+
+	Heartbleed bool `long:"heartbleed" description:"Check for Heartbleed (CVE-2014-0160) on a new connection by sending a heartbeat request with a payload length exceeding its payload before the handshake completes, and record whether the server is vulnerable, patched or does not support heartbeats in heartbleed. Leaked memory is not read"`
+	Resumption bool `long:"resumption" description:"Attempt to resume sessions by session ID, TLS 1.2 ticket and TLS 1.3 ticket, each with a second handshake following a first on new connections, and record in resumption whether the server resumes them, the lifetime hint of TLS 1.2 tickets and whether resumed sessions get a new ticket"`
+	Enumerate  bool `long:"enumerate" description:"Enumerate the protocol versions, cipher suites and groups the server accepts, and its preference order, with a ClientHello on a new connection for each, and record them in enumeration"`
+	Downgrade  bool `long:"downgrade" description:"Attempt handshakes of each version below the highest of the server with TLS_FALLBACK_SCSV on new connections, and record in downgrade whether the server rejects them and whether a TLS 1.3 server puts the downgrade sentinel in the random of TLS 1.2 ServerHellos"`
+
+	// ALPNEnumerate offers the ALPN protocols one at a time, since servers select only one of those offered together.
+	ALPNEnumerate bool   `long:"alpn-enumerate" description:"Offer each of --alpn-protocols alone in a handshake on a new connection, and record which the server selects in alpn"`
+	ALPNProtocols string `long:"alpn-protocols" default:"h2,http/1.1,http/1.0,spdy/3.1,acme-tls/1,dot,imap,pop3,smtp,ftp,xmpp-client,xmpp-server,mqtt,postgresql,irc,nntp,managesieve,sunrpc,coap" description:"Comma-separated ALPN protocols to offer with --alpn-enumerate"`
 }
 
 type TLSModule struct {
@@ -19,6 +50,9 @@ type TLSModule struct {
 
 type TLSScanner struct {
 	config            *TLSFlags
+	ctLogs            *zgrab2.CTLogList
+	pqGroups          []uint16
+	alpnProtocols     []string
 	dialerGroupConfig *zgrab2.DialerGroupConfig
 }
 
@@ -44,6 +78,15 @@ func (m *TLSModule) Description() string {
 }
 
 func (f *TLSFlags) Validate(_ []string) error {
+	if f.ValidateSCTs && f.CTLogList == "" {
+		// there's no built-in list, since one would go stale between releases
+		return fmt.Errorf("--validate-scts requires --ct-log-list: %w", zgrab2.ErrInvalidArguments)
+	}
+	if f.ECHConfig != "" {
+		if _, err := base64.StdEncoding.DecodeString(f.ECHConfig); err != nil {
+			return fmt.Errorf("invalid --ech-config: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -63,6 +106,27 @@ func (s *TLSScanner) Init(flags zgrab2.ScanFlags) error {
 		TLSEnabled:                      true,
 		TLSFlags:                        &f.TLSFlags,
 	}
+	if f.ValidateSCTs || f.CTLogList != "" {
+		logs, err := zgrab2.LoadCTLogList(f.CTLogList)
+		if err != nil {
+			return fmt.Errorf("could not load the CT log list: %w", err)
+		}
+		s.ctLogs = logs
+	}
+	if f.PQ || f.PQOnly {
+		groups, err := zgrab2.ParsePQGroups(f.PQGroups)
+		if err != nil {
+			return err
+		}
+		s.pqGroups = groups
+	}
+	if f.ALPNEnumerate {
+		for _, protocol := range strings.Split(f.ALPNProtocols, ",") {
+			if protocol = strings.TrimSpace(protocol); protocol != "" {
+				s.alpnProtocols = append(s.alpnProtocols, protocol)
+			}
+		}
+	}
 	return nil
 }
 
@@ -107,7 +171,79 @@ func (s *TLSScanner) Scan(ctx context.Context, dialerGroup *zgrab2.DialerGroup,
 	if !ok {
 		return zgrab2.SCAN_UNKNOWN_ERROR, nil, errors.New("scan returned non-TLS connection")
 	}
-	return zgrab2.SCAN_SUCCESS, tlsConn.GetLog(), nil
+	tlsLog := tlsConn.GetLog()
+	if s.config.ECH {
+		tlsLog.ECH = s.probeECH(ctx, target)
+	}
+	if s.ctLogs != nil {
+		tlsLog.CT = tlsConn.ValidateSCTs(s.ctLogs)
+	}
+	if s.pqGroups != nil {
+		tlsLog.PQ = s.probePQ(ctx, target)
+	}
+	if s.config.Heartbleed {
+		tlsLog.Heartbleed = zgrab2.ProbeHeartbleed(ctx, s.dialTarget(target), s.serverName(target))
+	}
+	if s.config.Resumption {
+		tlsLog.Resumption = zgrab2.ProbeResumption(ctx, s.dialTarget(target), s.serverName(target))
+	}
+	if s.config.Enumerate {
+		tlsLog.Enumeration = zgrab2.ProbeEnumeration(ctx, s.dialTarget(target), s.serverName(target))
+	}
+	if s.config.Downgrade {
+		tlsLog.Downgrade = zgrab2.ProbeDowngrade(ctx, s.dialTarget(target), s.serverName(target))
+	}
+	if s.alpnProtocols != nil {
+		tlsLog.ALPN = zgrab2.ProbeALPN(ctx, s.dialTarget(target), s.serverName(target), s.alpnProtocols)
+	}
+	if ocspLog := s.getOCSPLog(ctx, target, tlsConn); ocspLog.Stapled != nil || ocspLog.Query != nil {
+		tlsLog.OCSP = ocspLog
+	}
+	return zgrab2.SCAN_SUCCESS, tlsLog, nil
+}
+
+// probeECH attempts an ECH handshake with the server name of the target, on a new connection.
+func (s *TLSScanner) probeECH(ctx context.Context, target *zgrab2.ScanTarget) *zgrab2.ECHResult {
+	serverName := s.serverName(target)
+	var configList []byte
+	if s.config.ECHConfig != "" {
+		// validated in Validate
+		configList, _ = base64.StdEncoding.DecodeString(s.config.ECHConfig)
+	}
+	return zgrab2.ProbeECH(ctx, s.dialTarget(target), serverName, configList, !s.config.ECHNoDNS)
+}
+
+// serverName returns the name of the target for the probes: --server-name, or else its domain.
+func (s *TLSScanner) serverName(target *zgrab2.ScanTarget) string {
+	if s.config.ServerName != "" {
+		return s.config.ServerName
+	}
+	return target.Domain
+}
+
+// dialTarget returns a function opening new TCP connections to the target, for the probes that need their own.
+func (s *TLSScanner) dialTarget(target *zgrab2.ScanTarget) func(context.Context) (net.Conn, error) {
+	return func(ctx context.Context) (net.Conn, error) {
+		address := net.JoinHostPort(target.Host(), strconv.Itoa(int(target.Port)))
+		return zgrab2.GetDefaultTCPDialer(&s.config.BaseFlags)(ctx, target, address)
+	}
+}
+
+// probePQ offers the post-quantum groups of --pq-groups to the target on a new connection.
+func (s *TLSScanner) probePQ(ctx context.Context, target *zgrab2.ScanTarget) *zgrab2.PQKeyShareResult {
+	return zgrab2.ProbePQKeyShare(ctx, s.dialTarget(target), s.serverName(target), s.pqGroups, s.config.PQOnly)
+}
+
+// getOCSPLog returns the stapled OCSP response, and queries the OCSP responder with --ocsp-query.
+func (s *TLSScanner) getOCSPLog(ctx context.Context, target *zgrab2.ScanTarget, tlsConn *zgrab2.TLSConnection) *zgrab2.OCSPLog {
+	ocspLog := &zgrab2.OCSPLog{Stapled: tlsConn.StapledOCSP()}
+	if s.config.OCSPQuery {
+		dial := func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return zgrab2.GetDefaultTCPDialer(&s.config.BaseFlags)(ctx, target, addr)
+		}
+		ocspLog.Query = tlsConn.QueryOCSP(ctx, dial)
+	}
+	return ocspLog
 }
 
 // Protocol returns the protocol identifer for the scanner.
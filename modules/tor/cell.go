@@ -0,0 +1,249 @@
+package tor
+
+import (
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Cell commands.
+const (
+	commandPadding       = 0
+	commandNetInfo       = 8
+	commandVersions      = 7
+	commandVPadding      = 128
+	commandCerts         = 129
+	commandAuthChallenge = 130
+)
+
+// fixedPayloadLength is the payload length of fixed-length cells.
+const fixedPayloadLength = 509
+
+// maxVariablePayloadLength bounds the payload of variable-length cells from the relay.
+const maxVariablePayloadLength = 1 << 14
+
+// linkVersions are the link protocol versions the scan offers. Versions 1 and 2 negotiate differently and are long
+// obsolete.
+var linkVersions = []uint16{3, 4, 5}
+
+var errInvalidCell = errors.New("invalid cell")
+
+var certTypeNames = map[byte]string{
+	1: "link_x509",
+	2: "rsa_identity_x509",
+	3: "rsa_auth_x509",
+	4: "ed25519_signing",
+	5: "ed25519_tls_link",
+	6: "ed25519_auth",
+	7: "rsa_ed25519_crosscert",
+}
+
+// Certificate types used to derive the relay's identities.
+const (
+	certRSAIdentity   = 2
+	certEd25519Signer = 4
+)
+
+// buildVersions returns the VERSIONS cell, which always uses two-byte circuit IDs.
+func buildVersions() []byte {
+	cell := []byte{0, 0, commandVersions}
+	cell = binary.BigEndian.AppendUint16(cell, uint16(2*len(linkVersions)))
+	for _, version := range linkVersions {
+		cell = binary.BigEndian.AppendUint16(cell, version)
+	}
+	return cell
+}
+
+// isVariableLength reports whether cells with the command have a length field (for link protocol 3 and later).
+func isVariableLength(command byte) bool {
+	return command == commandVersions || command >= 128
+}
+
+// cell is a received cell.
+type cell struct {
+	command byte
+	payload []byte
+}
+
+// readCell reads a cell with circuit IDs of the given length.
+func readCell(r io.Reader, circIDLength int) (*cell, error) {
+	header := make([]byte, circIDLength+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	command := header[circIDLength]
+	length := fixedPayloadLength
+	if isVariableLength(command) {
+		b := make([]byte, 2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		length = int(binary.BigEndian.Uint16(b))
+		if length > maxVariablePayloadLength {
+			return nil, errInvalidCell
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return &cell{command: command, payload: payload}, nil
+}
+
+// parseVersions decodes the payload of a VERSIONS cell.
+func parseVersions(payload []byte) ([]uint16, error) {
+	if len(payload)%2 != 0 {
+		return nil, errInvalidCell
+	}
+	var versions []uint16
+	for i := 0; i < len(payload); i += 2 {
+		versions = append(versions, binary.BigEndian.Uint16(payload[i:]))
+	}
+	return versions, nil
+}
+
+// negotiate returns the highest version both sides support, or 0.
+func negotiate(versions []uint16) uint16 {
+	var best uint16
+	for _, theirs := range versions {
+		for _, ours := range linkVersions {
+			if theirs == ours && theirs > best {
+				best = theirs
+			}
+		}
+	}
+	return best
+}
+
+// Certificate is an entry of the CERTS cell.
+type Certificate struct {
+	Type     byte   `json:"type"`
+	TypeName string `json:"type_name,omitempty"`
+	Raw      []byte `json:"raw"`
+}
+
+// parseCerts decodes the payload of a CERTS cell.
+func parseCerts(payload []byte) ([]Certificate, error) {
+	if len(payload) < 1 {
+		return nil, errInvalidCell
+	}
+	count := int(payload[0])
+	data := payload[1:]
+	var certs []Certificate
+	for i := 0; i < count; i++ {
+		if len(data) < 3 || len(data) < 3+int(binary.BigEndian.Uint16(data[1:3])) {
+			return certs, errInvalidCell
+		}
+		length := int(binary.BigEndian.Uint16(data[1:3]))
+		certs = append(certs, Certificate{Type: data[0], TypeName: certTypeNames[data[0]], Raw: data[3 : 3+length]})
+		data = data[3+length:]
+	}
+	return certs, nil
+}
+
+// rsaFingerprint returns the relay fingerprint from the RSA identity certificate: the upper-case hex SHA-1 of the
+// PKCS#1 encoding of the identity key.
+func rsaFingerprint(der []byte) string {
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return ""
+	}
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return ""
+	}
+	sum := sha1.Sum(x509.MarshalPKCS1PublicKey(key))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// ed25519Identity returns the relay's Ed25519 identity from its signing key certificate: the key in the
+// signed-with-ed25519-key extension, base64-encoded without padding as Tor prints it.
+func ed25519Identity(cert []byte) string {
+	// version, type, expiration, key type, certified key, extension count
+	if len(cert) < 40 || cert[0] != 1 {
+		return ""
+	}
+	extensions := int(cert[39])
+	data := cert[40:]
+	for i := 0; i < extensions; i++ {
+		if len(data) < 4 || len(data) < 4+int(binary.BigEndian.Uint16(data[0:2])) {
+			return ""
+		}
+		length := int(binary.BigEndian.Uint16(data[0:2]))
+		if data[2] == 4 && length == 32 {
+			return base64.RawStdEncoding.EncodeToString(data[4 : 4+length])
+		}
+		data = data[4+length:]
+	}
+	return ""
+}
+
+// parseAuthChallenge returns the authentication methods of an AUTH_CHALLENGE cell.
+func parseAuthChallenge(payload []byte) ([]uint16, error) {
+	if len(payload) < 34 || len(payload) < 34+2*int(binary.BigEndian.Uint16(payload[32:34])) {
+		return nil, errInvalidCell
+	}
+	var methods []uint16
+	for i := 0; i < int(binary.BigEndian.Uint16(payload[32:34])); i++ {
+		methods = append(methods, binary.BigEndian.Uint16(payload[34+2*i:]))
+	}
+	return methods, nil
+}
+
+// NetInfo is the relay's NETINFO cell.
+type NetInfo struct {
+	// Timestamp is the relay's current time.
+	Timestamp time.Time `json:"timestamp"`
+
+	// OtherAddress is the scanner's address as seen by the relay.
+	OtherAddress string `json:"other_address,omitempty"`
+
+	// MyAddresses are the relay's own addresses.
+	MyAddresses []string `json:"my_addresses,omitempty"`
+}
+
+// readAddress decodes an address of type IPv4 (4) or IPv6 (6) and returns it with the remaining data.
+func readAddress(data []byte) (string, []byte, bool) {
+	if len(data) < 2 || len(data) < 2+int(data[1]) {
+		return "", nil, false
+	}
+	value := data[2 : 2+data[1]]
+	rest := data[2+data[1]:]
+	if (data[0] == 4 && len(value) == net.IPv4len) || (data[0] == 6 && len(value) == net.IPv6len) {
+		return net.IP(value).String(), rest, true
+	}
+	return "", rest, true
+}
+
+// parseNetInfo decodes the payload of a NETINFO cell.
+func parseNetInfo(payload []byte) (*NetInfo, error) {
+	if len(payload) < 4 {
+		return nil, errInvalidCell
+	}
+	info := &NetInfo{Timestamp: time.Unix(int64(binary.BigEndian.Uint32(payload[0:4])), 0).UTC()}
+	other, rest, ok := readAddress(payload[4:])
+	if !ok || len(rest) < 1 {
+		return nil, errInvalidCell
+	}
+	info.OtherAddress = other
+	count := int(rest[0])
+	rest = rest[1:]
+	for i := 0; i < count; i++ {
+		var address string
+		if address, rest, ok = readAddress(rest); !ok {
+			return info, errInvalidCell
+		}
+		if address != "" {
+			info.MyAddresses = append(info.MyAddresses, address)
+		}
+	}
+	return info, nil
+}
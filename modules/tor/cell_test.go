@@ -0,0 +1,76 @@
+package tor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestVersions(t *testing.T) {
+	c, err := readCell(bytes.NewReader(buildVersions()), 2)
+	if err != nil || c.command != commandVersions {
+		t.Fatalf("unexpected cell %+v (%v)", c, err)
+	}
+	versions, err := parseVersions(c.payload)
+	if err != nil || !reflect.DeepEqual(versions, linkVersions) {
+		t.Errorf("got %v (%v)", versions, err)
+	}
+	if v := negotiate([]uint16{1, 2, 3, 4}); v != 4 {
+		t.Errorf("negotiated %d", v)
+	}
+	if v := negotiate([]uint16{1, 2}); v != 0 {
+		t.Errorf("negotiated %d", v)
+	}
+}
+
+func TestCerts(t *testing.T) {
+	identity := bytes.Repeat([]byte{0xAB}, 32)
+	// Ed25519 signing key certificate with a signed-with-ed25519-key extension
+	signer := []byte{1, 4, 0, 0, 0, 0, 1}
+	signer = append(signer, make([]byte, 32)...)
+	signer = append(signer, 1, 0, 32, 4, 0)
+	signer = append(signer, identity...)
+	signer = append(signer, make([]byte, 64)...)
+
+	payload := []byte{2, 1, 0, 3, 'd', 'e', 'r', certEd25519Signer}
+	payload = binary.BigEndian.AppendUint16(payload, uint16(len(signer)))
+	payload = append(payload, signer...)
+	certs, err := parseCerts(payload)
+	if err != nil || len(certs) != 2 {
+		t.Fatalf("got %+v (%v)", certs, err)
+	}
+	if certs[0].TypeName != "link_x509" || string(certs[0].Raw) != "der" {
+		t.Errorf("unexpected certificate %+v", certs[0])
+	}
+	if id := ed25519Identity(certs[1].Raw); id != "q6urq6urq6urq6urq6urq6urq6urq6urq6urq6urq6s" {
+		t.Errorf("got identity %s", id)
+	}
+	if _, err = parseCerts(payload[:len(payload)-1]); err == nil {
+		t.Error("expected error for truncated CERTS")
+	}
+}
+
+func TestNetInfo(t *testing.T) {
+	payload := make([]byte, fixedPayloadLength)
+	binary.BigEndian.PutUint32(payload, 1700000000)
+	copy(payload[4:], []byte{4, 4, 192, 0, 2, 1, 1, 4, 4, 198, 51, 100, 7})
+	cell := append([]byte{0, 0, 0, 0, commandNetInfo}, payload...)
+	c, err := readCell(bytes.NewReader(cell), 4)
+	if err != nil || c.command != commandNetInfo {
+		t.Fatalf("unexpected cell %+v (%v)", c, err)
+	}
+	info, err := parseNetInfo(c.payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &NetInfo{
+		Timestamp:    time.Unix(1700000000, 0).UTC(),
+		OtherAddress: "192.0.2.1",
+		MyAddresses:  []string{"198.51.100.7"},
+	}
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("got %+v, expected %+v", info, expected)
+	}
+}
@@ -0,0 +1,213 @@
+// Package tor contains the zgrab2 Module implementation for the Tor OR (onion router) protocol.
+//
+// The scan performs the responder side of the Tor link handshake over TLS: it sends a VERSIONS cell and reads the
+// relay's VERSIONS, CERTS, AUTH_CHALLENGE and NETINFO cells. A relay is recognized by a valid VERSIONS answer and
+// CERTS cell, which other TLS services on the same ports don't send. The RSA identity certificate gives the relay
+// fingerprint as listed in the consensus.
+package tor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// maxCells bounds the number of cells read after the VERSIONS cell.
+const maxCells = 8
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// IsRelay is true if the server completed the responder side of the link handshake.
+	IsRelay bool `json:"is_relay"`
+
+	LinkVersions      []uint16 `json:"link_versions,omitempty"`
+	NegotiatedVersion uint16   `json:"negotiated_version,omitempty"`
+
+	Certificates []Certificate `json:"certificates,omitempty"`
+
+	// Fingerprint is the relay's RSA identity fingerprint.
+	Fingerprint string `json:"fingerprint,omitempty"`
+
+	// Ed25519Identity is the relay's Ed25519 identity key.
+	Ed25519Identity string `json:"ed25519_identity,omitempty"`
+
+	AuthMethods []uint16 `json:"auth_methods,omitempty"`
+	NetInfo     *NetInfo `json:"netinfo,omitempty"`
+
+	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
+}
+
+// Flags are the Tor-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.TLSFlags
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the tor zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("tor", "Tor", module.Description(), 9001, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Perform the Tor link handshake and record the relay's versions, certificates and NETINFO"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "tor"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+		TLSEnabled:                      true,
+		TLSFlags:                        &f.TLSFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// Scan performs the configured scan on the Tor relay.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	results := new(ScanResults)
+	conn, err := dialGroup.Dial(ctx, target)
+	if tlsConn, ok := conn.(*zgrab2.TLSConnection); ok {
+		results.TLSLog = tlsConn.GetLog()
+	}
+	if err != nil {
+		var partial any
+		if results.TLSLog != nil {
+			partial = results
+		}
+		return zgrab2.TryGetScanStatus(err), partial, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	if _, err = conn.Write(buildVersions()); err != nil {
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error sending VERSIONS to target %s: %w", target.String(), err)
+	}
+	versions, err := readCell(conn, 2)
+	if err == nil && versions.command != commandVersions {
+		err = errInvalidCell
+	}
+	if err == nil {
+		results.LinkVersions, err = parseVersions(versions.payload)
+	}
+	if err != nil {
+		if errors.Is(err, errInvalidCell) {
+			err = zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+		}
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error reading VERSIONS from target %s: %w", target.String(), err)
+	}
+	if results.NegotiatedVersion = negotiate(results.LinkVersions); results.NegotiatedVersion == 0 {
+		return zgrab2.SCAN_APPLICATION_ERROR, results, fmt.Errorf("target %s supports none of the offered link versions", target.String())
+	}
+
+	circIDLength := 2
+	if results.NegotiatedVersion >= 4 {
+		circIDLength = 4
+	}
+	for i := 0; i < maxCells && results.NetInfo == nil; i++ {
+		c, err := readCell(conn, circIDLength)
+		if err != nil {
+			if errors.Is(err, errInvalidCell) {
+				err = zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+			}
+			return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error reading cell from target %s: %w", target.String(), err)
+		}
+		switch c.command {
+		case commandCerts:
+			if results.Certificates, err = parseCerts(c.payload); err != nil {
+				return zgrab2.SCAN_PROTOCOL_ERROR, results, zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+			}
+			results.IsRelay = true
+			for _, cert := range results.Certificates {
+				switch cert.Type {
+				case certRSAIdentity:
+					results.Fingerprint = rsaFingerprint(cert.Raw)
+				case certEd25519Signer:
+					results.Ed25519Identity = ed25519Identity(cert.Raw)
+				}
+			}
+		case commandAuthChallenge:
+			results.AuthMethods, _ = parseAuthChallenge(c.payload)
+		case commandNetInfo:
+			if results.NetInfo, err = parseNetInfo(c.payload); err != nil {
+				log.Debugf("invalid NETINFO from target %s: %v", target.String(), err)
+				return zgrab2.SCAN_SUCCESS, results, nil
+			}
+		case commandPadding, commandVPadding:
+		default:
+			return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("unexpected cell command %d from target %s", c.command, target.String())
+		}
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
@@ -0,0 +1,146 @@
+package vnc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// Security types, see RFC 6143 section 7.1.2 and the IANA RFB registry
+const (
+	SecurityInvalid uint8 = 0
+	SecurityNone    uint8 = 1
+	SecurityVNCAuth uint8 = 2
+)
+
+var securityTypeNames = map[uint8]string{
+	0:   "invalid",
+	1:   "none",
+	2:   "vnc_auth",
+	5:   "ra2",
+	6:   "ra2ne",
+	16:  "tight",
+	17:  "ultra",
+	18:  "tls",
+	19:  "vencrypt",
+	20:  "gtk_vnc_sasl",
+	21:  "md5_hash",
+	22:  "colin_dean_xvp",
+	30:  "apple_ard",
+	113: "ultra_mslogon_ii",
+}
+
+// SecurityType is a security type offered by the server.
+type SecurityType struct {
+	Value uint8  `json:"value"`
+	Name  string `json:"name,omitempty"`
+}
+
+func newSecurityType(value uint8) SecurityType {
+	name, ok := securityTypeNames[value]
+	if !ok {
+		name = "unknown"
+	}
+	return SecurityType{Value: value, Name: name}
+}
+
+// PixelFormat is the server's native pixel format, see RFC 6143 section 7.4
+type PixelFormat struct {
+	BitsPerPixel uint8  `json:"bits_per_pixel"`
+	Depth        uint8  `json:"depth"`
+	BigEndian    bool   `json:"big_endian"`
+	TrueColour   bool   `json:"true_colour"`
+	RedMax       uint16 `json:"red_max"`
+	GreenMax     uint16 `json:"green_max"`
+	BlueMax      uint16 `json:"blue_max"`
+	RedShift     uint8  `json:"red_shift"`
+	GreenShift   uint8  `json:"green_shift"`
+	BlueShift    uint8  `json:"blue_shift"`
+}
+
+// ServerInit contains the desktop parameters the server sends after a successful (unauthenticated) handshake.
+type ServerInit struct {
+	Width       uint16       `json:"width"`
+	Height      uint16       `json:"height"`
+	PixelFormat *PixelFormat `json:"pixel_format,omitempty"`
+	Name        string       `json:"name"`
+}
+
+// maxReasonLength bounds failure reasons and desktop names read from the server.
+const maxReasonLength = 4096
+
+var errInvalidVersion = errors.New("invalid RFB protocol version")
+
+var versionRegex = regexp.MustCompile(`^RFB (\d{3})\.(\d{3})\n$`)
+
+// parseVersion parses a ProtocolVersion message such as "RFB 003.008\n".
+func parseVersion(raw []byte) (major, minor int, err error) {
+	match := versionRegex.FindSubmatch(raw)
+	if match == nil {
+		return 0, 0, errInvalidVersion
+	}
+	major, _ = strconv.Atoi(string(match[1]))
+	minor, _ = strconv.Atoi(string(match[2]))
+	return major, minor, nil
+}
+
+// clientVersion returns the version the client answers with: the highest of 3.3, 3.7 and 3.8 not above the
+// server's. Nonstandard minor versions (e.g. Apple's 3.889) are treated as 3.8.
+func clientVersion(major, minor int) (int, []byte) {
+	switch {
+	case major > 3 || minor >= 8:
+		return 8, []byte("RFB 003.008\n")
+	case minor == 7:
+		return 7, []byte("RFB 003.007\n")
+	default:
+		return 3, []byte("RFB 003.003\n")
+	}
+}
+
+// readString reads a uint32 length-prefixed string.
+func readString(r io.Reader) (string, error) {
+	var length uint32
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return "", err
+	}
+	if length > maxReasonLength {
+		return "", fmt.Errorf("string length %d exceeds maximum", length)
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// readServerInit reads a ServerInit message.
+func readServerInit(r io.Reader) (*ServerInit, error) {
+	header := make([]byte, 20)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	name, err := readString(r)
+	if err != nil {
+		return nil, err
+	}
+	return &ServerInit{
+		Width:  binary.BigEndian.Uint16(header[0:2]),
+		Height: binary.BigEndian.Uint16(header[2:4]),
+		PixelFormat: &PixelFormat{
+			BitsPerPixel: header[4],
+			Depth:        header[5],
+			BigEndian:    header[6] != 0,
+			TrueColour:   header[7] != 0,
+			RedMax:       binary.BigEndian.Uint16(header[8:10]),
+			GreenMax:     binary.BigEndian.Uint16(header[10:12]),
+			BlueMax:      binary.BigEndian.Uint16(header[12:14]),
+			RedShift:     header[14],
+			GreenShift:   header[15],
+			BlueShift:    header[16],
+		},
+		Name: name,
+	}, nil
+}
@@ -0,0 +1,244 @@
+// Package vnc contains the zgrab2 Module implementation for VNC (RFB, RFC 6143).
+//
+// The scan reads the server's protocol version and offered security types. Only if the server offers the "None"
+// security type does the scan continue to ClientInit to read the desktop parameters; authentication is never attempted.
+package vnc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// ProtocolVersion is the raw ProtocolVersion message sent by the server, e.g. "RFB 003.008".
+	ProtocolVersion string `json:"protocol_version,omitempty"`
+
+	// SecurityTypes are the security types offered by the server.
+	SecurityTypes []SecurityType `json:"security_types,omitempty"`
+
+	// ConnectionFailedReason is the reason the server gave if it offered no security types.
+	ConnectionFailedReason string `json:"connection_failed_reason,omitempty"`
+
+	// NoAuth is true if the server accepted the "None" security type.
+	NoAuth bool `json:"no_auth"`
+
+	// SecurityFailureReason is the reason the server gave for rejecting the "None" security type.
+	SecurityFailureReason string `json:"security_failure_reason,omitempty"`
+
+	// Desktop contains the ServerInit parameters, only set if NoAuth is true.
+	Desktop *ServerInit `json:"desktop,omitempty"`
+
+	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
+}
+
+// Flags are the VNC-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.TLSFlags
+
+	UseTLS       bool `long:"tls" description:"Wrap the connection in TLS"`
+	NoServerInit bool `long:"no-server-init" description:"Do not send ClientInit to read the desktop parameters when no authentication is required"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the vnc zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("vnc", "Virtual Network Computing (VNC/RFB)", module.Description(), 5900, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Grab the RFB version, security types and, if unauthenticated, the desktop parameters of a VNC server"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "vnc"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+		TLSEnabled:                      f.UseTLS,
+		TLSFlags:                        &f.TLSFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// handshake runs the RFB handshake up to (and, if no authentication is required, including) ServerInit.
+func (scanner *Scanner) handshake(conn net.Conn, results *ScanResults) error {
+	raw := make([]byte, 12)
+	if _, err := io.ReadFull(conn, raw); err != nil {
+		return err
+	}
+	major, minor, err := parseVersion(raw)
+	if err != nil {
+		return err
+	}
+	results.ProtocolVersion = string(raw[:11])
+
+	version, reply := clientVersion(major, minor)
+	if _, err = conn.Write(reply); err != nil {
+		return err
+	}
+
+	if version == 3 {
+		// the server decides on the security type
+		var securityType uint32
+		if err = binary.Read(conn, binary.BigEndian, &securityType); err != nil {
+			return err
+		}
+		if securityType == uint32(SecurityInvalid) {
+			results.ConnectionFailedReason, err = readString(conn)
+			return err
+		}
+		results.SecurityTypes = []SecurityType{newSecurityType(uint8(securityType))}
+		if securityType != uint32(SecurityNone) {
+			return nil
+		}
+	} else {
+		count := make([]byte, 1)
+		if _, err = io.ReadFull(conn, count); err != nil {
+			return err
+		}
+		if count[0] == 0 {
+			results.ConnectionFailedReason, err = readString(conn)
+			return err
+		}
+		types := make([]byte, count[0])
+		if _, err = io.ReadFull(conn, types); err != nil {
+			return err
+		}
+		offersNone := false
+		for _, t := range types {
+			results.SecurityTypes = append(results.SecurityTypes, newSecurityType(t))
+			offersNone = offersNone || t == SecurityNone
+		}
+		if !offersNone {
+			return nil
+		}
+		if _, err = conn.Write([]byte{SecurityNone}); err != nil {
+			return err
+		}
+	}
+
+	// RFB 3.8 always sends a SecurityResult; 3.3 and 3.7 omit it for the None type
+	if version == 8 {
+		var status uint32
+		if err = binary.Read(conn, binary.BigEndian, &status); err != nil {
+			return err
+		}
+		if status != 0 {
+			results.SecurityFailureReason, err = readString(conn)
+			return err
+		}
+	}
+	results.NoAuth = true
+	if scanner.config.NoServerInit {
+		return nil
+	}
+
+	// ClientInit with shared-flag set, so that other clients are not disconnected
+	if _, err = conn.Write([]byte{1}); err != nil {
+		return err
+	}
+	results.Desktop, err = readServerInit(conn)
+	return err
+}
+
+// Scan performs the configured scan on the VNC server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	results := new(ScanResults)
+	if tlsConn, ok := conn.(*zgrab2.TLSConnection); ok {
+		results.TLSLog = tlsConn.GetLog()
+	}
+
+	if err = scanner.handshake(conn, results); err != nil {
+		if errors.Is(err, errInvalidVersion) {
+			return zgrab2.SCAN_PROTOCOL_ERROR, nil, err
+		}
+		if results.ProtocolVersion == "" {
+			return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error reading RFB version from target %s: %w", target.String(), err)
+		}
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error during RFB handshake with target %s: %w", target.String(), err)
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
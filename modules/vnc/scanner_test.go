@@ -0,0 +1,126 @@
+package vnc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/zmap/zgrab2"
+)
+
+// rfbString encodes s with a uint32 length.
+func rfbString(s string) []byte {
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(s))), s...)
+}
+
+// scan runs the scanner against a fake server that serves a single connection with serve.
+func scan(t *testing.T, flags *Flags, serve func(server net.Conn)) (zgrab2.ScanStatus, *ScanResults, error) {
+	t.Helper()
+	dialGroup := &zgrab2.DialerGroup{TransportAgnosticDialer: func(ctx context.Context, target *zgrab2.ScanTarget) (net.Conn, error) {
+		client, server := net.Pipe()
+		go func() {
+			defer server.Close()
+			serve(server)
+		}()
+		return client, nil
+	}}
+	scanner := &Scanner{config: flags}
+	status, res, err := scanner.Scan(context.Background(), dialGroup, &zgrab2.ScanTarget{Port: 5900})
+	results, _ := res.(*ScanResults)
+	return status, results, err
+}
+
+// expect reads len(expected) bytes from the client, reporting whether they match.
+func expect(server net.Conn, expected []byte) bool {
+	buf := make([]byte, len(expected))
+	_, err := io.ReadFull(server, buf)
+	return err == nil && bytes.Equal(buf, expected)
+}
+
+func TestClientVersion(t *testing.T) {
+	for _, test := range []struct {
+		major, minor int
+		expected     string
+	}{{3, 3, "RFB 003.003\n"}, {3, 5, "RFB 003.003\n"}, {3, 7, "RFB 003.007\n"}, {3, 8, "RFB 003.008\n"}, {3, 889, "RFB 003.008\n"}, {4, 0, "RFB 003.008\n"}} {
+		if _, reply := clientVersion(test.major, test.minor); string(reply) != test.expected {
+			t.Errorf("%d.%d: client version %q, expected %q", test.major, test.minor, reply, test.expected)
+		}
+	}
+}
+
+func TestScanNoAuth(t *testing.T) {
+	serverInit := []byte{0x04, 0x00, 0x03, 0x00, 32, 24, 0, 1, 0, 255, 0, 255, 0, 255, 16, 8, 0, 0, 0, 0}
+	serverInit = append(serverInit, rfbString("desktop")...)
+	status, results, err := scan(t, new(Flags), func(server net.Conn) {
+		server.Write([]byte("RFB 003.008\n"))
+		if !expect(server, []byte("RFB 003.008\n")) {
+			return
+		}
+		server.Write([]byte{2, SecurityVNCAuth, SecurityNone})
+		if !expect(server, []byte{SecurityNone}) {
+			return
+		}
+		server.Write([]byte{0, 0, 0, 0})
+		// ClientInit must ask for a shared session
+		if !expect(server, []byte{1}) {
+			return
+		}
+		server.Write(serverInit)
+	})
+	if err != nil || status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status = %s, err = %v", status, err)
+	}
+	if results.ProtocolVersion != "RFB 003.008" || !results.NoAuth || len(results.SecurityTypes) != 2 || results.SecurityTypes[1].Name != "none" {
+		t.Errorf("results = %+v", results)
+	}
+	expectedFormat := PixelFormat{BitsPerPixel: 32, Depth: 24, TrueColour: true, RedMax: 255, GreenMax: 255, BlueMax: 255, RedShift: 16, GreenShift: 8}
+	if desktop := results.Desktop; desktop == nil || desktop.Width != 1024 || desktop.Height != 768 || desktop.Name != "desktop" || *desktop.PixelFormat != expectedFormat {
+		t.Errorf("desktop = %+v", results.Desktop)
+	}
+}
+
+func TestScanAuthRequired(t *testing.T) {
+	status, results, err := scan(t, new(Flags), func(server net.Conn) {
+		server.Write([]byte("RFB 003.008\n"))
+		if !expect(server, []byte("RFB 003.008\n")) {
+			return
+		}
+		server.Write([]byte{2, SecurityVNCAuth, 18})
+		// the client must not pick a security type it can't complete
+		io.Copy(io.Discard, server)
+	})
+	if err != nil || status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status = %s, err = %v", status, err)
+	}
+	if results.NoAuth || results.Desktop != nil || len(results.SecurityTypes) != 2 || results.SecurityTypes[0].Name != "vnc_auth" || results.SecurityTypes[1].Name != "tls" {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestScanVersion33(t *testing.T) {
+	status, results, err := scan(t, new(Flags), func(server net.Conn) {
+		server.Write([]byte("RFB 003.003\n"))
+		if !expect(server, []byte("RFB 003.003\n")) {
+			return
+		}
+		server.Write(append([]byte{0, 0, 0, SecurityInvalid}, rfbString("too many connections")...))
+	})
+	if err != nil || status != zgrab2.SCAN_SUCCESS {
+		t.Fatalf("status = %s, err = %v", status, err)
+	}
+	if results.ProtocolVersion != "RFB 003.003" || results.ConnectionFailedReason != "too many connections" || results.NoAuth {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestScanNotRFB(t *testing.T) {
+	status, _, err := scan(t, new(Flags), func(server net.Conn) {
+		server.Write([]byte("SSH-2.0-Open"))
+	})
+	if err == nil || status != zgrab2.SCAN_PROTOCOL_ERROR {
+		t.Errorf("status = %s, err = %v", status, err)
+	}
+}
@@ -0,0 +1,188 @@
+// Package whois contains the zgrab2 Module implementation for WHOIS (RFC 3912).
+//
+// The scan sends a query line and reads the response until the server closes the connection. It also extracts the
+// server the response refers the query to, from the referral fields used by IANA (refer:), the gTLD registries
+// (Registrar WHOIS Server:) and ARIN (ReferralServer:).
+package whois
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// referralFields are the response fields that name another WHOIS server, lowercased.
+var referralFields = []string{"refer", "whois", "registrar whois server", "referralserver"}
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	Query    string `json:"query"`
+	Response string `json:"response,omitempty"`
+
+	// Truncated is true if the response was longer than --max-size.
+	Truncated bool `json:"truncated,omitempty"`
+
+	// Referral is the WHOIS server the response refers to, e.g. whois.verisign-grs.com.
+	Referral string `json:"referral,omitempty"`
+
+	// ReferralField is the field the referral was found in.
+	ReferralField string `json:"referral_field,omitempty"`
+}
+
+// Flags are the WHOIS-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+
+	Query   string `long:"query" description:"Query to send. Defaults to the target's domain, or its IP"`
+	MaxSize int    `long:"max-size" default:"64" description:"Max kilobytes of the response to read"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the whois zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("whois", "WHOIS", module.Description(), 43, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Send a WHOIS query and record the response and the server it refers to"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	if f.MaxSize <= 0 {
+		return fmt.Errorf("max-size must be positive, given %d", f.MaxSize)
+	}
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "whois"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// findReferral returns the first referral field of a response and its value, without any whois:// scheme or
+// trailing port.
+func findReferral(response string) (field string, server string) {
+	for _, line := range strings.Split(response, "\n") {
+		name, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+		for _, candidate := range referralFields {
+			if name != candidate || value == "" {
+				continue
+			}
+			value = strings.TrimPrefix(strings.TrimPrefix(value, "rwhois://"), "whois://")
+			if host, port, ok := strings.Cut(value, ":"); ok && port != "" && !strings.Contains(port, ":") {
+				value = host
+			}
+			return strings.TrimSpace(line[:strings.Index(line, ":")]), strings.TrimSuffix(value, "/")
+		}
+	}
+	return "", ""
+}
+
+// Scan performs the configured scan on the WHOIS server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	results := &ScanResults{Query: scanner.config.Query}
+	if results.Query == "" {
+		results.Query = target.Domain
+		if results.Query == "" {
+			results.Query = target.Host()
+		}
+	}
+	if _, err = conn.Write([]byte(results.Query + "\r\n")); err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error sending query to target %s: %w", target.String(), err)
+	}
+	maxSize := int64(scanner.config.MaxSize) * 1024
+	response, err := io.ReadAll(io.LimitReader(conn, maxSize+1))
+	if err != nil && len(response) == 0 {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error reading response from target %s: %w", target.String(), err)
+	}
+	if int64(len(response)) > maxSize {
+		response = response[:maxSize]
+		results.Truncated = true
+	}
+	results.Response = string(response)
+	results.ReferralField, results.Referral = findReferral(results.Response)
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
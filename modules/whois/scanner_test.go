@@ -0,0 +1,22 @@
+package whois
+
+import "testing"
+
+func TestFindReferral(t *testing.T) {
+	tests := []struct {
+		response string
+		field    string
+		server   string
+	}{
+		{"% IANA WHOIS server\n\ndomain:       COM\n\nrefer:        whois.verisign-grs.com\n", "refer", "whois.verisign-grs.com"},
+		{"   Domain Name: EXAMPLE.COM\r\n   Registrar WHOIS Server: whois.iana.org\r\n", "Registrar WHOIS Server", "whois.iana.org"},
+		{"NetRange: 192.0.2.0 - 192.0.2.255\nReferralServer:  rwhois://rwhois.example.net:4321/\n", "ReferralServer", "rwhois.example.net"},
+		{"No match for \"EXAMPLE.INVALID\".\n", "", ""},
+	}
+	for _, test := range tests {
+		field, server := findReferral(test.response)
+		if field != test.field || server != test.server {
+			t.Errorf("findReferral(%q) = %q, %q; expected %q, %q", test.response, field, server, test.field, test.server)
+		}
+	}
+}
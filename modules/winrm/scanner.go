@@ -0,0 +1,228 @@
+// Package winrm contains the zgrab2 Module implementation for Windows Remote Management (WS-Management).
+//
+// The scan posts a WS-Man Identify request with the WSMANIDENTIFY: unauthenticated header, which Windows answers
+// without credentials with the protocol and product versions. It then posts the request without the header to collect
+// the authentication schemes the listener offers in its 401 response.
+package winrm
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+	"github.com/zmap/zgrab2/lib/http"
+	"github.com/zmap/zgrab2/modules/httpapi"
+)
+
+// identifyRequest is the SOAP envelope of a WS-Man Identify request.
+const identifyRequest = `<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" ` +
+	`xmlns:wsmid="http://schemas.dmtf.org/wbem/wsman/identity/1/wsmanidentity.xsd">` +
+	`<s:Header/><s:Body><wsmid:Identify/></s:Body></s:Envelope>`
+
+const soapContentType = "application/soap+xml;charset=UTF-8"
+
+// identifyEnvelope is the SOAP envelope of an IdentifyResponse.
+type identifyEnvelope struct {
+	Body struct {
+		IdentifyResponse *struct {
+			ProtocolVersion  string   `xml:"ProtocolVersion"`
+			ProductVendor    string   `xml:"ProductVendor"`
+			ProductVersion   string   `xml:"ProductVersion"`
+			SecurityProfiles []string `xml:"SecurityProfiles>SecurityProfileName"`
+		} `xml:"IdentifyResponse"`
+	} `xml:"Body"`
+}
+
+// productVersionPattern matches Windows product versions, e.g. "OS: 10.0.17763 SP: 0.0 Stack: 3.0".
+var productVersionPattern = regexp.MustCompile(`OS: (\S+) SP: (\S+) Stack: (\S+)`)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// AnonymousIdentify is true if the listener answered the Identify request without credentials.
+	AnonymousIdentify bool `json:"anonymous_identify"`
+
+	ProtocolVersion  string   `json:"protocol_version,omitempty"`
+	ProductVendor    string   `json:"product_vendor,omitempty"`
+	ProductVersion   string   `json:"product_version,omitempty"`
+	SecurityProfiles []string `json:"security_profiles,omitempty"`
+
+	// OSVersion and StackVersion are parsed from a Windows product version. Windows reports OS version 0.0.0 unless
+	// the request is authenticated.
+	OSVersion    string `json:"os_version,omitempty"`
+	StackVersion string `json:"stack_version,omitempty"`
+
+	// AuthSchemes are the schemes offered in the WWW-Authenticate headers, e.g. Negotiate, Kerberos or Basic.
+	AuthSchemes []string `json:"auth_schemes,omitempty"`
+
+	IdentifyResponse *httpapi.Response `json:"identify_response,omitempty"`
+	AuthResponse     *httpapi.Response `json:"auth_response,omitempty"`
+
+	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
+}
+
+// Flags are the WinRM-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.TLSFlags
+	httpapi.Flags
+
+	Path string `long:"path" default:"/wsman" description:"Path of the WS-Management endpoint"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the winrm zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("winrm", "Windows Remote Management (WinRM)", module.Description(), 5985, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Send a WS-Man Identify request and record the product version and authentication schemes (use --use-https for port 5986)"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "winrm"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = httpapi.NewDialerGroupConfig(&f.BaseFlags, &f.TLSFlags)
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// authSchemes returns the schemes of the WWW-Authenticate headers of resp.
+func authSchemes(resp *httpapi.Response) []string {
+	var schemes []string
+	for _, challenge := range resp.Values("WWW-Authenticate") {
+		if fields := strings.Fields(challenge); len(fields) > 0 {
+			schemes = append(schemes, strings.TrimSuffix(fields[0], ","))
+		}
+	}
+	return schemes
+}
+
+// readIdentify records the contents of an IdentifyResponse.
+func readIdentify(body string, results *ScanResults) error {
+	var envelope identifyEnvelope
+	if err := xml.Unmarshal([]byte(body), &envelope); err != nil {
+		return err
+	}
+	identify := envelope.Body.IdentifyResponse
+	if identify == nil {
+		return fmt.Errorf("no IdentifyResponse in body")
+	}
+	results.ProtocolVersion = identify.ProtocolVersion
+	results.ProductVendor = identify.ProductVendor
+	results.ProductVersion = identify.ProductVersion
+	results.SecurityProfiles = identify.SecurityProfiles
+	if match := productVersionPattern.FindStringSubmatch(identify.ProductVersion); match != nil {
+		results.OSVersion = match[1]
+		results.StackVersion = match[3]
+	}
+	return nil
+}
+
+// Scan performs the configured scan on the WinRM listener.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	client := httpapi.NewClient(ctx, dialGroup, target, &scanner.config.Flags)
+	client.Header = http.Header{"Wsmanidentify": {"unauthenticated"}}
+	results := new(ScanResults)
+
+	var err error
+	results.IdentifyResponse, err = client.Post(scanner.config.Path, soapContentType, []byte(identifyRequest))
+	results.TLSLog = client.TLSLog
+	if err != nil {
+		if results.TLSLog == nil {
+			return zgrab2.TryGetScanStatus(err), nil, err
+		}
+		return zgrab2.TryGetScanStatus(err), results, err
+	}
+	resp := results.IdentifyResponse
+	if resp.Success() {
+		if err = readIdentify(resp.Body, results); err != nil {
+			return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("invalid Identify response from target %s: %w", target.String(), err)
+		}
+		results.AnonymousIdentify = true
+	} else if resp.StatusCode != http.StatusUnauthorized {
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("target %s is not a WinRM listener (status %d)", target.String(), resp.StatusCode)
+	}
+
+	authResponse := resp
+	if resp.StatusCode != http.StatusUnauthorized {
+		client.Header = nil
+		if results.AuthResponse, err = client.Post(scanner.config.Path, soapContentType, []byte(identifyRequest)); err != nil {
+			log.Debugf("authentication probe to target %s failed: %v", target.String(), err)
+			return zgrab2.SCAN_SUCCESS, results, nil
+		}
+		authResponse = results.AuthResponse
+	}
+	results.AuthSchemes = authSchemes(authResponse)
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
@@ -0,0 +1,34 @@
+package winrm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReadIdentify(t *testing.T) {
+	body := `<s:Envelope xml:lang="en-US" xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Header/><s:Body>` +
+		`<wsmid:IdentifyResponse xmlns:wsmid="http://schemas.dmtf.org/wbem/wsman/identity/1/wsmanidentity.xsd">` +
+		`<wsmid:ProtocolVersion>http://schemas.dmtf.org/wbem/wsman/1/wsman.xsd</wsmid:ProtocolVersion>` +
+		`<wsmid:ProductVendor>Microsoft Corporation</wsmid:ProductVendor>` +
+		`<wsmid:ProductVersion>OS: 0.0.0 SP: 0.0 Stack: 3.0</wsmid:ProductVersion>` +
+		`<wsmid:SecurityProfiles><wsmid:SecurityProfileName>http://schemas.dmtf.org/wbem/wsman/1/wsman/secprofile/http/spnego-kerberos</wsmid:SecurityProfileName></wsmid:SecurityProfiles>` +
+		`</wsmid:IdentifyResponse></s:Body></s:Envelope>`
+	results := new(ScanResults)
+	if err := readIdentify(body, results); err != nil {
+		t.Fatal(err)
+	}
+	expected := &ScanResults{
+		ProtocolVersion:  "http://schemas.dmtf.org/wbem/wsman/1/wsman.xsd",
+		ProductVendor:    "Microsoft Corporation",
+		ProductVersion:   "OS: 0.0.0 SP: 0.0 Stack: 3.0",
+		SecurityProfiles: []string{"http://schemas.dmtf.org/wbem/wsman/1/wsman/secprofile/http/spnego-kerberos"},
+		OSVersion:        "0.0.0",
+		StackVersion:     "3.0",
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("got %+v", results)
+	}
+	if err := readIdentify(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body/></s:Envelope>`, new(ScanResults)); err == nil {
+		t.Error("expected error for an envelope without IdentifyResponse")
+	}
+}
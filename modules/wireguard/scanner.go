@@ -0,0 +1,190 @@
+// Package wireguard contains the zgrab2 Module implementation for WireGuard.
+//
+// WireGuard is silent by design: a responder drops any handshake initiation whose mac1 was not computed for its
+// public key, and only sends a cookie reply to a valid initiation while under load. The scan therefore sends an
+// initiation from a random key and classifies whatever comes back. No response is consistent with WireGuard (and
+// with a filtered port); an ICMP port unreachable or a non-WireGuard reply rules it out. When the server's public
+// key is known, --public-key makes the initiation pass the mac1 check.
+package wireguard
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// Response types.
+const (
+	// ResponseNone means nothing was received, as expected from a WireGuard responder.
+	ResponseNone = "none"
+
+	// ResponseUnreachable means the port answered with an ICMP unreachable, so no responder is listening.
+	ResponseUnreachable = "unreachable"
+
+	// ResponseCookieReply means the responder is under load and asked for a cookie round trip.
+	ResponseCookieReply = "cookie_reply"
+
+	// ResponseHandshakeResponse means the responder answered the handshake.
+	ResponseHandshakeResponse = "handshake_response"
+
+	// ResponseUnexpected means something other than WireGuard answered.
+	ResponseUnexpected = "unexpected"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// Response classifies the reaction to the initiation: none, unreachable, cookie_reply, handshake_response or
+	// unexpected.
+	Response string `json:"response"`
+
+	// ValidMAC1 is true if the initiation was authenticated for the server's public key.
+	ValidMAC1 bool `json:"valid_mac1"`
+
+	Reply *Reply `json:"reply,omitempty"`
+
+	UDPProbe *zgrab2.UDPProbeResult `json:"udp_probe,omitempty"`
+}
+
+// Flags are the WireGuard-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.UDPFlags
+
+	PublicKey string `long:"public-key" description:"Base64-encoded public key of the responder, used to compute a valid mac1"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the wireguard zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("wireguard", "WireGuard", module.Description(), 51820, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Send a WireGuard handshake initiation from a random key and classify the reply"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	if f.PublicKey != "" {
+		if key, err := base64.StdEncoding.DecodeString(f.PublicKey); err != nil || len(key) != 32 {
+			return fmt.Errorf("invalid public key %q", f.PublicKey)
+		}
+	}
+	return f.UDPFlags.Validate()
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "wireguard"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportUDP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// Scan performs the configured scan on the WireGuard responder.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	var serverKey []byte
+	if scanner.config.PublicKey != "" {
+		serverKey, _ = base64.StdEncoding.DecodeString(scanner.config.PublicKey)
+	}
+	index := make([]byte, 4)
+	_, _ = rand.Read(index)
+	senderIndex := binary.LittleEndian.Uint32(index)
+
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	results := &ScanResults{ValidMAC1: serverKey != nil}
+	probe := zgrab2.NewStaticUDPProbe("handshake-initiation", buildInitiation(senderIndex, serverKey), nil)
+	results.UDPProbe, err = zgrab2.SendUDPProbe(ctx, conn, probe, target, &scanner.config.UDPFlags)
+	if err != nil {
+		status := zgrab2.TryGetScanStatus(err)
+		switch {
+		case results.UDPProbe.ICMP != "":
+			results.Response = ResponseUnreachable
+		case status == zgrab2.SCAN_IO_TIMEOUT:
+			results.Response = ResponseNone
+		default:
+			return status, nil, err
+		}
+		log.Debugf("no reply to handshake initiation from target %s: %v", target.String(), err)
+		return status, results, err
+	}
+	results.Response, results.Reply = classifyReply(results.UDPProbe.Response, senderIndex)
+	if results.Response == ResponseUnexpected {
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("target %s answered with a non-WireGuard message", target.String())
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
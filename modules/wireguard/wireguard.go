@@ -0,0 +1,78 @@
+package wireguard
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+
+	"golang.org/x/crypto/blake2s"
+)
+
+// Message types.
+const (
+	messageHandshakeInitiation = 1
+	messageHandshakeResponse   = 2
+	messageCookieReply         = 3
+)
+
+// Message lengths. Handshake messages have a fixed size.
+const (
+	handshakeInitiationLength = 148
+	handshakeResponseLength   = 92
+	cookieReplyLength         = 64
+)
+
+// macOffset is the offset of mac1 in a handshake initiation; mac1 covers everything before it.
+const macOffset = 116
+
+var mac1Label = []byte("mac1----")
+
+// buildInitiation returns a handshake initiation from a random ephemeral key. The encrypted static key and
+// timestamp are random as well, so the responder can never complete the handshake. If serverKey is set, mac1 is
+// computed for it so the responder gets past its first check and may answer with a cookie reply when under load;
+// otherwise mac1 is random and the message is dropped without a reply.
+func buildInitiation(senderIndex uint32, serverKey []byte) []byte {
+	msg := make([]byte, handshakeInitiationLength)
+	msg[0] = messageHandshakeInitiation
+	binary.LittleEndian.PutUint32(msg[4:8], senderIndex)
+	_, _ = rand.Read(msg[8:macOffset])
+	if serverKey == nil {
+		_, _ = rand.Read(msg[macOffset : macOffset+16])
+		return msg
+	}
+	keyHash, _ := blake2s.New256(nil)
+	keyHash.Write(mac1Label)
+	keyHash.Write(serverKey)
+	mac, _ := blake2s.New128(keyHash.Sum(nil))
+	mac.Write(msg[:macOffset])
+	copy(msg[macOffset:], mac.Sum(nil))
+	// mac2 stays zero, as it is without a cookie
+	return msg
+}
+
+// Reply describes a datagram received in response to the initiation.
+type Reply struct {
+	MessageType uint8 `json:"message_type"`
+	Length      int   `json:"length"`
+
+	// ReceiverIndexMatches is true if the reply is addressed to the sender index of the initiation.
+	ReceiverIndexMatches bool `json:"receiver_index_matches"`
+}
+
+// classifyReply returns the response type of a datagram, or ResponseUnexpected if it isn't a WireGuard message.
+func classifyReply(data []byte, senderIndex uint32) (string, *Reply) {
+	reply := &Reply{Length: len(data)}
+	if len(data) < 8 || data[1] != 0 || data[2] != 0 || data[3] != 0 {
+		return ResponseUnexpected, reply
+	}
+	reply.MessageType = data[0]
+	switch {
+	case reply.MessageType == messageCookieReply && len(data) == cookieReplyLength:
+		reply.ReceiverIndexMatches = binary.LittleEndian.Uint32(data[4:8]) == senderIndex
+		return ResponseCookieReply, reply
+	case reply.MessageType == messageHandshakeResponse && len(data) == handshakeResponseLength:
+		reply.ReceiverIndexMatches = binary.LittleEndian.Uint32(data[8:12]) == senderIndex
+		return ResponseHandshakeResponse, reply
+	default:
+		return ResponseUnexpected, reply
+	}
+}
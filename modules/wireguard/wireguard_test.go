@@ -0,0 +1,41 @@
+package wireguard
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"golang.org/x/crypto/blake2s"
+)
+
+func TestBuildInitiation(t *testing.T) {
+	serverKey := bytes.Repeat([]byte{0x42}, 32)
+	msg := buildInitiation(0x01020304, serverKey)
+	if len(msg) != handshakeInitiationLength || msg[0] != messageHandshakeInitiation || binary.LittleEndian.Uint32(msg[4:8]) != 0x01020304 {
+		t.Fatalf("unexpected initiation %x", msg)
+	}
+	keyHash := blake2s.Sum256(append(append([]byte(nil), mac1Label...), serverKey...))
+	mac, _ := blake2s.New128(keyHash[:])
+	mac.Write(msg[:macOffset])
+	if !bytes.Equal(mac.Sum(nil), msg[macOffset:macOffset+16]) {
+		t.Error("mac1 does not verify")
+	}
+	if !bytes.Equal(msg[macOffset+16:], make([]byte, 16)) {
+		t.Error("mac2 is not zero")
+	}
+}
+
+func TestClassifyReply(t *testing.T) {
+	cookie := make([]byte, cookieReplyLength)
+	cookie[0] = messageCookieReply
+	binary.LittleEndian.PutUint32(cookie[4:8], 7)
+	if response, reply := classifyReply(cookie, 7); response != ResponseCookieReply || !reply.ReceiverIndexMatches {
+		t.Errorf("got %s %+v", response, reply)
+	}
+	if response, reply := classifyReply(cookie, 8); response != ResponseCookieReply || reply.ReceiverIndexMatches {
+		t.Errorf("got %s %+v", response, reply)
+	}
+	if response, _ := classifyReply([]byte("SSH-2.0-OpenSSH_9.6\r\n"), 7); response != ResponseUnexpected {
+		t.Errorf("got %s for a non-WireGuard reply", response)
+	}
+}
@@ -0,0 +1,177 @@
+// Package x11 contains the zgrab2 Module implementation for X11 display servers.
+//
+// The scan sends a connection setup request without an authorization cookie. A server with access control disabled
+// accepts it, and the scan records the vendor, release number and screens from the setup reply, then lists the
+// supported extensions. Otherwise the scan records the reason the server gives for refusing. Display N listens on
+// port 6000+N, for N up to 63.
+package x11
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// Status is the setup status: success, failed or authenticate.
+	Status string `json:"status"`
+
+	// AccessGranted is true if the server accepted the connection without authorization.
+	AccessGranted bool `json:"access_granted"`
+
+	// ProtocolVersion is the protocol version the server speaks, e.g. 11.0.
+	ProtocolVersion string `json:"protocol_version,omitempty"`
+
+	// Reason is the reason the server gave for refusing the connection.
+	Reason string `json:"reason,omitempty"`
+
+	Vendor           string   `json:"vendor,omitempty"`
+	ReleaseNumber    uint32   `json:"release_number,omitempty"`
+	MaxRequestLength uint16   `json:"max_request_length,omitempty"`
+	Screens          []Screen `json:"screens,omitempty"`
+	Extensions       []string `json:"extensions,omitempty"`
+}
+
+// Flags are the X11-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+
+	NoExtensions bool `long:"no-extensions" description:"Do not list the supported extensions when access is granted"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the x11 zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("x11", "X11", module.Description(), 6000, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Attempt an X11 connection setup without authorization and record the server's details"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "x11"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		BaseFlags:                       &f.BaseFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// Scan performs the configured scan on the X server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.Dial(ctx, target)
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+
+	if _, err = conn.Write(buildSetup()); err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error sending setup to target %s: %w", target.String(), err)
+	}
+	setup, err := readSetup(conn)
+	if setup == nil {
+		if err == errInvalidReply {
+			err = zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+		}
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error reading setup reply from target %s: %w", target.String(), err)
+	}
+	results := &ScanResults{
+		Status:           setupStatusNames[setup.Status],
+		AccessGranted:    setup.Status == setupSuccess,
+		ProtocolVersion:  fmt.Sprintf("%d.%d", setup.MajorVersion, setup.MinorVersion),
+		Reason:           setup.Reason,
+		Vendor:           setup.Vendor,
+		ReleaseNumber:    setup.ReleaseNumber,
+		MaxRequestLength: setup.MaxRequestLength,
+		Screens:          setup.Screens,
+	}
+	if err != nil {
+		if err == errInvalidReply {
+			err = zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+		}
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error reading setup reply from target %s: %w", target.String(), err)
+	}
+	if !results.AccessGranted || scanner.config.NoExtensions {
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+
+	if _, err = conn.Write(buildListExtensions()); err != nil {
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error sending ListExtensions to target %s: %w", target.String(), err)
+	}
+	if results.Extensions, err = readListExtensions(conn); err != nil {
+		log.Debugf("error reading ListExtensions reply from target %s: %v", target.String(), err)
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
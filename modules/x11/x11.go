@@ -0,0 +1,191 @@
+package x11
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Connection setup status codes.
+const (
+	setupFailed       = 0
+	setupSuccess      = 1
+	setupAuthenticate = 2
+)
+
+var setupStatusNames = map[byte]string{
+	setupFailed:       "failed",
+	setupSuccess:      "success",
+	setupAuthenticate: "authenticate",
+}
+
+// opListExtensions is the opcode of the ListExtensions request.
+const opListExtensions = 99
+
+// maxAdditionalData bounds the additional data of the setup and ListExtensions replies, in bytes.
+const maxAdditionalData = 1 << 20
+
+var errInvalidReply = errors.New("invalid X11 reply")
+
+// byteOrder is the byte order the scan announces and the server then uses for everything it sends.
+var byteOrder = binary.LittleEndian
+
+// buildSetup returns a little-endian connection setup request for protocol 11.0 without authorization.
+func buildSetup() []byte {
+	return []byte{'l', 0, 11, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+}
+
+// buildListExtensions returns a ListExtensions request.
+func buildListExtensions() []byte {
+	return []byte{opListExtensions, 0, 1, 0}
+}
+
+// pad4 returns n rounded up to a multiple of 4.
+func pad4(n int) int {
+	return (n + 3) &^ 3
+}
+
+// Screen describes one of the server's screens.
+type Screen struct {
+	Width     uint16 `json:"width"`
+	Height    uint16 `json:"height"`
+	WidthMM   uint16 `json:"width_mm"`
+	HeightMM  uint16 `json:"height_mm"`
+	RootDepth uint8  `json:"root_depth"`
+}
+
+// Setup is the decoded reply to the connection setup.
+type Setup struct {
+	Status       byte
+	MajorVersion uint16
+	MinorVersion uint16
+
+	// Reason is the reason a failed or authenticate reply gives.
+	Reason string
+
+	ReleaseNumber    uint32
+	MaxRequestLength uint16
+	Vendor           string
+	Screens          []Screen
+}
+
+// readSetup reads and decodes the reply to the connection setup.
+func readSetup(r io.Reader) (*Setup, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if _, ok := setupStatusNames[header[0]]; !ok {
+		return nil, errInvalidReply
+	}
+	setup := &Setup{
+		Status:       header[0],
+		MajorVersion: byteOrder.Uint16(header[2:4]),
+		MinorVersion: byteOrder.Uint16(header[4:6]),
+	}
+	length := int(byteOrder.Uint16(header[6:8])) * 4
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return setup, err
+	}
+
+	switch setup.Status {
+	case setupFailed:
+		// the second byte is the length of the reason
+		if int(header[1]) > len(data) {
+			return setup, errInvalidReply
+		}
+		setup.Reason = string(data[:header[1]])
+		return setup, nil
+	case setupAuthenticate:
+		setup.Reason = string(trimNull(data))
+		return setup, nil
+	}
+	return setup, parseSetupSuccess(setup, data)
+}
+
+// trimNull strips the padding from the end of a string.
+func trimNull(b []byte) []byte {
+	for len(b) > 0 && b[len(b)-1] == 0 {
+		b = b[:len(b)-1]
+	}
+	return b
+}
+
+// parseSetupSuccess decodes the additional data of a successful setup reply.
+func parseSetupSuccess(setup *Setup, data []byte) error {
+	if len(data) < 32 {
+		return errInvalidReply
+	}
+	setup.ReleaseNumber = byteOrder.Uint32(data[0:4])
+	vendorLength := int(byteOrder.Uint16(data[16:18]))
+	setup.MaxRequestLength = byteOrder.Uint16(data[18:20])
+	screens := int(data[20])
+	formats := int(data[21])
+	data = data[32:]
+	if len(data) < pad4(vendorLength) {
+		return errInvalidReply
+	}
+	setup.Vendor = string(data[:vendorLength])
+	data = data[pad4(vendorLength):]
+	if len(data) < formats*8 {
+		return errInvalidReply
+	}
+	data = data[formats*8:]
+
+	for i := 0; i < screens; i++ {
+		if len(data) < 40 {
+			return errInvalidReply
+		}
+		setup.Screens = append(setup.Screens, Screen{
+			Width:     byteOrder.Uint16(data[20:22]),
+			Height:    byteOrder.Uint16(data[22:24]),
+			WidthMM:   byteOrder.Uint16(data[24:26]),
+			HeightMM:  byteOrder.Uint16(data[26:28]),
+			RootDepth: data[38],
+		})
+		depths := int(data[39])
+		data = data[40:]
+		for j := 0; j < depths; j++ {
+			if len(data) < 8 {
+				return errInvalidReply
+			}
+			visuals := int(byteOrder.Uint16(data[2:4]))
+			if len(data) < 8+visuals*24 {
+				return errInvalidReply
+			}
+			data = data[8+visuals*24:]
+		}
+	}
+	return nil
+}
+
+// readListExtensions reads and decodes the reply to ListExtensions.
+func readListExtensions(r io.Reader) ([]string, error) {
+	header := make([]byte, 32)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if header[0] != 1 {
+		// an error (0) or an event
+		return nil, errInvalidReply
+	}
+	count := int(header[1])
+	length := int(byteOrder.Uint32(header[4:8])) * 4
+	if length > maxAdditionalData {
+		return nil, errInvalidReply
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		if len(data) < 1 || len(data) < 1+int(data[0]) {
+			return names, errInvalidReply
+		}
+		names = append(names, string(data[1:1+data[0]]))
+		data = data[1+data[0]:]
+	}
+	return names, nil
+}
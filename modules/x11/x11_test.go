@@ -0,0 +1,63 @@
+package x11
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestReadSetup(t *testing.T) {
+	reason := "No protocol specified\n"
+	failed := []byte{setupFailed, byte(len(reason)), 11, 0, 0, 0, byte(pad4(len(reason)) / 4), 0}
+	failed = append(failed, reason...)
+	failed = append(failed, make([]byte, pad4(len(reason))-len(reason))...)
+	setup, err := readSetup(bytes.NewReader(failed))
+	if err != nil || setup.Status != setupFailed || setup.Reason != reason || setup.MajorVersion != 11 {
+		t.Fatalf("unexpected setup %+v (%v)", setup, err)
+	}
+
+	vendor := "The X.Org Foundation"
+	data := make([]byte, 32)
+	binary.LittleEndian.PutUint32(data[0:4], 12101004)
+	binary.LittleEndian.PutUint16(data[16:18], uint16(len(vendor)))
+	binary.LittleEndian.PutUint16(data[18:20], 65535)
+	data[20], data[21] = 1, 1 // screens, formats
+	data = append(data, vendor...)
+	data = append(data, make([]byte, pad4(len(vendor))-len(vendor))...)
+	data = append(data, 24, 32, 32, 0, 0, 0, 0, 0) // format
+	screen := make([]byte, 40)
+	binary.LittleEndian.PutUint16(screen[20:22], 1920)
+	binary.LittleEndian.PutUint16(screen[22:24], 1080)
+	binary.LittleEndian.PutUint16(screen[24:26], 508)
+	binary.LittleEndian.PutUint16(screen[26:28], 285)
+	screen[38], screen[39] = 24, 1
+	data = append(data, screen...)
+	data = append(data, 24, 0, 1, 0, 0, 0, 0, 0) // depth with one visual
+	data = append(data, make([]byte, 24)...)
+
+	success := []byte{setupSuccess, 0, 11, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint16(success[6:8], uint16(len(data)/4))
+	setup, err = readSetup(bytes.NewReader(append(success, data...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if setup.Vendor != vendor || setup.ReleaseNumber != 12101004 || setup.MaxRequestLength != 65535 {
+		t.Errorf("unexpected setup %+v", setup)
+	}
+	expected := []Screen{{Width: 1920, Height: 1080, WidthMM: 508, HeightMM: 285, RootDepth: 24}}
+	if !reflect.DeepEqual(setup.Screens, expected) {
+		t.Errorf("got screens %+v, expected %+v", setup.Screens, expected)
+	}
+}
+
+func TestReadListExtensions(t *testing.T) {
+	names := []byte{5, 'R', 'A', 'N', 'D', 'R', 4, 'X', 'K', 'E', 'Y', 0}
+	reply := make([]byte, 32)
+	reply[0], reply[1] = 1, 2
+	binary.LittleEndian.PutUint32(reply[4:8], uint32(len(names)/4))
+	extensions, err := readListExtensions(bytes.NewReader(append(reply, names...)))
+	if err != nil || !reflect.DeepEqual(extensions, []string{"RANDR", "XKEY"}) {
+		t.Errorf("got %q (%v)", extensions, err)
+	}
+}
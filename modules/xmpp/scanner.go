@@ -0,0 +1,234 @@
+// Package xmpp contains the zgrab2 Module implementation for XMPP.
+//
+// The scan opens a client-to-server (or, with --server-to-server, a server-to-server) stream and records the
+// stream header and features: STARTTLS and whether it is required, SASL mechanisms, compression methods and any
+// other advertised features. If STARTTLS is offered, the scan upgrades the connection, recording the handshake, and
+// reads the features again over TLS, where servers often offer different SASL mechanisms.
+package xmpp
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+)
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	Stream   *Stream   `json:"stream,omitempty"`
+	Features *Features `json:"features,omitempty"`
+
+	// StreamError is the stream error the server sent instead of features, e.g. host-unknown.
+	StreamError *StreamError `json:"stream_error,omitempty"`
+
+	// StartTLSFailed is true if the server answered the STARTTLS request with failure.
+	StartTLSFailed bool `json:"starttls_failed,omitempty"`
+
+	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
+
+	// TLSStream and TLSFeatures are the stream header and features after the STARTTLS upgrade.
+	TLSStream   *Stream   `json:"tls_stream,omitempty"`
+	TLSFeatures *Features `json:"tls_features,omitempty"`
+}
+
+// Flags are the XMPP-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.TLSFlags
+
+	Domain         string `long:"domain" description:"Domain to address the stream to. Defaults to the target's domain or IP"`
+	ServerToServer bool   `long:"server-to-server" description:"Open a server-to-server (jabber:server) stream, e.g. on port 5269"`
+	NoStartTLS     bool   `long:"no-starttls" description:"Do not upgrade the connection with STARTTLS"`
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the xmpp zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("xmpp", "Extensible Messaging and Presence Protocol (XMPP)", module.Description(), 5222, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Open an XMPP stream, record its features and upgrade it with STARTTLS"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "xmpp"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = &zgrab2.DialerGroupConfig{
+		TransportAgnosticDialerProtocol: zgrab2.TransportTCP,
+		NeedSeparateL4Dialer:            true,
+		BaseFlags:                       &f.BaseFlags,
+		TLSEnabled:                      true,
+		TLSFlags:                        &f.TLSFlags,
+	}
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// domain returns the domain to address the stream to.
+func (scanner *Scanner) domain(target *zgrab2.ScanTarget) string {
+	if scanner.config.Domain != "" {
+		return scanner.config.Domain
+	}
+	if target.Domain != "" {
+		return target.Domain
+	}
+	return target.Host()
+}
+
+// open sends a stream header on conn and reads the server's header and features.
+func (scanner *Scanner) open(conn net.Conn, domain string) (*xml.Decoder, *Stream, *Features, error) {
+	if _, err := conn.Write(openStream(domain, scanner.config.ServerToServer)); err != nil {
+		return nil, nil, nil, err
+	}
+	decoder := xml.NewDecoder(conn)
+	stream, err := readStreamHeader(decoder)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	features, err := readFeatures(decoder)
+	return decoder, stream, features, err
+}
+
+// protocolError converts the errors of a server that doesn't speak XMPP into SCAN_PROTOCOL_ERRORs.
+func protocolError(err error) error {
+	var syntaxErr *xml.SyntaxError
+	if errors.Is(err, errNotXMPP) || errors.As(err, &syntaxErr) {
+		return zgrab2.NewScanError(zgrab2.SCAN_PROTOCOL_ERROR, err)
+	}
+	return err
+}
+
+// Scan performs the configured scan on the XMPP server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	conn, err := dialGroup.L4Dialer(target)(ctx, "tcp", net.JoinHostPort(target.Host(), strconv.Itoa(int(target.Port))))
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening connection to target %s: %w", target.String(), err)
+	}
+	defer func() {
+		zgrab2.CloseConnAndHandleError(conn)
+	}()
+
+	domain := scanner.domain(target)
+	results := new(ScanResults)
+	decoder, stream, features, err := scanner.open(conn, domain)
+	results.Stream, results.Features = stream, features
+	var streamErr *StreamError
+	if errors.As(err, &streamErr) {
+		results.StreamError = streamErr
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+	if err != nil {
+		err = protocolError(err)
+		if stream == nil {
+			return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error opening stream to target %s: %w", target.String(), err)
+		}
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error reading features from target %s: %w", target.String(), err)
+	}
+	if !features.StartTLS || scanner.config.NoStartTLS {
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+
+	if _, err = conn.Write([]byte("<starttls xmlns='" + nsTLS + "'/>")); err != nil {
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error sending STARTTLS to target %s: %w", target.String(), err)
+	}
+	answer, err := nextElement(decoder)
+	if err != nil {
+		err = protocolError(err)
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error reading STARTTLS response from target %s: %w", target.String(), err)
+	}
+	if answer.Name.Local != "proceed" {
+		results.StartTLSFailed = true
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+	tlsConn, err := dialGroup.TLSWrapper(ctx, target, conn)
+	if tlsConn != nil {
+		results.TLSLog = tlsConn.GetLog()
+	}
+	if err != nil {
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("TLS handshake with target %s failed: %w", target.String(), err)
+	}
+	conn = tlsConn
+
+	_, results.TLSStream, results.TLSFeatures, err = scanner.open(conn, domain)
+	if errors.As(err, &streamErr) {
+		results.StreamError = streamErr
+		return zgrab2.SCAN_SUCCESS, results, nil
+	}
+	if err != nil {
+		err = protocolError(err)
+		return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error reopening stream over TLS to target %s: %w", target.String(), err)
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
@@ -0,0 +1,208 @@
+package xmpp
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// XML namespaces
+const (
+	nsStream   = "http://etherx.jabber.org/streams"
+	nsTLS      = "urn:ietf:params:xml:ns:xmpp-tls"
+	nsSASL     = "urn:ietf:params:xml:ns:xmpp-sasl"
+	nsCompress = "http://jabber.org/features/compress"
+)
+
+var errNotXMPP = errors.New("server did not open an XMPP stream")
+
+// Stream holds the attributes of the server's stream header.
+type Stream struct {
+	ID      string `json:"id,omitempty"`
+	From    string `json:"from,omitempty"`
+	Version string `json:"version,omitempty"`
+	Lang    string `json:"lang,omitempty"`
+}
+
+// Features are the stream features the server offered.
+type Features struct {
+	StartTLS         bool `json:"starttls"`
+	StartTLSRequired bool `json:"starttls_required,omitempty"`
+
+	// Mechanisms are the SASL mechanisms offered, e.g. PLAIN or SCRAM-SHA-1.
+	Mechanisms []string `json:"mechanisms,omitempty"`
+
+	// Compression are the stream compression methods offered (XEP-0138).
+	Compression []string `json:"compression,omitempty"`
+
+	// Other lists the remaining features by element name and namespace, e.g. "sm (urn:xmpp:sm:3)".
+	Other []string `json:"other,omitempty"`
+}
+
+// StreamError is a stream-level error sent by the server.
+type StreamError struct {
+	Condition string `json:"condition"`
+	Text      string `json:"text,omitempty"`
+}
+
+func (e *StreamError) Error() string {
+	if e.Text != "" {
+		return fmt.Sprintf("stream error %s: %s", e.Condition, e.Text)
+	}
+	return "stream error " + e.Condition
+}
+
+// openStream returns the header that opens a stream to domain.
+func openStream(domain string, serverToServer bool) []byte {
+	ns := "jabber:client"
+	if serverToServer {
+		ns = "jabber:server"
+	}
+	return []byte(fmt.Sprintf("<?xml version='1.0'?><stream:stream to='%s' xmlns='%s' xmlns:stream='%s' version='1.0'>", xmlEscape(domain), ns, nsStream))
+}
+
+// xmlEscape escapes s for use in an attribute value.
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// nextElement returns the next start element, skipping character data and processing instructions.
+func nextElement(d *xml.Decoder) (*xml.StartElement, error) {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			return &t, nil
+		case xml.EndElement:
+			if t.Name.Space == nsStream && t.Name.Local == "stream" {
+				return nil, io.EOF
+			}
+		}
+	}
+}
+
+// readStreamHeader reads the server's stream header.
+func readStreamHeader(d *xml.Decoder) (*Stream, error) {
+	start, err := nextElement(d)
+	if err != nil {
+		var syntaxErr *xml.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, fmt.Errorf("%w: %v", errNotXMPP, err)
+		}
+		return nil, err
+	}
+	if start.Name.Space != nsStream || start.Name.Local != "stream" {
+		return nil, fmt.Errorf("%w: got <%s>", errNotXMPP, start.Name.Local)
+	}
+	stream := new(Stream)
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "id":
+			stream.ID = attr.Value
+		case "from":
+			stream.From = attr.Value
+		case "version":
+			stream.Version = attr.Value
+		case "lang":
+			stream.Lang = attr.Value
+		}
+	}
+	return stream, nil
+}
+
+// readFeatures reads the stream features element, or returns a *StreamError.
+func readFeatures(d *xml.Decoder) (*Features, error) {
+	start, err := nextElement(d)
+	if err != nil {
+		return nil, err
+	}
+	if start.Name.Space == nsStream && start.Name.Local == "error" {
+		return nil, readStreamError(d)
+	}
+	if start.Name.Space != nsStream || start.Name.Local != "features" {
+		return nil, fmt.Errorf("%w: expected features, got <%s>", errNotXMPP, start.Name.Local)
+	}
+	features := new(Features)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		var child xml.StartElement
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child = t
+		case xml.EndElement:
+			return features, nil
+		default:
+			continue
+		}
+		switch child.Name.Space + " " + child.Name.Local {
+		case nsTLS + " starttls":
+			var starttls struct {
+				Required *struct{} `xml:"required"`
+			}
+			if err = d.DecodeElement(&starttls, &child); err != nil {
+				return nil, err
+			}
+			features.StartTLS = true
+			features.StartTLSRequired = starttls.Required != nil
+		case nsSASL + " mechanisms":
+			var mechanisms struct {
+				Mechanism []string `xml:"mechanism"`
+			}
+			if err = d.DecodeElement(&mechanisms, &child); err != nil {
+				return nil, err
+			}
+			features.Mechanisms = mechanisms.Mechanism
+		case nsCompress + " compression":
+			var compression struct {
+				Method []string `xml:"method"`
+			}
+			if err = d.DecodeElement(&compression, &child); err != nil {
+				return nil, err
+			}
+			features.Compression = compression.Method
+		default:
+			features.Other = append(features.Other, fmt.Sprintf("%s (%s)", child.Name.Local, child.Name.Space))
+			if err = d.Skip(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// readStreamError decodes the body of a stream:error element.
+func readStreamError(d *xml.Decoder) error {
+	streamErr := new(StreamError)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "text" {
+				var text string
+				if err = d.DecodeElement(&text, &t); err != nil {
+					return err
+				}
+				streamErr.Text = text
+			} else {
+				streamErr.Condition = t.Name.Local
+				if err = d.Skip(); err != nil {
+					return err
+				}
+			}
+		case xml.EndElement:
+			return streamErr
+		}
+	}
+}
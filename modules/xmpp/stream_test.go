@@ -0,0 +1,57 @@
+package xmpp
+
+import (
+	"encoding/xml"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadFeatures(t *testing.T) {
+	response := `<?xml version='1.0'?><stream:stream xmlns='jabber:client' xmlns:stream='http://etherx.jabber.org/streams' id='abc' from='example.com' version='1.0' xml:lang='en'>` +
+		`<stream:features><starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'><required/></starttls>` +
+		`<mechanisms xmlns='urn:ietf:params:xml:ns:xmpp-sasl'><mechanism>SCRAM-SHA-1</mechanism><mechanism>PLAIN</mechanism></mechanisms>` +
+		`<compression xmlns='http://jabber.org/features/compress'><method>zlib</method></compression>` +
+		`<c xmlns='http://jabber.org/protocol/caps' hash='sha-1' node='http://prosody.im' ver='x'/></stream:features>`
+	d := xml.NewDecoder(strings.NewReader(response))
+	stream, err := readStreamHeader(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&Stream{ID: "abc", From: "example.com", Version: "1.0", Lang: "en"}); !reflect.DeepEqual(stream, want) {
+		t.Errorf("got %+v", stream)
+	}
+	features, err := readFeatures(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Features{
+		StartTLS:         true,
+		StartTLSRequired: true,
+		Mechanisms:       []string{"SCRAM-SHA-1", "PLAIN"},
+		Compression:      []string{"zlib"},
+		Other:            []string{"c (http://jabber.org/protocol/caps)"},
+	}
+	if !reflect.DeepEqual(features, want) {
+		t.Errorf("got %+v", features)
+	}
+}
+
+func TestReadStreamError(t *testing.T) {
+	response := `<stream:stream xmlns:stream='http://etherx.jabber.org/streams' version='1.0'>` +
+		`<stream:error><host-unknown xmlns='urn:ietf:params:xml:ns:xmpp-streams'/><text xmlns='urn:ietf:params:xml:ns:xmpp-streams'>no such host</text></stream:error>`
+	d := xml.NewDecoder(strings.NewReader(response))
+	if _, err := readStreamHeader(d); err != nil {
+		t.Fatal(err)
+	}
+	_, err := readFeatures(d)
+	var streamErr *StreamError
+	if !errors.As(err, &streamErr) || streamErr.Condition != "host-unknown" || streamErr.Text != "no such host" {
+		t.Errorf("got %v", err)
+	}
+
+	if _, err = readStreamHeader(xml.NewDecoder(strings.NewReader("<html><body/></html>"))); !errors.Is(err, errNotXMPP) {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package zookeeper
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Property is a name/value pair from the server's environment.
+type Property struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Command is the response to a four-letter-word command.
+type Command struct {
+	Name     string `json:"name"`
+	Response string `json:"response,omitempty"`
+
+	// NotAllowed is true if the command is disabled by 4lw.commands.whitelist (ZooKeeper 3.5.3+).
+	NotAllowed bool `json:"not_allowed,omitempty"`
+}
+
+// ServerStats are the fields of the srvr response.
+type ServerStats struct {
+	Version     string `json:"version,omitempty"`
+	Mode        string `json:"mode,omitempty"`
+	NodeCount   int64  `json:"node_count"`
+	Connections int64  `json:"connections,omitempty"`
+	Outstanding int64  `json:"outstanding,omitempty"`
+	Zxid        string `json:"zxid,omitempty"`
+	Latency     string `json:"latency,omitempty"`
+}
+
+// notAllowed reports whether a four-letter-word response says the command isn't whitelisted.
+func notAllowed(response string) bool {
+	return strings.Contains(response, "is not executed because it is not in the whitelist")
+}
+
+// parseSrvr parses the response to srvr:
+//
+//	Zookeeper version: 3.8.0-5a02a05eddb59aee6ac762f7ea82e92a68eb9c0f, built on 2022-02-25 08:49 UTC
+//	Latency min/avg/max: 0/0.0/0
+//	Connections: 1
+//	Zxid: 0x0
+//	Mode: standalone
+//	Node count: 5
+func parseSrvr(response string) *ServerStats {
+	stats := new(ServerStats)
+	for _, line := range strings.Split(response, "\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(name) {
+		case "Zookeeper version":
+			stats.Version = value
+		case "Mode":
+			stats.Mode = value
+		case "Node count":
+			stats.NodeCount, _ = strconv.ParseInt(value, 10, 64)
+		case "Connections":
+			stats.Connections, _ = strconv.ParseInt(value, 10, 64)
+		case "Outstanding":
+			stats.Outstanding, _ = strconv.ParseInt(value, 10, 64)
+		case "Zxid":
+			stats.Zxid = value
+		case "Latency min/avg/max":
+			stats.Latency = value
+		}
+	}
+	if stats.Version == "" {
+		return nil
+	}
+	return stats
+}
+
+// parseEnvi parses the name=value lines of the response to envi.
+func parseEnvi(response string) []Property {
+	var properties []Property
+	for _, line := range strings.Split(response, "\n") {
+		name, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		properties = append(properties, Property{Name: name, Value: value})
+	}
+	return properties
+}
@@ -0,0 +1,29 @@
+package zookeeper
+
+import "testing"
+
+func TestParseSrvr(t *testing.T) {
+	response := "Zookeeper version: 3.8.0-5a02a05eddb59aee6ac762f7ea82e92a68eb9c0f, built on 2022-02-25 08:49 UTC\n" +
+		"Latency min/avg/max: 0/0.0/0\nReceived: 1\nSent: 0\nConnections: 1\nOutstanding: 0\nZxid: 0x0\nMode: standalone\nNode count: 5\n"
+	stats := parseSrvr(response)
+	if stats == nil {
+		t.Fatal("got nil")
+	}
+	if stats.Version != "3.8.0-5a02a05eddb59aee6ac762f7ea82e92a68eb9c0f, built on 2022-02-25 08:49 UTC" || stats.Mode != "standalone" ||
+		stats.NodeCount != 5 || stats.Connections != 1 || stats.Zxid != "0x0" || stats.Latency != "0/0.0/0" {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if stats = parseSrvr("This ZooKeeper instance is not currently serving requests\n"); stats != nil {
+		t.Errorf("got %+v", stats)
+	}
+	if !notAllowed("envi is not executed because it is not in the whitelist.\n") {
+		t.Error("whitelist response not recognized")
+	}
+}
+
+func TestParseEnvi(t *testing.T) {
+	properties := parseEnvi("Environment:\nzookeeper.version=3.4.14-4c25d480e66aadd371de8bd2fd8da255ac140bcf\nos.name=Linux\n")
+	if len(properties) != 2 || properties[0].Name != "zookeeper.version" || properties[1].Value != "Linux" {
+		t.Errorf("unexpected properties %+v", properties)
+	}
+}
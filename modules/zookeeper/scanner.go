@@ -0,0 +1,304 @@
+// Package zookeeper contains the zgrab2 Module implementation for Apache ZooKeeper.
+//
+// By default the scan sends the four-letter-word commands ruok, srvr and envi on the client port, one connection
+// each, noting which are disabled by the server's whitelist. With --admin-server it instead queries the same
+// commands through the AdminServer HTTP API (/commands/<name>, port 8080 by default), which ZooKeeper 3.5+ offers
+// as the replacement for four-letter words.
+package zookeeper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zmap/zgrab2"
+	"github.com/zmap/zgrab2/modules/httpapi"
+)
+
+// maxResponseSize bounds the response to a four-letter word.
+const maxResponseSize = 64 * 1024
+
+// ScanResults is the output of the scan.
+type ScanResults struct {
+	// Healthy is true if the server answered ruok with imok.
+	Healthy bool `json:"healthy"`
+
+	Stats       *ServerStats `json:"stats,omitempty"`
+	Environment []Property   `json:"environment,omitempty"`
+
+	// Commands holds the raw response to each four-letter word sent.
+	Commands []*Command `json:"commands,omitempty"`
+
+	// AdminResponses holds the responses of the AdminServer, with --admin-server.
+	AdminResponses []*httpapi.Response `json:"admin_responses,omitempty"`
+
+	TLSLog *zgrab2.TLSLog `json:"tls,omitempty"`
+}
+
+// Flags are the ZooKeeper-specific command-line flags.
+type Flags struct {
+	zgrab2.BaseFlags
+	zgrab2.TLSFlags
+	httpapi.Flags
+
+	Commands    string `long:"commands" default:"ruok,srvr,envi" description:"Comma-separated four-letter-word commands to send"`
+	AdminServer bool   `long:"admin-server" description:"Query the AdminServer HTTP API instead of sending four-letter words"`
+
+	commands []string
+}
+
+// Module implements the zgrab2.Module interface.
+type Module struct {
+}
+
+// Scanner implements the zgrab2.Scanner interface, and holds the state
+// for a single scan.
+type Scanner struct {
+	config            *Flags
+	dialerGroupConfig *zgrab2.DialerGroupConfig
+}
+
+// RegisterModule registers the zookeeper zgrab2 module.
+func RegisterModule() {
+	var module Module
+	_, err := zgrab2.AddCommand("zookeeper", "Apache ZooKeeper", module.Description(), 2181, &module)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NewFlags returns the default flags object to be filled in with the
+// command-line arguments.
+func (m *Module) NewFlags() interface{} {
+	return new(Flags)
+}
+
+// NewScanner returns a new Scanner instance.
+func (m *Module) NewScanner() zgrab2.Scanner {
+	return new(Scanner)
+}
+
+// Description returns an overview of this module.
+func (m *Module) Description() string {
+	return "Query a ZooKeeper server's version, mode and node count with four-letter words or the AdminServer"
+}
+
+// Validate flags
+func (f *Flags) Validate(_ []string) error {
+	f.commands = nil
+	for _, command := range strings.Split(f.Commands, ",") {
+		command = strings.TrimSpace(command)
+		if command == "" {
+			continue
+		}
+		if len(command) != 4 && !f.AdminServer {
+			return fmt.Errorf("%q is not a four-letter word", command)
+		}
+		f.commands = append(f.commands, command)
+	}
+	if len(f.commands) == 0 {
+		return fmt.Errorf("no commands given")
+	}
+	return nil
+}
+
+// Help returns this module's help string.
+func (f *Flags) Help() string {
+	return ""
+}
+
+// Protocol returns the protocol identifier for the scanner.
+func (scanner *Scanner) Protocol() string {
+	return "zookeeper"
+}
+
+func (scanner *Scanner) GetDialerGroupConfig() *zgrab2.DialerGroupConfig {
+	return scanner.dialerGroupConfig
+}
+
+// GetScanMetadata returns any metadata on the scan itself from this module.
+func (scanner *Scanner) GetScanMetadata() any {
+	return nil
+}
+
+// Init initializes the Scanner instance with the flags from the command line.
+func (scanner *Scanner) Init(flags zgrab2.ScanFlags) error {
+	f, _ := flags.(*Flags)
+	scanner.config = f
+	scanner.dialerGroupConfig = httpapi.NewDialerGroupConfig(&f.BaseFlags, &f.TLSFlags)
+	return nil
+}
+
+// InitPerSender does nothing in this module.
+func (scanner *Scanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+// GetName returns the configured name for the Scanner.
+func (scanner *Scanner) GetName() string {
+	return scanner.config.Name
+}
+
+// GetTrigger returns the Trigger defined in the Flags.
+func (scanner *Scanner) GetTrigger() string {
+	return scanner.config.Trigger
+}
+
+// sendCommand sends a four-letter word on a new connection and reads the response until the server closes it.
+func sendCommand(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget, command string) (string, error) {
+	conn, err := dialGroup.L4Dialer(target)(ctx, "tcp", net.JoinHostPort(target.Host(), strconv.Itoa(int(target.Port))))
+	if err != nil {
+		return "", err
+	}
+	defer zgrab2.CloseConnAndHandleError(conn)
+	if _, err = conn.Write([]byte(command)); err != nil {
+		return "", err
+	}
+	response, err := io.ReadAll(io.LimitReader(conn, maxResponseSize))
+	if err != nil && len(response) == 0 {
+		return "", err
+	}
+	return string(response), nil
+}
+
+// Scan performs the configured scan on the ZooKeeper server.
+func (scanner *Scanner) Scan(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	if scanner.config.AdminServer {
+		return scanner.scanAdminServer(ctx, dialGroup, target)
+	}
+	results := new(ScanResults)
+	for _, name := range scanner.config.commands {
+		response, err := sendCommand(ctx, dialGroup, target, name)
+		if err != nil {
+			if len(results.Commands) == 0 {
+				return zgrab2.TryGetScanStatus(err), nil, fmt.Errorf("error sending %s to target %s: %w", name, target.String(), err)
+			}
+			return zgrab2.TryGetScanStatus(err), results, fmt.Errorf("error sending %s to target %s: %w", name, target.String(), err)
+		}
+		command := &Command{Name: name, Response: response, NotAllowed: notAllowed(response)}
+		results.Commands = append(results.Commands, command)
+		if command.NotAllowed {
+			continue
+		}
+		switch name {
+		case "ruok":
+			results.Healthy = response == "imok"
+		case "srvr", "stat":
+			if stats := parseSrvr(response); stats != nil {
+				results.Stats = stats
+			}
+		case "envi":
+			results.Environment = parseEnvi(response)
+		}
+	}
+	for _, command := range results.Commands {
+		if command.Response != "" {
+			return zgrab2.SCAN_SUCCESS, results, nil
+		}
+	}
+	// the server closed every connection without a word: not ZooKeeper, or every command is disabled silently
+	return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("target %s did not respond to any command", target.String())
+}
+
+// adminResponse holds the AdminServer fields common to every command, and those of srvr.
+type adminResponse struct {
+	Command     string  `json:"command"`
+	Error       *string `json:"error"`
+	Version     string  `json:"version"`
+	NodeCount   int64   `json:"node_count"`
+	ServerStats struct {
+		ServerState               string `json:"server_state"`
+		NumAliveClientConnections int64  `json:"num_alive_client_connections"`
+		OutstandingRequests       int64  `json:"outstanding_requests"`
+		LastProcessedZxid         int64  `json:"last_processed_zxid"`
+		MinLatency                int64  `json:"min_latency"`
+		AvgLatency                any    `json:"avg_latency"`
+		MaxLatency                int64  `json:"max_latency"`
+	} `json:"server_stats"`
+}
+
+// adminCommands maps four-letter words to the AdminServer commands that replace them.
+var adminCommands = map[string]string{
+	"envi": "environment",
+	"srvr": "server_stats",
+	"stat": "stats",
+	"conf": "configuration",
+	"mntr": "monitor",
+}
+
+// scanAdminServer queries the commands through the AdminServer HTTP API.
+func (scanner *Scanner) scanAdminServer(ctx context.Context, dialGroup *zgrab2.DialerGroup, target *zgrab2.ScanTarget) (zgrab2.ScanStatus, any, error) {
+	client := httpapi.NewClient(ctx, dialGroup, target, &scanner.config.Flags)
+	results := new(ScanResults)
+	recognized := false
+	for _, name := range scanner.config.commands {
+		command := name
+		if replacement, ok := adminCommands[name]; ok {
+			command = replacement
+		}
+		var admin adminResponse
+		resp, err := client.GetJSON("/commands/"+command, &admin)
+		results.TLSLog = client.TLSLog
+		if resp != nil {
+			results.AdminResponses = append(results.AdminResponses, resp)
+		}
+		if err != nil {
+			if len(results.AdminResponses) == 0 && results.TLSLog == nil {
+				return zgrab2.TryGetScanStatus(err), nil, err
+			}
+			return zgrab2.TryGetScanStatus(err), results, err
+		}
+		if !resp.Success() || admin.Command == "" {
+			continue
+		}
+		recognized = true
+		if admin.Error != nil {
+			continue
+		}
+		switch command {
+		case "ruok":
+			results.Healthy = true
+		case "server_stats", "stats":
+			results.Stats = &ServerStats{
+				Version:     admin.Version,
+				Mode:        admin.ServerStats.ServerState,
+				NodeCount:   admin.NodeCount,
+				Connections: admin.ServerStats.NumAliveClientConnections,
+				Outstanding: admin.ServerStats.OutstandingRequests,
+				Zxid:        "0x" + strconv.FormatInt(admin.ServerStats.LastProcessedZxid, 16),
+				Latency:     fmt.Sprintf("%d/%v/%d", admin.ServerStats.MinLatency, admin.ServerStats.AvgLatency, admin.ServerStats.MaxLatency),
+			}
+		case "environment":
+			results.Environment = adminEnvironment(resp.Body)
+		}
+	}
+	if !recognized {
+		return zgrab2.SCAN_PROTOCOL_ERROR, results, fmt.Errorf("target %s is not a ZooKeeper AdminServer", target.String())
+	}
+	return zgrab2.SCAN_SUCCESS, results, nil
+}
+
+// adminEnvironment returns the properties of an AdminServer environment response, sorted by name.
+func adminEnvironment(body string) []Property {
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(body), &fields); err != nil {
+		return nil
+	}
+	var properties []Property
+	for name, value := range fields {
+		if s, ok := value.(string); ok && name != "command" {
+			properties = append(properties, Property{Name: name, Value: s})
+		}
+	}
+	sort.Slice(properties, func(i, j int) bool {
+		return properties[i].Name < properties[j].Name
+	})
+	return properties
+}
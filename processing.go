@@ -2,11 +2,11 @@ package zgrab2
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"math/rand"
 	"net"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/zmap/zcrypto/tls"
@@ -16,16 +16,19 @@ import (
 
 // Grab contains all scan responses for a single host
 type Grab struct {
-	IP     string                  `json:"ip,omitempty"`
-	Port   uint                    `json:"port,omitempty"`
-	Domain string                  `json:"domain,omitempty"`
-	Data   map[string]ScanResponse `json:"data,omitempty"`
-	Error  string                  `json:"error,omitempty"` // an error that affects the entire grab, preventing any data from being returned
+	IP          string                  `json:"ip,omitempty"`
+	Port        uint                    `json:"port,omitempty"`
+	Domain      string                  `json:"domain,omitempty"`
+	ResolvedIPs []string                `json:"resolved_ips,omitempty"` // with --resolve-all, every address the domain resolved to
+	Data        map[string]ScanResponse `json:"data,omitempty"`
+	Error       string                  `json:"error,omitempty"` // an error that affects the entire grab, preventing any data from being returned
+	Run         *RunMetadata            `json:"run,omitempty"`   // identifies the scan run, if --run-metadata is set
 }
 
 // ScanTarget is the host that will be scanned
 type ScanTarget struct {
 	IP     net.IP
+	Zone   string // IPv6 zone identifier (e.g. the interface for a link-local address), if any
 	Domain string
 	Tag    string
 	Port   uint
@@ -37,9 +40,9 @@ func (target ScanTarget) String() string {
 	}
 	res := ""
 	if target.IP != nil && target.Domain != "" {
-		res = target.Domain + "(" + target.IP.String() + ")"
+		res = target.Domain + "(" + joinIPZone(target.IP, target.Zone) + ")"
 	} else if target.IP != nil {
-		res = target.IP.String()
+		res = joinIPZone(target.IP, target.Zone)
 	} else {
 		res = target.Domain
 	}
@@ -53,7 +56,7 @@ func (target ScanTarget) String() string {
 // or the domain if not.
 func (target *ScanTarget) Host() string {
 	if target.IP != nil {
-		return target.IP.String()
+		return joinIPZone(target.IP, target.Zone)
 	} else if target.Domain != "" {
 		return target.Domain
 	}
@@ -134,9 +137,26 @@ func GetDefaultTLSWrapper(tlsFlags *TLSFlags) func(ctx context.Context, t *ScanT
 				tlsConfig.ServerName = host
 			}
 		}
+		// Record the CertificateRequest of servers, answering it with the client certificate if one was given
+		certRequest := &certificateRequestRecorder{}
+		if len(tlsConfig.Certificates) > 0 {
+			certRequest.certificate = &tlsConfig.Certificates[0]
+		}
+		tlsConfig.GetClientCertificate = certRequest.getClientCertificate
+		var transcript *transcriptRecorder
+		if tlsFlags.HandshakeTranscript {
+			// The key log gives the secrets to decrypt the TLS 1.3 handshake with
+			transcript = newTranscriptRecorder(conn, tlsConfig.KeyLogWriter)
+			tlsConfig.KeyLogWriter = transcriptKeyLog{recorder: transcript}
+			conn = transcript
+		}
+		hellos := newHelloRecorder(conn)
 		tlsConn := TLSConnection{
-			Conn:  *(tls.Client(conn, tlsConfig)),
-			flags: tlsFlags,
+			Conn:        *(tls.Client(hellos, tlsConfig)),
+			flags:       tlsFlags,
+			hellos:      hellos,
+			certRequest: certRequest,
+			transcript:  transcript,
 		}
 		err = tlsConn.Handshake()
 		if err != nil && tlsConn.log == nil {
@@ -168,13 +188,14 @@ func GetDefaultUDPDialer(flags *BaseFlags) func(ctx context.Context, t *ScanTarg
 func BuildGrabFromInputResponse(t *ScanTarget, responses map[string]ScanResponse) *Grab {
 	var ipstr string
 	if t.IP != nil {
-		ipstr = t.IP.String()
+		ipstr = joinIPZone(t.IP, t.Zone)
 	}
 	return &Grab{
 		IP:     ipstr,
 		Port:   t.Port,
 		Domain: t.Domain,
 		Data:   responses,
+		Run:    runMetadata,
 	}
 }
 
@@ -195,47 +216,126 @@ func EncodeGrab(raw *Grab, includeDebug bool) ([]byte, error) {
 		}
 		outputData = stripped
 	}
-	return json.Marshal(outputData)
+	return marshalJSON(outputData)
+}
+
+// budgetContext gives the next module its share of the time remaining in the target's --target-budget: an equal
+// split between it and the remainingScanners-1 modules after it. Time a module doesn't use is left for the rest.
+func budgetContext(ctx context.Context, remainingScanners int) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok || config.TargetBudget == 0 || remainingScanners <= 1 {
+		return context.WithCancel(ctx)
+	}
+	share := time.Until(deadline) / time.Duration(remainingScanners)
+	return context.WithTimeout(ctx, share)
+}
+
+// budgetExhaustedResponse is the response recorded for a module that was skipped because the target's
+// --target-budget ran out before it could start.
+func budgetExhaustedResponse(scanner Scanner, m *Monitor, target ScanTarget) ScanResponse {
+	m.statusesChan <- moduleStatus{name: scanner.GetName(), st: statusFailure}
+	if target.Port == 0 {
+		target.Port = defaultDialerGroupConfigToScanners[scanner.GetName()].BaseFlags.Port
+	}
+	errString := "target budget exhausted before scan could start"
+	return ScanResponse{
+		Status:    SCAN_CONNECTION_TIMEOUT,
+		Protocol:  scanner.Protocol(),
+		Port:      target.Port,
+		Timestamp: time.Now().Format(time.RFC3339),
+		Error:     &errString,
+	}
+}
+
+// targetFailure is the Grab for a target that no module was able to scan, e.g. because its domain couldn't be
+// resolved. A failure is reported to the monitor for each scanner.
+func targetFailure(input ScanTarget, mon *Monitor, err error) *Grab {
+	for _, scannerName := range orderedScanners {
+		mon.statusesChan <- moduleStatus{name: scannerName, st: statusFailure}
+	}
+	var ipstr string
+	if input.IP != nil {
+		ipstr = joinIPZone(input.IP, input.Zone)
+	}
+	return &Grab{
+		IP:     ipstr,
+		Port:   input.Port,
+		Domain: input.Domain,
+		Error:  err.Error(),
+		Run:    runMetadata,
+	}
+}
+
+// lookupTarget resolves domain targets; tests replace it to avoid depending on DNS.
+var lookupTarget = resolveTarget
+
+// resolveTarget looks up the reachable IPs for a domain target, honoring --dns-resolution-timeout and the blocklist.
+func resolveTarget(ctx context.Context, domain string) ([]net.IP, error) {
+	dialer := NewDialer(nil)
+	if err := dialer.SetRandomLocalAddr("udp", config.localAddrs, config.localPorts); err != nil {
+		return nil, fmt.Errorf("could not set random local address: %w", err)
+	}
+	// only use special timeout if it's set, otherwise use the default context timeout
+	lookupCtx, cancel := context.WithTimeout(ctx, config.DNSResolutionTimeout)
+	defer cancel()
+	reachableIPs, err := dialer.lookupIPs(lookupCtx, domain)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve domain %s: %w", domain, err)
+	}
+	return reachableIPs, nil
+}
+
+// grabAllAddresses scans a domain target once for every address it resolves to (--resolve-all), returning a Grab
+// per address.
+func grabAllAddresses(ctx context.Context, input ScanTarget, m *Monitor) []*Grab {
+	reachableIPs, err := lookupTarget(ctx, input.Domain)
+	if err != nil {
+		return []*Grab{targetFailure(input, m, err)}
+	}
+	resolvedIPs := make([]string, 0, len(reachableIPs))
+	for _, ip := range reachableIPs {
+		resolvedIPs = append(resolvedIPs, ip.String())
+	}
+	grabs := make([]*Grab, 0, len(reachableIPs))
+	for _, ip := range reachableIPs {
+		target := input
+		target.IP = ip
+		grab := grabTarget(ctx, target, m)
+		grab.ResolvedIPs = resolvedIPs
+		grabs = append(grabs, grab)
+	}
+	return grabs
 }
 
 // grabTarget calls handler for each action
 func grabTarget(ctx context.Context, input ScanTarget, m *Monitor) *Grab {
 	moduleResult := make(map[string]ScanResponse)
+	if config.TargetBudget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.TargetBudget)
+		defer cancel()
+	}
 	if len(input.Domain) > 0 && input.IP == nil {
-		// If there's an issue with resolving the target, then no module will be able to scan it.
-		onResolutionFailure := func(input ScanTarget, mon *Monitor, err error) *Grab {
-			for _, scannerName := range orderedScanners {
-				// send a failure to the monitor for each scanner
-				mon.statusesChan <- moduleStatus{name: scannerName, st: statusFailure}
-			}
-			return &Grab{
-				Port:   input.Port,
-				Domain: input.Domain,
-				Error:  err.Error(),
-			}
-		}
 		// resolve the target's IP here once, so it doesn't need to be resolved in each module
-		dialer := NewDialer(nil)
-		err := dialer.SetRandomLocalAddr("udp", config.localAddrs, config.localPorts)
+		reachableIPs, err := lookupTarget(ctx, input.Domain)
 		if err != nil {
-			return onResolutionFailure(input, m, fmt.Errorf("could not set random local address: %w", err))
-		}
-		var reachableIPs []net.IP
-		// only use special timeout if it's set, otherwise use the default context timeout
-		lookupCtx, cancel := context.WithTimeout(ctx, config.DNSResolutionTimeout)
-		defer cancel()
-		reachableIPs, err = dialer.lookupIPs(lookupCtx, input.Domain)
-		if err != nil {
-			return onResolutionFailure(input, m, fmt.Errorf("could not resolve domain %s: %w", input.Domain, err))
+			return targetFailure(input, m, err)
 		}
 		input.IP = reachableIPs[rand.Intn(len(reachableIPs))]
 	}
+	if liveness != nil {
+		if err := checkLiveness(ctx, &input); err != nil {
+			return targetFailure(input, m, fmt.Errorf("liveness check failed for %s: %w", input.String(), err))
+		}
+	}
+	var triggeredScanners []string
 	for _, scannerName := range orderedScanners {
-		scanner := scanners[scannerName]
-		trigger := (*scanner).GetTrigger()
-		if input.Tag != trigger {
-			continue
+		if (*scanners[scannerName]).GetTrigger() == input.Tag {
+			triggeredScanners = append(triggeredScanners, scannerName)
 		}
+	}
+	for i, scannerName := range triggeredScanners {
+		scanner := scanners[scannerName]
 		defer func(name string) {
 			if e := recover(); e != nil {
 				log.Errorf("Panic on scanner %s when scanning target %s: %#v", scannerName, input.String(), e)
@@ -243,7 +343,16 @@ func grabTarget(ctx context.Context, input ScanTarget, m *Monitor) *Grab {
 				panic(e)
 			}
 		}(scannerName)
-		name, res := RunScanner(ctx, *scanner, m, input)
+		scanCtx, cancel := budgetContext(ctx, len(triggeredScanners)-i)
+		var name string
+		var res ScanResponse
+		if scanCtx.Err() != nil {
+			// the target's budget is used up, don't bother starting the scan
+			name, res = scannerName, budgetExhaustedResponse(*scanner, m, input)
+		} else {
+			name, res = RunScanner(scanCtx, *scanner, m, input)
+		}
+		cancel()
 		moduleResult[name] = res
 		if res.Error != nil && !config.Multiple.ContinueOnError {
 			break
@@ -285,13 +394,21 @@ func Process(mon *Monitor) {
 				}
 			}
 			for obj := range processQueue {
+				applyDefaultZone(&obj)
 				for run := uint(0); run < uint(config.ConnectionsPerHost); run++ {
-					grab := grabTarget(context.Background(), obj, mon)
-					result, err := EncodeGrab(grab, includeDebugOutput())
-					if err != nil {
-						log.Errorf("unable to marshal data: %s", err)
+					var grabs []*Grab
+					if config.ResolveAll && obj.IP == nil && obj.Domain != "" {
+						grabs = grabAllAddresses(context.Background(), obj, mon)
+					} else {
+						grabs = []*Grab{grabTarget(context.Background(), obj, mon)}
+					}
+					for _, grab := range grabs {
+						result, err := EncodeGrab(grab, includeDebugOutput())
+						if err != nil {
+							log.Errorf("unable to marshal data: %s", err)
+						}
+						outputQueue <- result
 					}
-					outputQueue <- result
 				}
 			}
 			workerDone.Done()
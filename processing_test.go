@@ -0,0 +1,205 @@
+package zgrab2
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeScanner is a module that succeeds after delay, regardless of its context, recording the target it was called
+// with.
+type fakeScanner struct {
+	name    string
+	delay   time.Duration
+	targets []ScanTarget
+}
+
+func (s *fakeScanner) Init(flags ScanFlags) error       { return nil }
+func (s *fakeScanner) InitPerSender(senderID int) error { return nil }
+func (s *fakeScanner) GetName() string                  { return s.name }
+func (s *fakeScanner) GetTrigger() string               { return "" }
+func (s *fakeScanner) Protocol() string                 { return "fake" }
+func (s *fakeScanner) GetScanMetadata() any             { return nil }
+
+func (s *fakeScanner) GetDialerGroupConfig() *DialerGroupConfig {
+	return &DialerGroupConfig{BaseFlags: &BaseFlags{Port: 1234}}
+}
+
+func (s *fakeScanner) Scan(ctx context.Context, dialerGroup *DialerGroup, t *ScanTarget) (ScanStatus, any, error) {
+	s.targets = append(s.targets, *t)
+	time.Sleep(s.delay)
+	return SCAN_SUCCESS, nil, nil
+}
+
+// useScanners registers modules as the only scanners for the duration of the test.
+func useScanners(t *testing.T, modules ...Scanner) {
+	oldScanners, oldOrdered := scanners, orderedScanners
+	oldGroups, oldConfigs := defaultDialerGroupToScanners, defaultDialerGroupConfigToScanners
+	t.Cleanup(func() {
+		scanners, orderedScanners = oldScanners, oldOrdered
+		defaultDialerGroupToScanners, defaultDialerGroupConfigToScanners = oldGroups, oldConfigs
+	})
+	scanners, orderedScanners = make(map[string]*Scanner), nil
+	defaultDialerGroupToScanners = make(map[string]*DialerGroup)
+	defaultDialerGroupConfigToScanners = make(map[string]*DialerGroupConfig)
+	for _, module := range modules {
+		name := module.GetName()
+		scanners[name] = &module
+		orderedScanners = append(orderedScanners, name)
+		defaultDialerGroupToScanners[name] = new(DialerGroup)
+		defaultDialerGroupConfigToScanners[name] = module.GetDialerGroupConfig()
+	}
+}
+
+func TestGrabAllAddresses(t *testing.T) {
+	scanner := &fakeScanner{name: "fake"}
+	useScanners(t, scanner)
+	oldLookup := lookupTarget
+	defer func() { lookupTarget = oldLookup }()
+	lookupTarget = func(ctx context.Context, domain string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1")}, nil
+	}
+
+	mon := &Monitor{statusesChan: make(chan moduleStatus, 10)}
+	grabs := grabAllAddresses(context.Background(), ScanTarget{Domain: "example.com"}, mon)
+	if len(grabs) != 2 {
+		t.Fatalf("got %d results, expected one per address", len(grabs))
+	}
+	expected := []string{"192.0.2.1", "2001:db8::1"}
+	for i, grab := range grabs {
+		if grab.IP != expected[i] || grab.Domain != "example.com" {
+			t.Errorf("result %d is for %s (%s)", i, grab.IP, grab.Domain)
+		}
+		if !slices.Equal(grab.ResolvedIPs, expected) {
+			t.Errorf("result %d resolved IPs = %v", i, grab.ResolvedIPs)
+		}
+		if res, ok := grab.Data["fake"]; !ok || res.Status != SCAN_SUCCESS || res.Port != 1234 {
+			t.Errorf("result %d data = %+v", i, grab.Data)
+		}
+	}
+	if len(scanner.targets) != 2 || !scanner.targets[0].IP.Equal(net.ParseIP("192.0.2.1")) || !scanner.targets[1].IP.Equal(net.ParseIP("2001:db8::1")) {
+		t.Errorf("scanned targets = %v", scanner.targets)
+	}
+}
+
+// useTargetBudget sets --target-budget for the duration of the test.
+func useTargetBudget(t *testing.T, budget time.Duration) {
+	old := config.TargetBudget
+	t.Cleanup(func() { config.TargetBudget = old })
+	config.TargetBudget = budget
+}
+
+func TestBudgetContext(t *testing.T) {
+	useTargetBudget(t, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	parentDeadline, _ := ctx.Deadline()
+	scanCtx, scanCancel := budgetContext(ctx, 3)
+	if deadline, _ := scanCtx.Deadline(); !deadline.Equal(parentDeadline) {
+		t.Errorf("without a budget, deadline = %s, expected %s", deadline, parentDeadline)
+	}
+	scanCancel()
+
+	useTargetBudget(t, 3*time.Second)
+	for remaining, expected := range map[int]time.Duration{3: time.Second, 2: 1500 * time.Millisecond, 1: 3 * time.Second} {
+		scanCtx, scanCancel := budgetContext(ctx, remaining)
+		deadline, ok := scanCtx.Deadline()
+		if share := time.Until(deadline); !ok || share > expected || share < expected-100*time.Millisecond {
+			t.Errorf("with %d modules remaining, share = %s, expected %s", remaining, share, expected)
+		}
+		scanCancel()
+	}
+
+	scanCtx, scanCancel = budgetContext(context.Background(), 3)
+	if _, ok := scanCtx.Deadline(); ok {
+		t.Error("budget applied to a context without a deadline")
+	}
+	scanCancel()
+}
+
+func TestBudgetExhaustedResponse(t *testing.T) {
+	scanner := &fakeScanner{name: "fake"}
+	useScanners(t, scanner)
+	mon := &Monitor{statusesChan: make(chan moduleStatus, 1)}
+	res := budgetExhaustedResponse(scanner, mon, ScanTarget{IP: net.ParseIP("192.0.2.1")})
+	if res.Status != SCAN_CONNECTION_TIMEOUT || res.Port != 1234 || res.Protocol != "fake" || res.Error == nil {
+		t.Errorf("response = %+v", res)
+	}
+	if status := <-mon.statusesChan; status.name != "fake" || status.st != statusFailure {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+func TestGrabTargetBudgetExhausted(t *testing.T) {
+	slow := &fakeScanner{name: "slow", delay: 100 * time.Millisecond}
+	skipped := &fakeScanner{name: "skipped"}
+	useScanners(t, slow, skipped)
+	useTargetBudget(t, 50*time.Millisecond)
+
+	mon := &Monitor{statusesChan: make(chan moduleStatus, 10)}
+	grab := grabTarget(context.Background(), ScanTarget{IP: net.ParseIP("192.0.2.1")}, mon)
+	if res := grab.Data["slow"]; res.Status != SCAN_SUCCESS {
+		t.Errorf("slow module = %+v", res)
+	}
+	if res := grab.Data["skipped"]; res.Status != SCAN_CONNECTION_TIMEOUT || res.Error == nil {
+		t.Errorf("module after the budget ran out = %+v", res)
+	}
+	if len(skipped.targets) != 0 {
+		t.Error("module was started after the budget ran out")
+	}
+}
+
+// portChecker is a livenessChecker for which targets are only live on the given ports, recording the ports it was
+// asked to check.
+type portChecker struct {
+	live  []uint
+	ports []uint
+}
+
+func (c *portChecker) Check(ctx context.Context, ip net.IP, zone string, port uint) error {
+	c.ports = append(c.ports, port)
+	if slices.Contains(c.live, port) {
+		return nil
+	}
+	return errNotLive
+}
+
+func TestGrabTargetLiveness(t *testing.T) {
+	first, second, third := &fakeScanner{name: "first"}, &fakeScanner{name: "second"}, &fakeScanner{name: "third"}
+	useScanners(t, first, second, third)
+	defaultDialerGroupConfigToScanners["second"].BaseFlags.Port = 4321
+	checker := new(portChecker)
+	oldLiveness := liveness
+	defer func() { liveness = oldLiveness }()
+	liveness = checker
+
+	mon := &Monitor{statusesChan: make(chan moduleStatus, 100)}
+	grab := grabTarget(context.Background(), ScanTarget{IP: net.ParseIP("192.0.2.1")}, mon)
+	if grab.Error == "" || len(grab.Data) != 0 || !slices.Equal(checker.ports, []uint{1234, 4321}) {
+		t.Errorf("dead target was scanned (grab %+v, checked ports %v)", grab, checker.ports)
+	}
+
+	// a target is scanned if any of the modules' ports is live
+	checker.live, checker.ports = []uint{4321}, nil
+	grab = grabTarget(context.Background(), ScanTarget{IP: net.ParseIP("192.0.2.1")}, mon)
+	if grab.Error != "" || len(grab.Data) != 3 || !slices.Equal(checker.ports, []uint{1234, 4321}) {
+		t.Errorf("target live on the second module's port wasn't scanned (grab %+v, checked ports %v)", grab, checker.ports)
+	}
+
+	// the target's own port replaces the modules'
+	checker.live, checker.ports = nil, nil
+	grabTarget(context.Background(), ScanTarget{IP: net.ParseIP("192.0.2.1"), Port: 8080}, mon)
+	if !slices.Equal(checker.ports, []uint{8080}) {
+		t.Errorf("checked ports %v, expected the target's", checker.ports)
+	}
+
+	// with a module without a port, the check is skipped rather than failing the target
+	defaultDialerGroupConfigToScanners["third"].BaseFlags.Port = 0
+	checker.ports = nil
+	grab = grabTarget(context.Background(), ScanTarget{IP: net.ParseIP("192.0.2.1")}, mon)
+	if grab.Error != "" || grab.Data["first"].Status != SCAN_SUCCESS || len(checker.ports) != 0 {
+		t.Errorf("target with a module without a port wasn't scanned (grab %+v, checked ports %v)", grab, checker.ports)
+	}
+}
@@ -0,0 +1,89 @@
+package zgrab2
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// Version is the zgrab2 version reported in run metadata. It can be set at build time with
+// -ldflags "-X github.com/zmap/zgrab2.Version=...", otherwise it is taken from the Go build info.
+var Version = ""
+
+// RunMetadata identifies the scan run that produced a result, so results merged from many runs remain attributable.
+// It is stamped onto every Grab when enabled with --run-metadata.
+type RunMetadata struct {
+	ScanID        string `json:"scan_id"`
+	OperatorLabel string `json:"operator_label,omitempty"`
+	Version       string `json:"zgrab2_version,omitempty"`
+	ConfigHash    string `json:"config_hash,omitempty"`
+	StartTime     string `json:"start_time"`
+}
+
+var (
+	runMetadata   *RunMetadata
+	runConfigHash string
+)
+
+// GetRunMetadata returns the metadata for the current run, or nil if run metadata isn't enabled.
+func GetRunMetadata() *RunMetadata {
+	return runMetadata
+}
+
+// SetRunConfigHash hashes the parsed flags of each module in the run, in order, for the config_hash field of the run
+// metadata. Runs with identical module configuration have identical hashes.
+func SetRunConfigHash(moduleFlags []any) error {
+	hash := sha256.New()
+	for _, flags := range moduleFlags {
+		encoded, err := json.Marshal(flags)
+		if err != nil {
+			return fmt.Errorf("could not encode module flags %T: %w", flags, err)
+		}
+		hash.Write(encoded)
+		hash.Write([]byte{'\n'})
+	}
+	runConfigHash = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+// getVersion returns Version if it was set at build time, or the module version/VCS revision from the build info.
+func getVersion() string {
+	if Version != "" {
+		return Version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	if info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			return setting.Value
+		}
+	}
+	return info.Main.Version
+}
+
+// newRunMetadata builds the metadata for a run started at start. A random scan ID is generated if scanID is empty.
+func newRunMetadata(scanID, operatorLabel string, start time.Time) (*RunMetadata, error) {
+	if scanID == "" {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return nil, fmt.Errorf("could not generate scan ID: %w", err)
+		}
+		scanID = hex.EncodeToString(id)
+	}
+	return &RunMetadata{
+		ScanID:        scanID,
+		OperatorLabel: operatorLabel,
+		Version:       getVersion(),
+		ConfigHash:    runConfigHash,
+		StartTime:     start.Format(time.RFC3339),
+	}, nil
+}
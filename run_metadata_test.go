@@ -0,0 +1,41 @@
+package zgrab2
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRunMetadata(t *testing.T) {
+	type flags struct {
+		Port uint
+		Name string
+	}
+	if err := SetRunConfigHash([]any{&flags{Port: 80, Name: "http"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first := runConfigHash
+	if err := SetRunConfigHash([]any{&flags{Port: 80, Name: "http"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if runConfigHash != first {
+		t.Errorf("identical configs produced different hashes (%s vs %s)", first, runConfigHash)
+	}
+	if err := SetRunConfigHash([]any{&flags{Port: 8080, Name: "http"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if runConfigHash == first {
+		t.Errorf("different configs produced the same hash (%s)", first)
+	}
+
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	md, err := newRunMetadata("", "lab", start)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(md.ScanID) != 32 || md.OperatorLabel != "lab" || md.StartTime != "2024-01-02T03:04:05Z" || md.ConfigHash != runConfigHash {
+		t.Errorf("wrong run metadata (got %+v)", md)
+	}
+	if md, _ = newRunMetadata("scan-1", "", start); md.ScanID != "scan-1" {
+		t.Errorf("wrong scan ID (got %s; expected scan-1)", md.ScanID)
+	}
+}
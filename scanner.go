@@ -79,9 +79,10 @@ func RunScanner(ctx context.Context, scanner Scanner, mon *Monitor, target ScanT
 	if dialerGroupConfig.BaseFlags.TargetTimeout > 0 {
 		// timeout is set, use it on the context
 		var cancel context.CancelFunc
-		ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(dialerGroupConfig.BaseFlags.TargetTimeout))
+		ctx, cancel = context.WithDeadline(ctx, time.Now().Add(dialerGroupConfig.BaseFlags.TargetTimeout))
 		defer cancel()
 	}
+	ctx, transport := withTransportRecorder(ctx)
 	status, res, e := scanner.Scan(ctx, dialerGroup, &target)
 	var err *string
 	if e == nil {
@@ -96,6 +97,6 @@ func RunScanner(ctx context.Context, scanner Scanner, mon *Monitor, target ScanT
 		errString := e.Error()
 		err = &errString
 	}
-	resp := ScanResponse{Result: res, Port: target.Port, Protocol: scanner.Protocol(), Error: err, Timestamp: t.Format(time.RFC3339), Status: status}
+	resp := ScanResponse{Result: res, Port: target.Port, Protocol: scanner.Protocol(), Transport: transport.transport, Error: err, Timestamp: t.Format(time.RFC3339), Status: status}
 	return scanner.GetName(), resp
 }
@@ -46,10 +46,15 @@ type TLSFlags struct {
 
 	// TODO: Do we just lump this with Verbose (and put Verbose in TLSFlags)?
 	KeepClientLogs bool `long:"keep-client-logs" description:"Include the client-side logs in the TLS handshake"`
+	// HandshakeTranscript records the handshake messages for offline analysis.
+	HandshakeTranscript bool `long:"handshake-transcript" description:"Include the raw handshake messages of both sides in transcript, with those TLS 1.3 encrypts decrypted"`
 
 	Time string `long:"time" description:"Explicit request time to use, instead of clock. YYYYMMDDhhmmss format."`
-	// TODO: directory? glob? How to map server name -> certificate?
-	Certificates string `long:"certificates" description:"Set of certificates to present to the server"`
+
+	Certificates              string `long:"certificates" description:"PEM file with a client certificate chain to present to servers requesting one, and its private key unless --certificate-key is set"`
+	CertificateKey            string `long:"certificate-key" description:"PEM file with the private key of the --certificates client certificate"`
+	CertificatePKCS12         string `long:"certificate-pkcs12" description:"PKCS#12 file with a client certificate and its private key to present to servers requesting one"`
+	CertificatePKCS12Password string `long:"certificate-pkcs12-password" description:"Password of the --certificate-pkcs12 file"`
 	// TODO: re-evaluate this, or at least specify the file format
 	CertificateMap string `long:"certificate-map" description:"A file mapping server names to certificates"`
 	// TODO: directory? glob?
@@ -70,8 +75,11 @@ type TLSFlags struct {
 	// TODO: format?
 	ClientRandom string `long:"client-random" description:"Set an explicit Client Random (base64 encoded)"`
 	// TODO: format?
-	ClientHello string `long:"client-hello" description:"Set an explicit ClientHello (base64 encoded)"`
-	OverrideSH  bool   `long:"override-sig-hash" description:"Override the default SignatureAndHashes TLS option with more expansive default"`
+	ClientHello         string `long:"client-hello" description:"Set an explicit ClientHello (base64 encoded)"`
+	ClientHelloHex      string `long:"client-hello-hex" description:"Set an explicit ClientHello (hex encoded), as a handshake message or a TLS record"`
+	ClientHelloFile     string `long:"client-hello-file" description:"File with an explicit ClientHello, raw or hex encoded, as a handshake message or a TLS record"`
+	ClientHelloTemplate string `long:"client-hello-template" choice:"chrome-131" choice:"firefox-128" choice:"openssl-3.0" description:"Send the ClientHello of a browser or library (cipher suites, extensions and their order, groups, signature algorithms, GREASE), limited to TLS 1.2"`
+	OverrideSH          bool   `long:"override-sig-hash" description:"Override the default SignatureAndHashes TLS option with more expansive default"`
 }
 
 // rootCAsStore is a struct to hold the value of the last x509.CertPool fetched using the RootCAs flag in TLSFlags
@@ -136,6 +144,16 @@ func getCSV(arg string) []string {
 	return ret[0]
 }
 
+// TLSKeyLogWriter returns the --tls-key-log-file to set as the KeyLogWriter of TLS configurations, or nil if session
+// secrets aren't logged. Both crypto/tls and zcrypto serialize writes to the key log, so every connection can share it.
+func TLSKeyLogWriter() io.Writer {
+	if config.tlsKeyLogFile == nil {
+		// a nil *os.File would be a non-nil io.Writer
+		return nil
+	}
+	return config.tlsKeyLogFile
+}
+
 func (t *TLSFlags) GetTLSConfig() (*tls.Config, error) {
 	return t.GetTLSConfigForTarget(nil)
 }
@@ -164,6 +182,8 @@ func (t *TLSFlags) GetTLSConfigForTarget(target *ScanTarget) (*tls.Config, error
 
 	ret := tls.Config{}
 
+	ret.KeyLogWriter = TLSKeyLogWriter()
+
 	if t.Time != "" {
 		// TODO: Find standard time format
 		var baseTime time.Time
@@ -178,9 +198,12 @@ func (t *TLSFlags) GetTLSConfigForTarget(target *ScanTarget) (*tls.Config, error
 			return baseTime.Add(offset)
 		}
 	}
-	if t.Certificates != "" {
-		// TODO FIXME: Implement
-		log.Fatalf("--certificates not implemented")
+	certificate, err := t.getClientCertificate()
+	if err != nil {
+		return nil, err
+	}
+	if certificate != nil {
+		ret.Certificates = []tls.Certificate{*certificate}
 	}
 	if t.CertificateMap != "" {
 		// TODO FIXME: Implement
@@ -291,11 +314,17 @@ func (t *TLSFlags) GetTLSConfigForTarget(target *ScanTarget) (*tls.Config, error
 		}
 	}
 
-	if t.ClientHello != "" {
-		ret.ExternalClientHello, err = base64.StdEncoding.DecodeString(t.ClientHello)
+	ret.ExternalClientHello, err = t.getExternalClientHello()
+	if err != nil {
+		return nil, err
+	}
+	if t.ClientHelloTemplate != "" {
+		ret.ClientFingerprintConfiguration, err = newClientHelloTemplate(t.ClientHelloTemplate)
 		if err != nil {
-			return nil, fmt.Errorf("error decoding --client-hello value '%s': %w", t.ClientHello, err)
+			return nil, err
 		}
+		// the templates offer the TLS 1.3 and GREASE cipher suites of the clients, which servers do not select in TLS 1.2
+		ret.ForceSuites = true
 	}
 
 	if t.OverrideSH {
@@ -315,13 +344,40 @@ func (t *TLSFlags) GetTLSConfigForTarget(target *ScanTarget) (*tls.Config, error
 
 type TLSConnection struct {
 	tls.Conn
-	flags *TLSFlags
-	log   *TLSLog
+	flags       *TLSFlags
+	log         *TLSLog
+	hellos      *helloRecorder
+	certRequest *certificateRequestRecorder
+	transcript  *transcriptRecorder
 }
 
 type TLSLog struct {
 	// TODO include TLSFlags?
 	HandshakeLog *tls.ServerHandshake `json:"handshake_log"`
+	// Fingerprints are computed from the hello messages of the handshake.
+	Fingerprints *TLSFingerprints `json:"fingerprints,omitempty"`
+	// ECH is the outcome of the Encrypted Client Hello handshake of the TLS module's --ech.
+	ECH *ECHResult `json:"ech,omitempty"`
+	// CT is the validation of the SCTs of the certificate of the TLS module's --validate-scts.
+	CT *CTResult `json:"ct,omitempty"`
+	// OCSP holds the stapled OCSP response, and that of the responder with the TLS module's --ocsp-query.
+	OCSP *OCSPLog `json:"ocsp,omitempty"`
+	// PQ is the group the server selects from the post-quantum key shares of the TLS module's --pq.
+	PQ *PQKeyShareResult `json:"pq,omitempty"`
+	// Heartbleed is the outcome of the heartbeat probe of the TLS module's --heartbleed.
+	Heartbleed *HeartbleedResult `json:"heartbleed,omitempty"`
+	// Resumption is the outcome of the session resumption attempts of the TLS module's --resumption.
+	Resumption *ResumptionResult `json:"resumption,omitempty"`
+	// CertificateRequest is the request of the server for a client certificate, whether or not one was sent.
+	CertificateRequest *CertificateRequest `json:"certificate_request,omitempty"`
+	// Enumeration holds the versions, cipher suites and groups the server accepts, of the TLS module's --enumerate.
+	Enumeration *EnumerationResult `json:"enumeration,omitempty"`
+	// ALPN holds the ALPN protocols the server selects when offered alone, of the TLS module's --alpn-enumerate.
+	ALPN *ALPNResult `json:"alpn,omitempty"`
+	// Downgrade holds the fallbacks with TLS_FALLBACK_SCSV of the TLS module's --downgrade.
+	Downgrade *DowngradeResult `json:"downgrade,omitempty"`
+	// Transcript holds the raw handshake messages, with --handshake-transcript.
+	Transcript *HandshakeTranscript `json:"transcript,omitempty"`
 }
 
 func (z *TLSConnection) GetLog() *TLSLog {
@@ -336,6 +392,15 @@ func (z *TLSConnection) Handshake() error {
 	log := z.GetLog()
 	defer func() {
 		log.HandshakeLog = z.GetHandshakeLog()
+		if z.hellos != nil {
+			log.Fingerprints = z.hellos.Fingerprints()
+		}
+		if z.certRequest != nil {
+			log.CertificateRequest = z.certRequest.request
+		}
+		if z.transcript != nil {
+			log.Transcript = z.transcript.Transcript()
+		}
 	}()
 	return z.Conn.Handshake()
 
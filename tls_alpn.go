@@ -0,0 +1,69 @@
+package zgrab2
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+)
+
+// ALPNAttempt is the outcome of a handshake offering a single ALPN protocol.
+type ALPNAttempt struct {
+	Protocol string `json:"protocol"`
+	// Selected tells whether the server selected the protocol, which servers without ALPN never do.
+	Selected bool `json:"selected"`
+	// Alert is the alert of a server rejecting the protocol, no_application_protocol with RFC 7301.
+	Alert string `json:"alert,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// ALPNResult holds the ALPN protocols a server selects, each offered alone in a handshake of its own.
+type ALPNResult struct {
+	Selected []string      `json:"selected,omitempty"`
+	Attempts []ALPNAttempt `json:"attempts"`
+}
+
+// probeALPNProtocol performs a handshake offering protocol as the only ALPN protocol.
+func probeALPNProtocol(ctx context.Context, dial func(context.Context) (net.Conn, error), serverName string, protocol string) ALPNAttempt {
+	attempt := ALPNAttempt{Protocol: protocol}
+	conn, err := dial(ctx)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer conn.Close()
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+		NextProtos:         []string{protocol},
+		KeyLogWriter:       TLSKeyLogWriter(),
+	})
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "remote error" {
+			attempt.Alert = opErr.Err.Error()
+		} else {
+			attempt.Error = err.Error()
+		}
+		return attempt
+	}
+	attempt.Selected = tlsConn.ConnectionState().NegotiatedProtocol == protocol
+	return attempt
+}
+
+// ProbeALPN offers each of protocols alone in a handshake on a connection opened with dial, and records which the
+// server selects.
+func ProbeALPN(ctx context.Context, dial func(context.Context) (net.Conn, error), serverName string, protocols []string) *ALPNResult {
+	result := &ALPNResult{}
+	for _, protocol := range protocols {
+		if ctx.Err() != nil {
+			break
+		}
+		attempt := probeALPNProtocol(ctx, dial, serverName, protocol)
+		if attempt.Selected {
+			result.Selected = append(result.Selected, protocol)
+		}
+		result.Attempts = append(result.Attempts, attempt)
+	}
+	return result
+}
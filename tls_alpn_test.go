@@ -0,0 +1,33 @@
+package zgrab2
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestProbeALPN(t *testing.T) {
+	server := httptest.NewUnstartedServer(nil)
+	server.TLS = &tls.Config{NextProtos: []string{"h2", "http/1.1"}}
+	server.StartTLS()
+	defer server.Close()
+	dial := func(ctx context.Context) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", server.Listener.Addr().String())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result := ProbeALPN(ctx, dial, "www.example", []string{"h2", "acme-tls/1", "http/1.1"})
+	if !slices.Equal(result.Selected, []string{"h2", "http/1.1"}) || len(result.Attempts) != 3 {
+		t.Fatalf("result: %+v", result)
+	}
+	// Go servers reject protocols they do not support
+	if attempt := result.Attempts[1]; attempt.Selected || attempt.Alert != "tls: no application protocol" || attempt.Error != "" {
+		t.Errorf("unsupported protocol: %+v", attempt)
+	}
+}
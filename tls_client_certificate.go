@@ -0,0 +1,114 @@
+package zgrab2
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/zmap/zcrypto/encoding/asn1"
+	"github.com/zmap/zcrypto/tls"
+	"github.com/zmap/zcrypto/x509/pkix"
+	"golang.org/x/crypto/pkcs12"
+)
+
+// CertificateRequest holds the CertificateRequest of a server asking the client to authenticate.
+type CertificateRequest struct {
+	// AcceptableCAs are the distinguished names of the CAs the server accepts client certificates of, none meaning
+	// any.
+	AcceptableCAs    []string `json:"acceptable_cas,omitempty"`
+	SignatureSchemes []string `json:"signature_schemes,omitempty"`
+	// CertificateSent tells whether a certificate of --certificates or --certificate-pkcs12 was sent in response.
+	CertificateSent bool `json:"certificate_sent"`
+}
+
+// certificateRequestRecorder answers the CertificateRequest of a server with the client certificate, if any, and
+// records it.
+type certificateRequestRecorder struct {
+	certificate *tls.Certificate
+	request     *CertificateRequest
+}
+
+func (r *certificateRequestRecorder) getClientCertificate(info *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.request = &CertificateRequest{CertificateSent: r.certificate != nil}
+	for _, raw := range info.AcceptableCAs {
+		var rdns pkix.RDNSequence
+		if _, err := asn1.Unmarshal(raw, &rdns); err != nil {
+			continue
+		}
+		var name pkix.Name
+		name.FillFromRDNSequence(&rdns)
+		r.request.AcceptableCAs = append(r.request.AcceptableCAs, name.String())
+	}
+	for _, scheme := range info.SignatureSchemes {
+		r.request.SignatureSchemes = append(r.request.SignatureSchemes, scheme.String())
+	}
+	if r.certificate == nil {
+		// an empty Certificate message declines to authenticate
+		return new(tls.Certificate), nil
+	}
+	return r.certificate, nil
+}
+
+// clientCertificateCache holds the client certificate of the flags, which are constant across targets in CLI usage.
+type clientCertificateCache struct {
+	sync.Mutex
+	key         string
+	certificate *tls.Certificate
+}
+
+var clientCertificates clientCertificateCache
+
+// getClientCertificate returns the client certificate of --certificates or --certificate-pkcs12, or nil if neither
+// is set.
+func (t *TLSFlags) getClientCertificate() (*tls.Certificate, error) {
+	if t.Certificates == "" && t.CertificatePKCS12 == "" {
+		return nil, nil
+	}
+	if t.Certificates != "" && t.CertificatePKCS12 != "" {
+		return nil, errors.New("--certificates and --certificate-pkcs12 are mutually exclusive")
+	}
+	key := t.Certificates + "\x00" + t.CertificateKey + "\x00" + t.CertificatePKCS12
+	clientCertificates.Lock()
+	defer clientCertificates.Unlock()
+	if clientCertificates.key == key {
+		return clientCertificates.certificate, nil
+	}
+	var certificate tls.Certificate
+	var err error
+	if t.CertificatePKCS12 != "" {
+		certificate, err = loadPKCS12(t.CertificatePKCS12, t.CertificatePKCS12Password)
+	} else if t.CertificateKey != "" {
+		certificate, err = tls.LoadX509KeyPair(t.Certificates, t.CertificateKey)
+	} else {
+		// the key is in the certificate file
+		certificate, err = tls.LoadX509KeyPair(t.Certificates, t.Certificates)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not load the client certificate: %w", err)
+	}
+	clientCertificates.key, clientCertificates.certificate = key, &certificate
+	return &certificate, nil
+}
+
+// loadPKCS12 reads a client certificate and its private key from a PKCS#12 file.
+func loadPKCS12(file string, password string) (tls.Certificate, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	blocks, err := pkcs12.ToPEM(data, password)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	var certificates, keys []byte
+	for _, block := range blocks {
+		if block.Type == "CERTIFICATE" {
+			certificates = append(certificates, pem.EncodeToMemory(block)...)
+		} else {
+			keys = append(keys, pem.EncodeToMemory(block)...)
+		}
+	}
+	return tls.X509KeyPair(certificates, keys)
+}
@@ -0,0 +1,86 @@
+package zgrab2
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	stdx509 "crypto/x509"
+	stdpkix "crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestClientCertificate(t *testing.T) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &stdx509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               stdpkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              stdx509.KeyUsageCertSign,
+	}
+	caDER, _ := stdx509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	ca, _ := stdx509.ParseCertificate(caDER)
+	clientKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	clientDER, _ := stdx509.CreateCertificate(rand.Reader, &stdx509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      stdpkix.Name{CommonName: "zgrab2"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []stdx509.ExtKeyUsage{stdx509.ExtKeyUsageClientAuth},
+	}, ca, &clientKey.PublicKey, caKey)
+	keyDER, _ := stdx509.MarshalPKCS8PrivateKey(clientKey)
+	certificateFile := filepath.Join(t.TempDir(), "client.pem")
+	keyFile := filepath.Join(t.TempDir(), "client.key")
+	os.WriteFile(certificateFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: clientDER}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600)
+
+	clientCAs := stdx509.NewCertPool()
+	clientCAs.AddCert(ca)
+	server := httptest.NewUnstartedServer(nil)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs, MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+	addr := server.Listener.Addr().(*net.TCPAddr)
+	handshake := func(tlsFlags *TLSFlags) (*TLSConnection, error) {
+		conn, err := net.Dial("tcp", addr.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return GetDefaultTLSWrapper(tlsFlags)(context.Background(), &ScanTarget{IP: addr.IP, Port: uint(addr.Port)}, conn)
+	}
+
+	tlsConn, err := handshake(&TLSFlags{})
+	if err == nil {
+		t.Error("handshake without a client certificate succeeded")
+	}
+	request := tlsConn.GetLog().CertificateRequest
+	if request == nil || !slices.Equal(request.AcceptableCAs, []string{"CN=Test CA"}) || request.CertificateSent ||
+		!slices.Contains(request.SignatureSchemes, "ECDSAWithP256AndSHA256") {
+		t.Errorf("certificate request without a client certificate: %+v", request)
+	}
+
+	tlsConn, err = handshake(&TLSFlags{Certificates: certificateFile, CertificateKey: keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request := tlsConn.GetLog().CertificateRequest; request == nil || !request.CertificateSent {
+		t.Errorf("certificate request with a client certificate: %+v", request)
+	}
+
+	if _, err := (&TLSFlags{Certificates: certificateFile, CertificatePKCS12: keyFile}).GetTLSConfig(); err == nil {
+		t.Error("GetTLSConfig accepted both --certificates and --certificate-pkcs12")
+	}
+}
@@ -0,0 +1,248 @@
+package zgrab2
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/zmap/zcrypto/tls"
+)
+
+// rawExtension is a ClientHello extension sent as is, without effect on the handshake.
+type rawExtension struct {
+	id   uint16
+	data []byte
+}
+
+func (e *rawExtension) WriteToConfig(*tls.Config) error {
+	return nil
+}
+
+func (e *rawExtension) CheckImplemented() error {
+	return nil
+}
+
+func (e *rawExtension) Marshal() []byte {
+	b := []byte{byte(e.id >> 8), byte(e.id), byte(len(e.data) >> 8), byte(len(e.data))}
+	return append(b, e.data...)
+}
+
+// uint16ListExtension is an extension holding a list of 16-bit values with a 16-bit length prefix.
+func uint16ListExtension(id uint16, values ...uint16) *rawExtension {
+	data := []byte{byte(len(values) >> 7), byte(len(values) << 1)}
+	for _, v := range values {
+		data = append(data, byte(v>>8), byte(v))
+	}
+	return &rawExtension{id: id, data: data}
+}
+
+// supportedGroupsExtension offers groups, including GREASE values and groups zcrypto does not implement, while the
+// key exchange uses the implemented ones.
+type supportedGroupsExtension struct {
+	*rawExtension
+	groups []uint16
+}
+
+func newSupportedGroupsExtension(groups ...uint16) *supportedGroupsExtension {
+	return &supportedGroupsExtension{rawExtension: uint16ListExtension(extensionSupportedGroups, groups...), groups: groups}
+}
+
+func (e *supportedGroupsExtension) WriteToConfig(c *tls.Config) error {
+	c.CurvePreferences = nil
+	for _, group := range e.groups {
+		if curve := tls.CurveID(group); slices.Contains([]tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384, tls.CurveP521}, curve) {
+			c.CurvePreferences = append(c.CurvePreferences, curve)
+		}
+	}
+	return nil
+}
+
+// signatureAlgorithmsExtension offers signature schemes, including those of TLS 1.3, while the server key exchange
+// is verified with the TLS 1.2 hash and signature pairs among them.
+type signatureAlgorithmsExtension struct {
+	*rawExtension
+	schemes []uint16
+}
+
+func newSignatureAlgorithmsExtension(schemes ...uint16) *signatureAlgorithmsExtension {
+	return &signatureAlgorithmsExtension{rawExtension: uint16ListExtension(extensionSignatureAlgorithms, schemes...), schemes: schemes}
+}
+
+func (e *signatureAlgorithmsExtension) WriteToConfig(c *tls.Config) error {
+	c.SignatureAndHashes = nil
+	for _, scheme := range e.schemes {
+		hash, signature := uint8(scheme>>8), uint8(scheme)
+		// MD5 to SHA-512 with RSA, DSA or ECDSA (RFC 5246 section 7.4.1.4.1)
+		if hash >= 1 && hash <= 6 && signature >= 1 && signature <= 3 {
+			c.SignatureAndHashes = append(c.SignatureAndHashes, tls.SigAndHash{Hash: hash, Signature: signature})
+		}
+	}
+	return nil
+}
+
+func alpnExtension() *tls.ALPNExtension {
+	return &tls.ALPNExtension{Protocols: []string{"h2", "http/1.1"}}
+}
+
+// clientHelloTemplates build the ClientHellos of --client-hello-template. They reproduce the cipher suites, the
+// extensions and their order, groups, signature schemes and GREASE values of the clients, less the extensions that
+// only negotiate TLS 1.3 (supported_versions, key_share, psk_key_exchange_modes) and padding, since zcrypto cannot
+// complete a TLS 1.3 handshake from a template, and extended_master_secret, which zcrypto offers but does not
+// implement.
+var clientHelloTemplates = map[string]func() *tls.ClientFingerprintConfiguration{
+	"chrome-131": func() *tls.ClientFingerprintConfiguration {
+		return &tls.ClientFingerprintConfiguration{
+			CipherSuites: []uint16{0x2a2a, 0x1301, 0x1302, 0x1303, 0xc02b, 0xc02f, 0xc02c, 0xc030, 0xcca9, 0xcca8,
+				0xc013, 0xc014, 0x009c, 0x009d, 0x002f, 0x0035},
+			Extensions: []tls.ClientExtension{
+				&rawExtension{id: 0x0a0a},
+				&tls.SNIExtension{Autopopulate: true},
+				&tls.SecureRenegotiationExtension{},
+				newSupportedGroupsExtension(0x3a3a, 0x001d, 0x0017, 0x0018),
+				&tls.PointFormatExtension{Formats: []uint8{0}},
+				&tls.SessionTicketExtension{},
+				alpnExtension(),
+				&tls.StatusRequestExtension{},
+				newSignatureAlgorithmsExtension(0x0403, 0x0804, 0x0401, 0x0503, 0x0805, 0x0501, 0x0806, 0x0601),
+				&tls.SCTExtension{},
+				// compress_certificate with brotli
+				&rawExtension{id: 0x001b, data: []byte{0x02, 0x00, 0x02}},
+				// application_settings for h2
+				&rawExtension{id: 0x4469, data: []byte{0x00, 0x03, 0x02, 'h', '2'}},
+				&rawExtension{id: 0x1a1a, data: []byte{0x00}},
+			},
+		}
+	},
+	"firefox-128": func() *tls.ClientFingerprintConfiguration {
+		return &tls.ClientFingerprintConfiguration{
+			CipherSuites: []uint16{0x1301, 0x1303, 0x1302, 0xc02b, 0xc02f, 0xcca9, 0xcca8, 0xc02c, 0xc030, 0xc00a,
+				0xc009, 0xc013, 0xc014, 0x009c, 0x009d, 0x002f, 0x0035},
+			Extensions: []tls.ClientExtension{
+				&tls.SNIExtension{Autopopulate: true},
+				&tls.SecureRenegotiationExtension{},
+				newSupportedGroupsExtension(0x001d, 0x0017, 0x0018, 0x0019, 0x0100, 0x0101),
+				&tls.PointFormatExtension{Formats: []uint8{0}},
+				&tls.SessionTicketExtension{},
+				alpnExtension(),
+				&tls.StatusRequestExtension{},
+				// delegated_credentials
+				uint16ListExtension(0x0022, 0x0403, 0x0503, 0x0603, 0x0203),
+				newSignatureAlgorithmsExtension(0x0403, 0x0503, 0x0603, 0x0804, 0x0805, 0x0806, 0x0401, 0x0501,
+					0x0601, 0x0203, 0x0201),
+				// record_size_limit
+				&rawExtension{id: 0x001c, data: []byte{0x40, 0x01}},
+			},
+		}
+	},
+	"openssl-3.0": func() *tls.ClientFingerprintConfiguration {
+		return &tls.ClientFingerprintConfiguration{
+			CipherSuites: []uint16{0x1302, 0x1303, 0x1301, 0xc02c, 0xc030, 0x009f, 0xcca9, 0xcca8, 0xccaa, 0xc02b,
+				0xc02f, 0x009e, 0xc024, 0xc028, 0x006b, 0xc023, 0xc027, 0x0067, 0xc00a, 0xc014, 0x0039, 0xc009,
+				0xc013, 0x0033, 0x009d, 0x009c, 0x003d, 0x003c, 0x0035, 0x002f, 0x00ff},
+			Extensions: []tls.ClientExtension{
+				&tls.SNIExtension{Autopopulate: true},
+				// ec_point_formats: uncompressed, ansiX962_compressed_prime, ansiX962_compressed_char2
+				&rawExtension{id: extensionECPointFormats, data: []byte{0x03, 0x00, 0x01, 0x02}},
+				newSupportedGroupsExtension(0x001d, 0x0017, 0x001e, 0x0019, 0x0018, 0x0100, 0x0101, 0x0102, 0x0103,
+					0x0104),
+				&tls.SessionTicketExtension{},
+				// encrypt_then_mac
+				&rawExtension{id: 0x0016},
+				newSignatureAlgorithmsExtension(0x0403, 0x0503, 0x0603, 0x0807, 0x0808, 0x0809, 0x080a, 0x080b,
+					0x0804, 0x0805, 0x0806, 0x0401, 0x0501, 0x0601),
+			},
+		}
+	},
+}
+
+// newClientHelloTemplate returns the fingerprint configuration of a template, with a random session ID as the
+// clients send.
+func newClientHelloTemplate(name string) (*tls.ClientFingerprintConfiguration, error) {
+	template, ok := clientHelloTemplates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown ClientHello template %s", name)
+	}
+	fingerprint := template()
+	fingerprint.HandshakeVersion = tls.VersionTLS12
+	fingerprint.CompressionMethods = []uint8{0}
+	fingerprint.SessionID = make([]byte, 32)
+	if _, err := rand.Read(fingerprint.SessionID); err != nil {
+		return nil, err
+	}
+	return fingerprint, nil
+}
+
+// decodeClientHello decodes a ClientHello given as raw bytes or hex. A ClientHello in a TLS record, as captured on the
+// wire, is taken out of it.
+func decodeClientHello(data []byte) ([]byte, error) {
+	if decoded, err := hex.DecodeString(string(bytes.Join(bytes.Fields(data), nil))); err == nil {
+		data = decoded
+	}
+	if len(data) > 5 && data[0] == recordTypeHandshake {
+		data = data[5:]
+	}
+	if len(data) < 4 || data[0] != handshakeTypeClientHello {
+		return nil, errors.New("not a ClientHello message")
+	}
+	return data, nil
+}
+
+// clientHelloFiles caches the ClientHellos read from --client-hello-file.
+var clientHelloFiles sync.Map
+
+func readClientHelloFile(name string) ([]byte, error) {
+	if hello, ok := clientHelloFiles.Load(name); ok {
+		return hello.([]byte), nil
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	hello, err := decodeClientHello(data)
+	if err != nil {
+		return nil, err
+	}
+	clientHelloFiles.Store(name, hello)
+	return hello, nil
+}
+
+// getExternalClientHello returns the ClientHello given with --client-hello, --client-hello-hex or
+// --client-hello-file, if any.
+func (t *TLSFlags) getExternalClientHello() ([]byte, error) {
+	set := 0
+	for _, value := range []string{t.ClientHello, t.ClientHelloHex, t.ClientHelloFile, t.ClientHelloTemplate} {
+		if value != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return nil, errors.New("only one of --client-hello, --client-hello-hex, --client-hello-file and --client-hello-template may be set")
+	}
+	switch {
+	case t.ClientHello != "":
+		hello, err := base64.StdEncoding.DecodeString(t.ClientHello)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding --client-hello value '%s': %w", t.ClientHello, err)
+		}
+		return hello, nil
+	case t.ClientHelloHex != "":
+		hello, err := decodeClientHello([]byte(t.ClientHelloHex))
+		if err != nil {
+			return nil, fmt.Errorf("error decoding --client-hello-hex: %w", err)
+		}
+		return hello, nil
+	case t.ClientHelloFile != "":
+		hello, err := readClientHelloFile(t.ClientHelloFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading --client-hello-file %s: %w", t.ClientHelloFile, err)
+		}
+		return hello, nil
+	}
+	return nil, nil
+}
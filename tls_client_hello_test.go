@@ -0,0 +1,79 @@
+package zgrab2
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecodeClientHello(t *testing.T) {
+	record := chromeClientHello()
+	hexRecord := hex.EncodeToString(record)
+	for name, data := range map[string][]byte{
+		"raw record":    record,
+		"raw message":   record[5:],
+		"hex record":    []byte(hexRecord),
+		"wrapped hex":   []byte(hexRecord[:64] + "\n" + hexRecord[64:] + "\n"),
+		"hex message":   []byte(hex.EncodeToString(record[5:])),
+		"uppercase hex": []byte(strings.ToUpper(hexRecord)),
+	} {
+		hello, err := decodeClientHello(data)
+		if err != nil || !bytes.Equal(hello, record[5:]) {
+			t.Errorf("%s: decodeClientHello = %x, %v", name, hello, err)
+		}
+	}
+	if _, err := decodeClientHello([]byte("16030100")); err == nil {
+		t.Error("truncated record accepted")
+	}
+
+	file := filepath.Join(t.TempDir(), "hello.hex")
+	if err := os.WriteFile(file, []byte(hexRecord), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	hello, err := (&TLSFlags{ClientHelloFile: file}).getExternalClientHello()
+	if err != nil || !bytes.Equal(hello, record[5:]) {
+		t.Errorf("--client-hello-file: %x, %v", hello, err)
+	}
+	if _, err := (&TLSFlags{ClientHelloHex: hexRecord, ClientHelloTemplate: "chrome-131"}).GetTLSConfig(); err == nil {
+		t.Error("several ClientHellos accepted")
+	}
+}
+
+func TestClientHelloTemplates(t *testing.T) {
+	server := httptest.NewUnstartedServer(nil)
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS13}
+	server.StartTLS()
+	defer server.Close()
+	addr := server.Listener.Addr().(*net.TCPAddr)
+
+	for name := range clientHelloTemplates {
+		conn, err := net.Dial("tcp", addr.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		target := &ScanTarget{IP: addr.IP, Port: uint(addr.Port), Domain: "example.com"}
+		tlsConn, err := GetDefaultTLSWrapper(&TLSFlags{ClientHelloTemplate: name})(context.Background(), target, conn)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			conn.Close()
+			continue
+		}
+		tlsLog := tlsConn.GetLog()
+		tlsConn.Close()
+		template, _ := newClientHelloTemplate(name)
+		wantCiphers := joinDecimal(withoutGREASE(template.CipherSuites))
+		if fields := strings.Split(tlsLog.Fingerprints.JA3String, ","); fields[1] != wantCiphers {
+			t.Errorf("%s: sent cipher suites %s, want %s", name, fields[1], wantCiphers)
+		}
+		if !strings.Contains(tlsLog.Fingerprints.JA4, "d") {
+			t.Errorf("%s: no SNI in JA4 %s", name, tlsLog.Fingerprints.JA4)
+		}
+	}
+}
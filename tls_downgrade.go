@@ -0,0 +1,105 @@
+package zgrab2
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"slices"
+)
+
+const (
+	cipherSuiteFallbackSCSV = 0x5600
+
+	alertInappropriateFallback = 86
+)
+
+// downgradeSentinelTLS12 ends the ServerHello random of TLS 1.3 servers negotiating TLS 1.2 (RFC 8446 section 4.1.3).
+var downgradeSentinelTLS12 = []byte("DOWNGRD\x01")
+
+// FallbackAttempt is the outcome of a handshake of a version below the highest of the server, signaling a fallback
+// with TLS_FALLBACK_SCSV.
+type FallbackAttempt struct {
+	Version string `json:"version"`
+	// Accepted tells whether the server completed the fallback up to its ServerHello.
+	Accepted bool `json:"accepted"`
+	// Rejected tells whether the server rejected the fallback with an inappropriate_fallback alert, as RFC 7507
+	// requires.
+	Rejected bool   `json:"rejected"`
+	Alert    string `json:"alert,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// DowngradeResult holds how a server handles version downgrades.
+type DowngradeResult struct {
+	MaxVersion string            `json:"max_version,omitempty"`
+	Fallbacks  []FallbackAttempt `json:"fallbacks,omitempty"`
+	// SCSVSupported tells whether the server rejected fallbacks, and accepted none.
+	SCSVSupported bool `json:"scsv_supported"`
+	// DowngradeSentinel tells whether a TLS 1.3 server puts the downgrade sentinel in the random of a TLS 1.2
+	// ServerHello. It is only checked with TLS 1.3 servers accepting TLS 1.2.
+	DowngradeSentinel *bool  `json:"downgrade_sentinel,omitempty"`
+	Error             string `json:"error,omitempty"`
+}
+
+// ProbeDowngrade finds the highest version of the server, and then attempts handshakes of each lower version with
+// TLS_FALLBACK_SCSV, on connections opened with dial.
+func ProbeDowngrade(ctx context.Context, dial func(context.Context) (net.Conn, error), serverName string) *DowngradeResult {
+	result := &DowngradeResult{}
+	e := &enumerator{dial: dial, serverName: serverName}
+	suites := legacyCipherSuites()
+	maxVersion := -1
+	for i, version := range enumerationVersions {
+		offered := suites
+		if version == tls.VersionTLS13 {
+			offered = tls13CipherSuites
+		}
+		if _, err := e.handshake(ctx, version, offered, enumerationGroups, false); err == nil {
+			maxVersion = i
+			break
+		}
+		if ctx.Err() != nil {
+			result.Error = ctx.Err().Error()
+			return result
+		}
+	}
+	if maxVersion < 0 {
+		result.Error = "no version accepted"
+		return result
+	}
+	result.MaxVersion = tls.VersionName(enumerationVersions[maxVersion])
+
+	fallbackSuites := append(slices.Clone(suites), cipherSuiteFallbackSCSV)
+	var accepted, rejected bool
+	for _, version := range enumerationVersions[maxVersion+1:] {
+		attempt := FallbackAttempt{Version: tls.VersionName(version)}
+		selected, err := e.handshake(ctx, version, fallbackSuites, enumerationGroups, false)
+		var alert tls.AlertError
+		switch {
+		case errors.As(err, &alert):
+			attempt.Alert = alert.Error()
+			attempt.Rejected = alert == alertInappropriateFallback
+		case err != nil:
+			attempt.Error = err.Error()
+		default:
+			attempt.Accepted = true
+		}
+		if selected != nil && enumerationVersions[maxVersion] == tls.VersionTLS13 && version == tls.VersionTLS12 {
+			sentinel := bytes.HasSuffix(selected.random, downgradeSentinelTLS12)
+			result.DowngradeSentinel = &sentinel
+		}
+		accepted, rejected = accepted || attempt.Accepted, rejected || attempt.Rejected
+		result.Fallbacks = append(result.Fallbacks, attempt)
+	}
+	result.SCSVSupported = rejected && !accepted
+
+	if enumerationVersions[maxVersion] == tls.VersionTLS13 && result.DowngradeSentinel == nil {
+		// the fallback was rejected, but a TLS 1.2 handshake without TLS_FALLBACK_SCSV shows the sentinel
+		if selected, err := e.handshake(ctx, tls.VersionTLS12, suites, enumerationGroups, false); err == nil {
+			sentinel := bytes.HasSuffix(selected.random, downgradeSentinelTLS12)
+			result.DowngradeSentinel = &sentinel
+		}
+	}
+	return result
+}
@@ -0,0 +1,53 @@
+package zgrab2
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProbeDowngrade(t *testing.T) {
+	newServer := func(config *tls.Config) func(context.Context) (net.Conn, error) {
+		server := httptest.NewUnstartedServer(nil)
+		server.TLS = config
+		server.StartTLS()
+		t.Cleanup(server.Close)
+		return func(ctx context.Context) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "tcp", server.Listener.Addr().String())
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result := ProbeDowngrade(ctx, newServer(&tls.Config{}), "www.example")
+	if result.MaxVersion != "TLS 1.3" || !result.SCSVSupported || result.DowngradeSentinel == nil || !*result.DowngradeSentinel {
+		t.Errorf("TLS 1.3 server: %+v", result)
+	}
+	if len(result.Fallbacks) != 3 || !result.Fallbacks[0].Rejected || result.Fallbacks[0].Alert != "tls: inappropriate fallback" {
+		t.Errorf("TLS 1.3 server fallbacks: %+v", result.Fallbacks)
+	}
+
+	// the server supports at most the version of each ClientHello, so it never sees a fallback
+	server := httptest.NewUnstartedServer(nil)
+	server.TLS = &tls.Config{MinVersion: tls.VersionTLS10, GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		config := server.TLS.Clone()
+		config.GetConfigForClient = nil
+		config.MaxVersion = hello.SupportedVersions[0]
+		return config, nil
+	}}
+	server.StartTLS()
+	defer server.Close()
+	ignoring := func(ctx context.Context) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", server.Listener.Addr().String())
+	}
+	result = ProbeDowngrade(ctx, ignoring, "www.example")
+	if result.SCSVSupported || result.DowngradeSentinel == nil || *result.DowngradeSentinel || len(result.Fallbacks) != 3 ||
+		!result.Fallbacks[0].Accepted {
+		t.Errorf("server ignoring TLS_FALLBACK_SCSV: %+v", result)
+	}
+}
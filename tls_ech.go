@@ -0,0 +1,206 @@
+package zgrab2
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// ECH config sources of an ECHResult.
+const (
+	ECHConfigFromFlag   = "flag"
+	ECHConfigFromDNS    = "dns"
+	ECHConfigFromGREASE = "grease"
+)
+
+const (
+	echConfigVersion = 0xfe0d
+	hpkeKEMX25519    = 0x0020
+	hpkeKDFSHA256    = 0x0001
+	hpkeAEADAES128   = 0x0001
+)
+
+// ECHResult is the outcome of an Encrypted Client Hello handshake (draft-ietf-tls-esni), attempted on a connection of
+// its own.
+type ECHResult struct {
+	// ConfigSource is where the ECHConfigList came from: a flag, the HTTPS record of the server name in DNS, or
+	// GREASE, a random config that servers supporting ECH reject by sending their retry configs.
+	ConfigSource string `json:"config_source"`
+	ConfigList   []byte `json:"config_list,omitempty"`
+	// PublicName is the name of the first config, sent in the outer ClientHello.
+	PublicName string `json:"public_name,omitempty"`
+
+	Accepted bool `json:"accepted"`
+	// RetryConfigs are the configs the server sent when it rejected ECH. A server that does not support ECH
+	// completes the handshake with the outer ClientHello without sending any.
+	RetryConfigs []byte `json:"retry_configs,omitempty"`
+	// RetryAccepted is set if the handshake was attempted again with the retry configs, and tells whether the server
+	// accepted them.
+	RetryAccepted *bool `json:"retry_accepted,omitempty"`
+
+	DNSError string `json:"dns_error,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// marshalECHConfigList returns an ECHConfigList holding a single X25519 config.
+func marshalECHConfigList(configID uint8, publicKey []byte, publicName string) []byte {
+	var b cryptobyte.Builder
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint16(echConfigVersion)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint8(configID)
+			b.AddUint16(hpkeKEMX25519)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(publicKey) })
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddUint16(hpkeKDFSHA256)
+				b.AddUint16(hpkeAEADAES128)
+			})
+			// maximum_name_length
+			b.AddUint8(0)
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte(publicName)) })
+			// extensions
+			b.AddUint16(0)
+		})
+	})
+	return b.BytesOrPanic()
+}
+
+// greaseECHConfigList returns an ECHConfigList with a random key and config ID.
+func greaseECHConfigList(publicName string) ([]byte, error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	configID := make([]byte, 1)
+	if _, err := rand.Read(configID); err != nil {
+		return nil, err
+	}
+	return marshalECHConfigList(configID[0], key.PublicKey().Bytes(), publicName), nil
+}
+
+// echPublicName returns the public name of the first config of an ECHConfigList with a known version.
+func echPublicName(list []byte) string {
+	s := cryptobyte.String(list)
+	var configs cryptobyte.String
+	if !s.ReadUint16LengthPrefixed(&configs) {
+		return ""
+	}
+	for !configs.Empty() {
+		var version uint16
+		var config cryptobyte.String
+		if !configs.ReadUint16(&version) || !configs.ReadUint16LengthPrefixed(&config) {
+			return ""
+		}
+		if version != echConfigVersion {
+			continue
+		}
+		var publicKey, cipherSuites, publicName cryptobyte.String
+		if !config.Skip(3) || !config.ReadUint16LengthPrefixed(&publicKey) ||
+			!config.ReadUint16LengthPrefixed(&cipherSuites) || !config.Skip(1) ||
+			!config.ReadUint8LengthPrefixed(&publicName) {
+			return ""
+		}
+		return string(publicName)
+	}
+	return ""
+}
+
+// lookupECHConfigList returns the ECHConfigList of the first HTTPS record of name that has one.
+func lookupECHConfigList(ctx context.Context, nameserver, name string) ([]byte, error) {
+	response, err := ExchangeDNS(ctx, nameserver, name, dnsmessage.TypeHTTPS)
+	if err != nil {
+		return nil, fmt.Errorf("HTTPS lookup of %s: %w", name, err)
+	}
+	for _, answer := range response.Answers {
+		if https, ok := answer.Body.(*dnsmessage.HTTPSResource); ok {
+			if list, ok := https.GetParam(dnsmessage.SVCParamECH); ok {
+				return list, nil
+			}
+		}
+	}
+	if response.Truncated {
+		return nil, fmt.Errorf("HTTPS lookup of %s: truncated response", name)
+	}
+	return nil, fmt.Errorf("no ECH config in the HTTPS records of %s", name)
+}
+
+// echHandshake performs a TLS 1.3 handshake offering ECH with configList on a new connection, and returns whether
+// the server accepted it, or the retry configs it sent when rejecting it.
+func echHandshake(ctx context.Context, dial func(context.Context) (net.Conn, error), serverName string, configList []byte) (bool, []byte, error) {
+	conn, err := dial(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+	defer conn.Close()
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:                     serverName,
+		InsecureSkipVerify:             true,
+		MinVersion:                     tls.VersionTLS13,
+		EncryptedClientHelloConfigList: configList,
+		// scans do not verify certificates, including that of the public name when ECH is rejected
+		EncryptedClientHelloRejectionVerify: func(tls.ConnectionState) error { return nil },
+		KeyLogWriter:                        TLSKeyLogWriter(),
+	})
+	err = tlsConn.HandshakeContext(ctx)
+	var rejection *tls.ECHRejectionError
+	if errors.As(err, &rejection) {
+		return false, rejection.RetryConfigList, nil
+	}
+	if err != nil {
+		return false, nil, err
+	}
+	return tlsConn.ConnectionState().ECHAccepted, nil, nil
+}
+
+// ProbeECH attempts an ECH handshake for serverName on connections opened with dial. Without a configList, the config
+// is looked up in the HTTPS record of serverName if lookupDNS is set, and GREASE is sent otherwise or if there is
+// none. When the server rejects the config with retry configs, the handshake is retried with them.
+func ProbeECH(ctx context.Context, dial func(context.Context) (net.Conn, error), serverName string, configList []byte, lookupDNS bool) *ECHResult {
+	result := &ECHResult{ConfigSource: ECHConfigFromFlag, ConfigList: configList}
+	if serverName == "" || net.ParseIP(serverName) != nil {
+		result.Error = "ECH requires a server name"
+		return result
+	}
+	if configList == nil && lookupDNS {
+		nameserver, err := DNSNameserver()
+		if err == nil {
+			configList, err = lookupECHConfigList(ctx, nameserver, serverName)
+		}
+		if err != nil {
+			result.DNSError = err.Error()
+		} else {
+			result.ConfigSource, result.ConfigList = ECHConfigFromDNS, configList
+		}
+	}
+	if configList == nil {
+		var err error
+		if configList, err = greaseECHConfigList(serverName); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		result.ConfigSource, result.ConfigList = ECHConfigFromGREASE, configList
+	}
+	result.PublicName = echPublicName(configList)
+
+	accepted, retryConfigs, err := echHandshake(ctx, dial, serverName, configList)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Accepted, result.RetryConfigs = accepted, retryConfigs
+	if len(retryConfigs) > 0 {
+		retryAccepted, _, err := echHandshake(ctx, dial, serverName, retryConfigs)
+		result.RetryAccepted = &retryAccepted
+		if err != nil {
+			result.Error = fmt.Sprintf("retry with the retry configs: %v", err)
+		}
+	}
+	return result
+}
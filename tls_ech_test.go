@@ -0,0 +1,117 @@
+package zgrab2
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/tls"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestProbeECH(t *testing.T) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configList := marshalECHConfigList(7, key.PublicKey().Bytes(), "public.example")
+	if name := echPublicName(configList); name != "public.example" {
+		t.Errorf("echPublicName = %q", name)
+	}
+
+	echServer := httptest.NewUnstartedServer(nil)
+	echServer.TLS = &tls.Config{EncryptedClientHelloKeys: []tls.EncryptedClientHelloKey{
+		{Config: configList[2:], PrivateKey: key.Bytes(), SendAsRetry: true},
+	}}
+	echServer.StartTLS()
+	defer echServer.Close()
+	plainServer := httptest.NewTLSServer(nil)
+	defer plainServer.Close()
+	dialer := func(server *httptest.Server) func(context.Context) (net.Conn, error) {
+		return func(ctx context.Context) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "tcp", server.Listener.Addr().String())
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result := ProbeECH(ctx, dialer(echServer), "www.example", configList, false)
+	if !result.Accepted || result.ConfigSource != ECHConfigFromFlag || result.Error != "" {
+		t.Errorf("with the server's config: %+v", result)
+	}
+
+	result = ProbeECH(ctx, dialer(echServer), "www.example", nil, false)
+	if result.Accepted || result.ConfigSource != ECHConfigFromGREASE || result.PublicName != "www.example" ||
+		!bytes.Equal(result.RetryConfigs, configList) || result.RetryAccepted == nil || !*result.RetryAccepted {
+		t.Errorf("GREASE to an ECH server: %+v", result)
+	}
+
+	result = ProbeECH(ctx, dialer(plainServer), "www.example", nil, false)
+	if result.Accepted || result.RetryConfigs != nil || result.RetryAccepted != nil || result.Error != "" {
+		t.Errorf("GREASE to a server without ECH: %+v", result)
+	}
+
+	if result := ProbeECH(ctx, dialer(plainServer), "192.0.2.1", nil, false); result.Error == "" {
+		t.Errorf("ECH without a server name: %+v", result)
+	}
+}
+
+func TestLookupECHConfigList(t *testing.T) {
+	configList := marshalECHConfigList(1, make([]byte, 32), "public.example")
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if query.Unpack(buf[:n]) != nil || len(query.Questions) != 1 || query.Questions[0].Type != dnsmessage.TypeHTTPS {
+				continue
+			}
+			question := query.Questions[0]
+			response := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true},
+				Questions: query.Questions,
+			}
+			if question.Name.String() == "ech.example." {
+				response.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: 60},
+					Body: &dnsmessage.HTTPSResource{SVCBResource: dnsmessage.SVCBResource{
+						Priority: 1,
+						Target:   dnsmessage.MustNewName("."),
+						Params: []dnsmessage.SVCParam{
+							{Key: dnsmessage.SVCParamALPN, Value: []byte("\x02h2")},
+							{Key: dnsmessage.SVCParamECH, Value: configList},
+						},
+					}},
+				}}
+			} else {
+				response.RCode = dnsmessage.RCodeNameError
+			}
+			packed, _ := response.Pack()
+			conn.WriteTo(packed, addr)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	list, err := lookupECHConfigList(ctx, conn.LocalAddr().String(), "ech.example")
+	if err != nil || !bytes.Equal(list, configList) {
+		t.Errorf("lookupECHConfigList = %x, %v", list, err)
+	}
+	if _, err := lookupECHConfigList(ctx, conn.LocalAddr().String(), "none.example"); err == nil {
+		t.Error("lookup of a missing name succeeded")
+	}
+}
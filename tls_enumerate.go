@@ -0,0 +1,340 @@
+package zgrab2
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+
+	ztls "github.com/zmap/zcrypto/tls"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+const (
+	handshakeTypeServerKeyExchange = 12
+
+	extensionRenegotiationInfo = 0xff01
+
+	// curveTypeNamedCurve is the ECParameters curve type of a ServerKeyExchange with a named group.
+	curveTypeNamedCurve = 3
+)
+
+// enumerationVersions are the versions --enumerate probes, from the newest.
+var enumerationVersions = []uint16{tls.VersionTLS13, tls.VersionTLS12, tls.VersionTLS11, tls.VersionTLS10}
+
+// tls13CipherSuites are the cipher suites of TLS 1.3, including those without encryption of RFC 9150.
+var tls13CipherSuites = []uint16{tls.TLS_AES_128_GCM_SHA256, tls.TLS_AES_256_GCM_SHA384, tls.TLS_CHACHA20_POLY1305_SHA256,
+	0x1304, 0x1305, 0xc0b4, 0xc0b5}
+
+// enumerationGroups are the groups --enumerate probes in TLS 1.3; TLS 1.2 probes those up to ecdh_x448.
+var enumerationGroups = []uint16{groupX25519, groupSecP256r1, groupSecP384r1, 0x0019, 0x001e,
+	GroupX25519MLKEM768, GroupSecP256r1MLKEM768, 0x11ed, GroupX25519Kyber768Draft00,
+	0x001f, 0x0020, 0x0021, 0x0100, 0x0101, 0x0102, 0x0103, 0x0104}
+
+// legacyCipherSuites returns the cipher suites of TLS 1.2 and earlier that zcrypto knows, excluding signaling cipher
+// suite values.
+func legacyCipherSuites() []uint16 {
+	var suites []uint16
+	for id := 1; id <= 0xffff; id++ {
+		suite := uint16(id)
+		if suite>>8 == 0x13 || suite == 0x00ff || suite == 0x5600 || slices.Contains(tls13CipherSuites, suite) {
+			continue
+		}
+		if ztls.CipherSuiteID(suite).String() != "unknown" {
+			suites = append(suites, suite)
+		}
+	}
+	return suites
+}
+
+// enumerationGroupName returns the name of a group.
+func enumerationGroupName(group uint16) string {
+	if name := ztls.CurveID(group).String(); name != "unknown" && name != "" {
+		return name
+	}
+	return PQGroupName(group)
+}
+
+// VersionEnumeration is the cipher suites a server accepts with a protocol version.
+type VersionEnumeration struct {
+	Version string `json:"version"`
+	// CipherSuites are in the order of the preference of the server if ServerPreference is set.
+	CipherSuites     []string `json:"cipher_suites"`
+	ServerPreference bool     `json:"server_preference"`
+}
+
+// EnumerationResult aggregates the protocol versions, cipher suites and groups a server accepts, found with a
+// handshake for each of them up to the ServerHello.
+type EnumerationResult struct {
+	Versions []VersionEnumeration `json:"versions,omitempty"`
+	// Groups are in the order of the preference of the server if GroupsServerPreference is set. They are enumerated
+	// with TLS 1.3 if the server supports it, and otherwise with the ServerKeyExchange of TLS 1.2 ECDHE cipher
+	// suites.
+	Groups                 []string `json:"groups,omitempty"`
+	GroupsVersion          string   `json:"groups_version,omitempty"`
+	GroupsServerPreference bool     `json:"groups_server_preference,omitempty"`
+	Handshakes             int      `json:"handshakes"`
+	Error                  string   `json:"error,omitempty"`
+}
+
+// marshalEnumerationHello returns a TLS record with a ClientHello of version offering suites and groups. TLS 1.3
+// ClientHellos send no key share, so that servers name the group they select in a HelloRetryRequest.
+func marshalEnumerationHello(serverName string, version uint16, suites, groups []uint16) ([]byte, error) {
+	random := make([]byte, 64)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	var b cryptobyte.Builder
+	b.AddUint8(recordTypeHandshake)
+	b.AddUint16(tls.VersionTLS10)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint8(handshakeTypeClientHello)
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(min(version, tls.VersionTLS12))
+			b.AddBytes(random[:32])
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+				if version == tls.VersionTLS13 {
+					b.AddBytes(random[32:])
+				}
+			})
+			addUint16List(b, suites...)
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint8(0) })
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				if serverName != "" && net.ParseIP(serverName) == nil {
+					b.AddUint16(extensionServerName)
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+							b.AddUint8(0)
+							b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte(serverName)) })
+						})
+					})
+				}
+				b.AddUint16(extensionSupportedGroups)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { addUint16List(b, groups...) })
+				b.AddUint16(extensionECPointFormats)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint8(0) })
+				})
+				b.AddUint16(extensionSignatureAlgorithms)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					addUint16List(b, 0x0403, 0x0804, 0x0401, 0x0503, 0x0805, 0x0501, 0x0806, 0x0601, 0x0807, 0x0808,
+						0x0809, 0x080a, 0x080b, 0x0603, 0x0201, 0x0203)
+				})
+				b.AddUint16(extensionRenegotiationInfo)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint8(0) })
+				if version == tls.VersionTLS13 {
+					b.AddUint16(extensionSupportedVersions)
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint16(tls.VersionTLS13) })
+					})
+					b.AddUint16(extensionPSKKeyExchangeModes)
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint8(1) })
+					})
+					b.AddUint16(extensionKeyShare)
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {})
+					})
+				}
+			})
+		})
+	})
+	return b.Bytes()
+}
+
+// readHandshakeFlight reads the handshake messages of the server, passing each to handle until it returns true.
+func readHandshakeFlight(conn net.Conn, handle func(msgType uint8, msg []byte) bool) error {
+	var handshake []byte
+	for read := 0; read < maxRecordedHello; {
+		recordType, length, body, err := readRecord(conn, true)
+		if err != nil {
+			return err
+		}
+		read += length
+		if recordType == recordTypeAlert && len(body) >= 2 {
+			return tls.AlertError(body[1])
+		}
+		if recordType != recordTypeHandshake {
+			return errors.New("unexpected record in the handshake")
+		}
+		handshake = append(handshake, body...)
+		for len(handshake) >= 4 {
+			length := int(handshake[1])<<16 | int(handshake[2])<<8 | int(handshake[3])
+			if len(handshake) < 4+length {
+				break
+			}
+			msgType, msg := handshake[0], handshake[4:4+length]
+			handshake = handshake[4+length:]
+			if handle(msgType, msg) {
+				return nil
+			}
+		}
+	}
+	return errors.New("handshake flight too long")
+}
+
+// enumerationHandshake is the answer of a server to the ClientHello of an enumeration probe.
+type enumerationHandshake struct {
+	version, suite, group uint16
+	random                []byte
+}
+
+// serverHelloCipherSuite returns the cipher suite of a ServerHello.
+func serverHelloCipherSuite(msg []byte) (uint16, bool) {
+	s := cryptobyte.String(msg)
+	var sessionID cryptobyte.String
+	var suite uint16
+	if !s.Skip(2+32) || !s.ReadUint8LengthPrefixed(&sessionID) || !s.ReadUint16(&suite) {
+		return 0, false
+	}
+	return suite, true
+}
+
+// enumerator sends the ClientHellos of an enumeration, each on a new connection.
+type enumerator struct {
+	dial       func(context.Context) (net.Conn, error)
+	serverName string
+	handshakes int
+}
+
+// handshake sends a ClientHello of version, and returns what the server selects, or an error if it declines. With
+// keyExchange, TLS 1.2 handshakes are read up to the ServerKeyExchange for the group of ECDHE cipher suites.
+func (e *enumerator) handshake(ctx context.Context, version uint16, suites, groups []uint16, keyExchange bool) (*enumerationHandshake, error) {
+	hello, err := marshalEnumerationHello(e.serverName, version, suites, groups)
+	if err != nil {
+		return nil, err
+	}
+	e.handshakes++
+	conn, err := e.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write(hello); err != nil {
+		return nil, err
+	}
+	var selected *enumerationHandshake
+	err = readHandshakeFlight(conn, func(msgType uint8, msg []byte) bool {
+		switch msgType {
+		case handshakeTypeServerHello:
+			selectedVersion, group, _, err := parseServerHelloGroup(msg)
+			suite, ok := serverHelloCipherSuite(msg)
+			if err != nil || !ok {
+				return true
+			}
+			selected = &enumerationHandshake{version: selectedVersion, suite: suite, group: group, random: msg[2:34]}
+			return !keyExchange || selectedVersion == tls.VersionTLS13
+		case handshakeTypeServerKeyExchange:
+			s := cryptobyte.String(msg)
+			var curveType uint8
+			if selected != nil && s.ReadUint8(&curveType) && curveType == curveTypeNamedCurve {
+				s.ReadUint16(&selected.group)
+			}
+			return true
+		}
+		return msgType == handshakeTypeServerHelloDone
+	})
+	if err != nil {
+		return nil, err
+	}
+	if selected == nil {
+		return nil, errors.New("malformed ServerHello")
+	}
+	if selected.version != version {
+		return nil, errors.New("server selected " + tls.VersionName(selected.version))
+	}
+	return selected, nil
+}
+
+// enumerate offers candidates, removing the one the server selects each time, until it declines the rest. It returns
+// the selected candidates, in the order of the preference of the server if it has one, and whether it does.
+func (e *enumerator) enumerate(ctx context.Context, candidates []uint16, selectOne func(candidates []uint16) (uint16, error)) ([]uint16, bool) {
+	var accepted []uint16
+	remaining := slices.Clone(candidates)
+	for len(remaining) > 0 && ctx.Err() == nil {
+		selected, err := selectOne(remaining)
+		if err != nil || !slices.Contains(remaining, selected) {
+			break
+		}
+		accepted = append(accepted, selected)
+		remaining = slices.DeleteFunc(remaining, func(candidate uint16) bool { return candidate == selected })
+	}
+	if len(accepted) < 2 {
+		return accepted, false
+	}
+	// a server with a preference selects the same candidate when they are offered in reverse order
+	reversed := slices.Clone(accepted)
+	slices.Reverse(reversed)
+	selected, err := selectOne(reversed)
+	return accepted, err == nil && selected == accepted[0]
+}
+
+// ProbeEnumeration enumerates the protocol versions, cipher suites and groups the server accepts, on connections
+// opened with dial.
+func ProbeEnumeration(ctx context.Context, dial func(context.Context) (net.Conn, error), serverName string) *EnumerationResult {
+	result := &EnumerationResult{}
+	e := &enumerator{dial: dial, serverName: serverName}
+	legacySuites := legacyCipherSuites()
+	var groupsVersion uint16
+	var ecdheSuites []uint16
+	for _, version := range enumerationVersions {
+		candidates := legacySuites
+		if version == tls.VersionTLS13 {
+			candidates = tls13CipherSuites
+		}
+		suites, serverPreference := e.enumerate(ctx, candidates, func(suites []uint16) (uint16, error) {
+			selected, err := e.handshake(ctx, version, suites, enumerationGroups, false)
+			if err != nil {
+				return 0, err
+			}
+			return selected.suite, nil
+		})
+		if ctx.Err() != nil {
+			result.Error = ctx.Err().Error()
+			break
+		}
+		if len(suites) == 0 {
+			continue
+		}
+		enumeration := VersionEnumeration{Version: tls.VersionName(version), ServerPreference: serverPreference}
+		for _, suite := range suites {
+			name := ztls.CipherSuiteID(suite).String()
+			enumeration.CipherSuites = append(enumeration.CipherSuites, name)
+			if version == tls.VersionTLS12 && strings.HasPrefix(name, "TLS_ECDHE_") {
+				ecdheSuites = append(ecdheSuites, suite)
+			}
+		}
+		result.Versions = append(result.Versions, enumeration)
+		if version == tls.VersionTLS13 || version == tls.VersionTLS12 && groupsVersion == 0 && len(ecdheSuites) > 0 {
+			groupsVersion = version
+		}
+	}
+
+	if groupsVersion != 0 && ctx.Err() == nil {
+		suites, candidates := tls13CipherSuites, enumerationGroups
+		if groupsVersion == tls.VersionTLS12 {
+			suites = ecdheSuites
+			candidates = slices.DeleteFunc(slices.Clone(enumerationGroups), func(group uint16) bool { return group > 0x001e })
+		}
+		groups, serverPreference := e.enumerate(ctx, candidates, func(groups []uint16) (uint16, error) {
+			selected, err := e.handshake(ctx, groupsVersion, suites, groups, true)
+			if err != nil {
+				return 0, err
+			}
+			return selected.group, nil
+		})
+		result.GroupsVersion = tls.VersionName(groupsVersion)
+		result.GroupsServerPreference = serverPreference
+		for _, group := range groups {
+			result.Groups = append(result.Groups, enumerationGroupName(group))
+		}
+	}
+	result.Handshakes = e.handshakes
+	return result
+}
@@ -0,0 +1,55 @@
+package zgrab2
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestProbeEnumeration(t *testing.T) {
+	newServer := func(config *tls.Config) func(context.Context) (net.Conn, error) {
+		server := httptest.NewUnstartedServer(nil)
+		server.TLS = config
+		server.StartTLS()
+		t.Cleanup(server.Close)
+		return func(ctx context.Context) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "tcp", server.Listener.Addr().String())
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result := ProbeEnumeration(ctx, newServer(&tls.Config{
+		CurvePreferences: []tls.CurveID{tls.CurveP384, tls.X25519},
+		CipherSuites:     []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA},
+	}), "www.example")
+	if result.Error != "" || len(result.Versions) != 2 {
+		t.Fatalf("result: %+v", result)
+	}
+	tls13, tls12 := result.Versions[0], result.Versions[1]
+	if tls13.Version != "TLS 1.3" || len(tls13.CipherSuites) != 3 {
+		t.Errorf("TLS 1.3: %+v", tls13)
+	}
+	if tls12.Version != "TLS 1.2" || !tls12.ServerPreference ||
+		!slices.Equal(tls12.CipherSuites, []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA"}) {
+		t.Errorf("TLS 1.2: %+v", tls12)
+	}
+	// Go servers order the groups of CurvePreferences by their own preference
+	if result.GroupsVersion != "TLS 1.3" || !result.GroupsServerPreference || !slices.Equal(result.Groups, []string{"ecdh_x25519", "secp384r1"}) {
+		t.Errorf("groups: %+v", result)
+	}
+
+	// groups are found in the ServerKeyExchange of TLS 1.2 servers
+	result = ProbeEnumeration(ctx, newServer(&tls.Config{MaxVersion: tls.VersionTLS12, CurvePreferences: []tls.CurveID{tls.CurveP256}}), "www.example")
+	if len(result.Versions) != 1 || result.Versions[0].Version != "TLS 1.2" {
+		t.Errorf("TLS 1.2 server versions: %+v", result.Versions)
+	}
+	if result.GroupsVersion != "TLS 1.2" || !slices.Equal(result.Groups, []string{"secp256r1"}) {
+		t.Errorf("TLS 1.2 server groups: %+v", result)
+	}
+}
@@ -0,0 +1,352 @@
+package zgrab2
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// TLSFingerprints holds the JA3 and JA4 fingerprints of the ClientHello and the JA3S and JA4S fingerprints of the
+// ServerHello of a handshake, for correlation with datasets of network sensors. The JA3 strings are the inputs of
+// the hashes.
+type TLSFingerprints struct {
+	JA3        string `json:"ja3,omitempty"`
+	JA3String  string `json:"ja3_string,omitempty"`
+	JA4        string `json:"ja4,omitempty"`
+	JA3S       string `json:"ja3s,omitempty"`
+	JA3SString string `json:"ja3s_string,omitempty"`
+	JA4S       string `json:"ja4s,omitempty"`
+}
+
+const (
+	recordTypeHandshake = 22
+
+	handshakeTypeClientHello = 1
+	handshakeTypeServerHello = 2
+
+	extensionServerName          = 0
+	extensionSupportedGroups     = 10
+	extensionECPointFormats      = 11
+	extensionSignatureAlgorithms = 13
+	extensionALPN                = 16
+	extensionSupportedVersions   = 43
+
+	// maxRecordedHello bounds the bytes recorded in each direction while looking for a hello message.
+	maxRecordedHello = 1 << 16
+)
+
+// helloRecorder is a connection that records the handshake records it writes and reads until they hold the first
+// ClientHello and ServerHello.
+type helloRecorder struct {
+	net.Conn
+
+	mutex       sync.Mutex
+	written     []byte
+	read        []byte
+	clientHello []byte
+	serverHello []byte
+}
+
+func newHelloRecorder(conn net.Conn) *helloRecorder {
+	return &helloRecorder{Conn: conn}
+}
+
+// firstHandshakeMessage returns the body of the first handshake message in a stream of TLS records, once the
+// records hold all of it.
+func firstHandshakeMessage(records []byte, msgType uint8) ([]byte, bool) {
+	var handshake []byte
+	for len(records) >= 5 && records[0] == recordTypeHandshake {
+		length := int(records[3])<<8 | int(records[4])
+		if len(records) < 5+length {
+			break
+		}
+		handshake = append(handshake, records[5:5+length]...)
+		records = records[5+length:]
+	}
+	if len(handshake) < 4 || handshake[0] != msgType {
+		return nil, false
+	}
+	length := int(handshake[1])<<16 | int(handshake[2])<<8 | int(handshake[3])
+	if len(handshake) < 4+length {
+		return nil, false
+	}
+	return handshake[4 : 4+length], true
+}
+
+// record appends b to buf until buf holds the hello message of msgType, which is then stored in hello.
+func record(buf *[]byte, hello *[]byte, b []byte, msgType uint8) {
+	if *hello != nil || *buf == nil && len(b) > 0 && b[0] != recordTypeHandshake || len(*buf) >= maxRecordedHello {
+		return
+	}
+	*buf = append(*buf, b...)
+	if msg, ok := firstHandshakeMessage(*buf, msgType); ok {
+		*hello = msg
+		*buf = nil
+	}
+}
+
+func (r *helloRecorder) Write(b []byte) (int, error) {
+	r.mutex.Lock()
+	record(&r.written, &r.clientHello, b, handshakeTypeClientHello)
+	r.mutex.Unlock()
+	return r.Conn.Write(b)
+}
+
+func (r *helloRecorder) Read(b []byte) (int, error) {
+	n, err := r.Conn.Read(b)
+	r.mutex.Lock()
+	record(&r.read, &r.serverHello, b[:n], handshakeTypeServerHello)
+	r.mutex.Unlock()
+	return n, err
+}
+
+// Fingerprints returns the fingerprints of the recorded hellos, or nil if the ClientHello was not recorded.
+func (r *helloRecorder) Fingerprints() *TLSFingerprints {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	client, ok := parseHello(r.clientHello, true)
+	if !ok {
+		return nil
+	}
+	fingerprints := new(TLSFingerprints)
+	fingerprints.JA3String, fingerprints.JA3 = client.ja3()
+	fingerprints.JA4 = client.ja4()
+	if server, ok := parseHello(r.serverHello, false); ok {
+		fingerprints.JA3SString, fingerprints.JA3S = server.ja3()
+		fingerprints.JA4S = server.ja4s()
+	}
+	return fingerprints
+}
+
+// helloInfo holds the fields of a ClientHello or ServerHello that the fingerprints are computed from. A ServerHello
+// has a single cipher suite.
+type helloInfo struct {
+	isClient            bool
+	version             uint16
+	cipherSuites        []uint16
+	extensions          []uint16
+	supportedGroups     []uint16
+	pointFormats        []uint8
+	signatureAlgorithms []uint16
+	alpn                []string
+	supportedVersions   []uint16
+}
+
+// isGREASE tells whether v is one of the reserved values of RFC 8701.
+func isGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
+
+func withoutGREASE(values []uint16) []uint16 {
+	return slices.DeleteFunc(slices.Clone(values), isGREASE)
+}
+
+func readUint16List(s *cryptobyte.String, lengthBytes int) ([]uint16, bool) {
+	var list cryptobyte.String
+	var ok bool
+	if lengthBytes == 1 {
+		ok = s.ReadUint8LengthPrefixed(&list)
+	} else {
+		ok = s.ReadUint16LengthPrefixed(&list)
+	}
+	if !ok {
+		return nil, false
+	}
+	var values []uint16
+	for !list.Empty() {
+		var v uint16
+		if !list.ReadUint16(&v) {
+			return nil, false
+		}
+		values = append(values, v)
+	}
+	return values, true
+}
+
+// parseHello parses the body of a ClientHello or ServerHello message.
+func parseHello(msg []byte, isClient bool) (*helloInfo, bool) {
+	if msg == nil {
+		return nil, false
+	}
+	s := cryptobyte.String(msg)
+	hello := &helloInfo{isClient: isClient}
+	var sessionID, compression cryptobyte.String
+	if !s.ReadUint16(&hello.version) || !s.Skip(32) || !s.ReadUint8LengthPrefixed(&sessionID) {
+		return nil, false
+	}
+	if isClient {
+		var ok bool
+		if hello.cipherSuites, ok = readUint16List(&s, 2); !ok || !s.ReadUint8LengthPrefixed(&compression) {
+			return nil, false
+		}
+	} else {
+		var cipherSuite uint16
+		if !s.ReadUint16(&cipherSuite) || !s.Skip(1) {
+			return nil, false
+		}
+		hello.cipherSuites = []uint16{cipherSuite}
+	}
+	if s.Empty() {
+		return hello, true
+	}
+	var extensions cryptobyte.String
+	if !s.ReadUint16LengthPrefixed(&extensions) {
+		return nil, false
+	}
+	for !extensions.Empty() {
+		var extType uint16
+		var data cryptobyte.String
+		if !extensions.ReadUint16(&extType) || !extensions.ReadUint16LengthPrefixed(&data) {
+			return nil, false
+		}
+		hello.extensions = append(hello.extensions, extType)
+		// the contents of the extensions are best-effort: a malformed one only misses from the fingerprints
+		switch extType {
+		case extensionSupportedGroups:
+			hello.supportedGroups, _ = readUint16List(&data, 2)
+		case extensionECPointFormats:
+			var formats cryptobyte.String
+			if data.ReadUint8LengthPrefixed(&formats) {
+				hello.pointFormats = formats
+			}
+		case extensionSignatureAlgorithms:
+			hello.signatureAlgorithms, _ = readUint16List(&data, 2)
+		case extensionALPN:
+			var protocols cryptobyte.String
+			if data.ReadUint16LengthPrefixed(&protocols) {
+				for !protocols.Empty() {
+					var protocol cryptobyte.String
+					if !protocols.ReadUint8LengthPrefixed(&protocol) {
+						break
+					}
+					hello.alpn = append(hello.alpn, string(protocol))
+				}
+			}
+		case extensionSupportedVersions:
+			if isClient {
+				hello.supportedVersions, _ = readUint16List(&data, 1)
+			} else {
+				var version uint16
+				if data.ReadUint16(&version) {
+					hello.supportedVersions = []uint16{version}
+				}
+			}
+		}
+	}
+	return hello, true
+}
+
+func joinDecimal[T uint8 | uint16](values []T) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(int(v))
+	}
+	return strings.Join(parts, "-")
+}
+
+func joinHex(values []uint16) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprintf("%04x", v)
+	}
+	return strings.Join(parts, ",")
+}
+
+// ja3 returns the JA3 string of a ClientHello, or the JA3S string of a ServerHello, and its MD5 hash.
+func (h *helloInfo) ja3() (string, string) {
+	fields := []string{
+		strconv.Itoa(int(h.version)),
+		joinDecimal(withoutGREASE(h.cipherSuites)),
+		joinDecimal(withoutGREASE(h.extensions)),
+	}
+	if h.isClient {
+		fields = append(fields, joinDecimal(withoutGREASE(h.supportedGroups)), joinDecimal(h.pointFormats))
+	}
+	s := strings.Join(fields, ",")
+	sum := md5.Sum([]byte(s))
+	return s, hex.EncodeToString(sum[:])
+}
+
+// ja4Version returns the JA4 code of the highest version of a hello, preferring its supported_versions extension.
+func (h *helloInfo) ja4Version() string {
+	version := h.version
+	if versions := withoutGREASE(h.supportedVersions); len(versions) > 0 {
+		version = slices.Max(versions)
+	}
+	switch version {
+	case 0x0304:
+		return "13"
+	case 0x0303:
+		return "12"
+	case 0x0302:
+		return "11"
+	case 0x0301:
+		return "10"
+	case 0x0300:
+		return "s3"
+	case 0x0002:
+		return "s2"
+	}
+	return "00"
+}
+
+// ja4ALPN returns the first and last characters of the first ALPN protocol, or those of its hex encoding if they
+// are not alphanumeric.
+func (h *helloInfo) ja4ALPN() string {
+	if len(h.alpn) == 0 || h.alpn[0] == "" {
+		return "00"
+	}
+	alnum := func(c byte) bool { return c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' }
+	protocol := h.alpn[0]
+	first, last := protocol[0], protocol[len(protocol)-1]
+	if !alnum(first) || !alnum(last) {
+		encoded := hex.EncodeToString([]byte(protocol))
+		return encoded[:1] + encoded[len(encoded)-1:]
+	}
+	return string([]byte{first, last})
+}
+
+// ja4Hash returns the first 12 hex characters of the SHA-256 of s, or zeros if s is empty.
+func ja4Hash(s string) string {
+	if s == "" {
+		return "000000000000"
+	}
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// ja4 returns the JA4 fingerprint of a ClientHello received over TCP.
+func (h *helloInfo) ja4() string {
+	ciphers := withoutGREASE(h.cipherSuites)
+	extensions := withoutGREASE(h.extensions)
+	sni := "i"
+	if slices.Contains(extensions, extensionServerName) {
+		sni = "d"
+	}
+	a := fmt.Sprintf("t%s%s%02d%02d%s", h.ja4Version(), sni, min(len(ciphers), 99), min(len(extensions), 99), h.ja4ALPN())
+
+	slices.Sort(ciphers)
+	sortedExtensions := slices.DeleteFunc(slices.Clone(extensions), func(ext uint16) bool {
+		return ext == extensionServerName || ext == extensionALPN
+	})
+	slices.Sort(sortedExtensions)
+	c := joinHex(sortedExtensions)
+	if c != "" && len(h.signatureAlgorithms) > 0 {
+		c += "_" + joinHex(h.signatureAlgorithms)
+	}
+	return a + "_" + ja4Hash(joinHex(ciphers)) + "_" + ja4Hash(c)
+}
+
+// ja4s returns the JA4S fingerprint of a ServerHello received over TCP.
+func (h *helloInfo) ja4s() string {
+	a := fmt.Sprintf("t%s%02d%s", h.ja4Version(), min(len(h.extensions), 99), h.ja4ALPN())
+	return a + "_" + joinHex(h.cipherSuites) + "_" + ja4Hash(joinHex(h.extensions))
+}
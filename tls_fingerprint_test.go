@@ -0,0 +1,188 @@
+package zgrab2
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/tls"
+	"encoding/hex"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+type testExtension struct {
+	id   uint16
+	data func(b *cryptobyte.Builder)
+}
+
+func uint16List(values ...uint16) func(b *cryptobyte.Builder) {
+	return func(b *cryptobyte.Builder) {
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			for _, v := range values {
+				b.AddUint16(v)
+			}
+		})
+	}
+}
+
+// handshakeRecord returns a handshake message in a TLS record.
+func handshakeRecord(msgType uint8, body func(b *cryptobyte.Builder), extensions []testExtension) []byte {
+	var b cryptobyte.Builder
+	b.AddUint8(recordTypeHandshake)
+	b.AddUint16(0x0301)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint8(msgType)
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(0x0303)
+			b.AddBytes(make([]byte, 32))
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {})
+			body(b)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				for _, ext := range extensions {
+					b.AddUint16(ext.id)
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						if ext.data != nil {
+							ext.data(b)
+						}
+					})
+				}
+			})
+		})
+	})
+	return b.BytesOrPanic()
+}
+
+// chromeClientHello returns a ClientHello with the fields of the Chrome example of the JA4 specification, and GREASE.
+func chromeClientHello() []byte {
+	return handshakeRecord(handshakeTypeClientHello, func(b *cryptobyte.Builder) {
+		uint16List(0x2a2a, 0x1301, 0x1302, 0x1303, 0xc02b, 0xc02f, 0xc02c, 0xc030, 0xcca9, 0xcca8, 0xc013, 0xc014,
+			0x009c, 0x009d, 0x002f, 0x0035)(b)
+		b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint8(0) })
+	}, []testExtension{
+		{id: 0x8a8a},
+		{id: extensionServerName},
+		{id: 0x0017},
+		{id: 0xff01},
+		{id: extensionSupportedGroups, data: uint16List(0x8a8a, 0x001d, 0x0017, 0x0018)},
+		{id: extensionECPointFormats, data: func(b *cryptobyte.Builder) {
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint8(0) })
+		}},
+		{id: 0x0023},
+		{id: extensionALPN, data: func(b *cryptobyte.Builder) {
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				for _, protocol := range []string{"h2", "http/1.1"} {
+					b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte(protocol)) })
+				}
+			})
+		}},
+		{id: 0x0005},
+		{id: extensionSignatureAlgorithms, data: uint16List(0x0403, 0x0804, 0x0401, 0x0503, 0x0805, 0x0501, 0x0806, 0x0601)},
+		{id: 0x0012},
+		{id: 0x0033},
+		{id: 0x002d},
+		{id: extensionSupportedVersions, data: func(b *cryptobyte.Builder) {
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint16(0xdada); b.AddUint16(0x0304); b.AddUint16(0x0303) })
+		}},
+		{id: 0x001b},
+		{id: 0x4469},
+		{id: 0x0015},
+	})
+}
+
+func TestTLSFingerprints(t *testing.T) {
+	serverHello := handshakeRecord(handshakeTypeServerHello, func(b *cryptobyte.Builder) {
+		b.AddUint16(0x1301)
+		b.AddUint8(0)
+	}, []testExtension{
+		{id: extensionSupportedVersions, data: func(b *cryptobyte.Builder) { b.AddUint16(0x0304) }},
+		{id: 0x0033},
+	})
+
+	client, server := net.Pipe()
+	defer client.Close()
+	hellos := newHelloRecorder(client)
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			if _, err := server.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+	// the hellos span several writes and reads
+	clientHello := chromeClientHello()
+	hellos.Write(clientHello[:10])
+	hellos.Write(clientHello[10:])
+	go func() {
+		server.Write(serverHello[:50])
+		server.Write(append(serverHello[50:], 0x14, 0x03, 0x03, 0x00, 0x01, 0x01))
+		server.Close()
+	}()
+	buf := make([]byte, 16)
+	for {
+		if _, err := hellos.Read(buf); err != nil {
+			break
+		}
+	}
+
+	fingerprints := hellos.Fingerprints()
+	if fingerprints == nil {
+		t.Fatal("no fingerprints")
+	}
+	wantJA3 := "771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53," +
+		"0-23-65281-10-11-35-16-5-13-18-51-45-43-27-17513-21,29-23-24,0"
+	ja3Sum := md5.Sum([]byte(wantJA3))
+	ja3sSum := md5.Sum([]byte("771,4865,43-51"))
+	want := TLSFingerprints{
+		JA3String:  wantJA3,
+		JA3:        hex.EncodeToString(ja3Sum[:]),
+		JA4:        "t13d1516h2_8daaf6152771_e5627efa2ab1",
+		JA3SString: "771,4865,43-51",
+		JA3S:       hex.EncodeToString(ja3sSum[:]),
+		JA4S:       "t130200_1301_a56c5b993250",
+	}
+	if *fingerprints != want {
+		t.Errorf("fingerprints = %+v, want %+v", *fingerprints, want)
+	}
+}
+
+func TestJA4ALPN(t *testing.T) {
+	for alpn, want := range map[string]string{"": "00", "h2": "h2", "http/1.1": "h1", "h": "hh", "\xab\xcd": "ad"} {
+		h := &helloInfo{alpn: []string{alpn}}
+		if got := h.ja4ALPN(); got != want {
+			t.Errorf("ja4ALPN(%q) = %s, want %s", alpn, got, want)
+		}
+	}
+}
+
+func TestTLSWrapperFingerprints(t *testing.T) {
+	server := httptest.NewUnstartedServer(nil)
+	server.TLS = &tls.Config{NextProtos: []string{"http/1.1"}}
+	server.StartTLS()
+	defer server.Close()
+	addr := server.Listener.Addr().(*net.TCPAddr)
+
+	conn, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsFlags := &TLSFlags{NextProtos: "http/1.1"}
+	tlsConn, err := GetDefaultTLSWrapper(tlsFlags)(context.Background(), &ScanTarget{IP: addr.IP, Port: uint(addr.Port)}, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tlsConn.Close()
+	fingerprints := tlsConn.GetLog().Fingerprints
+	if fingerprints == nil || !strings.HasPrefix(fingerprints.JA4, "t") || !strings.HasSuffix(strings.Split(fingerprints.JA4, "_")[0], "h1") {
+		t.Fatalf("fingerprints = %+v", fingerprints)
+	}
+	if !strings.HasPrefix(fingerprints.JA4S, "t12") && !strings.HasPrefix(fingerprints.JA4S, "t13") {
+		t.Errorf("JA4S = %s", fingerprints.JA4S)
+	}
+	if len(fingerprints.JA3) != 32 || len(fingerprints.JA3S) != 32 {
+		t.Errorf("fingerprints = %+v", fingerprints)
+	}
+}
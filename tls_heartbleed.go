@@ -0,0 +1,236 @@
+package zgrab2
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// Statuses of a HeartbleedResult.
+const (
+	HeartbleedVulnerable  = "vulnerable"
+	HeartbleedPatched     = "patched"
+	HeartbleedUnsupported = "unsupported"
+)
+
+const (
+	recordTypeHeartbeat = 24
+
+	handshakeTypeServerHelloDone = 14
+
+	extensionHeartbeat = 15
+
+	heartbeatRequest = 1
+
+	// heartbleedPayloadLength is the payload length the malformed heartbeat request claims, without sending any.
+	heartbleedPayloadLength = 0x4000
+	// heartbleedTimeout bounds the wait for a heartbeat response, which patched servers do not send.
+	heartbleedTimeout = 5 * time.Second
+)
+
+// HeartbleedResult is the outcome of the heartbeat probe of CVE-2014-0160.
+type HeartbleedResult struct {
+	// HeartbeatSupported tells whether the server negotiated the heartbeat extension, without which it is not probed.
+	HeartbeatSupported bool   `json:"heartbeat_supported"`
+	Status             string `json:"status,omitempty"`
+	// ResponseLength is the length of the heartbeat response record of a vulnerable server, whose contents, leaked
+	// memory, are not read.
+	ResponseLength int    `json:"response_length,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// marshalTLS12ClientHello returns a TLS record with a TLS 1.2 ClientHello with a session ID, offering the cipher
+// suites of common servers including the OpenSSL versions affected by Heartbleed, and the heartbeat extension if
+// heartbeat is set.
+func marshalTLS12ClientHello(serverName string, sessionID []byte, heartbeat bool) ([]byte, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	var b cryptobyte.Builder
+	b.AddUint8(recordTypeHandshake)
+	b.AddUint16(tls.VersionTLS10)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint8(handshakeTypeClientHello)
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(tls.VersionTLS12)
+			b.AddBytes(random)
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(sessionID) })
+			addUint16List(b, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+				tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256, tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+				tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA, tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+				tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA, tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+				tls.TLS_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+				tls.TLS_RSA_WITH_AES_128_CBC_SHA, tls.TLS_RSA_WITH_AES_256_CBC_SHA, tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+				// TLS_DHE_RSA_WITH_AES_128_CBC_SHA, TLS_DHE_RSA_WITH_AES_256_CBC_SHA
+				0x0033, 0x0039)
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint8(0) })
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				if serverName != "" && net.ParseIP(serverName) == nil {
+					b.AddUint16(extensionServerName)
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+							b.AddUint8(0)
+							b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte(serverName)) })
+						})
+					})
+				}
+				b.AddUint16(extensionSupportedGroups)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					addUint16List(b, groupX25519, groupSecP256r1, groupSecP384r1)
+				})
+				b.AddUint16(extensionECPointFormats)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint8(0) })
+				})
+				b.AddUint16(extensionSignatureAlgorithms)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					addUint16List(b, 0x0401, 0x0403, 0x0804, 0x0501, 0x0503, 0x0805, 0x0601, 0x0603, 0x0806, 0x0201, 0x0203)
+				})
+				if heartbeat {
+					b.AddUint16(extensionHeartbeat)
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						// peer_allowed_to_send
+						b.AddUint8(1)
+					})
+				}
+			})
+		})
+	})
+	return b.Bytes()
+}
+
+// readRecord reads the header of a TLS record, and its body if readBody is set.
+func readRecord(conn net.Conn, readBody bool) (recordType uint8, length int, body []byte, err error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return 0, 0, nil, err
+	}
+	recordType, length = header[0], int(header[3])<<8|int(header[4])
+	if !readBody {
+		return recordType, length, nil, nil
+	}
+	body = make([]byte, length)
+	if _, err := io.ReadFull(conn, body); err != nil {
+		return 0, 0, nil, err
+	}
+	return recordType, length, body, nil
+}
+
+// readServerHelloFlight reads the handshake messages of the server up to its ServerHelloDone, and returns whether its
+// ServerHello negotiated the heartbeat extension.
+func readServerHelloFlight(conn net.Conn) (bool, error) {
+	var handshake []byte
+	heartbeat := false
+	for len(handshake) < maxRecordedHello {
+		recordType, _, body, err := readRecord(conn, true)
+		if err != nil {
+			return false, err
+		}
+		if recordType == recordTypeAlert && len(body) >= 2 {
+			return false, tls.AlertError(body[1])
+		}
+		if recordType != recordTypeHandshake {
+			return false, errors.New("unexpected record before ServerHelloDone")
+		}
+		handshake = append(handshake, body...)
+		for len(handshake) >= 4 {
+			length := int(handshake[1])<<16 | int(handshake[2])<<8 | int(handshake[3])
+			if len(handshake) < 4+length {
+				break
+			}
+			msgType, msg := handshake[0], handshake[4:4+length]
+			handshake = handshake[4+length:]
+			switch msgType {
+			case handshakeTypeServerHello:
+				hello, ok := parseHello(msg, false)
+				if !ok {
+					return false, errors.New("malformed ServerHello")
+				}
+				for _, extension := range hello.extensions {
+					heartbeat = heartbeat || extension == extensionHeartbeat
+				}
+			case handshakeTypeServerHelloDone:
+				return heartbeat, nil
+			}
+		}
+	}
+	return false, errors.New("no ServerHelloDone")
+}
+
+// ProbeHeartbleed performs a TLS 1.2 handshake up to the ServerHelloDone on a connection opened with dial, and then
+// sends a heartbeat request claiming a payload it does not carry. Vulnerable servers answer with as much of their
+// memory; patched servers discard the request.
+func ProbeHeartbleed(ctx context.Context, dial func(context.Context) (net.Conn, error), serverName string) *HeartbleedResult {
+	result := &HeartbleedResult{}
+	hello, err := marshalTLS12ClientHello(serverName, nil, true)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	conn, err := dial(ctx)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write(hello); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if result.HeartbeatSupported, err = readServerHelloFlight(conn); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if !result.HeartbeatSupported {
+		result.Status = HeartbleedUnsupported
+		return result
+	}
+
+	request := []byte{recordTypeHeartbeat, 0x03, 0x03, 0x00, 0x03, heartbeatRequest, heartbleedPayloadLength >> 8, heartbleedPayloadLength & 0xff}
+	if _, err := conn.Write(request); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	deadline := time.Now().Add(heartbleedTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetDeadline(deadline)
+	for {
+		recordType, length, _, err := readRecord(conn, false)
+		if err != nil {
+			// patched servers discard the request, and may close the connection
+			result.Status = HeartbleedPatched
+			return result
+		}
+		switch recordType {
+		case recordTypeHeartbeat:
+			// a response to a request without payload has no payload either, unless the server leaks memory
+			if length > 3+16 {
+				result.Status, result.ResponseLength = HeartbleedVulnerable, length
+			} else {
+				result.Status = HeartbleedPatched
+			}
+			return result
+		case recordTypeAlert:
+			result.Status = HeartbleedPatched
+			return result
+		}
+		// skip the records of other types
+		if _, err := io.CopyN(io.Discard, conn, int64(length)); err != nil {
+			result.Status = HeartbleedPatched
+			return result
+		}
+	}
+}
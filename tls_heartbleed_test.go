@@ -0,0 +1,93 @@
+package zgrab2
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// heartbeatServer answers a ClientHello with a ServerHello negotiating heartbeats and a ServerHelloDone, and then
+// reads a heartbeat request and answers it with respond.
+func heartbeatServer(t *testing.T, respond func(conn net.Conn, request []byte)) func(context.Context) (net.Conn, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if _, _, _, err := readRecord(conn, true); err != nil {
+					return
+				}
+				serverHello := handshakeRecord(handshakeTypeServerHello, func(b *cryptobyte.Builder) {
+					b.AddUint16(tls.TLS_RSA_WITH_AES_128_CBC_SHA)
+					b.AddUint8(0)
+				}, []testExtension{{id: extensionHeartbeat, data: func(b *cryptobyte.Builder) { b.AddUint8(1) }}})
+				conn.Write(append(serverHello, recordTypeHandshake, 0x03, 0x03, 0x00, 0x04, handshakeTypeServerHelloDone, 0, 0, 0))
+				_, _, request, err := readRecord(conn, true)
+				if err != nil {
+					return
+				}
+				respond(conn, request)
+			}()
+		}
+	}()
+	return func(ctx context.Context) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", listener.Addr().String())
+	}
+}
+
+func TestProbeHeartbleed(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	vulnerable := heartbeatServer(t, func(conn net.Conn, request []byte) {
+		if !bytes.Equal(request, []byte{heartbeatRequest, 0x40, 0x00}) {
+			return
+		}
+		length := 3 + heartbleedPayloadLength + 16
+		response := append([]byte{recordTypeHeartbeat, 0x03, 0x03, byte(length >> 8), byte(length), 2, 0x40, 0x00},
+			bytes.Repeat([]byte("secret"), length/6)...)
+		conn.Write(response)
+	})
+	result := ProbeHeartbleed(ctx, vulnerable, "www.example")
+	if result.Status != HeartbleedVulnerable || !result.HeartbeatSupported || result.ResponseLength != 3+heartbleedPayloadLength+16 {
+		t.Errorf("vulnerable server: %+v", result)
+	}
+
+	patched := heartbeatServer(t, func(conn net.Conn, _ []byte) {
+		// discard the request and close the connection
+		io.Copy(io.Discard, conn)
+	})
+	closing, cancelClosing := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancelClosing()
+	if result := ProbeHeartbleed(closing, patched, "www.example"); result.Status != HeartbleedPatched || result.Error != "" {
+		t.Errorf("patched server: %+v", result)
+	}
+
+	server := httptest.NewUnstartedServer(nil)
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+	unsupported := func(ctx context.Context) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", server.Listener.Addr().String())
+	}
+	if result := ProbeHeartbleed(ctx, unsupported, "www.example"); result.Status != HeartbleedUnsupported || result.HeartbeatSupported {
+		t.Errorf("server without heartbeats: %+v", result)
+	}
+}
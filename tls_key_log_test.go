@@ -0,0 +1,97 @@
+package zgrab2
+
+import (
+	"context"
+	"encoding/hex"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTLSKeyLog(t *testing.T) {
+	oldName, oldFile := config.TLSKeyLogFileName, config.tlsKeyLogFile
+	defer func() { config.TLSKeyLogFileName, config.tlsKeyLogFile = oldName, oldFile }()
+	keyLogFile := filepath.Join(t.TempDir(), "keys.log")
+	t.Setenv("SSLKEYLOGFILE", keyLogFile)
+	config.TLSKeyLogFileName, config.tlsKeyLogFile = "", nil
+	if TLSKeyLogWriter() != nil {
+		t.Error("key log writer set without a key log file")
+	}
+	if err := openTLSKeyLog(); err != nil {
+		t.Fatal(err)
+	}
+	defer config.tlsKeyLogFile.Close()
+	if config.TLSKeyLogFileName != keyLogFile {
+		t.Errorf("key log file = %q, expected $SSLKEYLOGFILE", config.TLSKeyLogFileName)
+	}
+
+	server := httptest.NewTLSServer(nil)
+	defer server.Close()
+	addr := server.Listener.Addr().(*net.TCPAddr)
+	conn, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsConn, err := GetDefaultTLSWrapper(&TLSFlags{})(context.Background(), &ScanTarget{IP: addr.IP, Port: uint(addr.Port)}, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsConn.Close()
+
+	keyLog, err := os.ReadFile(keyLogFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// one line per secret, each labelled with the client random of the session
+	lines := strings.Split(strings.TrimSpace(string(keyLog)), "\n")
+	clientRandom := tlsConn.GetLog().HandshakeLog.ClientHello.Random
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[1] != hex.EncodeToString(clientRandom) {
+			t.Errorf("unexpected key log line %q", line)
+		}
+	}
+	if !strings.Contains(string(keyLog), "CLIENT_RANDOM ") && !strings.Contains(string(keyLog), "CLIENT_TRAFFIC_SECRET_0 ") {
+		t.Errorf("no session secret in key log %q", keyLog)
+	}
+}
+
+// useTLSKeyLog sets --tls-key-log-file to a new file for the duration of the test, returning its name.
+func useTLSKeyLog(t *testing.T) string {
+	oldName, oldFile := config.TLSKeyLogFileName, config.tlsKeyLogFile
+	t.Cleanup(func() { config.TLSKeyLogFileName, config.tlsKeyLogFile = oldName, oldFile })
+	config.TLSKeyLogFileName, config.tlsKeyLogFile = filepath.Join(t.TempDir(), "keys.log"), nil
+	if err := openTLSKeyLog(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { config.tlsKeyLogFile.Close() })
+	return config.TLSKeyLogFileName
+}
+
+// TestProbeKeyLog checks that the probes which build their own TLS configurations log their session secrets.
+func TestProbeKeyLog(t *testing.T) {
+	server := httptest.NewTLSServer(nil)
+	defer server.Close()
+	dial := func(ctx context.Context) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", server.Listener.Addr().String())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for name, probe := range map[string]func(){
+		"alpn":       func() { ProbeALPN(ctx, dial, "www.example", []string{"http/1.1"}) },
+		"ech":        func() { ProbeECH(ctx, dial, "www.example", nil, false) },
+		"resumption": func() { ProbeResumption(ctx, dial, "www.example") },
+	} {
+		keyLogFile := useTLSKeyLog(t)
+		probe()
+		keyLog, err := os.ReadFile(keyLogFile)
+		if err != nil || !strings.Contains(string(keyLog), "CLIENT_TRAFFIC_SECRET_0 ") {
+			t.Errorf("%s: key log = %q, %v", name, keyLog, err)
+		}
+	}
+}
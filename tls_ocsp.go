@@ -0,0 +1,179 @@
+package zgrab2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/zmap/zcrypto/x509"
+	"github.com/zmap/zcrypto/x509/revocation/crl"
+	"github.com/zmap/zcrypto/x509/revocation/ocsp"
+)
+
+// maxOCSPResponseSize bounds the OCSP responses read from responders.
+const maxOCSPResponseSize = 1 << 20
+
+// OCSPResponseInfo is an OCSP response for the leaf certificate of a handshake.
+type OCSPResponseInfo struct {
+	Raw []byte `json:"raw,omitempty"`
+	// ResponseStatus is the status of the response as a whole, which only holds a certificate status on success.
+	ResponseStatus string `json:"response_status"`
+
+	// CertStatus is good, revoked or unknown.
+	CertStatus       string                    `json:"cert_status,omitempty"`
+	ProducedAt       *time.Time                `json:"produced_at,omitempty"`
+	ThisUpdate       *time.Time                `json:"this_update,omitempty"`
+	NextUpdate       *time.Time                `json:"next_update,omitempty"`
+	RevokedAt        *time.Time                `json:"revoked_at,omitempty"`
+	RevocationReason *crl.RevocationReasonCode `json:"revocation_reason,omitempty"`
+
+	// SignatureValid tells whether the response is signed by the issuer of the certificate, or by a responder
+	// certificate it issued. It is only checked if the server sent the issuer.
+	SignatureValid bool   `json:"signature_valid"`
+	SignatureError string `json:"signature_error,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// OCSPQueryResult is the outcome of querying the OCSP responder of the leaf certificate.
+type OCSPQueryResult struct {
+	Responder string `json:"responder"`
+	// Latency is the time, in milliseconds, between sending the request and reading the whole response.
+	Latency    int64             `json:"latency_ms,omitempty"`
+	HTTPStatus int               `json:"http_status,omitempty"`
+	Response   *OCSPResponseInfo `json:"response,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// OCSPLog holds the OCSP response stapled to the handshake and the response of the responder of the certificate.
+type OCSPLog struct {
+	Stapled *OCSPResponseInfo `json:"stapled,omitempty"`
+	Query   *OCSPQueryResult  `json:"query,omitempty"`
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// parseOCSPResponse parses an OCSP response for leaf, and checks its signature if the issuer is known.
+func parseOCSPResponse(raw []byte, leaf, issuer *x509.Certificate) *OCSPResponseInfo {
+	info := &OCSPResponseInfo{Raw: raw, ResponseStatus: ocsp.Success.String()}
+	response, err := ocsp.ParseResponseForCert(raw, leaf, nil)
+	if err != nil {
+		var responseError ocsp.ResponseError
+		if errors.As(err, &responseError) {
+			info.ResponseStatus = responseError.Status.String()
+		} else {
+			info.Error = err.Error()
+		}
+		return info
+	}
+	switch response.Status {
+	case ocsp.Good:
+		info.CertStatus = "good"
+	case ocsp.Revoked:
+		info.CertStatus = "revoked"
+		info.RevokedAt = timePtr(response.RevokedAt)
+		info.RevocationReason = &response.RevocationReason
+	default:
+		info.CertStatus = "unknown"
+	}
+	info.ProducedAt = timePtr(response.ProducedAt)
+	info.ThisUpdate = timePtr(response.ThisUpdate)
+	info.NextUpdate = timePtr(response.NextUpdate)
+
+	if issuer == nil {
+		info.SignatureError = "the server did not send the issuer of the certificate"
+		return info
+	}
+	if response.Certificate != nil {
+		// the signature of the response was checked with the responder certificate while parsing
+		err = issuer.CheckSignature(response.Certificate.SignatureAlgorithm, response.Certificate.RawTBSCertificate, response.Certificate.Signature)
+	} else {
+		err = response.CheckSignatureFrom(issuer)
+	}
+	if err != nil {
+		info.SignatureError = err.Error()
+	} else {
+		info.SignatureValid = true
+	}
+	return info
+}
+
+// peerCertificates returns the leaf certificate of the handshake and its issuer, if the server sent it.
+func (z *TLSConnection) peerCertificates() (leaf, issuer *x509.Certificate) {
+	certificates := z.ConnectionState().PeerCertificates
+	if len(certificates) > 0 {
+		leaf = certificates[0]
+	}
+	if len(certificates) > 1 {
+		issuer = certificates[1]
+	}
+	return leaf, issuer
+}
+
+// StapledOCSP returns the OCSP response stapled to the handshake, or nil if there is none.
+func (z *TLSConnection) StapledOCSP() *OCSPResponseInfo {
+	raw := z.ConnectionState().OCSPResponse
+	if len(raw) == 0 {
+		return nil
+	}
+	leaf, issuer := z.peerCertificates()
+	return parseOCSPResponse(raw, leaf, issuer)
+}
+
+// QueryOCSP queries the first OCSP responder of the leaf certificate for its status, on connections opened with
+// dial. It returns nil if the certificate has no responder.
+func (z *TLSConnection) QueryOCSP(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error)) *OCSPQueryResult {
+	leaf, issuer := z.peerCertificates()
+	if leaf == nil || len(leaf.OCSPServer) == 0 {
+		return nil
+	}
+	result := &OCSPQueryResult{Responder: leaf.OCSPServer[0]}
+	if issuer == nil {
+		result.Error = "the server did not send the issuer of the certificate"
+		return result
+	}
+	request, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, result.Responder, bytes.NewReader(request))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	req.Header.Set("Accept", "application/ocsp-response")
+	client := &http.Client{Transport: &http.Transport{DialContext: dial, DisableKeepAlives: true}}
+
+	sent := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+	result.HTTPStatus = resp.StatusCode
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxOCSPResponseSize))
+	result.Latency = time.Since(sent).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if resp.StatusCode != http.StatusOK {
+		result.Error = fmt.Sprintf("responder returned HTTP %s", resp.Status)
+		return result
+	}
+	result.Response = parseOCSPResponse(body, leaf, issuer)
+	return result
+}
@@ -0,0 +1,126 @@
+package zgrab2
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	stdx509 "crypto/x509"
+	stdpkix "crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+func TestOCSP(t *testing.T) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &stdx509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               stdpkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              stdx509.KeyUsageCertSign,
+	}
+	caDER, _ := stdx509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	ca, _ := stdx509.ParseCertificate(caDER)
+
+	revokedAt := time.Now().Add(-time.Minute).Truncate(time.Second)
+	responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request, err := ocsp.ParseRequest(body)
+		if err != nil || r.Header.Get("Content-Type") != "application/ocsp-request" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		response, _ := ocsp.CreateResponse(ca, ca, ocsp.Response{
+			Status:           ocsp.Revoked,
+			SerialNumber:     request.SerialNumber,
+			ThisUpdate:       time.Now().Truncate(time.Second),
+			RevokedAt:        revokedAt,
+			RevocationReason: ocsp.KeyCompromise,
+		}, caKey)
+		w.Header().Set("Content-Type", "application/ocsp-response")
+		w.Write(response)
+	}))
+	defer responder.Close()
+
+	leafKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	leafTemplate := &stdx509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      stdpkix.Name{CommonName: "www.example"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		OCSPServer:   []string{responder.URL},
+	}
+	leafDER, _ := stdx509.CreateCertificate(rand.Reader, leafTemplate, ca, &leafKey.PublicKey, caKey)
+	nextUpdate := time.Now().Add(time.Hour).Truncate(time.Second)
+	// the staple is signed by another key
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	staple, err := ocsp.CreateResponse(ca, ca, ocsp.Response{
+		Status:       ocsp.Good,
+		SerialNumber: big.NewInt(2),
+		ThisUpdate:   time.Now().Truncate(time.Second),
+		NextUpdate:   nextUpdate,
+	}, otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(nil)
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{{
+		Certificate: [][]byte{leafDER, caDER},
+		PrivateKey:  leafKey,
+		OCSPStaple:  staple,
+	}}}
+	server.StartTLS()
+	defer server.Close()
+	addr := server.Listener.Addr().(*net.TCPAddr)
+	conn, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsConn, err := GetDefaultTLSWrapper(&TLSFlags{})(context.Background(), &ScanTarget{IP: addr.IP, Port: uint(addr.Port)}, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tlsConn.Close()
+
+	stapled := tlsConn.StapledOCSP()
+	if stapled == nil || stapled.ResponseStatus != "success" || stapled.CertStatus != "good" || stapled.Error != "" {
+		t.Fatalf("stapled = %+v", stapled)
+	}
+	if stapled.NextUpdate == nil || !stapled.NextUpdate.Equal(nextUpdate) || stapled.ProducedAt == nil {
+		t.Errorf("stapled times = %v, %v", stapled.ProducedAt, stapled.NextUpdate)
+	}
+	if stapled.SignatureValid || stapled.SignatureError == "" {
+		t.Errorf("staple signed by another key: %+v", stapled)
+	}
+
+	var d net.Dialer
+	query := tlsConn.QueryOCSP(context.Background(), d.DialContext)
+	if query == nil || query.Responder != responder.URL || query.HTTPStatus != http.StatusOK || query.Error != "" || query.Response == nil {
+		t.Fatalf("query = %+v", query)
+	}
+	response := query.Response
+	if response.CertStatus != "revoked" || !response.SignatureValid || response.RevokedAt == nil || !response.RevokedAt.Equal(revokedAt) ||
+		response.RevocationReason == nil || *response.RevocationReason != ocsp.KeyCompromise {
+		t.Errorf("response = %+v", response)
+	}
+}
+
+func TestParseOCSPResponseStatus(t *testing.T) {
+	// an OCSPResponse with the tryLater status and no responseBytes
+	info := parseOCSPResponse([]byte{0x30, 0x03, 0x0a, 0x01, 0x03}, nil, nil)
+	if info.ResponseStatus != "try later" || info.CertStatus != "" || info.Error != "" {
+		t.Errorf("info = %+v", info)
+	}
+}
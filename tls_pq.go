@@ -0,0 +1,312 @@
+package zgrab2
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/mlkem"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// Hybrid post-quantum key exchange groups.
+const (
+	GroupX25519MLKEM768        = 0x11ec
+	GroupSecP256r1MLKEM768     = 0x11eb
+	GroupX25519Kyber768Draft00 = 0x6399
+)
+
+const (
+	groupX25519    = 0x001d
+	groupSecP256r1 = 0x0017
+	groupSecP384r1 = 0x0018
+
+	recordTypeAlert = 21
+
+	extensionPSKKeyExchangeModes = 45
+	extensionKeyShare            = 51
+)
+
+// pqGroupNames are the names of the post-quantum groups --pq-groups accepts.
+var pqGroupNames = map[string]uint16{
+	"X25519MLKEM768":        GroupX25519MLKEM768,
+	"SecP256r1MLKEM768":     GroupSecP256r1MLKEM768,
+	"X25519Kyber768Draft00": GroupX25519Kyber768Draft00,
+}
+
+// DefaultPQGroups are the post-quantum groups offered unless --pq-groups is given: the standard hybrid, and the
+// draft hybrid of legacy servers.
+var DefaultPQGroups = []uint16{GroupX25519MLKEM768, GroupX25519Kyber768Draft00}
+
+// helloRetryRequestRandom is the random of a ServerHello that is a HelloRetryRequest (RFC 8446 section 4.1.3).
+var helloRetryRequestRandom = sha256.Sum256([]byte("HelloRetryRequest"))
+
+// PQGroupName returns the name of a key exchange group.
+func PQGroupName(group uint16) string {
+	for name, id := range pqGroupNames {
+		if id == group {
+			return name
+		}
+	}
+	return tls.CurveID(group).String()
+}
+
+// ParsePQGroups parses a comma-separated list of post-quantum group names.
+func ParsePQGroups(list string) ([]uint16, error) {
+	var groups []uint16
+	for _, name := range strings.Split(list, ",") {
+		group, ok := pqGroupNames[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown post-quantum group %s", name)
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// PQKeyShareResult is the group a server selects from a TLS 1.3 ClientHello offering post-quantum hybrid groups.
+type PQKeyShareResult struct {
+	OfferedGroups []string `json:"offered_groups"`
+	// PQOnly is set if no classical group was offered.
+	PQOnly  bool   `json:"pq_only,omitempty"`
+	Version string `json:"version,omitempty"`
+
+	SelectedGroup string `json:"selected_group,omitempty"`
+	// HelloRetryRequest is set if the server asked for a key share of the selected group in a second ClientHello.
+	HelloRetryRequest bool `json:"hello_retry_request,omitempty"`
+	PostQuantum       bool `json:"post_quantum"`
+
+	Alert string `json:"alert,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// pqKeyShare returns a key share of a group, with fresh keys.
+func pqKeyShare(group uint16) ([]byte, error) {
+	x25519, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if group == groupX25519 {
+		return x25519.PublicKey().Bytes(), nil
+	}
+	mlkemKey, err := mlkem.GenerateKey768()
+	if err != nil {
+		return nil, err
+	}
+	encapsulationKey := mlkemKey.EncapsulationKey().Bytes()
+	switch group {
+	case GroupX25519MLKEM768:
+		return append(encapsulationKey, x25519.PublicKey().Bytes()...), nil
+	case GroupX25519Kyber768Draft00:
+		// Kyber768 round 3 public keys are encoded as ML-KEM-768 encapsulation keys
+		return append(x25519.PublicKey().Bytes(), encapsulationKey...), nil
+	case GroupSecP256r1MLKEM768:
+		p256, err := ecdh.P256().GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return append(p256.PublicKey().Bytes(), encapsulationKey...), nil
+	}
+	return nil, fmt.Errorf("no key share for group %04x", group)
+}
+
+// marshalPQClientHello returns a TLS record with a ClientHello offering TLS 1.3 and 1.2, the groups of supported,
+// and key shares for those of shares.
+func marshalPQClientHello(serverName string, supported, shares []uint16) ([]byte, error) {
+	random := make([]byte, 64)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	keyShares := make([][]byte, len(shares))
+	for i, group := range shares {
+		share, err := pqKeyShare(group)
+		if err != nil {
+			return nil, err
+		}
+		keyShares[i] = share
+	}
+	var b cryptobyte.Builder
+	b.AddUint8(recordTypeHandshake)
+	b.AddUint16(tls.VersionTLS10)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint8(handshakeTypeClientHello)
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(tls.VersionTLS12)
+			b.AddBytes(random[:32])
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(random[32:]) })
+			addUint16List(b, tls.TLS_AES_128_GCM_SHA256, tls.TLS_AES_256_GCM_SHA384, tls.TLS_CHACHA20_POLY1305_SHA256,
+				tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+				tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256, tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256)
+			// null compression
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint8(0) })
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				if serverName != "" && net.ParseIP(serverName) == nil {
+					b.AddUint16(extensionServerName)
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+							b.AddUint8(0)
+							b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte(serverName)) })
+						})
+					})
+				}
+				b.AddUint16(extensionSupportedGroups)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { addUint16List(b, supported...) })
+				b.AddUint16(extensionECPointFormats)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint8(0) })
+				})
+				b.AddUint16(extensionSignatureAlgorithms)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					addUint16List(b, 0x0403, 0x0804, 0x0401, 0x0503, 0x0805, 0x0501, 0x0806, 0x0601)
+				})
+				b.AddUint16(extensionSupportedVersions)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddUint16(tls.VersionTLS13)
+						b.AddUint16(tls.VersionTLS12)
+					})
+				})
+				b.AddUint16(extensionPSKKeyExchangeModes)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					// psk_dhe_ke
+					b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint8(1) })
+				})
+				b.AddUint16(extensionKeyShare)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						for i, group := range shares {
+							b.AddUint16(group)
+							b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(keyShares[i]) })
+						}
+					})
+				})
+			})
+		})
+	})
+	return b.Bytes()
+}
+
+func addUint16List(b *cryptobyte.Builder, values ...uint16) {
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		for _, v := range values {
+			b.AddUint16(v)
+		}
+	})
+}
+
+// readServerHello reads TLS records until they hold a ServerHello, and returns its body, or the alert the server
+// sent instead.
+func readServerHello(conn net.Conn) ([]byte, error) {
+	var records []byte
+	buf := make([]byte, 4096)
+	for len(records) < maxRecordedHello {
+		n, err := conn.Read(buf)
+		records = append(records, buf[:n]...)
+		if len(records) >= 7 && records[0] == recordTypeAlert {
+			return nil, tls.AlertError(records[6])
+		}
+		if msg, ok := firstHandshakeMessage(records, handshakeTypeServerHello); ok {
+			return msg, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, errors.New("no ServerHello")
+}
+
+// parseServerHelloGroup returns the version and key share group of a ServerHello, and whether it is a
+// HelloRetryRequest.
+func parseServerHelloGroup(msg []byte) (version, group uint16, retry bool, err error) {
+	s := cryptobyte.String(msg)
+	var random, sessionID cryptobyte.String
+	if !s.ReadUint16(&version) || !s.ReadBytes((*[]byte)(&random), 32) || !s.ReadUint8LengthPrefixed(&sessionID) ||
+		!s.Skip(3) {
+		return 0, 0, false, errors.New("malformed ServerHello")
+	}
+	retry = bytes.Equal(random, helloRetryRequestRandom[:])
+	var extensions cryptobyte.String
+	if !s.Empty() && !s.ReadUint16LengthPrefixed(&extensions) {
+		return 0, 0, false, errors.New("malformed ServerHello")
+	}
+	for !extensions.Empty() {
+		var extType uint16
+		var data cryptobyte.String
+		if !extensions.ReadUint16(&extType) || !extensions.ReadUint16LengthPrefixed(&data) {
+			return 0, 0, false, errors.New("malformed ServerHello extensions")
+		}
+		switch extType {
+		case extensionSupportedVersions:
+			data.ReadUint16(&version)
+		case extensionKeyShare:
+			// the selected group of a HelloRetryRequest, or the group of the server share
+			data.ReadUint16(&group)
+		}
+	}
+	return version, group, retry, nil
+}
+
+// ProbePQKeyShare sends a TLS 1.3 ClientHello with key shares of the post-quantum hybrid groups pqGroups, along with
+// classical groups unless pqOnly is set, on a connection opened with dial, and records the group the server selects.
+// Servers that only support TLS 1.2 select no group in their ServerHello.
+func ProbePQKeyShare(ctx context.Context, dial func(context.Context) (net.Conn, error), serverName string, pqGroups []uint16, pqOnly bool) *PQKeyShareResult {
+	supported, shares := slices.Clone(pqGroups), slices.Clone(pqGroups)
+	if !pqOnly {
+		supported = append(supported, groupX25519, groupSecP256r1, groupSecP384r1)
+		shares = append(shares, groupX25519)
+	}
+	result := &PQKeyShareResult{PQOnly: pqOnly}
+	for _, group := range supported {
+		result.OfferedGroups = append(result.OfferedGroups, PQGroupName(group))
+	}
+	hello, err := marshalPQClientHello(serverName, supported, shares)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	conn, err := dial(ctx)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write(hello); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	msg, err := readServerHello(conn)
+	var alert tls.AlertError
+	if errors.As(err, &alert) {
+		result.Alert = alert.Error()
+		return result
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	version, group, retry, err := parseServerHelloGroup(msg)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Version = tls.VersionName(version)
+	result.HelloRetryRequest = retry
+	if group != 0 {
+		result.SelectedGroup = PQGroupName(group)
+		result.PostQuantum = slices.Contains(pqGroups, group)
+	}
+	return result
+}
@@ -0,0 +1,60 @@
+package zgrab2
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestProbePQKeyShare(t *testing.T) {
+	newServer := func(config *tls.Config) func(context.Context) (net.Conn, error) {
+		server := httptest.NewUnstartedServer(nil)
+		server.TLS = config
+		server.StartTLS()
+		t.Cleanup(server.Close)
+		return func(ctx context.Context) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "tcp", server.Listener.Addr().String())
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result := ProbePQKeyShare(ctx, newServer(&tls.Config{}), "www.example", DefaultPQGroups, false)
+	if result.SelectedGroup != "X25519MLKEM768" || !result.PostQuantum || result.HelloRetryRequest || result.Version != "TLS 1.3" {
+		t.Errorf("PQ server: %+v", result)
+	}
+	if !slices.Equal(result.OfferedGroups, []string{"X25519MLKEM768", "X25519Kyber768Draft00", "X25519", "CurveP256", "CurveP384"}) {
+		t.Errorf("offered groups %v", result.OfferedGroups)
+	}
+
+	// the server supports a classical group without a key share
+	result = ProbePQKeyShare(ctx, newServer(&tls.Config{CurvePreferences: []tls.CurveID{tls.CurveP384}}), "www.example", DefaultPQGroups, false)
+	if result.SelectedGroup != "CurveP384" || result.PostQuantum || !result.HelloRetryRequest {
+		t.Errorf("classical server: %+v", result)
+	}
+
+	result = ProbePQKeyShare(ctx, newServer(&tls.Config{CurvePreferences: []tls.CurveID{tls.X25519}}), "www.example", DefaultPQGroups, true)
+	if result.SelectedGroup != "" || result.Alert == "" || !result.PQOnly {
+		t.Errorf("PQ only to a classical server: %+v", result)
+	}
+
+	result = ProbePQKeyShare(ctx, newServer(&tls.Config{MaxVersion: tls.VersionTLS12}), "www.example", DefaultPQGroups, false)
+	if result.Version != "TLS 1.2" || result.SelectedGroup != "" || result.Error != "" {
+		t.Errorf("TLS 1.2 server: %+v", result)
+	}
+}
+
+func TestParsePQGroups(t *testing.T) {
+	groups, err := ParsePQGroups("X25519MLKEM768, SecP256r1MLKEM768")
+	if err != nil || !slices.Equal(groups, []uint16{GroupX25519MLKEM768, GroupSecP256r1MLKEM768}) {
+		t.Errorf("ParsePQGroups = %v, %v", groups, err)
+	}
+	if _, err := ParsePQGroups("X25519"); err == nil {
+		t.Error("ParsePQGroups accepted a classical group")
+	}
+}
@@ -0,0 +1,258 @@
+package zgrab2
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+const (
+	handshakeTypeNewSessionTicket = 4
+
+	// ticketWait bounds the wait for the TLS 1.3 tickets a server sends after the handshake.
+	ticketWait = time.Second
+)
+
+// ResumptionAttempt is the outcome of resuming a session with one mechanism.
+type ResumptionAttempt struct {
+	// Issued tells whether the first handshake gave a session ID or ticket to resume.
+	Issued  bool `json:"issued"`
+	Resumed bool `json:"resumed"`
+	// LifetimeHint is the lifetime, in seconds, the server gave its TLS 1.2 ticket. TLS 1.3 ticket lifetimes are
+	// encrypted.
+	LifetimeHint uint32 `json:"lifetime_hint,omitempty"`
+	// Tickets counts the tickets the server issued in the first handshake.
+	Tickets int `json:"tickets,omitempty"`
+	// Rotated tells whether the resumed handshake issued a ticket other than the one it resumed.
+	Rotated bool   `json:"rotated,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ResumptionResult holds the attempts to resume a session by session ID and by TLS 1.2 and TLS 1.3 tickets, each
+// on a pair of connections of their own.
+type ResumptionResult struct {
+	SessionID   *ResumptionAttempt `json:"session_id"`
+	TLS12Ticket *ResumptionAttempt `json:"tls12_ticket"`
+	TLS13Ticket *ResumptionAttempt `json:"tls13_ticket"`
+}
+
+// ticketRecorder is a session cache recording the tickets stored in it.
+type ticketRecorder struct {
+	tls.ClientSessionCache
+	mutex   sync.Mutex
+	tickets [][]byte
+}
+
+func (r *ticketRecorder) Put(key string, session *tls.ClientSessionState) {
+	if session != nil {
+		if ticket, _, err := session.ResumptionState(); err == nil {
+			r.mutex.Lock()
+			r.tickets = append(r.tickets, ticket)
+			r.mutex.Unlock()
+		}
+	}
+	r.ClientSessionCache.Put(key, session)
+}
+
+func (r *ticketRecorder) count() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.tickets)
+}
+
+// recordReader is a connection that records the records it reads until done is set.
+type recordReader struct {
+	net.Conn
+	records []byte
+	done    bool
+}
+
+func (r *recordReader) Read(b []byte) (int, error) {
+	n, err := r.Conn.Read(b)
+	if !r.done {
+		r.records = append(r.records, b[:n]...)
+		r.done = len(r.records) >= maxRecordedHello
+	}
+	return n, err
+}
+
+// ticketLifetimeHint returns the lifetime hint of the TLS 1.2 NewSessionTicket in records, which precedes the
+// ChangeCipherSpec of the server and so is not encrypted.
+func ticketLifetimeHint(records []byte) (uint32, bool) {
+	var handshake []byte
+	for len(records) >= 5 && records[0] == recordTypeHandshake {
+		length := int(records[3])<<8 | int(records[4])
+		if len(records) < 5+length {
+			break
+		}
+		handshake = append(handshake, records[5:5+length]...)
+		records = records[5+length:]
+	}
+	s := cryptobyte.String(handshake)
+	for !s.Empty() {
+		var msgType uint8
+		var msg cryptobyte.String
+		if !s.ReadUint8(&msgType) || !s.ReadUint24LengthPrefixed(&msg) {
+			return 0, false
+		}
+		var lifetime uint32
+		if msgType == handshakeTypeNewSessionTicket && msg.ReadUint32(&lifetime) {
+			return lifetime, true
+		}
+	}
+	return 0, false
+}
+
+// resumptionHandshake performs a handshake on a new connection, and waits for the tickets of TLS 1.3 servers. It
+// returns the connection state and the records read during the handshake.
+func resumptionHandshake(ctx context.Context, dial func(context.Context) (net.Conn, error), config *tls.Config) (tls.ConnectionState, []byte, error) {
+	conn, err := dial(ctx)
+	if err != nil {
+		return tls.ConnectionState{}, nil, err
+	}
+	defer conn.Close()
+	reader := &recordReader{Conn: conn}
+	tlsConn := tls.Client(reader, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return tls.ConnectionState{}, nil, err
+	}
+	reader.done = true
+	state := tlsConn.ConnectionState()
+	if state.Version == tls.VersionTLS13 {
+		// the tickets are post-handshake messages, processed while reading application data
+		tlsConn.SetReadDeadline(time.Now().Add(ticketWait))
+		tlsConn.Read(make([]byte, 1))
+	}
+	return state, reader.records, nil
+}
+
+// probeTicketResumption resumes a session by ticket of a TLS version.
+func probeTicketResumption(ctx context.Context, dial func(context.Context) (net.Conn, error), serverName string, version uint16) *ResumptionAttempt {
+	attempt := &ResumptionAttempt{}
+	tickets := &ticketRecorder{ClientSessionCache: tls.NewLRUClientSessionCache(1)}
+	config := &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+		MinVersion:         version,
+		MaxVersion:         version,
+		ClientSessionCache: tickets,
+		KeyLogWriter:       TLSKeyLogWriter(),
+	}
+	_, records, err := resumptionHandshake(ctx, dial, config)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	attempt.Tickets = tickets.count()
+	attempt.Issued = attempt.Tickets > 0
+	if version == tls.VersionTLS12 {
+		attempt.LifetimeHint, _ = ticketLifetimeHint(records)
+	}
+	if !attempt.Issued {
+		return attempt
+	}
+	resumed := tickets.tickets[len(tickets.tickets)-1]
+
+	state, _, err := resumptionHandshake(ctx, dial, config)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	attempt.Resumed = state.DidResume
+	for _, ticket := range tickets.tickets[attempt.Tickets:] {
+		attempt.Rotated = attempt.Rotated || !bytes.Equal(ticket, resumed)
+	}
+	return attempt
+}
+
+// serverHelloSessionID returns the session ID of a ServerHello.
+func serverHelloSessionID(msg []byte) ([]byte, bool) {
+	s := cryptobyte.String(msg)
+	var sessionID cryptobyte.String
+	if !s.Skip(2+32) || !s.ReadUint8LengthPrefixed(&sessionID) {
+		return nil, false
+	}
+	return sessionID, true
+}
+
+// resumeSessionID sends a TLS 1.2 ClientHello with sessionID on a new connection, and returns whether the server
+// resumes it, which it does by echoing it in the ServerHello.
+func resumeSessionID(ctx context.Context, dial func(context.Context) (net.Conn, error), serverName string, sessionID []byte) (bool, error) {
+	hello, err := marshalTLS12ClientHello(serverName, sessionID, false)
+	if err != nil {
+		return false, err
+	}
+	conn, err := dial(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write(hello); err != nil {
+		return false, err
+	}
+	msg, err := readServerHello(conn)
+	if err != nil {
+		return false, err
+	}
+	echoed, ok := serverHelloSessionID(msg)
+	if !ok {
+		return false, errors.New("malformed ServerHello")
+	}
+	return bytes.Equal(echoed, sessionID), nil
+}
+
+// probeSessionIDResumption completes a TLS 1.2 handshake without tickets, so that the server caches the session
+// under the ID it sends, and then attempts to resume it.
+func probeSessionIDResumption(ctx context.Context, dial func(context.Context) (net.Conn, error), serverName string) *ResumptionAttempt {
+	attempt := &ResumptionAttempt{}
+	conn, err := dial(ctx)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	hellos := newHelloRecorder(conn)
+	tlsConn := tls.Client(hellos, &tls.Config{
+		ServerName:             serverName,
+		InsecureSkipVerify:     true,
+		MinVersion:             tls.VersionTLS12,
+		MaxVersion:             tls.VersionTLS12,
+		SessionTicketsDisabled: true,
+		KeyLogWriter:           TLSKeyLogWriter(),
+	})
+	err = tlsConn.HandshakeContext(ctx)
+	tlsConn.Close()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	hellos.mutex.Lock()
+	sessionID, _ := serverHelloSessionID(hellos.serverHello)
+	hellos.mutex.Unlock()
+	attempt.Issued = len(sessionID) > 0
+	if !attempt.Issued {
+		return attempt
+	}
+	if attempt.Resumed, err = resumeSessionID(ctx, dial, serverName, sessionID); err != nil {
+		attempt.Error = err.Error()
+	}
+	return attempt
+}
+
+// ProbeResumption attempts to resume sessions by session ID, TLS 1.2 ticket and TLS 1.3 ticket with the server, on
+// connections opened with dial.
+func ProbeResumption(ctx context.Context, dial func(context.Context) (net.Conn, error), serverName string) *ResumptionResult {
+	return &ResumptionResult{
+		SessionID:   probeSessionIDResumption(ctx, dial, serverName),
+		TLS12Ticket: probeTicketResumption(ctx, dial, serverName, tls.VersionTLS12),
+		TLS13Ticket: probeTicketResumption(ctx, dial, serverName, tls.VersionTLS13),
+	}
+}
@@ -0,0 +1,120 @@
+package zgrab2
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+func TestProbeResumption(t *testing.T) {
+	newServer := func(config *tls.Config) func(context.Context) (net.Conn, error) {
+		server := httptest.NewUnstartedServer(nil)
+		server.TLS = config
+		server.StartTLS()
+		t.Cleanup(server.Close)
+		return func(ctx context.Context) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "tcp", server.Listener.Addr().String())
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Go servers give no lifetime hint with TLS 1.2 tickets
+	result := ProbeResumption(ctx, newServer(&tls.Config{}), "www.example")
+	if a := result.TLS12Ticket; !a.Issued || !a.Resumed || a.Error != "" {
+		t.Errorf("TLS 1.2 ticket: %+v", a)
+	}
+	// Go servers issue a new ticket on every handshake
+	if a := result.TLS13Ticket; !a.Issued || !a.Resumed || !a.Rotated || a.Error != "" {
+		t.Errorf("TLS 1.3 ticket: %+v", a)
+	}
+	// nor do they cache sessions by ID
+	if a := result.SessionID; a.Issued || a.Resumed || a.Error != "" {
+		t.Errorf("session ID: %+v", a)
+	}
+
+	result = ProbeResumption(ctx, newServer(&tls.Config{SessionTicketsDisabled: true}), "www.example")
+	if a := result.TLS12Ticket; a.Issued || a.Resumed || a.Error != "" {
+		t.Errorf("TLS 1.2 ticket without tickets: %+v", a)
+	}
+	if a := result.TLS13Ticket; a.Issued || a.Resumed || a.Error != "" {
+		t.Errorf("TLS 1.3 ticket without tickets: %+v", a)
+	}
+}
+
+func TestTicketLifetimeHint(t *testing.T) {
+	records := []byte{recordTypeHandshake, 0x03, 0x03, 0x00, 0x0e,
+		handshakeTypeNewSessionTicket, 0, 0, 0x0a, 0x00, 0x00, 0x1c, 0x20, 0x00, 0x04, 1, 2, 3, 4,
+		20, 0x03, 0x03, 0x00, 0x01, 1}
+	if lifetime, ok := ticketLifetimeHint(records); lifetime != 7200 || !ok {
+		t.Errorf("ticketLifetimeHint = %d, %v", lifetime, ok)
+	}
+	if _, ok := ticketLifetimeHint(records[19:]); ok {
+		t.Error("ticketLifetimeHint found a ticket after the ChangeCipherSpec")
+	}
+}
+
+func TestResumeSessionID(t *testing.T) {
+	// the server echoes the session ID of the ClientHello if it starts with 1
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _, body, err := readRecord(conn, true)
+				if err != nil {
+					return
+				}
+				var sessionID cryptobyte.String
+				s := cryptobyte.String(body[4:])
+				if !s.Skip(2+32) || !s.ReadUint8LengthPrefixed(&sessionID) {
+					return
+				}
+				if len(sessionID) == 0 || sessionID[0] != 1 {
+					sessionID = make([]byte, 32)
+				}
+				var b cryptobyte.Builder
+				b.AddUint8(recordTypeHandshake)
+				b.AddUint16(tls.VersionTLS12)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint8(handshakeTypeServerHello)
+					b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddUint16(tls.VersionTLS12)
+						b.AddBytes(make([]byte, 32))
+						b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(sessionID) })
+						b.AddUint16(tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256)
+						b.AddUint8(0)
+					})
+				})
+				conn.Write(b.BytesOrPanic())
+			}()
+		}
+	}()
+	dial := func(ctx context.Context) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", listener.Addr().String())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if resumed, err := resumeSessionID(ctx, dial, "www.example", []byte{1, 2, 3, 4}); !resumed || err != nil {
+		t.Errorf("resumeSessionID of a cached session = %v, %v", resumed, err)
+	}
+	if resumed, err := resumeSessionID(ctx, dial, "www.example", []byte{2, 3, 4}); resumed || err != nil {
+		t.Errorf("resumeSessionID of an unknown session = %v, %v", resumed, err)
+	}
+}
@@ -0,0 +1,334 @@
+package zgrab2
+
+import (
+	"bytes"
+	"crypto/sha256"
+	stdx509 "crypto/x509"
+	encoding_asn1 "encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zmap/zcrypto/ct"
+	"github.com/zmap/zcrypto/encoding/asn1"
+	"github.com/zmap/zcrypto/x509"
+	"github.com/zmap/zcrypto/x509/pkix"
+	"github.com/zmap/zcrypto/x509/revocation/ocsp"
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// Sources of an SCTResult.
+const (
+	SCTSourceEmbedded     = "embedded"
+	SCTSourceTLSExtension = "tls_extension"
+	SCTSourceOCSP         = "ocsp"
+)
+
+var (
+	// oidSCTList is the certificate extension holding the SCTs of a precertificate (RFC 6962 section 3.3).
+	oidSCTList = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+	// oidOCSPSCTList is the OCSP single extension holding SCTs (RFC 6962 section 3.3).
+	oidOCSPSCTList = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 5}
+)
+
+// CTLog is a Certificate Transparency log of a log list.
+type CTLog struct {
+	Description string
+	Operator    string
+	// State is the state of the log in the list, such as usable, readonly or retired.
+	State    string
+	verifier *ct.SignatureVerifier
+}
+
+// CTLogList holds the logs of a log list by log ID.
+type CTLogList struct {
+	logs map[[sha256.Size]byte]*CTLog
+}
+
+type ctLogJSON struct {
+	Description string                     `json:"description"`
+	Key         string                     `json:"key"`
+	State       map[string]json.RawMessage `json:"state"`
+}
+
+type ctLogListJSON struct {
+	Operators []struct {
+		Name      string      `json:"name"`
+		Logs      []ctLogJSON `json:"logs"`
+		TiledLogs []ctLogJSON `json:"tiled_logs"`
+	} `json:"operators"`
+}
+
+// ParseCTLogList parses a log list in the v3 format of the Chrome log list. The log IDs are computed from the keys.
+func ParseCTLogList(data []byte) (*CTLogList, error) {
+	var list ctLogListJSON
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	logs := &CTLogList{logs: make(map[[sha256.Size]byte]*CTLog)}
+	for _, operator := range list.Operators {
+		for _, log := range append(operator.Logs, operator.TiledLogs...) {
+			key, err := base64.StdEncoding.DecodeString(log.Key)
+			if err != nil {
+				return nil, fmt.Errorf("key of log %s: %w", log.Description, err)
+			}
+			publicKey, err := stdx509.ParsePKIXPublicKey(key)
+			if err != nil {
+				return nil, fmt.Errorf("key of log %s: %w", log.Description, err)
+			}
+			verifier, err := ct.NewSignatureVerifier(publicKey)
+			if err != nil {
+				return nil, fmt.Errorf("key of log %s: %w", log.Description, err)
+			}
+			ctLog := &CTLog{Description: log.Description, Operator: operator.Name, verifier: verifier}
+			for state := range log.State {
+				ctLog.State = state
+			}
+			logs.logs[sha256.Sum256(key)] = ctLog
+		}
+	}
+	return logs, nil
+}
+
+// LoadCTLogList parses the log list of a file.
+func LoadCTLogList(file string) (*CTLogList, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCTLogList(data)
+}
+
+// SCTResult is the validation of a Signed Certificate Timestamp of the leaf certificate.
+type SCTResult struct {
+	Source    string    `json:"source"`
+	LogID     []byte    `json:"log_id,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+
+	// KnownLog tells whether the log is in the log list, whose description, operator and state are then given.
+	KnownLog       bool   `json:"known_log"`
+	LogDescription string `json:"log_description,omitempty"`
+	LogOperator    string `json:"log_operator,omitempty"`
+	LogState       string `json:"log_state,omitempty"`
+
+	// Valid tells whether the signature of the SCT verifies with the key of the log.
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+// CTResult holds the SCTs of the leaf certificate, from the certificate itself, the TLS extension and the stapled
+// OCSP response.
+type CTResult struct {
+	SCTs []*SCTResult `json:"scts,omitempty"`
+	// ValidSCTs counts the valid SCTs of distinct logs, and ValidOperators lists the operators of these logs, the
+	// two figures CT policies require minimums of.
+	ValidSCTs      int      `json:"valid_scts"`
+	ValidOperators []string `json:"valid_operators,omitempty"`
+	Error          string   `json:"error,omitempty"`
+}
+
+// readSCTList returns the SCTs of a SignedCertificateTimestampList (RFC 6962 section 3.3).
+func readSCTList(data []byte) ([][]byte, error) {
+	s := cryptobyte.String(data)
+	var list cryptobyte.String
+	if !s.ReadUint16LengthPrefixed(&list) || !s.Empty() {
+		return nil, errors.New("malformed SCT list")
+	}
+	var scts [][]byte
+	for !list.Empty() {
+		var sct cryptobyte.String
+		if !list.ReadUint16LengthPrefixed(&sct) {
+			return nil, errors.New("malformed SCT list")
+		}
+		scts = append(scts, sct)
+	}
+	return scts, nil
+}
+
+// sctListExtension returns the SCTs of the extension id of extensions, whose value is an OCTET STRING holding a
+// SignedCertificateTimestampList.
+func sctListExtension(extensions []pkix.Extension, id asn1.ObjectIdentifier) ([][]byte, error) {
+	for _, extension := range extensions {
+		if !extension.Id.Equal(id) {
+			continue
+		}
+		value := cryptobyte.String(extension.Value)
+		var list cryptobyte.String
+		if !value.ReadASN1(&list, cryptobyte_asn1.OCTET_STRING) {
+			return nil, errors.New("malformed SCT list extension")
+		}
+		return readSCTList(list)
+	}
+	return nil, nil
+}
+
+// precertTBSCertificate returns the TBSCertificate of a certificate without its SCT list extension, which is what
+// the log signed for an SCT embedded in the certificate (RFC 6962 section 3.2).
+func precertTBSCertificate(tbs []byte) ([]byte, error) {
+	input := cryptobyte.String(tbs)
+	var fields cryptobyte.String
+	if !input.ReadASN1(&fields, cryptobyte_asn1.SEQUENCE) {
+		return nil, errors.New("malformed TBSCertificate")
+	}
+	extensionsTag := cryptobyte_asn1.Tag(3).Constructed().ContextSpecific()
+	var b cryptobyte.Builder
+	var err error
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		for !fields.Empty() {
+			var field cryptobyte.String
+			var tag cryptobyte_asn1.Tag
+			if !fields.ReadAnyASN1Element(&field, &tag) {
+				err = errors.New("malformed TBSCertificate")
+				return
+			}
+			if tag != extensionsTag {
+				b.AddBytes(field)
+				continue
+			}
+			var explicit, extensions cryptobyte.String
+			if !field.ReadASN1(&explicit, extensionsTag) || !explicit.ReadASN1(&extensions, cryptobyte_asn1.SEQUENCE) {
+				err = errors.New("malformed extensions")
+				return
+			}
+			b.AddASN1(extensionsTag, func(b *cryptobyte.Builder) {
+				b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+					for !extensions.Empty() {
+						var extension, contents cryptobyte.String
+						var id encoding_asn1.ObjectIdentifier
+						if !extensions.ReadASN1Element(&extension, cryptobyte_asn1.SEQUENCE) {
+							err = errors.New("malformed extension")
+							return
+						}
+						element := extension
+						if !element.ReadASN1(&contents, cryptobyte_asn1.SEQUENCE) || !contents.ReadASN1ObjectIdentifier(&id) {
+							err = errors.New("malformed extension")
+							return
+						}
+						if !id.Equal(encoding_asn1.ObjectIdentifier(oidSCTList)) {
+							b.AddBytes(extension)
+						}
+					}
+				})
+			})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes()
+}
+
+// ctLogEntry returns the log entry an SCT of a source signs: the precertificate of the leaf for embedded SCTs, which
+// needs the key of its issuer, and the leaf itself otherwise.
+func ctLogEntry(source string, leaf, issuer *x509.Certificate) (*ct.LogEntry, error) {
+	entry := &ct.LogEntry{Leaf: ct.MerkleTreeLeaf{LeafType: ct.TimestampedEntryLeafType}}
+	if source != SCTSourceEmbedded {
+		entry.Leaf.TimestampedEntry.EntryType = ct.X509LogEntryType
+		entry.Leaf.TimestampedEntry.X509Entry = leaf.Raw
+		return entry, nil
+	}
+	if issuer == nil {
+		return nil, errors.New("the server did not send the issuer of the certificate")
+	}
+	tbs, err := precertTBSCertificate(leaf.RawTBSCertificate)
+	if err != nil {
+		return nil, err
+	}
+	entry.Leaf.TimestampedEntry.EntryType = ct.PrecertLogEntryType
+	entry.Leaf.TimestampedEntry.PrecertEntry = ct.PreCert{
+		IssuerKeyHash:  sha256.Sum256(issuer.RawSubjectPublicKeyInfo),
+		TBSCertificate: tbs,
+	}
+	return entry, nil
+}
+
+// validateSCT validates a raw SCT of a source against the logs.
+func (logs *CTLogList) validateSCT(source string, raw []byte, leaf, issuer *x509.Certificate) *SCTResult {
+	result := &SCTResult{Source: source}
+	sct, err := ct.DeserializeSCT(bytes.NewReader(raw))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.LogID = sct.LogID[:]
+	result.Timestamp = time.UnixMilli(int64(sct.Timestamp)).UTC()
+	log, ok := logs.logs[sct.LogID]
+	if !ok {
+		result.Error = "unknown log"
+		return result
+	}
+	result.KnownLog, result.LogDescription, result.LogOperator, result.LogState = true, log.Description, log.Operator, log.State
+	entry, err := ctLogEntry(source, leaf, issuer)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if err := log.verifier.VerifySCTSignature(*sct, *entry); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Valid = true
+	return result
+}
+
+// ValidateSCTs validates the SCTs of the leaf certificate of the handshake against the logs.
+func (z *TLSConnection) ValidateSCTs(logs *CTLogList) *CTResult {
+	state := z.ConnectionState()
+	result := &CTResult{}
+	leaf, issuer := z.peerCertificates()
+	if leaf == nil {
+		result.Error = "no certificate"
+		return result
+	}
+
+	var errs []string
+	embedded, err := sctListExtension(leaf.Extensions, oidSCTList)
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("certificate: %v", err))
+	}
+	var stapled [][]byte
+	if len(state.OCSPResponse) > 0 {
+		response, err := ocsp.ParseResponse(state.OCSPResponse, nil)
+		if err == nil {
+			stapled, err = sctListExtension(response.Extensions, oidOCSPSCTList)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("OCSP response: %v", err))
+		}
+	}
+	for _, source := range []struct {
+		name string
+		scts [][]byte
+	}{
+		{SCTSourceEmbedded, embedded},
+		{SCTSourceTLSExtension, state.SignedCertificateTimestamps},
+		{SCTSourceOCSP, stapled},
+	} {
+		for _, raw := range source.scts {
+			result.SCTs = append(result.SCTs, logs.validateSCT(source.name, raw, leaf, issuer))
+		}
+	}
+
+	validLogs := make(map[string]bool)
+	for _, sct := range result.SCTs {
+		if sct.Valid && !validLogs[string(sct.LogID)] {
+			validLogs[string(sct.LogID)] = true
+			if !slices.Contains(result.ValidOperators, sct.LogOperator) {
+				result.ValidOperators = append(result.ValidOperators, sct.LogOperator)
+			}
+		}
+	}
+	result.ValidSCTs = len(validLogs)
+	sort.Strings(result.ValidOperators)
+	if len(errs) > 0 {
+		result.Error = strings.Join(errs, "; ")
+	}
+	return result
+}
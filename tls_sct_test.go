@@ -0,0 +1,224 @@
+package zgrab2
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	stdx509 "crypto/x509"
+	stdpkix "crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/zmap/zcrypto/ct"
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/ocsp"
+)
+
+type testCTLog struct {
+	key      *ecdsa.PrivateKey
+	id       [sha256.Size]byte
+	keyBytes []byte
+}
+
+func newTestCTLog(t *testing.T) *testCTLog {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := stdx509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCTLog{key: key, id: sha256.Sum256(der), keyBytes: der}
+}
+
+// sign returns an SCT of the log for a timestamped entry.
+func (l *testCTLog) sign(t *testing.T, entry ct.TimestampedEntry) []byte {
+	sct := ct.SignedCertificateTimestamp{SCTVersion: ct.V1, LogID: l.id, Timestamp: uint64(time.Now().UnixMilli())}
+	entry.Timestamp = sct.Timestamp
+	input, err := ct.SerializeSCTSignatureInput(sct, ct.LogEntry{Leaf: ct.MerkleTreeLeaf{LeafType: ct.TimestampedEntryLeafType, TimestampedEntry: entry}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256(input)
+	signature, err := ecdsa.SignASN1(rand.Reader, l.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sct.Signature = ct.DigitallySigned{HashAlgorithm: ct.SHA256, SignatureAlgorithm: ct.ECDSA, Signature: signature}
+	raw, err := ct.SerializeSCT(sct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// sctListValue returns an extension value holding a SignedCertificateTimestampList.
+func sctListValue(t *testing.T, scts ...[]byte) []byte {
+	var b cryptobyte.Builder
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		for _, sct := range scts {
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(sct) })
+		}
+	})
+	value, err := asn1.Marshal(b.BytesOrPanic())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestValidateSCTs(t *testing.T) {
+	logA, logB, unknownLog := newTestCTLog(t), newTestCTLog(t), newTestCTLog(t)
+	logList := fmt.Sprintf(`{"operators": [
+		{"name": "Operator A", "logs": [{"description": "Log A", "key": %q, "state": {"usable": {"timestamp": "2024-01-01T00:00:00Z"}}}]},
+		{"name": "Operator B", "tiled_logs": [{"description": "Log B", "key": %q, "state": {"readonly": {}}}]}
+	]}`, base64.StdEncoding.EncodeToString(logA.keyBytes), base64.StdEncoding.EncodeToString(logB.keyBytes))
+	logs, err := ParseCTLogList([]byte(logList))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &stdx509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               stdpkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              stdx509.KeyUsageCertSign,
+	}
+	caDER, err := stdx509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := stdx509.ParseCertificate(caDER)
+
+	// the precertificate is the certificate without the SCT list, which is then signed again with the SCTs of logA
+	leafKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	leafTemplate := &stdx509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      stdpkix.Name{CommonName: "www.example"},
+		DNSNames:     []string{"www.example"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	precertDER, err := stdx509.CreateCertificate(rand.Reader, leafTemplate, ca, &leafKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	precert, _ := stdx509.ParseCertificate(precertDER)
+	embedded := logA.sign(t, ct.TimestampedEntry{EntryType: ct.PrecertLogEntryType, PrecertEntry: ct.PreCert{
+		IssuerKeyHash:  sha256.Sum256(ca.RawSubjectPublicKeyInfo),
+		TBSCertificate: precert.RawTBSCertificate,
+	}})
+	leafTemplate.ExtraExtensions = []stdpkix.Extension{{Id: asn1.ObjectIdentifier(oidSCTList), Value: sctListValue(t, embedded)}}
+	leafDER, err := stdx509.CreateCertificate(rand.Reader, leafTemplate, ca, &leafKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ := stdx509.ParseCertificate(leafDER)
+	if tbs, err := precertTBSCertificate(leaf.RawTBSCertificate); err != nil || !bytes.Equal(tbs, precert.RawTBSCertificate) {
+		t.Fatalf("precertTBSCertificate does not remove the SCT list: %v", err)
+	}
+
+	x509Entry := ct.TimestampedEntry{EntryType: ct.X509LogEntryType, X509Entry: leafDER}
+	staple, err := ocsp.CreateResponse(ca, ca, ocsp.Response{
+		Status:          ocsp.Good,
+		SerialNumber:    leaf.SerialNumber,
+		ThisUpdate:      time.Now(),
+		ExtraExtensions: []stdpkix.Extension{{Id: asn1.ObjectIdentifier(oidOCSPSCTList), Value: sctListValue(t, logB.sign(t, x509Entry))}},
+	}, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(nil)
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{{
+		Certificate:                 [][]byte{leafDER, caDER},
+		PrivateKey:                  leafKey,
+		SignedCertificateTimestamps: [][]byte{unknownLog.sign(t, x509Entry)},
+		OCSPStaple:                  staple,
+	}}}
+	server.StartTLS()
+	defer server.Close()
+	addr := server.Listener.Addr().(*net.TCPAddr)
+	conn, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsConn, err := GetDefaultTLSWrapper(&TLSFlags{})(context.Background(), &ScanTarget{IP: addr.IP, Port: uint(addr.Port)}, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tlsConn.Close()
+
+	result := tlsConn.ValidateSCTs(logs)
+	if len(result.SCTs) != 3 || result.Error != "" {
+		t.Fatalf("result = %+v", result)
+	}
+	for i, want := range []SCTResult{
+		{Source: SCTSourceEmbedded, LogID: logA.id[:], KnownLog: true, LogDescription: "Log A", LogOperator: "Operator A", LogState: "usable", Valid: true},
+		{Source: SCTSourceTLSExtension, LogID: unknownLog.id[:], Error: "unknown log"},
+		{Source: SCTSourceOCSP, LogID: logB.id[:], KnownLog: true, LogDescription: "Log B", LogOperator: "Operator B", LogState: "readonly", Valid: true},
+	} {
+		got := *result.SCTs[i]
+		if time.Since(got.Timestamp) > time.Minute {
+			t.Errorf("SCT %d: timestamp %v", i, got.Timestamp)
+		}
+		got.Timestamp = time.Time{}
+		if !bytes.Equal(got.LogID, want.LogID) {
+			t.Errorf("SCT %d: log ID %x, want %x", i, got.LogID, want.LogID)
+		}
+		got.LogID, want.LogID = nil, nil
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("SCT %d = %+v, want %+v", i, got, want)
+		}
+	}
+	if result.ValidSCTs != 2 || !slices.Equal(result.ValidOperators, []string{"Operator A", "Operator B"}) {
+		t.Errorf("valid SCTs %d of %v", result.ValidSCTs, result.ValidOperators)
+	}
+
+	// an SCT over another certificate does not verify
+	other := logA.sign(t, ct.TimestampedEntry{EntryType: ct.X509LogEntryType, X509Entry: caDER})
+	zleaf := tlsConn.ConnectionState().PeerCertificates[0]
+	if sct := logs.validateSCT(SCTSourceTLSExtension, other, zleaf, nil); sct.Valid || sct.Error == "" {
+		t.Errorf("SCT of another certificate: %+v", sct)
+	}
+	if sct := logs.validateSCT(SCTSourceEmbedded, embedded, zleaf, nil); sct.Valid || sct.Error == "" {
+		t.Errorf("embedded SCT without issuer: %+v", sct)
+	}
+}
+
+func TestLoadCTLogList(t *testing.T) {
+	logList := fmt.Sprintf(`{"operators": [{"name": "Operator", "logs": [{"description": "Log", "key": %q}]}]}`,
+		base64.StdEncoding.EncodeToString(newTestCTLog(t).keyBytes))
+	file := filepath.Join(t.TempDir(), "log_list.json")
+	if err := os.WriteFile(file, []byte(logList), 0600); err != nil {
+		t.Fatal(err)
+	}
+	logs, err := LoadCTLogList(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs.logs) != 1 {
+		t.Errorf("loaded %d logs, expected 1", len(logs.logs))
+	}
+	if _, err := LoadCTLogList(""); err == nil {
+		t.Error("loaded a log list without a file")
+	}
+}
@@ -0,0 +1,291 @@
+package zgrab2
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+const (
+	recordTypeChangeCipherSpec = 20
+	recordTypeApplicationData  = 23
+
+	// maxTranscript bounds the bytes of the records recorded in each direction.
+	maxTranscript = 1 << 18
+)
+
+// handshakeTypeNames are the names of handshake message types.
+var handshakeTypeNames = map[uint8]string{
+	0:   "hello_request",
+	1:   "client_hello",
+	2:   "server_hello",
+	4:   "new_session_ticket",
+	5:   "end_of_early_data",
+	8:   "encrypted_extensions",
+	11:  "certificate",
+	12:  "server_key_exchange",
+	13:  "certificate_request",
+	14:  "server_hello_done",
+	15:  "certificate_verify",
+	16:  "client_key_exchange",
+	20:  "finished",
+	22:  "certificate_status",
+	24:  "key_update",
+	254: "message_hash",
+}
+
+// TranscriptMessage is a handshake message, alert or encrypted record of a handshake transcript.
+type TranscriptMessage struct {
+	// Sender is client or server.
+	Sender string `json:"sender"`
+	// Type is the name of the handshake message type, alert, change_cipher_spec, or encrypted for a record that
+	// could not be decrypted.
+	Type string `json:"type"`
+	// Decrypted tells whether the message was sent encrypted.
+	Decrypted bool `json:"decrypted,omitempty"`
+	// Raw is the message with its header, or the body of the record for other types.
+	Raw []byte `json:"raw"`
+}
+
+// HandshakeTranscript holds the messages of a handshake in the order they were sent and received. The messages of
+// TLS 1.3 are decrypted with the handshake traffic secrets; the encrypted Finished of TLS 1.2 is not.
+type HandshakeTranscript struct {
+	Messages  []TranscriptMessage `json:"messages"`
+	Truncated bool                `json:"truncated,omitempty"`
+}
+
+// transcriptRecord is a TLS record read or written by a connection.
+type transcriptRecord struct {
+	sender string
+	header []byte
+	body   []byte
+}
+
+// transcriptRecorder is a connection that records the records it writes and reads during the handshake, and the
+// TLS 1.3 handshake traffic secrets of a key log.
+type transcriptRecorder struct {
+	net.Conn
+	// keyLog is the key log the secrets are passed on to, if any.
+	keyLog io.Writer
+
+	mutex     sync.Mutex
+	done      bool
+	truncated bool
+	buffers   map[string]*bytes.Buffer
+	records   []transcriptRecord
+	secrets   map[string][]byte
+}
+
+func newTranscriptRecorder(conn net.Conn, keyLog io.Writer) *transcriptRecorder {
+	return &transcriptRecorder{
+		Conn:    conn,
+		keyLog:  keyLog,
+		buffers: map[string]*bytes.Buffer{"client": new(bytes.Buffer), "server": new(bytes.Buffer)},
+		secrets: make(map[string][]byte),
+	}
+}
+
+// record appends the complete records in the stream of sender.
+func (r *transcriptRecorder) record(sender string, b []byte) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	buf := r.buffers[sender]
+	if r.done || r.truncated {
+		return
+	}
+	if buf.Len()+len(b) > maxTranscript {
+		r.truncated = true
+		return
+	}
+	buf.Write(b)
+	for buf.Len() >= 5 {
+		length := int(binary.BigEndian.Uint16(buf.Bytes()[3:5]))
+		if buf.Len() < 5+length {
+			break
+		}
+		record := make([]byte, 5+length)
+		buf.Read(record)
+		r.records = append(r.records, transcriptRecord{sender: sender, header: record[:5], body: record[5:]})
+	}
+}
+
+func (r *transcriptRecorder) Write(b []byte) (int, error) {
+	r.record("client", b)
+	return r.Conn.Write(b)
+}
+
+func (r *transcriptRecorder) Read(b []byte) (int, error) {
+	n, err := r.Conn.Read(b)
+	r.record("server", b[:n])
+	return n, err
+}
+
+// transcriptKeyLog records the secrets of key log lines, and passes them on.
+type transcriptKeyLog struct {
+	recorder *transcriptRecorder
+}
+
+func (l transcriptKeyLog) Write(line []byte) (int, error) {
+	fields := strings.Fields(string(line))
+	if len(fields) == 3 {
+		if secret, err := hex.DecodeString(fields[2]); err == nil {
+			l.recorder.mutex.Lock()
+			l.recorder.secrets[fields[0]] = secret
+			l.recorder.mutex.Unlock()
+		}
+	}
+	if l.recorder.keyLog != nil {
+		return l.recorder.keyLog.Write(line)
+	}
+	return len(line), nil
+}
+
+// trafficKeys decrypt the TLS 1.3 records of a sender.
+type trafficKeys struct {
+	aead cipher.AEAD
+	iv   []byte
+	seq  uint64
+}
+
+// hkdfExpandLabel is HKDF-Expand-Label of RFC 8446 section 7.1, with an empty context.
+func hkdfExpandLabel(h func() hash.Hash, secret []byte, label string, length int) ([]byte, error) {
+	var b cryptobyte.Builder
+	b.AddUint16(uint16(length))
+	b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte("tls13 " + label)) })
+	b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {})
+	info, err := b.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return hkdf.Expand(h, secret, string(info), length)
+}
+
+// newTrafficKeys derives the keys of a TLS 1.3 cipher suite from a traffic secret.
+func newTrafficKeys(suite uint16, secret []byte) (*trafficKeys, error) {
+	h, keyLength := sha256.New, 16
+	switch suite {
+	case tls.TLS_AES_256_GCM_SHA384:
+		h, keyLength = sha512.New384, 32
+	case tls.TLS_CHACHA20_POLY1305_SHA256:
+		keyLength = chacha20poly1305.KeySize
+	case tls.TLS_AES_128_GCM_SHA256:
+	default:
+		return nil, fmt.Errorf("unsupported cipher suite %04x", suite)
+	}
+	key, err := hkdfExpandLabel(h, secret, "key", keyLength)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := hkdfExpandLabel(h, secret, "iv", 12)
+	if err != nil {
+		return nil, err
+	}
+	var aead cipher.AEAD
+	if suite == tls.TLS_CHACHA20_POLY1305_SHA256 {
+		aead, err = chacha20poly1305.New(key)
+	} else {
+		var block cipher.Block
+		if block, err = aes.NewCipher(key); err == nil {
+			aead, err = cipher.NewGCM(block)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &trafficKeys{aead: aead, iv: iv}, nil
+}
+
+// decrypt returns the content type and content of a TLS 1.3 record.
+func (k *trafficKeys) decrypt(header, body []byte) (uint8, []byte, error) {
+	nonce := make([]byte, len(k.iv))
+	copy(nonce, k.iv)
+	for i := 0; i < 8; i++ {
+		nonce[len(nonce)-1-i] ^= byte(k.seq >> (8 * i))
+	}
+	plaintext, err := k.aead.Open(nil, nonce, body, header)
+	if err != nil {
+		return 0, nil, err
+	}
+	k.seq++
+	// the content type follows the content and precedes the padding
+	plaintext = bytes.TrimRight(plaintext, "\x00")
+	if len(plaintext) == 0 {
+		return 0, nil, fmt.Errorf("record without content type")
+	}
+	return plaintext[len(plaintext)-1], plaintext[:len(plaintext)-1], nil
+}
+
+// Transcript stops the recording, and returns the messages of the records recorded.
+func (r *transcriptRecorder) Transcript() *HandshakeTranscript {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.done = true
+	transcript := &HandshakeTranscript{Truncated: r.truncated}
+	handshakes := map[string][]byte{}
+	keys := map[string]*trafficKeys{}
+	// encrypted tells whether the records of a sender are encrypted, after a TLS 1.2 ChangeCipherSpec
+	encrypted := map[string]bool{}
+	var version, suite uint16
+	for _, record := range r.records {
+		sender, recordType, content, decrypted := record.sender, record.header[0], record.body, false
+		if recordType == recordTypeApplicationData && version == tls.VersionTLS13 && keys[sender] != nil {
+			if innerType, plaintext, err := keys[sender].decrypt(record.header, record.body); err == nil {
+				recordType, content, decrypted = innerType, plaintext, true
+			}
+		}
+		switch {
+		case encrypted[sender] || recordType == recordTypeApplicationData && !decrypted:
+			transcript.Messages = append(transcript.Messages, TranscriptMessage{Sender: sender, Type: "encrypted", Raw: content})
+		case recordType == recordTypeAlert:
+			transcript.Messages = append(transcript.Messages, TranscriptMessage{Sender: sender, Type: "alert", Decrypted: decrypted, Raw: content})
+		case recordType == recordTypeChangeCipherSpec:
+			transcript.Messages = append(transcript.Messages, TranscriptMessage{Sender: sender, Type: "change_cipher_spec", Raw: content})
+			encrypted[sender] = version != tls.VersionTLS13
+		case recordType == recordTypeHandshake:
+			handshake := append(handshakes[sender], content...)
+			for len(handshake) >= 4 {
+				length := int(handshake[1])<<16 | int(handshake[2])<<8 | int(handshake[3])
+				if len(handshake) < 4+length {
+					break
+				}
+				msg := handshake[:4+length]
+				handshake = handshake[4+length:]
+				name, ok := handshakeTypeNames[msg[0]]
+				if !ok {
+					name = fmt.Sprintf("unknown(%d)", msg[0])
+				}
+				transcript.Messages = append(transcript.Messages, TranscriptMessage{Sender: sender, Type: name, Decrypted: decrypted, Raw: bytes.Clone(msg)})
+				if msg[0] == handshakeTypeServerHello {
+					var retry bool
+					version, _, retry, _ = parseServerHelloGroup(msg[4:])
+					suite, _ = serverHelloCipherSuite(msg[4:])
+					if version == tls.VersionTLS13 && !retry {
+						// the records following the ServerHello are encrypted with the handshake traffic secrets
+						for sender, label := range map[string]string{"client": "CLIENT_HANDSHAKE_TRAFFIC_SECRET", "server": "SERVER_HANDSHAKE_TRAFFIC_SECRET"} {
+							if secret := r.secrets[label]; secret != nil {
+								keys[sender], _ = newTrafficKeys(suite, secret)
+							}
+						}
+					}
+				}
+			}
+			handshakes[sender] = handshake
+		}
+	}
+	return transcript
+}
@@ -0,0 +1,53 @@
+package zgrab2
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestHandshakeTranscript(t *testing.T) {
+	transcript := func(config *tls.Config) []string {
+		server := httptest.NewUnstartedServer(nil)
+		server.TLS = config
+		server.StartTLS()
+		defer server.Close()
+		addr := server.Listener.Addr().(*net.TCPAddr)
+		conn, err := net.Dial("tcp", addr.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		tlsConn, err := GetDefaultTLSWrapper(&TLSFlags{HandshakeTranscript: true})(context.Background(), &ScanTarget{IP: addr.IP, Port: uint(addr.Port)}, conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var messages []string
+		for _, message := range tlsConn.GetLog().Transcript.Messages {
+			name := message.Sender + " " + message.Type
+			if message.Decrypted {
+				name += " (decrypted)"
+			}
+			messages = append(messages, name)
+		}
+		return messages
+	}
+
+	messages := transcript(&tls.Config{})
+	if !slices.Equal(messages, []string{"client client_hello", "server server_hello", "server change_cipher_spec",
+		"server encrypted_extensions (decrypted)", "server certificate (decrypted)", "server certificate_verify (decrypted)",
+		"server finished (decrypted)", "client change_cipher_spec", "client finished (decrypted)"}) {
+		t.Errorf("TLS 1.3 transcript %v", messages)
+	}
+
+	// the Finished messages of TLS 1.2 stay encrypted
+	messages = transcript(&tls.Config{MaxVersion: tls.VersionTLS12})
+	if !slices.Equal(messages, []string{"client client_hello", "server server_hello", "server certificate",
+		"server server_key_exchange", "server server_hello_done", "client client_key_exchange", "client change_cipher_spec",
+		"client encrypted", "server change_cipher_spec", "server encrypted"}) {
+		t.Errorf("TLS 1.2 transcript %v", messages)
+	}
+}
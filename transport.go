@@ -0,0 +1,115 @@
+package zgrab2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Transport names accepted by --transports
+const (
+	TransportNameTCP = "tcp"
+	TransportNameTLS = "tls"
+)
+
+// parseTransports splits a comma-separated, ordered list of transport names, as given to --transports. Since a TCP
+// connection has no handshake that can fail, nothing can follow tcp in the list.
+func parseTransports(s string) ([]string, error) {
+	var transports []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if slices.Contains(transports, TransportNameTCP) {
+			return nil, fmt.Errorf("transport %q can never be tried after %s, which always connects", name, TransportNameTCP)
+		}
+		switch name {
+		case TransportNameTCP, TransportNameTLS:
+			transports = append(transports, name)
+		default:
+			return nil, fmt.Errorf("unknown transport %q, must be one of %s, %s", name, TransportNameTCP, TransportNameTLS)
+		}
+	}
+	return transports, nil
+}
+
+type transportRecorderKey struct{}
+
+// transportRecorder holds the name of the transport a fallback dialer connected with, so the framework can report it
+type transportRecorder struct {
+	transport string
+}
+
+// withTransportRecorder returns a context in which a fallback dialer can record the transport that succeeded.
+func withTransportRecorder(ctx context.Context) (context.Context, *transportRecorder) {
+	recorder := new(transportRecorder)
+	return context.WithValue(ctx, transportRecorderKey{}, recorder), recorder
+}
+
+func recordTransport(ctx context.Context, transport string) {
+	if recorder, ok := ctx.Value(transportRecorderKey{}).(*transportRecorder); ok {
+		recorder.transport = transport
+	}
+}
+
+// getFallbackDialer returns a TransportAgnosticDialer that connects with each of the transports in order until one
+// completes its handshake. If there's more than one to try, the transport that succeeded is recorded in the scan
+// response.
+// If every transport fails, the errors from all attempts are returned. If the last attempt was a TLS handshake that
+// produced a handshake log, the connection is returned along with the error, like the default TLS dialer does.
+func getFallbackDialer(baseFlags *BaseFlags, tlsFlags *TLSFlags, transports []string) func(ctx context.Context, target *ScanTarget) (net.Conn, error) {
+	if tlsFlags == nil {
+		// modules without TLS flags still get a TLS handshake with the default configuration
+		tlsFlags = new(TLSFlags)
+	}
+	return func(ctx context.Context, target *ScanTarget) (net.Conn, error) {
+		address := net.JoinHostPort(target.Host(), strconv.Itoa(int(target.Port)))
+		record := func(transport string) {
+			// with a single transport there's no fallback, so it's not worth recording
+			if len(transports) > 1 {
+				recordTransport(ctx, transport)
+			}
+		}
+		var errs []error
+		for i, transport := range transports {
+			var conn net.Conn
+			var err error
+			switch transport {
+			case TransportNameTCP:
+				conn, err = GetDefaultTCPDialer(baseFlags)(ctx, target, address)
+			case TransportNameTLS:
+				conn, err = dialTLS(ctx, baseFlags, tlsFlags, target, address)
+			}
+			if err == nil {
+				record(transport)
+				return conn, nil
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", transport, err))
+			if i == len(transports)-1 || ctx.Err() != nil {
+				record(transport)
+				return conn, errors.Join(errs...)
+			}
+			if conn != nil {
+				conn.Close()
+			}
+		}
+		return nil, errors.New("no transports to try")
+	}
+}
+
+// dialTLS is GetDefaultTLSDialer, except that the returned net.Conn is nil (rather than a nil *TLSConnection) when
+// there's no connection, and the TCP connection is closed if the handshake fails without a handshake log.
+func dialTLS(ctx context.Context, baseFlags *BaseFlags, tlsFlags *TLSFlags, target *ScanTarget, address string) (net.Conn, error) {
+	l4Conn, err := GetDefaultTCPDialer(baseFlags)(ctx, target, address)
+	if err != nil {
+		return nil, fmt.Errorf("could not initiate a L4 connection with L4 dialer: %w", err)
+	}
+	tlsConn, err := GetDefaultTLSWrapper(tlsFlags)(ctx, target, l4Conn)
+	if tlsConn == nil {
+		l4Conn.Close()
+		return nil, err
+	}
+	return tlsConn, err
+}
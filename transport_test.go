@@ -0,0 +1,80 @@
+package zgrab2
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParseTransports(t *testing.T) {
+	transports, err := parseTransports("tls, TCP")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(transports) != 2 || transports[0] != TransportNameTLS || transports[1] != TransportNameTCP {
+		t.Errorf("wrong transports (got %v)", transports)
+	}
+	if _, err = parseTransports("tcp,quic"); err == nil {
+		t.Errorf("expected an error for an unknown transport")
+	}
+	if _, err = parseTransports("tcp,tls"); err == nil {
+		t.Errorf("expected an error for a transport after tcp")
+	}
+}
+
+func TestFallbackDialer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			// A plaintext server which answers any TLS ClientHello with garbage
+			go func(conn net.Conn) {
+				defer conn.Close()
+				buf := make([]byte, 1024)
+				if _, err := conn.Read(buf); err != nil {
+					return
+				}
+				_, _ = conn.Write([]byte("220 not tls\r\n"))
+			}(conn)
+		}
+	}()
+	addr := listener.Addr().(*net.TCPAddr)
+	target := &ScanTarget{IP: addr.IP, Port: uint(addr.Port)}
+	flags := &BaseFlags{ConnectTimeout: time.Second, TargetTimeout: 5 * time.Second}
+
+	ctx, recorder := withTransportRecorder(context.Background())
+	conn, err := getFallbackDialer(flags, nil, []string{TransportNameTLS, TransportNameTCP})(ctx, target)
+	if err != nil {
+		t.Fatalf("fallback dialer failed: %v", err)
+	}
+	conn.Close()
+	if recorder.transport != TransportNameTCP {
+		t.Errorf("wrong transport recorded (got %q; expected %q)", recorder.transport, TransportNameTCP)
+	}
+
+	ctx, recorder = withTransportRecorder(context.Background())
+	conn, err = getFallbackDialer(flags, nil, []string{TransportNameTCP, TransportNameTLS})(ctx, target)
+	if err != nil {
+		t.Fatalf("fallback dialer failed: %v", err)
+	}
+	conn.Close()
+	if recorder.transport != TransportNameTCP {
+		t.Errorf("wrong transport recorded (got %q; expected %q)", recorder.transport, TransportNameTCP)
+	}
+
+	ctx, recorder = withTransportRecorder(context.Background())
+	if _, err = getFallbackDialer(flags, nil, []string{TransportNameTLS})(ctx, target); err == nil {
+		t.Errorf("expected TLS-only dialer to fail against a plaintext server")
+	}
+	if recorder.transport != "" {
+		t.Errorf("transport recorded without a fallback (got %q)", recorder.transport)
+	}
+}
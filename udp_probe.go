@@ -0,0 +1,219 @@
+package zgrab2
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+	"text/template"
+	"time"
+)
+
+// UDPFlags are the retransmission options shared by modules built on SendUDPProbe. UDP modules embed them alongside
+// BaseFlags.
+type UDPFlags struct {
+	Retries    int           `long:"udp-retries" default:"2" description:"Number of times to retransmit a probe that gets no response"`
+	TryTimeout time.Duration `long:"udp-try-timeout" default:"2s" description:"How long to wait for a response to each transmission of a probe"`
+}
+
+// Validate checks the UDP retransmission options.
+func (flags *UDPFlags) Validate() error {
+	if flags.Retries < 0 {
+		return fmt.Errorf("udp-retries must be non-negative, given %d", flags.Retries)
+	}
+	if flags.TryTimeout <= 0 {
+		return fmt.Errorf("udp-try-timeout must be positive, given %s", flags.TryTimeout)
+	}
+	return nil
+}
+
+// ICMP unreachable classifications reported in UDPProbeResult.ICMP. On a connected UDP socket the kernel reports
+// ICMP errors for the peer as errors on the next read or write.
+const (
+	ICMPPortUnreachable = "port-unreachable"
+	ICMPHostUnreachable = "host-unreachable"
+	ICMPNetUnreachable  = "net-unreachable"
+	ICMPProhibited      = "prohibited"
+)
+
+// classifyICMPError returns the ICMP unreachable classification of a UDP socket error, or "" if err isn't one.
+func classifyICMPError(err error) string {
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ICMPPortUnreachable
+	case errors.Is(err, syscall.EHOSTUNREACH):
+		return ICMPHostUnreachable
+	case errors.Is(err, syscall.ENETUNREACH):
+		return ICMPNetUnreachable
+	case errors.Is(err, syscall.EACCES), errors.Is(err, syscall.EPERM):
+		return ICMPProhibited
+	}
+	return ""
+}
+
+// UDPProbe is a single UDP request and the means to recognize its response.
+type UDPProbe struct {
+	// Name identifies the probe in results.
+	Name string
+	// Payload returns the datagram to send to the target. It is called once per probe, so every retransmission sends
+	// the same bytes (and, e.g., the same transaction ID).
+	Payload func(t *ScanTarget) ([]byte, error)
+	// Match reports whether a received datagram is a response to payload. Datagrams that don't match are ignored and
+	// the probe keeps waiting. A nil Match accepts any datagram.
+	Match func(payload, response []byte) bool
+}
+
+// NewStaticUDPProbe returns a UDPProbe that always sends payload.
+func NewStaticUDPProbe(name string, payload []byte, match func(payload, response []byte) bool) *UDPProbe {
+	return &UDPProbe{
+		Name:    name,
+		Payload: func(*ScanTarget) ([]byte, error) { return payload, nil },
+		Match:   match,
+	}
+}
+
+// udpTemplateFuncs are available in UDP payload templates:
+//
+//	hex "0001ff"  the bytes with the given hex encoding
+//	rand 4        4 random bytes
+//	u16 1234      the big-endian 16-bit encoding of the number
+//	u32 1234      the big-endian 32-bit encoding of the number
+var udpTemplateFuncs = template.FuncMap{
+	"hex": func(s string) (string, error) {
+		b, err := hex.DecodeString(s)
+		return string(b), err
+	},
+	"rand": func(n int) (string, error) {
+		b := make([]byte, n)
+		_, err := rand.Read(b)
+		return string(b), err
+	},
+	"u16": func(n int) string {
+		return string([]byte{byte(n >> 8), byte(n)})
+	},
+	"u32": func(n int) string {
+		return string([]byte{byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)})
+	},
+}
+
+// udpTemplateData is the data a UDP payload template is executed with.
+type udpTemplateData struct {
+	Host   string // target IP, or domain if there is no IP
+	IP     string
+	Domain string
+	Port   uint
+}
+
+// NewTemplateUDPProbe returns a UDPProbe whose payload is rendered for each target from a text/template. The
+// template has the target's .Host, .IP, .Domain and .Port, and the functions hex, rand, u16 and u32 for binary data
+// (see udpTemplateFuncs), e.g. `{{rand 2}}{{hex "01000001"}}` or `OPTIONS sip:{{.Host}}:{{.Port}} SIP/2.0`.
+func NewTemplateUDPProbe(name string, payloadTemplate string, match func(payload, response []byte) bool) (*UDPProbe, error) {
+	tmpl, err := template.New(name).Funcs(udpTemplateFuncs).Parse(payloadTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse payload template for probe %s: %w", name, err)
+	}
+	return &UDPProbe{
+		Name: name,
+		Payload: func(t *ScanTarget) ([]byte, error) {
+			data := udpTemplateData{Domain: t.Domain, Port: t.Port}
+			if t.IP != nil {
+				data.IP = t.IP.String()
+			}
+			data.Host = t.Host()
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, &data); err != nil {
+				return nil, fmt.Errorf("could not render payload template for probe %s: %w", name, err)
+			}
+			return buf.Bytes(), nil
+		},
+		Match: match,
+	}, nil
+}
+
+// UDPProbeResult describes the outcome of sending a single UDPProbe.
+type UDPProbeResult struct {
+	Probe    string `json:"probe"`
+	Attempts int    `json:"attempts"`
+	Response []byte `json:"response,omitempty"`
+	// RTT is the round-trip time, in milliseconds, between the last transmission and the response
+	RTT int64 `json:"rtt_ms,omitempty"`
+	// ICMP is the ICMP unreachable classification, if the target responded with one
+	ICMP string `json:"icmp,omitempty"`
+	// Ignored is the number of datagrams received that didn't match the probe
+	Ignored int `json:"ignored,omitempty"`
+}
+
+// maxUDPDatagramSize is the largest datagram SendUDPProbe will read.
+const maxUDPDatagramSize = 65535
+
+// SendUDPProbe sends probe to the target on the connected UDP socket conn and waits for a matching response. If
+// none arrives within flags.TryTimeout the probe is retransmitted, up to flags.Retries times. The result is returned
+// even on failure; the error is a *ScanError whose status is SCAN_CONNECTION_REFUSED for an ICMP port unreachable,
+// SCAN_CONNECTION_TIMEOUT for other ICMP unreachables, and SCAN_IO_TIMEOUT if no response was received.
+func SendUDPProbe(ctx context.Context, conn net.Conn, probe *UDPProbe, t *ScanTarget, flags *UDPFlags) (*UDPProbeResult, error) {
+	result := &UDPProbeResult{Probe: probe.Name}
+	payload, err := probe.Payload(t)
+	if err != nil {
+		return result, NewScanError(SCAN_INVALID_INPUTS, err)
+	}
+	buf := make([]byte, maxUDPDatagramSize)
+	for result.Attempts <= flags.Retries {
+		result.Attempts++
+		sent := time.Now()
+		if _, err = conn.Write(payload); err != nil {
+			return result, udpProbeError(result, probe, err)
+		}
+		tryDeadline := sent.Add(flags.TryTimeout)
+		if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(tryDeadline) {
+			tryDeadline = ctxDeadline
+		}
+		for time.Now().Before(tryDeadline) {
+			if err = conn.SetReadDeadline(tryDeadline); err != nil {
+				return result, udpProbeError(result, probe, err)
+			}
+			n, err := conn.Read(buf)
+			if err != nil {
+				if IsTimeoutError(err) {
+					break
+				}
+				return result, udpProbeError(result, probe, err)
+			}
+			if probe.Match != nil && !probe.Match(payload, buf[:n]) {
+				result.Ignored++
+				continue
+			}
+			result.Response = append([]byte(nil), buf[:n]...)
+			result.RTT = time.Since(sent).Milliseconds()
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return result, NewScanError(SCAN_IO_TIMEOUT, fmt.Errorf("no response to probe %s after %s", probe.Name, pluralAttempts(result.Attempts)))
+}
+
+// udpProbeError classifies a socket error from SendUDPProbe, recording any ICMP unreachable in result.
+func udpProbeError(result *UDPProbeResult, probe *UDPProbe, err error) error {
+	result.ICMP = classifyICMPError(err)
+	switch result.ICMP {
+	case "":
+		return DetectScanError(fmt.Errorf("probe %s failed: %w", probe.Name, err))
+	case ICMPPortUnreachable:
+		return NewScanError(SCAN_CONNECTION_REFUSED, fmt.Errorf("probe %s: ICMP %s: %w", probe.Name, result.ICMP, err))
+	default:
+		return NewScanError(SCAN_CONNECTION_TIMEOUT, fmt.Errorf("probe %s: ICMP %s: %w", probe.Name, result.ICMP, err))
+	}
+}
+
+func pluralAttempts(n int) string {
+	if n == 1 {
+		return "1 attempt"
+	}
+	return strconv.Itoa(n) + " attempts"
+}
@@ -0,0 +1,115 @@
+package zgrab2
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestNewTemplateUDPProbe(t *testing.T) {
+	probe, err := NewTemplateUDPProbe("test", `{{hex "beef"}}{{u16 258}}{{u32 1}}{{rand 3}}{{.Host}}:{{.Port}}`, nil)
+	if err != nil {
+		t.Fatalf("could not parse template: %v", err)
+	}
+	payload, err := probe.Payload(&ScanTarget{IP: net.ParseIP("192.0.2.1"), Port: 5060})
+	if err != nil {
+		t.Fatalf("could not render template: %v", err)
+	}
+	if !bytes.HasPrefix(payload, []byte{0xbe, 0xef, 0x01, 0x02, 0, 0, 0, 1}) || !bytes.HasSuffix(payload, []byte("192.0.2.1:5060")) || len(payload) != 8+3+14 {
+		t.Errorf("wrong payload %q", payload)
+	}
+	if _, err = NewTemplateUDPProbe("bad", `{{hex`, nil); err == nil {
+		t.Errorf("expected an error for an invalid template")
+	}
+}
+
+func TestSendUDPProbe(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+	defer server.Close()
+	go func() {
+		buf := make([]byte, 1500)
+		for received := 0; ; received++ {
+			n, addr, err := server.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if received == 0 {
+				// drop the first transmission, forcing a retransmission
+				continue
+			}
+			_, _ = server.WriteTo([]byte("unrelated"), addr)
+			_, _ = server.WriteTo(append([]byte("re:"), buf[:n]...), addr)
+		}
+	}()
+	conn, err := net.Dial("udp", server.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	defer conn.Close()
+
+	match := func(payload, response []byte) bool {
+		return bytes.Equal(response, append([]byte("re:"), payload...))
+	}
+	probe := NewStaticUDPProbe("echo", []byte("ping"), match)
+	flags := &UDPFlags{Retries: 2, TryTimeout: 200 * time.Millisecond}
+	result, err := SendUDPProbe(context.Background(), conn, probe, &ScanTarget{}, flags)
+	if err != nil {
+		t.Fatalf("probe failed: %v", err)
+	}
+	if result.Attempts != 2 || result.Ignored != 1 || string(result.Response) != "re:ping" {
+		t.Errorf("wrong result %+v", result)
+	}
+}
+
+func TestSendUDPProbeNoResponse(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+	defer server.Close()
+	conn, err := net.Dial("udp", server.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	defer conn.Close()
+
+	probe := NewStaticUDPProbe("silent", []byte("ping"), nil)
+	flags := &UDPFlags{Retries: 1, TryTimeout: 50 * time.Millisecond}
+	result, err := SendUDPProbe(context.Background(), conn, probe, &ScanTarget{}, flags)
+	if TryGetScanStatus(err) != SCAN_IO_TIMEOUT {
+		t.Errorf("wrong status for no response (got %s: %v)", TryGetScanStatus(err), err)
+	}
+	if result.Attempts != 2 {
+		t.Errorf("wrong number of attempts (got %d; expected 2)", result.Attempts)
+	}
+}
+
+func TestSendUDPProbePortUnreachable(t *testing.T) {
+	// find a port nothing is listening on
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+	addr := server.LocalAddr().String()
+	server.Close()
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	defer conn.Close()
+
+	probe := NewStaticUDPProbe("closed", []byte("ping"), nil)
+	flags := &UDPFlags{Retries: 1, TryTimeout: 200 * time.Millisecond}
+	result, err := SendUDPProbe(context.Background(), conn, probe, &ScanTarget{}, flags)
+	if result.ICMP != ICMPPortUnreachable {
+		t.Skipf("no ICMP port unreachable reported on this platform (err=%v)", err)
+	}
+	if TryGetScanStatus(err) != SCAN_CONNECTION_REFUSED {
+		t.Errorf("wrong status for port unreachable (got %s)", TryGetScanStatus(err))
+	}
+}
@@ -9,6 +9,7 @@ from . import memcached
 from . import modbus
 from . import mongodb
 from . import mssql
+from . import mssql_browser
 from . import mysql
 from . import mysql_errors
 from . import ntp
@@ -27,3 +28,63 @@ from . import amqp091
 from . import socks5
 from . import mqtt
 from . import pptp
+from . import rdp
+from . import vnc
+from . import snmp
+from . import ldap
+from . import kerberos
+from . import elasticsearch
+from . import docker
+from . import kubernetes
+from . import etcd
+from . import coap
+from . import tftp
+from . import sip
+from . import rtsp
+from . import nats
+from . import zookeeper
+from . import zookeeper
+from . import cassandra
+from . import couchdb
+from . import influxdb
+from . import rsync
+from . import gitdaemon
+from . import xmpp
+from . import irc
+from . import whois
+from . import stun
+from . import openvpn
+from . import wireguard
+from . import ike
+from . import l2tp
+from . import ssdp
+from . import mdns
+from . import nbns
+from . import winrm
+from . import adb
+from . import jdwp
+from . import ajp
+from . import epmd
+from . import rmi
+from . import t3
+from . import x11
+from . import minecraft
+from . import bgp
+from . import bitcoin
+from . import devp2p
+from . import beanstalkd
+from . import tor
+from . import dns
+from . import quic
+from . import encdns
+from . import iscsi
+from . import portmap
+from . import opcua
+from . import enip
+from . import iec104
+from . import fins
+from . import codesys3
+from . import dicom
+from . import kafka
+from . import bolt
+from . import http3
@@ -0,0 +1,41 @@
+# zschema sub-schema for zgrab2's ADB module
+# Registers zgrab2-adb globally, and adb with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+adb_banner = SubRecord(
+    {
+        "system_type": String(),
+        "serial": String(),
+        "product": String(),
+        "model": String(),
+        "device": String(),
+        "features": ListOf(String()),
+    }
+)
+
+# Schema for ScanResults struct
+adb_scan_response = SubRecord(
+    {
+        "command": Enum(values=["CNXN", "AUTH", "STLS"]),
+        "version": Unsigned32BitInteger(),
+        "max_payload": Unsigned32BitInteger(),
+        "unauthenticated": Boolean(doc="True if the device accepted the connection without authentication"),
+        "auth_required": Boolean(),
+        "tls_required": Boolean(),
+        "banner": adb_banner,
+    }
+)
+
+adb_scan = SubRecord(
+    {
+        "result": adb_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-adb", adb_scan)
+zgrab2.register_scan_response_type("adb", adb_scan)
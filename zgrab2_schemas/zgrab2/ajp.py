@@ -0,0 +1,43 @@
+# zschema sub-schema for zgrab2's AJP module
+# Registers zgrab2-ajp globally, and ajp with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+ajp_header = SubRecord(
+    {
+        "name": String(),
+        "value": String(),
+    }
+)
+
+ajp_forward_response = SubRecord(
+    {
+        "status_code": Unsigned16BitInteger(),
+        "status_message": String(),
+        "headers": ListOf(ajp_header),
+        "body": String(),
+        "body_truncated": Boolean(),
+    }
+)
+
+# Schema for ScanResults struct
+ajp_scan_response = SubRecord(
+    {
+        "cpong": Boolean(doc="True if the container answered the CPing"),
+        "forward": ajp_forward_response,
+        "tomcat_version": String(),
+    }
+)
+
+ajp_scan = SubRecord(
+    {
+        "result": ajp_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-ajp", ajp_scan)
+zgrab2.register_scan_response_type("ajp", ajp_scan)
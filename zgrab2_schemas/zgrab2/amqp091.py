@@ -23,6 +23,9 @@ known_server_properties = SubRecord(
         "platform": String(),
         "copyright": String(),
         "information": String(),
+        "cluster_name": String(),
+        # map of capability name to bool
+        "capabilities": SubRecord({}),  # TODO FIXME: unconstrained dict
         "unknown_props": String(),
     }
 )
@@ -37,6 +40,14 @@ result_schema = SubRecord(
                 "version_minor": Unsigned32BitInteger(),
                 "server_properties": known_server_properties,
                 "locales": ListOf(String()),
+                "mechanisms": ListOf(
+                    String(),
+                    doc="The SASL mechanisms offered in connection.start.",
+                    examples=["PLAIN", "AMQPLAIN", "EXTERNAL"],
+                ),
+                "implicit_tls": Boolean(
+                    doc="True if TLS was used because of the AMQPS port (5671), without --use-tls."
+                ),
                 "auth_success": Boolean(),
                 "tune": connection_tune,
                 "tls": zgrab2.tls_log,
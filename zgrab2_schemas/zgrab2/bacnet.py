@@ -7,6 +7,33 @@ import zschema.registry
 import zcrypto_schemas
 from . import zgrab2
 
+object_identifier = SubRecord(
+    {
+        "type": Unsigned16BitInteger(),
+        "type_name": String(examples=["device", "network-port"]),
+        "instance": Unsigned32BitInteger(),
+    }
+)
+
+property_result = SubRecord(
+    {
+        "object": object_identifier,
+        "property": Unsigned32BitInteger(),
+        "property_name": String(examples=["object-list", "firmware-revision"]),
+        "array_index": Unsigned32BitInteger(),
+        "values": ListOf(String()),
+        "raw": Binary(),
+        "error": SubRecord(
+            {
+                "class": Unsigned32BitInteger(),
+                "class_name": String(),
+                "code": Unsigned32BitInteger(),
+                "code_name": String(examples=["unknown-property"]),
+            }
+        ),
+    }
+)
+
 bacnet_scan_response = SubRecord(
     {
         "result": SubRecord(
@@ -21,6 +48,17 @@ bacnet_scan_response = SubRecord(
                 "model_name": String(),
                 "description": String(),
                 "location": String(),
+                "properties": ListOf(
+                    property_result,
+                    doc="The results of the ReadPropertyMultiple requests, each either the values of a property or its error.",
+                ),
+                "object_list": ListOf(object_identifier),
+                "object_count": Unsigned32BitInteger(),
+                "network_numbers": ListOf(Unsigned16BitInteger()),
+                "source_network": Unsigned16BitInteger(
+                    doc="The network number of the device, if its responses were routed from a remote network."
+                ),
+                "read_property_multiple_error": String(),
             }
         )
     },
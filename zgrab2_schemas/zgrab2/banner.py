@@ -15,6 +15,20 @@ banner_scan_response = SubRecord(
                 "banner": String(),
                 "length": Unsigned32BitInteger(),
                 "tls": zgrab2.tls_log,
+                "label": String(
+                    doc="Label of the first probe from --probes-file whose response matched its pattern."
+                ),
+                "probes": ListOf(
+                    SubRecord(
+                        {
+                            "label": String(),
+                            "length": Unsigned32BitInteger(),
+                            "matched": Boolean(),
+                            "error": String(),
+                        }
+                    ),
+                    doc="Each probe from --probes-file sent, in order.",
+                ),
             }
         )
     },
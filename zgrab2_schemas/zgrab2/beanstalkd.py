@@ -0,0 +1,36 @@
+# zschema sub-schema for zgrab2's beanstalkd module
+# Registers zgrab2-beanstalkd globally, and beanstalkd with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+# Schema for ScanResults struct
+beanstalkd_scan_response = SubRecord(
+    {
+        "version": String(),
+        "uptime": Signed64BitInteger(),
+        "pid": Signed64BitInteger(),
+        "hostname": String(),
+        "os": String(),
+        "platform": String(),
+        "id": String(),
+        "draining": Boolean(),
+        "current_connections": Signed64BitInteger(),
+        "current_jobs_ready": Signed64BitInteger(),
+        "current_jobs_buried": Signed64BitInteger(),
+        "total_jobs": Signed64BitInteger(),
+        "tubes": ListOf(String()),
+    }
+)
+
+beanstalkd_scan = SubRecord(
+    {
+        "result": beanstalkd_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-beanstalkd", beanstalkd_scan)
+zgrab2.register_scan_response_type("beanstalkd", beanstalkd_scan)
@@ -0,0 +1,58 @@
+# zschema sub-schema for zgrab2's BGP module
+# Registers zgrab2-bgp globally, and bgp with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+bgp_capability = SubRecord(
+    {
+        "code": Unsigned8BitInteger(),
+        "name": String(),
+        "value": String(doc="Hex-encoded capability value"),
+    }
+)
+
+bgp_open = SubRecord(
+    {
+        "version": Unsigned8BitInteger(),
+        "my_as": Unsigned16BitInteger(),
+        "hold_time": Unsigned16BitInteger(),
+        "router_id": String(),
+        "asn": Unsigned32BitInteger(),
+        "capabilities": ListOf(bgp_capability),
+        "address_families": ListOf(String()),
+        "hostname": String(),
+        "domain_name": String(),
+    }
+)
+
+bgp_notification = SubRecord(
+    {
+        "code": Unsigned8BitInteger(),
+        "code_name": String(),
+        "subcode": Unsigned8BitInteger(),
+        "subcode_name": String(),
+        "data": String(doc="Hex-encoded notification data"),
+        "message": String(),
+    }
+)
+
+# Schema for ScanResults struct
+bgp_scan_response = SubRecord(
+    {
+        "open": bgp_open,
+        "notification": bgp_notification,
+    }
+)
+
+bgp_scan = SubRecord(
+    {
+        "result": bgp_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-bgp", bgp_scan)
+zgrab2.register_scan_response_type("bgp", bgp_scan)
@@ -0,0 +1,31 @@
+# zschema sub-schema for zgrab2's Bitcoin module
+# Registers zgrab2-bitcoin globally, and bitcoin with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+# Schema for ScanResults struct
+bitcoin_scan_response = SubRecord(
+    {
+        "protocol_version": Signed32BitInteger(),
+        "services": Signed64BitInteger(doc="Bitmap of the services the node offers"),
+        "service_names": ListOf(String()),
+        "user_agent": String(),
+        "start_height": Signed32BitInteger(),
+        "relay": Boolean(),
+        "timestamp": DateTime(),
+        "addr_recv": String(),
+    }
+)
+
+bitcoin_scan = SubRecord(
+    {
+        "result": bitcoin_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-bitcoin", bitcoin_scan)
+zgrab2.register_scan_response_type("bitcoin", bitcoin_scan)
@@ -0,0 +1,35 @@
+# zschema sub-schema for zgrab2's bolt module
+# Registers zgrab2-bolt globally, and bolt with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+bolt_scan_response = SubRecord(
+    {
+        "version": String(),
+        "manifest_versions": ListOf(String()),
+        "manifest_capabilities": Signed64BitInteger(),
+        "server": String(),
+        "connection_id": String(),
+        "auth_required": Boolean(),
+        "failure": SubRecord(
+            {
+                "code": String(),
+                "message": String(),
+            }
+        ),
+        "tls": zgrab2.tls_log,
+    }
+)
+
+bolt_scan = SubRecord(
+    {
+        "result": bolt_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-bolt", bolt_scan)
+zgrab2.register_scan_response_type("bolt", bolt_scan)
@@ -0,0 +1,47 @@
+# zschema sub-schema for zgrab2's Cassandra module
+# Registers zgrab2-cassandra globally, and cassandra with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+cassandra_option = SubRecord(
+    {
+        "name": String(),
+        "values": ListOf(String()),
+    }
+)
+
+cassandra_error = SubRecord(
+    {
+        "code": Signed32BitInteger(),
+        "name": String(),
+        "message": String(),
+    }
+)
+
+# Schema for ScanResults struct
+cassandra_scan_response = SubRecord(
+    {
+        "protocol_version": Unsigned8BitInteger(),
+        "protocol_versions": ListOf(String()),
+        "cql_versions": ListOf(String()),
+        "compression": ListOf(String()),
+        "supported": ListOf(cassandra_option),
+        "auth_required": Boolean(),
+        "authenticator": String(),
+        "error": cassandra_error,
+        "tls": zgrab2.tls_log,
+    }
+)
+
+cassandra_scan = SubRecord(
+    {
+        "result": cassandra_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-cassandra", cassandra_scan)
+zgrab2.register_scan_response_type("cassandra", cassandra_scan)
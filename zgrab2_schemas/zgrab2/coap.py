@@ -0,0 +1,52 @@
+# zschema sub-schema for zgrab2's CoAP module
+# Registers zgrab2-coap globally, and coap with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+coap_resource = SubRecord(
+    {
+        "path": String(),
+        "rt": String(doc="Resource type"),
+        "if": String(doc="Interface description"),
+        "ct": ListOf(Unsigned16BitInteger(), doc="Content-Format numbers"),
+        "title": String(),
+        "obs": Boolean(doc="Resource is observable"),
+        "attributes": ListOf(String(), doc="Other link attributes, verbatim"),
+    }
+)
+
+coap_dtls = SubRecord(
+    {
+        "version": String(),
+        "response": Enum(values=["hello_verify_request", "server_hello", "alert"]),
+    }
+)
+
+# Schema for ScanResults struct
+coap_scan_response = SubRecord(
+    {
+        "response_code": String(doc="Response code as class.detail, e.g. 2.05"),
+        "reset": Boolean(),
+        "content_format": String(),
+        "more_blocks": Boolean(),
+        "payload": String(),
+        "resources": ListOf(coap_resource),
+        "content_formats": ListOf(String()),
+        "dtls": coap_dtls,
+        "dtls_required": Boolean(),
+        "probes": ListOf(zgrab2.udp_probe_result),
+    }
+)
+
+coap_scan = SubRecord(
+    {
+        "result": coap_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-coap", coap_scan)
+zgrab2.register_scan_response_type("coap", coap_scan)
@@ -0,0 +1,37 @@
+# zschema sub-schema for zgrab2's codesys3 module
+# Registers zgrab2-codesys3 globally, and codesys3 with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+codesys3_identification = SubRecord(
+    {
+        "version": Unsigned16BitInteger(),
+        "max_channels": Unsigned16BitInteger(),
+        "byte_order": String(),
+        "node_name": String(),
+        "device_name": String(),
+        "vendor_name": String(),
+        "target_type": Unsigned32BitInteger(),
+        "target_id": Unsigned32BitInteger(),
+        "target_version": String(),
+    }
+)
+
+codesys3_scan_response = SubRecord(
+    {
+        "identification": codesys3_identification,
+    }
+)
+
+codesys3_scan = SubRecord(
+    {
+        "result": codesys3_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-codesys3", codesys3_scan)
+zgrab2.register_scan_response_type("codesys3", codesys3_scan)
@@ -0,0 +1,36 @@
+# zschema sub-schema for zgrab2's CouchDB module
+# Registers zgrab2-couchdb globally, and couchdb with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+# Schema for ScanResults struct
+couchdb_scan_response = SubRecord(
+    {
+        "version": String(),
+        "git_sha": String(),
+        "uuid": String(),
+        "features": ListOf(String()),
+        "vendor": String(),
+        "vendor_version": String(),
+        "status": String(doc="Node status from /_up"),
+        "anonymous_roles": ListOf(String()),
+        "admin_party": Boolean(doc="Anonymous requests have the _admin role"),
+        "root_response": zgrab2.http_api_response,
+        "up_response": zgrab2.http_api_response,
+        "session_response": zgrab2.http_api_response,
+        "tls": zgrab2.tls_log,
+    }
+)
+
+couchdb_scan = SubRecord(
+    {
+        "result": couchdb_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-couchdb", couchdb_scan)
+zgrab2.register_scan_response_type("couchdb", couchdb_scan)
@@ -0,0 +1,57 @@
+# zschema sub-schema for zgrab2's devp2p module
+# Registers zgrab2-devp2p globally, and devp2p with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+devp2p_pong = SubRecord(
+    {
+        "recipient_endpoint": String(),
+        "enr_seq": Signed64BitInteger(),
+    }
+)
+
+devp2p_enr = SubRecord(
+    {
+        "record": String(),
+        "seq": Signed64BitInteger(),
+        "id": String(),
+        "public_key": String(),
+        "node_id": String(),
+        "signature_valid": Boolean(),
+        "ip": String(),
+        "tcp": Unsigned16BitInteger(),
+        "udp": Unsigned16BitInteger(),
+        "ip6": String(),
+        "tcp6": Unsigned16BitInteger(),
+        "udp6": Unsigned16BitInteger(),
+        "fork_hash": String(),
+        "fork_next": Signed64BitInteger(),
+        "eth2_fork_digest": String(),
+        "client_name": String(),
+        "client_version": String(),
+        "client_build": String(),
+        "keys": ListOf(String()),
+    }
+)
+
+# Schema for ScanResults struct
+devp2p_scan_response = SubRecord(
+    {
+        "pong": devp2p_pong,
+        "enr": devp2p_enr,
+        "probes": ListOf(zgrab2.udp_probe_result),
+    }
+)
+
+devp2p_scan = SubRecord(
+    {
+        "result": devp2p_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-devp2p", devp2p_scan)
+zgrab2.register_scan_response_type("devp2p", devp2p_scan)
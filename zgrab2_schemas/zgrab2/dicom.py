@@ -0,0 +1,55 @@
+# zschema sub-schema for zgrab2's dicom module
+# Registers zgrab2-dicom globally, and dicom with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+dicom_presentation_context = SubRecord(
+    {
+        "id": Unsigned8BitInteger(),
+        "abstract_syntax": String(),
+        "name": String(),
+        "result": String(),
+        "accepted": Boolean(),
+        "transfer_syntax": String(),
+    }
+)
+
+dicom_association = SubRecord(
+    {
+        "max_pdu_length": Unsigned32BitInteger(),
+        "implementation_class_uid": String(),
+        "implementation_version_name": String(),
+        "presentation_contexts": ListOf(dicom_presentation_context),
+    }
+)
+
+dicom_rejection = SubRecord(
+    {
+        "result": String(),
+        "source": String(),
+        "reason": String(),
+    }
+)
+
+dicom_scan_response = SubRecord(
+    {
+        "response": String(),
+        "association": dicom_association,
+        "rejection": dicom_rejection,
+        "echo_status": Unsigned16BitInteger(),
+        "released": Boolean(),
+    }
+)
+
+dicom_scan = SubRecord(
+    {
+        "result": dicom_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-dicom", dicom_scan)
+zgrab2.register_scan_response_type("dicom", dicom_scan)
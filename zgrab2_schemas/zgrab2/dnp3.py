@@ -7,12 +7,57 @@ import zschema.registry
 import zcrypto_schemas.zcrypto as zcrypto
 from . import zgrab2
 
+application_fragment = SubRecord(
+    {
+        "source": Unsigned16BitInteger(),
+        "destination": Unsigned16BitInteger(),
+        "function_code": Unsigned8BitInteger(),
+        "function": String(),
+        "iin": Unsigned16BitInteger(),
+        "objects": Binary(),
+    }
+)
+
+device_attributes = SubRecord(
+    {
+        "source": Unsigned16BitInteger(),
+        "destination": Unsigned16BitInteger(),
+        "iin": Unsigned16BitInteger(),
+        "attributes": ListOf(
+            SubRecord(
+                {
+                    "variation": Unsigned8BitInteger(),
+                    "name": String(examples=["software_version", "product_name"]),
+                    "data_type": Unsigned8BitInteger(),
+                    "value": String(),
+                    "raw": Binary(),
+                }
+            )
+        ),
+        "error": String(),
+    },
+    doc="The device attributes (group 0) of the first outstation that responded; only present if --read-attributes is set.",
+)
+
 dnp3_scan_response = SubRecord(
     {
         "result": SubRecord(
             {
                 "is_dnp3": Boolean(),
                 "raw_response": Binary(),
+                "responders": ListOf(
+                    SubRecord(
+                        {
+                            "source": Unsigned16BitInteger(),
+                            "destination": Unsigned16BitInteger(),
+                            "function_code": Unsigned8BitInteger(),
+                            "function": String(),
+                        }
+                    ),
+                    doc="The link addresses that answered the link status requests.",
+                ),
+                "unsolicited_responses": ListOf(application_fragment),
+                "device_attributes": device_attributes,
             }
         )
     },
@@ -0,0 +1,69 @@
+# zschema sub-schema for zgrab2's DNS module
+# Registers zgrab2-dns globally, and dns with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+dns_answer = SubRecord(
+    {
+        "name": String(),
+        "type": String(),
+        "ttl": Unsigned32BitInteger(),
+        "data": String(),
+    }
+)
+
+dns_edns = SubRecord(
+    {
+        "version": Unsigned8BitInteger(),
+        "udp_size": Unsigned16BitInteger(),
+        "do": Boolean(),
+        "options": ListOf(Unsigned16BitInteger()),
+        "nsid": String(),
+    }
+)
+
+dns_response = SubRecord(
+    {
+        "name": String(),
+        "type": String(),
+        "class": String(),
+        "rcode": String(),
+        "authoritative": Boolean(),
+        "truncated": Boolean(),
+        "recursion_desired": Boolean(),
+        "recursion_available": Boolean(),
+        "authentic_data": Boolean(),
+        "checking_disabled": Boolean(),
+        "answers": ListOf(dns_answer),
+        "authority_count": Signed32BitInteger(),
+        "additional_count": Signed32BitInteger(),
+        "edns": dns_edns,
+    }
+)
+
+# Schema for ScanResults struct
+dns_scan_response = SubRecord(
+    {
+        "responses": ListOf(dns_response),
+        "recursion_available": Boolean(),
+        "open_resolver": Boolean(),
+        "edns": Boolean(),
+        "dnssec_ok": Boolean(),
+        "version": String(),
+        "hostname": String(),
+        "probes": ListOf(zgrab2.udp_probe_result),
+    }
+)
+
+dns_scan = SubRecord(
+    {
+        "result": dns_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-dns", dns_scan)
+zgrab2.register_scan_response_type("dns", dns_scan)
@@ -0,0 +1,45 @@
+# zschema sub-schema for zgrab2's Docker module
+# Registers zgrab2-docker globally, and docker with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+# Schema for ScanResults struct
+docker_scan_response = SubRecord(
+    {
+        "version": String(),
+        "api_version": String(),
+        "min_api_version": String(),
+        "git_commit": String(),
+        "go_version": String(),
+        "os": String(),
+        "arch": String(),
+        "kernel_version": String(),
+        "platform": String(),
+        "id": String(),
+        "name": String(),
+        "operating_system": String(),
+        "containers": Unsigned32BitInteger(),
+        "containers_running": Unsigned32BitInteger(),
+        "images": Unsigned32BitInteger(),
+        "swarm_node_state": String(),
+        "unauthenticated": Boolean(),
+        "tls_required": Boolean(),
+        "client_cert_required": Boolean(),
+        "version_response": zgrab2.http_api_response,
+        "info_response": zgrab2.http_api_response,
+        "tls": zgrab2.tls_log,
+    }
+)
+
+docker_scan = SubRecord(
+    {
+        "result": docker_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-docker", docker_scan)
+zgrab2.register_scan_response_type("docker", docker_scan)
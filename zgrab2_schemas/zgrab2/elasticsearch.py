@@ -0,0 +1,39 @@
+# zschema sub-schema for zgrab2's Elasticsearch module
+# Registers zgrab2-elasticsearch globally, and elasticsearch with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+# Schema for ScanResults struct
+elasticsearch_scan_response = SubRecord(
+    {
+        "distribution": String(doc="elasticsearch or opensearch"),
+        "version": String(),
+        "build_flavor": String(),
+        "build_hash": String(),
+        "lucene_version": String(),
+        "tagline": String(),
+        "node_name": String(),
+        "cluster_name": String(),
+        "cluster_uuid": String(),
+        "cluster_status": String(),
+        "number_of_nodes": Unsigned32BitInteger(),
+        "number_of_data_nodes": Unsigned32BitInteger(),
+        "unauthenticated": Boolean(),
+        "root": zgrab2.http_api_response,
+        "health": zgrab2.http_api_response,
+        "tls": zgrab2.tls_log,
+    }
+)
+
+elasticsearch_scan = SubRecord(
+    {
+        "result": elasticsearch_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-elasticsearch", elasticsearch_scan)
+zgrab2.register_scan_response_type("elasticsearch", elasticsearch_scan)
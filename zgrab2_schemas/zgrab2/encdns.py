@@ -0,0 +1,41 @@
+# zschema sub-schema for zgrab2's encdns module
+# Registers zgrab2-encdns globally, and encdns with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+encdns_response = SubRecord(
+    {
+        "rcode": String(),
+        "recursion_available": Boolean(),
+        "authentic_data": Boolean(),
+        "answers": ListOf(String()),
+        "nsid": String(),
+        "length": Unsigned16BitInteger(),
+        "padded": Boolean(),
+    }
+)
+
+# Schema for ScanResults struct
+encdns_scan_response = SubRecord(
+    {
+        "protocol": String(),
+        "response": encdns_response,
+        "version": String(),
+        "padded_to_block": Boolean(),
+        "http_response": zgrab2.http_api_response,
+        "tls": zgrab2.tls_log,
+    }
+)
+
+encdns_scan = SubRecord(
+    {
+        "result": encdns_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-encdns", encdns_scan)
+zgrab2.register_scan_response_type("encdns", encdns_scan)
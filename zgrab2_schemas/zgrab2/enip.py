@@ -0,0 +1,35 @@
+# zschema sub-schema for zgrab2's enip module
+# Registers zgrab2-enip globally, and enip with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+enip_scan_response = SubRecord(
+    {
+        "vendor_id": Unsigned16BitInteger(),
+        "vendor": String(),
+        "device_type_id": Unsigned16BitInteger(),
+        "device_type": String(),
+        "product_code": Unsigned16BitInteger(),
+        "revision": String(),
+        "serial": String(),
+        "product_name": String(),
+        "protocol_version": Unsigned16BitInteger(),
+        "status": Unsigned16BitInteger(),
+        "state": Unsigned8BitInteger(),
+        "socket_address": String(),
+        "socket_port": Unsigned16BitInteger(),
+    }
+)
+
+enip_scan = SubRecord(
+    {
+        "result": enip_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-enip", enip_scan)
+zgrab2.register_scan_response_type("enip", enip_scan)
@@ -0,0 +1,37 @@
+# zschema sub-schema for zgrab2's EPMD module
+# Registers zgrab2-epmd globally, and epmd with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+epmd_node = SubRecord(
+    {
+        "name": String(),
+        "port": Unsigned16BitInteger(),
+        "node_type": String(),
+        "protocol": Unsigned8BitInteger(),
+        "highest_version": Unsigned16BitInteger(),
+        "lowest_version": Unsigned16BitInteger(),
+        "extra": String(),
+    }
+)
+
+# Schema for ScanResults struct
+epmd_scan_response = SubRecord(
+    {
+        "epmd_port": Unsigned32BitInteger(),
+        "nodes": ListOf(epmd_node),
+    }
+)
+
+epmd_scan = SubRecord(
+    {
+        "result": epmd_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-epmd", epmd_scan)
+zgrab2.register_scan_response_type("epmd", epmd_scan)
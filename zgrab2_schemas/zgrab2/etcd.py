@@ -0,0 +1,48 @@
+# zschema sub-schema for zgrab2's etcd module
+# Registers zgrab2-etcd globally, and etcd with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+etcd_status = SubRecord(
+    {
+        "version": String(),
+        "cluster_id": String(),
+        "member_id": String(),
+        "leader": String(),
+        "is_leader": Boolean(),
+        "is_learner": Boolean(),
+        "db_size": Signed64BitInteger(),
+        "raft_term": Signed64BitInteger(),
+        "raft_index": Signed64BitInteger(),
+        "revision": Signed64BitInteger(),
+        "errors": ListOf(String()),
+    }
+)
+
+# Schema for ScanResults struct
+etcd_scan_response = SubRecord(
+    {
+        "server_version": String(),
+        "cluster_version": String(),
+        "gateway_prefix": String(),
+        "status": etcd_status,
+        "unauthenticated_read": Boolean(),
+        "key_count": Signed64BitInteger(),
+        "range_error": String(),
+        "version_response": zgrab2.http_api_response,
+        "tls": zgrab2.tls_log,
+    }
+)
+
+etcd_scan = SubRecord(
+    {
+        "result": etcd_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-etcd", etcd_scan)
+zgrab2.register_scan_response_type("etcd", etcd_scan)
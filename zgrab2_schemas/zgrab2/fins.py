@@ -0,0 +1,55 @@
+# zschema sub-schema for zgrab2's fins module
+# Registers zgrab2-fins globally, and fins with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+fins_controller = SubRecord(
+    {
+        "model": String(),
+        "version": String(),
+        "program_area_size": Unsigned16BitInteger(),
+        "iom_size": Unsigned8BitInteger(),
+        "dm_words": Unsigned16BitInteger(),
+        "timer_counter_size": Unsigned8BitInteger(),
+        "expansion_dm_size": Unsigned8BitInteger(),
+        "memory_card_type": Unsigned8BitInteger(),
+        "memory_card_size": Unsigned16BitInteger(),
+    }
+)
+
+fins_status = SubRecord(
+    {
+        "status": String(),
+        "mode": String(),
+        "fatal_error": Unsigned16BitInteger(),
+        "non_fatal_error": Unsigned16BitInteger(),
+        "fal_number": Unsigned16BitInteger(),
+        "error_message": String(),
+    }
+)
+
+fins_scan_response = SubRecord(
+    {
+        "transport": String(),
+        "client_node": Unsigned32BitInteger(),
+        "server_node": Unsigned32BitInteger(),
+        "end_code": Unsigned16BitInteger(),
+        "end_code_name": String(),
+        "controller": fins_controller,
+        "status": fins_status,
+        "probes": ListOf(zgrab2.udp_probe_result),
+    }
+)
+
+fins_scan = SubRecord(
+    {
+        "result": fins_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-fins", fins_scan)
+zgrab2.register_scan_response_type("fins", fins_scan)
@@ -16,6 +16,28 @@ ftp_scan_response = SubRecord(
                 "banner": String(),
                 "auth_tls": String(),
                 "auth_ssl": String(),
+                "implicit_tls": Boolean(),
+                "feat": String(doc="The response to the FEAT command."),
+                "features": ListOf(
+                    String(), doc="The features listed in a successful FEAT response."
+                ),
+                "syst": String(doc="The response to the SYST command."),
+                "anonymous": SubRecord(
+                    {
+                        "user": String(),
+                        "pass": String(),
+                        "logged_in": Boolean(),
+                        "pwd_resp": String(),
+                        "pwd": String(),
+                        "list_resp": String(),
+                        "list": String(
+                            doc="The start of the listing of the working directory, at most --list-max-size bytes."
+                        ),
+                        "list_truncated": Boolean(),
+                        "error": String(),
+                    },
+                    doc="The result of the anonymous login, with --anonymous-login.",
+                ),
             }
         )
     },
@@ -0,0 +1,37 @@
+# zschema sub-schema for zgrab2's git daemon module
+# Registers zgrab2-gitdaemon globally, and gitdaemon with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+gitdaemon_ref = SubRecord(
+    {
+        "name": String(),
+        "hash": String(),
+    }
+)
+
+# Schema for ScanResults struct
+gitdaemon_scan_response = SubRecord(
+    {
+        "repository": String(),
+        "capabilities": ListOf(String()),
+        "agent": String(),
+        "head": String(),
+        "refs": ListOf(gitdaemon_ref),
+        "ref_count": Unsigned32BitInteger(),
+        "error": String(),
+    }
+)
+
+gitdaemon_scan = SubRecord(
+    {
+        "result": gitdaemon_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-gitdaemon", gitdaemon_scan)
+zgrab2.register_scan_response_type("gitdaemon", gitdaemon_scan)
@@ -149,6 +149,39 @@ http_request_full = SubRecord(
     }
 )
 
+http2_field = SubRecord(
+    {
+        "name": String(),
+        "value": String(),
+    }
+)
+
+# lib/http/response.go: http.HTTP2Info
+http2_info = SubRecord(
+    {
+        "negotiation": String(),
+        "settings": ListOf(
+            SubRecord(
+                {
+                    "id": Unsigned16BitInteger(),
+                    "name": String(),
+                    "value": Unsigned32BitInteger(),
+                }
+            )
+        ),
+        "push_enabled": Boolean(),
+        "push_promises": ListOf(
+            SubRecord(
+                {
+                    "promised_stream_id": Unsigned32BitInteger(),
+                    "headers": ListOf(http2_field),
+                }
+            )
+        ),
+        "pseudo_headers": ListOf(http2_field),
+    }
+)
+
 # lib/http/response.go: http.Response
 http_response_full = SubRecord(
     {
@@ -162,10 +195,28 @@ http_response_full = SubRecord(
         "headers_raw": String(),
         "body": String(),
         "body_sha256": Binary(),
+        "compression": SubRecord(
+            {
+                "encoding": String(),
+                "compressed_size": Signed64BitInteger(),
+                "decompressed_size": Signed64BitInteger(),
+                "error": String(),
+            }
+        ),
+        "body_truncated": Boolean(),
+        "full_body": SubRecord(
+            {
+                "length": Signed64BitInteger(),
+                "sha256": Binary(),
+                "simhash": String(),
+                "truncated": Boolean(),
+            }
+        ),
         "content_length": Signed64BitInteger(),
         "transfer_encoding": ListOf(String()),
         "trailers": http_headers,
         "request": http_request_full,
+        "http2": http2_info,
     }
 )
 
@@ -176,17 +227,181 @@ redirects_to_resolved_ip = SubRecord(
     }
 )
 
+# modules/http/scanner.go: Results
+http_result_fields = {
+    "connect_request": http_request,
+    "connect_response": http_response,
+    "response": http_response_full,
+    "redirect_response_chain": ListOf(http_response_full),
+    "redirects_to_resolved_ips": ListOf(redirects_to_resolved_ip),
+    "cookies": ListOf(
+        SubRecord(
+            {
+                "url": String(),
+                "name": String(),
+                "value": String(),
+                "domain": String(),
+                "path": String(),
+                "expires": DateTime(),
+                "raw_expires": String(),
+                "max_age": Signed32BitInteger(),
+                "secure": Boolean(),
+                "http_only": Boolean(),
+                "same_site": Enum(["default", "lax", "strict", "none"]),
+                "partitioned": Boolean(),
+                "unparsed": ListOf(String()),
+            }
+        )
+    ),
+    "redirect_chain": ListOf(
+        SubRecord(
+            {
+                "url": String(),
+                "status_code": Signed32BitInteger(),
+                "location": String(),
+                "headers": http_headers,
+                "ip": String(),
+                "cross_host": Boolean(),
+                "cross_scheme": Boolean(),
+                "not_followed": Enum(["max-redirects", "cross-host", "cross-scheme"]),
+            }
+        )
+    ),
+    "sequence": ListOf(
+        SubRecord(
+            {
+                "response": http_response_full,
+                "error": String(),
+            }
+        )
+    ),
+    "favicons": ListOf(
+        SubRecord(
+            {
+                "url": String(),
+                "source": String(),
+                "status_code": Signed32BitInteger(),
+                "content_type": String(),
+                "size": Signed32BitInteger(),
+                "mmh3": Signed32BitInteger(),
+                "md5": String(),
+                "sha256": String(),
+                "error": String(),
+            }
+        )
+    ),
+    "html": SubRecord(
+        {
+            "title": String(),
+            "generator": String(),
+            "canonical": String(),
+        }
+    ),
+    "technologies": ListOf(
+        SubRecord(
+            {
+                "name": String(),
+                "version": String(),
+                "categories": ListOf(String()),
+            }
+        )
+    ),
+    "security_txt": SubRecord(
+        {
+            "url": String(),
+            "status_code": Signed32BitInteger(),
+            "content_type": String(),
+            "body": String(),
+            "error": String(),
+            "signed": Boolean(),
+            "contact": ListOf(String()),
+            "expires": String(),
+            "encryption": ListOf(String()),
+            "acknowledgments": ListOf(String()),
+            "preferred_languages": ListOf(String()),
+            "canonical": ListOf(String()),
+            "policy": ListOf(String()),
+            "hiring": ListOf(String()),
+            "csaf": ListOf(String()),
+            "unknown": ListOf(String()),
+        }
+    ),
+    "websocket": SubRecord(
+        {
+            "url": String(),
+            "upgraded": Boolean(),
+            "status_code": Signed32BitInteger(),
+            "headers": http_headers,
+            "accept_valid": Boolean(),
+            "protocols": ListOf(String()),
+            "extensions": ListOf(String()),
+            "error": String(),
+        }
+    ),
+    "ntlm": SubRecord(
+        {
+            "scheme": String(),
+            "status_code": Signed32BitInteger(),
+            "target_name": String(),
+            "netbios_domain_name": String(),
+            "netbios_computer_name": String(),
+            "dns_domain_name": String(),
+            "dns_computer_name": String(),
+            "dns_tree_name": String(),
+            "timestamp": DateTime(),
+            "negotiate_flags": Unsigned32BitInteger(),
+            "os_version": String(),
+            "ntlm_revision": Unsigned8BitInteger(),
+            "error": String(),
+        }
+    ),
+    "methods": SubRecord(
+        {
+            "url": String(),
+            "allow": ListOf(String()),
+            "public": ListOf(String()),
+            "probes": ListOf(
+                SubRecord(
+                    {
+                        "method": String(),
+                        "url": String(),
+                        "status_code": Signed32BitInteger(),
+                        "advertised": Boolean(),
+                        "accepted": Boolean(),
+                        "reflected": Boolean(),
+                        "error": String(),
+                    }
+                )
+            ),
+        }
+    ),
+    "robots_txt": SubRecord(
+        {
+            "url": String(),
+            "status_code": Signed32BitInteger(),
+            "content_type": String(),
+            "body": String(),
+            "error": String(),
+            "sitemaps": ListOf(String()),
+        }
+    ),
+}
+
+# modules/http/vhosts.go: VHostResult
+http_vhost_result = SubRecord(
+    {
+        "host": String(),
+        "status": String(),
+        "error": String(),
+        "result": SubRecord(http_result_fields),
+    }
+)
+
 # modules/http.go: HTTPResults
 http_scan_response = SubRecord(
     {
         "result": SubRecord(
-            {
-                "connect_request": http_request,
-                "connect_response": http_response,
-                "response": http_response_full,
-                "redirect_response_chain": ListOf(http_response_full),
-                "redirects_to_resolved_ips": ListOf(redirects_to_resolved_ip),
-            }
+            dict(http_result_fields, vhosts=ListOf(http_vhost_result)),
         )
     },
     extends=zgrab2.base_scan_response,
@@ -0,0 +1,70 @@
+# zschema sub-schema for zgrab2's HTTP/3 module
+# Registers zgrab2-http3 globally, and http3 with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+from . import http
+from . import quic
+
+http3_alt_svc = SubRecord(
+    {
+        "protocol": String(),
+        "host": String(),
+        "port": Unsigned16BitInteger(),
+        "max_age": Signed64BitInteger(),
+    }
+)
+
+http3_setting = SubRecord(
+    {
+        "id": Signed64BitInteger(),
+        "name": String(),
+        "value": Signed64BitInteger(),
+    }
+)
+
+# Schema for ScanResults struct
+http3_scan_response = SubRecord(
+    {
+        "http1_response": http.http_response_full,
+        "http1_tls": zgrab2.tls_log,
+        "http1_error": String(),
+        "alt_svc": ListOf(http3_alt_svc),
+        "alt_svc_h3": Boolean(),
+        "port": Unsigned16BitInteger(),
+        "alpn": String(),
+        "cipher_suite": String(),
+        "transport_parameters": quic.quic_transport_parameters,
+        "settings": ListOf(http3_setting),
+        "qpack": SubRecord(
+            {
+                "max_table_capacity": Signed64BitInteger(),
+                "blocked_streams": Signed64BitInteger(),
+            }
+        ),
+        "server_streams": ListOf(String()),
+        "goaway_id": Signed64BitInteger(),
+        "response": http.http_response_full,
+        "identity": SubRecord(
+            {
+                "status_code_match": Boolean(),
+                "body_match": Boolean(),
+                "server_match": Boolean(),
+                "differences": ListOf(String()),
+            }
+        ),
+        "connection_close": quic.quic_connection_close,
+    }
+)
+
+http3_scan = SubRecord(
+    {
+        "result": http3_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-http3", http3_scan)
+zgrab2.register_scan_response_type("http3", http3_scan)
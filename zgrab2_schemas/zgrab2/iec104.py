@@ -0,0 +1,37 @@
+# zschema sub-schema for zgrab2's iec104 module
+# Registers zgrab2-iec104 globally, and iec104 with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+iec104_interrogation = SubRecord(
+    {
+        "common_address": Unsigned16BitInteger(),
+        "confirmation": String(),
+        "negative": Boolean(),
+        "terminated": Boolean(),
+        "common_addresses": ListOf(Unsigned16BitInteger()),
+        "asdu_types": ListOf(String(), doc="Types of the ASDUs the station sent, as name=count"),
+        "information_objects": Unsigned32BitInteger(),
+    }
+)
+
+iec104_scan_response = SubRecord(
+    {
+        "testfr_confirmed": Boolean(),
+        "startdt_confirmed": Boolean(),
+        "interrogation": iec104_interrogation,
+    }
+)
+
+iec104_scan = SubRecord(
+    {
+        "result": iec104_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-iec104", iec104_scan)
+zgrab2.register_scan_response_type("iec104", iec104_scan)
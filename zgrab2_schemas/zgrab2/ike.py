@@ -0,0 +1,59 @@
+# zschema sub-schema for zgrab2's IKE module
+# Registers zgrab2-ike globally, and ike with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+ike_transform = SubRecord(
+    {
+        "type": String(doc="e.g. encryption, prf, integrity, dh_group, hash or auth_method"),
+        "id": Unsigned16BitInteger(),
+        "name": String(),
+        "key_length": Unsigned16BitInteger(),
+    }
+)
+
+ike_vendor_id = SubRecord(
+    {
+        "value": String(doc="Hex-encoded vendor ID"),
+        "name": String(),
+    }
+)
+
+ike_notification = SubRecord(
+    {
+        "type": Unsigned16BitInteger(),
+        "name": String(),
+        "data": String(doc="Hex-encoded notification data"),
+    }
+)
+
+# Schema for ScanResults struct
+ike_scan_response = SubRecord(
+    {
+        "version": String(),
+        "exchange": String(),
+        "responder_spi": String(),
+        "transforms": ListOf(ike_transform),
+        "key_exchange_group": Unsigned16BitInteger(),
+        "vendor_ids": ListOf(ike_vendor_id),
+        "notifications": ListOf(ike_notification),
+        "error": String(),
+        "nat_traversal": Boolean(),
+        "aggressive_mode": Boolean(doc="True if the responder answered an Aggressive Mode request with its hash"),
+        "certificate_request": Boolean(),
+        "probes": ListOf(zgrab2.udp_probe_result),
+    }
+)
+
+ike_scan = SubRecord(
+    {
+        "result": ike_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-ike", ike_scan)
+zgrab2.register_scan_response_type("ike", ike_scan)
@@ -12,9 +12,19 @@ imap_scan_response = SubRecord(
         "result": SubRecord(
             {
                 "banner": String(doc="The IMAP banner."),
+                "capability": String(
+                    doc="The server's response to the CAPABILITY command."
+                ),
+                "capabilities": ListOf(String()),
                 "starttls": String(
                     doc="The server's response to the STARTTLS command."
                 ),
+                "capability_after_starttls": String(
+                    doc="The server's response to the CAPABILITY command sent after STARTTLS."
+                ),
+                "capabilities_after_starttls": ListOf(String()),
+                "capabilities_added": ListOf(String()),
+                "capabilities_removed": ListOf(String()),
                 "close": String(doc="The server's response to the CLOSE command."),
                 "tls": zgrab2.tls_log,
             }
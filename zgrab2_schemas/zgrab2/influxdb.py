@@ -0,0 +1,35 @@
+# zschema sub-schema for zgrab2's InfluxDB module
+# Registers zgrab2-influxdb globally, and influxdb with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+# Schema for ScanResults struct
+influxdb_scan_response = SubRecord(
+    {
+        "version": String(),
+        "build": String(doc="Build type from X-Influxdb-Build: OSS, ENT or Cloud"),
+        "commit": String(),
+        "health_status": String(),
+        "health_message": String(),
+        "unauthenticated_query": Boolean(),
+        "databases": ListOf(String()),
+        "query_error": String(),
+        "ping_response": zgrab2.http_api_response,
+        "health_response": zgrab2.http_api_response,
+        "query_response": zgrab2.http_api_response,
+        "tls": zgrab2.tls_log,
+    }
+)
+
+influxdb_scan = SubRecord(
+    {
+        "result": influxdb_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-influxdb", influxdb_scan)
+zgrab2.register_scan_response_type("influxdb", influxdb_scan)
@@ -234,6 +234,52 @@ ipp_scan_response = SubRecord(
                         "ipp://BRNB8763F84DD6A.local./ipp/port1",
                     ],
                 ),
+                "attr_document_formats": ListOf(
+                    String(),
+                    doc="The document formats (MIME media types) listed in document-format-supported.",
+                    examples=["application/pdf", "image/urf"],
+                ),
+                "attr_firmware": ListOf(
+                    SubRecord(
+                        {
+                            "name": String(),
+                            "string_version": String(),
+                            "version": String(
+                                doc="Hex encoding of the printer-firmware-version octetString."
+                            ),
+                        }
+                    ),
+                    doc="Each firmware component listed in the printer-firmware-name, printer-firmware-string-version and printer-firmware-version attributes.",
+                ),
+                "attr_markers": ListOf(
+                    SubRecord(
+                        {
+                            "name": String(),
+                            "type": String(),
+                            "color": String(),
+                            "level": Signed32BitInteger(
+                                doc="Level in percent, or -1 (unavailable), -2 (unknown) or -3 (unknown, but not empty)."
+                            ),
+                            "low_level": Signed32BitInteger(),
+                            "high_level": Signed32BitInteger(),
+                        }
+                    ),
+                    doc="Each supply listed in the marker-* attributes.",
+                ),
+                "attr_identify_actions": ListOf(
+                    String(),
+                    doc="The values of identify-actions-supported.",
+                    examples=["display", "flash", "sound"],
+                ),
+                "attr_identify_actions_default": ListOf(String()),
+                "attr_make_and_model": String(),
+                "attr_device_id": String(
+                    doc="The IEEE 1284 device ID of the printer."
+                ),
+                "attr_uuid": String(),
+                "attr_location": String(),
+                "attr_info": String(),
+                "attr_state": String(examples=["idle", "processing", "stopped"]),
                 "response": http_response_full,
                 "cups_response": http_response_full,
                 "tls": zgrab2.tls_log,
@@ -0,0 +1,37 @@
+# zschema sub-schema for zgrab2's IRC module
+# Registers zgrab2-irc globally, and irc with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+# Schema for ScanResults struct
+irc_scan_response = SubRecord(
+    {
+        "notices": ListOf(String()),
+        "capabilities": ListOf(String(), doc="IRCv3 capabilities listed by CAP LS"),
+        "nick": String(),
+        "welcome": String(),
+        "your_host": String(),
+        "created": String(),
+        "server_name": String(),
+        "version": String(),
+        "user_modes": String(),
+        "channel_modes": String(),
+        "isupport": ListOf(String(), doc="RPL_ISUPPORT (005) tokens"),
+        "network": String(),
+        "error": String(),
+        "tls": zgrab2.tls_log,
+    }
+)
+
+irc_scan = SubRecord(
+    {
+        "result": irc_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-irc", irc_scan)
+zgrab2.register_scan_response_type("irc", irc_scan)
@@ -0,0 +1,40 @@
+# zschema sub-schema for zgrab2's iSCSI module
+# Registers zgrab2-iscsi globally, and iscsi with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+iscsi_target = SubRecord(
+    {
+        "name": String(),
+        "addresses": ListOf(String()),
+    }
+)
+
+# Schema for ScanResults struct
+iscsi_scan_response = SubRecord(
+    {
+        "login_status": String(),
+        "status_class": Unsigned8BitInteger(),
+        "status_detail": Unsigned8BitInteger(),
+        "version_max": Unsigned8BitInteger(),
+        "version_active": Unsigned8BitInteger(),
+        "login_keys": ListOf(String()),
+        "auth_required": Boolean(),
+        "chap_required": Boolean(),
+        "auth_method": String(),
+        "targets": ListOf(iscsi_target),
+    }
+)
+
+iscsi_scan = SubRecord(
+    {
+        "result": iscsi_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-iscsi", iscsi_scan)
+zgrab2.register_scan_response_type("iscsi", iscsi_scan)
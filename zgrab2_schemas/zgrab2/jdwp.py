@@ -0,0 +1,30 @@
+# zschema sub-schema for zgrab2's JDWP module
+# Registers zgrab2-jdwp globally, and jdwp with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+# Schema for ScanResults struct
+jdwp_scan_response = SubRecord(
+    {
+        "handshake": Boolean(doc="True if the agent completed the JDWP handshake"),
+        "description": String(),
+        "jdwp_major": Signed32BitInteger(),
+        "jdwp_minor": Signed32BitInteger(),
+        "vm_version": String(),
+        "vm_name": String(),
+        "error_code": Unsigned16BitInteger(),
+    }
+)
+
+jdwp_scan = SubRecord(
+    {
+        "result": jdwp_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-jdwp", jdwp_scan)
+zgrab2.register_scan_response_type("jdwp", jdwp_scan)
@@ -0,0 +1,56 @@
+# zschema sub-schema for zgrab2's kafka module
+# Registers zgrab2-kafka globally, and kafka with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+kafka_api_version = SubRecord(
+    {
+        "key": Signed32BitInteger(),
+        "name": String(),
+        "min_version": Signed32BitInteger(),
+        "max_version": Signed32BitInteger(),
+    }
+)
+
+kafka_broker = SubRecord(
+    {
+        "node_id": Signed32BitInteger(),
+        "host": String(),
+        "port": Signed32BitInteger(),
+        "rack": String(),
+    }
+)
+
+kafka_metadata = SubRecord(
+    {
+        "brokers": ListOf(kafka_broker),
+        "cluster_id": String(),
+        "controller_id": Signed32BitInteger(),
+    }
+)
+
+kafka_scan_response = SubRecord(
+    {
+        "tls_required": Boolean(),
+        "api_versions_error": Signed32BitInteger(),
+        "api_versions": ListOf(kafka_api_version),
+        "metadata": kafka_metadata,
+        "broker_count": Unsigned32BitInteger(),
+        "sasl_required": Boolean(),
+        "sasl_mechanisms": ListOf(String()),
+        "tls": zgrab2.tls_log,
+    }
+)
+
+kafka_scan = SubRecord(
+    {
+        "result": kafka_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-kafka", kafka_scan)
+zgrab2.register_scan_response_type("kafka", kafka_scan)
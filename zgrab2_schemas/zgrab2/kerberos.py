@@ -0,0 +1,51 @@
+# zschema sub-schema for zgrab2's Kerberos module
+# Registers zgrab2-kerberos globally, and kerberos with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+kerberos_etype = SubRecord(
+    {
+        "value": Signed32BitInteger(),
+        "name": String(),
+        "salt": String(),
+    }
+)
+
+kerberos_error = SubRecord(
+    {
+        "error_code": Signed32BitInteger(),
+        "error_name": String(),
+        "realm": String(),
+        "crealm": String(),
+        "sname": ListOf(String()),
+        "e_text": String(),
+        "e_data": Binary(),
+    }
+)
+
+# Schema for ScanResults struct
+kerberos_scan_response = SubRecord(
+    {
+        "principal": String(),
+        "error": kerberos_error,
+        "realm": String(),
+        "supported_etypes": ListOf(kerberos_etype),
+        "server_time": DateTime(),
+        "clock_skew": Signed64BitInteger(doc="KDC time minus local time, in seconds"),
+        "implementation": Enum(["mit", "heimdal", "windows"]),
+        "udp_probe": zgrab2.udp_probe_result,
+    }
+)
+
+kerberos_scan = SubRecord(
+    {
+        "result": kerberos_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-kerberos", kerberos_scan)
+zgrab2.register_scan_response_type("kerberos", kerberos_scan)
@@ -0,0 +1,43 @@
+# zschema sub-schema for zgrab2's Kubernetes module
+# Registers zgrab2-kubernetes globally, and kubernetes with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+kubernetes_build = SubRecord(
+    {
+        "major": String(),
+        "minor": String(),
+        "git_version": String(),
+        "git_commit": String(),
+        "git_tree_state": String(),
+        "build_date": String(),
+        "go_version": String(),
+        "compiler": String(),
+        "platform": String(),
+    }
+)
+
+# Schema for ScanResults struct
+kubernetes_scan_response = SubRecord(
+    {
+        "build": kubernetes_build,
+        "healthz": String(),
+        "anonymous_auth": Enum(["enabled", "enabled-but-forbidden", "disabled"]),
+        "version_response": zgrab2.http_api_response,
+        "healthz_response": zgrab2.http_api_response,
+        "tls": zgrab2.tls_log,
+    }
+)
+
+kubernetes_scan = SubRecord(
+    {
+        "result": kubernetes_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-kubernetes", kubernetes_scan)
+zgrab2.register_scan_response_type("kubernetes", kubernetes_scan)
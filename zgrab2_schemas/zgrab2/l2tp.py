@@ -0,0 +1,51 @@
+# zschema sub-schema for zgrab2's L2TP module
+# Registers zgrab2-l2tp globally, and l2tp with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+l2tp_capabilities = SubRecord(
+    {
+        "synchronous": Boolean(),
+        "asynchronous": Boolean(),
+    }
+)
+
+l2tp_result_code = SubRecord(
+    {
+        "result": Unsigned16BitInteger(),
+        "error": Unsigned16BitInteger(),
+        "message": String(),
+    }
+)
+
+# Schema for ScanResults struct
+l2tp_scan_response = SubRecord(
+    {
+        "message_type": String(),
+        "protocol_version": String(),
+        "host_name": String(),
+        "vendor_name": String(),
+        "firmware_revision": Unsigned16BitInteger(),
+        "assigned_tunnel_id": Unsigned16BitInteger(),
+        "receive_window_size": Unsigned16BitInteger(),
+        "framing_capabilities": l2tp_capabilities,
+        "bearer_capabilities": l2tp_capabilities,
+        "challenge_required": Boolean(),
+        "hidden_avps": Boolean(),
+        "result_code": l2tp_result_code,
+        "udp_probe": zgrab2.udp_probe_result,
+    }
+)
+
+l2tp_scan = SubRecord(
+    {
+        "result": l2tp_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-l2tp", l2tp_scan)
+zgrab2.register_scan_response_type("l2tp", l2tp_scan)
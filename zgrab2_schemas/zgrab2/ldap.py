@@ -0,0 +1,55 @@
+# zschema sub-schema for zgrab2's LDAP module
+# Registers zgrab2-ldap globally, and ldap with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+ldap_result = SubRecord(
+    {
+        "result_code": Signed64BitInteger(),
+        "result_name": String(),
+        "matched_dn": String(),
+        "diagnostic_message": String(),
+    }
+)
+
+ldap_root_dse = SubRecord(
+    {
+        "naming_contexts": ListOf(String()),
+        "default_naming_context": String(),
+        "supported_ldap_version": ListOf(String()),
+        "supported_sasl_mechanisms": ListOf(String()),
+        "supported_extension": ListOf(String()),
+        "supported_control": ListOf(String()),
+        "vendor_name": String(),
+        "vendor_version": String(),
+        "dns_host_name": String(),
+        "server_name": String(),
+        "attributes": ListOf(
+            SubRecord({"name": String(), "values": ListOf(String())})
+        ),
+    }
+)
+
+# Schema for ScanResults struct
+ldap_scan_response = SubRecord(
+    {
+        "bind": ldap_result,
+        "search": ldap_result,
+        "root_dse": ldap_root_dse,
+        "starttls": ldap_result,
+        "tls": zgrab2.tls_log,
+    }
+)
+
+ldap_scan = SubRecord(
+    {
+        "result": ldap_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-ldap", ldap_scan)
+zgrab2.register_scan_response_type("ldap", ldap_scan)
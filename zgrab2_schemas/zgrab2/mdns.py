@@ -0,0 +1,37 @@
+# zschema sub-schema for zgrab2's mDNS module
+# Registers zgrab2-mdns globally, and mdns with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+mdns_instance = SubRecord(
+    {
+        "name": String(),
+        "service_type": String(),
+        "host": String(),
+        "port": Unsigned16BitInteger(),
+        "txt": ListOf(String()),
+        "addresses": ListOf(String()),
+    }
+)
+
+# Schema for ScanResults struct
+mdns_scan_response = SubRecord(
+    {
+        "service_types": ListOf(String()),
+        "instances": ListOf(mdns_instance),
+        "probes": ListOf(zgrab2.udp_probe_result),
+    }
+)
+
+mdns_scan = SubRecord(
+    {
+        "result": mdns_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-mdns", mdns_scan)
+zgrab2.register_scan_response_type("mdns", mdns_scan)
@@ -117,6 +117,18 @@ response = SubRecord(
                         "round_robin_fallback": Unsigned32BitInteger(),
                     }
                 ),
+                "udp": SubRecord(
+                    {
+                        "request_bytes": Unsigned32BitInteger(),
+                        "response_bytes": Unsigned32BitInteger(),
+                        "datagrams": Unsigned32BitInteger(),
+                        "factor": Float(),
+                        "vulnerable": Boolean(
+                            doc="Whether the UDP response was larger than the request"
+                        ),
+                    },
+                    doc="UDP amplification, only present with --udp",
+                ),
             }
         )
     },
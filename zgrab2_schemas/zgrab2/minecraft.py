@@ -0,0 +1,48 @@
+# zschema sub-schema for zgrab2's Minecraft module
+# Registers zgrab2-minecraft globally, and minecraft with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+minecraft_player = SubRecord(
+    {
+        "name": String(),
+        "id": String(),
+    }
+)
+
+minecraft_mod = SubRecord(
+    {
+        "id": String(),
+        "version": String(),
+    }
+)
+
+# Schema for ScanResults struct
+minecraft_scan_response = SubRecord(
+    {
+        "version_name": String(),
+        "protocol_version": Signed32BitInteger(),
+        "motd": String(),
+        "players_online": Signed64BitInteger(),
+        "players_max": Signed64BitInteger(),
+        "player_sample": ListOf(minecraft_player),
+        "has_favicon": Boolean(),
+        "enforces_secure_chat": Boolean(),
+        "mod_loader": String(),
+        "mods": ListOf(minecraft_mod),
+        "status": String(),
+    }
+)
+
+minecraft_scan = SubRecord(
+    {
+        "result": minecraft_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-minecraft", minecraft_scan)
+zgrab2.register_scan_response_type("minecraft", minecraft_scan)
@@ -39,6 +39,50 @@ exception_response = SubRecord(
     {
         "exception_function": Unsigned8BitInteger(),
         "exception_type": Unsigned8BitInteger(),
+        "exception_name": Enum(
+            values=[
+                "illegal_function",
+                "illegal_data_address",
+                "illegal_data_value",
+                "server_device_failure",
+                "acknowledge",
+                "server_device_busy",
+                "memory_parity_error",
+                "gateway_path_unavailable",
+                "gateway_target_device_failed_to_respond",
+                "unknown",
+            ]
+        ),
+        "is_gateway_error": Boolean(),
+    }
+)
+
+device_identification = SubRecord(
+    {
+        "conformity_level": Unsigned8BitInteger(),
+        "categories": ListOf(
+            SubRecord(
+                {
+                    "name": Enum(values=["basic", "regular", "extended"]),
+                    "requests": Unsigned8BitInteger(),
+                    "object_count": Unsigned16BitInteger(),
+                    "exception_response": exception_response,
+                    "error": String(),
+                }
+            )
+        ),
+        "objects": mei_object_set,
+    },
+    doc="The objects of all categories supported by the device; only present if --full-device-id is set.",
+)
+
+unit = SubRecord(
+    {
+        "unit_id": Unsigned8BitInteger(),
+        "responded": Boolean(),
+        "mei_response": mei_response,
+        "exception_response": exception_response,
+        "error": String(),
     }
 )
 
@@ -53,6 +97,10 @@ modbus_scan_response = SubRecord(
                 "mei_response": mei_response,
                 "exception_response": exception_response,
                 "raw": Binary(),
+                "device_identification": device_identification,
+                "units": ListOf(
+                    unit, doc="The responses of each of the --unit-ids."
+                ),
             }
         )
     },
@@ -49,8 +49,32 @@ mongodb_scan_response = SubRecord(
                             )
                         ),
                         "total_size": Signed32BitInteger(),
+                        "error": String(
+                            doc="The error of a failed listDatabases command, typically because authentication is required."
+                        ),
+                        "code_name": String(),
                     }
                 ),
+                "hello": SubRecord(
+                    {
+                        "is_writable_primary": Boolean(),
+                        "secondary": Boolean(),
+                        "set_name": String(),
+                        "hosts": ListOf(String()),
+                        "primary": String(),
+                        "me": String(),
+                        "msg": String(),
+                        "connection_id": Signed32BitInteger(),
+                        "max_wire_version": Signed32BitInteger(),
+                    }
+                ),
+                "unauthenticated_access": Boolean(
+                    doc="Whether the server ran listDatabases without credentials."
+                ),
+                "storage_engine": String(
+                    doc="The storage engine in use, from serverStatus, which only runs with unauthenticated access.",
+                    examples=["wiredTiger", "inMemory"],
+                ),
                 "is_master": SubRecord(
                     {
                         "is_master": Boolean(),
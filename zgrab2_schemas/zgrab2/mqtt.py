@@ -6,11 +6,35 @@ import zschema.registry
 
 from . import zgrab2
 
+mqtt_connack_properties = SubRecord(
+    {
+        "session_expiry_interval": Unsigned32BitInteger(),
+        "receive_maximum": Unsigned16BitInteger(),
+        "maximum_qos": Unsigned8BitInteger(),
+        "retain_available": Boolean(),
+        "maximum_packet_size": Unsigned32BitInteger(),
+        "assigned_client_identifier": String(),
+        "topic_alias_maximum": Unsigned16BitInteger(),
+        "reason_string": String(),
+        "user_properties": ListOf(SubRecord({"name": String(), "value": String()})),
+        "wildcard_subscription_available": Boolean(),
+        "subscription_identifiers_available": Boolean(),
+        "shared_subscription_available": Boolean(),
+        "server_keep_alive": Unsigned16BitInteger(),
+        "response_information": String(),
+        "server_reference": String(),
+        "authentication_method": String(),
+    }
+)
+
 # Schema for ScanResults struct
 mqtt_scan_response = SubRecord(
     {
         "session_present": Boolean(),
         "connect_return_code": Unsigned32BitInteger(),
+        "return_code_name": String(),
+        "anonymous_accepted": Boolean(),
+        "properties": mqtt_connack_properties,
         "response": String(),
         "tls": zgrab2.tls_log,
     }
@@ -29,6 +29,7 @@ prelogin_options = SubRecord(
                 "major": Unsigned8BitInteger(),
                 "minor": Unsigned8BitInteger(),
                 "build_number": Unsigned16BitInteger(),
+                "sub_build_number": Unsigned16BitInteger(),
             }
         ),
         "encrypt_mode": Enum(values=ENCRYPT_MODES),
@@ -49,6 +50,12 @@ mssql_scan_response = SubRecord(
                 "version": WhitespaceAnalyzedString(),
                 "instance_name": WhitespaceAnalyzedString(),
                 "prelogin_options": prelogin_options,
+                "mars": Boolean(
+                    doc="Whether the server supports Multiple Active Result Sets, per the MARS PRELOGIN option."
+                ),
+                "fed_auth_required": Boolean(
+                    doc="Whether the server requires federated authentication, per the FEDAUTHREQUIRED PRELOGIN option."
+                ),
                 "encrypt_mode": Enum(
                     values=ENCRYPT_MODES,
                     doc="The negotiated ENCRYPT_MODE with the server.",
@@ -0,0 +1,38 @@
+# zschema sub-schema for zgrab2's mssql-browser module
+# Registers zgrab2-mssql-browser globally, and mssql-browser with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+mssql_browser_instance = SubRecord(
+    {
+        "server_name": String(),
+        "instance_name": String(examples=["MSSQLSERVER", "SQLEXPRESS"]),
+        "is_clustered": Boolean(),
+        "version": String(examples=["15.0.2000.5"]),
+        "tcp_port": Unsigned16BitInteger(
+            doc="The port the instance listens on, to scan with the mssql module."
+        ),
+        "named_pipe": String(),
+    }
+)
+
+# Schema for ScanResults struct
+mssql_browser_scan_response = SubRecord(
+    {
+        "instances": ListOf(mssql_browser_instance),
+        "udp_probe": zgrab2.udp_probe_result,
+    }
+)
+
+mssql_browser_scan = SubRecord(
+    {
+        "result": mssql_browser_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-mssql-browser", mssql_browser_scan)
+zgrab2.register_scan_response_type("mssql-browser", mssql_browser_scan)
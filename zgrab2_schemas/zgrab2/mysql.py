@@ -56,6 +56,13 @@ mysql_capability_flags = zgrab2.FlagsSet(
         "CLIENT_CAN_HANDLE_EXPIRED_PASSWORDS",
         "CLIENT_SESSION_TRACK",
         "CLIENT_DEPRECATED_EOF",
+        "CLIENT_OPTIONAL_RESULTSET_METADATA",
+        "CLIENT_ZSTD_COMPRESSION_ALGORITHM",
+        "CLIENT_QUERY_ATTRIBUTES",
+        "MULTI_FACTOR_AUTHENTICATION",
+        "CLIENT_CAPABILITY_EXTENSION",
+        "CLIENT_SSL_VERIFY_SERVER_CERT",
+        "CLIENT_REMEMBER_OPTIONS",
     ],
     doc="The set of capability flags the server returned in the initial HandshakePacket. Each entry corresponds to a bit being set in the flags; key names correspond to the #defines in the MySQL docs.",
 )
@@ -90,10 +97,47 @@ mysql_scan_response = SubRecord(
                     )
                 ),
                 "status_flags": mysql_server_status_flags,
-                "auth_plugin_name": zgrab2.DebugOnly(
-                    WhitespaceAnalyzedString(
-                        doc="The name of the authentication plugin, returned in the initial HandshakePacket."
-                    )
+                "auth_plugin_name": String(
+                    doc="The name of the authentication plugin, returned in the initial HandshakePacket.",
+                    examples=["mysql_native_password", "caching_sha2_password"],
+                ),
+                "auth_plugin_salt": SubRecord(
+                    {
+                        "length": Unsigned32BitInteger(
+                            doc="The length of the salt without a trailing NUL byte."
+                        ),
+                        "nul_terminated": Boolean(
+                            doc="Whether the salt ends with a NUL byte."
+                        ),
+                        "ascii": Boolean(doc="Whether all bytes of the salt are 7-bit."),
+                    },
+                    doc="The format of auth_plugin_data, the salt of the authentication plugin.",
+                ),
+                "caching_sha2": SubRecord(
+                    {
+                        "fast_authentication": Boolean(
+                            doc="Whether the server accepted the scramble from its cache."
+                        ),
+                        "full_authentication": Boolean(
+                            doc="Whether the server asked for full authentication."
+                        ),
+                        "auth_switch_plugin": String(
+                            doc="The plugin the server switched the authentication to instead, if it did."
+                        ),
+                        "public_key": String(
+                            doc="The PEM-encoded RSA public key the server sent."
+                        ),
+                        "public_key_bits": Unsigned32BitInteger(
+                            doc="The size of the modulus of the public key."
+                        ),
+                        "error_code": Signed32BitInteger(
+                            doc="The code of the error the server ended the exchange with, if it did."
+                        ),
+                        "error_message": WhitespaceAnalyzedString(
+                            doc="The message of the error the server ended the exchange with, if it did."
+                        ),
+                    },
+                    doc="The outcome of requesting the caching_sha2_password RSA public key on a second connection, with --request-public-key.",
                 ),
                 "error_code": Signed32BitInteger(
                     doc="Only set if there is an error returned by the server, for example if the scanner is not on the allowed hosts list."
@@ -0,0 +1,54 @@
+# zschema sub-schema for zgrab2's NATS module
+# Registers zgrab2-nats globally, and nats with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+nats_info = SubRecord(
+    {
+        "server_id": String(),
+        "server_name": String(),
+        "version": String(),
+        "proto": Signed32BitInteger(),
+        "git_commit": String(),
+        "go": String(),
+        "host": String(),
+        "port": Unsigned16BitInteger(),
+        "headers": Boolean(),
+        "auth_required": Boolean(),
+        "tls_required": Boolean(),
+        "tls_verify": Boolean(),
+        "tls_available": Boolean(),
+        "max_payload": Signed64BitInteger(),
+        "jetstream": Boolean(),
+        "client_id": Signed64BitInteger(),
+        "client_ip": String(),
+        "cluster": String(),
+        "connect_urls": ListOf(String()),
+        "ws_connect_urls": ListOf(String()),
+        "ldm": Boolean(doc="Server is in lame duck mode"),
+        "domain": String(),
+        "nonce": String(),
+    }
+)
+
+# Schema for ScanResults struct
+nats_scan_response = SubRecord(
+    {
+        "info": nats_info,
+        "raw": String(),
+        "tls": zgrab2.tls_log,
+    }
+)
+
+nats_scan = SubRecord(
+    {
+        "result": nats_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-nats", nats_scan)
+zgrab2.register_scan_response_type("nats", nats_scan)
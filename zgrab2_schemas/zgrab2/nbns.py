@@ -0,0 +1,45 @@
+# zschema sub-schema for zgrab2's NBNS module
+# Registers zgrab2-nbns globally, and nbns with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+nbns_name = SubRecord(
+    {
+        "name": String(),
+        "suffix": Unsigned8BitInteger(),
+        "group": Boolean(),
+        "service": String(),
+        "active": Boolean(),
+        "permanent": Boolean(),
+        "conflict": Boolean(),
+        "deregister": Boolean(),
+        "owner_node_type": Enum(values=["B", "P", "M", "H"]),
+    }
+)
+
+# Schema for ScanResults struct
+nbns_scan_response = SubRecord(
+    {
+        "names": ListOf(nbns_name),
+        "workstation": String(),
+        "domain": String(),
+        "mac_address": String(),
+        "file_server": Boolean(),
+        "domain_controller": Boolean(),
+        "master_browser": Boolean(),
+        "udp_probe": zgrab2.udp_probe_result,
+    }
+)
+
+nbns_scan = SubRecord(
+    {
+        "result": nbns_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-nbns", nbns_scan)
+zgrab2.register_scan_response_type("nbns", nbns_scan)
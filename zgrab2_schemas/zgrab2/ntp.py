@@ -56,6 +56,56 @@ mode7_header = SubRecord(
     }
 )
 
+mode6_header = SubRecord(
+    {
+        "leap_indicator": Unsigned8BitInteger(),
+        "version": Unsigned8BitInteger(),
+        "mode": Unsigned8BitInteger(),
+        "is_response": Boolean(),
+        "is_error": Boolean(),
+        "has_more": Boolean(),
+        "opcode": Unsigned8BitInteger(),
+        "sequence": Unsigned16BitInteger(),
+        "status": Unsigned16BitInteger(),
+        "association_id": Unsigned16BitInteger(),
+        "offset": Unsigned16BitInteger(),
+        "count": Unsigned16BitInteger(),
+    }
+)
+
+amplification = SubRecord(
+    {
+        "request_bytes": Unsigned32BitInteger(),
+        "response_bytes": Unsigned32BitInteger(),
+        "response_packets": Unsigned32BitInteger(),
+        "ratio": Float(doc="response_bytes / request_bytes"),
+        "truncated": Boolean(
+            doc="Whether the server had more responses to send when --max-response-packets was reached."
+        ),
+    }
+)
+
+readvar = SubRecord(
+    {
+        "header": mode6_header,
+        "variables": ListOf(
+            SubRecord(
+                {
+                    "name": String(),
+                    "value": String(),
+                }
+            ),
+            doc="The system variables returned by the server, in order.",
+        ),
+        "version": String(examples=["ntpd 4.2.8p15@1.3728-o"]),
+        "processor": String(),
+        "system": String(examples=["Linux/5.10.0"]),
+        "refid": String(),
+        "stratum": Unsigned8BitInteger(),
+        "amplification": amplification,
+    }
+)
+
 ntp_scan_response = SubRecord(
     {
         "result": SubRecord(
@@ -65,6 +115,8 @@ ntp_scan_response = SubRecord(
                 "time_response": ntp_header,
                 "monlist_response": Binary(),
                 "monlist_header": mode7_header,
+                "monlist_amplification": amplification,
+                "readvar": readvar,
             }
         )
     },
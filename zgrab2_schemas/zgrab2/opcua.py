@@ -0,0 +1,90 @@
+# zschema sub-schema for zgrab2's OPC UA module
+# Registers zgrab2-opcua globally, and opcua with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+import zcrypto_schemas.zcrypto as zcrypto
+from . import zgrab2
+
+opcua_acknowledge = SubRecord(
+    {
+        "protocol_version": Unsigned32BitInteger(),
+        "receive_buffer_size": Unsigned32BitInteger(),
+        "send_buffer_size": Unsigned32BitInteger(),
+        "max_message_size": Unsigned32BitInteger(),
+        "max_chunk_count": Unsigned32BitInteger(),
+    }
+)
+
+opcua_error = SubRecord(
+    {
+        "code": Unsigned32BitInteger(),
+        "name": String(),
+        "reason": String(),
+    }
+)
+
+opcua_application = SubRecord(
+    {
+        "uri": String(),
+        "product_uri": String(),
+        "name": String(),
+        "type": String(),
+        "gateway_uri": String(),
+        "discovery_urls": ListOf(String()),
+    }
+)
+
+opcua_endpoint = SubRecord(
+    {
+        "url": String(),
+        "security_mode": String(),
+        "security_policy": String(),
+        "security_level": Unsigned8BitInteger(),
+        "transport_profile": String(),
+        "user_tokens": ListOf(
+            SubRecord(
+                {
+                    "policy_id": String(),
+                    "type": String(),
+                    "security_policy": String(),
+                }
+            )
+        ),
+    }
+)
+
+opcua_build_info = SubRecord(
+    {
+        "product_uri": String(),
+        "manufacturer_name": String(),
+        "product_name": String(),
+        "software_version": String(),
+        "build_number": String(),
+        "build_date": DateTime(),
+    }
+)
+
+# Schema for ScanResults struct
+opcua_scan_response = SubRecord(
+    {
+        "acknowledge": opcua_acknowledge,
+        "error": opcua_error,
+        "server": opcua_application,
+        "endpoints": ListOf(opcua_endpoint),
+        "certificate": zcrypto.SimpleCertificate(),
+        "anonymous_access": Boolean(),
+        "build_info": opcua_build_info,
+    }
+)
+
+opcua_scan = SubRecord(
+    {
+        "result": opcua_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-opcua", opcua_scan)
+zgrab2.register_scan_response_type("opcua", opcua_scan)
@@ -0,0 +1,41 @@
+# zschema sub-schema for zgrab2's OpenVPN module
+# Registers zgrab2-openvpn globally, and openvpn with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+openvpn_control_packet = SubRecord(
+    {
+        "opcode": String(),
+        "key_id": Unsigned8BitInteger(),
+        "session_id": String(doc="Hex-encoded server session ID"),
+        "acked_ids": ListOf(Unsigned32BitInteger()),
+        "peer_session_id": String(doc="Hex-encoded session ID the packet acknowledges"),
+        "packet_id": Unsigned32BitInteger(),
+        "payload_length": Unsigned16BitInteger(),
+    }
+)
+
+# Schema for ScanResults struct
+openvpn_scan_response = SubRecord(
+    {
+        "transport": String(),
+        "response": Enum(values=["reset_server", "dropped", "none"]),
+        "unprotected": Boolean(doc="True if the server answered a reset without tls-auth or tls-crypt"),
+        "packet": openvpn_control_packet,
+        "acks_client": Boolean(),
+        "udp_probe": zgrab2.udp_probe_result,
+    }
+)
+
+openvpn_scan = SubRecord(
+    {
+        "result": openvpn_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-openvpn", openvpn_scan)
+zgrab2.register_scan_response_type("openvpn", openvpn_scan)
@@ -64,6 +64,18 @@ oracle_scan_response = SubRecord(
                         "accept_version": Unsigned16BitInteger(
                             doc="The protocol version number from the Accept packet."
                         ),
+                        "accept_sdu": Unsigned16BitInteger(
+                            doc="The Session Data Unit size from the Accept packet."
+                        ),
+                        "accept_tdu": Unsigned16BitInteger(
+                            doc="The Transfer Data Unit size from the Accept packet."
+                        ),
+                        "accept_extra_raw": Binary(
+                            doc="The data between the fixed fields of the Accept packet and its AcceptData."
+                        ),
+                        "accept_data_raw": Binary(
+                            doc="The AcceptData payload of the Accept packet."
+                        ),
                         "global_service_options": FlagsSet(
                             global_service_options,
                             doc="Set of flags that the server returns in the Accept packet.",
@@ -126,6 +138,25 @@ oracle_scan_response = SubRecord(
                     doc="The log of the Oracle / TDS handshake process.",
                 ),
                 "tls": zgrab2.tls_log,
+                "descriptor_probes": ListOf(
+                    SubRecord(
+                        {
+                            "kind": Enum(values=["SID", "SERVICE_NAME"]),
+                            "name": String(),
+                            "response": String(examples=["ACCEPT", "REFUSE", "REDIRECT"]),
+                            "acknowledged": Boolean(
+                                doc="True if the listener accepted or redirected the connect descriptor."
+                            ),
+                            "refuse_error_code": String(
+                                doc="The DESCRIPTION.ERR value of the Refuse packet.",
+                                examples=["12505", "12514"],
+                            ),
+                            "redirect_target_raw": WhitespaceAnalyzedString(),
+                            "error": String(),
+                        }
+                    ),
+                    doc="The listener's responses to each SID and service name tried with --probe-sids.",
+                ),
             }
         )
     },
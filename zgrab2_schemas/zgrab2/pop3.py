@@ -14,9 +14,17 @@ pop3_scan_response = SubRecord(
                 "banner": String(doc="The POP3 banner."),
                 "noop": String(doc="The server's response to the NOOP command."),
                 "help": String(doc="The server's response to the HELP command."),
+                "capa": String(doc="The server's response to the CAPA command."),
+                "capabilities": ListOf(String()),
                 "starttls": String(
                     doc="The server's response to the STARTTLS command."
                 ),
+                "capa_after_starttls": String(
+                    doc="The server's response to the CAPA command sent after STLS."
+                ),
+                "capabilities_after_starttls": ListOf(String()),
+                "capabilities_added": ListOf(String()),
+                "capabilities_removed": ListOf(String()),
                 "quit": String(doc="The server's response to the QUIT command."),
                 "tls": zgrab2.tls_log,
             }
@@ -0,0 +1,45 @@
+# zschema sub-schema for zgrab2's portmap module
+# Registers zgrab2-portmap globally, and portmap with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+portmap_program = SubRecord(
+    {
+        "program": Unsigned32BitInteger(),
+        "name": String(),
+        "version": Unsigned32BitInteger(),
+        "protocol": String(),
+        "port": Unsigned32BitInteger(),
+    }
+)
+
+portmap_export = SubRecord(
+    {
+        "directory": String(),
+        "groups": ListOf(String()),
+        "world_readable": Boolean(),
+    }
+)
+
+# Schema for ScanResults struct
+portmap_scan_response = SubRecord(
+    {
+        "programs": ListOf(portmap_program),
+        "mountd_port": Unsigned32BitInteger(),
+        "exports": ListOf(portmap_export),
+        "probes": ListOf(zgrab2.udp_probe_result),
+    }
+)
+
+portmap_scan = SubRecord(
+    {
+        "result": portmap_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-portmap", portmap_scan)
+zgrab2.register_scan_response_type("portmap", portmap_scan)
@@ -82,6 +82,30 @@ postgres_scan_response = SubRecord(
                 "server_parameters": WhitespaceAnalyzedString(),
                 "backend_key_data": postgres_key_data,
                 "transaction_status": WhitespaceAnalyzedString(),
+                "parameter_status": ListOf(
+                    SubRecord(
+                        {
+                            "name": String(),
+                            "value": WhitespaceAnalyzedString(),
+                        }
+                    ),
+                    doc="The ParameterStatus messages returned after the final StartupMessage, in order.",
+                ),
+                "sasl_mechanisms": ListOf(
+                    String(),
+                    doc="The mechanisms of the AuthenticationSASL message returned after the final StartupMessage.",
+                    examples=[["SCRAM-SHA-256-PLUS", "SCRAM-SHA-256"]],
+                ),
+                "supports_channel_binding": Boolean(
+                    doc="Whether the SASL mechanisms include SCRAM-SHA-256-PLUS."
+                ),
+                "gss_encryption": SubRecord(
+                    {
+                        "supported": Boolean(),
+                        "error": postgres_error,
+                    },
+                    doc="The response to a GSSENCRequest.",
+                ),
             }
         )
     },
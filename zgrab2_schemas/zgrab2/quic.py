@@ -0,0 +1,72 @@
+# zschema sub-schema for zgrab2's QUIC module
+# Registers zgrab2-quic globally, and quic with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+import zcrypto_schemas.zcrypto as zcrypto
+from . import zgrab2
+
+quic_transport_parameters = SubRecord(
+    {
+        "original_destination_connection_id": String(),
+        "initial_source_connection_id": String(),
+        "retry_source_connection_id": String(),
+        "stateless_reset_token": String(),
+        "max_idle_timeout": Signed64BitInteger(),
+        "max_udp_payload_size": Signed64BitInteger(),
+        "initial_max_data": Signed64BitInteger(),
+        "initial_max_stream_data_bidi_local": Signed64BitInteger(),
+        "initial_max_stream_data_bidi_remote": Signed64BitInteger(),
+        "initial_max_stream_data_uni": Signed64BitInteger(),
+        "initial_max_streams_bidi": Signed64BitInteger(),
+        "initial_max_streams_uni": Signed64BitInteger(),
+        "ack_delay_exponent": Signed64BitInteger(),
+        "max_ack_delay": Signed64BitInteger(),
+        "active_connection_id_limit": Signed64BitInteger(),
+        "max_datagram_frame_size": Signed64BitInteger(),
+        "disable_active_migration": Boolean(),
+        "preferred_address": Boolean(),
+        "grease_quic_bit": Boolean(),
+        "chosen_version": String(),
+        "available_versions": ListOf(String()),
+        "unknown": ListOf(Signed64BitInteger()),
+    }
+)
+
+quic_connection_close = SubRecord(
+    {
+        "error_code": Signed64BitInteger(),
+        "error_name": String(),
+        "application": Boolean(),
+        "frame_type": Signed64BitInteger(),
+        "reason": String(),
+    }
+)
+
+# Schema for ScanResults struct
+quic_scan_response = SubRecord(
+    {
+        "version_negotiation": Boolean(),
+        "supported_versions": ListOf(String()),
+        "version": String(),
+        "retry": Boolean(),
+        "transport_parameters": quic_transport_parameters,
+        "cipher_suite": String(),
+        "alpn": String(),
+        "certificates": ListOf(zcrypto.SimpleCertificate()),
+        "connection_close": quic_connection_close,
+        "handshake_complete": Boolean(),
+        "probes": ListOf(zgrab2.udp_probe_result),
+    }
+)
+
+quic_scan = SubRecord(
+    {
+        "result": quic_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-quic", quic_scan)
+zgrab2.register_scan_response_type("quic", quic_scan)
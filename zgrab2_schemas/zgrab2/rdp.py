@@ -0,0 +1,45 @@
+# zschema sub-schema for zgrab2's RDP module
+# Registers zgrab2-rdp globally, and rdp with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+rdp_negotiation_flags = SubRecord(
+    {
+        "extended_client_data_supported": Boolean(),
+        "dynvc_gfx_protocol_supported": Boolean(),
+        "restricted_admin_mode_supported": Boolean(),
+        "redirected_authentication_mode_supported": Boolean(),
+        "raw": Unsigned8BitInteger(),
+    }
+)
+
+rdp_negotiation = SubRecord(
+    {
+        "selected_protocol": String(),
+        "flags": rdp_negotiation_flags,
+        "failure": String(),
+        "failure_code": Unsigned32BitInteger(),
+    }
+)
+
+# Schema for ScanResults struct
+rdp_scan_response = SubRecord(
+    {
+        "negotiation": rdp_negotiation,
+        "supported_protocols": ListOf(String()),
+        "tls": zgrab2.tls_log,
+    }
+)
+
+rdp_scan = SubRecord(
+    {
+        "result": rdp_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-rdp", rdp_scan)
+zgrab2.register_scan_response_type("rdp", rdp_scan)
@@ -36,6 +36,44 @@ redis_scan_response = SubRecord(
                 "auth_response": String(
                     doc="The response from the AUTH command, if sent."
                 ),
+                "auth_required": Boolean(
+                    doc="Whether the server refused a command with a NOAUTH error."
+                ),
+                "protected_mode": Boolean(
+                    doc="Whether the server refused PING with a DENIED error, as servers in protected mode do."
+                ),
+                "info_sections": ListOf(
+                    SubRecord(
+                        {
+                            "name": String(examples=["Server", "Keyspace"]),
+                            "fields": ListOf(
+                                SubRecord(
+                                    {
+                                        "name": String(),
+                                        "value": String(),
+                                    }
+                                )
+                            ),
+                        }
+                    ),
+                    doc="The sections of the info_response with their fields, in order.",
+                ),
+                "modules": ListOf(
+                    SubRecord(
+                        {
+                            "name": String(),
+                            "version": Signed64BitInteger(),
+                        }
+                    ),
+                    doc="The modules loaded by the server, from MODULE LIST.",
+                ),
+                "config": SubRecord(
+                    {
+                        "bind": String(),
+                        "protected_mode": String(examples=["yes", "no"]),
+                    },
+                    doc="The values of CONFIG GET bind and CONFIG GET protected-mode, sent if INFO succeeds.",
+                ),
                 "nonexistent_response": String(
                     doc="The response from the NONEXISTENT command.",
                     examples=[
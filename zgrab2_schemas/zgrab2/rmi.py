@@ -0,0 +1,42 @@
+# zschema sub-schema for zgrab2's RMI module
+# Registers zgrab2-rmi globally, and rmi with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+rmi_endpoint = SubRecord(
+    {
+        "host": String(),
+        "port": Unsigned32BitInteger(),
+    }
+)
+
+rmi_jmx_connector = SubRecord(
+    {
+        "endpoint": rmi_endpoint,
+        "reachable": Boolean(),
+        "exception": String(),
+    }
+)
+
+# Schema for ScanResults struct
+rmi_scan_response = SubRecord(
+    {
+        "client_endpoint": rmi_endpoint,
+        "bound_names": ListOf(String()),
+        "exception": String(),
+        "jmx": rmi_jmx_connector,
+    }
+)
+
+rmi_scan = SubRecord(
+    {
+        "result": rmi_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-rmi", rmi_scan)
+zgrab2.register_scan_response_type("rmi", rmi_scan)
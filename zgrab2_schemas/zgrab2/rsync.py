@@ -0,0 +1,41 @@
+# zschema sub-schema for zgrab2's rsync module
+# Registers zgrab2-rsync globally, and rsync with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+rsync_greeting = SubRecord(
+    {
+        "protocol_version": String(),
+        "digests": ListOf(String()),
+    }
+)
+
+rsync_module = SubRecord(
+    {
+        "name": String(),
+        "comment": String(),
+    }
+)
+
+# Schema for ScanResults struct
+rsync_scan_response = SubRecord(
+    {
+        "greeting": rsync_greeting,
+        "motd": String(),
+        "modules": ListOf(rsync_module),
+        "error": String(),
+    }
+)
+
+rsync_scan = SubRecord(
+    {
+        "result": rsync_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-rsync", rsync_scan)
+zgrab2.register_scan_response_type("rsync", rsync_scan)
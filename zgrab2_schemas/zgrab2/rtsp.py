@@ -0,0 +1,46 @@
+# zschema sub-schema for zgrab2's RTSP module
+# Registers zgrab2-rtsp globally, and rtsp with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+rtsp_header = SubRecord(
+    {
+        "name": String(),
+        "value": String(),
+    }
+)
+
+rtsp_response = SubRecord(
+    {
+        "status_code": Unsigned16BitInteger(),
+        "reason_phrase": String(),
+        "headers": ListOf(rtsp_header),
+        "body": String(),
+        "body_truncated": Boolean(),
+    }
+)
+
+# Schema for ScanResults struct
+rtsp_scan_response = SubRecord(
+    {
+        "server": String(),
+        "methods": ListOf(String(), doc="Methods listed in the Public header"),
+        "auth_required": Boolean(),
+        "auth_schemes": ListOf(String()),
+        "options": rtsp_response,
+        "describe": rtsp_response,
+    }
+)
+
+rtsp_scan = SubRecord(
+    {
+        "result": rtsp_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-rtsp", rtsp_scan)
+zgrab2.register_scan_response_type("rtsp", rtsp_scan)
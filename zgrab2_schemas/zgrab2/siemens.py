@@ -7,6 +7,23 @@ import zschema.registry
 import zcrypto_schemas.zcrypto as zcrypto
 from . import zgrab2
 
+# modules/siemens/log.go - S7PlusLog
+siemens_s7_plus = SubRecord(
+    {
+        "is_s7_plus": Boolean(),
+        "protocol_version": Unsigned8BitInteger(),
+        "return_value": Signed64BitInteger(),
+        "session_id": Unsigned32BitInteger(),
+        "version": String(
+            doc="The version string of the server session.",
+            examples=["1;6ES7 511-1AK01-0AB0 ;V2.1"],
+        ),
+        "order_number": String(examples=["6ES7 511-1AK01-0AB0"]),
+        "firmware": String(examples=["2.1"]),
+        "error": String(),
+    }
+)
+
 siemens_scan_response = SubRecord(
     {
         "result": SubRecord(
@@ -26,6 +43,7 @@ siemens_scan_response = SubRecord(
                 "module_id": String(),
                 "hardware": String(),
                 "firmware": String(),
+                "s7_plus": siemens_s7_plus,
             }
         )
     },
@@ -0,0 +1,49 @@
+# zschema sub-schema for zgrab2's SIP module
+# Registers zgrab2-sip globally, and sip with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+sip_header = SubRecord(
+    {
+        "name": String(),
+        "value": String(),
+    }
+)
+
+sip_response = SubRecord(
+    {
+        "status_code": Unsigned16BitInteger(),
+        "reason_phrase": String(),
+        "server": String(),
+        "user_agent": String(),
+        "allow": ListOf(String(), doc="Methods the server supports"),
+        "supported": ListOf(String(), doc="Option tags (extensions) the server supports"),
+        "accept": ListOf(String()),
+        "allow_events": ListOf(String()),
+        "headers": ListOf(sip_header),
+    }
+)
+
+# Schema for ScanResults struct
+sip_scan_response = SubRecord(
+    {
+        "transport": Enum(values=["udp", "tcp", "tls"]),
+        "response": sip_response,
+        "provisional": ListOf(sip_response),
+        "tls": zgrab2.tls_log,
+        "probe": zgrab2.udp_probe_result,
+    }
+)
+
+sip_scan = SubRecord(
+    {
+        "result": sip_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-sip", sip_scan)
+zgrab2.register_scan_response_type("sip", sip_scan)
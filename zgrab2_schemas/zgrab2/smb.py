@@ -46,10 +46,42 @@ session_setup_log = SubRecord(
             "setup_flags": Unsigned16BitInteger(),
             "target_name": String(),
             "negotiate_flags": Unsigned32BitInteger(),
+            "ntlm_info": SubRecord(
+                {
+                    "netbios_computer_name": String(),
+                    "netbios_domain_name": String(),
+                    "dns_computer_name": String(),
+                    "dns_domain_name": String(),
+                    "dns_tree_name": String(),
+                    "timestamp": Unsigned32BitInteger(),
+                    "os_version": String(examples=["10.0.20348"]),
+                    "os_build": Unsigned16BitInteger(),
+                    "ntlm_revision": Unsigned8BitInteger(),
+                },
+                doc="The server's names, time and version from the NTLMSSP challenge.",
+            ),
         },
     )
 )
 
+dialect_log = SubRecord(
+    {
+        "dialect": String(examples=["SMB 1.0", "SMB 2.0.2", "SMB 3.1.1"]),
+        "supported": Boolean(),
+        "status": Unsigned32BitInteger(),
+        "signing_enabled": Boolean(),
+        "signing_required": Boolean(),
+        "capabilities": Unsigned32BitInteger(),
+        "encryption_supported": Boolean(),
+        "preauth_integrity_hash": String(examples=["SHA-512"]),
+        "encryption_cipher": String(examples=["AES-128-GCM", "AES-256-GCM"]),
+        "signing_algorithm": String(examples=["AES-CMAC", "AES-GMAC"]),
+        "compression_algorithms": ListOf(String()),
+        "rdma_transforms": ListOf(String()),
+        "error": String(),
+    }
+)
+
 
 smb_scan_response = SubRecord(
     {
@@ -96,6 +128,13 @@ smb_scan_response = SubRecord(
                     doc="Server supports the NTLM authentication method"
                 ),
                 "session_setup_log": session_setup_log,
+                "signing_required": Boolean(
+                    doc="Server requires message signing, per the negotiation response"
+                ),
+                "dialects": ListOf(
+                    dialect_log,
+                    doc="The responses to negotiating each dialect on its own, with --enumerate-dialects.",
+                ),
             }
         )
     },
@@ -7,18 +7,116 @@ import zschema.registry
 import zcrypto_schemas.zcrypto as zcrypto
 from . import zgrab2
 
+tlsa_record = SubRecord(
+    {
+        "usage": Unsigned8BitInteger(),
+        "selector": Unsigned8BitInteger(),
+        "matching_type": Unsigned8BitInteger(),
+        "data": String(),
+        "usable": Boolean(),
+        "matched": Boolean(),
+    }
+)
+
+address_probe = SubRecord(
+    {
+        "address": String(),
+        "response": String(),
+        "code": Unsigned32BitInteger(),
+        "exposed": Boolean(),
+    }
+)
+
+extension_check = SubRecord(
+    {
+        "advertised": Boolean(),
+        "honored": Boolean(),
+        "discrepancy": Boolean(),
+        "responses": ListOf(String()),
+        "error": String(),
+    }
+)
+
 smtp_scan_response = SubRecord(
     {
         "result": SubRecord(
             {
                 "banner": String(),
                 "ehlo": String(),
+                "extensions": ListOf(String()),
+                "auth_mechanisms": ListOf(String()),
                 "helo": String(),
                 "help": String(),
                 "starttls": String(),
+                "ehlo_after_starttls": String(),
+                "extensions_after_starttls": ListOf(String()),
+                "auth_mechanisms_after_starttls": ListOf(String()),
+                "vrfy": address_probe,
+                "expn": address_probe,
+                "relay": SubRecord(
+                    {
+                        "mail_from": String(),
+                        "mail_from_code": Unsigned32BitInteger(),
+                        "rcpt_to": String(),
+                        "rcpt_to_code": Unsigned32BitInteger(),
+                        "rset": String(),
+                        "verdict": Enum(
+                            [
+                                "accepted",
+                                "rejected",
+                                "temporary_failure",
+                                "sender_rejected",
+                            ]
+                        ),
+                    }
+                ),
+                "extension_checks": SubRecord(
+                    {
+                        "smtputf8": extension_check,
+                        "chunking": extension_check,
+                        "pipelining": extension_check,
+                    }
+                ),
                 "quit": String(),
                 "implicit_tls": Boolean(),
                 "tls": zgrab2.tls_log,
+                "mta_sts": SubRecord(
+                    {
+                        "domain": String(),
+                        "record": String(),
+                        "id": String(),
+                        "record_error": String(),
+                        "policy_url": String(),
+                        "http_status": Unsigned32BitInteger(),
+                        "policy": SubRecord(
+                            {
+                                "version": String(),
+                                "mode": String(),
+                                "mx": ListOf(String()),
+                                "max_age": Unsigned32BitInteger(),
+                                "raw": String(),
+                            }
+                        ),
+                        "policy_error": String(),
+                        "mx_matched": Boolean(),
+                    }
+                ),
+                "tls_rpt": SubRecord(
+                    {
+                        "record": String(),
+                        "rua": ListOf(String()),
+                        "error": String(),
+                    }
+                ),
+                "dane": SubRecord(
+                    {
+                        "name": String(),
+                        "authenticated": Boolean(),
+                        "records": ListOf(tlsa_record),
+                        "verdict": Enum(["no_records", "valid", "invalid", "unusable"]),
+                        "error": String(),
+                    }
+                ),
             }
         )
     },
@@ -0,0 +1,55 @@
+# zschema sub-schema for zgrab2's SNMP module
+# Registers zgrab2-snmp globally, and snmp with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+snmp_system = SubRecord(
+    {
+        "sys_descr": String(),
+        "sys_object_id": String(),
+        "sys_uptime": Unsigned32BitInteger(doc="System uptime in hundredths of a second"),
+    }
+)
+
+snmp_community_response = SubRecord(
+    {
+        "version": String(),
+        "community": String(),
+        "error_status": Signed64BitInteger(),
+        "system": snmp_system,
+    }
+)
+
+snmp_engine = SubRecord(
+    {
+        "engine_id": String(doc="Hex-encoded snmpEngineID"),
+        "engine_boots": Signed64BitInteger(),
+        "engine_time": Signed64BitInteger(),
+        "enterprise": Unsigned32BitInteger(),
+        "vendor": String(),
+        "format": String(),
+        "data": String(),
+    }
+)
+
+# Schema for ScanResults struct
+snmp_scan_response = SubRecord(
+    {
+        "communities": ListOf(snmp_community_response),
+        "engine": snmp_engine,
+        "probes": ListOf(zgrab2.udp_probe_result),
+    }
+)
+
+snmp_scan = SubRecord(
+    {
+        "result": snmp_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-snmp", snmp_scan)
+zgrab2.register_scan_response_type("snmp", snmp_scan)
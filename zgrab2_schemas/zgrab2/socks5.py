@@ -18,12 +18,41 @@ socks5_response_explanation = SubRecord(
     }
 )
 
+socks5_auth_method = SubRecord(
+    {
+        "method": Unsigned8BitInteger(),
+        "name": String(),
+        "accepted": Boolean(),
+        "error": String(),
+    }
+)
+
+socks5_command_result = SubRecord(
+    {
+        "reply": Unsigned8BitInteger(),
+        "reply_name": String(),
+        "bound_address": String(),
+        "bound_port": Unsigned16BitInteger(),
+        "egress": Enum(
+            ["target", "unspecified", "other"],
+            doc="Whether the bound address is the scanned address, unspecified (0.0.0.0 or ::), or another address.",
+        ),
+        "error": String(),
+    }
+)
+
 socks5_scan_response = SubRecord(
     {
         "version": String(),
         "method_selection": String(),
         "connection_response": String(),
         "connection_response_explanation": socks5_response_explanation,
+        "methods": ListOf(
+            socks5_auth_method,
+            doc="Each authentication method offered on its own, with --enumerate-methods.",
+        ),
+        "connect": socks5_command_result,
+        "udp_associate": socks5_command_result,
     }
 )
 
@@ -0,0 +1,74 @@
+# zschema sub-schema for zgrab2's SSDP module
+# Registers zgrab2-ssdp globally, and ssdp with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+ssdp_response = SubRecord(
+    {
+        "status_code": String(),
+        "server": String(),
+        "location": String(),
+        "usn": String(),
+        "st": String(),
+        "cache_control": String(),
+        "boot_id": String(),
+    }
+)
+
+ssdp_service = SubRecord(
+    {
+        "service_type": String(),
+        "service_id": String(),
+        "control_url": String(),
+        "scpd_url": String(),
+        "event_sub_url": String(),
+    }
+)
+
+ssdp_device_fields = {
+    "device_type": String(),
+    "friendly_name": String(),
+    "manufacturer": String(),
+    "manufacturer_url": String(),
+    "model_description": String(),
+    "model_name": String(),
+    "model_number": String(),
+    "model_url": String(),
+    "serial_number": String(),
+    "udn": String(),
+    "presentation_url": String(),
+    "services": ListOf(ssdp_service),
+}
+
+
+def ssdp_device(depth):
+    """Embedded devices nest arbitrarily; the schema covers the given depth."""
+    fields = dict(ssdp_device_fields)
+    if depth > 0:
+        fields["devices"] = ListOf(ssdp_device(depth - 1))
+    return SubRecord(fields)
+
+
+# Schema for ScanResults struct
+ssdp_scan_response = SubRecord(
+    {
+        "responses": ListOf(ssdp_response),
+        "device": ssdp_device(3),
+        "description_response": zgrab2.http_api_response,
+        "description_error": String(),
+        "udp_probe": zgrab2.udp_probe_result,
+    }
+)
+
+ssdp_scan = SubRecord(
+    {
+        "result": ssdp_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-ssdp", ssdp_scan)
+zgrab2.register_scan_response_type("ssdp", ssdp_scan)
@@ -0,0 +1,49 @@
+# zschema sub-schema for zgrab2's STUN module
+# Registers zgrab2-stun globally, and stun with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+stun_error_code = SubRecord(
+    {
+        "code": Unsigned16BitInteger(),
+        "reason": String(),
+    }
+)
+
+stun_turn = SubRecord(
+    {
+        "supported": Boolean(doc="True if the server answered the Allocate request"),
+        "allocated": Boolean(doc="True if an allocation was granted without credentials"),
+        "error": stun_error_code,
+        "realm": String(),
+        "software": String(),
+    }
+)
+
+# Schema for ScanResults struct
+stun_scan_response = SubRecord(
+    {
+        "transport": String(),
+        "mapped_address": String(doc="The client address as seen by the server"),
+        "software": String(),
+        "response_origin": String(),
+        "other_address": String(),
+        "binding_error": stun_error_code,
+        "turn": stun_turn,
+        "tls": zgrab2.tls_log,
+        "probes": ListOf(zgrab2.udp_probe_result),
+    }
+)
+
+stun_scan = SubRecord(
+    {
+        "result": stun_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-stun", stun_scan)
+zgrab2.register_scan_response_type("stun", stun_scan)
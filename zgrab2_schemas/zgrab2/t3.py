@@ -0,0 +1,45 @@
+# zschema sub-schema for zgrab2's T3 module
+# Registers zgrab2-t3 globally, and t3 with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+t3_param = SubRecord(
+    {
+        "name": String(),
+        "value": String(),
+    }
+)
+
+t3_hello = SubRecord(
+    {
+        "version": String(),
+        "tunneled": Boolean(),
+        "abbrev_table_size": Signed32BitInteger(),
+        "header_length": Signed32BitInteger(),
+        "max_message_size": Signed32BitInteger(),
+        "params": ListOf(t3_param),
+    }
+)
+
+# Schema for ScanResults struct
+t3_scan_response = SubRecord(
+    {
+        "response": ListOf(String()),
+        "hello": t3_hello,
+        "rejected": Boolean(),
+        "tls": zgrab2.tls_log,
+    }
+)
+
+t3_scan = SubRecord(
+    {
+        "result": t3_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-t3", t3_scan)
+zgrab2.register_scan_response_type("t3", t3_scan)
@@ -23,6 +23,34 @@ telnet_scan_response = SubRecord(
                 "do": ListOf(telnet_option),
                 "wont": ListOf(telnet_option),
                 "dont": ListOf(telnet_option),
+                "negotiation": ListOf(
+                    SubRecord(
+                        {
+                            "sender": Enum(values=["server", "client"]),
+                            "command": Enum(values=["WILL", "WONT", "DO", "DONT", "SB"]),
+                            "option": telnet_option,
+                            "data": Binary(doc="The payload of a subnegotiation."),
+                        }
+                    ),
+                    doc="The complete sequence of option commands sent by the server and the client, in order.",
+                ),
+                "terminal_type_requested": Boolean(
+                    doc="True if the server sent DO TERMINAL-TYPE."
+                ),
+                "terminal_type_queried": Boolean(
+                    doc="True if the server sent SB TERMINAL-TYPE SEND (only after the client agrees with --terminal-type)."
+                ),
+                "naws_requested": Boolean(doc="True if the server sent DO NAWS."),
+                "prompt": SubRecord(
+                    {
+                        "class": Enum(
+                            values=["router_cli", "busybox", "windows", "unknown"]
+                        ),
+                        "type": Enum(values=["login", "password", "shell"]),
+                        "line": String(doc="The last non-empty line of the banner."),
+                    },
+                    doc="Classification of the banner's device and final prompt.",
+                ),
             }
         )
     },
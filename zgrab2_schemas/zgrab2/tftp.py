@@ -0,0 +1,41 @@
+# zschema sub-schema for zgrab2's TFTP module
+# Registers zgrab2-tftp globally, and tftp with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+tftp_option = SubRecord(
+    {
+        "name": String(),
+        "value": String(),
+    }
+)
+
+# Schema for ScanResults struct
+tftp_scan_response = SubRecord(
+    {
+        "filename": String(),
+        "response": String(doc="Opcode of the response: error, oack or data"),
+        "error_code": Unsigned16BitInteger(),
+        "error_name": String(),
+        "error_message": String(),
+        "options": ListOf(tftp_option, doc="Options acknowledged in an OACK"),
+        "file_exists": Boolean(),
+        "server_port": Unsigned16BitInteger(doc="Port (transfer ID) the response came from"),
+        "same_port": Boolean(),
+        "implementation": String(),
+        "probe": zgrab2.udp_probe_result,
+    }
+)
+
+tftp_scan = SubRecord(
+    {
+        "result": tftp_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-tftp", tftp_scan)
+zgrab2.register_scan_response_type("tftp", tftp_scan)
@@ -0,0 +1,48 @@
+# zschema sub-schema for zgrab2's Tor module
+# Registers zgrab2-tor globally, and tor with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+tor_certificate = SubRecord(
+    {
+        "type": Unsigned8BitInteger(),
+        "type_name": String(),
+        "raw": Binary(),
+    }
+)
+
+tor_netinfo = SubRecord(
+    {
+        "timestamp": DateTime(),
+        "other_address": String(),
+        "my_addresses": ListOf(String()),
+    }
+)
+
+# Schema for ScanResults struct
+tor_scan_response = SubRecord(
+    {
+        "is_relay": Boolean(),
+        "link_versions": ListOf(Unsigned16BitInteger()),
+        "negotiated_version": Unsigned16BitInteger(),
+        "certificates": ListOf(tor_certificate),
+        "fingerprint": String(),
+        "ed25519_identity": String(),
+        "auth_methods": ListOf(Unsigned16BitInteger()),
+        "netinfo": tor_netinfo,
+        "tls": zgrab2.tls_log,
+    }
+)
+
+tor_scan = SubRecord(
+    {
+        "result": tor_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-tor", tor_scan)
+zgrab2.register_scan_response_type("tor", tor_scan)
@@ -0,0 +1,61 @@
+# zschema sub-schema for zgrab2's VNC module
+# Registers zgrab2-vnc globally, and vnc with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+vnc_security_type = SubRecord(
+    {
+        "value": Unsigned8BitInteger(),
+        "name": String(),
+    }
+)
+
+vnc_pixel_format = SubRecord(
+    {
+        "bits_per_pixel": Unsigned8BitInteger(),
+        "depth": Unsigned8BitInteger(),
+        "big_endian": Boolean(),
+        "true_colour": Boolean(),
+        "red_max": Unsigned16BitInteger(),
+        "green_max": Unsigned16BitInteger(),
+        "blue_max": Unsigned16BitInteger(),
+        "red_shift": Unsigned8BitInteger(),
+        "green_shift": Unsigned8BitInteger(),
+        "blue_shift": Unsigned8BitInteger(),
+    }
+)
+
+vnc_desktop = SubRecord(
+    {
+        "width": Unsigned16BitInteger(),
+        "height": Unsigned16BitInteger(),
+        "pixel_format": vnc_pixel_format,
+        "name": String(),
+    }
+)
+
+# Schema for ScanResults struct
+vnc_scan_response = SubRecord(
+    {
+        "protocol_version": String(),
+        "security_types": ListOf(vnc_security_type),
+        "connection_failed_reason": String(),
+        "no_auth": Boolean(),
+        "security_failure_reason": String(),
+        "desktop": vnc_desktop,
+        "tls": zgrab2.tls_log,
+    }
+)
+
+vnc_scan = SubRecord(
+    {
+        "result": vnc_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-vnc", vnc_scan)
+zgrab2.register_scan_response_type("vnc", vnc_scan)
@@ -0,0 +1,28 @@
+# zschema sub-schema for zgrab2's WHOIS module
+# Registers zgrab2-whois globally, and whois with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+# Schema for ScanResults struct
+whois_scan_response = SubRecord(
+    {
+        "query": String(),
+        "response": String(),
+        "truncated": Boolean(),
+        "referral": String(doc="WHOIS server the response refers to"),
+        "referral_field": String(),
+    }
+)
+
+whois_scan = SubRecord(
+    {
+        "result": whois_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-whois", whois_scan)
+zgrab2.register_scan_response_type("whois", whois_scan)
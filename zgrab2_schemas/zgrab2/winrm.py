@@ -0,0 +1,34 @@
+# zschema sub-schema for zgrab2's WinRM module
+# Registers zgrab2-winrm globally, and winrm with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+# Schema for ScanResults struct
+winrm_scan_response = SubRecord(
+    {
+        "anonymous_identify": Boolean(doc="True if the listener answered Identify without credentials"),
+        "protocol_version": String(),
+        "product_vendor": String(),
+        "product_version": String(),
+        "security_profiles": ListOf(String()),
+        "os_version": String(),
+        "stack_version": String(),
+        "auth_schemes": ListOf(String()),
+        "identify_response": zgrab2.http_api_response,
+        "auth_response": zgrab2.http_api_response,
+        "tls": zgrab2.tls_log,
+    }
+)
+
+winrm_scan = SubRecord(
+    {
+        "result": winrm_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-winrm", winrm_scan)
+zgrab2.register_scan_response_type("winrm", winrm_scan)
@@ -0,0 +1,37 @@
+# zschema sub-schema for zgrab2's WireGuard module
+# Registers zgrab2-wireguard globally, and wireguard with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+wireguard_reply = SubRecord(
+    {
+        "message_type": Unsigned8BitInteger(),
+        "length": Unsigned16BitInteger(),
+        "receiver_index_matches": Boolean(),
+    }
+)
+
+# Schema for ScanResults struct
+wireguard_scan_response = SubRecord(
+    {
+        "response": Enum(
+            values=["none", "unreachable", "cookie_reply", "handshake_response", "unexpected"]
+        ),
+        "valid_mac1": Boolean(doc="True if the initiation was authenticated for the server's public key"),
+        "reply": wireguard_reply,
+        "udp_probe": zgrab2.udp_probe_result,
+    }
+)
+
+wireguard_scan = SubRecord(
+    {
+        "result": wireguard_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-wireguard", wireguard_scan)
+zgrab2.register_scan_response_type("wireguard", wireguard_scan)
@@ -0,0 +1,42 @@
+# zschema sub-schema for zgrab2's X11 module
+# Registers zgrab2-x11 globally, and x11 with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+x11_screen = SubRecord(
+    {
+        "width": Unsigned16BitInteger(),
+        "height": Unsigned16BitInteger(),
+        "width_mm": Unsigned16BitInteger(),
+        "height_mm": Unsigned16BitInteger(),
+        "root_depth": Unsigned8BitInteger(),
+    }
+)
+
+# Schema for ScanResults struct
+x11_scan_response = SubRecord(
+    {
+        "status": String(),
+        "access_granted": Boolean(),
+        "protocol_version": String(),
+        "reason": String(),
+        "vendor": String(),
+        "release_number": Unsigned32BitInteger(),
+        "max_request_length": Unsigned16BitInteger(),
+        "screens": ListOf(x11_screen),
+        "extensions": ListOf(String()),
+    }
+)
+
+x11_scan = SubRecord(
+    {
+        "result": x11_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-x11", x11_scan)
+zgrab2.register_scan_response_type("x11", x11_scan)
@@ -0,0 +1,54 @@
+# zschema sub-schema for zgrab2's XMPP module
+# Registers zgrab2-xmpp globally, and xmpp with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+xmpp_stream = SubRecord(
+    {
+        "id": String(),
+        "from": String(),
+        "version": String(),
+        "lang": String(),
+    }
+)
+
+xmpp_features = SubRecord(
+    {
+        "starttls": Boolean(),
+        "starttls_required": Boolean(),
+        "mechanisms": ListOf(String(), doc="SASL mechanisms"),
+        "compression": ListOf(String(), doc="Stream compression methods (XEP-0138)"),
+        "other": ListOf(String(), doc="Other features, as 'element (namespace)'"),
+    }
+)
+
+# Schema for ScanResults struct
+xmpp_scan_response = SubRecord(
+    {
+        "stream": xmpp_stream,
+        "features": xmpp_features,
+        "stream_error": SubRecord(
+            {
+                "condition": String(),
+                "text": String(),
+            }
+        ),
+        "starttls_failed": Boolean(),
+        "tls": zgrab2.tls_log,
+        "tls_stream": xmpp_stream,
+        "tls_features": xmpp_features,
+    }
+)
+
+xmpp_scan = SubRecord(
+    {
+        "result": xmpp_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-xmpp", xmpp_scan)
+zgrab2.register_scan_response_type("xmpp", xmpp_scan)
@@ -33,7 +33,26 @@ grab_result = Record(
         "domain": String(
             required=False, doc="The domain name of the target, if available."
         ),
+        "resolved_ips": ListOf(
+            String(),
+            required=False,
+            doc="With --resolve-all, every address the domain resolved to.",
+        ),
         "data": SubRecord(scan_response_types, doc="The scan data for this host."),
+        # zgrab2/run_metadata.go: RunMetadata
+        "run": SubRecord(
+            {
+                "scan_id": String(doc="Identifier of the scan run."),
+                "operator_label": String(doc="Operator-supplied label for the run."),
+                "zgrab2_version": String(doc="Version of zgrab2 used for the run."),
+                "config_hash": String(
+                    doc="SHA-256 of the module configuration used for the run."
+                ),
+                "start_time": DateTime(doc="The time the run was started."),
+            },
+            required=False,
+            doc="Metadata identifying the scan run, if --run-metadata was set.",
+        ),
     }
 )
 
@@ -56,6 +75,11 @@ base_scan_response = SubRecord(
         "protocol": String(doc="The identifier of the protocol being scanned."),
         "port": Unsigned32BitInteger(doc="The port the scan was executed on."),
         "timestamp": DateTime(doc="The time the scan was started."),
+        "transport": Enum(
+            values=["tcp", "tls"],
+            required=False,
+            doc="The transport the scan connected with, if several were tried with --transports.",
+        ),
         "result": SubRecord(
             {}, required=False
         ),  # This is overridden by the protocols' implementations
@@ -67,11 +91,252 @@ base_scan_response = SubRecord(
     }
 )
 
+# zgrab2/tls.go: TLSLog
+ocsp_response = SubRecord(
+    {
+        "raw": Binary(),
+        "response_status": String(),
+        "cert_status": String(doc="good, revoked or unknown."),
+        "produced_at": DateTime(),
+        "this_update": DateTime(),
+        "next_update": DateTime(),
+        "revoked_at": DateTime(),
+        "revocation_reason": SubRecord({"value": Unsigned8BitInteger(), "name": String()}),
+        "signature_valid": Boolean(),
+        "signature_error": String(),
+        "error": String(),
+    }
+)
+
+# zgrab2/tls_resumption.go: ResumptionAttempt
+resumption_attempt = SubRecord(
+    {
+        "issued": Boolean(doc="Whether the first handshake gave a session ID or ticket."),
+        "resumed": Boolean(),
+        "lifetime_hint": Unsigned32BitInteger(doc="The lifetime hint of a TLS 1.2 ticket, in seconds."),
+        "tickets": Unsigned32BitInteger(doc="The tickets issued in the first handshake."),
+        "rotated": Boolean(doc="Whether the resumed handshake issued a new ticket."),
+        "error": String(),
+    }
+)
+
 # zgrab2/tls.go: TLSLog
 tls_log = SubRecord(
-    {"handshake_log": zcrypto.TLSHandshake(doc="The TLS handshake log.")}
+    {
+        "handshake_log": zcrypto.TLSHandshake(doc="The TLS handshake log."),
+        # zgrab2/tls_fingerprint.go: TLSFingerprints
+        "fingerprints": SubRecord(
+            {
+                "ja3": String(doc="MD5 of ja3_string."),
+                "ja3_string": String(doc="The JA3 string of the ClientHello."),
+                "ja4": String(doc="The JA4 fingerprint of the ClientHello."),
+                "ja3s": String(doc="MD5 of ja3s_string."),
+                "ja3s_string": String(doc="The JA3S string of the ServerHello."),
+                "ja4s": String(doc="The JA4S fingerprint of the ServerHello."),
+            },
+            doc="Fingerprints of the hello messages of the handshake.",
+        ),
+        "ech": SubRecord(
+            {
+                "config_source": String(doc="flag, dns or grease."),
+                "config_list": Binary(),
+                "public_name": String(),
+                "accepted": Boolean(),
+                "retry_configs": Binary(),
+                "retry_accepted": Boolean(),
+                "dns_error": String(),
+                "error": String(),
+            },
+            doc="The Encrypted Client Hello handshake of --ech.",
+        ),
+        "ct": SubRecord(
+            {
+                "scts": ListOf(
+                    SubRecord(
+                        {
+                            "source": String(doc="embedded, tls_extension or ocsp."),
+                            "log_id": Binary(),
+                            "timestamp": DateTime(),
+                            "known_log": Boolean(),
+                            "log_description": String(),
+                            "log_operator": String(),
+                            "log_state": String(),
+                            "valid": Boolean(),
+                            "error": String(),
+                        }
+                    )
+                ),
+                "valid_scts": Unsigned32BitInteger(doc="The number of distinct logs with a valid SCT."),
+                "valid_operators": ListOf(String()),
+                "error": String(),
+            },
+            doc="The validation of the SCTs of the certificate of --validate-scts.",
+        ),
+        "ocsp": SubRecord(
+            {
+                "stapled": ocsp_response,
+                "query": SubRecord(
+                    {
+                        "responder": String(),
+                        "latency_ms": Unsigned32BitInteger(),
+                        "http_status": Unsigned16BitInteger(),
+                        "response": ocsp_response,
+                        "error": String(),
+                    },
+                    doc="The response of the OCSP responder of the certificate, with --ocsp-query.",
+                ),
+            }
+        ),
+        "pq": SubRecord(
+            {
+                "offered_groups": ListOf(String()),
+                "pq_only": Boolean(),
+                "version": String(),
+                "selected_group": String(),
+                "hello_retry_request": Boolean(),
+                "post_quantum": Boolean(),
+                "alert": String(),
+                "error": String(),
+            },
+            doc="The group the server selects from the post-quantum hybrid key shares of --pq.",
+        ),
+        "heartbleed": SubRecord(
+            {
+                "heartbeat_supported": Boolean(),
+                "status": String(doc="vulnerable, patched or unsupported."),
+                "response_length": Unsigned32BitInteger(),
+                "error": String(),
+            },
+            doc="The Heartbleed (CVE-2014-0160) check of --heartbleed.",
+        ),
+        "resumption": SubRecord(
+            {
+                "session_id": resumption_attempt,
+                "tls12_ticket": resumption_attempt,
+                "tls13_ticket": resumption_attempt,
+            },
+            doc="The session resumption attempts of --resumption.",
+        ),
+        # zgrab2/tls_client_certificate.go: CertificateRequest
+        "certificate_request": SubRecord(
+            {
+                "acceptable_cas": ListOf(String()),
+                "signature_schemes": ListOf(String()),
+                "certificate_sent": Boolean(
+                    doc="Whether the client certificate of --certificates or --certificate-pkcs12 was sent."
+                ),
+            },
+            doc="The request of the server for a client certificate.",
+        ),
+        # zgrab2/tls_enumerate.go: EnumerationResult
+        "enumeration": SubRecord(
+            {
+                "versions": ListOf(
+                    SubRecord(
+                        {
+                            "version": String(),
+                            "cipher_suites": ListOf(String()),
+                            "server_preference": Boolean(),
+                        }
+                    )
+                ),
+                "groups": ListOf(String()),
+                "groups_version": String(doc="The version the groups were enumerated with."),
+                "groups_server_preference": Boolean(),
+                "handshakes": Unsigned32BitInteger(),
+                "error": String(),
+            },
+            doc="The versions, cipher suites and groups the server accepts, of --enumerate.",
+        ),
+        # zgrab2/tls_alpn.go: ALPNResult
+        "alpn": SubRecord(
+            {
+                "selected": ListOf(String()),
+                "attempts": ListOf(
+                    SubRecord(
+                        {
+                            "protocol": String(),
+                            "selected": Boolean(),
+                            "alert": String(),
+                            "error": String(),
+                        }
+                    )
+                ),
+            },
+            doc="The ALPN protocols the server selects when offered alone, of --alpn-enumerate.",
+        ),
+        # zgrab2/tls_downgrade.go: DowngradeResult
+        "downgrade": SubRecord(
+            {
+                "max_version": String(),
+                "fallbacks": ListOf(
+                    SubRecord(
+                        {
+                            "version": String(),
+                            "accepted": Boolean(),
+                            "rejected": Boolean(doc="Whether the server sent an inappropriate_fallback alert."),
+                            "alert": String(),
+                            "error": String(),
+                        }
+                    )
+                ),
+                "scsv_supported": Boolean(),
+                "downgrade_sentinel": Boolean(
+                    doc="Whether a TLS 1.3 server puts the downgrade sentinel in TLS 1.2 ServerHellos."
+                ),
+                "error": String(),
+            },
+            doc="The TLS_FALLBACK_SCSV fallbacks of --downgrade.",
+        ),
+        # zgrab2/tls_transcript.go: HandshakeTranscript
+        "transcript": SubRecord(
+            {
+                "messages": ListOf(
+                    SubRecord(
+                        {
+                            "sender": String(doc="client or server."),
+                            "type": String(),
+                            "decrypted": Boolean(),
+                            "raw": Binary(),
+                        }
+                    )
+                ),
+                "truncated": Boolean(),
+            },
+            doc="The raw handshake messages of --handshake-transcript.",
+        ),
+    }
 )
 
+# zgrab2/udp_probe.go: UDPProbeResult
+udp_probe_result = SubRecord(
+    {
+        "probe": String(doc="The name of the probe."),
+        "attempts": Unsigned32BitInteger(doc="How many times the probe was sent."),
+        "response": Binary(doc="The datagram received in response."),
+        "rtt_ms": Unsigned32BitInteger(doc="Round-trip time of the response, in ms."),
+        "icmp": Enum(
+            values=["port-unreachable", "host-unreachable", "net-unreachable", "prohibited"],
+            doc="The ICMP unreachable message received instead of a response, if any.",
+        ),
+        "ignored": Unsigned32BitInteger(
+            doc="Number of datagrams received that did not match the probe."
+        ),
+    }
+)
+
+# zgrab2/modules/httpapi/client.go: Response
+http_api_response = SubRecord(
+    {
+        "path": String(doc="The requested path."),
+        "status_code": Unsigned16BitInteger(),
+        "status": String(),
+        "content_type": String(),
+        "server": String(),
+        "www_authenticate": String(),
+        "body": String(doc="The response body, truncated to --max-size."),
+    }
+)
 
 # Register a schema type for responses with the given name.
 def register_scan_response_type(name, schema):
@@ -0,0 +1,56 @@
+# zschema sub-schema for zgrab2's ZooKeeper module
+# Registers zgrab2-zookeeper globally, and zookeeper with the main zgrab2 schema.
+from zschema.leaves import *
+from zschema.compounds import *
+import zschema.registry
+
+from . import zgrab2
+
+zookeeper_stats = SubRecord(
+    {
+        "version": String(),
+        "mode": String(doc="standalone, leader, follower or observer"),
+        "node_count": Signed64BitInteger(),
+        "connections": Signed64BitInteger(),
+        "outstanding": Signed64BitInteger(),
+        "zxid": String(),
+        "latency": String(doc="min/avg/max"),
+    }
+)
+
+zookeeper_property = SubRecord(
+    {
+        "name": String(),
+        "value": String(),
+    }
+)
+
+zookeeper_command = SubRecord(
+    {
+        "name": String(),
+        "response": String(),
+        "not_allowed": Boolean(doc="Command is disabled by 4lw.commands.whitelist"),
+    }
+)
+
+# Schema for ScanResults struct
+zookeeper_scan_response = SubRecord(
+    {
+        "healthy": Boolean(),
+        "stats": zookeeper_stats,
+        "environment": ListOf(zookeeper_property),
+        "commands": ListOf(zookeeper_command),
+        "admin_responses": ListOf(zgrab2.http_api_response),
+        "tls": zgrab2.tls_log,
+    }
+)
+
+zookeeper_scan = SubRecord(
+    {
+        "result": zookeeper_scan_response,
+    },
+    extends=zgrab2.base_scan_response,
+)
+
+zschema.registry.register_schema("zgrab2-zookeeper", zookeeper_scan)
+zgrab2.register_scan_response_type("zookeeper", zookeeper_scan)